@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/gorilla/mux"
+
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+)
+
+// spendWindow is the period max_daily_spend applies to, a sliding window
+const spendWindow = 24 * time.Hour
+
+// Limits an AccountLimitError names
+const (
+	limitConcurrentJobs = "max_concurrent_jobs"
+	limitDailySpend     = "max_daily_spend"
+)
+
+// AccountLimit caps the jobs of one client address, zero is unlimited
+type AccountLimit struct {
+	MaxConcurrentJobs int     `json:"max_concurrent_jobs" yaml:"max_concurrent_jobs,omitempty" mapstructure:"max_concurrent_jobs"` // submitted, queued and running jobs
+	MaxDailySpend     float64 `json:"max_daily_spend" yaml:"max_daily_spend,omitempty" mapstructure:"max_daily_spend"`             // MEDAS paid for jobs in the last 24 hours
+}
+
+// LimitsConfig holds the limits of every address and overrides per address
+type LimitsConfig struct {
+	Default  AccountLimit            `yaml:"default,omitempty" mapstructure:"default"`
+	Accounts map[string]AccountLimit `yaml:"accounts,omitempty" mapstructure:"accounts"` // replace the default of these addresses
+}
+
+// For returns the limits of addr
+func (c LimitsConfig) For(addr string) AccountLimit {
+	if limit, ok := c.Accounts[addr]; ok {
+		return limit
+	}
+	return c.Default
+}
+
+// Validate checks the limits and the addresses they are set for
+func (c LimitsConfig) Validate() error {
+	check := func(key string, limit AccountLimit) error {
+		if limit.MaxConcurrentJobs < 0 || limit.MaxDailySpend < 0 {
+			return fmt.Errorf("%s: limits must not be negative", key)
+		}
+		return nil
+	}
+	if err := check("payment.limits.default", c.Default); err != nil {
+		return err
+	}
+	for addr, limit := range c.Accounts {
+		if _, err := sdk.AccAddressFromBech32(addr); err != nil {
+			return fmt.Errorf("payment.limits.accounts: invalid address %s: %w", addr, err)
+		}
+		if err := check("payment.limits.accounts."+addr, limit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describeLimit is a limit for the startup banner
+func describeLimit(l AccountLimit) string {
+	jobs, spend := "unlimited jobs", "unlimited spend"
+	if l.MaxConcurrentJobs > 0 {
+		jobs = fmt.Sprintf("%d concurrent job(s)", l.MaxConcurrentJobs)
+	}
+	if l.MaxDailySpend > 0 {
+		spend = fmt.Sprintf("%g %s per 24h", l.MaxDailySpend, network.Current().DisplayDenom)
+	}
+	return jobs + ", " + spend
+}
+
+// AccountUsage is what counts against the limits of an address
+type AccountUsage struct {
+	Address        string       `json:"address"`
+	Limits         AccountLimit `json:"limits"`
+	ActiveJobs     int          `json:"active_jobs"`
+	DailySpend     float64      `json:"daily_spend"`               // MEDAS, paid and awaiting verification
+	RemainingJobs  *int         `json:"remaining_jobs,omitempty"`  // omitted without a limit
+	RemainingSpend *float64     `json:"remaining_spend,omitempty"` // omitted without a limit
+	Currency       string       `json:"currency"`
+}
+
+// AccountLimitError rejects a submission beyond the limits of its address
+type AccountLimitError struct {
+	Limit      string       `json:"limit"`
+	Message    string       `json:"message"`
+	Usage      AccountUsage `json:"usage"`
+	RetryAfter *time.Time   `json:"retry_after,omitempty"` // when the daily spend allows the job, nil if unknown
+}
+
+func (e *AccountLimitError) Error() string {
+	return e.Message
+}
+
+// accountLimitResponse is the body of a 429 for an account limit, in every
+// API version
+type accountLimitResponse struct {
+	Error string `json:"error"` // limit_exceeded
+	*AccountLimitError
+}
+
+// writeAccountLimitError answers 429 with the limit, usage and when to retry
+func writeAccountLimitError(w http.ResponseWriter, e *AccountLimitError) {
+	if e.RetryAfter != nil {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(*e.RetryAfter).Seconds()))))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(accountLimitResponse{"limit_exceeded", e})
+}
+
+// accountUsage counts the active jobs of addr and what it spent in the last
+// 24 hours: booked payments net of refunds and jobs whose payment is still
+// being verified. The ledger entries of the window are returned oldest first.
+func (rps *RealPaymentService) accountUsage(addr string, now time.Time) (AccountUsage, []LedgerEntry) {
+	usage := AccountUsage{Address: addr, Limits: rps.limits.For(addr), Currency: network.Current().DisplayDenom}
+	entries := rps.ledger.Entries(addr, now.Add(-spendWindow), time.Time{})
+	for _, e := range entries {
+		usage.DailySpend += netSpend(e)
+	}
+	for _, job := range rps.jobManager.ListJobs(addr, "") {
+		switch job.Status {
+		case compute.StatusSubmitted, compute.StatusQueued, compute.StatusRunning:
+		default:
+			continue
+		}
+		usage.ActiveJobs++
+		if !job.PaymentVerified && job.PriceBreakdown != nil && !job.SubmittedAt.Before(now.Add(-spendWindow)) {
+			usage.DailySpend += job.PriceBreakdown.TotalCost
+		}
+	}
+
+	if max := usage.Limits.MaxConcurrentJobs; max > 0 {
+		remaining := max - usage.ActiveJobs
+		if remaining < 0 {
+			remaining = 0
+		}
+		usage.RemainingJobs = &remaining
+	}
+	if max := usage.Limits.MaxDailySpend; max > 0 {
+		remaining := math.Max(0, max-usage.DailySpend)
+		usage.RemainingSpend = &remaining
+	}
+	return usage, entries
+}
+
+// netSpend is what a ledger entry was worth in MEDAS less its refund
+func netSpend(e LedgerEntry) float64 {
+	return e.ValueMEDAS * (1 - e.refundedShare())
+}
+
+// checkLimits returns an *AccountLimitError if n more jobs costing cost MEDAS
+// in total exceed the limits of addr
+func (rps *RealPaymentService) checkLimits(addr string, n int, cost float64, now time.Time) error {
+	usage, entries := rps.accountUsage(addr, now)
+	limit := usage.Limits
+	if limit.MaxConcurrentJobs > 0 && usage.ActiveJobs+n > limit.MaxConcurrentJobs {
+		return &AccountLimitError{
+			Limit:   limitConcurrentJobs,
+			Message: fmt.Sprintf("%s has %d active job(s), the limit is %d", addr, usage.ActiveJobs, limit.MaxConcurrentJobs),
+			Usage:   usage,
+		}
+	}
+	if limit.MaxDailySpend > 0 && usage.DailySpend+cost > limit.MaxDailySpend {
+		e := &AccountLimitError{
+			Limit: limitDailySpend,
+			Message: fmt.Sprintf("%s spent %.6f %s in the last 24 hours, %.6f more exceeds the limit of %.6f",
+				addr, usage.DailySpend, usage.Currency, cost, limit.MaxDailySpend),
+			Usage: usage,
+		}
+		// The job fits once enough booked payments leave the window
+		spend := usage.DailySpend
+		for _, entry := range entries {
+			spend -= netSpend(entry)
+			if spend+cost <= limit.MaxDailySpend {
+				retryAfter := entry.PaidAt.Add(spendWindow)
+				e.RetryAfter = &retryAfter
+				break
+			}
+		}
+		return e
+	}
+	return nil
+}
+
+// admit checks that n more jobs fit the limits of addr and runs submit while
+// no other submission is checked, so parallel requests cannot pass the limits
+// together. cost, the total of the jobs, is only asked for with a spend limit.
+func (rps *RealPaymentService) admit(addr string, n int, cost func() (float64, error), submit func() error) error {
+	rps.limitsMu.Lock()
+	defer rps.limitsMu.Unlock()
+
+	var total float64
+	if rps.limits.For(addr).MaxDailySpend > 0 {
+		var err error
+		if total, err = cost(); err != nil {
+			return err
+		}
+	}
+	if err := rps.checkLimits(addr, n, total, time.Now()); err != nil {
+		return err
+	}
+	if submit == nil {
+		return nil
+	}
+	return submit()
+}
+
+// handleGetAccountLimits returns the limits of a client address and how much
+// of them is used
+func (rps *RealPaymentService) handleGetAccountLimits(w http.ResponseWriter, r *http.Request) {
+	addr := mux.Vars(r)["addr"]
+	if _, err := sdk.AccAddressFromBech32(addr); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid address: %v", err), http.StatusBadRequest)
+		return
+	}
+	usage, _ := rps.accountUsage(addr, time.Now())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usage)
+}
+
+// asAccountLimitError reports whether err rejects a submission by the limits
+func asAccountLimitError(err error) (*AccountLimitError, bool) {
+	var limitErr *AccountLimitError
+	ok := errors.As(err, &limitErr)
+	return limitErr, ok
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/spf13/cobra"
+
+	blockchain "github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/notify"
+	"github.com/oxygene76/medasdigital-client/pkg/training"
+)
+
+// aiImportModelCmd registers weights produced by an external training worker
+var aiImportModelCmd = &cobra.Command{
+	Use:   "import-model [weights-file]",
+	Short: "Import trained model weights and anchor their hash on-chain",
+	Long: `Import model weights produced by an external worker from an exported training job.
+
+The weights are hashed (SHA-256), checked against --sha256 or a "<weights>.sha256"
+sidecar file if present, copied to ~/.medasdigital-client/models/ and the hash is
+anchored on-chain with a MEDAS_MODEL_REG memo transaction.
+
+Example:
+  medasdigital-client ai import-model ./out/yolo_tno.onnx \
+    --job-spec ./jobs/train-yolo_tno-1700000000.json \
+    --from mykey`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		weightsPath := args[0]
+
+		specPath, _ := cmd.Flags().GetString("job-spec")
+		name, _ := cmd.Flags().GetString("name")
+		expectedHash, _ := cmd.Flags().GetString("sha256")
+		from, _ := cmd.Flags().GetString("from")
+		keyringBackend := keyringBackendFlag(cmd)
+		offline, _ := cmd.Flags().GetBool("offline")
+
+		var spec *training.JobSpec
+		if specPath != "" {
+			var err error
+			spec, err = training.LoadJobSpec(specPath)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("📋 Training job: %s (%s)\n", spec.JobID, spec.Architecture.Name)
+		}
+
+		if expectedHash == "" {
+			if sidecar, ok := training.ReadExpectedHash(weightsPath); ok {
+				expectedHash = sidecar
+				fmt.Println("🔍 Using hash from .sha256 sidecar file")
+			}
+		}
+
+		registry := training.NewModelRegistry(filepath.Join(homeDir, "models"))
+		record, err := registry.Import(weightsPath, name, expectedHash, spec)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("✅ Model imported: %s\n", record.Name)
+		fmt.Printf("🔑 SHA-256: %s\n", record.SHA256)
+		if expectedHash != "" {
+			fmt.Println("✅ Hash matches worker output")
+		}
+		fmt.Printf("💾 Stored at: %s\n", record.Path)
+
+		if offline {
+			fmt.Println("💡 Skipping on-chain registration (--offline)")
+			return nil
+		}
+		if from == "" {
+			return fmt.Errorf("--from is required for on-chain registration (or use --offline)")
+		}
+
+		clientCtx, err := signingClientContext(cmd.Context(), from, keyringBackend)
+		if err != nil {
+			return err
+		}
+
+		result, err := blockchain.RegisterModelHash(clientCtx, clientCtx.GetFromAddress().String(), record.SHA256, record.JobID)
+		if err != nil {
+			return fmt.Errorf("on-chain registration failed: %w", err)
+		}
+
+		record.TxHash = result.TxHash
+		record.BlockHeight = result.Height
+		if err := registry.Save(record); err != nil {
+			return fmt.Errorf("failed to update model index: %w", err)
+		}
+		fmt.Printf("📊 Transaction Hash: %s\n", result.TxHash)
+
+		// Broadcast is sync, wait for inclusion before verifying the anchor
+		ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Second)
+		defer cancel()
+		for {
+			err = blockchain.VerifyModelHash(ctx, clientCtx, result.TxHash, record.SHA256)
+			if err == nil || ctx.Err() != nil {
+				break
+			}
+			time.Sleep(2 * time.Second)
+		}
+		if err != nil {
+			fmt.Printf("⚠️  Could not verify on-chain anchor yet: %v\n", err)
+			fmt.Printf("💡 Check later with: medasdigital-client ai verify-model %s %s\n", record.Path, result.TxHash)
+			return nil
+		}
+
+		fmt.Println("✅ Model hash verified on-chain")
+		return nil
+	},
+}
+
+// aiVerifyModelCmd checks local weights against an on-chain anchor
+var aiVerifyModelCmd = &cobra.Command{
+	Use:   "verify-model [weights-file] [tx-hash]",
+	Short: "Verify model weights against their on-chain hash registration",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sum, err := training.HashFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to hash model: %w", err)
+		}
+
+		cfg := loadConfig()
+		rpcClient, err := client.NewClientFromNode(cfg.Chain.RPCEndpoint)
+		if err != nil {
+			return fmt.Errorf("failed to create RPC client: %w", err)
+		}
+		clientCtx := client.Context{}.
+			WithClient(rpcClient).
+			WithChainID(cfg.Chain.ID).
+			WithCodec(currentApp().Codec()).
+			WithInterfaceRegistry(currentApp().InterfaceRegistry())
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), 15*time.Second)
+		defer cancel()
+
+		fmt.Printf("🔑 SHA-256: %s\n", sum)
+		if err := blockchain.VerifyModelHash(ctx, clientCtx, args[1], sum); err != nil {
+			return err
+		}
+		fmt.Println("✅ Model hash matches on-chain registration")
+		return nil
+	},
+}
+
+// exportTrainingJob writes a portable training job for an external PyTorch worker
+func exportTrainingJob(cmd *cobra.Command, trainingData, architecture, outDir string) error {
+	gpuDevices, _ := cmd.Flags().GetIntSlice("gpu-devices")
+	batchSize, _ := cmd.Flags().GetInt("batch-size")
+	epochs, _ := cmd.Flags().GetInt("epochs")
+	learningRate, _ := cmd.Flags().GetFloat64("learning-rate")
+	optimizer, _ := cmd.Flags().GetString("optimizer")
+	inputSize, _ := cmd.Flags().GetInt("input-size")
+	channels, _ := cmd.Flags().GetInt("channels")
+	numClasses, _ := cmd.Flags().GetInt("num-classes")
+
+	fmt.Printf("📦 Exporting training job for architecture: %s\n", architecture)
+
+	started := time.Now()
+	spec, err := training.NewJobSpec(trainingData,
+		training.ArchitectureConfig{
+			Name:       architecture,
+			InputSize:  inputSize,
+			Channels:   channels,
+			NumClasses: numClasses,
+		},
+		training.Hyperparameters{
+			Epochs:       epochs,
+			BatchSize:    batchSize,
+			LearningRate: learningRate,
+			Optimizer:    optimizer,
+			GPUDevices:   gpuDevices,
+		})
+	if err != nil {
+		return err
+	}
+
+	specPath, archivePath, err := spec.WriteBundle(trainingData, outDir)
+	notifyDone(notify.KindTraining, fmt.Sprintf("Training export %s (%s)", spec.JobID, architecture),
+		"Bundle: "+archivePath, started, err)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🆔 Job ID: %s\n", spec.JobID)
+	fmt.Printf("📁 Files: %d (%.1f MB)\n", len(spec.Data.Files), float64(spec.Data.TotalBytes)/1024/1024)
+	fmt.Printf("📋 Job spec: %s\n", specPath)
+	fmt.Printf("🗜️  Bundle: %s\n", archivePath)
+	fmt.Println("\n💡 Run the bundle on a PyTorch worker, then import the result with:")
+	fmt.Printf("   medasdigital-client ai import-model <weights.onnx> --job-spec %s --from <key>\n", specPath)
+	return nil
+}
+
+// signingClientContext builds a client context that signs and broadcasts with
+// the given key, its calls are bounded by ctx
+func signingClientContext(ctx context.Context, from, keyringBackend string) (client.Context, error) {
+	clientCtx, err := initKeysClientContextWithBackend(keyringBackend)
+	if err != nil {
+		return client.Context{}, fmt.Errorf("failed to initialize client context: %w", err)
+	}
+
+	keyInfo, err := clientCtx.Keyring.Key(from)
+	if err != nil {
+		return client.Context{}, fmt.Errorf("failed to get key info for '%s': %w", from, err)
+	}
+	addr, err := keyInfo.GetAddress()
+	if err != nil {
+		return client.Context{}, fmt.Errorf("failed to get address from key: %w", err)
+	}
+
+	cfg := loadConfig()
+	rpcClient, err := client.NewClientFromNode(cfg.Chain.RPCEndpoint)
+	if err != nil {
+		return client.Context{}, fmt.Errorf("failed to create RPC client: %w", err)
+	}
+
+	granter, err := feeGranter()
+	if err != nil {
+		return client.Context{}, err
+	}
+
+	txConfig := currentApp().TxConfig()
+	return clientCtx.
+		WithFromName(from).
+		WithFromAddress(addr).
+		WithTxConfig(txConfig).
+		WithClient(rpcClient).
+		WithChainID(cfg.Chain.ID).
+		WithCodec(currentApp().Codec()).
+		WithInterfaceRegistry(currentApp().InterfaceRegistry()).
+		WithBroadcastMode(flags.BroadcastSync).
+		WithFeeGranterAddress(granter).
+		WithSimulation(dryRun).
+		WithCmdContext(ctx), nil
+}
+
+func init() {
+	aiCmd.AddCommand(aiImportModelCmd)
+	aiCmd.AddCommand(aiVerifyModelCmd)
+
+	aiImportModelCmd.Flags().String("job-spec", "", "Job spec JSON written by 'ai train --export-job'")
+	aiImportModelCmd.Flags().String("name", "", "Model name (default: file name)")
+	aiImportModelCmd.Flags().String("sha256", "", "Expected SHA-256 reported by the worker")
+	aiImportModelCmd.Flags().String("from", "", "Key name to sign the registration transaction")
+	aiImportModelCmd.Flags().String("keyring-backend", "", "Keyring backend (test|file|os, default client.keyring_backend)")
+	aiImportModelCmd.Flags().Bool("offline", false, "Import locally without on-chain registration")
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+	"github.com/oxygene76/medasdigital-client/pkg/openapi"
+	"github.com/oxygene76/medasdigital-client/pkg/ratelimit"
+)
+
+// OpenAPI documents of the payment service and the free service, generated
+// from the request and response types of the handlers. Keep the operations in
+// step with the routes in Router.
+
+// newAPIDocument starts the document of an API version, fail describes its
+// error responses
+func newAPIDocument(title, description string, apiVersion int) (doc *openapi.Document, fail func(string) *openapi.Response) {
+	errorFormat := "Errors are answered as plain text unless noted."
+	if apiVersion >= apiV2 {
+		errorFormat = "Errors are answered as JSON with a code in error and a message."
+	}
+	doc = openapi.New(title, version, description+" "+errorFormat)
+	doc.Servers = []openapi.Server{{URL: fmt.Sprintf("/api/v%d", apiVersion)}}
+	fail = openapi.Text
+	if apiVersion >= apiV2 {
+		fail = func(description string) *openapi.Response {
+			return doc.JSON(description, apiError{})
+		}
+	}
+	return doc, fail
+}
+
+// apiDocument describes the routes of Router in an API version
+func (rps *RealPaymentService) apiDocument(apiVersion int) *openapi.Document {
+	doc, fail := newAPIDocument("MEDAS Payment Service API",
+		"Paid PI computations: pricing, job submission against an on-chain payment, invoices, "+
+			"per-client accounting and service status.", apiVersion)
+	admin := doc.BearerAuth("adminToken", "The --admin-token of the service")
+	addr := openapi.Parameter{Name: "addr", In: "path", Required: true, Description: "Client address", Schema: &openapi.Schema{Type: "string"}}
+	badRequest := fail("Invalid request")
+	notFound := fail("Not found")
+	limited := doc.JSON("An account limit is exceeded, Retry-After tells when to retry", accountLimitResponse{})
+
+	doc.Add("GET", "/pricing", &openapi.Operation{
+		Summary:   "Pricing, accepted denoms and payment addresses",
+		Tags:      []string{"pricing"},
+		Responses: map[string]*openapi.Response{"200": doc.JSON("Pricing", pricingResponse{})},
+	})
+	doc.Add("POST", "/pricing/estimate", &openapi.Operation{
+		Summary:     "Estimate the price of a PI calculation",
+		Tags:        []string{"pricing"},
+		RequestBody: doc.Body(priceEstimateRequest{}),
+		Responses:   map[string]*openapi.Response{"200": doc.JSON("Price and payment memo", priceEstimateResponse{}), "400": badRequest},
+	})
+	doc.Add("POST", "/pricing/compare", &openapi.Operation{
+		Summary:     "Compare the price of all service tiers",
+		Tags:        []string{"pricing"},
+		RequestBody: doc.Body(tierCompareRequest{}),
+		Responses:   map[string]*openapi.Response{"200": doc.JSON("Price per tier", tierCompareResponse{}), "400": badRequest},
+	})
+
+	doc.Add("POST", "/jobs/submit", &openapi.Operation{
+		Summary:     "Submit a job paid by a transaction or a subscription",
+		Description: "The job starts once the payment is verified on chain. Retries with the same Idempotency-Key get the response of the first request.",
+		Tags:        []string{"jobs"},
+		Parameters:  []openapi.Parameter{openapi.Header(IdempotencyKeyHeader, "Key that makes retries safe, same as client_job_id")},
+		RequestBody: doc.Body(submitJobRequest{}),
+		Responses: map[string]*openapi.Response{
+			"200": doc.JSON("Job accepted, payment verification pending", submitJobResponse{}),
+			"400": badRequest,
+			"409": fail("The payment was already used, or the idempotency key is in use"),
+			"429": limited,
+		},
+	})
+	doc.Add("GET", "/jobs", &openapi.Operation{
+		Summary: "List jobs",
+		Tags:    []string{"jobs"},
+		Parameters: []openapi.Parameter{
+			openapi.Query("client_address", "string", "Only jobs of this client"),
+			openapi.Query("status", "string", "Only jobs in this status"),
+			openapi.Query("limit", "integer", "Echoed in filters, default 50"),
+		},
+		Responses: map[string]*openapi.Response{"200": doc.JSON("Jobs", jobListResponse{})},
+	})
+	doc.Add("GET", "/jobs/{id}", &openapi.Operation{
+		Summary:   "Job details and result",
+		Tags:      []string{"jobs"},
+		Responses: map[string]*openapi.Response{"200": doc.JSON("Job", compute.ComputeJob{}), "404": notFound},
+	})
+	doc.Add("POST", "/jobs/{id}/cancel", &openapi.Operation{
+		Summary:   "Cancel a job",
+		Tags:      []string{"jobs"},
+		Responses: map[string]*openapi.Response{"200": doc.JSON("Cancelled", cancelJobResponse{}), "400": fail("The job cannot be cancelled")},
+	})
+	doc.Add("GET", "/jobs/{id}/stream", &openapi.Operation{
+		Summary:     "Digits of a PI job in verified blocks",
+		Description: "Newline delimited JSON, one event per line, until the job finishes.",
+		Tags:        []string{"jobs"},
+		Parameters:  []openapi.Parameter{openapi.Query("from", "integer", "First block index to send")},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Stream of events", Content: map[string]openapi.MediaType{"application/x-ndjson": {Schema: doc.Schema(compute.StreamEvent{})}}},
+			"400": badRequest,
+			"404": notFound,
+		},
+	})
+	doc.Add("GET", "/jobs/{id}/artifact", &openapi.Operation{
+		Summary:     "Result file of a completed job",
+		Description: "Supports range requests; HEAD returns the headers only.",
+		Tags:        []string{"jobs"},
+		Parameters:  []openapi.Parameter{openapi.Header("Range", "Byte range to fetch")},
+		Responses: map[string]*openapi.Response{
+			"200": openapi.Raw("Artifact", "application/octet-stream"),
+			"206": openapi.Raw("Requested range", "application/octet-stream"),
+			"304": {Description: "The If-None-Match ETag is current"},
+			"404": notFound,
+			"409": fail("Payment or job not finished yet"),
+		},
+	})
+
+	doc.Add("POST", "/payment/verify", &openapi.Operation{
+		Summary:     "Check a payment transaction",
+		Tags:        []string{"payments"},
+		RequestBody: doc.Body(verifyPaymentRequest{}),
+		Responses: map[string]*openapi.Response{
+			"200": doc.JSON("Verification result", verifyPaymentResponse{}),
+			"400": badRequest,
+			"500": fail("The transaction could not be checked"),
+		},
+	})
+	lookupNotFound := fail("No job for the payment yet")
+	if apiVersion < apiV2 {
+		lookupNotFound = doc.JSON("No job for the payment yet", paymentLookupError{})
+	}
+	doc.Add("GET", "/payments/{tx_hash}", &openapi.Operation{
+		Summary: "Job created for a payment",
+		Tags:    []string{"payments"},
+		Responses: map[string]*openapi.Response{
+			"200": doc.JSON("Job", compute.ComputeJob{}),
+			"404": lookupNotFound,
+		},
+	})
+	doc.Add("POST", "/invoices", &openapi.Operation{
+		Summary:     "Quote a job, returns the amount, memo and expiry to pay with",
+		Tags:        []string{"payments"},
+		RequestBody: doc.Body(createInvoiceRequest{}),
+		Responses: map[string]*openapi.Response{
+			"201": doc.JSON("Invoice", createInvoiceResponse{}),
+			"400": badRequest,
+			"429": limited,
+			"503": fail("The service runs without --watch-payments"),
+		},
+	})
+	doc.Add("GET", "/invoices/{id}", &openapi.Operation{
+		Summary:   "Invoice status",
+		Tags:      []string{"payments"},
+		Responses: map[string]*openapi.Response{"200": doc.JSON("Invoice", Invoice{}), "404": notFound},
+	})
+
+	doc.Add("GET", "/accounts/{addr}", &openapi.Operation{
+		Summary:    "Totals paid, jobs and compute time of a client",
+		Tags:       []string{"accounts"},
+		Parameters: []openapi.Parameter{addr},
+		Responses:  map[string]*openapi.Response{"200": doc.JSON("Account totals", AccountSummary{})},
+	})
+	doc.Add("GET", "/accounts/{addr}/statement", &openapi.Operation{
+		Summary: "Monthly statement of a client",
+		Tags:    []string{"accounts"},
+		Parameters: []openapi.Parameter{addr,
+			openapi.Query("month", "string", "YYYY-MM, default the current month"),
+			openapi.Query("format", "string", "csv for a CSV download"),
+		},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Statement", Content: map[string]openapi.MediaType{
+				openapi.MediaJSON: {Schema: doc.Schema(statementResponse{})},
+				"text/csv":        {Schema: &openapi.Schema{Type: "string"}},
+			}},
+			"400": badRequest,
+		},
+	})
+	doc.Add("GET", "/accounts/{addr}/limits", &openapi.Operation{
+		Summary:    "Concurrency and daily spend limits and their use",
+		Tags:       []string{"accounts"},
+		Parameters: []openapi.Parameter{addr},
+		Responses:  map[string]*openapi.Response{"200": doc.JSON("Limits and usage", AccountUsage{}), "400": badRequest},
+	})
+	doc.Add("GET", "/subscriptions/{addr}", &openapi.Operation{
+		Summary:    "Prepaid monthly quota, renewals and overage policy",
+		Tags:       []string{"accounts"},
+		Parameters: []openapi.Parameter{addr},
+		Responses:  map[string]*openapi.Response{"200": doc.JSON("Subscription", SubscriptionStatus{}), "400": badRequest},
+	})
+
+	doc.Add("GET", "/admin/refunds", &openapi.Operation{
+		Summary:    "Refund queue",
+		Tags:       []string{"admin"},
+		Security:   admin,
+		Parameters: []openapi.Parameter{openapi.Query("status", "string", "Only refunds in this status")},
+		Responses: map[string]*openapi.Response{
+			"200": doc.JSON("Refunds, newest first", refundListResponse{}),
+			"401": fail("Wrong token"),
+			"403": fail("The admin API is disabled"),
+		},
+	})
+	doc.Add("POST", "/admin/refunds/{id}/retry", &openapi.Operation{
+		Summary:  "Queue a failed refund again",
+		Tags:     []string{"admin"},
+		Security: admin,
+		Responses: map[string]*openapi.Response{
+			"200": doc.JSON("Refund", Refund{}),
+			"400": badRequest,
+			"401": fail("Wrong token"),
+			"403": fail("The admin API is disabled"),
+		},
+	})
+
+	doc.Add("GET", "/status", &openapi.Operation{
+		Summary:   "Service status",
+		Tags:      []string{"service"},
+		Responses: map[string]*openapi.Response{"200": doc.JSON("Status", serviceStatusResponse{})},
+	})
+	doc.Add("GET", "/statistics", &openapi.Operation{
+		Summary:   "Job statistics",
+		Tags:      []string{"service"},
+		Responses: map[string]*openapi.Response{"200": doc.JSON("Statistics", compute.JobStatistics{})},
+	})
+	doc.Add("GET", "/queue", &openapi.Operation{
+		Summary:   "Queue status",
+		Tags:      []string{"service"},
+		Responses: map[string]*openapi.Response{"200": doc.JSON("Queue", compute.QueueStatus{})},
+	})
+	doc.Add("GET", "/cache", &openapi.Operation{
+		Summary:   "Result cache statistics",
+		Tags:      []string{"service"},
+		Responses: map[string]*openapi.Response{"200": doc.JSON("Cache statistics", compute.CacheStats{}), "404": fail("The result cache is disabled")},
+	})
+	doc.Add("GET", "/community/stats", &openapi.Operation{
+		Summary:    "Community pool stats and distribution history",
+		Tags:       []string{"service"},
+		Parameters: []openapi.Parameter{openapi.Query("pages", "integer", "Pages of on-chain transfers to reconcile, 1-20, default 4")},
+		Responses:  map[string]*openapi.Response{"200": doc.JSON("Community pool", communityStatsResponse{})},
+	})
+	doc.Add("GET", "/providers", &openapi.Operation{
+		Summary:    "Provider reputation from the compute contract history",
+		Tags:       []string{"providers"},
+		Parameters: []openapi.Parameter{openapi.Query("active", "boolean", "Only active providers")},
+		Responses: map[string]*openapi.Response{
+			"200": doc.JSON("Providers by rank", providerListResponse{}),
+			"502": fail("The contract history could not be read"),
+			"503": fail("No compute contract configured"),
+		},
+	})
+	doc.Add("GET", "/providers/{addr}", &openapi.Operation{
+		Summary:    "Reputation and rank of a provider",
+		Tags:       []string{"providers"},
+		Parameters: []openapi.Parameter{{Name: "addr", In: "path", Required: true, Description: "Provider address", Schema: &openapi.Schema{Type: "string"}}},
+		Responses: map[string]*openapi.Response{
+			"200": doc.JSON("Provider", providerResponse{}),
+			"404": notFound,
+			"502": fail("The contract history could not be read"),
+			"503": fail("No compute contract configured"),
+		},
+	})
+	return doc
+}
+
+// apiDocument describes the routes of Router in an API version
+func (sfts *SecureFreeTestService) apiDocument(apiVersion int) *openapi.Document {
+	doc, fail := newAPIDocument("MEDAS Free PI Service API",
+		"Small PI calculations without payment, rate limited per IP.", apiVersion)
+
+	doc.Add("GET", "/status", &openapi.Operation{
+		Summary:   "Service status and limits",
+		Responses: map[string]*openapi.Response{"200": doc.JSON("Status", freeStatusResponse{})},
+	})
+	calculate := &openapi.Operation{
+		Summary:     "Calculate PI",
+		RequestBody: doc.Body(calculateRequest{}),
+		Responses: map[string]*openapi.Response{
+			"200": doc.JSON("Result", calculateResponse{}),
+			"400": fail("Invalid request or digits above the limit"),
+			"408": fail("The calculation timed out"),
+			"429": fail("Rate limit exceeded"),
+			"500": fail("The calculation failed"),
+		},
+	}
+	switch {
+	case sfts.pow != nil:
+		calculate.Parameters = append(calculate.Parameters, openapi.Parameter{
+			Name: ratelimit.PoWHeader, In: "header", Required: true,
+			Description: "Solution of a challenge from GET /challenge", Schema: &openapi.Schema{Type: "string"},
+		})
+		calculate.Responses["403"] = fail("The solution is invalid")
+		calculate.Responses["428"] = fail("No solution sent")
+	case sfts.deposits != nil:
+		calculate.Parameters = append(calculate.Parameters, openapi.Parameter{
+			Name: depositHeader, In: "header", Required: true,
+			Description: "Hash of the deposit transaction that pays for calculations", Schema: &openapi.Schema{Type: "string"},
+		})
+		calculate.Responses["402"] = fail("No deposit sent, or its calculations are used up")
+		calculate.Responses["403"] = fail("The deposit is invalid")
+	}
+	doc.Add("POST", "/calculate", calculate)
+	doc.Add("GET", "/limits", &openapi.Operation{
+		Summary:   "Limits and the methods within them",
+		Responses: map[string]*openapi.Response{"200": doc.JSON("Limits", freeLimitsResponse{})},
+	})
+	if sfts.pow != nil {
+		doc.Add("GET", "/challenge", &openapi.Operation{
+			Summary:   "Proof of work challenge",
+			Responses: map[string]*openapi.Response{"200": doc.JSON("Challenge", ratelimit.Challenge{})},
+		})
+	}
+	return doc
+}
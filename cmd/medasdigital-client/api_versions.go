@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Versions of the REST API of serve and payment-service. Both versions run
+// the same handlers with the same response types: within a version fields
+// are only added, never renamed, retyped or removed. Fields to be dropped
+// are tagged deprecated, which the OpenAPI documents show, and stay until
+// the next version.
+//
+// v1 answers errors as plain text. v2 answers them as JSON apiError.
+const (
+	apiV1 = 1
+	apiV2 = 2
+
+	latestAPIVersion = apiV2
+)
+
+// APIVersionHeader tells which API version answered
+const APIVersionHeader = "API-Version"
+
+// apiError is the body of error responses of /api/v2. Error is a code like
+// not_found, the limit error of the accounts adds its fields to it.
+type apiError struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+type apiVersionKey struct{}
+
+// apiVersionOf returns the API version a request was routed to
+func apiVersionOf(r *http.Request) int {
+	if v, ok := r.Context().Value(apiVersionKey{}).(int); ok {
+		return v
+	}
+	return apiV1
+}
+
+// mountAPI registers the routes of every API version under /api/v<N> of r
+func mountAPI(r *mux.Router, routes func(api *mux.Router, version int)) {
+	for v := apiV1; v <= latestAPIVersion; v++ {
+		api := r.PathPrefix(fmt.Sprintf("/api/v%d", v)).Subrouter()
+		api.Use(apiVersionMiddleware(v))
+		routes(api, v)
+	}
+}
+
+func apiVersionMiddleware(version int) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(APIVersionHeader, fmt.Sprint(version))
+			r = r.WithContext(context.WithValue(r.Context(), apiVersionKey{}, version))
+			if version < latestAPIVersion {
+				successor := fmt.Sprintf("/api/v%d/", latestAPIVersion) + strings.TrimPrefix(r.URL.Path, fmt.Sprintf("/api/v%d/", version))
+				w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+				next.ServeHTTP(w, r)
+				return
+			}
+			jw := &jsonErrorWriter{ResponseWriter: w}
+			next.ServeHTTP(jw, r)
+			jw.finish()
+		})
+	}
+}
+
+// writeAPIError answers an error in the format of the request's API version
+func writeAPIError(w http.ResponseWriter, r *http.Request, message string, status int) {
+	if apiVersionOf(r) < apiV2 {
+		http.Error(w, message, status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiError{Error: errorCode(status), Message: message})
+}
+
+// errorCode turns a status into a code, 404 into not_found
+func errorCode(status int) string {
+	text := strings.ToLower(http.StatusText(status))
+	if text == "" {
+		return fmt.Sprintf("status_%d", status)
+	}
+	return strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text)
+}
+
+// jsonErrorWriter turns the plain text errors of http.Error into apiError
+type jsonErrorWriter struct {
+	http.ResponseWriter
+	status  int
+	message *bytes.Buffer // set while a plain text error is written
+}
+
+func (w *jsonErrorWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	if status >= http.StatusBadRequest && strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		w.message = &bytes.Buffer{}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Del("Content-Length")
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *jsonErrorWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.message != nil {
+		return w.message.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush keeps streamed responses streaming
+func (w *jsonErrorWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *jsonErrorWriter) finish() {
+	if w.message != nil {
+		json.NewEncoder(w.ResponseWriter).Encode(apiError{Error: errorCode(w.status), Message: strings.TrimSpace(w.message.String())})
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/contract"
+)
+
+// resultsVerifyCmd checks the signed receipt a provider issued for a contract job
+var resultsVerifyCmd = &cobra.Command{
+	Use:   "verify <job-id>",
+	Short: "Verify the provider's signed attestation of a contract job result",
+	Long: `Check the attestation a provider signed when it completed a contract job: the
+signature must come from the provider account that ran the job, and the
+attested result hash and parameters must match what the contract recorded.
+The result itself is downloaded and checked against the anchored hash.
+
+The attestation is read from the contract job or, for contracts that do not
+keep it, from the provider's result endpoint. --save stores it as a receipt,
+--attestation verifies a stored receipt.
+
+Examples:
+  medasdigital-client results verify 42
+  medasdigital-client results verify 42 --save receipt-42.json
+  medasdigital-client results verify 42 --attestation receipt-42.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jobID, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid job id %q: %w", args[0], err)
+		}
+		receiptFile, _ := cmd.Flags().GetString("attestation")
+		saveFile, _ := cmd.Flags().GetString("save")
+
+		cfg := loadConfig()
+		contractAddr, err := contractAddress(cmd)
+		if err != nil {
+			return err
+		}
+		client := contract.NewClient(contract.Config{
+			ContractAddress: contractAddr,
+			RPCEndpoint:     cfg.Chain.RPCEndpoint,
+			ChainID:         cfg.Chain.ID,
+			FeeGranter:      cfg.Client.FeeGranter,
+		}, "", "", cfg.Client.KeyringBackend)
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), 60*time.Second)
+		defer cancel()
+		job, err := client.GetJob(ctx, jobID)
+		if err != nil {
+			return fmt.Errorf("failed to query job %d: %w", jobID, err)
+		}
+		job.ID = jobID
+
+		var att *contract.Attestation
+		switch {
+		case receiptFile != "":
+			data, err := os.ReadFile(receiptFile)
+			if err != nil {
+				return fmt.Errorf("failed to read attestation: %w", err)
+			}
+			if err := json.Unmarshal(data, &att); err != nil {
+				return fmt.Errorf("invalid attestation %s: %w", receiptFile, err)
+			}
+		case job.Attestation != nil:
+			att = job.Attestation
+		case job.ResultURL != "":
+			if att, err = fetchAttestation(ctx, job.ResultURL); err != nil {
+				return err
+			}
+		}
+		if att == nil {
+			return fmt.Errorf("job %d has no attestation, its provider completed it without a signing key", jobID)
+		}
+
+		fmt.Printf("Job:          #%d (%s)\n", att.JobID, att.JobType)
+		fmt.Printf("Provider:     %s\n", att.Provider)
+		fmt.Printf("Result hash:  %s\n", att.ResultHash)
+		fmt.Printf("Params hash:  %s\n", att.ParamsHash)
+		fmt.Printf("Started:      %s\n", att.StartedAt.Format(time.RFC3339))
+		fmt.Printf("Completed:    %s (%v)\n", att.CompletedAt.Format(time.RFC3339), att.CompletedAt.Sub(att.StartedAt).Round(time.Second))
+		fmt.Println()
+
+		if err := att.Check(job); err != nil {
+			fmt.Println("❌ Attestation is not valid")
+			return err
+		}
+		fmt.Printf("✅ Signed by %s, matches the job on chain\n", att.Provider)
+
+		if job.ResultURL != "" {
+			if _, err := contract.FetchResult(ctx, job.ResultURL, att.ResultHash); err != nil {
+				fmt.Printf("⚠️  Result not checked: %v\n", err)
+			} else {
+				fmt.Println("✅ Published result matches the attested hash")
+			}
+		}
+
+		if saveFile != "" {
+			data, err := json.MarshalIndent(att, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(saveFile, data, 0644); err != nil {
+				return fmt.Errorf("failed to save attestation: %w", err)
+			}
+			fmt.Printf("Receipt saved to: %s\n", saveFile)
+		}
+		return nil
+	},
+}
+
+// fetchAttestation reads the attestation a provider serves with a result
+func fetchAttestation(ctx context.Context, url string) (*contract.Attestation, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch attestation: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch attestation: %s", resp.Status)
+	}
+	var body struct {
+		Attestation *contract.Attestation `json:"attestation"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid result response: %w", err)
+	}
+	return body.Attestation, nil
+}
+
+// attestationSigner signs provider attestations with the provider key from
+// the client keyring, the key must belong to the registered provider address
+func attestationSigner(keyName, keyringBackend, providerAddr string) (contract.SignFunc, error) {
+	clientCtx, err := initKeysClientContextWithBackend(keyringBackend)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo, err := clientCtx.Keyring.Key(keyName)
+	if err != nil {
+		return nil, fmt.Errorf("key %s not in the client keyring: %w", keyName, err)
+	}
+	addr, err := keyInfo.GetAddress()
+	if err != nil {
+		return nil, err
+	}
+	if addr.String() != providerAddr {
+		return nil, fmt.Errorf("key %s has address %s, not the provider address %s", keyName, addr, providerAddr)
+	}
+	return func(msg []byte) ([]byte, cryptotypes.PubKey, error) {
+		return clientCtx.Keyring.Sign(keyName, msg, signing.SignMode_SIGN_MODE_DIRECT)
+	}, nil
+}
+
+func init() {
+	resultsCmd.AddCommand(resultsVerifyCmd)
+
+	resultsVerifyCmd.Flags().String("contract", "", "Contract address (default: contract.address from the config)")
+	resultsVerifyCmd.Flags().String("attestation", "", "Verify a saved attestation instead of the one on chain")
+	resultsVerifyCmd.Flags().String("save", "", "Save the attestation as receipt file")
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/indexer"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+	"github.com/oxygene76/medasdigital-client/pkg/plot"
+)
+
+// balanceHistoryCmd reconstructs the balance of an address over time
+var balanceHistoryCmd = &cobra.Command{
+	Use:   "history [address]",
+	Short: "Balance over time from the local transaction index, as CSV or PNG chart",
+	Long: `Reconstruct the balance of an address per hour, day, week or month from the
+transfers in the local transaction index, e.g. to follow how a grant-funded
+account is spent on compute jobs. The index is synced first unless --offline
+is given; the reconstruction starts at zero, fees are included as sent.
+
+  csv  one row per period and denom: period_start, denom, balance, received,
+       sent, transfers (amounts in base units)
+  png  a line chart of the balance in one denom
+
+The format follows the output name unless --format is given; without -o CSV
+is written to stdout.
+
+Examples:
+  medasdigital-client balance history medas1... --granularity week
+  medasdigital-client balance history medas1... --from 2025-01-01 -o grant.png`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBalanceHistory,
+}
+
+func runBalanceHistory(cmd *cobra.Command, args []string) error {
+	address := args[0]
+	if _, err := sdk.AccAddressFromBech32(address); err != nil {
+		return fmt.Errorf("invalid address %s: %w", address, err)
+	}
+	granularity, _ := cmd.Flags().GetString("granularity")
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+	denom, _ := cmd.Flags().GetString("denom")
+	offline, _ := cmd.Flags().GetBool("offline")
+	from, to, err := indexPeriod(cmd)
+	if err != nil {
+		return err
+	}
+
+	if format == "" {
+		format = "csv"
+		if strings.EqualFold(filepath.Ext(output), ".png") {
+			format = "png"
+		}
+	}
+	if format != "csv" && format != "png" {
+		return fmt.Errorf("unknown format %q (csv or png)", format)
+	}
+	if format == "png" && output == "" {
+		return fmt.Errorf("a PNG chart needs an output file, use -o")
+	}
+	if denom == "" {
+		denom = network.Current().BaseDenom
+	}
+
+	cfg := loadConfig()
+	ix, err := openIndex(cfg)
+	if err != nil {
+		return err
+	}
+	defer ix.Close()
+	if err := prepareIndex(cmd.Context(), ix, cfg, address, offline, true); err != nil {
+		return err
+	}
+
+	points, err := ix.BalanceHistory(address, granularity, from, to, time.Local)
+	if err != nil {
+		return err
+	}
+	if len(points) == 0 {
+		return fmt.Errorf("no indexed transfers for %s%s", address, periodLabel(from, to))
+	}
+	if !offline && to.IsZero() {
+		checkReconstructedBalance(cmd.Context(), cfg, address, points[len(points)-1].Balance)
+	}
+
+	if format == "png" {
+		if err := writeBalanceChart(output, address, denom, granularity, points); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "📈 Chart of %d %ss written to %s\n", len(points), granularity, output)
+		return nil
+	}
+
+	w := io.Writer(os.Stdout)
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := writeBalanceCSV(w, points); err != nil {
+		return err
+	}
+	if output != "" {
+		fmt.Fprintf(os.Stderr, "📄 %d %ss written to %s\n", len(points), granularity, output)
+	}
+	return nil
+}
+
+// checkReconstructedBalance warns when the index does not explain the balance on chain
+func checkReconstructedBalance(ctx context.Context, cfg *Config, address string, reconstructed sdk.Coins) {
+	bc, err := createFullBlockchainClient(client.Context{}, cfg)
+	if err != nil {
+		return
+	}
+	actual, err := bc.GetAccountBalance(ctx, address)
+	if err != nil {
+		return
+	}
+	if !actual.Equal(reconstructed) {
+		fmt.Fprintf(os.Stderr, "⚠️  Reconstructed balance %s differs from the balance on chain %s\n",
+			denoms().FormatCoins(reconstructed), denoms().FormatCoins(actual))
+		fmt.Fprintln(os.Stderr, "   Funds moved without a transfer event (e.g. genesis or staking) are not in the index.")
+	}
+}
+
+// historyDenoms lists every denom that appears in the history
+func historyDenoms(points []indexer.BalancePoint) []string {
+	seen := map[string]bool{}
+	for _, p := range points {
+		for _, coins := range []sdk.Coins{p.Balance, p.Received, p.Sent} {
+			for _, c := range coins {
+				seen[c.Denom] = true
+			}
+		}
+	}
+	return sortedDenoms(seen)
+}
+
+func writeBalanceCSV(w io.Writer, points []indexer.BalancePoint) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"period_start", "denom", "balance", "received", "sent", "transfers"})
+	denoms := historyDenoms(points)
+	for _, p := range points {
+		start := p.Start.Format(time.RFC3339)
+		if len(denoms) == 0 {
+			cw.Write([]string{start, "", "0", "0", "0", strconv.Itoa(p.Transfers)})
+		}
+		for _, d := range denoms {
+			cw.Write([]string{
+				start, d,
+				p.Balance.AmountOf(d).String(),
+				p.Received.AmountOf(d).String(),
+				p.Sent.AmountOf(d).String(),
+				strconv.Itoa(p.Transfers),
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// chartWidth is the width of the balance chart in pixels
+const chartWidth = 1000
+
+// writeBalanceChart draws the balance in denom as a line chart
+func writeBalanceChart(path, address, denom, granularity string, points []indexer.BalancePoint) error {
+	net := network.Current()
+	unit := denom
+	value := func(c sdk.Coins) float64 {
+		f, _ := c.AmountOf(denom).ToLegacyDec().Float64()
+		return f
+	}
+	if denom == net.BaseDenom && net.DisplayDenom != "" {
+		unit = net.DisplayDenom
+		value = func(c sdk.Coins) float64 {
+			return net.ToDisplay(c.AmountOf(denom).Int64())
+		}
+	}
+
+	labels := make([]string, len(points))
+	values := make([]float64, len(points))
+	for i, p := range points {
+		labels[i] = p.Start.Format(periodFormat(granularity))
+		values[i] = value(p.Balance)
+	}
+	fig := plot.Line(plot.Chart{
+		Title:  fmt.Sprintf("Balance of %s in %s per %s", address, unit, granularity),
+		YLabel: unit,
+		Width:  chartWidth,
+	}, labels, values)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := fig.WritePNG(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func periodFormat(granularity string) string {
+	switch granularity {
+	case indexer.GranularityHour:
+		return "01-02 15:04"
+	case indexer.GranularityMonth:
+		return "2006-01"
+	}
+	return indexDate
+}
+
+func init() {
+	balanceCmd.AddCommand(balanceHistoryCmd)
+
+	balanceHistoryCmd.Flags().String("granularity", indexer.GranularityDay, "Period of one point: hour, day, week or month")
+	balanceHistoryCmd.Flags().String("format", "", "Output format (csv, png), default from the output name")
+	balanceHistoryCmd.Flags().StringP("output", "o", "", "Output file (default: CSV to stdout)")
+	balanceHistoryCmd.Flags().String("denom", "", "Denom of the chart (default: the network's base denom)")
+	balanceHistoryCmd.Flags().String("from", "", "First day, YYYY-MM-DD")
+	balanceHistoryCmd.Flags().String("to", "", "Day after the last one, YYYY-MM-DD")
+	balanceHistoryCmd.Flags().Bool("offline", false, "Use the index as is, without syncing")
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+)
+
+// performanceProfilePath is where the calibrated profile of this host is kept
+func performanceProfilePath() string {
+	return filepath.Join(homeDir, "profile", "performance.json")
+}
+
+// loadPerformanceProfile returns the saved profile, nil if the host was never
+// calibrated
+func loadPerformanceProfile() *compute.PerformanceProfile {
+	profile, err := compute.LoadPerformanceProfile(performanceProfilePath())
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("⚠️ Ignoring performance profile: %v", err)
+		}
+		return nil
+	}
+	return profile
+}
+
+// calibrate benchmarks the host and saves the profile
+func calibrate(ctx context.Context) (*compute.PerformanceProfile, error) {
+	fmt.Println("📐 Calibrating, this takes about ten seconds...")
+	profile, err := compute.Calibrate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("calibration failed: %w", err)
+	}
+	if err := profile.Save(performanceProfilePath()); err != nil {
+		return nil, fmt.Errorf("failed to save performance profile: %w", err)
+	}
+	return profile, nil
+}
+
+// paymentServiceCalibrateCmd measures the host for duration estimates and prices
+var paymentServiceCalibrateCmd = &cobra.Command{
+	Use:   "calibrate",
+	Short: "Benchmark this host for realistic duration estimates and prices",
+	Long: `Time the PI job of every method, run the reference PI benchmark and measure
+matrix multiplication GFLOPS. The profile is saved to
+~/.medasdigital-client/profile/performance.json and used by payment-service for
+the estimated time of every job, the method multipliers of its prices and to
+reject jobs that would exceed the runtime limit of their tier.
+
+Recalibrate after hardware changes, or start the service with --calibrate.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		profile, err := calibrate(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(profile)
+		}
+
+		pricing := compute.NewPricingManager("")
+		pricing.SetPerformanceProfile(profile)
+
+		fmt.Printf("🖥️  %s (%s, %d CPUs)\n", profile.Host, profile.Arch, profile.CPUs)
+		methods := make([]string, 0, len(profile.Methods))
+		for method := range profile.Methods {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			t := profile.Methods[method]
+			d, _ := profile.EstimateDuration(10000, method)
+			multiplier := pricing.GetPricingInfo().MethodMultipliers[method]
+			fmt.Printf("   %-11s %8.0f digits/s, 10,000 digits in %v, price ×%.2f\n", method, t.DigitsPerSecond, d.Round(time.Millisecond), multiplier)
+		}
+		fmt.Printf("   Benchmark   %8.0f digits/s (reference PI, big integers)\n", profile.Benchmark.Score)
+		fmt.Printf("   Matrix      %8.2f GFLOPS\n", profile.MatrixGFLOPS)
+		fmt.Printf("💾 Saved to %s\n", performanceProfilePath())
+		return nil
+	},
+}
+
+func init() {
+	realPaymentServiceCmd.AddCommand(paymentServiceCalibrateCmd)
+	paymentServiceCalibrateCmd.Flags().Bool("json", false, "Print the profile as JSON")
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	comethttp "github.com/cometbft/cometbft/rpc/client/http"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/chat"
+	"github.com/oxygene76/medasdigital-client/pkg/peers"
+	"github.com/oxygene76/medasdigital-client/pkg/votable"
+)
+
+// chatCmd groups the encrypted messaging commands
+var chatCmd = &cobra.Command{
+	Use:   "chat",
+	Short: "Encrypted messages between registered chat clients",
+	Long: `Send end-to-end encrypted messages to clients registered with 'register chat'.
+
+Messages are encrypted to the X25519 chat key of the recipient's registration.
+They are delivered to a chat endpoint of the recipient if it registered one
+('chat serve'), otherwise in the memo of a minimal transaction, which limits
+them to ` + fmt.Sprint(chat.MaxMemoMessage) + ` bytes.`,
+}
+
+// chatKeyCmd prints the chat key of an account
+var chatKeyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "Show the chat public key of an account, creating it if needed",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from")
+		clientCtx, err := initKeysClientContextWithBackend(keyringBackendFlag(cmd))
+		if err != nil {
+			return err
+		}
+		address, err := keyAddress(clientCtx.Keyring, from)
+		if err != nil {
+			return err
+		}
+
+		key, created, err := chat.LoadOrCreateKey(homeDir, address)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("📍 Address:  %s\n", address)
+		fmt.Printf("🔑 Chat key: %s\n", base64.StdEncoding.EncodeToString(key.PublicKey().Bytes()))
+		if created {
+			fmt.Println("\n💡 New key, publish it with: ./bin/medasdigital-client register chat --from", from, "--display-name <name>")
+		}
+		return nil
+	},
+}
+
+// chatSendCmd encrypts and delivers a message
+var chatSendCmd = &cobra.Command{
+	Use:   "send <address|client-id|name> <message>",
+	Short: "Send an encrypted message",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from")
+		via, _ := cmd.Flags().GetString("via")
+		offline, _ := cmd.Flags().GetBool("offline")
+		body := strings.Join(args[1:], " ")
+
+		if via != "auto" && via != chat.TransportChain && via != chat.TransportEndpoint {
+			return fmt.Errorf("invalid --via %q (auto, chain or endpoint)", via)
+		}
+
+		cfg := loadConfig()
+		idx, err := peers.LoadIndex(homeDir, cfg.Chain.ID)
+		if err != nil {
+			return err
+		}
+		if !offline {
+			if err := syncPeers(cmd.Context(), idx, cfg.Chain.RPCEndpoint, peers.SyncOptions{}, false); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Peer sync failed, using index up to height %d: %v\n", idx.LastHeight, err)
+			}
+		}
+		peer, err := findChatPeer(idx, args[0])
+		if err != nil {
+			return err
+		}
+		recipientKey, err := chat.ParsePublicKey(peer.ChatKey)
+		if err != nil {
+			return fmt.Errorf("invalid chat key of %s: %w", peer.Address, err)
+		}
+
+		clientCtx, err := signingClientContext(cmd.Context(), from, keyringBackendFlag(cmd))
+		if err != nil {
+			return err
+		}
+		sender := clientCtx.GetFromAddress().String()
+		sealed, err := chat.Seal(recipientKey, sender, peer.Address, []byte(body))
+		if err != nil {
+			return fmt.Errorf("failed to encrypt message: %w", err)
+		}
+
+		mb, err := chat.OpenMailbox(homeDir, sender)
+		if err != nil {
+			return err
+		}
+		msg := &chat.Message{Direction: chat.Outgoing, Peer: peer.Address, Body: body, Time: time.Now(), Read: true}
+
+		// Endpoints take any length and cost no fee
+		delivered := false
+		if via != chat.TransportChain && len(peer.Endpoints) > 0 {
+			env := &chat.Envelope{From: sender, To: peer.Address, SentAt: msg.Time, Sealed: *sealed}
+			sign := func(b []byte) ([]byte, cryptotypes.PubKey, error) {
+				return clientCtx.Keyring.Sign(from, b, signing.SignMode_SIGN_MODE_DIRECT)
+			}
+			if err := env.Sign(sign); err != nil {
+				return err
+			}
+			for _, endpoint := range peer.Endpoints {
+				ctx, cancel := context.WithTimeout(cmd.Context(), 15*time.Second)
+				err := chat.Deliver(ctx, endpoint, env)
+				cancel()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+					continue
+				}
+				id := sha256.Sum256(env.Signature)
+				msg.ID = hex.EncodeToString(id[:])
+				msg.Transport = chat.TransportEndpoint
+				delivered = true
+				fmt.Printf("📨 Delivered to %s\n", endpoint)
+				break
+			}
+		}
+
+		if !delivered {
+			if via == chat.TransportEndpoint {
+				return fmt.Errorf("no chat endpoint of %s accepted the message", peer.Address)
+			}
+			memo, err := sealed.Memo()
+			if err != nil {
+				return err
+			}
+			result, err := blockchain.SendMemo(clientCtx, sender, peer.Address, memo)
+			if err != nil {
+				return err
+			}
+			msg.ID = result.TxHash
+			msg.TxHash = result.TxHash
+			msg.Transport = chat.TransportChain
+			fmt.Printf("📨 Sent on chain: %s\n", result.TxHash)
+		}
+
+		mb.Add(msg)
+		return mb.Save()
+	},
+}
+
+// chatInboxCmd fetches and shows messages
+var chatInboxCmd = &cobra.Command{
+	Use:   "inbox",
+	Short: "Show received messages",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from")
+		offline, _ := cmd.Flags().GetBool("offline")
+		unread, _ := cmd.Flags().GetBool("unread")
+		with, _ := cmd.Flags().GetString("with")
+		showSent, _ := cmd.Flags().GetBool("sent")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		clientCtx, err := initKeysClientContextWithBackend(keyringBackendFlag(cmd))
+		if err != nil {
+			return err
+		}
+		address, err := keyAddress(clientCtx.Keyring, from)
+		if err != nil {
+			return err
+		}
+		key, err := chat.LoadKey(homeDir, address)
+		if err != nil {
+			return err
+		}
+		mb, err := chat.OpenMailbox(homeDir, address)
+		if err != nil {
+			return err
+		}
+
+		if !offline {
+			cfg := loadConfig()
+			rpcClient, err := comethttp.New(cfg.Chain.RPCEndpoint, "/websocket")
+			if err != nil {
+				return fmt.Errorf("failed to create RPC client: %w", err)
+			}
+			found, err := chat.SyncChain(cmd.Context(), rpcClient, currentApp().Codec(), key, mb)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Chain sync failed: %v\n", err)
+			} else if found > 0 && !asJSON {
+				fmt.Printf("📥 %d new message(s)\n", found)
+			}
+		}
+
+		direction := chat.Incoming
+		if showSent {
+			direction = ""
+		}
+		list := mb.Filter(direction, with, unread)
+		if asJSON {
+			data, err := json.MarshalIndent(list, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+		} else if len(list) == 0 {
+			fmt.Println("No messages")
+		} else {
+			for _, m := range list {
+				arrow, marker := "←", " "
+				if m.Direction == chat.Outgoing {
+					arrow = "→"
+				} else if !m.Read {
+					marker = "*"
+				}
+				fmt.Printf("%s %s %s %s [%s]\n  %s\n", marker, m.Time.Local().Format("2006-01-02 15:04"), arrow, m.Peer, m.Transport, m.Body)
+			}
+		}
+
+		mb.MarkRead(list)
+		return mb.Save()
+	},
+}
+
+// chatServeCmd runs the chat endpoint of an account
+var chatServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Accept messages on a chat endpoint",
+	Long: `Accept messages delivered to the chat endpoint registered with
+'register chat --chat-endpoint'. Senders are verified by the signature of
+their account key, messages are stored in the same mailbox as 'chat inbox'.
+Blobs of results shared with 'results share' are stored here as well.
+
+With --tap the server also answers TAP queries on /tap over the results in
+~/.medasdigital-client/results, so TOPCAT and Aladin can query them directly
+(see 'results export --format votable' for the table layout).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from")
+		listen, _ := cmd.Flags().GetString("listen")
+		tap, _ := cmd.Flags().GetBool("tap")
+
+		clientCtx, err := initKeysClientContextWithBackend(keyringBackendFlag(cmd))
+		if err != nil {
+			return err
+		}
+		address, err := keyAddress(clientCtx.Keyring, from)
+		if err != nil {
+			return err
+		}
+		key, err := chat.LoadKey(homeDir, address)
+		if err != nil {
+			return err
+		}
+		mb, err := chat.OpenMailbox(homeDir, address)
+		if err != nil {
+			return err
+		}
+
+		var handler http.Handler = chat.Handler(key, mb, func(m *chat.Message) {
+			fmt.Printf("📥 %s %s: %s\n", m.Time.Local().Format("15:04"), m.Peer, m.Body)
+		})
+		if tap {
+			mux := http.NewServeMux()
+			mux.Handle("/", handler)
+			mux.Handle(votable.TAPPath+"/", votable.TAPHandler(votable.TAPPath, localResultTables))
+			handler = mux
+		}
+		server := &http.Server{
+			Addr:              listen,
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx := cmd.Context()
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, done := context.WithTimeout(context.Background(), 5*time.Second)
+			defer done()
+			server.Shutdown(shutdownCtx)
+		}()
+
+		fmt.Printf("💬 Chat endpoint for %s on %s%s\n", address, listen, chat.MessagesPath)
+		if tap {
+			fmt.Printf("🔭 TAP service on %s%s\n", listen, votable.TAPPath)
+		}
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+// keyAddress returns the address of a key in the keyring
+func keyAddress(kr keyring.Keyring, name string) (string, error) {
+	keyInfo, err := kr.Key(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to get key info for '%s': %w", name, err)
+	}
+	addr, err := keyInfo.GetAddress()
+	if err != nil {
+		return "", fmt.Errorf("failed to get address from key: %w", err)
+	}
+	return addr.String(), nil
+}
+
+// findChatPeer looks up a chat registration by address, client ID or display name
+func findChatPeer(idx *peers.Index, recipient string) (*peers.Peer, error) {
+	var matches []*peers.Peer
+	for _, p := range idx.Filter("", "chat") {
+		if p.Address == recipient || p.ClientID == recipient || strings.EqualFold(p.DisplayName, recipient) {
+			matches = append(matches, p)
+		}
+	}
+	switch {
+	case len(matches) == 0:
+		return nil, fmt.Errorf("no chat registration found for %s (see: peers list --type chat)", recipient)
+	case len(matches) > 1 && matches[0].Address != matches[1].Address:
+		return nil, fmt.Errorf("%s matches %d clients, use the address", recipient, len(matches))
+	case len(matches[0].ChatKey) == 0:
+		return nil, fmt.Errorf("%s registered without a chat key and cannot receive messages", recipient)
+	}
+	return matches[0], nil
+}
+
+func init() {
+	rootCmd.AddCommand(chatCmd)
+	chatCmd.AddCommand(chatKeyCmd)
+	chatCmd.AddCommand(chatSendCmd)
+	chatCmd.AddCommand(chatInboxCmd)
+	chatCmd.AddCommand(chatServeCmd)
+
+	chatCmd.PersistentFlags().String("from", "", "Key name of the chat account (required)")
+	chatCmd.PersistentFlags().String("keyring-backend", "", "Keyring backend (test|file|os, default client.keyring_backend)")
+	chatCmd.MarkPersistentFlagRequired("from")
+
+	chatSendCmd.Flags().String("via", "auto", "Transport: auto (endpoint, then chain), chain or endpoint")
+	chatSendCmd.Flags().Bool("offline", false, "Look up the recipient in the cached peer index without syncing")
+
+	chatInboxCmd.Flags().Bool("offline", false, "Show stored messages without scanning the chain")
+	chatInboxCmd.Flags().Bool("unread", false, "Only unread messages")
+	chatInboxCmd.Flags().String("with", "", "Only messages with this address")
+	chatInboxCmd.Flags().Bool("sent", false, "Include sent messages")
+	chatInboxCmd.Flags().Bool("json", false, "Print the messages as JSON")
+
+	chatServeCmd.Flags().String("listen", ":8090", "Listen address")
+	chatServeCmd.Flags().Bool("tap", false, "Also serve a TAP/ADQL endpoint over the local results on /tap")
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// reconcileWindow is how far a local distribution and an on-chain transfer may be apart in time
+const reconcileWindow = 30 * time.Minute
+
+// Distribution status values
+const (
+	DistributionSimulated = "simulated" // service has no signing key, nothing was sent
+	DistributionSent      = "sent"      // transaction broadcast by the service
+)
+
+// Reconciliation status values
+const (
+	ReconcileConfirmed = "confirmed" // local record matched to an on-chain transfer
+	ReconcileMissing   = "missing"   // local record without matching transfer
+	ReconcileUntracked = "untracked" // on-chain transfer without local record
+)
+
+// CommunityDistribution is a locally recorded community fee claim for a job
+type CommunityDistribution struct {
+	JobID        string    `json:"job_id"`
+	PaymentTx    string    `json:"payment_tx_hash"`
+	AmountUmedas int64     `json:"amount_umedas"`
+	Status       string    `json:"status"`
+	TxHash       string    `json:"tx_hash,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ReconciledDistribution pairs a local claim and/or an on-chain transfer
+type ReconciledDistribution struct {
+	Status       string     `json:"status"`
+	JobID        string     `json:"job_id,omitempty"`
+	TxHash       string     `json:"tx_hash,omitempty"`
+	Height       int64      `json:"height,omitempty"`
+	Sender       string     `json:"sender,omitempty"`
+	AmountUmedas int64      `json:"amount_umedas"`
+	ClaimedAt    *time.Time `json:"claimed_at,omitempty"`
+	SettledAt    *time.Time `json:"settled_at,omitempty"`
+	Memo         string     `json:"memo,omitempty"`
+}
+
+// CommunityHistory keeps the local distribution ledger of a payment service
+type CommunityHistory struct {
+	mu            sync.Mutex
+	path          string
+	distributions []CommunityDistribution
+}
+
+// NewCommunityHistory loads the distribution ledger from disk
+func NewCommunityHistory(path string) *CommunityHistory {
+	h := &CommunityHistory{path: path}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if err := json.Unmarshal(data, &h.distributions); err != nil {
+			log.Printf("⚠️ Could not parse community history %s: %v", path, err)
+		}
+	}
+	return h
+}
+
+// Record appends a distribution and persists the ledger
+func (h *CommunityHistory) Record(d CommunityDistribution) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.distributions = append(h.distributions, d)
+	if err := h.save(); err != nil {
+		log.Printf("⚠️ Could not save community history: %v", err)
+	}
+}
+
+// List returns a copy of all recorded distributions
+func (h *CommunityHistory) List() []CommunityDistribution {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	out := make([]CommunityDistribution, len(h.distributions))
+	copy(out, h.distributions)
+	return out
+}
+
+func (h *CommunityHistory) save() error {
+	defer telemetry.Track(telemetry.CategoryDisk, "save_community_history")()
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(h.distributions, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := h.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, h.path)
+}
+
+// reconcileDistributions matches local claims against on-chain transfers from the service
+// address. A claim matches a transfer with the same umedas amount within reconcileWindow;
+// transfers carrying the job ID in the memo are matched first.
+func reconcileDistributions(local []CommunityDistribution, transfers []blockchain.Transfer, serviceAddr string) []ReconciledDistribution {
+	used := make([]bool, len(transfers))
+	var out []ReconciledDistribution
+
+	match := func(d CommunityDistribution) int {
+		best := -1
+		for i, t := range transfers {
+			if used[i] || t.Sender != serviceAddr || t.Amount.AmountOf(network.Current().BaseDenom).Int64() != d.AmountUmedas {
+				continue
+			}
+			if d.TxHash != "" && t.TxHash == d.TxHash {
+				return i
+			}
+			if d.JobID != "" && strings.Contains(t.Memo, d.JobID) {
+				return i
+			}
+			if best == -1 && absDuration(t.Time.Sub(d.CreatedAt)) <= reconcileWindow {
+				best = i
+			}
+		}
+		return best
+	}
+
+	for _, d := range local {
+		claimedAt := d.CreatedAt
+		r := ReconciledDistribution{
+			JobID:        d.JobID,
+			AmountUmedas: d.AmountUmedas,
+			ClaimedAt:    &claimedAt,
+			Status:       ReconcileMissing,
+		}
+		if i := match(d); i >= 0 {
+			used[i] = true
+			t := transfers[i]
+			settledAt := t.Time
+			r.Status = ReconcileConfirmed
+			r.TxHash = t.TxHash
+			r.Height = t.Height
+			r.Sender = t.Sender
+			r.SettledAt = &settledAt
+			r.Memo = t.Memo
+		}
+		out = append(out, r)
+	}
+
+	for i, t := range transfers {
+		if used[i] {
+			continue
+		}
+		settledAt := t.Time
+		out = append(out, ReconciledDistribution{
+			Status:       ReconcileUntracked,
+			TxHash:       t.TxHash,
+			Height:       t.Height,
+			Sender:       t.Sender,
+			AmountUmedas: t.Amount.AmountOf(network.Current().BaseDenom).Int64(),
+			SettledAt:    &settledAt,
+			Memo:         t.Memo,
+		})
+	}
+
+	// Newest first, using settlement time when known
+	sort.SliceStable(out, func(i, j int) bool {
+		return reconciledTime(out[i]).After(reconciledTime(out[j]))
+	})
+	return out
+}
+
+// fetchCommunityTransfers loads up to maxPages pages of transfers received by the community address
+func (rps *RealPaymentService) fetchCommunityTransfers(ctx context.Context, maxPages int) ([]blockchain.Transfer, error) {
+	const perPage = 50
+	var all []blockchain.Transfer
+
+	for page := 1; page <= maxPages; page++ {
+		transfers, total, err := rps.blockchainClient.GetIncomingTransfers(ctx, rps.communityAddr, page, perPage)
+		if err != nil {
+			return all, err
+		}
+		all = append(all, transfers...)
+		if page*perPage >= total {
+			break
+		}
+	}
+	return all, nil
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func reconciledTime(r ReconciledDistribution) time.Time {
+	if r.SettledAt != nil && !r.SettledAt.IsZero() {
+		return *r.SettledAt
+	}
+	if r.ClaimedAt != nil {
+		return *r.ClaimedAt
+	}
+	return time.Time{}
+}
+
+// formatUmedas renders a base denom amount in the display denom
+func formatUmedas(amount int64) string {
+	return network.Current().FormatAmount(amount)
+}
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+	"github.com/oxygene76/medasdigital-client/pkg/computeclient"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+	"github.com/oxygene76/medasdigital-client/pkg/notify"
+)
+
+// computePaymentMemo marks the transfer without a COMPUTE_ memo, so a watching
+// service does not create a second job from it
+const computePaymentMemo = "medasdigital-client compute submit"
+
+// computeCmd groups commands that use a remote payment service
+var computeCmd = &cobra.Command{
+	Use:   "compute",
+	Short: "Run paid computations on a remote payment service",
+}
+
+// computeSubmitCmd pays for and runs a PI calculation on a payment service
+var computeSubmitCmd = &cobra.Command{
+	Use:   "submit",
+	Short: "Pay for a PI calculation, submit it and wait for the result",
+	Long: `Estimate the price on a payment service, send the payment from your key,
+submit the job with the payment transaction and wait until the result is ready.
+With --subscription the job is drawn from the prepaid quota of the key instead,
+see 'compute subscription'.
+
+The result is saved to ~/.medasdigital-client/results/<job-id>.json unless --output is set.
+With --stream the digits arrive in verified blocks while the job runs and are
+appended to ~/.medasdigital-client/results/<job-id>.digits.
+
+Example:
+  medasdigital-client compute submit --service http://localhost:8080 \
+    --digits 5000 --tier standard --from mykey`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serviceURL, _ := cmd.Flags().GetString("service")
+		digits, _ := cmd.Flags().GetInt("digits")
+		method, _ := cmd.Flags().GetString("method")
+		tier, _ := cmd.Flags().GetString("tier")
+		verification, _ := cmd.Flags().GetString("verification")
+		from, _ := cmd.Flags().GetString("from")
+		keyringBackend := keyringBackendFlag(cmd)
+		denom, _ := cmd.Flags().GetString("denom")
+		fee, _ := cmd.Flags().GetInt64("fee")
+		maxPrice, _ := cmd.Flags().GetFloat64("max-price")
+		output, _ := cmd.Flags().GetString("output")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		useSubscription, _ := cmd.Flags().GetBool("subscription")
+		stream, _ := cmd.Flags().GetBool("stream")
+
+		if serviceURL == "" {
+			return fmt.Errorf("--service is required")
+		}
+		if from == "" {
+			return fmt.Errorf("--from is required to pay for the job")
+		}
+		if digits <= 0 {
+			return fmt.Errorf("--digits must be positive")
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+		defer cancel()
+
+		svc := computeclient.New(serviceURL, computeclient.Options{})
+		net := network.Current()
+
+		// 1. Price
+		est, err := svc.EstimateCost(ctx, computeclient.EstimateRequest{
+			Digits:       digits,
+			Method:       method,
+			Tier:         compute.ServiceTier(tier),
+			Verification: compute.VerificationLevel(verification),
+		})
+		if err != nil {
+			return fmt.Errorf("price estimate failed: %w", err)
+		}
+		if est.PriceBreakdown == nil {
+			return fmt.Errorf("service returned no price")
+		}
+		price := est.PriceBreakdown.TotalCost
+		if maxPrice > 0 && price > maxPrice {
+			return fmt.Errorf("price %.6f %s exceeds --max-price %.6f", price, net.DisplayDenom, maxPrice)
+		}
+
+		payDenom := denom
+		if payDenom == "" {
+			payDenom = net.BaseDenom
+		}
+		var amount int64
+		var display string
+		for _, opt := range est.PaymentInfo.PaymentOptions {
+			if opt.Denom == payDenom {
+				amount, display = opt.Amount, opt.Display
+			}
+		}
+		if amount <= 0 {
+			return fmt.Errorf("service does not accept payments in %s", payDenom)
+		}
+		payment := sdk.NewInt64Coin(payDenom, amount)
+		if display == "" {
+			display = formatCoin(payment)
+		}
+
+		fmt.Printf("🧮 PI to %d digits (%s tier) on %s\n", digits, est.PriceBreakdown.Tier, svc.BaseURL())
+		fmt.Printf("💰 Price: %s (%s)\n", display, payment)
+
+		// 2. Job, drawn from the subscription or paid on its own
+		clientCtx, err := signingClientContext(cmd.Context(), from, keyringBackend)
+		if err != nil {
+			return err
+		}
+		jobReq := computeclient.JobRequest{
+			Type:          compute.JobTypePICalculation,
+			Parameters:    map[string]interface{}{"digits": digits, "method": method},
+			Tier:          compute.ServiceTier(tier),
+			Verification:  compute.VerificationLevel(verification),
+			ClientAddress: clientCtx.GetFromAddress().String(),
+			Denom:         denom,
+		}
+		var sub *computeclient.Submission
+		if useSubscription {
+			sub, err = submitFromSubscription(ctx, svc, jobReq, clientCtx, from)
+			if err != nil {
+				return fmt.Errorf("job submission failed: %w", err)
+			}
+		}
+		if sub == nil {
+			if sub, err = payAndSubmit(ctx, svc, jobReq, clientCtx, est.PaymentInfo.ServiceAddress, payment, fee); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("🚀 Job submitted: %s\n", sub.JobID)
+		submitted := time.Now()
+		jobTitle := fmt.Sprintf("Paid job %s: PI to %d digits", sub.JobID, digits)
+
+		// 3. Result, the digits first when streaming
+		if stream {
+			err = streamJobDigits(ctx, svc, sub.JobID, filepath.Join(homeDir, "results", sub.JobID+".digits"), 0)
+			var failed *computeclient.JobFailedError
+			if err != nil && !errors.As(err, &failed) {
+				return fmt.Errorf("streaming job %s failed: %w", sub.JobID, err)
+			}
+		}
+		job, err := svc.StreamProgress(ctx, sub.JobID, func(p computeclient.Progress) {
+			verified := ""
+			if p.PaymentVerified {
+				verified = ", payment verified"
+			}
+			fmt.Printf("⏳ %s %d%%%s\n", p.Status, p.Percent, verified)
+		})
+		var failed *computeclient.JobFailedError
+		if errors.As(err, &failed) {
+			fmt.Println("💡 Paid jobs that fail are refunded by the service, subscription jobs get their quota back")
+			notifyDone(notify.KindJob, jobTitle, "", submitted, err)
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("waiting for job %s failed: %w", sub.JobID, err)
+		}
+
+		if output == "" {
+			output = filepath.Join(homeDir, "results", job.ID+".json")
+		}
+		if err := saveComputeResult(output, job); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Job %s completed\n", job.ID)
+		fmt.Printf("💾 Result saved to: %s\n", output)
+		if !stream && job.Artifact != nil && !hasInlineDigits(job) {
+			// Large results come without their digits
+			path := filepath.Join(filepath.Dir(output), job.Artifact.Name)
+			if err := svc.DownloadArtifact(ctx, job.ID, path); err != nil {
+				return fmt.Errorf("downloading the digits of job %s failed: %w", job.ID, err)
+			}
+			fmt.Printf("💾 Digits saved to: %s\n", path)
+		}
+		notifyDone(notify.KindJob, jobTitle, "Result saved to "+output, submitted, nil)
+		return nil
+	},
+}
+
+// submitFromSubscription draws the job from the subscription of the key. It
+// returns no submission when the quota is used up and the overage policy
+// lets the job be paid on its own.
+func submitFromSubscription(ctx context.Context, svc *computeclient.Client, req computeclient.JobRequest, clientCtx client.Context, from string) (*computeclient.Submission, error) {
+	sign := func(b []byte) ([]byte, cryptotypes.PubKey, error) {
+		return clientCtx.Keyring.Sign(from, b, signing.SignMode_SIGN_MODE_DIRECT)
+	}
+	sub, err := svc.SubmitSubscriptionJob(ctx, req, sign)
+	if !errors.Is(err, computeclient.ErrPaymentRequired) {
+		return sub, err
+	}
+	status, statusErr := svc.GetSubscription(ctx, req.ClientAddress)
+	if statusErr != nil || status.Overage != compute.OveragePayPerJob {
+		return nil, err
+	}
+	fmt.Printf("📅 Subscription quota used up (%.6f left), paying for this job separately\n", status.Remaining)
+	return nil, nil
+}
+
+// payAndSubmit sends the payment for a job, waits for it to be in a block and
+// submits the job with it
+func payAndSubmit(ctx context.Context, svc *computeclient.Client, req computeclient.JobRequest, clientCtx client.Context, serviceAddr string, payment sdk.Coin, fee int64) (*computeclient.Submission, error) {
+	net := network.Current()
+	sender, err := blockchain.NewSender(clientCtx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := sender.Send(ctx, serviceAddr, sdk.NewCoins(payment), sdk.NewCoins(sdk.NewInt64Coin(net.BaseDenom, fee)), computePaymentMemo)
+	if err != nil {
+		return nil, fmt.Errorf("payment failed: %w", err)
+	}
+	fmt.Printf("📤 Payment sent: %s\n", res.TxHash)
+
+	// The service verifies the payment on chain, it has to be in a block first
+	height, err := sender.WaitForTx(ctx, res.TxHash)
+	if err != nil {
+		return nil, fmt.Errorf("payment %s: %w", res.TxHash, err)
+	}
+	fmt.Printf("✅ Payment included in block %d\n", height)
+
+	req.PaymentTxHash = res.TxHash
+	sub, err := svc.SubmitJob(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("job submission failed, payment %s was sent: %w", res.TxHash, err)
+	}
+	return sub, nil
+}
+
+// computeSubscriptionCmd shows the prepaid quota of an account
+var computeSubscriptionCmd = &cobra.Command{
+	Use:   "subscription <address>",
+	Short: "Show the prepaid monthly quota of an account on a payment service",
+	Long: `Show quota, usage, paid renewals and the overage policy of a subscription.
+
+A subscription is funded by sending MEDAS to the service address with the memo
+SUBSCRIBE (jobs beyond the quota are rejected) or SUBSCRIBE_PAY_PER_JOB (jobs
+beyond the quota are paid one by one). Every payment adds a month of quota
+worth the amount paid; a payment during a running month starts when it ends.
+
+Example:
+  medasdigital-client compute subscription medas1... --service http://localhost:8080`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serviceURL, _ := cmd.Flags().GetString("service")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if serviceURL == "" {
+			return fmt.Errorf("--service is required")
+		}
+
+		sub, err := computeclient.New(serviceURL, computeclient.Options{}).GetSubscription(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(sub)
+		}
+
+		denom := network.Current().DisplayDenom
+		if !sub.Active {
+			fmt.Printf("📅 %s has no active subscription\n", sub.Address)
+		} else {
+			p := sub.Current
+			fmt.Printf("📅 Subscription of %s (overage: %s)\n", sub.Address, sub.Overage)
+			fmt.Printf("   Period:    %s - %s\n", p.Start.Local().Format(time.DateTime), p.End.Local().Format(time.DateTime))
+			fmt.Printf("   Quota:     %.6f %s\n", p.Quota, denom)
+			fmt.Printf("   Used:      %.6f %s\n", p.Used, denom)
+			fmt.Printf("   Remaining: %.6f %s\n", sub.Remaining, denom)
+		}
+		for _, r := range sub.Renewals {
+			fmt.Printf("   Renewal:   %s - %s, %.6f %s\n", r.Start.Local().Format(time.DateTime), r.End.Local().Format(time.DateTime), r.Quota, denom)
+		}
+		return nil
+	},
+}
+
+// computeStreamCmd follows the digits of a running PI job
+var computeStreamCmd = &cobra.Command{
+	Use:   "stream <job-id>",
+	Short: "Receive the digits of a PI job in verified blocks as they are calculated",
+	Long: `Connect to the digit stream of a PI job on a payment service and append each
+block to the output file as it arrives. Every block is checked by the service
+against an independent computation, and its hash chains it to the blocks before.
+
+An existing output file is continued: the stream starts after the digits it
+already holds, so an interrupted download does not start over.
+
+Example:
+  medasdigital-client compute stream pi_calculation-7 --service http://localhost:8080`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serviceURL, _ := cmd.Flags().GetString("service")
+		output, _ := cmd.Flags().GetString("output")
+		if serviceURL == "" {
+			return fmt.Errorf("--service is required")
+		}
+		if output == "" {
+			output = filepath.Join(homeDir, "results", args[0]+".digits")
+		}
+
+		// Blocks the file holds already, all but the last one are full
+		from := 0
+		if info, err := os.Stat(output); err == nil && info.Size() <= 2 {
+			os.Remove(output)
+		} else if err == nil {
+			held := int(info.Size()) - 2
+			if held%compute.DefaultBlockDigits != 0 {
+				return fmt.Errorf("%s ends within a block, remove it to start over", output)
+			}
+			from = held / compute.DefaultBlockDigits
+		}
+
+		svc := computeclient.New(serviceURL, computeclient.Options{})
+		if err := streamJobDigits(cmd.Context(), svc, args[0], output, from); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Job %s completed\n", args[0])
+		return nil
+	},
+}
+
+// computeDownloadCmd fetches the result file of a completed job
+var computeDownloadCmd = &cobra.Command{
+	Use:   "download <job-id>",
+	Short: "Download the result file of a completed job",
+	Long: `Download the result file (artifact) of a completed job from a payment service:
+the digits of a PI calculation as text, other results as JSON. An existing
+partial file is continued with a range request and the finished file is
+checked against the SHA-256 of the artifact.
+
+Example:
+  medasdigital-client compute download pi_calculation-7 --service http://localhost:8080`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serviceURL, _ := cmd.Flags().GetString("service")
+		output, _ := cmd.Flags().GetString("output")
+		if serviceURL == "" {
+			return fmt.Errorf("--service is required")
+		}
+
+		svc := computeclient.New(serviceURL, computeclient.Options{})
+		if output == "" {
+			job, err := svc.GetJob(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			if job.Artifact == nil {
+				return fmt.Errorf("job %s is %s and has no artifact", job.ID, job.Status)
+			}
+			output = filepath.Join(homeDir, "results", job.Artifact.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+			return err
+		}
+		if err := svc.DownloadArtifact(cmd.Context(), args[0], output); err != nil {
+			return err
+		}
+		fmt.Printf("💾 Artifact of %s saved to: %s\n", args[0], output)
+		return nil
+	},
+}
+
+// hasInlineDigits reports whether the job JSON carries the digits of its PI
+// result, services leave them out of large ones
+func hasInlineDigits(job *compute.ComputeJob) bool {
+	result, ok := job.Result.(map[string]interface{})
+	if !ok {
+		return true
+	}
+	value, _ := result["value"].(string)
+	return value != ""
+}
+
+// streamJobDigits appends the blocks of a job from index from on to path
+func streamJobDigits(ctx context.Context, svc *computeclient.Client, jobID, path string, from int) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	fmt.Printf("📡 Streaming digits of %s to %s\n", jobID, path)
+	expected := from*compute.DefaultBlockDigits + 1
+	return svc.StreamDigits(ctx, jobID, from, func(b compute.DigitBlock) error {
+		if b.Offset != expected {
+			return fmt.Errorf("block %d starts at digit %d, %s holds %d", b.Index, b.Offset, path, expected-1)
+		}
+		if err := appendDigits(path, b); err != nil {
+			return err
+		}
+		expected += len(b.Digits)
+		state := "verified"
+		if !b.Verified {
+			state = "received"
+		}
+		fmt.Printf("📦 Digits %d-%d %s (%s...)\n", b.Offset, b.Offset+len(b.Digits)-1, state, b.Hash[:12])
+		return nil
+	})
+}
+
+// saveComputeResult writes the finished job including its result
+func saveComputeResult(path string, job *compute.ComputeJob) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create result directory: %w", err)
+	}
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save result: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(computeCmd)
+	computeCmd.AddCommand(computeSubmitCmd)
+	computeCmd.AddCommand(computeSubscriptionCmd)
+	computeCmd.AddCommand(computeStreamCmd)
+	computeCmd.AddCommand(computeDownloadCmd)
+
+	computeSubmitCmd.Flags().String("service", "", "Payment service URL, e.g. http://localhost:8080")
+	computeSubmitCmd.Flags().Int("digits", 1000, "Number of PI digits")
+	computeSubmitCmd.Flags().String("method", "chudnovsky", "Calculation method")
+	computeSubmitCmd.Flags().String("tier", string(compute.TierStandard), "Service tier (basic|standard|premium)")
+	computeSubmitCmd.Flags().String("verification", "", "Result verification level")
+	computeSubmitCmd.Flags().String("from", "", "Key name to pay with")
+	computeSubmitCmd.Flags().String("keyring-backend", "", "Keyring backend (test|file|os, default client.keyring_backend)")
+	computeSubmitCmd.Flags().String("denom", "", "Pay in an IBC token the service accepts (ibc/...)")
+	computeSubmitCmd.Flags().Int64("fee", 5000, "Transaction fee in the base denom")
+	computeSubmitCmd.Flags().Float64("max-price", 0, "Abort if the price exceeds this amount (0 = no limit)")
+	computeSubmitCmd.Flags().String("output", "", "Result file (default ~/.medasdigital-client/results/<job-id>.json)")
+	computeSubmitCmd.Flags().Duration("timeout", 30*time.Minute, "Give up waiting after this duration")
+	computeSubmitCmd.Flags().Bool("subscription", false, "Draw the job from the prepaid quota of the key, pay per job only if its overage policy allows")
+	computeSubmitCmd.Flags().Bool("stream", false, "Receive the digits in verified blocks while the job runs")
+
+	computeStreamCmd.Flags().String("service", "", "Payment service URL, e.g. http://localhost:8080")
+	computeStreamCmd.Flags().String("output", "", "Digits file, continued if it exists (default ~/.medasdigital-client/results/<job-id>.digits)")
+
+	computeDownloadCmd.Flags().String("service", "", "Payment service URL, e.g. http://localhost:8080")
+	computeDownloadCmd.Flags().String("output", "", "Output file, continued if it exists (default ~/.medasdigital-client/results/<artifact name>)")
+
+	computeSubscriptionCmd.Flags().String("service", "", "Payment service URL, e.g. http://localhost:8080")
+	computeSubscriptionCmd.Flags().Bool("json", false, "Output as JSON")
+}
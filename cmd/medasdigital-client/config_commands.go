@@ -0,0 +1,661 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+)
+
+// configVersion is the format init writes; older files are upgraded by config migrate
+const configVersion = 2
+
+// keyringBackends are the backends the cosmos keyring supports
+var keyringBackends = []string{"os", "file", "kwallet", "pass", "test", "memory"}
+
+// configMigration upgrades a config file from one version to the next
+type configMigration struct {
+	From        int
+	Description string
+	Apply       func(root *yaml.Node) []string // returns the changes made
+}
+
+// configMigrations are applied in order, version 1 is the format of the
+// original client (pkg/utils.ClientConfig) and of configs without a version
+var configMigrations = []configMigration{
+	{From: 1, Description: "unify chain and GPU keys with the CLI schema", Apply: migrateConfigV1},
+}
+
+// configCmd groups the config file commands
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read, change, validate and migrate the config file",
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Show the effective value of a config key, e.g. chain.rpc_endpoint",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := strings.Split(args[0], ".")
+		if _, err := configFieldType(path); err != nil {
+			return err
+		}
+
+		// Effective values include the network defaults
+		data, err := yaml.Marshal(loadConfig())
+		if err != nil {
+			return err
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		node := yamlLookup(&doc, path)
+		if node == nil {
+			return nil
+		}
+		if node.Kind == yaml.ScalarNode {
+			fmt.Println(node.Value)
+			return nil
+		}
+		out, err := yaml.Marshal(node)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(out))
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a config key, lists are comma separated",
+	Long: `Change a key in the config file. The value is checked against the schema and
+the file is only written if it stays valid. Comments in the file are kept.
+
+Examples:
+  medasdigital-client config set chain.rpc_endpoint https://rpc.example.org:26657
+  medasdigital-client config set client.keyring_backend file
+  medasdigital-client config set gpu.devices 0,1`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := strings.Split(args[0], ".")
+		typ, err := configFieldType(path)
+		if err != nil {
+			return err
+		}
+		value, err := configValueNode(typ, args[1])
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", args[0], err)
+		}
+
+		doc, err := readConfigDoc(cfgFile)
+		if err != nil {
+			return err
+		}
+		before, _ := encodeConfigDoc(doc)
+		yamlSet(doc, path, value)
+		after, err := encodeConfigDoc(doc)
+		if err != nil {
+			return err
+		}
+
+		// Only refuse problems this change introduces
+		known := map[string]bool{}
+		for _, p := range validateConfigData(before) {
+			known[p] = true
+		}
+		var introduced []string
+		for _, p := range validateConfigData(after) {
+			if !known[p] {
+				introduced = append(introduced, p)
+			}
+		}
+		if len(introduced) > 0 {
+			return fmt.Errorf("%s not changed:\n  %s", cfgFile, strings.Join(introduced, "\n  "))
+		}
+
+		if err := writeConfigFile(cfgFile, after); err != nil {
+			return err
+		}
+		fmt.Printf("✅ %s = %s\n", args[0], args[1])
+		return nil
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file against the schema",
+	Long: `Check the config file against the schema: unknown keys, wrong types, bad URLs
+and keyring backends. With --probe the REST endpoint of the selected network is
+queried as well, it has to answer and serve the configured chain.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(cfgFile)
+		if err != nil {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+		problems := validateConfigData(data)
+		if probe, _ := cmd.Flags().GetBool("probe"); probe {
+			problems = append(problems, probeRESTEndpoint(cmd.Context(), loadConfig())...)
+		}
+		if len(problems) == 0 {
+			fmt.Printf("✅ %s is valid (version %d)\n", cfgFile, configVersion)
+			return nil
+		}
+		fmt.Printf("❌ %s has %d problem(s):\n", cfgFile, len(problems))
+		for _, p := range problems {
+			fmt.Printf("   • %s\n", p)
+		}
+		return fmt.Errorf("invalid config")
+	},
+}
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade the config file to the current format",
+	Long: `Upgrade an older config file to the current format. The original file is kept
+as config.yaml.v<version>.bak next to it.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		original, err := os.ReadFile(cfgFile)
+		if err != nil {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+		doc, err := readConfigDoc(cfgFile)
+		if err != nil {
+			return err
+		}
+		from := configFileVersion(doc)
+		if from >= configVersion {
+			fmt.Printf("✅ %s is already version %d\n", cfgFile, from)
+			return nil
+		}
+
+		for _, m := range configMigrations {
+			if m.From < from {
+				continue
+			}
+			fmt.Printf("🔄 v%d → v%d: %s\n", m.From, m.From+1, m.Description)
+			for _, change := range m.Apply(doc) {
+				fmt.Printf("   • %s\n", change)
+			}
+			yamlSet(doc, []string{"version"}, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(m.From + 1)})
+		}
+
+		data, err := encodeConfigDoc(doc)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			fmt.Printf("\n%s", data)
+			return nil
+		}
+
+		backup := fmt.Sprintf("%s.v%d.bak", cfgFile, from)
+		if err := os.WriteFile(backup, original, 0600); err != nil {
+			return fmt.Errorf("failed to back up config: %w", err)
+		}
+		if err := writeConfigFile(cfgFile, data); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Migrated %s to version %d (backup: %s)\n", cfgFile, configVersion, backup)
+		for _, p := range validateConfigData(data) {
+			fmt.Printf("⚠️  %s\n", p)
+		}
+		return nil
+	},
+}
+
+// migrateConfigV1 renames the keys of the original client format
+func migrateConfigV1(root *yaml.Node) []string {
+	var changes []string
+	rename := func(from, to string) {
+		fromPath, toPath := strings.Split(from, "."), strings.Split(to, ".")
+		node := yamlLookup(root, fromPath)
+		if node == nil {
+			return
+		}
+		yamlDelete(root, fromPath)
+		if yamlLookup(root, toPath) != nil {
+			changes = append(changes, fmt.Sprintf("removed %s, %s is already set", from, to))
+			return
+		}
+		yamlSet(root, toPath, node)
+		changes = append(changes, fmt.Sprintf("renamed %s to %s", from, to))
+	}
+	drop := func(key, reason string) {
+		if yamlLookup(root, strings.Split(key, ".")) != nil {
+			yamlDelete(root, strings.Split(key, "."))
+			changes = append(changes, fmt.Sprintf("removed %s (%s)", key, reason))
+		}
+	}
+
+	rename("chain.id", "chain.chain_id")
+	rename("gpu.cuda_devices", "gpu.devices")
+
+	if node := yamlLookup(root, []string{"gpu", "max_memory_gb"}); node != nil {
+		yamlDelete(root, []string{"gpu", "max_memory_gb"})
+		gb, err := strconv.ParseFloat(node.Value, 64)
+		switch {
+		case err != nil:
+			changes = append(changes, fmt.Sprintf("removed gpu.max_memory_gb, %q is not a number", node.Value))
+		case yamlLookup(root, []string{"gpu", "memory_limit"}) != nil:
+			changes = append(changes, "removed gpu.max_memory_gb, gpu.memory_limit is already set")
+		default:
+			mb := strconv.Itoa(int(gb * 1024))
+			yamlSet(root, []string{"gpu", "memory_limit"}, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: mb})
+			changes = append(changes, fmt.Sprintf("converted gpu.max_memory_gb %s to gpu.memory_limit %s (MB)", node.Value, mb))
+		}
+	}
+
+	for _, key := range []string{"gpu.device_count", "gpu.use_all_devices"} {
+		drop(key, "list the devices in gpu.devices")
+	}
+	for _, key := range []string{"gpu.benchmark_on_init", "gpu.power_limit", "gpu.temp_limit", "client.timeout", "client.retry_attempts"} {
+		drop(key, "not supported")
+	}
+	drop("analysis", "analysis settings are command flags")
+
+	if len(changes) == 0 {
+		changes = append(changes, "no legacy keys found")
+	}
+	return changes
+}
+
+// probeRESTEndpoint checks that chain.rest_endpoint answers for the configured chain
+func probeRESTEndpoint(ctx context.Context, cfg *Config) []string {
+	rest, err := blockchain.NewREST(cfg.Chain.RESTEndpoint, cfg.Chain.ID)
+	if err == nil {
+		err = rest.Health(ctx)
+	}
+	if err != nil {
+		return []string{fmt.Sprintf("chain.rest_endpoint: %v", err)}
+	}
+	return nil
+}
+
+// validateConfigData checks a config file against the schema and the value rules
+func validateConfigData(data []byte) []string {
+	var problems []string
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []string{fmt.Sprintf("not valid YAML: %v", err)}
+	}
+	if version := configFileVersion(&doc); version < configVersion {
+		problems = append(problems, fmt.Sprintf("config format version %d, run 'config migrate' to upgrade to %d", version, configVersion))
+	} else if version > configVersion {
+		problems = append(problems, fmt.Sprintf("config format version %d is newer than this client (%d)", version, configVersion))
+	}
+
+	// Unknown keys and wrong types
+	var cfg Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && err != io.EOF {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			for _, msg := range typeErr.Errors {
+				// "line 3: field id not found in type main.ChainConfig"
+				if before, _, ok := strings.Cut(msg, " not found in type "); ok {
+					msg = strings.Replace(before, "field ", "unknown key ", 1)
+				}
+				problems = append(problems, msg)
+			}
+		} else {
+			problems = append(problems, err.Error())
+		}
+		// Check the values that could be read
+		cfg = Config{}
+		yaml.Unmarshal(data, &cfg)
+	}
+
+	checkURL := func(key, value string, schemes ...string) {
+		if value == "" {
+			return
+		}
+		u, err := url.Parse(value)
+		if err != nil || u.Host == "" || !containsString(schemes, u.Scheme) {
+			problems = append(problems, fmt.Sprintf("%s: %q is not a %s URL", key, value, strings.Join(schemes, "/")))
+		}
+	}
+	checkURL("chain.rpc_endpoint", cfg.Chain.RPCEndpoint, "http", "https", "tcp")
+	checkURL("chain.ws_endpoint", cfg.Chain.WSEndpoint, "ws", "wss")
+	checkURL("chain.rest_endpoint", cfg.Chain.RESTEndpoint, "http", "https")
+
+	names := make([]string, 0, len(cfg.Networks))
+	for name := range cfg.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		n := cfg.Networks[name]
+		n.Name = name
+		if err := network.Register(n); err != nil {
+			problems = append(problems, fmt.Sprintf("networks.%s: %v", name, err))
+		}
+		checkURL("networks."+name+".rpc_endpoint", n.RPCEndpoint, "http", "https", "tcp")
+		checkURL("networks."+name+".ws_endpoint", n.WSEndpoint, "ws", "wss")
+		checkURL("networks."+name+".rest_endpoint", n.RESTEndpoint, "http", "https")
+	}
+	if cfg.Chain.Network != "" {
+		if _, err := network.Get(cfg.Chain.Network); err != nil {
+			problems = append(problems, fmt.Sprintf("chain.network: %v", err))
+		}
+	}
+	checkURL("provider.endpoint", cfg.Provider.Endpoint, "http", "https")
+
+	for key, backend := range map[string]string{"client.keyring_backend": cfg.Client.KeyringBackend, "provider.keyring_backend": cfg.Provider.KeyringBackend} {
+		if backend != "" && !containsString(keyringBackends, backend) {
+			problems = append(problems, fmt.Sprintf("%s: unknown backend %q (%s)", key, backend, strings.Join(keyringBackends, ", ")))
+		}
+	}
+	if cfg.Provider.Port < 0 || cfg.Provider.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("provider.port: %d is not a port", cfg.Provider.Port))
+	}
+	if cfg.Provider.MaxBalance != 0 && cfg.Provider.MinBalance > cfg.Provider.MaxBalance {
+		problems = append(problems, "provider.min_balance is above provider.max_balance")
+	}
+	if err := cfg.Payment.Limits.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := cfg.Payment.ResultCache.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if cfg.Payment.PricingFile != "" {
+		if _, err := compute.LoadPricingFile(cfg.Payment.PricingFile); err != nil {
+			problems = append(problems, fmt.Sprintf("payment.pricing_file: %v", err))
+		}
+	}
+	for i, rule := range cfg.Provider.Harvest {
+		if err := rule.Validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("provider.harvest[%d]: %v", i, err))
+		}
+	}
+	if err := cfg.Notifications.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := cfg.CrossMatch.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	seenContracts := map[string]bool{}
+	for _, addr := range cfg.Provider.Contracts {
+		if seenContracts[addr] {
+			problems = append(problems, fmt.Sprintf("provider.contracts: %s is listed twice", addr))
+		}
+		seenContracts[addr] = true
+	}
+	if s := cfg.Provider.Bidding.Strategy; s != "" && s != "accept" && s != "bid" {
+		problems = append(problems, fmt.Sprintf("provider.bidding.strategy: %q is not accept or bid", s))
+	}
+	if cfg.GPU.MemoryLimit < 0 {
+		problems = append(problems, "gpu.memory_limit must not be negative")
+	}
+	if _, err := compute.PaymentDenoms(cfg.Payment.IBCDenoms); err != nil {
+		problems = append(problems, fmt.Sprintf("payment.ibc_denoms: %v", err))
+	}
+	if t := cfg.Payment.TolerancePercent; t < 0 || t >= 100 {
+		problems = append(problems, fmt.Sprintf("payment.tolerance_percent: %g is not between 0 and 100", t))
+	}
+	if cfg.Payment.ArtifactRetention < 0 {
+		problems = append(problems, "payment.artifact_retention must not be negative")
+	}
+	if cfg.Payment.MemoryBudgetMB < 0 {
+		problems = append(problems, "payment.memory_budget_mb must not be negative")
+	}
+	if err := cfg.HTTP.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	return problems
+}
+
+// configFileVersion returns the format version, files without one are version 1
+func configFileVersion(doc *yaml.Node) int {
+	node := yamlLookup(doc, []string{"version"})
+	if node == nil {
+		return 1
+	}
+	v, err := strconv.Atoi(node.Value)
+	if err != nil {
+		return 1
+	}
+	return v
+}
+
+// configFieldType returns the Go type of a dotted config key
+func configFieldType(path []string) (reflect.Type, error) {
+	typ := reflect.TypeOf(Config{})
+	for i, key := range path {
+		switch typ.Kind() {
+		case reflect.Struct:
+			field, ok := yamlField(typ, key)
+			if !ok {
+				return nil, fmt.Errorf("unknown config key %q", strings.Join(path[:i+1], "."))
+			}
+			typ = field.Type
+		case reflect.Map:
+			typ = typ.Elem()
+		default:
+			return nil, fmt.Errorf("%s has no key %q", strings.Join(path[:i], "."), key)
+		}
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+	}
+	return typ, nil
+}
+
+func yamlField(typ reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == key {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// configValueNode parses a command line value into a YAML node of the key's type
+func configValueNode(typ reflect.Type, value string) (*yaml.Node, error) {
+	scalar := func(tag, v string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v}
+	}
+
+	if typ == reflect.TypeOf(time.Duration(0)) {
+		if _, err := time.ParseDuration(value); err != nil {
+			return nil, err
+		}
+		return scalar("!!str", value), nil
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		return scalar("!!str", value), nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not true or false", value)
+		}
+		return scalar("!!bool", strconv.FormatBool(b)), nil
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("%q is not an integer", value)
+		}
+		return scalar("!!int", value), nil
+	case reflect.Uint, reflect.Uint64, reflect.Uint32:
+		if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("%q is not a positive integer", value)
+		}
+		return scalar("!!int", value), nil
+	case reflect.Float64, reflect.Float32:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return scalar("!!float", value), nil
+	case reflect.Slice:
+		seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+		if strings.TrimSpace(value) == "" {
+			return seq, nil
+		}
+		for _, item := range strings.Split(value, ",") {
+			elem, err := configValueNode(typ.Elem(), strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			seq.Content = append(seq.Content, elem)
+		}
+		return seq, nil
+	}
+	return nil, fmt.Errorf("edit %s in the config file directly", typ)
+}
+
+func readConfigDoc(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	var doc yaml.Node
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+		yamlSet(&doc, []string{"version"}, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(configVersion)})
+	}
+	return &doc, nil
+}
+
+func encodeConfigDoc(doc *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(4)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	enc.Close()
+	return buf.Bytes(), nil
+}
+
+func writeConfigFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// yamlLookup returns the node at path below a document or mapping node
+func yamlLookup(node *yaml.Node, path []string) *yaml.Node {
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		node = node.Content[0]
+	}
+	for _, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+// yamlSet replaces or adds the value at path, creating mappings on the way
+func yamlSet(node *yaml.Node, path []string, value *yaml.Node) {
+	if node.Kind == yaml.DocumentNode {
+		node = node.Content[0]
+	}
+	for i, key := range path {
+		var next *yaml.Node
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value == key {
+				if i == len(path)-1 {
+					// Keep comments attached to the old value
+					value.HeadComment, value.LineComment = node.Content[j+1].HeadComment, node.Content[j+1].LineComment
+					node.Content[j+1] = value
+					return
+				}
+				next = node.Content[j+1]
+				break
+			}
+		}
+		if next == nil {
+			keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+			if i == len(path)-1 {
+				node.Content = append(node.Content, keyNode, value)
+				return
+			}
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content, keyNode, next)
+		}
+		if next.Kind != yaml.MappingNode {
+			*next = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		node = next
+	}
+}
+
+// yamlDelete removes the key at path
+func yamlDelete(node *yaml.Node, path []string) {
+	parent := yamlLookup(node, path[:len(path)-1])
+	if parent == nil || parent.Kind != yaml.MappingNode {
+		return
+	}
+	key := path[len(path)-1]
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == key {
+			parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configMigrateCmd)
+
+	configValidateCmd.Flags().Bool("probe", false, "Also check that the REST endpoint is reachable and serves the chain")
+	configMigrateCmd.Flags().Bool("dry-run", false, "Show the migrated config without writing it")
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// ConsumedPayment is a payment transaction and the jobs it paid for
+type ConsumedPayment struct {
+	TxHash     string    `json:"tx_hash"`
+	Uses       int       `json:"uses"`    // jobs the payment covers, more than one for a bundle memo
+	Claimed    int       `json:"claimed"` // jobs created or being created, at most Uses
+	JobIDs     []string  `json:"job_ids,omitempty"`
+	ConsumedAt time.Time `json:"consumed_at"`
+}
+
+// ConsumedPayments keeps the payment transactions already turned into jobs on
+// disk, so a payment cannot be used twice, also not across restarts
+type ConsumedPayments struct {
+	mu       sync.Mutex
+	path     string
+	payments map[string]*ConsumedPayment // by upper case tx hash
+}
+
+// NewConsumedPayments loads the consumed payments from disk
+func NewConsumedPayments(path string) *ConsumedPayments {
+	s := &ConsumedPayments{path: path, payments: make(map[string]*ConsumedPayment)}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var list []*ConsumedPayment
+		if err := json.Unmarshal(data, &list); err != nil {
+			log.Printf("⚠️ Could not parse consumed payments %s: %v", path, err)
+		}
+		for _, p := range list {
+			// Payments stored before Claimed were used up when claimed
+			if p.Claimed == 0 {
+				p.Claimed = p.Uses
+			}
+			s.payments[normalizeTxHash(p.TxHash)] = p
+		}
+	}
+	return s
+}
+
+// normalizeTxHash makes hashes comparable, clients may send them in lower case
+func normalizeTxHash(txHash string) string {
+	return strings.ToUpper(strings.TrimSpace(txHash))
+}
+
+// Claim reserves up to jobs of a payment that covers uses jobs and returns how
+// many it reserved, 0 when the payment is used up. The bundle size of a memo
+// raises the uses of a payment claimed before as a single job.
+func (s *ConsumedPayments) Claim(txHash string, jobs, uses int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if jobs < 1 {
+		jobs = 1
+	}
+	key := normalizeTxHash(txHash)
+	p, ok := s.payments[key]
+	if !ok {
+		p = &ConsumedPayment{TxHash: key, ConsumedAt: time.Now()}
+		s.payments[key] = p
+	}
+	if uses > p.Uses {
+		p.Uses = uses
+	}
+	if p.Uses < 1 {
+		p.Uses = 1
+	}
+	if free := p.Uses - p.Claimed; jobs > free {
+		jobs = free
+	}
+	if jobs <= 0 {
+		return 0
+	}
+	p.Claimed += jobs
+	s.persist()
+	return jobs
+}
+
+// Bind records a job created for a claimed payment
+func (s *ConsumedPayments) Bind(txHash, jobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.payments[normalizeTxHash(txHash)]
+	if !ok {
+		return
+	}
+	p.JobIDs = append(p.JobIDs, jobID)
+	s.persist()
+}
+
+// Release gives back claimed jobs that were not created
+func (s *ConsumedPayments) Release(txHash string, jobs int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.release(normalizeTxHash(txHash), jobs)
+}
+
+// Unbind gives back the claim of a job the payment turned out not to pay
+// for, e.g. when the job was submitted with another sender's transaction
+func (s *ConsumedPayments) Unbind(txHash, jobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := normalizeTxHash(txHash)
+	p, ok := s.payments[key]
+	if !ok {
+		return
+	}
+	for i, id := range p.JobIDs {
+		if id == jobID {
+			p.JobIDs = append(p.JobIDs[:i:i], p.JobIDs[i+1:]...)
+			break
+		}
+	}
+	s.release(key, 1)
+}
+
+// release gives back claimed jobs, never those bound to a job
+func (s *ConsumedPayments) release(key string, jobs int) {
+	p, ok := s.payments[key]
+	if !ok {
+		return
+	}
+	p.Claimed -= jobs
+	if p.Claimed < len(p.JobIDs) {
+		p.Claimed = len(p.JobIDs)
+	}
+	if p.Claimed == 0 {
+		delete(s.payments, key)
+	}
+	s.persist()
+}
+
+// Get returns a consumed payment
+func (s *ConsumedPayments) Get(txHash string) (ConsumedPayment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.payments[normalizeTxHash(txHash)]
+	if !ok {
+		return ConsumedPayment{}, false
+	}
+	out := *p
+	out.JobIDs = append([]string(nil), p.JobIDs...)
+	return out, true
+}
+
+func (s *ConsumedPayments) persist() {
+	if err := s.save(); err != nil {
+		log.Printf("⚠️ Could not save consumed payments: %v", err)
+	}
+}
+
+func (s *ConsumedPayments) save() error {
+	defer telemetry.Track(telemetry.CategoryDisk, "save_consumed_payments")()
+
+	list := make([]*ConsumedPayment, 0, len(s.payments))
+	for _, p := range s.payments {
+		list = append(list, p)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// cancelTimeout releases the deadline set by applyTimeout
+var cancelTimeout context.CancelFunc = func() {}
+
+// signalContext is cancelled by the first Ctrl-C or SIGTERM. Commands get it
+// from cmd.Context() and stop cleanly; a second signal kills the process.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// applyTimeout bounds the command context by --timeout or $MEDAS_TIMEOUT
+func applyTimeout(cmd *cobra.Command) {
+	timeout := viper.GetDuration("timeout")
+	if timeout <= 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+	cmd.SetContext(ctx)
+	cancelTimeout = cancel
+}
+
+// withTimeout bounds a single call to d, or less if the command deadline is
+// earlier
+func withTimeout(cmd *cobra.Command, d time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(cmd.Context(), d)
+}
@@ -3,13 +3,23 @@ package main
 import (
     "context"
     "fmt"
+    "log"
+    "os"
     "os/exec"
     "encoding/json"
+    "runtime"
+    "sort"
+    "strconv"
     "strings"
     "time"
     
+    sdk "github.com/cosmos/cosmos-sdk/types"
     "github.com/spf13/cobra"
+    "gopkg.in/yaml.v3"
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/planet9"
+    "github.com/oxygene76/medasdigital-client/pkg/compute"
     "github.com/oxygene76/medasdigital-client/pkg/contract"
+    "github.com/oxygene76/medasdigital-client/pkg/gpu"
 )
 
 var contractCmd = &cobra.Command{
@@ -20,10 +30,25 @@ var contractCmd = &cobra.Command{
 var contractListProvidersCmd = &cobra.Command{
     Use:   "list-providers",
     Short: "List available providers",
+    Long: `List the providers registered with the compute contract.
+
+--sort reputation aggregates each provider's on-chain history: completed,
+failed and disputed jobs, the average turnaround from submission to result
+and the share of heartbeat intervals in --window in which it sent a
+heartbeat. The score is the smoothed success rate in percent, reduced by up
+to half for missed heartbeats.
+
+Examples:
+  medasdigital-client contract list-providers
+  medasdigital-client contract list-providers --sort reputation
+  medasdigital-client contract list-providers --sort reputation --window 72h`,
     RunE: func(cmd *cobra.Command, args []string) error {
         cfg := loadConfig()  // ← HINZUFÜGEN
         
-        contractAddr, _ := cmd.Flags().GetString("contract")
+        contractAddr, err := contractAddress(cmd)
+        if err != nil {
+            return err
+        }
         
         client := contract.NewClient(contract.Config{
             ContractAddress: contractAddr,
@@ -31,7 +56,15 @@ var contractListProvidersCmd = &cobra.Command{
             ChainID:         cfg.Chain.ID,
         }, "", "", "")  // Leer bei Queries
         
-        providers, err := client.ListProviders(context.Background())
+        sortBy, _ := cmd.Flags().GetString("sort")
+        window, _ := cmd.Flags().GetDuration("window")
+        switch sortBy {
+        case "", "reputation", "completed", "capacity":
+        default:
+            return fmt.Errorf("unknown sort %q, use reputation, completed or capacity", sortBy)
+        }
+        
+        providers, err := client.ListProviders(cmd.Context())
         if err != nil {
             return err
         }
@@ -41,6 +74,36 @@ var contractListProvidersCmd = &cobra.Command{
             return nil
         }
         
+        reputations := map[string]contract.ProviderReputation{}
+        switch sortBy {
+        case "reputation":
+            ctx, cancel := context.WithTimeout(cmd.Context(), 2*time.Minute)
+            defer cancel()
+            reps, err := client.ProviderReputations(ctx, contract.ReputationOptions{
+                Window:            window,
+                HeartbeatInterval: time.Duration(cfg.Provider.HeartbeatIntervalMinutes) * time.Minute,
+            })
+            if err != nil {
+                return fmt.Errorf("failed to aggregate provider history: %w", err)
+            }
+            rank := map[string]int{}
+            for i, r := range reps {
+                reputations[r.Address] = r
+                rank[r.Address] = i
+            }
+            sort.SliceStable(providers, func(i, j int) bool {
+                return rank[providers[i].Address] < rank[providers[j].Address]
+            })
+        case "completed":
+            sort.SliceStable(providers, func(i, j int) bool {
+                return providers[i].TotalCompleted > providers[j].TotalCompleted
+            })
+        case "capacity":
+            sort.SliceStable(providers, func(i, j int) bool {
+                return providers[i].Capacity-providers[i].ActiveJobs > providers[j].Capacity-providers[j].ActiveJobs
+            })
+        }
+        
         fmt.Println("Available Computing Providers")
         fmt.Println(strings.Repeat("=", 80))
         
@@ -57,6 +120,12 @@ var contractListProvidersCmd = &cobra.Command{
             fmt.Printf("   Endpoint: %s\n", p.Endpoint)
             fmt.Printf("   Capacity: %d/%d (%.0f%% free)\n", p.ActiveJobs, p.Capacity, capacity)
             fmt.Printf("   Completed: %d | Reputation: %s\n", p.TotalCompleted, p.Reputation)
+            if p.Specs != nil {
+                fmt.Printf("   Specs: %s\n", specsSummary(p))
+            }
+            if r, ok := reputations[p.Address]; ok {
+                printReputation(r)
+            }
             
             fmt.Printf("   Services:\n")
             for _, cap := range p.Capabilities {
@@ -76,10 +145,19 @@ var contractListProvidersCmd = &cobra.Command{
 var contractSubmitJobCmd = &cobra.Command{
     Use:   "submit-job",
     Short: "Submit computing job",
+    Long: `Submit a job to the best provider for --criteria.
+
+Providers can be limited to machines whose signed specs meet minimum
+requirements, see "contract specs":
+
+  medasdigital-client contract submit-job --from alice --digits 50000 --require-gpu --min-benchmark 5000`,
     RunE: func(cmd *cobra.Command, args []string) error {
         cfg := loadConfig()  
         
-        contractAddr, _ := cmd.Flags().GetString("contract")
+        contractAddr, err := contractAddress(cmd)
+        if err != nil {
+            return err
+        }
         clientKey, _ := cmd.Flags().GetString("from")
         jobType, _ := cmd.Flags().GetString("type")
         digits, _ := cmd.Flags().GetInt("digits")
@@ -87,6 +165,27 @@ var contractSubmitJobCmd = &cobra.Command{
         criteria, _ := cmd.Flags().GetString("criteria")
         payment, _ := cmd.Flags().GetString("payment")
         simulate, _ := cmd.Flags().GetBool("simulate")
+        verificationStr, _ := cmd.Flags().GetString("verification")
+        
+        if payment == "" {
+            payment = "1000000" + cfg.Chain.BaseDenom
+        }
+        
+        verification, err := compute.ParseVerificationLevel(verificationStr)
+        if err != nil {
+            return err
+        }
+        
+        // Verification kostet extra, Default-Payment entsprechend skalieren
+        multiplier := compute.VerificationMultiplier(verification)
+        if !cmd.Flags().Changed("payment") && multiplier != 1.0 {
+            coin, err := sdk.ParseCoinNormalized(payment)
+            if err != nil {
+                return fmt.Errorf("invalid payment: %w", err)
+            }
+            scaled := coin.Amount.MulRaw(int64(multiplier * 100)).QuoRaw(100)
+            payment = sdk.NewCoin(coin.Denom, scaled).String()
+        }
         
         // Adresse vom Keyring holen
         clientCtx, err := initKeysClientContext()
@@ -108,34 +207,77 @@ var contractSubmitJobCmd = &cobra.Command{
         
         client := contract.NewClient(contract.Config{
             ContractAddress: contractAddr,
-            RPCEndpoint:     defaultRPCEndpoint,
-            ChainID:         defaultChainID,
+            RPCEndpoint:     cfg.Chain.RPCEndpoint,
+            ChainID:         cfg.Chain.ID,
+            FeeGranter:      cfg.Client.FeeGranter,
         }, clientKey, clientAddrStr, cfg.Client.KeyringBackend)  
+        signer, err := contractSigner(cmd, cfg, contractAddr, clientKey, cfg.Client.KeyringBackend)
+        if err != nil {
+            return err
+        }
+        client.WithSigner(signer)
+        
+        params := map[string]interface{}{
+            "digits":       digits,
+            "method":       method,
+            "verification": string(verification),
+        }
+        
+        req := jobRequirements(cmd)
+        redundancy, _ := cmd.Flags().GetInt("redundancy")
+        if redundancy < 1 {
+            return fmt.Errorf("--redundancy must be at least 1")
+        }
+        if redundancy > 1 {
+            fmt.Printf("Finding %d providers...\n", redundancy)
+            providers, err := client.RankProviders(cmd.Context(), jobType, digits, criteria)
+            if err != nil {
+                return err
+            }
+            if providers, err = req.Filter(providers); err != nil {
+                return err
+            }
+            if len(providers) < redundancy {
+                return fmt.Errorf("--redundancy %d needs %d providers for %s, found %d", redundancy, redundancy, jobType, len(providers))
+            }
+            providers = providers[:redundancy]
+            for _, p := range providers {
+                fmt.Printf("Selected: %s (%s MEDAS/digit)\n", p.Name, p.Pricing[jobType].BasePrice)
+            }
+            fmt.Printf("  Verification: %s (x%.2f)\n", verification, multiplier)
+            fmt.Printf("  Payment: %s per provider, held until the results match\n", payment)
+            if simulate {
+                fmt.Println("Simulation mode - not submitting")
+                return nil
+            }
+            return runRedundantJob(cmd.Context(), client, providers, jobType, params, payment)
+        }
         
         fmt.Println("Finding best provider...")
         
-        provider, err := client.FindBestProvider(context.Background(), jobType, digits, criteria)
+        providers, err := client.RankProviders(cmd.Context(), jobType, digits, criteria)
         if err != nil {
             return err
         }
+        if providers, err = req.Filter(providers); err != nil {
+            return err
+        }
+        provider := &providers[0]
         
         fmt.Printf("Selected: %s\n", provider.Name)
         fmt.Printf("  Price: %s MEDAS/digit\n", provider.Pricing[jobType].BasePrice)
+        fmt.Printf("  Verification: %s (x%.2f)\n", verification, multiplier)
+        fmt.Printf("  Payment: %s\n", payment)
         
         if simulate {
             fmt.Println("Simulation mode - not submitting")
             return nil
         }
         
-        params := map[string]interface{}{
-            "digits": digits,
-            "method": method,
-        }
-        
         fmt.Println("Submitting job...")
         
         jobID, txHash, err := client.SubmitJob(
-            context.Background(),
+            cmd.Context(),
             provider.Address,
             jobType,
             params,
@@ -150,7 +292,7 @@ var contractSubmitJobCmd = &cobra.Command{
         fmt.Printf("  TX Hash: %s\n", txHash)
         fmt.Println("\nWaiting for completion...")
         
-        completedJob, err := client.WaitForCompletion(context.Background(), jobID, 10*time.Minute)
+        completedJob, err := client.WaitForCompletion(cmd.Context(), jobID, 10*time.Minute)
         if err != nil {
             fmt.Printf("Check status: contract get-job --job-id %d\n", jobID)
             return err
@@ -168,7 +310,10 @@ var contractGetJobCmd = &cobra.Command{
     RunE: func(cmd *cobra.Command, args []string) error {
         cfg := loadConfig()  // ← HINZUFÜGEN
         
-        contractAddr, _ := cmd.Flags().GetString("contract")
+        contractAddr, err := contractAddress(cmd)
+        if err != nil {
+            return err
+        }
         jobID, _ := cmd.Flags().GetUint64("job-id")
         
         client := contract.NewClient(contract.Config{
@@ -177,22 +322,12 @@ var contractGetJobCmd = &cobra.Command{
             ChainID:         cfg.Chain.ID,
         }, "", "", "") 
         
-        job, err := client.GetJob(context.Background(), jobID)
+        job, err := client.GetJob(cmd.Context(), jobID)
         if err != nil {
             return err
         }
         
-        fmt.Printf("Job #%d\n", job.ID)
-        fmt.Println(strings.Repeat("=", 60))
-        fmt.Printf("Status: %s\n", job.Status)
-        fmt.Printf("Provider: %s\n", job.Provider)
-        fmt.Printf("Type: %s\n", job.JobType)
-        fmt.Printf("Payment: %s umedas\n", job.PaymentAmount)
-        
-        if job.Status == "completed" {
-            fmt.Printf("Result: %s\n", job.ResultURL)
-        }
-        
+        printJob(job, cfg)
         return nil
     },
 }
@@ -205,11 +340,28 @@ var contractCancelJobCmd = &cobra.Command{
     RunE: func(cmd *cobra.Command, args []string) error {
         cfg := loadConfig()
         
-        contractAddr, _ := cmd.Flags().GetString("contract")
+        contractAddr, err := contractAddress(cmd)
+        if err != nil {
+            return err
+        }
         jobID, _ := cmd.Flags().GetUint64("job-id")
         from, _ := cmd.Flags().GetString("from")
         
-        msg := fmt.Sprintf(`{"cancel_job":{"job_id":%d}}`, jobID)
+        cancel := contract.ExecuteMsg{CancelJob: &contract.JobRef{JobID: jobID}}
+        
+        signer, err := contractSigner(cmd, cfg, contractAddr, from, cfg.Client.KeyringBackend)
+        if err != nil {
+            return err
+        }
+        if signer != nil {
+            if _, err := signer.Execute(cmd.Context(), cancel, nil); err != nil {
+                return fmt.Errorf("cancel failed: %w", err)
+            }
+            fmt.Printf("✅ Job #%d cancelled successfully\n", jobID)
+            fmt.Println("Full refund will be processed")
+            return nil
+        }
+        msg := cancel.JSON()
         
         execCmd := exec.Command(
             "medasdigitald", "tx", "wasm", "execute",
@@ -218,7 +370,7 @@ var contractCancelJobCmd = &cobra.Command{
             "--keyring-backend", cfg.Client.KeyringBackend,
             "--gas", "auto",
             "--gas-adjustment", "1.3",
-            "--gas-prices", "0.025umedas",
+            "--gas-prices", cfg.Chain.GasPrice,
             "--node", cfg.Chain.RPCEndpoint,
             "--chain-id", cfg.Chain.ID,
             "-y",
@@ -236,16 +388,272 @@ var contractCancelJobCmd = &cobra.Command{
     },
 }
 
+var contractRefundCmd = &cobra.Command{
+    Use:   "refund [job-id]",
+    Short: "Request and claim the refund of a failed job",
+    Long: `Show the refund state of a job and, with --from, move it forward:
+a failed job is refunded with refund_job, a credited refund is withdrawn with claim_refund.`,
+    Args: cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        cfg := loadConfig()
+        
+        contractAddr, err := contractAddress(cmd)
+        if err != nil {
+            return err
+        }
+        jobID, err := strconv.ParseUint(args[0], 10, 64)
+        if err != nil {
+            return fmt.Errorf("invalid job id %q: %w", args[0], err)
+        }
+        from, _ := cmd.Flags().GetString("from")
+        statusOnly, _ := cmd.Flags().GetBool("status")
+        
+        var clientAddr string
+        if from != "" {
+            clientCtx, err := initKeysClientContext()
+            if err != nil {
+                return fmt.Errorf("failed to init keyring: %w", err)
+            }
+            keyInfo, err := clientCtx.Keyring.Key(from)
+            if err != nil {
+                return fmt.Errorf("key not found: %w", err)
+            }
+            addr, err := keyInfo.GetAddress()
+            if err != nil {
+                return fmt.Errorf("failed to get address: %w", err)
+            }
+            clientAddr = addr.String()
+        }
+        
+        client := contract.NewClient(contract.Config{
+            ContractAddress: contractAddr,
+            RPCEndpoint:     cfg.Chain.RPCEndpoint,
+            ChainID:         cfg.Chain.ID,
+            FeeGranter:      cfg.Client.FeeGranter,
+        }, from, clientAddr, cfg.Client.KeyringBackend)
+        if from != "" {
+            signer, err := contractSigner(cmd, cfg, contractAddr, from, cfg.Client.KeyringBackend)
+            if err != nil {
+                return err
+            }
+            client.WithSigner(signer)
+        }
+        
+        ctx := cmd.Context()
+        refund, err := client.GetRefund(ctx, jobID)
+        if err != nil {
+            return err
+        }
+        printRefund(refund, cfg)
+        
+        if statusOnly {
+            return nil
+        }
+        if from == "" {
+            if refund.Status == contract.RefundStatusEligible || refund.Status == contract.RefundStatusClaimable {
+                fmt.Printf("\n💡 Run again with --from <key> to claim it\n")
+            }
+            return nil
+        }
+        if refund.Client != "" && refund.Client != clientAddr {
+            return fmt.Errorf("job #%d belongs to %s, not %s", jobID, refund.Client, clientAddr)
+        }
+        
+        switch refund.Status {
+        case contract.RefundStatusEligible:
+            fmt.Println("\nRequesting refund...")
+            txHash, err := client.RefundJob(ctx, jobID)
+            if err != nil {
+                return fmt.Errorf("refund_job failed: %w", err)
+            }
+            fmt.Printf("✅ refund_job sent: %s\n", txHash)
+            
+            // Contracts that credit instead of paying out need a claim afterwards
+            time.Sleep(6 * time.Second)
+            refund, err = client.GetRefund(ctx, jobID)
+            if err != nil || refund.Status != contract.RefundStatusClaimable {
+                return nil
+            }
+            fallthrough
+        case contract.RefundStatusClaimable:
+            fmt.Println("\nClaiming refund...")
+            txHash, err := client.ClaimRefund(ctx, jobID)
+            if err != nil {
+                return fmt.Errorf("claim_refund failed: %w", err)
+            }
+            fmt.Printf("✅ claim_refund sent: %s\n", txHash)
+        case contract.RefundStatusClaimed:
+            fmt.Println("\nNothing to do, the refund was already paid out")
+        default:
+            return fmt.Errorf("job #%d is not refundable", jobID)
+        }
+        return nil
+    },
+}
+
+var contractReleaseCmd = &cobra.Command{
+    Use:   "release [job-id]",
+    Short: "Release the held payment of a completed job to its provider",
+    Long: `Pay out the escrow of a job submitted with held payment (submit-job --redundancy)
+after checking its result yourself.`,
+    Args: cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        client, job, err := heldJob(cmd, args[0])
+        if err != nil {
+            return err
+        }
+        if job.Status != contract.JobStatusCompleted {
+            return fmt.Errorf("job #%d is %s, only completed jobs can be released", job.ID, job.Status)
+        }
+        txHash, err := client.ReleasePayment(cmd.Context(), job.ID)
+        if err != nil {
+            return fmt.Errorf("release_payment failed: %w", err)
+        }
+        fmt.Printf("✅ Payment of job #%d released to %s: %s\n", job.ID, job.Provider, txHash)
+        return nil
+    },
+}
+
+var contractDisputeCmd = &cobra.Command{
+    Use:   "dispute [job-id]",
+    Short: "Dispute the result of a job with held payment",
+    Long: `Flag the result of a job submitted with held payment as wrong. The escrow is
+not paid out to the provider while the dispute is open.`,
+    Args: cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        client, job, err := heldJob(cmd, args[0])
+        if err != nil {
+            return err
+        }
+        if job.Status != contract.JobStatusCompleted {
+            return fmt.Errorf("job #%d is %s, only completed jobs can be disputed", job.ID, job.Status)
+        }
+        reason, _ := cmd.Flags().GetString("reason")
+        txHash, err := client.DisputeJob(cmd.Context(), job.ID, reason)
+        if err != nil {
+            return fmt.Errorf("dispute_job failed: %w", err)
+        }
+        fmt.Printf("⚠ Job #%d disputed: %s\n", job.ID, txHash)
+        return nil
+    },
+}
+
+// heldJob loads a job for release or dispute and checks it belongs to --from
+func heldJob(cmd *cobra.Command, arg string) (*contract.Client, *contract.ContractJob, error) {
+    cfg := loadConfig()
+    contractAddr, err := contractAddress(cmd)
+    if err != nil {
+        return nil, nil, err
+    }
+    jobID, err := strconv.ParseUint(arg, 10, 64)
+    if err != nil {
+        return nil, nil, fmt.Errorf("invalid job id %q: %w", arg, err)
+    }
+    from, _ := cmd.Flags().GetString("from")
+    
+    clientCtx, err := initKeysClientContext()
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to init keyring: %w", err)
+    }
+    keyInfo, err := clientCtx.Keyring.Key(from)
+    if err != nil {
+        return nil, nil, fmt.Errorf("key not found: %w", err)
+    }
+    addr, err := keyInfo.GetAddress()
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to get address: %w", err)
+    }
+    
+    client := contract.NewClient(contract.Config{
+        ContractAddress: contractAddr,
+        RPCEndpoint:     cfg.Chain.RPCEndpoint,
+        ChainID:         cfg.Chain.ID,
+        FeeGranter:      cfg.Client.FeeGranter,
+    }, from, addr.String(), cfg.Client.KeyringBackend)
+    signer, err := contractSigner(cmd, cfg, contractAddr, from, cfg.Client.KeyringBackend)
+    if err != nil {
+        return nil, nil, err
+    }
+    client.WithSigner(signer)
+    
+    job, err := client.GetJob(cmd.Context(), jobID)
+    if err != nil {
+        return nil, nil, err
+    }
+    if job.Client != "" && job.Client != addr.String() {
+        return nil, nil, fmt.Errorf("job #%d belongs to %s, not %s", jobID, job.Client, addr.String())
+    }
+    job.ID = jobID
+    return client, job, nil
+}
+
+func printRefund(refund *contract.Refund, cfg *Config) {
+    fmt.Printf("Refund for Job #%d\n", refund.JobID)
+    fmt.Println(strings.Repeat("=", 60))
+    fmt.Printf("Status: %s\n", refund.Status)
+    if refund.Client != "" {
+        fmt.Printf("Client: %s\n", refund.Client)
+    }
+    if refund.Amount != "" {
+        fmt.Printf("Amount: %s %s\n", refund.Amount, cfg.Chain.BaseDenom)
+    }
+    if refund.Reason != "" {
+        fmt.Printf("Reason: %s\n", refund.Reason)
+    }
+    if refund.ClaimedAt != "" {
+        fmt.Printf("Claimed: %s\n", refund.ClaimedAt)
+    }
+}
+
+var contractSpecsCmd = &cobra.Command{
+    Use:   "specs",
+    Short: "Show the machine specs this host would advertise as provider",
+    Long: `Run the reference PI benchmark and show the cores, memory, GPU and benchmark
+score that provider-node --register advertises. At registration the specs are
+signed with the provider key, clients filter on them with submit-job
+--require-gpu, --min-benchmark, --min-cores and --min-memory.`,
+    RunE: func(cmd *cobra.Command, args []string) error {
+        specs, err := hostSpecs("")
+        if err != nil {
+            return fmt.Errorf("benchmark failed: %w", err)
+        }
+        fmt.Printf("Cores:      %d\n", specs.Cores)
+        fmt.Printf("Memory:     %d MB\n", specs.MemoryMB)
+        if specs.GPUModel != "" {
+            fmt.Printf("GPU:        %s (%d MB)\n", specs.GPUModel, specs.GPUMemoryMB)
+        } else {
+            fmt.Println("GPU:        none")
+        }
+        fmt.Printf("Benchmark:  %.0f digits/s (%d digits of PI in %v) ✅ verified\n",
+            specs.Benchmark.Score, specs.Benchmark.Digits, specs.Benchmark.Duration.Round(time.Millisecond))
+        return nil
+    },
+}
+
 var contractHeartbeatCmd = &cobra.Command{
     Use:   "heartbeat",
     Short: "Send manual heartbeat (v2.0)",
     RunE: func(cmd *cobra.Command, args []string) error {
         cfg := loadConfig()
         
-        contractAddr, _ := cmd.Flags().GetString("contract")
+        contractAddr, err := contractAddress(cmd)
+        if err != nil {
+            return err
+        }
         from, _ := cmd.Flags().GetString("from")
         
-        msg := `{"heart_beat":{}}`
+        signer, err := contractSigner(cmd, cfg, contractAddr, from, cfg.Provider.KeyringBackend)
+        if err != nil {
+            return err
+        }
+        if signer != nil {
+            if _, err := signer.HeartBeat(cmd.Context()); err != nil {
+                return fmt.Errorf("heartbeat failed: %w", err)
+            }
+            fmt.Println("💓 Heartbeat sent successfully")
+            return nil
+        }
+        msg := contract.ExecuteMsg{HeartBeat: &struct{}{}}.JSON()
         
         execCmd := exec.Command(
             "medasdigitald", "tx", "wasm", "execute",
@@ -254,7 +662,7 @@ var contractHeartbeatCmd = &cobra.Command{
             "--keyring-backend", cfg.Provider.KeyringBackend,
             "--gas", "auto",
             "--gas-adjustment", "1.3",
-            "--gas-prices", "0.025umedas",
+            "--gas-prices", cfg.Chain.GasPrice,
             "--node", cfg.Chain.RPCEndpoint,
             "--chain-id", cfg.Chain.ID,
             "-y",
@@ -276,7 +684,10 @@ var contractConfigCmd = &cobra.Command{
     RunE: func(cmd *cobra.Command, args []string) error {
         cfg := loadConfig()
         
-        contractAddr, _ := cmd.Flags().GetString("contract")
+        contractAddr, err := contractAddress(cmd)
+        if err != nil {
+            return err
+        }
         
         query := `{"get_config":{}}`
         
@@ -299,6 +710,7 @@ var contractConfigCmd = &cobra.Command{
                 DefaultJobTimeout     int    `json:"default_job_timeout"`
                 HeartbeatTimeout      int    `json:"heartbeat_timeout"`
                 Paused                bool   `json:"paused"`
+                Successor             string `json:"successor"`
             } `json:"data"`
         }
         
@@ -314,6 +726,9 @@ var contractConfigCmd = &cobra.Command{
         fmt.Printf("Heartbeat Timeout: %d seconds (%d hours)\n", 
             result.Data.HeartbeatTimeout, result.Data.HeartbeatTimeout/3600)
         fmt.Printf("Contract Paused: %v\n", result.Data.Paused)
+        if result.Data.Successor != "" {
+            fmt.Printf("Migrated To: %s\n", result.Data.Successor)
+        }
         
         return nil
     },
@@ -323,7 +738,10 @@ var contractProviderNodeCmd = &cobra.Command{
     Short: "Run provider node",
     Long:  "Start provider node to process computing jobs",
     RunE: func(cmd *cobra.Command, args []string) error {
-        contractAddr, _ := cmd.Flags().GetString("contract")
+        contractAddr, err := contractAddress(cmd)
+        if err != nil {
+            return err
+        }
         register, _ := cmd.Flags().GetBool("register")
         
         // Load config
@@ -333,8 +751,8 @@ var contractProviderNodeCmd = &cobra.Command{
             return fmt.Errorf("provider not enabled in config. Set provider.enabled: true")
         }
         
-        if cfg.Provider.FundingAddress == "" {
-            fmt.Println("⚠️  Warning: No funding_address set - auto-harvest disabled")
+        if cfg.Provider.FundingAddress == "" && len(cfg.Provider.Harvest) == 0 {
+            fmt.Println("⚠️  Warning: No funding_address or provider.harvest set - auto-harvest disabled")
         }
         
         // Get provider address from key
@@ -351,12 +769,21 @@ var contractProviderNodeCmd = &cobra.Command{
         fmt.Printf("=== Provider Node v2.0 ===\n")  // ADD
         fmt.Printf("Provider Address: %s\n", providerAddr)
         fmt.Printf("Contract (v2.0): %s\n", contractAddr)  // ADD
+        extraContracts := cfg.Provider.Contracts
+        if cmd.Flags().Changed("contracts") {
+            extraContracts, _ = cmd.Flags().GetStringSlice("contracts")
+        }
+        for _, addr := range extraContracts {
+            fmt.Printf("Also serving:    %s\n", addr)
+        }
         fmt.Printf("Heartbeat: every %d minutes\n", cfg.Provider.HeartbeatIntervalMinutes)  // ADD
         
         if register {
-            fmt.Println("Registering provider...")
-            if err := registerProvider(cfg, contractAddr, providerAddr); err != nil {
-                return fmt.Errorf("registration failed: %w", err)
+            for _, addr := range append([]string{contractAddr}, extraContracts...) {
+                fmt.Printf("Registering provider on %s...\n", addr)
+                if err := registerProvider(cfg, addr, providerAddr); err != nil {
+                    return fmt.Errorf("registration on %s failed: %w", addr, err)
+                }
             }
             fmt.Println("✅ Provider registered")
             time.Sleep(5 * time.Second)
@@ -379,16 +806,219 @@ var contractProviderNodeCmd = &cobra.Command{
     cfg.Provider.HarvestIntervalHours,
     cfg.Provider.HeartbeatIntervalMinutes,  // ADD THIS!
 )
+    for _, addr := range extraContracts {
+        node.AddContract(addr)
+    }
+    store, err := contract.OpenJobStore(cfg.Provider.JobStore)
+    if err != nil {
+        return err
+    }
+    defer store.Close()
+    node.SetJobStore(store)
+    if len(cfg.Provider.Harvest) > 0 {
+        if err := node.SetHarvestRules(cfg.Provider.Harvest); err != nil {
+            return fmt.Errorf("invalid provider.harvest: %w", err)
+        }
+    }
     fmt.Println("\n🚀 Starting with v2.0 features:")
     fmt.Println("  ✅ Automatic heartbeat every", cfg.Provider.HeartbeatIntervalMinutes, "minutes")
+    if len(extraContracts) > 0 {
+        fmt.Printf("  ✅ Serving %d contracts with separate subscriptions, heartbeats and accounting\n", len(extraContracts)+1)
+    }
     fmt.Println("  ✅ WebSocket auto-reconnection")
     fmt.Println("  ✅ Job failure handling with refunds")
-    fmt.Println("  ✅ Balance auto-harvesting")
+    fmt.Printf("  ✅ Jobs and results persisted in %s, recovered after a restart\n", store.Dir())
+    if len(cfg.Provider.Harvest) > 0 {
+        fmt.Printf("  ✅ Balance auto-harvesting (%d denoms)\n", len(cfg.Provider.Harvest))
+    } else if cfg.Provider.FundingAddress != "" {
+        fmt.Println("  ✅ Balance auto-harvesting")
+    }
+    if c, err := analysisClient(); err == nil && c.GPUScheduler() != nil {
+        scheduler := c.GPUScheduler()
+        node.SetGPUScheduler(scheduler)
+        fmt.Printf("  ✅ GPU scheduling (memory limit %d MB per device)\n", cfg.GPU.MemoryLimit)
+    }
+    sandboxCfg, err := sandboxConfig(cmd, cfg)
+    if err != nil {
+        return err
+    }
+    if sandboxCfg.Runtime != "" && sandboxCfg.Runtime != compute.SandboxNone {
+        sandbox, err := compute.NewSandbox(sandboxCfg)
+        if err != nil {
+            return fmt.Errorf("failed to set up sandbox: %w", err)
+        }
+        node.SetSandbox(sandbox)
+        sc := sandbox.Config()
+        fmt.Printf("  ✅ %s sandbox (%s, %.1f CPUs, %d MB, %v, no network)\n", sc.Runtime, sc.Image, sc.CPUs, sc.MemoryMB, sc.Timeout)
+    } else {
+        fmt.Println("  ⚠️  Jobs run unsandboxed on this host (use --sandbox docker for third-party workloads)")
+    }
+    biddingCfg := biddingConfig(cmd, cfg)
+    if biddingCfg.Enabled {
+        if err := node.SetBidding(biddingCfg); err != nil {
+            return fmt.Errorf("failed to set up bidding: %w", err)
+        }
+        mode := ""
+        if biddingCfg.DryRun {
+            mode = " (dry-run)"
+        }
+        fmt.Printf("  ✅ Auto-bidding on open jobs%s, floor %d %s\n", mode, biddingCfg.PriceFloor, cfg.Chain.BaseDenom)
+    }
+    if sign, err := attestationSigner(cfg.Provider.KeyName, cfg.Provider.KeyringBackend, providerAddr); err != nil {
+        fmt.Printf("  ⚠️  Results are not attested: %v\n", err)
+    } else {
+        node.SetAttestationSigner(sign)
+        fmt.Println("  ✅ Signed result attestations")
+    }
+    successor := cfg.Contract.Successor
+    if cmd.Flags().Changed("successor") {
+        successor, _ = cmd.Flags().GetString("successor")
+    }
+    if err := node.SetMigration(contract.MigrationConfig{
+        Successor: successor,
+        Register: func(ctx context.Context, contractAddr string) error {
+            return registerProvider(cfg, contractAddr, providerAddr)
+        },
+        OnMigrated: func(from, to string) {
+            if err := saveMigratedContract(from, to); err != nil {
+                log.Printf("⚠️ Replace %s with %s in the config, saving it failed: %v", from, to, err)
+                return
+            }
+            log.Printf("Config updated: %s replaced by %s", from, to)
+        },
+    }); err != nil {
+        return err
+    }
+    if successor != "" {
+        fmt.Printf("  ✅ Migrates to successor contract %s once %s is paused\n", successor, contractAddr)
+    } else {
+        fmt.Println("  ✅ Follows a contract migration announced in get_config")
+    }
+    adminToken := cfg.Provider.AdminToken
+    if cmd.Flags().Changed("admin-token") {
+        adminToken, _ = cmd.Flags().GetString("admin-token")
+    }
+    if adminToken != "" {
+        node.SetAdminToken(adminToken)
+        fmt.Printf("  ✅ Admin API on :%d/admin (jobs, retry, pause)\n", cfg.Provider.Port)
+    }
     fmt.Println("")
-        return node.Start(context.Background())
+        return node.Start(cmd.Context())
     },
 }
 
+// saveMigratedContract replaces a migrated contract in the config, so a
+// restarted node does not go back to the old one
+func saveMigratedContract(from, to string) error {
+    doc, err := readConfigDoc(cfgFile)
+    if err != nil {
+        return err
+    }
+    extra := yamlLookup(doc, []string{"provider", "contracts"})
+    if extra != nil && extra.Kind != yaml.SequenceNode {
+        extra = nil
+    }
+
+    // A further contract is replaced in provider.contracts, the primary in contract.address
+    primary := true
+    if extra != nil {
+        for _, item := range extra.Content {
+            if item.Value == from {
+                item.Value, primary = to, false
+            }
+        }
+    }
+    if primary {
+        yamlSet(doc, []string{"contract", "address"}, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: to})
+        yamlDelete(doc, []string{"contract", "successor"})
+    }
+
+    // The successor may have been served already, keep it listed once
+    if extra != nil {
+        seen := map[string]bool{}
+        if address := yamlLookup(doc, []string{"contract", "address"}); address != nil {
+            seen[address.Value] = true
+        }
+        kept := extra.Content[:0]
+        for _, item := range extra.Content {
+            if !seen[item.Value] {
+                seen[item.Value] = true
+                kept = append(kept, item)
+            }
+        }
+        extra.Content = kept
+    }
+
+    data, err := encodeConfigDoc(doc)
+    if err != nil {
+        return err
+    }
+    return writeConfigFile(cfgFile, data)
+}
+
+// contractSigner returns a native signer for --native, nil means medasdigitald signs.
+// --dry-run always signs natively, only then the transaction can be simulated.
+func contractSigner(cmd *cobra.Command, cfg *Config, contractAddr, from, keyringBackend string) (*contract.ContractClient, error) {
+    native, _ := cmd.Flags().GetBool("native")
+    if !native && !dryRun {
+        return nil, nil
+    }
+    clientCtx, err := signingClientContext(cmd.Context(), from, keyringBackend)
+    if err != nil {
+        return nil, err
+    }
+    return contract.NewContractClient(clientCtx, contractAddr, cfg.Chain.GasPrice), nil
+}
+
+// biddingConfig applies the --auto-bid flags over provider.bidding from the config
+func biddingConfig(cmd *cobra.Command, cfg *Config) contract.BiddingConfig {
+    bc := cfg.Provider.Bidding
+    flags := cmd.Flags()
+    if flags.Changed("auto-bid") {
+        bc.Enabled, _ = flags.GetBool("auto-bid")
+    }
+    if flags.Changed("bid-dry-run") {
+        bc.DryRun, _ = flags.GetBool("bid-dry-run")
+        // --bid-dry-run alone is enough to watch what the bidder would do
+        bc.Enabled = bc.Enabled || bc.DryRun
+    }
+    if flags.Changed("bid-strategy") {
+        bc.Strategy, _ = flags.GetString("bid-strategy")
+    }
+    if flags.Changed("bid-price-floor") {
+        bc.PriceFloor, _ = flags.GetUint64("bid-price-floor")
+    }
+    return bc
+}
+
+// sandboxConfig applies the --sandbox flags over provider.sandbox from the config
+func sandboxConfig(cmd *cobra.Command, cfg *Config) (compute.SandboxConfig, error) {
+    sc := cfg.Provider.Sandbox
+    flags := cmd.Flags()
+    if flags.Changed("sandbox") {
+        sc.Runtime, _ = flags.GetString("sandbox")
+    }
+    if flags.Changed("sandbox-image") {
+        sc.Image, _ = flags.GetString("sandbox-image")
+    }
+    if flags.Changed("sandbox-cpus") {
+        sc.CPUs, _ = flags.GetFloat64("sandbox-cpus")
+    }
+    if flags.Changed("sandbox-memory") {
+        sc.MemoryMB, _ = flags.GetInt("sandbox-memory")
+    }
+    if flags.Changed("sandbox-timeout") {
+        sc.Timeout, _ = flags.GetDuration("sandbox-timeout")
+    }
+    
+    switch sc.Runtime {
+    case "", compute.SandboxNone, compute.SandboxDocker, compute.SandboxPodman:
+        return sc, nil
+    default:
+        return sc, fmt.Errorf("invalid --sandbox %q (none, docker, podman)", sc.Runtime)
+    }
+}
+
 func getProviderAddressFromKey(keyName string) (string, error) {
     clientCtx, err := initKeysClientContext()
     if err != nil {
@@ -408,24 +1038,204 @@ func getProviderAddressFromKey(keyName string) (string, error) {
     return addr.String(), nil
 }
 
-func registerProvider(cfg *Config, contractAddr, providerAddr string) error {
-    msg := fmt.Sprintf(`{
-        "register_provider": {
-            "name": "MEDAS Provider Node",
-            "capabilities": [{
-                "service_type": "pi_calculation",
-                "max_complexity": 100000,
-                "avg_completion_time": 180
-            }],
-            "pricing": {
-                "pi_calculation": {
-                    "base_price": "0.0001",
-                    "unit": "digit"
-                }
-            },
-            "endpoint": "%s"
+// printReputation shows the aggregated on-chain history of a provider
+func printReputation(r contract.ProviderReputation) {
+    if r.Error != "" && r.Score == 0 {
+        fmt.Printf("   History: ⚠️  %s\n", r.Error)
+        return
+    }
+    fmt.Printf("   History: %d completed, %d failed, %d disputed (%.0f%% success)",
+        r.Completed, r.Failed, r.Disputed, r.SuccessRate*100)
+    if r.AvgTurnaround > 0 {
+        fmt.Printf(", avg turnaround %v", time.Duration(r.AvgTurnaround*float64(time.Second)).Round(time.Second))
+    }
+    fmt.Println()
+    if r.HeartbeatUptime != nil {
+        last := "never"
+        if r.LastHeartbeat != nil {
+            last = time.Since(*r.LastHeartbeat).Round(time.Minute).String() + " ago"
+        }
+        fmt.Printf("   Heartbeat uptime: %.0f%% (last %s)\n", *r.HeartbeatUptime*100, last)
+    } else if r.Error != "" {
+        fmt.Printf("   Heartbeat uptime: unknown (%s)\n", r.Error)
+    }
+    fmt.Printf("   Score: %.1f\n", r.Score)
+}
+
+// contractAddress returns the --contract flag or the configured contract for the network
+func contractAddress(cmd *cobra.Command) (string, error) {
+    addr, _ := cmd.Flags().GetString("contract")
+    if addr == "" {
+        addr = loadConfig().Contract.Address
+    }
+    if addr == "" {
+        return "", fmt.Errorf("no contract address for this network, set contract.address in the config or use --contract")
+    }
+    return addr, nil
+}
+
+// runRedundantJob submits the same job to every provider with the payment
+// held in escrow, compares the results and releases the payments when they
+// match or disputes the jobs when they differ
+func runRedundantJob(ctx context.Context, client *contract.Client, providers []contract.Provider, jobType string, params map[string]interface{}, payment string) error {
+    fmt.Println("Submitting jobs...")
+    var jobs []contract.RedundantJob
+    for _, p := range providers {
+        jobID, txHash, err := client.SubmitHeldJob(ctx, p.Address, jobType, params, payment)
+        if err != nil {
+            fmt.Printf("\n❌ Submission to %s failed: %v\n", p.Name, err)
+            continue
         }
-    }`, cfg.Provider.Endpoint)
+        fmt.Printf("\n  Job %d → %s (tx %s)\n", jobID, p.Name, txHash)
+        jobs = append(jobs, contract.RedundantJob{JobID: jobID, Provider: p.Address, TxHash: txHash, Status: contract.JobStatusSubmitted})
+    }
+    if len(jobs) == 0 {
+        return fmt.Errorf("no job could be submitted")
+    }
+    
+    fmt.Println("\nWaiting for completion...")
+    v := client.CrossValidate(ctx, jobType, jobs, 10*time.Minute)
+    for _, j := range v.Jobs {
+        if j.ContentHash != "" {
+            fmt.Printf("  Job %d: %s, result %s\n", j.JobID, j.Status, j.ContentHash[:16])
+        } else {
+            fmt.Printf("  Job %d: %s, %s\n", j.JobID, j.Status, j.Error)
+        }
+    }
+    
+    switch {
+    case v.Agreed():
+        fmt.Printf("\n✅ All %d results match, releasing payments\n", len(v.Jobs))
+        for _, j := range v.Jobs {
+            txHash, err := client.ReleasePayment(ctx, j.JobID)
+            if err != nil {
+                return fmt.Errorf("release_payment for job %d failed: %w", j.JobID, err)
+            }
+            fmt.Printf("  Job %d released: %s\n", j.JobID, txHash)
+        }
+        return nil
+    case v.Mismatch():
+        reason := v.Reason()
+        fmt.Printf("\n❌ Results differ, disputing the jobs: %s\n", reason)
+        for _, j := range v.Completed() {
+            txHash, err := client.DisputeJob(ctx, j.JobID, reason)
+            if err != nil {
+                fmt.Printf("  ⚠ dispute_job for job %d failed: %v\n", j.JobID, err)
+                continue
+            }
+            fmt.Printf("  Job %d disputed: %s\n", j.JobID, txHash)
+        }
+        return fmt.Errorf("redundant results differ, payments withheld")
+    default:
+        fmt.Println("\n⚠ Not enough results to cross-check, the payments stay held")
+        for _, j := range v.Completed() {
+            fmt.Printf("  contract release %d --from <key>   or   contract dispute %d --from <key>\n", j.JobID, j.JobID)
+        }
+        fmt.Println("  Failed jobs are refundable with: contract refund <job-id> --from <key>")
+        return fmt.Errorf("cross-check incomplete")
+    }
+}
+
+// hostSpecs measures the machine a provider advertises, including the
+// reference PI benchmark
+func hostSpecs(providerAddr string) (*contract.MachineSpecs, error) {
+    bench, err := compute.RunPIBenchmark()
+    if err != nil {
+        return nil, err
+    }
+    specs := &contract.MachineSpecs{
+        Provider:  providerAddr,
+        Cores:     runtime.NumCPU(),
+        MemoryMB:  hostMemoryMB(),
+        Benchmark: bench,
+    }
+    if devices := gpu.Detect(); len(devices) > 0 {
+        specs.GPUModel = devices[0].Name
+        specs.GPUMemoryMB = devices[0].MemoryTotal / 1024 / 1024
+    }
+    return specs, nil
+}
+
+// hostMemoryMB reads the installed memory, 0 where /proc/meminfo is missing
+func hostMemoryMB() int64 {
+    data, err := os.ReadFile("/proc/meminfo")
+    if err != nil {
+        return 0
+    }
+    for _, line := range strings.Split(string(data), "\n") {
+        if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "MemTotal:" {
+            kb, _ := strconv.ParseInt(fields[1], 10, 64)
+            return kb / 1024
+        }
+    }
+    return 0
+}
+
+// specsSummary renders advertised specs for listings
+func specsSummary(p contract.Provider) string {
+    s := p.Specs
+    summary := fmt.Sprintf("%d cores, %d MB", s.Cores, s.MemoryMB)
+    if s.GPUModel != "" {
+        summary += fmt.Sprintf(", %s (%d MB)", s.GPUModel, s.GPUMemoryMB)
+    }
+    if s.Benchmark != nil {
+        summary += fmt.Sprintf(", benchmark %.0f", s.Benchmark.Score)
+    }
+    if s.Provider != p.Address {
+        return summary + " ⚠️  specs of another account"
+    }
+    if err := s.Verify(); err != nil {
+        return summary + " ⚠️  " + err.Error()
+    }
+    return summary + " ✅ signed"
+}
+
+// jobRequirements reads the minimum provider specs of submit-job
+func jobRequirements(cmd *cobra.Command) contract.Requirements {
+    var req contract.Requirements
+    req.GPU, _ = cmd.Flags().GetBool("require-gpu")
+    req.MinBenchmark, _ = cmd.Flags().GetFloat64("min-benchmark")
+    req.MinCores, _ = cmd.Flags().GetInt("min-cores")
+    req.MinMemoryMB, _ = cmd.Flags().GetInt64("min-memory")
+    return req
+}
+
+func registerProvider(cfg *Config, contractAddr, providerAddr string) error {
+    fmt.Println("Measuring machine specs (PI benchmark)...")
+    specs, err := hostSpecs(providerAddr)
+    if err != nil {
+        return fmt.Errorf("benchmark failed: %w", err)
+    }
+    sign, err := attestationSigner(cfg.Provider.KeyName, cfg.Provider.KeyringBackend, providerAddr)
+    if err == nil {
+        err = specs.Sign(sign)
+    }
+    if err != nil {
+        // Unsigned specs would not pass any client's requirements
+        fmt.Printf("⚠️  Registering without machine specs, cannot sign them: %v\n", err)
+        specs = nil
+    } else {
+        fmt.Printf("Specs: %s\n", specsSummary(contract.Provider{Address: providerAddr, Specs: specs}))
+    }
+    
+    msg := contract.ExecuteMsg{RegisterProvider: &contract.RegisterProviderMsg{
+        Name: "MEDAS Provider Node",
+        Capabilities: []contract.Capability{{
+            ServiceType:       "pi_calculation",
+            MaxComplexity:     100000,
+            AvgCompletionTime: 180,
+        }, {
+            ServiceType:       planet9.JobType,
+            MaxComplexity:     10000,
+            AvgCompletionTime: 1800,
+        }},
+        Pricing: map[string]contract.PriceInfo{
+            "pi_calculation": {BasePrice: "0.0001", Unit: "digit"},
+            planet9.JobType:  {BasePrice: fmt.Sprint(compute.Planet9PricePerPointKyr), Unit: "point_kyr"},
+        },
+        Endpoint: cfg.Provider.Endpoint,
+        Specs:    specs,
+    }}.JSON()
     
     cmd := exec.Command(
         "medasdigitald", "tx", "wasm", "execute",
@@ -433,7 +1243,7 @@ func registerProvider(cfg *Config, contractAddr, providerAddr string) error {
         "--from", cfg.Provider.KeyName,
         "--keyring-backend", cfg.Provider.KeyringBackend,
         "--gas", "300000",
-        "--fees", "80000umedas",
+        "--fees", "80000"+cfg.Chain.BaseDenom,
         "--node", cfg.Chain.RPCEndpoint,
         "--chain-id", cfg.Chain.ID,
         "-y",
@@ -455,19 +1265,31 @@ func init() {
     contractCmd.AddCommand(contractCancelJobCmd)      // ADD
     contractCmd.AddCommand(contractConfigCmd)  
     contractCmd.AddCommand(contractHeartbeatCmd)      // ADD
+    contractCmd.AddCommand(contractSpecsCmd)
     contractCmd.AddCommand(contractProviderNodeCmd)
+    contractCmd.AddCommand(contractRefundCmd)
+    contractCmd.AddCommand(contractReleaseCmd)
+    contractCmd.AddCommand(contractDisputeCmd)
     
-    contractCmd.PersistentFlags().String("contract",
-    "medas1xr3rq8yvd7qplsw5yx90ftsr2zdhg4e9z60h5duusgxpv72hud3s3cca97",
-    "Contract address")
+    contractCmd.PersistentFlags().String("contract", "", "Contract address (default: contract.address or the network default)")
+    contractCmd.PersistentFlags().Bool("native", false, "Sign and broadcast with the built-in keyring instead of medasdigitald")
+    
+    contractListProvidersCmd.Flags().String("sort", "", "Sort providers: reputation, completed, capacity (default: contract order)")
+    contractListProvidersCmd.Flags().Duration("window", contract.DefaultReputationWindow, "Heartbeat history used for the uptime with --sort reputation")
     
     contractSubmitJobCmd.Flags().String("from", "", "Client key (required)")
     contractSubmitJobCmd.Flags().String("type", "pi_calculation", "Job type")
     contractSubmitJobCmd.Flags().Int("digits", 1000, "Digits")
     contractSubmitJobCmd.Flags().String("method", "chudnovsky", "Method")
     contractSubmitJobCmd.Flags().String("criteria", "price", "Selection criteria")
-    contractSubmitJobCmd.Flags().String("payment", "1000000umedas", "Payment")
+    contractSubmitJobCmd.Flags().String("payment", "", "Payment (default: 1000000 in the chain base denom)")
     contractSubmitJobCmd.Flags().Bool("simulate", false, "Simulate only")
+    contractSubmitJobCmd.Flags().String("verification", "none", "Result verification level (none, spot-check, full-recompute, commitment)")
+    contractSubmitJobCmd.Flags().Int("redundancy", 1, "Run the job on this many providers and release payment only if the results match")
+    contractSubmitJobCmd.Flags().Bool("require-gpu", false, "Only use providers with a GPU in their signed specs")
+    contractSubmitJobCmd.Flags().Float64("min-benchmark", 0, "Minimum PI benchmark score (digits per second) of the provider")
+    contractSubmitJobCmd.Flags().Int("min-cores", 0, "Minimum CPU cores of the provider")
+    contractSubmitJobCmd.Flags().Int64("min-memory", 0, "Minimum memory of the provider in MB")
     contractSubmitJobCmd.MarkFlagRequired("from")
     
     contractGetJobCmd.Flags().Uint64("job-id", 0, "Job ID (required)")
@@ -484,6 +1306,28 @@ func init() {
     // contractProviderNodeCmd.MarkFlagRequired("endpoint")
 
     contractProviderNodeCmd.Flags().Bool("register", false, "Register provider first")
+    contractProviderNodeCmd.Flags().String("sandbox", "none", "Run jobs in a container: none, docker, podman")
+    contractProviderNodeCmd.Flags().String("sandbox-image", compute.DefaultSandboxImage, "Container image for sandboxed jobs")
+    contractProviderNodeCmd.Flags().Float64("sandbox-cpus", 1, "CPU limit per sandboxed job")
+    contractProviderNodeCmd.Flags().Int("sandbox-memory", 2048, "Memory limit per sandboxed job in MB")
+    contractProviderNodeCmd.Flags().Duration("sandbox-timeout", 30*time.Minute, "Time limit per sandboxed job")
+    contractProviderNodeCmd.Flags().Bool("auto-bid", false, "Bid on or accept open market jobs")
+    contractProviderNodeCmd.Flags().Bool("bid-dry-run", false, "Only log the bidding decisions")
+    contractProviderNodeCmd.Flags().String("bid-strategy", contract.BidStrategyAccept, "Bidding strategy: accept, bid")
+    contractProviderNodeCmd.Flags().Uint64("bid-price-floor", 0, "Minimum payment for open jobs in the base denom")
+    contractProviderNodeCmd.Flags().String("successor", "", "Successor contract to migrate to (default: contract.successor)")
+    contractProviderNodeCmd.Flags().StringSlice("contracts", nil, "Further marketplace contracts to serve (default: provider.contracts)")
+    contractProviderNodeCmd.Flags().String("admin-token", "", "Bearer token for the /admin endpoints (default: provider.admin_token, disabled if empty)")
+
+    contractRefundCmd.Flags().String("from", "", "Client key that paid for the job")
+    contractRefundCmd.Flags().Bool("status", false, "Only show the refund state")
+    
+    for _, c := range []*cobra.Command{contractReleaseCmd, contractDisputeCmd} {
+        c.Flags().String("from", "", "Client key that paid for the job (required)")
+        c.MarkFlagRequired("from")
+    }
+    contractDisputeCmd.Flags().String("reason", "", "Why the result is wrong (required)")
+    contractDisputeCmd.MarkFlagRequired("reason")
 
     // Cancel job flags
     contractCancelJobCmd.Flags().Uint64("job-id", 0, "Job ID (required)")
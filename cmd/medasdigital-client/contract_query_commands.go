@@ -0,0 +1,227 @@
+package main
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/spf13/cobra"
+    "github.com/oxygene76/medasdigital-client/pkg/contract"
+)
+
+var contractListJobsCmd = &cobra.Command{
+    Use:   "list-jobs",
+    Short: "List jobs of the marketplace contract",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        cfg := loadConfig()
+
+        client, err := contractQueryClient(cmd, cfg)
+        if err != nil {
+            return err
+        }
+
+        var filter contract.JobFilter
+        filter.Provider, _ = cmd.Flags().GetString("provider")
+        filter.Client, _ = cmd.Flags().GetString("client")
+        filter.Status, _ = cmd.Flags().GetString("status")
+        filter.StartAfter, _ = cmd.Flags().GetUint64("start-after")
+        filter.Limit, _ = cmd.Flags().GetUint32("limit")
+
+        jobs, err := client.ListJobs(cmd.Context(), filter)
+        if err != nil {
+            return err
+        }
+        if len(jobs) == 0 {
+            fmt.Println("No jobs found")
+            return nil
+        }
+
+        fmt.Printf("%-8s %-11s %-16s %-20s %-18s %s\n", "ID", "STATUS", "TYPE", "ESCROW", "DEADLINE", "PROVIDER")
+        fmt.Println(strings.Repeat("=", 100))
+        for _, job := range jobs {
+            fmt.Printf("%-8d %-11s %-16s %-20s %-18s %s\n",
+                job.ID, job.Status, job.JobType,
+                formatEscrow(job, cfg), formatDeadline(job.Deadline, time.Now()), job.Provider)
+        }
+        if filter.Limit > 0 && uint32(len(jobs)) == filter.Limit {
+            fmt.Printf("\nMore jobs may exist, continue with --start-after %d\n", jobs[len(jobs)-1].ID)
+        }
+        return nil
+    },
+}
+
+var contractJobCmd = &cobra.Command{
+    Use:   "job [job-id]",
+    Short: "Show state, deadline and escrow of a job",
+    Args:  cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        cfg := loadConfig()
+
+        jobID, err := strconv.ParseUint(args[0], 10, 64)
+        if err != nil {
+            return fmt.Errorf("invalid job ID %q: %w", args[0], err)
+        }
+        client, err := contractQueryClient(cmd, cfg)
+        if err != nil {
+            return err
+        }
+
+        job, err := client.GetJob(cmd.Context(), jobID)
+        if err != nil {
+            return err
+        }
+        printJob(job, cfg)
+        return nil
+    },
+}
+
+var contractProviderCmd = &cobra.Command{
+    Use:   "provider [address]",
+    Short: "Show a registered provider",
+    Args:  cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        cfg := loadConfig()
+
+        client, err := contractQueryClient(cmd, cfg)
+        if err != nil {
+            return err
+        }
+        p, err := client.GetProvider(cmd.Context(), args[0])
+        if err != nil {
+            return err
+        }
+
+        statusIcon := "✅ active"
+        if !p.Active {
+            statusIcon = "❌ inactive"
+        }
+        fmt.Printf("Provider %s\n", p.Name)
+        fmt.Println(strings.Repeat("=", 60))
+        fmt.Printf("Address: %s\n", p.Address)
+        fmt.Printf("Status: %s\n", statusIcon)
+        fmt.Printf("Endpoint: %s\n", p.Endpoint)
+        fmt.Printf("Capacity: %d/%d jobs\n", p.ActiveJobs, p.Capacity)
+        fmt.Printf("Completed: %d | Reputation: %s\n", p.TotalCompleted, p.Reputation)
+        if p.RegisteredAt != "" {
+            fmt.Printf("Registered: %s\n", formatContractTime(p.RegisteredAt))
+        }
+        fmt.Println("Services:")
+        for _, cap := range p.Capabilities {
+            price := "N/A"
+            if pInfo, ok := p.Pricing[cap.ServiceType]; ok {
+                price = fmt.Sprintf("%s/%s", pInfo.BasePrice, pInfo.Unit)
+            }
+            fmt.Printf("  - %s: %d max, ~%ds, %s %s\n",
+                cap.ServiceType, cap.MaxComplexity, cap.AvgCompletionTime, price, cfg.Chain.BaseDenom)
+        }
+
+        showJobs, _ := cmd.Flags().GetBool("jobs")
+        if !showJobs {
+            return nil
+        }
+        jobs, err := client.ListJobs(cmd.Context(), contract.JobFilter{Provider: p.Address})
+        if err != nil {
+            return err
+        }
+        fmt.Printf("\nJobs (%d):\n", len(jobs))
+        for _, job := range jobs {
+            fmt.Printf("  #%d %s %s, escrow %s, deadline %s\n",
+                job.ID, job.Status, job.JobType, formatEscrow(job, cfg), formatDeadline(job.Deadline, time.Now()))
+        }
+        return nil
+    },
+}
+
+// contractQueryClient builds a read-only contract client
+func contractQueryClient(cmd *cobra.Command, cfg *Config) (*contract.Client, error) {
+    contractAddr, err := contractAddress(cmd)
+    if err != nil {
+        return nil, err
+    }
+    return contract.NewClient(contract.Config{
+        ContractAddress: contractAddr,
+        RPCEndpoint:     cfg.Chain.RPCEndpoint,
+        ChainID:         cfg.Chain.ID,
+    }, "", "", ""), nil
+}
+
+func printJob(job *contract.ContractJob, cfg *Config) {
+    fmt.Printf("Job #%d\n", job.ID)
+    fmt.Println(strings.Repeat("=", 60))
+    fmt.Printf("Status: %s\n", job.Status)
+    fmt.Printf("Type: %s\n", job.JobType)
+    fmt.Printf("Client: %s\n", job.Client)
+    if job.Provider != "" {
+        fmt.Printf("Provider: %s\n", job.Provider)
+    }
+    fmt.Printf("Payment: %s %s\n", job.PaymentAmount, cfg.Chain.BaseDenom)
+    fmt.Printf("Escrow: %s\n", formatEscrow(*job, cfg))
+    if job.CreatedAt != "" {
+        fmt.Printf("Created: %s\n", formatContractTime(job.CreatedAt))
+    }
+    if job.Deadline != "" {
+        fmt.Printf("Deadline: %s (%s)\n", formatContractTime(job.Deadline), formatDeadline(job.Deadline, time.Now()))
+    }
+    if job.CompletedAt != "" {
+        fmt.Printf("Completed: %s\n", formatContractTime(job.CompletedAt))
+    }
+    if job.ResultHash != "" {
+        fmt.Printf("Result hash: %s\n", job.ResultHash)
+    }
+    if job.ResultURL != "" {
+        fmt.Printf("Result: %s\n", job.ResultURL)
+    }
+}
+
+// formatEscrow shows what the contract still holds for a job. Older contracts
+// do not report escrow_amount, the payment is held until the job is settled.
+func formatEscrow(job contract.ContractJob, cfg *Config) string {
+    amount := job.EscrowAmount
+    if amount == "" {
+        switch job.Status {
+        case contract.JobStatusSubmitted, contract.JobStatusAccepted, contract.JobStatusFailed:
+            amount = job.PaymentAmount
+        default:
+            amount = "0"
+        }
+    }
+    return amount + " " + cfg.Chain.BaseDenom
+}
+
+func formatContractTime(s string) string {
+    if t, ok := contract.ParseTimestamp(s); ok {
+        return t.Local().Format("2006-01-02 15:04:05")
+    }
+    return s
+}
+
+// formatDeadline renders the time left until a deadline
+func formatDeadline(deadline string, now time.Time) string {
+    if deadline == "" {
+        return "-"
+    }
+    t, ok := contract.ParseTimestamp(deadline)
+    if !ok {
+        return deadline
+    }
+    left := t.Sub(now).Round(time.Second)
+    if left <= 0 {
+        return fmt.Sprintf("expired %v ago", -left)
+    }
+    return fmt.Sprintf("in %v", left)
+}
+
+func init() {
+    contractCmd.AddCommand(contractListJobsCmd)
+    contractCmd.AddCommand(contractJobCmd)
+    contractCmd.AddCommand(contractProviderCmd)
+
+    contractListJobsCmd.Flags().String("provider", "", "Only jobs of this provider address")
+    contractListJobsCmd.Flags().String("client", "", "Only jobs of this client address")
+    contractListJobsCmd.Flags().String("status", "", "Only jobs with this status (submitted, accepted, completed, failed, cancelled, refunded)")
+    contractListJobsCmd.Flags().Uint64("start-after", 0, "Pagination: list jobs after this ID")
+    contractListJobsCmd.Flags().Uint32("limit", 30, "Maximum number of jobs")
+
+    contractProviderCmd.Flags().Bool("jobs", false, "Also list the provider's jobs")
+}
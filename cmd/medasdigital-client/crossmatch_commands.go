@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/crossmatch"
+	"github.com/oxygene76/medasdigital-client/pkg/inference"
+)
+
+// crossmatchedDetection is a detection with its catalog counterparts
+type crossmatchedDetection struct {
+	inference.Detection
+	Status  string             `json:"status"` // known, stationary, candidate or no_position
+	Matches []crossmatch.Match `json:"matches,omitempty"`
+}
+
+// crossmatchReport is the file written by ai crossmatch
+type crossmatchReport struct {
+	Input        string                  `json:"input"`
+	RadiusArcsec float64                 `json:"radius_arcsec"`
+	Catalogs     []string                `json:"catalogs"`
+	Counts       map[string]int          `json:"counts"`
+	Detections   []crossmatchedDetection `json:"detections"`
+}
+
+// statusNoPosition marks detections in images without a WCS
+const statusNoPosition = "no_position"
+
+// aiCrossmatchCmd annotates detections with Gaia and SIMBAD sources
+var aiCrossmatchCmd = &cobra.Command{
+	Use:   "crossmatch <detections.json>",
+	Short: "Cross-match detections with Gaia DR3 and SIMBAD",
+	Long: `Look up every detection of 'ai detect --output' in Gaia DR3 and SIMBAD with a
+cone search of --radius arcseconds around its sky position. Gaia stars are
+moved by their proper motion to the observation time of the image.
+
+Each detection gets a status:
+
+  known       a catalog source lies within the radius, a false positive for
+              moving object searches
+  stationary  no catalog source, but detected at the same position in another
+              exposure; an uncatalogued fixed source or an artefact
+  candidate   no catalog source and not stationary, a possible mover
+
+Sky positions need FITS cutouts with a TAN WCS (CTYPE RA---TAN/DEC--TAN);
+detections in PNG/JPEG cutouts are reported as no_position. Radius, catalogs
+and TAP service URLs default to the crossmatch section of the config.
+
+  medasdigital-client ai crossmatch detections.json --radius 3 -o xmatch.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := loadConfig().CrossMatch
+		if cmd.Flags().Changed("radius") {
+			cfg.RadiusArcsec, _ = cmd.Flags().GetFloat64("radius")
+		}
+		if cmd.Flags().Changed("catalogs") {
+			list, _ := cmd.Flags().GetString("catalogs")
+			cfg.Catalogs = crossmatch.ParseCatalogs(list)
+		}
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		output, _ := cmd.Flags().GetString("output")
+		onlyCandidates, _ := cmd.Flags().GetBool("candidates")
+
+		detections, err := readDetections(args[0])
+		if err != nil {
+			return err
+		}
+
+		var targets []crossmatch.Target
+		var positioned []int
+		report := crossmatchReport{Input: args[0], RadiusArcsec: cfg.Radius(), Counts: map[string]int{}}
+		report.Detections = make([]crossmatchedDetection, len(detections))
+		for i, d := range detections {
+			report.Detections[i].Detection = d
+			if d.Sky == nil {
+				report.Detections[i].Status = statusNoPosition
+				continue
+			}
+			targets = append(targets, crossmatch.Target{Image: d.Image, RA: d.Sky.RA, Dec: d.Sky.Dec, ObsTime: d.Sky.ObsTime})
+			positioned = append(positioned, i)
+		}
+		if len(targets) == 0 {
+			return fmt.Errorf("none of the %d detections has a sky position, cross-matching needs FITS cutouts with a WCS", len(detections))
+		}
+
+		catalogs := cfg.NewCatalogs()
+		for _, c := range catalogs {
+			report.Catalogs = append(report.Catalogs, c.Name())
+		}
+		fmt.Printf("Cross-matching %d detections with %s (radius %.1f\")\n", len(targets), strings.Join(report.Catalogs, ", "), report.RadiusArcsec)
+
+		ctx := cmd.Context()
+		results, err := crossmatch.Run(ctx, targets, crossmatch.Options{
+			RadiusArcsec: report.RadiusArcsec,
+			Catalogs:     catalogs,
+			Progress: func(done, total int) {
+				fmt.Fprintf(os.Stderr, "\r  %d/%d exposures", done, total)
+				if done == total {
+					fmt.Fprintln(os.Stderr)
+				}
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("cross-match failed: %w", err)
+		}
+		for k, i := range positioned {
+			report.Detections[i].Status = results[k].Status
+			report.Detections[i].Matches = results[k].Matches
+		}
+		for _, d := range report.Detections {
+			report.Counts[d.Status]++
+		}
+
+		printCrossmatch(&report, onlyCandidates)
+
+		if output == "" {
+			output = strings.TrimSuffix(args[0], filepath.Ext(args[0])) + "_xmatch.json"
+		}
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", output, err)
+		}
+		fmt.Printf("\nCross-match saved to: %s\n", output)
+		return nil
+	},
+}
+
+// readDetections reads the detections of an ai detect result or a plain list
+func readDetections(path string) ([]inference.Detection, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read detections: %w", err)
+	}
+	var detections []inference.Detection
+	if err := json.Unmarshal(data, &detections); err == nil {
+		return detections, nil
+	}
+	var result struct {
+		AnalysisType string `json:"analysis_type"`
+		Data         struct {
+			Detections []inference.Detection `json:"detections"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("%s is not an ai detect result: %w", path, err)
+	}
+	if result.AnalysisType != "" && result.AnalysisType != "ai_detection" {
+		return nil, fmt.Errorf("%s is a %s result, not ai_detection", path, result.AnalysisType)
+	}
+	if len(result.Data.Detections) == 0 {
+		return nil, fmt.Errorf("no detections in %s", path)
+	}
+	return result.Data.Detections, nil
+}
+
+// printCrossmatch prints the counts and the detections
+func printCrossmatch(r *crossmatchReport, onlyCandidates bool) {
+	fmt.Printf("\nKnown: %d  Stationary: %d  Candidates: %d", r.Counts[crossmatch.StatusKnown],
+		r.Counts[crossmatch.StatusStationary], r.Counts[crossmatch.StatusCandidate])
+	if n := r.Counts[statusNoPosition]; n > 0 {
+		fmt.Printf("  Without position: %d", n)
+	}
+	fmt.Println()
+
+	fmt.Printf("\n%-24s %-11s %-10s %-10s %-6s %s\n", "Image", "Status", "RA", "Dec", "Score", "Nearest source")
+	fmt.Println(strings.Repeat("-", 90))
+	for _, d := range r.Detections {
+		if d.Sky == nil || (onlyCandidates && d.Status != crossmatch.StatusCandidate) {
+			continue
+		}
+		nearest := ""
+		if len(d.Matches) > 0 {
+			m := d.Matches[0]
+			nearest = fmt.Sprintf("%s (%.2f\")", m.ID, m.SeparationArcsec)
+			if m.Type != "" {
+				nearest += " " + m.Type
+			}
+		}
+		fmt.Printf("%-24s %-11s %-10.5f %-+10.5f %-6.2f %s\n", shortImageName(d.Image), d.Status, d.Sky.RA, d.Sky.Dec, d.Score, nearest)
+	}
+}
+
+// shortImageName keeps the end of long file names, where exposures differ
+func shortImageName(name string) string {
+	if len(name) <= 24 {
+		return name
+	}
+	return "…" + name[len(name)-23:]
+}
+
+func init() {
+	aiCmd.AddCommand(aiCrossmatchCmd)
+
+	aiCrossmatchCmd.Flags().Float64("radius", crossmatch.DefaultRadiusArcsec, "Match radius in arcseconds (default crossmatch.radius_arcsec)")
+	aiCrossmatchCmd.Flags().String("catalogs", "gaia,simbad", "Catalogs to query (gaia, simbad)")
+	aiCrossmatchCmd.Flags().StringP("output", "o", "", "Output file (default <input>_xmatch.json)")
+	aiCrossmatchCmd.Flags().Bool("candidates", false, "Only list candidates, the output file has all detections")
+}
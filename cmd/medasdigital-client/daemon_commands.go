@@ -0,0 +1,537 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/control"
+	"github.com/oxygene76/medasdigital-client/pkg/gpu"
+	"github.com/oxygene76/medasdigital-client/pkg/indexer"
+	"github.com/oxygene76/medasdigital-client/pkg/scheduler"
+)
+
+// daemonAnnotation marks commands that use a running daemon instead of
+// setting up their own client
+const daemonAnnotation = "daemon"
+
+// daemonCmd keeps the client state alive between commands
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Keep the chain connection, indexer, scheduler and GPU monitor running",
+	Long: `Run the client as a long-lived daemon. It keeps the chain connection open and
+polls the node, syncs the transaction index periodically, runs the scheduled
+jobs and watches the GPUs. Its control API listens on <home>/daemon.sock.
+
+While it runs, status, gpu status and the index commands get their data from
+the daemon instead of initializing their own RPC client; --no-daemon turns
+this off. The daemon is only used when it serves the same chain ID and RPC
+endpoint as the command.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		d := &clientDaemon{cfg: loadConfig(), started: time.Now()}
+		d.chainInterval, _ = cmd.Flags().GetDuration("chain-interval")
+		d.indexInterval, _ = cmd.Flags().GetDuration("index-interval")
+		d.gpuInterval, _ = cmd.Flags().GetDuration("gpu-interval")
+		noScheduler, _ := cmd.Flags().GetBool("no-scheduler")
+		if d.chainInterval <= 0 {
+			return fmt.Errorf("--chain-interval must be positive")
+		}
+
+		ctx := cmd.Context()
+		return d.run(ctx, !noScheduler)
+	},
+}
+
+// daemonStatusCmd shows what the daemon is doing
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of the running daemon",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		c := control.Dial(homeDir)
+		if c == nil {
+			fmt.Println("Daemon not running, start it with: medasdigital-client daemon")
+			return nil
+		}
+		st, err := c.Status(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			data, err := json.MarshalIndent(st, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		fmt.Printf("🛰️  Daemon %s (pid %d), up %s\n", st.Version, st.PID, time.Since(st.Started).Round(time.Second))
+		fmt.Printf("   Network: %s (%s), %s\n", st.Network, st.ChainID, st.RPCEndpoint)
+		if st.Chain.Error != "" {
+			fmt.Printf("   Chain:     ❌ %s\n", st.Chain.Error)
+		} else {
+			fmt.Printf("   Chain:     ✅ block %d at %s, checked %s ago\n", st.Chain.Height,
+				st.Chain.BlockTime.Local().Format("15:04:05"), time.Since(st.Chain.Checked).Round(time.Second))
+		}
+		switch {
+		case !st.Index.Enabled:
+			fmt.Println("   Index:     off")
+		case st.Index.Error != "":
+			fmt.Printf("   Index:     ❌ %s\n", st.Index.Error)
+		case st.Index.LastSync.IsZero():
+			fmt.Printf("   Index:     %d addresses, every %s, not synced yet\n", st.Index.Addresses, st.Index.Interval)
+		default:
+			fmt.Printf("   Index:     %d addresses, every %s, last sync %s\n", st.Index.Addresses, st.Index.Interval,
+				st.Index.LastSync.Local().Format("15:04:05"))
+		}
+		switch {
+		case !st.Scheduler.Enabled:
+			fmt.Println("   Scheduler: off")
+		case st.Scheduler.Error != "":
+			fmt.Printf("   Scheduler: ❌ %s\n", st.Scheduler.Error)
+		default:
+			fmt.Printf("   Scheduler: %d jobs\n", st.Scheduler.Jobs)
+		}
+		if st.GPU.Checked.IsZero() {
+			fmt.Println("   GPUs:      off")
+		} else {
+			fmt.Printf("   GPUs:      %d detected\n", len(st.GPU.Devices))
+		}
+		return nil
+	},
+}
+
+// daemonStopCmd shuts the daemon down
+var daemonStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the running daemon",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c := control.Dial(homeDir)
+		if c == nil {
+			return fmt.Errorf("daemon not running")
+		}
+		if err := c.Do(cmd.Context(), http.MethodPost, "/v1/shutdown", nil, nil); err != nil {
+			return err
+		}
+		// Running scheduled jobs are cancelled, give them a moment
+		for i := 0; i < 100; i++ {
+			if control.Dial(homeDir) == nil {
+				fmt.Println("✅ Daemon stopped")
+				return nil
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		return fmt.Errorf("daemon did not stop within 10s")
+	},
+}
+
+var (
+	daemonOnce sync.Once
+	daemonConn *control.Client
+)
+
+// connectDaemon returns the daemon of the home directory if it runs for the
+// configured chain, nil otherwise
+func connectDaemon() *control.Client {
+	daemonOnce.Do(func() {
+		if noDaemon {
+			return
+		}
+		c := control.Dial(homeDir)
+		if c == nil {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		st, err := c.Status(ctx)
+		if err != nil {
+			return
+		}
+		cfg := loadConfig()
+		if st.ChainID != cfg.Chain.ID || st.RPCEndpoint != cfg.Chain.RPCEndpoint {
+			return
+		}
+		daemonConn = c
+	})
+	return daemonConn
+}
+
+// clientDaemon is the state of a running daemon
+type clientDaemon struct {
+	cfg           *Config
+	started       time.Time
+	chainInterval time.Duration
+	indexInterval time.Duration
+	gpuInterval   time.Duration
+
+	bc       *blockchain.Client
+	ix       *indexer.Index // nil when the index cannot be opened
+	schedDir string
+	stop     context.CancelFunc
+
+	mu        sync.Mutex
+	chain     control.ChainState
+	index     control.IndexState
+	scheduler control.SchedulerState
+	gpus      control.GPUState
+
+	syncMu sync.Mutex // one index sync at a time
+}
+
+// run starts the components and serves the control API until ctx ends
+func (d *clientDaemon) run(ctx context.Context, withScheduler bool) error {
+	var (
+		sched *scheduler.Daemon
+		err   error
+	)
+	if withScheduler {
+		if sched, err = newScheduleDaemon(); err != nil {
+			return err
+		}
+	}
+	if d.bc, err = createFullBlockchainClient(client.Context{}, d.cfg); err != nil {
+		return err
+	}
+	ln, err := control.Listen(control.SocketPath(homeDir))
+	if err != nil {
+		return err
+	}
+	ctx, d.stop = context.WithCancel(ctx)
+	defer d.stop()
+
+	var wg sync.WaitGroup
+	start := func(fn func(context.Context)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(ctx)
+		}()
+	}
+
+	start(d.pollChain)
+	// The index stays open for syncs requested by commands
+	if d.ix, err = openIndex(d.cfg); err != nil {
+		log.Printf("⚠️  Index disabled: %v", err)
+	} else {
+		defer d.ix.Close()
+		if d.indexInterval > 0 {
+			d.index = control.IndexState{Enabled: true, Interval: d.indexInterval}
+			start(d.syncIndexLoop)
+		}
+	}
+	if d.gpuInterval > 0 {
+		start(d.pollGPUs)
+	}
+	if sched != nil {
+		d.schedDir = sched.Dir
+		d.scheduler.Enabled = true
+		start(func(ctx context.Context) {
+			if err := sched.Run(ctx); err != nil {
+				log.Printf("⚠️  Scheduler not started: %v", err)
+				d.mu.Lock()
+				d.scheduler.Error = err.Error()
+				d.mu.Unlock()
+			}
+		})
+	}
+
+	log.Printf("🛰️  Daemon started for %s (%s), control socket %s", d.cfg.Chain.ID, d.cfg.Chain.RPCEndpoint, ln.Addr())
+	err = control.Serve(ctx, ln, d.routes())
+	d.stop()
+	wg.Wait()
+	log.Printf("Daemon stopped")
+	return err
+}
+
+func (d *clientDaemon) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", func(w http.ResponseWriter, r *http.Request) {
+		control.WriteJSON(w, http.StatusOK, d.status())
+	})
+	mux.HandleFunc("GET /v1/chain", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		control.WriteJSON(w, http.StatusOK, d.chain)
+	})
+	mux.HandleFunc("GET /v1/gpus", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		control.WriteJSON(w, http.StatusOK, d.gpus)
+	})
+	mux.HandleFunc("POST /v1/index/sync", func(w http.ResponseWriter, r *http.Request) {
+		if d.ix == nil {
+			control.WriteError(w, http.StatusServiceUnavailable, fmt.Errorf("the daemon runs without index"))
+			return
+		}
+		var req control.IndexSyncRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				control.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+				return
+			}
+		}
+		results, err := d.syncIndex(r.Context(), req.Addresses)
+		if err != nil {
+			control.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		control.WriteJSON(w, http.StatusOK, results)
+	})
+	mux.HandleFunc("POST /v1/shutdown", func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("Shutdown requested")
+		control.WriteJSON(w, http.StatusOK, map[string]bool{"stopping": true})
+		d.stop()
+	})
+	return mux
+}
+
+func (d *clientDaemon) status() control.Status {
+	st := control.Status{
+		PID:         os.Getpid(),
+		Version:     version,
+		Started:     d.started,
+		Network:     d.cfg.Chain.Network,
+		ChainID:     d.cfg.Chain.ID,
+		RPCEndpoint: d.cfg.Chain.RPCEndpoint,
+	}
+	if d.ix != nil {
+		if states, err := d.ix.Addresses(); err == nil {
+			d.mu.Lock()
+			d.index.Addresses = len(states)
+			d.mu.Unlock()
+		}
+	}
+	if d.schedDir != "" {
+		if store, err := scheduler.Load(d.schedDir); err == nil {
+			d.mu.Lock()
+			d.scheduler.Jobs = len(store.Jobs)
+			d.mu.Unlock()
+		}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	st.Chain, st.Index, st.Scheduler, st.GPU = d.chain, d.index, d.scheduler, d.gpus
+	return st
+}
+
+// every runs fn now and then at each interval until ctx ends
+func every(ctx context.Context, interval time.Duration, fn func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		fn()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollChain keeps the node status current and logs connection changes
+func (d *clientDaemon) pollChain(ctx context.Context) {
+	every(ctx, d.chainInterval, func() {
+		rctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		status, err := d.bc.GetStatus(rctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		wasDown := d.chain.Error != ""
+		state := control.ChainState{Checked: time.Now()}
+		if err != nil {
+			state.Error = err.Error()
+			state.Height, state.BlockTime = d.chain.Height, d.chain.BlockTime
+			if !wasDown {
+				log.Printf("❌ Node unreachable: %v", err)
+			}
+		} else {
+			state.Height = status.SyncInfo.LatestBlockHeight
+			state.BlockTime = status.SyncInfo.LatestBlockTime
+			state.CatchingUp = status.SyncInfo.CatchingUp
+			state.NodeVersion = status.NodeInfo.Version
+			if wasDown {
+				log.Printf("✅ Node reachable again at block %d", state.Height)
+			}
+		}
+		d.chain = state
+	})
+}
+
+// pollGPUs rescans the GPUs
+func (d *clientDaemon) pollGPUs(ctx context.Context) {
+	every(ctx, d.gpuInterval, func() {
+		devices := gpu.Detect()
+		d.mu.Lock()
+		d.gpus = control.GPUState{Devices: devices, Checked: time.Now()}
+		d.mu.Unlock()
+	})
+}
+
+// syncIndexLoop syncs all tracked addresses periodically
+func (d *clientDaemon) syncIndexLoop(ctx context.Context) {
+	every(ctx, d.indexInterval, func() {
+		results, err := d.syncIndex(ctx, nil)
+		if err != nil || ctx.Err() != nil {
+			return
+		}
+		for _, r := range results {
+			if r.Error != "" {
+				log.Printf("⚠️  Index sync of %s failed: %s", r.Address, r.Error)
+			} else if r.Transfers > 0 {
+				log.Printf("🗂️  Indexed %d new transfers of %s", r.Transfers, r.Address)
+			}
+		}
+	})
+}
+
+// syncIndex syncs the given addresses, all tracked ones if empty. Failures
+// of single addresses are reported in the results.
+func (d *clientDaemon) syncIndex(ctx context.Context, addresses []string) ([]control.IndexSyncResult, error) {
+	d.syncMu.Lock()
+	defer d.syncMu.Unlock()
+
+	if len(addresses) == 0 {
+		states, err := d.ix.Addresses()
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range states {
+			addresses = append(addresses, s.Address)
+		}
+		if len(addresses) == 0 {
+			return nil, fmt.Errorf("no addresses tracked, pass one or set indexer.addresses")
+		}
+	}
+
+	results := make([]control.IndexSyncResult, 0, len(addresses))
+	var failed []string
+	for _, addr := range addresses {
+		r := control.IndexSyncResult{Address: addr}
+		stats, err := d.ix.Sync(ctx, d.bc, addr)
+		if err != nil {
+			r.Error = err.Error()
+			failed = append(failed, addr)
+		} else {
+			r.ToHeight, r.Transfers = stats.ToHeight, stats.Transfers
+		}
+		results = append(results, r)
+	}
+
+	d.mu.Lock()
+	d.index.LastSync = time.Now()
+	d.index.Error = ""
+	if len(failed) > 0 {
+		d.index.Error = "sync failed for " + strings.Join(failed, ", ")
+	}
+	d.mu.Unlock()
+	return results, nil
+}
+
+// syncIndexViaDaemon lets the daemon sync, so that only one process writes
+// the index
+func syncIndexViaDaemon(ctx context.Context, c *control.Client, addresses []string, verbose bool) error {
+	var results []control.IndexSyncResult
+	if err := c.Do(ctx, http.MethodPost, "/v1/index/sync", control.IndexSyncRequest{Addresses: addresses}, &results); err != nil {
+		return err
+	}
+	var errs []error
+	for _, r := range results {
+		if r.Error != "" {
+			errs = append(errs, fmt.Errorf("sync of %s failed: %s", r.Address, r.Error))
+			continue
+		}
+		if verbose {
+			fmt.Fprintf(os.Stderr, "🔍 Synced %s via daemon: %d new transfers, height %d\n", r.Address, r.Transfers, r.ToHeight)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// chainStatus asks the daemon for the node status, or the node itself
+func chainStatus(ctx context.Context, cfg *Config) (*ChainStatus, error) {
+	c := connectDaemon()
+	if c == nil {
+		return getDetailedChainStatus(ctx, cfg.Chain.RPCEndpoint)
+	}
+	var state control.ChainState
+	if err := c.Do(ctx, http.MethodGet, "/v1/chain", nil, &state); err != nil {
+		return nil, err
+	}
+	if state.Error != "" {
+		return nil, errors.New(state.Error)
+	}
+	return &ChainStatus{
+		LatestBlockHeight: state.Height,
+		LatestBlockTime:   state.BlockTime,
+		ChainID:           cfg.Chain.ID,
+		NodeVersion:       state.NodeVersion,
+		CatchingUp:        state.CatchingUp,
+	}, nil
+}
+
+// detectGPUs takes the daemon's last GPU scan, or scans
+func detectGPUs() []gpu.DetectedDevice {
+	if c := connectDaemon(); c != nil {
+		var state control.GPUState
+		if err := c.Do(context.Background(), http.MethodGet, "/v1/gpus", nil, &state); err == nil && !state.Checked.IsZero() {
+			return state.Devices
+		}
+	}
+	return gpu.Detect()
+}
+
+// printDetectedGPUs lists GPUs for gpu status when the daemon serves it
+func printDetectedGPUs(devices []gpu.DetectedDevice) error {
+	if len(devices) == 0 {
+		fmt.Println("GPU not available or not enabled")
+		return nil
+	}
+	fmt.Printf("GPU Status: Available\n")
+	fmt.Printf("GPU Backend: %s\n", strings.ToUpper(devices[0].Backend))
+	fmt.Printf("GPU Device Count: %d\n", len(devices))
+	for _, d := range devices {
+		fmt.Printf("  GPU %d: %s [%s]\n", d.Index, d.Name, d.Vendor)
+		fmt.Printf("         %d/%d MB used\n", d.MemoryUsed/1024/1024, d.MemoryTotal/1024/1024)
+	}
+	return nil
+}
+
+func init() {
+	// Commands that take their chain, GPU or index data from a running daemon
+	for _, cmd := range []*cobra.Command{statusCmd, gpuStatusCmd, indexSyncCmd, indexEarningsCmd} {
+		if cmd.Annotations == nil {
+			cmd.Annotations = map[string]string{}
+		}
+		cmd.Annotations[daemonAnnotation] = "true"
+	}
+
+	rootCmd.AddCommand(daemonCmd)
+	daemonCmd.AddCommand(daemonStatusCmd)
+	daemonCmd.AddCommand(daemonStopCmd)
+
+	daemonCmd.Flags().Duration("chain-interval", 15*time.Second, "How often the node status is polled")
+	daemonCmd.Flags().Duration("index-interval", 10*time.Minute, "How often the tracked addresses are synced (0 = only on request)")
+	daemonCmd.Flags().Duration("gpu-interval", 30*time.Second, "How often the GPUs are scanned (0 = off)")
+	daemonCmd.Flags().Bool("no-scheduler", false, "Do not run the scheduled jobs")
+
+	daemonStatusCmd.Flags().Bool("json", false, "Print JSON")
+}
@@ -0,0 +1,477 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+	"github.com/oxygene76/medasdigital-client/pkg/computeclient"
+	"github.com/oxygene76/medasdigital-client/pkg/contract"
+	"github.com/oxygene76/medasdigital-client/pkg/gpu"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+)
+
+const (
+	// dashboardMaxJobs is how many jobs the job table shows
+	dashboardMaxJobs = 20
+	// registrationRefresh is how often the registration is re-read from chain
+	registrationRefresh = time.Minute
+	// earningsMaxPages bounds the contract queries for provider earnings
+	earningsMaxPages = 10
+)
+
+// dashboardSnapshot is everything the dashboard shows at one point in time
+type dashboardSnapshot struct {
+	UpdatedAt time.Time
+
+	Chain    *ChainStatus
+	ChainErr error
+
+	Registration    *blockchain.BlockchainRegistrationData
+	LocalRegs       int
+	RegistrationErr error
+
+	Jobs    []compute.ComputeJob
+	JobsErr error
+
+	GPUs []gpu.DetectedDevice
+
+	Provider       *contract.Provider
+	ProviderEarned uint64 // base denom, completed contract jobs
+	ProviderErr    error
+}
+
+// dashboard collects the data of a snapshot
+type dashboard struct {
+	cfg          *Config
+	service      *computeclient.Client
+	contract     *contract.Client
+	providerAddr string
+	clientAddr   string
+
+	mu           sync.Mutex
+	registration *blockchain.BlockchainRegistrationData
+	localRegs    int
+	regErr       error
+	regCheckedAt time.Time
+}
+
+// dashboardCmd shows chain, jobs, GPUs and provider stats in a terminal UI
+var dashboardCmd = &cobra.Command{
+	Use:   "dashboard",
+	Short: "Live terminal dashboard for chain, jobs, GPUs and provider earnings",
+	Long: `Show chain height, the client registration, compute jobs of a payment service
+with their progress, GPU memory and provider earnings, refreshed in real time.
+
+Jobs are shown with --service, provider stats with --provider.
+Press q or Esc to quit, r to refresh immediately.
+
+Example:
+  medasdigital-client dashboard --service http://localhost:8080 --provider medas1...`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serviceURL, _ := cmd.Flags().GetString("service")
+		providerAddr, _ := cmd.Flags().GetString("provider")
+		clientAddr, _ := cmd.Flags().GetString("client-address")
+		refresh, _ := cmd.Flags().GetDuration("refresh")
+		once, _ := cmd.Flags().GetBool("once")
+
+		if refresh < time.Second {
+			refresh = time.Second
+		}
+
+		d := &dashboard{cfg: loadConfig(), providerAddr: providerAddr, clientAddr: clientAddr}
+		if serviceURL != "" {
+			d.service = computeclient.New(serviceURL, computeclient.Options{Retries: -1})
+		}
+		if providerAddr != "" {
+			client, err := contractQueryClient(cmd, d.cfg)
+			if err != nil {
+				return err
+			}
+			d.contract = client
+		}
+
+		if once {
+			ctx, cancel := withTimeout(cmd, 30*time.Second)
+			defer cancel()
+			snap := d.collect(ctx)
+			fmt.Println("=== MedasDigital Dashboard ===")
+			for _, section := range []struct{ title, body string }{
+				{"Chain", d.renderChain(snap)},
+				{"Registration", d.renderRegistration(snap)},
+				{"Jobs", d.renderJobsText(snap)},
+				{"GPUs", d.renderGPUs(snap)},
+				{"Provider", d.renderProvider(snap)},
+			} {
+				fmt.Printf("\n--- %s ---\n%s\n", section.title, stripColors(section.body))
+			}
+			return nil
+		}
+		return d.run(cmd.Context(), refresh)
+	},
+}
+
+// run starts the terminal UI and refreshes it until the user quits or ctx
+// is done
+func (d *dashboard) run(parent context.Context, refresh time.Duration) error {
+	app := tview.NewApplication()
+
+	chainView := dashboardPanel("Chain")
+	regView := dashboardPanel("Registration")
+	gpuView := dashboardPanel("GPUs")
+	providerView := dashboardPanel("Provider")
+	footer := tview.NewTextView().SetDynamicColors(true)
+
+	jobs := tview.NewTable().SetFixed(1, 0)
+	jobs.SetBorder(true).SetTitle(" Compute Jobs ")
+
+	top := tview.NewFlex().
+		AddItem(chainView, 0, 1, false).
+		AddItem(regView, 0, 1, false)
+	bottom := tview.NewFlex().
+		AddItem(gpuView, 0, 1, false).
+		AddItem(providerView, 0, 1, false)
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(top, 7, 0, false).
+		AddItem(jobs, 0, 1, true).
+		AddItem(bottom, 8, 0, false).
+		AddItem(footer, 1, 0, false)
+
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	refreshNow := make(chan struct{}, 1)
+
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape, event.Rune() == 'q':
+			app.Stop()
+			return nil
+		case event.Rune() == 'r':
+			select {
+			case refreshNow <- struct{}{}:
+			default:
+			}
+			return nil
+		}
+		return event
+	})
+
+	go func() {
+		ticker := time.NewTicker(refresh)
+		defer ticker.Stop()
+		for {
+			snap := d.collect(ctx)
+			if ctx.Err() != nil {
+				app.Stop()
+				return
+			}
+			app.QueueUpdateDraw(func() {
+				chainView.SetText(d.renderChain(snap))
+				regView.SetText(d.renderRegistration(snap))
+				gpuView.SetText(d.renderGPUs(snap))
+				providerView.SetText(d.renderProvider(snap))
+				d.fillJobTable(jobs, snap)
+				footer.SetText(fmt.Sprintf(" [gray]updated %s · refresh %v · [white]q[gray] quit · [white]r[gray] refresh", snap.UpdatedAt.Format("15:04:05"), refresh))
+			})
+
+			select {
+			case <-ctx.Done():
+				app.Stop()
+				return
+			case <-ticker.C:
+			case <-refreshNow:
+			}
+		}
+	}()
+
+	return app.SetRoot(layout, true).Run()
+}
+
+func dashboardPanel(title string) *tview.TextView {
+	view := tview.NewTextView().SetDynamicColors(true)
+	view.SetBorder(true).SetTitle(" " + title + " ")
+	return view
+}
+
+// collect queries all sources concurrently, each section keeps its own error
+func (d *dashboard) collect(ctx context.Context) *dashboardSnapshot {
+	snap := &dashboardSnapshot{UpdatedAt: time.Now()}
+	var wg sync.WaitGroup
+	run := func(fn func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn()
+		}()
+	}
+
+	run(func() { snap.Chain, snap.ChainErr = getDetailedChainStatus(ctx, d.cfg.Chain.RPCEndpoint) })
+	run(func() { snap.Registration, snap.LocalRegs, snap.RegistrationErr = d.loadRegistration(ctx) })
+	run(func() { snap.GPUs = gpu.Detect() })
+	if d.service != nil {
+		run(func() { snap.Jobs, snap.JobsErr = d.loadJobs(ctx) })
+	}
+	if d.contract != nil {
+		run(func() { snap.Provider, snap.ProviderEarned, snap.ProviderErr = d.loadProvider(ctx) })
+	}
+	wg.Wait()
+	return snap
+}
+
+// loadRegistration returns the latest registration, cached for a minute
+func (d *dashboard) loadRegistration(ctx context.Context) (*blockchain.BlockchainRegistrationData, int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.regCheckedAt.IsZero() && time.Since(d.regCheckedAt) < registrationRefresh {
+		return d.registration, d.localRegs, d.regErr
+	}
+	d.regCheckedAt = time.Now()
+
+	hashes, err := blockchain.GetLocalRegistrationHashes()
+	if err != nil {
+		d.registration, d.localRegs, d.regErr = nil, 0, nil
+		return nil, 0, nil
+	}
+	var latest *blockchain.BlockchainRegistrationData
+	var lastErr error
+	for _, hash := range hashes {
+		regData, err := blockchain.FetchRegistrationFromBlockchain(ctx, hash, d.cfg.Chain.RPCEndpoint, d.cfg.Chain.ID, currentApp().Codec())
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if latest == nil || regData.BlockTime.After(latest.BlockTime) {
+			latest = regData
+		}
+	}
+	if latest != nil {
+		lastErr = nil
+	}
+	d.registration, d.localRegs, d.regErr = latest, len(hashes), lastErr
+	return latest, len(hashes), lastErr
+}
+
+// loadJobs returns unfinished jobs first, then the most recent ones
+func (d *dashboard) loadJobs(ctx context.Context) ([]compute.ComputeJob, error) {
+	jobs, err := d.service.ListJobs(ctx, computeclient.JobFilter{ClientAddress: d.clientAddr})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(jobs, func(a, b int) bool {
+		fa, fb := jobFinished(jobs[a].Status), jobFinished(jobs[b].Status)
+		if fa != fb {
+			return !fa
+		}
+		return jobs[a].SubmittedAt.After(jobs[b].SubmittedAt)
+	})
+	if len(jobs) > dashboardMaxJobs {
+		jobs = jobs[:dashboardMaxJobs]
+	}
+	return jobs, nil
+}
+
+// loadProvider returns the provider and what its completed contract jobs paid
+func (d *dashboard) loadProvider(ctx context.Context) (*contract.Provider, uint64, error) {
+	p, err := d.contract.GetProvider(ctx, d.providerAddr)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var earned uint64
+	filter := contract.JobFilter{Provider: d.providerAddr, Status: contract.JobStatusCompleted, Limit: 100}
+	for page := 0; page < earningsMaxPages; page++ {
+		jobs, err := d.contract.ListJobs(ctx, filter)
+		if err != nil {
+			return p, earned, err
+		}
+		for _, job := range jobs {
+			amount, _ := strconv.ParseUint(job.PaymentAmount, 10, 64)
+			earned += amount
+		}
+		if len(jobs) < int(filter.Limit) {
+			break
+		}
+		filter.StartAfter = jobs[len(jobs)-1].ID
+	}
+	return p, earned, nil
+}
+
+func jobFinished(status compute.JobStatus) bool {
+	return status == compute.StatusCompleted || status == compute.StatusFailed || status == compute.StatusCancelled
+}
+
+func (d *dashboard) renderChain(snap *dashboardSnapshot) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Network: %s (%s)\n", d.cfg.Chain.Network, d.cfg.Chain.ID)
+	fmt.Fprintf(&b, "RPC:     %s\n", d.cfg.Chain.RPCEndpoint)
+	if snap.ChainErr != nil {
+		fmt.Fprintf(&b, "[red]❌ Disconnected: %v[-]", snap.ChainErr)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Height:  [green]%d[-] (%s ago)\n", snap.Chain.LatestBlockHeight, time.Since(snap.Chain.LatestBlockTime).Round(time.Second))
+	if snap.Chain.CatchingUp {
+		b.WriteString("[yellow]⏳ Node is catching up[-]")
+	} else {
+		b.WriteString("✅ In sync")
+	}
+	return b.String()
+}
+
+func (d *dashboard) renderRegistration(snap *dashboardSnapshot) string {
+	reg := snap.Registration
+	if reg == nil {
+		if snap.LocalRegs == 0 {
+			return "[yellow]❌ Not registered[-]\n💡 medasdigital-client register --from <key>"
+		}
+		msg := fmt.Sprintf("[yellow]⚠️  %d local registration(s), none verified on chain[-]", snap.LocalRegs)
+		if snap.RegistrationErr != nil {
+			msg += fmt.Sprintf("\n%v", snap.RegistrationErr)
+		}
+		return msg
+	}
+	return fmt.Sprintf("Client ID: %s\nAddress:   %s\nSince:     %s (block %d)\n✅ Verified on chain",
+		reg.ClientID, reg.FromAddress, reg.BlockTime.Format("2006-01-02 15:04"), reg.BlockHeight)
+}
+
+func (d *dashboard) renderGPUs(snap *dashboardSnapshot) string {
+	if len(snap.GPUs) == 0 {
+		return "No supported GPU detected"
+	}
+	var b strings.Builder
+	for _, dev := range snap.GPUs {
+		fmt.Fprintf(&b, "#%d %s\n", dev.Index, dev.Summary())
+		if dev.MemoryTotal > 0 {
+			used := int(dev.MemoryUsed * 100 / dev.MemoryTotal)
+			fmt.Fprintf(&b, "   mem %s %d/%d MB\n", progressBar(used, 20), dev.MemoryUsed/1024/1024, dev.MemoryTotal/1024/1024)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func (d *dashboard) renderProvider(snap *dashboardSnapshot) string {
+	if d.contract == nil {
+		return "[gray]Use --provider <address> to show provider stats[-]"
+	}
+	if snap.ProviderErr != nil && snap.Provider == nil {
+		return fmt.Sprintf("[red]❌ %v[-]", snap.ProviderErr)
+	}
+	p := snap.Provider
+	status := "[green]active[-]"
+	if !p.Active {
+		status = "[red]inactive[-]"
+	}
+	net := network.Current()
+	text := fmt.Sprintf("%s (%s)\nJobs:       %d active / %d capacity\nCompleted:  %d\nReputation: %s\nEarned:     [green]%.6f %s[-]",
+		p.Name, status, p.ActiveJobs, p.Capacity, p.TotalCompleted, p.Reputation, net.ToDisplay(int64(snap.ProviderEarned)), net.DisplayDenom)
+	if snap.ProviderErr != nil {
+		text += fmt.Sprintf("\n[yellow]⚠️  %v[-]", snap.ProviderErr)
+	}
+	return text
+}
+
+// fillJobTable renders the jobs with a progress bar each
+func (d *dashboard) fillJobTable(table *tview.Table, snap *dashboardSnapshot) {
+	table.Clear()
+	for col, title := range []string{"Job", "Status", "Progress", "Tier", "Client", "Submitted"} {
+		table.SetCell(0, col, tview.NewTableCell(title).SetTextColor(tcell.ColorYellow).SetSelectable(false))
+	}
+	if d.service == nil {
+		table.SetCell(1, 0, tview.NewTableCell("Use --service <url> to show jobs of a payment service").SetTextColor(tcell.ColorGray))
+		return
+	}
+	if snap.JobsErr != nil {
+		table.SetCell(1, 0, tview.NewTableCell(fmt.Sprintf("❌ %v", snap.JobsErr)).SetTextColor(tcell.ColorRed))
+		return
+	}
+	if len(snap.Jobs) == 0 {
+		table.SetCell(1, 0, tview.NewTableCell("No jobs").SetTextColor(tcell.ColorGray))
+		return
+	}
+	for i, job := range snap.Jobs {
+		row := i + 1
+		table.SetCell(row, 0, tview.NewTableCell(job.ID))
+		table.SetCell(row, 1, tview.NewTableCell(string(job.Status)).SetTextColor(jobStatusColor(job.Status)))
+		table.SetCell(row, 2, tview.NewTableCell(progressBar(job.Progress, 20)))
+		table.SetCell(row, 3, tview.NewTableCell(string(job.Tier)))
+		table.SetCell(row, 4, tview.NewTableCell(shortAddress(job.ClientAddr)))
+		table.SetCell(row, 5, tview.NewTableCell(job.SubmittedAt.Format("15:04:05")))
+	}
+}
+
+// renderJobsText is the job table for --once
+func (d *dashboard) renderJobsText(snap *dashboardSnapshot) string {
+	if d.service == nil {
+		return "Use --service <url> to show jobs of a payment service"
+	}
+	if snap.JobsErr != nil {
+		return fmt.Sprintf("❌ %v", snap.JobsErr)
+	}
+	if len(snap.Jobs) == 0 {
+		return "No jobs"
+	}
+	var b strings.Builder
+	for _, job := range snap.Jobs {
+		fmt.Fprintf(&b, "%-24s %-10s %s %-8s %s\n", job.ID, job.Status, progressBar(job.Progress, 20), job.Tier, shortAddress(job.ClientAddr))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func jobStatusColor(status compute.JobStatus) tcell.Color {
+	switch status {
+	case compute.StatusCompleted:
+		return tcell.ColorGreen
+	case compute.StatusFailed, compute.StatusCancelled:
+		return tcell.ColorRed
+	case compute.StatusRunning:
+		return tcell.ColorAqua
+	}
+	return tcell.ColorWhite
+}
+
+// progressBar renders percent as a bar of width cells
+func progressBar(percent, width int) string {
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	filled := percent * width / 100
+	return fmt.Sprintf("%s%s %3d%%", strings.Repeat("█", filled), strings.Repeat("░", width-filled), percent)
+}
+
+func shortAddress(addr string) string {
+	if len(addr) <= 20 {
+		return addr
+	}
+	return addr[:12] + "…" + addr[len(addr)-6:]
+}
+
+// stripColors removes tview color tags for plain output
+func stripColors(s string) string {
+	for _, tag := range []string{"[red]", "[green]", "[yellow]", "[gray]", "[white]", "[-]"} {
+		s = strings.ReplaceAll(s, tag, "")
+	}
+	return s
+}
+
+func init() {
+	rootCmd.AddCommand(dashboardCmd)
+
+	dashboardCmd.Flags().String("service", "", "Payment service URL to show compute jobs from")
+	dashboardCmd.Flags().String("provider", "", "Provider address to show contract stats and earnings for")
+	dashboardCmd.Flags().String("client-address", "", "Only show jobs of this client address")
+	dashboardCmd.Flags().String("contract", "", "Contract address (default from config)")
+	dashboardCmd.Flags().Duration("refresh", 5*time.Second, "Refresh interval")
+	dashboardCmd.Flags().Bool("once", false, "Print one snapshot as text and exit")
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/astronomy/catalog"
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// etnoCatalogDir is where data update keeps the ETNO snapshots
+func etnoCatalogDir() string {
+	return filepath.Join(homeDir, "catalog", "etno")
+}
+
+// legacyETNOFile is the data file scripts/fetch_jpl_api.py writes, used
+// without --catalog-version while it exists
+const legacyETNOFile = "data/solar_system_jpl.json"
+
+var dataCmd = &cobra.Command{
+	Use:   "data",
+	Short: "Manage the object catalogs searches run on",
+}
+
+var dataUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Fetch current ETNO elements from JPL SBDB into a new snapshot",
+	Long: `Query the JPL Small-Body Database for all objects with a semi-major axis above
+--min-a and a perihelion above --min-q and save their current elements as a
+new catalog snapshot in ~/.medasdigital-client/catalog/etno.
+
+Snapshots are never changed afterwards. Each records when it was retrieved,
+the epochs of its elements and a SHA-256 of the objects; its version is the
+retrieval date (YYYY.MM.DD, .2 for a second one that day). When the elements
+are unchanged since the latest snapshot no new one is written unless --force.
+
+Pin a snapshot for a reproducible search:
+
+  medasdigital-client data update
+  medasdigital-client planet9 search akari2025 --catalog-version 2026.10.17`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		q := catalog.Query{}
+		q.URL, _ = cmd.Flags().GetString("url")
+		q.MinSemiMajorAxis, _ = cmd.Flags().GetFloat64("min-a")
+		q.MinPerihelion, _ = cmd.Flags().GetFloat64("min-q")
+		force, _ := cmd.Flags().GetBool("force")
+
+		_, _, err := updateETNOCatalog(cmd, q, force)
+		return err
+	},
+}
+
+var dataListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the ETNO catalog snapshots",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := catalog.NewStore(etnoCatalogDir())
+		list, err := store.List()
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			fmt.Printf("No snapshots in %s, run 'data update'\n", store.Dir())
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tRETRIEVED\tETNOS\tEPOCHS\tSELECTION")
+		for _, m := range list {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", m.Version, m.RetrievedAt.Local().Format("2006-01-02 15:04"), m.Count, epochSpan(m), m.Selection)
+		}
+		return w.Flush()
+	},
+}
+
+// updateETNOCatalog fetches the elements of q and saves them as a snapshot,
+// unless they are unchanged since the latest one. It returns the path and
+// the snapshot used.
+func updateETNOCatalog(cmd *cobra.Command, q catalog.Query, force bool) (string, *catalog.Snapshot, error) {
+	if q.URL == "" {
+		q.URL = catalog.DefaultSBDBURL
+	}
+	fmt.Printf("🔭 Querying %s for objects with a > %g AU, q > %g AU...\n", q.URL, q.MinSemiMajorAxis, q.MinPerihelion)
+	stopTimer := telemetry.Track(telemetry.CategoryRPC, "sbdb_query")
+	snap, err := catalog.Fetch(cmd.Context(), q)
+	stopTimer()
+	if err != nil {
+		return "", nil, err
+	}
+
+	store := catalog.NewStore(etnoCatalogDir())
+	if !force {
+		if path, err := store.Path(catalog.Latest); err == nil {
+			latest, err := catalog.Load(path)
+			if err == nil && latest.Metadata.SHA256 == snap.Metadata.SHA256 && latest.Metadata.Selection == snap.Metadata.Selection {
+				fmt.Printf("✅ Unchanged since version %s (%d ETNOs), no new snapshot\n", latest.Metadata.Version, latest.Metadata.Count)
+				return path, latest, nil
+			}
+		}
+	}
+
+	path, err := store.Save(snap)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to save the snapshot: %w", err)
+	}
+	fmt.Printf("✅ Catalog version %s: %d ETNOs, epochs %s\n", snap.Metadata.Version, snap.Metadata.Count, epochSpan(snap.Metadata))
+	fmt.Printf("📁 %s\n", path)
+	fmt.Printf("📌 Pin it with: planet9 search --catalog-version %s\n", snap.Metadata.Version)
+	return path, snap, nil
+}
+
+// epochSpan describes the element epochs of a catalog as dates
+func epochSpan(m catalog.Metadata) string {
+	if m.EpochMinJD == 0 || m.EpochMinJD == m.EpochMaxJD {
+		if m.EpochJD == 0 {
+			return "unknown"
+		}
+		return catalog.EpochTime(m.EpochJD).Format("2006-01-02")
+	}
+	return fmt.Sprintf("%s to %s (mostly %s)", catalog.EpochTime(m.EpochMinJD).Format("2006-01-02"),
+		catalog.EpochTime(m.EpochMaxJD).Format("2006-01-02"), catalog.EpochTime(m.EpochJD).Format("2006-01-02"))
+}
+
+// etnoCatalogPath resolves --catalog-version to a file: the given snapshot,
+// or without one the data file of earlier releases, the latest snapshot or a
+// snapshot fetched now
+func etnoCatalogPath(cmd *cobra.Command, version string) (string, error) {
+	store := catalog.NewStore(etnoCatalogDir())
+	if version != "" {
+		return store.Path(version)
+	}
+	if _, err := os.Stat(legacyETNOFile); err == nil {
+		return legacyETNOFile, nil
+	}
+	if path, err := store.Path(catalog.Latest); err == nil {
+		return path, nil
+	}
+	fmt.Println("\n⚠ No ETNO catalog yet. Fetching one from JPL SBDB...")
+	path, _, err := updateETNOCatalog(cmd, catalog.Query{
+		MinSemiMajorAxis: catalog.DefaultMinSemiMajorAxis,
+		MinPerihelion:    catalog.DefaultMinPerihelion,
+	}, false)
+	return path, err
+}
+
+func init() {
+	dataUpdateCmd.Flags().Float64("min-a", catalog.DefaultMinSemiMajorAxis, "Smallest semi-major axis in AU")
+	dataUpdateCmd.Flags().Float64("min-q", catalog.DefaultMinPerihelion, "Smallest perihelion distance in AU")
+	dataUpdateCmd.Flags().String("url", catalog.DefaultSBDBURL, "SBDB query API")
+	dataUpdateCmd.Flags().Bool("force", false, "Save a snapshot even if the elements are unchanged")
+
+	dataCmd.AddCommand(dataUpdateCmd)
+	dataCmd.AddCommand(dataListCmd)
+	rootCmd.AddCommand(dataCmd)
+}
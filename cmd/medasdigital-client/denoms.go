@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/oxygene76/medasdigital-client/pkg/denom"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+)
+
+var (
+	denomsOnce     sync.Once
+	denomsResolver *denom.Resolver
+)
+
+// denoms resolves the display units of denoms: the base denom of the network,
+// the payment denoms of the config and whatever the chain has bank metadata for
+func denoms() *denom.Resolver {
+	denomsOnce.Do(func() {
+		cfg := loadConfig()
+		known := []denom.Metadata{network.Current().Denom()}
+		for _, d := range cfg.Payment.IBCDenoms {
+			if d.Symbol != "" {
+				known = append(known, denom.Metadata{Base: d.Denom, Display: d.Symbol, Exponent: d.Decimals})
+			}
+		}
+		denomsResolver = denom.NewResolver(func() (denom.Querier, error) {
+			rpcClient, err := client.NewClientFromNode(cfg.Chain.RPCEndpoint)
+			if err != nil {
+				return nil, err
+			}
+			return client.Context{}.WithClient(rpcClient).WithChainID(cfg.Chain.ID), nil
+		}, known...)
+	})
+	return denomsResolver
+}
+
+// formatCoin renders a coin in its display unit, e.g. 12.345678 MEDAS
+func formatCoin(coin sdk.Coin) string {
+	return denoms().Format(coin)
+}
+
+// displayCoins renders a coins string like 1000umedas,5uatom in display
+// units, unparsable ones as they are
+func displayCoins(s string) string {
+	if s == "" {
+		return "0"
+	}
+	coins, err := sdk.ParseCoinsNormalized(s)
+	if err != nil {
+		return s
+	}
+	return denoms().FormatCoins(coins)
+}
+
+// displayChange renders signed per-denom changes like +1000umedas,-5uatom
+func displayChange(s string) string {
+	parts := strings.Split(s, ",")
+	for i, part := range parts {
+		sign := part[:min(1, len(part))]
+		if sign != "+" && sign != "-" {
+			sign = ""
+		}
+		coin, err := sdk.ParseCoinNormalized(strings.TrimPrefix(part, sign))
+		if err != nil {
+			continue
+		}
+		parts[i] = sign + formatCoin(coin)
+	}
+	return strings.Join(parts, ", ")
+}
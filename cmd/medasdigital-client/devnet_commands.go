@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cosmos/cosmos-sdk/crypto/hd"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/oxygene76/medasdigital-client/internal/devnet"
+)
+
+// devnetProfile is the network profile a started devnet is registered as
+const devnetProfile = "devnet"
+
+// devnetKeyPrefix keeps the devnet keys apart from real keys in the client keyring
+const devnetKeyPrefix = "devnet-"
+
+// devnetCmd groups the local test chain commands
+var devnetCmd = &cobra.Command{
+	Use:   "devnet",
+	Short: "Run a local single-node chain for integration tests",
+}
+
+// devnetStartCmd creates and starts the devnet
+var devnetStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start a local chain with funded test accounts",
+	Long: `Start a single-node chain with medasdigitald (or in Docker with --docker),
+fund test accounts and optionally deploy the marketplace contract.
+
+The chain is registered as the network profile "devnet" and the test keys are
+imported into the client keyring (test backend) as devnet-<name>, so the other
+commands can use it right away:
+
+  medasdigital-client devnet start --contract-wasm ./medas_compute.wasm --detach
+  medasdigital-client --network devnet balance --from devnet-alice
+  medasdigital-client devnet stop
+
+The node home is kept between starts, --reset creates a new genesis.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		binary, _ := cmd.Flags().GetString("binary")
+		image, _ := cmd.Flags().GetString("docker")
+		accounts, _ := cmd.Flags().GetStringSlice("accounts")
+		balance, _ := cmd.Flags().GetInt64("balance")
+		wasm, _ := cmd.Flags().GetString("contract-wasm")
+		instantiateMsg, _ := cmd.Flags().GetString("instantiate-msg")
+		rpcPort, _ := cmd.Flags().GetInt("rpc-port")
+		apiPort, _ := cmd.Flags().GetInt("api-port")
+		grpcPort, _ := cmd.Flags().GetInt("grpc-port")
+		detach, _ := cmd.Flags().GetBool("detach")
+		reset, _ := cmd.Flags().GetBool("reset")
+
+		if dir == "" {
+			dir = filepath.Join(homeDir, "devnet")
+		}
+		if reset {
+			devnet.StopDetached(dir)
+			if err := os.RemoveAll(dir); err != nil {
+				return fmt.Errorf("failed to reset %s: %w", dir, err)
+			}
+		}
+
+		ctx := cmd.Context()
+
+		// --chain-id only applies when the genesis is created
+		net := devnet.New(devnet.Options{
+			Dir:            dir,
+			Binary:         binary,
+			Image:          image,
+			ChainID:        chainIDFlag,
+			Accounts:       accounts,
+			Balance:        balance,
+			ContractWasm:   wasm,
+			InstantiateMsg: instantiateMsg,
+			RPCPort:        rpcPort,
+			APIPort:        apiPort,
+			GRPCPort:       grpcPort,
+			Log:            os.Stdout,
+		})
+		info, err := net.Start(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := registerDevnetProfile(info); err != nil {
+			net.Stop()
+			return err
+		}
+		if err := importDevnetKeys(info); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Test keys not imported: %v\n", err)
+		}
+
+		fmt.Printf("\n🌐 Devnet %s\n", info.ChainID)
+		fmt.Printf("   RPC:  %s\n", info.RPCEndpoint)
+		fmt.Printf("   REST: %s\n", info.RESTEndpoint)
+		fmt.Printf("   gRPC: %s\n", info.GRPCEndpoint)
+		if info.ContractAddress != "" {
+			fmt.Printf("   Contract: %s (code %d)\n", info.ContractAddress, info.CodeID)
+		}
+		fmt.Printf("   Use it with: --network %s\n", devnetProfile)
+
+		if detach {
+			if err := net.Detach(); err != nil {
+				return err
+			}
+			fmt.Println("\n✅ Devnet running in the background, stop it with: medasdigital-client devnet stop")
+			return nil
+		}
+
+		fmt.Println("\nPress Ctrl+C to stop")
+		select {
+		case <-ctx.Done():
+		case <-waitChan(net.Wait):
+			return fmt.Errorf("node exited, see %s", filepath.Join(dir, "node.log"))
+		}
+		fmt.Println("\n🛑 Stopping devnet...")
+		return net.Stop()
+	},
+}
+
+// devnetStopCmd stops a devnet started with --detach
+var devnetStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop a devnet started with --detach",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		if dir == "" {
+			dir = filepath.Join(homeDir, "devnet")
+		}
+		if err := devnet.StopDetached(dir); err != nil {
+			return err
+		}
+		fmt.Println("✅ Devnet stopped")
+		return nil
+	},
+}
+
+// devnetInfoCmd prints the endpoints and test accounts
+var devnetInfoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show endpoints and test accounts of the devnet",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		if dir == "" {
+			dir = filepath.Join(homeDir, "devnet")
+		}
+		info, err := devnet.Load(dir)
+		if err != nil {
+			return fmt.Errorf("no devnet in %s: %w", dir, err)
+		}
+
+		fmt.Printf("Chain ID: %s\n", info.ChainID)
+		fmt.Printf("RPC:      %s\n", info.RPCEndpoint)
+		fmt.Printf("REST:     %s\n", info.RESTEndpoint)
+		fmt.Printf("gRPC:     %s\n", info.GRPCEndpoint)
+		if info.ContractAddress != "" {
+			fmt.Printf("Contract: %s (code %d)\n", info.ContractAddress, info.CodeID)
+		}
+		fmt.Println("\nAccounts (mnemonics in devnet.json):")
+		for _, acc := range info.Accounts {
+			fmt.Printf("  %-20s %s\n", devnetKeyPrefix+acc.Name, acc.Address)
+		}
+		return nil
+	},
+}
+
+// registerDevnetProfile writes the devnet endpoints to networks.devnet in the config
+func registerDevnetProfile(info *devnet.Info) error {
+	doc, err := readConfigDoc(cfgFile)
+	if err != nil {
+		return err
+	}
+	values := [][2]string{
+		{"chain_id", info.ChainID},
+		{"rpc_endpoint", info.RPCEndpoint},
+		{"rest_endpoint", info.RESTEndpoint},
+		{"grpc_endpoint", info.GRPCEndpoint},
+		{"base_denom", info.Denom},
+		{"contract_address", info.ContractAddress},
+	}
+	for _, kv := range values {
+		path := []string{"networks", devnetProfile, kv[0]}
+		if kv[1] == "" {
+			yamlDelete(doc, path)
+			continue
+		}
+		yamlSet(doc, path, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: kv[1]})
+	}
+
+	data, err := encodeConfigDoc(doc)
+	if err != nil {
+		return err
+	}
+	if err := writeConfigFile(cfgFile, data); err != nil {
+		return err
+	}
+	fmt.Printf("📝 Network profile %q written to %s\n", devnetProfile, cfgFile)
+	return nil
+}
+
+// importDevnetKeys adds the test accounts to the client keyring
+func importDevnetKeys(info *devnet.Info) error {
+	clientCtx, err := initKeysClientContextWithBackend("test")
+	if err != nil {
+		return err
+	}
+	for _, acc := range info.Accounts {
+		name := devnetKeyPrefix + acc.Name
+		if existing, err := clientCtx.Keyring.Key(name); err == nil {
+			if addr, _ := existing.GetAddress(); addr.String() == acc.Address {
+				continue
+			}
+			// The key of an earlier devnet
+			if err := clientCtx.Keyring.Delete(name); err != nil {
+				return err
+			}
+		}
+		if _, err := clientCtx.Keyring.NewAccount(name, acc.Mnemonic, "", sdk.FullFundraiserPath, hd.Secp256k1); err != nil {
+			return fmt.Errorf("failed to import %s: %w", name, err)
+		}
+		fmt.Printf("🔑 Imported %s (%s)\n", name, acc.Address)
+	}
+	return nil
+}
+
+// waitChan runs wait in the background and reports when it returned
+func waitChan(wait func() error) <-chan struct{} {
+	ch := make(chan struct{})
+	go func() {
+		wait()
+		close(ch)
+	}()
+	return ch
+}
+
+func init() {
+	rootCmd.AddCommand(devnetCmd)
+	devnetCmd.AddCommand(devnetStartCmd)
+	devnetCmd.AddCommand(devnetStopCmd)
+	devnetCmd.AddCommand(devnetInfoCmd)
+
+	devnetCmd.PersistentFlags().String("dir", "", "Node home (default ~/.medasdigital-client/devnet)")
+
+	devnetStartCmd.Flags().String("binary", devnet.DefaultBinary, "medasdigitald binary")
+	devnetStartCmd.Flags().String("docker", "", "Run the node in this Docker image instead of the local binary")
+	devnetStartCmd.Flags().StringSlice("accounts", devnet.DefaultAccounts, "Funded test accounts besides the validator")
+	devnetStartCmd.Flags().Int64("balance", 1_000_000_000_000, "Base units for every test account")
+	devnetStartCmd.Flags().String("contract-wasm", "", "Store and instantiate this contract on the first start")
+	devnetStartCmd.Flags().String("instantiate-msg", "", "Instantiate message JSON (default: validator is the community pool, 15% fee)")
+	devnetStartCmd.Flags().Int("rpc-port", 26657, "RPC port")
+	devnetStartCmd.Flags().Int("api-port", 1317, "REST API port")
+	devnetStartCmd.Flags().Int("grpc-port", 9090, "gRPC port")
+	devnetStartCmd.Flags().Bool("detach", false, "Leave the node running in the background")
+	devnetStartCmd.Flags().Bool("reset", false, "Delete the node home and create a new genesis")
+}
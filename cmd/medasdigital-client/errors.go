@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+)
+
+// Exit codes scripts can branch on, documented in the README
+const (
+	exitError               = 1
+	exitKeyNotFound         = 3
+	exitInsufficientFunds   = 4
+	exitTxNotFound          = 5
+	exitPaymentInsufficient = 6
+	exitGPUUnavailable      = 7
+	exitTimeout             = 124 // as timeout(1)
+	exitCanceled            = 130 // as a shell after Ctrl-C
+)
+
+// errorClasses maps errors to exit codes, the first match wins
+var errorClasses = []struct {
+	err  error
+	code int
+	name string
+}{
+	{blockchain.ErrTxNotFound, exitTxNotFound, "tx_not_found"},
+	{blockchain.ErrKeyNotFound, exitKeyNotFound, "key_not_found"},
+	{blockchain.ErrPaymentInsufficient, exitPaymentInsufficient, "payment_insufficient"},
+	{blockchain.ErrInsufficientFunds, exitInsufficientFunds, "insufficient_funds"},
+	{compute.ErrGPUUnavailable, exitGPUUnavailable, "gpu_unavailable"},
+	{context.DeadlineExceeded, exitTimeout, "timeout"},
+	{context.Canceled, exitCanceled, "canceled"},
+}
+
+// classifyError returns the exit code and a stable name of err
+func classifyError(err error) (int, string) {
+	for _, c := range errorClasses {
+		if errors.Is(err, c.err) {
+			return c.code, c.name
+		}
+	}
+	return exitError, "error"
+}
+
+// jsonErrors reports whether errors are printed as JSON: with
+// --error-format json, $MEDAS_ERROR_FORMAT=json or when the command was asked
+// for --json output
+func jsonErrors(cmd *cobra.Command) bool {
+	if viper.GetString("error_format") == "json" {
+		return true
+	}
+	if cmd == nil {
+		return false
+	}
+	asJSON, _ := cmd.Flags().GetBool("json")
+	return asJSON
+}
+
+// printError writes err to w, as text or as
+// {"error": ..., "code": ..., "exit_code": ...}, and returns the exit code
+func printError(w io.Writer, cmd *cobra.Command, err error) int {
+	code, name := classifyError(err)
+	if jsonErrors(cmd) {
+		json.NewEncoder(w).Encode(struct {
+			Error    string `json:"error"`
+			Code     string `json:"code"`
+			ExitCode int    `json:"exit_code"`
+		}{err.Error(), name, code})
+		return code
+	}
+	fmt.Fprintf(w, "Error: %v\n", err)
+	return code
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+)
+
+// blockCmd shows the transactions of a block
+var blockCmd = &cobra.Command{
+	Use:   "block <height|latest>",
+	Short: "Show a block and a summary of its transactions",
+	Long: `Show the header of a block and one line per transaction: sender, messages,
+memo, fee and whether it succeeded. Use "tx show <hash>" for the details of one.
+
+  medasdigital-client block latest
+  medasdigital-client block 1234567 --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		var height int64
+		if args[0] != "latest" {
+			h, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil || h <= 0 {
+				return fmt.Errorf("invalid height %q, use a positive number or latest", args[0])
+			}
+			height = h
+		}
+
+		bc, err := createFullBlockchainClient(client.Context{}, loadConfig())
+		if err != nil {
+			return err
+		}
+		block, err := bc.Block(cmd.Context(), height)
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			return printIndentedJSON(block)
+		}
+
+		fmt.Printf("🧱 Block %d\n", block.Height)
+		fmt.Printf("   Hash: %s\n", block.Hash)
+		fmt.Printf("   Time: %s\n", block.Time.Local().Format("2006-01-02 15:04:05"))
+		fmt.Printf("   Proposer: %s\n", block.Proposer)
+		fmt.Printf("   Transactions: %d\n", len(block.Txs))
+		for _, tx := range block.Txs {
+			printTxSummary(tx)
+		}
+		return nil
+	},
+}
+
+// mempoolCmd shows what waits for a block
+var mempoolCmd = &cobra.Command{
+	Use:   "mempool",
+	Short: "Show the unconfirmed transactions of the node",
+	Long: `Show how many transactions wait in the mempool of the RPC node and a summary of
+the first of them. With --address only transactions from or to the address are
+listed, e.g. to see whether a payment is stuck.
+
+  medasdigital-client mempool
+  medasdigital-client mempool --address medas1client... --limit 100`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		limit, _ := cmd.Flags().GetInt("limit")
+		address, _ := cmd.Flags().GetString("address")
+		if limit < 1 {
+			return fmt.Errorf("--limit must be at least 1")
+		}
+		if address != "" {
+			if _, err := sdk.AccAddressFromBech32(address); err != nil {
+				return fmt.Errorf("invalid address %s: %w", address, err)
+			}
+		}
+
+		bc, err := createFullBlockchainClient(client.Context{}, loadConfig())
+		if err != nil {
+			return err
+		}
+		mempool, err := bc.Mempool(cmd.Context(), limit)
+		if err != nil {
+			return err
+		}
+		listed := len(mempool.Txs)
+		if address != "" {
+			mempool.Txs = txsOf(mempool.Txs, address)
+		}
+		if asJSON {
+			return printIndentedJSON(mempool)
+		}
+
+		fmt.Printf("⏳ %d unconfirmed transaction(s), %d bytes\n", mempool.Count, mempool.TotalBytes)
+		if address != "" {
+			fmt.Printf("   %d of the first %d involve %s\n", len(mempool.Txs), listed, address)
+		} else if listed < mempool.Count {
+			fmt.Printf("   Showing the first %d, see --limit\n", listed)
+		}
+		for _, tx := range mempool.Txs {
+			printTxSummary(tx)
+		}
+		return nil
+	},
+}
+
+// txsOf keeps the transactions sent by or to address
+func txsOf(txs []blockchain.TxSummary, address string) []blockchain.TxSummary {
+	var matched []blockchain.TxSummary
+	for _, tx := range txs {
+		found := tx.Sender == address
+		for _, m := range tx.Messages {
+			found = found || m.To == address
+		}
+		if found {
+			matched = append(matched, tx)
+		}
+	}
+	return matched
+}
+
+func printTxSummary(tx blockchain.TxSummary) {
+	status := "⏳"
+	if tx.Code != nil {
+		status = "✅"
+		if *tx.Code != 0 {
+			status = "❌"
+		}
+	}
+	fmt.Printf("\n%s %s (%d bytes)\n", status, tx.Hash, tx.Size)
+	if tx.Error != "" {
+		fmt.Printf("   Not decodable: %s\n", tx.Error)
+		return
+	}
+	if tx.Sender != "" {
+		fmt.Printf("   From: %s\n", tx.Sender)
+	}
+	for _, m := range tx.Messages {
+		if m.To == "" {
+			fmt.Printf("   %s\n", m.Type)
+			continue
+		}
+		amount := m.Amount
+		if coins, err := sdk.ParseCoinsNormalized(m.Amount); err == nil {
+			amount = denoms().FormatCoins(coins)
+		}
+		fmt.Printf("   %s: %s to %s\n", m.Type, amount, m.To)
+	}
+	if tx.Memo != "" {
+		fmt.Printf("   Memo: %s\n", tx.Memo)
+	}
+	fee := tx.Fee
+	if coins, err := sdk.ParseCoinsNormalized(tx.Fee); err == nil && !coins.IsZero() {
+		fee = denoms().FormatCoins(coins)
+	}
+	if tx.GasUsed > 0 {
+		fmt.Printf("   Fee: %s, gas %d used of %d\n", fee, tx.GasUsed, tx.GasWanted)
+	} else {
+		fmt.Printf("   Fee: %s, gas %d\n", fee, tx.GasWanted)
+	}
+	if tx.Log != "" {
+		fmt.Printf("   Error: %s\n", strings.TrimSpace(tx.Log))
+	}
+}
+
+// printIndentedJSON prints v as indented JSON
+func printIndentedJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(blockCmd)
+	rootCmd.AddCommand(mempoolCmd)
+
+	blockCmd.Flags().Bool("json", false, "Print the block as JSON")
+	mempoolCmd.Flags().Bool("json", false, "Print the mempool as JSON")
+	mempoolCmd.Flags().Int("limit", 30, "Summarize at most this many transactions")
+	mempoolCmd.Flags().String("address", "", "Only list transactions from or to this address")
+}
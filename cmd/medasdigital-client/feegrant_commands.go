@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/feegrant"
+)
+
+// feegrantMessageAliases are short names for --allowed-messages
+var feegrantMessageAliases = map[string]string{
+	"send":     "/cosmos.bank.v1beta1.MsgSend",         // registrations, memo payments
+	"contract": "/cosmwasm.wasm.v1.MsgExecuteContract", // contract job submissions
+}
+
+var feegrantCmd = &cobra.Command{
+	Use:   "feegrant",
+	Short: "Pay the transaction fees of other accounts",
+	Long: `Grant an account a fee allowance, so it can send transactions without holding
+tokens for gas. The grantee uses the grant with --fee-granter <granter address>,
+e.g. a lab PI sponsors the registration and job submissions of students:
+
+  medasdigital-client feegrant grant medas1student... --from pi-key \
+    --spend-limit 5000000umedas --expire-in 720h --allowed-messages send,contract
+  medasdigital-client register --from student-key --fee-granter medas1pi...`,
+}
+
+var feegrantGrantCmd = &cobra.Command{
+	Use:   "grant <grantee>",
+	Short: "Allow an account to pay its transaction fees from yours",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		spendLimit, _ := cmd.Flags().GetString("spend-limit")
+		expireIn, _ := cmd.Flags().GetDuration("expire-in")
+		allowed, _ := cmd.Flags().GetStringSlice("allowed-messages")
+
+		var allowance feegrant.Allowance
+		if spendLimit != "" {
+			coins, err := sdk.ParseCoinsNormalized(spendLimit)
+			if err != nil {
+				return fmt.Errorf("invalid --spend-limit: %w", err)
+			}
+			allowance.SpendLimit = coins
+		}
+		if expireIn > 0 {
+			expiration := time.Now().Add(expireIn).UTC()
+			allowance.Expiration = &expiration
+		}
+		for _, msg := range allowed {
+			if typeURL, ok := feegrantMessageAliases[msg]; ok {
+				msg = typeURL
+			}
+			if !strings.HasPrefix(msg, "/") {
+				return fmt.Errorf("invalid message type %q, use send, contract or a type URL like /cosmos.bank.v1beta1.MsgSend", msg)
+			}
+			allowance.AllowedMessages = append(allowance.AllowedMessages, msg)
+		}
+
+		return broadcastFeegrant(cmd, args[0], func(granter string) sdk.Msg {
+			return &feegrant.MsgGrantAllowance{Granter: granter, Grantee: args[0], Allowance: allowance}
+		})
+	},
+}
+
+var feegrantRevokeCmd = &cobra.Command{
+	Use:   "revoke <grantee>",
+	Short: "Revoke the fee allowance of an account",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return broadcastFeegrant(cmd, args[0], func(granter string) sdk.Msg {
+			return &feegrant.MsgRevokeAllowance{Granter: granter, Grantee: args[0]}
+		})
+	},
+}
+
+// broadcastFeegrant sends the message built for the --from key, which pays
+// the fee itself
+func broadcastFeegrant(cmd *cobra.Command, grantee string, build func(granter string) sdk.Msg) error {
+	from, _ := cmd.Flags().GetString("from")
+	if from == "" {
+		return fmt.Errorf("--from is required")
+	}
+	if _, err := sdk.AccAddressFromBech32(grantee); err != nil {
+		return fmt.Errorf("invalid grantee address: %w", err)
+	}
+
+	clientCtx, err := signingClientContext(cmd.Context(), from, keyringBackendFlag(cmd))
+	if err != nil {
+		return err
+	}
+	clientCtx = clientCtx.WithFeeGranterAddress(nil)
+	granter := clientCtx.GetFromAddress().String()
+	if granter == grantee {
+		return fmt.Errorf("cannot grant a fee allowance to yourself")
+	}
+
+	res, err := blockchain.SignAndBroadcast(cmd.Context(), clientCtx, loadConfig().Chain.GasPrice, build(granter))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Transaction sent: %s\n", res.TxHash)
+	return nil
+}
+
+// feeGranter returns the account set with --fee-granter, nil without one
+func feeGranter() (sdk.AccAddress, error) {
+	granter := viper.GetString("client.fee_granter")
+	if granter == "" {
+		return nil, nil
+	}
+	addr, err := sdk.AccAddressFromBech32(granter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fee granter %q: %w", granter, err)
+	}
+	return addr, nil
+}
+
+func init() {
+	feegrantCmd.AddCommand(feegrantGrantCmd)
+	feegrantCmd.AddCommand(feegrantRevokeCmd)
+	rootCmd.AddCommand(feegrantCmd)
+
+	for _, c := range []*cobra.Command{feegrantGrantCmd, feegrantRevokeCmd} {
+		c.Flags().String("from", "", "Key of the granter (required)")
+		c.Flags().String("keyring-backend", "", "Keyring backend (test|file|os, default client.keyring_backend)")
+	}
+	feegrantGrantCmd.Flags().String("spend-limit", "", "Maximum the grantee may spend on fees, e.g. 5000000umedas (default unlimited)")
+	feegrantGrantCmd.Flags().Duration("expire-in", 0, "Grant expires after this duration, e.g. 720h (default never)")
+	feegrantGrantCmd.Flags().StringSlice("allowed-messages", nil, "Only pay for these messages: send, contract or type URLs (default all)")
+}
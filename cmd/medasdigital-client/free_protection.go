@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+	"github.com/oxygene76/medasdigital-client/pkg/ratelimit"
+)
+
+// Abuse protection of the free service, rate limits per IP alone do not stop
+// clients rotating their addresses
+const (
+	protectionOff     = "off"
+	protectionPoW     = "pow"
+	protectionDeposit = "deposit"
+)
+
+// depositHeader names the transaction of a deposit
+const depositHeader = "X-Deposit-Tx"
+
+// powChallengeTTL is how long a client has to solve a challenge
+const powChallengeTTL = 5 * time.Minute
+
+var (
+	errDepositMissing   = errors.New("deposit required")
+	errDepositExhausted = errors.New("deposit used up")
+)
+
+// depositGate admits calculations paid for by a small transfer to the
+// deposit address: each deposit covers a number of calculations within
+// maxAge of its block time
+type depositGate struct {
+	address   string
+	amount    sdk.Coin
+	requests  int
+	maxAge    time.Duration
+	transfers func(ctx context.Context, txHash, recipient string) ([]blockchain.Transfer, error)
+	used      ratelimit.Store
+
+	mu       sync.Mutex
+	verified map[string]time.Time // deposit tx → end of its use
+}
+
+func newDepositGate(address string, amount float64, requests int, maxAge time.Duration, bc *blockchain.Client, used ratelimit.Store) (*depositGate, error) {
+	if _, err := sdk.AccAddressFromBech32(address); err != nil {
+		return nil, fmt.Errorf("--deposit-address: %w", err)
+	}
+	if amount <= 0 || requests <= 0 || maxAge <= 0 {
+		return nil, fmt.Errorf("deposit amount, requests and max age must be positive")
+	}
+	net := network.Current()
+	return &depositGate{
+		address:   address,
+		amount:    sdk.NewInt64Coin(net.BaseDenom, net.ToBase(amount)),
+		requests:  requests,
+		maxAge:    maxAge,
+		transfers: bc.TransfersInTx,
+		used:      used,
+		verified:  make(map[string]time.Time),
+	}, nil
+}
+
+// Admit counts a calculation against a deposit and returns how many remain
+func (d *depositGate) Admit(ctx context.Context, txHash string) (int, error) {
+	txHash = strings.ToUpper(strings.TrimSpace(txHash))
+	if txHash == "" {
+		return 0, errDepositMissing
+	}
+	until, err := d.verify(ctx, txHash)
+	if err != nil {
+		return 0, err
+	}
+	remaining := time.Until(until)
+	if remaining <= 0 {
+		return 0, fmt.Errorf("deposit %s is older than %v", txHash, d.maxAge)
+	}
+	count, _, err := d.used.Hit("deposit:"+txHash, remaining)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deposit: %w", err)
+	}
+	if count > d.requests {
+		return 0, fmt.Errorf("%w: %s paid for %d calculations", errDepositExhausted, txHash, d.requests)
+	}
+	return d.requests - count, nil
+}
+
+// verify looks up a deposit once and returns when it stops counting
+func (d *depositGate) verify(ctx context.Context, txHash string) (time.Time, error) {
+	d.mu.Lock()
+	until, ok := d.verified[txHash]
+	d.mu.Unlock()
+	if ok {
+		return until, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	transfers, err := d.transfers(ctx, txHash, d.address)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("deposit %s: %w", txHash, err)
+	}
+	paid := sdk.NewInt64Coin(d.amount.Denom, 0)
+	var blockTime time.Time
+	for _, t := range transfers {
+		paid = paid.AddAmount(t.Amount.AmountOf(d.amount.Denom))
+		blockTime = t.Time
+	}
+	if paid.IsLT(d.amount) {
+		return time.Time{}, fmt.Errorf("deposit %s sent %s to %s, %s required", txHash, paid, d.address, d.amount)
+	}
+	if blockTime.IsZero() {
+		return time.Time{}, fmt.Errorf("deposit %s: block time unknown", txHash)
+	}
+
+	until = blockTime.Add(d.maxAge)
+	d.mu.Lock()
+	for hash, end := range d.verified {
+		if time.Now().After(end) {
+			delete(d.verified, hash)
+		}
+	}
+	d.verified[txHash] = until
+	d.mu.Unlock()
+	return until, nil
+}
+
+// configureAbuseProtection sets up the mode of --abuse-protection, solved
+// challenges and used deposits are counted in the rate limit store
+func configureAbuseProtection(cmd *cobra.Command, sfts *SecureFreeTestService, store ratelimit.Store) error {
+	mode, _ := cmd.Flags().GetString("abuse-protection")
+	switch strings.ToLower(mode) {
+	case "", protectionOff:
+		return nil
+	case protectionPoW:
+		difficulty, _ := cmd.Flags().GetInt("pow-difficulty")
+		secret, _ := cmd.Flags().GetString("pow-secret")
+		pow, err := ratelimit.NewPoW(secret, difficulty, powChallengeTTL, store)
+		if err != nil {
+			return err
+		}
+		sfts.SetAbuseProtection(pow, nil)
+		fmt.Printf("🧩 Proof of work required: %d bits per calculation\n", difficulty)
+	case protectionDeposit:
+		address, _ := cmd.Flags().GetString("deposit-address")
+		amount, _ := cmd.Flags().GetFloat64("deposit-amount")
+		requests, _ := cmd.Flags().GetInt("deposit-requests")
+		maxAge, _ := cmd.Flags().GetDuration("deposit-max-age")
+		bc, err := createFullBlockchainClient(client.Context{}, loadConfig())
+		if err != nil {
+			return fmt.Errorf("deposits need a chain connection: %w", err)
+		}
+		deposits, err := newDepositGate(address, amount, requests, maxAge, bc, store)
+		if err != nil {
+			return err
+		}
+		sfts.SetAbuseProtection(nil, deposits)
+		fmt.Printf("🪙 Deposit required: %s to %s per %d calculations\n", formatCoin(deposits.amount), address, requests)
+	default:
+		return fmt.Errorf("unknown --abuse-protection %q (off, pow, deposit)", mode)
+	}
+	return nil
+}
+
+// SetAbuseProtection requires a proof of work or a deposit for calculations,
+// at most one of them
+func (sfts *SecureFreeTestService) SetAbuseProtection(pow *ratelimit.PoW, deposits *depositGate) {
+	sfts.pow = pow
+	sfts.deposits = deposits
+}
+
+// abuseProtection is the abuse protection in status and limits, the fields
+// of the other modes are omitted
+type abuseProtection struct {
+	Mode       string `json:"mode"` // off, pow or deposit
+	Difficulty int    `json:"difficulty,omitempty"`
+	Challenge  string `json:"challenge,omitempty"`
+	Address    string `json:"address,omitempty"`
+	Amount     string `json:"amount,omitempty"`
+	Requests   int    `json:"requests,omitempty"`
+	MaxAge     string `json:"max_age,omitempty"`
+	Header     string `json:"header,omitempty"`
+}
+
+// protectionInfo describes the abuse protection for status and limits
+func (sfts *SecureFreeTestService) protectionInfo() abuseProtection {
+	switch {
+	case sfts.pow != nil:
+		return abuseProtection{
+			Mode:       protectionPoW,
+			Difficulty: sfts.pow.Difficulty(),
+			Challenge:  "GET /api/v1/challenge",
+			Header:     ratelimit.PoWHeader,
+		}
+	case sfts.deposits != nil:
+		return abuseProtection{
+			Mode:     protectionDeposit,
+			Address:  sfts.deposits.address,
+			Amount:   formatCoin(sfts.deposits.amount),
+			Requests: sfts.deposits.requests,
+			MaxAge:   sfts.deposits.maxAge.String(),
+			Header:   depositHeader,
+		}
+	default:
+		return abuseProtection{Mode: protectionOff}
+	}
+}
+
+// handleChallenge issues a proof of work challenge
+func (sfts *SecureFreeTestService) handleChallenge(w http.ResponseWriter, r *http.Request) {
+	challenge, err := sfts.pow.Issue()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Could not create challenge: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(challenge)
+}
+
+// protectionMiddleware rejects calculations without a solved challenge or a
+// valid deposit, after the rate limit so failed attempts count
+func (sfts *SecureFreeTestService) protectionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch {
+		case sfts.pow != nil:
+			if err := sfts.pow.Verify(r.Header.Get(ratelimit.PoWHeader)); err != nil {
+				status := http.StatusForbidden
+				if errors.Is(err, ratelimit.ErrPoWMissing) {
+					status = http.StatusPreconditionRequired
+				}
+				http.Error(w, fmt.Sprintf("%v: solve a challenge from GET /api/v1/challenge (%d bits) and send it in %s", err, sfts.pow.Difficulty(), ratelimit.PoWHeader), status)
+				return
+			}
+		case sfts.deposits != nil:
+			remaining, err := sfts.deposits.Admit(r.Context(), r.Header.Get(depositHeader))
+			if err != nil {
+				if errors.Is(err, errDepositMissing) || errors.Is(err, errDepositExhausted) {
+					http.Error(w, fmt.Sprintf("%v: send %s to %s and pass the transaction hash in %s, each deposit pays for %d calculations",
+						err, formatCoin(sfts.deposits.amount), sfts.deposits.address, depositHeader, sfts.deposits.requests), http.StatusPaymentRequired)
+					return
+				}
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			w.Header().Set("X-Deposit-Remaining", strconv.Itoa(remaining))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxBodyBytes is the request body limit when the config sets none
+const defaultMaxBodyBytes = 10 * 1024
+
+// CORSConfig controls which web origins may call a service from the browser
+type CORSConfig struct {
+	AllowedOrigins   []string `yaml:"allowed_origins,omitempty" mapstructure:"allowed_origins"` // "*", exact origins or https://*.example.org
+	AllowedMethods   []string `yaml:"allowed_methods,omitempty" mapstructure:"allowed_methods"`
+	AllowedHeaders   []string `yaml:"allowed_headers,omitempty" mapstructure:"allowed_headers"`
+	AllowCredentials bool     `yaml:"allow_credentials,omitempty" mapstructure:"allow_credentials"`
+	MaxAge           int      `yaml:"max_age,omitempty" mapstructure:"max_age"` // seconds browsers may cache a preflight
+}
+
+// HTTPRouteConfig overrides the HTTP settings for paths starting with Path
+type HTTPRouteConfig struct {
+	Path         string      `yaml:"path" mapstructure:"path"`
+	CORS         *CORSConfig `yaml:"cors,omitempty" mapstructure:"cors"`
+	MaxBodyBytes int64       `yaml:"max_body_bytes,omitempty" mapstructure:"max_body_bytes"`
+}
+
+// HTTPConfig holds the CORS, body size and security header settings of the HTTP services
+type HTTPConfig struct {
+	CORS            CORSConfig        `yaml:"cors" mapstructure:"cors"`
+	MaxBodyBytes    int64             `yaml:"max_body_bytes" mapstructure:"max_body_bytes"`
+	SecurityHeaders map[string]string `yaml:"security_headers,omitempty" mapstructure:"security_headers"`
+	Routes          []HTTPRouteConfig `yaml:"routes,omitempty" mapstructure:"routes"`
+}
+
+// DefaultHTTPConfig is what the services used before the settings were configurable
+func DefaultHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Content-Type", "Authorization"},
+		},
+		MaxBodyBytes: defaultMaxBodyBytes,
+		SecurityHeaders: map[string]string{
+			"X-Content-Type-Options": "nosniff",
+			"X-Frame-Options":        "DENY",
+		},
+	}
+}
+
+// withDefaults fills the settings the config left out
+func (c HTTPConfig) withDefaults() HTTPConfig {
+	def := DefaultHTTPConfig()
+	if c.CORS.AllowedOrigins == nil {
+		c.CORS.AllowedOrigins = def.CORS.AllowedOrigins
+	}
+	if len(c.CORS.AllowedMethods) == 0 {
+		c.CORS.AllowedMethods = def.CORS.AllowedMethods
+	}
+	if len(c.CORS.AllowedHeaders) == 0 {
+		c.CORS.AllowedHeaders = def.CORS.AllowedHeaders
+	}
+	if c.MaxBodyBytes == 0 {
+		c.MaxBodyBytes = def.MaxBodyBytes
+	}
+	if c.SecurityHeaders == nil {
+		c.SecurityHeaders = def.SecurityHeaders
+	}
+	// Route overrides inherit what they leave out from the top-level policy
+	routes := make([]HTTPRouteConfig, len(c.Routes))
+	for i, route := range c.Routes {
+		if route.CORS != nil {
+			cors := *route.CORS
+			if cors.AllowedOrigins == nil {
+				cors.AllowedOrigins = c.CORS.AllowedOrigins
+			}
+			if len(cors.AllowedMethods) == 0 {
+				cors.AllowedMethods = c.CORS.AllowedMethods
+			}
+			if len(cors.AllowedHeaders) == 0 {
+				cors.AllowedHeaders = c.CORS.AllowedHeaders
+			}
+			route.CORS = &cors
+		}
+		routes[i] = route
+	}
+	c.Routes = routes
+	return c
+}
+
+// Validate checks the configured origins
+func (c HTTPConfig) Validate() error {
+	check := func(where string, cors CORSConfig) error {
+		for _, origin := range cors.AllowedOrigins {
+			if origin == "*" {
+				continue
+			}
+			u, err := url.Parse(strings.Replace(origin, "*.", "", 1))
+			if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+				return fmt.Errorf("%s: invalid CORS origin %q, expected scheme://host[:port]", where, origin)
+			}
+		}
+		return nil
+	}
+	if err := check("http.cors", c.CORS); err != nil {
+		return err
+	}
+	for _, route := range c.Routes {
+		if !strings.HasPrefix(route.Path, "/") {
+			return fmt.Errorf("http.routes: path %q must start with /", route.Path)
+		}
+		if route.CORS != nil {
+			if err := check("http.routes "+route.Path, *route.CORS); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// forPath returns the settings of the longest route override matching path
+func (c HTTPConfig) forPath(path string) (CORSConfig, int64) {
+	cors, maxBody := c.CORS, c.MaxBodyBytes
+	matched := -1
+	for _, route := range c.Routes {
+		if !strings.HasPrefix(path, route.Path) || len(route.Path) <= matched {
+			continue
+		}
+		matched = len(route.Path)
+		cors, maxBody = c.CORS, c.MaxBodyBytes
+		if route.CORS != nil {
+			cors = *route.CORS
+		}
+		if route.MaxBodyBytes != 0 {
+			maxBody = route.MaxBodyBytes
+		}
+	}
+	return cors, maxBody
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin, empty if not allowed
+func (c CORSConfig) allowOrigin(origin string) string {
+	for _, allowed := range c.AllowedOrigins {
+		switch {
+		case allowed == "*":
+			// Browsers reject * together with credentials
+			if c.AllowCredentials && origin != "" {
+				return origin
+			}
+			return "*"
+		case strings.EqualFold(allowed, origin):
+			return origin
+		case strings.Contains(allowed, "://*."):
+			scheme, domain, _ := strings.Cut(allowed, "://*.")
+			if strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(origin, "."+domain) {
+				return origin
+			}
+		}
+	}
+	return ""
+}
+
+// Wrap applies security headers, the body size limit and CORS to every request,
+// including preflights that match no route
+func (c HTTPConfig) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, value := range c.SecurityHeaders {
+			w.Header().Set(name, value)
+		}
+		cors, maxBody := c.forPath(r.URL.Path)
+
+		origin := r.Header.Get("Origin")
+		allowOrigin := cors.allowOrigin(origin)
+		if allowOrigin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+			if allowOrigin != "*" {
+				w.Header().Add("Vary", "Origin")
+			}
+			if cors.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(cors.AllowedMethods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(cors.AllowedHeaders, ", "))
+			if cors.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(cors.MaxAge))
+			}
+		}
+
+		if r.Method == "OPTIONS" {
+			if origin != "" && allowOrigin == "" {
+				http.Error(w, "Origin not allowed", http.StatusForbidden)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if maxBody > 0 {
+			if r.ContentLength > maxBody {
+				http.Error(w, "Request too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			// Chunked bodies have no Content-Length
+			r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
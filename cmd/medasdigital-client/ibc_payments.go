@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+	"github.com/oxygene76/medasdigital-client/pkg/denom"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+)
+
+// baseUnits is an amount in base units without the int64 limit, 18-decimal
+// tokens easily exceed it. It is written as a JSON number like the int64
+// amounts before it and also read from a string.
+type baseUnits struct {
+	sdkmath.Int
+}
+
+// MarshalJSON writes the amount as a number
+func (u baseUnits) MarshalJSON() ([]byte, error) {
+	return []byte(u.value().String()), nil
+}
+
+// UnmarshalJSON reads a number or a string
+func (u *baseUnits) UnmarshalJSON(data []byte) error {
+	amount, ok := sdkmath.NewIntFromString(strings.Trim(string(data), `"`))
+	if !ok {
+		return fmt.Errorf("invalid amount %s", data)
+	}
+	u.Int = amount
+	return nil
+}
+
+// value is the amount, zero when it was never set
+func (u baseUnits) value() sdkmath.Int {
+	if u.Int.IsNil() {
+		return sdkmath.ZeroInt()
+	}
+	return u.Int
+}
+
+// unitsFloat converts base units to a float for MEDAS values
+func unitsFloat(amount sdkmath.Int) float64 {
+	f, _ := new(big.Float).SetInt(amount.BigInt()).Float64()
+	return f
+}
+
+// paymentCoin picks the coin a transfer pays with, MEDAS first, then the
+// configured IBC denoms. It returns the coin and its value in MEDAS.
+func (rps *RealPaymentService) paymentCoin(coins sdk.Coins) (sdk.Coin, float64, bool) {
+	net := network.Current()
+	if amount := coins.AmountOf(net.BaseDenom); amount.IsPositive() {
+		return sdk.NewCoin(net.BaseDenom, amount), unitsFloat(amount) * net.ToDisplay(1), true
+	}
+	for _, coin := range coins {
+		if d, ok := rps.paymentDenoms[coin.Denom]; ok && coin.Amount.IsPositive() {
+			return coin, d.ToMEDAS(coin.Amount), true
+		}
+	}
+	return sdk.Coin{}, 0, false
+}
+
+// acceptsDenom reports whether payments in denom are accepted
+func (rps *RealPaymentService) acceptsDenom(denom string) bool {
+	if denom == network.Current().BaseDenom {
+		return true
+	}
+	_, ok := rps.paymentDenoms[denom]
+	return ok
+}
+
+// priceIn converts a MEDAS price to base units of denom
+func (rps *RealPaymentService) priceIn(denom string, medas float64) sdkmath.Int {
+	if d, ok := rps.paymentDenoms[denom]; ok {
+		return d.FromMEDAS(medas)
+	}
+	return sdkmath.NewInt(network.Current().ToBase(medas))
+}
+
+// minimumPayment is the lowest amount of denom accepted for a MEDAS price, the
+// full price in strict mode
+func (rps *RealPaymentService) minimumPayment(denom string, medas float64) sdkmath.Int {
+	price := rps.priceIn(denom, medas)
+	slack, _ := new(big.Float).Mul(new(big.Float).SetInt(price.BigInt()), big.NewFloat(rps.tolerance())).Int(nil)
+	return price.Sub(sdkmath.NewIntFromBigInt(slack))
+}
+
+// refundFeeIn is the refund network fee expressed in denom. Denoms without a
+// rate are refunded in full, the service carries the fee.
+func (rps *RealPaymentService) refundFeeIn(denom string) sdkmath.Int {
+	if d, ok := rps.paymentDenoms[denom]; ok {
+		return d.FromMEDAS(network.Current().ToDisplay(rps.refundFee))
+	}
+	if denom != network.Current().BaseDenom {
+		return sdkmath.ZeroInt()
+	}
+	return sdkmath.NewInt(rps.refundFee)
+}
+
+// paymentOption is the amount of a price in one accepted denom
+type paymentOption struct {
+	Denom   string    `json:"denom"`
+	Symbol  string    `json:"symbol"`
+	Channel string    `json:"channel,omitempty"` // IBC denoms only
+	Amount  baseUnits `json:"amount"`
+	Display string    `json:"display"`
+}
+
+// paymentOptions lists the amount of a MEDAS price in every accepted denom
+func (rps *RealPaymentService) paymentOptions(medas float64) []paymentOption {
+	net := network.Current()
+	amount := net.ToBase(medas)
+	options := []paymentOption{{
+		Denom:   net.BaseDenom,
+		Symbol:  net.DisplayDenom,
+		Amount:  baseUnits{sdkmath.NewInt(amount)},
+		Display: net.FormatAmount(amount),
+	}}
+	for _, d := range rps.sortedPaymentDenoms() {
+		amount := d.FromMEDAS(medas)
+		options = append(options, paymentOption{
+			Denom:   d.Denom,
+			Symbol:  d.Label(),
+			Channel: d.Channel,
+			Amount:  baseUnits{amount},
+			Display: denom.Metadata{Base: d.Denom, Display: d.Label(), Exponent: d.Decimals}.Format(amount),
+		})
+	}
+	return options
+}
+
+func (rps *RealPaymentService) sortedPaymentDenoms() []compute.PaymentDenom {
+	list := make([]compute.PaymentDenom, 0, len(rps.paymentDenoms))
+	for _, d := range rps.paymentDenoms {
+		list = append(list, d)
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].Label() < list[b].Label() })
+	return list
+}
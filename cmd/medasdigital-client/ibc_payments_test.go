@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+)
+
+func TestLargeEighteenDecimalPaymentIsRefundedInFull(t *testing.T) {
+	rps := newTestPaymentService(t)
+	d := compute.PaymentDenom{Denom: compute.IBCDenom("channel-0", "aevmos"), Symbol: "EVMOS", Decimals: 18, Rate: 2}
+	rps.paymentDenoms = map[string]compute.PaymentDenom{d.Denom: d}
+
+	// 50 tokens are far beyond int64 in base units
+	amount, _ := sdkmath.NewIntFromString("50000000000000000000")
+	coin, medas, ok := rps.paymentCoin(sdk.NewCoins(sdk.NewCoin(d.Denom, amount)))
+	if !ok || !coin.Amount.Equal(amount) {
+		t.Fatalf("payment not accepted: %s, %v", coin, ok)
+	}
+	if medas != 100 {
+		t.Fatalf("payment worth %v MEDAS, want 100", medas)
+	}
+
+	rps.queueRefund("A1B2C3", "medas1payer", coin, RefundJobFailed, "", "")
+	want := amount.Sub(rps.refundFeeIn(d.Denom))
+	list := rps.refunds.List("", false)
+	if len(list) != 1 || list[0].Status != RefundQueued || !list[0].Amount.Equal(want) {
+		t.Fatalf("unexpected refunds %+v, want %s queued", list, want)
+	}
+
+	// The amount survives a restart
+	reloaded := NewRefundQueue(rps.refunds.path).List("", false)
+	if len(reloaded) != 1 || !reloaded[0].Amount.Equal(want) {
+		t.Fatalf("reloaded refunds %+v, want %s", reloaded, want)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader carries the key of a job submission, the
+// client_job_id field of the request is the same
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyTTL is how long a response is replayed for its key
+const idempotencyTTL = 24 * time.Hour
+
+// maxIdempotencyKeyLen bounds the keys a client can make the service keep
+const maxIdempotencyKeyLen = 128
+
+// idempotentResponse is the stored answer to a keyed request, without status
+// while the first request is still being handled
+type idempotentResponse struct {
+	fingerprint [sha256.Size]byte
+	status      int
+	header      http.Header
+	body        []byte
+	created     time.Time
+}
+
+// IdempotencyStore remembers the responses of keyed job submissions, so a
+// client retrying after a network error gets the original job instead of a
+// second one. Like the jobs it is kept in memory.
+type IdempotencyStore struct {
+	mu        sync.Mutex
+	responses map[string]*idempotentResponse
+}
+
+// NewIdempotencyStore creates an empty store
+func NewIdempotencyStore() *IdempotencyStore {
+	return &IdempotencyStore{responses: make(map[string]*idempotentResponse)}
+}
+
+// Begin claims key for a request with body. It returns the stored response
+// of an earlier request with the key, or ok false with the status to answer
+// when the key is in use by a running or a different request.
+func (s *IdempotencyStore) Begin(key string, body []byte) (replay *idempotentResponse, status int, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, r := range s.responses {
+		if r.status != 0 && now.Sub(r.created) > idempotencyTTL {
+			delete(s.responses, k)
+		}
+	}
+
+	fingerprint := sha256.Sum256(body)
+	if r, seen := s.responses[key]; seen {
+		switch {
+		case r.fingerprint != fingerprint:
+			return nil, http.StatusUnprocessableEntity, false
+		case r.status == 0:
+			return nil, http.StatusConflict, false
+		}
+		return r, 0, true
+	}
+	s.responses[key] = &idempotentResponse{fingerprint: fingerprint, created: now}
+	return nil, 0, true
+}
+
+// Finish stores the response of the request that claimed key. Server errors
+// are not stored, the key is released so the request can be retried.
+func (s *IdempotencyStore) Finish(key string, rec *responseRecorder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, seen := s.responses[key]
+	if !seen {
+		return
+	}
+	if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+		delete(s.responses, key)
+		return
+	}
+	r.status = rec.status
+	r.header = rec.Header().Clone()
+	r.body = rec.body.Bytes()
+	r.created = time.Now()
+}
+
+// write replays the stored response
+func (r *idempotentResponse) write(w http.ResponseWriter) {
+	for k, v := range r.header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(r.status)
+	w.Write(r.body)
+}
+
+// responseRecorder passes a response through and keeps a copy
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/indexer"
+)
+
+// indexDate is the format of --from and --to
+const indexDate = "2006-01-02"
+
+// indexCmd manages the local transaction index
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Local SQLite index of the transactions of your addresses",
+	Long: `Keep the transfers and registrations of selected addresses in a local SQLite
+database, so balance history, registration and earnings queries work offline
+and without repeated TxSearch calls. Addresses come from indexer.addresses in
+the config and from index sync; only blocks after the last sync are fetched.`,
+}
+
+// indexSyncCmd fetches the new transactions of the tracked addresses
+var indexSyncCmd = &cobra.Command{
+	Use:   "sync [address...]",
+	Short: "Fetch new transactions of the tracked addresses, adding the given ones",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, addr := range args {
+			if _, err := sdk.AccAddressFromBech32(addr); err != nil {
+				return fmt.Errorf("invalid address %s: %w", addr, err)
+			}
+		}
+		cfg := loadConfig()
+		ix, err := openIndex(cfg)
+		if err != nil {
+			return err
+		}
+		defer ix.Close()
+		if err := ix.Track(args...); err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		return syncIndex(ctx, ix, cfg, nil, true)
+	},
+}
+
+// indexStatusCmd shows the tracked addresses
+var indexStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the tracked addresses and how far they are synced",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := loadConfig()
+		ix, err := openIndex(cfg)
+		if err != nil {
+			return err
+		}
+		defer ix.Close()
+
+		states, err := ix.Addresses()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("🗂️  Index: %s\n", ix.Path())
+		if len(states) == 0 {
+			fmt.Println("No addresses tracked, add one with: medasdigital-client index sync <address>")
+			return nil
+		}
+		fmt.Printf("%-46s %10s %10s  %s\n", "ADDRESS", "HEIGHT", "TRANSFERS", "SYNCED")
+		for _, s := range states {
+			synced := "never"
+			if !s.SyncedAt.IsZero() {
+				synced = s.SyncedAt.Local().Format("2006-01-02 15:04")
+			}
+			fmt.Printf("%-46s %10d %10d  %s\n", s.Address, s.SyncedHeight, s.Transfers, synced)
+		}
+		return nil
+	},
+}
+
+// indexUntrackCmd stops syncing an address
+var indexUntrackCmd = &cobra.Command{
+	Use:   "untrack [address]",
+	Short: "Stop syncing an address, its indexed transfers are kept",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := loadConfig()
+		for _, addr := range cfg.Indexer.Addresses {
+			if addr == args[0] {
+				return fmt.Errorf("%s is listed in indexer.addresses, remove it there", addr)
+			}
+		}
+		ix, err := openIndex(cfg)
+		if err != nil {
+			return err
+		}
+		defer ix.Close()
+		if err := ix.Untrack(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("✅ %s is no longer synced\n", args[0])
+		return nil
+	},
+}
+
+// indexEarningsCmd sums what an address received
+var indexEarningsCmd = &cobra.Command{
+	Use:   "earnings [address]",
+	Short: "Sum the transfers an address received, per denom and sender",
+	Long: `Sum the incoming transfers of an indexed address, e.g. a provider or the
+payment service address. The index is synced first unless --offline is given.
+
+  medasdigital-client index earnings medas1... --from 2025-01-01 --to 2025-04-01`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		address := args[0]
+		offline, _ := cmd.Flags().GetBool("offline")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		from, to, err := indexPeriod(cmd)
+		if err != nil {
+			return err
+		}
+
+		cfg := loadConfig()
+		ix, err := openIndex(cfg)
+		if err != nil {
+			return err
+		}
+		defer ix.Close()
+		if err := prepareIndex(cmd.Context(), ix, cfg, address, offline, !asJSON); err != nil {
+			return err
+		}
+
+		earnings, err := ix.Earnings(address, from, to)
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			data, err := json.MarshalIndent(earnings, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		fmt.Printf("💰 Earnings of %s%s\n", address, periodLabel(from, to))
+		fmt.Printf("   Total: %s in %d transfers\n", denoms().FormatCoins(earnings.Total), earnings.Transfers)
+		senders := make([]string, 0, len(earnings.BySender))
+		for s := range earnings.BySender {
+			senders = append(senders, s)
+		}
+		sort.Strings(senders)
+		for _, s := range senders {
+			fmt.Printf("   %-46s %s\n", s, denoms().FormatCoins(earnings.BySender[s]))
+		}
+		return nil
+	},
+}
+
+// openIndex opens the index of the configured chain and tracks the configured addresses
+func openIndex(cfg *Config) (*indexer.Index, error) {
+	ix, err := indexer.Open(cfg.Indexer.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := ix.Track(cfg.Indexer.Addresses...); err != nil {
+		ix.Close()
+		return nil, err
+	}
+	return ix, nil
+}
+
+// prepareIndex makes sure address is tracked and, unless offline, synced
+func prepareIndex(ctx context.Context, ix *indexer.Index, cfg *Config, address string, offline, verbose bool) error {
+	state, err := ix.State(address)
+	if err != nil {
+		return err
+	}
+	if offline {
+		if state == nil || state.SyncedHeight == 0 {
+			return fmt.Errorf("%s is not indexed yet, run: medasdigital-client index sync %s", address, address)
+		}
+		return nil
+	}
+	if state == nil {
+		if err := ix.Track(address); err != nil {
+			return err
+		}
+	}
+	return syncIndex(ctx, ix, cfg, []string{address}, verbose)
+}
+
+// syncIndex syncs the given addresses, all tracked ones if nil
+func syncIndex(ctx context.Context, ix *indexer.Index, cfg *Config, addresses []string, verbose bool) error {
+	if addresses == nil {
+		states, err := ix.Addresses()
+		if err != nil {
+			return err
+		}
+		for _, s := range states {
+			addresses = append(addresses, s.Address)
+		}
+		if len(addresses) == 0 {
+			return fmt.Errorf("no addresses tracked, pass one or set indexer.addresses")
+		}
+	}
+	if c := connectDaemon(); c != nil {
+		return syncIndexViaDaemon(ctx, c, addresses, verbose)
+	}
+
+	bc, err := createFullBlockchainClient(client.Context{}, cfg)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addresses {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "🔍 Syncing %s...", addr)
+		}
+		stats, err := ix.Sync(ctx, bc, addr)
+		if err != nil {
+			if verbose {
+				fmt.Fprintln(os.Stderr)
+			}
+			return fmt.Errorf("sync of %s failed: %w", addr, err)
+		}
+		if verbose {
+			fmt.Fprintf(os.Stderr, " %d new transfers, height %d\n", stats.Transfers, stats.ToHeight)
+		}
+	}
+	return nil
+}
+
+// indexPeriod reads --from and --to, dates in local time
+func indexPeriod(cmd *cobra.Command) (time.Time, time.Time, error) {
+	var period [2]time.Time
+	for i, name := range []string{"from", "to"} {
+		value, _ := cmd.Flags().GetString(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.ParseInLocation(indexDate, value, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid --%s %q, use YYYY-MM-DD", name, value)
+		}
+		period[i] = t
+	}
+	return period[0], period[1], nil
+}
+
+func periodLabel(from, to time.Time) string {
+	var parts []string
+	if !from.IsZero() {
+		parts = append(parts, "from "+from.Format(indexDate))
+	}
+	if !to.IsZero() {
+		parts = append(parts, "until "+to.Format(indexDate))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+	indexCmd.AddCommand(indexSyncCmd)
+	indexCmd.AddCommand(indexStatusCmd)
+	indexCmd.AddCommand(indexUntrackCmd)
+	indexCmd.AddCommand(indexEarningsCmd)
+
+	indexEarningsCmd.Flags().String("from", "", "First day, YYYY-MM-DD")
+	indexEarningsCmd.Flags().String("to", "", "Day after the last one, YYYY-MM-DD")
+	indexEarningsCmd.Flags().Bool("offline", false, "Use the index as is, without syncing")
+	indexEarningsCmd.Flags().Bool("json", false, "Print JSON")
+}
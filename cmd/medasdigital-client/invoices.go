@@ -0,0 +1,409 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/gorilla/mux"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// InvoiceMemoPrefix starts the unique memo a client has to send with an invoice payment
+const InvoiceMemoPrefix = "INV_"
+
+// defaultInvoiceTTL is how long an invoice can be paid
+const defaultInvoiceTTL = 30 * time.Minute
+
+// Invoice status values
+const (
+	InvoicePending = "pending"
+	InvoicePaid    = "paid"
+	InvoiceExpired = "expired"
+)
+
+// Invoice is a quoted job waiting for its payment
+type Invoice struct {
+	ID            string                    `json:"id"`
+	Memo          string                    `json:"memo"`
+	AmountUmedas  int64                     `json:"amount_umedas"`
+	Amount        float64                   `json:"amount"` // display denom
+	Denom         string                    `json:"denom"`
+	Status        string                    `json:"status"`
+	JobType       compute.JobType           `json:"job_type"`
+	Parameters    map[string]interface{}    `json:"parameters"`
+	Tier          compute.ServiceTier       `json:"tier"`
+	Verification  compute.VerificationLevel `json:"verification"`
+	ClientAddress string                    `json:"client_address,omitempty"` // optional, any payer is accepted
+	CreatedAt     time.Time                 `json:"created_at"`
+	ExpiresAt     time.Time                 `json:"expires_at"`
+	PaidAt        *time.Time                `json:"paid_at,omitempty"`
+	PaymentTx     string                    `json:"payment_tx_hash,omitempty"`
+	Payer         string                    `json:"payer,omitempty"`
+	JobID         string                    `json:"job_id,omitempty"`
+}
+
+// InvoiceStore keeps the invoices of a payment service on disk
+type InvoiceStore struct {
+	mu       sync.Mutex
+	path     string
+	invoices map[string]*Invoice // by ID
+}
+
+// NewInvoiceStore loads the invoices from disk
+func NewInvoiceStore(path string) *InvoiceStore {
+	s := &InvoiceStore{path: path, invoices: make(map[string]*Invoice)}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var list []*Invoice
+		if err := json.Unmarshal(data, &list); err != nil {
+			log.Printf("⚠️ Could not parse invoices %s: %v", path, err)
+		}
+		for _, inv := range list {
+			s.invoices[inv.ID] = inv
+		}
+	}
+	return s
+}
+
+// Create stores a new pending invoice with a unique memo
+func (s *InvoiceStore) Create(inv *Invoice) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		code, err := invoiceCode()
+		if err != nil {
+			return err
+		}
+		if _, exists := s.invoices[code]; !exists {
+			inv.ID = code
+			inv.Memo = InvoiceMemoPrefix + code
+			break
+		}
+	}
+	inv.Status = InvoicePending
+	s.invoices[inv.ID] = inv
+	return s.save()
+}
+
+// Get returns a copy of an invoice
+func (s *InvoiceStore) Get(id string) (Invoice, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inv, ok := s.invoices[id]
+	if !ok {
+		return Invoice{}, false
+	}
+	return *inv, true
+}
+
+// ByMemo finds the invoice a payment memo refers to
+func (s *InvoiceStore) ByMemo(memo string) (Invoice, bool) {
+	memo = strings.ToUpper(strings.TrimSpace(memo))
+	if !strings.HasPrefix(memo, InvoiceMemoPrefix) {
+		return Invoice{}, false
+	}
+	return s.Get(strings.TrimPrefix(memo, InvoiceMemoPrefix))
+}
+
+// MarkPaid settles a pending invoice, it fails if the invoice was paid or expired meanwhile
+func (s *InvoiceStore) MarkPaid(id string, t blockchain.Transfer, jobID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inv, ok := s.invoices[id]
+	if !ok {
+		return fmt.Errorf("invoice %s not found", id)
+	}
+	if inv.Status != InvoicePending {
+		return fmt.Errorf("invoice %s is %s", id, inv.Status)
+	}
+	paidAt := t.Time
+	if paidAt.IsZero() {
+		paidAt = time.Now()
+	}
+	inv.Status = InvoicePaid
+	inv.PaidAt = &paidAt
+	inv.PaymentTx = t.TxHash
+	inv.Payer = t.Sender
+	inv.JobID = jobID
+	return s.save()
+}
+
+// ExpireDue marks pending invoices past their expiry as expired
+func (s *InvoiceStore) ExpireDue(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expired := 0
+	for _, inv := range s.invoices {
+		if inv.Status == InvoicePending && now.After(inv.ExpiresAt) {
+			inv.Status = InvoiceExpired
+			expired++
+		}
+	}
+	if expired > 0 {
+		if err := s.save(); err != nil {
+			log.Printf("⚠️ Could not save invoices: %v", err)
+		}
+	}
+	return expired
+}
+
+func (s *InvoiceStore) save() error {
+	defer telemetry.Track(telemetry.CategoryDisk, "save_invoices")()
+
+	list := make([]*Invoice, 0, len(s.invoices))
+	for _, inv := range s.invoices {
+		list = append(list, inv)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// invoiceCode returns 10 random base32 characters, short enough to type into a wallet memo
+func invoiceCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)[:10], nil
+}
+
+// runInvoiceExpiry expires unpaid invoices until ctx is done
+func (rps *RealPaymentService) runInvoiceExpiry(ctx context.Context) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n := rps.invoices.ExpireDue(time.Now()); n > 0 {
+				log.Printf("⌛ %d invoice(s) expired", n)
+			}
+		}
+	}
+}
+
+// handleInvoicePayment settles the invoice a transfer's memo refers to
+func (rps *RealPaymentService) handleInvoicePayment(t blockchain.Transfer, inv Invoice) {
+	// The same transfer may arrive via catch-up and the subscription
+	if rps.consumed.Claim(t.TxHash, 1, 1) == 0 {
+		return
+	}
+	paid, paidMEDAS, ok := rps.paymentCoin(t.Amount)
+	if !ok {
+		log.Printf("❌ Payment %s for invoice %s: %s is not an accepted payment denom", t.TxHash, inv.ID, t.Amount)
+		rps.refundRejected(t, inv.ID)
+		return
+	}
+	if inv.Status != InvoicePending {
+		log.Printf("❌ Payment %s for %s invoice %s from %s", t.TxHash, inv.Status, inv.ID, t.Sender)
+		rps.queueRefund(t.TxHash, t.Sender, paid, RefundInvoiceClosed, "", inv.ID)
+		return
+	}
+	if t.Time.After(inv.ExpiresAt) {
+		log.Printf("❌ Payment %s for invoice %s arrived after its expiry", t.TxHash, inv.ID)
+		rps.queueRefund(t.TxHash, t.Sender, paid, RefundInvoiceClosed, "", inv.ID)
+		return
+	}
+	// MEDAS payments must match the invoiced base amount, IBC tokens are converted at the current rate
+	required := sdkmath.NewInt(inv.AmountUmedas)
+	if paid.Denom != inv.Denom {
+		required = rps.priceIn(paid.Denom, inv.Amount)
+	}
+	if paid.Amount.LT(required) {
+		log.Printf("❌ Payment %s for invoice %s: %s paid, %s%s invoiced", t.TxHash, inv.ID, paid, required, paid.Denom)
+		rps.queueRefund(t.TxHash, t.Sender, paid, RefundUnderpaid, "", inv.ID)
+		return
+	}
+
+	clientAddr := inv.ClientAddress
+	if clientAddr == "" {
+		clientAddr = t.Sender
+	}
+	var job *compute.ComputeJob
+	err := rps.admit(clientAddr, 1, func() (float64, error) { return inv.Amount, nil }, func() (err error) {
+		job, err = rps.jobManager.SubmitJob(inv.JobType, inv.Parameters, clientAddr, inv.Tier, inv.Verification, t.TxHash)
+		if err != nil {
+			return err
+		}
+		if err := rps.invoices.MarkPaid(inv.ID, t, job.ID); err != nil {
+			rps.jobManager.CancelJob(job.ID)
+			log.Printf("❌ Invoice %s: %v", inv.ID, err)
+			rps.queueRefund(t.TxHash, t.Sender, paid, RefundInvoiceClosed, "", inv.ID)
+			job = nil
+			return nil
+		}
+		job.PaymentVerified = true
+		job.PaymentDenom = paid.Denom
+		rps.consumed.Bind(t.TxHash, job.ID)
+		rps.ledger.Record(job, paid, paidMEDAS)
+		return nil
+	})
+	if limitErr, ok := asAccountLimitError(err); ok {
+		log.Printf("🚦 Invoice %s paid by %s refunded: %v", inv.ID, t.TxHash, limitErr)
+		rps.queueRefund(t.TxHash, t.Sender, paid, RefundLimitExceeded, "", inv.ID)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Invoice %s paid by %s but job submission failed: %v", inv.ID, t.TxHash, err)
+		rps.queueRefund(t.TxHash, t.Sender, paid, RefundJobFailed, "", inv.ID)
+		return
+	}
+	if job == nil {
+		return
+	}
+
+	log.Printf("🧾 Invoice %s paid by %s (tx %s, %s), job %s started", inv.ID, t.Sender, t.TxHash, paid, job.ID)
+	if excess := paid.Amount.Sub(required); excess.GT(rps.refundFeeIn(paid.Denom)) {
+		rps.queueRefund(t.TxHash, t.Sender, sdk.NewCoin(paid.Denom, excess), RefundOverpaid, job.ID, inv.ID)
+	}
+	go rps.distributeCommunityFee(job)
+}
+
+// createInvoiceRequest is the body of POST /invoices
+type createInvoiceRequest struct {
+	Type          string                    `json:"type"`
+	Parameters    map[string]interface{}    `json:"parameters"`
+	Tier          compute.ServiceTier       `json:"tier"`
+	Verification  compute.VerificationLevel `json:"verification"`
+	ClientAddress string                    `json:"client_address"`
+	TTLSeconds    int                       `json:"ttl_seconds"`
+}
+
+// createInvoiceResponse is the body POST /invoices answers with
+type createInvoiceResponse struct {
+	Invoice        *Invoice                `json:"invoice"`
+	PayTo          string                  `json:"pay_to"`
+	PriceBreakdown *compute.PriceBreakdown `json:"price_breakdown"`
+	PaymentOptions []paymentOption         `json:"payment_options"`
+	Instructions   string                  `json:"instructions"`
+}
+
+// handleCreateInvoice quotes a job and returns the amount and memo to pay with
+func (rps *RealPaymentService) handleCreateInvoice(w http.ResponseWriter, r *http.Request) {
+	var req createInvoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if !rps.watchPayments {
+		http.Error(w, "Invoices need the payment watcher (--watch-payments)", http.StatusServiceUnavailable)
+		return
+	}
+	if compute.JobType(req.Type) != compute.JobTypePICalculation {
+		http.Error(w, fmt.Sprintf("unsupported job type: %s", req.Type), http.StatusBadRequest)
+		return
+	}
+	if req.Tier == "" {
+		req.Tier = compute.TierBasic
+	}
+	verification, err := compute.ParseVerificationLevel(string(req.Verification))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	method, _ := req.Parameters["method"].(string)
+	if method == "" {
+		method = "chudnovsky"
+		if req.Parameters == nil {
+			req.Parameters = map[string]interface{}{}
+		}
+		req.Parameters["method"] = method
+	}
+	price, err := rps.jobManager.QuoteJob(compute.JobTypePICalculation, req.Parameters, req.Tier, verification)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Price calculation failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// Payments are checked again, this only fails early
+	if req.ClientAddress != "" {
+		err := rps.admit(req.ClientAddress, 1, func() (float64, error) { return price.TotalCost, nil }, nil)
+		if limitErr, ok := asAccountLimitError(err); ok {
+			writeAccountLimitError(w, limitErr)
+			return
+		}
+	}
+
+	ttl := defaultInvoiceTTL
+	if req.TTLSeconds > 0 && req.TTLSeconds <= 24*3600 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	net := network.Current()
+	now := time.Now()
+	inv := &Invoice{
+		AmountUmedas:  net.ToBase(price.TotalCost),
+		Amount:        price.TotalCost,
+		Denom:         net.BaseDenom,
+		JobType:       compute.JobTypePICalculation,
+		Parameters:    req.Parameters,
+		Tier:          req.Tier,
+		Verification:  verification,
+		ClientAddress: req.ClientAddress,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(ttl),
+	}
+	if err := rps.invoices.Create(inv); err != nil {
+		http.Error(w, fmt.Sprintf("Could not create invoice: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createInvoiceResponse{
+		Invoice:        inv,
+		PayTo:          rps.serviceAddr,
+		PriceBreakdown: price,
+		PaymentOptions: rps.paymentOptions(price.TotalCost),
+		Instructions:   fmt.Sprintf("Send %d%s to %s with memo %s before %s", inv.AmountUmedas, inv.Denom, rps.serviceAddr, inv.Memo, inv.ExpiresAt.Format(time.RFC3339)),
+	})
+}
+
+// handleGetInvoice returns an invoice and its payment state
+func (rps *RealPaymentService) handleGetInvoice(w http.ResponseWriter, r *http.Request) {
+	inv, ok := rps.invoices.Get(strings.ToUpper(mux.Vars(r)["id"]))
+	if !ok {
+		http.Error(w, "Invoice not found", http.StatusNotFound)
+		return
+	}
+	// The expiry routine runs every 30s, don't report a stale pending state
+	if inv.Status == InvoicePending && time.Now().After(inv.ExpiresAt) {
+		inv.Status = InvoiceExpired
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inv)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+)
+
+// maxInlineDigits is the largest PI value the job JSON carries, the digits of
+// larger results are only in the artifact
+const maxInlineDigits = 100000
+
+// artifactDir is where the payment service keeps the result files of completed jobs
+func artifactDir() string {
+	return filepath.Join(homeDir, "artifacts")
+}
+
+// runArtifactGC removes artifacts no job refers to once they are older than
+// the retention of the service
+func (rps *RealPaymentService) runArtifactGC(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		n, err := rps.jobManager.CollectArtifacts(rps.artifactRetention)
+		if err != nil {
+			log.Printf("⚠️ Artifact cleanup: %v", err)
+		} else if n > 0 {
+			log.Printf("🧹 %d unreferenced artifact(s) removed", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// jobView is the job as the API returns it, without the digits of a large PI
+// result that can be downloaded as artifact. A result from the cache shows
+// once the payment is verified.
+func jobView(job *compute.ComputeJob) *compute.ComputeJob {
+	if awaitingPayment(job) {
+		view := *job
+		if view.Status == compute.StatusCompleted {
+			view.Status = compute.StatusQueued
+		}
+		view.Progress = 0
+		view.Result = nil
+		view.Artifact = nil
+		view.VerificationReport = nil
+		view.CompletedAt = nil
+		view.StreamedDigits = 0
+		return &view
+	}
+	pi, ok := job.Result.(*compute.PIResult)
+	if !ok || job.Artifact == nil || len(pi.Value) <= maxInlineDigits+2 {
+		return job
+	}
+	result := *pi
+	result.Value = ""
+	view := *job
+	view.Result = &result
+	return &view
+}
+
+// handleJobArtifact serves the result file of a completed job. Range
+// requests let clients fetch parts of it or continue a broken download, the
+// ETag is the SHA-256 of the content.
+func (rps *RealPaymentService) handleJobArtifact(w http.ResponseWriter, r *http.Request) {
+	job, err := rps.jobManager.GetJob(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if awaitingPayment(job) {
+		http.Error(w, "Payment verification in progress, the artifact is available once it is verified", http.StatusConflict)
+		return
+	}
+	path := rps.jobManager.ArtifactPath(job)
+	if path == "" {
+		if job.Status == compute.StatusCompleted {
+			http.Error(w, "Job has no artifact", http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("Job is %s, the artifact is available once it completed", job.Status), http.StatusConflict)
+		}
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		http.Error(w, "Artifact not available", http.StatusNotFound)
+		return
+	}
+	artifact := job.Artifact
+	w.Header().Set("Content-Type", artifact.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", artifact.Name))
+	w.Header().Set("Vary", "Accept-Encoding")
+
+	// Whole downloads go out as stored when the client takes zstd, ranges
+	// are of the uncompressed content
+	if r.Header.Get("Range") == "" && acceptsEncoding(r, artifact.Encoding) {
+		f, err := os.Open(path)
+		if err != nil {
+			http.Error(w, "Artifact not available", http.StatusNotFound)
+			return
+		}
+		defer f.Close()
+		w.Header().Set("Content-Encoding", artifact.Encoding)
+		w.Header().Set("ETag", `"`+artifact.SHA256+"-"+artifact.Encoding+`"`)
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+		if checkNotModified(w, r) {
+			return
+		}
+		if r.Method != http.MethodHead {
+			io.Copy(w, f)
+		}
+		return
+	}
+
+	content, err := compute.OpenArtifact(path, artifact.Size)
+	if err != nil {
+		http.Error(w, "Artifact not available", http.StatusInternalServerError)
+		return
+	}
+	defer content.Close()
+	w.Header().Set("ETag", `"`+artifact.SHA256+`"`)
+	http.ServeContent(w, r, artifact.Name, info.ModTime(), content)
+}
+
+// acceptsEncoding reports whether the Accept-Encoding of r lists encoding
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.EqualFold(strings.TrimSpace(name), encoding) {
+			return strings.ReplaceAll(params, " ", "") != "q=0"
+		}
+	}
+	return false
+}
+
+// checkNotModified answers a matching If-None-Match with 304
+func checkNotModified(w http.ResponseWriter, r *http.Request) bool {
+	match := r.Header.Get("If-None-Match")
+	if match == "" {
+		return false
+	}
+	etag := w.Header().Get("ETag")
+	for _, tag := range strings.Split(match, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			w.Header().Del("Content-Length")
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+)
+
+// jobExecCmd is the worker entry point inside the provider sandbox
+var jobExecCmd = &cobra.Command{
+	Use:    "job-exec [job-file] [result-file]",
+	Short:  "Execute a single job file (used inside the provider sandbox)",
+	Hidden: true,
+	Args:   cobra.ExactArgs(2),
+	// The sandbox has no config, keyring or network
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read job file: %w", err)
+		}
+		var spec compute.SandboxJob
+		if err := json.Unmarshal(data, &spec); err != nil {
+			return fmt.Errorf("invalid job file: %w", err)
+		}
+
+		out, err := json.Marshal(compute.ExecuteJob(cmd.Context(), spec))
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(args[1], out, 0o644)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(jobExecCmd)
+}
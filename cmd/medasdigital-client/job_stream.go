@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+)
+
+// checkpointDir is where the payment service keeps checkpoints of running PI jobs
+func checkpointDir() string {
+	return filepath.Join(homeDir, "checkpoints")
+}
+
+// resumeJobs queues the jobs of the given checkpoint files again
+func (rps *RealPaymentService) resumeJobs(paths []string) error {
+	for _, path := range paths {
+		cp, err := compute.LoadPICheckpoint(path)
+		if err != nil {
+			return err
+		}
+		job, err := rps.jobManager.ResumeJob(cp)
+		if err != nil {
+			return fmt.Errorf("cannot resume %s: %w", path, err)
+		}
+		digits, _ := job.Parameters["digits"].(float64)
+		fmt.Printf("♻️  Resuming job %s after %d of %.0f digits\n", job.ID, job.StreamedDigits, digits)
+		if !job.PaymentVerified {
+			go rps.verifyAndStartJob(job)
+		}
+	}
+	return nil
+}
+
+// warnCheckpoints points out jobs a previous run left unfinished
+func warnCheckpoints() {
+	paths, _ := filepath.Glob(filepath.Join(checkpointDir(), "*.json"))
+	if len(paths) > 0 {
+		log.Printf("⚠️ %d interrupted job(s) in %s, continue them with --resume-from", len(paths), checkpointDir())
+	}
+}
+
+// handleStreamJob sends the digit blocks of a PI job as newline-delimited JSON
+// while they are calculated, from block ?from= on. The last line is the
+// status the job ended with.
+func (rps *RealPaymentService) handleStreamJob(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+	from := 0
+	if s := r.URL.Query().Get("from"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			http.Error(w, "from must be a block index", http.StatusBadRequest)
+			return
+		}
+		from = n
+	}
+	job, err := rps.jobManager.GetJob(jobID)
+	if err != nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if job.Type != compute.JobTypePICalculation {
+		http.Error(w, "Only PI calculations are streamed", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	for {
+		// Read before the blocks, so every block of a finished job is sent.
+		// Cached results are sent once their payment is verified.
+		status := job.Status
+		if awaitingPayment(job) {
+			if status != compute.StatusCompleted {
+				enc.Encode(compute.StreamEvent{Status: status, Error: job.Error})
+				return
+			}
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		blocks, changed, err := rps.jobManager.Blocks(jobID, from)
+		if err != nil {
+			return
+		}
+		for i := range blocks {
+			if err := enc.Encode(compute.StreamEvent{Block: &blocks[i]}); err != nil {
+				return
+			}
+		}
+		from += len(blocks)
+		switch status {
+		case compute.StatusCompleted, compute.StatusFailed, compute.StatusCancelled:
+			enc.Encode(compute.StreamEvent{Status: status, Error: job.Error})
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		// Jobs failed outside the job manager do not wake the stream
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changed:
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// appendDigits writes streamed blocks to a file, the first one after "3."
+func appendDigits(path string, block compute.DigitBlock) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if block.Index == 0 {
+		if _, err := f.WriteString("3."); err != nil {
+			return err
+		}
+	}
+	_, err = f.WriteString(block.Digits)
+	return err
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/gorilla/mux"
+
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// statementMonth is the format of the ?month= parameter
+const statementMonth = "2006-01"
+
+// LedgerEntry is one paid job of a client
+type LedgerEntry struct {
+	PaymentTx      string              `json:"payment_tx_hash"`
+	Address        string              `json:"address"`
+	JobID          string              `json:"job_id"`
+	JobType        compute.JobType     `json:"job_type"`
+	Tier           compute.ServiceTier `json:"tier"`
+	Description    string              `json:"description"`
+	Amount         baseUnits           `json:"amount"` // in Denom
+	Denom          string              `json:"denom"`
+	ValueMEDAS     float64             `json:"value_medas"` // at the time of payment
+	Refunded       baseUnits           `json:"refunded"` // in Denom
+	Status         compute.JobStatus   `json:"status"`
+	ComputeSeconds float64             `json:"compute_seconds"`
+	PaidAt         time.Time           `json:"paid_at"`
+	CompletedAt    *time.Time          `json:"completed_at,omitempty"`
+}
+
+// AccountSummary adds up the ledger entries of a client
+type AccountSummary struct {
+	Address        string  `json:"address"`
+	Jobs           int     `json:"jobs"`
+	JobsCompleted  int     `json:"jobs_completed"`
+	JobsFailed     int     `json:"jobs_failed"`
+	Paid           string  `json:"paid"`
+	Refunded       string  `json:"refunded"`
+	PaidMEDAS      float64 `json:"paid_medas"`
+	RefundedMEDAS  float64 `json:"refunded_medas"`
+	ComputeSeconds float64 `json:"compute_seconds"`
+}
+
+// Ledger keeps per-client accounting of paid jobs on disk
+type Ledger struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]*LedgerEntry // by job ID, a bundle payment pays for several jobs
+}
+
+// NewLedger loads the ledger from disk
+func NewLedger(path string) *Ledger {
+	l := &Ledger{path: path, entries: make(map[string]*LedgerEntry)}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var list []*LedgerEntry
+		if err := json.Unmarshal(data, &list); err != nil {
+			log.Printf("⚠️ Could not parse ledger %s: %v", path, err)
+		}
+		for _, e := range list {
+			e.Amount.Int, e.Refunded.Int = e.Amount.value(), e.Refunded.value()
+			l.entries[e.JobID] = e
+		}
+	}
+	return l
+}
+
+// Record books the payment of a job, valueMEDAS is what the payment was worth
+func (l *Ledger) Record(job *compute.ComputeJob, paid sdk.Coin, valueMEDAS float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := &LedgerEntry{
+		PaymentTx:   job.PaymentTxHash,
+		Address:     job.ClientAddr,
+		JobID:       job.ID,
+		JobType:     job.Type,
+		Tier:        job.Tier,
+		Description: jobDescription(job),
+		Amount:      baseUnits{paid.Amount},
+		Denom:       paid.Denom,
+		ValueMEDAS:  valueMEDAS,
+		Status:      job.Status,
+		PaidAt:      time.Now(),
+		Refunded:    baseUnits{sdkmath.ZeroInt()},
+	}
+	if old, ok := l.entries[e.JobID]; ok {
+		e.Refunded = old.Refunded
+	}
+	// Fast jobs may already be done when the payment is booked
+	e.finish(job)
+	l.entries[e.JobID] = e
+	l.persist()
+}
+
+// Finish records the outcome and compute time of a booked job
+func (l *Ledger) Finish(job *compute.ComputeJob) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[job.ID]
+	if !ok {
+		return
+	}
+	e.finish(job)
+	l.persist()
+}
+
+// Refunded books a refund sent for a payment on the job it was for, or on
+// the first job of the payment
+func (l *Ledger) Refunded(paymentTx, jobID string, amount sdk.Coin) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[jobID]
+	if !ok || e.PaymentTx != paymentTx {
+		e = l.firstEntry(paymentTx)
+	}
+	if e == nil || e.Denom != amount.Denom {
+		return
+	}
+	e.Refunded.Int = e.Refunded.Add(amount.Amount)
+	l.persist()
+}
+
+// firstEntry is the earliest job paid by paymentTx, nil if there is none
+func (l *Ledger) firstEntry(paymentTx string) *LedgerEntry {
+	var first *LedgerEntry
+	for _, e := range l.entries {
+		if e.PaymentTx == paymentTx && (first == nil || e.PaidAt.Before(first.PaidAt)) {
+			first = e
+		}
+	}
+	return first
+}
+
+// Entries returns the entries of addr paid in [from, to), oldest first
+func (l *Ledger) Entries(addr string, from, to time.Time) []LedgerEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	list := []LedgerEntry{}
+	for _, e := range l.entries {
+		if e.Address != addr {
+			continue
+		}
+		if !from.IsZero() && e.PaidAt.Before(from) || !to.IsZero() && !e.PaidAt.Before(to) {
+			continue
+		}
+		list = append(list, *e)
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].PaidAt.Before(list[b].PaidAt) })
+	return list
+}
+
+// Summarize adds up ledger entries
+func Summarize(addr string, entries []LedgerEntry) AccountSummary {
+	s := AccountSummary{Address: addr}
+	paid, refunded := sdk.NewCoins(), sdk.NewCoins()
+	for _, e := range entries {
+		s.Jobs++
+		switch e.Status {
+		case compute.StatusCompleted:
+			s.JobsCompleted++
+		case compute.StatusFailed:
+			s.JobsFailed++
+		}
+		paid = paid.Add(sdk.NewCoin(e.Denom, e.Amount.Int))
+		s.PaidMEDAS += e.ValueMEDAS
+		if e.Refunded.IsPositive() {
+			refunded = refunded.Add(sdk.NewCoin(e.Denom, e.Refunded.Int))
+			s.RefundedMEDAS += e.ValueMEDAS * e.refundedShare()
+		}
+		s.ComputeSeconds += e.ComputeSeconds
+	}
+	s.Paid = paid.String()
+	s.Refunded = refunded.String()
+	return s
+}
+
+// refundedShare is the part of the payment that was refunded
+func (e LedgerEntry) refundedShare() float64 {
+	if !e.Refunded.IsPositive() || !e.Amount.IsPositive() {
+		return 0
+	}
+	share, _ := new(big.Rat).SetFrac(e.Refunded.BigInt(), e.Amount.BigInt()).Float64()
+	return share
+}
+
+func (e *LedgerEntry) finish(job *compute.ComputeJob) {
+	e.Status = job.Status
+	if job.CompletedAt == nil {
+		return
+	}
+	completedAt := *job.CompletedAt
+	e.CompletedAt = &completedAt
+	if job.StartedAt != nil {
+		e.ComputeSeconds = completedAt.Sub(*job.StartedAt).Seconds()
+	}
+}
+
+func (l *Ledger) persist() {
+	if err := l.save(); err != nil {
+		log.Printf("⚠️ Could not save ledger: %v", err)
+	}
+}
+
+func (l *Ledger) save() error {
+	defer telemetry.Track(telemetry.CategoryDisk, "save_ledger")()
+
+	list := make([]*LedgerEntry, 0, len(l.entries))
+	for _, e := range l.entries {
+		list = append(list, e)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path)
+}
+
+// jobDescription is the line item text of a job on a statement
+func jobDescription(job *compute.ComputeJob) string {
+	if job.Type != compute.JobTypePICalculation {
+		return string(job.Type)
+	}
+	digits, _ := job.Parameters["digits"].(float64)
+	method, _ := job.Parameters["method"].(string)
+	if method == "" {
+		method = string(compute.MethodChudnovsky)
+	}
+	return fmt.Sprintf("PI to %d digits (%s, %s tier)", int(digits), method, job.Tier)
+}
+
+// handleJobFinished books the outcome and compute time of a job
+func (rps *RealPaymentService) handleJobFinished(job *compute.ComputeJob) {
+	rps.ledger.Finish(job)
+}
+
+// handleGetAccount returns the cumulative totals of a client address
+func (rps *RealPaymentService) handleGetAccount(w http.ResponseWriter, r *http.Request) {
+	addr := mux.Vars(r)["addr"]
+	entries := rps.ledger.Entries(addr, time.Time{}, time.Time{})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Summarize(addr, entries))
+}
+
+// statementResponse is the JSON body of GET /accounts/{addr}/statement
+type statementResponse struct {
+	Address     string         `json:"address"`
+	Month       string         `json:"month"`
+	PeriodStart time.Time      `json:"period_start"`
+	PeriodEnd   time.Time      `json:"period_end"`
+	Summary     AccountSummary `json:"summary"`
+	Entries     []LedgerEntry  `json:"entries"`
+	Issuer      string         `json:"issuer"`
+	Currency    string         `json:"currency"`
+}
+
+// handleAccountStatement returns the monthly statement of a client address,
+// as CSV with ?format=csv
+func (rps *RealPaymentService) handleAccountStatement(w http.ResponseWriter, r *http.Request) {
+	addr := mux.Vars(r)["addr"]
+
+	month := r.URL.Query().Get("month")
+	if month == "" {
+		month = time.Now().UTC().Format(statementMonth)
+	}
+	from, err := time.Parse(statementMonth, month)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid month %q, expected YYYY-MM", month), http.StatusBadRequest)
+		return
+	}
+	to := from.AddDate(0, 1, 0)
+	entries := rps.ledger.Entries(addr, from, to)
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"statement-%s-%s.csv\"", addr, month))
+		if err := writeStatementCSV(w, entries); err != nil {
+			log.Printf("⚠️ Could not write statement for %s: %v", addr, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statementResponse{
+		Address:     addr,
+		Month:       month,
+		PeriodStart: from,
+		PeriodEnd:   to,
+		Summary:     Summarize(addr, entries),
+		Entries:     entries,
+		Issuer:      rps.serviceAddr,
+		Currency:    network.Current().DisplayDenom,
+	})
+}
+
+func writeStatementCSV(w http.ResponseWriter, entries []LedgerEntry) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"paid_at", "payment_tx_hash", "job_id", "description", "status", "amount", "denom", "value_medas", "refunded", "compute_seconds", "completed_at"})
+	for _, e := range entries {
+		completedAt := ""
+		if e.CompletedAt != nil {
+			completedAt = e.CompletedAt.UTC().Format(time.RFC3339)
+		}
+		cw.Write([]string{
+			e.PaidAt.UTC().Format(time.RFC3339),
+			e.PaymentTx,
+			e.JobID,
+			e.Description,
+			string(e.Status),
+			e.Amount.String(),
+			e.Denom,
+			strconv.FormatFloat(e.ValueMEDAS, 'f', 6, 64),
+			e.Refunded.String(),
+			strconv.FormatFloat(e.ComputeSeconds, 'f', 3, 64),
+			completedAt,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/internal/types"
+	"github.com/oxygene76/medasdigital-client/pkg/crossmatch"
+	"github.com/oxygene76/medasdigital-client/pkg/tracklet"
+)
+
+// linkReport is the file written by ai link
+type linkReport struct {
+	Input        string                 `json:"input"`
+	MinRate      float64                `json:"min_rate"` // arcsec/hour
+	MaxRate      float64                `json:"max_rate"`
+	Tolerance    float64                `json:"tolerance_arcsec"`
+	Observations []tracklet.Observation `json:"observations"`
+	Tracklets    []tracklet.Tracklet    `json:"tracklets"`
+	Objects      []types.TNOObject      `json:"objects"`
+}
+
+// aiLinkCmd links detections of a night into moving object candidates
+var aiLinkCmd = &cobra.Command{
+	Use:   "link <detections.json|xmatch.json>",
+	Short: "Link detections across exposures into moving object candidates",
+	Long: `Link the detections of several exposures of a night into tracklets: detections
+moving on a straight line at --min-rate to --max-rate arcseconds per hour,
+found in at least --min-detections exposures within --max-span. Detections
+at the same position in another exposure are stationary and never linked.
+
+Every tracklet gets a preliminary circular orbit from its position and rate
+of motion. Tracklets whose motion no circular orbit explains are chance
+alignments and are dropped unless --all is given.
+
+The input is the output of 'ai detect' or 'ai crossmatch'; for a cross-match
+only the candidate detections are linked. --csv writes the orbits in the
+input format of 'analyze orbital-dynamics':
+
+  medasdigital-client ai link detections_xmatch.json --csv candidates.csv
+  medasdigital-client analyze orbital-dynamics candidates.csv`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := tracklet.DefaultOptions()
+		opts.MinRate, _ = cmd.Flags().GetFloat64("min-rate")
+		opts.MaxRate, _ = cmd.Flags().GetFloat64("max-rate")
+		opts.Tolerance, _ = cmd.Flags().GetFloat64("tolerance")
+		opts.MinDetections, _ = cmd.Flags().GetInt("min-detections")
+		opts.MaxSpan, _ = cmd.Flags().GetDuration("max-span")
+		if err := opts.Validate(); err != nil {
+			return err
+		}
+		keepAll, _ := cmd.Flags().GetBool("all")
+		output, _ := cmd.Flags().GetString("output")
+		csvPath, _ := cmd.Flags().GetString("csv")
+
+		obs, skipped, err := readLinkObservations(args[0])
+		if err != nil {
+			return err
+		}
+		if len(obs) == 0 {
+			return fmt.Errorf("no detections with a sky position in %s, linking needs FITS cutouts with a WCS", args[0])
+		}
+		fmt.Printf("Linking %d detections (%.1f-%.1f\"/h, tolerance %.1f\")\n", len(obs), opts.MinRate, opts.MaxRate, opts.Tolerance)
+		if skipped > 0 {
+			fmt.Printf("Skipped %d detections without position or matched to a catalog\n", skipped)
+		}
+
+		tracks, err := tracklet.Link(obs, opts)
+		if err != nil {
+			return err
+		}
+		report := linkReport{Input: args[0], MinRate: opts.MinRate, MaxRate: opts.MaxRate, Tolerance: opts.Tolerance, Observations: obs}
+		unphysical := 0
+		for _, t := range tracks {
+			orbit, err := tracklet.FitCircularOrbit(&t)
+			if err == nil {
+				t.Orbit = orbit
+			}
+			if !keepAll && (orbit == nil || !orbit.Physical(t.Rate)) {
+				unphysical++
+				continue
+			}
+			report.Tracklets = append(report.Tracklets, t)
+			report.Objects = append(report.Objects, t.TNOObject(obs))
+		}
+
+		printTracklets(report.Tracklets)
+		if unphysical > 0 {
+			fmt.Printf("\nDropped %d tracklets without a physical orbit (--all keeps them)\n", unphysical)
+		}
+
+		if output == "" {
+			output = strings.TrimSuffix(args[0], filepath.Ext(args[0])) + "_tracklets.json"
+		}
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", output, err)
+		}
+		fmt.Printf("\nTracklets saved to: %s\n", output)
+
+		if csvPath != "" {
+			f, err := os.Create(csvPath)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", csvPath, err)
+			}
+			defer f.Close()
+			if err := tracklet.WriteOrbitalCSV(f, report.Objects); err != nil {
+				return fmt.Errorf("failed to write %s: %w", csvPath, err)
+			}
+			fmt.Printf("Orbits saved to: %s\n", csvPath)
+		}
+		return nil
+	},
+}
+
+// readLinkObservations reads the positioned detections of an ai detect
+// result, or the candidates of an ai crossmatch report
+func readLinkObservations(path string) ([]tracklet.Observation, int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read detections: %w", err)
+	}
+	var obs []tracklet.Observation
+	skipped := 0
+
+	var report crossmatchReport
+	if json.Unmarshal(data, &report) == nil && report.Counts != nil {
+		for _, d := range report.Detections {
+			if d.Sky == nil || d.Status != crossmatch.StatusCandidate {
+				skipped++
+				continue
+			}
+			obs = append(obs, tracklet.Observation{Image: d.Image, Time: d.Sky.ObsTime, RA: d.Sky.RA, Dec: d.Sky.Dec, Score: float64(d.Score)})
+		}
+		return obs, skipped, nil
+	}
+
+	detections, err := readDetections(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, d := range detections {
+		if d.Sky == nil {
+			skipped++
+			continue
+		}
+		obs = append(obs, tracklet.Observation{Image: d.Image, Time: d.Sky.ObsTime, RA: d.Sky.RA, Dec: d.Sky.Dec, Score: float64(d.Score)})
+	}
+	return obs, skipped, nil
+}
+
+// printTracklets prints one line per tracklet
+func printTracklets(tracks []tracklet.Tracklet) {
+	fmt.Printf("\nFound %d tracklets\n", len(tracks))
+	if len(tracks) == 0 {
+		return
+	}
+	fmt.Printf("\n%-8s %-4s %-10s %-10s %-7s %-6s %-6s %-8s %-7s %s\n", "ID", "N", "RA", "Dec", "Rate\"/h", "PA", "RMS\"", "a (AU)", "i", "Class")
+	fmt.Println(strings.Repeat("-", 85))
+	for _, t := range tracks {
+		a, inc, class := "-", "-", "-"
+		if t.Orbit != nil {
+			a = fmt.Sprintf("%.1f", t.Orbit.SemiMajorAxis)
+			inc = fmt.Sprintf("%.1f", t.Orbit.Inclination)
+			class = tracklet.Classify(t.Orbit.SemiMajorAxis)
+		}
+		fmt.Printf("%-8s %-4d %-10.5f %-+10.5f %-7.2f %-6.1f %-6.2f %-8s %-7s %s\n", t.ID, len(t.Observations),
+			t.MidRA, t.MidDec, t.Rate, t.PositionAngle, t.RMS, a, inc, class)
+	}
+}
+
+func init() {
+	aiCmd.AddCommand(aiLinkCmd)
+
+	defaults := tracklet.DefaultOptions()
+	aiLinkCmd.Flags().Float64("min-rate", defaults.MinRate, "Slowest motion in arcsec/hour")
+	aiLinkCmd.Flags().Float64("max-rate", defaults.MaxRate, "Fastest motion in arcsec/hour")
+	aiLinkCmd.Flags().Float64("tolerance", defaults.Tolerance, "Largest offset from the linear track in arcsec")
+	aiLinkCmd.Flags().Int("min-detections", defaults.MinDetections, "Detections per tracklet")
+	aiLinkCmd.Flags().Duration("max-span", defaults.MaxSpan, "Longest time between first and last detection")
+	aiLinkCmd.Flags().Bool("all", false, "Keep tracklets without a physical orbit")
+	aiLinkCmd.Flags().StringP("output", "o", "", "Output file (default <input>_tracklets.json)")
+	aiLinkCmd.Flags().String("csv", "", "Write the orbits as CSV for analyze orbital-dynamics")
+}
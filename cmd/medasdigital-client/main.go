@@ -2,13 +2,15 @@ package main
 
 import (
 	"context"
+	"errors"
     "fmt"
 	"sync" 
 	"os"
-	"io"
+	"os/signal"
+	"syscall"
 	"strconv"
+	"net"
 	"net/http"
-	"os/exec"
 	"path/filepath"
 	"time"
 	"strings"
@@ -22,22 +24,31 @@ import (
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
 
 	"github.com/cosmos/cosmos-sdk/client"
-	"github.com/cosmos/cosmos-sdk/codec"
-	"github.com/cosmos/cosmos-sdk/codec/types"
 	"github.com/cosmos/cosmos-sdk/crypto/hd"
 	"github.com/cosmos/cosmos-sdk/crypto/keyring"
-	"github.com/cosmos/cosmos-sdk/std"
 
 
 	
 	// ✅ KORREKTE v0.50 IMPORTS für echte Blockchain-Kommunikation:
-	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"           // Für TxConfig
 	"github.com/cosmos/cosmos-sdk/client/flags"              // Für BroadcastMode
 	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"    // Für AccountRetriever
 
+	"github.com/oxygene76/medasdigital-client/pkg/analysis"
+	"github.com/oxygene76/medasdigital-client/pkg/astronomy/orbital"
+	"github.com/oxygene76/medasdigital-client/pkg/astronomy/survey"
 	blockchain "github.com/oxygene76/medasdigital-client/pkg/blockchain"  // Wieder hinzufügen
 	medasClient "github.com/oxygene76/medasdigital-client/pkg/client"
 	"github.com/oxygene76/medasdigital-client/pkg/compute"
+	"github.com/oxygene76/medasdigital-client/pkg/contract"
+	"github.com/oxygene76/medasdigital-client/pkg/crossmatch"
+	"github.com/oxygene76/medasdigital-client/pkg/indexer"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+	"github.com/oxygene76/medasdigital-client/pkg/notify"
+	"github.com/oxygene76/medasdigital-client/pkg/openapi"
+	"github.com/oxygene76/medasdigital-client/pkg/ratelimit"
+	"github.com/oxygene76/medasdigital-client/pkg/state"
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
+	"github.com/oxygene76/medasdigital-client/pkg/utils"
     "github.com/gorilla/mux"  // Für HTTP Router
 )
 
@@ -46,28 +57,38 @@ const (
 	appName = "medasdigital-client"
 	version = "v1.0.0"
 	
-	// Default chain configuration
-	defaultChainID     = "medasdigital-2"
-	defaultRPCEndpoint = "https://rpc.medas-digital.io:26657"
-	defaultBech32Prefix = "medas"
-	defaultBaseDenom    = "umedas"        // ← NEU HINZUFÜGEN
+	// Default chain configuration, see pkg/network for the bundled values
+	defaultNetwork = network.DefaultNetwork
 )
 
+// envOverrides are environment variables that override config keys, the
+// --chain-id and --node flags take precedence over them
+var envOverrides = map[string]string{
+	"chain.network":          "MEDAS_NETWORK",
+	"chain.chain_id":         "MEDAS_CHAIN_ID",
+	"chain.rpc_endpoint":     "MEDAS_RPC",
+	"client.keyring_backend": "MEDAS_KEYRING_BACKEND",
+	"client.fee_granter":     "MEDAS_FEE_GRANTER",
+	"timeout":                "MEDAS_TIMEOUT",
+	"error_format":           "MEDAS_ERROR_FORMAT",
+}
+
 var (
-	// Global client instance
-	globalClient *medasClient.MedasDigitalClient
+	// cliApp is the client of this invocation, see currentApp
+	cliApp *medasClient.App
 	
 	// Configuration
-	cfgFile string
-	homeDir string
-	
-	// ✅ NEU: Globale Registry-Instanzen um Konflikte zu vermeiden
-	globalInterfaceRegistry types.InterfaceRegistry
-	globalCodec             codec.Codec
+	cfgFile  string
+	homeDir  string
+	timing   bool
+	nodeFlag    string // --node, also bound to chain.rpc_endpoint
+	chainIDFlag string // --chain-id, also bound to chain.chain_id
+	networkFlag string // --network, also bound to chain.network
+	noDaemon    bool   // --no-daemon, ignore a running daemon
+	dryRun      bool   // --dry-run, simulate transactions instead of broadcasting them
 	
 	// ✅ Computing Service Tracking (NEU HINZUGEFÜGT)
 	serviceStartTime time.Time
-	globalClientCtx  interface{} // Für payment service
 )
 
 
@@ -79,18 +100,38 @@ type ChainStatus struct {
 	CatchingUp       bool
 }
 
+// ChainConfig holds the chain settings, unset values fall back to the selected network
+type ChainConfig struct {
+    Network      string `yaml:"network"`
+    ID           string `yaml:"chain_id"`
+    RPCEndpoint  string `yaml:"rpc_endpoint"`
+    WSEndpoint   string `yaml:"ws_endpoint,omitempty"`
+    RESTEndpoint string `yaml:"rest_endpoint,omitempty"`
+    GRPCEndpoint string `yaml:"grpc_endpoint,omitempty"`
+    Bech32Prefix string `yaml:"bech32_prefix"`
+    BaseDenom    string `yaml:"base_denom"`
+    DisplayDenom string `yaml:"display_denom,omitempty"`
+    Decimals     int    `yaml:"decimals,omitempty"`
+    GasPrice     string `yaml:"gas_price,omitempty"`
+}
+
+// ContractConfig holds the compute contract settings
+type ContractConfig struct {
+    Address          string `yaml:"address"`
+    CommunityAddress string `yaml:"community_address"`
+    Successor        string `yaml:"successor,omitempty"` // provider nodes migrate to it, see provider-node
+}
+
 // Config represents the application configuration
 type Config struct {
-    Chain struct {
-        ID           string `yaml:"chain_id"`
-        RPCEndpoint  string `yaml:"rpc_endpoint"`
-        Bech32Prefix string `yaml:"bech32_prefix"`
-        BaseDenom    string `yaml:"base_denom"`
-    } `yaml:"chain"`
+    Version  int            `yaml:"version"` // format version, see config migrate
+    Chain    ChainConfig    `yaml:"chain"`
+    Contract ContractConfig `yaml:"contract"`
     Client struct {
         KeyringDir     string   `yaml:"keyring_dir"`
         KeyringBackend string   `yaml:"keyring_backend"`
         Capabilities   []string `yaml:"capabilities"`
+        FeeGranter     string   `yaml:"fee_granter,omitempty"` // account paying the transaction fees, see feegrant
     } `yaml:"client"`
     Provider struct {  // ← NEU HINZUFÜGEN
         Enabled              bool   `yaml:"enabled"`
@@ -104,14 +145,67 @@ type Config struct {
         Workers              int    `yaml:"workers"`
         HarvestIntervalHours int    `yaml:"harvest_interval_hours"`
 		HeartbeatIntervalMinutes int `yaml:"heartbeat_interval_minutes"` 
+        Sandbox              compute.SandboxConfig `yaml:"sandbox,omitempty"`
+        Bidding              contract.BiddingConfig `yaml:"bidding,omitempty"`
+        Contracts            []string `yaml:"contracts,omitempty"` // served besides contract.address
+        Harvest              []contract.HarvestRule `yaml:"harvest,omitempty"` // per denom, replaces funding_address
+        JobStore             string `yaml:"job_store,omitempty"` // directory for crash recovery, default <home>/provider/jobs
+        AdminToken           string `yaml:"admin_token,omitempty"` // bearer token of the /admin endpoints
     } `yaml:"provider"`
+    Payment struct {
+        IBCDenoms []compute.PaymentDenom `yaml:"ibc_denoms,omitempty"` // IBC tokens the payment service accepts
+        TolerancePercent float64 `yaml:"tolerance_percent,omitempty"` // accepted difference to the price in percent, default 0.1
+        Strict bool `yaml:"strict,omitempty"` // require the exact price, ignores tolerance_percent
+        MemoryBudgetMB int `yaml:"memory_budget_mb,omitempty"` // PI calculations keep larger products on disk, 0 keeps all in memory
+        SpillDir string `yaml:"spill_dir,omitempty"` // where products are spilled, default the system temp dir
+        ArtifactRetention time.Duration `yaml:"artifact_retention,omitempty"` // keep result files no job refers to, default 168h
+        PricingFile string `yaml:"pricing_file,omitempty"` // tiers, surge and fiat prices instead of the built-in tiers
+        Limits LimitsConfig `yaml:"limits,omitempty"` // concurrent jobs and daily spend per client address
+        ResultCache ResultCacheConfig `yaml:"result_cache,omitempty"` // identical jobs answered from earlier results
+    } `yaml:"payment"`
+    HTTP HTTPConfig `yaml:"http"` // CORS, body size limit and security headers of the HTTP services
+    Networks map[string]network.Network `yaml:"networks,omitempty"` // custom network profiles, see --network
+    Notifications notify.Config `yaml:"notifications,omitempty"` // email, Slack or Matrix alerts when long work ends
+    CrossMatch crossmatch.Config `yaml:"crossmatch,omitempty"` // Gaia/SIMBAD cross-match of ai detect results
+    Indexer struct {
+        Addresses []string `yaml:"addresses,omitempty"` // synced into the local transaction index
+        Path      string   `yaml:"path,omitempty"`      // default <home>/index/<chain id>.db
+    } `yaml:"indexer,omitempty"`
     GPU struct {
-        Enabled     bool `yaml:"enabled"`
-        DeviceID    int  `yaml:"device_id"`
-        MemoryLimit int  `yaml:"memory_limit"`
+        Enabled     bool  `yaml:"enabled"`
+        DeviceID    int   `yaml:"device_id"`
+        Devices     []int `yaml:"devices,omitempty"`  // multi-GPU, overrides device_id
+        MemoryLimit int   `yaml:"memory_limit"`       // MB per device
     } `yaml:"gpu"`
 }
 
+// clientConfig converts the config for the analysis client in pkg/client.
+// GPUs are left disabled there and enabled with ConfigureGPU.
+func (c *Config) clientConfig() *medasClient.Config {
+    cc := &medasClient.Config{}
+    cc.Chain.ID = c.Chain.ID
+    cc.Chain.RPCEndpoint = c.Chain.RPCEndpoint
+    cc.Client.KeyringDir = c.Client.KeyringDir
+    cc.Client.KeyringBackend = c.Client.KeyringBackend
+    cc.Client.Capabilities = c.Client.Capabilities
+    if len(cc.Client.Capabilities) == 0 {
+        cc.Client.Capabilities = medasClient.LoadDefaultConfig().Client.Capabilities
+    }
+    return cc
+}
+
+// gpuConfig converts the gpu section for the GPU manager
+func (c *Config) gpuConfig() utils.GPUConfig {
+    gpuConfig := *utils.DefaultGPUConfig()
+    gpuConfig.Enabled = c.GPU.Enabled
+    gpuConfig.DeviceID = c.GPU.DeviceID
+    gpuConfig.CUDADevices = c.GPU.Devices
+    if len(gpuConfig.CUDADevices) == 0 {
+        gpuConfig.CUDADevices = []int{c.GPU.DeviceID}
+    }
+    return gpuConfig
+}
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   appName,
@@ -126,13 +220,26 @@ results and collaborate with other researchers in the search for Planet 9
 and other astronomical objects.`,
 	Version: version,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if timing {
+			telemetry.Enable()
+		}
+		applyTimeout(cmd)
+		if jsonErrors(cmd) {
+			cmd.SilenceUsage = true
+		}
+
 		// Initialize configuration
 		if err := initConfig(); err != nil {
 			return fmt.Errorf("failed to initialize config: %w", err)
 		}
 		
 		// Initialize client context for blockchain commands
-		if cmd.Name() != "init" && cmd.Name() != "version" && cmd.Name() != "help" {
+		// config and network commands must work on configs the client cannot start with
+		if cmd.Name() != "init" && cmd.Name() != "version" && cmd.Name() != "help" && !(cmd.HasParent() && (cmd.Parent() == configCmd || cmd.Parent() == networkCmd || cmd.Parent() == daemonCmd)) {
+			// A running daemon already holds the chain connection
+			if cmd.Annotations[daemonAnnotation] != "" && connectDaemon() != nil {
+				return setupApp()
+			}
 			if err := initializeClient(); err != nil {
 				return fmt.Errorf("failed to initialize client: %w", err)
 			}
@@ -158,24 +265,28 @@ operation.`,
 		}
 		
 		// Create default configuration
+		if err := registerNetworkProfiles(); err != nil {
+			return err
+		}
+		net, err := network.Get(viper.GetString("chain.network"))
+		if err != nil {
+			return err
+		}
 config := &Config{
-    Chain: struct {
-        ID           string `yaml:"chain_id"`
-        RPCEndpoint  string `yaml:"rpc_endpoint"`
-        Bech32Prefix string `yaml:"bech32_prefix"`
-        BaseDenom    string `yaml:"base_denom"`     // ← NEU HINZUFÜGEN
-    }{
-        ID:           defaultChainID,
-        RPCEndpoint:  defaultRPCEndpoint,
-        Bech32Prefix: defaultBech32Prefix,
-        BaseDenom:    defaultBaseDenom,                       // ← NEU HINZUFÜGEN
+    Chain: ChainConfig{
+        Network:      net.Name,
+        ID:           net.ChainID,
+        RPCEndpoint:  net.RPCEndpoint,
+        Bech32Prefix: net.Bech32Prefix,
+        BaseDenom:    net.BaseDenom,
     },
 			Client: struct {
 			KeyringDir     string   `yaml:"keyring_dir"`
 			KeyringBackend string   `yaml:"keyring_backend"`  // ← NEU
 			Capabilities   []string `yaml:"capabilities"`
+			FeeGranter     string   `yaml:"fee_granter,omitempty"`
 		}{
-			KeyringDir:     filepath.Join(homeDir, "keyring"),
+			KeyringDir:     filepath.Join(homeDir, state.KeyringDir),
 			KeyringBackend: "test",  // ← NEU HINZUFÜGEN
 			Capabilities:   []string{"orbital_dynamics", "photometric_analysis"},
 		},
@@ -191,6 +302,12 @@ config := &Config{
                 Workers              int    `yaml:"workers"`
                 HarvestIntervalHours int    `yaml:"harvest_interval_hours"`
 				HeartbeatIntervalMinutes int `yaml:"heartbeat_interval_minutes"` 
+                Sandbox              compute.SandboxConfig `yaml:"sandbox,omitempty"`
+                Bidding              contract.BiddingConfig `yaml:"bidding,omitempty"`
+                Contracts            []string `yaml:"contracts,omitempty"`
+                Harvest              []contract.HarvestRule `yaml:"harvest,omitempty"`
+                JobStore             string `yaml:"job_store,omitempty"`
+                AdminToken           string `yaml:"admin_token,omitempty"`
             }{
                 Enabled:              false,
                 KeyName:              "my-provider",
@@ -206,9 +323,10 @@ config := &Config{
 				 
             },
 			GPU: struct {
-				Enabled     bool `yaml:"enabled"`
-				DeviceID    int  `yaml:"device_id"`
-				MemoryLimit int  `yaml:"memory_limit"`
+				Enabled     bool  `yaml:"enabled"`
+				DeviceID    int   `yaml:"device_id"`
+				Devices     []int `yaml:"devices,omitempty"`
+				MemoryLimit int   `yaml:"memory_limit"`
 			}{
 				Enabled:     false,
 				DeviceID:    0,
@@ -217,7 +335,9 @@ config := &Config{
 		}
 		
 		// Save configuration using viper
+		viper.Set("version", configVersion)
 		viper.Set("chain", config.Chain)
+		viper.Set("contract", map[string]string{"address": net.ContractAddress})
 		viper.Set("client", config.Client)
 		viper.Set("gpu", config.GPU)
 		
@@ -254,7 +374,7 @@ var statusCmd = &cobra.Command{
 		if err == nil && len(localHashes) > 0 {
 			// Try to fetch the most recent registration from blockchain
 			for _, hash := range localHashes {
-				if regData, err := blockchain.FetchRegistrationFromBlockchain(hash, cfg.Chain.RPCEndpoint, cfg.Chain.ID, globalCodec); err == nil {
+				if regData, err := blockchain.FetchRegistrationFromBlockchain(cmd.Context(), hash, cfg.Chain.RPCEndpoint, cfg.Chain.ID, currentApp().Codec()); err == nil {
 					if blockchainRegistration == nil || regData.BlockTime.After(blockchainRegistration.BlockTime) {
 						blockchainRegistration = regData
 						isRegistered = true
@@ -310,7 +430,7 @@ var statusCmd = &cobra.Command{
 		
 		// Test blockchain connection with detailed info
 		fmt.Print("Blockchain Status: ")
-		if status, err := getDetailedChainStatus(cfg.Chain.RPCEndpoint); err != nil {
+		if status, err := chainStatus(cmd.Context(), cfg); err != nil {
 			fmt.Printf("❌ Disconnected (%v)\n", err)
 		} else {
 			fmt.Printf("✅ Connected (Block: %d, %s)\n", 
@@ -377,7 +497,7 @@ var checkAccountCmd = &cobra.Command{
 		
 		// Test connection first
 		fmt.Printf("🔗 Connecting to: %s\n", cfg.Chain.RPCEndpoint)
-		if err := testBlockchainConnection(cfg.Chain.RPCEndpoint); err != nil {
+		if err := testBlockchainConnection(cmd.Context(), cfg.Chain.RPCEndpoint); err != nil {
 			return fmt.Errorf("blockchain connection failed: %w", err)
 		}
 		
@@ -390,18 +510,11 @@ var checkAccountCmd = &cobra.Command{
 		}
 		
 		// Create minimal client context for query
-		if globalInterfaceRegistry == nil {
-			globalInterfaceRegistry = getInterfaceRegistry()
-		}
-		if globalCodec == nil {
-			globalCodec = codec.NewProtoCodec(globalInterfaceRegistry)
-		}
-		
 		queryCtx := client.Context{}.
 			WithClient(rpcClient).
 			WithChainID(cfg.Chain.ID).
-			WithCodec(globalCodec).
-			WithInterfaceRegistry(globalInterfaceRegistry)
+			WithCodec(currentApp().Codec()).
+			WithInterfaceRegistry(currentApp().InterfaceRegistry())
 		
 		// Parse address for validation
 		_, err = sdk.AccAddressFromBech32(address)
@@ -411,7 +524,7 @@ var checkAccountCmd = &cobra.Command{
 
 		// TEST 1: Bank balance query (Protobuf method)
 		fmt.Println("🔍 Testing Bank Balance Query (v0.50.10):")
-		denoms := []string{"umedas", "medas", "stake"}
+		denoms := []string{network.Current().BaseDenom, "stake"}
 		for _, denom := range denoms {
 			fmt.Printf("   Testing denom '%s':\n", denom)
 			
@@ -441,7 +554,7 @@ var checkAccountCmd = &cobra.Command{
 					fmt.Printf("     ❌ Failed to decode response: %v\n", err)
 				} else {
 					if balanceResp.Balance != nil && !balanceResp.Balance.Amount.IsZero() {
-						fmt.Printf("     💰 BALANCE FOUND: %s %s\n", balanceResp.Balance.Amount, balanceResp.Balance.Denom)
+						fmt.Printf("     💰 BALANCE FOUND: %s\n", formatCoin(*balanceResp.Balance))
 					} else {
 						fmt.Printf("     💰 Balance: 0 %s\n", denom)
 					}
@@ -473,7 +586,7 @@ var checkAccountCmd = &cobra.Command{
 					if len(allBalancesResp.Balances) > 0 {
 						fmt.Printf("     💰 TOTAL BALANCES FOUND:\n")
 						for _, balance := range allBalancesResp.Balances {
-							fmt.Printf("       %s %s\n", balance.Amount, balance.Denom)
+							fmt.Printf("       %s\n", formatCoin(balance))
 						}
 					} else {
 						fmt.Printf("     💰 No balances found (empty account)\n")
@@ -485,7 +598,9 @@ var checkAccountCmd = &cobra.Command{
 		// TEST 3: Transaction-based balance estimation
 		fmt.Printf("\n   Transaction-based balance analysis:\n")
 		query := fmt.Sprintf("transfer.recipient='%s' OR transfer.sender='%s'", address, address)
-		txSearchResult, err := rpcClient.TxSearch(context.Background(), query, false, nil, nil, "desc")
+		stopTimer := telemetry.Track(telemetry.CategoryRPC, "tx_search")
+		txSearchResult, err := rpcClient.TxSearch(cmd.Context(), query, false, nil, nil, "desc")
+		stopTimer()
 		if err != nil {
 			fmt.Printf("     ❌ Could not search transactions: %v\n", err)
 		} else {
@@ -518,9 +633,10 @@ var checkAccountCmd = &cobra.Command{
 								}
 							}
 							
-							if amount != "" && strings.Contains(amount, "umedas") {
+							baseDenom := network.Current().BaseDenom
+							if amount != "" && strings.HasSuffix(amount, baseDenom) {
 								// Extract numeric amount
-								amountStr := strings.Replace(amount, "umedas", "", -1)
+								amountStr := strings.TrimSuffix(amount, baseDenom)
 								if amountVal, err := strconv.ParseInt(amountStr, 10, 64); err == nil {
 									if isReceiver {
 										totalReceived += amountVal
@@ -536,9 +652,10 @@ var checkAccountCmd = &cobra.Command{
 				
 				if totalReceived > 0 || totalSent > 0 {
 					fmt.Printf("     💸 Transaction analysis (last 10 txs):\n")
-					fmt.Printf("       Received: %d umedas\n", totalReceived)
-					fmt.Printf("       Sent: %d umedas\n", totalSent)
-					fmt.Printf("       Net: %d umedas\n", totalReceived-totalSent)
+					net := network.Current()
+					fmt.Printf("       Received: %s\n", net.FormatAmount(totalReceived))
+					fmt.Printf("       Sent: %s\n", net.FormatAmount(totalSent))
+					fmt.Printf("       Net: %s\n", net.FormatAmount(totalReceived-totalSent))
 					fmt.Printf("     💡 Note: This is not exact balance, just transaction history\n")
 				}
 			}
@@ -548,7 +665,9 @@ var checkAccountCmd = &cobra.Command{
 
 		// TEST 4: Chain information that works
 		fmt.Println("🔍 Working Chain Information:")
-		status, err := queryCtx.Client.Status(context.Background())
+		stopTimer = telemetry.Track(telemetry.CategoryRPC, "status")
+		status, err := queryCtx.Client.Status(cmd.Context())
+		stopTimer()
 		if err != nil {
 			fmt.Printf("   Status Error: %v\n", err)
 		} else {
@@ -624,10 +743,33 @@ calculate Planet 9 influence probabilities.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		inputFile := args[0]
 		outputFile, _ := cmd.Flags().GetString("output")
+		epochFlag, _ := cmd.Flags().GetString("epoch")
+		propagation, _ := cmd.Flags().GetString("propagation")
+		frameFlag, _ := cmd.Flags().GetString("frame")
+		clones, _ := cmd.Flags().GetInt("clones")
+		seed, _ := cmd.Flags().GetInt64("seed")
+		surveys, _ := cmd.Flags().GetString("surveys")
+		epoch, err := orbital.ParseEpoch(epochFlag)
+		if err != nil {
+			return err
+		}
+		frame, err := orbital.ParseFrame(frameFlag)
+		if err != nil {
+			return err
+		}
+		selection, err := survey.ParseSelection(surveys)
+		if err != nil {
+			return err
+		}
 		
 		fmt.Printf("Starting orbital dynamics analysis on: %s\n", inputFile)
 		
-		if err := globalClient.AnalyzeOrbitalDynamics(inputFile, outputFile); err != nil {
+		c, err := analysisClient()
+		if err != nil {
+			return err
+		}
+		opts := analysis.OrbitalOptions{Epoch: epoch, Propagation: propagation, Frame: frame, Clones: clones, Seed: seed, Selection: selection}
+		if err := c.AnalyzeOrbitalDynamics(cmd.Context(), inputFile, outputFile, opts); err != nil {
 			return fmt.Errorf("orbital dynamics analysis failed: %w", err)
 		}
 		
@@ -648,7 +790,11 @@ var analyzePhotometricCmd = &cobra.Command{
 		
 		fmt.Printf("Starting photometric analysis on: %s\n", surveyData)
 		
-		if err := globalClient.AnalyzePhotometric(surveyData, targetList); err != nil {
+		c, err := analysisClient()
+		if err != nil {
+			return err
+		}
+		if err := c.AnalyzePhotometric(cmd.Context(), surveyData, targetList); err != nil {
 			return fmt.Errorf("photometric analysis failed: %w", err)
 		}
 		
@@ -698,7 +844,7 @@ var balanceCmd = &cobra.Command{
 		cfg := loadConfig()
 		
 		// Method 1: Direct Tendermint RPC Balance Query
-		if balance, err := queryBalanceViaTendermint(address, cfg); err != nil {
+		if balance, err := queryBalanceViaTendermint(cmd.Context(), address, cfg); err != nil {
 			fmt.Printf("❌ Tendermint RPC Query failed: %v\n", err)
 		} else {
 			fmt.Println("✅ Tendermint RPC Balance Query:")
@@ -706,22 +852,22 @@ var balanceCmd = &cobra.Command{
 				fmt.Printf("   Balance: 0 (no funds)\n")
 			} else {
 				for _, coin := range balance {
-					fmt.Printf("   %s %s\n", coin.Amount, coin.Denom)
+					fmt.Printf("   %s\n", formatCoin(coin))
 				}
 			}
 		}
 		
 		// Method 2: Alternative REST Query (different format)
 		fmt.Println("\n🔍 Alternative REST Query:")
-		if balance, err := queryBalanceViaREST(address, cfg); err != nil {
+		if balance, err := queryBalanceViaREST(cmd.Context(), address, cfg); err != nil {
 			fmt.Printf("❌ REST Query failed: %v\n", err)
 		} else {
 			fmt.Println("✅ REST Balance Query:")
 			if len(balance) == 0 {
 				fmt.Printf("   Balance: 0 (no funds)\n")
 			} else {
-				for denom, amount := range balance {
-					fmt.Printf("   %s %s\n", amount, denom)
+				for _, coin := range balance {
+					fmt.Printf("   %s\n", formatCoin(coin))
 				}
 			}
 		}
@@ -736,14 +882,14 @@ var balanceCmd = &cobra.Command{
 				fmt.Printf("   Balance: 0 (no funds)\n")
 			} else {
 				for _, coin := range balance {
-					fmt.Printf("   %s %s\n", coin.Amount, coin.Denom)
+					fmt.Printf("   %s\n", formatCoin(coin))
 				}
 			}
 		}
 		
 		// Method 4: Transaction History Analysis
 		fmt.Println("\n📊 Transaction History Analysis:")
-		if err := analyzeTransactionHistory(address, cfg); err != nil {
+		if err := analyzeTransactionHistory(cmd.Context(), address, cfg); err != nil {
 			fmt.Printf("❌ Transaction analysis failed: %v\n", err)
 		}
 		
@@ -760,7 +906,11 @@ var analyzeClusteringCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		fmt.Println("Starting clustering analysis...")
 		
-		if err := globalClient.AnalyzeClustering(); err != nil {
+		c, err := analysisClient()
+		if err != nil {
+			return err
+		}
+		if err := c.AnalyzeClustering(cmd.Context()); err != nil {
 			return fmt.Errorf("clustering analysis failed: %w", err)
 		}
 		
@@ -787,12 +937,23 @@ var aiTrainCmd = &cobra.Command{
 		architecture := args[1]
 		
 		gpuDevices, _ := cmd.Flags().GetIntSlice("gpu-devices")
+		gpuCount, _ := cmd.Flags().GetInt("gpu-count")
+		gpuMemory, _ := cmd.Flags().GetInt("gpu-memory")
 		batchSize, _ := cmd.Flags().GetInt("batch-size")
 		epochs, _ := cmd.Flags().GetInt("epochs")
+		exportDir, _ := cmd.Flags().GetString("export-job")
+		
+		if exportDir != "" {
+			return exportTrainingJob(cmd, trainingData, architecture, exportDir)
+		}
 		
 		fmt.Printf("Starting AI training with architecture: %s\n", architecture)
 		
-		if err := globalClient.TrainDeepDetector(trainingData, architecture, gpuDevices, batchSize, epochs); err != nil {
+		c, err := analysisClient()
+		if err != nil {
+			return err
+		}
+		if err := c.TrainDeepDetector(cmd.Context(), trainingData, architecture, gpuDevices, gpuCount, gpuMemory, batchSize, epochs); err != nil {
 			return fmt.Errorf("AI training failed: %w", err)
 		}
 		
@@ -812,10 +973,28 @@ var aiDetectCmd = &cobra.Command{
 		surveyImages := args[1]
 		
 		gpuAccel, _ := cmd.Flags().GetBool("gpu")
+		deviceID, _ := cmd.Flags().GetInt("device")
+		gpuMemory, _ := cmd.Flags().GetInt("gpu-memory")
+		batchSize, _ := cmd.Flags().GetInt("batch-size")
+		threshold, _ := cmd.Flags().GetFloat32("threshold")
+		ortLib, _ := cmd.Flags().GetString("ort-lib")
+		outputFile, _ := cmd.Flags().GetString("output")
 		
 		fmt.Printf("Starting AI detection on: %s\n", surveyImages)
 		
-		if err := globalClient.AIDetection(modelPath, surveyImages, gpuAccel); err != nil {
+		opts := analysis.DetectionOptions{
+			UseGPU:         gpuAccel,
+			DeviceID:       deviceID,
+			MemoryMB:       gpuMemory,
+			BatchSize:      batchSize,
+			ScoreThreshold: threshold,
+			LibraryPath:    ortLib,
+		}
+		c, err := analysisClient()
+		if err != nil {
+			return err
+		}
+		if err := c.AIDetection(cmd.Context(), modelPath, surveyImages, outputFile, opts); err != nil {
 			return fmt.Errorf("AI detection failed: %w", err)
 		}
 		
@@ -837,7 +1016,15 @@ var gpuStatusCmd = &cobra.Command{
 	Short: "Show GPU status",
 	Long:  "Display current GPU status including memory usage, temperature, and utilization.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return globalClient.GPUStatus()
+		if connectDaemon() != nil {
+			// Served by the daemon, which only knows the detected devices
+			return printDetectedGPUs(detectGPUs())
+		}
+		c, err := analysisClient()
+		if err != nil {
+			return err
+		}
+		return c.GPUStatus()
 	},
 }
 
@@ -847,7 +1034,11 @@ var gpuBenchmarkCmd = &cobra.Command{
 	Short: "Run GPU benchmark",
 	Long:  "Run a performance benchmark to test GPU computational capabilities.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return globalClient.GPUBenchmark()
+		c, err := analysisClient()
+		if err != nil {
+			return err
+		}
+		return c.GPUBenchmark()
 	},
 }
 
@@ -855,10 +1046,31 @@ var gpuBenchmarkCmd = &cobra.Command{
 var resultsCmd = &cobra.Command{
 	Use:   "results",
 	Short: "Retrieve analysis results",
-	Long:  "Retrieve recent analysis results from the blockchain.",
+	Long: `Retrieve analysis results from the blockchain, newest first and one page at
+a time. Results can be filtered by analysis type, client ID and creation date;
+the next page is fetched with the --cursor printed below a full page.
+
+  medasdigital-client results --type planet9_search --from 2025-01-01 --limit 20
+  medasdigital-client results --order height --asc --cursor <cursor>`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		limit, _ := cmd.Flags().GetInt("limit")
-		return globalClient.Results(limit)
+		q := blockchain.ResultsQuery{}
+		q.Limit, _ = cmd.Flags().GetInt("limit")
+		q.Cursor, _ = cmd.Flags().GetString("cursor")
+		q.AnalysisType, _ = cmd.Flags().GetString("type")
+		q.ClientID, _ = cmd.Flags().GetString("client-id")
+		q.OrderBy, _ = cmd.Flags().GetString("order")
+		q.Ascending, _ = cmd.Flags().GetBool("asc")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		from, to, err := indexPeriod(cmd)
+		if err != nil {
+			return err
+		}
+		q.From, q.To = from, to
+		c, err := analysisClient()
+		if err != nil {
+			return err
+		}
+		return c.Results(cmd.Context(), q, asJSON)
 	},
 }
 
@@ -871,7 +1083,11 @@ var queryCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		queryType := args[0]
 		queryID := args[1]
-		return globalClient.Query(queryType, queryID)
+		c, err := analysisClient()
+		if err != nil {
+			return err
+		}
+		return c.Query(cmd.Context(), queryType, queryID)
 	},
 }
 
@@ -882,10 +1098,28 @@ func init() {
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.medasdigital-client/config.yaml)")
 	rootCmd.PersistentFlags().StringVar(&homeDir, "home", "", "home directory (default is $HOME/.medasdigital-client)")
+	rootCmd.PersistentFlags().BoolVar(&timing, "timing", false, "print a breakdown of time spent in RPC, signing, computation and disk I/O")
+	rootCmd.PersistentFlags().StringVar(&networkFlag, "network", "", "network profile (mainnet|testnet|local or from networks:), overrides chain.network and $MEDAS_NETWORK")
+	rootCmd.PersistentFlags().StringVar(&chainIDFlag, "chain-id", "", "chain ID, overrides chain.chain_id and $MEDAS_CHAIN_ID")
+	rootCmd.PersistentFlags().BoolVar(&noDaemon, "no-daemon", false, "do not use a running daemon, connect directly")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "simulate transactions and print their messages, gas, fee and events without broadcasting")
+	rootCmd.PersistentFlags().StringVar(&nodeFlag, "node", "", "RPC endpoint, overrides chain.rpc_endpoint and $MEDAS_RPC")
+	viper.BindPFlag("chain.network", rootCmd.PersistentFlags().Lookup("network"))
+	viper.BindPFlag("chain.chain_id", rootCmd.PersistentFlags().Lookup("chain-id"))
+	rootCmd.PersistentFlags().Duration("timeout", 0, "cancel the command after this duration, e.g. 2m (default none, also $MEDAS_TIMEOUT)")
+	viper.BindPFlag("chain.rpc_endpoint", rootCmd.PersistentFlags().Lookup("node"))
+	viper.BindPFlag("timeout", rootCmd.PersistentFlags().Lookup("timeout"))
+	rootCmd.PersistentFlags().String("error-format", "text", "print errors as text or json, also $MEDAS_ERROR_FORMAT; see the README for exit codes")
+	viper.BindPFlag("error_format", rootCmd.PersistentFlags().Lookup("error-format"))
+	rootCmd.PersistentFlags().String("fee-granter", "", "address that pays the transaction fees through a fee grant, overrides client.fee_granter and $MEDAS_FEE_GRANTER")
+	viper.BindPFlag("client.fee_granter", rootCmd.PersistentFlags().Lookup("fee-granter"))
+	// main prints errors, as text or JSON
+	rootCmd.SilenceErrors = true
 
 	addKeysCommands()
 	checkAccountCmd.Flags().String("from", "", "Key name to check")
 	balanceCmd.Flags().String("from", "", "Key name to check balance for")
+	listRegistrationsCmd.Flags().Bool("offline", false, "Only read registrations from the local transaction index")
 	
 	
 	// Add subcommands
@@ -916,9 +1150,19 @@ func init() {
     serveCmd.Flags().Int("max-jobs", 2, "Maximum concurrent jobs")
     serveCmd.Flags().Duration("max-runtime", 30*time.Minute, "Maximum runtime per job")
     serveCmd.Flags().Bool("test-mode", true, "Enable test mode")
+    serveCmd.Flags().String("rate-limit-store", "file", "Where rate limits are kept: memory, file (survives restarts) or redis (shared by instances)")
+    serveCmd.Flags().String("redis-url", "", "Redis for --rate-limit-store redis, e.g. redis://:password@localhost:6379/0")
+    serveCmd.Flags().StringSlice("trusted-proxies", nil, "Proxy IPs/CIDRs whose X-Forwarded-For header is trusted")
+    serveCmd.Flags().String("abuse-protection", protectionOff, "Require per calculation: off, pow (proof of work) or deposit (a transfer to --deposit-address)")
+    serveCmd.Flags().Int("pow-difficulty", 20, "Leading zero bits of a proof of work, each bit doubles the work")
+    serveCmd.Flags().String("pow-secret", "", "Secret signing the challenges, the same on all instances sharing a redis store (default random)")
+    serveCmd.Flags().String("deposit-address", "", "Address receiving deposits for --abuse-protection deposit")
+    serveCmd.Flags().Float64("deposit-amount", 0.01, "Minimum deposit in MEDAS")
+    serveCmd.Flags().Int("deposit-requests", 20, "Calculations one deposit pays for")
+    serveCmd.Flags().Duration("deposit-max-age", 24*time.Hour, "How long after its block a deposit can be used")
     
     // Flags für pi calculate command
-    piCalculateCmd.Flags().String("method", "chudnovsky", "Calculation method (chudnovsky|machin|bailey)")
+    piCalculateCmd.Flags().String("method", "chudnovsky", "Calculation method (chudnovsky|machin|bailey|auto)")
     piCalculateCmd.Flags().String("output", "", "Output file for result")
     piCalculateCmd.Flags().Bool("verbose", false, "Verbose output")
 	
@@ -937,20 +1181,48 @@ func init() {
 	
 	// Analyze orbital flags
 	analyzeOrbitalCmd.Flags().String("output", "", "Output file for results")
+	analyzeOrbitalCmd.Flags().String("epoch", "J2000", "Common epoch the objects are propagated to (J2000, a Julian date or YYYY-MM-DD)")
+	analyzeOrbitalCmd.Flags().String("frame", string(orbital.DefaultFrame), "Reference frame the clustering is measured in, the input is heliocentric-ecliptic")
+	analyzeOrbitalCmd.Flags().String("propagation", orbital.PropagationTwoBody, "Propagation to the common epoch ("+strings.Join(orbital.Propagations, ", ")+", none)")
+	analyzeOrbitalCmd.Flags().Int("clones", 0, "Monte Carlo clones drawn from the element uncertainties (CSV sigma columns), 0 = none")
+	analyzeOrbitalCmd.Flags().Int64("seed", 1, "Random seed of the clones and the survey simulation")
+	analyzeOrbitalCmd.Flags().String("surveys", "default", "Survey footprints the clustering significance is debiased for: default, none or a JSON file")
 	
 	// Analyze photometric flags
 	analyzePhotometricCmd.Flags().String("targets", "", "Target list file")
 	
 	// AI train flags
-	aiTrainCmd.Flags().IntSlice("gpu-devices", []int{0}, "GPU device IDs to use")
+	aiTrainCmd.Flags().IntSlice("gpu-devices", nil, "GPU device IDs to use (default: least loaded devices)")
+	aiTrainCmd.Flags().Int("gpu-count", 1, "Number of GPUs to schedule when --gpu-devices is not set")
+	aiTrainCmd.Flags().Int("gpu-memory", 0, "GPU memory in MB needed per device (default 2048)")
 	aiTrainCmd.Flags().Int("batch-size", 32, "Training batch size")
 	aiTrainCmd.Flags().Int("epochs", 100, "Number of training epochs")
+	aiTrainCmd.Flags().String("export-job", "", "Export a portable training job (JSON + tar.gz) to this directory instead of training locally")
+	aiTrainCmd.Flags().Float64("learning-rate", 0.001, "Learning rate (exported jobs)")
+	aiTrainCmd.Flags().String("optimizer", "adam", "Optimizer (exported jobs)")
+	aiTrainCmd.Flags().Int("input-size", 128, "Model input size in pixels (exported jobs)")
+	aiTrainCmd.Flags().Int("channels", 1, "Model input channels (exported jobs)")
+	aiTrainCmd.Flags().Int("num-classes", 1, "Number of object classes (exported jobs)")
 	
 	// AI detect flags
-	aiDetectCmd.Flags().Bool("gpu", true, "Use GPU acceleration")
+	aiDetectCmd.Flags().Bool("gpu", true, "Use GPU acceleration (CUDA execution provider)")
+	aiDetectCmd.Flags().Int("device", -1, "CUDA device ID for inference (default: least loaded device)")
+	aiDetectCmd.Flags().Int("gpu-memory", 0, "GPU memory in MB to reserve (default 2048)")
+	aiDetectCmd.Flags().Int("batch-size", 8, "Images per inference batch")
+	aiDetectCmd.Flags().Float32("threshold", 0.5, "Minimum detection score")
+	aiDetectCmd.Flags().String("ort-lib", "", "Path to the ONNX Runtime shared library (default: $ONNXRUNTIME_LIB)")
+	aiDetectCmd.Flags().String("output", "", "Output file for detections (JSON)")
 	
 	// Results flags
-	resultsCmd.Flags().Int("limit", 10, "Maximum number of results to retrieve")
+	resultsCmd.Flags().Int("limit", 10, "Maximum number of results per page")
+	resultsCmd.Flags().String("cursor", "", "Continue after the previous page")
+	resultsCmd.Flags().String("type", "", "Only this analysis type (e.g. planet9_search, orbital_dynamics)")
+	resultsCmd.Flags().String("client-id", "", "Only results of this client (default: your client)")
+	resultsCmd.Flags().String("from", "", "Created on or after this day, YYYY-MM-DD")
+	resultsCmd.Flags().String("to", "", "Created before this day, YYYY-MM-DD")
+	resultsCmd.Flags().String("order", blockchain.OrderByCreated, "Order by created or height")
+	resultsCmd.Flags().Bool("asc", false, "Oldest first")
+	resultsCmd.Flags().Bool("json", false, "Print the page as JSON")
 	
 
 }
@@ -969,8 +1241,13 @@ var serveCmd = &cobra.Command{
 - Maximum 10 calculations per IP per hour
 - 5 minute timeout per job
 
+Open deployments can require a proof of work (--abuse-protection pow) or a
+small deposit to --deposit-address (--abuse-protection deposit) per
+calculation, so clients rotating IPs cannot exhaust the service.
+
 Example:
-  medasdigital-client serve --port 8080 --max-jobs 2`,
+  medasdigital-client serve --port 8080 --max-jobs 2
+  medasdigital-client serve --abuse-protection pow --pow-difficulty 22`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		port, _ := cmd.Flags().GetInt("port")
 		maxJobs, _ := cmd.Flags().GetInt("max-jobs")
@@ -1000,6 +1277,43 @@ Example:
 		fmt.Println("💡 For unlimited calculations, use: payment-service")
 		
 		service := NewSecureFreeTestService(maxJobs, maxRuntime, testMode)
+		service.httpConfig = loadConfig().HTTP
+		if err := service.httpConfig.Validate(); err != nil {
+			return err
+		}
+		
+		storeKind, _ := cmd.Flags().GetString("rate-limit-store")
+		redisURL, _ := cmd.Flags().GetString("redis-url")
+		proxies, _ := cmd.Flags().GetStringSlice("trusted-proxies")
+		trusted, err := ratelimit.ParseTrustedProxies(proxies)
+		if err != nil {
+			return err
+		}
+		store, err := ratelimit.Open(storeKind, filepath.Join(homeDir, "ratelimit", "free-service.json"), redisURL)
+		if err != nil {
+			return fmt.Errorf("failed to open rate limit store: %w", err)
+		}
+		defer store.Close()
+		service.SetRateLimiting(storeKind, store, trusted)
+		if err := configureAbuseProtection(cmd, service, store); err != nil {
+			return err
+		}
+		
+		// Save the counters when the service is stopped
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-stop
+			if err := store.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Could not save rate limits: %v\n", err)
+			}
+			os.Exit(0)
+		}()
+		fmt.Printf("🚦 Rate limit store: %s\n", storeKind)
+		if len(trusted) > 0 {
+			fmt.Printf("🔁 Trusting X-Forwarded-For from: %s\n", strings.Join(proxies, ", "))
+		}
+		
 		return service.Start(port)
 	},
 }
@@ -1021,7 +1335,10 @@ For higher precision, use payment-service.
 
 Examples:
   medasdigital-client pi calculate 100
-  medasdigital-client pi calculate 1000 --method chudnovsky`,
+  medasdigital-client pi calculate 1000 --method chudnovsky
+  medasdigital-client pi calculate 1000 --method auto
+
+See 'pi methods' for the methods and the precisions they suit.`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		digits, err := strconv.Atoi(args[0])
@@ -1038,10 +1355,15 @@ Examples:
 		output, _ := cmd.Flags().GetString("output")
 		verbose, _ := cmd.Flags().GetBool("verbose")
 		
+		method, err = resolvePIMethod(method, digits)
+		if err != nil {
+			return err
+		}
+		
 		fmt.Printf("🧮 Calculating PI to %d decimal places (CLI mode)\n", digits)
 		fmt.Printf("📊 Method: %s\n", method)
 		
-		result, err := calculatePIDirectly(digits, method, verbose)
+		result, err := calculatePIDirectly(cmd.Context(), digits, method, verbose)
 		if err != nil {
 			return fmt.Errorf("PI calculation failed: %w", err)
 		}
@@ -1080,7 +1402,7 @@ This tests different algorithms and digit counts to measure system performance.`
 		fmt.Println("🏁 Starting PI Calculation Benchmark")
 		fmt.Println("====================================")
 		
-		results := runPIBenchmark()
+		results := runPIBenchmark(cmd.Context())
 		displayBenchmarkResults(results)
 		
 		return nil
@@ -1264,15 +1586,19 @@ func addKeysCommands() {
 
 func initViper() {
 	if homeDir == "" {
-		homeDir = filepath.Join(os.Getenv("HOME"), ".medasdigital-client")
+		homeDir = state.DefaultRoot()
 	}
+	state.SetCurrent(homeDir)
 	
 	if cfgFile == "" {
-		cfgFile = filepath.Join(homeDir, "config.yaml")
+		cfgFile = filepath.Join(homeDir, state.ConfigFile)
 	}
 	
 	viper.SetConfigFile(cfgFile)
 	viper.AutomaticEnv()
+	for key, env := range envOverrides {
+		viper.BindEnv(key, env)
+	}
 	
 	if err := viper.ReadInConfig(); err == nil {
 		fmt.Fprintf(os.Stderr, "Using config file: %s\n", viper.ConfigFileUsed())
@@ -1289,56 +1615,68 @@ func initConfig() error {
 }
 
 func initializeClient() error {
-	// Initialize SDK config with default values
-	sdkConfig := sdk.GetConfig()
-	
-	// Get bech32 prefix from config or use default
-	bech32Prefix := viper.GetString("chain.bech32_prefix")
-	if bech32Prefix == "" {
-		bech32Prefix = defaultBech32Prefix
+	if err := setupApp(); err != nil {
+		return err
 	}
-	
-	sdkConfig.SetBech32PrefixForAccount(bech32Prefix, bech32Prefix+"pub")
-	sdkConfig.SetBech32PrefixForValidator(bech32Prefix+"valoper", bech32Prefix+"valoperpub")
-	sdkConfig.SetBech32PrefixForConsensusNode(bech32Prefix+"valcons", bech32Prefix+"valconspub")
-	sdkConfig.Seal()
-	
-	// Initialize global registry and codec ONCE
-	if globalInterfaceRegistry == nil {
-		globalInterfaceRegistry = getInterfaceRegistry()
+	if _, err := cliApp.Client(); err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	// The scheduler keeps jobs within memory_limit
+	if err := cliApp.GPUError(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  GPU disabled: %v\n", err)
 	}
-	if globalCodec == nil {
-		globalCodec = codec.NewProtoCodec(globalInterfaceRegistry)
+	return nil
+}
+
+// setupApp creates the app for the configured network without connecting,
+// enough for commands served by the daemon
+func setupApp() error {
+	// Resolve the network so packages without config access use the same chain settings
+	net, err := resolveNetwork()
+	if err != nil {
+		return err
 	}
+	network.SetCurrent(net)
 	
-	// Initialize global client
-	var err error
-	globalClient, err = medasClient.NewMedasDigitalClient()
+	cfg := loadConfig()
+	app, err := medasClient.NewApp(
+		medasClient.WithNetwork(net),
+		medasClient.WithConfig(cfg.clientConfig()),
+		medasClient.WithGPU(cfg.gpuConfig(), cfg.GPU.MemoryLimit),
+	)
 	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
+		return err
 	}
-
-	globalClientCtx = globalClient 
+	cliApp = app
 	return nil
 }
 
+// currentApp returns the app of this invocation. Commands that skip the
+// client setup, e.g. keys, get one for the configured network on first use.
+func currentApp() *medasClient.App {
+	if cliApp == nil {
+		if err := setupApp(); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %v, using %s defaults\n", err, network.Current().Name)
+			cliApp, _ = medasClient.NewApp()
+		}
+	}
+	return cliApp
+}
+
+// analysisClient is the connected client of this invocation
+func analysisClient() (*medasClient.MedasDigitalClient, error) {
+	return currentApp().Client()
+}
+
 // Helper function to initialize client context for keys operations
 func initKeysClientContext() (client.Context, error) {
 	// Load config first
 	cfg := loadConfig()
 	
-	// Use global codec instances to avoid conflicts
-	if globalInterfaceRegistry == nil {
-		globalInterfaceRegistry = getInterfaceRegistry()
-	}
-	if globalCodec == nil {
-		globalCodec = codec.NewProtoCodec(globalInterfaceRegistry)
-	}
-	
 	clientCtx := client.Context{}.
 		WithKeyringDir(cfg.Client.KeyringDir).
-		WithCodec(globalCodec).
-		WithInterfaceRegistry(globalInterfaceRegistry)
+		WithCodec(currentApp().Codec()).
+		WithInterfaceRegistry(currentApp().InterfaceRegistry())
 	
 	// Initialize keyring with proper backend
 	keyringBackend := keyring.BackendTest // Use test backend as default
@@ -1351,7 +1689,7 @@ func initKeysClientContext() (client.Context, error) {
 		keyringBackend,
 		cfg.Client.KeyringDir,
 		nil, // no input
-		globalCodec,
+		currentApp().Codec(),
 	)
 	if err != nil {
 		return client.Context{}, fmt.Errorf("failed to create keyring: %w", err)
@@ -1364,38 +1702,42 @@ func initKeysClientContext() (client.Context, error) {
 
 // Helper function to load configuration
 func loadConfig() *Config {
-	config := &Config{}
-	
-	// Set defaults if not in config
-	config.Chain.ID = viper.GetString("chain.id")
-	if config.Chain.ID == "" {
-		config.Chain.ID = defaultChainID
-	}
-	
-	config.Chain.RPCEndpoint = viper.GetString("chain.rpc_endpoint")
-	if config.Chain.RPCEndpoint == "" {
-		config.Chain.RPCEndpoint = defaultRPCEndpoint
-	}
+	config := &Config{Version: viper.GetInt("version")}
 	
-	config.Chain.Bech32Prefix = viper.GetString("chain.bech32_prefix")
-	if config.Chain.Bech32Prefix == "" {
-		config.Chain.Bech32Prefix = defaultBech32Prefix
-	}
-	
-	config.Chain.BaseDenom = viper.GetString("chain.base_denom")
-	if config.Chain.BaseDenom == "" {
-		config.Chain.BaseDenom = defaultBaseDenom
-	}
+	// Chain settings fall back to the selected network's bundled defaults
+	net, err := resolveNetwork()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v, using %s defaults\n", err, defaultNetwork)
+		net = network.Current()
+	}
+	config.Chain = ChainConfig{
+		Network:      net.Name,
+		ID:           net.ChainID,
+		RPCEndpoint:  net.RPCEndpoint,
+		WSEndpoint:   net.WSEndpoint,
+		RESTEndpoint: net.RESTEndpoint,
+		GRPCEndpoint: net.GRPCEndpoint,
+		Bech32Prefix: net.Bech32Prefix,
+		BaseDenom:    net.BaseDenom,
+		DisplayDenom: net.DisplayDenom,
+		Decimals:     net.Decimals,
+		GasPrice:     net.GasPrice,
+	}
+	config.Contract.Address = net.ContractAddress
+	config.Contract.CommunityAddress = net.CommunityAddress
+	config.Contract.Successor = viper.GetString("contract.successor")
 	
 	config.Client.KeyringDir = viper.GetString("client.keyring_dir")
 	if config.Client.KeyringDir == "" {
-		config.Client.KeyringDir = filepath.Join(homeDir, "keyring")
+		config.Client.KeyringDir = filepath.Join(homeDir, state.KeyringDir)
 	}
 	
 	config.Client.KeyringBackend = viper.GetString("client.keyring_backend")
 	if config.Client.KeyringBackend == "" {
 		config.Client.KeyringBackend = "test" // Safe default
 	}
+	config.Client.Capabilities = viper.GetStringSlice("client.capabilities")
+	config.Client.FeeGranter = viper.GetString("client.fee_granter")
 
 	config.Provider.Enabled = viper.GetBool("provider.enabled")
     config.Provider.KeyName = viper.GetString("provider.key_name")
@@ -1408,39 +1750,159 @@ func loadConfig() *Config {
     config.Provider.Workers = viper.GetInt("provider.workers")
     config.Provider.HarvestIntervalHours = viper.GetInt("provider.harvest_interval_hours")
 	config.Provider.HeartbeatIntervalMinutes = viper.GetInt("provider.heartbeat_interval_minutes")
+	config.Provider.Sandbox = compute.SandboxConfig{
+		Runtime:   viper.GetString("provider.sandbox.runtime"),
+		Image:     viper.GetString("provider.sandbox.image"),
+		CPUs:      viper.GetFloat64("provider.sandbox.cpus"),
+		MemoryMB:  viper.GetInt("provider.sandbox.memory_mb"),
+		PidsLimit: viper.GetInt("provider.sandbox.pids_limit"),
+		Timeout:   viper.GetDuration("provider.sandbox.timeout"),
+		Binary:    viper.GetString("provider.sandbox.binary"),
+		WorkDir:   viper.GetString("provider.sandbox.work_dir"),
+	}
+	config.Provider.Bidding = contract.BiddingConfig{
+		Enabled:       viper.GetBool("provider.bidding.enabled"),
+		DryRun:        viper.GetBool("provider.bidding.dry_run"),
+		Strategy:      viper.GetString("provider.bidding.strategy"),
+		PriceFloor:    viper.GetUint64("provider.bidding.price_floor"),
+		Markup:        viper.GetFloat64("provider.bidding.markup"),
+		ServiceTypes:  viper.GetStringSlice("provider.bidding.service_types"),
+		MaxDigits:     viper.GetInt("provider.bidding.max_digits"),
+		MaxConcurrent: viper.GetInt("provider.bidding.max_concurrent"),
+		PollInterval:  viper.GetDuration("provider.bidding.poll_interval"),
+	}
+	config.Provider.Contracts = viper.GetStringSlice("provider.contracts")
+	config.Provider.JobStore = viper.GetString("provider.job_store")
+	config.Provider.AdminToken = viper.GetString("provider.admin_token")
+	if config.Provider.JobStore == "" {
+		config.Provider.JobStore = filepath.Join(homeDir, state.ProviderJobsDir)
+	}
+	if err := viper.UnmarshalKey("provider.harvest", &config.Provider.Harvest); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not read provider.harvest: %v\n", err)
+	}
+	
+	if err := viper.UnmarshalKey("payment.ibc_denoms", &config.Payment.IBCDenoms); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not read payment.ibc_denoms: %v\n", err)
+	}
+	config.Payment.TolerancePercent = blockchain.DefaultPaymentTolerance * 100
+	if viper.IsSet("payment.tolerance_percent") {
+		config.Payment.TolerancePercent = viper.GetFloat64("payment.tolerance_percent")
+	}
+	config.Payment.Strict = viper.GetBool("payment.strict")
+	config.Payment.MemoryBudgetMB = viper.GetInt("payment.memory_budget_mb")
+	config.Payment.SpillDir = viper.GetString("payment.spill_dir")
+	config.Payment.ArtifactRetention = compute.DefaultArtifactRetention
+	if d := viper.GetDuration("payment.artifact_retention"); d > 0 {
+		config.Payment.ArtifactRetention = d
+	}
+	config.Payment.PricingFile = viper.GetString("payment.pricing_file")
+	if err := viper.UnmarshalKey("payment.limits", &config.Payment.Limits); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not read payment.limits: %v\n", err)
+	}
+	if err := viper.UnmarshalKey("payment.result_cache", &config.Payment.ResultCache); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not read payment.result_cache: %v\n", err)
+	}
+	config.Payment.ResultCache = config.Payment.ResultCache.withDefaults()
+	if err := viper.UnmarshalKey("http", &config.HTTP); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not read http: %v\n", err)
+	}
+	config.HTTP = config.HTTP.withDefaults()
+	if err := viper.UnmarshalKey("networks", &config.Networks); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not read networks: %v\n", err)
+	}
+	if err := viper.UnmarshalKey("notifications", &config.Notifications); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not read notifications: %v\n", err)
+	}
+	if err := viper.UnmarshalKey("crossmatch", &config.CrossMatch); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not read crossmatch: %v\n", err)
+	}
+	config.Indexer.Addresses = viper.GetStringSlice("indexer.addresses")
+	config.Indexer.Path = viper.GetString("indexer.path")
+	if config.Indexer.Path == "" {
+		config.Indexer.Path = indexer.Path(homeDir, config.Chain.ID)
+	}
+	
+	config.GPU.Enabled = viper.GetBool("gpu.enabled")
+	config.GPU.DeviceID = viper.GetInt("gpu.device_id")
+	config.GPU.Devices = viper.GetIntSlice("gpu.devices")
+	config.GPU.MemoryLimit = viper.GetInt("gpu.memory_limit")
 	
 	return config
 }
 
-// Helper functions for codec
-func getInterfaceRegistry() types.InterfaceRegistry {
-	// Only create once to avoid conflicts
-	if globalInterfaceRegistry != nil {
-		return globalInterfaceRegistry
+// resolveNetwork merges the chain/contract config over the bundled network defaults
+func resolveNetwork() (network.Network, error) {
+	if err := registerNetworkProfiles(); err != nil {
+		return network.Network{}, err
 	}
-	
-	interfaceRegistry := types.NewInterfaceRegistry()
-	
-	// Register standard Cosmos SDK interfaces
-	std.RegisterInterfaces(interfaceRegistry)
-	
-	// ✅ WICHTIG: Register Account interfaces für v0.50.10
-	authtypes.RegisterInterfaces(interfaceRegistry)
-	
-	// ✅ NEU: Register bank interfaces
-	banktypes.RegisterInterfaces(interfaceRegistry)
-	
-	// ✅ NEU: Register base account implementations
-	interfaceRegistry.RegisterImplementations(
-		(*authtypes.AccountI)(nil),
-		&authtypes.BaseAccount{},
-		&authtypes.ModuleAccount{},
-	)
-	
-	// ✅ Register our blockchain messages
-	blockchain.RegisterInterfaces(interfaceRegistry)
-	
-	return interfaceRegistry
+	net, err := network.Get(viper.GetString("chain.network"))
+	if err != nil {
+		return network.Network{}, err
+	}
+
+	// A profile selected with --network or $MEDAS_NETWORK is used as is, the
+	// chain settings in the config belong to the configured network
+	if networkFlag != "" || os.Getenv(envOverrides["chain.network"]) != "" {
+		override := network.Network{ChainID: chainIDFlag, RPCEndpoint: nodeFlag}
+		if override.ChainID == "" {
+			override.ChainID = os.Getenv(envOverrides["chain.chain_id"])
+		}
+		if override.RPCEndpoint == "" {
+			override.RPCEndpoint = os.Getenv(envOverrides["chain.rpc_endpoint"])
+		}
+		return net.Merge(override), nil
+	}
+
+	// Older configs were read with chain.id, init writes chain.chain_id
+	chainID := viper.GetString("chain.chain_id")
+	if chainID == "" {
+		chainID = viper.GetString("chain.id")
+	}
+
+	// The configured websocket belongs to the configured node, not to --node or $MEDAS_RPC
+	wsEndpoint := viper.GetString("chain.ws_endpoint")
+	if nodeFlag != "" || os.Getenv(envOverrides["chain.rpc_endpoint"]) != "" {
+		wsEndpoint = ""
+	}
+
+	return net.Merge(network.Network{
+		ChainID:          chainID,
+		RPCEndpoint:      viper.GetString("chain.rpc_endpoint"),
+		WSEndpoint:       wsEndpoint,
+		RESTEndpoint:     viper.GetString("chain.rest_endpoint"),
+		GRPCEndpoint:     viper.GetString("chain.grpc_endpoint"),
+		Bech32Prefix:     viper.GetString("chain.bech32_prefix"),
+		BaseDenom:        viper.GetString("chain.base_denom"),
+		DisplayDenom:     viper.GetString("chain.display_denom"),
+		Decimals:         viper.GetInt("chain.decimals"),
+		GasPrice:         viper.GetString("chain.gas_price"),
+		ContractAddress:  viper.GetString("contract.address"),
+		CommunityAddress: viper.GetString("contract.community_address"),
+	}), nil
+}
+
+// registerNetworkProfiles makes the profiles under networks: in the config selectable
+func registerNetworkProfiles() error {
+	var profiles map[string]network.Network
+	if err := viper.UnmarshalKey("networks", &profiles); err != nil {
+		return fmt.Errorf("invalid networks config: %w", err)
+	}
+	for name, n := range profiles {
+		n.Name = name
+		if err := network.Register(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keyringBackendFlag returns --keyring-backend if given, otherwise
+// client.keyring_backend from $MEDAS_KEYRING_BACKEND or the config
+func keyringBackendFlag(cmd *cobra.Command) string {
+	if backend, _ := cmd.Flags().GetString("keyring-backend"); cmd.Flags().Changed("keyring-backend") {
+		return backend
+	}
+	return loadConfig().Client.KeyringBackend
 }
 
 func initKeysClientContextWithBackend(keyringBackend string) (client.Context, error) {
@@ -1455,25 +1917,17 @@ func initKeysClientContextWithBackend(keyringBackend string) (client.Context, er
 		keyringBackend = "test" // Safe default
 	}
 	
-	// Use global codec instances to avoid conflicts
-	if globalInterfaceRegistry == nil {
-		globalInterfaceRegistry = getInterfaceRegistry()
-	}
-	if globalCodec == nil {
-		globalCodec = codec.NewProtoCodec(globalInterfaceRegistry)
-	}
-	
 	clientCtx := client.Context{}.
 		WithKeyringDir(cfg.Client.KeyringDir).
-		WithCodec(globalCodec).
-		WithInterfaceRegistry(globalInterfaceRegistry)
+		WithCodec(currentApp().Codec()).
+		WithInterfaceRegistry(currentApp().InterfaceRegistry())
 	
 	kr, err := keyring.New(
 		sdk.KeyringServiceName(),
 		keyringBackend,
 		cfg.Client.KeyringDir,
 		nil, // no input
-		globalCodec,
+		currentApp().Codec(),
 	)
 	if err != nil {
 		return client.Context{}, fmt.Errorf("failed to create keyring with backend '%s': %w", keyringBackend, err)
@@ -1495,26 +1949,23 @@ func createFullBlockchainClient(clientCtx client.Context, cfg *Config) (*blockch
 		return nil, fmt.Errorf("failed to create RPC client: %w", err)
 	}
 	
-	// Use the global codec instances that we already created
-	if globalInterfaceRegistry == nil {
-		globalInterfaceRegistry = getInterfaceRegistry()
-	}
-	if globalCodec == nil {
-		globalCodec = codec.NewProtoCodec(globalInterfaceRegistry)
-	}
-	
 	// Create TxConfig using v0.50 API
-	txConfig := authtx.NewTxConfig(globalCodec, authtx.DefaultSignModes)
+	txConfig := currentApp().TxConfig()
 	
 	// Create AccountRetriever
 	accountRetriever := authtypes.AccountRetriever{}
 	
+	granter, err := feeGranter()
+	if err != nil {
+		return nil, err
+	}
+	
 	// ✅ WICHTIG: Verwenden Sie das GLEICHE Keyring wie bei check-account!
 	fullClientCtx := clientCtx.
 		WithClient(rpcClient).
 		WithChainID(cfg.Chain.ID).
-		WithCodec(globalCodec).
-		WithInterfaceRegistry(globalInterfaceRegistry).
+		WithCodec(currentApp().Codec()).
+		WithInterfaceRegistry(currentApp().InterfaceRegistry()).
 		WithTxConfig(txConfig).
 		WithAccountRetriever(accountRetriever).
 		WithNodeURI(cfg.Chain.RPCEndpoint).
@@ -1523,8 +1974,9 @@ func createFullBlockchainClient(clientCtx client.Context, cfg *Config) (*blockch
 		WithFromAddress(clientCtx.GetFromAddress()). // ✅ FROM ADDRESS
 		WithOffline(false).
 		WithGenerateOnly(false).
-		WithSimulation(false).
+		WithSimulation(dryRun).
 		WithUseLedger(false).
+		WithFeeGranterAddress(granter).
 		WithBroadcastMode(flags.BroadcastSync)
 	
 	// Create blockchain client
@@ -1534,7 +1986,7 @@ func createFullBlockchainClient(clientCtx client.Context, cfg *Config) (*blockch
 }
 
 // Neue sichere Connection-Test Funktion:
-func testBlockchainConnection(rpcEndpoint string) error {
+func testBlockchainConnection(ctx context.Context, rpcEndpoint string) error {
 	// Einfacher Connection-Test ohne vollständigen Client Context
 	rpcClient, err := client.NewClientFromNode(rpcEndpoint)
 	if err != nil {
@@ -1542,7 +1994,8 @@ func testBlockchainConnection(rpcEndpoint string) error {
 	}
 	
 	// Test simple status call
-	_, err = rpcClient.Status(context.Background())
+	defer telemetry.Track(telemetry.CategoryRPC, "status")()
+	_, err = rpcClient.Status(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get status: %w", err)
 	}
@@ -1571,13 +2024,15 @@ func simulateRegistration(keyName, address string, capabilities []string, metada
 
 
 // Get detailed chain status
-func getDetailedChainStatus(rpcEndpoint string) (*ChainStatus, error) {
+func getDetailedChainStatus(ctx context.Context, rpcEndpoint string) (*ChainStatus, error) {
 	rpcClient, err := client.NewClientFromNode(rpcEndpoint)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create RPC client: %w", err)
 	}
 	
-	status, err := rpcClient.Status(context.Background())
+	stopTimer := telemetry.Track(telemetry.CategoryRPC, "status")
+	status, err := rpcClient.Status(ctx)
+	stopTimer()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get status: %w", err)
 	}
@@ -1595,6 +2050,9 @@ func getDetailedChainStatus(rpcEndpoint string) (*ChainStatus, error) {
 var listRegistrationsCmd = &cobra.Command{
 	Use:   "list-registrations",
 	Short: "List all registrations with blockchain verification",
+	Long: `List the local registrations with their on-chain data. Registrations found in
+the local transaction index (see index sync) are shown from there without an
+RPC call; --offline only uses the index.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Get local hashes
 		hashes, err := blockchain.GetLocalRegistrationHashes()
@@ -1605,15 +2063,43 @@ var listRegistrationsCmd = &cobra.Command{
 		}
 		
 		cfg := loadConfig()
+		offline, _ := cmd.Flags().GetBool("offline")
+		ix, err := openIndex(cfg)
+		if err != nil {
+			if offline {
+				return err
+			}
+			fmt.Printf("⚠️  Local index unavailable: %v\n", err)
+		} else {
+			defer ix.Close()
+		}
 		fmt.Printf("📋 Found %d local registration hash(es), fetching from blockchain...\n", len(hashes))
 		fmt.Println("=" + strings.Repeat("=", 80))
 		
 		var validRegistrations []*blockchain.BlockchainRegistrationData
+		indexed := 0
 		
 		for i, hash := range hashes {
 			fmt.Printf("\n%d. 📊 Transaction Hash: %s\n", i+1, hash)
 			
-			regData, err := blockchain.FetchRegistrationFromBlockchain(hash, cfg.Chain.RPCEndpoint, cfg.Chain.ID, globalCodec)
+			if ix != nil {
+				if reg, err := ix.Registration(hash); err == nil && reg != nil {
+					indexed++
+					fmt.Printf("   🆔 Client ID: %s\n", blockchain.GenerateClientIDFromHash(hash))
+					fmt.Printf("   📍 Address: %s\n", reg.Address)
+					fmt.Printf("   🔧 Capabilities: %v\n", reg.Memo.Capabilities)
+					fmt.Printf("   🏔️  Block: %d\n", reg.Height)
+					fmt.Printf("   🕒 Time: %s\n", reg.Time.Format("2006-01-02 15:04:05"))
+					fmt.Printf("   🗂️  Source: local index (%s registration)\n", reg.Memo.Type)
+					continue
+				}
+			}
+			if offline {
+				fmt.Println("   ⏭️  Not in the local index, run index sync for its address")
+				continue
+			}
+			
+			regData, err := blockchain.FetchRegistrationFromBlockchain(cmd.Context(), hash, cfg.Chain.RPCEndpoint, cfg.Chain.ID, currentApp().Codec())
 			if err != nil {
 				fmt.Printf("   ❌ Failed to fetch from blockchain: %v\n", err)
 				continue
@@ -1634,7 +2120,10 @@ var listRegistrationsCmd = &cobra.Command{
 		
 		fmt.Println("\n=" + strings.Repeat("=", 80))
 		fmt.Printf("✅ Successfully verified %d/%d registrations from blockchain\n", 
-			len(validRegistrations), len(hashes))
+			len(validRegistrations), len(hashes)-indexed)
+		if indexed > 0 {
+			fmt.Printf("🗂️  %d registration(s) read from the local index\n", indexed)
+		}
 		
 		return nil
 	},
@@ -1657,7 +2146,7 @@ var whoamiCmd = &cobra.Command{
 		
 		// Find most recent valid registration from blockchain
 		for _, hash := range hashes {
-			if regData, err := blockchain.FetchRegistrationFromBlockchain(hash, cfg.Chain.RPCEndpoint, cfg.Chain.ID, globalCodec); err == nil {
+			if regData, err := blockchain.FetchRegistrationFromBlockchain(cmd.Context(), hash, cfg.Chain.RPCEndpoint, cfg.Chain.ID, currentApp().Codec()); err == nil {
 				if latest == nil || regData.BlockTime.After(latest.BlockTime) {
 					latest = regData
 				}
@@ -1688,37 +2177,30 @@ var whoamiCmd = &cobra.Command{
 }
 
 
-// Test GPU availability
+// Test GPU availability (NVIDIA, AMD ROCm or Apple Metal)
 func testGPUAvailability() (bool, string) {
-	cmd := exec.Command("nvidia-smi", "--query-gpu=name,memory.total", "--format=csv,noheader,nounits")
-	output, err := cmd.Output()
-	if err != nil {
-		return false, "nvidia-smi not available"
+	devices := detectGPUs()
+	if len(devices) == 0 {
+		return false, "No supported GPU detected (nvidia-smi, rocm-smi, amdgpu sysfs, Metal)"
 	}
 	
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	if len(lines) > 0 && lines[0] != "" {
-		parts := strings.Split(lines[0], ",")
-		if len(parts) >= 2 {
-			name := strings.TrimSpace(parts[0])
-			memory := strings.TrimSpace(parts[1])
-			return true, fmt.Sprintf("%s (%s MB)", name, memory)
-		}
+	summaries := make([]string, 0, len(devices))
+	for _, d := range devices {
+		summaries = append(summaries, d.Summary())
 	}
-	
-	return false, "No NVIDIA GPUs detected"
+	return true, strings.Join(summaries, ", ")
 }
 
 
 // Method 1: Direct Tendermint RPC Query
-func queryBalanceViaTendermint(address string, cfg *Config) ([]sdk.Coin, error) {
+func queryBalanceViaTendermint(ctx context.Context, address string, cfg *Config) ([]sdk.Coin, error) {
 	rpcClient, err := client.NewClientFromNode(cfg.Chain.RPCEndpoint)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create RPC client: %w", err)
 	}
 	
 	// Query using ABCI query directly
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 	
 	// Try different query paths
@@ -1729,7 +2211,9 @@ func queryBalanceViaTendermint(address string, cfg *Config) ([]sdk.Coin, error)
 	}
 	
 	for _, path := range queryPaths {
+		stopTimer := telemetry.Track(telemetry.CategoryRPC, "abci_query")
 		result, err := rpcClient.ABCIQuery(ctx, path, nil)
+		stopTimer()
 		if err == nil && result.Response.Code == 0 && len(result.Response.Value) > 0 {
 			// Try to decode the response
 			fmt.Printf("   Found data via path: %s\n", path)
@@ -1746,65 +2230,14 @@ func queryBalanceViaTendermint(address string, cfg *Config) ([]sdk.Coin, error)
 	return nil, fmt.Errorf("no balance data found via Tendermint RPC")
 }
 
-// Method 2: Alternative REST Query
-func queryBalanceViaREST(address string, cfg *Config) (map[string]string, error) {
-	// Parse the RPC endpoint to get the base URL
-	rpcURL := cfg.Chain.RPCEndpoint
-	// Convert RPC URL to REST URL (common pattern)
-	restURL := strings.Replace(rpcURL, ":26657", ":1317", 1)
-	restURL = strings.Replace(restURL, "rpc.", "api.", 1)
-	
-	// Try different REST endpoints
-	endpoints := []string{
-		fmt.Sprintf("%s/cosmos/bank/v1beta1/balances/%s", restURL, address),
-		fmt.Sprintf("%s/bank/balances/%s", restURL, address),
-		fmt.Sprintf("%s/cosmos/bank/v1beta1/all_balances/%s", restURL, address),
-	}
-	
-	for _, endpoint := range endpoints {
-		fmt.Printf("   Trying: %s\n", endpoint)
-		
-		resp, err := http.Get(endpoint)
-		if err != nil {
-			fmt.Printf("   HTTP Error: %v\n", err)
-			continue
-		}
-		defer resp.Body.Close()
-		
-		if resp.StatusCode == 200 {
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				continue
-			}
-			
-			fmt.Printf("   Response: %s\n", string(body))
-			
-			// Try to parse the JSON response
-			var result map[string]interface{}
-			if err := json.Unmarshal(body, &result); err == nil {
-				balances := make(map[string]string)
-				
-				// Different possible JSON structures
-				if balancesArray, ok := result["balances"].([]interface{}); ok {
-					for _, bal := range balancesArray {
-						if balMap, ok := bal.(map[string]interface{}); ok {
-							if denom, ok := balMap["denom"].(string); ok {
-								if amount, ok := balMap["amount"].(string); ok {
-									balances[denom] = amount
-								}
-							}
-						}
-					}
-				}
-				
-				if len(balances) > 0 {
-					return balances, nil
-				}
-			}
-		}
+// Method 2: REST Query against chain.rest_endpoint
+func queryBalanceViaREST(ctx context.Context, address string, cfg *Config) (sdk.Coins, error) {
+	rest, err := blockchain.NewREST(cfg.Chain.RESTEndpoint, cfg.Chain.ID)
+	if err != nil {
+		return nil, err
 	}
-	
-	return nil, fmt.Errorf("no REST endpoint returned valid balance data")
+	fmt.Printf("   Endpoint: %s\n", rest.Endpoint())
+	return rest.Balances(ctx, address)
 }
 
 // 4. FIX für queryBalanceViaBankModule Funktion - KOMPLETT ERSETZEN:
@@ -1815,18 +2248,11 @@ func queryBalanceViaBankModule(address string, cfg *Config) ([]sdk.Coin, error)
 		return nil, fmt.Errorf("failed to create RPC client: %w", err)
 	}
 	
-	if globalInterfaceRegistry == nil {
-		globalInterfaceRegistry = getInterfaceRegistry()
-	}
-	if globalCodec == nil {
-		globalCodec = codec.NewProtoCodec(globalInterfaceRegistry)
-	}
-	
 	queryCtx := client.Context{}.
 		WithClient(rpcClient).
 		WithChainID(cfg.Chain.ID).
-		WithCodec(globalCodec).
-		WithInterfaceRegistry(globalInterfaceRegistry)
+		WithCodec(currentApp().Codec()).
+		WithInterfaceRegistry(currentApp().InterfaceRegistry())
 	
 	// Try to use bank query client directly
 	_, err = sdk.AccAddressFromBech32(address)
@@ -1835,7 +2261,7 @@ func queryBalanceViaBankModule(address string, cfg *Config) ([]sdk.Coin, error)
 	}
 	
 	// Try different bank query approaches
-	denoms := []string{"umedas", "medas", "stake", "token"}
+	denoms := []string{network.Current().BaseDenom, "stake", "token"}
 	
 	var totalBalance []sdk.Coin
 	for _, denom := range denoms {
@@ -1850,7 +2276,9 @@ func queryBalanceViaBankModule(address string, cfg *Config) ([]sdk.Coin, error)
 			continue
 		}
 		
+		stopTimer := telemetry.Track(telemetry.CategoryRPC, "bank_balance")
 		res, _, err := queryCtx.QueryWithData("/cosmos.bank.v1beta1.Query/Balance", reqBytes)
+		stopTimer()
 		if err != nil {
 			fmt.Printf("   Error querying %s: %v\n", denom, err)
 			continue
@@ -1870,20 +2298,22 @@ func queryBalanceViaBankModule(address string, cfg *Config) ([]sdk.Coin, error)
 }
 
 // Method 4: Analyze Transaction History for Balance
-func analyzeTransactionHistory(address string, cfg *Config) error {
+func analyzeTransactionHistory(ctx context.Context, address string, cfg *Config) error {
 	rpcClient, err := client.NewClientFromNode(cfg.Chain.RPCEndpoint)
 	if err != nil {
 		return fmt.Errorf("failed to create RPC client: %w", err)
 	}
 	
 	// Get recent transactions
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 	
 	// Search for transactions involving this address
 	query := fmt.Sprintf("transfer.recipient='%s' OR transfer.sender='%s'", address, address)
 	
+	stopTimer := telemetry.Track(telemetry.CategoryRPC, "tx_search")
 	result, err := rpcClient.TxSearch(ctx, query, false, nil, nil, "desc")
+	stopTimer()
 	if err != nil {
 		return fmt.Errorf("failed to search transactions: %w", err)
 	}
@@ -1928,7 +2358,7 @@ func min(a, b int) int {
 // ========================================
 
 // calculatePIDirectly berechnet PI direkt ohne Service
-func calculatePIDirectly(digits int, method string, verbose bool) (*compute.PIResult, error) {
+func calculatePIDirectly(ctx context.Context, digits int, method string, verbose bool) (*compute.PIResult, error) {
 	if verbose {
 		fmt.Printf("🚀 Starting PI calculation: %d digits using %s\n", digits, method)
 	}
@@ -1937,7 +2367,9 @@ func calculatePIDirectly(digits int, method string, verbose bool) (*compute.PIRe
 	calc := compute.NewPICalculator(digits, method)
 	
 	// Calculate PI
-	result, err := calc.Calculate()
+	stopTimer := telemetry.Track(telemetry.CategoryCompute, "pi_"+method)
+	result, err := calc.Calculate(ctx)
+	stopTimer()
 	if err != nil {
 		return nil, err
 	}
@@ -1951,7 +2383,7 @@ func calculatePIDirectly(digits int, method string, verbose bool) (*compute.PIRe
 }
 
 // runPIBenchmark führt Benchmark-Tests durch
-func runPIBenchmark() []BenchmarkResult {
+func runPIBenchmark(ctx context.Context) []BenchmarkResult {
 	fmt.Println("🧮 Testing different digit counts and methods...")
 	
 	tests := []struct {
@@ -1971,7 +2403,7 @@ func runPIBenchmark() []BenchmarkResult {
 		fmt.Printf("📊 Testing: %d digits, %s method\n", test.digits, test.method)
 		
 		start := time.Now()
-		result, err := calculatePIDirectly(test.digits, test.method, false)
+		result, err := calculatePIDirectly(ctx, test.digits, test.method, false)
 		duration := time.Since(start)
 		
 		benchResult := BenchmarkResult{
@@ -2034,6 +2466,7 @@ func writeResultToFile(result *compute.PIResult, filename string) error {
 	content += fmt.Sprintf("Timestamp: %s\n", result.Timestamp.Format(time.RFC3339))
 	content += fmt.Sprintf("\nResult:\n%s\n", result.Value)
 	
+	defer telemetry.Track(telemetry.CategoryDisk, "write_result")()
 	return os.WriteFile(filename, []byte(content), 0644)
 }
 
@@ -2059,17 +2492,15 @@ type SecureFreeTestService struct {
 	jobCounter    int64
 	
 	// SICHERHEITSFEATURES
-	rateLimiter   map[string]*RateLimit
-	mu            sync.RWMutex
-	maxDigits     int
-	maxJobsPerIP  int
-}
-
-// RateLimit für IP-basierte Begrenzung
-type RateLimit struct {
-	Count     int
-	ResetTime time.Time
-	IP        string
+	rateLimiter    *ratelimit.Limiter
+	rateStore      string
+	trustedProxies []*net.IPNet
+	pow            *ratelimit.PoW
+	deposits       *depositGate
+	httpConfig     HTTPConfig
+	mu             sync.RWMutex
+	maxDigits      int
+	maxJobsPerIP   int
 }
 
 // TestJob für kostenlose Test-Berechnungen
@@ -2102,25 +2533,49 @@ func NewSecureFreeTestService(maxJobs int, maxRuntime time.Duration, testMode bo
 		testMode:     testMode,
 		activeJobs:   make(map[string]*TestJob),
 		jobCounter:   0,
-		rateLimiter:  make(map[string]*RateLimit),
+		rateLimiter:  ratelimit.New(ratelimit.NewMemoryStore(), FREE_SERVICE_MAX_JOBS_PER_IP, FREE_SERVICE_RATE_WINDOW),
+		rateStore:    "memory",
+		httpConfig:   DefaultHTTPConfig(),
 		maxDigits:    FREE_SERVICE_MAX_DIGITS,
 		maxJobsPerIP: FREE_SERVICE_MAX_JOBS_PER_IP,
 	}
 }
 
-// Start startet den sicheren kostenlosen Service
-func (sfts *SecureFreeTestService) Start(port int) error {
+// SetRateLimiting replaces the in-memory rate limit store and sets the proxies
+// whose X-Forwarded-For header is trusted
+func (sfts *SecureFreeTestService) SetRateLimiting(kind string, store ratelimit.Store, trustedProxies []*net.IPNet) {
+	sfts.rateLimiter.Close()
+	sfts.rateLimiter = ratelimit.New(store, sfts.maxJobsPerIP, FREE_SERVICE_RATE_WINDOW)
+	sfts.rateStore = kind
+	sfts.trustedProxies = trustedProxies
+}
+
+// Router liefert die HTTP-Routen inkl. Security-Middleware
+func (sfts *SecureFreeTestService) Router() http.Handler {
 	r := mux.NewRouter()
 	
-	// Security Middleware
-	r.Use(sfts.securityMiddleware)
-	r.Use(sfts.rateLimitMiddleware)
+	// API routes, the same under every version
+	mountAPI(r, func(api *mux.Router, version int) {
+		api.Use(sfts.rateLimitMiddleware)
+		api.Use(sfts.protectionMiddleware)
+		
+		api.HandleFunc("/status", sfts.handleStatus).Methods("GET")
+		api.HandleFunc("/calculate", sfts.handleCalculate).Methods("POST")
+		api.HandleFunc("/limits", sfts.handleLimits).Methods("GET")
+		if sfts.pow != nil {
+			api.HandleFunc("/challenge", sfts.handleChallenge).Methods("GET")
+		}
+		api.Handle("/openapi.json", sfts.apiDocument(version).Handler()).Methods("GET")
+		api.Handle("/docs", openapi.Docs(fmt.Sprintf("MEDAS Free PI Service API v%d", version), "openapi.json")).Methods("GET")
+	})
 	
-	// API routes
-	api := r.PathPrefix("/api/v1").Subrouter()
-	api.HandleFunc("/status", sfts.handleStatus).Methods("GET")
-	api.HandleFunc("/calculate", sfts.handleCalculate).Methods("POST")
-	api.HandleFunc("/limits", sfts.handleLimits).Methods("GET")
+	// Security headers, body limit and CORS wrap the router so preflights reach them
+	return sfts.httpConfig.Wrap(r)
+}
+
+// Start startet den sicheren kostenlosen Service
+func (sfts *SecureFreeTestService) Start(port int) error {
+	r := sfts.Router()
 	
 	fmt.Printf("🚀 Secure Free PI Test Service started on http://localhost:%d\n", port)
 	fmt.Println("\n🔒 SECURITY FEATURES ENABLED:")
@@ -2128,11 +2583,23 @@ func (sfts *SecureFreeTestService) Start(port int) error {
 	fmt.Printf("   ✅ Max concurrent jobs: %d\n", FREE_SERVICE_MAX_CONCURRENT)
 	fmt.Printf("   ✅ Rate limit: %d requests/hour/IP\n", FREE_SERVICE_MAX_JOBS_PER_IP)
 	fmt.Printf("   ✅ Job timeout: %v\n", FREE_SERVICE_MAX_RUNTIME)
+	if sfts.pow != nil {
+		fmt.Printf("   ✅ Proof of work: %d bits per calculation\n", sfts.pow.Difficulty())
+	}
+	if sfts.deposits != nil {
+		fmt.Printf("   ✅ Deposit: %s to %s per %d calculations\n", formatCoin(sfts.deposits.amount), sfts.deposits.address, sfts.deposits.requests)
+	}
 	
 	fmt.Println("\n📋 Available endpoints:")
 	fmt.Println("   GET  /api/v1/status           - Service status")
 	fmt.Println("   POST /api/v1/calculate        - Submit PI calculation (LIMITED)")
 	fmt.Println("   GET  /api/v1/limits           - Show current limits")
+	if sfts.pow != nil {
+		fmt.Println("   GET  /api/v1/challenge        - Proof of work challenge")
+	}
+	fmt.Println("   GET  /api/v1/openapi.json     - OpenAPI document")
+	fmt.Println("   GET  /api/v1/docs             - API documentation (Swagger UI)")
+	fmt.Println("   All endpoints are also under /api/v2, which answers errors as JSON")
 	
 	fmt.Println("\n🧮 Example PI calculation (MAX 100 digits):")
 	fmt.Printf("   curl -X POST http://localhost:%d/api/v1/calculate \\\n", port)
@@ -2145,27 +2612,58 @@ func (sfts *SecureFreeTestService) Start(port int) error {
 	return http.ListenAndServe(fmt.Sprintf(":%d", port), r)
 }
 
+// freeStatusResponse is the body of GET /status of the free service
+type freeStatusResponse struct {
+	Service         string          `json:"service"`
+	Status          string          `json:"status"`
+	MaxDigits       int             `json:"max_digits"`
+	MaxRuntime      string          `json:"max_runtime"`
+	RateLimit       string          `json:"rate_limit"`
+	Cost            string          `json:"cost"`
+	Methods         []string        `json:"methods"`
+	AbuseProtection abuseProtection `json:"abuse_protection"`
+}
+
 // Handler methods (vereinfacht für main.go)
 func (sfts *SecureFreeTestService) handleStatus(w http.ResponseWriter, r *http.Request) {
-	status := map[string]interface{}{
-		"service":        "Secure Free PI Computation Service",
-		"status":         "running",
-		"max_digits":     sfts.maxDigits,
-		"max_runtime":    sfts.maxRuntime.String(),
-		"rate_limit":     fmt.Sprintf("%d/hour/IP", sfts.maxJobsPerIP),
-		"cost":           "FREE (with limits)",
-		"methods":        compute.GetAvailableMethods(),
+	status := freeStatusResponse{
+		Service:         "Secure Free PI Computation Service",
+		Status:          "running",
+		MaxDigits:       sfts.maxDigits,
+		MaxRuntime:      sfts.maxRuntime.String(),
+		RateLimit:       fmt.Sprintf("%d/hour/IP", sfts.maxJobsPerIP),
+		Cost:            "FREE (with limits)",
+		Methods:         compute.GetAvailableMethods(),
+		AbuseProtection: sfts.protectionInfo(),
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(status)
 }
 
+// calculateRequest is the body of POST /calculate
+type calculateRequest struct {
+	Digits int    `json:"digits"`
+	Method string `json:"method"`
+}
+
+// calculateResponse is the body POST /calculate answers with
+type calculateResponse struct {
+	Result      *compute.PIResult `json:"result"`
+	Cost        string            `json:"cost"`
+	Limits      calculateLimits   `json:"limits"`
+	UpgradeInfo string            `json:"upgrade_info"`
+}
+
+type calculateLimits struct {
+	MaxDigits       int    `json:"max_digits"`
+	MaxRuntime      string `json:"max_runtime"`
+	UsedDigits      int    `json:"used_digits"`
+	CalculationTime string `json:"calculation_time"`
+}
+
 func (sfts *SecureFreeTestService) handleCalculate(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Digits int    `json:"digits"`
-		Method string `json:"method"`
-	}
+	var req calculateRequest
 	
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
@@ -2183,15 +2681,19 @@ func (sfts *SecureFreeTestService) handleCalculate(w http.ResponseWriter, r *htt
 		return
 	}
 	
-	if req.Method == "" {
-		req.Method = "chudnovsky"
+	method, err := resolvePIMethod(req.Method, req.Digits)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	req.Method = method
 	
 	clientIP := sfts.getClientIP(r)
 	fmt.Printf("🧮 Free calculation request: %d digits, %s method from IP %s\n", req.Digits, req.Method, clientIP)
 	
 	// Calculate PI mit Timeout
-	ctx, cancel := context.WithTimeout(context.Background(), sfts.maxRuntime)
+	// The calculation stops when the client goes away or maxRuntime passes
+	ctx, cancel := context.WithTimeout(r.Context(), sfts.maxRuntime)
 	defer cancel()
 	
 	// Channel for result
@@ -2200,7 +2702,7 @@ func (sfts *SecureFreeTestService) handleCalculate(w http.ResponseWriter, r *htt
 	
 	// Start calculation in goroutine
 	go func() {
-		result, err := calculatePIDirectly(req.Digits, req.Method, false)
+		result, err := calculatePIDirectly(ctx, req.Digits, req.Method, false)
 		if err != nil {
 			errorChan <- err
 		} else {
@@ -2211,16 +2713,16 @@ func (sfts *SecureFreeTestService) handleCalculate(w http.ResponseWriter, r *htt
 	// Wait for result or timeout
 	select {
 	case result := <-resultChan:
-		response := map[string]interface{}{
-			"result":     result,
-			"cost":       "FREE",
-			"limits": map[string]interface{}{
-				"max_digits":        sfts.maxDigits,
-				"max_runtime":       sfts.maxRuntime.String(),
-				"used_digits":       req.Digits,
-				"calculation_time":  result.Duration.String(),
+		response := calculateResponse{
+			Result: result,
+			Cost:   "FREE",
+			Limits: calculateLimits{
+				MaxDigits:       sfts.maxDigits,
+				MaxRuntime:      sfts.maxRuntime.String(),
+				UsedDigits:      req.Digits,
+				CalculationTime: result.Duration.String(),
 			},
-			"upgrade_info": "For unlimited calculations, use payment-service with MEDAS tokens",
+			UpgradeInfo: "For unlimited calculations, use payment-service with MEDAS tokens",
 		}
 		
 		w.Header().Set("Content-Type", "application/json")
@@ -2238,16 +2740,44 @@ func (sfts *SecureFreeTestService) handleCalculate(w http.ResponseWriter, r *htt
 	}
 }
 
+// freeLimitsResponse is the body of GET /limits of the free service
+type freeLimitsResponse struct {
+	ServiceType     string           `json:"service_type"`
+	MaxDigits       int              `json:"max_digits"`
+	MaxRuntime      string           `json:"max_runtime"`
+	RateLimit       string           `json:"rate_limit"`
+	RateLimitStore  string           `json:"rate_limit_store"`
+	Methods         *piMethodsReport `json:"methods"`
+	AbuseProtection abuseProtection  `json:"abuse_protection"`
+	UpgradeInfo     upgradeInfo      `json:"upgrade_info"`
+}
+
+type upgradeInfo struct {
+	UnlimitedService string `json:"unlimited_service"`
+	MaxDigits        string `json:"max_digits"`
+	Cost             string `json:"cost"`
+}
+
 func (sfts *SecureFreeTestService) handleLimits(w http.ResponseWriter, r *http.Request) {
-	limits := map[string]interface{}{
-		"service_type":     "Free PI Calculation Service",
-		"max_digits":       sfts.maxDigits,
-		"max_runtime":      sfts.maxRuntime.String(),
-		"rate_limit":       fmt.Sprintf("%d/hour/IP", sfts.maxJobsPerIP),
-		"upgrade_info": map[string]interface{}{
-			"unlimited_service": "payment-service",
-			"max_digits":        "100,000+",
-			"cost":              "MEDAS tokens",
+	// The methods as they suit the largest free calculation
+	methods, err := describePIMethods(sfts.maxDigits)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	limits := freeLimitsResponse{
+		ServiceType:     "Free PI Calculation Service",
+		MaxDigits:       sfts.maxDigits,
+		MaxRuntime:      sfts.maxRuntime.String(),
+		RateLimit:       fmt.Sprintf("%d/hour/IP", sfts.maxJobsPerIP),
+		RateLimitStore:  sfts.rateStore,
+		Methods:         methods,
+		AbuseProtection: sfts.protectionInfo(),
+		UpgradeInfo: upgradeInfo{
+			UnlimitedService: "payment-service",
+			MaxDigits:        "1,000,000",
+			Cost:             "MEDAS tokens",
 		},
 	}
 	
@@ -2255,21 +2785,6 @@ func (sfts *SecureFreeTestService) handleLimits(w http.ResponseWriter, r *http.R
 	json.NewEncoder(w).Encode(limits)
 }
 
-// Security middleware methods
-func (sfts *SecureFreeTestService) securityMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("X-Content-Type-Options", "nosniff")
-		w.Header().Set("X-Frame-Options", "DENY")
-		
-		if r.ContentLength > 1024*10 { // 10KB limit
-			http.Error(w, "Request too large", http.StatusRequestEntityTooLarge)
-			return
-		}
-		
-		next.ServeHTTP(w, r)
-	})
-}
-
 func (sfts *SecureFreeTestService) rateLimitMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
@@ -2279,61 +2794,46 @@ func (sfts *SecureFreeTestService) rateLimitMiddleware(next http.Handler) http.H
 		
 		clientIP := sfts.getClientIP(r)
 		
-		sfts.mu.Lock()
-		rateLimit, exists := sfts.rateLimiter[clientIP]
-		
-		if exists && time.Now().After(rateLimit.ResetTime) {
-			rateLimit.Count = 0
-			rateLimit.ResetTime = time.Now().Add(FREE_SERVICE_RATE_WINDOW)
+		result, err := sfts.rateLimiter.Allow(clientIP)
+		if err != nil {
+			// Don't take the service down with the rate limit store
+			fmt.Printf("⚠️  Rate limit store error, allowing request from %s: %v\n", clientIP, err)
 		}
 		
-		if !exists {
-			rateLimit = &RateLimit{
-				Count:     0,
-				ResetTime: time.Now().Add(FREE_SERVICE_RATE_WINDOW),
-				IP:        clientIP,
-			}
-			sfts.rateLimiter[clientIP] = rateLimit
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		if !result.Reset.IsZero() {
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(result.Reset.Unix(), 10))
 		}
 		
-		if rateLimit.Count >= sfts.maxJobsPerIP {
-			sfts.mu.Unlock()
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(result.Reset).Seconds())+1))
 			http.Error(w, fmt.Sprintf("Rate limit exceeded. Max %d requests per hour per IP.", sfts.maxJobsPerIP), http.StatusTooManyRequests)
 			return
 		}
 		
-		rateLimit.Count++
-		sfts.mu.Unlock()
-		
 		next.ServeHTTP(w, r)
 	})
 }
 
+// getClientIP only honours forwarding headers from --trusted-proxies
 func (sfts *SecureFreeTestService) getClientIP(r *http.Request) string {
-	forwarded := r.Header.Get("X-Forwarded-For")
-	if forwarded != "" {
-		ips := strings.Split(forwarded, ",")
-		return strings.TrimSpace(ips[0])
-	}
-	
-	realIP := r.Header.Get("X-Real-IP")
-	if realIP != "" {
-		return realIP
-	}
-	
-	ip := r.RemoteAddr
-	if strings.Contains(ip, ":") {
-		ip, _, _ = strings.Cut(ip, ":")
-	}
-	
-	return ip
+	return ratelimit.ClientIP(r, sfts.trustedProxies)
 }
 
 
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	ctx, stop := signalContext()
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	cancelTimeout()
+	stop()
+	telemetry.PrintSummary(os.Stderr)
+	if errors.Is(err, blockchain.ErrDryRun) {
+		fmt.Fprintln(os.Stderr, "🧪 Dry run, nothing was broadcast")
+		return
+	}
+	if err != nil {
+		os.Exit(printError(os.Stderr, cmd, err))
 	}
 }
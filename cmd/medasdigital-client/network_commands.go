@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+)
+
+// networkCmd shows the network profiles --network can select
+var networkCmd = &cobra.Command{
+	Use:   "network",
+	Short: "Show the available network profiles",
+}
+
+// networkListCmd lists bundled and custom profiles
+var networkListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List network profiles",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := registerNetworkProfiles(); err != nil {
+			return err
+		}
+		selected, err := network.Get(viper.GetString("chain.network"))
+		if err != nil {
+			return err
+		}
+
+		for _, name := range network.Names() {
+			n, err := network.Get(name)
+			if err != nil {
+				return err
+			}
+			marker := " "
+			if name == selected.Name {
+				marker = "*"
+			}
+			fmt.Printf("%s %-10s %-24s %s\n", marker, name, n.ChainID, n.RPCEndpoint)
+		}
+		return nil
+	},
+}
+
+// networkShowCmd prints one profile as it is used, config overrides included
+var networkShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show the settings of a network profile (default: the selected network)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var n network.Network
+		var err error
+		if len(args) == 0 {
+			n, err = resolveNetwork()
+		} else if err = registerNetworkProfiles(); err == nil {
+			n, err = network.Get(args[0])
+		}
+		if err != nil {
+			return err
+		}
+
+		data, err := yaml.Marshal(n)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(networkCmd)
+	networkCmd.AddCommand(networkListCmd)
+	networkCmd.AddCommand(networkShowCmd)
+}
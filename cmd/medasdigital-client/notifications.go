@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/notify"
+)
+
+// notifyTimeout bounds the delivery of one notification to all channels
+const notifyTimeout = 30 * time.Second
+
+// notifyDone reports the end of long-running work through the channels in the
+// notifications section of the config. Delivery problems are only printed,
+// they never change the outcome of the command.
+func notifyDone(kind, title, message string, started time.Time, workErr error) {
+	d, err := notify.New(loadConfig().Notifications)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Notifications disabled: %v\n", err)
+		return
+	}
+	if d == nil {
+		return
+	}
+
+	e := notify.Event{
+		Kind:     kind,
+		Level:    notify.LevelSuccess,
+		Title:    title,
+		Message:  message,
+		Duration: time.Since(started),
+	}
+	if workErr != nil {
+		e.Level = notify.LevelError
+		e.Message = workErr.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if err := d.Send(ctx, e); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Notification failed: %v\n", err)
+	}
+}
+
+// notifyTestCmd sends a test message to every configured channel
+var notifyTestCmd = &cobra.Command{
+	Use:   "notify-test",
+	Short: "Send a test notification to the configured channels",
+	Long: `Send a test message through the email, Slack and Matrix channels of the
+notifications section, ignoring min_duration and the event filter.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := loadConfig().Notifications
+		cfg.MinDuration, cfg.Events = 0, nil
+		d, err := notify.New(cfg)
+		if err != nil {
+			return err
+		}
+		if d == nil {
+			return fmt.Errorf("no notification channel configured, see notifications in the config")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+		if err := d.Send(ctx, notify.Event{
+			Kind:    notify.KindJob,
+			Level:   notify.LevelSuccess,
+			Title:   "Test notification",
+			Message: "Notifications from medasdigital-client work.",
+		}); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Test notification sent via %v\n", d.Notifiers())
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(notifyTestCmd)
+}
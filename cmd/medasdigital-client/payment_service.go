@@ -3,22 +3,32 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
+	"math/big"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/spf13/cobra"
 	
 	"github.com/oxygene76/medasdigital-client/pkg/compute"
+	"github.com/oxygene76/medasdigital-client/pkg/contract"
 	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+	"github.com/oxygene76/medasdigital-client/pkg/openapi"
 	
+	sdkmath "cosmossdk.io/math"
 	"github.com/cosmos/cosmos-sdk/client"
 	sdk "github.com/cosmos/cosmos-sdk/types"
-    authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
     authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
     "github.com/cosmos/cosmos-sdk/codec"
 )
@@ -33,6 +43,7 @@ This service includes:
 - Community pool fee distribution (15%)
 - Multi-tier service levels (Basic, Standard, Premium)
 - Job queue management with priority processing
+- Selectable result verification (none, spot-check, full-recompute, commitment)
 - Real-time progress monitoring
 
 Example:
@@ -49,6 +60,7 @@ Example:
 		minConfirmations, _ := cmd.Flags().GetInt("min-confirmations")
 		maxJobs, _ := cmd.Flags().GetInt("max-jobs")
 		workers, _ := cmd.Flags().GetInt("workers")
+		watchPayments, _ := cmd.Flags().GetBool("watch-payments")
 		
 		// Validate required flags
 		if serviceAddr == "" {
@@ -60,6 +72,103 @@ Example:
 		
 		// Create and start the real payment service
 		service := NewRealPaymentService(serviceAddr, communityAddr, communityFee, minConfirmations, maxJobs, workers)
+		service.watchPayments = watchPayments
+		service.adminToken, _ = cmd.Flags().GetString("admin-token")
+		if err := service.httpConfig.Validate(); err != nil {
+			return err
+		}
+		if err := service.limits.Validate(); err != nil {
+			return err
+		}
+		
+		paymentDenoms, err := compute.PaymentDenoms(loadConfig().Payment.IBCDenoms)
+		if err != nil {
+			return fmt.Errorf("invalid payment.ibc_denoms: %w", err)
+		}
+		service.paymentDenoms = paymentDenoms
+		service.refundFee, _ = cmd.Flags().GetInt64("refund-fee")
+		if cmd.Flags().Changed("payment-tolerance") {
+			percent, _ := cmd.Flags().GetFloat64("payment-tolerance")
+			if percent < 0 || percent >= 100 {
+				return fmt.Errorf("--payment-tolerance must be between 0 and 100, got %g", percent)
+			}
+			service.paymentTolerance = percent / 100
+		}
+		if cmd.Flags().Changed("strict-payments") {
+			service.strictPayments, _ = cmd.Flags().GetBool("strict-payments")
+		}
+		if recalibrate, _ := cmd.Flags().GetBool("calibrate"); recalibrate {
+			profile, err := calibrate(cmd.Context())
+			if err != nil {
+				return err
+			}
+			service.pricingManager.SetPerformanceProfile(profile)
+		}
+		
+		pricingFile := loadConfig().Payment.PricingFile
+		if cmd.Flags().Changed("pricing-file") {
+			pricingFile, _ = cmd.Flags().GetString("pricing-file")
+		}
+		if pricingFile != "" {
+			pricing, err := compute.NewFilePricing(cmd.Context(), pricingFile, service.load)
+			if err != nil {
+				return err
+			}
+			service.pricing = pricing
+			service.pricingManager.SetPricingEngine(pricing)
+		}
+		
+		service.resumeFrom, _ = cmd.Flags().GetStringSlice("resume-from")
+		
+		outOfCore := service.outOfCore
+		if cmd.Flags().Changed("memory-budget") {
+			mb, _ := cmd.Flags().GetInt("memory-budget")
+			if mb < 0 {
+				return fmt.Errorf("--memory-budget must not be negative, got %d", mb)
+			}
+			outOfCore.MemoryBudget = int64(mb) * 1024 * 1024
+		}
+		if cmd.Flags().Changed("spill-dir") {
+			outOfCore.TempDir, _ = cmd.Flags().GetString("spill-dir")
+		}
+		service.setOutOfCore(outOfCore)
+		if cmd.Flags().Changed("artifact-retention") {
+			service.artifactRetention, _ = cmd.Flags().GetDuration("artifact-retention")
+		}
+		if service.artifactRetention <= 0 {
+			return fmt.Errorf("artifact retention must be positive, got %v", service.artifactRetention)
+		}
+		
+		cacheConfig := loadConfig().Payment.ResultCache
+		if cmd.Flags().Changed("result-cache") {
+			cacheConfig.Enabled, _ = cmd.Flags().GetBool("result-cache")
+		}
+		if cmd.Flags().Changed("cache-price-factor") {
+			cacheConfig.PriceFactor, _ = cmd.Flags().GetFloat64("cache-price-factor")
+		}
+		if cacheConfig.Enabled {
+			cache, err := compute.NewResultCache(resultCacheDir(), cacheConfig.TTL, cacheConfig.MaxEntries, cacheConfig.PriceFactor)
+			if err != nil {
+				return err
+			}
+			service.jobManager.SetResultCache(cache)
+		}
+		
+		if refundFrom, _ := cmd.Flags().GetString("refund-from"); refundFrom != "" {
+			keyringBackend := keyringBackendFlag(cmd)
+			clientCtx, err := signingClientContext(cmd.Context(), refundFrom, keyringBackend)
+			if err != nil {
+				return err
+			}
+			sender, err := blockchain.NewSender(clientCtx)
+			if err != nil {
+				return err
+			}
+			if sender.Address() != serviceAddr {
+				return fmt.Errorf("refund key %s has address %s, not the service address %s", refundFrom, sender.Address(), serviceAddr)
+			}
+			service.refundSender = sender
+		}
 		
 		fmt.Println("🚀 Starting MEDAS Payment-Enabled Computing Service")
 		fmt.Println("=================================================")
@@ -69,9 +178,42 @@ Example:
 		fmt.Printf("👥 Max concurrent jobs: %d\n", maxJobs)
 		fmt.Printf("⚙️  Worker threads: %d\n", workers)
 		fmt.Printf("🔐 Min confirmations: %d\n", minConfirmations)
+		if service.strictPayments {
+			fmt.Println("🎯 Payment amounts: exact (strict)")
+		} else {
+			fmt.Printf("🎯 Payment tolerance: %g%%\n", service.paymentTolerance*100)
+		}
+		if service.outOfCore.Enabled() {
+			dir := service.outOfCore.TempDir
+			if dir == "" {
+				dir = os.TempDir()
+			}
+			fmt.Printf("💾 PI products beyond %d MB spill to %s\n", service.outOfCore.MemoryBudget/1024/1024, dir)
+		}
+		if profile := service.pricingManager.PerformanceProfile(); profile != nil {
+			fmt.Printf("📐 Estimates and prices from the performance profile of %s\n", profile.CalibratedAt.Format(time.DateTime))
+		} else {
+			fmt.Println("📐 No performance profile, estimates are rough (run payment-service calibrate)")
+		}
+		if l := service.limits.Default; l.MaxConcurrentJobs > 0 || l.MaxDailySpend > 0 || len(service.limits.Accounts) > 0 {
+			fmt.Printf("🚦 Limits per client: %s (%d address override(s))\n", describeLimit(l), len(service.limits.Accounts))
+		}
+		if cache := service.jobManager.ResultCache(); cache != nil {
+			stats := cache.Stats()
+			fmt.Printf("🗃️  Result cache: %d result(s), identical jobs at %g%% of the price\n", stats.Entries, stats.PriceFactor*100)
+		}
+		if service.pricing != nil {
+			fmt.Printf("💹 Prices from %s, reloaded when it changes\n", service.pricing.Path())
+			if currency, price, _ := service.pricing.ExchangeRate(); currency != "MEDAS" {
+				fmt.Printf("💱 1 MEDAS = %g %s (price oracle)\n", price, currency)
+			}
+		}
+		for _, d := range service.sortedPaymentDenoms() {
+			fmt.Printf("🌉 Accepting %s (%s) at %g MEDAS\n", d.Label(), d.Denom, d.Rate)
+		}
 		fmt.Println("\n💡 This service accepts real MEDAS token payments!")
 		
-		return service.Start(port)
+		return service.Start(cmd.Context(), port)
 	},
 }
 
@@ -84,74 +226,231 @@ type RealPaymentService struct {
 	
 	// Core managers
 	pricingManager    *compute.PricingManager
+	pricing           *compute.FilePricing // prices from payment.pricing_file, nil for the built-in tiers
 	jobManager        *compute.JobManager
+	communityHistory  *CommunityHistory
+	invoices          *InvoiceStore
+	refunds           *RefundQueue
+	ledger            *Ledger
+	subscriptions     *SubscriptionStore
 	
 	// Blockchain client - erweiterte Version mit Transaction-Query-Methoden
 	blockchainClient  *blockchain.Client
+	checkPaymentFn    func(txHash, senderAddr, denom string, expectedMEDAS float64) (*blockchain.PaymentCheck, error)
 	clientCtx         client.Context
 	rpcEndpoint       string
 	chainID           string
+	
+	// Concurrency and daily spend per client address, see admit
+	limits            LimitsConfig
+	limitsMu          sync.Mutex
+	
+	// Fallback of balance queries when the RPC query fails, nil without chain.rest_endpoint
+	rest              *blockchain.REST
+	
+	// Payments already turned into jobs
+	consumed          *ConsumedPayments
+	idempotency       *IdempotencyStore
+	watchPayments     bool
+	
+	// Refunds are sent automatically when the service key is available
+	refundSender      *blockchain.Sender
+	refundFee         int64
+	adminToken        string
+	
+	// Aggregated provider history, nil without a compute contract
+	reputations       *ReputationCache
+	
+	// IBC tokens accepted besides MEDAS, by denom
+	paymentDenoms     map[string]compute.PaymentDenom
+	
+	// Accepted relative difference between payment and price, unused in strict mode
+	paymentTolerance  float64
+	strictPayments    bool
+	
+	// CORS, body size limit and security headers from the http config
+	httpConfig        HTTPConfig
+	
+	// Checkpoints of interrupted PI jobs to continue on start
+	resumeFrom        []string
+	
+	// Memory budget of PI calculations, products beyond it go to disk
+	outOfCore         compute.OutOfCore
+	
+	// How long artifacts no job refers to are kept
+	artifactRetention time.Duration
 }
 
 // NewRealPaymentService creates a new real payment service
 func NewRealPaymentService(serviceAddr, communityAddr string, communityFee float64, minConfirmations, maxJobs, workers int) *RealPaymentService {
-	// Create pricing manager
+	cfg := loadConfig()
+	
+	// Create pricing manager, with the measured speed of this host if calibrated
 	pricingManager := compute.NewPricingManager(communityAddr)
+	pricingManager.SetPerformanceProfile(loadPerformanceProfile())
 	
 	// Create job manager  
 	jobManager := compute.NewJobManager(maxJobs, workers, pricingManager)
 	
-	return &RealPaymentService{
+	rps := &RealPaymentService{
 		serviceAddr:      serviceAddr,
 		communityAddr:    communityAddr,
 		communityFee:     communityFee,
 		minConfirmations: minConfirmations,
 		pricingManager:   pricingManager,
 		jobManager:       jobManager,
-		rpcEndpoint:      defaultRPCEndpoint,  // aus main.go
-		chainID:          defaultChainID,      // aus main.go
+		communityHistory: NewCommunityHistory(filepath.Join(homeDir, "community", "distributions.json")),
+		invoices:         NewInvoiceStore(filepath.Join(homeDir, "invoices", "invoices.json")),
+		rpcEndpoint:      cfg.Chain.RPCEndpoint,
+		chainID:          cfg.Chain.ID,
+		refunds:          NewRefundQueue(filepath.Join(homeDir, "refunds", "refunds.json")),
+		ledger:           NewLedger(filepath.Join(homeDir, "ledger", "ledger.json")),
+		subscriptions:    NewSubscriptionStore(filepath.Join(homeDir, "subscriptions", "subscriptions.json")),
+		refundFee:        defaultRefundFee,
+		consumed:         NewConsumedPayments(filepath.Join(homeDir, "payments", "consumed.json")),
+		idempotency:      NewIdempotencyStore(),
+		httpConfig:       cfg.HTTP,
+		paymentTolerance: cfg.Payment.TolerancePercent / 100,
+		strictPayments:   cfg.Payment.Strict,
+		artifactRetention: cfg.Payment.ArtifactRetention,
+		limits:           cfg.Payment.Limits,
 	}
+	if rest, err := blockchain.NewREST(cfg.Chain.RESTEndpoint, cfg.Chain.ID); err == nil {
+		rps.rest = rest
+	} else if !errors.Is(err, blockchain.ErrNoRESTEndpoint) {
+		log.Printf("⚠️ %v, balance queries have no REST fallback", err)
+	}
+	if cfg.Contract.Address != "" {
+		rps.reputations = NewReputationCache(contract.NewClient(contract.Config{
+			ContractAddress: cfg.Contract.Address,
+			RPCEndpoint:     cfg.Chain.RPCEndpoint,
+			ChainID:         cfg.Chain.ID,
+		}, "", "", ""), contract.ReputationOptions{
+			HeartbeatInterval: time.Duration(cfg.Provider.HeartbeatIntervalMinutes) * time.Minute,
+		})
+	}
+	jobManager.SetFailureHandler(rps.handleJobFailed)
+	jobManager.SetCompletionHandler(rps.handleJobFinished)
+	jobManager.SetCheckpointDir(checkpointDir())
+	jobManager.SetArtifactDir(artifactDir())
+	rps.setOutOfCore(compute.OutOfCore{
+		MemoryBudget: int64(cfg.Payment.MemoryBudgetMB) * 1024 * 1024,
+		TempDir:      cfg.Payment.SpillDir,
+	})
+	
+	return rps
 }
 
-// Start starts the payment service HTTP server
-func (rps *RealPaymentService) Start(port int) error {
-	// Initialize blockchain client context
-	if err := rps.initializeBlockchainClient(); err != nil {
-		return fmt.Errorf("failed to initialize blockchain client: %w", err)
+// load is the number of busy workers and queued jobs per worker, surge
+// pricing starts at a configured load
+func (rps *RealPaymentService) load() float64 {
+	q := rps.jobManager.GetQueueStatus()
+	if q.MaxWorkers == 0 {
+		return 0
 	}
-	
+	return float64(q.ActiveWorkers+q.TotalQueued) / float64(q.MaxWorkers)
+}
+
+// setOutOfCore sets the memory budget of the PI calculations of following jobs
+func (rps *RealPaymentService) setOutOfCore(o compute.OutOfCore) {
+	rps.outOfCore = o
+	rps.jobManager.SetOutOfCore(o)
+}
+
+// Router returns the payment service HTTP routes
+func (rps *RealPaymentService) Router() http.Handler {
 	// Setup HTTP router
 	r := mux.NewRouter()
 	
-	// Add CORS middleware
-	r.Use(corsMiddleware)
-	
-	// API routes
-	api := r.PathPrefix("/api/v1").Subrouter()
-	
-	// Pricing endpoints
-	api.HandleFunc("/pricing", rps.handleGetPricing).Methods("GET")
-	api.HandleFunc("/pricing/estimate", rps.handleEstimatePrice).Methods("POST")
-	api.HandleFunc("/pricing/compare", rps.handleCompareTiers).Methods("POST")
+	// API routes, the same under every version
+	mountAPI(r, func(api *mux.Router, version int) {
+		// Pricing endpoints
+		api.HandleFunc("/pricing", rps.handleGetPricing).Methods("GET")
+		api.HandleFunc("/pricing/estimate", rps.handleEstimatePrice).Methods("POST")
+		api.HandleFunc("/pricing/compare", rps.handleCompareTiers).Methods("POST")
+	
+		// Job submission and management
+		api.HandleFunc("/jobs/submit", rps.handleSubmitJob).Methods("POST")
+		api.HandleFunc("/jobs", rps.handleListJobs).Methods("GET")
+		api.HandleFunc("/jobs/{id}", rps.handleGetJob).Methods("GET")
+		api.HandleFunc("/jobs/{id}/cancel", rps.handleCancelJob).Methods("POST")
+		api.HandleFunc("/jobs/{id}/stream", rps.handleStreamJob).Methods("GET")
+		api.HandleFunc("/jobs/{id}/artifact", rps.handleJobArtifact).Methods("GET", "HEAD")
+	
+		// Payment verification
+		api.HandleFunc("/payment/verify", rps.handleVerifyPayment).Methods("POST")
+		api.HandleFunc("/payments/{tx_hash}", rps.handlePaymentLookup).Methods("GET")
+		api.HandleFunc("/invoices", rps.handleCreateInvoice).Methods("POST")
+		api.HandleFunc("/invoices/{id}", rps.handleGetInvoice).Methods("GET")
+	
+		// Per-client accounting
+		api.HandleFunc("/accounts/{addr}", rps.handleGetAccount).Methods("GET")
+		api.HandleFunc("/accounts/{addr}/statement", rps.handleAccountStatement).Methods("GET")
+		api.HandleFunc("/accounts/{addr}/limits", rps.handleGetAccountLimits).Methods("GET")
+		api.HandleFunc("/subscriptions/{addr}", rps.handleGetSubscription).Methods("GET")
+	
+		// Admin endpoints, require --admin-token
+		api.HandleFunc("/admin/refunds", rps.adminOnly(rps.handleListRefunds)).Methods("GET")
+		api.HandleFunc("/admin/refunds/{id}/retry", rps.adminOnly(rps.handleRetryRefund)).Methods("POST")
+	
+		// Service status and statistics
+		api.HandleFunc("/status", rps.handleServiceStatus).Methods("GET")
+		api.HandleFunc("/statistics", rps.handleStatistics).Methods("GET")
+		api.HandleFunc("/queue", rps.handleQueueStatus).Methods("GET")
+		api.HandleFunc("/cache", rps.handleCacheStats).Methods("GET")
+	
+		// Community pool endpoints
+		api.HandleFunc("/community/stats", rps.handleCommunityStats).Methods("GET")
+	
+		// Provider reputation from the compute contract history
+		api.HandleFunc("/providers", rps.handleListProviderReputations).Methods("GET")
+		api.HandleFunc("/providers/{addr}", rps.handleGetProviderReputation).Methods("GET")
+	
+		// API documentation
+		api.Handle("/openapi.json", rps.apiDocument(version).Handler()).Methods("GET")
+		api.Handle("/docs", openapi.Docs(fmt.Sprintf("MEDAS Payment Service API v%d", version), "openapi.json")).Methods("GET")
+	})
 	
-	// Job submission and management
-	api.HandleFunc("/jobs/submit", rps.handleSubmitJob).Methods("POST")
-	api.HandleFunc("/jobs", rps.handleListJobs).Methods("GET")
-	api.HandleFunc("/jobs/{id}", rps.handleGetJob).Methods("GET")
-	api.HandleFunc("/jobs/{id}/cancel", rps.handleCancelJob).Methods("POST")
+	// CORS, body limit and security headers wrap the router so preflights reach them
+	return rps.httpConfig.Wrap(r)
+}
+
+// Start starts the payment service HTTP server, it shuts down when ctx is
+// cancelled
+func (rps *RealPaymentService) Start(ctx context.Context, port int) error {
+	// Initialize blockchain client context
+	if err := rps.initializeBlockchainClient(); err != nil {
+		return fmt.Errorf("failed to initialize blockchain client: %w", err)
+	}
 	
-	// Payment verification
-	api.HandleFunc("/payment/verify", rps.handleVerifyPayment).Methods("POST")
+	r := rps.Router()
+	go rps.runArtifactGC(ctx)
+	if rps.pricing != nil {
+		go rps.pricing.Run(ctx)
+	}
 	
-	// Service status and statistics
-	api.HandleFunc("/status", rps.handleServiceStatus).Methods("GET")
-	api.HandleFunc("/statistics", rps.handleStatistics).Methods("GET")
-	api.HandleFunc("/queue", rps.handleQueueStatus).Methods("GET")
+	if len(rps.resumeFrom) > 0 {
+		if err := rps.resumeJobs(rps.resumeFrom); err != nil {
+			return err
+		}
+	} else {
+		warnCheckpoints()
+	}
 	
-	// Community pool endpoints
-	api.HandleFunc("/community/stats", rps.handleCommunityStats).Methods("GET")
+	if rps.watchPayments {
+		go rps.runPaymentWatcher(ctx)
+		go rps.runInvoiceExpiry(ctx)
+		log.Printf("👀 Watching transfers to %s for %s<TYPE>_<PARAMS>, invoice and %s memos", rps.serviceAddr, compute.MemoPrefix, compute.SubscriptionMemo)
+	}
+	if rps.refundSender != nil {
+		go rps.runRefunds(ctx)
+		log.Printf("↩️ Refunds are sent automatically from %s (network fee %d%s)", rps.refundSender.Address(), rps.refundFee, network.Current().BaseDenom)
+	} else if n := len(rps.refunds.Queued()); n > 0 {
+		log.Printf("⚠️ %d refund(s) are queued, start with --refund-from to send them", n)
+	}
 	
 	fmt.Printf("🌐 API Endpoints available at http://localhost:%d/api/v1/\n", port)
+	fmt.Printf("   and at http://localhost:%d/api/v2/ with JSON errors\n", port)
 	fmt.Println("\n📋 Available endpoints:")
 	fmt.Println("   GET  /api/v1/pricing           - Get pricing information")
 	fmt.Println("   POST /api/v1/pricing/estimate  - Estimate job cost")
@@ -160,11 +459,24 @@ func (rps *RealPaymentService) Start(port int) error {
 	fmt.Println("   GET  /api/v1/jobs              - List jobs")
 	fmt.Println("   GET  /api/v1/jobs/{id}         - Get job details")
 	fmt.Println("   POST /api/v1/jobs/{id}/cancel  - Cancel job")
+	fmt.Println("   GET  /api/v1/jobs/{id}/stream  - Digits of a PI job in verified blocks (NDJSON)")
+	fmt.Println("   GET  /api/v1/jobs/{id}/artifact - Result file of a completed job (range requests)")
 	fmt.Println("   POST /api/v1/payment/verify    - Verify payment")
+	fmt.Println("   GET  /api/v1/payments/{tx}     - Job created for a payment")
+	fmt.Println("   POST /api/v1/invoices          - Quote a job, returns amount, memo and expiry")
+	fmt.Println("   GET  /api/v1/invoices/{id}     - Invoice status (pending, paid, expired)")
 	fmt.Println("   GET  /api/v1/status            - Service status")
 	fmt.Println("   GET  /api/v1/statistics        - Job statistics")
 	fmt.Println("   GET  /api/v1/queue             - Queue status")
-	fmt.Println("   GET  /api/v1/community/stats   - Community pool stats")
+	fmt.Println("   GET  /api/v1/cache             - Result cache statistics")
+	fmt.Println("   GET  /api/v1/community/stats   - Community pool stats and distribution history")
+	fmt.Println("   GET  /api/v1/accounts/{addr}   - Totals paid, jobs and compute time of a client")
+	fmt.Println("   GET  /api/v1/accounts/{addr}/statement?month=YYYY-MM[&format=csv] - Monthly statement")
+	fmt.Println("   GET  /api/v1/accounts/{addr}/limits - Concurrency and daily spend limits and their use")
+	fmt.Println("   GET  /api/v1/subscriptions/{addr} - Prepaid monthly quota, renewals and overage policy")
+	fmt.Println("   GET  /api/v1/admin/refunds     - Refund queue (admin token)")
+	fmt.Println("   GET  /api/v1/openapi.json      - OpenAPI document")
+	fmt.Println("   GET  /api/v1/docs              - API documentation (Swagger UI)")
 	
 	fmt.Println("\n💰 Example job submission:")
 	fmt.Printf("   curl -X POST http://localhost:%d/api/v1/jobs/submit \\\n", port)
@@ -173,11 +485,27 @@ func (rps *RealPaymentService) Start(port int) error {
 	fmt.Println("       \"type\": \"pi_calculation\",")
 	fmt.Println("       \"parameters\": {\"digits\": 1000, \"method\": \"chudnovsky\"},")
 	fmt.Println("       \"tier\": \"standard\",")
+	fmt.Println("       \"verification\": \"spot-check\",")
 	fmt.Println("       \"payment_tx_hash\": \"ABC123...\",")
 	fmt.Println("       \"client_address\": \"medas1...\"")
 	fmt.Println("     }'")
+	if rps.watchPayments {
+		fmt.Println("\n💸 Or just pay with a job memo, the job starts when the transfer is committed:")
+		fmt.Printf("   medasdigitald tx bank send <key> %s 1000000%s --note %s\n",
+			rps.serviceAddr, network.Current().BaseDenom, compute.PaymentMemo(1000, "chudnovsky", compute.TierStandard, compute.VerificationNone))
+	}
 	
-	return http.ListenAndServe(fmt.Sprintf(":%d", port), r)
+	server := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: r}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
 
 func (rps *RealPaymentService) initializeBlockchainClient() error {
@@ -188,14 +516,14 @@ func (rps *RealPaymentService) initializeBlockchainClient() error {
     }
 
     // Create TxConfig with proper codec setup
-    txConfig := authtx.NewTxConfig(globalCodec, authtx.DefaultSignModes)
+    txConfig := currentApp().TxConfig()
     
     // Create client context with ALL required components
     rps.clientCtx = client.Context{}.
         WithClient(rpcClient).
         WithChainID(rps.chainID).
-        WithCodec(globalCodec).
-        WithInterfaceRegistry(globalInterfaceRegistry).
+        WithCodec(currentApp().Codec()).
+        WithInterfaceRegistry(currentApp().InterfaceRegistry()).
         WithTxConfig(txConfig).  // WICHTIG: TxConfig explizit setzen
         WithLegacyAmino(codec.NewLegacyAmino()).
         WithAccountRetriever(authtypes.AccountRetriever{})
@@ -206,40 +534,90 @@ func (rps *RealPaymentService) initializeBlockchainClient() error {
     log.Printf("✅ Blockchain client initialized for payment verification")
     log.Printf("🔗 Connected to: %s (Chain: %s)", rps.rpcEndpoint, rps.chainID)
     
+    if rps.rest != nil {
+        ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        defer cancel()
+        if err := rps.rest.Health(ctx); err != nil {
+            log.Printf("⚠️ %v, it is retried when the REST fallback is needed", err)
+        } else {
+            log.Printf("🔗 REST fallback: %s", rps.rest.Endpoint())
+        }
+    }
+    
     return nil
 }
 
 // HTTP Handlers - ALLE ORIGINAL-HANDLER BEIBEHALTEN
 
+// chainInfo is the chain payments are verified on
+type chainInfo struct {
+	ChainID          string `json:"chain_id"`
+	RPCEndpoint      string `json:"rpc_endpoint"`
+	MinConfirmations int    `json:"min_confirmations"`
+}
+
+func (rps *RealPaymentService) chainInfo() chainInfo {
+	return chainInfo{ChainID: rps.chainID, RPCEndpoint: rps.rpcEndpoint, MinConfirmations: rps.minConfirmations}
+}
+
+// pricingResponse is the body of GET /pricing
+type pricingResponse struct {
+	PricingInfo            *compute.PricingInfo   `json:"pricing_info"`
+	AvailableMethods       []string               `json:"available_methods"`
+	VerificationLevels     []string               `json:"verification_levels"`
+	ServiceAddress         string                 `json:"service_address"`
+	CommunityAddress       string                 `json:"community_address"`
+	CommunityFeePercentage float64                `json:"community_fee_percentage"`
+	AcceptedTokens         []string               `json:"accepted_tokens"`
+	IBCDenoms              []compute.PaymentDenom `json:"ibc_denoms"`
+	BlockchainInfo         chainInfo              `json:"blockchain_info"`
+}
+
 // handleGetPricing returns comprehensive pricing information
 func (rps *RealPaymentService) handleGetPricing(w http.ResponseWriter, r *http.Request) {
-	pricingInfo := rps.pricingManager.GetPricingInfo()
-	
-	response := map[string]interface{}{
-		"pricing_info":     pricingInfo,
-		"available_methods": compute.GetAvailableMethods(),
-		"service_address":   rps.serviceAddr,
-		"community_address": rps.communityAddr,
-		"community_fee_percentage": rps.communityFee * 100,
-		"accepted_tokens": []string{"MEDAS", "umedas"},
-		"blockchain_info": map[string]interface{}{
-			"chain_id": rps.chainID,
-			"rpc_endpoint": rps.rpcEndpoint,
-			"min_confirmations": rps.minConfirmations,
-		},
+	response := pricingResponse{
+		PricingInfo:            rps.pricingManager.GetPricingInfo(),
+		AvailableMethods:       compute.GetAvailableMethods(),
+		VerificationLevels:     compute.GetVerificationLevels(),
+		ServiceAddress:         rps.serviceAddr,
+		CommunityAddress:       rps.communityAddr,
+		CommunityFeePercentage: rps.communityFee * 100,
+		AcceptedTokens:         []string{network.Current().DisplayDenom, network.Current().BaseDenom},
+		IBCDenoms:              rps.sortedPaymentDenoms(),
+		BlockchainInfo:         rps.chainInfo(),
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// priceEstimateRequest is the body of POST /pricing/estimate
+type priceEstimateRequest struct {
+	Digits       int                       `json:"digits"`
+	Method       string                    `json:"method"`
+	Tier         compute.ServiceTier       `json:"tier"`
+	Verification compute.VerificationLevel `json:"verification"`
+}
+
+// priceEstimateResponse is the body POST /pricing/estimate answers with
+type priceEstimateResponse struct {
+	PriceBreakdown *compute.PriceBreakdown    `json:"price_breakdown"`
+	MethodInfo     *compute.PICalculationInfo `json:"method_info"`
+	PaymentInfo    paymentInfo                `json:"payment_info"`
+}
+
+// paymentInfo tells where and how to pay for an estimated job
+type paymentInfo struct {
+	ServiceAddress   string          `json:"service_address"`
+	CommunityAddress string          `json:"community_address"`
+	MemoSuggested    string          `json:"memo_suggested"`
+	ChainID          string          `json:"chain_id"`
+	PaymentOptions   []paymentOption `json:"payment_options"`
+}
+
 // handleEstimatePrice estimates the cost for a computation job
 func (rps *RealPaymentService) handleEstimatePrice(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Digits int                 `json:"digits"`
-		Method string              `json:"method"`
-		Tier   compute.ServiceTier `json:"tier"`
-	}
+	var req priceEstimateRequest
 	
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
@@ -261,11 +639,19 @@ func (rps *RealPaymentService) handleEstimatePrice(w http.ResponseWriter, r *htt
 	}
 	
 	// Calculate price
-	breakdown, err := rps.pricingManager.CalculatePrice(req.Digits, req.Tier, req.Method)
+	breakdown, err := rps.pricingManager.CalculatePriceWithVerification(req.Digits, req.Tier, req.Method, req.Verification)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Price calculation failed: %v", err), http.StatusBadRequest)
 		return
 	}
+	// auto is priced as the method it picks
+	req.Method = breakdown.Method
+	
+	// An identical job is answered from the result cache at the cache price
+	params := map[string]interface{}{"digits": float64(req.Digits), "method": req.Method}
+	if quote, err := rps.jobManager.QuoteJob(compute.JobTypePICalculation, params, req.Tier, req.Verification); err == nil && quote.Cached {
+		breakdown = quote
+	}
 	
 	// Add method information
 	methodInfo := compute.GetMethodInfo(req.Digits)
@@ -277,14 +663,15 @@ func (rps *RealPaymentService) handleEstimatePrice(w http.ResponseWriter, r *htt
 		}
 	}
 	
-	response := map[string]interface{}{
-		"price_breakdown": breakdown,
-		"method_info":     selectedMethodInfo,
-		"payment_info": map[string]interface{}{
-			"service_address":   rps.serviceAddr,
-			"community_address": rps.communityAddr,
-			"memo_suggested":    fmt.Sprintf("PI calculation: %d digits", req.Digits),
-			"chain_id":          rps.chainID,
+	response := priceEstimateResponse{
+		PriceBreakdown: breakdown,
+		MethodInfo:     selectedMethodInfo,
+		PaymentInfo: paymentInfo{
+			ServiceAddress:   rps.serviceAddr,
+			CommunityAddress: rps.communityAddr,
+			MemoSuggested:    compute.PaymentMemo(req.Digits, req.Method, req.Tier, req.Verification),
+			ChainID:          rps.chainID,
+			PaymentOptions:   rps.paymentOptions(breakdown.TotalCost),
 		},
 	}
 	
@@ -292,12 +679,22 @@ func (rps *RealPaymentService) handleEstimatePrice(w http.ResponseWriter, r *htt
 	json.NewEncoder(w).Encode(response)
 }
 
+// tierCompareRequest is the body of POST /pricing/compare
+type tierCompareRequest struct {
+	Digits       int                       `json:"digits"`
+	Method       string                    `json:"method"`
+	Verification compute.VerificationLevel `json:"verification"`
+}
+
+// tierCompareResponse is the body POST /pricing/compare answers with
+type tierCompareResponse struct {
+	Comparisons     []compute.PriceBreakdown `json:"comparisons"`
+	RecommendedTier compute.ServiceTier      `json:"recommended_tier"`
+}
+
 // handleCompareTiers compares all service tiers for given parameters
 func (rps *RealPaymentService) handleCompareTiers(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Digits int    `json:"digits"`
-		Method string `json:"method"`
-	}
+	var req tierCompareRequest
 	
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
@@ -309,76 +706,177 @@ func (rps *RealPaymentService) handleCompareTiers(w http.ResponseWriter, r *http
 	}
 	
 	// Compare all tiers
-	comparisons, err := rps.pricingManager.CompareServiceTiers(req.Digits, req.Method)
+	comparisons, err := rps.pricingManager.CompareServiceTiers(req.Digits, req.Method, req.Verification)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Tier comparison failed: %v", err), http.StatusBadRequest)
 		return
 	}
 	
-	response := map[string]interface{}{
-		"comparisons": comparisons,
-		"recommended_tier": rps.pricingManager.GetTierForDigits(req.Digits),
+	response := tierCompareResponse{
+		Comparisons:     comparisons,
+		RecommendedTier: rps.pricingManager.GetTierForDigits(req.Digits),
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// submitJobRequest is the body of POST /jobs/submit
+type submitJobRequest struct {
+	Type          string                 `json:"type"`
+	Parameters    map[string]interface{} `json:"parameters"`
+	Tier          compute.ServiceTier    `json:"tier"`
+	Verification  compute.VerificationLevel `json:"verification"`
+	PaymentTxHash string                 `json:"payment_tx_hash"`
+	ClientAddress string                 `json:"client_address"`
+	Denom         string                 `json:"denom"` // optional, an accepted ibc/... denom
+	ClientJobID   string                 `json:"client_job_id"` // optional, same as the Idempotency-Key header
+	Subscription  bool                   `json:"subscription"` // draw from the prepaid quota instead of payment_tx_hash
+	SignedAt      time.Time              `json:"signed_at"` // required with subscription
+}
+
+// submitJobResponse is the body POST /jobs/submit answers with
+type submitJobResponse struct {
+	JobID                  string                    `json:"job_id"`
+	Status                 compute.JobStatus         `json:"status"`
+	SubmittedAt            time.Time                 `json:"submitted_at"`
+	PriceBreakdown         *compute.PriceBreakdown   `json:"price_breakdown"`
+	Verification           compute.VerificationLevel `json:"verification"`
+	Cache                  *compute.CacheProvenance  `json:"cache"`
+	BlockchainVerification *paymentVerification      `json:"blockchain_verification,omitempty"` // jobs paid by a transaction
+	Subscription           *subscriptionQuota        `json:"subscription,omitempty"`            // jobs paid from a subscription
+	Message                string                    `json:"message"`
+}
+
+type paymentVerification struct {
+	TxHash           string `json:"tx_hash"`
+	Status           string `json:"status"`
+	MinConfirmations int    `json:"min_confirmations"`
+}
+
 // handleSubmitJob submits a new computation job with payment verification
 func (rps *RealPaymentService) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Type          string                 `json:"type"`
-		Parameters    map[string]interface{} `json:"parameters"`
-		Tier          compute.ServiceTier    `json:"tier"`
-		PaymentTxHash string                 `json:"payment_tx_hash"`
-		ClientAddress string                 `json:"client_address"`
-	}
+	var req submitJobRequest
 	
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
 	
+	// Retries with the same key get the response of the first request
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if key == "" {
+		key = req.ClientJobID
+	} else if req.ClientJobID != "" && req.ClientJobID != key {
+		http.Error(w, "client_job_id and Idempotency-Key differ", http.StatusBadRequest)
+		return
+	}
+	if len(key) > maxIdempotencyKeyLen {
+		http.Error(w, fmt.Sprintf("Idempotency key longer than %d characters", maxIdempotencyKeyLen), http.StatusBadRequest)
+		return
+	}
+	if key != "" {
+		// Keys are per client, two clients may pick the same one
+		key = req.ClientAddress + "/" + key
+		replay, status, ok := rps.idempotency.Begin(key, body)
+		switch {
+		case !ok && status == http.StatusConflict:
+			http.Error(w, "A request with this idempotency key is still in progress", status)
+			return
+		case !ok:
+			http.Error(w, "Idempotency key was used for a different request", status)
+			return
+		case replay != nil:
+			replay.write(w)
+			return
+		}
+		rec := &responseRecorder{ResponseWriter: w}
+		defer rps.idempotency.Finish(key, rec)
+		w = rec
+	}
+	
 	// Validate required fields
 	if req.Type == "" {
 		http.Error(w, "Job type is required", http.StatusBadRequest)
 		return
 	}
 	
+	if req.ClientAddress == "" {
+		http.Error(w, "Client address is required", http.StatusBadRequest)
+		return
+	}
+	
+	if req.Subscription && req.PaymentTxHash == "" {
+		rps.submitSubscriptionJob(w, r, body, compute.JobType(req.Type), req.Parameters, req.ClientAddress, req.Tier, req.Verification, req.SignedAt)
+		return
+	}
+	
 	if req.PaymentTxHash == "" {
 		http.Error(w, "Payment transaction hash is required", http.StatusBadRequest)
 		return
 	}
 	
-	if req.ClientAddress == "" {
-		http.Error(w, "Client address is required", http.StatusBadRequest)
+	if req.Denom != "" && !rps.acceptsDenom(req.Denom) {
+		http.Error(w, fmt.Sprintf("Payments in %s are not accepted", req.Denom), http.StatusBadRequest)
+		return
+	}
+	
+	if rps.consumed.Claim(req.PaymentTxHash, 1, 1) == 0 {
+		http.Error(w, "Payment transaction was already used for a job", http.StatusConflict)
 		return
 	}
 	
 	// Convert type to JobType
 	jobType := compute.JobType(req.Type)
 	
-	// Submit job
-	job, err := rps.jobManager.SubmitJob(jobType, req.Parameters, req.ClientAddress, req.Tier, req.PaymentTxHash)
+	// Submit job within the limits of the client
+	var job *compute.ComputeJob
+	err = rps.admit(req.ClientAddress, 1, func() (float64, error) {
+		price, err := rps.jobManager.QuoteJob(jobType, req.Parameters, req.Tier, req.Verification)
+		if err != nil {
+			return 0, err
+		}
+		return price.TotalCost, nil
+	}, func() (err error) {
+		job, err = rps.jobManager.SubmitJob(jobType, req.Parameters, req.ClientAddress, req.Tier, req.Verification, req.PaymentTxHash)
+		return err
+	})
 	if err != nil {
+		rps.consumed.Release(req.PaymentTxHash, 1)
+		if limitErr, ok := asAccountLimitError(err); ok {
+			writeAccountLimitError(w, limitErr)
+			return
+		}
 		http.Error(w, fmt.Sprintf("Job submission failed: %v", err), http.StatusBadRequest)
 		return
 	}
+	rps.consumed.Bind(req.PaymentTxHash, job.ID)
+	job.PaymentDenom = req.Denom
+	
+	// A cached result is reported queued until the payment is verified
+	status := jobView(job).Status
 	
 	// Start payment verification in background
 	go rps.verifyAndStartJob(job)
 	
-	response := map[string]interface{}{
-		"job_id":        job.ID,
-		"status":        job.Status,
-		"submitted_at":  job.SubmittedAt,
-		"price_breakdown": job.PriceBreakdown,
-		"blockchain_verification": map[string]interface{}{
-			"tx_hash": req.PaymentTxHash,
-			"status": "pending",
-			"min_confirmations": rps.minConfirmations,
+	response := submitJobResponse{
+		JobID:          job.ID,
+		Status:         status,
+		SubmittedAt:    job.SubmittedAt,
+		PriceBreakdown: job.PriceBreakdown,
+		Verification:   job.Verification,
+		Cache:          job.Cache,
+		BlockchainVerification: &paymentVerification{
+			TxHash:           req.PaymentTxHash,
+			Status:           "pending",
+			MinConfirmations: rps.minConfirmations,
 		},
-		"message":       "Job submitted. Payment verification in progress...",
+		Message: "Job submitted. Payment verification in progress...",
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
@@ -389,19 +887,23 @@ func (rps *RealPaymentService) handleSubmitJob(w http.ResponseWriter, r *http.Re
 func (rps *RealPaymentService) verifyAndStartJob(job *compute.ComputeJob) {
 	log.Printf("🔍 Starting payment verification for job %s", job.ID)
 	
-	// Verify payment using the enhanced blockchain client
-	verified, err := rps.verifyPayment(job.PaymentTxHash, job.ClientAddr, job.PriceBreakdown.TotalCost)
+	check, err := rps.checkJobPayment(job)
+	if err == nil {
+		err = check.Err()
+	}
 	if err != nil {
 		log.Printf("❌ Payment verification failed for job %s: %v", job.ID, err)
-		job.Status = compute.StatusFailed
-		job.Error = fmt.Sprintf("Payment verification failed: %v", err)
-		return
-	}
-	
-	if !verified {
-		log.Printf("❌ Payment not verified for job %s", job.ID)
-		job.Status = compute.StatusFailed
-		job.Error = "Payment verification failed"
+		if check != nil && check.Underpaid() {
+			// The client's own transfer, it is returned like an underpaid memo payment
+			rps.queueRefund(job.PaymentTxHash, job.ClientAddr, receivedCoin(check), RefundUnderpaid, job.ID, "")
+		} else {
+			// Without a transfer from the client, e.g. the tx hash of someone
+			// else's payment, the payment stays usable for its sender
+			rps.consumed.Unbind(job.PaymentTxHash, job.ID)
+		}
+		if err := rps.jobManager.FailJob(job.ID, fmt.Sprintf("Payment verification failed: %v", err)); err != nil {
+			log.Printf("⚠️ Could not fail job %s: %v", job.ID, err)
+		}
 		return
 	}
 	
@@ -409,6 +911,11 @@ func (rps *RealPaymentService) verifyAndStartJob(job *compute.ComputeJob) {
 	
 	// Mark payment as verified
 	job.PaymentVerified = true
+	denom := job.PaymentDenom
+	if denom == "" {
+		denom = network.Current().BaseDenom
+	}
+	rps.ledger.Record(job, sdk.NewCoin(denom, rps.priceIn(denom, job.PriceBreakdown.TotalCost)), job.PriceBreakdown.TotalCost)
 	
 	// Distribute community fee (in background)
 	go rps.distributeCommunityFee(job)
@@ -418,6 +925,19 @@ func (rps *RealPaymentService) verifyAndStartJob(job *compute.ComputeJob) {
 	log.Printf("🚀 Job %s verified and queued for processing", job.ID)
 }
 
+// jobListResponse is the body of GET /jobs
+type jobListResponse struct {
+	Jobs    []*compute.ComputeJob `json:"jobs"`
+	Count   int                   `json:"count"`
+	Filters jobFilters            `json:"filters"`
+}
+
+type jobFilters struct {
+	ClientAddress string `json:"client_address"`
+	Status        string `json:"status"`
+	Limit         int    `json:"limit"`
+}
+
 // handleListJobs lists jobs with optional filtering
 func (rps *RealPaymentService) handleListJobs(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters - exakt wie original
@@ -438,15 +958,14 @@ func (rps *RealPaymentService) handleListJobs(w http.ResponseWriter, r *http.Req
     	status = compute.JobStatus(statusStr)
 	}
 	jobs := rps.jobManager.ListJobs(clientAddr, status)
+	for i, job := range jobs {
+		jobs[i] = jobView(job)
+	}
 	
-	response := map[string]interface{}{
-		"jobs":  jobs,
-		"count": len(jobs),
-		"filters": map[string]interface{}{
-			"client_address": clientAddr,
-			"status": statusStr,
-			"limit": limit,
-		},
+	response := jobListResponse{
+		Jobs:    jobs,
+		Count:   len(jobs),
+		Filters: jobFilters{ClientAddress: clientAddr, Status: statusStr, Limit: limit},
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
@@ -465,7 +984,14 @@ func (rps *RealPaymentService) handleGetJob(w http.ResponseWriter, r *http.Reque
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(job)
+	json.NewEncoder(w).Encode(jobView(job))
+}
+
+// cancelJobResponse is the body POST /jobs/{id}/cancel answers with
+type cancelJobResponse struct {
+	JobID     string    `json:"job_id"`
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
 // handleCancelJob cancels a job
@@ -479,78 +1005,121 @@ func (rps *RealPaymentService) handleCancelJob(w http.ResponseWriter, r *http.Re
 		return
 	}
 	
-	response := map[string]interface{}{
-		"job_id": jobID,
-		"status": "cancelled",
-		"timestamp": time.Now(),
-	}
+	response := cancelJobResponse{JobID: jobID, Status: "cancelled", Timestamp: time.Now()}
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// verifyPaymentRequest is the body of POST /payment/verify
+type verifyPaymentRequest struct {
+	TxHash        string  `json:"tx_hash"`
+	SenderAddr    string  `json:"sender_address"`
+	ExpectedAmount float64 `json:"expected_amount"`
+}
+
+// verifyPaymentResponse is the body POST /payment/verify answers with
+type verifyPaymentResponse struct {
+	Verified       bool      `json:"verified"`
+	TxHash         string    `json:"tx_hash"`
+	Expected       float64   `json:"expected"`
+	Received       float64   `json:"received"`
+	Denom          string    `json:"denom"`
+	Tolerance      float64   `json:"tolerance"`
+	Strict         bool      `json:"strict"`
+	Reason         string    `json:"reason,omitempty"` // why the payment is not verified
+	Timestamp      time.Time `json:"timestamp"`
+	BlockchainInfo chainInfo `json:"blockchain_info"`
+}
+
 // handleVerifyPayment manually verifies a payment
 func (rps *RealPaymentService) handleVerifyPayment(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		TxHash        string  `json:"tx_hash"`
-		SenderAddr    string  `json:"sender_address"`
-		ExpectedAmount float64 `json:"expected_amount"`
-	}
+	var req verifyPaymentRequest
 	
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
 	
-	verified, err := rps.verifyPayment(req.TxHash, req.SenderAddr, req.ExpectedAmount)
+	check, err := rps.checkPayment(r.Context(), req.TxHash, req.SenderAddr, network.Current().BaseDenom, req.ExpectedAmount)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Verification failed: %v", err), http.StatusInternalServerError)
 		return
 	}
 	
-	response := map[string]interface{}{
-		"verified":  verified,
-		"tx_hash":   req.TxHash,
-		"timestamp": time.Now(),
-		"blockchain_info": map[string]interface{}{
-			"chain_id": rps.chainID,
-			"min_confirmations": rps.minConfirmations,
-		},
+	response := verifyPaymentResponse{
+		Verified:       check.Verified,
+		TxHash:         req.TxHash,
+		Expected:       check.Expected,
+		Received:       check.Received,
+		Denom:          check.Denom,
+		Tolerance:      check.Tolerance,
+		Strict:         rps.strictPayments,
+		Timestamp:      time.Now(),
+		BlockchainInfo: rps.chainInfo(),
+	}
+	if err := check.Err(); err != nil {
+		response.Reason = err.Error()
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// serviceStatusResponse is the body of GET /status
+type serviceStatusResponse struct {
+	Service                string                `json:"service"`
+	Status                 string                `json:"status"`
+	ServiceAddress         string                `json:"service_address"`
+	CommunityAddress       string                `json:"community_address"`
+	CommunityFee           float64               `json:"community_fee" deprecated:"a fraction unlike the percentages elsewhere, use community_fee_percentage"`
+	CommunityFeePercentage float64               `json:"community_fee_percentage"`
+	Uptime                 string                `json:"uptime"`
+	QueueStatus            compute.QueueStatus   `json:"queue_status"`
+	Statistics             compute.JobStatistics `json:"statistics"`
+	Blockchain             blockchainStatus      `json:"blockchain"`
+}
+
+type blockchainStatus struct {
+	Status           string `json:"status"` // connected, disconnected or disabled
+	ChainID          string `json:"chain_id"`
+	RPCEndpoint      string `json:"rpc_endpoint"`
+	LatestBlock      int64  `json:"latest_block"`
+	MinConfirmations int    `json:"min_confirmations"`
+}
+
 // handleServiceStatus returns service status
 func (rps *RealPaymentService) handleServiceStatus(w http.ResponseWriter, r *http.Request) {
 	queueStatus := rps.jobManager.GetQueueStatus()
 	stats := rps.jobManager.GetStatistics()
 	
 	// Test blockchain connection using enhanced blockchain client
-	blockchainStatus := "connected"
+	chainStatus := "connected"
 	var latestBlock int64
-	if status, err := rps.blockchainClient.GetStatus(context.Background()); err != nil {
-		blockchainStatus = "disconnected"
+	if rps.blockchainClient == nil {
+		chainStatus = "disabled"
+	} else if status, err := rps.blockchainClient.GetStatus(r.Context()); err != nil {
+		chainStatus = "disconnected"
 	} else {
 		latestBlock = status.SyncInfo.LatestBlockHeight
 	}
 	
-	response := map[string]interface{}{
-		"service":         "MEDAS Payment Computing Service",
-		"status":          "running",
-		"service_address": rps.serviceAddr,
-		"community_address": rps.communityAddr,
-		"community_fee":   rps.communityFee,
-		"uptime":          time.Since(serviceStartTime).String(),
-		"queue_status":    queueStatus,
-		"statistics":      stats,
-		"blockchain": map[string]interface{}{
-			"status": blockchainStatus,
-			"chain_id": rps.chainID,
-			"rpc_endpoint": rps.rpcEndpoint,
-			"latest_block": latestBlock,
-			"min_confirmations": rps.minConfirmations,
+	response := serviceStatusResponse{
+		Service:                "MEDAS Payment Computing Service",
+		Status:                 "running",
+		ServiceAddress:         rps.serviceAddr,
+		CommunityAddress:       rps.communityAddr,
+		CommunityFee:           rps.communityFee,
+		CommunityFeePercentage: rps.communityFee * 100,
+		Uptime:                 time.Since(serviceStartTime).String(),
+		QueueStatus:            queueStatus,
+		Statistics:             stats,
+		Blockchain: blockchainStatus{
+			Status:           chainStatus,
+			ChainID:          rps.chainID,
+			RPCEndpoint:      rps.rpcEndpoint,
+			LatestBlock:      latestBlock,
+			MinConfirmations: rps.minConfirmations,
 		},
 	}
 	
@@ -574,23 +1143,112 @@ func (rps *RealPaymentService) handleQueueStatus(w http.ResponseWriter, r *http.
 	json.NewEncoder(w).Encode(queueStatus)
 }
 
-// handleCommunityStats returns community pool statistics
+// communityStatsResponse is the body of GET /community/stats
+type communityStatsResponse struct {
+	CommunityAddress string                   `json:"community_address"`
+	Balance          string                   `json:"balance"` // unknown when the chain is unreachable
+	Denom            string                   `json:"denom"`
+	FeePercentage    float64                  `json:"fee_percentage"`
+	Totals           communityTotals          `json:"totals"`
+	Reconciliation   reconciliationCounts     `json:"reconciliation"`
+	Distributions    []ReconciledDistribution `json:"distributions"`
+	BlockchainInfo   communityChainInfo       `json:"blockchain_info"`
+}
+
+type communityTotals struct {
+	ClaimedUmedas            int64  `json:"claimed_umedas"`
+	ConfirmedUmedas          int64  `json:"confirmed_umedas"`
+	UnsettledUmedas          int64  `json:"unsettled_umedas"`
+	OnchainFromServiceUmedas int64  `json:"onchain_from_service_umedas"`
+	OnchainFromOthersUmedas  int64  `json:"onchain_from_others_umedas"`
+	Claimed                  string `json:"claimed"`
+	OnchainFromService       string `json:"onchain_from_service"`
+}
+
+type reconciliationCounts struct {
+	Confirmed int    `json:"confirmed"`
+	Missing   int    `json:"missing"`
+	Untracked int    `json:"untracked"`
+	Window    string `json:"window"`
+}
+
+type communityChainInfo struct {
+	ChainID          string `json:"chain_id"`
+	Verified         bool   `json:"verified"`
+	HistoryVerified  bool   `json:"history_verified"`
+	TransfersScanned int    `json:"transfers_scanned"`
+}
+
+// handleCommunityStats returns community pool statistics together with the
+// distribution history reconciled against on-chain transfers to the pool
 func (rps *RealPaymentService) handleCommunityStats(w http.ResponseWriter, r *http.Request) {
 	// Get real community pool balance using enhanced blockchain client
-	balance, err := rps.getCommunityPoolBalance()
+	balance, err := rps.getCommunityPoolBalance(r.Context())
 	if err != nil {
 		log.Printf("Could not fetch community pool balance: %v", err)
 		balance = "unknown"
 	}
 	
-	response := map[string]interface{}{
-		"community_address": rps.communityAddr,
-		"balance": balance,
-		"denom": "umedas",
-		"fee_percentage": rps.communityFee * 100,
-		"blockchain_info": map[string]interface{}{
-			"chain_id": rps.chainID,
-			"verified": err == nil,
+	maxPages := 4
+	if p, perr := strconv.Atoi(r.URL.Query().Get("pages")); perr == nil && p > 0 && p <= 20 {
+		maxPages = p
+	}
+	
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	
+	local := rps.communityHistory.List()
+	transfers, historyErr := rps.fetchCommunityTransfers(ctx, maxPages)
+	if historyErr != nil {
+		log.Printf("Could not fetch community transfer history: %v", historyErr)
+	}
+	history := reconcileDistributions(local, transfers, rps.serviceAddr)
+	
+	var claimed, confirmed, fromService, fromOthers int64
+	counts := map[string]int{}
+	for _, d := range local {
+		claimed += d.AmountUmedas
+	}
+	for _, h := range history {
+		counts[h.Status]++
+		if h.Status == ReconcileConfirmed {
+			confirmed += h.AmountUmedas
+		}
+	}
+	for _, t := range transfers {
+		if t.Sender == rps.serviceAddr {
+			fromService += t.Amount.AmountOf(network.Current().BaseDenom).Int64()
+		} else {
+			fromOthers += t.Amount.AmountOf(network.Current().BaseDenom).Int64()
+		}
+	}
+	
+	response := communityStatsResponse{
+		CommunityAddress: rps.communityAddr,
+		Balance:          balance,
+		Denom:            network.Current().BaseDenom,
+		FeePercentage:    rps.communityFee * 100,
+		Totals: communityTotals{
+			ClaimedUmedas:            claimed,
+			ConfirmedUmedas:          confirmed,
+			UnsettledUmedas:          claimed - confirmed,
+			OnchainFromServiceUmedas: fromService,
+			OnchainFromOthersUmedas:  fromOthers,
+			Claimed:                  formatUmedas(claimed),
+			OnchainFromService:       formatUmedas(fromService),
+		},
+		Reconciliation: reconciliationCounts{
+			Confirmed: counts[ReconcileConfirmed],
+			Missing:   counts[ReconcileMissing],
+			Untracked: counts[ReconcileUntracked],
+			Window:    reconcileWindow.String(),
+		},
+		Distributions: history,
+		BlockchainInfo: communityChainInfo{
+			ChainID:          rps.chainID,
+			Verified:         err == nil,
+			HistoryVerified:  historyErr == nil,
+			TransfersScanned: len(transfers),
 		},
 	}
 	
@@ -600,56 +1258,80 @@ func (rps *RealPaymentService) handleCommunityStats(w http.ResponseWriter, r *ht
 
 // Background payment verification and job processing
 
-// verifyPayment verifies a blockchain payment transaction using enhanced blockchain client
-func (rps *RealPaymentService) verifyPayment(txHash, senderAddr string, expectedAmount float64) (bool, error) {
-	log.Printf("🔍 Verifying payment: tx=%s, sender=%s, amount=%.6f MEDAS", txHash, senderAddr, expectedAmount)
+// checkJobPayment compares the transaction a job was submitted with to its price
+func (rps *RealPaymentService) checkJobPayment(job *compute.ComputeJob) (*blockchain.PaymentCheck, error) {
+	denom := job.PaymentDenom
+	if denom == "" {
+		denom = network.Current().BaseDenom
+	}
+	if rps.checkPaymentFn != nil {
+		return rps.checkPaymentFn(job.PaymentTxHash, job.ClientAddr, denom, job.PriceBreakdown.TotalCost)
+	}
 	
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	
-	// KORREKTUR: expectedAmount ist in MEDAS, aber VerifyPaymentTransaction behandelt es fälschlicherweise als umedas
-	// Keine Konvertierung nötig - expectedAmount ist bereits korrekt in MEDAS
-	verified, err := rps.blockchainClient.VerifyPaymentTransaction(
-		ctx,
-		txHash,
-		senderAddr,
-		rps.serviceAddr,
-		expectedAmount, // Bleibt in MEDAS
-		"umedas",
-	)
-	
-	if err != nil {
-		log.Printf("❌ Blockchain verification failed: %v", err)
-		return false, err
+	check, err := rps.checkPayment(ctx, job.PaymentTxHash, job.ClientAddr, denom, job.PriceBreakdown.TotalCost)
+	if err != nil || !check.Verified {
+		return check, err
 	}
-	
-	if verified {
-		log.Printf("✅ Payment verification successful")
-		
-		// Additional confirmation check using enhanced client
-		if txResponse, err := rps.blockchainClient.GetTx(ctx, txHash); err == nil {
-			confirmations, err := rps.blockchainClient.GetTransactionConfirmations(ctx, txResponse.TxResponse.Height)
-			if err != nil {
-				log.Printf("⚠️ Could not check confirmations: %v", err)
-			} else if confirmations < int64(rps.minConfirmations) {
-				log.Printf("⚠️ Insufficient confirmations: %d (required: %d)", confirmations, rps.minConfirmations)
-				// For demo, accept anyway - in production you might want to wait
-			} else {
-				log.Printf("✅ Sufficient confirmations: %d", confirmations)
-			}
+	log.Printf("✅ Payment verification successful")
+	
+	// Additional confirmation check using enhanced client
+	if txResponse, err := rps.blockchainClient.GetTx(ctx, job.PaymentTxHash); err == nil {
+		confirmations, err := rps.blockchainClient.GetTransactionConfirmations(ctx, txResponse.TxResponse.Height)
+		if err != nil {
+			log.Printf("⚠️ Could not check confirmations: %v", err)
+		} else if confirmations < int64(rps.minConfirmations) {
+			log.Printf("⚠️ Insufficient confirmations: %d (required: %d)", confirmations, rps.minConfirmations)
+			// For demo, accept anyway - in production you might want to wait
+		} else {
+			log.Printf("✅ Sufficient confirmations: %d", confirmations)
 		}
 	}
+	return check, nil
+}
+
+// receivedCoin is the amount a payment check found, in base units
+func receivedCoin(check *blockchain.PaymentCheck) sdk.Coin {
+	if net := network.Current(); check.Denom == net.BaseDenom {
+		return sdk.NewInt64Coin(check.Denom, net.ToBase(check.Received))
+	}
+	amount, _ := big.NewFloat(math.Round(check.Received)).Int(nil)
+	return sdk.NewCoin(check.Denom, sdkmath.NewIntFromBigInt(amount))
+}
+
+// checkPayment compares a payment to the service address with a MEDAS price,
+// using the configured tolerance. IBC denoms are compared in their base units.
+func (rps *RealPaymentService) checkPayment(ctx context.Context, txHash, senderAddr, denom string, expectedMEDAS float64) (*blockchain.PaymentCheck, error) {
+	expected := expectedMEDAS
+	if denom != network.Current().BaseDenom {
+		expected = unitsFloat(rps.priceIn(denom, expectedMEDAS))
+	}
+	log.Printf("🔍 Verifying payment: tx=%s, sender=%s, amount=%.6f %s (%.6f MEDAS)", txHash, senderAddr, expected, denom, expectedMEDAS)
 	
-	return verified, nil
+	return rps.blockchainClient.CheckPayment(ctx, txHash, senderAddr, rps.serviceAddr, expected, denom, rps.tolerance())
+}
+
+// tolerance is the accepted relative difference between payment and price
+func (rps *RealPaymentService) tolerance() float64 {
+	if rps.strictPayments {
+		return 0
+	}
+	return rps.paymentTolerance
 }
 
 // getCommunityPoolBalance gets the real balance of the community pool address
-func (rps *RealPaymentService) getCommunityPoolBalance() (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (rps *RealPaymentService) getCommunityPoolBalance(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 	
 	// Use enhanced blockchain client to get balance
 	balances, err := rps.blockchainClient.GetAccountBalance(ctx, rps.communityAddr)
+	if err != nil && rps.rest != nil {
+		log.Printf("⚠️ RPC balance query failed, trying REST %s: %v", rps.rest.Endpoint(), err)
+		balances, err = rps.rest.Balances(ctx, rps.communityAddr)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to query balance: %w", err)
 	}
@@ -661,7 +1343,7 @@ func (rps *RealPaymentService) getCommunityPoolBalance() (string, error) {
 	}
 	
 	if len(balanceStrs) == 0 {
-		return "0 umedas", nil
+		return "0 " + network.Current().BaseDenom, nil
 	}
 	
 	return strings.Join(balanceStrs, ", "), nil
@@ -674,8 +1356,18 @@ func (rps *RealPaymentService) distributeCommunityFee(job *compute.ComputeJob) {
 	log.Printf("🏛️ Distributing community fee: %.6f MEDAS to %s", communityAmount, rps.communityAddr)
 	
 	// Convert amount to sdk.Coins
-	amountInt := int64(communityAmount * 1000000) // Convert to umedas (6 decimals)
-	coins := sdk.NewCoins(sdk.NewInt64Coin("umedas", amountInt))
+	net := network.Current()
+	amountInt := net.ToBase(communityAmount)
+	coins := sdk.NewCoins(sdk.NewInt64Coin(net.BaseDenom, amountInt))
+	
+	// Record the claim so it can be reconciled against on-chain transfers later
+	defer rps.communityHistory.Record(CommunityDistribution{
+		JobID:        job.ID,
+		PaymentTx:    job.PaymentTxHash,
+		AmountUmedas: amountInt,
+		Status:       DistributionSimulated,
+		CreatedAt:    time.Now(),
+	})
 	
 	// Create transaction using enhanced blockchain client
 	// NOTE: This would require the service to have signing capabilities
@@ -697,24 +1389,6 @@ func (rps *RealPaymentService) distributeCommunityFee(job *compute.ComputeJob) {
 	log.Printf("✅ Community fee distribution simulated successfully")
 }
 
-// Utility functions
-
-// corsMiddleware enables CORS for web client integration
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		
-		next.ServeHTTP(w, r)
-	})
-}
-
 // init initializes the payment service command
 func init() {
 	// Command flags - exakt wie original
@@ -725,6 +1399,21 @@ func init() {
 	realPaymentServiceCmd.Flags().Int("min-confirmations", 2, "Minimum blockchain confirmations required")
 	realPaymentServiceCmd.Flags().Int("max-jobs", 10, "Maximum concurrent jobs")
 	realPaymentServiceCmd.Flags().Int("workers", 4, "Number of worker threads")
+	realPaymentServiceCmd.Flags().Bool("watch-payments", true, "Create jobs from transfers with a COMPUTE_<TYPE>_<PARAMS> memo")
+	realPaymentServiceCmd.Flags().String("refund-from", "", "Key of the service address, enables automatic refunds")
+	realPaymentServiceCmd.Flags().String("keyring-backend", "", "Keyring backend (test|file|os, default client.keyring_backend)")
+	realPaymentServiceCmd.Flags().Int64("refund-fee", defaultRefundFee, "Network fee in base denom deducted from each refund")
+	realPaymentServiceCmd.Flags().String("admin-token", "", "Bearer token for the /api/v1/admin endpoints (disabled if empty)")
+	realPaymentServiceCmd.Flags().Float64("payment-tolerance", blockchain.DefaultPaymentTolerance*100, "Accepted difference between payment and price in percent, overrides payment.tolerance_percent")
+	realPaymentServiceCmd.Flags().Bool("strict-payments", false, "Require the exact price, overrides payment.strict")
+	realPaymentServiceCmd.Flags().Bool("calibrate", false, "Benchmark this host before starting and save the performance profile")
+	realPaymentServiceCmd.Flags().Int("memory-budget", 0, "Memory in MB for the products of a PI calculation, larger ones go to disk (0 = no limit), overrides payment.memory_budget_mb")
+	realPaymentServiceCmd.Flags().String("spill-dir", "", "Directory for products spilled to disk, overrides payment.spill_dir (default system temp dir)")
+	realPaymentServiceCmd.Flags().Duration("artifact-retention", compute.DefaultArtifactRetention, "Keep result files no job refers to for this long, overrides payment.artifact_retention")
+	realPaymentServiceCmd.Flags().String("pricing-file", "", "YAML file with tiers, surge pricing and fiat prices, reloaded when it changes, overrides payment.pricing_file")
+	realPaymentServiceCmd.Flags().Bool("result-cache", false, "Answer jobs identical to earlier ones from their results, overrides payment.result_cache.enabled")
+	realPaymentServiceCmd.Flags().Float64("cache-price-factor", compute.DefaultCachePriceFactor, "Share of the full price a cached result costs, overrides payment.result_cache.price_factor")
+	realPaymentServiceCmd.Flags().StringSlice("resume-from", nil, "Checkpoint of an interrupted PI job to continue, from ~/.medasdigital-client/checkpoints (repeatable)")
 	
 	// Required flags
 	realPaymentServiceCmd.MarkFlagRequired("service-address")
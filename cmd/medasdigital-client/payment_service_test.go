@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+)
+
+// newTestPaymentService returns a payment service with its stores in a
+// temporary directory and no chain connection
+func newTestPaymentService(t *testing.T) *RealPaymentService {
+	t.Helper()
+	homeDir = t.TempDir()
+	rps := NewRealPaymentService("medas1service", "medas1community", 0.15, 1, 2, 1)
+	t.Cleanup(func() { rps.jobManager.Shutdown(5 * time.Second) })
+	return rps
+}
+
+// submitJob posts a job paid with txHash and returns its ID
+func submitJob(t *testing.T, rps *RealPaymentService, txHash, client string) string {
+	t.Helper()
+	body := `{"type":"pi_calculation","parameters":{"digits":10},"tier":"basic",` +
+		`"payment_tx_hash":"` + txHash + `","client_address":"` + client + `"}`
+	rec := httptest.NewRecorder()
+	rps.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/jobs/submit", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("submit: %d %s", rec.Code, rec.Body.String())
+	}
+	var resp submitJobResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.JobID
+}
+
+// waitUntil polls cond until it holds or a few seconds passed
+func waitUntil(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestForeignPaymentTxHashStaysUsable(t *testing.T) {
+	rps := newTestPaymentService(t)
+	const txHash = "A1B2C3"
+	// The transaction pays the service, but not from the submitting client
+	rps.checkPaymentFn = func(txHash, senderAddr, denom string, expectedMEDAS float64) (*blockchain.PaymentCheck, error) {
+		return &blockchain.PaymentCheck{Expected: expectedMEDAS, Denom: denom}, nil
+	}
+
+	jobID := submitJob(t, rps, txHash, "medas1intruder")
+	waitUntil(t, "the claim is released", func() bool {
+		_, claimed := rps.consumed.Get(txHash)
+		return !claimed
+	})
+
+	// The real payer, e.g. through the memo watcher, can still use it
+	if n := rps.consumed.Claim(txHash, 1, 1); n != 1 {
+		t.Fatalf("payer claimed %d jobs, want 1", n)
+	}
+	if p, _ := rps.consumed.Get(txHash); len(p.JobIDs) != 0 {
+		t.Fatalf("payment still bound to %v, the failed job was %s", p.JobIDs, jobID)
+	}
+}
+
+func TestUnderpaidSubmittedPaymentIsRefunded(t *testing.T) {
+	rps := newTestPaymentService(t)
+	const txHash = "D4E5F6"
+	rps.checkPaymentFn = func(txHash, senderAddr, denom string, expectedMEDAS float64) (*blockchain.PaymentCheck, error) {
+		return &blockchain.PaymentCheck{Expected: expectedMEDAS, Received: expectedMEDAS / 2, Denom: denom}, nil
+	}
+
+	jobID := submitJob(t, rps, txHash, "medas1payer")
+	waitUntil(t, "the payment is refunded", func() bool {
+		return len(rps.refunds.List("", false)) > 0
+	})
+
+	r := rps.refunds.List("", false)[0]
+	if r.Reason != RefundUnderpaid || r.PaymentTx != txHash || r.Recipient != "medas1payer" || r.JobID != jobID {
+		t.Fatalf("unexpected refund %+v", r)
+	}
+	waitUntil(t, "the job failed", func() bool {
+		job, err := rps.jobManager.GetJob(jobID)
+		return err == nil && job.Status == compute.StatusFailed
+	})
+	// The payer's own transfer stays used up
+	if n := rps.consumed.Claim(txHash, 1, 1); n != 0 {
+		t.Fatalf("underpaid payment claimed again for %d jobs", n)
+	}
+}
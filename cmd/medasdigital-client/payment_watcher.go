@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/gorilla/mux"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+)
+
+// runPaymentWatcher creates jobs for transfers to the service address whose memo
+// is COMPUTE_<TYPE>_<PARAMS>, so clients only have to send the payment
+func (rps *RealPaymentService) runPaymentWatcher(ctx context.Context) {
+	since := time.Now()
+	backoff := time.Second
+
+	for {
+		// Transfers committed while the subscription was down
+		rps.catchUpPayments(ctx, since)
+
+		err := rps.blockchainClient.WatchIncomingTransfers(ctx, rps.serviceAddr, rps.handlePayment)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("⚠️ Payment watcher disconnected: %v, reconnecting in %v", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > time.Minute {
+			backoff = time.Minute
+		}
+	}
+}
+
+// catchUpPayments handles recent transfers, payments from before the service started are ignored
+func (rps *RealPaymentService) catchUpPayments(ctx context.Context, since time.Time) {
+	transfers, _, err := rps.blockchainClient.GetIncomingTransfers(ctx, rps.serviceAddr, 1, 50)
+	if err != nil {
+		log.Printf("⚠️ Could not fetch recent payments: %v", err)
+		return
+	}
+	for i := len(transfers) - 1; i >= 0; i-- {
+		if transfers[i].Time.Before(since) {
+			continue
+		}
+		rps.handlePayment(transfers[i])
+	}
+}
+
+// handlePayment turns a transfer with an invoice or COMPUTE_ memo into paid
+// jobs, one unless the memo bundles several with X<N>
+func (rps *RealPaymentService) handlePayment(t blockchain.Transfer) {
+	if inv, ok := rps.invoices.ByMemo(t.Memo); ok {
+		rps.handleInvoicePayment(t, inv)
+		return
+	}
+	if compute.IsSubscriptionMemo(t.Memo) {
+		rps.handleSubscriptionPayment(t)
+		return
+	}
+	if !compute.IsPaymentMemo(t.Memo) {
+		return
+	}
+
+	memoJob, err := compute.ParsePaymentMemo(t.Memo)
+	if err != nil {
+		// Claimed anyway, so catch-up does not report it again
+		if rps.consumed.Claim(t.TxHash, 1, 1) > 0 {
+			log.Printf("❌ Payment %s from %s has an invalid memo %q: %v", t.TxHash, t.Sender, t.Memo, err)
+			rps.refundRejected(t, "")
+		}
+		return
+	}
+	// The same transfer may arrive via catch-up and the subscription, and
+	// jobs/submit may have used part of a bundle already
+	count := rps.consumed.Claim(t.TxHash, memoJob.Count, memoJob.Count)
+	if count == 0 {
+		return
+	}
+
+	// Quoted like the job, a cached result costs the cache price
+	price, err := rps.jobManager.QuoteJob(memoJob.Type, memoJob.Parameters, memoJob.Tier, memoJob.Verification)
+	if err != nil {
+		log.Printf("❌ Payment %s: pricing failed: %v", t.TxHash, err)
+		rps.refundRejected(t, "")
+		return
+	}
+
+	net := network.Current()
+	coin, paid, ok := rps.paymentCoin(t.Amount)
+	if !ok {
+		log.Printf("❌ Payment %s from %s: %s is not an accepted payment denom", t.TxHash, t.Sender, t.Amount)
+		rps.refundRejected(t, "")
+		return
+	}
+	// Each job of a bundle is booked with its share of the payment, refunds
+	// leave out the shares of jobs created by jobs/submit
+	bundle := memoJob.Count
+	share := sdk.NewCoin(coin.Denom, coin.Amount.QuoRaw(int64(bundle)))
+	claimedCoin := coin
+	if count < bundle {
+		claimedCoin = sdk.NewCoin(coin.Denom, share.Amount.MulRaw(int64(count)))
+	}
+	if coin.Amount.LT(rps.minimumPayment(coin.Denom, float64(bundle)*price.TotalCost)) {
+		log.Printf("❌ Payment %s from %s: %s (%.6f %s) paid, %.6f required for %s", t.TxHash, t.Sender, coin, paid, net.DisplayDenom, float64(bundle)*price.TotalCost, t.Memo)
+		rps.queueRefund(t.TxHash, t.Sender, claimedCoin, RefundUnderpaid, "", "")
+		return
+	}
+
+	var jobs []*compute.ComputeJob
+	err = rps.admit(t.Sender, count, func() (float64, error) { return float64(count) * price.TotalCost, nil }, func() error {
+		for i := 0; i < count; i++ {
+			job, err := rps.jobManager.SubmitJob(memoJob.Type, memoJob.Parameters, t.Sender, memoJob.Tier, memoJob.Verification, t.TxHash)
+			if err != nil {
+				log.Printf("❌ Payment %s: job %d/%d submission failed: %v", t.TxHash, i+1, count, err)
+				rps.queueRefund(t.TxHash, t.Sender, sdk.NewCoin(coin.Denom, share.Amount.MulRaw(int64(count-i))), RefundJobFailed, "", "")
+				break
+			}
+			// The transfer was read from a committed block, no separate verification needed
+			job.PaymentVerified = true
+			job.PaymentDenom = coin.Denom
+			rps.consumed.Bind(t.TxHash, job.ID)
+			rps.ledger.Record(job, share, paid/float64(bundle))
+			jobs = append(jobs, job)
+		}
+		return nil
+	})
+	if limitErr, ok := asAccountLimitError(err); ok {
+		log.Printf("🚦 Payment %s from %s refunded: %v", t.TxHash, t.Sender, limitErr)
+		rps.queueRefund(t.TxHash, t.Sender, claimedCoin, RefundLimitExceeded, "", "")
+		return
+	}
+	if len(jobs) == 0 {
+		return
+	}
+
+	log.Printf("💸 Payment %s from %s (%s, %.6f %s) created %d job(s) starting with %s", t.TxHash, t.Sender, coin, paid, net.DisplayDenom, len(jobs), jobs[0].ID)
+	if excess := coin.Amount.Sub(rps.priceIn(coin.Denom, price.TotalCost).MulRaw(int64(bundle))); excess.GT(rps.refundFeeIn(coin.Denom)) {
+		rps.queueRefund(t.TxHash, t.Sender, sdk.NewCoin(coin.Denom, excess), RefundOverpaid, jobs[0].ID, "")
+	}
+	for _, job := range jobs {
+		go rps.distributeCommunityFee(job)
+	}
+}
+
+// paymentLookupError is the v1 body of a 404 of GET /payments/{tx_hash}, v2
+// answers with an apiError
+type paymentLookupError struct {
+	TxHash string `json:"tx_hash"`
+	Error  string `json:"error"`
+}
+
+// handlePaymentLookup returns the job created for a payment
+func (rps *RealPaymentService) handlePaymentLookup(w http.ResponseWriter, r *http.Request) {
+	txHash := mux.Vars(r)["tx_hash"]
+
+	// The first job of a bundle payment
+	payment, seen := rps.consumed.Get(txHash)
+
+	if !seen || len(payment.JobIDs) == 0 {
+		if apiVersionOf(r) >= apiV2 {
+			writeAPIError(w, r, "no job for this payment (yet)", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(paymentLookupError{TxHash: txHash, Error: "no job for this payment (yet)"})
+		return
+	}
+
+	job, err := rps.jobManager.GetJob(payment.JobIDs[0])
+	if err != nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobView(job))
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	comethttp "github.com/cometbft/cometbft/rpc/client/http"
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/peers"
+)
+
+// peersCmd discovers other registered clients
+var peersCmd = &cobra.Command{
+	Use:   "peers",
+	Short: "Discover other registered clients on chain",
+}
+
+// peersListCmd lists the indexed clients, syncing first
+var peersListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List registered clients, optionally by capability",
+	Long: `List the clients that registered with MEDAS_CLIENT_REG, MEDAS_SIMPLE_REG or
+MEDAS_CHAT_REG memos. The index is kept in ~/.medasdigital-client/peers and
+only the blocks after the last scanned height are read on every run.
+
+  medasdigital-client peers list --capability orbital_dynamics
+  medasdigital-client peers list --type chat --offline`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		capability, _ := cmd.Flags().GetString("capability")
+		regType, _ := cmd.Flags().GetString("type")
+		offline, _ := cmd.Flags().GetBool("offline")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		if regType != "" && regType != "simple" && regType != "chat" {
+			return fmt.Errorf("invalid --type %q (simple or chat)", regType)
+		}
+
+		cfg := loadConfig()
+		idx, err := peers.LoadIndex(homeDir, cfg.Chain.ID)
+		if err != nil {
+			return err
+		}
+		if !offline {
+			if err := syncPeers(cmd.Context(), idx, cfg.Chain.RPCEndpoint, peerSyncOptions(cmd), !asJSON); err != nil {
+				if idx.LastHeight == 0 {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "⚠️  Sync failed, showing index up to height %d: %v\n", idx.LastHeight, err)
+			}
+		}
+
+		list := idx.Filter(capability, regType)
+		if asJSON {
+			data, err := json.MarshalIndent(list, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		if len(list) == 0 {
+			fmt.Println("No registered clients found")
+			return nil
+		}
+		fmt.Printf("%-16s %-7s %-46s %-8s %s\n", "CLIENT ID", "TYPE", "ADDRESS", "HEIGHT", "CAPABILITIES")
+		for _, p := range list {
+			fmt.Printf("%-16s %-7s %-46s %-8d %s\n", p.ClientID, p.Type, p.Address, p.Height, strings.Join(p.Capabilities, ","))
+			if p.DisplayName != "" {
+				fmt.Printf("%16s name: %s\n", "", p.DisplayName)
+			}
+			for _, e := range p.Endpoints {
+				fmt.Printf("%16s endpoint: %s\n", "", e)
+			}
+		}
+		fmt.Printf("\n%d client(s), index at height %d\n", len(list), idx.LastHeight)
+		return nil
+	},
+}
+
+// peersSyncCmd updates the index without listing
+var peersSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Scan new blocks for client registrations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reset, _ := cmd.Flags().GetBool("reset")
+
+		cfg := loadConfig()
+		idx, err := peers.LoadIndex(homeDir, cfg.Chain.ID)
+		if err != nil {
+			return err
+		}
+		if reset {
+			idx.Reset()
+		}
+		if err := syncPeers(cmd.Context(), idx, cfg.Chain.RPCEndpoint, peerSyncOptions(cmd), true); err != nil {
+			return err
+		}
+		fmt.Printf("✅ %d client(s) indexed up to height %d\n", len(idx.Peers), idx.LastHeight)
+		return nil
+	},
+}
+
+// peersMalformedCmd lists the registrations the schemas rejected
+var peersMalformedCmd = &cobra.Command{
+	Use:   "malformed",
+	Short: "List registration memos that violate the registration schemas",
+	Long: `List the transactions whose registration memo was rejected during a scan,
+with the reason. Their senders are not listed as clients. Uses the cached index.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		cfg := loadConfig()
+		idx, err := peers.LoadIndex(homeDir, cfg.Chain.ID)
+		if err != nil {
+			return err
+		}
+		list := idx.MalformedList()
+		if asJSON {
+			data, err := json.MarshalIndent(list, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		if len(list) == 0 {
+			fmt.Printf("No malformed registrations up to height %d\n", idx.LastHeight)
+			return nil
+		}
+		for _, m := range list {
+			fmt.Printf("%-8d %s %s\n", m.Height, m.TxHash, m.Address)
+			fmt.Printf("%8s %s\n", "", m.Reason)
+		}
+		fmt.Printf("\n%d malformed registration(s)\n", len(list))
+		return nil
+	},
+}
+
+// peerSyncOptions reads --from-height and --max-blocks
+func peerSyncOptions(cmd *cobra.Command) peers.SyncOptions {
+	fromHeight, _ := cmd.Flags().GetInt64("from-height")
+	maxBlocks, _ := cmd.Flags().GetInt64("max-blocks")
+	return peers.SyncOptions{FromHeight: fromHeight, MaxBlocks: maxBlocks}
+}
+
+// syncPeers scans the chain into idx and saves it, also when the scan stopped early
+func syncPeers(ctx context.Context, idx *peers.Index, rpcEndpoint string, opts peers.SyncOptions, verbose bool) error {
+	client, err := comethttp.New(rpcEndpoint, "/websocket")
+	if err != nil {
+		return fmt.Errorf("failed to create RPC client: %w", err)
+	}
+
+	if verbose {
+		opts.Progress = func(height, latest int64) {
+			fmt.Fprintf(os.Stderr, "\r🔍 Scanning block %d / %d", height, latest)
+		}
+	}
+	result, syncErr := idx.Sync(ctx, client, currentApp().Codec(), opts)
+	if verbose && result != nil && result.To >= result.From {
+		fmt.Fprintln(os.Stderr)
+	}
+	if result != nil {
+		if err := idx.Save(); err != nil {
+			return err
+		}
+	}
+	if syncErr != nil {
+		return syncErr
+	}
+	if verbose && result.Malformed > 0 {
+		fmt.Fprintf(os.Stderr, "⚠️  Skipped %d malformed registration(s), see 'peers malformed'\n", result.Malformed)
+	}
+	if verbose && result.To < result.Latest {
+		fmt.Fprintf(os.Stderr, "⏳ Stopped at height %d of %d, run again to continue\n", result.To, result.Latest)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(peersCmd)
+	peersCmd.AddCommand(peersListCmd)
+	peersCmd.AddCommand(peersSyncCmd)
+	peersCmd.AddCommand(peersMalformedCmd)
+
+	peersCmd.PersistentFlags().Int64("from-height", 0, "Scan from this height instead of the last scanned one")
+	peersCmd.PersistentFlags().Int64("max-blocks", 0, "Scan at most this many blocks per run (0 = up to the latest block)")
+
+	peersListCmd.Flags().String("capability", "", "Only clients with this capability")
+	peersListCmd.Flags().String("type", "", "Only simple or chat registrations")
+	peersListCmd.Flags().Bool("offline", false, "Use the cached index without syncing")
+	peersListCmd.Flags().Bool("json", false, "Print the clients as JSON")
+
+	peersSyncCmd.Flags().Bool("reset", false, "Drop the index and scan from the start")
+
+	peersMalformedCmd.Flags().Bool("json", false, "Print the registrations as JSON")
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+)
+
+// piMethodInfo is a registered PI method as reported for a precision
+type piMethodInfo struct {
+	compute.MethodSpec
+	Suitable bool   `json:"suitable"`
+	Estimate string `json:"estimated_time,omitempty"`
+}
+
+// piMethodsReport lists the PI methods for a precision and the one auto picks
+type piMethodsReport struct {
+	Digits  int            `json:"digits"`
+	Auto    string         `json:"auto"`
+	Methods []piMethodInfo `json:"methods"`
+}
+
+// piPricing estimates with the calibrated profile of this host, if any
+func piPricing() *compute.PricingManager {
+	pricing := compute.NewPricingManager("")
+	pricing.SetPerformanceProfile(loadPerformanceProfile())
+	return pricing
+}
+
+// resolvePIMethod validates method for digits and turns auto into a method
+func resolvePIMethod(method string, digits int) (string, error) {
+	resolved, err := piPricing().ResolveMethod(method, digits)
+	if err != nil {
+		return "", err
+	}
+	return string(resolved), nil
+}
+
+// describePIMethods reports every method for digits
+func describePIMethods(digits int) (*piMethodsReport, error) {
+	pricing := piPricing()
+	auto, err := pricing.ResolveMethod(string(compute.MethodAuto), digits)
+	if err != nil {
+		return nil, err
+	}
+	report := &piMethodsReport{Digits: digits, Auto: string(auto)}
+	for _, m := range compute.PIMethods() {
+		info := piMethodInfo{MethodSpec: m, Suitable: m.Suits(digits)}
+		if info.Suitable {
+			info.Estimate = pricing.EstimateResourceUsage(digits, string(m.Name)).EstimatedTime.Round(time.Millisecond).String()
+		}
+		report.Methods = append(report.Methods, info)
+	}
+	return report, nil
+}
+
+// piMethodsCmd lists the PI methods and what auto picks
+var piMethodsCmd = &cobra.Command{
+	Use:   "methods [digits]",
+	Short: "List the PI calculation methods",
+	Long: `List the PI calculation methods with the precisions they suit and their
+estimated runtime on this host. The method --method auto picks for the digits
+(default 1000) is marked; estimates use the profile of payment-service calibrate
+when the host was calibrated.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		digits := 1000
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid digits: %s (must be positive integer)", args[0])
+			}
+			digits = n
+		}
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		report, err := describePIMethods(digits)
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(report)
+		}
+
+		fmt.Printf("🧮 PI methods for %d digits\n", digits)
+		for _, m := range report.Methods {
+			mark := " "
+			if string(m.Name) == report.Auto {
+				mark = "*"
+			}
+			estimate := "unsuitable"
+			if m.Suitable {
+				estimate = "~" + m.Estimate
+			}
+			fmt.Printf(" %s %-11s %d-%d digits, %s\n", mark, m.Name, m.MinDigits, m.MaxDigits, estimate)
+			fmt.Printf("   %-11s %s\n", "", m.Description)
+		}
+		fmt.Printf("\n* picked by --method %s\n", compute.MethodAuto)
+		return nil
+	},
+}
+
+func init() {
+	piCmd.AddCommand(piMethodsCmd)
+	piMethodsCmd.Flags().Bool("json", false, "Print the methods as JSON")
+}
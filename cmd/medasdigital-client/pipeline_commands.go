@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/inference"
+	"github.com/oxygene76/medasdigital-client/pkg/preprocess"
+	"github.com/oxygene76/medasdigital-client/pkg/workflow"
+)
+
+// pipelineCmd groups the survey processing stages
+var pipelineCmd = &cobra.Command{
+	Use:   "pipeline",
+	Short: "Survey image processing pipeline",
+	Long:  "Prepare survey images for object detection and run processing stages.",
+}
+
+// pipelinePreprocessCmd calibrates raw frames for ai detect
+var pipelinePreprocessCmd = &cobra.Command{
+	Use:   "preprocess <images>",
+	Short: "Calibrate, plate solve and template-subtract raw frames",
+	Long: `Turn raw survey frames into calibrated, differenced images for 'ai detect':
+
+  1. subtract the median-combined master bias (--bias)
+  2. divide by the master flat, bias subtracted and normalized (--flat)
+  3. plate solve with astrometry.net's solve-field or a compatible command
+     (--solve); a <name>.wcs file next to a frame is used instead
+  4. subtract the reference image (--template) resampled onto each frame
+     through both WCS and scaled to its flux
+
+Images, --bias and --flat take a directory, glob pattern or comma separated
+list of FITS files. Results are written to --output-dir as <name>_cal.fits
+and, with a template, <name>_diff.fits, together with master_bias.fits and
+master_flat.fits.
+
+  medasdigital-client pipeline preprocess ./raw --bias ./bias --flat ./flats \
+    --solve --scale-low 0.2 --scale-high 0.4 --template ref.fits
+  medasdigital-client ai detect model.onnx 'preprocessed/*_diff.fits'`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		biasSpec, _ := cmd.Flags().GetString("bias")
+		flatSpec, _ := cmd.Flags().GetString("flat")
+		templatePath, _ := cmd.Flags().GetString("template")
+		solve, _ := cmd.Flags().GetBool("solve")
+		outDir, _ := cmd.Flags().GetString("output-dir")
+		solver := preprocess.Solver{}
+		solver.Command, _ = cmd.Flags().GetString("solver")
+		solver.ScaleLow, _ = cmd.Flags().GetFloat64("scale-low")
+		solver.ScaleHigh, _ = cmd.Flags().GetFloat64("scale-high")
+		solver.Timeout, _ = cmd.Flags().GetDuration("solve-timeout")
+
+		frames, err := collectFITS(args[0])
+		if err != nil {
+			return err
+		}
+		if solve && !solver.Available() {
+			return fmt.Errorf("plate solver %q not found, install astrometry.net or set --solver", solver.Command)
+		}
+		if err := os.MkdirAll(outDir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", outDir, err)
+		}
+
+		var bias, flat *preprocess.Image
+		if biasSpec != "" {
+			files, err := collectFITS(biasSpec)
+			if err != nil {
+				return err
+			}
+			if bias, err = preprocess.MasterBias(files); err != nil {
+				return err
+			}
+			if err := bias.Write(filepath.Join(outDir, "master_bias.fits")); err != nil {
+				return err
+			}
+			fmt.Printf("Master bias from %d frames\n", len(files))
+		}
+		if flatSpec != "" {
+			files, err := collectFITS(flatSpec)
+			if err != nil {
+				return err
+			}
+			if flat, err = preprocess.MasterFlat(files, bias); err != nil {
+				return err
+			}
+			if err := flat.Write(filepath.Join(outDir, "master_flat.fits")); err != nil {
+				return err
+			}
+			fmt.Printf("Master flat from %d frames\n", len(files))
+		}
+
+		ctx := cmd.Context()
+
+		var template *preprocess.Image
+		if templatePath != "" {
+			if template, err = preprocess.ReadImage(templatePath); err != nil {
+				return err
+			}
+			if template.WCS() == nil && solve {
+				if err := solver.Solve(ctx, templatePath, template); err != nil {
+					return fmt.Errorf("template: %w", err)
+				}
+			}
+			if template.WCS() == nil {
+				return fmt.Errorf("template %s has no TAN WCS, plate solve it or use --solve", templatePath)
+			}
+		}
+
+		fmt.Printf("\n%-24s %-10s %-8s %s\n", "Frame", "WCS", "Scale", "Output")
+		fmt.Println(strings.Repeat("-", 80))
+		failed := 0
+		for _, path := range frames {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			out, wcs, scale, err := preprocessFrame(ctx, path, bias, flat, template, solve, solver, outDir)
+			if err != nil {
+				failed++
+				fmt.Printf("%-24s %s\n", shortImageName(filepath.Base(path)), err)
+				continue
+			}
+			fmt.Printf("%-24s %-10s %-8s %s\n", shortImageName(filepath.Base(path)), wcs, scale, out)
+		}
+		fmt.Printf("\nPreprocessed %d of %d frames into %s\n", len(frames)-failed, len(frames), outDir)
+		if failed == len(frames) {
+			return fmt.Errorf("no frame could be preprocessed")
+		}
+		return nil
+	},
+}
+
+// preprocessFrame runs the stages on one frame and returns the file for
+// ai detect with a short WCS and template scale summary
+func preprocessFrame(ctx context.Context, path string, bias, flat, template *preprocess.Image,
+	solve bool, solver preprocess.Solver, outDir string) (string, string, string, error) {
+	img, err := preprocess.ReadImage(path)
+	if err != nil {
+		return "", "", "", err
+	}
+	if err := preprocess.Calibrate(img, bias, flat); err != nil {
+		return "", "", "", err
+	}
+	wcs := "header"
+	if solve {
+		if err := solver.Solve(ctx, path, img); err != nil {
+			return "", "", "", err
+		}
+		wcs = "solved"
+	}
+	if img.WCS() == nil {
+		wcs = "none"
+	}
+
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	out := filepath.Join(outDir, base+"_cal.fits")
+	if err := img.Write(out); err != nil {
+		return "", "", "", err
+	}
+	if template == nil {
+		return out, wcs, "-", nil
+	}
+	if wcs == "none" {
+		return "", "", "", fmt.Errorf("no WCS, template subtraction needs --solve")
+	}
+	diff, scale, err := preprocess.Subtract(img, template)
+	if err != nil {
+		return "", "", "", err
+	}
+	out = filepath.Join(outDir, base+"_diff.fits")
+	if err := diff.Write(out); err != nil {
+		return "", "", "", err
+	}
+	return out, wcs, fmt.Sprintf("%.3f", scale), nil
+}
+
+// pipelineRunCmd runs a workflow file
+var pipelineRunCmd = &cobra.Command{
+	Use:   "run <workflow.yaml>",
+	Short: "Run a batch analysis workflow",
+	Long: `Run the steps of a workflow file in order, each as a client command:
+
+  name: night-2026-09-20
+  workdir: runs/2026-09-20
+  parallelism: 4
+  retries: 1
+  steps:
+    - name: preprocess
+      uses: preprocess
+      foreach: raw/field*
+      args: ["{item}"]
+      params: {bias: bias/, flat: flats/, solve: true, template: "refs/{stem}.fits", output-dir: "{workdir}/{stem}"}
+    - name: detect
+      uses: detect
+      foreach: raw/field*
+      args: [models/tno.onnx, "{workdir}/{stem}/*_diff.fits"]
+      params: {output: "{workdir}/{stem}/detections.json", threshold: 0.6}
+      retries: 2
+    - name: link
+      uses: link
+      foreach: raw/field*
+      args: ["{workdir}/{stem}/detections.json"]
+      params: {csv: "{workdir}/{stem}/candidates.csv"}
+    - name: orbit-fit
+      uses: orbit-fit
+      foreach: raw/field*
+      args: ["{workdir}/{stem}/candidates.csv"]
+    - name: store
+      uses: store
+      foreach: raw/field*
+      args: ["{workdir}/{stem}/detections_tracklets.json"]
+      params: {type: tracklet_linking}
+
+Step types: preprocess, detect, crossmatch, link, orbit-fit (analyze
+orbital-dynamics), store (results store) and command, which runs any client
+command given as command: [...]. params become --flags. A step with foreach
+runs once per matching file, up to parallelism at a time, where {item} is
+the file and {stem} its name without extension. Failed commands are retried
+with retries and retry_delay, per step commands can have a timeout.
+
+Finished commands are recorded in <workdir>/state.json: running the workflow
+again resumes after the last finished command. Changed params rerun a step and
+every step after it. --restart ignores the state, --from reruns from a step.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		wf, err := workflow.Load(args[0])
+		if err != nil {
+			return err
+		}
+		run, err := clientCommand()
+		if err != nil {
+			return err
+		}
+		runner := &workflow.Runner{Exec: run, Out: os.Stdout}
+		runner.Restart, _ = cmd.Flags().GetBool("restart")
+		runner.From, _ = cmd.Flags().GetString("from")
+		runner.DryRun, _ = cmd.Flags().GetBool("dry-run")
+
+		ctx := cmd.Context()
+		fmt.Printf("Workflow %s (%d steps, state in %s)\n", wf.Name, len(wf.Steps), wf.StatePath())
+		started := time.Now()
+		if err := runner.Run(ctx, wf); err != nil {
+			return fmt.Errorf("workflow %s stopped: %w; run it again to resume", wf.Name, err)
+		}
+		fmt.Printf("Workflow %s finished in %s\n", wf.Name, time.Since(started).Round(time.Second))
+		return nil
+	},
+}
+
+// clientCommand returns a function running this client binary as a child
+// process, with the same config, home and network as this run
+func clientCommand() (func(ctx context.Context, dir string, args []string, out io.Writer) error, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("cannot locate the client binary: %w", err)
+	}
+	var global []string
+	for _, f := range []struct{ name, value string }{
+		{"config", cfgFile}, {"home", homeDir}, {"network", networkFlag}, {"chain-id", chainIDFlag}, {"node", nodeFlag},
+	} {
+		if f.value != "" {
+			global = append(global, "--"+f.name+"="+f.value)
+		}
+	}
+	return func(ctx context.Context, dir string, args []string, out io.Writer) error {
+		c := exec.CommandContext(ctx, self, append(append([]string(nil), args...), global...)...)
+		c.Dir = dir
+		c.Stdout = out
+		c.Stderr = out
+		return c.Run()
+	}, nil
+}
+
+// collectFITS expands a directory, glob or list into FITS files
+func collectFITS(spec string) ([]string, error) {
+	files, err := inference.CollectImages(spec)
+	if err != nil {
+		return nil, err
+	}
+	var fits []string
+	for _, f := range files {
+		switch strings.ToLower(filepath.Ext(f)) {
+		case ".fits", ".fit", ".fts":
+			fits = append(fits, f)
+		}
+	}
+	if len(fits) == 0 {
+		return nil, fmt.Errorf("no FITS files in %s", spec)
+	}
+	return fits, nil
+}
+
+func init() {
+	rootCmd.AddCommand(pipelineCmd)
+	pipelineCmd.AddCommand(pipelinePreprocessCmd)
+	pipelineCmd.AddCommand(pipelineRunCmd)
+
+	pipelinePreprocessCmd.Flags().String("bias", "", "Bias frames (directory, glob or list)")
+	pipelinePreprocessCmd.Flags().String("flat", "", "Flat field frames (directory, glob or list)")
+	pipelinePreprocessCmd.Flags().String("template", "", "Reference image to subtract, with a WCS")
+	pipelinePreprocessCmd.Flags().Bool("solve", false, "Plate solve the frames")
+	pipelinePreprocessCmd.Flags().String("solver", "solve-field", "astrometry.net compatible solver command")
+	pipelinePreprocessCmd.Flags().Float64("scale-low", 0, "Lower pixel scale hint in arcsec/pixel")
+	pipelinePreprocessCmd.Flags().Float64("scale-high", 0, "Upper pixel scale hint in arcsec/pixel")
+	pipelinePreprocessCmd.Flags().Duration("solve-timeout", 0, "Plate solving time limit per frame (default 2m)")
+	pipelinePreprocessCmd.Flags().String("output-dir", "preprocessed", "Output directory")
+
+	pipelineRunCmd.Flags().Bool("restart", false, "Ignore the state of earlier runs")
+	pipelineRunCmd.Flags().String("from", "", "Rerun from this step")
+	pipelineRunCmd.Flags().Bool("dry-run", false, "Print the commands without running them")
+}
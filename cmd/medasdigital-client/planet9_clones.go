@@ -0,0 +1,73 @@
+package main
+
+import (
+    "fmt"
+    "os"
+
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/catalog"
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/orbital"
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/planet9"
+)
+
+// drawCloneSets draws the --clones sets of ETNO elements from their
+// covariances at the catalog epochs and propagates each set like the
+// nominal elements
+func drawCloneSets(etnos []orbital.OrbitalElements, covs []orbital.Covariance, meta *catalog.Metadata, seed int64) error {
+    p9CloneSets = nil
+    if p9Clones <= 0 {
+        return nil
+    }
+    uncertain := 0
+    for _, c := range covs {
+        if !c.IsZero() {
+            uncertain++
+        }
+    }
+    if uncertain == 0 {
+        fmt.Printf("⚠ Catalog %s has no element uncertainties, --clones ignored (run 'data update' for a catalog with them)\n", meta.Version)
+        return nil
+    }
+
+    sets, err := orbital.CloneSets(etnos, covs, p9Clones, seed)
+    if err != nil {
+        return fmt.Errorf("failed to draw ETNO clones: %w", err)
+    }
+    for k := range sets {
+        if sets[k], _, err = propagateETNOs(sets[k], meta); err != nil {
+            return err
+        }
+    }
+    if uncertain < len(etnos) {
+        fmt.Printf("⚠ %d of %d ETNOs have no element uncertainties, their clones are exact\n", len(etnos)-uncertain, len(etnos))
+    }
+    p9CloneSets = sets
+    return nil
+}
+
+// addCloneIntervals re-simulates the best point once per clone set and
+// attaches the spread of its clustering score to the result
+func addCloneIntervals(result *planet9.SearchResult, simDuration float64) {
+    if len(p9CloneSets) == 0 {
+        return
+    }
+    fmt.Printf("\nSimulating the best point with %d ETNO clone sets...\n", len(p9CloneSets))
+    opts := planet9.CloneOptions{
+        DurationYears: simDuration,
+        Integrator:    p9Integrator,
+        Frame:         orbital.Frame(p9Frame),
+        Workers:       p9Workers,
+    }
+    if p9ShowProgress {
+        opts.Progress = func(done, total int) {
+            fmt.Fprintf(os.Stderr, "\r  [%d/%d] clone sets", done, total)
+        }
+    }
+    result.Clones = planet9.RunClones(result.Parameters, p9CloneSets, opts)
+    if p9ShowProgress {
+        fmt.Fprintln(os.Stderr)
+    }
+
+    fmt.Printf("\n=== UNCERTAINTY (%d clones) ===\n", result.Clones.Clones)
+    fmt.Printf("Clustering Score: %.3f nominal, %s\n", result.ClusteringScore, result.Clones.ClusteringScore)
+    fmt.Printf("Chance Probability: %s\n", result.Clones.ChanceProbability)
+}
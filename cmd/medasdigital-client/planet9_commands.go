@@ -1,17 +1,27 @@
 package main
 
 import (
+    "encoding/csv"
     "encoding/json"
     "fmt"
     "math"
     "os"
     "os/exec"
+    "path/filepath"
+    "strconv"
     "strings"
     "time"
     
     "github.com/spf13/cobra"
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/nbody"
     "github.com/oxygene76/medasdigital-client/pkg/astronomy/planet9"
     "github.com/oxygene76/medasdigital-client/pkg/astronomy/orbital"
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/catalog"
+    "github.com/oxygene76/medasdigital-client/pkg/telemetry"
+    "github.com/oxygene76/medasdigital-client/pkg/contract"
+    "github.com/oxygene76/medasdigital-client/pkg/notify"
+    "github.com/oxygene76/medasdigital-client/pkg/export"
+    "github.com/oxygene76/medasdigital-client/pkg/votable"
 )
 
 var planet9Cmd = &cobra.Command{
@@ -62,6 +72,65 @@ Examples:
 
   # Quick test with reduced grid
   medasdigital-client planet9 search --quick
+
+Every parameter given as a min-max range is swept, single values and presets
+are held fixed. --sampling grid (default) simulates every combination of
+evenly spaced values, --grid-points caps the total (default 3 per swept
+parameter, 2 with --quick, 5 with --fine). --sampling lhs draws a Latin
+hypercube of --grid-points samples instead, which covers many parameters
+with far fewer simulations. The best --top points are ranked by clustering
+score, --output saves the best result and --surface-output the score of
+every point.
+
+--integrator selects the N-body integrator: leapfrog (default), whfast (a
+symplectic Wisdom-Holman integrator that solves the Kepler motion exactly and
+keeps the energy error bounded over long runs, with IAS15 taking over close
+encounters), ias15 (adaptive 15th order, reference accuracy) or rk4. The
+relative energy drift is reported for every run.
+
+--kozai and --resonance sample the ETNO elements during single-point runs and
+report per ETNO whether the argument of perihelion librates with eccentricity
+and inclination cycling against each other (Kozai-Lidov), and which p:q
+mean-motion resonance with Planet 9 is nearest and whether its critical angle
+librates. Libration cycles take tens of kyr to Myr, shorter runs come out as
+inconclusive.
+
+Single-point runs stream all body states to --snapshot-file every
+--snapshot-every-kyr (gzip compressed when the name ends in .gz). Convert the
+stream with 'planet9 render' for plotting or ParaView.
+
+--sampler mcmc samples the posterior over the swept parameters instead, with
+an affine-invariant ensemble sampler, flat priors over the ranges and the
+Rayleigh statistic n·R² of the simulated ETNO perihelion longitudes as log
+likelihood. It reports the posterior median with 16–84% interval, the
+Gelman-Rubin R̂, autocorrelation time and effective sample size per
+parameter. --chain-output saves the chains and --corner-output the samples
+after --burn-in:
+
+  # Sweep ω around a preset
+  medasdigital-client planet9 search batygin_brown_2016 --omega 100-200 --grid-points 11
+
+  # Posterior over mass and semi-major axis with 12 walkers
+  medasdigital-client planet9 search custom --mass 7-17 --semi-major 500-700 \
+    --sampler mcmc --walkers 12 --steps 200 --corner-output posterior.csv
+
+  # 200-point Latin hypercube over all six parameters
+  medasdigital-client planet9 search custom --mass 7-17 --semi-major 500-700 \
+    --eccentricity 0.3-0.6 --inclination 10-40 --node 0-360 --omega 100-200 \
+    --sampling lhs --grid-points 200 --surface-output surface.csv
+
+--distribute runs a sweep on the provider network instead: the samples are
+split into --chunks (default one per provider), each chunk is submitted as a
+planet9_search contract job to a different provider offering that service,
+and the partial results are collected, checked against the result hash the
+provider anchored on chain and verified by re-running --verify-samples
+random points and the chunk's best point locally. Verified chunks are merged
+into one ranking, chunks that disagree with the local re-run are flagged and
+left out. Progress is kept in --plan, 'planet9 collect' resumes collecting
+from it:
+
+  medasdigital-client planet9 search custom --mass 7-17 --semi-major 500-700 \
+    --grid-points 400 --distribute --chunks 8 --from alice --surface-output surface.csv
 `,
     Args: cobra.MaximumNArgs(1),
     RunE: runPlanet9Search,
@@ -116,9 +185,22 @@ var (
     p9GridPoints     int
     p9QuickSearch    bool
     p9FineSearch     bool
+    p9Sampling       string
+    p9Seed           int64
+    p9Workers        int
+
+    // MCMC sampling
+    p9Sampler        string
+    p9Walkers        int
+    p9Steps          int
+    p9BurnIn         int
+    p9Thin           int
+    p9ChainFile      string
+    p9CornerFile     string
     
     // Simulation options
     p9SimYears       float64
+    p9Integrator     string
     p9IncludeKozai   bool
     p9IncludeResonance bool
     
@@ -126,6 +208,8 @@ var (
     p9OutputFile     string
     p9OutputFormat   string
     p9ShowProgress   bool
+    p9SurfaceFile    string
+    p9TopN           int
     
     // Job submission
     p9JobPayment     string
@@ -133,6 +217,22 @@ var (
 
     p9SnapshotEveryKyr float64
     p9SnapshotFile     string
+
+    // ETNO catalog snapshot, see data update
+    p9CatalogVersion   string
+    p9CatalogUsed      string
+
+    // Common epoch the ETNOs are propagated to
+    p9Epoch            string
+    p9Propagation      string
+    p9EpochUsed        float64
+
+    // Reference frame of the ETNO elements in the results
+    p9Frame            string
+
+    // ETNO clones drawn from the element uncertainties
+    p9Clones           int
+    p9CloneSets        [][]orbital.OrbitalElements
 )
 
 func init() {
@@ -157,24 +257,43 @@ func init() {
     planet9SearchCmd.Flags().IntVar(&p9GridPoints, "grid-points", 0, "Total grid points (overrides resolution)")
     planet9SearchCmd.Flags().BoolVar(&p9QuickSearch, "quick", false, "Quick search with coarse grid")
     planet9SearchCmd.Flags().BoolVar(&p9FineSearch, "fine", false, "Fine search with dense grid")
+    planet9SearchCmd.Flags().StringVar(&p9Sampling, "sampling", planet9.SamplingGrid, "Sampling of the parameter ranges (grid, lhs)")
+    planet9SearchCmd.Flags().Int64Var(&p9Seed, "seed", 0, "Random seed for Latin hypercube sampling (0 = random)")
+    planet9SearchCmd.Flags().IntVar(&p9Workers, "workers", 0, "Parallel simulations (0 = one per CPU)")
+
+    planet9SearchCmd.Flags().StringVar(&p9Sampler, "sampler", "sweep", "Search strategy (sweep, mcmc)")
+    planet9SearchCmd.Flags().IntVar(&p9Walkers, "walkers", 0, "MCMC walkers, even and at least twice the swept parameters (0 = 2×parameters+2)")
+    planet9SearchCmd.Flags().IntVar(&p9Steps, "steps", 100, "MCMC steps per walker")
+    planet9SearchCmd.Flags().IntVar(&p9BurnIn, "burn-in", -1, "MCMC steps to discard (-1 = a quarter of --steps)")
+    planet9SearchCmd.Flags().IntVar(&p9Thin, "thin", 1, "Keep every n-th MCMC step in the posterior samples")
+    planet9SearchCmd.Flags().StringVar(&p9ChainFile, "chain-output", "", "Save the full MCMC chains (.csv or .json)")
+    planet9SearchCmd.Flags().StringVar(&p9CornerFile, "corner-output", "", "Save the posterior samples after burn-in as CSV for corner plots")
     
     planet9SearchCmd.Flags().Float64Var(&p9SimYears, "sim-years", 1000, "Simulation duration in years")
+    planet9SearchCmd.Flags().StringVar(&p9Integrator, "integrator", "leapfrog", "N-body integrator ("+strings.Join(planet9.Integrators, ", ")+")")
     planet9SearchCmd.Flags().BoolVar(&p9IncludeKozai, "kozai", false, "Test for Kozai-Lidov oscillations")
     planet9SearchCmd.Flags().BoolVar(&p9IncludeResonance, "resonance", false, "Test for mean-motion resonances")
     
     planet9SearchCmd.Flags().StringVar(&p9OutputFile, "output", "", "Save results to file")
-    planet9SearchCmd.Flags().StringVar(&p9OutputFormat, "format", "json", "Output format (json, csv, summary)")
+    planet9SearchCmd.Flags().StringVar(&p9OutputFormat, "format", "json", "Output format (json, csv, summary, votable)")
     planet9SearchCmd.Flags().BoolVar(&p9ShowProgress, "progress", true, "Show progress bar")
+    planet9SearchCmd.Flags().StringVar(&p9SurfaceFile, "surface-output", "", "Save the score of every grid point (.csv or .json)")
+    planet9SearchCmd.Flags().IntVar(&p9TopN, "top", 10, "Number of best grid points to list")
     
     // Job submission flags
-    planet9JobCmd.Flags().StringVar(&p9JobPayment, "payment", "10000000umedas", "Payment amount")
+    planet9JobCmd.Flags().StringVar(&p9JobPayment, "payment", "", "Payment amount (default: 10000000 in the chain base denom)")
     planet9JobCmd.Flags().StringVar(&p9JobPriority, "priority", "normal", "Job priority (low, normal, high)")
 
     planet9SearchCmd.Flags().Float64Var(&p9SnapshotEveryKyr, "snapshot-every-kyr", 0.2, "Snapshot cadence in kyr (0 = disable)")
-    planet9SearchCmd.Flags().StringVar(&p9SnapshotFile, "snapshot-file", "snapshots.jsonl", "Path for streamed JSONL snapshots")
+    planet9SearchCmd.Flags().StringVar(&p9SnapshotFile, "snapshot-file", "snapshots.jsonl", "Path for streamed JSONL snapshots (.gz compresses)")
+    planet9SearchCmd.Flags().StringVar(&p9CatalogVersion, "catalog-version", "", "ETNO catalog snapshot of 'data update' to search with, or latest (default "+legacyETNOFile+" if present, else the latest snapshot)")
+    planet9SearchCmd.Flags().StringVar(&p9Epoch, "epoch", "catalog", "Epoch the simulation starts at, the ETNOs are propagated to it (catalog = the most common element epoch, J2000, a Julian date or YYYY-MM-DD)")
+    planet9SearchCmd.Flags().StringVar(&p9Frame, "frame", string(orbital.DefaultFrame), "Reference frame of the ETNO elements and the clustering score (heliocentric-ecliptic, barycentric-ecliptic, heliocentric-equatorial, barycentric-equatorial)")
+    planet9SearchCmd.Flags().IntVar(&p9Clones, "clones", 0, "Re-simulate the best point with this many ETNO clone sets drawn from the element uncertainties and report confidence intervals")
+    planet9SearchCmd.Flags().StringVar(&p9Propagation, "propagation", orbital.PropagationTwoBody, "Propagation to the common epoch ("+strings.Join(orbital.Propagations, ", ")+", none)")
 }
 
-func runPlanet9Search(cmd *cobra.Command, args []string) error {
+func runPlanet9Search(cmd *cobra.Command, args []string) (err error) {
     // Determine preset
     preset := planet9.PresetCustom
     if len(args) > 0 {
@@ -194,85 +313,149 @@ func runPlanet9Search(cmd *cobra.Command, args []string) error {
         }
     }
     
-    // Get preset parameters or build custom
-    var searchParams planet9.SearchParameters
-    if preset != planet9.PresetCustom {
-        searchParams = planet9.GetPresetParameters(preset)
-    } else {
-        searchParams = buildCustomParameters()
+    // Get preset parameters or build custom ranges, range flags override presets
+    ranges, err := buildParameterRanges(preset)
+    if err != nil {
+        return err
     }
-    
-    // Adjust simulation time for quick/fine search
+    if _, err := planet9.NewIntegrator(p9Integrator); err != nil {
+        return err
+    }
+    validateParameters(ranges.Center().Mass, ranges.Center().SemiMajorAxis,
+        ranges.Center().Eccentricity, ranges.Center().Inclination)
+
+    // Adjust simulation time and grid resolution for quick/fine search
     simDuration := p9SimYears
+    resolution := 3
     if p9QuickSearch {
         simDuration = 100  // 100 years for quick test
+        resolution = 2
     } else if p9FineSearch {
         simDuration = 10000 // 10,000 years for fine search
+        resolution = 5
     }
-    
-    // Load TNO data
-    dataFile := "data/solar_system_jpl.json"
-    if _, err := os.Stat(dataFile); os.IsNotExist(err) {
-        fmt.Println("\n⚠ TNO data file not found. Downloading from JPL...")
-        if err := downloadJPLData(); err != nil {
-            return fmt.Errorf("failed to download data: %w", err)
+    points := p9GridPoints
+    if points <= 0 {
+        points = int(math.Pow(float64(resolution), float64(ranges.FreeDimensions())))
+    }
+    seed := p9Seed
+    if seed == 0 {
+        seed = time.Now().UnixNano()
+    }
+    frame, err := orbital.ParseFrame(p9Frame)
+    if err != nil {
+        return err
+    }
+    p9Frame = string(frame)
+    var samples []planet9.SearchParameters
+    switch p9Sampler {
+    case "sweep":
+        samples, err = planet9.Samples(ranges, p9Sampling, points, seed)
+        if err != nil {
+            return err
+        }
+    case "mcmc":
+        if p9Distribute {
+            return fmt.Errorf("--distribute splits sweeps, use --sampler sweep")
         }
+        if ranges.FreeDimensions() == 0 {
+            return fmt.Errorf("--sampler mcmc needs at least one parameter range, e.g. --mass 7-17")
+        }
+    default:
+        return fmt.Errorf("unknown sampler %q (sweep or mcmc)", p9Sampler)
     }
     
-    // Load ETNOs from data file
-    etnos, err := loadETNOData(dataFile)
+    // Load ETNOs from the pinned catalog snapshot or the current data
+    dataFile, err := etnoCatalogPath(cmd, p9CatalogVersion)
+    if err != nil {
+        return err
+    }
+    etnos, covs, catalogInfo, err := loadETNOData(dataFile)
     if err != nil {
         return fmt.Errorf("failed to load ETNO data: %w", err)
     }
+    p9CatalogUsed = catalogInfo.Version
+    if err := drawCloneSets(etnos, covs, catalogInfo, seed); err != nil {
+        return err
+    }
+    etnos, moved, err := propagateETNOs(etnos, catalogInfo)
+    if err != nil {
+        return err
+    }
     
     fmt.Println("========================================")
     fmt.Println("   PLANET 9 ORBITAL PARAMETER SEARCH")
     fmt.Println("========================================")
     fmt.Printf("\nPreset: %s\n", preset)
     fmt.Printf("Parameters:\n")
-    fmt.Printf("  Mass: %.1f Earth masses\n", searchParams.Mass)
-    fmt.Printf("  Semi-major axis: %.0f AU\n", searchParams.SemiMajorAxis)
-    fmt.Printf("  Eccentricity: %.2f\n", searchParams.Eccentricity)
-    fmt.Printf("  Inclination: %.1f°\n", searchParams.Inclination)
-    fmt.Printf("  Simulation: %.0f years\n", simDuration)
-    fmt.Printf("  ETNOs loaded: %d\n\n", len(etnos))
+    fmt.Printf("  Mass: %s Earth masses\n", formatRange(ranges.Mass, "%.1f"))
+    fmt.Printf("  Semi-major axis: %s AU\n", formatRange(ranges.SemiMajorAxis, "%.0f"))
+    fmt.Printf("  Eccentricity: %s\n", formatRange(ranges.Eccentricity, "%.2f"))
+    fmt.Printf("  Inclination: %s°\n", formatRange(ranges.Inclination, "%.1f"))
+    fmt.Printf("  Node: %s°\n", formatRange(ranges.LongitudeAscendingNode, "%.1f"))
+    fmt.Printf("  Perihelion argument: %s°\n", formatRange(ranges.ArgumentPerihelion, "%.1f"))
+    fmt.Printf("  Simulation: %.0f years (%s)\n", simDuration, p9Integrator)
+    fmt.Printf("  ETNOs loaded: %d\n", len(etnos))
+    fmt.Printf("  Catalog: %s (%s, epochs %s)\n", catalogInfo.Version, dataFile, epochSpan(*catalogInfo))
+    if p9EpochUsed != 0 {
+        fmt.Printf("  Epoch: JD %.1f (%s), %d ETNOs propagated (%s)\n", p9EpochUsed,
+            catalog.EpochTime(p9EpochUsed).Format("2006-01-02"), moved, p9Propagation)
+    } else {
+        fmt.Printf("  Epoch: elements used at their own epochs\n")
+    }
+    fmt.Printf("  Frame: %s\n", p9Frame)
+    if len(p9CloneSets) > 0 {
+        fmt.Printf("  Clones: %d sets from the element uncertainties\n", len(p9CloneSets))
+    }
+    fmt.Println()
+
+    // Searches run for hours, tell the user when they end
+    searchStart := time.Now()
+    defer func() {
+        notifyDone(notify.KindPlanet9, fmt.Sprintf("Planet 9 search (%s, %s sampler)", preset, p9Sampler),
+            planet9OutputNote(), searchStart, err)
+    }()
+
+    if p9Sampler == "mcmc" {
+        return runPlanet9MCMC(cmd, ranges, etnos, simDuration, seed)
+    }
+    if p9Distribute {
+        return runPlanet9Distributed(cmd, ranges, samples, etnos, simDuration, seed)
+    }
+    if len(samples) > 1 {
+        return runPlanet9Sweep(cmd, ranges, samples, etnos, simDuration, seed)
+    }
     
     // Run simulation
     startTime := time.Now()
     fmt.Println("Running N-body simulation...")
     
+    stopTimer := telemetry.Track(telemetry.CategoryCompute, "nbody_simulation")
     result := planet9.RunSimulation(
-    searchParams,
+    samples[0],
     etnos,
     simDuration,
     planet9.RunOpts{
         SnapshotEveryKyr: p9SnapshotEveryKyr,
         SnapshotFile:     p9SnapshotFile,
+        Integrator:       p9Integrator,
+        Kozai:            p9IncludeKozai,
+        Resonance:        p9IncludeResonance,
+        Frame:            orbital.Frame(p9Frame),
     },
     )
+    stopTimer()
     
     elapsed := time.Since(startTime)
     
     // Display results
     fmt.Printf("\n=== RESULTS ===\n")
     fmt.Printf("Clustering Score: %.3f\n", result.ClusteringScore)
+    printEnergyReport(result.Energy)
     fmt.Printf("Compute Time: %v\n\n", elapsed)
-    
-    // Show ETNO effects
-    if len(result.ETNOEffects) > 0 {
-        fmt.Println("ETNO Orbital Changes:")
-        fmt.Println("Object          Perihelion Shift  Inclination Change")
-        fmt.Println("------------------------------------------------------")
-        for i, effect := range result.ETNOEffects {
-            if i >= 10 {
-                break // Show only first 10
-            }
-            fmt.Printf("%-15s  %+6.2f AU         %+6.2f°\n",
-                effect.ObjectID,
-                effect.PerihelionShift,
-                effect.InclinationChange)
-        }
-    }
+    printETNOEffects(&result)
+    printDynamicsDiagnostics(&result)
+    addCloneIntervals(&result, simDuration)
     
     // Save results if requested
     if p9OutputFile != "" {
@@ -285,6 +468,260 @@ func runPlanet9Search(cmd *cobra.Command, args []string) error {
     return nil
 }
 
+// runPlanet9Sweep simulates every sample, ranks the points by clustering
+// score and saves the best result and the score surface
+func runPlanet9Sweep(cmd *cobra.Command, ranges planet9.ParameterRanges, samples []planet9.SearchParameters,
+    etnos []orbital.OrbitalElements, simDuration float64, seed int64) error {
+    if p9Sampling == planet9.SamplingLatinHypercube {
+        fmt.Printf("Sampling: Latin hypercube, %d points (seed %d)\n", len(samples), seed)
+    } else {
+        shape := ranges.GridShape(len(samples))
+        dims := make([]string, 0, len(shape))
+        for _, n := range shape {
+            if n > 1 {
+                dims = append(dims, fmt.Sprint(n))
+            }
+        }
+        fmt.Printf("Sampling: grid %s = %d points\n", strings.Join(dims, "×"), len(samples))
+    }
+    if cmd.Flags().Changed("snapshot-every-kyr") && p9SnapshotEveryKyr > 0 {
+        fmt.Println("⚠ Snapshots are only written for single-point runs")
+    }
+    if p9IncludeKozai || p9IncludeResonance {
+        fmt.Println("⚠ Kozai and resonance diagnostics are only computed for single-point runs")
+    }
+
+    startTime := time.Now()
+    fmt.Println("Running N-body simulations...")
+
+    best := -1.0
+    opts := planet9.SweepOptions{DurationYears: simDuration, Integrator: p9Integrator, Frame: orbital.Frame(p9Frame), Workers: p9Workers}
+    if p9ShowProgress {
+        opts.Progress = func(done, total int, p planet9.SurfacePoint) {
+            if p.ClusteringScore > best {
+                best = p.ClusteringScore
+            }
+            fmt.Fprintf(os.Stderr, "\r  [%d/%d] best clustering score %.3f", done, total, best)
+            if done == total {
+                fmt.Fprintln(os.Stderr)
+            }
+        }
+    }
+
+    stopTimer := telemetry.Track(telemetry.CategoryCompute, "nbody_sweep")
+    sweep := planet9.RunSweep(samples, etnos, opts)
+    stopTimer()
+
+    elapsed := time.Since(startTime)
+
+    printSweepTop(sweep, p9Integrator)
+    fmt.Printf("Compute Time: %v\n\n", elapsed)
+    printETNOEffects(&sweep.Best)
+    addCloneIntervals(&sweep.Best, simDuration)
+
+    if p9OutputFile != "" {
+        if err := saveSearchResults(&sweep.Best, p9OutputFile, p9OutputFormat); err != nil {
+            return fmt.Errorf("failed to save results: %w", err)
+        }
+        fmt.Printf("\nBest result saved to: %s\n", p9OutputFile)
+    }
+    if p9SurfaceFile != "" {
+        surface := scoreSurface{
+            Sampling: p9Sampling,
+            Points:   len(sweep.Surface),
+            SimYears: simDuration,
+            Ranges:   ranges,
+            Frame:    sweep.Frame,
+            Surface:  sweep.Surface,
+        }
+        if p9Sampling == planet9.SamplingLatinHypercube {
+            surface.Seed = seed
+        }
+        if err := saveScoreSurface(&surface, p9SurfaceFile); err != nil {
+            return fmt.Errorf("failed to save score surface: %w", err)
+        }
+        fmt.Printf("Score surface saved to: %s\n", p9SurfaceFile)
+    }
+    return nil
+}
+
+// printSweepTop lists the best points of a sweep and the worst energy drift
+func printSweepTop(sweep *planet9.SweepResult, integrator string) {
+    fmt.Printf("\n=== TOP %d OF %d ===\n", min(p9TopN, len(sweep.Surface)), len(sweep.Surface))
+    fmt.Println("Rank  Score   Mass    a (AU)  e      i (°)   Ω (°)   ω (°)")
+    fmt.Println("-------------------------------------------------------------")
+    for rank, p := range planet9.TopN(sweep.Surface, p9TopN) {
+        fmt.Printf("%4d  %.3f  %5.1f  %7.0f  %.3f  %5.1f  %6.1f  %6.1f\n",
+            rank+1, p.ClusteringScore,
+            p.Parameters.Mass, p.Parameters.SemiMajorAxis, p.Parameters.Eccentricity,
+            p.Parameters.Inclination, p.Parameters.LongitudeAscendingNode, p.Parameters.ArgumentPerihelion)
+    }
+    fmt.Printf("\nBest Clustering Score: %.3f\n", sweep.Best.ClusteringScore)
+    worst := 0.0
+    for _, p := range sweep.Surface {
+        worst = math.Max(worst, p.EnergyDrift)
+    }
+    fmt.Printf("Energy: worst relative drift %.2e over all points (%s)\n", worst, integrator)
+}
+
+// runPlanet9MCMC samples the posterior over the swept parameters and reports
+// the convergence diagnostics
+func runPlanet9MCMC(cmd *cobra.Command, ranges planet9.ParameterRanges, etnos []orbital.OrbitalElements, simDuration float64, seed int64) error {
+    if cmd.Flags().Changed("snapshot-every-kyr") && p9SnapshotEveryKyr > 0 {
+        fmt.Println("⚠ Snapshots are only written for single-point runs")
+    }
+    if p9IncludeKozai || p9IncludeResonance {
+        fmt.Println("⚠ Kozai and resonance diagnostics are only computed for single-point runs")
+    }
+
+    startTime := time.Now()
+    fmt.Printf("Running MCMC: %d steps, seed %d...\n", p9Steps, seed)
+
+    opts := planet9.MCMCOptions{
+        Walkers:       p9Walkers,
+        Steps:         p9Steps,
+        BurnIn:        p9BurnIn,
+        Thin:          p9Thin,
+        Seed:          seed,
+        DurationYears: simDuration,
+        Integrator:    p9Integrator,
+        Frame:         orbital.Frame(p9Frame),
+        Workers:       p9Workers,
+    }
+    if p9ShowProgress {
+        opts.Progress = func(step, steps int, acceptance float64) {
+            fmt.Fprintf(os.Stderr, "\r  [%d/%d] acceptance %.2f", step, steps, acceptance)
+            if step == steps {
+                fmt.Fprintln(os.Stderr)
+            }
+        }
+    }
+
+    stopTimer := telemetry.Track(telemetry.CategoryCompute, "nbody_mcmc")
+    chain, err := planet9.RunMCMC(ranges, etnos, opts)
+    stopTimer()
+    if err != nil {
+        return err
+    }
+    elapsed := time.Since(startTime)
+
+    diags := chain.Diagnostics()
+    fmt.Printf("\n=== POSTERIOR (%d walkers, %d steps, burn-in %d) ===\n", chain.Walkers, chain.Steps, chain.BurnIn)
+    fmt.Println("Parameter                 Median      -1σ         +1σ         R̂      τ      ESS")
+    fmt.Println("-----------------------------------------------------------------------------------")
+    converged := true
+    for _, d := range diags {
+        fmt.Printf("%-24s  %-10.4g  %-10.4g  %-10.4g  %5.3f  %5.1f  %6.0f\n",
+            d.Parameter, d.Median, d.Median-d.Lower, d.Upper-d.Median, d.RHat, d.AutocorrTime, d.ESS)
+        if d.RHat > 1.1 || float64(chain.Steps-chain.BurnIn) < 50*d.AutocorrTime {
+            converged = false
+        }
+    }
+    fmt.Printf("\nMean acceptance fraction: %.2f\n", chain.MeanAcceptance())
+    if !converged {
+        fmt.Println("⚠ Chains may not have converged (R̂ > 1.1 or fewer than 50 autocorrelation times after burn-in), run more --steps")
+    }
+    mapParams, mapScore := chain.MAP()
+    fmt.Printf("MAP: %.1f M⊕, a=%.0f AU, e=%.3f, i=%.1f°, Ω=%.1f°, ω=%.1f° (clustering %.3f)\n",
+        mapParams.Mass, mapParams.SemiMajorAxis, mapParams.Eccentricity,
+        mapParams.Inclination, mapParams.LongitudeAscendingNode, mapParams.ArgumentPerihelion, mapScore)
+    fmt.Printf("Compute Time: %v\n\n", elapsed)
+    printETNOEffects(&chain.Best)
+    addCloneIntervals(&chain.Best, simDuration)
+
+    if p9OutputFile != "" {
+        if err := saveSearchResults(&chain.Best, p9OutputFile, p9OutputFormat); err != nil {
+            return fmt.Errorf("failed to save results: %w", err)
+        }
+        fmt.Printf("\nBest result saved to: %s\n", p9OutputFile)
+    }
+    if p9ChainFile != "" {
+        if err := saveChain(chain, diags, p9ChainFile); err != nil {
+            return fmt.Errorf("failed to save chains: %w", err)
+        }
+        fmt.Printf("Chains saved to: %s\n", p9ChainFile)
+    }
+    if p9CornerFile != "" {
+        if err := saveCornerSamples(chain, p9CornerFile); err != nil {
+            return fmt.Errorf("failed to save posterior samples: %w", err)
+        }
+        fmt.Printf("Posterior samples saved to: %s\n", p9CornerFile)
+    }
+    return nil
+}
+
+// printEnergyReport shows how well the integration conserved energy
+func printEnergyReport(e nbody.EnergyReport) {
+    fmt.Printf("Energy: max relative drift %.2e, final %.2e after %d steps (%s)\n",
+        e.MaxDrift, e.FinalDrift, e.Steps, e.Integrator)
+    if e.MaxDrift > 1e-4 {
+        fmt.Println("⚠ Large energy error, try --integrator whfast or ias15")
+    }
+}
+
+// printETNOEffects shows the orbital changes of the first ETNOs
+func printETNOEffects(result *planet9.SearchResult) {
+    if len(result.ETNOEffects) == 0 {
+        return
+    }
+    fmt.Println("ETNO Orbital Changes:")
+    fmt.Println("Object          Perihelion Shift  Inclination Change")
+    fmt.Println("------------------------------------------------------")
+    for i, effect := range result.ETNOEffects {
+        if i >= 10 {
+            break // Show only first 10
+        }
+        fmt.Printf("%-15s  %+6.2f AU         %+6.2f°\n",
+            effect.ObjectID,
+            effect.PerihelionShift,
+            effect.InclinationChange)
+    }
+}
+
+// printDynamicsDiagnostics shows the Kozai and resonance state of every ETNO
+// that has one
+func printDynamicsDiagnostics(result *planet9.SearchResult) {
+    var kozai, resonance bool
+    for _, e := range result.ETNOEffects {
+        kozai = kozai || e.Kozai != nil
+        resonance = resonance || e.Resonance != nil
+    }
+    if kozai {
+        fmt.Println("\nKozai-Lidov (argument of perihelion):")
+        fmt.Println("Object          Status        ω center  Amplitude  e range        i range          corr(e,i)")
+        fmt.Println("-----------------------------------------------------------------------------------------------")
+        for _, e := range result.ETNOEffects {
+            k := e.Kozai
+            if k == nil {
+                continue
+            }
+            status := k.Status
+            if k.Detected {
+                status += " ✓"
+            }
+            fmt.Printf("%-15s %-13s %7.1f°  %8.1f°  %.3f–%.3f  %6.2f°–%6.2f°  %+.2f\n",
+                e.ObjectID, status, k.OmegaCenter, k.OmegaAmplitude,
+                k.EccentricityMin, k.EccentricityMax, k.InclinationMin, k.InclinationMax, k.EInclCorrelation)
+        }
+    }
+    if resonance {
+        fmt.Println("\nMean-motion resonances with Planet 9:")
+        fmt.Println("Object          Ratio   Critical angle  Libration amp.  Librating")
+        fmt.Println("-----------------------------------------------------------------")
+        for _, e := range result.ETNOEffects {
+            r := e.Resonance
+            if r == nil {
+                continue
+            }
+            fmt.Printf("%-15s %-7s %12.1f°  %13.1f°  %v\n",
+                e.ObjectID, r.Ratio, r.CriticalAngle, r.LibrationAmp, r.IsLibrating)
+        }
+    }
+    if kozai || resonance {
+        fmt.Println("(inconclusive = the run is shorter than one libration cycle, increase --sim-years)")
+    }
+}
+
 func runPlanet9Test(cmd *cobra.Command, args []string) error {
     fmt.Println("Running Planet 9 test simulation...")
     
@@ -321,27 +758,46 @@ func runPlanet9Test(cmd *cobra.Command, args []string) error {
     return nil
 }
 
-func buildCustomParameters() planet9.SearchParameters {
-    // Parse range strings and use middle value
+func buildParameterRanges(preset planet9.SearchPreset) (planet9.ParameterRanges, error) {
     // Updated defaults based on IRAS/AKARI observational constraints
-    mass := parseRangeMiddle(p9MassRange, 12.0)      // Middle of 7-17 M⊕
-    semiMajor := parseRangeMiddle(p9SemiMajorRange, 600.0)  // Middle of 500-700 AU
-    ecc := parseRangeMiddle(p9EccRange, 0.45)       // Middle of 0.3-0.6
-    inc := parseRangeMiddle(p9IncRange, 25.0)       // Middle of 10-40°
-    node := parseRangeMiddle(p9NodeRange, 100.0)    
-    omega := parseRangeMiddle(p9OmegaRange, 150.0)  // ETNO clustering
-    
-    // Validate and warn about implausible values
-    validateParameters(mass, semiMajor, ecc, inc)
-    
-    return planet9.SearchParameters{
-        Mass:                   mass,
-        SemiMajorAxis:          semiMajor,
-        Eccentricity:           ecc,
-        Inclination:            inc,
-        LongitudeAscendingNode: node,
-        ArgumentPerihelion:     omega,
+    defaults := planet9.SearchParameters{
+        Mass:                   12.0,  // Middle of 7-17 M⊕
+        SemiMajorAxis:          600.0, // Middle of 500-700 AU
+        Eccentricity:           0.45,  // Middle of 0.3-0.6
+        Inclination:            25.0,  // Middle of 10-40°
+        LongitudeAscendingNode: 100.0,
+        ArgumentPerihelion:     150.0, // ETNO clustering
+    }
+    if preset != planet9.PresetCustom {
+        defaults = planet9.GetPresetParameters(preset)
+    }
+    ranges := planet9.FixedRanges(defaults)
+
+    for _, f := range []struct {
+        flag  string
+        value string
+        r     *planet9.Range
+    }{
+        {"mass", p9MassRange, &ranges.Mass},
+        {"semi-major", p9SemiMajorRange, &ranges.SemiMajorAxis},
+        {"eccentricity", p9EccRange, &ranges.Eccentricity},
+        {"inclination", p9IncRange, &ranges.Inclination},
+        {"node", p9NodeRange, &ranges.LongitudeAscendingNode},
+        {"omega", p9OmegaRange, &ranges.ArgumentPerihelion},
+    } {
+        r, err := parseRange(f.value, *f.r)
+        if err != nil {
+            return ranges, fmt.Errorf("invalid --%s: %w", f.flag, err)
+        }
+        *f.r = r
+    }
+    if ranges.Eccentricity.Min < 0 || ranges.Eccentricity.Max >= 1 {
+        return ranges, fmt.Errorf("eccentricity must be in [0, 1)")
     }
+    if ranges.Mass.Min <= 0 || ranges.SemiMajorAxis.Min <= 0 {
+        return ranges, fmt.Errorf("mass and semi-major axis must be positive")
+    }
+    return ranges, nil
 }
 
 func validateParameters(mass, semiMajor, ecc, inc float64) {
@@ -410,58 +866,58 @@ func calculateTemperature(semiMajor float64) float64 {
     return 50.0 * math.Sqrt(600.0/semiMajor)  // Normalized to ~50K at 600 AU
 }
 
-func parseRangeMiddle(s string, defaultVal float64) float64 {
+// parseRange reads "min-max" or a single value, empty keeps the default
+func parseRange(s string, defaultVal planet9.Range) (planet9.Range, error) {
+    s = strings.TrimSpace(s)
     if s == "" {
-        return defaultVal
+        return defaultVal, nil
     }
     
     parts := strings.Split(s, "-")
-    if len(parts) != 2 {
-        // Try to parse as single value
-        var val float64
-        if _, err := fmt.Sscanf(s, "%f", &val); err == nil {
-            return val
+    if len(parts) == 1 {
+        val, err := strconv.ParseFloat(s, 64)
+        if err != nil {
+            return defaultVal, fmt.Errorf("%q is not a number", s)
         }
-        return defaultVal
+        return planet9.Fixed(val), nil
     }
-    
-    var min, max float64
-    fmt.Sscanf(parts[0], "%f", &min)
-    fmt.Sscanf(parts[1], "%f", &max)
-    
-    if min == 0 && max == 0 {
-        return defaultVal
+    if len(parts) != 2 {
+        return defaultVal, fmt.Errorf("%q is not a min-max range", s)
     }
     
-    return (min + max) / 2.0 // Return middle of range
-}
-
-func loadETNOData(dataFile string) ([]orbital.OrbitalElements, error) {
-    data, err := os.ReadFile(dataFile)
+    min, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
     if err != nil {
-        return nil, err
+        return defaultVal, fmt.Errorf("%q is not a min-max range", s)
     }
-    
-    var solarSystem struct {
-        ETNOs []struct {
-            OrbitalElements struct {
-                SemiMajorAxis          float64 `json:"semimajor_axis"`
-                Eccentricity           float64 `json:"eccentricity"`
-                Inclination            float64 `json:"inclination"`
-                LongitudeAscendingNode float64 `json:"longitude_ascending_node"`
-                ArgumentPerihelion     float64 `json:"argument_perihelion"`
-                MeanAnomaly            float64 `json:"mean_anomaly"`
-            } `json:"orbital_elements"`
-        } `json:"etnos"`
+    max, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+    if err != nil {
+        return defaultVal, fmt.Errorf("%q is not a min-max range", s)
     }
-    
-    if err := json.Unmarshal(data, &solarSystem); err != nil {
-        return nil, err
+    if min > max {
+        return defaultVal, fmt.Errorf("minimum %g is above maximum %g", min, max)
+    }
+    return planet9.Range{Min: min, Max: max}, nil
+}
+
+// loadETNOData reads the ETNOs of a catalog snapshot or data file, with the
+// covariances of their elements and the catalog metadata
+func loadETNOData(dataFile string) ([]orbital.OrbitalElements, []orbital.Covariance, *catalog.Metadata, error) {
+    stopTimer := telemetry.Track(telemetry.CategoryDisk, "load_etno_data")
+    snap, err := catalog.Load(dataFile)
+    stopTimer()
+    if err != nil {
+        return nil, nil, nil, err
     }
     
     // Convert to orbital.OrbitalElements with radians
     etnos := make([]orbital.OrbitalElements, 0)
-    for _, e := range solarSystem.ETNOs {
+    covs := make([]orbital.Covariance, 0, len(snap.ETNOs))
+    for _, e := range snap.ETNOs {
+        cov, err := orbital.CovarianceFromUncertainty(e.Uncertainty)
+        if err != nil {
+            return nil, nil, nil, fmt.Errorf("%s: %w", e.Name, err)
+        }
+        covs = append(covs, cov)
         // Convert degrees to radians
         etnos = append(etnos, orbital.OrbitalElements{
             SemiMajorAxis:          e.OrbitalElements.SemiMajorAxis,
@@ -470,10 +926,52 @@ func loadETNOData(dataFile string) ([]orbital.OrbitalElements, error) {
             LongitudeAscendingNode: e.OrbitalElements.LongitudeAscendingNode * 0.017453293,
             ArgumentPerihelion:     e.OrbitalElements.ArgumentPerihelion * 0.017453293,
             MeanAnomaly:            e.OrbitalElements.MeanAnomaly * 0.017453293,
+            Epoch:                  snap.EpochOf(e),
         })
     }
+
+    // The simulation places the ETNOs around the Sun in the ecliptic
+    if from := snap.Metadata.Frame.OrDefault(); from != orbital.HeliocentricEcliptic {
+        for i, e := range etnos {
+            if etnos[i], err = e.ConvertFrame(from, orbital.HeliocentricEcliptic); err != nil {
+                return nil, nil, nil, fmt.Errorf("%s: %w", snap.ETNOs[i].Name, err)
+            }
+        }
+    }
     
-    return etnos, nil
+    return etnos, covs, &snap.Metadata, nil
+}
+
+// propagateETNOs moves the ETNOs to the epoch of --epoch, so elements taken
+// at different epochs describe the same moment. It returns how many moved.
+func propagateETNOs(etnos []orbital.OrbitalElements, meta *catalog.Metadata) ([]orbital.OrbitalElements, int, error) {
+    p9EpochUsed = 0
+    if p9Propagation == "none" {
+        return etnos, 0, nil
+    }
+    epoch := meta.EpochJD
+    if p9Epoch != "catalog" {
+        var err error
+        if epoch, err = orbital.ParseEpoch(p9Epoch); err != nil {
+            return nil, 0, err
+        }
+    }
+    if epoch == 0 {
+        return etnos, 0, nil
+    }
+
+    moved := 0
+    for _, e := range etnos {
+        if e.Epoch != 0 && e.Epoch != epoch {
+            moved++
+        }
+    }
+    propagated, err := orbital.PropagateAll(etnos, epoch, p9Propagation)
+    if err != nil {
+        return nil, 0, fmt.Errorf("failed to propagate the ETNOs to JD %.1f: %w", epoch, err)
+    }
+    p9EpochUsed = epoch
+    return propagated, moved, nil
 }
 
 func submitPlanet9Job(cmd *cobra.Command, args []string) error {
@@ -493,8 +991,13 @@ func submitPlanet9Job(cmd *cobra.Command, args []string) error {
     
     paramsJSON, _ := json.Marshal(params)
     
-    // Use hardcoded contract address if not in config
-    contractAddr := "medas1xr3rq8yvd7qplsw5yx90ftsr2zdhg4e9z60h5duusgxpv72hud3s3cca97"
+    contractAddr := cfg.Contract.Address
+    if contractAddr == "" {
+        return fmt.Errorf("no contract address for this network, set contract.address in the config")
+    }
+    if p9JobPayment == "" {
+        p9JobPayment = "10000000" + cfg.Chain.BaseDenom
+    }
     
     fmt.Println("Submitting Planet 9 search job to blockchain...")
     fmt.Printf("  Contract: %s\n", contractAddr)
@@ -511,13 +1014,17 @@ func submitPlanet9Job(cmd *cobra.Command, args []string) error {
     execCmd := exec.Command(
         "medasdigitald", "tx", "wasm", "execute",
         contractAddr,
-        fmt.Sprintf(`{"submit_job":{"service_type":"planet9_search","parameters":"%s","max_price":"1000000","auto_accept":true}}`,
-            strings.ReplaceAll(string(paramsJSON), `"`, `\"`)),
+        contract.ExecuteMsg{SubmitJob: &contract.SubmitJobMsg{
+            ServiceType: "planet9_search",
+            Parameters:  string(paramsJSON),
+            MaxPrice:    "1000000",
+            AutoAccept:  true,
+        }}.JSON(),
         "--from", keyName,
         "--amount", p9JobPayment,
         "--gas", "auto",
         "--gas-adjustment", "1.3",
-        "--gas-prices", "0.025umedas",
+        "--gas-prices", cfg.Chain.GasPrice,
         "--keyring-backend", cfg.Provider.KeyringBackend,
         "--node", cfg.Chain.RPCEndpoint,
         "--chain-id", cfg.Chain.ID,
@@ -558,11 +1065,20 @@ func analyzePlanet9Results(cmd *cobra.Command, args []string) error {
     fmt.Printf("\nScores:\n")
     fmt.Printf("  Clustering: %.3f\n", result.ClusteringScore)
     fmt.Printf("\nETNO Effects: %d objects analyzed\n", len(result.ETNOEffects))
+    printDynamicsDiagnostics(&result)
     
     return nil
 }
 
 func saveSearchResults(result *planet9.SearchResult, filename, format string) error {
+    defer telemetry.Track(telemetry.CategoryDisk, "save_results")()
+
+    if result.Catalog == "" {
+        result.Catalog = p9CatalogUsed
+    }
+    if result.Epoch == 0 {
+        result.Epoch = p9EpochUsed
+    }
     switch format {
     case "json":
         data, err := json.MarshalIndent(result, "", "  ")
@@ -570,6 +1086,20 @@ func saveSearchResults(result *planet9.SearchResult, filename, format string) er
             return err
         }
         return os.WriteFile(filename, data, 0644)
+
+    case "csv":
+        f, err := os.Create(filename)
+        if err != nil {
+            return err
+        }
+        defer f.Close()
+        w := csv.NewWriter(f)
+        w.Write([]string{"object_id", "perihelion_shift_au", "inclination_change_deg", "long_peri_change_rad"})
+        for _, e := range result.ETNOEffects {
+            w.Write([]string{e.ObjectID, formatFloat(e.PerihelionShift), formatFloat(e.InclinationChange), formatFloat(e.LongPeriChange)})
+        }
+        w.Flush()
+        return w.Error()
         
     case "summary":
         summary := fmt.Sprintf(`Planet 9 Search Results
@@ -580,27 +1110,219 @@ Eccentricity: %.3f
 Inclination: %.1f°
 Clustering Score: %.3f
 ETNOs Analyzed: %d
+Integrator: %s
+Energy Drift: %.2e
+ETNO Catalog: %s
+Epoch: JD %.1f
+Frame: %s
 `, 
             result.Parameters.Mass,
             result.Parameters.SemiMajorAxis,
             result.Parameters.Eccentricity,
             result.Parameters.Inclination,
             result.ClusteringScore,
-            len(result.ETNOEffects))
+            len(result.ETNOEffects),
+            result.Energy.Integrator,
+            result.Energy.MaxDrift,
+            result.Catalog,
+            result.Epoch,
+            result.Frame)
+        if c := result.Clones; c != nil {
+            summary += fmt.Sprintf("Clustering Score over %d clones: %s\nChance Probability over clones: %s\n",
+                c.Clones, c.ClusteringScore, c.ChanceProbability)
+        }
         
         return os.WriteFile(filename, []byte(summary), 0644)
-        
+
+    case "votable":
+        params := map[string]string{
+            "p9_mass_earth":      formatFloat(result.Parameters.Mass),
+            "p9_semi_major_axis": formatFloat(result.Parameters.SemiMajorAxis),
+            "p9_eccentricity":    formatFloat(result.Parameters.Eccentricity),
+            "p9_inclination":     formatFloat(result.Parameters.Inclination),
+            "p9_longitude_node":  formatFloat(result.Parameters.LongitudeAscendingNode),
+            "p9_arg_perihelion":  formatFloat(result.Parameters.ArgumentPerihelion),
+            "clustering_score":   formatFloat(result.ClusteringScore),
+            "integrator":         result.Energy.Integrator,
+            "energy_drift":       formatFloat(result.Energy.MaxDrift),
+            "etno_catalog":       result.Catalog,
+            "epoch_jd":           formatFloat(result.Epoch),
+            "frame":              string(result.Frame),
+        }
+        if c := result.Clones; c != nil {
+            params["clones"] = strconv.Itoa(c.Clones)
+            params["clustering_score_median"] = formatFloat(c.ClusteringScore.Median)
+            params["clustering_score_lower_68"] = formatFloat(c.ClusteringScore.Lower68)
+            params["clustering_score_upper_68"] = formatFloat(c.ClusteringScore.Upper68)
+        }
+        return writeVOTableFile(filename, votable.Document{
+            Name:        "planet9_search",
+            Description: "Planet 9 search result, ETNO orbits before and after the simulation",
+            Params:      params,
+            Tables:      []*export.Table{etnoEffectsTable(result.ETNOEffects)},
+        })
+
     default:
         return fmt.Errorf("unknown format: %s", format)
     }
 }
 
-func downloadJPLData() error {
-    // Execute Python script to download data
-    cmd := exec.Command("python3", "scripts/fetch_jpl_api.py")
-    cmd.Stdout = os.Stdout
-    cmd.Stderr = os.Stderr
-    return cmd.Run()
+// etnoEffectsTable lists the ETNO orbits with units and UCDs for VOTable
+func etnoEffectsTable(effects []planet9.ETNOEffect) *export.Table {
+    column := func(name, unit, ucd string, value func(e planet9.ETNOEffect) float64) *export.Column {
+        c := &export.Column{Name: name, Kind: export.Float, Unit: unit, UCD: ucd}
+        for _, e := range effects {
+            c.Floats = append(c.Floats, value(e))
+        }
+        return c
+    }
+    ids := &export.Column{Name: "object_id", Kind: export.String, UCD: "meta.id;meta.main"}
+    for _, e := range effects {
+        ids.Strings = append(ids.Strings, e.ObjectID)
+    }
+    deg := 180 / math.Pi
+    return &export.Table{Name: "etno_effects", Columns: []*export.Column{
+        ids,
+        column("initial_a", "AU", "phys.size.smajAxis", func(e planet9.ETNOEffect) float64 { return e.InitialElements.SemiMajorAxis }),
+        column("initial_e", "", "src.orbital.eccentricity", func(e planet9.ETNOEffect) float64 { return e.InitialElements.Eccentricity }),
+        column("initial_i", "deg", "src.orbital.inclination", func(e planet9.ETNOEffect) float64 { return e.InitialElements.Inclination * deg }),
+        column("final_a", "AU", "phys.size.smajAxis", func(e planet9.ETNOEffect) float64 { return e.FinalElements.SemiMajorAxis }),
+        column("final_e", "", "src.orbital.eccentricity", func(e planet9.ETNOEffect) float64 { return e.FinalElements.Eccentricity }),
+        column("final_i", "deg", "src.orbital.inclination", func(e planet9.ETNOEffect) float64 { return e.FinalElements.Inclination * deg }),
+        column("perihelion_shift_au", "AU", "", func(e planet9.ETNOEffect) float64 { return e.PerihelionShift }),
+        column("inclination_change_deg", "deg", "", func(e planet9.ETNOEffect) float64 { return e.InclinationChange }),
+        column("long_peri_change_rad", "rad", "", func(e planet9.ETNOEffect) float64 { return e.LongPeriChange }),
+    }}
+}
+
+// scoreSurface is the clustering score of every point of a sweep
+type scoreSurface struct {
+    Sampling string                  `json:"sampling"`
+    Seed     int64                   `json:"seed,omitempty"`
+    Points   int                     `json:"points"`
+    SimYears float64                 `json:"sim_years"`
+    Ranges   planet9.ParameterRanges `json:"ranges"`
+    Frame    orbital.Frame           `json:"frame,omitempty"` // the scores were measured in
+    Surface  []planet9.SurfacePoint  `json:"surface"`
+}
+
+// saveScoreSurface writes the surface as CSV or JSON depending on the extension
+func saveScoreSurface(surface *scoreSurface, filename string) error {
+    defer telemetry.Track(telemetry.CategoryDisk, "save_results")()
+
+    if !strings.EqualFold(filepath.Ext(filename), ".csv") {
+        data, err := json.MarshalIndent(surface, "", "  ")
+        if err != nil {
+            return err
+        }
+        return os.WriteFile(filename, data, 0644)
+    }
+
+    f, err := os.Create(filename)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+    w := csv.NewWriter(f)
+    w.Write([]string{"index", "mass", "semi_major_axis", "eccentricity", "inclination",
+        "longitude_ascending_node", "argument_perihelion", "clustering_score", "etnos_analyzed", "energy_drift"})
+    for _, p := range surface.Surface {
+        w.Write([]string{
+            strconv.Itoa(p.Index),
+            formatFloat(p.Parameters.Mass),
+            formatFloat(p.Parameters.SemiMajorAxis),
+            formatFloat(p.Parameters.Eccentricity),
+            formatFloat(p.Parameters.Inclination),
+            formatFloat(p.Parameters.LongitudeAscendingNode),
+            formatFloat(p.Parameters.ArgumentPerihelion),
+            formatFloat(p.ClusteringScore),
+            strconv.Itoa(p.ETNOsAnalyzed),
+            formatFloat(p.EnergyDrift),
+        })
+    }
+    w.Flush()
+    return w.Error()
+}
+
+// saveChain writes the chains as CSV (one row per step and walker) or as JSON
+// together with the diagnostics
+func saveChain(chain *planet9.Chain, diags []planet9.Diagnostic, filename string) error {
+    defer telemetry.Track(telemetry.CategoryDisk, "save_results")()
+
+    if !strings.EqualFold(filepath.Ext(filename), ".csv") {
+        data, err := json.MarshalIndent(struct {
+            *planet9.Chain
+            AcceptanceFraction float64              `json:"acceptance_fraction"`
+            Diagnostics        []planet9.Diagnostic `json:"diagnostics"`
+        }{chain, chain.MeanAcceptance(), diags}, "", "  ")
+        if err != nil {
+            return err
+        }
+        return os.WriteFile(filename, data, 0644)
+    }
+
+    f, err := os.Create(filename)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+    w := csv.NewWriter(f)
+    header := append([]string{"step", "walker"}, chain.Parameters...)
+    w.Write(append(header, "log_prob", "clustering_score"))
+    for step, walkers := range chain.Positions {
+        for k, x := range walkers {
+            row := []string{strconv.Itoa(step), strconv.Itoa(k)}
+            for _, v := range x {
+                row = append(row, formatFloat(v))
+            }
+            w.Write(append(row, formatFloat(chain.LogProb[step][k]), formatFloat(chain.Scores[step][k])))
+        }
+    }
+    w.Flush()
+    return w.Error()
+}
+
+// saveCornerSamples writes the flattened posterior samples, one column per
+// swept parameter
+func saveCornerSamples(chain *planet9.Chain, filename string) error {
+    defer telemetry.Track(telemetry.CategoryDisk, "save_results")()
+
+    f, err := os.Create(filename)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+    w := csv.NewWriter(f)
+    w.Write(chain.Parameters)
+    for _, x := range chain.Samples() {
+        row := make([]string, len(x))
+        for i, v := range x {
+            row[i] = formatFloat(v)
+        }
+        w.Write(row)
+    }
+    w.Flush()
+    return w.Error()
+}
+
+// planet9OutputNote says where the results of a search went, for notifications
+func planet9OutputNote() string {
+    if p9OutputFile == "" {
+        return "Results were printed to the terminal"
+    }
+    return "Results saved to " + p9OutputFile
+}
+
+func formatFloat(v float64) string {
+    return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// formatRange shows a swept range as min–max and a fixed one as its value
+func formatRange(r planet9.Range, format string) string {
+    if !r.Free() {
+        return fmt.Sprintf(format, r.Min)
+    }
+    return fmt.Sprintf(format+"–"+format, r.Min, r.Max)
 }
 
 func showPlanet9Ranges(cmd *cobra.Command, args []string) error {
@@ -670,7 +1392,6 @@ SEARCH STRATEGY:
       --mass 7-17 --semi-major 500-700 --eccentricity 0.3-0.6
       
   Quick test:
-    medasdigital-client planet9 search batygin_brown_2016 --quick
-`)
+    medasdigital-client planet9 search batygin_brown_2016 --quick`)
     return nil
 }
@@ -0,0 +1,474 @@
+package main
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "math/rand"
+    "net/http"
+    "os"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/orbital"
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/planet9"
+    "github.com/oxygene76/medasdigital-client/pkg/compute"
+    "github.com/oxygene76/medasdigital-client/pkg/contract"
+    "github.com/oxygene76/medasdigital-client/pkg/network"
+    "github.com/oxygene76/medasdigital-client/pkg/notify"
+    "github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+var planet9CollectCmd = &cobra.Command{
+    Use:   "collect <plan.json>",
+    Short: "Collect, verify and merge the chunks of a distributed search",
+    Long: `Resume collecting a sweep started with 'planet9 search --distribute'. Chunks
+that are already verified or flagged are kept, the others are waited for,
+downloaded, checked against their on-chain result hash and verified by a
+local re-run. The verified chunks are merged into one best-fit report.
+
+Examples:
+  medasdigital-client planet9 collect p9_distributed_plan.json
+  medasdigital-client planet9 collect plan.json --wait 6h --output best.json --surface-output surface.csv`,
+    Args: cobra.ExactArgs(1),
+    RunE: runPlanet9Collect,
+}
+
+var (
+    p9Distribute    bool
+    p9Chunks        int
+    p9ChunkPayment  string
+    p9VerifySamples int
+    p9From          string
+    p9Criteria      string
+    p9PlanFile      string
+    p9Wait          time.Duration
+)
+
+// Chunk states in a distributed plan
+const (
+    chunkUnsubmitted  = "unsubmitted"   // submission failed
+    chunkSubmitted    = "submitted"     // waiting for the provider
+    chunkFailed       = "failed"        // job failed, timed out or the result was unavailable
+    chunkHashMismatch = "hash_mismatch" // downloaded result differs from the anchored hash
+    chunkFlagged      = "flagged"       // local re-run disagrees with the provider
+    chunkVerified     = "verified"
+)
+
+// distributedPlan tracks a sweep split across providers, it is saved after
+// every change so collection can resume
+type distributedPlan struct {
+    CreatedAt  time.Time               `json:"created_at"`
+    Contract   string                  `json:"contract"`
+    From       string                  `json:"from"`
+    Client     string                  `json:"client"`
+    Integrator string                  `json:"integrator"`
+    SimYears   float64                 `json:"sim_years"`
+    Sampling   string                  `json:"sampling"`
+    Seed       int64                   `json:"seed,omitempty"`
+    Points     int                     `json:"points"`
+    Ranges     planet9.ParameterRanges `json:"ranges"`
+    Chunks     []distributedChunk      `json:"chunks"`
+}
+
+type distributedChunk struct {
+    Chunk        planet9.Chunk              `json:"chunk"`
+    Provider     string                     `json:"provider"`
+    JobID        uint64                     `json:"job_id,omitempty"`
+    TxHash       string                     `json:"tx_hash,omitempty"`
+    Payment      string                     `json:"payment"`
+    Status       string                     `json:"status"`
+    ResultHash   string                     `json:"result_hash,omitempty"`
+    Verification *planet9.ChunkVerification `json:"verification,omitempty"`
+    Result       *planet9.ChunkResult       `json:"result,omitempty"`
+    Error        string                     `json:"error,omitempty"`
+}
+
+func init() {
+    planet9Cmd.AddCommand(planet9CollectCmd)
+
+    planet9SearchCmd.Flags().BoolVar(&p9Distribute, "distribute", false, "Split the sweep into contract jobs across providers")
+    planet9SearchCmd.Flags().IntVar(&p9Chunks, "chunks", 0, "Number of chunks for --distribute (0 = one per provider)")
+    planet9SearchCmd.Flags().StringVar(&p9ChunkPayment, "chunk-payment", "", "Payment per chunk (default: priced by points × sim years)")
+    planet9SearchCmd.Flags().StringVar(&p9From, "from", "", "Client key paying for the chunks")
+    planet9SearchCmd.Flags().StringVar(&p9Criteria, "criteria", "reputation", "Provider ranking (reputation, price, speed, availability)")
+    planet9SearchCmd.Flags().String("contract", "", "Contract address (default: contract.address from the config)")
+    planet9SearchCmd.Flags().Bool("native", false, "Sign in-process instead of shelling out to medasdigitald")
+    for _, c := range []*cobra.Command{planet9SearchCmd, planet9CollectCmd} {
+        c.Flags().IntVar(&p9VerifySamples, "verify-samples", 1, "Random points per chunk re-run locally (the chunk's best point is always re-run)")
+        c.Flags().StringVar(&p9PlanFile, "plan", "p9_distributed_plan.json", "Distributed plan file")
+        c.Flags().DurationVar(&p9Wait, "wait", 2*time.Hour, "How long to wait for the chunks")
+    }
+    planet9CollectCmd.Flags().StringVar(&p9OutputFile, "output", "", "Save the best result to file")
+    planet9CollectCmd.Flags().StringVar(&p9OutputFormat, "format", "json", "Output format (json, csv, summary)")
+    planet9CollectCmd.Flags().StringVar(&p9SurfaceFile, "surface-output", "", "Save the merged score surface (.csv or .json)")
+    planet9CollectCmd.Flags().IntVar(&p9TopN, "top", 10, "Number of best grid points to list")
+}
+
+// runPlanet9Distributed submits the sweep chunks to providers and collects them
+func runPlanet9Distributed(cmd *cobra.Command, ranges planet9.ParameterRanges, samples []planet9.SearchParameters,
+    etnos []orbital.OrbitalElements, simDuration float64, seed int64) error {
+    if p9From == "" {
+        return fmt.Errorf("--distribute needs the paying key, use --from")
+    }
+    if p9IncludeKozai || p9IncludeResonance {
+        fmt.Println("⚠ Kozai and resonance diagnostics are only computed for single-point runs")
+    }
+    cfg := loadConfig()
+    contractAddr, err := contractAddress(cmd)
+    if err != nil {
+        return err
+    }
+
+    clientCtx, err := initKeysClientContext()
+    if err != nil {
+        return fmt.Errorf("failed to init keyring: %w", err)
+    }
+    keyInfo, err := clientCtx.Keyring.Key(p9From)
+    if err != nil {
+        return fmt.Errorf("key not found: %w", err)
+    }
+    clientAddr, err := keyInfo.GetAddress()
+    if err != nil {
+        return fmt.Errorf("failed to get address: %w", err)
+    }
+
+    client := contract.NewClient(contract.Config{
+        ContractAddress: contractAddr,
+        RPCEndpoint:     cfg.Chain.RPCEndpoint,
+        ChainID:         cfg.Chain.ID,
+        FeeGranter:      cfg.Client.FeeGranter,
+    }, p9From, clientAddr.String(), cfg.Client.KeyringBackend)
+    signer, err := contractSigner(cmd, cfg, contractAddr, p9From, cfg.Client.KeyringBackend)
+    if err != nil {
+        return err
+    }
+    client.WithSigner(signer)
+
+    ctx := cmd.Context()
+
+    providers, err := client.RankProviders(ctx, planet9.JobType, 0, p9Criteria)
+    if err != nil {
+        return fmt.Errorf("failed to find %s providers: %w", planet9.JobType, err)
+    }
+    n := p9Chunks
+    if n <= 0 {
+        n = len(providers)
+    }
+    chunks := planet9.SplitSweep(samples, etnos, simDuration, p9Integrator, orbital.Frame(p9Frame), n)
+    if len(chunks) < n {
+        fmt.Printf("⚠ Only %d points, using %d chunks\n", len(samples), len(chunks))
+    }
+    if len(chunks) > len(providers) {
+        fmt.Printf("⚠ %d chunks for %d providers, some providers get several\n", len(chunks), len(providers))
+    }
+
+    plan := &distributedPlan{
+        CreatedAt:  time.Now().UTC(),
+        Contract:   contractAddr,
+        From:       p9From,
+        Client:     clientAddr.String(),
+        Integrator: p9Integrator,
+        SimYears:   simDuration,
+        Sampling:   p9Sampling,
+        Points:     len(samples),
+        Ranges:     ranges,
+    }
+    if p9Sampling == planet9.SamplingLatinHypercube {
+        plan.Seed = seed
+    }
+
+    fmt.Printf("Distributing %d points as %d chunks over %d providers...\n", len(samples), len(chunks), min(len(chunks), len(providers)))
+    pricing := compute.NewPricingManager("")
+    for i, c := range chunks {
+        provider := providers[i%len(providers)]
+        dc := distributedChunk{Chunk: c, Provider: provider.Address, Payment: p9ChunkPayment}
+        if dc.Payment == "" {
+            price, err := pricing.CalculatePlanet9Price(len(c.Samples), simDuration, compute.TierStandard, compute.VerificationNone)
+            if err != nil {
+                return err
+            }
+            dc.Payment = fmt.Sprintf("%d%s", max(network.Current().ToBase(price.TotalCost), 1), cfg.Chain.BaseDenom)
+        }
+
+        params, err := chunkJobParams(c)
+        if err != nil {
+            return err
+        }
+        jobID, txHash, err := client.SubmitJob(ctx, provider.Address, planet9.JobType, params, dc.Payment)
+        if err != nil {
+            dc.Status = chunkUnsubmitted
+            dc.Error = err.Error()
+            fmt.Printf("  ❌ chunk %d/%d → %s: %v\n", c.Index+1, len(chunks), provider.Name, err)
+        } else {
+            dc.JobID, dc.TxHash, dc.Status = jobID, txHash, chunkSubmitted
+            fmt.Printf("  ✅ chunk %d/%d (%d points) → %s, job %d, %s\n",
+                c.Index+1, len(chunks), len(c.Samples), provider.Name, jobID, dc.Payment)
+        }
+        plan.Chunks = append(plan.Chunks, dc)
+        if err := saveDistributedPlan(plan, p9PlanFile); err != nil {
+            return fmt.Errorf("failed to save plan: %w", err)
+        }
+    }
+    fmt.Printf("Plan saved to: %s\n\n", p9PlanFile)
+
+    return collectDistributedPlan(ctx, client, plan, p9PlanFile)
+}
+
+func runPlanet9Collect(cmd *cobra.Command, args []string) error {
+    plan, err := loadDistributedPlan(args[0])
+    if err != nil {
+        return err
+    }
+    cfg := loadConfig()
+    client := contract.NewClient(contract.Config{
+        ContractAddress: plan.Contract,
+        RPCEndpoint:     cfg.Chain.RPCEndpoint,
+        ChainID:         cfg.Chain.ID,
+        FeeGranter:      cfg.Client.FeeGranter,
+    }, plan.From, plan.Client, cfg.Client.KeyringBackend)
+
+    ctx := cmd.Context()
+    started := time.Now()
+    err = collectDistributedPlan(ctx, client, plan, args[0])
+    notifyDone(notify.KindPlanet9, fmt.Sprintf("Planet 9 distributed search (%d chunks)", len(plan.Chunks)),
+        "Plan: "+args[0], started, err)
+    return err
+}
+
+// collectDistributedPlan waits for the open chunks, verifies them and merges
+// every verified chunk into the combined report
+func collectDistributedPlan(ctx context.Context, client *contract.Client, plan *distributedPlan, planFile string) error {
+    var (
+        wg sync.WaitGroup
+        mu sync.Mutex
+    )
+    startTime := time.Now()
+    pending := 0
+    for i := range plan.Chunks {
+        dc := &plan.Chunks[i]
+        if dc.JobID == 0 || dc.Status == chunkVerified || dc.Status == chunkFlagged || dc.Status == chunkHashMismatch {
+            continue
+        }
+        pending++
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            collectChunk(ctx, client, dc, &mu)
+            mu.Lock()
+            defer mu.Unlock()
+            printChunkStatus(dc, len(plan.Chunks))
+            if err := saveDistributedPlan(plan, planFile); err != nil {
+                fmt.Printf("⚠ Failed to save plan: %v\n", err)
+            }
+        }()
+    }
+    if pending > 0 {
+        fmt.Printf("Waiting for %d chunks (up to %v)...\n", pending, p9Wait)
+    }
+    wg.Wait()
+
+    var results []*planet9.ChunkResult
+    var flagged []distributedChunk
+    covered := 0
+    for _, dc := range plan.Chunks {
+        switch dc.Status {
+        case chunkVerified:
+            results = append(results, dc.Result)
+            covered += len(dc.Chunk.Samples)
+        case chunkSubmitted:
+        default:
+            flagged = append(flagged, dc)
+        }
+    }
+
+    fmt.Printf("\n=== DISTRIBUTED SEARCH ===\n")
+    fmt.Printf("Chunks verified: %d of %d\n", len(results), len(plan.Chunks))
+    fmt.Printf("Coverage: %d of %d points (%.0f%%)\n", covered, plan.Points, 100*float64(covered)/float64(max(plan.Points, 1)))
+    if len(flagged) > 0 {
+        fmt.Printf("\n⚠ Left out of the merge:\n")
+        for _, dc := range flagged {
+            fmt.Printf("  chunk %d (job %d, %s): %s", dc.Chunk.Index+1, dc.JobID, dc.Provider, dc.Status)
+            if dc.Error != "" {
+                fmt.Printf(", %s", dc.Error)
+            }
+            fmt.Println()
+            if dc.Verification != nil {
+                for _, m := range dc.Verification.Mismatches {
+                    if m.Missing {
+                        fmt.Printf("    point %d missing from the result\n", m.Index)
+                    } else {
+                        fmt.Printf("    point %d: reported %.6f, local %.6f\n", m.Index, m.Reported, m.Local)
+                    }
+                }
+            }
+        }
+    }
+    if len(results) == 0 {
+        return fmt.Errorf("no verified chunks yet, resume with: planet9 collect %s", planFile)
+    }
+
+    merged := planet9.MergeChunks(results)
+    printSweepTop(merged, plan.Integrator)
+    fmt.Printf("Collect Time: %v\n\n", time.Since(startTime).Round(time.Second))
+    printETNOEffects(&merged.Best)
+    if covered < plan.Points {
+        fmt.Printf("\n⚠ Partial result, resume with: planet9 collect %s\n", planFile)
+    }
+
+    if p9OutputFile != "" {
+        if err := saveSearchResults(&merged.Best, p9OutputFile, p9OutputFormat); err != nil {
+            return fmt.Errorf("failed to save results: %w", err)
+        }
+        fmt.Printf("\nBest result saved to: %s\n", p9OutputFile)
+    }
+    if p9SurfaceFile != "" {
+        surface := scoreSurface{
+            Sampling: plan.Sampling,
+            Seed:     plan.Seed,
+            Points:   len(merged.Surface),
+            SimYears: plan.SimYears,
+            Ranges:   plan.Ranges,
+            Frame:    merged.Frame,
+            Surface:  merged.Surface,
+        }
+        if err := saveScoreSurface(&surface, p9SurfaceFile); err != nil {
+            return fmt.Errorf("failed to save score surface: %w", err)
+        }
+        fmt.Printf("Score surface saved to: %s\n", p9SurfaceFile)
+    }
+    return nil
+}
+
+// collectChunk waits for one chunk job, downloads its result, checks it
+// against the anchored hash and re-runs a sample locally. mu guards the
+// chunk while other chunks save the plan.
+func collectChunk(ctx context.Context, client *contract.Client, dc *distributedChunk, mu *sync.Mutex) {
+    fail := func(status, msg string) {
+        mu.Lock()
+        dc.Status, dc.Error = status, msg
+        mu.Unlock()
+    }
+
+    job, err := client.WaitForCompletion(ctx, dc.JobID, p9Wait)
+    if err != nil {
+        fail(chunkFailed, err.Error())
+        return
+    }
+    result, hash, err := fetchChunkResult(ctx, job.ResultURL)
+    if err != nil {
+        fail(chunkFailed, err.Error())
+        return
+    }
+    if hash != job.ResultHash {
+        fail(chunkHashMismatch, fmt.Sprintf("result hash %s, anchored %s", hash, job.ResultHash))
+        return
+    }
+
+    stopTimer := telemetry.Track(telemetry.CategoryCompute, "planet9_chunk_verify")
+    rng := rand.New(rand.NewSource(time.Now().UnixNano() + int64(dc.Chunk.Index)))
+    v := planet9.VerifyChunk(dc.Chunk, result, p9VerifySamples, rng, 1e-9)
+    stopTimer()
+
+    mu.Lock()
+    defer mu.Unlock()
+    dc.ResultHash, dc.Result, dc.Verification, dc.Error = hash, result, &v, ""
+    dc.Status = chunkVerified
+    if !v.Passed() {
+        dc.Status = chunkFlagged
+    }
+}
+
+// fetchChunkResult downloads a chunk result from the provider's /results
+// endpoint and hashes it the way the provider did when completing the job
+func fetchChunkResult(ctx context.Context, url string) (*planet9.ChunkResult, string, error) {
+    if url == "" {
+        return nil, "", fmt.Errorf("job has no result URL")
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+    if err != nil {
+        return nil, "", err
+    }
+    stopTimer := telemetry.Track(telemetry.CategoryRPC, "planet9_chunk_result")
+    resp, err := http.DefaultClient.Do(req)
+    stopTimer()
+    if err != nil {
+        return nil, "", fmt.Errorf("failed to fetch result: %w", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, "", fmt.Errorf("failed to fetch result: %s", resp.Status)
+    }
+
+    var body struct {
+        Result *planet9.ChunkResult `json:"result"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+        return nil, "", fmt.Errorf("invalid result: %w", err)
+    }
+    if body.Result == nil {
+        return nil, "", fmt.Errorf("result is empty")
+    }
+    data, err := json.Marshal(body.Result)
+    if err != nil {
+        return nil, "", err
+    }
+    sum := sha256.Sum256(data)
+    return body.Result, hex.EncodeToString(sum[:]), nil
+}
+
+// chunkJobParams turns a chunk into the parameter map of a contract job
+func chunkJobParams(c planet9.Chunk) (map[string]interface{}, error) {
+    data, err := json.Marshal(c)
+    if err != nil {
+        return nil, err
+    }
+    var params map[string]interface{}
+    if err := json.Unmarshal(data, &params); err != nil {
+        return nil, err
+    }
+    return params, nil
+}
+
+func printChunkStatus(dc *distributedChunk, total int) {
+    icon := "❌"
+    switch dc.Status {
+    case chunkVerified:
+        icon = "✅"
+    case chunkFlagged, chunkHashMismatch:
+        icon = "⚠"
+    }
+    line := fmt.Sprintf("  %s chunk %d/%d (job %d): %s", icon, dc.Chunk.Index+1, total, dc.JobID, strings.ReplaceAll(dc.Status, "_", " "))
+    if dc.Verification != nil {
+        line += fmt.Sprintf(", %d points re-run", len(dc.Verification.Checked))
+    }
+    if dc.Error != "" {
+        line += ", " + dc.Error
+    }
+    fmt.Println(line)
+}
+
+func saveDistributedPlan(plan *distributedPlan, filename string) error {
+    data, err := json.MarshalIndent(plan, "", "  ")
+    if err != nil {
+        return err
+    }
+    return os.WriteFile(filename, data, 0644)
+}
+
+func loadDistributedPlan(filename string) (*distributedPlan, error) {
+    data, err := os.ReadFile(filename)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read plan: %w", err)
+    }
+    var plan distributedPlan
+    if err := json.Unmarshal(data, &plan); err != nil {
+        return nil, fmt.Errorf("invalid plan %s: %w", filename, err)
+    }
+    return &plan, nil
+}
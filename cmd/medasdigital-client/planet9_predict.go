@@ -0,0 +1,241 @@
+package main
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    itypes "github.com/oxygene76/medasdigital-client/internal/types"
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/orbital"
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/planet9"
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/survey"
+    "github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+var planet9PredictCmd = &cobra.Command{
+    Use:   "predict-position <result.json|chain.json>",
+    Short: "Map where on the sky Planet 9 is now",
+    Long: `Turn the best-fit parameters of 'planet9 search --output' or the posterior
+of 'planet9 search --sampler mcmc --chain-output chain.json' into a probability
+map of the current position of Planet 9.
+
+The clustering constrains the orbit but not where Planet 9 is on it, so every
+draw takes a parameter set and a uniform mean anomaly: Planet 9 is most likely
+near aphelion, where it moves slowest. Positions where the --surveys would
+already have detected it are dropped.
+
+The map is a CSV (pixel, ra, dec, probability, density per deg², cumulative,
+magnitude) of the cells with draws, most probable first, or JSON with a .json
+output. 'cumulative' selects credible regions, e.g. cumulative <= 0.9. A
+Planet9Candidate with the predicted position and the search region of the
+--credible level is written next to it.
+
+Examples:
+  medasdigital-client planet9 predict-position p9.json -o p9_map.csv
+  medasdigital-client planet9 predict-position chain.json --scheme healpix --nside 64 --epoch 2027-01-01`,
+    Args: cobra.ExactArgs(1),
+    RunE: runPlanet9Predict,
+}
+
+var (
+    p9PredictOutput    string
+    p9PredictCandidate string
+    p9PredictEpoch     string
+    p9PredictScheme    string
+    p9PredictRes       float64
+    p9PredictNSide     int
+    p9PredictDraws     int
+    p9PredictAlbedo    float64
+    p9PredictSurveys   string
+    p9PredictCredible  float64
+    p9PredictSeed      int64
+)
+
+func init() {
+    planet9Cmd.AddCommand(planet9PredictCmd)
+
+    planet9PredictCmd.Flags().StringVarP(&p9PredictOutput, "output", "o", "planet9_position.csv", "Probability map (.csv or .json)")
+    planet9PredictCmd.Flags().StringVar(&p9PredictCandidate, "candidate-output", "", "Planet9Candidate JSON (default: next to the map)")
+    planet9PredictCmd.Flags().StringVar(&p9PredictEpoch, "epoch", "now", "Date of the prediction (now, J2000, a Julian date or YYYY-MM-DD)")
+    planet9PredictCmd.Flags().StringVar(&p9PredictScheme, "scheme", planet9.SchemeGrid, "Map cells (grid, healpix)")
+    planet9PredictCmd.Flags().Float64Var(&p9PredictRes, "resolution", 1, "Grid cell size in degrees")
+    planet9PredictCmd.Flags().IntVar(&p9PredictNSide, "nside", 32, "HEALPix nside, a power of two")
+    planet9PredictCmd.Flags().IntVar(&p9PredictDraws, "draws", planet9.DefaultDraws, "Positions drawn")
+    planet9PredictCmd.Flags().Float64Var(&p9PredictAlbedo, "albedo", planet9.DefaultAlbedo, "Geometric albedo for the expected magnitudes")
+    planet9PredictCmd.Flags().StringVar(&p9PredictSurveys, "surveys", "default", "Surveys that would have detected Planet 9: default, none or a JSON file of footprints")
+    planet9PredictCmd.Flags().Float64Var(&p9PredictCredible, "credible", 0.68, "Probability of the search region of the candidate")
+    planet9PredictCmd.Flags().Int64Var(&p9PredictSeed, "seed", 1, "Random seed")
+}
+
+func runPlanet9Predict(cmd *cobra.Command, args []string) error {
+    if p9PredictCredible <= 0 || p9PredictCredible > 1 {
+        return fmt.Errorf("--credible must be within 0-1")
+    }
+    epoch := orbital.JulianDate(time.Now())
+    if p9PredictEpoch != "now" {
+        var err error
+        if epoch, err = orbital.ParseEpoch(p9PredictEpoch); err != nil {
+            return err
+        }
+    }
+    exclude, err := survey.ParseSelection(p9PredictSurveys)
+    if err != nil {
+        return err
+    }
+
+    samples, best, score, err := loadPredictionInput(args[0])
+    if err != nil {
+        return err
+    }
+    fmt.Printf("Predicting the position of Planet 9 at JD %.1f from %d parameter sets\n", epoch, len(samples))
+
+    points, excluded, err := planet9.PredictPositions(samples, planet9.PredictOptions{
+        Epoch:   epoch,
+        Draws:   p9PredictDraws,
+        Seed:    p9PredictSeed,
+        Albedo:  p9PredictAlbedo,
+        Exclude: exclude,
+    })
+    if err != nil {
+        return err
+    }
+
+    var m *planet9.SkyMap
+    switch p9PredictScheme {
+    case planet9.SchemeGrid:
+        m, err = planet9.GridMap(points, p9PredictRes)
+    case planet9.SchemeHEALPix:
+        m, err = planet9.HEALPixMap(points, p9PredictNSide)
+    default:
+        err = fmt.Errorf("unknown scheme %q (grid, healpix)", p9PredictScheme)
+    }
+    if err != nil {
+        return err
+    }
+    m.Epoch = epoch
+    m.Excluded = excluded
+
+    candidate := m.Candidate(best, points, p9PredictCredible)
+    if score != 0 {
+        candidate.SupportingEvidence = []itypes.Evidence{{
+            Type:        "orbital_clustering",
+            Strength:    score,
+            Description: "clustering of the simulated ETNO longitudes of perihelion (Rayleigh R)",
+            Reference:   args[0],
+        }}
+    }
+
+    if err := saveSkyMap(m, p9PredictOutput); err != nil {
+        return fmt.Errorf("failed to save map: %w", err)
+    }
+    candidateFile := p9PredictCandidate
+    if candidateFile == "" {
+        candidateFile = strings.TrimSuffix(p9PredictOutput, filepath.Ext(p9PredictOutput)) + "_candidate.json"
+    }
+    data, err := json.MarshalIndent(candidate, "", "  ")
+    if err != nil {
+        return err
+    }
+    if err := os.WriteFile(candidateFile, data, 0644); err != nil {
+        return fmt.Errorf("failed to save candidate: %w", err)
+    }
+
+    if excluded > 0 {
+        fmt.Printf("Excluded by surveys: %d of %d draws (%.1f%%)\n", excluded, excluded+len(points),
+            100*float64(excluded)/float64(excluded+len(points)))
+    }
+    credible := m.Credible(p9PredictCredible)
+    area := 0.0
+    for _, c := range credible {
+        area += c.Probability / c.Density
+    }
+    peak := candidate.PredictedPosition
+    region := candidate.SearchRegion
+    fmt.Printf("Most probable position: RA %.1f°, Dec %+.1f°\n", peak.RA, peak.Dec)
+    fmt.Printf("%.0f%% region: %.1f deg² in %d cells, box RA %.1f±%.1f°, Dec %+.1f±%.1f°, V ≈ %.1f\n",
+        p9PredictCredible*100, area, len(credible),
+        region.CenterRA, region.RadiusRA, region.CenterDec, region.RadiusDec, region.ExpectedMag)
+    fmt.Printf("✅ Map of %d cells → %s, candidate → %s\n", len(m.Cells), p9PredictOutput, candidateFile)
+    return nil
+}
+
+// loadPredictionInput reads the parameter sets to predict from: the best
+// point of a search result or the posterior samples of an MCMC chain. It
+// also returns the best parameters and their clustering score.
+func loadPredictionInput(filename string) ([]planet9.SearchParameters, planet9.SearchParameters, float64, error) {
+    data, err := os.ReadFile(filename)
+    if err != nil {
+        return nil, planet9.SearchParameters{}, 0, fmt.Errorf("failed to read %s: %w", filename, err)
+    }
+    var probe map[string]json.RawMessage
+    if err := json.Unmarshal(data, &probe); err != nil {
+        return nil, planet9.SearchParameters{}, 0, fmt.Errorf("failed to parse %s: %w", filename, err)
+    }
+
+    if _, ok := probe["positions"]; ok {
+        chain, err := planet9.LoadChain(data)
+        if err != nil {
+            return nil, planet9.SearchParameters{}, 0, fmt.Errorf("failed to parse chain %s: %w", filename, err)
+        }
+        rows := chain.Samples()
+        if len(rows) == 0 {
+            return nil, planet9.SearchParameters{}, 0, fmt.Errorf("chain %s has no samples after burn-in", filename)
+        }
+        samples := make([]planet9.SearchParameters, len(rows))
+        for i, x := range rows {
+            samples[i] = chain.Params(x)
+        }
+        best, score := chain.MAP()
+        return samples, best, score, nil
+    }
+
+    var result planet9.SearchResult
+    if err := json.Unmarshal(data, &result); err != nil {
+        return nil, planet9.SearchParameters{}, 0, fmt.Errorf("failed to parse result %s: %w", filename, err)
+    }
+    if result.Parameters.SemiMajorAxis == 0 {
+        return nil, planet9.SearchParameters{}, 0, fmt.Errorf("%s is neither a search result nor a chain", filename)
+    }
+    return []planet9.SearchParameters{result.Parameters}, result.Parameters, result.ClusteringScore, nil
+}
+
+// saveSkyMap writes the map as CSV or, for a .json name, as JSON
+func saveSkyMap(m *planet9.SkyMap, filename string) error {
+    defer telemetry.Track(telemetry.CategoryDisk, "save_results")()
+
+    if strings.EqualFold(filepath.Ext(filename), ".json") {
+        data, err := json.MarshalIndent(m, "", "  ")
+        if err != nil {
+            return err
+        }
+        return os.WriteFile(filename, data, 0644)
+    }
+
+    f, err := os.Create(filename)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+    w := csv.NewWriter(f)
+    w.Write([]string{"pixel", "ra", "dec", "probability", "density_per_deg2", "cumulative", "magnitude"})
+    for _, c := range m.Cells {
+        w.Write([]string{
+            strconv.Itoa(c.Pixel),
+            formatFloat(c.RA),
+            formatFloat(c.Dec),
+            formatFloat(c.Probability),
+            formatFloat(c.Density),
+            formatFloat(c.Cumulative),
+            formatFloat(c.Magnitude),
+        })
+    }
+    w.Flush()
+    return w.Error()
+}
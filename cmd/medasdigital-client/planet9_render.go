@@ -0,0 +1,126 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "github.com/spf13/cobra"
+
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/nbody"
+    "github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+var planet9RenderCmd = &cobra.Command{
+    Use:   "render <snapshots.jsonl>",
+    Short: "Convert a snapshot stream to CSV or VTK for visualization",
+    Long: `Convert the body states streamed by 'planet9 search --snapshot-every-kyr'
+into formats visualization tools read directly. Plain and gzip compressed
+(.jsonl.gz) streams are accepted.
+
+  csv  one row per body and snapshot (time_days, time_years, id, mass, x, y, z,
+       vx, vy, vz), for pandas, gnuplot or TOPCAT
+  vtk  a directory with one legacy VTK file per snapshot and a
+       snapshots.vtk.series index, ParaView opens it as a time series
+
+The format follows the output name: a .csv file renders CSV, anything else is
+used as VTK directory. Positions are in AU and velocities in AU/day, barycentric
+unless --heliocentric is given.
+
+Examples:
+  medasdigital-client planet9 render snapshots.jsonl.gz -o orbits.csv
+  medasdigital-client planet9 render snapshots.jsonl -o vtk/ --bodies Sun,Planet9,ETNO_
+  medasdigital-client planet9 render snapshots.jsonl -o orbits.csv --every 10 --heliocentric`,
+    Args: cobra.ExactArgs(1),
+    RunE: runPlanet9Render,
+}
+
+var (
+    p9RenderOutput       string
+    p9RenderFormat       string
+    p9RenderBodies       string
+    p9RenderEvery        int
+    p9RenderHeliocentric bool
+)
+
+func init() {
+    planet9Cmd.AddCommand(planet9RenderCmd)
+
+    planet9RenderCmd.Flags().StringVarP(&p9RenderOutput, "output", "o", "", "Output CSV file or VTK directory (default: next to the input)")
+    planet9RenderCmd.Flags().StringVar(&p9RenderFormat, "format", "", "Output format (csv, vtk), default from the output name")
+    planet9RenderCmd.Flags().StringVar(&p9RenderBodies, "bodies", "", "Comma-separated body IDs or ID prefixes to keep (default: all)")
+    planet9RenderCmd.Flags().IntVar(&p9RenderEvery, "every", 1, "Keep every n-th snapshot")
+    planet9RenderCmd.Flags().BoolVar(&p9RenderHeliocentric, "heliocentric", false, "Positions and velocities relative to the Sun")
+}
+
+func runPlanet9Render(cmd *cobra.Command, args []string) error {
+    input := args[0]
+    if p9RenderEvery < 1 {
+        return fmt.Errorf("--every must be at least 1")
+    }
+
+    format := strings.ToLower(p9RenderFormat)
+    output := p9RenderOutput
+    if format == "" {
+        format = "vtk"
+        if output == "" || strings.EqualFold(filepath.Ext(output), ".csv") {
+            format = "csv"
+        }
+    }
+    if format != "csv" && format != "vtk" {
+        return fmt.Errorf("unknown format %q (csv, vtk)", p9RenderFormat)
+    }
+    if output == "" {
+        base := strings.TrimSuffix(strings.TrimSuffix(input, ".gz"), ".jsonl")
+        output = base + ".csv"
+        if format == "vtk" {
+            output = base + "_vtk"
+        }
+    }
+
+    opts := nbody.RenderOptions{Every: p9RenderEvery, Heliocentric: p9RenderHeliocentric}
+    for _, id := range strings.Split(p9RenderBodies, ",") {
+        if id = strings.TrimSpace(id); id != "" {
+            opts.Bodies = append(opts.Bodies, id)
+        }
+    }
+
+    r, err := nbody.OpenSnapshots(input)
+    if err != nil {
+        return fmt.Errorf("failed to open snapshots: %w", err)
+    }
+    defer r.Close()
+
+    stopTimer := telemetry.Track(telemetry.CategoryDisk, "snapshot_render")
+    var n int
+    if format == "csv" {
+        f, err := os.Create(output)
+        if err != nil {
+            stopTimer()
+            return fmt.Errorf("failed to create %s: %w", output, err)
+        }
+        n, err = nbody.RenderCSV(r, f, opts)
+        if cerr := f.Close(); err == nil {
+            err = cerr
+        }
+        if err != nil {
+            stopTimer()
+            return fmt.Errorf("failed to render CSV: %w", err)
+        }
+    } else {
+        n, err = nbody.RenderVTK(r, output, opts)
+        if err != nil {
+            stopTimer()
+            return fmt.Errorf("failed to render VTK: %w", err)
+        }
+    }
+    stopTimer()
+
+    if n == 0 {
+        fmt.Printf("⚠ No snapshots in %s\n", input)
+        return nil
+    }
+    fmt.Printf("✅ Rendered %d snapshots as %s → %s\n", n, strings.ToUpper(format), output)
+    return nil
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/astronomy/catalog"
+	"github.com/oxygene76/medasdigital-client/pkg/export"
+	"github.com/oxygene76/medasdigital-client/pkg/plot"
+)
+
+// Size of the panels of multi-panel figures
+const (
+	panelWidth  = 520
+	panelHeight = 360
+)
+
+// Elements and bins of plot orbit by default
+const (
+	defaultOrbitElements = "a,e,i,node,peri,varpi"
+	defaultOrbitBins     = 18
+)
+
+var plotCmd = &cobra.Command{
+	Use:   "plot",
+	Short: "Plot orbits, light curves and clustering as PNG or SVG",
+	Long: `Draw figures of analysis results and ETNO catalogs. The format follows the
+extension of --output (.png or .svg) unless --format is given; the default
+output is <input>_<plot>.png next to the input.
+
+<input> is a result in ~/.medasdigital-client/results/<id>.json or a file:
+an analyze result, a stored result or, for orbit and clustering, an ETNO
+catalog snapshot of 'data update'.`,
+}
+
+var plotOrbitCmd = &cobra.Command{
+	Use:   "orbit <result.json|catalog.json>",
+	Short: "Histograms of the orbital elements",
+	Long: `Plot the distributions of the orbital elements of an orbital dynamics result
+or ETNO catalog: semi-major axis, eccentricity, inclination, longitude of the
+ascending node, argument and longitude of perihelion. A clustered population
+shows as peaks in the node and perihelion angles.
+
+  medasdigital-client plot orbit orbital.json
+  medasdigital-client plot orbit ~/.medasdigital-client/catalog/2026.10.01.json --elements varpi,i -o etnos.svg`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPlotOrbit,
+}
+
+var plotLightcurveCmd = &cobra.Command{
+	Use:   "lightcurve <result.json>",
+	Short: "Phase-folded light curves of a photometric result",
+	Long: `Fold every light curve of a photometric result on its period and plot
+magnitude over two cycles of phase, one panel per object and filter.
+
+The period is --period if given, else the period of the light curve, else the
+highest peak of a Lomb-Scargle periodogram between --min-period and
+--max-period. Phase 0 is the phase_zero of the light curve (JD) or its first
+measurement.
+
+  medasdigital-client plot lightcurve photometric.json
+  medasdigital-client plot lightcurve photometric.json --object 2003VB12 --period 10.27 -o sedna.svg`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPlotLightcurve,
+}
+
+var plotClusteringCmd = &cobra.Command{
+	Use:   "clustering <result.json|catalog.json>",
+	Short: "Scatter plots of semi-major axis, eccentricity and inclination",
+	Long: `Plot e over a, i over a and i over e of the objects of an orbital dynamics
+result or ETNO catalog. With --clusters, the members of each cluster of a
+clustering result are colored, matched by designation.
+
+  medasdigital-client plot clustering orbital.json
+  medasdigital-client plot clustering orbital.json --clusters clustering.json -o aei.svg`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPlotClustering,
+}
+
+func init() {
+	rootCmd.AddCommand(plotCmd)
+	plotCmd.AddCommand(plotOrbitCmd, plotLightcurveCmd, plotClusteringCmd)
+
+	plotCmd.PersistentFlags().StringP("output", "o", "", "Figure file (.png or .svg), default <input>_<plot>.png")
+	plotCmd.PersistentFlags().String("format", "", "Figure format (png, svg), default from the output extension or png")
+
+	plotOrbitCmd.Flags().String("elements", defaultOrbitElements, "Elements to plot (a, e, i, q, node, peri, varpi)")
+	plotOrbitCmd.Flags().Int("bins", defaultOrbitBins, "Histogram bins")
+
+	plotLightcurveCmd.Flags().String("object", "", "Plot only this object")
+	plotLightcurveCmd.Flags().Float64("period", 0, "Fold on this period in days instead of the light curve's")
+	plotLightcurveCmd.Flags().Float64("min-period", 0.05, "Shortest period of the periodogram in days")
+	plotLightcurveCmd.Flags().Float64("max-period", 0, "Longest period of the periodogram in days (0 = half the time span)")
+
+	plotClusteringCmd.Flags().String("clusters", "", "Clustering result whose members are colored")
+}
+
+// orbitRow holds the elements of one object in AU and degrees
+type orbitRow struct {
+	name                   string
+	a, e, i, node, peri, q float64
+}
+
+// plotElements describes the elements plot orbit knows
+var plotElements = map[string]struct {
+	label  string
+	lo, hi float64 // fixed range for angles, else from the data
+	value  func(o orbitRow) float64
+}{
+	"a":     {"a (AU)", 0, 0, func(o orbitRow) float64 { return o.a }},
+	"e":     {"e", 0, 1, func(o orbitRow) float64 { return o.e }},
+	"i":     {"i (deg)", 0, 0, func(o orbitRow) float64 { return o.i }},
+	"q":     {"q (AU)", 0, 0, func(o orbitRow) float64 { return o.q }},
+	"node":  {"Omega (deg)", 0, 360, func(o orbitRow) float64 { return o.node }},
+	"peri":  {"omega (deg)", 0, 360, func(o orbitRow) float64 { return o.peri }},
+	"varpi": {"varpi (deg)", 0, 360, func(o orbitRow) float64 { return math.Mod(o.node+o.peri, 360) }},
+}
+
+func runPlotOrbit(cmd *cobra.Command, args []string) error {
+	elements, _ := cmd.Flags().GetString("elements")
+	bins, _ := cmd.Flags().GetInt("bins")
+	if bins < 1 {
+		return fmt.Errorf("--bins must be positive")
+	}
+
+	input := resultPath(args[0])
+	rows, err := loadOrbitRows(input)
+	if err != nil {
+		return err
+	}
+	figure, err := orbitFigure(rows, strings.Split(elements, ","), bins)
+	if err != nil {
+		return err
+	}
+	return savePlot(cmd, input, "orbit", figure)
+}
+
+// orbitFigure draws a histogram per element, three to a row
+func orbitFigure(rows []orbitRow, elements []string, bins int) (*plot.Figure, error) {
+	var panels []*plot.Figure
+	for _, name := range elements {
+		name = strings.TrimSpace(name)
+		el, ok := plotElements[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown element %q (a, e, i, q, node, peri, varpi)", name)
+		}
+		values := make([]float64, len(rows))
+		for k, o := range rows {
+			values[k] = el.value(o)
+		}
+		lo, hi := el.lo, el.hi
+		if hi <= lo {
+			lo, hi = values[0], values[0]
+			for _, v := range values {
+				lo, hi = math.Min(lo, v), math.Max(hi, v)
+			}
+			if hi == lo {
+				lo, hi = lo-1, hi+1
+			}
+		}
+		panels = append(panels, plot.Histogram(plot.Chart{
+			Title:  fmt.Sprintf("%s of %d objects", el.label, len(rows)),
+			XLabel: el.label,
+			Width:  panelWidth,
+			Height: panelHeight,
+		}, values, lo, hi, bins))
+	}
+	return plot.Grid(min(len(panels), 3), panels...), nil
+}
+
+func runPlotLightcurve(cmd *cobra.Command, args []string) error {
+	object, _ := cmd.Flags().GetString("object")
+	fixedPeriod, _ := cmd.Flags().GetFloat64("period")
+	minPeriod, _ := cmd.Flags().GetFloat64("min-period")
+	maxPeriod, _ := cmd.Flags().GetFloat64("max-period")
+
+	input := resultPath(args[0])
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return fmt.Errorf("failed to read result: %w", err)
+	}
+	ds, err := export.Load(data, export.TypePhotometric)
+	if err != nil {
+		return err
+	}
+
+	// Measurements per object and filter, in the order of the light curves
+	type curve struct {
+		object, filter    string
+		period, phaseZero float64
+		times, magnitudes []float64
+	}
+	var curves []*curve
+	index := map[string]*curve{}
+	if t := ds.Table("light_curves"); t != nil {
+		ids, filters := t.Column("object_id"), t.Column("filter")
+		periods, zeros := t.Floats("period"), t.Floats("phase_zero")
+		for i := 0; ids != nil && i < t.Rows(); i++ {
+			c := &curve{object: ids.Strings[i]}
+			if filters != nil {
+				c.filter = filters.Strings[i]
+			}
+			if i < len(periods) {
+				c.period = periods[i]
+			}
+			if i < len(zeros) {
+				c.phaseZero = zeros[i]
+			}
+			curves = append(curves, c)
+			index[c.object+"\x00"+c.filter] = c
+		}
+	}
+	points := ds.Table("light_curve_points")
+	if points == nil {
+		return fmt.Errorf("%s has no light curve measurements", input)
+	}
+	ids, filters := points.Column("object_id"), points.Column("filter")
+	times, mags := points.Floats("time"), points.Floats("magnitude")
+	for i := 0; i < points.Rows(); i++ {
+		key := ids.Strings[i] + "\x00" + filters.Strings[i]
+		c := index[key]
+		if c == nil {
+			c = &curve{object: ids.Strings[i], filter: filters.Strings[i]}
+			curves = append(curves, c)
+			index[key] = c
+		}
+		if !math.IsNaN(mags[i]) {
+			c.times = append(c.times, times[i])
+			c.magnitudes = append(c.magnitudes, mags[i])
+		}
+	}
+
+	var panels []*plot.Figure
+	for _, c := range curves {
+		if (object != "" && c.object != object) || len(c.times) == 0 {
+			continue
+		}
+		period, source := c.period, "light curve"
+		if fixedPeriod > 0 {
+			period, source = fixedPeriod, "--period"
+		} else if period <= 0 {
+			var power float64
+			period, power, err = plot.BestPeriod(c.times, c.magnitudes, minPeriod, maxPeriod)
+			if err != nil {
+				fmt.Printf("⚠️  %s %s: %v, skipped\n", c.object, c.filter, err)
+				continue
+			}
+			source = fmt.Sprintf("periodogram, power %.2f", power)
+		}
+		epoch := c.phaseZero
+		if epoch == 0 {
+			epoch = c.times[0]
+		}
+		phases := plot.Fold(c.times, period, epoch)
+		second := make([]float64, len(phases))
+		for k, p := range phases {
+			second[k] = p + 1
+		}
+
+		name := strings.TrimSpace(c.object + " " + c.filter)
+		fmt.Printf("   %-24s %5d points, P = %.5f d (%s)\n", name, len(c.times), period, source)
+		panels = append(panels, plot.Scatter(plot.Chart{
+			Title:   fmt.Sprintf("%s, P = %.5g d", name, period),
+			XLabel:  "phase",
+			YLabel:  "magnitude",
+			Width:   panelWidth,
+			Height:  panelHeight,
+			XMin:    0,
+			XMax:    2,
+			InvertY: true,
+		}, plot.Series{X: append(phases, second...), Y: append(c.magnitudes, c.magnitudes...)}))
+	}
+	if len(panels) == 0 {
+		if object != "" {
+			return fmt.Errorf("no light curve of %s in %s", object, input)
+		}
+		return fmt.Errorf("no light curve in %s could be folded", input)
+	}
+	return savePlot(cmd, input, "lightcurve", plot.Grid(min(len(panels), 2), panels...))
+}
+
+func runPlotClustering(cmd *cobra.Command, args []string) error {
+	clustersFile, _ := cmd.Flags().GetString("clusters")
+
+	input := resultPath(args[0])
+	rows, err := loadOrbitRows(input)
+	if err != nil {
+		return err
+	}
+
+	// One series per cluster, the objects in none last
+	groups := map[string][]orbitRow{}
+	var names []string
+	if clustersFile != "" {
+		membership, err := loadClusterMembership(resultPath(clustersFile))
+		if err != nil {
+			return err
+		}
+		for _, o := range rows {
+			name := "no cluster"
+			if id, ok := membership[normalizeDesignation(o.name)]; ok {
+				name = fmt.Sprintf("cluster %d", id)
+			}
+			if _, ok := groups[name]; !ok {
+				names = append(names, name)
+			}
+			groups[name] = append(groups[name], o)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if (names[i] == "no cluster") != (names[j] == "no cluster") {
+				return names[j] == "no cluster"
+			}
+			return names[i] < names[j]
+		})
+	} else {
+		names = []string{""}
+		groups[""] = rows
+	}
+
+	scatter := func(title, xLabel, yLabel string, x, y func(orbitRow) float64) *plot.Figure {
+		var series []plot.Series
+		for _, name := range names {
+			s := plot.Series{Name: name}
+			for _, o := range groups[name] {
+				s.X = append(s.X, x(o))
+				s.Y = append(s.Y, y(o))
+			}
+			series = append(series, s)
+		}
+		return plot.Scatter(plot.Chart{Title: title, XLabel: xLabel, YLabel: yLabel, Width: panelWidth, Height: panelHeight}, series...)
+	}
+	a := func(o orbitRow) float64 { return o.a }
+	e := func(o orbitRow) float64 { return o.e }
+	i := func(o orbitRow) float64 { return o.i }
+	figure := plot.Grid(3,
+		scatter("Eccentricity over semi-major axis", "a (AU)", "e", a, e),
+		scatter("Inclination over semi-major axis", "a (AU)", "i (deg)", a, i),
+		scatter("Inclination over eccentricity", "e", "i (deg)", e, i))
+	return savePlot(cmd, input, "clustering", figure)
+}
+
+// loadOrbitRows reads the objects of an orbital dynamics result or the
+// ETNOs of a catalog snapshot
+func loadOrbitRows(path string) ([]orbitRow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	var rows []orbitRow
+	if _, ok := probe["etnos"]; ok {
+		snap, err := catalog.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range snap.ETNOs {
+			el := o.OrbitalElements
+			rows = append(rows, orbitRow{
+				name: firstNonEmptyString(o.Designation, o.Name),
+				a:    el.SemiMajorAxis, e: el.Eccentricity, i: el.Inclination,
+				node: el.LongitudeAscendingNode, peri: el.ArgumentPerihelion,
+			})
+		}
+	} else {
+		ds, err := export.Load(data, export.TypeOrbitalDynamics)
+		if err != nil {
+			return nil, err
+		}
+		t := ds.Table("objects")
+		a, e, inc := t.Floats("semimajor_axis"), t.Floats("eccentricity"), t.Floats("inclination")
+		node, peri := t.Floats("longitude_node"), t.Floats("argument_periapsis")
+		designations := t.Column("designation")
+		if len(a) == 0 || len(e) != len(a) || len(inc) != len(a) || len(node) != len(a) || len(peri) != len(a) {
+			return nil, fmt.Errorf("%s has no objects with orbital elements", path)
+		}
+		for k := range a {
+			row := orbitRow{a: a[k], e: e[k], i: inc[k], node: node[k], peri: peri[k]}
+			if designations != nil {
+				row.name = designations.Strings[k]
+			}
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s has no objects", path)
+	}
+	for k := range rows {
+		rows[k].q = rows[k].a * (1 - rows[k].e)
+		rows[k].node = math.Mod(rows[k].node+360, 360)
+		rows[k].peri = math.Mod(rows[k].peri+360, 360)
+	}
+	return rows, nil
+}
+
+// loadClusterMembership maps the normalized designations of the members of
+// a clustering result to their cluster
+func loadClusterMembership(path string) (map[string]int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clusters: %w", err)
+	}
+	ds, err := export.Load(data, export.TypeClustering)
+	if err != nil {
+		return nil, err
+	}
+	t := ds.Table("cluster_members")
+	ids, members := t.Column("cluster_id"), t.Column("object_id")
+	if ids == nil || members == nil {
+		return nil, fmt.Errorf("%s has no cluster members", path)
+	}
+	membership := make(map[string]int64, t.Rows())
+	for k := 0; k < t.Rows(); k++ {
+		membership[normalizeDesignation(members.Strings[k])] = ids.Ints[k]
+	}
+	return membership, nil
+}
+
+// normalizeDesignation matches "2012 VP113" with "2012VP113"
+func normalizeDesignation(s string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(s), ""))
+}
+
+func firstNonEmptyString(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// savePlot writes figure to --output, by default <input>_<name>.<format>
+func savePlot(cmd *cobra.Command, input, name string, figure *plot.Figure) error {
+	output, _ := cmd.Flags().GetString("output")
+	format, _ := cmd.Flags().GetString("format")
+	if format == "" {
+		format = plot.FormatPNG
+		if output != "" {
+			format = plot.Format(output)
+		}
+	}
+	if format != plot.FormatPNG && format != plot.FormatSVG {
+		return fmt.Errorf("unknown format %q (%s or %s)", format, plot.FormatPNG, plot.FormatSVG)
+	}
+	if output == "" {
+		output = strings.TrimSuffix(input, filepath.Ext(input)) + "_" + name + "." + format
+	} else if plot.Format(output) != format {
+		output = strings.TrimSuffix(output, filepath.Ext(output)) + "." + format
+	}
+	if err := figure.Save(output); err != nil {
+		return fmt.Errorf("failed to save figure: %w", err)
+	}
+	fmt.Printf("✅ %s plot → %s\n", name, output)
+	return nil
+}
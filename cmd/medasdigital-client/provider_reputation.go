@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/oxygene76/medasdigital-client/pkg/contract"
+)
+
+// reputationTTL is how long aggregated provider history is served from memory
+const reputationTTL = 5 * time.Minute
+
+// ReputationCache keeps the aggregated provider history of the compute
+// contract, rebuilding it at most every reputationTTL
+type ReputationCache struct {
+	client  *contract.Client
+	opts    contract.ReputationOptions
+	mu      sync.Mutex
+	reps    []contract.ProviderReputation
+	fetched time.Time
+}
+
+// NewReputationCache aggregates provider history from the given contract
+func NewReputationCache(client *contract.Client, opts contract.ReputationOptions) *ReputationCache {
+	return &ReputationCache{client: client, opts: opts}
+}
+
+// List returns every provider's reputation, best first, and when it was aggregated
+func (rc *ReputationCache) List(ctx context.Context) ([]contract.ProviderReputation, time.Time, error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.reps != nil && time.Since(rc.fetched) < reputationTTL {
+		return rc.reps, rc.fetched, nil
+	}
+	reps, err := rc.client.ProviderReputations(ctx, rc.opts)
+	if err != nil {
+		if rc.reps != nil {
+			return rc.reps, rc.fetched, nil // stale beats nothing while the node is down
+		}
+		return nil, time.Time{}, err
+	}
+	rc.reps, rc.fetched = reps, time.Now()
+	return rc.reps, rc.fetched, nil
+}
+
+// providerListResponse is the body of GET /providers
+type providerListResponse struct {
+	Providers    []contract.ProviderReputation `json:"providers"`
+	Count        int                           `json:"count"`
+	AggregatedAt time.Time                     `json:"aggregated_at"`
+}
+
+// handleListProviderReputations serves the aggregated history of all providers
+func (rps *RealPaymentService) handleListProviderReputations(w http.ResponseWriter, r *http.Request) {
+	if rps.reputations == nil {
+		http.Error(w, "No compute contract configured", http.StatusServiceUnavailable)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+	defer cancel()
+
+	reps, fetched, err := rps.reputations.List(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to aggregate provider history: %v", err), http.StatusBadGateway)
+		return
+	}
+	if r.URL.Query().Get("active") == "true" {
+		active := make([]contract.ProviderReputation, 0, len(reps))
+		for _, rep := range reps {
+			if rep.Active {
+				active = append(active, rep)
+			}
+		}
+		reps = active
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(providerListResponse{
+		Providers:    reps,
+		Count:        len(reps),
+		AggregatedAt: fetched,
+	})
+}
+
+// providerResponse is the body of GET /providers/{addr}
+type providerResponse struct {
+	Rank       int                         `json:"rank"`
+	Reputation contract.ProviderReputation `json:"reputation"`
+}
+
+// handleGetProviderReputation serves the aggregated history of one provider
+func (rps *RealPaymentService) handleGetProviderReputation(w http.ResponseWriter, r *http.Request) {
+	if rps.reputations == nil {
+		http.Error(w, "No compute contract configured", http.StatusServiceUnavailable)
+		return
+	}
+	addr := mux.Vars(r)["addr"]
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+	defer cancel()
+
+	reps, _, err := rps.reputations.List(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to aggregate provider history: %v", err), http.StatusBadGateway)
+		return
+	}
+	for i, rep := range reps {
+		if rep.Address == addr {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(providerResponse{Rank: i + 1, Reputation: rep})
+			return
+		}
+	}
+	http.Error(w, "Provider not found", http.StatusNotFound)
+}
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/gorilla/mux"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+	"github.com/oxygene76/medasdigital-client/pkg/contract"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+	"github.com/oxygene76/medasdigital-client/pkg/notify"
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// Refund reasons
+const (
+	RefundUnderpaid     = "underpaid"
+	RefundOverpaid      = "overpaid"
+	RefundInvoiceClosed = "invoice_not_payable"
+	RefundJobFailed     = "job_failed"
+	RefundLimitExceeded = "limit_exceeded"
+	RefundRejected      = "rejected" // invalid memo, job that cannot be priced or denom not accepted
+)
+
+// Refund status values
+const (
+	RefundQueued  = "queued"
+	RefundSent    = "sent"
+	RefundFailed  = "failed"
+	RefundSkipped = "skipped"
+)
+
+// maxRefundAttempts is how often a refund is broadcast before it needs a manual retry
+const maxRefundAttempts = 5
+
+// refundInclusionTimeout is how long a broadcast refund may take to be
+// included in a block before the attempt counts as failed
+const refundInclusionTimeout = 2 * time.Minute
+
+// defaultRefundFee is kept from each refund to pay the network fee of the refund tx
+const defaultRefundFee = 5000
+
+// Refund returns (part of) a payment to its sender
+type Refund struct {
+	ID           string    `json:"id"`
+	PaymentTx    string    `json:"payment_tx_hash"`
+	Recipient    string    `json:"recipient"`
+	Amount       baseUnits `json:"amount"` // in Denom, sent to the recipient with the fee deducted
+	Denom        string    `json:"denom"`
+	FeeUmedas    int64     `json:"fee_umedas"` // network fee of the refund tx
+	Reason       string    `json:"reason"`
+	JobID        string    `json:"job_id,omitempty"`
+	InvoiceID    string    `json:"invoice_id,omitempty"`
+	Status       string    `json:"status"`
+	RefundTx     string    `json:"refund_tx_hash,omitempty"`
+	Attempts     int       `json:"attempts"`
+	Error        string    `json:"error,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// RefundQueue keeps pending and processed refunds on disk
+type RefundQueue struct {
+	mu      sync.Mutex
+	path    string
+	refunds map[string]*Refund // by ID
+	wake    chan struct{}
+}
+
+// NewRefundQueue loads the refunds from disk
+func NewRefundQueue(path string) *RefundQueue {
+	q := &RefundQueue{path: path, refunds: make(map[string]*Refund), wake: make(chan struct{}, 1)}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var list []*Refund
+		if err := json.Unmarshal(data, &list); err != nil {
+			log.Printf("⚠️ Could not parse refunds %s: %v", path, err)
+		}
+		for _, r := range list {
+			r.Amount.Int = r.Amount.value()
+			q.refunds[r.ID] = r
+		}
+	}
+	return q
+}
+
+// Enqueue adds a refund, a payment is refunded at most once per reason.
+// It reports whether the refund was added.
+func (q *RefundQueue) Enqueue(r *Refund) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	r.ID = r.PaymentTx + ":" + r.Reason
+	if r.JobID != "" {
+		// Jobs of a bundle payment are refunded separately
+		r.ID += ":" + r.JobID
+	}
+	if _, exists := q.refunds[r.ID]; exists {
+		return false
+	}
+	now := time.Now()
+	r.CreatedAt, r.UpdatedAt = now, now
+	r.Status = RefundQueued
+	if !r.Amount.IsPositive() {
+		r.Status = RefundSkipped
+		r.Error = "amount does not cover the network fee"
+	}
+	q.refunds[r.ID] = r
+	if err := q.save(); err != nil {
+		log.Printf("⚠️ Could not save refunds: %v", err)
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// Queued returns copies of the refunds waiting to be sent, oldest first
+func (q *RefundQueue) Queued() []Refund {
+	return q.List(RefundQueued, true)
+}
+
+// List returns copies of the refunds with the given status (all if empty)
+func (q *RefundQueue) List(status string, oldestFirst bool) []Refund {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	list := make([]Refund, 0, len(q.refunds))
+	for _, r := range q.refunds {
+		if status == "" || r.Status == status {
+			list = append(list, *r)
+		}
+	}
+	sort.Slice(list, func(a, b int) bool {
+		if oldestFirst {
+			return list[a].CreatedAt.Before(list[b].CreatedAt)
+		}
+		return list[a].CreatedAt.After(list[b].CreatedAt)
+	})
+	return list
+}
+
+// Broadcast records the transaction of an attempt before it is included, so
+// a later attempt can check it first
+func (q *RefundQueue) Broadcast(id, txHash string) {
+	q.update(id, func(r *Refund) {
+		r.RefundTx = txHash
+	})
+}
+
+// Sent records the refund transaction once it is included in a block
+func (q *RefundQueue) Sent(id, txHash string) {
+	q.update(id, func(r *Refund) {
+		r.Status = RefundSent
+		r.RefundTx = txHash
+		r.Attempts++
+		r.Error = ""
+	})
+}
+
+// Failed records a failed attempt, the refund stays queued until maxRefundAttempts
+func (q *RefundQueue) Failed(id string, err error) {
+	q.update(id, func(r *Refund) {
+		r.Attempts++
+		r.Error = err.Error()
+		if r.Attempts >= maxRefundAttempts {
+			r.Status = RefundFailed
+		}
+	})
+}
+
+// Retry queues a failed refund again
+func (q *RefundQueue) Retry(id string) (Refund, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	r, ok := q.refunds[id]
+	if !ok {
+		return Refund{}, fmt.Errorf("refund %s not found", id)
+	}
+	if r.Status != RefundFailed {
+		return Refund{}, fmt.Errorf("refund %s is %s", id, r.Status)
+	}
+	if !r.Amount.IsPositive() {
+		return Refund{}, fmt.Errorf("refund %s has to be sent manually: %s", id, r.Error)
+	}
+	r.Status = RefundQueued
+	r.Attempts = 0
+	r.UpdatedAt = time.Now()
+	if err := q.save(); err != nil {
+		return Refund{}, err
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return *r, nil
+}
+
+func (q *RefundQueue) update(id string, fn func(r *Refund)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	r, ok := q.refunds[id]
+	if !ok {
+		return
+	}
+	fn(r)
+	r.UpdatedAt = time.Now()
+	if err := q.save(); err != nil {
+		log.Printf("⚠️ Could not save refunds: %v", err)
+	}
+}
+
+func (q *RefundQueue) save() error {
+	defer telemetry.Track(telemetry.CategoryDisk, "save_refunds")()
+
+	list := make([]*Refund, 0, len(q.refunds))
+	for _, r := range q.refunds {
+		list = append(list, r)
+	}
+	if err := os.MkdirAll(filepath.Dir(q.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.path)
+}
+
+// queueRefund returns coin minus the network fee to recipient. For IBC denoms
+// the fee is converted with the configured rate.
+func (rps *RealPaymentService) queueRefund(paymentTx, recipient string, coin sdk.Coin, reason, jobID, invoiceID string) {
+	if recipient == "" || recipient == rps.serviceAddr {
+		return
+	}
+	r := &Refund{
+		PaymentTx: paymentTx,
+		Recipient: recipient,
+		Denom:     coin.Denom,
+		FeeUmedas: rps.refundFee,
+		Reason:    reason,
+		JobID:     jobID,
+		InvoiceID: invoiceID,
+	}
+	r.Amount = baseUnits{coin.Amount.Sub(rps.refundFeeIn(coin.Denom))}
+	if !rps.refunds.Enqueue(r) {
+		return
+	}
+
+	switch {
+	case r.Status == RefundSkipped:
+		log.Printf("↩️ Payment %s (%s) is not refunded: %s does not cover the network fee", paymentTx, reason, coin)
+	case rps.refundSender == nil:
+		log.Printf("↩️ Refund of %s%s to %s queued (%s), no refund key configured, send it manually", r.Amount.Int, r.Denom, recipient, reason)
+	default:
+		log.Printf("↩️ Refund of %s%s to %s queued (%s)", r.Amount.Int, r.Denom, recipient, reason)
+	}
+}
+
+// refundRejected returns a paid transfer that cannot create a job, in the
+// accepted payment coin or else the first coin of the transfer
+func (rps *RealPaymentService) refundRejected(t blockchain.Transfer, invoiceID string) {
+	coin, _, ok := rps.paymentCoin(t.Amount)
+	if !ok {
+		if len(t.Amount) == 0 {
+			return
+		}
+		coin = t.Amount[0]
+	}
+	rps.queueRefund(t.TxHash, t.Sender, coin, RefundRejected, "", invoiceID)
+}
+
+// handleJobFailed refunds the price of a paid job that failed, or returns
+// it to the quota of a subscription
+func (rps *RealPaymentService) handleJobFailed(job *compute.ComputeJob) {
+	rps.handleJobFinished(job)
+	go notifyDone(notify.KindJob, fmt.Sprintf("Paid job %s of %s", job.ID, job.ClientAddr), "",
+		job.SubmittedAt, fmt.Errorf("job failed: %s", job.Error))
+	if !job.PaymentVerified || job.PriceBreakdown == nil {
+		return
+	}
+	// Prepaid jobs get their quota back instead of a refund
+	if rps.subscriptions.Return(job.ClientAddr, job.ID) {
+		log.Printf("📅 Job %s failed, its price is back in the subscription quota of %s", job.ID, job.ClientAddr)
+		return
+	}
+	denom := job.PaymentDenom
+	if denom == "" {
+		denom = network.Current().BaseDenom
+	}
+	amount := sdk.NewCoin(denom, rps.priceIn(denom, job.PriceBreakdown.TotalCost))
+	rps.queueRefund(job.PaymentTxHash, job.ClientAddr, amount, RefundJobFailed, job.ID, "")
+}
+
+// runRefunds sends queued refunds from the service wallet until ctx is done
+func (rps *RealPaymentService) runRefunds(ctx context.Context) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	// With --dry-run refunds stay queued, each is simulated once
+	simulated := make(map[string]bool)
+	for {
+		for _, r := range rps.refunds.Queued() {
+			if ctx.Err() != nil {
+				return
+			}
+			if simulated[r.ID] {
+				continue
+			}
+			simulated[r.ID] = rps.sendRefund(ctx, r)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-rps.refunds.wake:
+		}
+	}
+}
+
+// sendRefund sends a queued refund and waits for it to be included in a
+// block, it reports whether it was only simulated
+func (rps *RealPaymentService) sendRefund(ctx context.Context, r Refund) bool {
+	amount := sdk.NewCoins(sdk.NewCoin(r.Denom, r.Amount.Int))
+	fee := sdk.NewCoins(sdk.NewInt64Coin(network.Current().BaseDenom, r.FeeUmedas))
+	memo := fmt.Sprintf("Refund of payment %s (%s)", r.PaymentTx, r.Reason)
+
+	// The transaction of the last attempt may have been included after all
+	if r.RefundTx != "" && rps.waitForRefund(ctx, r.RefundTx, 10*time.Second) == nil {
+		rps.refundIncluded(r, amount[0], r.RefundTx)
+		return false
+	}
+
+	res, err := rps.refundSender.Send(ctx, r.Recipient, amount, fee, memo)
+	if errors.Is(err, blockchain.ErrDryRun) {
+		log.Printf("🧪 Refund %s to %s simulated, it stays queued", r.ID, r.Recipient)
+		return true
+	}
+	if err == nil {
+		rps.refunds.Broadcast(r.ID, res.TxHash)
+		// Dropped or failed in the block, the refund is retried
+		err = rps.waitForRefund(ctx, res.TxHash, refundInclusionTimeout)
+	}
+	if err != nil {
+		rps.refunds.Failed(r.ID, err)
+		log.Printf("❌ Refund %s to %s failed (attempt %d/%d): %v", r.ID, r.Recipient, r.Attempts+1, maxRefundAttempts, err)
+		return false
+	}
+	rps.refundIncluded(r, amount[0], res.TxHash)
+	return false
+}
+
+// waitForRefund waits up to timeout for a refund transaction to be included
+// without an error
+func (rps *RealPaymentService) waitForRefund(ctx context.Context, txHash string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	_, err := rps.refundSender.WaitForTx(ctx, txHash)
+	return err
+}
+
+// refundIncluded marks a refund sent and books it in the ledger
+func (rps *RealPaymentService) refundIncluded(r Refund, amount sdk.Coin, txHash string) {
+	rps.refunds.Sent(r.ID, txHash)
+	rps.ledger.Refunded(r.PaymentTx, r.JobID, amount)
+	log.Printf("✅ Refunded %s to %s for payment %s (tx %s)", amount, r.Recipient, r.PaymentTx, txHash)
+}
+
+// adminOnly requires the admin token as bearer token, admin routes are off without one
+func (rps *RealPaymentService) adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if contract.AdminAuthorized(w, r, rps.adminToken, "service") {
+			next(w, r)
+		}
+	}
+}
+
+// refundListResponse is the body of GET /admin/refunds
+type refundListResponse struct {
+	Refunds          []Refund `json:"refunds"`
+	Count            int      `json:"count"`
+	Refunded         string   `json:"refunded"` // total of the sent refunds
+	Automatic        bool     `json:"automatic"`
+	NetworkFeeUmedas int64    `json:"network_fee_umedas"`
+}
+
+// handleListRefunds lists refunds, newest first, optionally filtered by ?status=
+func (rps *RealPaymentService) handleListRefunds(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	refunds := rps.refunds.List(status, false)
+
+	total := sdk.NewCoins()
+	for _, ref := range refunds {
+		if ref.Status == RefundSent {
+			total = total.Add(sdk.NewCoin(ref.Denom, ref.Amount.Int))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(refundListResponse{
+		Refunds:          refunds,
+		Count:            len(refunds),
+		Refunded:         total.String(),
+		Automatic:        rps.refundSender != nil,
+		NetworkFeeUmedas: rps.refundFee,
+	})
+}
+
+// handleRetryRefund queues a failed refund again
+func (rps *RealPaymentService) handleRetryRefund(w http.ResponseWriter, r *http.Request) {
+	refund, err := rps.refunds.Retry(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(refund)
+}
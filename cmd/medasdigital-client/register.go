@@ -2,6 +2,7 @@
 package main
 
 import (
+	"encoding/base64"
 	"fmt"
 	"strings"
 	"time"
@@ -10,9 +11,9 @@ import (
 	
 	"github.com/cosmos/cosmos-sdk/client"
 	"github.com/cosmos/cosmos-sdk/client/flags"
-	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
 	
 	blockchain "github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/chat"
 )
 
 // registerCmd represents the register command with enhanced features
@@ -55,7 +56,7 @@ func init() {
 	
 	// Global register flags
 	registerCmd.PersistentFlags().String("from", "", "Key name to sign transaction (required)")
-	registerCmd.PersistentFlags().String("keyring-backend", "test", "Keyring backend (test|file|os)")
+	registerCmd.PersistentFlags().String("keyring-backend", "", "Keyring backend (test|file|os, default client.keyring_backend)")
 	registerCmd.PersistentFlags().Uint64("gas", 0, "Manual gas limit (0 = auto estimation)")
 	registerCmd.PersistentFlags().StringSlice("capabilities", []string{}, "Client capabilities")
 	registerCmd.PersistentFlags().String("metadata", "", "Additional metadata (legacy)")
@@ -73,6 +74,7 @@ func init() {
 	registerChatCmd.Flags().StringSlice("expertise", []string{}, "Your research expertise areas")
 	registerChatCmd.Flags().String("contact", "", "Contact information (optional)")
 	registerChatCmd.Flags().String("type", "researcher", "Registration type (researcher|institution|student|developer)")
+	registerChatCmd.Flags().StringSlice("chat-endpoint", []string{}, "URL where 'chat serve' accepts messages (optional)")
 	
 	// Mark required flags for chat registration
 	registerChatCmd.MarkFlagRequired("display-name")
@@ -96,7 +98,7 @@ func runSimpleRegistration(cmd *cobra.Command, args []string) error {
 	
 	// Get flags
 	from, _ := cmd.Flags().GetString("from")
-	keyringBackend, _ := cmd.Flags().GetString("keyring-backend")
+	keyringBackend := keyringBackendFlag(cmd)
 	// ENTFERNT: gas, _ := cmd.Flags().GetUint64("gas")
 	capabilities, _ := cmd.Flags().GetStringSlice("capabilities")
 	metadata, _ := cmd.Flags().GetString("metadata")
@@ -114,6 +116,15 @@ func runSimpleRegistration(cmd *cobra.Command, args []string) error {
 	
 	fmt.Printf("👤 Key: %s\n", from)
 	fmt.Printf("🔧 Capabilities: %v\n", capabilities)
+	for _, capability := range capabilities {
+		if capability == "gpu_compute" {
+			if ok, info := testGPUAvailability(); ok {
+				fmt.Printf("🎮 GPU: %s\n", info)
+			} else {
+				fmt.Printf("⚠️  gpu_compute requested but %s\n", info)
+			}
+		}
+	}
 	if metadata != "" {
 		fmt.Printf("📋 Metadata: %s\n", metadata)
 	}
@@ -143,7 +154,11 @@ func runSimpleRegistration(cmd *cobra.Command, args []string) error {
 	cfg := loadConfig()
 	fmt.Printf("🔍 Testing connection to %s...\n", cfg.Chain.RPCEndpoint)
 	
-	if err := testBlockchainConnection(cfg.Chain.RPCEndpoint); err != nil {
+	if err := testBlockchainConnection(cmd.Context(), cfg.Chain.RPCEndpoint); err != nil {
+		if dryRun {
+			// --dry-run needs the node to simulate against
+			return err
+		}
 		fmt.Printf("⚠️  Blockchain connection failed: %v\n", err)
 		fmt.Println("💡 Running in simulation mode...")
 		return simulateRegistration(from, addr.String(), capabilities, metadata)
@@ -157,20 +172,31 @@ func runSimpleRegistration(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create RPC client: %w", err)
 	}
 
-	txConfig := authtx.NewTxConfig(globalCodec, authtx.DefaultSignModes)
+	granter, err := feeGranter()
+	if err != nil {
+		return err
+	}
+
+	txConfig := currentApp().TxConfig()
 	fullClientCtx := clientCtx.
 		WithFromName(from).
 		WithFromAddress(addr).
 		WithTxConfig(txConfig).
 		WithClient(rpcClient).
 		WithChainID(cfg.Chain.ID).
-		WithCodec(globalCodec).
-		WithInterfaceRegistry(globalInterfaceRegistry).
-		WithBroadcastMode(flags.BroadcastSync)
+		WithCodec(currentApp().Codec()).
+		WithInterfaceRegistry(currentApp().InterfaceRegistry()).
+		WithBroadcastMode(flags.BroadcastSync).
+		WithFeeGranterAddress(granter).
+		WithSimulation(dryRun).
+		WithCmdContext(cmd.Context())
 	
 	// Perform simple registration using new package
 	result, err := blockchain.RegisterClientSimple(fullClientCtx, addr.String(), capabilities, metadata, 0)
 	if err != nil {
+		if dryRun {
+			return err
+		}
 		fmt.Printf("❌ Registration failed: %v\n", err)
 		fmt.Println("💡 Falling back to simulation...")
 		return simulateRegistration(from, addr.String(), capabilities, metadata)
@@ -186,7 +212,7 @@ func runChatRegistration(cmd *cobra.Command, args []string) error {
 	
 	// Get flags
 	from, _ := cmd.Flags().GetString("from")
-	keyringBackend, _ := cmd.Flags().GetString("keyring-backend")
+	keyringBackend := keyringBackendFlag(cmd)
 	// ENTFERNT: gas, _ := cmd.Flags().GetUint64("gas")
 	capabilities, _ := cmd.Flags().GetStringSlice("capabilities")
 	
@@ -197,6 +223,7 @@ func runChatRegistration(cmd *cobra.Command, args []string) error {
 	expertise, _ := cmd.Flags().GetStringSlice("expertise")
 	contact, _ := cmd.Flags().GetString("contact")
 	regType, _ := cmd.Flags().GetString("type")
+	endpoints, _ := cmd.Flags().GetStringSlice("chat-endpoint")
 	
 	// Validate required flags
 	if from == "" {
@@ -245,7 +272,11 @@ func runChatRegistration(cmd *cobra.Command, args []string) error {
 	cfg := loadConfig()
 	fmt.Printf("🔍 Testing connection to %s...\n", cfg.Chain.RPCEndpoint)
 	
-	if err := testBlockchainConnection(cfg.Chain.RPCEndpoint); err != nil {
+	if err := testBlockchainConnection(cmd.Context(), cfg.Chain.RPCEndpoint); err != nil {
+		if dryRun {
+			// --dry-run needs the node to simulate against
+			return err
+		}
 		fmt.Printf("⚠️  Blockchain connection failed: %v\n", err)
 		fmt.Println("💡 Running in simulation mode...")
 		return simulateChatRegistration(from, addr.String(), displayName, institution, capabilities)
@@ -259,16 +290,33 @@ func runChatRegistration(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create RPC client: %w", err)
 	}
 
-	txConfig := authtx.NewTxConfig(globalCodec, authtx.DefaultSignModes)
+	granter, err := feeGranter()
+	if err != nil {
+		return err
+	}
+
+	txConfig := currentApp().TxConfig()
 	fullClientCtx := clientCtx.
 		WithFromName(from).
 		WithFromAddress(addr).
 		WithTxConfig(txConfig).
 		WithClient(rpcClient).
 		WithChainID(cfg.Chain.ID).
-		WithCodec(globalCodec).
-		WithInterfaceRegistry(globalInterfaceRegistry).
-		WithBroadcastMode(flags.BroadcastSync)
+		WithCodec(currentApp().Codec()).
+		WithInterfaceRegistry(currentApp().InterfaceRegistry()).
+		WithBroadcastMode(flags.BroadcastSync).
+		WithFeeGranterAddress(granter).
+		WithSimulation(dryRun).
+		WithCmdContext(cmd.Context())
+
+	// Messages to this client are encrypted to its chat key
+	chatKey, created, err := chat.LoadOrCreateKey(homeDir, addr.String())
+	if err != nil {
+		return err
+	}
+	if created {
+		fmt.Printf("🔑 New chat key saved to %s\n", chat.KeyPath(homeDir, addr.String()))
+	}
 
 	// Create enhanced registration data
 	registration := &blockchain.ChatClientRegistration{
@@ -280,6 +328,8 @@ func runChatRegistration(cmd *cobra.Command, args []string) error {
 		Expertise:        expertise,
 		ContactInfo:      contact,
 		RegistrationType: regType,
+		ChatPubKey:       chatKey.PublicKey().Bytes(),
+		ChatEndpoints:    endpoints,
 		Timestamp:        time.Now(),
 		Version:          "1.0.0",
 	}
@@ -287,6 +337,9 @@ func runChatRegistration(cmd *cobra.Command, args []string) error {
 	// Perform enhanced registration
 	result, err := blockchain.RegisterChatClient(fullClientCtx, registration)
 	if err != nil {
+		if dryRun {
+			return err
+		}
 		fmt.Printf("❌ Chat registration failed: %v\n", err)
 		fmt.Println("💡 Falling back to simulation...")
 		return simulateChatRegistration(from, addr.String(), displayName, institution, capabilities)
@@ -342,7 +395,7 @@ func displayChatRegistrationSuccess(result *blockchain.RegistrationResult, chain
 		fmt.Printf("📊 Type: %s\n", regData.RegistrationType)
 		fmt.Printf("🔧 Capabilities: %v\n", regData.Capabilities)
 		if len(regData.ChatPubKey) > 0 {
-			fmt.Printf("🔑 Chat Key: Generated (%d bytes)\n", len(regData.ChatPubKey))
+			fmt.Printf("🔑 Chat Key: %s\n", base64.StdEncoding.EncodeToString(regData.ChatPubKey))
 		}
 	}
 	
@@ -356,8 +409,8 @@ func displayChatRegistrationSuccess(result *blockchain.RegistrationResult, chain
 	fmt.Println("✅ Your chat client is now ready for scientific collaboration!")
 	fmt.Println("\n💡 Next steps:")
 	fmt.Println("   1. Check status: ./bin/medasdigital-client status")
-	fmt.Println("   2. Discover peers: ./bin/medasdigital-client chat peers")
-	fmt.Println("   3. Start chatting: ./bin/medasdigital-client chat start")
+	fmt.Println("   2. Discover peers: ./bin/medasdigital-client peers list --type chat")
+	fmt.Println("   3. Start chatting: ./bin/medasdigital-client chat send <address> <message> --from <key>")
 	
 	return nil
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/export"
+	"github.com/oxygene76/medasdigital-client/pkg/report"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Reports of analysis and Planet 9 results",
+}
+
+// reportGenerateCmd writes a Markdown or PDF report of a result
+var reportGenerateCmd = &cobra.Command{
+	Use:   "generate <result.json|id>",
+	Short: "Turn an orbital, photometric, clustering or Planet 9 result into a Markdown or PDF report",
+	Long: `Write a report of a result with a parameter summary, its tables, plots and
+the blockchain record that verifies it (transaction hash, block height, client
+and the SHA-256 of the result file).
+
+Orbital dynamics reports plot eccentricity over semi-major axis and the
+longitudes of perihelion, photometric reports the light curves and clustering
+reports the cluster sizes. Planet 9 search results (planet9 search --output)
+list the best-fit parameters, the effect on every ETNO and the clone intervals.
+
+Markdown reports save their plots as PNG files in <report>_figures/, PDF
+reports embed them. Tables are cut to --max-rows; 'results export' writes the
+complete data.
+
+<id> is a result in ~/.medasdigital-client/results/<id>.json or a file.
+
+  medasdigital-client report generate orbital.json
+  medasdigital-client report generate p9.json -o p9_report.pdf
+  medasdigital-client report generate 4f2a9c --format pdf --title "Q3 ETNO clustering"`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		resultType, _ := cmd.Flags().GetString("type")
+		title, _ := cmd.Flags().GetString("title")
+		maxRows, _ := cmd.Flags().GetInt("max-rows")
+
+		input := resultPath(args[0])
+		data, err := os.ReadFile(input)
+		if err != nil {
+			return fmt.Errorf("failed to read result: %w", err)
+		}
+
+		if format == "" {
+			format = report.FormatMarkdown
+			if strings.EqualFold(filepath.Ext(output), ".pdf") {
+				format = report.FormatPDF
+			}
+		}
+		if format != report.FormatMarkdown && format != report.FormatPDF {
+			return fmt.Errorf("unknown format %q (%s or %s)", format, report.FormatMarkdown, report.FormatPDF)
+		}
+		if output == "" {
+			output = strings.TrimSuffix(input, filepath.Ext(input)) + "_report." + format
+		}
+
+		doc, err := report.Load(data, report.Options{
+			Title:   title,
+			Type:    resultType,
+			Source:  filepath.Base(input),
+			MaxRows: maxRows,
+		})
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create report: %w", err)
+		}
+		if format == report.FormatPDF {
+			err = report.WritePDF(doc, f)
+		} else {
+			figures := strings.TrimSuffix(output, filepath.Ext(output)) + "_figures"
+			err = report.WriteMarkdown(doc, f, filepath.Dir(output), figures)
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+
+		fmt.Printf("✅ %s → %s\n", doc.Title, output)
+		for _, s := range doc.Sections {
+			fmt.Printf("   %-24s %d fields, %d tables, %d figures\n", s.Title, len(s.Fields), len(s.Tables), len(s.Figures))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.AddCommand(reportGenerateCmd)
+
+	reportGenerateCmd.Flags().String("format", "", "Report format (md, pdf), default from the output extension or md")
+	reportGenerateCmd.Flags().StringP("output", "o", "", "Report file, default <result>_report.<format> next to the input")
+	reportGenerateCmd.Flags().String("type", "", "Result type ("+strings.Join(append(export.Types(), report.TypePlanet9), ", ")+"), default from the result")
+	reportGenerateCmd.Flags().String("title", "", "Report title, default from the result type")
+	reportGenerateCmd.Flags().Int("max-rows", report.DefaultMaxRows, "Rows per table")
+}
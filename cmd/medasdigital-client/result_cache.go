@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+)
+
+// ResultCacheConfig is payment.result_cache: identical jobs are answered
+// from the results of earlier ones at a fraction of the price
+type ResultCacheConfig struct {
+	Enabled     bool          `yaml:"enabled" mapstructure:"enabled"`
+	TTL         time.Duration `yaml:"ttl,omitempty" mapstructure:"ttl"`                   // default 720h
+	MaxEntries  int           `yaml:"max_entries,omitempty" mapstructure:"max_entries"`   // least recently used go first, default 200
+	PriceFactor float64       `yaml:"price_factor,omitempty" mapstructure:"price_factor"` // share of the full price, default 0.1
+}
+
+// withDefaults fills the unset values
+func (c ResultCacheConfig) withDefaults() ResultCacheConfig {
+	if c.TTL == 0 {
+		c.TTL = compute.DefaultCacheTTL
+	}
+	if c.MaxEntries == 0 {
+		c.MaxEntries = compute.DefaultCacheEntries
+	}
+	if c.PriceFactor == 0 {
+		c.PriceFactor = compute.DefaultCachePriceFactor
+	}
+	return c
+}
+
+// Validate checks the values of payment.result_cache
+func (c ResultCacheConfig) Validate() error {
+	if c.TTL < 0 || c.MaxEntries < 0 {
+		return fmt.Errorf("payment.result_cache: ttl and max_entries must not be negative")
+	}
+	if c.PriceFactor < 0 || c.PriceFactor > 1 {
+		return fmt.Errorf("payment.result_cache.price_factor must be between 0 and 1, got %g", c.PriceFactor)
+	}
+	return nil
+}
+
+// resultCacheDir is where cached results are kept
+func resultCacheDir() string {
+	return filepath.Join(homeDir, "result-cache")
+}
+
+// handleCacheStats returns the size, hits and price of the result cache
+func (rps *RealPaymentService) handleCacheStats(w http.ResponseWriter, r *http.Request) {
+	cache := rps.jobManager.ResultCache()
+	if cache == nil {
+		http.Error(w, "Result cache is disabled (--result-cache)", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cache.Stats())
+}
+
+// awaitingPayment reports whether a job answered from the cache waits for
+// its payment: its result is withheld until the payment is verified, a
+// computed one would not be ready either
+func awaitingPayment(job *compute.ComputeJob) bool {
+	return job.Cache != nil && !job.PaymentVerified
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/export"
+	"github.com/oxygene76/medasdigital-client/pkg/votable"
+)
+
+// resultsExportCmd converts a result to HDF5, Parquet or VOTable
+var resultsExportCmd = &cobra.Command{
+	Use:   "export <id|file>",
+	Short: "Export an orbital dynamics, photometric or clustering result to HDF5, Parquet or VOTable",
+	Long: `Convert a result to columnar files for numerical tools. Every list in the
+result becomes a table (objects, predictions, light_curve_points, clusters,
+cluster_members, ...) with one column per field; scalar values and metadata
+are stored as attributes.
+
+  hdf5     one file, a group per table and a dataset per column, attributes
+           on the root group (h5py, HDFView, MATLAB)
+  parquet  a directory with one <table>.parquet per table, attributes as
+           key/value metadata (pandas, Arrow, DuckDB, Spark)
+  votable  one VOTable 1.4 file with a TABLE per table and attributes as
+           PARAMs, ra/dec columns carry UCDs (TOPCAT, Aladin, astropy)
+
+<id> is a result in ~/.medasdigital-client/results/<id>.json or a file, e.g.
+written by analyze --output. The result type is read from analysis_type
+unless --type is given.
+
+  medasdigital-client results export orbital.json --format hdf5
+  medasdigital-client results export orbital.json --format parquet -o orbital_parquet/
+  medasdigital-client results export orbital.json --format votable`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		resultType, _ := cmd.Flags().GetString("type")
+
+		input := resultPath(args[0])
+		data, err := os.ReadFile(input)
+		if err != nil {
+			return fmt.Errorf("failed to read result: %w", err)
+		}
+		ds, err := export.Load(data, resultType)
+		if err != nil {
+			return err
+		}
+		if len(ds.Tables) == 0 {
+			return fmt.Errorf("the %s result in %s has no tabular data to export", ds.Type, input)
+		}
+
+		base := strings.TrimSuffix(input, filepath.Ext(input))
+		switch format {
+		case export.FormatHDF5:
+			if output == "" {
+				output = base + ".h5"
+			}
+			if err := export.WriteHDF5(output, ds); err != nil {
+				return fmt.Errorf("HDF5 export failed: %w", err)
+			}
+			fmt.Printf("✅ %s result exported to %s\n", ds.Type, output)
+		case export.FormatParquet:
+			if output == "" {
+				output = base + "_parquet"
+			}
+			paths, err := export.WriteParquet(output, ds)
+			if err != nil {
+				return fmt.Errorf("Parquet export failed: %w", err)
+			}
+			fmt.Printf("✅ %s result exported to %s\n", ds.Type, output)
+			for _, p := range paths {
+				fmt.Printf("   %s\n", filepath.Base(p))
+			}
+		case export.FormatVOTable:
+			if output == "" {
+				output = base + ".vot"
+			}
+			if err := writeVOTableFile(output, votable.FromDataset(filepath.Base(base), ds)); err != nil {
+				return fmt.Errorf("VOTable export failed: %w", err)
+			}
+			fmt.Printf("✅ %s result exported to %s\n", ds.Type, output)
+		default:
+			return fmt.Errorf("unknown format %q (%s, %s or %s)", format, export.FormatHDF5, export.FormatParquet, export.FormatVOTable)
+		}
+
+		for _, t := range ds.Tables {
+			fmt.Printf("   %-22s %6d rows, %d columns\n", t.Name, t.Rows(), len(t.Columns))
+		}
+		return nil
+	},
+}
+
+func init() {
+	resultsCmd.AddCommand(resultsExportCmd)
+
+	resultsExportCmd.Flags().String("format", export.FormatHDF5, "Output format (hdf5, parquet, votable)")
+	resultsExportCmd.Flags().StringP("output", "o", "", "Output file (hdf5, votable) or directory (parquet), default next to the input")
+	resultsExportCmd.Flags().String("type", "", "Result type ("+strings.Join(export.Types(), ", ")+"), default from analysis_type")
+}
+
+// writeVOTableFile writes doc to path
+func writeVOTableFile(path string, doc votable.Document) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := votable.Write(f, doc); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// localResultTables loads the exportable results in the results directory for
+// the TAP service, tables are named <result>_<table>
+func localResultTables() ([]*export.Table, error) {
+	paths, err := filepath.Glob(filepath.Join(homeDir, "results", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var tables []*export.Table
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		ds, err := export.Load(data, "")
+		if err != nil {
+			continue
+		}
+		base := strings.TrimSuffix(filepath.Base(p), ".json")
+		for _, t := range ds.Tables {
+			tables = append(tables, &export.Table{Name: tapTableName(base + "_" + t.Name), Columns: t.Columns})
+		}
+	}
+	return tables, nil
+}
+
+// tapTableName makes name a plain ADQL identifier
+func tapTableName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, name)
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "t_" + name
+	}
+	return name
+}
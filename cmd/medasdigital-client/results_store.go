@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/internal/types"
+)
+
+// resultsStoreCmd stores a local result file on chain
+var resultsStoreCmd = &cobra.Command{
+	Use:   "store <file>",
+	Short: "Store a local result file on chain",
+	Long: `Store a JSON result on chain. Files saved by the analyze and ai commands are
+stored as they are; any other JSON object, such as the tracklets of 'ai link',
+is stored as the data of a result of --type.
+
+  medasdigital-client results store detections_xmatch_tracklets.json --type tracklet_linking`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		analysisType, _ := cmd.Flags().GetString("type")
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", args[0], err)
+		}
+
+		var result types.AnalysisResult
+		if err := json.Unmarshal(data, &result); err != nil || result.AnalysisType == "" || result.Data == nil {
+			var doc map[string]interface{}
+			if err := json.Unmarshal(data, &doc); err != nil {
+				return fmt.Errorf("%s is not a JSON object: %w", args[0], err)
+			}
+			if analysisType == "" {
+				return fmt.Errorf("%s is not an analysis result, set its --type", args[0])
+			}
+			result = types.AnalysisResult{Data: doc, Timestamp: time.Now()}
+		}
+		if analysisType != "" {
+			result.AnalysisType = analysisType
+		}
+		if result.Metadata == nil {
+			result.Metadata = map[string]string{}
+		}
+		result.Metadata["source_file"] = args[0]
+
+		c, err := analysisClient()
+		if err != nil {
+			return err
+		}
+		if err := c.StoreResult(cmd.Context(), &result); err != nil {
+			return err
+		}
+		fmt.Printf("Stored %s result from %s\n", result.AnalysisType, args[0])
+		return nil
+	},
+}
+
+func init() {
+	resultsCmd.AddCommand(resultsStoreCmd)
+
+	resultsStoreCmd.Flags().String("type", "", "Analysis type, required for files that are not analysis results")
+}
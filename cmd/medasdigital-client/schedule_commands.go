@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/notify"
+	"github.com/oxygene76/medasdigital-client/pkg/scheduler"
+)
+
+// scheduleCmd manages recurring commands
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Run client commands on a cron schedule",
+	Long: `Register client commands with a cron schedule and run them from a long-lived
+daemon ('schedule daemon'). Jobs, run history and logs are kept in
+<home>/scheduler; jobs added or removed while the daemon runs are picked up
+within 30 seconds. Failed runs are sent to the notification channels of the
+config (event kind schedule).`,
+}
+
+// scheduleAddCmd registers a job
+var scheduleAddCmd = &cobra.Command{
+	Use:   "add <cron> <command...>",
+	Short: "Schedule a client command",
+	Long: `Schedule a client command. The schedule is a five field cron expression
+(minute hour day-of-month month day-of-week, with *, lists, ranges and steps),
+@hourly, @daily, @weekly, @monthly, @yearly or "@every <duration>". Flags of
+the scheduled command follow it; flags of add go before the schedule.
+
+  medasdigital-client schedule add "0 3 * * *" analyze photometric survey.csv
+  medasdigital-client schedule add --name nightly "30 6 * * mon-fri" pipeline run night.yaml
+  medasdigital-client schedule add "@every 6h" index sync`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		job := &scheduler.Job{Schedule: args[0], Args: args[1:]}
+		job.Name, _ = cmd.Flags().GetString("name")
+		job.TimeZone, _ = cmd.Flags().GetString("tz")
+		job.NotifySuccess, _ = cmd.Flags().GetBool("notify-success")
+
+		target, _, err := rootCmd.Find(job.Args)
+		if err != nil || target == rootCmd {
+			return fmt.Errorf("unknown command %q", strings.Join(job.Args, " "))
+		}
+		if target.HasParent() && target.Parent() == scheduleCmd {
+			return fmt.Errorf("schedule commands cannot be scheduled")
+		}
+
+		store, err := scheduler.Load(scheduler.Dir(homeDir))
+		if err != nil {
+			return err
+		}
+		if job.Name != "" {
+			if _, ok := store.Find(job.Name); ok {
+				return fmt.Errorf("a job named %s exists already", job.Name)
+			}
+		}
+		if err := store.Add(job); err != nil {
+			return err
+		}
+		if err := store.Save(); err != nil {
+			return err
+		}
+		sched, _ := job.Parse()
+		fmt.Printf("✅ Scheduled job %s: %s\n", job.ID, strings.Join(job.Args, " "))
+		fmt.Printf("   Next run: %s\n", sched.Next(time.Now()).Format("2006-01-02 15:04 MST"))
+		if scheduler.DaemonPID(scheduler.Dir(homeDir)) == 0 {
+			fmt.Println("   The scheduler daemon is not running, start it with: schedule daemon")
+		}
+		return nil
+	},
+}
+
+// scheduleListCmd lists the jobs
+var scheduleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List scheduled jobs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := scheduler.Dir(homeDir)
+		store, err := scheduler.Load(dir)
+		if err != nil {
+			return err
+		}
+		if len(store.Jobs) == 0 {
+			fmt.Println("No scheduled jobs")
+			return nil
+		}
+		last := map[string]scheduler.Run{}
+		runs, _ := scheduler.History(dir, "", 0)
+		for i := len(runs) - 1; i >= 0; i-- {
+			last[runs[i].JobID] = runs[i]
+		}
+
+		fmt.Printf("%-8s %-16s %-16s %-17s %-8s %s\n", "ID", "Name", "Schedule", "Next run", "Last", "Command")
+		fmt.Println(strings.Repeat("-", 100))
+		now := time.Now()
+		for _, j := range store.Jobs {
+			next := "invalid"
+			if sched, err := j.Parse(); err == nil {
+				next = sched.Next(now).Format("2006-01-02 15:04")
+			}
+			if j.Paused {
+				next = "paused"
+			}
+			status := "-"
+			if r, ok := last[j.ID]; ok {
+				status = r.Status
+			}
+			fmt.Printf("%-8s %-16s %-16s %-17s %-8s %s\n", j.ID, j.Name, j.Schedule, next, status, strings.Join(j.Args, " "))
+		}
+		if pid := scheduler.DaemonPID(dir); pid > 0 {
+			fmt.Printf("\nDaemon running (pid %d)\n", pid)
+		} else {
+			fmt.Println("\nDaemon not running")
+		}
+		return nil
+	},
+}
+
+// scheduleRemoveCmd deletes a job
+var scheduleRemoveCmd = &cobra.Command{
+	Use:   "remove <id|name>",
+	Short: "Remove a scheduled job",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := scheduler.Load(scheduler.Dir(homeDir))
+		if err != nil {
+			return err
+		}
+		job, ok := store.Remove(args[0])
+		if !ok {
+			return fmt.Errorf("no job %s", args[0])
+		}
+		if err := store.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Removed job %s (%s)\n", job.ID, job.Label())
+		return nil
+	},
+}
+
+// setPaused pauses or resumes a job
+func setPaused(key string, paused bool) error {
+	store, err := scheduler.Load(scheduler.Dir(homeDir))
+	if err != nil {
+		return err
+	}
+	job, ok := store.Find(key)
+	if !ok {
+		return fmt.Errorf("no job %s", key)
+	}
+	job.Paused = paused
+	if err := store.Save(); err != nil {
+		return err
+	}
+	state := "resumed"
+	if paused {
+		state = "paused"
+	}
+	fmt.Printf("✅ Job %s %s\n", job.ID, state)
+	return nil
+}
+
+var schedulePauseCmd = &cobra.Command{
+	Use:   "pause <id|name>",
+	Short: "Pause a scheduled job",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setPaused(args[0], true)
+	},
+}
+
+var scheduleResumeCmd = &cobra.Command{
+	Use:   "resume <id|name>",
+	Short: "Resume a paused job",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setPaused(args[0], false)
+	},
+}
+
+// scheduleHistoryCmd shows past runs
+var scheduleHistoryCmd = &cobra.Command{
+	Use:   "history [id|name]",
+	Short: "Show the run history of all or one job",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, _ := cmd.Flags().GetInt("limit")
+		dir := scheduler.Dir(homeDir)
+		jobID := ""
+		if len(args) == 1 {
+			jobID = args[0]
+			if store, err := scheduler.Load(dir); err == nil {
+				if j, ok := store.Find(args[0]); ok {
+					jobID = j.ID
+				}
+			}
+		}
+		runs, err := scheduler.History(dir, jobID, limit)
+		if err != nil {
+			return err
+		}
+		if len(runs) == 0 {
+			fmt.Println("No runs recorded")
+			return nil
+		}
+		fmt.Printf("%-8s %-24s %-19s %-9s %-8s %s\n", "Job", "Label", "Started", "Duration", "Status", "Log / error")
+		fmt.Println(strings.Repeat("-", 100))
+		for _, r := range runs {
+			detail := r.Log
+			if r.Error != "" {
+				detail = r.Error
+			}
+			fmt.Printf("%-8s %-24s %-19s %-9s %-8s %s\n", r.JobID, truncate(r.Label, 24), r.Started.Format("2006-01-02 15:04:05"),
+				r.Finished.Sub(r.Started).Round(time.Second), r.Status, detail)
+		}
+		return nil
+	},
+}
+
+// scheduleRunCmd runs a job immediately
+var scheduleRunCmd = &cobra.Command{
+	Use:   "run <id|name>",
+	Short: "Run a scheduled job now",
+	Long:  "Run a job once in the foreground; the run is recorded in the history and notified like a scheduled run.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		d, err := newScheduleDaemon()
+		if err != nil {
+			return err
+		}
+		store, err := scheduler.Load(d.Dir)
+		if err != nil {
+			return err
+		}
+		job, ok := store.Find(args[0])
+		if !ok {
+			return fmt.Errorf("no job %s", args[0])
+		}
+		ctx := cmd.Context()
+		r := d.RunNow(ctx, job)
+		fmt.Printf("Job %s %s in %s, log: %s\n", job.ID, r.Status, r.Finished.Sub(r.Started).Round(time.Second), r.Log)
+		if r.Status == scheduler.StatusFailed {
+			return fmt.Errorf("job %s failed: %s", job.ID, r.Error)
+		}
+		return nil
+	},
+}
+
+// scheduleDaemonCmd runs the scheduler in the foreground
+var scheduleDaemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run the scheduler daemon",
+	Long: `Run scheduled jobs until interrupted. Keep it running with systemd, tmux or
+nohup; only one daemon runs per home directory. On shutdown running jobs are
+cancelled and recorded as failed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		d, err := newScheduleDaemon()
+		if err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+		return d.Run(ctx)
+	},
+}
+
+// newScheduleDaemon builds a daemon running this client, notifying failures
+// and, if the job asks for it, successes
+func newScheduleDaemon() (*scheduler.Daemon, error) {
+	run, err := clientCommand()
+	if err != nil {
+		return nil, err
+	}
+	return &scheduler.Daemon{
+		Dir: scheduler.Dir(homeDir),
+		Exec: func(ctx context.Context, args []string, out io.Writer) error {
+			return run(ctx, "", args, out)
+		},
+		OnFinish: func(j *scheduler.Job, r scheduler.Run) {
+			if r.Status == scheduler.StatusOK && !j.NotifySuccess {
+				return
+			}
+			var runErr error
+			if r.Status != scheduler.StatusOK {
+				runErr = fmt.Errorf("%s: %s (log %s)", r.Status, r.Error, r.Log)
+			}
+			notifyDone(notify.KindSchedule, "Scheduled job "+j.Label(), "Log: "+r.Log, r.Started, runErr)
+		},
+		Logger: log.New(os.Stderr, "scheduler: ", log.LstdFlags),
+	}, nil
+}
+
+// truncate shortens s to n characters
+func truncate(s string, n int) string {
+	if len([]rune(s)) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+func init() {
+	rootCmd.AddCommand(scheduleCmd)
+	scheduleCmd.AddCommand(scheduleAddCmd, scheduleListCmd, scheduleRemoveCmd, schedulePauseCmd,
+		scheduleResumeCmd, scheduleHistoryCmd, scheduleRunCmd, scheduleDaemonCmd)
+
+	// Flags after the schedule belong to the scheduled command
+	scheduleAddCmd.Flags().SetInterspersed(false)
+	scheduleAddCmd.Flags().String("name", "", "Job name, usable instead of the ID")
+	scheduleAddCmd.Flags().String("tz", "", "Time zone of the schedule, e.g. UTC (default local time)")
+	scheduleAddCmd.Flags().Bool("notify-success", false, "Notify successful runs too, not only failures")
+
+	scheduleHistoryCmd.Flags().Int("limit", 20, "Number of runs to show, 0 for all")
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/internal/e2e"
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/contract"
+)
+
+// selftestCmd groups checks operators run after installing or upgrading
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Validate this installation",
+}
+
+// selftestE2ECmd runs the job lifecycle against in-process services
+var selftestE2ECmd = &cobra.Command{
+	Use:   "e2e",
+	Short: "Run the end-to-end job lifecycle against in-process services",
+	Long: `Start the free service, a payment service with mocked payment verification and a
+provider node without chain access in-process, then drive a PI job through each of
+them via their public HTTP APIs.
+
+Use --free-url, --payment-url and --provider-url to check a running deployment instead
+of the in-process service. Payments against a real payment service are not mocked, so
+only point --payment-url at a test deployment.
+
+Example:
+  medasdigital-client selftest e2e
+  medasdigital-client selftest e2e --free-url http://localhost:8080`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		digits, _ := cmd.Flags().GetInt("digits")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		freeURL, _ := cmd.Flags().GetString("free-url")
+		paymentURL, _ := cmd.Flags().GetString("payment-url")
+		providerURL, _ := cmd.Flags().GetString("provider-url")
+
+		workDir, err := os.MkdirTemp("", "medas-e2e-")
+		if err != nil {
+			return fmt.Errorf("failed to create work directory: %w", err)
+		}
+		defer os.RemoveAll(workDir)
+
+		serviceStartTime = time.Now()
+		services := e2eServices(workDir)
+
+		harness := e2e.New(services, e2e.Options{
+			Digits:      digits,
+			JobTimeout:  timeout,
+			FreeURL:     freeURL,
+			PaymentURL:  paymentURL,
+			ProviderURL: providerURL,
+		})
+		harness.Start()
+		defer harness.Close()
+
+		fmt.Println("🧪 Running end-to-end self test...")
+		ctx, cancel := context.WithTimeout(cmd.Context(), 3*timeout)
+		defer cancel()
+		report := harness.Run(ctx)
+
+		fmt.Println()
+		for _, step := range report.Steps {
+			switch {
+			case step.Skipped:
+				fmt.Printf("⏭️  %-18s skipped (%s)\n", step.Name, step.Detail)
+			case step.Passed:
+				fmt.Printf("✅ %-18s %-10s %s\n", step.Name, step.Duration.Round(time.Millisecond), step.Detail)
+			default:
+				fmt.Printf("❌ %-18s %-10s %s\n", step.Name, step.Duration.Round(time.Millisecond), step.Error)
+			}
+		}
+		fmt.Println(strings.Repeat("-", 60))
+
+		if !report.Passed {
+			return fmt.Errorf("self test failed after %v", report.Duration.Round(time.Millisecond))
+		}
+		fmt.Printf("✅ All checks passed in %v\n", report.Duration.Round(time.Millisecond))
+		return nil
+	},
+}
+
+// e2eServices builds the in-process services, nothing is sent to the chain
+func e2eServices(workDir string) e2e.Services {
+	free := NewSecureFreeTestService(2, time.Minute, true)
+
+	payment := NewRealPaymentService("e2e-service", "e2e-community", 0.15, 1, 2, 2)
+	payment.communityHistory = NewCommunityHistory(filepath.Join(workDir, "distributions.json"))
+	payment.invoices = NewInvoiceStore(filepath.Join(workDir, "invoices.json"))
+	payment.refunds = NewRefundQueue(filepath.Join(workDir, "refunds.json"))
+	payment.ledger = NewLedger(filepath.Join(workDir, "ledger.json"))
+	payment.checkPaymentFn = func(txHash, senderAddr, denom string, expectedMEDAS float64) (*blockchain.PaymentCheck, error) {
+		return &blockchain.PaymentCheck{Verified: strings.HasPrefix(txHash, "E2E"), Expected: expectedMEDAS, Denom: denom}, nil
+	}
+
+	provider := contract.NewProviderNode(
+		"", "e2e-provider", "", "", "",
+		"E2E Provider", "http://localhost",
+		0, 2,
+		"", 0, 0, 0, 0,
+	)
+
+	return e2e.Services{
+		Free:     free.Router(),
+		Payment:  payment.Router(),
+		Provider: provider.Handler(),
+		RunProviderJob: func(ctx context.Context, params map[string]interface{}) (string, string, error) {
+			job, err := provider.RunJob(ctx, params, "e2e-client")
+			if err != nil {
+				return "", "", err
+			}
+			return job.ID, contract.ResultHash(job), nil
+		},
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(selftestCmd)
+	selftestCmd.AddCommand(selftestE2ECmd)
+
+	selftestE2ECmd.Flags().Int("digits", 50, "PI digits per test job")
+	selftestE2ECmd.Flags().Duration("timeout", 2*time.Minute, "Timeout per job")
+	selftestE2ECmd.Flags().String("free-url", "", "Test a running free service instead of the in-process one")
+	selftestE2ECmd.Flags().String("payment-url", "", "Test a running payment service instead of the in-process one")
+	selftestE2ECmd.Flags().String("provider-url", "", "Test a running provider node instead of the in-process one")
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdh"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	comethttp "github.com/cometbft/cometbft/rpc/client/http"
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/chat"
+	"github.com/oxygene76/medasdigital-client/pkg/peers"
+)
+
+// resultsShareCmd encrypts a result for registered clients and anchors it on chain
+var resultsShareCmd = &cobra.Command{
+	Use:   "share <id|file> --to <client-id>",
+	Short: "Share a result with registered chat clients",
+	Long: `Encrypt a result to the chat key of each recipient and anchor the SHA-256 of
+the encrypted blob on chain in a minimal transaction to the recipient.
+
+<id> is a result in ~/.medasdigital-client/results/<id>.json or a file path.
+The blob is stored on the recipient's chat endpoint. For recipients without
+one, pass --url with a location you publish the blob under; the blob is
+written to ~/.medasdigital-client/shares/ for you to upload.
+
+  medasdigital-client results share pi_calculation-1 --to client-071be41f --from mykey`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from")
+		recipients, _ := cmd.Flags().GetStringSlice("to")
+		baseURL, _ := cmd.Flags().GetString("url")
+		offline, _ := cmd.Flags().GetBool("offline")
+
+		if len(recipients) == 0 {
+			return fmt.Errorf("--to is required")
+		}
+		path := resultPath(args[0])
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("result %s not found: %w", args[0], err)
+		}
+		file := &chat.SharedFile{Name: filepath.Base(path), CreatedAt: time.Now(), Data: data}
+
+		cfg := loadConfig()
+		idx, err := peers.LoadIndex(homeDir, cfg.Chain.ID)
+		if err != nil {
+			return err
+		}
+		if !offline {
+			if err := syncPeers(cmd.Context(), idx, cfg.Chain.RPCEndpoint, peers.SyncOptions{}, false); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Peer sync failed, using index up to height %d: %v\n", idx.LastHeight, err)
+			}
+		}
+
+		clientCtx, err := signingClientContext(cmd.Context(), from, keyringBackendFlag(cmd))
+		if err != nil {
+			return err
+		}
+		sender := clientCtx.GetFromAddress().String()
+		shares, err := chat.LoadShares(homeDir, sender)
+		if err != nil {
+			return err
+		}
+
+		for _, recipient := range recipients {
+			peer, err := findChatPeer(idx, recipient)
+			if err != nil {
+				return err
+			}
+			recipientKey, err := chat.ParsePublicKey(peer.ChatKey)
+			if err != nil {
+				return fmt.Errorf("invalid chat key of %s: %w", peer.Address, err)
+			}
+			blob, hash, err := chat.SealBlob(recipientKey, sender, peer.Address, file)
+			if err != nil {
+				return err
+			}
+
+			url, err := publishBlob(cmd.Context(), peer, blob, hash, baseURL)
+			if err != nil {
+				return err
+			}
+			memo, err := chat.ShareMemo(hash, url)
+			if err != nil {
+				return err
+			}
+			result, err := blockchain.SendMemo(clientCtx, sender, peer.Address, memo)
+			if err != nil {
+				return err
+			}
+
+			shares.Add(&chat.Share{
+				TxHash:    result.TxHash,
+				Direction: chat.Outgoing,
+				Peer:      peer.Address,
+				Hash:      hash,
+				URL:       url,
+				Name:      file.Name,
+				Time:      time.Now(),
+			})
+			fmt.Printf("🔐 Shared %s with %s\n", file.Name, peer.Address)
+			fmt.Printf("   Blob: %s\n", url)
+			fmt.Printf("   Anchor TX: %s\n", result.TxHash)
+		}
+		return shares.Save()
+	},
+}
+
+// resultsSharedWithMeCmd fetches and decrypts the results shared with an account
+var resultsSharedWithMeCmd = &cobra.Command{
+	Use:   "shared-with-me",
+	Short: "List and decrypt results other clients shared with you",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from")
+		offline, _ := cmd.Flags().GetBool("offline")
+		outDir, _ := cmd.Flags().GetString("out")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		clientCtx, err := initKeysClientContextWithBackend(keyringBackendFlag(cmd))
+		if err != nil {
+			return err
+		}
+		address, err := keyAddress(clientCtx.Keyring, from)
+		if err != nil {
+			return err
+		}
+		key, err := chat.LoadKey(homeDir, address)
+		if err != nil {
+			return err
+		}
+		mb, err := chat.OpenMailbox(homeDir, address)
+		if err != nil {
+			return err
+		}
+		shares, err := chat.LoadShares(homeDir, address)
+		if err != nil {
+			return err
+		}
+		if outDir == "" {
+			outDir = filepath.Join(homeDir, "results", "shared")
+		}
+
+		ctx := cmd.Context()
+		if !offline {
+			cfg := loadConfig()
+			rpcClient, err := comethttp.New(cfg.Chain.RPCEndpoint, "/websocket")
+			if err != nil {
+				return fmt.Errorf("failed to create RPC client: %w", err)
+			}
+			if _, err := chat.SyncShares(ctx, rpcClient, currentApp().Codec(), shares); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Chain sync failed: %v\n", err)
+			}
+		}
+
+		// Decrypt what has not been saved yet
+		list := shares.Filter(chat.Incoming)
+		for _, share := range list {
+			if share.Path != "" {
+				continue
+			}
+			if err := openShare(ctx, key, mb, share, outDir); err != nil {
+				share.Error = err.Error()
+				continue
+			}
+			share.Error = ""
+		}
+		if err := shares.Save(); err != nil {
+			return err
+		}
+
+		if asJSON {
+			data, err := json.MarshalIndent(list, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+		if len(list) == 0 {
+			fmt.Println("No shared results")
+			return nil
+		}
+		for _, share := range list {
+			fmt.Printf("%s from %s (tx %s)\n", share.Time.Local().Format("2006-01-02 15:04"), share.Peer, share.TxHash)
+			if share.Path != "" {
+				fmt.Printf("  ✅ %s\n", share.Path)
+			} else {
+				fmt.Printf("  ❌ %s\n", share.Error)
+			}
+		}
+		return nil
+	},
+}
+
+// resultPath resolves a result ID to its file, paths are used as they are
+func resultPath(id string) string {
+	if _, err := os.Stat(id); err == nil {
+		return id
+	}
+	return filepath.Join(homeDir, "results", strings.TrimSuffix(id, ".json")+".json")
+}
+
+// publishBlob stores the blob where the recipient can fetch it and returns the URL
+func publishBlob(ctx context.Context, peer *peers.Peer, blob []byte, hash, baseURL string) (string, error) {
+	if baseURL != "" {
+		path := filepath.Join(homeDir, "shares", hash+".blob")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", fmt.Errorf("failed to create share directory: %w", err)
+		}
+		if err := os.WriteFile(path, blob, 0644); err != nil {
+			return "", fmt.Errorf("failed to write blob: %w", err)
+		}
+		url := strings.TrimRight(baseURL, "/") + "/" + hash + ".blob"
+		fmt.Printf("📦 Upload %s to %s\n", path, url)
+		return url, nil
+	}
+
+	for _, endpoint := range peer.Endpoints {
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+		url, err := chat.UploadBlob(ctx, endpoint, blob)
+		cancel()
+		if err == nil {
+			return url, nil
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+	}
+	if len(peer.Endpoints) == 0 {
+		return "", fmt.Errorf("%s has no chat endpoint, publish the blob yourself with --url", peer.Address)
+	}
+	return "", fmt.Errorf("no chat endpoint of %s accepted the blob, publish it yourself with --url", peer.Address)
+}
+
+// openShare gets the blob of a received share, decrypts it and saves the file
+func openShare(ctx context.Context, key *ecdh.PrivateKey, mb *chat.Mailbox, share *chat.Share, outDir string) error {
+	// Blobs uploaded to our chat endpoint are already here
+	blob, err := os.ReadFile(mb.BlobPath(share.Hash))
+	if err != nil {
+		if share.URL == "" {
+			return fmt.Errorf("no blob URL in the anchor")
+		}
+		fetchCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+		blob, err = chat.FetchBlob(fetchCtx, share.URL)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+
+	file, err := chat.OpenBlob(key, blob, share.Hash, share.Peer, mb.Address)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(outDir, share.Peer, share.Hash[:12]+"-"+filepath.Base(file.Name))
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, file.Data, 0600); err != nil {
+		return fmt.Errorf("failed to save shared result: %w", err)
+	}
+	share.Name = file.Name
+	share.Path = path
+	return nil
+}
+
+func init() {
+	resultsCmd.AddCommand(resultsShareCmd)
+	resultsCmd.AddCommand(resultsSharedWithMeCmd)
+
+	for _, c := range []*cobra.Command{resultsShareCmd, resultsSharedWithMeCmd} {
+		c.Flags().String("from", "", "Key name of the chat account (required)")
+		c.Flags().String("keyring-backend", "", "Keyring backend (test|file|os, default client.keyring_backend)")
+		c.Flags().Bool("offline", false, "Use the cached index without syncing")
+		c.MarkFlagRequired("from")
+	}
+
+	resultsShareCmd.Flags().StringSlice("to", nil, "Recipient address, client ID or display name (repeatable)")
+	resultsShareCmd.Flags().String("url", "", "Base URL you publish the blob under instead of the recipient's chat endpoint")
+
+	resultsSharedWithMeCmd.Flags().String("out", "", "Directory for decrypted results (default ~/.medasdigital-client/results/shared)")
+	resultsSharedWithMeCmd.Flags().Bool("json", false, "Print the shares as JSON")
+}
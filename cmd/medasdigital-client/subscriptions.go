@@ -0,0 +1,439 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/gorilla/mux"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// errQuotaExceeded is a job that does not fit the remaining quota
+var errQuotaExceeded = errors.New("subscription quota exceeded")
+
+// errNoSubscription is a client without a running period
+var errNoSubscription = errors.New("no active subscription")
+
+// SubscriptionDraw is a job paid from a period's quota
+type SubscriptionDraw struct {
+	JobID    string    `json:"job_id"`
+	Amount   float64   `json:"amount"` // MEDAS
+	At       time.Time `json:"at"`
+	Returned bool      `json:"returned,omitempty"` // the job failed, the amount is back in the quota
+}
+
+// SubscriptionPeriod is one prepaid month
+type SubscriptionPeriod struct {
+	Start     time.Time          `json:"start"`
+	End       time.Time          `json:"end"`
+	Quota     float64            `json:"quota"` // MEDAS
+	Used      float64            `json:"used"`
+	PaymentTx string             `json:"payment_tx_hash"`
+	Paid      string             `json:"paid"` // the transferred coin
+	Draws     []SubscriptionDraw `json:"draws,omitempty"`
+}
+
+// Remaining is the quota left in the period
+func (p *SubscriptionPeriod) Remaining() float64 {
+	return p.Quota - p.Used
+}
+
+// Subscription is the prepaid quota of one client. Each payment adds a
+// month, a payment during a running month renews it from its end. Quota that
+// is not used in its month does not carry over.
+type Subscription struct {
+	Address string                `json:"address"`
+	Overage string                `json:"overage"` // compute.OverageReject or compute.OveragePayPerJob
+	Periods []*SubscriptionPeriod `json:"periods"`
+}
+
+// current returns the period running at now
+func (s *Subscription) current(now time.Time) *SubscriptionPeriod {
+	for _, p := range s.Periods {
+		if !now.Before(p.Start) && now.Before(p.End) {
+			return p
+		}
+	}
+	return nil
+}
+
+// SubscriptionStatus is the quota of a client as served by the API
+type SubscriptionStatus struct {
+	Address   string               `json:"address"`
+	Active    bool                 `json:"active"`
+	Overage   string               `json:"overage,omitempty"`
+	Current   *SubscriptionPeriod  `json:"current,omitempty"`
+	Remaining float64              `json:"remaining"`            // MEDAS left in the current period
+	Renewals  []SubscriptionPeriod `json:"renewals,omitempty"`   // paid periods that have not started
+	RenewsAt  *time.Time           `json:"renews_at,omitempty"`  // start of the next paid period
+	ExpiresAt *time.Time           `json:"expires_at,omitempty"` // end of the last paid period
+}
+
+// SubscriptionStore keeps the subscriptions of a payment service on disk
+type SubscriptionStore struct {
+	mu            sync.Mutex
+	path          string
+	subscriptions map[string]*Subscription // by client address
+}
+
+// NewSubscriptionStore loads the subscriptions from disk
+func NewSubscriptionStore(path string) *SubscriptionStore {
+	s := &SubscriptionStore{path: path, subscriptions: make(map[string]*Subscription)}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var list []*Subscription
+		if err := json.Unmarshal(data, &list); err != nil {
+			log.Printf("⚠️ Could not parse subscriptions %s: %v", path, err)
+		}
+		for _, sub := range list {
+			s.subscriptions[sub.Address] = sub
+		}
+	}
+	return s
+}
+
+// Fund adds a month of quota for a payment. It starts at paidAt, or when the
+// last paid period ends if that is later.
+func (s *SubscriptionStore) Fund(address, overage string, quota float64, paid sdk.Coin, txHash string, paidAt time.Time) SubscriptionPeriod {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subscriptions[address]
+	if !ok {
+		sub = &Subscription{Address: address}
+		s.subscriptions[address] = sub
+	}
+	sub.Overage = overage
+
+	start := paidAt
+	if n := len(sub.Periods); n > 0 && sub.Periods[n-1].End.After(start) {
+		start = sub.Periods[n-1].End
+	}
+	p := &SubscriptionPeriod{
+		Start:     start,
+		End:       start.AddDate(0, 1, 0),
+		Quota:     quota,
+		PaymentTx: txHash,
+		Paid:      paid.String(),
+	}
+	sub.Periods = append(sub.Periods, p)
+	s.persist()
+	return *p
+}
+
+// Draw takes amount from the quota of the current period for a job. It
+// returns the period and the overage policy, errNoSubscription or
+// errQuotaExceeded when the job does not fit.
+func (s *SubscriptionStore) Draw(address, jobID string, amount float64, now time.Time) (SubscriptionPeriod, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subscriptions[address]
+	if !ok {
+		return SubscriptionPeriod{}, compute.OverageReject, errNoSubscription
+	}
+	p := sub.current(now)
+	if p == nil {
+		return SubscriptionPeriod{}, sub.Overage, errNoSubscription
+	}
+	if amount > p.Remaining() {
+		return SubscriptionPeriod{}, sub.Overage, fmt.Errorf("%w: %.6f left, %.6f required", errQuotaExceeded, p.Remaining(), amount)
+	}
+	p.Used += amount
+	p.Draws = append(p.Draws, SubscriptionDraw{JobID: jobID, Amount: amount, At: now})
+	s.persist()
+	out := *p
+	out.Draws = nil
+	return out, sub.Overage, nil
+}
+
+// Bind replaces the placeholder ID a draw was made with by the job's ID
+func (s *SubscriptionStore) Bind(address, placeholder, jobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, i := s.findDraw(address, placeholder); p != nil {
+		p.Draws[i].JobID = jobID
+		s.persist()
+	}
+}
+
+// Release removes a draw no job was created for
+func (s *SubscriptionStore) Release(address, placeholder string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, i := s.findDraw(address, placeholder); p != nil {
+		p.Used -= p.Draws[i].Amount
+		p.Draws = append(p.Draws[:i], p.Draws[i+1:]...)
+		s.persist()
+	}
+}
+
+// Return puts the amount drawn for a failed job back into its period. It
+// reports whether the job was paid from a subscription.
+func (s *SubscriptionStore) Return(address, jobID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, i := s.findDraw(address, jobID)
+	if p == nil {
+		return false
+	}
+	if d := &p.Draws[i]; !d.Returned {
+		d.Returned = true
+		p.Used -= d.Amount
+		s.persist()
+	}
+	return true
+}
+
+// findDraw returns the period and index of the draw for a job
+func (s *SubscriptionStore) findDraw(address, jobID string) (*SubscriptionPeriod, int) {
+	sub, ok := s.subscriptions[address]
+	if !ok {
+		return nil, 0
+	}
+	for _, p := range sub.Periods {
+		for i := range p.Draws {
+			if p.Draws[i].JobID == jobID {
+				return p, i
+			}
+		}
+	}
+	return nil, 0
+}
+
+// Status returns the quota of a client at now
+func (s *SubscriptionStore) Status(address string, now time.Time) SubscriptionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := SubscriptionStatus{Address: address}
+	sub, ok := s.subscriptions[address]
+	if !ok {
+		return status
+	}
+	status.Overage = sub.Overage
+	if p := sub.current(now); p != nil {
+		current := *p
+		current.Draws = append([]SubscriptionDraw(nil), p.Draws...)
+		status.Active = true
+		status.Current = &current
+		status.Remaining = p.Remaining()
+	}
+	for _, p := range sub.Periods {
+		if p.Start.After(now) {
+			renewal := *p
+			renewal.Draws = nil
+			if status.RenewsAt == nil {
+				renewsAt := p.Start
+				status.RenewsAt = &renewsAt
+			}
+			status.Renewals = append(status.Renewals, renewal)
+		}
+	}
+	if n := len(sub.Periods); n > 0 && sub.Periods[n-1].End.After(now) {
+		expiresAt := sub.Periods[n-1].End
+		status.ExpiresAt = &expiresAt
+	}
+	return status
+}
+
+func (s *SubscriptionStore) persist() {
+	if err := s.save(); err != nil {
+		log.Printf("⚠️ Could not save subscriptions: %v", err)
+	}
+}
+
+func (s *SubscriptionStore) save() error {
+	defer telemetry.Track(telemetry.CategoryDisk, "save_subscriptions")()
+
+	list := make([]*Subscription, 0, len(s.subscriptions))
+	for _, sub := range s.subscriptions {
+		list = append(list, sub)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// handleSubscriptionPayment adds a month of quota for a SUBSCRIBE transfer
+func (rps *RealPaymentService) handleSubscriptionPayment(t blockchain.Transfer) {
+	// The same transfer may arrive via catch-up and the subscription
+	if rps.consumed.Claim(t.TxHash, 1, 1) == 0 {
+		return
+	}
+	overage, err := compute.ParseSubscriptionMemo(t.Memo)
+	if err != nil {
+		log.Printf("❌ Payment %s from %s: %v", t.TxHash, t.Sender, err)
+		rps.refundRejected(t, "")
+		return
+	}
+	paid, paidMEDAS, ok := rps.paymentCoin(t.Amount)
+	if !ok {
+		log.Printf("❌ Subscription payment %s from %s: %s is not an accepted payment denom", t.TxHash, t.Sender, t.Amount)
+		rps.refundRejected(t, "")
+		return
+	}
+	if !paid.Amount.GT(rps.refundFeeIn(paid.Denom)) {
+		log.Printf("❌ Subscription payment %s from %s: %s does not buy any quota", t.TxHash, t.Sender, paid)
+		return
+	}
+	paidAt := t.Time
+	if paidAt.IsZero() {
+		paidAt = time.Now()
+	}
+
+	p := rps.subscriptions.Fund(t.Sender, overage, paidMEDAS, paid, t.TxHash, paidAt)
+	log.Printf("📅 Subscription of %s funded by %s: %.6f %s from %s to %s (overage: %s)",
+		t.Sender, t.TxHash, p.Quota, network.Current().DisplayDenom, p.Start.Format(time.RFC3339), p.End.Format(time.RFC3339), overage)
+}
+
+// verifySubscriptionRequest checks the signature headers of a job submission
+// drawn from a subscription and the age of its signed_at
+func verifySubscriptionRequest(r *http.Request, body []byte, clientAddr string, signedAt time.Time, now time.Time) error {
+	pubKey, err := base64.StdEncoding.DecodeString(r.Header.Get(compute.ClientPubKeyHeader))
+	if err != nil || len(pubKey) == 0 {
+		return fmt.Errorf("%s header with the base64 public key of client_address is required", compute.ClientPubKeyHeader)
+	}
+	signature, err := base64.StdEncoding.DecodeString(r.Header.Get(compute.ClientSignatureHeader))
+	if err != nil || len(signature) == 0 {
+		return fmt.Errorf("%s header with the base64 signature of the body is required", compute.ClientSignatureHeader)
+	}
+	if err := compute.VerifySubscriptionSignature(body, pubKey, signature, clientAddr); err != nil {
+		return err
+	}
+	// A copied request stops working, retries within the window are answered
+	// by the idempotency key
+	if signedAt.IsZero() || now.Sub(signedAt) > compute.SubscriptionSignatureTTL || signedAt.Sub(now) > time.Minute {
+		return fmt.Errorf("signed_at must be within %s of the server time", compute.SubscriptionSignatureTTL)
+	}
+	return nil
+}
+
+// submitSubscriptionJob creates a job paid from the client's quota. The
+// request has to be signed with the client's account key, the subscription
+// payment proves nothing about who submits jobs against it.
+func (rps *RealPaymentService) submitSubscriptionJob(w http.ResponseWriter, r *http.Request, body []byte, jobType compute.JobType, parameters map[string]interface{}, clientAddr string, tier compute.ServiceTier, verification compute.VerificationLevel, signedAt time.Time) {
+	now := time.Now()
+	if err := verifySubscriptionRequest(r, body, clientAddr, signedAt, now); err != nil {
+		http.Error(w, fmt.Sprintf("Subscription request not authenticated: %v", err), http.StatusUnauthorized)
+		return
+	}
+	price, err := rps.jobManager.QuoteJob(jobType, parameters, tier, verification)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Job submission failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	placeholder := fmt.Sprintf("pending-%d", now.UnixNano())
+	var (
+		period  SubscriptionPeriod
+		overage string
+		drawErr error
+		job     *compute.ComputeJob
+	)
+	// Quota draws count against the daily spend like payments
+	err = rps.admit(clientAddr, 1, func() (float64, error) { return price.TotalCost, nil }, func() (err error) {
+		period, overage, drawErr = rps.subscriptions.Draw(clientAddr, placeholder, price.TotalCost, now)
+		if drawErr != nil {
+			return nil
+		}
+		job, err = rps.jobManager.SubmitJob(jobType, parameters, clientAddr, tier, verification, period.PaymentTx)
+		if err != nil {
+			rps.subscriptions.Release(clientAddr, placeholder)
+		}
+		return err
+	})
+	if limitErr, ok := asAccountLimitError(err); ok {
+		writeAccountLimitError(w, limitErr)
+		return
+	}
+	if drawErr != nil {
+		msg := fmt.Sprintf("%v, the job costs %.6f %s", drawErr, price.TotalCost, network.Current().DisplayDenom)
+		if overage == compute.OveragePayPerJob {
+			msg += "; overage policy pay_per_job: submit it with its own payment_tx_hash"
+		} else {
+			msg += fmt.Sprintf("; send a %s payment to %s to add a month of quota", compute.SubscriptionMemo, rps.serviceAddr)
+		}
+		http.Error(w, msg, http.StatusPaymentRequired)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Job submission failed: %v", err), http.StatusBadRequest)
+		return
+	}
+	rps.subscriptions.Bind(clientAddr, placeholder, job.ID)
+
+	// Paid in advance, the quota was funded by a verified transfer
+	denom := network.Current().BaseDenom
+	if paid, err := sdk.ParseCoinNormalized(period.Paid); err == nil {
+		denom = paid.Denom
+	}
+	job.PaymentVerified = true
+	job.PaymentDenom = denom
+	rps.ledger.Record(job, sdk.NewCoin(denom, rps.priceIn(denom, job.PriceBreakdown.TotalCost)), job.PriceBreakdown.TotalCost)
+	go rps.distributeCommunityFee(job)
+
+	log.Printf("📅 Job %s of %s drawn from its subscription (%.6f of %.6f %s left)",
+		job.ID, clientAddr, period.Remaining(), period.Quota, network.Current().DisplayDenom)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(submitJobResponse{
+		JobID:          job.ID,
+		Status:         job.Status,
+		SubmittedAt:    job.SubmittedAt,
+		PriceBreakdown: job.PriceBreakdown,
+		Verification:   job.Verification,
+		Subscription: &subscriptionQuota{
+			PeriodEnd: period.End,
+			Quota:     period.Quota,
+			Used:      period.Used,
+			Remaining: period.Remaining(),
+		},
+		Message: "Job submitted, paid from the subscription quota",
+	})
+}
+
+// subscriptionQuota is what is left of the quota after a job drew from it
+type subscriptionQuota struct {
+	PeriodEnd time.Time `json:"period_end"`
+	Quota     float64   `json:"quota"`
+	Used      float64   `json:"used"`
+	Remaining float64   `json:"remaining"`
+}
+
+// handleGetSubscription returns the quota, renewals and overage policy of a client
+func (rps *RealPaymentService) handleGetSubscription(w http.ResponseWriter, r *http.Request) {
+	addr := mux.Vars(r)["addr"]
+	if _, err := sdk.AccAddressFromBech32(addr); err != nil {
+		http.Error(w, "Invalid address", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rps.subscriptions.Status(addr, time.Now()))
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/chat"
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+)
+
+// txCmd groups the transaction commands
+var txCmd = &cobra.Command{
+	Use:   "tx",
+	Short: "Inspect transactions",
+}
+
+// txShowCmd decodes a transaction
+var txShowCmd = &cobra.Command{
+	Use:   "show <hash>",
+	Short: "Decode a transaction: messages, memo, fee and events",
+	Long: `Fetch a transaction and decode its messages, fee, events and memo. Memos of
+MedasDigital transactions are decoded as well: registrations (MEDAS_CLIENT_REG,
+MEDAS_SIMPLE_REG, MEDAS_CHAT_REG), job payments (COMPUTE_*), subscriptions,
+invoice payments, chat messages, shared results and model anchors.
+
+  medasdigital-client tx show 3F9A...
+  medasdigital-client tx show 3F9A... --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		hash := strings.ToUpper(strings.TrimPrefix(args[0], "0x"))
+
+		bc, err := createFullBlockchainClient(client.Context{}, loadConfig())
+		if err != nil {
+			return err
+		}
+		details, err := bc.InspectTx(cmd.Context(), hash)
+		if err != nil {
+			return err
+		}
+
+		view := txView{TxDetails: details}
+		view.MemoType, view.MemoData = decodeMemo(details)
+		if asJSON {
+			data, err := json.MarshalIndent(view, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+		printTx(view)
+		return nil
+	},
+}
+
+// txView is a decoded transaction with what its memo means
+type txView struct {
+	*blockchain.TxDetails
+	MemoType string      `json:"memo_type,omitempty"`
+	MemoData interface{} `json:"memo_data,omitempty"`
+}
+
+// Memo types of tx show
+const (
+	memoRegistration = "registration"
+	memoPayment      = "compute_payment"
+	memoSubscription = "subscription"
+	memoInvoice      = "invoice_payment"
+	memoChatMessage  = "chat_message"
+	memoShare        = "shared_result"
+	memoModel        = "model_anchor"
+)
+
+// decodeMemo recognizes the memo formats of the client, the type is empty
+// for other memos
+func decodeMemo(d *blockchain.TxDetails) (string, interface{}) {
+	memo := d.Memo
+	switch {
+	case d.Registration != nil:
+		// Decoded by InspectTx already
+		return memoRegistration, nil
+	case blockchain.IsRegistrationMemo(memo):
+		_, err := blockchain.ParseRegistrationMemo(memo)
+		return memoRegistration, map[string]interface{}{"error": err.Error()}
+	case compute.IsPaymentMemo(memo):
+		job, err := compute.ParsePaymentMemo(memo)
+		if err != nil {
+			return memoPayment, map[string]interface{}{"error": err.Error()}
+		}
+		return memoPayment, map[string]interface{}{
+			"job_type":     job.Type,
+			"parameters":   job.Parameters,
+			"tier":         job.Tier,
+			"verification": job.Verification,
+			"count":        job.Count,
+		}
+	case compute.IsSubscriptionMemo(memo):
+		overage, _ := compute.ParseSubscriptionMemo(memo)
+		return memoSubscription, map[string]interface{}{"overage": overage}
+	case strings.HasPrefix(memo, InvoiceMemoPrefix):
+		return memoInvoice, nil
+	case strings.HasPrefix(memo, chat.MemoPrefix):
+		sealed, err := chat.ParseMemo(memo)
+		if err != nil {
+			return memoChatMessage, map[string]interface{}{"error": err.Error()}
+		}
+		return memoChatMessage, map[string]interface{}{"ciphertext_bytes": len(sealed.Ciphertext)}
+	case strings.HasPrefix(memo, chat.ShareMemoPrefix):
+		hash, url, err := chat.ParseShareMemo(memo)
+		if err != nil {
+			return memoShare, map[string]interface{}{"error": err.Error()}
+		}
+		return memoShare, map[string]interface{}{"blob_hash": hash, "url": url}
+	case strings.HasPrefix(memo, blockchain.ModelMemoPrefix):
+		sha, jobID, _ := strings.Cut(strings.TrimPrefix(memo, blockchain.ModelMemoPrefix), ":")
+		data := map[string]interface{}{"sha256": sha}
+		if jobID != "" {
+			data["job_id"] = jobID
+		}
+		return memoModel, data
+	}
+	return "", nil
+}
+
+func printTx(v txView) {
+	d := v.TxDetails
+	fmt.Printf("🔎 Transaction %s\n", d.Hash)
+	fmt.Printf("   Height: %d\n", d.Height)
+	if d.Time != "" {
+		fmt.Printf("   Time: %s\n", d.Time)
+	}
+	if d.Succeeded() {
+		fmt.Println("   Status: ✅ success")
+	} else {
+		fmt.Printf("   Status: ❌ failed (code %d %s)\n", d.Code, d.Codespace)
+		fmt.Printf("   Error: %s\n", d.Log)
+	}
+	fmt.Printf("   Gas: %d used of %d\n", d.GasUsed, d.GasWanted)
+	if fee, err := sdk.ParseCoinsNormalized(d.Fee); err == nil && !fee.IsZero() {
+		fmt.Printf("   Fee: %s\n", denoms().FormatCoins(fee))
+	} else {
+		fmt.Printf("   Fee: %s\n", d.Fee)
+	}
+	if d.FeeGranter != "" {
+		fmt.Printf("   Fee paid by: %s (fee grant)\n", d.FeeGranter)
+	}
+
+	if d.Memo != "" {
+		fmt.Printf("\n📝 Memo: %s\n", d.Memo)
+		printMemo(v)
+	}
+
+	fmt.Printf("\n📨 Messages (%d):\n", len(d.Messages))
+	for i, msg := range d.Messages {
+		var typed struct {
+			Type string `json:"@type"`
+		}
+		json.Unmarshal(msg, &typed)
+		fmt.Printf("   %d. %s\n", i+1, typed.Type)
+		data, err := json.MarshalIndent(msg, "      ", "  ")
+		if err == nil {
+			fmt.Printf("      %s\n", data)
+		}
+	}
+
+	if len(d.Events) > 0 {
+		fmt.Printf("\n📡 Events (%d):\n", len(d.Events))
+		for _, e := range d.Events {
+			attrs := make([]string, 0, len(e.Attributes))
+			for _, a := range e.Attributes {
+				attrs = append(attrs, a.Key+"="+a.Value)
+			}
+			fmt.Printf("   %s: %s\n", e.Type, strings.Join(attrs, ", "))
+		}
+	}
+}
+
+// printMemo explains a recognized memo
+func printMemo(v txView) {
+	data, _ := v.MemoData.(map[string]interface{})
+	if errMsg, ok := data["error"]; ok {
+		fmt.Printf("   ⚠️  Looks like a %s memo but does not parse: %v\n", strings.ReplaceAll(v.MemoType, "_", " "), errMsg)
+		return
+	}
+	switch v.MemoType {
+	case memoRegistration:
+		reg := v.Registration
+		fmt.Printf("   Registration: %s client\n", reg.Type)
+		if reg.Timestamp > 0 {
+			fmt.Printf("   Registered at: %s\n", time.Unix(reg.Timestamp, 0).UTC().Format(time.RFC3339))
+		}
+		if len(reg.Capabilities) > 0 {
+			fmt.Printf("   Capabilities: %s\n", strings.Join(reg.Capabilities, ", "))
+		}
+		if reg.DisplayName != "" {
+			fmt.Printf("   Name: %s\n", reg.DisplayName)
+		}
+		if len(reg.Endpoints) > 0 {
+			fmt.Printf("   Chat endpoints: %s\n", strings.Join(reg.Endpoints, ", "))
+		}
+		if len(reg.ChatKey) > 0 {
+			fmt.Printf("   Chat key: %x\n", reg.ChatKey)
+		}
+	case memoPayment:
+		fmt.Printf("   Job payment: %v, parameters %v, %v tier, verification %v", data["job_type"], data["parameters"], data["tier"], data["verification"])
+		if count, _ := data["count"].(int); count > 1 {
+			fmt.Printf(", %d jobs", count)
+		}
+		fmt.Println()
+	case memoSubscription:
+		fmt.Printf("   Subscription payment, overage policy %v\n", data["overage"])
+	case memoInvoice:
+		fmt.Println("   Invoice payment")
+	case memoChatMessage:
+		fmt.Printf("   Encrypted chat message (%v bytes of ciphertext)\n", data["ciphertext_bytes"])
+	case memoShare:
+		fmt.Printf("   Shared result blob %v", data["blob_hash"])
+		if url, _ := data["url"].(string); url != "" {
+			fmt.Printf(" at %s", url)
+		}
+		fmt.Println()
+	case memoModel:
+		fmt.Printf("   Model weights anchor, SHA-256 %v", data["sha256"])
+		if jobID, ok := data["job_id"]; ok {
+			fmt.Printf(" of training job %v", jobID)
+		}
+		fmt.Println()
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(txCmd)
+	txCmd.AddCommand(txShowCmd)
+
+	txShowCmd.Flags().Bool("json", false, "Print the decoded transaction as JSON")
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+)
+
+// Types of account events
+const (
+	accountEventIncoming     = "incoming"
+	accountEventOutgoing     = "outgoing"
+	accountEventBalance      = "balance"
+	accountEventRegistration = "registration"
+)
+
+// accountEvent is printed, or posted to the webhook, for every change of a watched account
+type accountEvent struct {
+	Type         string                       `json:"type"`
+	Address      string                       `json:"address"`
+	Time         time.Time                    `json:"time"`
+	TxHash       string                       `json:"tx_hash,omitempty"`
+	Height       int64                        `json:"height,omitempty"`
+	Counterparty string                       `json:"counterparty,omitempty"`
+	Amount       string                       `json:"amount,omitempty"`
+	Memo         string                       `json:"memo,omitempty"`
+	Registration *blockchain.RegistrationMemo `json:"registration,omitempty"`
+	Balance      string                       `json:"balance,omitempty"`
+	Change       string                       `json:"change,omitempty"`  // per denom, e.g. +1000umedas,-5uatom
+	Display      string                       `json:"display,omitempty"` // amount or balance in display units, e.g. 12.345678 MEDAS
+}
+
+// watchCmd follows chain activity live
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Follow chain activity in real time",
+}
+
+// watchAccountCmd prints the transfers and balance changes of an address as they happen
+var watchAccountCmd = &cobra.Command{
+	Use:   "account [address]",
+	Short: "Watch transfers, balance changes and registration memos of an address",
+	Long: `Subscribe to the chain event stream and report every transfer from or to the
+address, the balance change it caused and registrations made with a MEDAS_*_REG
+memo. Useful for operators watching the payment service address.
+
+  medasdigital-client watch account medas1service...
+  medasdigital-client watch account medas1service... --json --webhook https://ops.example/hook
+
+With --webhook every event is POSTed as JSON as well. Fees show up as outgoing
+transfers to the fee collector.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		address := args[0]
+		if _, err := sdk.AccAddressFromBech32(address); err != nil {
+			return fmt.Errorf("invalid address %s: %w", address, err)
+		}
+		webhook, _ := cmd.Flags().GetString("webhook")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		interval, _ := cmd.Flags().GetDuration("balance-interval")
+
+		cfg := loadConfig()
+		bc, err := createFullBlockchainClient(client.Context{}, cfg)
+		if err != nil {
+			return err
+		}
+
+		w := &accountWatcher{
+			address:    address,
+			client:     bc,
+			webhook:    webhook,
+			asJSON:     asJSON,
+			httpClient: &http.Client{Timeout: 10 * time.Second},
+		}
+
+		ctx := cmd.Context()
+
+		if !asJSON {
+			fmt.Printf("👀 Watching %s on %s (Ctrl+C to stop)\n", address, cfg.Chain.ID)
+			if webhook != "" {
+				fmt.Printf("   Events are posted to %s\n", webhook)
+			}
+		}
+		w.refreshBalance(ctx)
+		if interval > 0 {
+			go w.pollBalance(ctx, interval)
+		}
+		w.run(ctx)
+		return nil
+	},
+}
+
+// accountWatcher turns the transfers of one address into account events
+type accountWatcher struct {
+	address    string
+	client     *blockchain.Client
+	webhook    string
+	asJSON     bool
+	httpClient *http.Client
+
+	mu                 sync.Mutex
+	balance            sdk.Coins
+	known              bool // balance queried at least once
+	lastRegistrationTx string
+}
+
+// run follows the transfers until ctx is done, reconnecting like the payment watcher
+func (w *accountWatcher) run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		err := w.client.WatchAccountTransfers(ctx, w.address, func(t blockchain.Transfer) {
+			w.handleTransfer(ctx, t)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("⚠️ Watch disconnected: %v, reconnecting in %v", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		// Transfers missed meanwhile still show up as balance change
+		w.refreshBalance(ctx)
+		backoff *= 2
+		if backoff > time.Minute {
+			backoff = time.Minute
+		}
+	}
+}
+
+// pollBalance catches balance changes without a transfer event, e.g. vesting
+func (w *accountWatcher) pollBalance(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.refreshBalance(ctx)
+		}
+	}
+}
+
+func (w *accountWatcher) handleTransfer(ctx context.Context, t blockchain.Transfer) {
+	event := accountEvent{
+		Type:    accountEventIncoming,
+		Address: w.address,
+		Time:    t.Time,
+		TxHash:  t.TxHash,
+		Height:  t.Height,
+		Amount:  t.Amount.String(),
+		Memo:    t.Memo,
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	if t.Sender == w.address {
+		event.Type, event.Counterparty = accountEventOutgoing, t.Recipient
+	} else {
+		event.Counterparty = t.Sender
+	}
+	w.emit(event)
+
+	// Registration transactions carry a fee and a self transfer, report them once
+	if reg, err := blockchain.ParseRegistrationMemo(t.Memo); err == nil && w.firstOfTx(t.TxHash) {
+		w.emit(accountEvent{
+			Type:         accountEventRegistration,
+			Address:      w.address,
+			Time:         event.Time,
+			TxHash:       t.TxHash,
+			Height:       t.Height,
+			Counterparty: t.Sender,
+			Memo:         t.Memo,
+			Registration: reg,
+		})
+	}
+
+	w.refreshBalance(ctx)
+}
+
+// firstOfTx reports whether a registration of the transaction was not reported yet
+func (w *accountWatcher) firstOfTx(hash string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.lastRegistrationTx == hash {
+		return false
+	}
+	w.lastRegistrationTx = hash
+	return true
+}
+
+// refreshBalance queries the balance and reports it if it changed
+func (w *accountWatcher) refreshBalance(ctx context.Context) {
+	balance, err := w.client.GetAccountBalance(ctx, w.address)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("⚠️ %v", err)
+		}
+		return
+	}
+
+	w.mu.Lock()
+	previous, known := w.balance, w.known
+	w.balance, w.known = balance, true
+	w.mu.Unlock()
+
+	change := ""
+	if known {
+		if change = coinsChange(previous, balance); change == "" {
+			return
+		}
+	}
+	w.emit(accountEvent{
+		Type:    accountEventBalance,
+		Address: w.address,
+		Time:    time.Now().UTC(),
+		Balance: balance.String(),
+		Change:  change,
+	})
+}
+
+// coinsChange lists the per denom difference, empty if nothing changed
+func coinsChange(before, after sdk.Coins) string {
+	denoms := map[string]bool{}
+	for _, coins := range []sdk.Coins{before, after} {
+		for _, c := range coins {
+			denoms[c.Denom] = true
+		}
+	}
+	var parts []string
+	for _, denom := range sortedDenoms(denoms) {
+		diff := after.AmountOf(denom).Sub(before.AmountOf(denom))
+		switch {
+		case diff.IsPositive():
+			parts = append(parts, "+"+diff.String()+denom)
+		case diff.IsNegative():
+			parts = append(parts, diff.String()+denom)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func sortedDenoms(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// emit prints an event and posts it to the webhook
+func (w *accountWatcher) emit(event accountEvent) {
+	switch {
+	case event.Amount != "":
+		event.Display = displayCoins(event.Amount)
+	case event.Type == accountEventBalance:
+		event.Display = displayCoins(event.Balance)
+	}
+	if w.asJSON {
+		data, _ := json.Marshal(event)
+		fmt.Println(string(data))
+	} else {
+		printAccountEvent(event)
+	}
+	if w.webhook != "" {
+		go w.post(event)
+	}
+}
+
+func (w *accountWatcher) post(event accountEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	resp, err := w.httpClient.Post(w.webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("⚠️ Webhook failed: %v", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("⚠️ Webhook answered %s", resp.Status)
+	}
+}
+
+func printAccountEvent(e accountEvent) {
+	ts := e.Time.Local().Format("15:04:05")
+	switch e.Type {
+	case accountEventIncoming:
+		fmt.Printf("📥 %s  +%s from %s (height %d, tx %s)\n", ts, e.Display, e.Counterparty, e.Height, e.TxHash)
+	case accountEventOutgoing:
+		fmt.Printf("📤 %s  -%s to %s (height %d, tx %s)\n", ts, e.Display, e.Counterparty, e.Height, e.TxHash)
+	case accountEventRegistration:
+		fmt.Printf("📝 %s  %s registered as %s client", ts, e.Counterparty, e.Registration.Type)
+		if len(e.Registration.Capabilities) > 0 {
+			fmt.Printf(" (%s)", strings.Join(e.Registration.Capabilities, ", "))
+		}
+		fmt.Printf(", tx %s\n", e.TxHash)
+		return
+	case accountEventBalance:
+		if e.Change == "" {
+			fmt.Printf("💰 %s  Balance: %s\n", ts, e.Display)
+		} else {
+			fmt.Printf("💰 %s  Balance: %s (%s)\n", ts, e.Display, displayChange(e.Change))
+		}
+		return
+	}
+	if e.Memo != "" {
+		fmt.Printf("            memo: %s\n", e.Memo)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.AddCommand(watchAccountCmd)
+
+	watchAccountCmd.Flags().String("webhook", "", "POST every event as JSON to this URL")
+	watchAccountCmd.Flags().Bool("json", false, "Print one JSON event per line")
+	watchAccountCmd.Flags().Duration("balance-interval", time.Minute, "Also poll the balance this often (0 disables)")
+}
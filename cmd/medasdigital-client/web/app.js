@@ -0,0 +1,364 @@
+'use strict';
+
+// Dashboard of 'medasdigital-client web'. Everything is rendered with
+// textContent, results and logs are never parsed as HTML.
+
+const $ = (sel) => document.querySelector(sel);
+
+let chainCursor = '';
+
+async function api(path, options = {}) {
+  if (options.body !== undefined) {
+    options.method = options.method || 'POST';
+    options.headers = { 'Content-Type': 'application/json' };
+    options.body = JSON.stringify(options.body);
+  }
+  const res = await fetch('/api' + path, options);
+  if (res.status === 401) {
+    showLogin();
+    throw new Error('Sign in required');
+  }
+  if (!res.ok) {
+    throw new Error((await res.text()).trim() || res.statusText);
+  }
+  const type = res.headers.get('Content-Type') || '';
+  return type.startsWith('application/json') ? res.json() : null;
+}
+
+function el(tag, text, attrs = {}) {
+  const e = document.createElement(tag);
+  if (text !== undefined && text !== null) {
+    e.textContent = String(text);
+  }
+  for (const [k, v] of Object.entries(attrs)) {
+    e.setAttribute(k, v);
+  }
+  return e;
+}
+
+function row(cells) {
+  const tr = el('tr');
+  for (const c of cells) {
+    const td = el('td');
+    if (c instanceof Node) {
+      td.append(c);
+    } else {
+      td.textContent = c === undefined || c === null ? '' : String(c);
+    }
+    tr.append(td);
+  }
+  return tr;
+}
+
+function fillTable(sel, rows, empty) {
+  const body = $(sel + ' tbody');
+  body.replaceChildren(...rows);
+  if (rows.length === 0) {
+    const cols = $(sel + ' thead tr').children.length;
+    body.append(el('tr')).append(el('td', empty, { colspan: cols, class: 'muted' }));
+  }
+}
+
+function fillList(sel, pairs) {
+  const dl = $(sel);
+  dl.replaceChildren();
+  for (const [k, v] of pairs) {
+    dl.append(el('dt', k), el('dd', v));
+  }
+}
+
+function when(t) {
+  return t && !t.startsWith('0001') ? new Date(t).toLocaleString() : '';
+}
+
+function link(text, href) {
+  return el('a', text, { href, target: '_blank', rel: 'noopener' });
+}
+
+// Login
+
+function showLogin() {
+  if (!$('#login').open) {
+    $('#login').showModal();
+  }
+}
+
+$('#login-form').addEventListener('submit', async (ev) => {
+  ev.preventDefault();
+  const token = new FormData(ev.target).get('token');
+  try {
+    await api('/login', { body: { token } });
+    $('#login').close();
+    refreshAll();
+  } catch (err) {
+    $('#login-error').textContent = err.message;
+  }
+});
+
+// Chain status
+
+async function refreshStatus() {
+  let s;
+  try {
+    s = await api('/status');
+  } catch (err) {
+    $('#chain-badge').textContent = err.message;
+    $('#chain-badge').className = 'badge bad';
+    return;
+  }
+  const badge = $('#chain-badge');
+  if (s.chain) {
+    badge.textContent = `${s.chain.chain_id} #${s.chain.height}` + (s.chain.catching_up ? ' (syncing)' : '');
+    badge.className = 'badge ' + (s.chain.catching_up ? '' : 'ok');
+  } else {
+    badge.textContent = 'chain unreachable';
+    badge.className = 'badge bad';
+  }
+
+  const chain = [['Network', s.network], ['Chain ID', s.chain_id], ['RPC', s.rpc]];
+  if (s.chain) {
+    chain.push(['Height', s.chain.height], ['Block time', when(s.chain.block_time)],
+      ['Node version', s.chain.node_version], ['Catching up', s.chain.catching_up ? 'yes' : 'no']);
+  } else {
+    chain.push(['Error', s.chain_error]);
+  }
+  fillList('#status-chain', chain);
+
+  const reg = [['Local registrations', s.local_registrations]];
+  if (s.registration) {
+    reg.push(['Transaction', s.registration.transaction_hash], ['Height', s.registration.block_height],
+      ['Time', when(s.registration.block_time)], ['Address', s.registration.from_address]);
+  } else if (s.registration_error) {
+    reg.push(['Error', s.registration_error]);
+  }
+  fillList('#status-registration', reg);
+
+  const gpus = $('#status-gpus');
+  gpus.replaceChildren();
+  for (const g of s.gpus || []) {
+    const mem = g.memory_total ? ` ${(g.memory_used / 2 ** 30).toFixed(1)} / ${(g.memory_total / 2 ** 30).toFixed(1)} GiB` : '';
+    gpus.append(el('li', `${g.name} (${g.backend})${mem}`));
+  }
+  if (!gpus.children.length) {
+    gpus.append(el('li', 'No GPU detected', { class: 'muted' }));
+  }
+
+  const jobs = (s.jobs || []).map((j) => row([
+    j.id, j.type, el('span', j.status, { class: j.status }), j.progress !== undefined ? j.progress + '%' : '',
+  ]));
+  fillTable('#status-jobs', jobs, s.jobs_error || (s.service ? 'No jobs' : 'Start the dashboard with --service to list jobs'));
+}
+
+// Job submission and tasks
+
+const taskForm = $('#task-form');
+
+function showKindFields() {
+  const kind = taskForm.elements.kind.value;
+  for (const fs of taskForm.querySelectorAll('fieldset')) {
+    fs.hidden = fs.dataset.kind !== kind;
+  }
+}
+
+taskForm.elements.kind.addEventListener('change', showKindFields);
+
+taskForm.addEventListener('submit', async (ev) => {
+  ev.preventDefault();
+  const f = taskForm.elements;
+  const req = { kind: f.kind.value };
+  if (req.kind === 'planet9_search') {
+    for (const name of ['mass', 'semi_major', 'eccentricity', 'inclination']) {
+      req[name] = f[name].value.trim();
+    }
+    req.sim_years = Number(f.sim_years.value) || 0;
+    req.quick = f.grid.value === 'quick';
+    req.fine = f.grid.value === 'fine';
+  } else if (req.kind === 'orbital_dynamics') {
+    req.input = f.input.value.trim();
+  } else {
+    req.digits = Number(f.digits.value);
+    req.from = f.from.value.trim();
+    req.payment = f.payment.value.trim();
+  }
+  $('#task-error').textContent = '';
+  try {
+    const task = await api('/tasks', { body: req });
+    showLog(task.id);
+    refreshTasks();
+  } catch (err) {
+    $('#task-error').textContent = err.message;
+  }
+});
+
+let logTask = '';
+
+async function showLog(id) {
+  logTask = id;
+  await refreshLog();
+}
+
+async function refreshLog() {
+  if (!logTask) {
+    return;
+  }
+  const pre = $('#task-log');
+  try {
+    const t = await api('/tasks/' + logTask);
+    pre.textContent = `$ medasdigital-client ${t.args.join(' ')}\n${t.log || ''}` + (t.error ? `\n${t.error}` : '');
+    pre.hidden = false;
+    pre.scrollTop = pre.scrollHeight;
+  } catch (err) {
+    pre.textContent = err.message;
+  }
+}
+
+function taskActions(t) {
+  const span = el('span');
+  const log = el('a', 'log', { href: '#' });
+  log.addEventListener('click', (ev) => {
+    ev.preventDefault();
+    showLog(t.id);
+  });
+  span.append(log);
+  if (t.status === 'running') {
+    const cancel = el('a', 'cancel', { href: '#' });
+    cancel.addEventListener('click', async (ev) => {
+      ev.preventDefault();
+      await api(`/tasks/${t.id}/cancel`, { method: 'POST' });
+      refreshTasks();
+    });
+    span.append(' ', cancel);
+  }
+  if (t.result) {
+    span.append(' ', link('result', '/api/results/local/' + encodeURIComponent(t.result)));
+  }
+  return span;
+}
+
+async function refreshTasks() {
+  let tasks;
+  try {
+    tasks = await api('/tasks');
+  } catch (err) {
+    return;
+  }
+  fillTable('#tasks', tasks.map((t) => row([
+    t.id, t.kind, el('span', t.status, { class: t.status }), when(t.started), taskActions(t),
+  ])), 'No tasks');
+  renderPlanet9(tasks.filter((t) => t.kind === 'planet9_search'));
+  refreshLog();
+}
+
+// Planet 9 monitor, the log tail of each search
+
+async function renderPlanet9(tasks) {
+  const box = $('#planet9-tasks');
+  if (tasks.length === 0) {
+    box.replaceChildren(el('p', 'No searches started from this dashboard.', { class: 'muted' }));
+    return;
+  }
+  const cards = [];
+  for (const summary of tasks.slice(0, 5)) {
+    let t = summary;
+    if (t.status === 'running' || !box.querySelector(`[data-id="${t.id}"]`)) {
+      try {
+        t = await api('/tasks/' + t.id);
+      } catch (err) {
+        continue;
+      }
+    }
+    const card = el('div', null, { 'data-id': t.id });
+    const title = el('h3');
+    title.append(`#${t.id} `, el('span', t.status, { class: t.status }), ` started ${when(t.started)}`);
+    card.append(title, el('p', t.args.slice(2).join(' '), { class: 'muted' }));
+    if (t.log !== undefined) {
+      const tail = (t.log || '').split(/[\r\n]+/).filter(Boolean).slice(-12).join('\n');
+      card.append(el('pre', tail || 'waiting for output…'));
+    } else {
+      const old = box.querySelector(`[data-id="${t.id}"] pre`);
+      if (old) {
+        card.append(old);
+      }
+    }
+    if (t.result) {
+      const name = encodeURIComponent(t.result);
+      const links = el('p');
+      links.append(link('result', '/api/results/local/' + name), ' ', link('PDF report', `/api/results/local/${name}/report.pdf`));
+      card.append(links);
+    }
+    cards.push(card);
+  }
+  box.replaceChildren(...cards);
+}
+
+// Results
+
+async function refreshLocalResults() {
+  let results;
+  try {
+    results = await api('/results/local');
+  } catch (err) {
+    return;
+  }
+  fillTable('#results-local', results.map((r) => {
+    const name = encodeURIComponent(r.name);
+    const actions = el('span');
+    actions.append(link('json', '/api/results/local/' + name), ' ', link('pdf', `/api/results/local/${name}/report.pdf`));
+    if (r.type === 'orbital_dynamics') {
+      const plot = el('a', 'plot', { href: '#' });
+      plot.addEventListener('click', (ev) => {
+        ev.preventDefault();
+        const img = $('#result-plot');
+        img.src = `/api/results/local/${name}/orbit.svg`;
+        img.alt = 'Orbital elements of ' + r.name;
+        img.hidden = false;
+      });
+      actions.append(' ', plot);
+    }
+    return row([r.name, r.type, when(r.modified), r.tx_hash ? r.tx_hash.slice(0, 12) + '…' : '', actions]);
+  }), 'No results in the results directory');
+}
+
+async function searchChain(more) {
+  const f = $('#chain-form').elements;
+  const params = new URLSearchParams({ type: f.type.value.trim(), client_id: f.client_id.value.trim() });
+  if (more) {
+    params.set('cursor', chainCursor);
+  }
+  $('#chain-error').textContent = '';
+  let page;
+  try {
+    page = await api('/results/chain?' + params);
+  } catch (err) {
+    $('#chain-error').textContent = err.message;
+    return;
+  }
+  const rows = (page.results || []).map((r) => row([r.id, r.analysis_type, r.block_height, when(r.created_at), r.tx_hash]));
+  if (more) {
+    $('#results-chain tbody').append(...rows);
+  } else {
+    fillTable('#results-chain', rows, 'No results on chain');
+  }
+  chainCursor = page.next_cursor || '';
+  $('#chain-more').hidden = !chainCursor;
+}
+
+$('#chain-form').addEventListener('submit', (ev) => {
+  ev.preventDefault();
+  searchChain(false);
+});
+$('#chain-more').addEventListener('click', () => searchChain(true));
+
+// Polling
+
+function refreshAll() {
+  refreshStatus();
+  refreshTasks();
+  refreshLocalResults();
+}
+
+showKindFields();
+refreshAll();
+setInterval(refreshTasks, 2000);
+setInterval(refreshStatus, 15000);
+setInterval(refreshLocalResults, 30000);
@@ -0,0 +1,763 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/cobra"
+
+	"github.com/oxygene76/medasdigital-client/pkg/astronomy/planet9"
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+	"github.com/oxygene76/medasdigital-client/pkg/computeclient"
+	"github.com/oxygene76/medasdigital-client/pkg/contract"
+	"github.com/oxygene76/medasdigital-client/pkg/gpu"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+	"github.com/oxygene76/medasdigital-client/pkg/report"
+)
+
+//go:embed web
+var webAssets embed.FS
+
+const (
+	// webLogBytes is how much output of a task the web UI keeps
+	webLogBytes = 64 << 10
+	// webMaxTasks bounds the tasks kept in memory, finished ones are dropped first
+	webMaxTasks = 100
+	// webTokenCookie holds the token after a login in the browser
+	webTokenCookie = "medas_web_token"
+)
+
+// Task kinds the web UI can start
+const (
+	webTaskPlanet9 = "planet9_search"
+	webTaskOrbital = "orbital_dynamics"
+	webTaskPI      = "pi_calculation"
+)
+
+// Status of a web task
+const (
+	webTaskRunning   = "running"
+	webTaskOK        = "ok"
+	webTaskFailed    = "failed"
+	webTaskCancelled = "cancelled"
+)
+
+// webCmd serves the browser dashboard
+var webCmd = &cobra.Command{
+	Use:   "web",
+	Short: "Serve a web dashboard for jobs, results, Planet 9 searches and chain status",
+	Long: `Serve a small web dashboard so a shared client instance can be used from a
+browser: a job submission form, a browser for local and on-chain results
+with PDF reports and orbit plots, a monitor of running Planet 9 searches
+and the chain status.
+
+Jobs run as child processes of this client with its config, home and
+network. The form only starts Planet 9 searches, orbital dynamics analyses
+of files in --data-dir and PI jobs signed with a key named by --allow-key;
+without --allow-key the UI cannot spend funds.
+
+Anyone reaching the listen address can use the dashboard. Keep the default
+loopback address or set --token, which the browser asks for once.
+
+Example:
+  medasdigital-client web --listen 0.0.0.0:8070 --token $WEB_TOKEN \
+    --data-dir ~/surveys --service http://localhost:8080 --allow-key lab`,
+	RunE: runWeb,
+}
+
+func init() {
+	rootCmd.AddCommand(webCmd)
+
+	webCmd.Flags().String("listen", "127.0.0.1:8070", "Address to serve the dashboard on")
+	webCmd.Flags().String("token", "", "Bearer token required for the API (recommended off loopback)")
+	webCmd.Flags().String("service", "", "Payment service URL to show compute jobs from")
+	webCmd.Flags().String("client-address", "", "Only show jobs of this client address")
+	webCmd.Flags().String("provider", "", "Provider address to show contract stats and earnings for")
+	webCmd.Flags().String("contract", "", "Contract address (default from config)")
+	webCmd.Flags().String("data-dir", "", "Directory of input files for orbital dynamics analyses")
+	webCmd.Flags().StringSlice("allow-key", nil, "Keys the dashboard may sign PI jobs with")
+}
+
+// webServer serves the API of the dashboard
+type webServer struct {
+	ctx        context.Context
+	dash       *dashboard
+	token      string
+	dataDir    string
+	keys       []string
+	resultsDir string
+	run        func(ctx context.Context, dir string, args []string, out io.Writer) error
+
+	mu     sync.Mutex
+	tasks  []*webTask
+	nextID int
+}
+
+// webTask is a client command started from the dashboard
+type webTask struct {
+	ID       string     `json:"id"`
+	Kind     string     `json:"kind"`
+	Args     []string   `json:"args"`
+	Status   string     `json:"status"`
+	Error    string     `json:"error,omitempty"`
+	Result   string     `json:"result,omitempty"` // file in the results directory
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Log      string     `json:"log,omitempty"`
+
+	cancel    context.CancelFunc
+	cancelled bool
+	log       *tailBuffer
+}
+
+// webTaskRequest is the job form, fields apply to their kind
+type webTaskRequest struct {
+	Kind string `json:"kind"`
+
+	Mass         string  `json:"mass"`
+	SemiMajor    string  `json:"semi_major"`
+	Eccentricity string  `json:"eccentricity"`
+	Inclination  string  `json:"inclination"`
+	Quick        bool    `json:"quick"`
+	Fine         bool    `json:"fine"`
+	SimYears     float64 `json:"sim_years"`
+
+	Input string `json:"input"`
+
+	Digits  int    `json:"digits"`
+	From    string `json:"from"`
+	Payment string `json:"payment"`
+}
+
+// webStatus is the status panel, errors as text
+type webStatus struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	Network   string    `json:"network"`
+	ChainID   string    `json:"chain_id"`
+	RPC       string    `json:"rpc"`
+
+	Chain    *webChain `json:"chain,omitempty"`
+	ChainErr string    `json:"chain_error,omitempty"`
+
+	Registration       *blockchain.BlockchainRegistrationData `json:"registration,omitempty"`
+	LocalRegistrations int                                    `json:"local_registrations"`
+	RegistrationErr    string                                 `json:"registration_error,omitempty"`
+
+	Jobs    []compute.ComputeJob `json:"jobs"`
+	JobsErr string               `json:"jobs_error,omitempty"`
+	Service bool                 `json:"service"`
+
+	GPUs []gpu.DetectedDevice `json:"gpus"`
+
+	Provider       *contract.Provider `json:"provider,omitempty"`
+	ProviderEarned uint64             `json:"provider_earned,omitempty"`
+	ProviderErr    string             `json:"provider_error,omitempty"`
+	Denom          string             `json:"denom"`
+}
+
+type webChain struct {
+	Height      int64     `json:"height"`
+	BlockTime   time.Time `json:"block_time"`
+	ChainID     string    `json:"chain_id"`
+	NodeVersion string    `json:"node_version"`
+	CatchingUp  bool      `json:"catching_up"`
+}
+
+// webResult is a file in the results directory
+type webResult struct {
+	Name     string    `json:"name"`
+	Type     string    `json:"type,omitempty"`
+	TxHash   string    `json:"tx_hash,omitempty"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+func runWeb(cmd *cobra.Command, args []string) error {
+	listen, _ := cmd.Flags().GetString("listen")
+	token, _ := cmd.Flags().GetString("token")
+	serviceURL, _ := cmd.Flags().GetString("service")
+	clientAddr, _ := cmd.Flags().GetString("client-address")
+	providerAddr, _ := cmd.Flags().GetString("provider")
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+	keys, _ := cmd.Flags().GetStringSlice("allow-key")
+
+	cfg := loadConfig()
+	d := &dashboard{cfg: cfg, providerAddr: providerAddr, clientAddr: clientAddr}
+	if serviceURL != "" {
+		d.service = computeclient.New(serviceURL, computeclient.Options{Retries: -1})
+	}
+	if providerAddr != "" {
+		client, err := contractQueryClient(cmd, cfg)
+		if err != nil {
+			return err
+		}
+		d.contract = client
+	}
+	if dataDir != "" {
+		abs, err := filepath.Abs(dataDir)
+		if err != nil {
+			return err
+		}
+		if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+			return fmt.Errorf("--data-dir %s is not a directory", dataDir)
+		}
+		dataDir = abs
+	}
+	run, err := clientCommand()
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	s := &webServer{
+		ctx:        ctx,
+		dash:       d,
+		token:      token,
+		dataDir:    dataDir,
+		keys:       keys,
+		resultsDir: filepath.Join(homeDir, "results"),
+		run:        run,
+	}
+	if err := os.MkdirAll(s.resultsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create results directory: %w", err)
+	}
+
+	server := &http.Server{
+		Addr:              listen,
+		Handler:           cfg.HTTP.Wrap(s.router()),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, done := context.WithTimeout(context.Background(), 5*time.Second)
+		defer done()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("🌐 Web dashboard on http://%s/\n", listen)
+	if token == "" && !loopbackAddress(listen) {
+		fmt.Println("⚠️  Listening beyond loopback without --token, anyone on the network can start jobs")
+	}
+	if len(keys) > 0 {
+		fmt.Printf("🔑 PI jobs may be signed with: %s\n", strings.Join(keys, ", "))
+	}
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// loopbackAddress reports whether a listen address only accepts local connections
+func loopbackAddress(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *webServer) router() http.Handler {
+	r := mux.NewRouter()
+	r.HandleFunc("/api/login", s.handleLogin).Methods("POST")
+
+	api := r.PathPrefix("/api").Subrouter()
+	api.Use(s.requireToken)
+	api.HandleFunc("/status", s.handleStatus).Methods("GET")
+	api.HandleFunc("/results/local", s.handleLocalResults).Methods("GET")
+	api.HandleFunc("/results/local/{name}", s.handleLocalResult).Methods("GET")
+	api.HandleFunc("/results/local/{name}/report.pdf", s.handleResultReport).Methods("GET")
+	api.HandleFunc("/results/local/{name}/orbit.svg", s.handleResultOrbit).Methods("GET")
+	api.HandleFunc("/results/chain", s.handleChainResults).Methods("GET")
+	api.HandleFunc("/tasks", s.handleListTasks).Methods("GET")
+	api.HandleFunc("/tasks", s.handleStartTask).Methods("POST")
+	api.HandleFunc("/tasks/{id}", s.handleGetTask).Methods("GET")
+	api.HandleFunc("/tasks/{id}/cancel", s.handleCancelTask).Methods("POST")
+
+	assets, _ := fs.Sub(webAssets, "web")
+	r.PathPrefix("/").Handler(http.FileServer(http.FS(assets)))
+	return r
+}
+
+// requireToken checks the bearer token or the login cookie. POST bodies must
+// be JSON, so other sites cannot submit forms to the API.
+func (s *webServer) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" && r.ContentLength != 0 {
+			if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct != "application/json" {
+				http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+				return
+			}
+		}
+		if s.token != "" && !s.validToken(r) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *webServer) validToken(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		c, err := r.Cookie(webTokenCookie)
+		if err != nil {
+			return false
+		}
+		token = c.Value
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// handleLogin sets the token cookie of the browser
+func (s *webServer) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if s.token == "" || subtle.ConstantTimeCompare([]byte(req.Token), []byte(s.token)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     webTokenCookie,
+		Value:    req.Token,
+		Path:     "/api",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		Secure:   r.TLS != nil,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *webServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+	snap := s.dash.collect(ctx)
+
+	status := webStatus{
+		UpdatedAt:          snap.UpdatedAt,
+		Network:            network.Current().Name,
+		ChainID:            s.dash.cfg.Chain.ID,
+		RPC:                s.dash.cfg.Chain.RPCEndpoint,
+		ChainErr:           errorText(snap.ChainErr),
+		Registration:       snap.Registration,
+		LocalRegistrations: snap.LocalRegs,
+		RegistrationErr:    errorText(snap.RegistrationErr),
+		Jobs:               snap.Jobs,
+		JobsErr:            errorText(snap.JobsErr),
+		Service:            s.dash.service != nil,
+		GPUs:               snap.GPUs,
+		Provider:           snap.Provider,
+		ProviderEarned:     snap.ProviderEarned,
+		ProviderErr:        errorText(snap.ProviderErr),
+		Denom:              network.Current().BaseDenom,
+	}
+	if c := snap.Chain; c != nil {
+		status.Chain = &webChain{
+			Height:      c.LatestBlockHeight,
+			BlockTime:   c.LatestBlockTime,
+			ChainID:     c.ChainID,
+			NodeVersion: c.NodeVersion,
+			CatchingUp:  c.CatchingUp,
+		}
+	}
+	writeWebJSON(w, http.StatusOK, status)
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// handleLocalResults lists the results directory, newest first
+func (s *webServer) handleLocalResults(w http.ResponseWriter, r *http.Request) {
+	entries, err := os.ReadDir(s.resultsDir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	results := []webResult{}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		res := webResult{Name: e.Name(), Size: info.Size(), Modified: info.ModTime()}
+		if data, err := os.ReadFile(filepath.Join(s.resultsDir, e.Name())); err == nil {
+			res.Type, res.TxHash = webResultType(data)
+		}
+		results = append(results, res)
+	}
+	sort.Slice(results, func(a, b int) bool { return results[a].Modified.After(results[b].Modified) })
+	writeWebJSON(w, http.StatusOK, results)
+}
+
+// webResultType returns the analysis type and transaction of a result file
+func webResultType(data []byte) (string, string) {
+	var probe map[string]json.RawMessage
+	if json.Unmarshal(data, &probe) != nil {
+		return "", ""
+	}
+	var typ, txHash string
+	json.Unmarshal(probe["analysis_type"], &typ)
+	json.Unmarshal(probe["tx_hash"], &txHash)
+	if _, ok := probe["ClusteringScore"]; ok && typ == "" {
+		typ = report.TypePlanet9
+	}
+	return typ, txHash
+}
+
+// localResultPath resolves {name} to a JSON file in the results directory
+func (s *webServer) localResultPath(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := mux.Vars(r)["name"]
+	if !filepath.IsLocal(name) || filepath.Base(name) != name || filepath.Ext(name) != ".json" {
+		http.Error(w, "Invalid result name", http.StatusBadRequest)
+		return "", false
+	}
+	path := filepath.Join(s.resultsDir, name)
+	if _, err := os.Stat(path); err != nil {
+		http.Error(w, "Result not found", http.StatusNotFound)
+		return "", false
+	}
+	return path, true
+}
+
+func (s *webServer) handleLocalResult(w http.ResponseWriter, r *http.Request) {
+	path, ok := s.localResultPath(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	http.ServeFile(w, r, path)
+}
+
+// handleResultReport renders the PDF report of a result
+func (s *webServer) handleResultReport(w http.ResponseWriter, r *http.Request) {
+	path, ok := s.localResultPath(w, r)
+	if !ok {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	doc, err := report.Load(data, report.Options{Source: filepath.Base(path)})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	var buf bytes.Buffer
+	if err := report.WritePDF(doc, &buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", strings.TrimSuffix(filepath.Base(path), ".json")+"_report.pdf"))
+	w.Write(buf.Bytes())
+}
+
+// handleResultOrbit plots the element histograms of an orbital result
+func (s *webServer) handleResultOrbit(w http.ResponseWriter, r *http.Request) {
+	path, ok := s.localResultPath(w, r)
+	if !ok {
+		return
+	}
+	rows, err := loadOrbitRows(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	figure, err := orbitFigure(rows, strings.Split(defaultOrbitElements, ","), defaultOrbitBins)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	var buf bytes.Buffer
+	if err := figure.WriteSVG(&buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Write(buf.Bytes())
+}
+
+// handleChainResults pages through the results stored on chain
+func (s *webServer) handleChainResults(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			http.Error(w, "limit must be 1-100", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	c, err := analysisClient()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	page, err := c.ResultsPage(ctx, blockchain.ResultsQuery{
+		ClientID:     r.URL.Query().Get("client_id"),
+		AnalysisType: r.URL.Query().Get("type"),
+		Cursor:       r.URL.Query().Get("cursor"),
+		Limit:        limit,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeWebJSON(w, http.StatusOK, page)
+}
+
+func (s *webServer) handleListTasks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	tasks := make([]webTask, 0, len(s.tasks))
+	for i := len(s.tasks) - 1; i >= 0; i-- {
+		tasks = append(tasks, s.tasks[i].view(false))
+	}
+	s.mu.Unlock()
+	writeWebJSON(w, http.StatusOK, tasks)
+}
+
+func (s *webServer) handleGetTask(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	t := s.findTask(mux.Vars(r)["id"])
+	var view webTask
+	if t != nil {
+		view = t.view(true)
+	}
+	s.mu.Unlock()
+	if t == nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	writeWebJSON(w, http.StatusOK, view)
+}
+
+func (s *webServer) handleCancelTask(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	t := s.findTask(mux.Vars(r)["id"])
+	if t != nil && t.Status == webTaskRunning {
+		t.cancelled = true
+		t.cancel()
+	}
+	s.mu.Unlock()
+	if t == nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *webServer) handleStartTask(w http.ResponseWriter, r *http.Request) {
+	var req webTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	args, err := s.taskArgs(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	t := &webTask{Kind: req.Kind, Status: webTaskRunning, Started: time.Now(), cancel: cancel, log: &tailBuffer{}}
+	s.mu.Lock()
+	s.nextID++
+	t.ID = strconv.Itoa(s.nextID)
+	result := ""
+	if req.Kind != webTaskPI {
+		result = fmt.Sprintf("web-%s-%s.json", t.Started.Format("20060102-150405"), t.ID)
+		args = append(args, "--output="+filepath.Join(s.resultsDir, result))
+	}
+	t.Args = args
+	s.addTask(t)
+	view := t.view(false)
+	s.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		err := s.run(ctx, "", args, t.log)
+		finished := time.Now()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		t.Finished = &finished
+		switch {
+		case t.cancelled:
+			t.Status = webTaskCancelled
+		case err != nil:
+			t.Status, t.Error = webTaskFailed, err.Error()
+		default:
+			t.Status = webTaskOK
+			if result != "" {
+				if _, err := os.Stat(filepath.Join(s.resultsDir, result)); err == nil {
+					t.Result = result
+				}
+			}
+		}
+	}()
+	writeWebJSON(w, http.StatusAccepted, view)
+}
+
+// taskArgs builds the client command of a job form, without the output of
+// the result. Values are passed as --flag=value, so they cannot add flags of
+// their own.
+func (s *webServer) taskArgs(req webTaskRequest) ([]string, error) {
+	switch req.Kind {
+	case webTaskPlanet9:
+		args := []string{"planet9", "search", "--progress=false", "--snapshot-every-kyr=0"}
+		for _, f := range []struct{ flag, value string }{
+			{"mass", req.Mass}, {"semi-major", req.SemiMajor}, {"eccentricity", req.Eccentricity}, {"inclination", req.Inclination},
+		} {
+			if f.value == "" {
+				continue
+			}
+			if _, err := parseRange(f.value, planet9.Range{}); err != nil {
+				return nil, fmt.Errorf("%s: %w", f.flag, err)
+			}
+			args = append(args, "--"+f.flag+"="+f.value)
+		}
+		if req.Quick && req.Fine {
+			return nil, fmt.Errorf("choose quick or fine, not both")
+		}
+		if req.Quick {
+			args = append(args, "--quick")
+		}
+		if req.Fine {
+			args = append(args, "--fine")
+		}
+		if req.SimYears < 0 {
+			return nil, fmt.Errorf("sim_years must be positive")
+		}
+		if req.SimYears > 0 {
+			args = append(args, "--sim-years="+strconv.FormatFloat(req.SimYears, 'g', -1, 64))
+		}
+		return args, nil
+
+	case webTaskOrbital:
+		if s.dataDir == "" {
+			return nil, fmt.Errorf("orbital dynamics analyses need the web command started with --data-dir")
+		}
+		if req.Input == "" || !filepath.IsLocal(req.Input) {
+			return nil, fmt.Errorf("input must be a file in the data directory")
+		}
+		input := filepath.Join(s.dataDir, req.Input)
+		if info, err := os.Stat(input); err != nil || info.IsDir() {
+			return nil, fmt.Errorf("input %s not found in the data directory", req.Input)
+		}
+		return []string{"analyze", "orbital-dynamics", input}, nil
+
+	case webTaskPI:
+		if !slices.Contains(s.keys, req.From) {
+			return nil, fmt.Errorf("key %q may not sign jobs from the web dashboard, allow it with --allow-key", req.From)
+		}
+		if req.Digits < 1 {
+			return nil, fmt.Errorf("digits must be positive")
+		}
+		args := []string{"contract", "submit-job", "--type=" + webTaskPI, "--digits=" + strconv.Itoa(req.Digits), "--from=" + req.From}
+		if req.Payment != "" {
+			args = append(args, "--payment="+req.Payment)
+		}
+		return args, nil
+	}
+	return nil, fmt.Errorf("unknown task kind %q (%s, %s, %s)", req.Kind, webTaskPlanet9, webTaskOrbital, webTaskPI)
+}
+
+// addTask appends t, dropping the oldest finished tasks beyond webMaxTasks.
+// Called with s.mu held.
+func (s *webServer) addTask(t *webTask) {
+	s.tasks = append(s.tasks, t)
+	for i := 0; len(s.tasks) > webMaxTasks && i < len(s.tasks); {
+		if s.tasks[i].Status != webTaskRunning {
+			s.tasks = slices.Delete(s.tasks, i, i+1)
+			continue
+		}
+		i++
+	}
+}
+
+// findTask is called with s.mu held
+func (s *webServer) findTask(id string) *webTask {
+	for _, t := range s.tasks {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// view copies the task for JSON, called with s.mu held
+func (t *webTask) view(withLog bool) webTask {
+	v := webTask{ID: t.ID, Kind: t.Kind, Args: t.Args, Status: t.Status, Error: t.Error, Result: t.Result, Started: t.Started, Finished: t.Finished}
+	if withLog {
+		v.Log = t.log.String()
+	}
+	return v
+}
+
+// tailBuffer keeps the last webLogBytes written to it
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - webLogBytes; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
+
+func writeWebJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
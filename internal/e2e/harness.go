@@ -0,0 +1,398 @@
+// Package e2e drives the full job lifecycle against the free service, the
+// payment service and a provider node through their public HTTP APIs.
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+	"github.com/oxygene76/medasdigital-client/pkg/computeclient"
+	"github.com/oxygene76/medasdigital-client/pkg/ratelimit"
+)
+
+// piPrefix is what every correct PI result must start with
+const piPrefix = "3.14159"
+
+// Services are the handlers the harness serves in-process.
+// A nil handler skips that service unless a URL is configured in Options.
+type Services struct {
+	Free     http.Handler
+	Payment  http.Handler
+	Provider http.Handler
+
+	// RunProviderJob hands a job to the provider the way a contract event would
+	// and returns the provider job ID and the result hash it would anchor on chain
+	RunProviderJob func(ctx context.Context, params map[string]interface{}) (jobID, resultHash string, err error)
+}
+
+// Options control the scenario
+type Options struct {
+	Digits        int
+	Method        string
+	ClientAddress string
+	JobTimeout    time.Duration
+
+	// Optional URLs of a running deployment, used instead of the in-process handler
+	FreeURL     string
+	PaymentURL  string
+	ProviderURL string
+}
+
+// Step is the outcome of one scenario step
+type Step struct {
+	Name     string        `json:"name"`
+	Passed   bool          `json:"passed"`
+	Skipped  bool          `json:"skipped,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Detail   string        `json:"detail,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Report collects all steps of a run
+type Report struct {
+	Steps    []Step        `json:"steps"`
+	Passed   bool          `json:"passed"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Harness runs the end-to-end scenario
+type Harness struct {
+	svc     Services
+	opts    Options
+	client  *http.Client
+	servers []*httptest.Server
+
+	freeURL     string
+	paymentURL  string
+	providerURL string
+	payment     *computeclient.Client
+}
+
+// New creates a harness, call Start before Run
+func New(svc Services, opts Options) *Harness {
+	if opts.Digits <= 0 {
+		opts.Digits = 50
+	}
+	if opts.Method == "" {
+		opts.Method = "chudnovsky"
+	}
+	if opts.ClientAddress == "" {
+		opts.ClientAddress = "e2e-client"
+	}
+	if opts.JobTimeout <= 0 {
+		opts.JobTimeout = 2 * time.Minute
+	}
+	return &Harness{
+		svc:    svc,
+		opts:   opts,
+		client: &http.Client{Timeout: opts.JobTimeout},
+	}
+}
+
+// Start serves the in-process handlers on loopback ports
+func (h *Harness) Start() {
+	h.freeURL = h.serve(h.opts.FreeURL, h.svc.Free)
+	h.paymentURL = h.serve(h.opts.PaymentURL, h.svc.Payment)
+	h.providerURL = h.serve(h.opts.ProviderURL, h.svc.Provider)
+	if h.paymentURL != "" {
+		h.payment = computeclient.New(h.paymentURL, computeclient.Options{
+			HTTPClient:   h.client,
+			Retries:      -1,
+			PollInterval: 500 * time.Millisecond,
+		})
+	}
+}
+
+// Close stops the in-process servers
+func (h *Harness) Close() {
+	for _, s := range h.servers {
+		s.Close()
+	}
+	h.servers = nil
+}
+
+func (h *Harness) serve(url string, handler http.Handler) string {
+	if url != "" {
+		return strings.TrimSuffix(url, "/")
+	}
+	if handler == nil {
+		return ""
+	}
+	s := httptest.NewServer(handler)
+	h.servers = append(h.servers, s)
+	return s.URL
+}
+
+// Run executes the scenario, later steps of a service are skipped after a failure
+func (h *Harness) Run(ctx context.Context) *Report {
+	start := time.Now()
+	report := &Report{Passed: true}
+
+	record := func(step Step) bool {
+		report.Steps = append(report.Steps, step)
+		if !step.Passed && !step.Skipped {
+			report.Passed = false
+		}
+		return step.Passed
+	}
+
+	if h.freeURL == "" {
+		record(skipped("free service"))
+	} else {
+		_ = record(h.step("free/status", func() (string, error) {
+			return "", h.getJSON(ctx, h.freeURL+"/api/v1/status", nil)
+		})) && record(h.step("free/calculate", func() (string, error) {
+			return h.freeCalculate(ctx)
+		}))
+	}
+
+	if h.paymentURL == "" {
+		record(skipped("payment service"))
+	} else {
+		var jobID string
+		_ = record(h.step("payment/pricing", func() (string, error) {
+			return "", h.getJSON(ctx, h.paymentURL+"/api/v1/pricing", nil)
+		})) && record(h.step("payment/estimate", func() (string, error) {
+			return h.paymentEstimate(ctx)
+		})) && record(h.step("payment/submit", func() (detail string, err error) {
+			jobID, err = h.paymentSubmit(ctx)
+			return "job " + jobID, err
+		})) && record(h.step("payment/complete", func() (string, error) {
+			return h.paymentWait(ctx, jobID)
+		}))
+	}
+
+	if h.providerURL == "" {
+		record(skipped("provider node"))
+	} else {
+		ok := record(h.step("provider/health", func() (string, error) {
+			return "", h.getJSON(ctx, h.providerURL+"/health", nil)
+		}))
+		if ok && h.svc.RunProviderJob != nil && h.opts.ProviderURL == "" {
+			record(h.step("provider/job", func() (string, error) {
+				return h.providerJob(ctx)
+			}))
+		}
+	}
+
+	report.Duration = time.Since(start)
+	return report
+}
+
+func (h *Harness) step(name string, fn func() (string, error)) Step {
+	start := time.Now()
+	detail, err := fn()
+	step := Step{Name: name, Passed: err == nil, Duration: time.Since(start), Detail: detail}
+	if err != nil {
+		step.Error = err.Error()
+	}
+	return step
+}
+
+func skipped(name string) Step {
+	return Step{Name: name, Skipped: true, Detail: "not configured"}
+}
+
+func (h *Harness) freeCalculate(ctx context.Context) (string, error) {
+	var resp struct {
+		Result struct {
+			Value  string `json:"value"`
+			Digits int    `json:"digits"`
+		} `json:"result"`
+	}
+	header, err := h.freeProtection(ctx)
+	if err != nil {
+		return "", err
+	}
+	req := map[string]interface{}{"digits": h.opts.Digits, "method": h.opts.Method}
+	if err := h.postJSON(ctx, h.freeURL+"/api/v1/calculate", header, req, &resp); err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(resp.Result.Value, piPrefix) {
+		return "", fmt.Errorf("unexpected PI value %q", truncate(resp.Result.Value))
+	}
+	return fmt.Sprintf("%d digits", resp.Result.Digits), nil
+}
+
+// freeProtection solves the proof of work a free service asks for
+func (h *Harness) freeProtection(ctx context.Context) (http.Header, error) {
+	var limits struct {
+		AbuseProtection struct {
+			Mode string `json:"mode"`
+		} `json:"abuse_protection"`
+	}
+	if err := h.getJSON(ctx, h.freeURL+"/api/v1/limits", &limits); err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	switch mode := limits.AbuseProtection.Mode; mode {
+	case "", "off":
+	case "pow":
+		var challenge ratelimit.Challenge
+		if err := h.getJSON(ctx, h.freeURL+"/api/v1/challenge", &challenge); err != nil {
+			return nil, err
+		}
+		solution, err := ratelimit.Solve(ctx, challenge)
+		if err != nil {
+			return nil, fmt.Errorf("proof of work: %w", err)
+		}
+		header.Set(ratelimit.PoWHeader, solution)
+	default:
+		return nil, fmt.Errorf("free service requires abuse protection %q", mode)
+	}
+	return header, nil
+}
+
+func (h *Harness) paymentEstimate(ctx context.Context) (string, error) {
+	est, err := h.payment.EstimateCost(ctx, computeclient.EstimateRequest{
+		Digits: h.opts.Digits,
+		Method: h.opts.Method,
+		Tier:   compute.TierBasic,
+	})
+	if err != nil {
+		return "", err
+	}
+	if est.PriceBreakdown == nil || est.PriceBreakdown.TotalCost <= 0 {
+		return "", fmt.Errorf("estimate returned no price")
+	}
+	return fmt.Sprintf("%.6f", est.PriceBreakdown.TotalCost), nil
+}
+
+func (h *Harness) paymentSubmit(ctx context.Context) (string, error) {
+	sub, err := h.payment.SubmitJob(ctx, computeclient.JobRequest{
+		Type:          compute.JobTypePICalculation,
+		Parameters:    map[string]interface{}{"digits": h.opts.Digits, "method": h.opts.Method},
+		Tier:          compute.TierBasic,
+		PaymentTxHash: fmt.Sprintf("E2E%X", time.Now().UnixNano()),
+		ClientAddress: h.opts.ClientAddress,
+	})
+	if err != nil {
+		return "", err
+	}
+	if sub.JobID == "" {
+		return "", fmt.Errorf("no job_id in response")
+	}
+	return sub.JobID, nil
+}
+
+func (h *Harness) paymentWait(ctx context.Context, jobID string) (string, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, h.opts.JobTimeout)
+	defer cancel()
+
+	job, err := h.payment.WaitForResult(waitCtx, jobID)
+	if err != nil {
+		if waitCtx.Err() != nil && ctx.Err() == nil && job != nil {
+			return "", fmt.Errorf("job %s still %s after %v", jobID, job.Status, h.opts.JobTimeout)
+		}
+		return "", err
+	}
+
+	// Results are decoded generically, the PI value is a string field
+	var result struct {
+		Value string `json:"value"`
+	}
+	raw, _ := json.Marshal(job.Result)
+	json.Unmarshal(raw, &result)
+	if !strings.HasPrefix(result.Value, piPrefix) {
+		return "", fmt.Errorf("unexpected PI value %q", truncate(result.Value))
+	}
+	return "completed, payment verified", nil
+}
+
+func (h *Harness) providerJob(ctx context.Context) (string, error) {
+	jobCtx, cancel := context.WithTimeout(ctx, h.opts.JobTimeout)
+	defer cancel()
+
+	// Contract jobs carry their parameters as JSON, decode them the same way
+	var params map[string]interface{}
+	raw, _ := json.Marshal(map[string]interface{}{"digits": h.opts.Digits, "method": h.opts.Method})
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return "", err
+	}
+	jobID, resultHash, err := h.svc.RunProviderJob(jobCtx, params)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		Status string          `json:"status"`
+		Result json.RawMessage `json:"result"`
+	}
+	if err := h.getJSON(ctx, fmt.Sprintf("%s/results/%s.json", h.providerURL, jobID), &resp); err != nil {
+		return "", err
+	}
+	if resp.Status != "completed" {
+		return "", fmt.Errorf("result status %q", resp.Status)
+	}
+
+	// The published result must match the hash the provider would anchor on chain
+	sum := sha256.Sum256(bytes.TrimSpace(resp.Result))
+	if got := hex.EncodeToString(sum[:]); got != resultHash {
+		return "", fmt.Errorf("result hash mismatch: served %s, anchored %s", got, resultHash)
+	}
+	return "hash " + resultHash[:16], nil
+}
+
+func (h *Harness) getJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	return h.do(req, out)
+}
+
+func (h *Harness) postJSON(ctx context.Context, url string, header http.Header, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return h.do(req, out)
+}
+
+func (h *Harness) do(req *http.Request, out interface{}) error {
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", req.Method, req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", req.Method, req.URL.Path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: HTTP %d: %s", req.Method, req.URL.Path, resp.StatusCode, truncate(strings.TrimSpace(string(body))))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("%s %s: invalid response: %w", req.Method, req.URL.Path, err)
+	}
+	return nil
+}
+
+func truncate(s string) string {
+	if len(s) > 80 {
+		return s[:80] + "..."
+	}
+	return s
+}
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"cosmossdk.io/errors"
+
+	"github.com/oxygene76/medasdigital-client/pkg/astronomy/orbital"
 )
 
 // Error definitions
@@ -42,10 +44,24 @@ type OrbitalDynamicsResult struct {
 	ClusteringSignificance float64         `json:"clustering_significance"`
 	GravitationalEffects   []GravEffect    `json:"gravitational_effects"`
 	Recommendations        []Recommendation `json:"recommendations"`
+	Frame                  string           `json:"frame,omitempty"` // of the object elements, e.g. heliocentric-ecliptic
+	MonteCarlo             *MonteCarloResult `json:"monte_carlo,omitempty"`
+	// Set when ClusteringSignificance is debiased for the survey selection
+	RawClusteringSignificance float64  `json:"raw_clustering_significance,omitempty"`
+	ClusteringPValue          float64  `json:"clustering_p_value,omitempty"`
+	Surveys                   []string `json:"surveys,omitempty"`
         
 
 }
 
+// MonteCarloResult holds the spread of the orbital analysis over clones
+// drawn from the element uncertainties
+type MonteCarloResult struct {
+	Clones                 int              `json:"clones"`
+	ClusteringSignificance orbital.Interval `json:"clustering_significance"`
+	Planet9Probability     orbital.Interval `json:"planet9_probability"`
+}
+
 // PhotometricResult represents photometric analysis results
 type PhotometricResult struct {
 	*AnalysisResult
@@ -89,6 +105,8 @@ type AITrainingResult struct {
 type GPUDevice struct {
 	ID                 int     `json:"id"`
 	Name               string  `json:"name"`
+	Vendor             string  `json:"vendor,omitempty"`    // nvidia, amd or apple
+	Backend            string  `json:"backend,omitempty"`   // cuda, rocm or metal
 	Memory             int64   `json:"memory"`              // Total memory in bytes
 	MemoryUsed         int64   `json:"memory_used"`         // Used memory in bytes
 	MemoryFree         int64   `json:"memory_free"`         // Free memory in bytes
@@ -147,6 +165,7 @@ type GPUInfo struct {
 	Devices            []GPUDevice `json:"devices"`
 	TotalMemoryGB      float64     `json:"total_memory_gb"`
 	AvailableMemoryGB  float64     `json:"available_memory_gb"`
+	Backend            string      `json:"backend,omitempty"`
 	CUDAVersion        string      `json:"cuda_version"`
 	DriverVersion      string      `json:"driver_version"`
 	IsInitialized      bool        `json:"is_initialized"`
@@ -1,6 +1,7 @@
 package analysis
 
 import (
+	"context"
 	"encoding/csv"
 	"fmt"
 	"log"
@@ -10,13 +11,17 @@ import (
 	"time"
 
 	"github.com/oxygene76/medasdigital-client/internal/types"
+	"github.com/oxygene76/medasdigital-client/pkg/astronomy/orbital"
+	"github.com/oxygene76/medasdigital-client/pkg/astronomy/survey"
 	"github.com/oxygene76/medasdigital-client/pkg/gpu"
+	"github.com/oxygene76/medasdigital-client/pkg/inference"
 	"gonum.org/v1/gonum/stat"
 )
 
 // Manager handles all analysis operations
 type Manager struct {
 	gpuManager *gpu.Manager
+	quiet      bool // no per-step logs, for the Monte Carlo clones
 }
 
 // NewManager creates a new analysis manager
@@ -26,8 +31,18 @@ func NewManager(gpuManager *gpu.Manager) *Manager {
 	}
 }
 
+// OrbitalOptions configures AnalyzeOrbitalDynamics
+type OrbitalOptions struct {
+	Epoch       float64                  // JD the objects are propagated to, default J2000
+	Propagation string                   // orbital.PropagationTwoBody (default), orbital.PropagationSecular or none
+	Frame       orbital.Frame            // the clustering is measured in, default heliocentric-ecliptic like the input
+	Clones      int                      // Monte Carlo clones drawn from the element uncertainties, 0 = none
+	Seed        int64                    // of the clone draws and the survey simulation
+	Selection   survey.SelectionFunction // surveys the clustering significance is debiased for, nil = raw significance
+}
+
 // AnalyzeOrbitalDynamics performs orbital dynamics analysis
-func (m *Manager) AnalyzeOrbitalDynamics(inputFile string) (*types.AnalysisResult, error) {
+func (m *Manager) AnalyzeOrbitalDynamics(ctx context.Context, inputFile string, opts OrbitalOptions) (*types.AnalysisResult, error) {
 	log.Printf("Starting orbital dynamics analysis on file: %s", inputFile)
 	start := time.Now()
 
@@ -39,11 +54,53 @@ func (m *Manager) AnalyzeOrbitalDynamics(inputFile string) (*types.AnalysisResul
 
 	log.Printf("Loaded %d TNO objects", len(objects))
 
+	// The clones start from the catalog elements, before propagation
+	var nominal []types.TNOObject
+	if opts.Clones > 0 {
+		nominal = append(nominal, objects...)
+	}
+
+	// Elements of different epochs are compared at a common one
+	epoch, moved, err := propagateTNOs(objects, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Propagation != "none" {
+		log.Printf("Propagated %d TNO objects to JD %.1f", moved, epoch)
+	}
+
+	// The surveys select on the heliocentric ecliptic elements, so the
+	// debiased significance is measured before the frame changes
+	var null *survey.Null
+	var debiased, pValue float64
+	if opts.Selection != nil {
+		null = newClusteringNull(objects, opts)
+		debiased, pValue = debiasedSignificance(null, objects)
+		log.Printf("Debiased clustering significance for %d surveys: %.2fσ (p=%.3g)", len(opts.Selection), debiased, pValue)
+	}
+	frame := opts.Frame.OrDefault()
+	if err := convertTNOFrame(objects, frame, epoch); err != nil {
+		return nil, err
+	}
+
 	// Perform analysis
-	result, err := m.performOrbitalAnalysis(objects)
+	result, err := m.performOrbitalAnalysis(ctx, objects)
 	if err != nil {
 		return nil, fmt.Errorf("orbital analysis failed: %w", err)
 	}
+	result.Frame = string(frame)
+	if null != nil {
+		result.RawClusteringSignificance = result.ClusteringSignificance
+		result.ClusteringSignificance = debiased
+		result.ClusteringPValue = pValue
+		result.Surveys = opts.Selection.Names()
+	}
+
+	if opts.Clones > 0 {
+		if result.MonteCarlo, err = m.monteCarlo(ctx, nominal, opts, null); err != nil {
+			return nil, fmt.Errorf("monte carlo cloning failed: %w", err)
+		}
+	}
 
 	// Create analysis result
 	analysisResult := &types.AnalysisResult{
@@ -60,6 +117,9 @@ func (m *Manager) AnalyzeOrbitalDynamics(inputFile string) (*types.AnalysisResul
 			"num_objects":     fmt.Sprintf("%d", len(objects)),
 			"analysis_method": "n_body_simulation",
 			"version":         "1.0.0",
+			"epoch_jd":        fmt.Sprintf("%.1f", epoch),
+			"frame":           string(frame),
+			"debiased":        fmt.Sprintf("%t", null != nil),
 		},
 		Timestamp:   time.Now(),
 		ClientID:    "",
@@ -117,6 +177,198 @@ func (m *Manager) loadTNOData(filename string) ([]types.TNOObject, error) {
 	return objects, nil
 }
 
+// propagateTNOs moves the objects with a known epoch to the epoch of opts
+// and returns it, 0 when propagation is off, and the number moved
+func propagateTNOs(objects []types.TNOObject, opts OrbitalOptions) (float64, int, error) {
+	if opts.Propagation == "none" {
+		return 0, 0, nil
+	}
+	epoch := opts.Epoch
+	if epoch == 0 {
+		epoch = orbital.J2000
+	}
+
+	moved := 0
+	for i := range objects {
+		obj := &objects[i]
+		if obj.Epoch == 0 || obj.Epoch == epoch {
+			continue
+		}
+		p, err := tnoElements(obj).PropagateTo(epoch, opts.Propagation)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to propagate %s to JD %.1f: %w", obj.Designation, epoch, err)
+		}
+		setTNOElements(obj, p)
+		moved++
+	}
+	return epoch, moved, nil
+}
+
+// clusteringElements returns the elements of the distant, eccentric objects
+// the clustering is measured on, and their absolute magnitudes
+func clusteringElements(objects []types.TNOObject) ([]orbital.OrbitalElements, []float64) {
+	var elements []orbital.OrbitalElements
+	var h []float64
+	for i := range objects {
+		if objects[i].SemimajorAxis > 30 && objects[i].Eccentricity > 0.3 {
+			elements = append(elements, tnoElements(&objects[i]))
+			h = append(h, objects[i].AbsoluteMagnitude)
+		}
+	}
+	return elements, h
+}
+
+// newClusteringNull simulates the clustering the surveys of opts produce
+// for objects with random orientations
+func newClusteringNull(objects []types.TNOObject, opts OrbitalOptions) *survey.Null {
+	elements, h := clusteringElements(objects)
+	null := survey.NewNull(opts.Selection, elements, h, survey.DefaultTrials, opts.Seed)
+	if null.Undetectable > 0 {
+		log.Printf("Warning: %d objects are outside the survey footprints, their orientations are taken as unbiased", null.Undetectable)
+	}
+	return null
+}
+
+// debiasedSignificance compares the clustering of objects to the null and
+// returns it in σ with its p-value
+func debiasedSignificance(null *survey.Null, objects []types.TNOObject) (float64, float64) {
+	elements, _ := clusteringElements(objects)
+	if len(elements) < 3 {
+		return 0, 1
+	}
+	stat := survey.ClusteringStatistic(elements)
+	return null.Significance(stat), null.PValue(stat)
+}
+
+// monteCarlo repeats the clustering and Planet 9 analysis for clones of
+// the objects drawn from their element uncertainties. With a null the
+// clustering significance of each clone is debiased.
+func (m *Manager) monteCarlo(ctx context.Context, objects []types.TNOObject, opts OrbitalOptions, null *survey.Null) (*types.MonteCarloResult, error) {
+	elements := make([]orbital.OrbitalElements, len(objects))
+	covs := make([]orbital.Covariance, len(objects))
+	uncertain := 0
+	for i := range objects {
+		elements[i] = tnoElements(&objects[i])
+		cov, err := orbital.CovarianceFromUncertainty(objects[i].OrbitalElements.Uncertainty)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", objects[i].Designation, err)
+		}
+		covs[i] = cov
+		if !cov.IsZero() {
+			uncertain++
+		}
+	}
+	if uncertain == 0 {
+		log.Printf("Warning: no element uncertainties in the input, skipping %d clones", opts.Clones)
+		return nil, nil
+	}
+	log.Printf("Analyzing %d clones of %d objects with uncertainties", opts.Clones, uncertain)
+
+	sets, err := orbital.CloneSets(elements, covs, opts.Clones, opts.Seed)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Manager{gpuManager: m.gpuManager, quiet: true}
+	significance := make([]float64, len(sets))
+	probability := make([]float64, len(sets))
+	for k, set := range sets {
+		clones := append([]types.TNOObject(nil), objects...)
+		for i := range clones {
+			setTNOElements(&clones[i], set[i])
+		}
+		epoch, _, err := propagateTNOs(clones, opts)
+		if err != nil {
+			return nil, err
+		}
+		if null != nil {
+			significance[k], _ = debiasedSignificance(null, clones)
+		}
+		if err := convertTNOFrame(clones, opts.Frame.OrDefault(), epoch); err != nil {
+			return nil, err
+		}
+
+		effects, err := q.simulateGravitationalEffects(ctx, clones)
+		if err != nil {
+			return nil, err
+		}
+		if null == nil {
+			significance[k] = q.calculateClusteringSignificance(clones)
+		}
+		probability[k] = q.calculatePlanet9Probability(clones, effects)
+	}
+
+	result := &types.MonteCarloResult{
+		Clones:                 len(sets),
+		ClusteringSignificance: orbital.NewInterval(significance),
+		Planet9Probability:     orbital.NewInterval(probability),
+	}
+	log.Printf("Monte Carlo: clustering significance %s, Planet 9 probability %s",
+		result.ClusteringSignificance, result.Planet9Probability)
+	return result, nil
+}
+
+// convertTNOFrame moves the heliocentric ecliptic elements of the input to
+// frame. Objects without an epoch are taken at epoch, J2000 if it is 0.
+func convertTNOFrame(objects []types.TNOObject, frame orbital.Frame, epoch float64) error {
+	if frame == orbital.HeliocentricEcliptic {
+		return nil
+	}
+	if epoch == 0 {
+		epoch = orbital.J2000
+	}
+	for i := range objects {
+		obj := &objects[i]
+		o := tnoElements(obj)
+		if o.Epoch == 0 {
+			o.Epoch = epoch
+		}
+		c, err := o.ConvertFrame(orbital.HeliocentricEcliptic, frame)
+		if err != nil {
+			return fmt.Errorf("failed to convert %s to %s: %w", obj.Designation, frame, err)
+		}
+		setTNOElements(obj, c)
+		obj.Epoch = c.Epoch
+	}
+	return nil
+}
+
+// tnoElements returns the elements of an object with the angles in radians
+func tnoElements(obj *types.TNOObject) orbital.OrbitalElements {
+	toRad := math.Pi / 180
+	return orbital.OrbitalElements{
+		SemiMajorAxis:          obj.SemimajorAxis,
+		Eccentricity:           obj.Eccentricity,
+		Inclination:            obj.Inclination * toRad,
+		LongitudeAscendingNode: obj.LongitudeNode * toRad,
+		ArgumentPerihelion:     obj.ArgumentPeriapsis * toRad,
+		MeanAnomaly:            obj.MeanAnomaly * toRad,
+		Epoch:                  obj.Epoch,
+	}
+}
+
+// setTNOElements stores elements on an object, the angles in degrees
+func setTNOElements(obj *types.TNOObject, o orbital.OrbitalElements) {
+	toDeg := 180 / math.Pi
+	obj.SemimajorAxis = o.SemiMajorAxis
+	obj.Eccentricity = o.Eccentricity
+	obj.Inclination = o.Inclination * toDeg
+	obj.LongitudeNode = o.LongitudeAscendingNode * toDeg
+	obj.ArgumentPeriapsis = o.ArgumentPerihelion * toDeg
+	obj.MeanAnomaly = o.MeanAnomaly * toDeg
+	obj.Epoch = o.Epoch
+}
+
+// tnoSigmaColumns names the uncertainty columns following the absolute magnitude
+var tnoSigmaColumns = []string{
+	orbital.UncertaintySemiMajorAxis,
+	orbital.UncertaintyEccentricity,
+	orbital.UncertaintyInclination,
+	orbital.UncertaintyNode,
+	orbital.UncertaintyPerihelion,
+	orbital.UncertaintyMeanAnomaly,
+}
+
 // parseTNORecord parses a single TNO record from CSV
 func (m *Manager) parseTNORecord(record []string) (types.TNOObject, error) {
 	parseFloat := func(s string) (float64, error) {
@@ -158,6 +410,23 @@ func (m *Manager) parseTNORecord(record []string) (types.TNOObject, error) {
 		obj.AbsoluteMagnitude, _ = parseFloat(record[8])
 	}
 
+	// Optional 1σ of the elements, in the order of the element columns
+	for i, name := range tnoSigmaColumns {
+		if len(record) <= 9+i {
+			break
+		}
+		sigma, err := parseFloat(record[9+i])
+		if err != nil {
+			return obj, fmt.Errorf("invalid uncertainty of %s: %w", name, err)
+		}
+		if sigma > 0 {
+			if obj.OrbitalElements.Uncertainty == nil {
+				obj.OrbitalElements.Uncertainty = make(map[string]float64)
+			}
+			obj.OrbitalElements.Uncertainty[name] = sigma
+		}
+	}
+
 	// Estimate diameter from absolute magnitude (simplified)
 	if obj.AbsoluteMagnitude != 0 {
 		obj.AlbedoEstimate = 0.1 // Assume 10% albedo
@@ -167,6 +436,13 @@ func (m *Manager) parseTNORecord(record []string) (types.TNOObject, error) {
 	return obj, nil
 }
 
+// logf logs unless the manager is quiet
+func (m *Manager) logf(format string, args ...interface{}) {
+	if !m.quiet {
+		log.Printf(format, args...)
+	}
+}
+
 // estimateDiameter estimates object diameter from absolute magnitude
 func (m *Manager) estimateDiameter(H, albedo float64) float64 {
 	// Using the standard formula: D = 1329 * sqrt(albedo) * 10^(-H/5)
@@ -177,14 +453,17 @@ func (m *Manager) estimateDiameter(H, albedo float64) float64 {
 }
 
 // performOrbitalAnalysis performs the main orbital analysis
-func (m *Manager) performOrbitalAnalysis(objects []types.TNOObject) (*types.OrbitalDynamicsResult, error) {
+func (m *Manager) performOrbitalAnalysis(ctx context.Context, objects []types.TNOObject) (*types.OrbitalDynamicsResult, error) {
 	log.Println("Performing orbital dynamics analysis...")
 
 	// Calculate clustering significance
 	clusteringSig := m.calculateClusteringSignificance(objects)
 
 	// Simulate gravitational effects
-	gravEffects := m.simulateGravitationalEffects(objects)
+	gravEffects, err := m.simulateGravitationalEffects(ctx, objects)
+	if err != nil {
+		return nil, err
+	}
 
 	// Calculate Planet 9 probability
 	planet9Prob := m.calculatePlanet9Probability(objects, gravEffects)
@@ -241,14 +520,14 @@ func (m *Manager) calculateClusteringSignificance(objects []types.TNOObject) flo
 	// Combined significance
 	significance := math.Sqrt(periapsisSig*periapsisSig + inclinationSig*inclinationSig)
 
-	log.Printf("Clustering analysis: periapsis=%.2f±%.2f°, inclination=%.2f±%.2f°, significance=%.2fσ",
+	m.logf("Clustering analysis: periapsis=%.2f±%.2f°, inclination=%.2f±%.2f°, significance=%.2fσ",
 		meanPeriapsis, stdPeriapsis, meanInclination, stdInclination, significance)
 
 	return significance
 }
 
 // simulateGravitationalEffects simulates gravitational effects of hypothetical Planet 9
-func (m *Manager) simulateGravitationalEffects(objects []types.TNOObject) []types.GravEffect {
+func (m *Manager) simulateGravitationalEffects(ctx context.Context, objects []types.TNOObject) ([]types.GravEffect, error) {
 	var effects []types.GravEffect
 
 	// Hypothetical Planet 9 parameters
@@ -256,10 +535,13 @@ func (m *Manager) simulateGravitationalEffects(objects []types.TNOObject) []type
 	planet9Distance := 600.0  // AU
 	planet9Inclination := 30.0 // degrees
 
-	log.Printf("Simulating gravitational effects for Planet 9 (mass=%.1f M⊕, distance=%.1f AU)",
+	m.logf("Simulating gravitational effects for Planet 9 (mass=%.1f M⊕, distance=%.1f AU)",
 		planet9Mass, planet9Distance)
 
 	for _, obj := range objects {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		// Only analyze distant objects that could be affected
 		if obj.SemimajorAxis < 30 {
 			continue
@@ -271,8 +553,8 @@ func (m *Manager) simulateGravitationalEffects(objects []types.TNOObject) []type
 		}
 	}
 
-	log.Printf("Found %d objects with significant gravitational effects", len(effects))
-	return effects
+	m.logf("Found %d objects with significant gravitational effects", len(effects))
+	return effects, nil
 }
 
 // calculateGravitationalEffect calculates gravitational effect on a single object
@@ -329,7 +611,7 @@ func (m *Manager) calculatePlanet9Probability(objects []types.TNOObject, effects
 
 	probability := math.Min(0.95, probabilityBase*significanceBoost*2.0)
 
-	log.Printf("Planet 9 probability calculation: %d/%d significant effects, avg significance=%.2f, probability=%.2f",
+	m.logf("Planet 9 probability calculation: %d/%d significant effects, avg significance=%.2f, probability=%.2f",
 		significantEffects, len(effects), totalSignificance/float64(len(effects)), probability)
 
 	return probability
@@ -391,7 +673,7 @@ func (m *Manager) generateRecommendations(objects []types.TNOObject, effects []t
 }
 
 // AnalyzePhotometric performs photometric analysis (placeholder)
-func (m *Manager) AnalyzePhotometric(surveyData, targetList string) (*types.AnalysisResult, error) {
+func (m *Manager) AnalyzePhotometric(ctx context.Context, surveyData, targetList string) (*types.AnalysisResult, error) {
 	log.Printf("Starting photometric analysis on survey: %s", surveyData)
 
 	result := &types.AnalysisResult{
@@ -415,7 +697,7 @@ func (m *Manager) AnalyzePhotometric(surveyData, targetList string) (*types.Anal
 }
 
 // AnalyzeClustering performs clustering analysis (placeholder)
-func (m *Manager) AnalyzeClustering() (*types.AnalysisResult, error) {
+func (m *Manager) AnalyzeClustering(ctx context.Context) (*types.AnalysisResult, error) {
 	log.Println("Starting clustering analysis")
 
 	result := &types.AnalysisResult{
@@ -437,21 +719,81 @@ func (m *Manager) AnalyzeClustering() (*types.AnalysisResult, error) {
 	return result, nil
 }
 
-// AIDetection performs AI-powered object detection (placeholder)
-func (m *Manager) AIDetection(modelPath, surveyImages string, gpuAccel bool) (*types.AnalysisResult, error) {
+// DetectionOptions configures ONNX inference for AIDetection
+type DetectionOptions struct {
+	UseGPU         bool
+	DeviceID       int // -1 lets the GPU scheduler pick a device
+	MemoryMB       int // GPU memory reserved with the scheduler
+	BatchSize      int
+	ScoreThreshold float32
+	LibraryPath    string
+}
+
+// AIDetection runs an exported ONNX detection model over FITS/PNG cutouts
+func (m *Manager) AIDetection(ctx context.Context, modelPath, surveyImages string, opts DetectionOptions) (*types.AnalysisResult, error) {
 	log.Printf("Starting AI detection with model: %s", modelPath)
+	start := time.Now()
+
+	files, err := inference.CollectImages(surveyImages)
+	if err != nil {
+		return nil, err
+	}
+
+	cutouts := make([]*inference.Cutout, 0, len(files))
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		c, err := inference.LoadCutout(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load image: %w", err)
+		}
+		cutouts = append(cutouts, c)
+	}
+	log.Printf("Loaded %d images", len(cutouts))
+
+	detector, err := inference.NewDetector(inference.Options{
+		ModelPath:      modelPath,
+		LibraryPath:    opts.LibraryPath,
+		UseGPU:         opts.UseGPU,
+		DeviceID:       opts.DeviceID,
+		BatchSize:      opts.BatchSize,
+		ScoreThreshold: opts.ScoreThreshold,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer detector.Close()
+
+	detections, err := detector.Detect(ctx, cutouts)
+	if err != nil {
+		return nil, fmt.Errorf("inference failed: %w", err)
+	}
+
+	perImage := make(map[string]int)
+	for _, d := range detections {
+		perImage[d.Image]++
+	}
 
 	result := &types.AnalysisResult{
 		AnalysisType: "ai_detection",
 		Data: map[string]interface{}{
-			"id":      fmt.Sprintf("ai_detection_%d", time.Now().Unix()),
-			"status":  "completed",
-			"message": "AI detection placeholder",
+			"id":               fmt.Sprintf("ai_detection_%d", time.Now().Unix()),
+			"status":           "completed",
+			"images":           len(cutouts),
+			"detections":       detections,
+			"detection_count":  len(detections),
+			"per_image_counts": perImage,
+			"duration":         time.Since(start).String(),
 		},
 		Metadata: map[string]string{
-			"input_files": surveyImages,
-			"gpu_used":    fmt.Sprintf("%t", gpuAccel),
-			"version":     "1.0.0",
+			"input_files":     surveyImages,
+			"model":           modelPath,
+			"gpu_used":        fmt.Sprintf("%t", detector.GPUUsed),
+			"batch_size":      fmt.Sprintf("%d", opts.BatchSize),
+			"score_threshold": fmt.Sprintf("%.2f", opts.ScoreThreshold),
+			"runtime":         "onnxruntime",
+			"version":         "1.0.0",
 		},
 		Timestamp:   time.Now(),
 		ClientID:    "",
@@ -459,11 +801,12 @@ func (m *Manager) AIDetection(modelPath, surveyImages string, gpuAccel bool) (*t
 		TxHash:      "",
 	}
 
+	log.Printf("AI detection found %d objects in %d images (%v)", len(detections), len(cutouts), time.Since(start))
 	return result, nil
 }
 
 // TrainDeepDetector trains a deep learning detector (placeholder)
-func (m *Manager) TrainDeepDetector(trainingData, architecture string, gpuDevices []int, batchSize, epochs int) (*types.AnalysisResult, error) {
+func (m *Manager) TrainDeepDetector(ctx context.Context, trainingData, architecture string, gpuDevices []int, batchSize, epochs int) (*types.AnalysisResult, error) {
 	log.Printf("Starting deep detector training with architecture: %s", architecture)
 
 	result := &types.AnalysisResult{
@@ -492,7 +835,7 @@ func (m *Manager) TrainDeepDetector(trainingData, architecture string, gpuDevice
 }
 
 // TrainAnomalyDetector trains an anomaly detection model (placeholder)
-func (m *Manager) TrainAnomalyDetector() (*types.AnalysisResult, error) {
+func (m *Manager) TrainAnomalyDetector(ctx context.Context) (*types.AnalysisResult, error) {
 	log.Println("Starting anomaly detector training")
 
 	result := &types.AnalysisResult{
@@ -0,0 +1,445 @@
+package catalog
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/orbital"
+)
+
+// DefaultSBDBURL is the query API of the JPL Small-Body Database
+const DefaultSBDBURL = "https://ssd-api.jpl.nasa.gov/sbdb_query.api"
+
+// The usual ETNO definition, a > 150 AU and q > 30 AU
+const (
+    DefaultMinSemiMajorAxis = 150.0
+    DefaultMinPerihelion    = 30.0
+)
+
+// Latest names the most recent snapshot of a Store
+const Latest = "latest"
+
+// sbdbFields are requested in this order
+var sbdbFields = []string{"full_name", "pdes", "name", "epoch", "a", "e", "i", "om", "w", "ma", "q", "ad", "per", "H", "diameter"}
+
+// sbdbSigmas are the 1σ uncertainties requested after sbdbFields, by the
+// element names of an uncertainty map. Objects without an orbit solution
+// have none.
+var sbdbSigmas = [][2]string{
+    {"sigma_a", orbital.UncertaintySemiMajorAxis},
+    {"sigma_e", orbital.UncertaintyEccentricity},
+    {"sigma_i", orbital.UncertaintyInclination},
+    {"sigma_om", orbital.UncertaintyNode},
+    {"sigma_w", orbital.UncertaintyPerihelion},
+    {"sigma_ma", orbital.UncertaintyMeanAnomaly},
+}
+
+// Elements are osculating elements in AU, degrees and days, named like the
+// entries of data/solar_system_objects.json
+type Elements struct {
+    SemiMajorAxis          float64 `json:"semimajor_axis"`
+    Eccentricity           float64 `json:"eccentricity"`
+    Inclination            float64 `json:"inclination"`
+    LongitudeAscendingNode float64 `json:"longitude_ascending_node"`
+    ArgumentPerihelion     float64 `json:"argument_perihelion"`
+    MeanAnomaly            float64 `json:"mean_anomaly"`
+    Perihelion             float64 `json:"perihelion,omitempty"`
+    Aphelion               float64 `json:"aphelion,omitempty"`
+    OrbitalPeriod          float64 `json:"orbital_period,omitempty"`
+}
+
+// Physical holds what SBDB knows about the size of an object
+type Physical struct {
+    AbsoluteMagnitude float64 `json:"absolute_magnitude,omitempty"`
+    DiameterKm        float64 `json:"estimated_diameter_km,omitempty"`
+}
+
+// Object is an ETNO with the elements at its own epoch
+type Object struct {
+    Name            string    `json:"name"`
+    Designation     string    `json:"designation"`
+    Type            string    `json:"type"`
+    EpochJD         float64   `json:"epoch_jd,omitempty"` // TDB, the metadata epoch if unset
+    OrbitalElements Elements  `json:"orbital_elements"`
+    Physical        *Physical `json:"physical,omitempty"`
+
+    // 1σ of the elements in AU and degrees, see orbital.CovarianceFromUncertainty
+    Uncertainty map[string]float64 `json:"uncertainty,omitempty"`
+}
+
+// Metadata is the provenance of a catalog
+type Metadata struct {
+    Version     string            `json:"version"`
+    Source      string            `json:"source,omitempty"`
+    URL         string            `json:"url,omitempty"`
+    Selection   string            `json:"selection,omitempty"`
+    RetrievedAt time.Time         `json:"retrieved_at,omitempty"`
+    EpochJD     float64           `json:"epoch_jd"`               // the most common element epoch
+    EpochMinJD  float64           `json:"epoch_jd_min,omitempty"` // the elements span epochs from
+    EpochMaxJD  float64           `json:"epoch_jd_max,omitempty"` // to
+    Count       int               `json:"count,omitempty"`
+    Frame       orbital.Frame     `json:"frame,omitempty"` // of the elements, heliocentric-ecliptic if unset
+    SHA256      string            `json:"sha256,omitempty"` // of the objects, equal for unchanged elements
+    Units       map[string]string `json:"units,omitempty"`
+}
+
+// Snapshot is a catalog as retrieved at one time. Its layout is the one of
+// data/solar_system_objects.json, so both load alike.
+type Snapshot struct {
+    Metadata Metadata `json:"metadata"`
+    ETNOs    []Object `json:"etnos"`
+}
+
+// EpochOf returns the epoch of an object's elements
+func (s *Snapshot) EpochOf(o Object) float64 {
+    if o.EpochJD != 0 {
+        return o.EpochJD
+    }
+    return s.Metadata.EpochJD
+}
+
+// EpochTime converts a Julian date to a time, the about one minute between
+// TDB and UTC is ignored
+func EpochTime(jd float64) time.Time {
+    return time.Unix(0, int64((jd-2440587.5)*86400e9)).UTC()
+}
+
+// Query selects the objects Fetch retrieves
+type Query struct {
+    URL              string  // default DefaultSBDBURL
+    MinSemiMajorAxis float64 // AU
+    MinPerihelion    float64 // AU
+}
+
+// requestedFields are sbdbFields followed by the sigmas
+func requestedFields() []string {
+    fields := append([]string(nil), sbdbFields...)
+    for _, s := range sbdbSigmas {
+        fields = append(fields, s[0])
+    }
+    return fields
+}
+
+// Fetch retrieves the current elements of all objects beyond the limits of q
+// from SBDB
+func Fetch(ctx context.Context, q Query) (*Snapshot, error) {
+    if q.URL == "" {
+        q.URL = DefaultSBDBURL
+    }
+    constraint, _ := json.Marshal(map[string][]string{"AND": {
+        fmt.Sprintf("a|GT|%g", q.MinSemiMajorAxis),
+        fmt.Sprintf("q|GT|%g", q.MinPerihelion),
+    }})
+    params := url.Values{
+        "fields":    {strings.Join(requestedFields(), ",")},
+        "sb-kind":   {"a"},
+        "sb-cdata":  {string(constraint)},
+        "full-prec": {"true"},
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, q.URL+"?"+params.Encode(), nil)
+    if err != nil {
+        return nil, err
+    }
+    client := &http.Client{Timeout: 2 * time.Minute}
+    resp, err := client.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("SBDB query failed: %w", err)
+    }
+    defer resp.Body.Close()
+    body, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return nil, fmt.Errorf("SBDB query failed: %w", err)
+    }
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("SBDB query failed: %s: %s", resp.Status, strings.TrimSpace(string(body[:min(len(body), 512)])))
+    }
+
+    objects, source, err := parseSBDB(body)
+    if err != nil {
+        return nil, err
+    }
+    if len(objects) == 0 {
+        return nil, fmt.Errorf("SBDB returned no objects with a > %g AU and q > %g AU", q.MinSemiMajorAxis, q.MinPerihelion)
+    }
+    snap := &Snapshot{
+        Metadata: Metadata{
+            Source:      source,
+            URL:         q.URL,
+            Selection:   fmt.Sprintf("a > %g AU, q > %g AU", q.MinSemiMajorAxis, q.MinPerihelion),
+            RetrievedAt: time.Now().UTC(),
+            Frame:       orbital.HeliocentricEcliptic,
+            Units: map[string]string{
+                "distance": "AU",
+                "angle":    "degrees",
+                "time":     "days",
+                "epoch":    "JD TDB",
+            },
+        },
+        ETNOs: objects,
+    }
+    snap.summarize()
+    return snap, nil
+}
+
+// parseSBDB reads the objects of a query API answer, its rows hold strings,
+// numbers or null in the order of the fields
+func parseSBDB(body []byte) ([]Object, string, error) {
+    var answer struct {
+        Signature struct {
+            Source  string `json:"source"`
+            Version string `json:"version"`
+        } `json:"signature"`
+        Fields []string         `json:"fields"`
+        Data   [][]interface{} `json:"data"`
+    }
+    if err := json.Unmarshal(body, &answer); err != nil {
+        return nil, "", fmt.Errorf("unexpected SBDB answer: %w", err)
+    }
+    column := make(map[string]int, len(answer.Fields))
+    for i, f := range answer.Fields {
+        column[f] = i
+    }
+    for _, f := range sbdbFields {
+        if _, ok := column[f]; !ok {
+            return nil, "", fmt.Errorf("SBDB answer lacks the field %s", f)
+        }
+    }
+
+    objects := make([]Object, 0, len(answer.Data))
+    for _, row := range answer.Data {
+        text := func(field string) string {
+            i := column[field]
+            if i >= len(row) {
+                return ""
+            }
+            switch v := row[i].(type) {
+            case string:
+                return strings.TrimSpace(v)
+            case float64:
+                return strconv.FormatFloat(v, 'g', -1, 64)
+            default:
+                return ""
+            }
+        }
+        var bad string
+        number := func(field string) float64 {
+            s := text(field)
+            if s == "" {
+                return 0
+            }
+            v, err := strconv.ParseFloat(s, 64)
+            if err != nil {
+                bad = field
+            }
+            return v
+        }
+
+        o := Object{
+            Name:        text("name"),
+            Designation: designation(text("full_name"), text("pdes")),
+            Type:        "extreme_tno",
+            EpochJD:     number("epoch"),
+            OrbitalElements: Elements{
+                SemiMajorAxis:          number("a"),
+                Eccentricity:           number("e"),
+                Inclination:            number("i"),
+                LongitudeAscendingNode: number("om"),
+                ArgumentPerihelion:     number("w"),
+                MeanAnomaly:            number("ma"),
+                Perihelion:             number("q"),
+                Aphelion:               number("ad"),
+                OrbitalPeriod:          number("per"),
+            },
+        }
+        if o.Name == "" {
+            o.Name = o.Designation
+        }
+        for _, s := range sbdbSigmas {
+            if _, ok := column[s[0]]; !ok {
+                continue
+            }
+            if v := number(s[0]); v > 0 {
+                if o.Uncertainty == nil {
+                    o.Uncertainty = make(map[string]float64)
+                }
+                o.Uncertainty[s[1]] = v
+            }
+        }
+        if h, d := number("H"), number("diameter"); h != 0 || d != 0 {
+            o.Physical = &Physical{AbsoluteMagnitude: h, DiameterKm: d}
+        }
+        if bad == "" && (o.Designation == "" || o.OrbitalElements.SemiMajorAxis <= 0) {
+            bad = "designation or semi-major axis"
+        }
+        if bad != "" {
+            return nil, "", fmt.Errorf("SBDB row of %q has an invalid %s", text("full_name"), bad)
+        }
+        objects = append(objects, o)
+    }
+    sort.Slice(objects, func(i, j int) bool { return objects[i].Designation < objects[j].Designation })
+
+    source := answer.Signature.Source
+    if answer.Signature.Version != "" {
+        source += " " + answer.Signature.Version
+    }
+    return objects, source, nil
+}
+
+// designation is the provisional designation of an object, "(2003 VB12)" in
+// the full name "90377 Sedna (2003 VB12)", or the primary one
+func designation(fullName, pdes string) string {
+    if open := strings.LastIndex(fullName, "("); open >= 0 {
+        if end := strings.Index(fullName[open:], ")"); end > 1 {
+            return fullName[open+1 : open+end]
+        }
+    }
+    return pdes
+}
+
+// summarize fills the epochs, count and digest of the metadata
+func (s *Snapshot) summarize() {
+    counts := make(map[float64]int)
+    s.Metadata.EpochMinJD, s.Metadata.EpochMaxJD = 0, 0
+    for _, o := range s.ETNOs {
+        if o.EpochJD == 0 {
+            continue
+        }
+        counts[o.EpochJD]++
+        if s.Metadata.EpochMinJD == 0 || o.EpochJD < s.Metadata.EpochMinJD {
+            s.Metadata.EpochMinJD = o.EpochJD
+        }
+        if o.EpochJD > s.Metadata.EpochMaxJD {
+            s.Metadata.EpochMaxJD = o.EpochJD
+        }
+    }
+    for epoch, n := range counts {
+        if n > counts[s.Metadata.EpochJD] || (n == counts[s.Metadata.EpochJD] && epoch > s.Metadata.EpochJD) {
+            s.Metadata.EpochJD = epoch
+        }
+    }
+    s.Metadata.Count = len(s.ETNOs)
+    data, _ := json.Marshal(s.ETNOs)
+    sum := sha256.Sum256(data)
+    s.Metadata.SHA256 = hex.EncodeToString(sum[:])
+}
+
+// Load reads a snapshot or a data file of the same layout
+func Load(path string) (*Snapshot, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var s Snapshot
+    if err := json.Unmarshal(data, &s); err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    return &s, nil
+}
+
+// Store keeps snapshots in a directory, one file per version. Versions are
+// the retrieval date, YYYY.MM.DD, with .2, .3, … for later ones that day.
+type Store struct {
+    dir string
+}
+
+// NewStore uses the snapshots in dir
+func NewStore(dir string) *Store {
+    return &Store{dir: dir}
+}
+
+// Dir returns the directory of the snapshots
+func (s *Store) Dir() string {
+    return s.dir
+}
+
+// Save assigns the next version to a snapshot and writes it
+func (s *Store) Save(snap *Snapshot) (string, error) {
+    if err := os.MkdirAll(s.dir, 0755); err != nil {
+        return "", err
+    }
+    retrieved := snap.Metadata.RetrievedAt
+    if retrieved.IsZero() {
+        retrieved = time.Now().UTC()
+    }
+    base := retrieved.UTC().Format("2006.01.02")
+    version := base
+    for n := 2; ; n++ {
+        if _, err := os.Stat(s.path(version)); os.IsNotExist(err) {
+            break
+        }
+        version = fmt.Sprintf("%s.%d", base, n)
+    }
+    snap.Metadata.Version = version
+
+    data, err := json.MarshalIndent(snap, "", "  ")
+    if err != nil {
+        return "", err
+    }
+    path := s.path(version)
+    tmp := path + ".tmp"
+    if err := os.WriteFile(tmp, data, 0644); err != nil {
+        return "", err
+    }
+    if err := os.Rename(tmp, path); err != nil {
+        return "", err
+    }
+    return path, nil
+}
+
+// List returns the metadata of all snapshots, oldest first
+func (s *Store) List() ([]Metadata, error) {
+    paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+    if err != nil {
+        return nil, err
+    }
+    var list []Metadata
+    for _, path := range paths {
+        snap, err := Load(path)
+        if err != nil {
+            return nil, err
+        }
+        list = append(list, snap.Metadata)
+    }
+    sort.Slice(list, func(i, j int) bool {
+        if !list[i].RetrievedAt.Equal(list[j].RetrievedAt) {
+            return list[i].RetrievedAt.Before(list[j].RetrievedAt)
+        }
+        return list[i].Version < list[j].Version
+    })
+    return list, nil
+}
+
+// Path returns the file of a version, Latest for the most recent one
+func (s *Store) Path(version string) (string, error) {
+    if version == Latest {
+        list, err := s.List()
+        if err != nil {
+            return "", err
+        }
+        if len(list) == 0 {
+            return "", fmt.Errorf("no catalog snapshots in %s, run 'data update'", s.dir)
+        }
+        version = list[len(list)-1].Version
+    }
+    path := s.path(version)
+    if _, err := os.Stat(path); err != nil {
+        return "", fmt.Errorf("catalog version %s not found in %s (see 'data list')", version, s.dir)
+    }
+    return path, nil
+}
+
+func (s *Store) path(version string) string {
+    return filepath.Join(s.dir, version+".json")
+}
@@ -0,0 +1,184 @@
+package nbody
+
+import (
+    "bufio"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+)
+
+// RenderOptions selects what a snapshot stream export contains
+type RenderOptions struct {
+    Bodies       []string // body IDs or ID prefixes to keep, empty = all
+    Every        int      // keep every n-th snapshot, 0 or 1 = all
+    Heliocentric bool     // positions and velocities relative to the heaviest body
+}
+
+// filter applies the options to a snapshot, it returns false for snapshots
+// that are skipped
+func (o RenderOptions) filter(index int, snap Snapshot) (Snapshot, bool) {
+    if o.Every > 1 && index%o.Every != 0 {
+        return snap, false
+    }
+    var ref Body
+    if o.Heliocentric && len(snap.Bodies) > 0 {
+        ref = snap.Bodies[0]
+        for _, b := range snap.Bodies {
+            if b.Mass > ref.Mass {
+                ref = b
+            }
+        }
+    }
+    out := Snapshot{Time: snap.Time, Bodies: make([]Body, 0, len(snap.Bodies))}
+    for _, b := range snap.Bodies {
+        if !o.keep(b.ID) {
+            continue
+        }
+        b.Position = b.Position.Sub(ref.Position)
+        b.Velocity = b.Velocity.Sub(ref.Velocity)
+        out.Bodies = append(out.Bodies, b)
+    }
+    return out, true
+}
+
+func (o RenderOptions) keep(id string) bool {
+    if len(o.Bodies) == 0 {
+        return true
+    }
+    for _, want := range o.Bodies {
+        if id == want || strings.HasPrefix(id, want) {
+            return true
+        }
+    }
+    return false
+}
+
+// RenderCSV writes the stream as one row per body and snapshot, the long
+// format plotting tools like pandas, gnuplot or TOPCAT read directly. It
+// returns the number of snapshots written.
+func RenderCSV(r *SnapshotReader, w io.Writer, opts RenderOptions) (int, error) {
+    cw := csv.NewWriter(w)
+    if err := cw.Write([]string{"time_days", "time_years", "id", "mass", "x", "y", "z", "vx", "vy", "vz"}); err != nil {
+        return 0, err
+    }
+    f := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+
+    written := 0
+    for i := 0; ; i++ {
+        snap, err := r.Next()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return written, err
+        }
+        snap, ok := opts.filter(i, snap)
+        if !ok {
+            continue
+        }
+        for _, b := range snap.Bodies {
+            if err := cw.Write([]string{
+                f(snap.Time), f(snap.Time / 365.25), b.ID, f(b.Mass),
+                f(b.Position.X), f(b.Position.Y), f(b.Position.Z),
+                f(b.Velocity.X), f(b.Velocity.Y), f(b.Velocity.Z),
+            }); err != nil {
+                return written, err
+            }
+        }
+        written++
+    }
+    cw.Flush()
+    return written, cw.Error()
+}
+
+// vtkSeries is the ParaView .vtk.series index of a time series
+type vtkSeries struct {
+    Version string          `json:"file-series-version"`
+    Files   []vtkSeriesFile `json:"files"`
+}
+
+type vtkSeriesFile struct {
+    Name string  `json:"name"`
+    Time float64 `json:"time"`
+}
+
+// RenderVTK writes every snapshot as a legacy ASCII VTK poly data file into
+// dir, plus a snapshots.vtk.series index so ParaView opens them as one time
+// series (time in years). Point data carries mass, velocity and the body
+// index, which stays stable across the series. It returns the number of
+// files written.
+func RenderVTK(r *SnapshotReader, dir string, opts RenderOptions) (int, error) {
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return 0, err
+    }
+    series := vtkSeries{Version: "1.0"}
+    for i := 0; ; i++ {
+        snap, err := r.Next()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return len(series.Files), err
+        }
+        snap, ok := opts.filter(i, snap)
+        if !ok {
+            continue
+        }
+        name := fmt.Sprintf("snapshot_%05d.vtk", len(series.Files))
+        if err := writeVTK(filepath.Join(dir, name), snap); err != nil {
+            return len(series.Files), err
+        }
+        series.Files = append(series.Files, vtkSeriesFile{Name: name, Time: snap.Time / 365.25})
+    }
+
+    data, err := json.MarshalIndent(series, "", "  ")
+    if err != nil {
+        return len(series.Files), err
+    }
+    return len(series.Files), os.WriteFile(filepath.Join(dir, "snapshots.vtk.series"), data, 0644)
+}
+
+func writeVTK(path string, snap Snapshot) error {
+    f, err := os.Create(path)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+    w := bufio.NewWriter(f)
+
+    n := len(snap.Bodies)
+    fmt.Fprintf(w, "# vtk DataFile Version 3.0\n")
+    fmt.Fprintf(w, "N-body snapshot t=%.6g days\n", snap.Time)
+    fmt.Fprintf(w, "ASCII\nDATASET POLYDATA\n")
+    fmt.Fprintf(w, "POINTS %d double\n", n)
+    for _, b := range snap.Bodies {
+        fmt.Fprintf(w, "%.10g %.10g %.10g\n", b.Position.X, b.Position.Y, b.Position.Z)
+    }
+    fmt.Fprintf(w, "VERTICES %d %d\n", n, 2*n)
+    for i := 0; i < n; i++ {
+        fmt.Fprintf(w, "1 %d\n", i)
+    }
+    fmt.Fprintf(w, "POINT_DATA %d\n", n)
+    fmt.Fprintf(w, "SCALARS mass double 1\nLOOKUP_TABLE default\n")
+    for _, b := range snap.Bodies {
+        fmt.Fprintf(w, "%.10g\n", b.Mass)
+    }
+    fmt.Fprintf(w, "SCALARS body_index int 1\nLOOKUP_TABLE default\n")
+    for i := range snap.Bodies {
+        fmt.Fprintf(w, "%d\n", i)
+    }
+    fmt.Fprintf(w, "VECTORS velocity double\n")
+    for _, b := range snap.Bodies {
+        fmt.Fprintf(w, "%.10g %.10g %.10g\n", b.Velocity.X, b.Velocity.Y, b.Velocity.Z)
+    }
+
+    if err := w.Flush(); err != nil {
+        return err
+    }
+    return f.Close()
+}
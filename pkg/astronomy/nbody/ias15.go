@@ -0,0 +1,310 @@
+package nbody
+
+import (
+    "math"
+
+    astromath "github.com/oxygene76/medasdigital-client/pkg/astronomy/math"
+)
+
+// radauH are the spacings of the 8-point Gauss-Radau quadrature on [0, 1]
+var radauH = [8]float64{
+    0,
+    0.0562625605369221464656521910318,
+    0.180240691736892364987579942780,
+    0.352624717113169637373907769648,
+    0.547153626330555383001448554766,
+    0.734210177215410531523210605558,
+    0.885320946839095768090359771030,
+    0.977520613561287501891174488626,
+}
+
+// radauC[k][m] is the coefficient of h^m in h·(h-h1)···(h-hk), it turns the
+// divided differences g into the power series coefficients b
+var radauC = func() (c [7][8]float64) {
+    poly := []float64{0, 1} // h
+    for k := 0; k < 7; k++ {
+        if k > 0 {
+            next := make([]float64, len(poly)+1)
+            for m, p := range poly {
+                next[m+1] += p
+                next[m] -= p * radauH[k]
+            }
+            poly = next
+        }
+        copy(c[k][:], poly)
+    }
+    return
+}()
+
+const (
+    ias15Epsilon       = 1e-9  // default relative error per step
+    ias15SafetyFactor  = 0.25  // reject steps that want to shrink by more, grow by at most 1/factor
+    ias15MaxIterations = 12    // predictor-corrector iterations per step
+    ias15Converged     = 1e-16 // predictor-corrector convergence of b6 relative to the accelerations
+)
+
+// IAS15 is a 15th order Gauss-Radau integrator with adaptive internal step
+// size after Rein & Spiegel (2015). The error per step stays near machine
+// precision, which makes it suited for close encounters and as reference.
+type IAS15 struct {
+    Epsilon float64 // relative error per step, 0 = 1e-9
+    MinDt   float64 // smallest internal step in days, 0 = no limit
+
+    dt     float64 // next internal step
+    dtLast float64 // step the coefficients b belong to
+    b      [7][]float64
+}
+
+// NewIAS15 creates an IAS15 integrator with the default tolerance
+func NewIAS15() *IAS15 {
+    return &IAS15{}
+}
+
+func (*IAS15) Name() string { return "ias15" }
+
+// Step advances the system by exactly dt, with as many internal steps as the
+// error control needs
+func (ias *IAS15) Step(s *System, dt float64) {
+    remaining := dt
+    for remaining > 0 {
+        if ias.dt <= 0 {
+            ias.dt = remaining
+        }
+        h := math.Min(ias.dt, remaining)
+        done, next := ias.step(s, h)
+        remaining -= done
+        // A step cut short to hit dt says little about the step size
+        if done >= ias.dt || next < ias.dt {
+            ias.dt = next
+        }
+        if remaining < 1e-12*dt {
+            break
+        }
+    }
+}
+
+// step tries one internal step of size dt, shrinking it until the error is
+// acceptable. It returns the step taken and the proposed next step.
+func (ias *IAS15) step(s *System, dt float64) (float64, float64) {
+    eps := ias.Epsilon
+    if eps <= 0 {
+        eps = ias15Epsilon
+    }
+    n := 3 * len(s.Bodies)
+    x0, v0 := flattenState(s)
+    a0 := flattenVectors(s.calculateAccelerations())
+
+    for {
+        g := ias.initialG(n, dt)
+        var b [7][]float64
+        for k := range b {
+            b[k] = make([]float64, n)
+        }
+        computeB(&b, &g)
+
+        var at [8][]float64
+        at[0] = a0
+        x := make([]float64, n) // displacement from x0
+        prevB6 := make([]float64, n)
+        for iter := 0; iter < ias15MaxIterations; iter++ {
+            copy(prevB6, b[6])
+            for sub := 1; sub < 8; sub++ {
+                h := radauH[sub]
+                for i := 0; i < n; i++ {
+                    sum := a0[i] / 2
+                    hp := h
+                    for k := 0; k < 7; k++ {
+                        sum += b[k][i] * hp / float64((k+2)*(k+3))
+                        hp *= h
+                    }
+                    x[i] = dt*h*v0[i] + dt*dt*h*h*sum
+                }
+                at[sub] = accelerationsAt(s, x0, x)
+
+                // Divided difference of a_0..a_sub is the new g[sub-1]
+                for i := 0; i < n; i++ {
+                    var d [8]float64
+                    for j := 0; j <= sub; j++ {
+                        d[j] = at[j][i]
+                    }
+                    for level := 1; level <= sub; level++ {
+                        for j := sub; j >= level; j-- {
+                            d[j] = (d[j] - d[j-1]) / (radauH[j] - radauH[j-level])
+                        }
+                    }
+                    g[sub-1][i] = d[sub]
+                }
+                computeB(&b, &g)
+            }
+
+            if maxAbsDiff(b[6], prevB6)/maxAbs(at[7]) < ias15Converged {
+                break
+            }
+        }
+
+        // Error estimate from the highest order coefficient
+        dtNew := dt / ias15SafetyFactor
+        if err := maxAbs(b[6]) / maxAbs(at[7]); err > 0 && !math.IsNaN(err) {
+            dtNew = dt * math.Pow(eps/err, 1.0/7.0)
+        }
+        if dtNew < ias15SafetyFactor*dt && (ias.MinDt == 0 || dtNew > ias.MinDt) {
+            dt = dtNew // reject, retry smaller
+            ias.b = [7][]float64{}
+            continue
+        }
+        if dtNew > dt/ias15SafetyFactor {
+            dtNew = dt / ias15SafetyFactor
+        }
+        if ias.MinDt > 0 && dtNew < ias.MinDt {
+            dtNew = ias.MinDt
+        }
+
+        // Accept: evaluate the series at h = 1
+        for i := 0; i < n; i++ {
+            sx, sv := a0[i]/2, a0[i]
+            for k := 0; k < 7; k++ {
+                sx += b[k][i] / float64((k+2)*(k+3))
+                sv += b[k][i] / float64(k+2)
+            }
+            x[i] = x0[i] + dt*v0[i] + dt*dt*sx
+            v0[i] += dt * sv
+        }
+        restoreState(s, x, v0)
+        s.Time += dt
+        ias.b, ias.dtLast = b, dt
+        return dt, dtNew
+    }
+}
+
+// initialG extrapolates the acceleration polynomial of the last step into the
+// next one, a good start for the predictor-corrector
+func (ias *IAS15) initialG(n int, dt float64) [7][]float64 {
+    var g [7][]float64
+    for k := range g {
+        g[k] = make([]float64, n)
+    }
+    if ias.b[0] == nil || len(ias.b[0]) != n || ias.dtLast == 0 {
+        return g
+    }
+
+    // A(τ) = Σ c_m τ^m with c_0 = a0 and c_m = b_{m-1}; at τ = 1 + qτ' the
+    // coefficient of τ'^j is q^j Σ_{m≥j} C(m,j) c_m
+    q := dt / ias.dtLast
+    var bNew [7][]float64
+    for j := 1; j <= 7; j++ {
+        bNew[j-1] = make([]float64, n)
+        qj := math.Pow(q, float64(j))
+        for m := j; m <= 7; m++ {
+            binom := binomial(m, j)
+            for i := 0; i < n; i++ {
+                bNew[j-1][i] += qj * binom * ias.b[m-1][i]
+            }
+        }
+    }
+
+    // Back-substitute b = Σ g_k C[k] for g, C[k][k+1] = 1
+    for m := 7; m >= 1; m-- {
+        for i := 0; i < n; i++ {
+            v := bNew[m-1][i]
+            for k := m; k < 7; k++ {
+                v -= g[k][i] * radauC[k][m]
+            }
+            g[m-1][i] = v
+        }
+    }
+    return g
+}
+
+func binomial(n, k int) float64 {
+    r := 1.0
+    for i := 1; i <= k; i++ {
+        r = r * float64(n-k+i) / float64(i)
+    }
+    return r
+}
+
+// computeB converts the divided differences into power series coefficients
+func computeB(b, g *[7][]float64) {
+    for m := 1; m <= 7; m++ {
+        out := b[m-1]
+        for i := range out {
+            v := 0.0
+            for k := m - 1; k < 7; k++ {
+                v += g[k][i] * radauC[k][m]
+            }
+            out[i] = v
+        }
+    }
+}
+
+func flattenState(s *System) ([]float64, []float64) {
+    x := make([]float64, 3*len(s.Bodies))
+    v := make([]float64, 3*len(s.Bodies))
+    for i, b := range s.Bodies {
+        x[3*i], x[3*i+1], x[3*i+2] = b.Position.X, b.Position.Y, b.Position.Z
+        v[3*i], v[3*i+1], v[3*i+2] = b.Velocity.X, b.Velocity.Y, b.Velocity.Z
+    }
+    return x, v
+}
+
+func flattenVectors(vs []astromath.Vector3) []float64 {
+    out := make([]float64, 3*len(vs))
+    for i, v := range vs {
+        out[3*i], out[3*i+1], out[3*i+2] = v.X, v.Y, v.Z
+    }
+    return out
+}
+
+func restoreState(s *System, x, v []float64) {
+    for i := range s.Bodies {
+        s.Bodies[i].Position = astromath.Vector3{X: x[3*i], Y: x[3*i+1], Z: x[3*i+2]}
+        s.Bodies[i].Velocity = astromath.Vector3{X: v[3*i], Y: v[3*i+1], Z: v[3*i+2]}
+    }
+}
+
+// accelerationsAt evaluates the accelerations at positions x0 + dx like
+// calculateAccelerations. Separations are taken as (x0_j - x0_i) + (dx_j - dx_i)
+// so that tiny substeps of a close pair are not lost to the rounding of
+// positions far from the origin.
+func accelerationsAt(s *System, x0, dx []float64) []float64 {
+    acc := make([]float64, len(x0))
+    for i := range s.Bodies {
+        for j, bj := range s.Bodies {
+            if i == j || bj.Mass <= 0 {
+                continue
+            }
+            rx := (x0[3*j] - x0[3*i]) + (dx[3*j] - dx[3*i])
+            ry := (x0[3*j+1] - x0[3*i+1]) + (dx[3*j+1] - dx[3*i+1])
+            rz := (x0[3*j+2] - x0[3*i+2]) + (dx[3*j+2] - dx[3*i+2])
+            r := math.Sqrt(rx*rx + ry*ry + rz*rz)
+            if r < 1e-10 {
+                continue
+            }
+            f := s.G * bj.Mass / (r * r * r)
+            acc[3*i] += f * rx
+            acc[3*i+1] += f * ry
+            acc[3*i+2] += f * rz
+        }
+    }
+    return acc
+}
+
+func maxAbs(x []float64) float64 {
+    m := 0.0
+    for _, v := range x {
+        if a := math.Abs(v); a > m {
+            m = a
+        }
+    }
+    return m
+}
+
+func maxAbsDiff(x, y []float64) float64 {
+    m := 0.0
+    for i := range x {
+        if a := math.Abs(x[i] - y[i]); a > m {
+            m = a
+        }
+    }
+    return m
+}
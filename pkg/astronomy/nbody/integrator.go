@@ -9,15 +9,15 @@ import (
 
 // Body represents a celestial body in the N-body system
 type Body struct {
-    ID       string              // Identifier
-    Mass     float64             // Mass in solar masses
-    Position astromath.Vector3   // Position in AU
-    Velocity astromath.Vector3   // Velocity in AU/day
+    ID       string              `json:"id"`       // Identifier
+    Mass     float64             `json:"mass"`     // Mass in solar masses
+    Position astromath.Vector3   `json:"position"` // Position in AU
+    Velocity astromath.Vector3   `json:"velocity"` // Velocity in AU/day
 }
 
 type Snapshot struct {
-    Time   float64
-    Bodies []Body
+    Time   float64 `json:"time_days"`
+    Bodies []Body  `json:"bodies"`
 }
 
 // System represents the N-body system
@@ -26,6 +26,55 @@ type System struct {
     Time   float64 // Current time in Julian days
     G      float64 // Gravitational constant in AU³/(M☉·day²)
     Eps2   float64 // Softening^2 in AU^2
+
+    Integrator Integrator   // nil = leapfrog
+    Energy     EnergyReport // filled by IntegrateWithMonitorAndSink
+}
+
+// Integrator advances a system by exactly dt days. Implementations may keep
+// state between steps (coordinate order, internal step size), so every system
+// needs its own instance.
+type Integrator interface {
+    Name() string
+    Step(s *System, dt float64)
+}
+
+// Leapfrog is the default kick-drift-kick integrator
+type Leapfrog struct{}
+
+func (Leapfrog) Name() string                { return "leapfrog" }
+func (Leapfrog) Step(s *System, dt float64) { s.LeapfrogStep(dt) }
+
+// EnergyReport summarizes how well an integration conserved the total energy
+// of the massive bodies, drifts are relative to the initial energy
+type EnergyReport struct {
+    Integrator string  `json:"integrator"`
+    Steps      int     `json:"steps"`
+    Initial    float64 `json:"initial"`
+    Final      float64 `json:"final"`
+    FinalDrift float64 `json:"final_drift"`
+    MaxDrift   float64 `json:"max_drift"`
+}
+
+func (s *System) step(dt float64) {
+    if s.Integrator == nil {
+        s.LeapfrogStep(dt)
+        return
+    }
+    s.Integrator.Step(s, dt)
+}
+
+func (s *System) integratorName() string {
+    if s.Integrator == nil {
+        return Leapfrog{}.Name()
+    }
+    return s.Integrator.Name()
+}
+
+// Accelerations returns the gravitational accelerations of all bodies at the
+// current positions, test particles only feel the massive bodies
+func (s *System) Accelerations() []astromath.Vector3 {
+    return s.calculateAccelerations()
 }
 
 // NewSystem creates a new N-body system
@@ -44,6 +93,7 @@ func (s *System) Copy() *System {
         Bodies: make([]Body, len(s.Bodies)),
         Time:   s.Time,
         G:      s.G,
+        Eps2:   s.Eps2,
     }
     copy(newSystem.Bodies, s.Bodies)
     return newSystem
@@ -63,7 +113,7 @@ func (s *System) Integrate(duration, timestep float64) []Snapshot {
     E0 := s.GetTotalEnergy()
 
 for i := 0; i < steps; i++ {
-    s.LeapfrogStep(timestep)
+    s.step(timestep)
 
     // === Energieüberwachung alle 1000 Schritte ===
     if (i+1)%1000 == 0 {
@@ -388,7 +438,7 @@ func (s *System) IntegrateWithMonitor(durationDays, timestepDays, monitorEveryDa
     nextMonitorTime := s.Time + monitorEveryDays
 
     for i := 0; i < steps; i++ {
-        s.LeapfrogStep(timestepDays)
+        s.step(timestepDays)
 
         // Monitor: alle monitorEveryDays
         if monitor != nil && s.Time >= nextMonitorTime {
@@ -436,6 +486,7 @@ func (s *System) IntegrateWithMonitorAndSink(
     }
 
     if sink != nil {
+        defer sink.Close() // auch bei Fehlern mitten im Lauf
         if err := sink.OnStart(steps, snapEvery); err != nil { return err }
         // Initialzustand auf Disk?
         if snapEvery > 0 {
@@ -451,15 +502,22 @@ func (s *System) IntegrateWithMonitorAndSink(
     E0 := s.GetTotalEnergy()
     startTime := s.Time
     nextMonitorTime := s.Time + monitorEveryDays
+    s.Energy = EnergyReport{Integrator: s.integratorName(), Initial: E0, Final: E0}
 
     for i := 0; i < steps; i++ {
-        s.LeapfrogStep(timestepDays)
+        s.step(timestepDays)
+
+        // Energieerhaltung nach jedem Schritt (nur massive Körper, billig)
+        E := s.GetTotalEnergy()
+        drift := 0.0
+        if E0 != 0 { drift = math.Abs((E - E0) / E0) }
+        s.Energy.Steps = i + 1
+        s.Energy.Final = E
+        s.Energy.FinalDrift = drift
+        if drift > s.Energy.MaxDrift || math.IsNaN(drift) { s.Energy.MaxDrift = drift }
 
         // Monitor
         if monitor != nil && monitorEveryDays > 0 && s.Time >= nextMonitorTime {
-            E := s.GetTotalEnergy()
-            drift := 0.0
-            if E0 != 0 { drift = math.Abs((E - E0) / E0) }
             monitor(i+1, s.Time-startTime, drift, s)
             nextMonitorTime += monitorEveryDays
         }
@@ -471,8 +529,12 @@ func (s *System) IntegrateWithMonitorAndSink(
     }
 
     if sink != nil {
+        // Endzustand immer mitschreiben, auch wenn er nicht auf die Kadenz fällt
+        if snapEvery > 0 && steps%snapEvery != 0 {
+            if err := sink.OnSnapshot(s.Time, s.copyBodies()); err != nil { return err }
+        }
         if err := sink.OnEnd(s.Time - startTime); err != nil { return err }
-        _ = sink.Close()
+        if err := sink.Close(); err != nil { return err }
     }
 
     if lastSnap != nil {
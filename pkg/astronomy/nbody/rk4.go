@@ -0,0 +1,49 @@
+package nbody
+
+import (
+    astromath "github.com/oxygene76/medasdigital-client/pkg/astronomy/math"
+)
+
+// RK4 is the classical fourth order Runge-Kutta integrator. It is not
+// symplectic, the energy error grows linearly with time.
+type RK4 struct{}
+
+func (RK4) Name() string { return "rk4" }
+
+// Step advances the system by dt with four force evaluations
+func (RK4) Step(s *System, dt float64) {
+    n := len(s.Bodies)
+    x0 := make([]astromath.Vector3, n)
+    v0 := make([]astromath.Vector3, n)
+    for i, b := range s.Bodies {
+        x0[i], v0[i] = b.Position, b.Velocity
+    }
+
+    // derivative at x0 + h·dx, v0 + h·dv
+    eval := func(h float64, dx, dv []astromath.Vector3) ([]astromath.Vector3, []astromath.Vector3) {
+        vel := make([]astromath.Vector3, n)
+        for i := range s.Bodies {
+            if dx != nil {
+                s.Bodies[i].Position = x0[i].Add(dx[i].Scale(h))
+                vel[i] = v0[i].Add(dv[i].Scale(h))
+            } else {
+                s.Bodies[i].Position = x0[i]
+                vel[i] = v0[i]
+            }
+        }
+        return vel, s.calculateAccelerations()
+    }
+
+    k1x, k1v := eval(0, nil, nil)
+    k2x, k2v := eval(dt/2, k1x, k1v)
+    k3x, k3v := eval(dt/2, k2x, k2v)
+    k4x, k4v := eval(dt, k3x, k3v)
+
+    for i := range s.Bodies {
+        s.Bodies[i].Position = x0[i].Add(
+            k1x[i].Add(k2x[i].Scale(2)).Add(k3x[i].Scale(2)).Add(k4x[i]).Scale(dt / 6))
+        s.Bodies[i].Velocity = v0[i].Add(
+            k1v[i].Add(k2v[i].Scale(2)).Add(k3v[i].Scale(2)).Add(k4v[i]).Scale(dt / 6))
+    }
+    s.Time += dt
+}
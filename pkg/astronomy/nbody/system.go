@@ -2,8 +2,12 @@ package nbody
 
 import (
     "bufio"
+    "compress/gzip"
     "encoding/json"
+    "fmt"
+    "io"
     "os"
+    "strings"
 )
 
 // SnapshotSink: wohin Snapshots geschrieben werden
@@ -14,37 +18,93 @@ type SnapshotSink interface {
     Close() error
 }
 
-// JSONL writer auf Disk
+// JSONL writer auf Disk, gzip-komprimiert wenn der Pfad auf .gz endet
 type JSONLSnapshotWriter struct {
     f     *os.File
+    gz    *gzip.Writer
     bw    *bufio.Writer
-}
-
-type jsonlSnapshot struct {
-    TimeDays float64 `json:"time_days"`
-    Bodies   []Body  `json:"bodies"`
+    count int
 }
 
 func NewJSONLSnapshotWriter(path string) (*JSONLSnapshotWriter, error) {
     f, err := os.Create(path)
     if err != nil { return nil, err }
-    return &JSONLSnapshotWriter{f: f, bw: bufio.NewWriter(f)}, nil
+    w := &JSONLSnapshotWriter{f: f}
+    if strings.HasSuffix(path, ".gz") {
+        w.gz = gzip.NewWriter(f)
+        w.bw = bufio.NewWriter(w.gz)
+    } else {
+        w.bw = bufio.NewWriter(f)
+    }
+    return w, nil
 }
 
+// Count liefert die Zahl der geschriebenen Snapshots
+func (w *JSONLSnapshotWriter) Count() int { return w.count }
+
 func (w *JSONLSnapshotWriter) OnStart(totalSteps int, snapEvery int) error { return nil }
 
 func (w *JSONLSnapshotWriter) OnSnapshot(tDays float64, bodies []Body) error {
-    rec := jsonlSnapshot{TimeDays: tDays, Bodies: bodies}
+    rec := Snapshot{Time: tDays, Bodies: bodies}
     b, err := json.Marshal(rec)
     if err != nil { return err }
     if _, err := w.bw.Write(b); err != nil { return err }
     if err := w.bw.WriteByte('\n'); err != nil { return err }
+    w.count++
     return nil
 }
 
 func (w *JSONLSnapshotWriter) OnEnd(finalTDays float64) error { return w.bw.Flush() }
+
+// Close schreibt alle Puffer und schließt die Datei, mehrfacher Aufruf ist harmlos
 func (w *JSONLSnapshotWriter) Close() error {
-    if w.bw != nil { _ = w.bw.Flush() }
-    if w.f != nil { return w.f.Close() }
-    return nil
+    if w.f == nil { return nil }
+    err := w.bw.Flush()
+    if w.gz != nil {
+        if cerr := w.gz.Close(); err == nil { err = cerr }
+    }
+    if cerr := w.f.Close(); err == nil { err = cerr }
+    w.f = nil
+    return err
+}
+
+// SnapshotReader liest einen JSONL-Snapshot-Stream, gzip wird am Header erkannt
+type SnapshotReader struct {
+    f    *os.File
+    gz   *gzip.Reader
+    dec  *json.Decoder
+    line int
+}
+
+func OpenSnapshots(path string) (*SnapshotReader, error) {
+    f, err := os.Open(path)
+    if err != nil { return nil, err }
+    br := bufio.NewReader(f)
+    r := &SnapshotReader{f: f}
+    var src io.Reader = br
+    if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+        if r.gz, err = gzip.NewReader(br); err != nil {
+            f.Close()
+            return nil, fmt.Errorf("%s: %w", path, err)
+        }
+        src = r.gz
+    }
+    r.dec = json.NewDecoder(src)
+    return r, nil
+}
+
+// Next liefert den nächsten Snapshot oder io.EOF am Ende des Streams
+func (r *SnapshotReader) Next() (Snapshot, error) {
+    var snap Snapshot
+    if err := r.dec.Decode(&snap); err != nil {
+        if err == io.EOF { return snap, err }
+        return snap, fmt.Errorf("snapshot %d: %w", r.line+1, err)
+    }
+    r.line++
+    return snap, nil
+}
+
+func (r *SnapshotReader) Close() error {
+    if r.gz != nil { r.gz.Close() }
+    return r.f.Close()
 }
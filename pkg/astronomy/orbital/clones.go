@@ -0,0 +1,203 @@
+package orbital
+
+import (
+    "fmt"
+    "math"
+    "math/rand"
+    "sort"
+    "strings"
+)
+
+// Element names of an uncertainty map, as in the JSON of the analysis types.
+// Values are 1σ in AU and degrees, covariances are kept under
+// "cov:<name>:<name>".
+const (
+    UncertaintySemiMajorAxis = "semi_major_axis"
+    UncertaintyEccentricity  = "eccentricity"
+    UncertaintyInclination   = "inclination"
+    UncertaintyNode          = "longitude_ascending"
+    UncertaintyPerihelion    = "argument_periapsis"
+    UncertaintyMeanAnomaly   = "mean_anomaly"
+)
+
+// uncertaintyElements is the order of the rows of a Covariance
+var uncertaintyElements = [6]string{UncertaintySemiMajorAxis, UncertaintyEccentricity, UncertaintyInclination,
+    UncertaintyNode, UncertaintyPerihelion, UncertaintyMeanAnomaly}
+
+// Covariance of a, e, i, Ω, ω and M in AU and degrees
+type Covariance [6][6]float64
+
+// CovarianceFromUncertainty builds the covariance of an uncertainty map,
+// elements it leaves out are exact
+func CovarianceFromUncertainty(u map[string]float64) (Covariance, error) {
+    var c Covariance
+    index := func(name string) (int, bool) {
+        for i, n := range uncertaintyElements {
+            if n == name {
+                return i, true
+            }
+        }
+        return 0, false
+    }
+    for key, v := range u {
+        if strings.HasPrefix(key, "cov:") {
+            names := strings.Split(strings.TrimPrefix(key, "cov:"), ":")
+            if len(names) != 2 {
+                return c, fmt.Errorf("covariance %q must name two elements", key)
+            }
+            i, ok1 := index(names[0])
+            j, ok2 := index(names[1])
+            if !ok1 || !ok2 {
+                return c, fmt.Errorf("covariance %q names an unknown element", key)
+            }
+            c[i][j], c[j][i] = v, v
+            continue
+        }
+        i, ok := index(key)
+        if !ok {
+            return c, fmt.Errorf("uncertainty of unknown element %q", key)
+        }
+        if v < 0 {
+            return c, fmt.Errorf("uncertainty of %s is negative", key)
+        }
+        c[i][i] = v * v
+    }
+    return c, nil
+}
+
+// IsZero reports whether the elements are exact
+func (c Covariance) IsZero() bool {
+    return c == Covariance{}
+}
+
+// cholesky returns the lower triangular L with L·Lᵀ = c. Exact elements
+// give zero rows.
+func (c Covariance) cholesky() ([6][6]float64, error) {
+    var l [6][6]float64
+    for i := 0; i < 6; i++ {
+        for j := 0; j <= i; j++ {
+            sum := c[i][j]
+            for k := 0; k < j; k++ {
+                sum -= l[i][k] * l[j][k]
+            }
+            if i == j {
+                if sum < -1e-12*math.Max(1, c[i][i]) {
+                    return l, fmt.Errorf("covariance is not positive semi-definite")
+                }
+                l[i][i] = math.Sqrt(math.Max(sum, 0))
+            } else if l[j][j] > 0 {
+                l[i][j] = sum / l[j][j]
+            }
+        }
+    }
+    return l, nil
+}
+
+// Clones draws n sets of elements from the covariance c around o. Draws
+// with e outside [0, 1) or a ≤ 0 are repeated, so the clones stay bound.
+func (o OrbitalElements) Clones(c Covariance, n int, rng *rand.Rand) ([]OrbitalElements, error) {
+    o.EnsureRadians()
+    clones := make([]OrbitalElements, n)
+    if c.IsZero() {
+        for k := range clones {
+            clones[k] = o
+        }
+        return clones, nil
+    }
+    l, err := c.cholesky()
+    if err != nil {
+        return nil, err
+    }
+
+    deg := math.Pi / 180
+    for k := range clones {
+        for attempt := 0; ; attempt++ {
+            if attempt == 1000 {
+                return nil, fmt.Errorf("no bound clone in 1000 draws, the uncertainties are too large")
+            }
+            var z, d [6]float64
+            for i := range z {
+                z[i] = rng.NormFloat64()
+            }
+            for i := 0; i < 6; i++ {
+                for j := 0; j <= i; j++ {
+                    d[i] += l[i][j] * z[j]
+                }
+            }
+            clone := o
+            clone.SemiMajorAxis += d[0]
+            clone.Eccentricity += d[1]
+            clone.Inclination = math.Abs(clone.Inclination + d[2]*deg)
+            clone.LongitudeAscendingNode = wrapAngle(clone.LongitudeAscendingNode + d[3]*deg)
+            clone.ArgumentPerihelion = wrapAngle(clone.ArgumentPerihelion + d[4]*deg)
+            clone.MeanAnomaly = wrapAngle(clone.MeanAnomaly + d[5]*deg)
+            if clone.SemiMajorAxis > 0 && clone.Eccentricity >= 0 && clone.Eccentricity < 1 {
+                clones[k] = clone
+                break
+            }
+        }
+    }
+    return clones, nil
+}
+
+// CloneSets draws n clones of every object. Set k holds clone k of each
+// object, so it can replace the nominal elements in an analysis.
+func CloneSets(elements []OrbitalElements, covs []Covariance, n int, seed int64) ([][]OrbitalElements, error) {
+    if len(covs) != len(elements) {
+        return nil, fmt.Errorf("%d covariances for %d objects", len(covs), len(elements))
+    }
+    rng := rand.New(rand.NewSource(seed))
+    sets := make([][]OrbitalElements, n)
+    for k := range sets {
+        sets[k] = make([]OrbitalElements, len(elements))
+    }
+    for i, o := range elements {
+        clones, err := o.Clones(covs[i], n, rng)
+        if err != nil {
+            return nil, fmt.Errorf("object %d: %w", i, err)
+        }
+        for k, c := range clones {
+            sets[k][i] = c
+        }
+    }
+    return sets, nil
+}
+
+// Interval summarizes a quantity over the clones by its median and the
+// central 68% and 95% of the values
+type Interval struct {
+    Median  float64 `json:"median"`
+    Lower68 float64 `json:"lower_68"`
+    Upper68 float64 `json:"upper_68"`
+    Lower95 float64 `json:"lower_95"`
+    Upper95 float64 `json:"upper_95"`
+}
+
+// NewInterval returns the interval of values
+func NewInterval(values []float64) Interval {
+    if len(values) == 0 {
+        return Interval{}
+    }
+    sorted := append([]float64(nil), values...)
+    sort.Float64s(sorted)
+    q := func(p float64) float64 {
+        pos := p * float64(len(sorted)-1)
+        lo := int(math.Floor(pos))
+        if lo+1 >= len(sorted) {
+            return sorted[len(sorted)-1]
+        }
+        return sorted[lo] + (pos-float64(lo))*(sorted[lo+1]-sorted[lo])
+    }
+    return Interval{
+        Median:  q(0.5),
+        Lower68: q(0.16),
+        Upper68: q(0.84),
+        Lower95: q(0.025),
+        Upper95: q(0.975),
+    }
+}
+
+// String formats the median with the 68% and 95% intervals
+func (iv Interval) String() string {
+    return fmt.Sprintf("%.3g (68%%: %.3g-%.3g, 95%%: %.3g-%.3g)", iv.Median, iv.Lower68, iv.Upper68, iv.Lower95, iv.Upper95)
+}
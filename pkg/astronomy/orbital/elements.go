@@ -175,7 +175,7 @@ func CartesianToOrbital(pos, vel astromath.Vector3, mu float64) OrbitalElements
     i := math.Acos(math.Min(1.0, math.Max(-1.0, h.Z/hMag)))
     
     // Node vector (points along line of nodes)
-    n := astromath.Vector3{0, 0, 1}.Cross(h)
+    n := astromath.Vector3{X: 0, Y: 0, Z: 1}.Cross(h)
     nMag := n.Magnitude()
     
     // Longitude of ascending node (angle from x-axis to node)
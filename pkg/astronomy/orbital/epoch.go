@@ -0,0 +1,123 @@
+package orbital
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// J2000 is the Julian date of the J2000.0 epoch
+const J2000 = 2451545.0
+
+// gaussK is the Gaussian gravitational constant, the mean motion of an
+// orbit is gaussK / a^1.5 in rad/day
+const gaussK = 0.01720209895
+
+// Propagation methods of PropagateTo
+const (
+    PropagationTwoBody = "twobody" // Keplerian motion about the Sun
+    PropagationSecular = "secular" // plus the orbit-averaged precession by the giant planets
+)
+
+// Propagations lists the propagation methods
+var Propagations = []string{PropagationTwoBody, PropagationSecular}
+
+// giantPlanetJ2R2 is J2·R² in AU² of the giant planets smeared into rings,
+// ½ Σ m a², the quadrupole a distant orbit feels from them
+func giantPlanetJ2R2() float64 {
+    sum := 0.0
+    for _, p := range giantPlanets {
+        sum += p.mass * p.a * p.a
+    }
+    return sum / 2
+}
+
+// PropagateTo returns the elements moved from their epoch to epoch (JD).
+// Two-body propagation advances the mean anomaly; secular propagation also
+// turns the node and perihelion under the quadrupole of the giant planets,
+// which dominates the precession of distant objects. Elements without an
+// epoch are returned unchanged.
+func (o OrbitalElements) PropagateTo(epoch float64, method string) (OrbitalElements, error) {
+    if method != "" && method != PropagationTwoBody && method != PropagationSecular {
+        return o, fmt.Errorf("unknown propagation %q (%s)", method, strings.Join(Propagations, ", "))
+    }
+    if o.Epoch == 0 || o.Epoch == epoch {
+        return o, nil
+    }
+    if o.SemiMajorAxis <= 0 || o.Eccentricity < 0 || o.Eccentricity >= 1 {
+        return o, fmt.Errorf("only bound orbits can be propagated (a=%g, e=%g)", o.SemiMajorAxis, o.Eccentricity)
+    }
+    o.EnsureRadians()
+
+    dt := epoch - o.Epoch // days
+    a, e := o.SemiMajorAxis, o.Eccentricity
+    n := gaussK / math.Pow(a, 1.5)
+
+    switch method {
+    case PropagationSecular:
+        p := a * (1 - e*e)
+        f := n * giantPlanetJ2R2() / (p * p)
+        cosI := math.Cos(o.Inclination)
+        o.LongitudeAscendingNode += -1.5 * f * cosI * dt
+        o.ArgumentPerihelion += 0.75 * f * (5*cosI*cosI - 1) * dt
+        o.MeanAnomaly += (n + 0.75*f*math.Sqrt(1-e*e)*(3*cosI*cosI-1)) * dt
+    default:
+        o.MeanAnomaly += n * dt
+    }
+
+    o.LongitudeAscendingNode = wrapAngle(o.LongitudeAscendingNode)
+    o.ArgumentPerihelion = wrapAngle(o.ArgumentPerihelion)
+    o.MeanAnomaly = wrapAngle(o.MeanAnomaly)
+    o.Epoch = epoch
+    return o, nil
+}
+
+// PropagateAll moves elements to a common epoch, see PropagateTo
+func PropagateAll(elements []OrbitalElements, epoch float64, method string) ([]OrbitalElements, error) {
+    out := make([]OrbitalElements, len(elements))
+    for i, o := range elements {
+        p, err := o.PropagateTo(epoch, method)
+        if err != nil {
+            return nil, fmt.Errorf("object %d: %w", i, err)
+        }
+        out[i] = p
+    }
+    return out, nil
+}
+
+// ParseEpoch reads an epoch as J2000, a Julian date (2460200.5 or
+// JD2460200.5) or a date (2023-09-13)
+func ParseEpoch(s string) (float64, error) {
+    s = strings.TrimSpace(s)
+    if strings.EqualFold(s, "J2000") {
+        return J2000, nil
+    }
+    if jd, err := strconv.ParseFloat(strings.TrimPrefix(strings.ToUpper(s), "JD"), 64); err == nil {
+        return jd, nil
+    }
+    t, err := time.Parse("2006-01-02", s)
+    if err != nil {
+        return 0, fmt.Errorf("invalid epoch %q, use J2000, a Julian date or YYYY-MM-DD", s)
+    }
+    return JulianDate(t), nil
+}
+
+// JulianDate converts a time to a Julian date
+func JulianDate(t time.Time) float64 {
+    return 2440587.5 + float64(t.UTC().UnixNano())/86400e9
+}
+
+// JulianDateTime converts a Julian date to a time
+func JulianDateTime(jd float64) time.Time {
+    return time.Unix(0, int64((jd-2440587.5)*86400e9)).UTC()
+}
+
+func wrapAngle(x float64) float64 {
+    x = math.Mod(x, 2*math.Pi)
+    if x < 0 {
+        x += 2 * math.Pi
+    }
+    return x
+}
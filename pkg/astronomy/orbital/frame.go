@@ -0,0 +1,212 @@
+package orbital
+
+import (
+    "fmt"
+    "math"
+    "strings"
+
+    astromath "github.com/oxygene76/medasdigital-client/pkg/astronomy/math"
+)
+
+// Frame names a reference frame as center-plane. Catalog elements such as
+// the ones of JPL SBDB are heliocentric-ecliptic (J2000); for distant
+// objects barycentric elements are steadier, since the Sun moves around the
+// barycenter by about a solar radius within Jupiter's period.
+type Frame string
+
+// Reference frames
+const (
+    HeliocentricEcliptic   Frame = "heliocentric-ecliptic"
+    HeliocentricEquatorial Frame = "heliocentric-equatorial"
+    BarycentricEcliptic    Frame = "barycentric-ecliptic"
+    BarycentricEquatorial  Frame = "barycentric-equatorial"
+)
+
+// DefaultFrame is the frame of elements without an annotation
+const DefaultFrame = HeliocentricEcliptic
+
+// Frames lists the reference frames
+var Frames = []Frame{HeliocentricEcliptic, HeliocentricEquatorial, BarycentricEcliptic, BarycentricEquatorial}
+
+// Obliquity is the angle between ecliptic and equator at J2000 in radians
+const Obliquity = 23.4392911 * math.Pi / 180
+
+// ParseFrame reads a frame name, a center alone means its ecliptic frame
+// and an empty name the default frame
+func ParseFrame(s string) (Frame, error) {
+    s = strings.ToLower(strings.TrimSpace(s))
+    switch s {
+    case "":
+        return DefaultFrame, nil
+    case "heliocentric":
+        return HeliocentricEcliptic, nil
+    case "barycentric":
+        return BarycentricEcliptic, nil
+    }
+    for _, f := range Frames {
+        if Frame(s) == f {
+            return f, nil
+        }
+    }
+    names := make([]string, len(Frames))
+    for i, f := range Frames {
+        names[i] = string(f)
+    }
+    return "", fmt.Errorf("unknown frame %q (%s)", s, strings.Join(names, ", "))
+}
+
+// OrDefault returns the frame, DefaultFrame if it is unset
+func (f Frame) OrDefault() Frame {
+    if f == "" {
+        return DefaultFrame
+    }
+    return f
+}
+
+// Barycentric reports whether the frame is centered on the barycenter
+func (f Frame) Barycentric() bool {
+    return strings.HasPrefix(string(f), "barycentric")
+}
+
+// Equatorial reports whether the frame lies in the equator
+func (f Frame) Equatorial() bool {
+    return strings.HasSuffix(string(f), "equatorial")
+}
+
+// Mu is the gravitational parameter in AU³/yr² orbits in the frame are
+// computed with: the Sun's, or with the giant planets added to it around
+// the barycenter
+func (f Frame) Mu() float64 {
+    mu := 4 * math.Pi * math.Pi
+    if f.Barycentric() {
+        mu *= 1 + giantPlanetMass()
+    }
+    return mu
+}
+
+// EclipticToEquatorial rotates a vector from the ecliptic to the equator
+func EclipticToEquatorial(v astromath.Vector3) astromath.Vector3 {
+    c, s := math.Cos(Obliquity), math.Sin(Obliquity)
+    return astromath.Vector3{X: v.X, Y: c*v.Y - s*v.Z, Z: s*v.Y + c*v.Z}
+}
+
+// EquatorialToEcliptic rotates a vector from the equator to the ecliptic
+func EquatorialToEcliptic(v astromath.Vector3) astromath.Vector3 {
+    c, s := math.Cos(Obliquity), math.Sin(Obliquity)
+    return astromath.Vector3{X: v.X, Y: c*v.Y + s*v.Z, Z: -s*v.Y + c*v.Z}
+}
+
+// RADec returns the right ascension and declination in degrees of the
+// direction of an ecliptic vector
+func RADec(v astromath.Vector3) (float64, float64) {
+    eq := EclipticToEquatorial(v)
+    toDeg := 180 / math.Pi
+    ra := math.Atan2(eq.Y, eq.X) * toDeg
+    if ra < 0 {
+        ra += 360
+    }
+    return ra, math.Asin(eq.Z/eq.Magnitude()) * toDeg
+}
+
+// meanElements are approximate Keplerian elements of a planet at J2000 and
+// their rates per Julian century (Standish, valid 1800-2050), ecliptic and
+// angles in degrees
+type meanElements struct {
+    name                       string
+    mass                       float64 // solar masses
+    a, e, i, L, varpi, node    float64
+    da, de, di, dL, dvarpi, dN float64
+}
+
+var giantPlanets = []meanElements{
+    {"Jupiter", 0.0009545942, 5.20288700, 0.04838624, 1.30439695, 34.39644051, 14.72847983, 100.47390909,
+        -0.00011607, -0.00013253, -0.00183714, 3034.74612775, 0.21252668, 0.20469106},
+    {"Saturn", 0.0002857214, 9.53667594, 0.05386179, 2.48599187, 49.95424423, 92.59887831, 113.66242448,
+        -0.00125060, -0.00050991, 0.00193609, 1222.49362201, -0.41897216, -0.28867794},
+    {"Uranus", 0.00004365785, 19.18916464, 0.04725744, 0.77263783, 313.23810451, 170.95427630, 74.01692503,
+        -0.00196176, -0.00004397, -0.00242939, 428.48202785, 0.40805281, 0.04240589},
+    {"Neptune", 0.00005149497, 30.06992276, 0.00859048, 1.77004347, -55.12002969, 44.96476227, 131.78422574,
+        0.00026291, 0.00005105, 0.00035372, 218.45945325, -0.32241464, -0.00508664},
+}
+
+// at returns the heliocentric ecliptic elements of the planet at jd
+func (p meanElements) at(jd float64) OrbitalElements {
+    t := (jd - J2000) / 36525
+    deg := math.Pi / 180
+    varpi := p.varpi + p.dvarpi*t
+    node := p.node + p.dN*t
+    return OrbitalElements{
+        SemiMajorAxis:          p.a + p.da*t,
+        Eccentricity:           p.e + p.de*t,
+        Inclination:            (p.i + p.di*t) * deg,
+        LongitudeAscendingNode: wrapAngle(node * deg),
+        ArgumentPerihelion:     wrapAngle((varpi - node) * deg),
+        MeanAnomaly:            wrapAngle((p.L + p.dL*t - varpi) * deg),
+        Epoch:                  jd,
+    }
+}
+
+func giantPlanetMass() float64 {
+    m := 0.0
+    for _, p := range giantPlanets {
+        m += p.mass
+    }
+    return m
+}
+
+// SunBarycentric returns the position (AU) and velocity (AU/yr) of the Sun
+// relative to the barycenter of the Sun and the giant planets at jd, in the
+// ecliptic
+func SunBarycentric(jd float64) (astromath.Vector3, astromath.Vector3) {
+    var pos, vel astromath.Vector3
+    total := 1.0
+    for _, p := range giantPlanets {
+        r, v := p.at(jd).ToCartesian(4 * math.Pi * math.Pi * (1 + p.mass))
+        pos = pos.Add(r.Scale(p.mass))
+        vel = vel.Add(v.Scale(p.mass))
+        total += p.mass
+    }
+    return pos.Scale(-1 / total), vel.Scale(-1 / total)
+}
+
+// ConvertState moves a position (AU) and velocity (AU/yr) at jd from one
+// frame to another
+func ConvertState(pos, vel astromath.Vector3, jd float64, from, to Frame) (astromath.Vector3, astromath.Vector3) {
+    from, to = from.OrDefault(), to.OrDefault()
+    if from == to {
+        return pos, vel
+    }
+    if from.Equatorial() {
+        pos, vel = EquatorialToEcliptic(pos), EquatorialToEcliptic(vel)
+    }
+    if from.Barycentric() != to.Barycentric() {
+        sunPos, sunVel := SunBarycentric(jd)
+        if from.Barycentric() {
+            pos, vel = pos.Sub(sunPos), vel.Sub(sunVel)
+        } else {
+            pos, vel = pos.Add(sunPos), vel.Add(sunVel)
+        }
+    }
+    if to.Equatorial() {
+        pos, vel = EclipticToEquatorial(pos), EclipticToEquatorial(vel)
+    }
+    return pos, vel
+}
+
+// ConvertFrame returns the elements in another frame. A change of center
+// needs the epoch, the position of the Sun depends on it.
+func (o OrbitalElements) ConvertFrame(from, to Frame) (OrbitalElements, error) {
+    from, to = from.OrDefault(), to.OrDefault()
+    if from == to {
+        return o, nil
+    }
+    if from.Barycentric() != to.Barycentric() && o.Epoch == 0 {
+        return o, fmt.Errorf("elements without an epoch cannot be moved from %s to %s", from, to)
+    }
+    o.EnsureRadians()
+    pos, vel := o.ToCartesian(from.Mu())
+    pos, vel = ConvertState(pos, vel, o.Epoch, from, to)
+    c := CartesianToOrbital(pos, vel, to.Mu())
+    c.Epoch = o.Epoch
+    return c, nil
+}
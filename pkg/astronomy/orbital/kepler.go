@@ -0,0 +1,84 @@
+package orbital
+
+import (
+    "math"
+
+    astromath "github.com/oxygene76/medasdigital-client/pkg/astronomy/math"
+)
+
+// KeplerDrift advances a two-body state by dt on its exact Kepler orbit using
+// universal variables, so elliptic, parabolic and hyperbolic orbits are all
+// handled. Units only need to be consistent, e.g. AU, AU/day and AU³/day².
+func KeplerDrift(pos, vel astromath.Vector3, mu, dt float64) (astromath.Vector3, astromath.Vector3) {
+    r0 := pos.Magnitude()
+    if r0 == 0 || mu == 0 || dt == 0 {
+        return pos.Add(vel.Scale(dt)), vel
+    }
+    sqrtMu := math.Sqrt(mu)
+    v2 := vel.Dot(vel)
+    eta := pos.Dot(vel) / sqrtMu // r0·vr0/√μ
+    alpha := 2/r0 - v2/mu        // 1/a, negative for hyperbolic orbits
+
+    // Whole periods change nothing on a bound orbit
+    if alpha > 0 {
+        period := 2 * math.Pi / (sqrtMu * math.Pow(alpha, 1.5))
+        dt = math.Mod(dt, period)
+    }
+
+    chi := universalAnomaly(r0, eta, alpha, sqrtMu, dt)
+    z := alpha * chi * chi
+    c, s := stumpff(z)
+
+    f := 1 - chi*chi/r0*c
+    g := dt - chi*chi*chi/sqrtMu*s
+    newPos := pos.Scale(f).Add(vel.Scale(g))
+    r := newPos.Magnitude()
+    fdot := sqrtMu / (r * r0) * chi * (z*s - 1)
+    gdot := 1 - chi*chi/r*c
+    return newPos, pos.Scale(fdot).Add(vel.Scale(gdot))
+}
+
+// universalAnomaly solves the universal Kepler equation for χ with the
+// Laguerre-Conway iteration, which converges from poor starting values
+func universalAnomaly(r0, eta, alpha, sqrtMu, dt float64) float64 {
+    chi := sqrtMu * dt / r0
+    if alpha > 0 {
+        chi = sqrtMu * alpha * dt
+    }
+
+    const order = 5.0
+    for i := 0; i < 50; i++ {
+        z := alpha * chi * chi
+        c, s := stumpff(z)
+        f := eta*chi*chi*c + (1-alpha*r0)*chi*chi*chi*s + r0*chi - sqrtMu*dt
+        fp := eta*chi*(1-z*s) + (1-alpha*r0)*chi*chi*c + r0
+        fpp := eta*(1-z*c) + (1-alpha*r0)*chi*(1-z*s)
+
+        disc := math.Sqrt(math.Abs((order-1)*(order-1)*fp*fp - order*(order-1)*f*fpp))
+        denom := fp + math.Copysign(disc, fp)
+        if denom == 0 {
+            break
+        }
+        delta := order * f / denom
+        chi -= delta
+        if math.Abs(delta) <= 1e-14*math.Max(1, math.Abs(chi)) {
+            break
+        }
+    }
+    return chi
+}
+
+// stumpff returns the Stumpff functions C(z) and S(z)
+func stumpff(z float64) (float64, float64) {
+    switch {
+    case math.Abs(z) < 1e-3:
+        return 1.0/2 - z/24 + z*z/720 - z*z*z/40320,
+            1.0/6 - z/120 + z*z/5040 - z*z*z/362880
+    case z > 0:
+        sz := math.Sqrt(z)
+        return (1 - math.Cos(sz)) / z, (sz - math.Sin(sz)) / (sz * sz * sz)
+    default:
+        sz := math.Sqrt(-z)
+        return (math.Cosh(sz) - 1) / -z, (math.Sinh(sz) - sz) / (sz * sz * sz)
+    }
+}
@@ -0,0 +1,210 @@
+package orbital
+
+import (
+    "math"
+    "sort"
+
+    astromath "github.com/oxygene76/medasdigital-client/pkg/astronomy/math"
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/nbody"
+)
+
+// defaultEncounterHillRadii is how close, in Hill radii of the planet, a body
+// may get before WHFast hands the step to IAS15
+const defaultEncounterHillRadii = 3.0
+
+// WHFast is a symplectic Wisdom-Holman integrator in Jacobi coordinates in
+// the style of Rein & Tamayo (2015). Every body follows its exact Kepler
+// orbit between interaction kicks (drift-kick-drift), so the energy error
+// stays bounded over long integrations and steps can be a sizeable fraction of
+// the innermost orbital period.
+//
+// Wisdom-Holman breaks down when two bodies meet. Steps in which any body may
+// come within EncounterHillRadii of a planet are integrated with IAS15
+// instead, which adapts its step to the encounter.
+type WHFast struct {
+    EncounterHillRadii float64 // 0 = 3
+    Encounters         int     // steps handed to IAS15
+
+    order    []int     // central body, planets inner to outer, test particles
+    hill     []float64 // Hill radius by body index, 0 for the central body and test particles
+    fallback *nbody.IAS15
+}
+
+// NewWHFast creates a WHFast integrator
+func NewWHFast() *WHFast {
+    return &WHFast{}
+}
+
+func (*WHFast) Name() string { return "whfast" }
+
+// Step advances the system by dt
+func (w *WHFast) Step(s *nbody.System, dt float64) {
+    if len(w.order) != len(s.Bodies) {
+        w.setup(s)
+    }
+    if w.closeEncounter(s, dt) {
+        if w.fallback == nil {
+            w.fallback = nbody.NewIAS15()
+        }
+        w.Encounters++
+        w.fallback.Step(s, dt)
+        return
+    }
+    w.fallback = nil // restart IAS15 fresh at the next encounter
+
+    x, v, mu := w.toJacobi(s)
+    w.drift(x, v, mu, dt/2)
+    w.kick(s, x, v, mu, dt)
+    w.drift(x, v, mu, dt/2)
+    w.fromJacobi(s, x, v)
+    s.Time += dt
+}
+
+// setup orders the bodies for the Jacobi coordinates: the heaviest body
+// first, then the planets by distance from it, then the test particles
+func (w *WHFast) setup(s *nbody.System) {
+    central := 0
+    for i, b := range s.Bodies {
+        if b.Mass > s.Bodies[central].Mass {
+            central = i
+        }
+    }
+    c := s.Bodies[central]
+
+    var planets, particles []int
+    for i, b := range s.Bodies {
+        switch {
+        case i == central:
+        case b.Mass > 0:
+            planets = append(planets, i)
+        default:
+            particles = append(particles, i)
+        }
+    }
+    dist := func(i int) float64 { return s.Bodies[i].Position.Distance(c.Position) }
+    sort.SliceStable(planets, func(a, b int) bool { return dist(planets[a]) < dist(planets[b]) })
+
+    w.order = append(append([]int{central}, planets...), particles...)
+    w.hill = make([]float64, len(s.Bodies))
+    for _, i := range planets {
+        w.hill[i] = dist(i) * math.Cbrt(s.Bodies[i].Mass/(3*c.Mass))
+    }
+}
+
+// closeEncounter reports whether any body may get within the encounter radius
+// of a planet during the next step
+func (w *WHFast) closeEncounter(s *nbody.System, dt float64) bool {
+    k := w.EncounterHillRadii
+    if k <= 0 {
+        k = defaultEncounterHillRadii
+    }
+    for p, rh := range w.hill {
+        if rh == 0 {
+            continue
+        }
+        bp := s.Bodies[p]
+        for j, bj := range s.Bodies {
+            if j == p || j == w.order[0] {
+                continue
+            }
+            reach := k*rh + bj.Velocity.Sub(bp.Velocity).Magnitude()*dt
+            if bj.Position.Distance(bp.Position) < reach {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// toJacobi returns the Jacobi positions and velocities in w.order, index 0
+// being the centre of mass, and the Kepler μ of every Jacobi body
+func (w *WHFast) toJacobi(s *nbody.System) ([]astromath.Vector3, []astromath.Vector3, []float64) {
+    n := len(w.order)
+    x := make([]astromath.Vector3, n)
+    v := make([]astromath.Vector3, n)
+    mu := make([]float64, n)
+
+    m0 := s.Bodies[w.order[0]].Mass
+    eta := m0
+    com := s.Bodies[w.order[0]].Position
+    comV := s.Bodies[w.order[0]].Velocity
+    for k := 1; k < n; k++ {
+        b := s.Bodies[w.order[k]]
+        x[k] = b.Position.Sub(com)
+        v[k] = b.Velocity.Sub(comV)
+        etaNext := eta + b.Mass
+        mu[k] = s.G * m0 * etaNext / eta
+        com = com.Scale(eta).Add(b.Position.Scale(b.Mass)).Scale(1 / etaNext)
+        comV = comV.Scale(eta).Add(b.Velocity.Scale(b.Mass)).Scale(1 / etaNext)
+        eta = etaNext
+    }
+    x[0], v[0] = com, comV
+    return x, v, mu
+}
+
+// jacobiToInertial transforms Jacobi vectors back, positions and
+// velocities transform alike
+func (w *WHFast) jacobiToInertial(s *nbody.System, jac []astromath.Vector3) []astromath.Vector3 {
+    n := len(w.order)
+    out := make([]astromath.Vector3, n)
+    eta := 0.0
+    for _, i := range w.order {
+        eta += s.Bodies[i].Mass
+    }
+    com := jac[0]
+    for k := n - 1; k >= 1; k-- {
+        m := s.Bodies[w.order[k]].Mass
+        com = com.Sub(jac[k].Scale(m / eta)) // centre of mass of the bodies before k
+        out[k] = jac[k].Add(com)
+        eta -= m
+    }
+    out[0] = com
+    return out
+}
+
+func (w *WHFast) fromJacobi(s *nbody.System, x, v []astromath.Vector3) {
+    pos := w.jacobiToInertial(s, x)
+    vel := w.jacobiToInertial(s, v)
+    for k, i := range w.order {
+        s.Bodies[i].Position = pos[k]
+        s.Bodies[i].Velocity = vel[k]
+    }
+}
+
+// drift moves every Jacobi body along its Kepler orbit and the centre of mass
+// in a straight line
+func (w *WHFast) drift(x, v []astromath.Vector3, mu []float64, dt float64) {
+    x[0] = x[0].Add(v[0].Scale(dt))
+    for k := 1; k < len(x); k++ {
+        x[k], v[k] = KeplerDrift(x[k], v[k], mu[k], dt)
+    }
+}
+
+// kick applies the interaction part: the full Newtonian accelerations in
+// Jacobi coordinates minus the Kepler accelerations already in the drift
+func (w *WHFast) kick(s *nbody.System, x, v []astromath.Vector3, mu []float64, dt float64) {
+    pos := w.jacobiToInertial(s, x)
+    for k, i := range w.order {
+        s.Bodies[i].Position = pos[k]
+    }
+    acc := s.Accelerations()
+
+    ordered := make([]astromath.Vector3, len(w.order))
+    for k, i := range w.order {
+        ordered[k] = acc[i]
+    }
+    // Accelerations transform like positions
+    eta := s.Bodies[w.order[0]].Mass
+    com := ordered[0]
+    for k := 1; k < len(w.order); k++ {
+        m := s.Bodies[w.order[k]].Mass
+        a := ordered[k].Sub(com)
+        r := x[k].Magnitude()
+        a = a.Add(x[k].Scale(mu[k] / (r * r * r)))
+        v[k] = v[k].Add(a.Scale(dt))
+
+        etaNext := eta + m
+        com = com.Scale(eta).Add(ordered[k].Scale(m)).Scale(1 / etaNext)
+        eta = etaNext
+    }
+}
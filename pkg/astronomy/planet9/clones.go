@@ -0,0 +1,68 @@
+package planet9
+
+import (
+    "math"
+    "runtime"
+    "sync"
+
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/orbital"
+)
+
+// CloneOptions configure RunClones
+type CloneOptions struct {
+    DurationYears float64
+    Integrator    string
+    Frame         orbital.Frame
+    Workers       int                   // parallel simulations, 0 = one per CPU
+    Progress      func(done, total int) // called after every clone set, serialized
+}
+
+// CloneSummary is the spread of a result over ETNO clones drawn from the
+// element uncertainties
+type CloneSummary struct {
+    Clones            int              `json:"clones"`
+    ClusteringScore   orbital.Interval `json:"clustering_score"`
+    ChanceProbability orbital.Interval `json:"chance_probability"` // of the clustering from uniform longitudes, exp(-n·R²)
+    Scores            []float64        `json:"scores"`             // in clone order
+}
+
+// RunClones simulates params once per clone set, each set holding one
+// clone of every ETNO, and summarizes the clustering scores
+func RunClones(params SearchParameters, sets [][]orbital.OrbitalElements, opts CloneOptions) *CloneSummary {
+    workers := opts.Workers
+    if workers <= 0 {
+        workers = runtime.NumCPU()
+    }
+    scores := make([]float64, len(sets))
+    chance := make([]float64, len(sets))
+
+    var mu sync.Mutex
+    var wg sync.WaitGroup
+    sem := make(chan struct{}, workers)
+    done := 0
+    for k, set := range sets {
+        wg.Add(1)
+        sem <- struct{}{}
+        go func(k int, set []orbital.OrbitalElements) {
+            defer func() { <-sem; wg.Done() }()
+            r := RunSimulation(params, set, opts.DurationYears, RunOpts{Quiet: true, Integrator: opts.Integrator, Frame: opts.Frame})
+            scores[k] = r.ClusteringScore
+            chance[k] = math.Exp(-logPosterior(r))
+
+            mu.Lock()
+            done++
+            if opts.Progress != nil {
+                opts.Progress(done, len(sets))
+            }
+            mu.Unlock()
+        }(k, set)
+    }
+    wg.Wait()
+
+    return &CloneSummary{
+        Clones:            len(sets),
+        ClusteringScore:   orbital.NewInterval(scores),
+        ChanceProbability: orbital.NewInterval(chance),
+        Scores:            scores,
+    }
+}
@@ -0,0 +1,217 @@
+package planet9
+
+import (
+    "fmt"
+    "math"
+    "math/rand"
+    "sort"
+
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/orbital"
+)
+
+// JobType is the contract job type of a distributed sweep chunk
+const JobType = "planet9_search"
+
+// Chunk is a self-contained part of a sweep that a provider runs as one job.
+// It carries the ETNO elements so every provider, and the verification,
+// integrate exactly the same system.
+type Chunk struct {
+    Index         int                       `json:"chunk"`
+    Count         int                       `json:"chunks"`
+    Offset        int                       `json:"offset"` // sweep index of the first sample
+    Samples       []SearchParameters        `json:"samples"`
+    ETNOs         []orbital.OrbitalElements `json:"etnos"`
+    DurationYears float64                   `json:"sim_years"`
+    Integrator    string                    `json:"integrator,omitempty"`
+    Frame         orbital.Frame             `json:"frame,omitempty"` // default heliocentric-ecliptic
+}
+
+// ChunkResult is what a provider returns for a chunk, the surface carries the
+// sweep indices of the full search
+type ChunkResult struct {
+    Chunk   int            `json:"chunk"`
+    Surface []SurfacePoint `json:"surface"`
+    Best    SearchResult   `json:"best"`
+}
+
+// SplitSweep cuts the samples into at most n chunks of nearly equal size
+func SplitSweep(samples []SearchParameters, etnos []orbital.OrbitalElements, durationYears float64, integrator string, frame orbital.Frame, n int) []Chunk {
+    if n > len(samples) {
+        n = len(samples)
+    }
+    if n < 1 {
+        return nil
+    }
+    chunks := make([]Chunk, n)
+    offset := 0
+    for i := range chunks {
+        size := len(samples) / n
+        if i < len(samples)%n {
+            size++
+        }
+        chunks[i] = Chunk{
+            Index:         i,
+            Count:         n,
+            Offset:        offset,
+            Samples:       samples[offset : offset+size],
+            ETNOs:         etnos,
+            DurationYears: durationYears,
+            Integrator:    integrator,
+            Frame:         frame,
+        }
+        offset += size
+    }
+    return chunks
+}
+
+// Validate checks a chunk received as job parameters
+func (c Chunk) Validate() error {
+    if len(c.Samples) == 0 {
+        return fmt.Errorf("chunk has no samples")
+    }
+    if len(c.ETNOs) == 0 {
+        return fmt.Errorf("chunk has no ETNOs")
+    }
+    if c.DurationYears <= 0 {
+        return fmt.Errorf("sim_years must be positive")
+    }
+    if _, err := NewIntegrator(c.Integrator); err != nil {
+        return err
+    }
+    if _, err := orbital.ParseFrame(string(c.Frame)); err != nil {
+        return err
+    }
+    for i, p := range c.Samples {
+        if p.Mass <= 0 || p.SemiMajorAxis <= 0 || p.Eccentricity < 0 || p.Eccentricity >= 1 {
+            return fmt.Errorf("sample %d: invalid parameters", c.Offset+i)
+        }
+    }
+    return nil
+}
+
+// RunChunk sweeps the samples of a chunk
+func RunChunk(c Chunk, workers int, progress func(done, total int)) *ChunkResult {
+    sweep := RunSweep(c.Samples, c.ETNOs, SweepOptions{
+        DurationYears: c.DurationYears,
+        Integrator:    c.Integrator,
+        Frame:         c.Frame,
+        Workers:       workers,
+        Progress: func(done, total int, _ SurfacePoint) {
+            if progress != nil {
+                progress(done, total)
+            }
+        },
+    })
+    res := &ChunkResult{Chunk: c.Index, Surface: sweep.Surface, Best: sweep.Best}
+    for i := range res.Surface {
+        res.Surface[i].Index += c.Offset
+        // JSON has no NaN, a blown up integration reports a drift of 1
+        if d := res.Surface[i].EnergyDrift; math.IsNaN(d) || math.IsInf(d, 0) {
+            res.Surface[i].EnergyDrift = 1
+        }
+    }
+    if d := res.Best.Energy.MaxDrift; math.IsNaN(d) || math.IsInf(d, 0) {
+        res.Best.Energy.MaxDrift = 1
+    }
+    return res
+}
+
+// Mismatch is a sample whose score a provider reported differently from the
+// local re-run
+type Mismatch struct {
+    Index    int     `json:"index"`
+    Missing  bool    `json:"missing,omitempty"` // not in the reported surface at all
+    Reported float64 `json:"reported"`
+    Local    float64 `json:"local"`
+}
+
+// ChunkVerification is the outcome of re-running part of a chunk locally
+type ChunkVerification struct {
+    Checked    []int      `json:"checked"` // sweep indices
+    Mismatches []Mismatch `json:"mismatches,omitempty"`
+}
+
+// Passed reports whether every re-run sample matched
+func (v ChunkVerification) Passed() bool { return len(v.Mismatches) == 0 }
+
+// VerifyChunk re-runs n randomly chosen samples of a chunk and its best point
+// and compares their scores with the reported surface. Integrations are deterministic, the
+// tolerance only absorbs floating point differences between platforms.
+func VerifyChunk(c Chunk, r *ChunkResult, n int, rng *rand.Rand, tolerance float64) ChunkVerification {
+    var v ChunkVerification
+    reported := make(map[int]SurfacePoint, len(r.Surface))
+    for _, p := range r.Surface {
+        reported[p.Index] = p
+    }
+    // A surface that misses samples fails on those first
+    for i := range c.Samples {
+        if _, ok := reported[c.Offset+i]; !ok {
+            v.Checked = append(v.Checked, c.Offset+i)
+            v.Mismatches = append(v.Mismatches, Mismatch{Index: c.Offset + i, Missing: true})
+        }
+    }
+    if len(v.Mismatches) > 0 {
+        return v
+    }
+
+    if n > len(c.Samples) {
+        n = len(c.Samples)
+    }
+    picks := rng.Perm(len(c.Samples))[:n]
+    // The chunk's best point decides the combined result, it is always re-run
+    for i, p := range c.Samples {
+        if p == r.Best.Parameters && reported[c.Offset+i].ClusteringScore == r.Best.ClusteringScore {
+            if !containsInt(picks, i) {
+                picks = append(picks, i)
+            }
+            break
+        }
+    }
+    sort.Ints(picks)
+    for _, i := range picks {
+        idx := c.Offset + i
+        local := RunSimulation(c.Samples[i], c.ETNOs, c.DurationYears, RunOpts{Quiet: true, Integrator: c.Integrator, Frame: c.Frame})
+        v.Checked = append(v.Checked, idx)
+        got := reported[idx].ClusteringScore
+        if math.Abs(got-local.ClusteringScore) > tolerance*math.Max(1, math.Abs(local.ClusteringScore)) {
+            v.Mismatches = append(v.Mismatches, Mismatch{Index: idx, Reported: got, Local: local.ClusteringScore})
+        }
+    }
+    return v
+}
+
+// MergeChunks combines chunk results into one sweep result. The surface is
+// ordered by sweep index and the best point is chosen as RunSweep does.
+func MergeChunks(results []*ChunkResult) *SweepResult {
+    merged := &SweepResult{}
+    bestIdx := -1
+    for _, r := range results {
+        if r == nil {
+            continue
+        }
+        merged.Surface = append(merged.Surface, r.Surface...)
+        for _, p := range r.Surface {
+            if p.Parameters != r.Best.Parameters {
+                continue
+            }
+            if bestIdx < 0 || p.ClusteringScore > merged.Best.ClusteringScore ||
+                (p.ClusteringScore == merged.Best.ClusteringScore && p.Index < bestIdx) {
+                bestIdx = p.Index
+                merged.Best = r.Best
+            }
+            break
+        }
+    }
+    sort.Slice(merged.Surface, func(i, j int) bool { return merged.Surface[i].Index < merged.Surface[j].Index })
+    merged.Frame = merged.Best.Frame.OrDefault()
+    return merged
+}
+
+func containsInt(list []int, v int) bool {
+    for _, x := range list {
+        if x == v {
+            return true
+        }
+    }
+    return false
+}
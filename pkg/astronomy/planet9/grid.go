@@ -0,0 +1,312 @@
+package planet9
+
+import (
+    "fmt"
+    "math"
+    "math/rand"
+    "runtime"
+    "sort"
+    "sync"
+
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/orbital"
+)
+
+// Sampling strategies for a parameter sweep
+const (
+    SamplingGrid           = "grid" // regular grid, every combination of evenly spaced values
+    SamplingLatinHypercube = "lhs"  // Latin hypercube, one sample per stratum in every dimension
+)
+
+// Range is a closed parameter interval, Min == Max pins the parameter
+type Range struct {
+    Min float64 `json:"min"`
+    Max float64 `json:"max"`
+}
+
+// Fixed returns a range pinned to v
+func Fixed(v float64) Range {
+    return Range{Min: v, Max: v}
+}
+
+// Free reports whether the range spans more than one value
+func (r Range) Free() bool {
+    return r.Max > r.Min
+}
+
+func (r Range) at(frac float64) float64 {
+    return r.Min + frac*(r.Max-r.Min)
+}
+
+// ParameterRanges spans the search space, angles in degrees
+type ParameterRanges struct {
+    Mass                   Range `json:"mass"`
+    SemiMajorAxis          Range `json:"semi_major_axis"`
+    Eccentricity           Range `json:"eccentricity"`
+    Inclination            Range `json:"inclination"`
+    LongitudeAscendingNode Range `json:"longitude_ascending_node"`
+    ArgumentPerihelion     Range `json:"argument_perihelion"`
+}
+
+// FixedRanges pins every parameter to params
+func FixedRanges(params SearchParameters) ParameterRanges {
+    return ParameterRanges{
+        Mass:                   Fixed(params.Mass),
+        SemiMajorAxis:          Fixed(params.SemiMajorAxis),
+        Eccentricity:           Fixed(params.Eccentricity),
+        Inclination:            Fixed(params.Inclination),
+        LongitudeAscendingNode: Fixed(params.LongitudeAscendingNode),
+        ArgumentPerihelion:     Fixed(params.ArgumentPerihelion),
+    }
+}
+
+// dims lists the ranges in SearchParameters order
+func (pr ParameterRanges) dims() []Range {
+    return []Range{pr.Mass, pr.SemiMajorAxis, pr.Eccentricity, pr.Inclination,
+        pr.LongitudeAscendingNode, pr.ArgumentPerihelion}
+}
+
+// FreeDimensions counts the parameters that are swept
+func (pr ParameterRanges) FreeDimensions() int {
+    n := 0
+    for _, r := range pr.dims() {
+        if r.Free() {
+            n++
+        }
+    }
+    return n
+}
+
+// Center is the midpoint of every range
+func (pr ParameterRanges) Center() SearchParameters {
+    return fromValues(pr.values(func(_ int, r Range) float64 { return r.at(0.5) }))
+}
+
+func (pr ParameterRanges) values(f func(dim int, r Range) float64) []float64 {
+    dims := pr.dims()
+    v := make([]float64, len(dims))
+    for i, r := range dims {
+        v[i] = f(i, r)
+    }
+    return v
+}
+
+func fromValues(v []float64) SearchParameters {
+    return SearchParameters{
+        Mass:                   v[0],
+        SemiMajorAxis:          v[1],
+        Eccentricity:           v[2],
+        Inclination:            v[3],
+        LongitudeAscendingNode: v[4],
+        ArgumentPerihelion:     v[5],
+    }
+}
+
+// GridShape splits a budget of points over the free dimensions as evenly as
+// possible without exceeding it, pinned dimensions get one value
+func (pr ParameterRanges) GridShape(points int) []int {
+    dims := pr.dims()
+    shape := make([]int, len(dims))
+    free := 0
+    for i, r := range dims {
+        shape[i] = 1
+        if r.Free() {
+            free++
+        }
+    }
+    if free == 0 || points < 2 {
+        return shape
+    }
+
+    per := int(math.Floor(math.Pow(float64(points), 1/float64(free)) + 1e-9))
+    if per < 2 {
+        per = 2
+    }
+    for i, r := range dims {
+        if r.Free() {
+            shape[i] = per
+        }
+    }
+    // Spend the rest of the budget one dimension at a time
+    for grown := true; grown; {
+        grown = false
+        for i, r := range dims {
+            if !r.Free() {
+                continue
+            }
+            if total(shape)/shape[i]*(shape[i]+1) <= points {
+                shape[i]++
+                grown = true
+            }
+        }
+    }
+    return shape
+}
+
+func total(shape []int) int {
+    n := 1
+    for _, s := range shape {
+        n *= s
+    }
+    return n
+}
+
+// GridSamples returns the points of a regular grid with at most points
+// samples (at least two per free dimension), endpoints included
+func GridSamples(pr ParameterRanges, points int) []SearchParameters {
+    shape := pr.GridShape(points)
+    dims := pr.dims()
+    samples := make([]SearchParameters, 0, total(shape))
+    idx := make([]int, len(shape))
+    for {
+        samples = append(samples, fromValues(pr.values(func(d int, r Range) float64 {
+            if shape[d] == 1 {
+                return r.at(0.5)
+            }
+            return r.at(float64(idx[d]) / float64(shape[d]-1))
+        })))
+
+        // Advance the odometer, last dimension fastest
+        d := len(dims) - 1
+        for ; d >= 0; d-- {
+            idx[d]++
+            if idx[d] < shape[d] {
+                break
+            }
+            idx[d] = 0
+        }
+        if d < 0 {
+            return samples
+        }
+    }
+}
+
+// LatinHypercubeSamples draws points samples so that every free dimension
+// has exactly one sample in each of its points equal strata
+func LatinHypercubeSamples(pr ParameterRanges, points int, rng *rand.Rand) []SearchParameters {
+    if points < 1 {
+        points = 1
+    }
+    dims := pr.dims()
+    strata := make([][]int, len(dims))
+    for d := range dims {
+        strata[d] = rng.Perm(points)
+    }
+    samples := make([]SearchParameters, points)
+    for i := range samples {
+        samples[i] = fromValues(pr.values(func(d int, r Range) float64 {
+            if !r.Free() {
+                return r.Min
+            }
+            return r.at((float64(strata[d][i]) + rng.Float64()) / float64(points))
+        }))
+    }
+    return samples
+}
+
+// Samples draws the sweep points with the given strategy
+func Samples(pr ParameterRanges, sampling string, points int, seed int64) ([]SearchParameters, error) {
+    if pr.FreeDimensions() == 0 {
+        return []SearchParameters{pr.Center()}, nil
+    }
+    switch sampling {
+    case SamplingGrid, "":
+        return GridSamples(pr, points), nil
+    case SamplingLatinHypercube:
+        return LatinHypercubeSamples(pr, points, rand.New(rand.NewSource(seed))), nil
+    default:
+        return nil, fmt.Errorf("unknown sampling %q (grid or lhs)", sampling)
+    }
+}
+
+// SurfacePoint is the score of one sampled parameter set
+type SurfacePoint struct {
+    Index           int              `json:"index"`
+    Parameters      SearchParameters `json:"parameters"`
+    ClusteringScore float64          `json:"clustering_score"`
+    ETNOsAnalyzed   int              `json:"etnos_analyzed"`
+    EnergyDrift     float64          `json:"energy_drift"` // largest relative energy error
+}
+
+// SweepOptions configure RunSweep
+type SweepOptions struct {
+    DurationYears float64
+    Integrator    string
+    Frame         orbital.Frame                         // of the ETNO elements, default heliocentric-ecliptic
+    Workers       int                                   // parallel simulations, 0 = one per CPU
+    Progress      func(done, total int, p SurfacePoint) // called after every point, serialized
+}
+
+// SweepResult holds the score surface in sample order and the full result of
+// the best point
+type SweepResult struct {
+    Surface []SurfacePoint
+    Best    SearchResult
+    Frame   orbital.Frame // the clustering scores were measured in
+}
+
+// RunSweep simulates every sample and scores it. Simulations run quietly in
+// parallel, snapshots are not written.
+func RunSweep(samples []SearchParameters, etnos []orbital.OrbitalElements, opts SweepOptions) *SweepResult {
+    workers := opts.Workers
+    if workers <= 0 {
+        workers = runtime.NumCPU()
+    }
+    if workers > len(samples) {
+        workers = len(samples)
+    }
+
+    res := &SweepResult{Surface: make([]SurfacePoint, len(samples)), Frame: opts.Frame.OrDefault()}
+    bestIdx := -1
+
+    var mu sync.Mutex
+    var wg sync.WaitGroup
+    jobs := make(chan int)
+    done := 0
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := range jobs {
+                r := RunSimulation(samples[i], etnos, opts.DurationYears, RunOpts{Quiet: true, Integrator: opts.Integrator, Frame: opts.Frame})
+                p := SurfacePoint{
+                    Index:           i,
+                    Parameters:      r.Parameters,
+                    ClusteringScore: r.ClusteringScore,
+                    ETNOsAnalyzed:   len(r.ETNOEffects),
+                    EnergyDrift:     r.Energy.MaxDrift,
+                }
+
+                mu.Lock()
+                res.Surface[i] = p
+                if bestIdx < 0 || p.ClusteringScore > res.Surface[bestIdx].ClusteringScore ||
+                    (p.ClusteringScore == res.Surface[bestIdx].ClusteringScore && i < bestIdx) {
+                    bestIdx = i
+                    res.Best = r
+                }
+                done++
+                if opts.Progress != nil {
+                    opts.Progress(done, len(samples), p)
+                }
+                mu.Unlock()
+            }
+        }()
+    }
+    for i := range samples {
+        jobs <- i
+    }
+    close(jobs)
+    wg.Wait()
+    return res
+}
+
+// TopN returns the n best points by clustering score, ties in sample order
+func TopN(surface []SurfacePoint, n int) []SurfacePoint {
+    ranked := append([]SurfacePoint(nil), surface...)
+    sort.SliceStable(ranked, func(i, j int) bool {
+        return ranked[i].ClusteringScore > ranked[j].ClusteringScore
+    })
+    if n > 0 && n < len(ranked) {
+        ranked = ranked[:n]
+    }
+    return ranked
+}
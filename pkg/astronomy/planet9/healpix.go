@@ -0,0 +1,89 @@
+package planet9
+
+import "math"
+
+// HEALPixIndex returns the RING ordered HEALPix pixel of nside containing
+// ra, dec (degrees), after Górski et al. (2005)
+func HEALPixIndex(nside int, ra, dec float64) int {
+    z := math.Sin(dec * math.Pi / 180)
+    za := math.Abs(z)
+    tt := math.Mod(ra, 360) / 90 // in [0, 4)
+    if tt < 0 {
+        tt += 4
+    }
+    ns := float64(nside)
+
+    if za <= 2.0/3 {
+        // Equatorial belt
+        t1 := ns * (0.5 + tt)
+        t2 := ns * z * 0.75
+        jp := int(t1 - t2) // ascending edge line
+        jm := int(t1 + t2) // descending edge line
+        ir := nside + 1 + jp - jm
+        kshift := 1 - ir&1
+        ip := (jp + jm - nside + kshift + 1) / 2
+        ip %= 4 * nside
+        return 2*nside*(nside-1) + (ir-1)*4*nside + ip
+    }
+
+    // Polar caps
+    tp := tt - math.Floor(tt)
+    tmp := ns * math.Sqrt(3*(1-za))
+    jp := int(tp * tmp)
+    jm := int((1 - tp) * tmp)
+    ir := jp + jm + 1
+    ip := int(tt * float64(ir))
+    ip %= 4 * ir
+    if z > 0 {
+        return 2*ir*(ir-1) + ip
+    }
+    return 12*nside*nside - 2*ir*(ir+1) + ip
+}
+
+// HEALPixCenter returns the ra, dec (degrees) of the center of a RING
+// ordered pixel of nside
+func HEALPixCenter(nside, pix int) (float64, float64) {
+    npix := 12 * nside * nside
+    ncap := 2 * nside * (nside - 1)
+    fact2 := 4 / float64(npix)
+    var z, phi float64
+
+    switch {
+    case pix < ncap:
+        // North polar cap
+        ring := (1 + isqrt(1+2*pix)) / 2
+        iphi := pix + 1 - 2*ring*(ring-1)
+        z = 1 - float64(ring*ring)*fact2
+        phi = (float64(iphi) - 0.5) * math.Pi / (2 * float64(ring))
+    case pix < npix-ncap:
+        // Equatorial belt
+        ip := pix - ncap
+        ring := ip/(4*nside) + nside
+        iphi := ip%(4*nside) + 1
+        fodd := 0.5
+        if (ring+nside)&1 == 1 {
+            fodd = 1
+        }
+        z = float64(2*nside-ring) * 2 / (3 * float64(nside))
+        phi = (float64(iphi) - fodd) * math.Pi / (2 * float64(nside))
+    default:
+        // South polar cap
+        ip := npix - pix
+        ring := (1 + isqrt(2*ip-1)) / 2
+        iphi := 4*ring + 1 - (ip - 2*ring*(ring-1))
+        z = -1 + float64(ring*ring)*fact2
+        phi = (float64(iphi) - 0.5) * math.Pi / (2 * float64(ring))
+    }
+    return phi * 180 / math.Pi, math.Asin(z) * 180 / math.Pi
+}
+
+func isqrt(n int) int {
+    r := int(math.Sqrt(float64(n)))
+    for r*r > n {
+        r--
+    }
+    for (r+1)*(r+1) <= n {
+        r++
+    }
+    return r
+}
@@ -0,0 +1,417 @@
+package planet9
+
+import (
+    "encoding/json"
+    "fmt"
+    "math"
+    "math/rand"
+    "runtime"
+    "sort"
+    "sync"
+
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/orbital"
+)
+
+// ParameterNames are the column names of the six search parameters
+var ParameterNames = []string{"mass", "semi_major_axis", "eccentricity", "inclination",
+    "longitude_ascending_node", "argument_perihelion"}
+
+// stretchScale is the a parameter of the Goodman & Weare stretch move
+const stretchScale = 2.0
+
+// MCMCOptions configure RunMCMC
+type MCMCOptions struct {
+    Walkers       int // 0 = 2×dimensions+2
+    Steps         int
+    BurnIn        int // steps dropped from samples and diagnostics, -1 = Steps/4
+    Thin          int // keep every Thin-th step in Samples
+    Seed          int64
+    DurationYears float64
+    Integrator    string
+    Frame         orbital.Frame                             // of the ETNO elements, default heliocentric-ecliptic
+    Workers       int                                       // parallel simulations, 0 = one per CPU
+    Progress      func(step, steps int, acceptance float64) // called after every step
+}
+
+// Chain is the output of the ensemble sampler. Only the swept parameters are
+// sampled, pinned ones keep their value.
+type Chain struct {
+    Parameters []string        `json:"parameters"`
+    Ranges     ParameterRanges `json:"ranges"`
+    Walkers    int             `json:"walkers"`
+    Steps      int             `json:"steps"`
+    BurnIn     int             `json:"burn_in"`
+    Thin       int             `json:"thin"`
+    Seed       int64           `json:"seed"`
+    Frame      orbital.Frame   `json:"frame"`            // the clustering scores were measured in
+    Positions  [][][]float64   `json:"positions"`        // [step][walker][parameter]
+    LogProb    [][]float64     `json:"log_prob"`         // [step][walker]
+    Scores     [][]float64     `json:"clustering_score"` // [step][walker]
+    Accepted   []int           `json:"accepted"`         // accepted proposals per walker
+    Best       SearchResult    `json:"-"`                // highest posterior point evaluated, with its ETNO effects
+
+    free []int
+}
+
+// logPosterior is flat inside the ranges. The likelihood is the Rayleigh
+// statistic Z = n·R² of the simulated longitudes of perihelion: the chance of
+// clustering this strong from uniform longitudes is about exp(-Z).
+func logPosterior(r SearchResult) float64 {
+    n := float64(len(r.ETNOEffects))
+    return n * r.ClusteringScore * r.ClusteringScore
+}
+
+// RunMCMC samples the posterior over the swept parameters of ranges with the
+// affine-invariant ensemble sampler of Goodman & Weare (2010). The walkers
+// start on a Latin hypercube over the ranges, the two halves of the ensemble
+// are updated in turn and each half is simulated in parallel.
+func RunMCMC(ranges ParameterRanges, etnos []orbital.OrbitalElements, opts MCMCOptions) (*Chain, error) {
+    c := &Chain{Ranges: ranges, Seed: opts.Seed, Thin: opts.Thin, Steps: opts.Steps, Frame: opts.Frame.OrDefault()}
+    for d, r := range ranges.dims() {
+        if r.Free() {
+            c.free = append(c.free, d)
+            c.Parameters = append(c.Parameters, ParameterNames[d])
+        }
+    }
+    ndim := len(c.free)
+    if ndim == 0 {
+        return nil, fmt.Errorf("MCMC needs at least one parameter range")
+    }
+    if opts.Steps < 1 {
+        return nil, fmt.Errorf("MCMC needs at least one step")
+    }
+    c.Walkers = opts.Walkers
+    if c.Walkers == 0 {
+        c.Walkers = 2*ndim + 2
+    }
+    if c.Walkers < 2*ndim || c.Walkers%2 != 0 {
+        return nil, fmt.Errorf("walkers must be even and at least %d (twice the swept parameters)", 2*ndim)
+    }
+    c.BurnIn = opts.BurnIn
+    if c.BurnIn < 0 {
+        c.BurnIn = opts.Steps / 4
+    }
+    if c.BurnIn >= opts.Steps {
+        return nil, fmt.Errorf("burn-in (%d) must be shorter than the chain (%d steps)", c.BurnIn, opts.Steps)
+    }
+    if c.Thin < 1 {
+        c.Thin = 1
+    }
+    workers := opts.Workers
+    if workers <= 0 {
+        workers = runtime.NumCPU()
+    }
+
+    rng := rand.New(rand.NewSource(opts.Seed))
+    bestLP := math.Inf(-1)
+    var mu sync.Mutex
+
+    // evaluate simulates the positions in parallel
+    evaluate := func(xs [][]float64) ([]float64, []float64) {
+        lp := make([]float64, len(xs))
+        score := make([]float64, len(xs))
+        var wg sync.WaitGroup
+        sem := make(chan struct{}, workers)
+        for i, x := range xs {
+            params := c.Params(x)
+            if !c.inside(params) {
+                lp[i] = math.Inf(-1)
+                continue
+            }
+            wg.Add(1)
+            sem <- struct{}{}
+            go func(i int) {
+                defer func() { <-sem; wg.Done() }()
+                r := RunSimulation(params, etnos, opts.DurationYears, RunOpts{Quiet: true, Integrator: opts.Integrator, Frame: opts.Frame})
+                lp[i], score[i] = logPosterior(r), r.ClusteringScore
+                mu.Lock()
+                if lp[i] > bestLP {
+                    bestLP = lp[i]
+                    c.Best = r
+                }
+                mu.Unlock()
+            }(i)
+        }
+        wg.Wait()
+        return lp, score
+    }
+
+    pos := make([][]float64, c.Walkers)
+    for k, p := range LatinHypercubeSamples(ranges, c.Walkers, rng) {
+        pos[k] = c.vector(p)
+    }
+    lp, score := evaluate(pos)
+    c.Accepted = make([]int, c.Walkers)
+
+    half := c.Walkers / 2
+    for step := 0; step < opts.Steps; step++ {
+        for s := 0; s < 2; s++ {
+            active, other := s*half, (1-s)*half
+
+            proposals := make([][]float64, half)
+            zs := make([]float64, half)
+            for i := 0; i < half; i++ {
+                x := pos[active+i]
+                y := pos[other+rng.Intn(half)]
+                u := rng.Float64()
+                z := math.Pow((stretchScale-1)*u+1, 2) / stretchScale
+                prop := make([]float64, ndim)
+                for d := range prop {
+                    prop[d] = y[d] + z*(x[d]-y[d])
+                }
+                proposals[i], zs[i] = prop, z
+            }
+
+            propLP, propScore := evaluate(proposals)
+            for i := 0; i < half; i++ {
+                k := active + i
+                if math.IsInf(propLP[i], -1) {
+                    continue
+                }
+                logAccept := float64(ndim-1)*math.Log(zs[i]) + propLP[i] - lp[k]
+                if logAccept >= 0 || math.Log(rng.Float64()) < logAccept {
+                    pos[k], lp[k], score[k] = proposals[i], propLP[i], propScore[i]
+                    c.Accepted[k]++
+                }
+            }
+        }
+
+        c.Positions = append(c.Positions, copyPositions(pos))
+        c.LogProb = append(c.LogProb, append([]float64(nil), lp...))
+        c.Scores = append(c.Scores, append([]float64(nil), score...))
+        if opts.Progress != nil {
+            opts.Progress(step+1, opts.Steps, c.MeanAcceptance())
+        }
+    }
+    return c, nil
+}
+
+func copyPositions(pos [][]float64) [][]float64 {
+    out := make([][]float64, len(pos))
+    for i, p := range pos {
+        out[i] = append([]float64(nil), p...)
+    }
+    return out
+}
+
+// LoadChain reads a chain saved as JSON
+func LoadChain(data []byte) (*Chain, error) {
+    var c Chain
+    if err := json.Unmarshal(data, &c); err != nil {
+        return nil, err
+    }
+    for d, r := range c.Ranges.dims() {
+        if r.Free() {
+            c.free = append(c.free, d)
+        }
+    }
+    if len(c.free) != len(c.Parameters) {
+        return nil, fmt.Errorf("chain has %d parameters for %d free ranges", len(c.Parameters), len(c.free))
+    }
+    if c.Thin < 1 {
+        c.Thin = 1
+    }
+    return &c, nil
+}
+
+// vector picks the swept parameters of p
+func (c *Chain) vector(p SearchParameters) []float64 {
+    all := []float64{p.Mass, p.SemiMajorAxis, p.Eccentricity, p.Inclination,
+        p.LongitudeAscendingNode, p.ArgumentPerihelion}
+    x := make([]float64, len(c.free))
+    for i, d := range c.free {
+        x[i] = all[d]
+    }
+    return x
+}
+
+// Params fills the pinned parameters into a sampled position
+func (c *Chain) Params(x []float64) SearchParameters {
+    v := c.Ranges.values(func(_ int, r Range) float64 { return r.Min })
+    for i, d := range c.free {
+        v[d] = x[i]
+    }
+    return fromValues(v)
+}
+
+// inside checks the flat prior
+func (c *Chain) inside(p SearchParameters) bool {
+    x := c.vector(p)
+    dims := c.Ranges.dims()
+    for i, d := range c.free {
+        if x[i] < dims[d].Min || x[i] > dims[d].Max {
+            return false
+        }
+    }
+    return true
+}
+
+// MeanAcceptance is the accepted fraction of all proposals so far
+func (c *Chain) MeanAcceptance() float64 {
+    if len(c.Positions) == 0 {
+        return 0
+    }
+    total := 0
+    for _, a := range c.Accepted {
+        total += a
+    }
+    return float64(total) / float64(len(c.Positions)*c.Walkers)
+}
+
+// Samples returns the positions after burn-in, every Thin-th step, flattened
+// over the walkers. Each row has one value per entry of Parameters.
+func (c *Chain) Samples() [][]float64 {
+    var out [][]float64
+    for step := c.BurnIn; step < len(c.Positions); step += c.Thin {
+        out = append(out, c.Positions[step]...)
+    }
+    return out
+}
+
+// MAP is the highest posterior position in the chain after burn-in
+func (c *Chain) MAP() (SearchParameters, float64) {
+    bestStep, bestWalker := c.BurnIn, 0
+    for step := c.BurnIn; step < len(c.LogProb); step++ {
+        for k, lp := range c.LogProb[step] {
+            if lp > c.LogProb[bestStep][bestWalker] {
+                bestStep, bestWalker = step, k
+            }
+        }
+    }
+    return c.Params(c.Positions[bestStep][bestWalker]), c.Scores[bestStep][bestWalker]
+}
+
+// Diagnostic summarizes the posterior and convergence of one parameter
+type Diagnostic struct {
+    Parameter    string  `json:"parameter"`
+    Mean         float64 `json:"mean"`
+    Median       float64 `json:"median"`
+    Lower        float64 `json:"lower"`         // 16th percentile
+    Upper        float64 `json:"upper"`         // 84th percentile
+    RHat         float64 `json:"r_hat"`         // Gelman-Rubin over the walkers
+    AutocorrTime float64 `json:"autocorr_time"` // integrated autocorrelation time in steps
+    ESS          float64 `json:"ess"`           // effective sample size
+}
+
+// Diagnostics computes the posterior summary and convergence statistics of
+// every sampled parameter after burn-in
+func (c *Chain) Diagnostics() []Diagnostic {
+    n := len(c.Positions) - c.BurnIn
+    if n < 1 {
+        return nil
+    }
+    diags := make([]Diagnostic, len(c.Parameters))
+    for d, name := range c.Parameters {
+        // series[k] is the trace of walker k
+        series := make([][]float64, c.Walkers)
+        var all []float64
+        for k := range series {
+            series[k] = make([]float64, n)
+            for t := 0; t < n; t++ {
+                series[k][t] = c.Positions[c.BurnIn+t][k][d]
+            }
+            all = append(all, series[k]...)
+        }
+        sort.Float64s(all)
+        tau := autocorrTime(series)
+        diags[d] = Diagnostic{
+            Parameter:    name,
+            Mean:         mean(all),
+            Median:       quantile(all, 0.5),
+            Lower:        quantile(all, 0.16),
+            Upper:        quantile(all, 0.84),
+            RHat:         gelmanRubin(series),
+            AutocorrTime: tau,
+            ESS:          float64(n*c.Walkers) / tau,
+        }
+    }
+    return diags
+}
+
+func mean(x []float64) float64 {
+    s := 0.0
+    for _, v := range x {
+        s += v
+    }
+    return s / float64(len(x))
+}
+
+func variance(x []float64) float64 {
+    if len(x) < 2 {
+        return 0
+    }
+    m := mean(x)
+    s := 0.0
+    for _, v := range x {
+        s += (v - m) * (v - m)
+    }
+    return s / float64(len(x)-1)
+}
+
+// quantile of sorted values with linear interpolation
+func quantile(sorted []float64, q float64) float64 {
+    pos := q * float64(len(sorted)-1)
+    i := int(pos)
+    if i+1 >= len(sorted) {
+        return sorted[len(sorted)-1]
+    }
+    return sorted[i] + (pos-float64(i))*(sorted[i+1]-sorted[i])
+}
+
+// gelmanRubin is the potential scale reduction factor with the walkers as
+// chains, values close to 1 indicate convergence
+func gelmanRubin(chains [][]float64) float64 {
+    n := float64(len(chains[0]))
+    if n < 2 {
+        return math.NaN()
+    }
+    means := make([]float64, len(chains))
+    w := 0.0
+    for k, ch := range chains {
+        means[k] = mean(ch)
+        w += variance(ch)
+    }
+    w /= float64(len(chains))
+    if w == 0 {
+        return math.NaN()
+    }
+    b := n * variance(means)
+    return math.Sqrt(((n-1)/n*w + b/n) / w)
+}
+
+// autocorrTime estimates the integrated autocorrelation time from the
+// walker-averaged autocorrelation function with the automatic window of
+// Sokal (c = 5), as done by emcee
+func autocorrTime(chains [][]float64) float64 {
+    n := len(chains[0])
+    acf := make([]float64, n)
+    for _, ch := range chains {
+        m := mean(ch)
+        c0 := 0.0
+        for _, v := range ch {
+            c0 += (v - m) * (v - m)
+        }
+        if c0 == 0 {
+            continue
+        }
+        for lag := 0; lag < n; lag++ {
+            s := 0.0
+            for t := 0; t+lag < n; t++ {
+                s += (ch[t] - m) * (ch[t+lag] - m)
+            }
+            acf[lag] += s / c0 / float64(len(chains))
+        }
+    }
+    if acf[0] == 0 {
+        return 1
+    }
+
+    tau := 1.0
+    for window := 1; window < n; window++ {
+        tau += 2 * acf[window] / acf[0]
+        if float64(window) >= 5*tau {
+            break
+        }
+    }
+    return math.Max(tau, 1)
+}
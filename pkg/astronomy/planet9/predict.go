@@ -0,0 +1,297 @@
+package planet9
+
+import (
+    "fmt"
+    "math"
+    "math/rand"
+    "sort"
+
+    itypes "github.com/oxygene76/medasdigital-client/internal/types"
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/orbital"
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/survey"
+)
+
+// DefaultDraws is the number of positions PredictPositions draws
+const DefaultDraws = 200000
+
+// DefaultAlbedo is the geometric albedo assumed for Planet 9
+const DefaultAlbedo = 0.5
+
+// Sky map schemes
+const (
+    SchemeGrid    = "grid"    // equal steps in RA and Dec
+    SchemeHEALPix = "healpix" // equal area HEALPix pixels, RING ordering
+)
+
+// PredictOptions configure PredictPositions
+type PredictOptions struct {
+    Epoch   float64 // JD of the prediction
+    Draws   int     // 0 = DefaultDraws
+    Seed    int64
+    Albedo  float64                  // 0 = DefaultAlbedo
+    Exclude survey.SelectionFunction // positions these surveys would have detected Planet 9 at are dropped
+}
+
+// SkyPoint is one drawn position of Planet 9
+type SkyPoint struct {
+    RA        float64 // degrees
+    Dec       float64 // degrees
+    Distance  float64 // AU from the Sun
+    Magnitude float64 // V at opposition
+}
+
+// AbsoluteMagnitude estimates H of a planet of mass (Earth masses) from a
+// mass-radius relation R ∝ M^0.55 of icy sub-Neptunes and its albedo
+func AbsoluteMagnitude(mass, albedo float64) float64 {
+    diameter := 2 * 6371 * math.Pow(mass, 0.55) // km
+    return 5 * math.Log10(1329/(diameter*math.Sqrt(albedo)))
+}
+
+// PredictPositions draws where Planet 9 is on the sky. The clustering
+// constrains the orbit but not the position on it, so each draw takes one
+// of samples and a uniform mean anomaly, weighting the orbit by the time
+// spent on it. Positions the surveys of opts.Exclude would have detected it
+// at are dropped and counted.
+func PredictPositions(samples []SearchParameters, opts PredictOptions) ([]SkyPoint, int, error) {
+    if len(samples) == 0 {
+        return nil, 0, fmt.Errorf("no Planet 9 parameters to predict from")
+    }
+    draws := opts.Draws
+    if draws <= 0 {
+        draws = DefaultDraws
+    }
+    albedo := opts.Albedo
+    if albedo <= 0 {
+        albedo = DefaultAlbedo
+    }
+
+    rng := rand.New(rand.NewSource(opts.Seed))
+    points := make([]SkyPoint, 0, draws)
+    excluded := 0
+    deg := math.Pi / 180
+    for k := 0; k < draws; k++ {
+        p := samples[rng.Intn(len(samples))]
+        if p.SemiMajorAxis <= 0 || p.Eccentricity < 0 || p.Eccentricity >= 1 {
+            return nil, 0, fmt.Errorf("parameters a=%g e=%g are not a bound orbit", p.SemiMajorAxis, p.Eccentricity)
+        }
+        o := orbital.OrbitalElements{
+            SemiMajorAxis:          p.SemiMajorAxis,
+            Eccentricity:           p.Eccentricity,
+            Inclination:            p.Inclination * deg,
+            LongitudeAscendingNode: p.LongitudeAscendingNode * deg,
+            ArgumentPerihelion:     p.ArgumentPerihelion * deg,
+            MeanAnomaly:            rng.Float64() * 2 * math.Pi,
+            Epoch:                  opts.Epoch,
+        }
+        pos, _ := o.ToCartesian(4 * math.Pi * math.Pi)
+        h := AbsoluteMagnitude(p.Mass, albedo)
+        if opts.Exclude != nil && opts.Exclude.Detectable(pos, h) {
+            excluded++
+            continue
+        }
+        r := pos.Magnitude()
+        ra, dec := orbital.RADec(pos)
+        points = append(points, SkyPoint{RA: ra, Dec: dec, Distance: r, Magnitude: survey.Magnitude(h, r)})
+    }
+    if len(points) == 0 {
+        return nil, excluded, fmt.Errorf("the surveys would have detected Planet 9 at all %d drawn positions", draws)
+    }
+    return points, excluded, nil
+}
+
+// MapCell is one cell of a SkyMap
+type MapCell struct {
+    Pixel       int     `json:"pixel"`       // grid index (row·columns + column) or HEALPix pixel
+    RA          float64 `json:"ra"`          // center, degrees
+    Dec         float64 `json:"dec"`         // center, degrees
+    Probability float64 `json:"probability"` // of Planet 9 being in the cell
+    Density     float64 `json:"density"`     // probability per square degree
+    Cumulative  float64 `json:"cumulative"`  // of this and all more probable cells, the credible region the cell is in
+    Magnitude   float64 `json:"magnitude"`   // median V of the draws in the cell
+}
+
+// SkyMap is the probability of where Planet 9 is on the sky, the cells
+// without draws left out
+type SkyMap struct {
+    Scheme     string    `json:"scheme"`
+    Resolution float64   `json:"resolution,omitempty"` // degrees per grid cell
+    NSide      int       `json:"nside,omitempty"`
+    Epoch      float64   `json:"epoch"`    // JD
+    Draws      int       `json:"draws"`    // kept in the map
+    Excluded   int       `json:"excluded"` // draws the surveys would have detected
+    Cells      []MapCell `json:"cells"`    // most probable first
+}
+
+// GridMap bins points into cells of resolution degrees in RA and Dec
+func GridMap(points []SkyPoint, resolution float64) (*SkyMap, error) {
+    if resolution <= 0 || resolution > 90 {
+        return nil, fmt.Errorf("grid resolution must be within 0-90 degrees")
+    }
+    cols := int(math.Ceil(360 / resolution))
+    rows := int(math.Ceil(180 / resolution))
+    index := func(ra, dec float64) int {
+        c := int(ra / resolution)
+        r := int((dec + 90) / resolution)
+        return min(r, rows-1)*cols + min(c, cols-1)
+    }
+    cell := func(pix int) (float64, float64, float64) {
+        r, c := pix/cols, pix%cols
+        dec1 := -90 + float64(r)*resolution
+        dec2 := math.Min(dec1+resolution, 90)
+        deg := math.Pi / 180
+        area := resolution * (math.Sin(dec2*deg) - math.Sin(dec1*deg)) / deg
+        return (float64(c) + 0.5) * resolution, (dec1 + dec2) / 2, area
+    }
+    m := binPoints(points, index, cell)
+    m.Scheme = SchemeGrid
+    m.Resolution = resolution
+    return m, nil
+}
+
+// HEALPixMap bins points into the HEALPix pixels of nside
+func HEALPixMap(points []SkyPoint, nside int) (*SkyMap, error) {
+    if nside < 1 || nside&(nside-1) != 0 || nside > 8192 {
+        return nil, fmt.Errorf("nside must be a power of two up to 8192")
+    }
+    area := 4 * math.Pi * math.Pow(180/math.Pi, 2) / float64(12*nside*nside)
+    index := func(ra, dec float64) int { return HEALPixIndex(nside, ra, dec) }
+    cell := func(pix int) (float64, float64, float64) {
+        ra, dec := HEALPixCenter(nside, pix)
+        return ra, dec, area
+    }
+    m := binPoints(points, index, cell)
+    m.Scheme = SchemeHEALPix
+    m.NSide = nside
+    return m, nil
+}
+
+func binPoints(points []SkyPoint, index func(ra, dec float64) int, cell func(pix int) (float64, float64, float64)) *SkyMap {
+    mags := map[int][]float64{}
+    for _, p := range points {
+        pix := index(p.RA, p.Dec)
+        mags[pix] = append(mags[pix], p.Magnitude)
+    }
+    m := &SkyMap{Draws: len(points)}
+    for pix, v := range mags {
+        ra, dec, area := cell(pix)
+        prob := float64(len(v)) / float64(len(points))
+        sort.Float64s(v)
+        m.Cells = append(m.Cells, MapCell{Pixel: pix, RA: ra, Dec: dec, Probability: prob, Density: prob / area, Magnitude: v[len(v)/2]})
+    }
+    sort.Slice(m.Cells, func(i, j int) bool {
+        if m.Cells[i].Probability != m.Cells[j].Probability {
+            return m.Cells[i].Probability > m.Cells[j].Probability
+        }
+        return m.Cells[i].Pixel < m.Cells[j].Pixel
+    })
+    sum := 0.0
+    for i := range m.Cells {
+        sum += m.Cells[i].Probability
+        m.Cells[i].Cumulative = sum
+    }
+    return m
+}
+
+// Credible returns the most probable cells holding level of the probability
+func (m *SkyMap) Credible(level float64) []MapCell {
+    for i, c := range m.Cells {
+        if c.Cumulative >= level {
+            return m.Cells[:i+1]
+        }
+    }
+    return m.Cells
+}
+
+// Candidate describes Planet 9 for observation planning: the predicted
+// position is the most probable cell and the search region the RA/Dec box
+// around the credible region of level. The confidence is the share of the
+// draws inside the box, which exceeds level where the region is patchy.
+func (m *SkyMap) Candidate(params SearchParameters, points []SkyPoint, level float64) itypes.Planet9Candidate {
+    cells := m.Credible(level)
+    epoch := orbital.JulianDateTime(m.Epoch)
+
+    area := 0.0
+    for _, c := range cells {
+        area += c.Probability / c.Density
+    }
+
+    // The box is the shortest RA arc and the Dec range covering the
+    // credible cells, widened by half a cell
+    half := m.Resolution / 2
+    if m.Scheme == SchemeHEALPix {
+        half = math.Sqrt(4*math.Pi/float64(12*m.NSide*m.NSide)) * 180 / math.Pi / 2
+    }
+    ras := make([]float64, len(cells))
+    minDec, maxDec := 90.0, -90.0
+    for i, c := range cells {
+        ras[i] = c.RA
+        minDec = math.Min(minDec, c.Dec)
+        maxDec = math.Max(maxDec, c.Dec)
+    }
+    sort.Float64s(ras)
+    gap, start := 360-ras[len(ras)-1]+ras[0], ras[0] // the largest gap between the cells is outside the arc
+    for i := 1; i < len(ras); i++ {
+        if ras[i]-ras[i-1] > gap {
+            gap, start = ras[i]-ras[i-1], ras[i]
+        }
+    }
+    radiusRA := math.Min((360-gap)/2+half, 180)
+    centerRA := math.Mod(start+(360-gap)/2, 360)
+    centerDec := (minDec + maxDec) / 2
+    radiusDec := (maxDec-minDec)/2 + half
+    dRA := func(ra float64) float64 {
+        return math.Abs(math.Mod(ra-centerRA+540, 360) - 180)
+    }
+
+    var inside []float64
+    for _, p := range points {
+        if dRA(p.RA) <= radiusRA && math.Abs(p.Dec-centerDec) <= radiusDec {
+            inside = append(inside, p.Magnitude)
+        }
+    }
+    sort.Float64s(inside)
+    expected := 0.0
+    if len(inside) > 0 {
+        expected = inside[len(inside)/2]
+    }
+
+    peak := m.Cells[0]
+    return itypes.Planet9Candidate{
+        ID:              fmt.Sprintf("planet9-jd%.0f", m.Epoch),
+        ConfidenceScore: float64(len(inside)) / float64(len(points)),
+        OrbitalElements: itypes.OrbitalElements{
+            SemiMajorAxis:      params.SemiMajorAxis,
+            Eccentricity:       params.Eccentricity,
+            Inclination:        params.Inclination,
+            LongitudeAscending: params.LongitudeAscendingNode,
+            ArgumentPeriapsis:  params.ArgumentPerihelion,
+            Epoch:              epoch,
+            Period:             math.Pow(params.SemiMajorAxis, 1.5),
+        },
+        PredictedPosition: itypes.SkyPosition{
+            RA:          peak.RA,
+            Dec:         peak.Dec,
+            Uncertainty: math.Sqrt(area/math.Pi) * 60, // radius of a circle as large as the credible region
+            Epoch:       epoch,
+        },
+        SearchRegion: itypes.SearchRegion{
+            CenterRA:    centerRA,
+            CenterDec:   centerDec,
+            RadiusRA:    radiusRA,
+            RadiusDec:   radiusDec,
+            Priority:    1,
+            ExpectedMag: expected,
+        },
+        ModelParameters: map[string]float64{
+            "mass":                     params.Mass,
+            "semi_major_axis":          params.SemiMajorAxis,
+            "eccentricity":             params.Eccentricity,
+            "inclination":              params.Inclination,
+            "longitude_ascending_node": params.LongitudeAscendingNode,
+            "argument_perihelion":      params.ArgumentPerihelion,
+            "credible_level":           level,
+        },
+        DetectionMethod:  "orbital_clustering_prediction",
+        ValidationStatus: "pending",
+    }
+}
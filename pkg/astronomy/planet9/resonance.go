@@ -0,0 +1,372 @@
+package planet9
+
+import (
+    "fmt"
+    "math"
+
+    itypes "github.com/oxygene76/medasdigital-client/internal/types"
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/nbody"
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/orbital"
+)
+
+const (
+    // Libration status of an angle over the integrated time span
+    LibrationLibrating    = "librating"
+    LibrationCirculating  = "circulating"
+    LibrationInconclusive = "inconclusive" // neither a full circulation nor a full libration cycle
+
+    librationMaxAmplitude = 170.0 // degrees, wider excursions count as circulation
+    resonanceMaxCoeff     = 12    // largest p and q tried for p:q resonances
+    resonanceMaxOrder     = 8     // largest |p-q|
+    resonanceTolerance    = 0.05  // relative distance of the mean motion ratio from p/q
+    trackerMaxSamples     = 20000
+)
+
+// KozaiInfo describes the Kozai-Lidov state of an ETNO: the argument of
+// perihelion librates instead of circulating while eccentricity and
+// inclination oscillate against each other. Elements are heliocentric and
+// ecliptic.
+type KozaiInfo struct {
+    Status           string  `json:"status"`          // libration status of ω
+    OmegaCenter      float64 `json:"omega_center"`    // degrees
+    OmegaAmplitude   float64 `json:"omega_amplitude"` // degrees, half the orbit-averaged swing
+    EccentricityMin  float64 `json:"eccentricity_min"`
+    EccentricityMax  float64 `json:"eccentricity_max"`
+    InclinationMin   float64 `json:"inclination_min"`    // degrees
+    InclinationMax   float64 `json:"inclination_max"`    // degrees
+    EInclCorrelation float64 `json:"e_incl_correlation"` // Pearson correlation of e and i, Kozai cycles are negative
+    Detected         bool    `json:"detected"`           // ω librates and e, i cycle in anti-phase
+}
+
+// elementTrack is the osculating heliocentric element history of one body
+type elementTrack struct {
+    t          []float64 // years
+    a, e, inc  []float64
+    node, peri []float64 // Ω, ω in radians
+    meanLong   []float64 // λ = Ω + ω + M
+}
+
+func (tr *elementTrack) add(tYears float64, oe orbital.OrbitalElements) {
+    tr.t = append(tr.t, tYears)
+    tr.a = append(tr.a, oe.SemiMajorAxis)
+    tr.e = append(tr.e, oe.Eccentricity)
+    tr.inc = append(tr.inc, oe.Inclination)
+    tr.node = append(tr.node, oe.LongitudeAscendingNode)
+    tr.peri = append(tr.peri, oe.ArgumentPerihelion)
+    tr.meanLong = append(tr.meanLong, oe.LongitudeAscendingNode+oe.ArgumentPerihelion+oe.MeanAnomaly)
+}
+
+// meanMotion is the mean of sqrt(μ/a³) in rad/year
+func (tr *elementTrack) meanMotion(muYear float64) float64 {
+    sum := 0.0
+    for _, a := range tr.a {
+        sum += math.Sqrt(muYear / (a * a * a))
+    }
+    return sum / float64(len(tr.a))
+}
+
+// elementTracker samples the elements of Planet 9 and the ETNOs during the
+// integration for the Kozai and resonance diagnostics
+type elementTracker struct {
+    planet9Index int
+    etnoStart    int
+    frame        orbital.Frame
+    muYear       float64
+    planet9      elementTrack
+    etnos        []elementTrack
+}
+
+func newElementTracker(planet9Index, etnoStart, etnoCount int, frame orbital.Frame) *elementTracker {
+    return &elementTracker{
+        planet9Index: planet9Index,
+        etnoStart:    etnoStart,
+        frame:        frame,
+        muYear:       frame.Mu(),
+        etnos:        make([]elementTrack, etnoCount),
+    }
+}
+
+// cadenceDays picks a sampling interval of a fiftieth of the shortest period
+// among Planet 9 and the ETNOs, but no more than trackerMaxSamples samples
+func (tr *elementTracker) cadenceDays(sys *nbody.System, durationDays float64) float64 {
+    minPeriod := math.Inf(1)
+    for _, i := range append([]int{tr.planet9Index}, tr.etnoIndices(sys)...) {
+        oe := frameElements(sys.Bodies, i, tr.frame)
+        if oe.SemiMajorAxis > 0 {
+            minPeriod = math.Min(minPeriod, nbody.KeplerPeriodYears(oe.SemiMajorAxis)*365.25)
+        }
+    }
+    cadence := minPeriod / 50
+    if math.IsInf(cadence, 1) || cadence < durationDays/trackerMaxSamples {
+        cadence = durationDays / trackerMaxSamples
+    }
+    return cadence
+}
+
+func (tr *elementTracker) etnoIndices(sys *nbody.System) []int {
+    idx := make([]int, 0, len(tr.etnos))
+    for k := range tr.etnos {
+        if tr.etnoStart+k < len(sys.Bodies) {
+            idx = append(idx, tr.etnoStart+k)
+        }
+    }
+    return idx
+}
+
+// sample records the current elements, bodies on unbound orbits are skipped
+func (tr *elementTracker) sample(tDays float64, sys *nbody.System) {
+    elements := func(i int) (orbital.OrbitalElements, bool) {
+        oe := frameElements(sys.Bodies, i, tr.frame)
+        return oe, oe.SemiMajorAxis > 0 && oe.Eccentricity < 1
+    }
+    p9, ok := elements(tr.planet9Index)
+    if !ok {
+        return
+    }
+    tYears := tDays / 365.25
+    tr.planet9.add(tYears, p9)
+    for k, i := range tr.etnoIndices(sys) {
+        if oe, ok := elements(i); ok {
+            tr.etnos[k].add(tYears, oe)
+        }
+    }
+}
+
+// apply attaches the diagnostics to the effects of the ETNOs they belong to
+func (tr *elementTracker) apply(effects []ETNOEffect, kozai, resonance bool) {
+    n9 := 0.0
+    if len(tr.planet9.t) > 0 {
+        n9 = tr.planet9.meanMotion(tr.muYear)
+    }
+    for i := range effects {
+        var k int
+        if _, err := fmt.Sscanf(effects[i].ObjectID, "ETNO_%d", &k); err != nil || k >= len(tr.etnos) {
+            continue
+        }
+        track := &tr.etnos[k]
+        if len(track.t) < 3 {
+            continue
+        }
+        // Average over the longer of the two orbital periods
+        window := 2 * math.Pi / math.Min(track.meanMotion(tr.muYear), n9)
+        if kozai {
+            effects[i].Kozai = kozaiDiagnostic(track, window)
+        }
+        if resonance && n9 > 0 {
+            effects[i].Resonance = resonanceDiagnostic(track, &tr.planet9, n9, tr.muYear, window)
+        }
+    }
+}
+
+// kozaiDiagnostic checks whether ω librates with anti-correlated e and i
+func kozaiDiagnostic(tr *elementTrack, window float64) *KozaiInfo {
+    status, center, amp := libration(tr.t, tr.peri, window)
+    info := &KozaiInfo{
+        Status:           status,
+        OmegaCenter:      center * 180 / math.Pi,
+        OmegaAmplitude:   amp * 180 / math.Pi,
+        EccentricityMin:  minOf(tr.e),
+        EccentricityMax:  maxOf(tr.e),
+        InclinationMin:   minOf(tr.inc) * 180 / math.Pi,
+        InclinationMax:   maxOf(tr.inc) * 180 / math.Pi,
+        EInclCorrelation: correlation(tr.e, tr.inc),
+    }
+    cycling := info.EccentricityMax-info.EccentricityMin > 0.01 && info.EInclCorrelation < -0.5
+    info.Detected = status == LibrationLibrating && cycling
+    return info
+}
+
+// resonanceDiagnostic tries every p:q commensurability near the mean motion
+// ratio of the ETNO and Planet 9. The critical angle of the resonance with
+// p·n₉ ≈ q·n is φ = p·λ₉ − q·λ − (p−q)·ϖ. The librating candidate with the
+// smallest amplitude wins, otherwise the closest ratio is reported as not
+// librating. It returns nil when no ratio is close enough.
+func resonanceDiagnostic(tr, p9 *elementTrack, n9, muYear, window float64) *itypes.ResonanceInfo {
+    // Planet 9 may have missed samples the ETNO has and the other way round
+    p9At := make(map[float64]int, len(p9.t))
+    for j, t := range p9.t {
+        p9At[t] = j
+    }
+    ratio := tr.meanMotion(muYear) / n9
+
+    var best *itypes.ResonanceInfo
+    bestMismatch := math.Inf(1)
+    for q := 1; q <= resonanceMaxCoeff; q++ {
+        for p := 1; p <= resonanceMaxCoeff; p++ {
+            if gcd(p, q) != 1 || absInt(p-q) > resonanceMaxOrder {
+                continue
+            }
+            exact := float64(p) / float64(q)
+            mismatch := math.Abs(ratio-exact) / exact
+            if mismatch > resonanceTolerance {
+                continue
+            }
+
+            var t, phi []float64
+            for i, ti := range tr.t {
+                j, ok := p9At[ti]
+                if !ok {
+                    continue
+                }
+                varpi := tr.node[i] + tr.peri[i]
+                t = append(t, ti)
+                phi = append(phi, float64(p)*p9.meanLong[j]-float64(q)*tr.meanLong[i]-float64(p-q)*varpi)
+            }
+            if len(phi) < 3 {
+                continue
+            }
+            status, center, amp := libration(t, phi, window)
+            info := &itypes.ResonanceInfo{
+                // Periods outer:inner, like Neptune:Pluto 3:2
+                Ratio:         fmt.Sprintf("%d:%d", max(p, q), min(p, q)),
+                LibrationAmp:  amp * 180 / math.Pi,
+                CriticalAngle: center * 180 / math.Pi,
+                IsLibrating:   status == LibrationLibrating,
+            }
+            switch {
+            case best == nil,
+                info.IsLibrating && (!best.IsLibrating || info.LibrationAmp < best.LibrationAmp),
+                !info.IsLibrating && !best.IsLibrating && mismatch < bestMismatch:
+                best, bestMismatch = info, mismatch
+            }
+        }
+    }
+    return best
+}
+
+// libration classifies an angle series in radians sampled at times t in
+// years. Short-period terms of the osculating elements are averaged out over
+// windowYears first, typically the longer orbital period involved. The
+// smoothed angle circulates when it covers a full turn and librates when it
+// turns back and forth at least twice by clearly more than the remaining
+// noise. It returns the status, the circular mean and the half width of the
+// smoothed swing.
+func libration(t, angles []float64, windowYears float64) (string, float64, float64) {
+    var c, s float64
+    for _, a := range angles {
+        c += math.Cos(a)
+        s += math.Sin(a)
+    }
+    center := math.Atan2(s, c)
+    if center < 0 {
+        center += 2 * math.Pi
+    }
+
+    n := len(angles)
+    unwrapped := make([]float64, n)
+    unwrapped[0] = angles[0]
+    for i := 1; i < n; i++ {
+        unwrapped[i] = unwrapped[i-1] + wrapPi(angles[i]-angles[i-1])
+    }
+
+    // Centred moving average over the window
+    half := 0
+    if n > 1 && t[n-1] > t[0] {
+        half = int(windowYears / (t[n-1] - t[0]) * float64(n-1) / 2)
+    }
+    if 2*half+1 > n/2 {
+        return LibrationInconclusive, center, 0
+    }
+    smooth := make([]float64, n)
+    noise := 0.0
+    for i := range unwrapped {
+        lo, hi := max(0, i-half), min(n-1, i+half)
+        sum := 0.0
+        for j := lo; j <= hi; j++ {
+            sum += unwrapped[j]
+        }
+        smooth[i] = sum / float64(hi-lo+1)
+        noise += (unwrapped[i] - smooth[i]) * (unwrapped[i] - smooth[i])
+    }
+    noise = math.Sqrt(noise / float64(n))
+    amp := (maxOf(smooth) - minOf(smooth)) / 2
+    if 2*amp >= 2*math.Pi {
+        return LibrationCirculating, center, math.Pi
+    }
+
+    // Turning points with hysteresis so the noise does not count
+    threshold := math.Max(3*noise, 2*math.Pi/180)
+    turns, dir := 0, 0
+    hi, lo := smooth[0], smooth[0]
+    for _, v := range smooth[1:] {
+        switch {
+        case dir == 0:
+            hi, lo = math.Max(hi, v), math.Min(lo, v)
+            if v > lo+threshold {
+                dir, hi = 1, v
+            } else if v < hi-threshold {
+                dir, lo = -1, v
+            }
+        case dir > 0 && v > hi:
+            hi = v
+        case dir > 0 && v < hi-threshold:
+            turns, dir, lo = turns+1, -1, v
+        case dir < 0 && v < lo:
+            lo = v
+        case dir < 0 && v > lo+threshold:
+            turns, dir, hi = turns+1, 1, v
+        }
+    }
+    if turns >= 2 && amp < librationMaxAmplitude*math.Pi/180 {
+        return LibrationLibrating, center, amp
+    }
+    return LibrationInconclusive, center, amp
+}
+
+func wrapPi(x float64) float64 {
+    x = math.Mod(x+math.Pi, 2*math.Pi)
+    if x < 0 {
+        x += 2 * math.Pi
+    }
+    return x - math.Pi
+}
+
+func correlation(x, y []float64) float64 {
+    n := float64(len(x))
+    var mx, my float64
+    for i := range x {
+        mx += x[i]
+        my += y[i]
+    }
+    mx, my = mx/n, my/n
+    var sxy, sxx, syy float64
+    for i := range x {
+        sxy += (x[i] - mx) * (y[i] - my)
+        sxx += (x[i] - mx) * (x[i] - mx)
+        syy += (y[i] - my) * (y[i] - my)
+    }
+    if sxx == 0 || syy == 0 {
+        return 0
+    }
+    return sxy / math.Sqrt(sxx*syy)
+}
+
+func minOf(x []float64) float64 {
+    m := math.Inf(1)
+    for _, v := range x {
+        m = math.Min(m, v)
+    }
+    return m
+}
+
+func maxOf(x []float64) float64 {
+    m := math.Inf(-1)
+    for _, v := range x {
+        m = math.Max(m, v)
+    }
+    return m
+}
+
+func gcd(a, b int) int {
+    for b != 0 {
+        a, b = b, a%b
+    }
+    return a
+}
+
+func absInt(x int) int {
+    if x < 0 {
+        return -x
+    }
+    return x
+}
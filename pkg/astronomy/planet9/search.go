@@ -3,7 +3,9 @@ package planet9
 import (
     "fmt"
     "math"
+    "strings"
     
+    itypes "github.com/oxygene76/medasdigital-client/internal/types"
     "github.com/oxygene76/medasdigital-client/pkg/astronomy/nbody"
     "github.com/oxygene76/medasdigital-client/pkg/astronomy/orbital"
     astromath "github.com/oxygene76/medasdigital-client/pkg/astronomy/math"
@@ -33,6 +35,11 @@ type SearchResult struct {
     Parameters      SearchParameters
     ETNOEffects     []ETNOEffect
     ClusteringScore float64
+    Energy          nbody.EnergyReport
+    Catalog         string        `json:",omitempty"` // version of the ETNO catalog searched with
+    Epoch           float64       `json:",omitempty"` // JD the ETNOs were propagated to
+    Frame           orbital.Frame `json:",omitempty"` // of the ETNO elements and the clustering score
+    Clones          *CloneSummary `json:",omitempty"` // spread over the ETNO uncertainties
 }
 
 type ETNOEffect struct {
@@ -42,11 +49,62 @@ type ETNOEffect struct {
     PerihelionShift   float64
     InclinationChange float64
     LongPeriChange    float64  // Change in longitude of perihelion
+
+    Kozai     *KozaiInfo            `json:",omitempty"` // with RunOpts.Kozai
+    Resonance *itypes.ResonanceInfo `json:",omitempty"` // with RunOpts.Resonance
 }
 
 type RunOpts struct {
-    SnapshotEveryKyr float64 // 0 = aus
-    SnapshotFile     string  // JSONL Pfad
+    SnapshotEveryKyr float64       // 0 = aus
+    SnapshotFile     string        // JSONL Pfad
+    Quiet            bool          // no step size, monitor and ETNO warnings, for sweeps
+    Integrator       string        // leapfrog (default), whfast, ias15 or rk4
+    Kozai            bool          // Kozai-Lidov diagnostics per ETNO
+    Resonance        bool          // mean-motion resonance diagnostics per ETNO
+    Frame            orbital.Frame // of the ETNO elements in the result, default heliocentric-ecliptic
+}
+
+// planetStepDays returns 1/substeps of the shortest planetary period around
+// the heaviest body. ChooseStepForSystem also counts the barycentric wobble of
+// the Sun itself, which is what keeps the leapfrog step small.
+func planetStepDays(system *nbody.System, substeps int) float64 {
+    central := 0
+    for i, b := range system.Bodies {
+        if b.Mass > system.Bodies[central].Mass {
+            central = i
+        }
+    }
+    minPeriodYears := math.Inf(1)
+    for i, b := range system.Bodies {
+        if i == central || b.Mass <= 0 {
+            continue
+        }
+        r := b.Position.Distance(system.Bodies[central].Position)
+        minPeriodYears = math.Min(minPeriodYears, nbody.KeplerPeriodYears(r))
+    }
+    if math.IsInf(minPeriodYears, 1) {
+        return 365.25
+    }
+    return minPeriodYears * 365.25 / float64(substeps)
+}
+
+// Integrators lists the names NewIntegrator accepts
+var Integrators = []string{"leapfrog", "whfast", "ias15", "rk4"}
+
+// NewIntegrator creates a fresh integrator by name, empty is leapfrog
+func NewIntegrator(name string) (nbody.Integrator, error) {
+    switch name {
+    case "", "leapfrog":
+        return nbody.Leapfrog{}, nil
+    case "whfast":
+        return orbital.NewWHFast(), nil
+    case "ias15":
+        return nbody.NewIAS15(), nil
+    case "rk4":
+        return nbody.RK4{}, nil
+    default:
+        return nil, fmt.Errorf("unknown integrator %q (%s)", name, strings.Join(Integrators, ", "))
+    }
 }
 
 // GetPresetParameters returns parameters for known presets
@@ -119,8 +177,8 @@ func RunSimulation(params SearchParameters, etnos []orbital.OrbitalElements,
     system.Bodies = append(system.Bodies, nbody.Body{
         ID:       "Sun",
         Mass:     1.0,  // Solar masses
-        Position: astromath.Vector3{0, 0, 0},
-        Velocity: astromath.Vector3{0, 0, 0},
+        Position: astromath.Vector3{},
+        Velocity: astromath.Vector3{},
     })
     
     
@@ -165,21 +223,38 @@ func RunSimulation(params SearchParameters, etnos []orbital.OrbitalElements,
 
   system.RecenterToBarycenter()
 
+    integrator, err := NewIntegrator(opts.Integrator)
+    if err != nil {
+        fmt.Printf("⚠ %v, using leapfrog\n", err)
+        integrator = nbody.Leapfrog{}
+    }
+    system.Integrator = integrator
+
+    // Wisdom-Holman solves the Kepler motion exactly and IAS15 adapts its own
+    // substeps, both only need a fraction of Jupiter's period
     dtDays := system.ChooseStepForSystem(5000, 0.5, 2.0)
-    fmt.Printf("dt = %.2f days (~%.3f yr)\n", dtDays, dtDays/365.25)
+    if name := integrator.Name(); name == "whfast" || name == "ias15" {
+        dtDays = planetStepDays(system, 50)
+    }
+    if !opts.Quiet {
+        fmt.Printf("dt = %.2f days (~%.3f yr), integrator %s\n", dtDays, dtDays/365.25, integrator.Name())
+    }
 
     durationDays := durationYears * 365.25
 
     // Snapshot-Kadenz: kyr → days
     snapshotEveryDays := 0.0
     var sink nbody.SnapshotSink
+    var snapWriter *nbody.JSONLSnapshotWriter
+    snapPath := opts.SnapshotFile
+    if snapPath == "" { snapPath = "snapshots.jsonl" }
     if opts.SnapshotEveryKyr > 0 {
         snapshotEveryDays = opts.SnapshotEveryKyr * 1000.0 * 365.25
-        path := opts.SnapshotFile
-        if path == "" { path = "snapshots.jsonl" }
-        if w, err := nbody.NewJSONLSnapshotWriter(path); err == nil {
-            sink = w
-            fmt.Printf("Snapshot stream → %s (every %.1f kyr)\n", path, opts.SnapshotEveryKyr)
+        if w, err := nbody.NewJSONLSnapshotWriter(snapPath); err == nil {
+            sink, snapWriter = w, w
+            if !opts.Quiet {
+                fmt.Printf("Snapshot stream → %s (every %g kyr)\n", snapPath, opts.SnapshotEveryKyr)
+            }
         } else {
             fmt.Printf("⚠ snapshot sink failed (%v), continuing without disk snapshots\n", err)
         }
@@ -189,7 +264,27 @@ func RunSimulation(params SearchParameters, etnos []orbital.OrbitalElements,
     etnoStart := 6
     etnoCount := len(etnos)
     monitorEveryDays := 10000.0 * 365.25
-    monitor := makeRayleighMonitor(etnoStart, etnoCount, muYear)
+    var monitor nbody.MonitorFunc
+    if !opts.Quiet {
+        monitor = makeRayleighMonitor(etnoStart, etnoCount, opts.Frame)
+    }
+
+    // Kozai/Resonanz: Bahnelemente in feiner Kadenz mitschreiben, der
+    // Rayleigh-Monitor läuft weiter alle 10 kyr
+    var tracker *elementTracker
+    if opts.Kozai || opts.Resonance {
+        tracker = newElementTracker(1, etnoStart, etnoCount, opts.Frame)
+        tracker.sample(0, system)
+        rayleigh, nextRayleigh := monitor, monitorEveryDays
+        monitorEveryDays = tracker.cadenceDays(system, durationDays)
+        monitor = func(step int, tDays float64, energyDrift float64, sys *nbody.System) {
+            tracker.sample(tDays, sys)
+            if rayleigh != nil && tDays >= nextRayleigh {
+                rayleigh(step, tDays, energyDrift, sys)
+                nextRayleigh += 10000.0 * 365.25
+            }
+        }
+    }
 
     // Nur Start/Ende im RAM behalten (OOM-sicher)
     var firstSnap, lastSnap nbody.Snapshot
@@ -205,10 +300,16 @@ func RunSimulation(params SearchParameters, etnos []orbital.OrbitalElements,
     ); err != nil {
         fmt.Printf("integration error: %v\n", err)
     }
+    if snapWriter != nil && !opts.Quiet {
+        fmt.Printf("Snapshots: %d written to %s\n", snapWriter.Count(), snapPath)
+    }
 
     // Analyse aus 2 Snapshots
-    result := SearchResult{Parameters: params}
-    result.ETNOEffects = analyzeETNOChangesFromTwo(&firstSnap, &lastSnap, etnos)
+    result := SearchResult{Parameters: params, Energy: system.Energy, Frame: opts.Frame.OrDefault()}
+    result.ETNOEffects = analyzeETNOChangesFromTwo(&firstSnap, &lastSnap, etnos, opts.Frame, opts.Quiet)
+    if tracker != nil {
+        tracker.apply(result.ETNOEffects, opts.Kozai, opts.Resonance)
+    }
     result.ClusteringScore = calculateClustering(result.ETNOEffects)
     return result
 
@@ -395,18 +496,16 @@ func addOuterPlanets(system *nbody.System) {
         })
     }
 }
-func makeRayleighMonitor(etnoStart, etnoCount int, muYear float64) func(step int, tDays float64, energyDrift float64, s *nbody.System) {
+func makeRayleighMonitor(etnoStart, etnoCount int, frame orbital.Frame) func(step int, tDays float64, energyDrift float64, s *nbody.System) {
     return func(step int, tDays float64, energyDrift float64, sys *nbody.System) {
         if len(sys.Bodies) == 0 { return }
-        sun := sys.Bodies[0] // baryzentrische Sonne
 
         longs := make([]float64, 0, etnoCount)
         for k := 0; k < etnoCount && etnoStart+k < len(sys.Bodies); k++ {
             b := sys.Bodies[etnoStart+k]
             if b.Position.IsZero() { continue }
 
-            r, vYr := heliocentricState(b, sun)             // <<< neu: helio
-            oe := orbital.CartesianToOrbital(r, vYr, muYear) // GM☉
+            oe := frameElements(sys.Bodies, etnoStart+k, frame)
             if oe.Eccentricity >= 1.0 || oe.SemiMajorAxis <= 0 { continue }
 
             L := oe.LongitudeAscendingNode + oe.ArgumentPerihelion
@@ -431,18 +530,13 @@ func makeRayleighMonitor(etnoStart, etnoCount int, muYear float64) func(step int
 
 // analyzeETNOChangesFromTwo: wertet nur ersten/letzten Snapshot aus (RAM-schonend)
 // NEU: nutzt heliocentrische Vektoren (relativ zur Sonne) und etnoStart := 6
-func analyzeETNOChangesFromTwo(first, last *nbody.Snapshot, initialETNOs []orbital.OrbitalElements) []ETNOEffect {
+func analyzeETNOChangesFromTwo(first, last *nbody.Snapshot, initialETNOs []orbital.OrbitalElements, frame orbital.Frame, quiet bool) []ETNOEffect {
     if first == nil || last == nil || len(first.Bodies) == 0 || len(last.Bodies) == 0 {
         return nil
     }
 
     effects := make([]ETNOEffect, 0, len(initialETNOs))
     const etnoStart = 6 // Sun(0), P9(1), Jupiter(2), Saturn(3), Uranus(4), Neptune(5) -> ETNOs ab 6
-    muYear := 4 * math.Pi * math.Pi // AU^3/(M☉·yr^2)
-
-    // NEU: Sonne aus erstem/letztem Snapshot holen (heliocentrischer Bezug)
-    sun0 := first.Bodies[0]
-    sun1 := last.Bodies[0]
 
     for i := 0; i < len(initialETNOs) && etnoStart+i < len(first.Bodies) && etnoStart+i < len(last.Bodies); i++ {
         bi := first.Bodies[etnoStart+i]
@@ -451,27 +545,21 @@ func analyzeETNOChangesFromTwo(first, last *nbody.Snapshot, initialETNOs []orbit
             continue
         }
 
-        // NEU: heliocentrische Vektoren bilden (r = r_body - r_sun, v = v_body - v_sun)
-        r0 := bi.Position.Sub(sun0.Position)
-        v0Day := bi.Velocity.Sub(sun0.Velocity)
-        r1 := bf.Position.Sub(sun1.Position)
-        v1Day := bf.Velocity.Sub(sun1.Velocity)
-
-        // AU/day -> AU/year für Bahnelemente
-        v0 := v0Day.Scale(365.25)
-        v1 := v1Day.Scale(365.25)
-
-        // Kartesisch -> Bahnelemente
-        initOE := orbital.CartesianToOrbital(r0, v0, muYear)
-        finlOE := orbital.CartesianToOrbital(r1, v1, muYear)
+        // Bahnelemente im gewählten Bezugssystem
+        initOE := frameElements(first.Bodies, etnoStart+i, frame)
+        finlOE := frameElements(last.Bodies, etnoStart+i, frame)
 
         // Plausibilitätschecks
         if finlOE.Eccentricity >= 1.0 || finlOE.Eccentricity < 0 {
-            fmt.Printf("Warning: ETNO_%d bad eccentricity: %.3f\n", i, finlOE.Eccentricity)
+            if !quiet {
+                fmt.Printf("Warning: ETNO_%d bad eccentricity: %.3f\n", i, finlOE.Eccentricity)
+            }
             continue
         }
         if finlOE.SemiMajorAxis <= 0 || finlOE.SemiMajorAxis > 10000 {
-            fmt.Printf("Warning: ETNO_%d bad semi-major axis: %.1f\n", i, finlOE.SemiMajorAxis)
+            if !quiet {
+                fmt.Printf("Warning: ETNO_%d bad semi-major axis: %.1f\n", i, finlOE.SemiMajorAxis)
+            }
             continue
         }
 
@@ -480,7 +568,9 @@ func analyzeETNOChangesFromTwo(first, last *nbody.Snapshot, initialETNOs []orbit
         q1 := finlOE.SemiMajorAxis * (1 - finlOE.Eccentricity)
         dq := q1 - q0
         if math.Abs(dq) > 10 { // konservative Grenze
-            fmt.Printf("Warning: ETNO_%d unrealistic perihelion shift: %.1f AU\n", i, dq)
+            if !quiet {
+                fmt.Printf("Warning: ETNO_%d unrealistic perihelion shift: %.1f AU\n", i, dq)
+            }
             continue
         }
 
@@ -496,9 +586,16 @@ func analyzeETNOChangesFromTwo(first, last *nbody.Snapshot, initialETNOs []orbit
         }
         dLongPeri -= math.Pi
 
+        // Eingangsdaten sind heliozentrisch-ekliptikal, sonst die Startelemente im Bezugssystem
+        initial := initialETNOs[i]
+        if frame.OrDefault() != orbital.HeliocentricEcliptic {
+            initOE.Epoch = initial.Epoch
+            initial = initOE
+        }
+
         effects = append(effects, ETNOEffect{
             ObjectID:          fmt.Sprintf("ETNO_%d", i),
-            InitialElements:   initialETNOs[i], // deine Eingangsdaten (heliocentrisch, bereits in rad)
+            InitialElements:   initial,
             FinalElements:     finlOE,
             PerihelionShift:   dq,
             InclinationChange: diDeg,
@@ -514,3 +611,33 @@ func heliocentricState(body, sun nbody.Body) (r astromath.Vector3, vYr astromath
     vYr = vDay.Scale(365.25) // AU/day -> AU/year
     return
 }
+
+// frameState returns position (AU) and velocity (AU/yr) of body i in frame:
+// relative to the Sun or to the barycenter of the Sun and the giant planets,
+// which leaves Planet 9 out like the barycentric elements of catalogs do
+func frameState(bodies []nbody.Body, i int, frame orbital.Frame) (astromath.Vector3, astromath.Vector3) {
+    ref := bodies[0]
+    if frame.Barycentric() && len(bodies) >= 6 {
+        ref = nbody.Body{}
+        mass := 0.0
+        for _, k := range []int{0, 2, 3, 4, 5} {
+            b := bodies[k]
+            ref.Position = ref.Position.Add(b.Position.Scale(b.Mass))
+            ref.Velocity = ref.Velocity.Add(b.Velocity.Scale(b.Mass))
+            mass += b.Mass
+        }
+        ref.Position = ref.Position.Scale(1 / mass)
+        ref.Velocity = ref.Velocity.Scale(1 / mass)
+    }
+    r, v := heliocentricState(bodies[i], ref)
+    if frame.Equatorial() {
+        r, v = orbital.EclipticToEquatorial(r), orbital.EclipticToEquatorial(v)
+    }
+    return r, v
+}
+
+// frameElements returns the elements of body i in frame
+func frameElements(bodies []nbody.Body, i int, frame orbital.Frame) orbital.OrbitalElements {
+    r, v := frameState(bodies, i, frame)
+    return orbital.CartesianToOrbital(r, v, frame.Mu())
+}
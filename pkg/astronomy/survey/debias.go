@@ -0,0 +1,118 @@
+package survey
+
+import (
+    "math"
+    "math/rand"
+    "sort"
+
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/orbital"
+    "gonum.org/v1/gonum/stat/distuv"
+)
+
+// DefaultTrials is the number of synthetic samples of a Null
+const DefaultTrials = 2000
+
+// DefaultH is taken for objects without an absolute magnitude, typical of
+// the extreme TNOs
+const DefaultH = 6.0
+
+const (
+    poolSize     = 256    // detectable orientations drawn per object
+    poolAttempts = 200000 // orientations tried per object
+)
+
+// ClusteringStatistic measures the clustering of the longitudes of
+// perihelion and of the nodes as the sum of their Rayleigh statistics
+// n·R², each about exponential with mean 1 for uniform angles
+func ClusteringStatistic(elements []orbital.OrbitalElements) float64 {
+    varpi := make([]float64, len(elements))
+    node := make([]float64, len(elements))
+    for i, o := range elements {
+        o.EnsureRadians()
+        varpi[i] = o.LongitudeAscendingNode + o.ArgumentPerihelion
+        node[i] = o.LongitudeAscendingNode
+    }
+    return rayleigh(varpi) + rayleigh(node)
+}
+
+func rayleigh(angles []float64) float64 {
+    if len(angles) == 0 {
+        return 0
+    }
+    var c, s float64
+    for _, a := range angles {
+        c += math.Cos(a)
+        s += math.Sin(a)
+    }
+    return (c*c + s*s) / float64(len(angles))
+}
+
+// Null is the distribution of the clustering statistic of objects with the
+// observed a, e, i and H but random orientations, kept only where the
+// surveys would have found them. Comparing the observed statistic to it
+// removes the clustering the survey pointings alone produce.
+type Null struct {
+    Stats        []float64 // sorted
+    Undetectable int       // objects no orientation made detectable, taken as unbiased
+}
+
+// NewNull simulates trials samples of the objects under the selection
+// function. h holds the absolute magnitudes, 0 for unknown.
+func NewNull(sel SelectionFunction, elements []orbital.OrbitalElements, h []float64, trials int, seed int64) *Null {
+    if trials <= 0 {
+        trials = DefaultTrials
+    }
+    rng := rand.New(rand.NewSource(seed))
+    n := &Null{}
+
+    pools := make([][][2]float64, len(elements)) // ϖ and Ω of the detectable orientations
+    for i, o := range elements {
+        o.EnsureRadians()
+        hi := DefaultH
+        if i < len(h) && h[i] != 0 {
+            hi = h[i]
+        }
+        for attempt := 0; attempt < poolAttempts && len(pools[i]) < poolSize; attempt++ {
+            o.LongitudeAscendingNode = rng.Float64() * 2 * math.Pi
+            o.ArgumentPerihelion = rng.Float64() * 2 * math.Pi
+            o.MeanAnomaly = rng.Float64() * 2 * math.Pi
+            pos, _ := o.ToCartesian(4 * math.Pi * math.Pi)
+            if sel.Detectable(pos, hi) {
+                pools[i] = append(pools[i], [2]float64{o.LongitudeAscendingNode + o.ArgumentPerihelion, o.LongitudeAscendingNode})
+            }
+        }
+        if len(pools[i]) == 0 {
+            n.Undetectable++
+        }
+    }
+
+    sample := make([]orbital.OrbitalElements, len(elements))
+    n.Stats = make([]float64, trials)
+    for t := range n.Stats {
+        for i, pool := range pools {
+            var varpi, node float64
+            if len(pool) == 0 {
+                varpi, node = rng.Float64()*2*math.Pi, rng.Float64()*2*math.Pi
+            } else {
+                p := pool[rng.Intn(len(pool))]
+                varpi, node = p[0], p[1]
+            }
+            sample[i] = orbital.OrbitalElements{LongitudeAscendingNode: node, ArgumentPerihelion: varpi - node}
+        }
+        n.Stats[t] = ClusteringStatistic(sample)
+    }
+    sort.Float64s(n.Stats)
+    return n
+}
+
+// PValue is the fraction of the synthetic samples clustered at least as
+// strongly as stat, never below 1/(trials+1)
+func (n *Null) PValue(stat float64) float64 {
+    above := len(n.Stats) - sort.SearchFloat64s(n.Stats, stat)
+    return float64(above+1) / float64(len(n.Stats)+1)
+}
+
+// Significance converts the p-value of stat to one-sided Gaussian σ
+func (n *Null) Significance(stat float64) float64 {
+    return math.Max(0, distuv.UnitNormal.Quantile(1-n.PValue(stat)))
+}
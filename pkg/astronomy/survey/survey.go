@@ -0,0 +1,138 @@
+package survey
+
+import (
+    "encoding/json"
+    "fmt"
+    "math"
+    "os"
+
+    astromath "github.com/oxygene76/medasdigital-client/pkg/astronomy/math"
+    "github.com/oxygene76/medasdigital-client/pkg/astronomy/orbital"
+)
+
+// Footprint is the part of the sky a survey covered and the faintest
+// magnitude it detected there
+type Footprint struct {
+    Name                string  `json:"name"`
+    RAMin               float64 `json:"ra_min"` // degrees, RAMin > RAMax wraps through 0
+    RAMax               float64 `json:"ra_max"`
+    DecMin              float64 `json:"dec_min"` // degrees
+    DecMax              float64 `json:"dec_max"`
+    MaxEclipticLatitude float64 `json:"max_ecliptic_latitude,omitempty"` // degrees, 0 = any
+    LimitingMagnitude   float64 `json:"limiting_magnitude"`              // V
+}
+
+// SelectionFunction is the set of surveys the objects were found in. An
+// object is detectable where it is inside a footprint and brighter than its
+// limiting magnitude.
+type SelectionFunction []Footprint
+
+// Default approximates the wide surveys most distant TNOs come from
+func Default() SelectionFunction {
+    return SelectionFunction{
+        {Name: "Pan-STARRS1 3pi", RAMin: 0, RAMax: 360, DecMin: -30, DecMax: 90, LimitingMagnitude: 21.5},
+        {Name: "DES wide", RAMin: 300, RAMax: 100, DecMin: -65, DecMax: 5, LimitingMagnitude: 23.8},
+    }
+}
+
+// Load reads a selection function from a JSON list of footprints
+func Load(path string) (SelectionFunction, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read survey footprints: %w", err)
+    }
+    var s SelectionFunction
+    if err := json.Unmarshal(data, &s); err != nil {
+        return nil, fmt.Errorf("failed to parse survey footprints %s: %w", path, err)
+    }
+    if err := s.Validate(); err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    return s, nil
+}
+
+// ParseSelection reads the value of a --surveys flag: default, none (no
+// selection, nil) or a JSON file of footprints
+func ParseSelection(s string) (SelectionFunction, error) {
+    switch s {
+    case "none":
+        return nil, nil
+    case "", "default":
+        return Default(), nil
+    }
+    return Load(s)
+}
+
+// Validate checks the footprints
+func (s SelectionFunction) Validate() error {
+    if len(s) == 0 {
+        return fmt.Errorf("no survey footprints")
+    }
+    for i, f := range s {
+        name := f.Name
+        if name == "" {
+            name = fmt.Sprintf("footprint %d", i)
+        }
+        switch {
+        case f.RAMin < 0 || f.RAMin > 360 || f.RAMax < 0 || f.RAMax > 360:
+            return fmt.Errorf("%s: right ascension must be within 0-360", name)
+        case f.DecMin < -90 || f.DecMax > 90 || f.DecMin >= f.DecMax:
+            return fmt.Errorf("%s: declination must be an increasing range within -90-90", name)
+        case f.MaxEclipticLatitude < 0 || f.MaxEclipticLatitude > 90:
+            return fmt.Errorf("%s: max ecliptic latitude must be within 0-90", name)
+        case f.LimitingMagnitude <= 0:
+            return fmt.Errorf("%s: limiting magnitude is required", name)
+        }
+    }
+    return nil
+}
+
+// Names lists the surveys
+func (s SelectionFunction) Names() []string {
+    names := make([]string, len(s))
+    for i, f := range s {
+        names[i] = f.Name
+    }
+    return names
+}
+
+// Contains reports whether the footprint covers ra, dec and the ecliptic
+// latitude beta, all in degrees
+func (f Footprint) Contains(ra, dec, beta float64) bool {
+    if dec < f.DecMin || dec > f.DecMax {
+        return false
+    }
+    if f.MaxEclipticLatitude > 0 && math.Abs(beta) > f.MaxEclipticLatitude {
+        return false
+    }
+    if f.RAMin <= f.RAMax {
+        return ra >= f.RAMin && ra <= f.RAMax
+    }
+    return ra >= f.RAMin || ra <= f.RAMax
+}
+
+// Magnitude is the V magnitude at opposition of an object with absolute
+// magnitude h at heliocentric distance r (AU), where the surveys observe
+func Magnitude(h, r float64) float64 {
+    delta := math.Max(r-1, 1e-3)
+    return h + 5*math.Log10(r*delta)
+}
+
+// Detectable reports whether an object with absolute magnitude h at the
+// heliocentric ecliptic position pos (AU) is found by any survey. Distant
+// objects are seen from Earth in their heliocentric direction.
+func (s SelectionFunction) Detectable(pos astromath.Vector3, h float64) bool {
+    r := pos.Magnitude()
+    if r == 0 {
+        return false
+    }
+    v := Magnitude(h, r)
+    ra, dec := orbital.RADec(pos)
+    beta := math.Asin(pos.Z/r) * 180 / math.Pi
+    for _, f := range s {
+        if v <= f.LimitingMagnitude && f.Contains(ra, dec, beta) {
+            return true
+        }
+    }
+    return false
+}
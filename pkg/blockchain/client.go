@@ -2,7 +2,10 @@ package blockchain
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
+	"math"
+	"math/big"
 	"time"
 
 	"github.com/cosmos/cosmos-sdk/client"
@@ -13,9 +16,13 @@ import (
 	comethttp "github.com/cometbft/cometbft/rpc/client/http"
 	comet "github.com/cometbft/cometbft/rpc/core/types"
 	abci "github.com/cometbft/cometbft/abci/types"
+	cmtbytes "github.com/cometbft/cometbft/libs/bytes"
+	cmttypes "github.com/cometbft/cometbft/types"
 	"github.com/cosmos/cosmos-sdk/codec/types"
 
 	itypes "github.com/oxygene76/medasdigital-client/internal/types"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
 )
 
 // Client handles blockchain communication for MedasDigital
@@ -58,9 +65,9 @@ func NewClient(clientCtx client.Context) *Client {
 }
 
 // RegisterClient registers a new analysis client on the blockchain
-func (c *Client) RegisterClient(creator string, capabilities []string, metadata map[string]interface{}) (string, error) {
+func (c *Client) RegisterClient(ctx context.Context, creator string, capabilities []string, metadata map[string]interface{}) (string, error) {
 	// Convert metadata to JSON
-	metadataBytes, err := c.codec.MarshalJSON(metadata)
+	metadataBytes, err := c.codec.ToJSON(metadata)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal metadata: %w", err)
 	}
@@ -73,7 +80,7 @@ func (c *Client) RegisterClient(creator string, capabilities []string, metadata
 	}
 
 	// Send transaction
-	res, err := c.sendTransaction(msg, creator)
+	res, err := c.sendTransaction(ctx, msg, creator)
 	if err != nil {
 		return "", fmt.Errorf("failed to register client: %w", err)
 	}
@@ -88,7 +95,7 @@ func (c *Client) RegisterClient(creator string, capabilities []string, metadata
 }
 
 // StoreAnalysisResult stores analysis results on the blockchain
-func (c *Client) StoreAnalysisResult(creator, clientID, analysisType string, data []byte, height int64, txHash string) error {
+func (c *Client) StoreAnalysisResult(ctx context.Context, creator, clientID, analysisType string, data []byte, height int64, txHash string) error {
 	// Create analysis storage message
 	msg := &MsgStoreAnalysis{
 		Creator:      creator,
@@ -100,7 +107,7 @@ func (c *Client) StoreAnalysisResult(creator, clientID, analysisType string, dat
 	}
 
 	// Send transaction
-	_, err := c.sendTransaction(msg, creator)
+	_, err := c.sendTransaction(ctx, msg, creator)
 	if err != nil {
 		return fmt.Errorf("failed to store analysis result: %w", err)
 	}
@@ -109,9 +116,9 @@ func (c *Client) StoreAnalysisResult(creator, clientID, analysisType string, dat
 }
 
 // UpdateClient updates client information
-func (c *Client) UpdateClient(creator, clientID string, capabilities []string, metadata map[string]interface{}) error {
+func (c *Client) UpdateClient(ctx context.Context, creator, clientID string, capabilities []string, metadata map[string]interface{}) error {
 	// Convert metadata to JSON
-	metadataBytes, err := c.codec.MarshalJSON(metadata)
+	metadataBytes, err := c.codec.ToJSON(metadata)
 	if err != nil {
 		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
@@ -125,7 +132,7 @@ func (c *Client) UpdateClient(creator, clientID string, capabilities []string, m
 	}
 
 	// Send transaction
-	_, err = c.sendTransaction(msg, creator)
+	_, err = c.sendTransaction(ctx, msg, creator)
 	if err != nil {
 		return fmt.Errorf("failed to update client: %w", err)
 	}
@@ -134,7 +141,7 @@ func (c *Client) UpdateClient(creator, clientID string, capabilities []string, m
 }
 
 // DeactivateClient deactivates a client
-func (c *Client) DeactivateClient(creator, clientID string) error {
+func (c *Client) DeactivateClient(ctx context.Context, creator, clientID string) error {
 	// Create deactivation message
 	msg := &MsgDeactivateClient{
 		Creator:  creator,
@@ -142,7 +149,7 @@ func (c *Client) DeactivateClient(creator, clientID string) error {
 	}
 
 	// Send transaction
-	_, err := c.sendTransaction(msg, creator)
+	_, err := c.sendTransaction(ctx, msg, creator)
 	if err != nil {
 		return fmt.Errorf("failed to deactivate client: %w", err)
 	}
@@ -151,7 +158,7 @@ func (c *Client) DeactivateClient(creator, clientID string) error {
 }
 
 // sendTransaction signs and broadcasts a transaction
-func (c *Client) sendTransaction(msg sdk.Msg, signerName string) (*sdk.TxResponse, error) {
+func (c *Client) sendTransaction(ctx context.Context, msg sdk.Msg, signerName string) (*sdk.TxResponse, error) {
 	// Create transaction builder
 	txBuilder := c.clientCtx.TxConfig.NewTxBuilder()
 	if err := txBuilder.SetMsgs(msg); err != nil {
@@ -159,7 +166,7 @@ func (c *Client) sendTransaction(msg sdk.Msg, signerName string) (*sdk.TxRespons
 	}
 
 	// Estimate gas
-	gasLimit, err := c.estimateGas([]sdk.Msg{msg})
+	gasLimit, err := c.estimateGas(ctx, []sdk.Msg{msg})
 	if err != nil {
 		return nil, fmt.Errorf("failed to estimate gas: %w", err)
 	}
@@ -169,9 +176,12 @@ func (c *Client) sendTransaction(msg sdk.Msg, signerName string) (*sdk.TxRespons
 
 	// Set fee (optional - can be calculated from gas price)
 	// For now, we'll let the node calculate the fee
+	txBuilder.SetFeeGranter(c.clientCtx.FeeGranter)
 
 	// Sign transaction - FIXED: Added context parameter for v0.50
-	err = tx.Sign(context.Background(), c.txFactory, signerName, txBuilder, true)
+	stopTimer := telemetry.Track(telemetry.CategorySigning, "sign_tx")
+	err = tx.Sign(ctx, c.txFactory, signerName, txBuilder, true)
+	stopTimer()
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign transaction: %w", err)
 	}
@@ -183,7 +193,9 @@ func (c *Client) sendTransaction(msg sdk.Msg, signerName string) (*sdk.TxRespons
 	}
 
 	// Broadcast transaction
-	res, err := c.clientCtx.BroadcastTx(txBytes)
+	stopTimer = telemetry.Track(telemetry.CategoryRPC, "broadcast_tx")
+	res, err := BroadcastContext(ctx, c.clientCtx, txBytes)
+	stopTimer()
 	if err != nil {
 		return nil, fmt.Errorf("failed to broadcast transaction: %w", err)
 	}
@@ -195,7 +207,11 @@ func (c *Client) sendTransaction(msg sdk.Msg, signerName string) (*sdk.TxRespons
 // Ersetzen Sie die estimateGas Funktion in pkg/blockchain/client.go:
 
 // estimateGas estimates gas for a transaction - clean version without fallback
-func (c *Client) estimateGas(msgs []sdk.Msg) (uint64, error) {
+func (c *Client) estimateGas(ctx context.Context, msgs []sdk.Msg) (uint64, error) {
+	// tx.CalculateGas simulates without a context, check it at least before
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 	fmt.Println("🔧 Starting gas estimation...")
 	
 	// ✅ DEBUG: Keyring-Informationen anzeigen
@@ -242,9 +258,11 @@ func (c *Client) estimateGas(msgs []sdk.Msg) (uint64, error) {
 	fmt.Println("🔧 Calculating gas with proper keyring...")
 	
 	// Calculate gas - if this fails, return the error
+	stopTimer := telemetry.Track(telemetry.CategoryRPC, "simulate_tx")
 	simRes, adjustedGas, err := tx.CalculateGas(simClientCtx, simFactory, msgs...)
+	stopTimer()
 	if err != nil {
-		return 0, fmt.Errorf("gas calculation failed: %w", err)
+		return 0, fmt.Errorf("gas calculation failed: %w", SimulationError(err))
 	}
 
 	fmt.Printf("✅ Gas estimation successful: %d\n", adjustedGas)
@@ -259,7 +277,7 @@ func (c *Client) estimateGas(msgs []sdk.Msg) (uint64, error) {
 
 // ENTFERNEN Sie die estimateGasFallback Funktion komplett
 // GetClient retrieves client information
-func (c *Client) GetClient(clientID string) (*itypes.RegisteredClient, error) {
+func (c *Client) GetClient(ctx context.Context, clientID string) (*itypes.RegisteredClient, error) {
 	// Query client information from blockchain
 	queryPath := fmt.Sprintf("/medas.client.v1.Query/Client")
 	
@@ -267,41 +285,27 @@ func (c *Client) GetClient(clientID string) (*itypes.RegisteredClient, error) {
 	reqBytes := []byte(fmt.Sprintf(`{"client_id":"%s"}`, clientID))
 	
 	// Execute query
-	res, _, err := c.clientCtx.QueryWithData(queryPath, reqBytes)
+	res, _, err := QueryContext(ctx, c.clientCtx, queryPath, reqBytes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query client: %w", err)
 	}
 
 	// Parse response
 	var client itypes.RegisteredClient
-	if err := c.codec.UnmarshalJSON(res, &client); err != nil {
+	if err := c.codec.FromJSON(res, &client); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal client: %w", err)
 	}
 
 	return &client, nil
 }
 
-// GetAnalysisResults retrieves analysis results
-func (c *Client) GetAnalysisResults(clientID string, limit int) ([]*itypes.StoredAnalysis, error) {
-	// Query analysis results from blockchain
-	queryPath := fmt.Sprintf("/medas.analysis.v1.Query/AnalysisResults")
-	
-	// Create query request
-	reqBytes := []byte(fmt.Sprintf(`{"client_id":"%s","limit":%d}`, clientID, limit))
-	
-	// Execute query
-	res, _, err := c.clientCtx.QueryWithData(queryPath, reqBytes)
+// GetAnalysisResults retrieves the newest analysis results of a client
+func (c *Client) GetAnalysisResults(ctx context.Context, clientID string, limit int) ([]*itypes.StoredAnalysis, error) {
+	page, err := c.QueryAnalysisResults(ctx, ResultsQuery{ClientID: clientID, Limit: limit})
 	if err != nil {
-		return nil, fmt.Errorf("failed to query analysis results: %w", err)
-	}
-
-	// Parse response
-	var results []*itypes.StoredAnalysis
-	if err := c.codec.UnmarshalJSON(res, &results); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal results: %w", err)
+		return nil, err
 	}
-
-	return results, nil
+	return page.Results, nil
 }
 
 // extractClientIDFromEvents extracts client ID from transaction events
@@ -319,7 +323,7 @@ func (c *Client) extractClientIDFromEvents(events []abci.Event) (string, error)
 }
 
 // StartEventMonitoring starts monitoring blockchain events
-func (c *Client) StartEventMonitoring() error {
+func (c *Client) StartEventMonitoring(ctx context.Context) error {
 	if c.monitoring {
 		return fmt.Errorf("event monitoring already started")
 	}
@@ -328,7 +332,7 @@ func (c *Client) StartEventMonitoring() error {
 	if httpClient, ok := c.clientCtx.Client.(*comethttp.HTTP); ok {
 		// Subscribe to events - FIXED: Handle 2 return values
 		query := "tm.event='NewBlock'"
-		_, err := httpClient.Subscribe(context.Background(), "medas-client", query, 100)
+		_, err := httpClient.Subscribe(ctx, "medas-client", query, 100)
 		if err != nil {
 			return fmt.Errorf("failed to subscribe to events: %w", err)
 		}
@@ -341,7 +345,7 @@ func (c *Client) StartEventMonitoring() error {
 }
 
 // StopEventMonitoring stops monitoring blockchain events
-func (c *Client) StopEventMonitoring() error {
+func (c *Client) StopEventMonitoring(ctx context.Context) error {
 	if !c.monitoring {
 		return fmt.Errorf("event monitoring not started")
 	}
@@ -349,7 +353,7 @@ func (c *Client) StopEventMonitoring() error {
 	// Cast client to CometBFT HTTP client for event unsubscription
 	if httpClient, ok := c.clientCtx.Client.(*comethttp.HTTP); ok {
 		query := "tm.event='NewBlock'"
-		err := httpClient.Unsubscribe(context.Background(), "medas-client", query)
+		err := httpClient.Unsubscribe(ctx, "medas-client", query)
 		if err != nil {
 			return fmt.Errorf("failed to unsubscribe from events: %w", err)
 		}
@@ -360,9 +364,10 @@ func (c *Client) StopEventMonitoring() error {
 }
 
 // GetChainStatus returns blockchain status information
-func (c *Client) GetChainStatus() (*ChainStatus, error) {
+func (c *Client) GetChainStatus(ctx context.Context) (*ChainStatus, error) {
 	// Get node status
-	status, err := c.clientCtx.Client.Status(context.Background())
+	defer telemetry.Track(telemetry.CategoryRPC, "chain_status")()
+	status, err := c.clientCtx.Client.Status(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get node status: %w", err)
 	}
@@ -370,7 +375,7 @@ func (c *Client) GetChainStatus() (*ChainStatus, error) {
 	// Get network info
 	var networkInfo *comet.ResultNetInfo
 	if httpClient, ok := c.clientCtx.Client.(*comethttp.HTTP); ok {
-		networkInfo, err = httpClient.NetInfo(context.Background())
+		networkInfo, err = httpClient.NetInfo(ctx)
 		if err != nil {
 			// Don't fail if network info is not available
 			networkInfo = nil
@@ -395,9 +400,10 @@ func (c *Client) GetChainStatus() (*ChainStatus, error) {
 }
 
 // GetLatestBlock returns the latest block information
-func (c *Client) GetLatestBlock() (*BlockInfo, error) {
+func (c *Client) GetLatestBlock(ctx context.Context) (*BlockInfo, error) {
 	// Get latest block
-	block, err := c.clientCtx.Client.Block(context.Background(), nil)
+	defer telemetry.Track(telemetry.CategoryRPC, "latest_block")()
+	block, err := c.clientCtx.Client.Block(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get latest block: %w", err)
 	}
@@ -414,9 +420,9 @@ func (c *Client) GetLatestBlock() (*BlockInfo, error) {
 }
 
 // Health checks the health of the blockchain connection
-func (c *Client) Health() error {
+func (c *Client) Health(ctx context.Context) error {
 	// Try to get node status
-	_, err := c.clientCtx.Client.Status(context.Background())
+	_, err := c.clientCtx.Client.Status(ctx)
 	if err != nil {
 		return fmt.Errorf("blockchain connection unhealthy: %w", err)
 	}
@@ -434,8 +440,12 @@ func (c *Client) GetTx(ctx context.Context, txHash string) (*txtypes.GetTxRespon
 	queryClient := txtypes.NewServiceClient(c.clientCtx)
 	
 	// Query transaction
+	defer telemetry.Track(telemetry.CategoryRPC, "get_tx")()
 	req := &txtypes.GetTxRequest{Hash: txHash}
 	resp, err := queryClient.GetTx(ctx, req)
+	if err != nil && txNotFound(err) {
+		return nil, fmt.Errorf("%w: %s", ErrTxNotFound, txHash)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to query transaction %s: %w", txHash, err)
 	}
@@ -446,6 +456,7 @@ func (c *Client) GetTx(ctx context.Context, txHash string) (*txtypes.GetTxRespon
 // GetStatus returns the current blockchain status (alias for existing method)
 func (c *Client) GetStatus(ctx context.Context) (*comet.ResultStatus, error) {
 	// Get status from CometBFT client
+	defer telemetry.Track(telemetry.CategoryRPC, "status")()
 	status, err := c.clientCtx.Client.Status(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get blockchain status: %w", err)
@@ -457,7 +468,8 @@ func (c *Client) GetStatus(ctx context.Context) (*comet.ResultStatus, error) {
 // QueryWithData performs a generic query with custom data (alias for existing method)
 func (c *Client) QueryWithData(ctx context.Context, path string, data []byte) ([]byte, int64, error) {
 	// Use the client context to perform the query
-	result, height, err := c.clientCtx.QueryWithData(path, data)
+	defer telemetry.Track(telemetry.CategoryRPC, "abci_query")()
+	result, height, err := QueryContext(ctx, c.clientCtx, path, data)
 	if err != nil {
 		return nil, 0, fmt.Errorf("query failed for path %s: %w", path, err)
 	}
@@ -466,84 +478,124 @@ func (c *Client) QueryWithData(ctx context.Context, path string, data []byte) ([
 }
 
 
+// DefaultPaymentTolerance is the relative difference to the expected amount
+// VerifyPaymentTransaction accepts, for rounding between display and base units
+const DefaultPaymentTolerance = 0.001
+
+// PaymentCheck is the outcome of comparing a payment transaction with the
+// expected amount. Amounts are in the display denom for the base denom and in
+// base units for other denoms.
+type PaymentCheck struct {
+    Verified  bool    `json:"verified"`
+    Expected  float64 `json:"expected"`
+    Received  float64 `json:"received"` // the matching transfer closest to Expected, 0 without one
+    Denom     string  `json:"denom"`
+    Tolerance float64 `json:"tolerance"` // relative, 0 requires the exact amount
+}
+
+// Underpaid reports whether a matching transfer was found but below the
+// accepted range
+func (p *PaymentCheck) Underpaid() bool {
+    return !p.Verified && p.Received > 0 && p.Received < p.Expected
+}
+
+// Err explains why a payment was not verified, nil if it was
+func (p *PaymentCheck) Err() error {
+    switch {
+    case p.Verified:
+        return nil
+    case p.Underpaid():
+        return fmt.Errorf("%w: paid %.6f, expected %.6f %s", ErrPaymentInsufficient, p.Received, p.Expected, p.Denom)
+    case p.Received > 0:
+        return fmt.Errorf("payment of %.6f %s does not match the expected %.6f", p.Received, p.Denom, p.Expected)
+    }
+    return fmt.Errorf("no valid payment found in transaction")
+}
+
+// VerifyPaymentTransaction checks a payment with DefaultPaymentTolerance
 func (c *Client) VerifyPaymentTransaction(ctx context.Context, txHash, senderAddr, recipientAddr string, expectedAmount float64, denom string) (bool, error) {
-    fmt.Printf("🔍 DEBUG: Looking for payment from %s to %s, expecting %.6f %s\n", senderAddr, recipientAddr, expectedAmount, denom)
-    
+    check, err := c.CheckPayment(ctx, txHash, senderAddr, recipientAddr, expectedAmount, denom, DefaultPaymentTolerance)
+    if err != nil {
+        return false, err
+    }
+    if err := check.Err(); err != nil {
+        return false, err
+    }
+    return true, nil
+}
+
+// CheckPayment looks for a transfer of expectedAmount denom from senderAddr to
+// recipientAddr in a transaction: a bank send or multi-send, a send executed
+// with an authz grant or an IBC transfer from senderAddr on another chain.
+// tolerance is the accepted relative difference, with 0 the amount must match
+// to the base unit.
+func (c *Client) CheckPayment(ctx context.Context, txHash, senderAddr, recipientAddr string, expectedAmount float64, denom string, tolerance float64) (*PaymentCheck, error) {
     // 1. Query transaction by hash
     txResponse, err := c.GetTx(ctx, txHash)
     if err != nil {
-        return false, fmt.Errorf("failed to query transaction: %w", err)
+        return nil, fmt.Errorf("failed to query transaction: %w", err)
     }
     
     if txResponse.TxResponse == nil {
-        return false, fmt.Errorf("transaction not found")
+        return nil, fmt.Errorf("%w: %s", ErrTxNotFound, txHash)
     }
     
     // 2. Check transaction success
     if txResponse.TxResponse.Code != 0 {
-        return false, fmt.Errorf("transaction failed with code %d", txResponse.TxResponse.Code)
+        return nil, fmt.Errorf("transaction failed with code %d", txResponse.TxResponse.Code)
     }
     
-    // 3. Parse transaction messages
-    decodedTx, err := c.decodeTxFromAny(txResponse.TxResponse.Tx)
-    if err != nil {
-        return false, fmt.Errorf("failed to decode transaction: %w", err)
+    // 3. Collect the transfers: bank, multi-send and authz messages, and IBC
+    // receipts, whose messages this client cannot decode
+    var payments []payment
+    decodedTx, decodeErr := c.decodeTxFromAny(txResponse.TxResponse.Tx)
+    if decodeErr == nil {
+        payments = paymentsFromMsgs(decodedTx.GetMsgs())
+    }
+    payments = append(payments, ibcPayments(txResponse.TxResponse.Events)...)
+    if decodeErr != nil && len(payments) == 0 {
+        return nil, fmt.Errorf("failed to decode transaction: %w", decodeErr)
     }
     
-    // 4. Verify payment details
-    fmt.Printf("🔍 DEBUG: Transaction has %d messages\n", len(decodedTx.GetMsgs()))
+    // Amounts of the base denom are compared in the display denom, half a
+    // base unit absorbs the float conversion in strict mode
+    net := network.Current()
+    margin := expectedAmount * tolerance
+    if tolerance <= 0 {
+        margin = 0.5
+        if denom == net.BaseDenom {
+            margin = net.ToDisplay(1) / 2
+        }
+    }
+    check := &PaymentCheck{Expected: expectedAmount, Denom: denom, Tolerance: tolerance}
     
-    for i, msg := range decodedTx.GetMsgs() {
-        fmt.Printf("🔍 DEBUG: Message %d type: %T\n", i, msg)
+    // 4. Verify payment details
+    for _, p := range payments {
+        if !sameAccount(p.sender, senderAddr) || p.recipient != recipientAddr {
+            continue
+        }
         
-        if bankMsg, ok := msg.(*banktypes.MsgSend); ok {
-            fmt.Printf("🔍 DEBUG: Found MsgSend: from=%s, to=%s\n", bankMsg.FromAddress, bankMsg.ToAddress)
-            
-            // Check sender address
-            if bankMsg.FromAddress != senderAddr {
-                fmt.Printf("🔍 DEBUG: Sender mismatch: expected=%s, actual=%s\n", senderAddr, bankMsg.FromAddress)
-                continue
-            }
-            
-            // Check recipient address
-            if bankMsg.ToAddress != recipientAddr {
-                fmt.Printf("🔍 DEBUG: Recipient mismatch: expected=%s, actual=%s\n", recipientAddr, bankMsg.ToAddress)
-                continue
-            }
-            
-            // Check amount and denomination
-            fmt.Printf("🔍 DEBUG: Message has %d coins\n", len(bankMsg.Amount))
-            
-            for j, coin := range bankMsg.Amount {
-                fmt.Printf("🔍 DEBUG: Coin %d: %s %s\n", j, coin.Amount, coin.Denom)
-                
-                if coin.Denom == denom {
-                    // Convert amount based on denomination
-                    var actualAmount float64
-                    if denom == "umedas" {
-                        actualAmount = float64(coin.Amount.Int64()) / 1000000.0 // 6 decimals
-                    } else {
-                        actualAmount = float64(coin.Amount.Int64())
-                    }
-                    
-                    fmt.Printf("🔍 DEBUG: Found matching denom. Actual: %.6f, Expected: %.6f\n", actualAmount, expectedAmount)
-                    
-                    // Allow small rounding differences (±0.1%)
-                    tolerance := expectedAmount * 0.001
-                    fmt.Printf("🔍 DEBUG: Tolerance: %.6f (range: %.6f - %.6f)\n", tolerance, expectedAmount-tolerance, expectedAmount+tolerance)
-                    
-                    if actualAmount >= expectedAmount-tolerance && actualAmount <= expectedAmount+tolerance {
-                        fmt.Printf("✅ DEBUG: Payment verified!\n")
-                        return true, nil
-                    } else {
-                        fmt.Printf("❌ DEBUG: Amount outside tolerance\n")
-                    }
-                }
-            }
+        coin := sdk.Coin{Denom: denom, Amount: p.amount.AmountOf(denom)}
+        if !coin.IsPositive() {
+            continue
+        }
+        // Convert amount based on denomination, 18-decimal amounts exceed int64
+        actualAmount, _ := new(big.Float).SetInt(coin.Amount.BigInt()).Float64()
+        if denom == net.BaseDenom {
+            actualAmount *= net.ToDisplay(1)
+        }
+        
+        if check.Received == 0 || math.Abs(actualAmount-expectedAmount) < math.Abs(check.Received-expectedAmount) {
+            check.Received = actualAmount
+        }
+        if math.Abs(actualAmount-expectedAmount) <= margin {
+            check.Received = actualAmount
+            check.Verified = true
+            return check, nil
         }
     }
     
-    return false, fmt.Errorf("no valid payment found in transaction")
+    return check, nil
 }
 
 // ===================================
@@ -597,8 +649,8 @@ func (c *Client) VerifyPaymentTransaction(ctx context.Context, txHash, senderAdd
 				if coin.Denom == denom {
         // Convert amount based on denomination
         var actualAmount float64
-        if denom == "umedas" {
-            actualAmount = float64(coin.Amount.Int64()) / 1000000.0 // 6 decimals
+        if net := network.Current(); denom == net.BaseDenom {
+            actualAmount = net.ToDisplay(coin.Amount.Int64())
         		} else {
             		actualAmount = float64(coin.Amount.Int64())
        			 }
@@ -634,6 +686,197 @@ func (c *Client) GetTransactionConfirmations(ctx context.Context, txHeight int64
 	return confirmations, nil
 }
 
+// GetIncomingTransfers searches bank transfers received by an address.
+// Results are ordered newest first; block times are resolved per height.
+func (c *Client) GetIncomingTransfers(ctx context.Context, recipient string, page, perPage int) ([]Transfer, int, error) {
+	query := fmt.Sprintf("transfer.recipient='%s'", recipient)
+	
+	stopTimer := telemetry.Track(telemetry.CategoryRPC, "tx_search")
+	result, err := c.clientCtx.Client.TxSearch(ctx, query, false, &page, &perPage, "desc")
+	stopTimer()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search transfers to %s: %w", recipient, err)
+	}
+	
+	blockTimes := make(map[int64]time.Time)
+	var transfers []Transfer
+	
+	for _, txRes := range result.Txs {
+		if txRes.TxResult.Code != 0 {
+			continue
+		}
+		
+		blockTime, ok := blockTimes[txRes.Height]
+		if !ok {
+			blockTime = c.blockTime(ctx, txRes.Height)
+			blockTimes[txRes.Height] = blockTime
+		}
+		
+		transfers = append(transfers, c.transfersFromTx(txRes.Hash.String(), txRes.Height, txRes.Tx, txRes.TxResult.Events, blockTime, recipient)...)
+	}
+	
+	return transfers, result.TotalCount, nil
+}
+
+// GetAccountTransfers searches the transactions in which address is the
+// sender or recipient (side) of a transfer, in the heights (minHeight,
+// maxHeight], oldest first. Every transfer of such a transaction from or to
+// address is returned, those of one transaction in event order.
+func (c *Client) GetAccountTransfers(ctx context.Context, address, side string, minHeight, maxHeight int64, page, perPage int) ([]Transfer, int, error) {
+	if side != "sender" && side != "recipient" {
+		return nil, 0, fmt.Errorf("invalid transfer side %q", side)
+	}
+	query := fmt.Sprintf("transfer.%s='%s' AND tx.height>%d AND tx.height<=%d", side, address, minHeight, maxHeight)
+	
+	stopTimer := telemetry.Track(telemetry.CategoryRPC, "tx_search")
+	result, err := c.clientCtx.Client.TxSearch(ctx, query, false, &page, &perPage, "asc")
+	stopTimer()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search transfers of %s: %w", address, err)
+	}
+	
+	match := func(sender, recipient string) bool {
+		return sender == address || recipient == address
+	}
+	blockTimes := make(map[int64]time.Time)
+	var transfers []Transfer
+	for _, txRes := range result.Txs {
+		if txRes.TxResult.Code != 0 {
+			continue
+		}
+		blockTime, ok := blockTimes[txRes.Height]
+		if !ok {
+			blockTime = c.blockTime(ctx, txRes.Height)
+			blockTimes[txRes.Height] = blockTime
+		}
+		transfers = append(transfers, c.matchTransfers(txRes.Hash.String(), txRes.Height, txRes.Tx, txRes.TxResult.Events, blockTime, match)...)
+	}
+	
+	return transfers, result.TotalCount, nil
+}
+
+// TransfersInTx returns the transfers to recipient in a committed transaction
+func (c *Client) TransfersInTx(ctx context.Context, txHash, recipient string) ([]Transfer, error) {
+	hash, err := hex.DecodeString(txHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction hash %q", txHash)
+	}
+	stopTimer := telemetry.Track(telemetry.CategoryRPC, "tx")
+	res, err := c.clientCtx.Client.Tx(ctx, hash, false)
+	stopTimer()
+	if err != nil && txNotFound(err) {
+		return nil, fmt.Errorf("%w: %s", ErrTxNotFound, txHash)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transaction %s: %w", txHash, err)
+	}
+	if res.TxResult.Code != 0 {
+		return nil, fmt.Errorf("transaction failed with code %d", res.TxResult.Code)
+	}
+	return c.transfersFromTx(res.Hash.String(), res.Height, res.Tx, res.TxResult.Events, c.blockTime(ctx, res.Height), recipient), nil
+}
+
+// WatchIncomingTransfers calls handle for every bank transfer to recipient in
+// newly committed transactions. It blocks until ctx is done or the websocket
+// subscription breaks; callers reconnect and catch up with GetIncomingTransfers.
+func (c *Client) WatchIncomingTransfers(ctx context.Context, recipient string, handle func(Transfer)) error {
+	httpClient, ok := c.clientCtx.Client.(*comethttp.HTTP)
+	if !ok {
+		return fmt.Errorf("client does not support event subscription")
+	}
+	if !httpClient.IsRunning() {
+		if err := httpClient.Start(); err != nil {
+			return fmt.Errorf("failed to start websocket client: %w", err)
+		}
+	}
+	
+	subscriber := "medas-payments-" + recipient
+	query := fmt.Sprintf("tm.event='Tx' AND transfer.recipient='%s'", recipient)
+	events, err := httpClient.Subscribe(ctx, subscriber, query, 100)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to transfers: %w", err)
+	}
+	defer httpClient.Unsubscribe(context.Background(), subscriber, query)
+	
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-events:
+			if !ok {
+				return fmt.Errorf("transfer subscription closed")
+			}
+			data, ok := event.Data.(cmttypes.EventDataTx)
+			if !ok || data.Result.Code != 0 {
+				continue
+			}
+			hash := cmtbytes.HexBytes(cmttypes.Tx(data.Tx).Hash()).String()
+			blockTime := c.blockTime(ctx, data.Height)
+			for _, t := range c.transfersFromTx(hash, data.Height, data.Tx, data.Result.Events, blockTime, recipient) {
+				handle(t)
+			}
+		}
+	}
+}
+
+// transfersFromTx extracts the transfer events to recipient from a transaction
+func (c *Client) transfersFromTx(hash string, height int64, txBytes []byte, events []abci.Event, blockTime time.Time, recipient string) []Transfer {
+	return c.matchTransfers(hash, height, txBytes, events, blockTime, func(_, to string) bool {
+		return to == recipient
+	})
+}
+
+// matchTransfers extracts the transfer events of a transaction that match
+func (c *Client) matchTransfers(hash string, height int64, txBytes []byte, events []abci.Event, blockTime time.Time, match func(sender, recipient string) bool) []Transfer {
+	var memo string
+	if decoded, err := c.decodeTx(txBytes); err == nil {
+		if txWithMemo, ok := decoded.(interface{ GetMemo() string }); ok {
+			memo = txWithMemo.GetMemo()
+		}
+	}
+	
+	var transfers []Transfer
+	for _, event := range events {
+		if event.Type != "transfer" {
+			continue
+		}
+		
+		attrs := make(map[string]string)
+		for _, attr := range event.Attributes {
+			attrs[attr.Key] = attr.Value
+		}
+		if !match(attrs["sender"], attrs["recipient"]) {
+			continue
+		}
+		
+		amount, err := sdk.ParseCoinsNormalized(attrs["amount"])
+		if err != nil {
+			continue
+		}
+		
+		transfers = append(transfers, Transfer{
+			TxHash:    hash,
+			Height:    height,
+			Time:      blockTime,
+			Sender:    attrs["sender"],
+			Recipient: attrs["recipient"],
+			Amount:    amount,
+			Memo:      memo,
+		})
+	}
+	return transfers
+}
+
+// blockTime returns the time of a block, zero if it cannot be fetched
+func (c *Client) blockTime(ctx context.Context, height int64) time.Time {
+	defer telemetry.Track(telemetry.CategoryRPC, "block")()
+	block, err := c.clientCtx.Client.Block(ctx, &height)
+	if err != nil {
+		return time.Time{}
+	}
+	return block.Block.Time
+}
+
 // ===================================
 // BALANCE QUERY METHODS (NEU)
 // ===================================
@@ -647,6 +890,7 @@ func (c *Client) GetAccountBalance(ctx context.Context, address string) (sdk.Coi
 		Address: address,
 	}
 	
+	defer telemetry.Track(telemetry.CategoryRPC, "all_balances")()
 	res, err := queryClient.AllBalances(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query balance for %s: %w", address, err)
@@ -665,6 +909,7 @@ func (c *Client) GetAccountBalanceByDenom(ctx context.Context, address, denom st
 		Denom:   denom,
 	}
 	
+	defer telemetry.Track(telemetry.CategoryRPC, "balance")()
 	res, err := queryClient.Balance(ctx, req)
 	if err != nil {
 		return sdk.Coin{}, fmt.Errorf("failed to query balance for %s/%s: %w", address, denom, err)
@@ -678,7 +923,7 @@ func (c *Client) GetAccountBalanceByDenom(ctx context.Context, address, denom st
 // ===================================
 
 // CreateSendTransaction creates a MsgSend transaction
-func (c *Client) CreateSendTransaction(fromAddr, toAddr string, amount sdk.Coins, memo string) (*sdk.TxResponse, error) {
+func (c *Client) CreateSendTransaction(ctx context.Context, fromAddr, toAddr string, amount sdk.Coins, memo string) (*sdk.TxResponse, error) {
 	// Convert addresses
 	fromAddress, err := sdk.AccAddressFromBech32(fromAddr)
 	if err != nil {
@@ -694,7 +939,7 @@ func (c *Client) CreateSendTransaction(fromAddr, toAddr string, amount sdk.Coins
 	msg := banktypes.NewMsgSend(fromAddress, toAddress, amount)
 	
 	// Create transaction
-	return c.sendTransaction(msg, fromAddr)
+	return c.sendTransaction(ctx, msg, fromAddr)
 }
 
 // ===================================
@@ -820,3 +1065,14 @@ type TransactionData struct {
 	Amount      string `json:"amount"`
 	Denom       string `json:"denom"`
 }
+
+// Transfer represents a single bank transfer found on chain
+type Transfer struct {
+	TxHash    string    `json:"tx_hash"`
+	Height    int64     `json:"height"`
+	Time      time.Time `json:"time"`
+	Sender    string    `json:"sender"`
+	Recipient string    `json:"recipient"`
+	Amount    sdk.Coins `json:"amount"`
+	Memo      string    `json:"memo,omitempty"`
+}
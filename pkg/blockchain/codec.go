@@ -8,6 +8,8 @@ import (
 	"github.com/cosmos/cosmos-sdk/codec/types"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/gogoproto/proto"
+
+	"github.com/oxygene76/medasdigital-client/pkg/network"
 )
 
 var (
@@ -45,9 +47,9 @@ func NewCodec() *Codec {
 	return &Codec{
 		marshaler:         marshaler,
 		interfaceRegistry: globalInterfaceRegistry,
-		addressCodec:      NewBech32AddressCodec("medas"),
+		addressCodec:      NewBech32AddressCodec(network.Current().Bech32Prefix),
 		config: CodecConfig{
-			Bech32Prefix:   "medas",
+			Bech32Prefix:   network.Current().Bech32Prefix,
 			UseProtobuf:    true,
 			UseLegacyAmino: true,
 		},
@@ -93,8 +95,9 @@ func (c *Codec) SetAddressCodec(codec AddressCodec) {
 	c.addressCodec = codec
 }
 
-// MarshalJSON marshals an object to JSON
-func (c *Codec) MarshalJSON(obj interface{}) ([]byte, error) {
+// ToJSON marshals an object to JSON. It was MarshalJSON, which go vet rejects
+// for not matching json.Marshaler.
+func (c *Codec) ToJSON(obj interface{}) ([]byte, error) {
 	if protoMsg, ok := obj.(proto.Message); ok {
 		return c.marshaler.MarshalJSON(protoMsg)
 	}
@@ -103,8 +106,9 @@ func (c *Codec) MarshalJSON(obj interface{}) ([]byte, error) {
 	return json.Marshal(obj)
 }
 
-// UnmarshalJSON unmarshals JSON to an object
-func (c *Codec) UnmarshalJSON(data []byte, obj interface{}) error {
+// FromJSON unmarshals JSON to an object. It was UnmarshalJSON, which go vet
+// rejects for not matching json.Unmarshaler.
+func (c *Codec) FromJSON(data []byte, obj interface{}) error {
 	if protoMsg, ok := obj.(proto.Message); ok {
 		return c.marshaler.UnmarshalJSON(data, protoMsg)
 	}
@@ -210,7 +214,7 @@ func (c *Codec) GetTypeURL(msg proto.Message) string {
 
 // MustMarshalJSON marshals to JSON or panics
 func (c *Codec) MustMarshalJSON(obj interface{}) []byte {
-	data, err := c.MarshalJSON(obj)
+	data, err := c.ToJSON(obj)
 	if err != nil {
 		panic(err)
 	}
@@ -219,7 +223,7 @@ func (c *Codec) MustMarshalJSON(obj interface{}) []byte {
 
 // MustUnmarshalJSON unmarshals from JSON or panics
 func (c *Codec) MustUnmarshalJSON(data []byte, obj interface{}) {
-	if err := c.UnmarshalJSON(data, obj); err != nil {
+	if err := c.FromJSON(data, obj); err != nil {
 		panic(err)
 	}
 }
@@ -263,7 +267,7 @@ func MarshalJSON(codec *Codec, obj interface{}) ([]byte, error) {
 	if codec == nil {
 		codec = GetGlobalCodec()
 	}
-	return codec.MarshalJSON(obj)
+	return codec.ToJSON(obj)
 }
 
 // UnmarshalJSON unmarshals using global codec
@@ -271,7 +275,7 @@ func UnmarshalJSON(codec *Codec, data []byte, obj interface{}) error {
 	if codec == nil {
 		codec = GetGlobalCodec()
 	}
-	return codec.UnmarshalJSON(data, obj)
+	return codec.FromJSON(data, obj)
 }
 
 // MustMarshalJSON marshals to JSON or panics using global codec
@@ -0,0 +1,99 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// simulatePath is the ABCI query path of the tx service simulation
+const simulatePath = "/cosmos.tx.v1beta1.Service/Simulate"
+
+// ErrDryRun is returned instead of a broadcast result when the client
+// context is in simulation mode. The transaction was simulated and printed.
+var ErrDryRun = errors.New("dry run, transaction not broadcast")
+
+// DryRun is what a simulated transaction would have done
+type DryRun struct {
+	Messages   []json.RawMessage `json:"messages"`
+	Memo       string            `json:"memo,omitempty"`
+	Fee        string            `json:"fee"`
+	FeeGranter string            `json:"fee_granter,omitempty"`
+	GasWanted  uint64            `json:"gas_wanted"`
+	GasUsed    uint64            `json:"gas_used"`
+	Events     sdk.StringEvents  `json:"events"`
+}
+
+// SimulateTx decodes signed tx bytes and simulates them on the node without
+// broadcasting
+func SimulateTx(ctx context.Context, clientCtx client.Context, txBytes []byte) (*DryRun, error) {
+	decoded, err := clientCtx.TxConfig.TxDecoder()(txBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	messages, memo, err := txJSON(clientCtx, decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DryRun{Messages: messages, Memo: memo}
+	if feeTx, ok := decoded.(sdk.FeeTx); ok {
+		result.Fee = feeTx.GetFee().String()
+		result.GasWanted = feeTx.GetGas()
+	}
+	_, result.FeeGranter = feeAccounts(decoded)
+
+	req := &txtypes.SimulateRequest{TxBytes: txBytes}
+	data, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	stopTimer := telemetry.Track(telemetry.CategoryRPC, "simulate_tx")
+	res, _, err := QueryContext(ctx, clientCtx, simulatePath, data)
+	stopTimer()
+	if err != nil {
+		return result, fmt.Errorf("simulation failed: %w", SimulationError(err))
+	}
+	var resp txtypes.SimulateResponse
+	if err := resp.Unmarshal(res); err != nil {
+		return result, fmt.Errorf("failed to decode simulation: %w", err)
+	}
+	if resp.GasInfo != nil {
+		result.GasUsed = resp.GasInfo.GasUsed
+	}
+	if resp.Result != nil {
+		result.Events = sdk.StringifyEvents(resp.Result.Events)
+	}
+	return result, nil
+}
+
+// dryRun simulates txBytes in place of broadcasting them, prints the result
+// as JSON to the output of clientCtx and returns ErrDryRun
+func dryRun(ctx context.Context, clientCtx client.Context, txBytes []byte) error {
+	result, err := SimulateTx(ctx, clientCtx, txBytes)
+	if result == nil {
+		return err
+	}
+	var out io.Writer = os.Stdout
+	if clientCtx.Output != nil {
+		out = clientCtx.Output
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if encErr := enc.Encode(result); encErr != nil {
+		return encErr
+	}
+	if err != nil {
+		return err
+	}
+	return ErrDryRun
+}
@@ -0,0 +1,58 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	errorsmod "cosmossdk.io/errors"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+)
+
+// Errors callers match with errors.Is. ErrKeyNotFound and
+// ErrInsufficientFunds are the registered SDK errors, so keyring lookups and
+// rejected transactions match them as well.
+var (
+	ErrKeyNotFound         = sdkerrors.ErrKeyNotFound
+	ErrInsufficientFunds   = sdkerrors.ErrInsufficientFunds
+	ErrTxNotFound          = errors.New("transaction not found")
+	ErrPaymentInsufficient = errors.New("payment below the expected amount")
+)
+
+// TxFailedError is a transaction the chain rejected or executed with an
+// error code
+type TxFailedError struct {
+	TxHash    string
+	Codespace string
+	Code      uint32
+	Log       string
+}
+
+func (e *TxFailedError) Error() string {
+	return fmt.Sprintf("transaction failed with code %d: %s", e.Code, e.Log)
+}
+
+// Is matches the SDK error registered for the code, e.g. ErrInsufficientFunds
+func (e *TxFailedError) Is(target error) bool {
+	registered, ok := target.(*errorsmod.Error)
+	if !ok {
+		return false
+	}
+	return registered.Codespace() == e.Codespace && registered.ABCICode() == e.Code
+}
+
+// txNotFound reports whether a lookup failed because the node does not know
+// the transaction. The gRPC tx service and the CometBFT RPC both only say so
+// in the message.
+func txNotFound(err error) bool {
+	return strings.Contains(err.Error(), "not found")
+}
+
+// SimulationError marks a failed simulation as ErrInsufficientFunds when the
+// account cannot pay. Simulations return the SDK error only as text.
+func SimulationError(err error) error {
+	if err != nil && !errors.Is(err, ErrInsufficientFunds) && strings.Contains(err.Error(), ErrInsufficientFunds.Error()) {
+		return fmt.Errorf("%w: %v", ErrInsufficientFunds, err)
+	}
+	return err
+}
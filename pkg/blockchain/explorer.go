@@ -0,0 +1,149 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cmtbytes "github.com/cometbft/cometbft/libs/bytes"
+	rpcclient "github.com/cometbft/cometbft/rpc/client"
+	cmttypes "github.com/cometbft/cometbft/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// TxSummary is one line about a transaction in a block or the mempool
+type TxSummary struct {
+	Hash      string       `json:"hash"`
+	Sender    string       `json:"sender,omitempty"` // fee payer, the first signer
+	Messages  []MsgSummary `json:"messages"`
+	Memo      string       `json:"memo,omitempty"`
+	Fee       string       `json:"fee,omitempty"`
+	GasWanted int64        `json:"gas_wanted,omitempty"`
+	GasUsed   int64        `json:"gas_used,omitempty"`
+	Code      *uint32      `json:"code,omitempty"` // nil in the mempool
+	Log       string       `json:"log,omitempty"`  // error of a failed transaction
+	Size      int          `json:"size"`
+	Error     string       `json:"error,omitempty"` // the transaction could not be decoded
+}
+
+// MsgSummary names a message, bank sends with recipient and amount
+type MsgSummary struct {
+	Type   string `json:"type"`
+	To     string `json:"to,omitempty"`
+	Amount string `json:"amount,omitempty"`
+}
+
+// BlockSummary is a block with a summary of each transaction
+type BlockSummary struct {
+	Height   int64       `json:"height"`
+	Hash     string      `json:"hash"`
+	Time     time.Time   `json:"time"`
+	Proposer string      `json:"proposer"` // consensus address
+	Txs      []TxSummary `json:"txs"`
+}
+
+// MempoolSummary lists unconfirmed transactions
+type MempoolSummary struct {
+	Count      int         `json:"count"` // in the mempool
+	TotalBytes int64       `json:"total_bytes"`
+	Txs        []TxSummary `json:"txs"` // the first of them, see Mempool
+}
+
+// Block summarizes the block at height, the latest for 0, with the results
+// of its transactions
+func (c *Client) Block(ctx context.Context, height int64) (*BlockSummary, error) {
+	var h *int64
+	if height > 0 {
+		h = &height
+	}
+	stopTimer := telemetry.Track(telemetry.CategoryRPC, "block")
+	block, err := c.clientCtx.Client.Block(ctx, h)
+	stopTimer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block: %w", err)
+	}
+	header := block.Block.Header
+	summary := &BlockSummary{
+		Height:   header.Height,
+		Hash:     block.BlockID.Hash.String(),
+		Time:     header.Time,
+		Proposer: header.ProposerAddress.String(),
+		Txs:      make([]TxSummary, 0, len(block.Block.Txs)),
+	}
+	if len(block.Block.Txs) == 0 {
+		return summary, nil
+	}
+
+	stopTimer = telemetry.Track(telemetry.CategoryRPC, "block_results")
+	results, err := c.clientCtx.Client.BlockResults(ctx, &header.Height)
+	stopTimer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block results: %w", err)
+	}
+	for i, tx := range block.Block.Txs {
+		s := c.summarizeTx(tx)
+		if i < len(results.TxsResults) {
+			r := results.TxsResults[i]
+			code := r.Code
+			s.Code, s.GasWanted, s.GasUsed = &code, r.GasWanted, r.GasUsed
+			if code != 0 {
+				s.Log = r.Log
+			}
+		}
+		summary.Txs = append(summary.Txs, s)
+	}
+	return summary, nil
+}
+
+// Mempool summarizes up to limit unconfirmed transactions of the node
+func (c *Client) Mempool(ctx context.Context, limit int) (*MempoolSummary, error) {
+	mempool, ok := c.clientCtx.Client.(rpcclient.MempoolClient)
+	if !ok {
+		return nil, fmt.Errorf("RPC client cannot query the mempool")
+	}
+	stopTimer := telemetry.Track(telemetry.CategoryRPC, "unconfirmed_txs")
+	res, err := mempool.UnconfirmedTxs(ctx, &limit)
+	stopTimer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unconfirmed transactions: %w", err)
+	}
+	summary := &MempoolSummary{
+		Count:      res.Total,
+		TotalBytes: res.TotalBytes,
+		Txs:        make([]TxSummary, 0, len(res.Txs)),
+	}
+	for _, tx := range res.Txs {
+		summary.Txs = append(summary.Txs, c.summarizeTx(tx))
+	}
+	return summary, nil
+}
+
+// summarizeTx decodes what a summary shows, undecodable transactions keep
+// their hash and size
+func (c *Client) summarizeTx(tx cmttypes.Tx) TxSummary {
+	s := TxSummary{Hash: cmtbytes.HexBytes(tx.Hash()).String(), Size: len(tx)}
+	decoded, err := c.decodeTx(tx)
+	if err != nil {
+		s.Error = err.Error()
+		return s
+	}
+	if txWithMemo, ok := decoded.(interface{ GetMemo() string }); ok {
+		s.Memo = txWithMemo.GetMemo()
+	}
+	if feeTx, ok := decoded.(sdk.FeeTx); ok {
+		s.Fee = feeTx.GetFee().String()
+		s.GasWanted = int64(feeTx.GetGas())
+	}
+	s.Sender, _ = feeAccounts(decoded)
+	for _, msg := range decoded.GetMsgs() {
+		m := MsgSummary{Type: sdk.MsgTypeURL(msg)}
+		if send, ok := msg.(*banktypes.MsgSend); ok {
+			m.To, m.Amount = send.ToAddress, send.Amount.String()
+		}
+		s.Messages = append(s.Messages, m)
+	}
+	return s
+}
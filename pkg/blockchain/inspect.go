@@ -0,0 +1,125 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// TxDetails is a transaction decoded for inspection
+type TxDetails struct {
+	Hash         string            `json:"hash"`
+	Height       int64             `json:"height"`
+	Time         string            `json:"time,omitempty"`
+	Code         uint32            `json:"code"`
+	Codespace    string            `json:"codespace,omitempty"`
+	Log          string            `json:"log,omitempty"` // error of a failed transaction
+	GasWanted    int64             `json:"gas_wanted"`
+	GasUsed      int64             `json:"gas_used"`
+	Fee          string            `json:"fee"`
+	FeePayer     string            `json:"fee_payer,omitempty"`
+	FeeGranter   string            `json:"fee_granter,omitempty"`
+	Memo         string            `json:"memo,omitempty"`
+	Registration *RegistrationMemo `json:"registration,omitempty"` // decoded MEDAS_*_REG memo
+	Messages     []json.RawMessage `json:"messages"`
+	Events       sdk.StringEvents  `json:"events"`
+}
+
+// Succeeded reports whether the transaction executed without error
+func (d *TxDetails) Succeeded() bool {
+	return d.Code == 0
+}
+
+// InspectTx fetches a transaction and decodes its messages, memo, fee and
+// events
+func (c *Client) InspectTx(ctx context.Context, txHash string) (*TxDetails, error) {
+	resp, err := c.GetTx(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+	res := resp.TxResponse
+	if res == nil || res.Tx == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTxNotFound, txHash)
+	}
+	details := &TxDetails{
+		Hash:      res.TxHash,
+		Height:    res.Height,
+		Time:      res.Timestamp,
+		Code:      res.Code,
+		Codespace: res.Codespace,
+		GasWanted: res.GasWanted,
+		GasUsed:   res.GasUsed,
+		Events:    sdk.StringifyEvents(res.Events),
+	}
+	if res.Code != 0 {
+		details.Log = res.RawLog
+	}
+
+	decoded, err := c.decodeTxFromAny(res.Tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	if details.Messages, details.Memo, err = txJSON(c.clientCtx, decoded); err != nil {
+		return nil, err
+	}
+	if feeTx, ok := decoded.(sdk.FeeTx); ok {
+		details.Fee = feeTx.GetFee().String()
+	}
+	details.FeePayer, details.FeeGranter = feeAccounts(decoded)
+	if reg, err := ParseRegistrationTx(decoded.GetMsgs(), details.Memo); err == nil {
+		// Registration messages carry no timestamp, use the block time
+		if t, err := time.Parse(time.RFC3339, details.Time); err == nil && reg.Timestamp == 0 {
+			reg.Timestamp = t.Unix()
+		}
+		details.Registration = reg
+	}
+	return details, nil
+}
+
+// txJSON returns the messages of a decoded transaction as JSON, with their
+// type URL in @type, and its memo
+func txJSON(clientCtx client.Context, decoded sdk.Tx) ([]json.RawMessage, string, error) {
+	data, err := clientCtx.TxConfig.TxJSONEncoder()(decoded)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode transaction as JSON: %w", err)
+	}
+	var tx struct {
+		Body struct {
+			Messages []json.RawMessage `json:"messages"`
+			Memo     string            `json:"memo"`
+		} `json:"body"`
+	}
+	if err := json.Unmarshal(data, &tx); err != nil {
+		return nil, "", fmt.Errorf("failed to decode transaction JSON: %w", err)
+	}
+	return tx.Body.Messages, tx.Body.Memo, nil
+}
+
+// feeAccounts returns the account paying the fee, the first signer unless the
+// fee names a payer, and the fee granter. The fee fields are read as written:
+// the interface registry has no address codec, which FeePayer and FeeGranter
+// of the decoded transaction need.
+func feeAccounts(decoded sdk.Tx) (payer, granter string) {
+	if protoTx, ok := decoded.(interface{ GetProtoTx() *txtypes.Tx }); ok {
+		if tx := protoTx.GetProtoTx(); tx != nil && tx.AuthInfo != nil && tx.AuthInfo.Fee != nil {
+			payer, granter = tx.AuthInfo.Fee.Payer, tx.AuthInfo.Fee.Granter
+		}
+	}
+	if payer != "" {
+		return payer, granter
+	}
+	if sigTx, ok := decoded.(interface {
+		GetPubKeys() ([]cryptotypes.PubKey, error)
+	}); ok {
+		if pks, err := sigTx.GetPubKeys(); err == nil && len(pks) > 0 && pks[0] != nil {
+			payer = sdk.AccAddress(pks[0].Address()).String()
+		}
+	}
+	return payer, granter
+}
@@ -42,11 +42,16 @@ const (
 	ModuleName = "clientregistry"
 )
 
-// MsgRegisterClient defines the message for registering a new client
+// MsgRegisterClient defines the message for registering a new client. Chat
+// clients add their display name, endpoints and X25519 chat key.
 type MsgRegisterClient struct {
-	Creator      string   `protobuf:"bytes,1,opt,name=creator,proto3" json:"creator,omitempty"`
-	Capabilities []string `protobuf:"bytes,2,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
-	Metadata     string   `protobuf:"bytes,3,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	Creator          string   `protobuf:"bytes,1,opt,name=creator,proto3" json:"creator,omitempty"`
+	Capabilities     []string `protobuf:"bytes,2,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	Metadata         string   `protobuf:"bytes,3,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	RegistrationType string   `protobuf:"bytes,4,opt,name=registration_type,proto3" json:"registration_type,omitempty"` // simple or chat, empty is simple
+	DisplayName      string   `protobuf:"bytes,5,opt,name=display_name,proto3" json:"display_name,omitempty"`
+	Endpoints        []string `protobuf:"bytes,6,rep,name=endpoints,proto3" json:"endpoints,omitempty"`
+	ChatKey          []byte   `protobuf:"bytes,7,opt,name=chat_key,proto3" json:"chat_key,omitempty"`
 }
 
 // Route implements sdk.Msg interface (legacy)
@@ -116,6 +121,22 @@ func (msg *MsgRegisterClient) ValidateBasic() error {
 		return errors.Wrap(ErrInvalidMessage, "metadata too large (max 10KB)")
 	}
 
+	switch msg.RegistrationType {
+	case "", "simple":
+	case "chat":
+		if len(msg.ChatKey) != 32 {
+			return errors.Wrapf(ErrInvalidMessage, "chat key must be a 32 byte X25519 key, got %d bytes", len(msg.ChatKey))
+		}
+		if msg.DisplayName == "" || len(msg.DisplayName) > 50 {
+			return errors.Wrap(ErrInvalidMessage, "display name must have 1 to 50 characters")
+		}
+	default:
+		return errors.Wrapf(ErrInvalidMessage, "invalid registration type: %s", msg.RegistrationType)
+	}
+	if len(msg.Endpoints) > 8 {
+		return errors.Wrap(ErrInvalidMessage, "too many endpoints (max 8)")
+	}
+
 	return nil
 }
 
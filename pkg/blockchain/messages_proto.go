@@ -0,0 +1,364 @@
+package blockchain
+
+import (
+	"bytes"
+	"compress/gzip"
+
+	msgv1 "cosmossdk.io/api/cosmos/msg/v1"
+	gogoproto "github.com/cosmos/gogoproto/proto"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Proto files the messages are declared in, there is no generated code for
+// them so the descriptors are built here
+const (
+	clientRegistryProtoFile = "medas/clientregistry/v1/tx.proto"
+	analysisProtoFile       = "medas/analysis/v1/tx.proto"
+)
+
+// Type URLs of the registration messages in a transaction
+const (
+	MsgRegisterClientTypeURL   = "/medas.clientregistry.v1.MsgRegisterClient"
+	MsgUpdateClientTypeURL     = "/medas.clientregistry.v1.MsgUpdateClient"
+	MsgDeactivateClientTypeURL = "/medas.clientregistry.v1.MsgDeactivateClient"
+	MsgStoreAnalysisTypeURL    = "/medas.analysis.v1.MsgStoreAnalysis"
+)
+
+// Gzipped file descriptors, SDK 0.50 resolves signers and sign mode encoding from them
+var (
+	clientRegistryDescriptor = buildClientRegistryDescriptor()
+	analysisDescriptor       = buildAnalysisDescriptor()
+)
+
+func init() {
+	gogoproto.RegisterFile(clientRegistryProtoFile, clientRegistryDescriptor)
+	gogoproto.RegisterFile(analysisProtoFile, analysisDescriptor)
+	gogoproto.RegisterType((*MsgRegisterClient)(nil), "medas.clientregistry.v1.MsgRegisterClient")
+	gogoproto.RegisterType((*MsgUpdateClient)(nil), "medas.clientregistry.v1.MsgUpdateClient")
+	gogoproto.RegisterType((*MsgDeactivateClient)(nil), "medas.clientregistry.v1.MsgDeactivateClient")
+	gogoproto.RegisterType((*MsgStoreAnalysis)(nil), "medas.analysis.v1.MsgStoreAnalysis")
+}
+
+// Descriptor returns the gzipped file descriptor and the message index in it
+func (*MsgRegisterClient) Descriptor() ([]byte, []int) {
+	return clientRegistryDescriptor, []int{0}
+}
+
+// Descriptor returns the gzipped file descriptor and the message index in it
+func (*MsgUpdateClient) Descriptor() ([]byte, []int) {
+	return clientRegistryDescriptor, []int{1}
+}
+
+// Descriptor returns the gzipped file descriptor and the message index in it
+func (*MsgDeactivateClient) Descriptor() ([]byte, []int) {
+	return clientRegistryDescriptor, []int{2}
+}
+
+// Descriptor returns the gzipped file descriptor and the message index in it
+func (*MsgStoreAnalysis) Descriptor() ([]byte, []int) {
+	return analysisDescriptor, []int{0}
+}
+
+// Marshal encodes the message in protobuf wire format
+func (msg *MsgRegisterClient) Marshal() ([]byte, error) {
+	var b []byte
+	b = appendString(b, 1, msg.Creator)
+	for _, c := range msg.Capabilities {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendString(b, c)
+	}
+	b = appendString(b, 3, msg.Metadata)
+	b = appendString(b, 4, msg.RegistrationType)
+	b = appendString(b, 5, msg.DisplayName)
+	for _, e := range msg.Endpoints {
+		b = protowire.AppendTag(b, 6, protowire.BytesType)
+		b = protowire.AppendString(b, e)
+	}
+	if len(msg.ChatKey) > 0 {
+		b = protowire.AppendTag(b, 7, protowire.BytesType)
+		b = protowire.AppendBytes(b, msg.ChatKey)
+	}
+	return b, nil
+}
+
+// Unmarshal decodes the protobuf wire format
+func (msg *MsgRegisterClient) Unmarshal(data []byte) error {
+	msg.Reset()
+	return decodeFields(data, func(num protowire.Number, value []byte, _ uint64) error {
+		switch num {
+		case 1:
+			msg.Creator = string(value)
+		case 2:
+			msg.Capabilities = append(msg.Capabilities, string(value))
+		case 3:
+			msg.Metadata = string(value)
+		case 4:
+			msg.RegistrationType = string(value)
+		case 5:
+			msg.DisplayName = string(value)
+		case 6:
+			msg.Endpoints = append(msg.Endpoints, string(value))
+		case 7:
+			msg.ChatKey = append([]byte(nil), value...)
+		}
+		return nil
+	})
+}
+
+// Size returns the encoded length
+func (msg *MsgRegisterClient) Size() int {
+	b, _ := msg.Marshal()
+	return len(b)
+}
+
+// Marshal encodes the message in protobuf wire format
+func (msg *MsgUpdateClient) Marshal() ([]byte, error) {
+	var b []byte
+	b = appendString(b, 1, msg.Creator)
+	b = appendString(b, 2, msg.ClientID)
+	for _, c := range msg.NewCapabilities {
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendString(b, c)
+	}
+	b = appendString(b, 4, msg.NewMetadata)
+	return b, nil
+}
+
+// Unmarshal decodes the protobuf wire format
+func (msg *MsgUpdateClient) Unmarshal(data []byte) error {
+	msg.Reset()
+	return decodeFields(data, func(num protowire.Number, value []byte, _ uint64) error {
+		switch num {
+		case 1:
+			msg.Creator = string(value)
+		case 2:
+			msg.ClientID = string(value)
+		case 3:
+			msg.NewCapabilities = append(msg.NewCapabilities, string(value))
+		case 4:
+			msg.NewMetadata = string(value)
+		}
+		return nil
+	})
+}
+
+// Size returns the encoded length
+func (msg *MsgUpdateClient) Size() int {
+	b, _ := msg.Marshal()
+	return len(b)
+}
+
+// Marshal encodes the message in protobuf wire format
+func (msg *MsgDeactivateClient) Marshal() ([]byte, error) {
+	var b []byte
+	b = appendString(b, 1, msg.Creator)
+	b = appendString(b, 2, msg.ClientID)
+	b = appendString(b, 3, msg.Reason)
+	return b, nil
+}
+
+// Unmarshal decodes the protobuf wire format
+func (msg *MsgDeactivateClient) Unmarshal(data []byte) error {
+	msg.Reset()
+	return decodeFields(data, func(num protowire.Number, value []byte, _ uint64) error {
+		switch num {
+		case 1:
+			msg.Creator = string(value)
+		case 2:
+			msg.ClientID = string(value)
+		case 3:
+			msg.Reason = string(value)
+		}
+		return nil
+	})
+}
+
+// Size returns the encoded length
+func (msg *MsgDeactivateClient) Size() int {
+	b, _ := msg.Marshal()
+	return len(b)
+}
+
+// Marshal encodes the message in protobuf wire format
+func (msg *MsgStoreAnalysis) Marshal() ([]byte, error) {
+	var b []byte
+	b = appendString(b, 1, msg.Creator)
+	b = appendString(b, 2, msg.ClientID)
+	b = appendString(b, 3, msg.AnalysisType)
+	b = appendString(b, 4, msg.Data)
+	if msg.BlockHeight != 0 {
+		b = protowire.AppendTag(b, 5, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(msg.BlockHeight))
+	}
+	b = appendString(b, 6, msg.TxHash)
+	return b, nil
+}
+
+// Unmarshal decodes the protobuf wire format
+func (msg *MsgStoreAnalysis) Unmarshal(data []byte) error {
+	msg.Reset()
+	return decodeFields(data, func(num protowire.Number, value []byte, varint uint64) error {
+		switch num {
+		case 1:
+			msg.Creator = string(value)
+		case 2:
+			msg.ClientID = string(value)
+		case 3:
+			msg.AnalysisType = string(value)
+		case 4:
+			msg.Data = string(value)
+		case 5:
+			msg.BlockHeight = int64(varint)
+		case 6:
+			msg.TxHash = string(value)
+		}
+		return nil
+	})
+}
+
+// Size returns the encoded length
+func (msg *MsgStoreAnalysis) Size() int {
+	b, _ := msg.Marshal()
+	return len(b)
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+// decodeFields walks the fields of a message, fn gets the value of length
+// delimited fields and the number of varints, other wire types are skipped
+func decodeFields(data []byte, fn func(num protowire.Number, value []byte, varint uint64) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		switch typ {
+		case protowire.BytesType:
+			value, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			if err := fn(num, value, 0); err != nil {
+				return err
+			}
+			data = data[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			if err := fn(num, nil, v); err != nil {
+				return err
+			}
+			data = data[n:]
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return nil
+}
+
+// protoField declares a field of a message descriptor
+func protoField(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, repeated bool) *descriptorpb.FieldDescriptorProto {
+	label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	if repeated {
+		label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	}
+	return &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		Number:   proto.Int32(number),
+		Type:     typ.Enum(),
+		Label:    label.Enum(),
+		JsonName: proto.String(name),
+	}
+}
+
+// signedMessage declares a message signed by its creator field
+func signedMessage(name string, fields ...*descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+	options := &descriptorpb.MessageOptions{}
+	proto.SetExtension(options, msgv1.E_Signer, []string{"creator"})
+	return &descriptorpb.DescriptorProto{Name: proto.String(name), Field: fields, Options: options}
+}
+
+func buildClientRegistryDescriptor() []byte {
+	str, bin := descriptorpb.FieldDescriptorProto_TYPE_STRING, descriptorpb.FieldDescriptorProto_TYPE_BYTES
+	return gzipDescriptor(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String(clientRegistryProtoFile),
+		Package:    proto.String("medas.clientregistry.v1"),
+		Dependency: []string{"cosmos/msg/v1/msg.proto"},
+		Syntax:     proto.String("proto3"),
+		Options:    &descriptorpb.FileOptions{GoPackage: proto.String("github.com/oxygene76/medasdigital-client/pkg/blockchain")},
+		MessageType: []*descriptorpb.DescriptorProto{
+			signedMessage("MsgRegisterClient",
+				protoField("creator", 1, str, false),
+				protoField("capabilities", 2, str, true),
+				protoField("metadata", 3, str, false),
+				protoField("registration_type", 4, str, false),
+				protoField("display_name", 5, str, false),
+				protoField("endpoints", 6, str, true),
+				protoField("chat_key", 7, bin, false),
+			),
+			signedMessage("MsgUpdateClient",
+				protoField("creator", 1, str, false),
+				protoField("client_id", 2, str, false),
+				protoField("new_capabilities", 3, str, true),
+				protoField("new_metadata", 4, str, false),
+			),
+			signedMessage("MsgDeactivateClient",
+				protoField("creator", 1, str, false),
+				protoField("client_id", 2, str, false),
+				protoField("reason", 3, str, false),
+			),
+		},
+	})
+}
+
+func buildAnalysisDescriptor() []byte {
+	str := descriptorpb.FieldDescriptorProto_TYPE_STRING
+	return gzipDescriptor(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String(analysisProtoFile),
+		Package:    proto.String("medas.analysis.v1"),
+		Dependency: []string{"cosmos/msg/v1/msg.proto"},
+		Syntax:     proto.String("proto3"),
+		Options:    &descriptorpb.FileOptions{GoPackage: proto.String("github.com/oxygene76/medasdigital-client/pkg/blockchain")},
+		MessageType: []*descriptorpb.DescriptorProto{
+			signedMessage("MsgStoreAnalysis",
+				protoField("creator", 1, str, false),
+				protoField("client_id", 2, str, false),
+				protoField("analysis_type", 3, str, false),
+				protoField("data", 4, str, false),
+				protoField("block_height", 5, descriptorpb.FieldDescriptorProto_TYPE_INT64, false),
+				protoField("tx_hash", 6, str, false),
+			),
+		},
+	})
+}
+
+func gzipDescriptor(file *descriptorpb.FileDescriptorProto) []byte {
+	raw, err := proto.Marshal(file)
+	if err != nil {
+		panic(err)
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(raw); err != nil {
+		panic(err)
+	}
+	if err := zw.Close(); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,76 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// ModelMemoPrefix marks transactions that anchor a model weights hash
+const ModelMemoPrefix = "MEDAS_MODEL_REG:"
+
+// ModelMemo builds the memo for a model hash, optionally tagged with the training job ID
+func ModelMemo(sha256Hex, jobID string) string {
+	memo := ModelMemoPrefix + strings.ToLower(sha256Hex)
+	if jobID != "" {
+		memo += ":" + jobID
+	}
+	// Memo limit is 256 bytes
+	if len(memo) > 256 {
+		memo = memo[:256]
+	}
+	return memo
+}
+
+// RegisterModelHash anchors a model hash on chain with a minimal self-send
+func (rm *RegistrationManager) RegisterModelHash(clientCtx client.Context, fromAddress, sha256Hex, jobID string) (*sdk.TxResponse, error) {
+	if len(sha256Hex) != 64 {
+		return nil, fmt.Errorf("invalid sha256 hash: %s", sha256Hex)
+	}
+
+	memo := ModelMemo(sha256Hex, jobID)
+	fmt.Printf("📋 Model memo: %s (%d bytes)\n", memo, len(memo))
+	fmt.Println("📡 Broadcasting model registration transaction...")
+
+	return rm.broadcastMemoTx(clientCtx, fromAddress, memo, rm.config.GasLimit)
+}
+
+// RegisterModelHash anchors a model hash using the default registration settings
+func RegisterModelHash(clientCtx client.Context, fromAddress, sha256Hex, jobID string) (*sdk.TxResponse, error) {
+	rm := NewRegistrationManager(network.Current().BaseDenom)
+	return rm.RegisterModelHash(clientCtx, fromAddress, sha256Hex, jobID)
+}
+
+// VerifyModelHash checks that txHash is a successful transaction anchoring sha256Hex
+func VerifyModelHash(ctx context.Context, clientCtx client.Context, txHash, sha256Hex string) error {
+	queryClient := txtypes.NewServiceClient(clientCtx)
+
+	stopTimer := telemetry.Track(telemetry.CategoryRPC, "get_tx")
+	resp, err := queryClient.GetTx(ctx, &txtypes.GetTxRequest{Hash: txHash})
+	stopTimer()
+	if err != nil && txNotFound(err) {
+		return fmt.Errorf("%w: %s", ErrTxNotFound, txHash)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query transaction %s: %w", txHash, err)
+	}
+	if resp.TxResponse == nil || resp.Tx == nil || resp.Tx.Body == nil {
+		return fmt.Errorf("%w: %s", ErrTxNotFound, txHash)
+	}
+	if resp.TxResponse.Code != 0 {
+		return fmt.Errorf("transaction %s failed with code %d", txHash, resp.TxResponse.Code)
+	}
+
+	expected := ModelMemoPrefix + strings.ToLower(sha256Hex)
+	if !strings.HasPrefix(resp.Tx.Body.Memo, expected) {
+		return fmt.Errorf("transaction %s does not anchor model %s (memo: %q)", txHash, sha256Hex, resp.Tx.Body.Memo)
+	}
+	return nil
+}
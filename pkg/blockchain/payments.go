@@ -0,0 +1,96 @@
+package blockchain
+
+import (
+	"bytes"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// payment is a transfer in a transaction that can settle a payment
+type payment struct {
+	sender    string
+	recipient string
+	amount    sdk.Coins
+	via       string // msg_send, multi_send, authz or ibc
+}
+
+// paymentsFromMsgs collects the transfers of bank sends, multi-sends and sends
+// executed through an authz grant, as exchanges and custodial wallets do
+func paymentsFromMsgs(msgs []sdk.Msg) []payment {
+	var payments []payment
+	for _, msg := range msgs {
+		switch m := msg.(type) {
+		case *banktypes.MsgSend:
+			payments = append(payments, payment{m.FromAddress, m.ToAddress, m.Amount, "msg_send"})
+		case *banktypes.MsgMultiSend:
+			// Since v0.47 a multi-send has exactly one input
+			if len(m.Inputs) != 1 {
+				continue
+			}
+			for _, out := range m.Outputs {
+				payments = append(payments, payment{m.Inputs[0].Address, out.Address, out.Coins, "multi_send"})
+			}
+		case *authz.MsgExec:
+			inner, err := m.GetMessages()
+			if err != nil {
+				continue
+			}
+			for _, p := range paymentsFromMsgs(inner) {
+				p.via = "authz"
+				payments = append(payments, p)
+			}
+		}
+	}
+	return payments
+}
+
+// ibcPayments collects the tokens received with IBC transfers. The packet
+// event names the sender on the source chain, the voucher paid out is in the
+// bank transfer events before it. Relayers may receive several packets in
+// one transaction.
+func ibcPayments(events []abci.Event) []payment {
+	var payments, received []payment
+	for _, event := range events {
+		attrs := eventAttributes(event)
+		switch event.Type {
+		case "transfer":
+			if amount, err := sdk.ParseCoinsNormalized(attrs["amount"]); err == nil {
+				received = append(received, payment{recipient: attrs["recipient"], amount: amount})
+			}
+		case "fungible_token_packet":
+			// Acknowledgements on the sending side use the same event type
+			if attrs["success"] == "true" && attrs["acknowledgement"] == "" {
+				for _, r := range received {
+					if r.recipient == attrs["receiver"] {
+						payments = append(payments, payment{attrs["sender"], r.recipient, r.amount, "ibc"})
+					}
+				}
+			}
+			received = nil
+		}
+	}
+	return payments
+}
+
+func eventAttributes(event abci.Event) map[string]string {
+	attrs := make(map[string]string, len(event.Attributes))
+	for _, attr := range event.Attributes {
+		attrs[attr.Key] = attr.Value
+	}
+	return attrs
+}
+
+// sameAccount compares addresses by their bytes, so the sender of an IBC
+// transfer also matches with the prefix of this chain
+func sameAccount(a, b string) bool {
+	if a == b {
+		return true
+	}
+	_, aBytes, errA := bech32.DecodeAndConvert(a)
+	_, bBytes, errB := bech32.DecodeAndConvert(b)
+	return errA == nil && errB == nil && bytes.Equal(aBytes, bBytes)
+}
@@ -6,9 +6,11 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -20,6 +22,10 @@ import (
 	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
 	sdkmath "cosmossdk.io/math"
+
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+	"github.com/oxygene76/medasdigital-client/pkg/state"
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
 )
 
 // Enhanced Client Registration Data for Chat System
@@ -103,6 +109,13 @@ type TxData struct {
 	Denom       string
 	Fee         string
 	Memo        string
+	Msgs        []sdk.Msg
+}
+
+// Registration returns the registration the transaction carries, from a
+// MsgRegisterClient or the memo
+func (d *TxData) Registration() (*RegistrationMemo, error) {
+	return ParseRegistrationTx(d.Msgs, d.Memo)
 }
 
 // NewRegistrationManager creates a new registration manager
@@ -132,24 +145,9 @@ func (rm *RegistrationManager) CheckExistingRegistration(address string, regType
 
 // getLocalRegistrationByAddress searches local registrations for address and type
 func (rm *RegistrationManager) getLocalRegistrationByAddress(address string, regType string) (*RegistrationResult, error) {
-	homeDir, _ := os.UserHomeDir()
-	indexPath := filepath.Join(homeDir, ".medasdigital-client", "registrations", "index.json")
-	
-	// Check if index file exists
-	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("no local registrations found")
-	}
-	
-	// Read index file
-	data, err := os.ReadFile(indexPath)
+	registrations, err := readRegistrationIndex(state.Current())
 	if err != nil {
-		return nil, fmt.Errorf("failed to read registration index: %w", err)
-	}
-	
-	// Parse registrations
-	var registrations []RegistrationResult
-	if err := json.Unmarshal(data, &registrations); err != nil {
-		return nil, fmt.Errorf("failed to parse registration index: %w", err)
+		return nil, err
 	}
 	
 	// Search for address AND registration type in registrations
@@ -336,35 +334,122 @@ func (rm *RegistrationManager) RegisterChatClient(clientCtx client.Context, regi
 		registration.Capabilities = rm.config.DefaultCapabilities
 	}
 	
-	// Generate chat keys if not provided
-	if len(registration.ChatPubKey) == 0 {
-		registration.ChatPubKey = rm.generateChatPubKey()
-	}
-	
 	// Use internal registration function
 	return rm.performRegistration(clientCtx, registration.ClientAddress, registration, rm.config.GasLimit, "chat")
 }
 // performRegistration handles the actual blockchain transaction
 func (rm *RegistrationManager) performRegistration(clientCtx client.Context, fromAddress string, regData interface{}, gas uint64, regType string) (*RegistrationResult, error) {
-	// Create minimal memo for blockchain (max 256 chars limit)
-	timestamp := time.Now().Unix()
-	memo := fmt.Sprintf("MEDAS_%s_REG:%d", strings.ToUpper(regType), timestamp)
+	// Refuse what other clients would reject as malformed
+	if err := ValidateRegistrationData(regData); err != nil {
+		return nil, fmt.Errorf("invalid %s registration: %w", regType, err)
+	}
 	
-	fmt.Printf("📋 Minimal memo: %s (%d bytes)\n", memo, len(memo))
+	// Capabilities and endpoints go on chain for discovery, the rest is stored locally
+	memo := registrationMemoFor(regType, regData).String()
+	if _, err := ParseRegistrationMemo(memo); err != nil {
+		return nil, fmt.Errorf("invalid registration memo: %w", err)
+	}
 	
+	result, err := rm.broadcastRegistration(clientCtx, fromAddress, regType, regData, memo, gas)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Generate client ID
+	clientID := rm.generateClientID(result.TxHash)
+	
+	// Create registration result with FULL data (stored locally only)
+	regResult := &RegistrationResult{
+		TransactionHash:  result.TxHash,
+		ClientID:         clientID,
+		RegistrationData: regData, // Full registration data stored locally
+		BlockHeight:      result.Height,
+		RegisteredAt:     time.Now(),
+		RegistrationType: regType,
+	}
+	
+	// Save complete registration locally (no size limit)
+	if err := rm.saveRegistrationResult(regResult); err != nil {
+		fmt.Printf("⚠️  Warning: Failed to save registration locally: %v\n", err)
+	}
+	
+	fmt.Println("✅ Registration transaction successful!")
+	fmt.Printf("📝 Transaction Hash: %s\n", result.TxHash)
+	fmt.Printf("🆔 Client ID: %s\n", clientID)
+	fmt.Printf("💾 Full registration data saved locally\n")
+	
+	return regResult, nil
+}
+
+// broadcastRegistration broadcasts a MsgRegisterClient and falls back to the
+// memo self-send when the message does not pass its checks or the chain does
+// not know the client registry module
+func (rm *RegistrationManager) broadcastRegistration(clientCtx client.Context, fromAddress, regType string, regData interface{}, memo string, gas uint64) (*sdk.TxResponse, error) {
+	msg := NewMsgRegisterClient(fromAddress, regType, regData)
+	if err := msg.ValidateBasic(); err != nil {
+		fmt.Printf("⚠️  Registration message not usable (%v), using memo registration\n", err)
+	} else {
+		fmt.Println("📡 Broadcasting MsgRegisterClient...")
+		result, err := rm.signAndBroadcast(clientCtx, fromAddress, []sdk.Msg{msg}, "", gas)
+		if err == nil || !unknownMsgType(err) {
+			return result, err
+		}
+		fmt.Println("⚠️  Chain does not support MsgRegisterClient, using memo registration")
+	}
+	
+	fmt.Printf("📋 Minimal memo: %s (%d bytes)\n", memo, len(memo))
+	fmt.Println("📡 Broadcasting registration transaction...")
+	return rm.broadcastMemoTx(clientCtx, fromAddress, memo, gas)
+}
+
+// unknownMsgType reports whether the chain rejected a transaction because it
+// cannot decode one of its messages
+func unknownMsgType(err error) bool {
+	return strings.Contains(err.Error(), "unable to resolve type URL")
+}
+
+// broadcastMemoTx signs and broadcasts a minimal self-send carrying memo
+func (rm *RegistrationManager) broadcastMemoTx(clientCtx client.Context, fromAddress, memo string, gas uint64) (*sdk.TxResponse, error) {
+	return rm.sendMemoTx(clientCtx, fromAddress, fromAddress, memo, gas)
+}
+
+// SendMemo broadcasts a minimal send to toAddress carrying memo
+func (rm *RegistrationManager) SendMemo(clientCtx client.Context, fromAddress, toAddress, memo string) (*sdk.TxResponse, error) {
+	if _, err := sdk.AccAddressFromBech32(toAddress); err != nil {
+		return nil, fmt.Errorf("invalid recipient address: %w", err)
+	}
+	return rm.sendMemoTx(clientCtx, fromAddress, toAddress, memo, rm.config.GasLimit)
+}
+
+// sendMemoTx signs and broadcasts a minimal send carrying memo
+func (rm *RegistrationManager) sendMemoTx(clientCtx client.Context, fromAddress, toAddress, memo string, gas uint64) (*sdk.TxResponse, error) {
 	// Parse address
 	fromAddr, err := sdk.AccAddressFromBech32(fromAddress)
 	if err != nil {
 		return nil, fmt.Errorf("invalid from address: %w", err)
 	}
+	toAddr, err := sdk.AccAddressFromBech32(toAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to address: %w", err)
+	}
 	
-	// Create self-send transaction with minimal memo
+	// Create send transaction with minimal memo
 	amount := sdk.NewCoins(sdk.NewCoin(rm.config.BaseDenom, sdkmath.NewInt(rm.config.RegistrationFee)))
-	msgSend := banktypes.NewMsgSend(fromAddr, fromAddr, amount)
+	msgSend := banktypes.NewMsgSend(fromAddr, toAddr, amount)
+	
+	return rm.signAndBroadcast(clientCtx, fromAddress, []sdk.Msg{msgSend}, memo, gas)
+}
+
+// signAndBroadcast signs msgs with the key of fromAddress and broadcasts them
+func (rm *RegistrationManager) signAndBroadcast(clientCtx client.Context, fromAddress string, msgs []sdk.Msg, memo string, gas uint64) (*sdk.TxResponse, error) {
+	fromAddr, err := sdk.AccAddressFromBech32(fromAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address: %w", err)
+	}
 	
 	// Create transaction builder
 	txBuilder := clientCtx.TxConfig.NewTxBuilder()
-	if err := txBuilder.SetMsgs(msgSend); err != nil {
+	if err := txBuilder.SetMsgs(msgs...); err != nil {
 		return nil, fmt.Errorf("failed to set messages: %w", err)
 	}
 	
@@ -387,12 +472,15 @@ func (rm *RegistrationManager) performRegistration(clientCtx client.Context, fro
 	}
 	feeAmount := sdk.NewCoins(sdk.NewCoin(rm.config.BaseDenom, totalFee))
 	txBuilder.SetFeeAmount(feeAmount)
+	txBuilder.SetFeeGranter(clientCtx.FeeGranter)
 	
 	fmt.Printf("💰 Calculated fee: %s %s\n", totalFee.String(), rm.config.BaseDenom)
 	
 	// Get account info for signing
 	accountRetriever := authtypes.AccountRetriever{}
+	stopTimer := telemetry.Track(telemetry.CategoryRPC, "get_account")
 	account, err := accountRetriever.GetAccount(clientCtx, fromAddr)
+	stopTimer()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get account info: %w", err)
 	}
@@ -414,7 +502,9 @@ func (rm *RegistrationManager) performRegistration(clientCtx client.Context, fro
 		return nil, fmt.Errorf("from name not set in client context")
 	}
 	
-	err = tx.Sign(context.Background(), txFactory, fromName, txBuilder, true)
+	stopTimer = telemetry.Track(telemetry.CategorySigning, "sign_tx")
+	err = tx.Sign(cmdContext(clientCtx), txFactory, fromName, txBuilder, true)
+	stopTimer()
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign transaction: %w", err)
 	}
@@ -425,40 +515,18 @@ func (rm *RegistrationManager) performRegistration(clientCtx client.Context, fro
 		return nil, fmt.Errorf("failed to encode transaction: %w", err)
 	}
 	
-	fmt.Println("📡 Broadcasting registration transaction...")
-	result, err := clientCtx.BroadcastTx(txBytes)
+	stopTimer = telemetry.Track(telemetry.CategoryRPC, "broadcast_tx")
+	result, err := BroadcastContext(cmdContext(clientCtx), clientCtx, txBytes)
+	stopTimer()
 	if err != nil {
 		return nil, fmt.Errorf("failed to broadcast transaction: %w", err)
 	}
 	
 	if result.Code != 0 {
-		return nil, fmt.Errorf("transaction failed with code %d: %s", result.Code, result.RawLog)
-	}
-	
-	// Generate client ID
-	clientID := rm.generateClientID(result.TxHash)
-	
-	// Create registration result with FULL data (stored locally only)
-	regResult := &RegistrationResult{
-		TransactionHash:  result.TxHash,
-		ClientID:         clientID,
-		RegistrationData: regData, // Full registration data stored locally
-		BlockHeight:      result.Height,
-		RegisteredAt:     time.Now(),
-		RegistrationType: regType,
+		return nil, &TxFailedError{TxHash: result.TxHash, Codespace: result.Codespace, Code: result.Code, Log: result.RawLog}
 	}
 	
-	// Save complete registration locally (no size limit)
-	if err := rm.saveRegistrationResult(regResult); err != nil {
-		fmt.Printf("⚠️  Warning: Failed to save registration locally: %v\n", err)
-	}
-	
-	fmt.Println("✅ Registration transaction successful!")
-	fmt.Printf("📝 Transaction Hash: %s\n", result.TxHash)
-	fmt.Printf("🆔 Client ID: %s\n", clientID)
-	fmt.Printf("💾 Full registration data saved locally\n")
-	
-	return regResult, nil
+	return result, nil
 }
 
 // validateChatRegistration validates chat registration data
@@ -483,6 +551,11 @@ func (rm *RegistrationManager) validateChatRegistration(reg *ChatClientRegistrat
 		reg.Capabilities = rm.config.DefaultCapabilities
 	}
 	
+	// Messages are encrypted to this key, see pkg/chat
+	if len(reg.ChatPubKey) != 32 {
+		return fmt.Errorf("chat public key must be a 32 byte X25519 key, got %d bytes", len(reg.ChatPubKey))
+	}
+	
 	// Validate registration type
 	validTypes := []string{"researcher", "institution", "student", "developer"}
 	if reg.RegistrationType != "" {
@@ -511,60 +584,93 @@ func (rm *RegistrationManager) generateClientID(txHash string) string {
 	return fmt.Sprintf("client-%s", shortHash)
 }
 
-// generateChatPubKey generates a new chat public key (mock implementation)
-func (rm *RegistrationManager) generateChatPubKey() []byte {
-	// TODO: Implement proper Ed25519 key generation
-	// For now, return a mock key
-	return []byte("mock_chat_pubkey_" + fmt.Sprintf("%d", time.Now().Unix()))
-}
-
 // saveRegistrationResult saves registration to local storage
 func (rm *RegistrationManager) saveRegistrationResult(result *RegistrationResult) error {
-	// Create registrations directory
-	homeDir, _ := os.UserHomeDir()
-	regDir := filepath.Join(homeDir, ".medasdigital-client", "registrations")
-	if err := os.MkdirAll(regDir, 0755); err != nil {
-		return fmt.Errorf("failed to create registrations directory: %w", err)
+	defer telemetry.Track(telemetry.CategoryDisk, "save_registration")()
+
+	home := state.Current()
+	lock, err := home.Lock(state.LockRegistrations)
+	if err != nil {
+		return err
 	}
-	
+	defer lock.Unlock()
+
 	// Save individual registration file
 	filename := fmt.Sprintf("registration-%s.json", result.ClientID)
-	filepath := filepath.Join(regDir, filename)
-	
-	data, err := json.MarshalIndent(result, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal registration result: %w", err)
-	}
-	
-	if err := os.WriteFile(filepath, data, 0644); err != nil {
+	if err := state.WriteJSON(home.Path(state.RegistrationsDir, filename), result, 0644); err != nil {
 		return fmt.Errorf("failed to write registration file: %w", err)
 	}
 	
 	// Update index
-	return rm.updateRegistrationIndex(result)
+	return rm.updateRegistrationIndex(home, result)
 }
 
-// updateRegistrationIndex updates the registration index file
-func (rm *RegistrationManager) updateRegistrationIndex(result *RegistrationResult) error {
-	homeDir, _ := os.UserHomeDir()
-	indexPath := filepath.Join(homeDir, ".medasdigital-client", "registrations", "index.json")
-	
-	// Load existing index
-	var index []RegistrationResult
-	if data, err := os.ReadFile(indexPath); err == nil {
-		json.Unmarshal(data, &index)
+// updateRegistrationIndex adds result to the registration index, the caller
+// holds the registrations lock
+func (rm *RegistrationManager) updateRegistrationIndex(home *state.Home, result *RegistrationResult) error {
+	index, err := readRegistrationIndex(home)
+	if err != nil && !errors.Is(err, errNoRegistrations) {
+		// A damaged index is rebuilt from the registration files
+		if index, err = scanRegistrationFiles(home); err != nil {
+			return err
+		}
 	}
 	
-	// Add new registration
-	index = append(index, *result)
+	// Replace an entry of the same client, add new registrations
+	replaced := false
+	for i := range index {
+		if index[i].ClientID == result.ClientID {
+			index[i] = *result
+			replaced = true
+		}
+	}
+	if !replaced {
+		index = append(index, *result)
+	}
 	
-	// Save updated index
-	data, err := json.MarshalIndent(index, "", "  ")
+	return state.WriteJSON(home.Path(state.RegistrationIndex), index, 0644)
+}
+
+// errNoRegistrations is returned while nothing was registered from this home
+var errNoRegistrations = errors.New("no local registrations found")
+
+// readRegistrationIndex reads the registration index of home
+func readRegistrationIndex(home *state.Home) ([]RegistrationResult, error) {
+	data, err := os.ReadFile(home.Path(state.RegistrationIndex))
+	if os.IsNotExist(err) {
+		return nil, errNoRegistrations
+	}
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to read registration index: %w", err)
 	}
 	
-	return os.WriteFile(indexPath, data, 0644)
+	var registrations []RegistrationResult
+	if err := json.Unmarshal(data, &registrations); err != nil {
+		return nil, fmt.Errorf("failed to parse registration index: %w", err)
+	}
+	return registrations, nil
+}
+
+// scanRegistrationFiles reads the registration files of home, oldest first
+func scanRegistrationFiles(home *state.Home) ([]RegistrationResult, error) {
+	paths, err := filepath.Glob(home.Path(state.RegistrationsDir, "registration-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var registrations []RegistrationResult
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var reg RegistrationResult
+		if err := json.Unmarshal(data, &reg); err != nil {
+			continue // skip damaged files, the chain still has the registration
+		}
+		registrations = append(registrations, reg)
+	}
+	sort.Slice(registrations, func(i, j int) bool { return registrations[i].RegisteredAt.Before(registrations[j].RegisteredAt) })
+	return registrations, nil
 }
 
 // QueryRegistrations queries registrations from blockchain
@@ -588,11 +694,13 @@ func (rm *RegistrationManager) QueryRegistrations(clientCtx client.Context, quer
 	
 	fmt.Printf("🔍 Searching for registrations: %s\n", query)
 	
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(cmdContext(clientCtx), 30*time.Second)
 	defer cancel()
 	
 	// Search transactions
+	stopTimer := telemetry.Track(telemetry.CategoryRPC, "tx_search")
 	searchResult, err := rpcClient.TxSearch(ctx, query, false, nil, nil, "desc")
+	stopTimer()
 	if err != nil {
 		return nil, fmt.Errorf("failed to search transactions: %w", err)
 	}
@@ -623,8 +731,7 @@ func (rm *RegistrationManager) parseRegistrationFromTx(tx interface{}) (*Registr
 
 // Helper function for backward compatibility with existing main.go
 func RegisterClientSimple(clientCtx client.Context, fromAddress string, capabilities []string, metadata string, gas uint64) (*RegistrationResult, error) {
-	// Extract base denom from clientCtx or use default
-	baseDenom := "umedas" // Default, should be extracted from config
+	baseDenom := network.Current().BaseDenom
 	
 	rm := NewRegistrationManager(baseDenom)
 	return rm.RegisterClientSimple(clientCtx, fromAddress, capabilities, metadata, gas)
@@ -632,30 +739,23 @@ func RegisterClientSimple(clientCtx client.Context, fromAddress string, capabili
 
 // Enhanced registration function for chat system
 func RegisterChatClient(clientCtx client.Context, registration *ChatClientRegistration) (*RegistrationResult, error) {
-	// Extract base denom from clientCtx or use default
-	baseDenom := "umedas" // Default, should be extracted from config
+	baseDenom := network.Current().BaseDenom
 	
 	rm := NewRegistrationManager(baseDenom)
 	return rm.RegisterChatClient(clientCtx, registration)
 }
 
+// SendMemo sends a memo to toAddress using the default registration settings
+func SendMemo(clientCtx client.Context, fromAddress, toAddress, memo string) (*sdk.TxResponse, error) {
+	rm := NewRegistrationManager(network.Current().BaseDenom)
+	return rm.SendMemo(clientCtx, fromAddress, toAddress, memo)
+}
+
 // GetLocalRegistrationHashes retrieves local registration transaction hashes
 func GetLocalRegistrationHashes() ([]string, error) {
-	homeDir, _ := os.UserHomeDir()
-	indexPath := filepath.Join(homeDir, ".medasdigital-client", "registrations", "index.json")
-	
-	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("no local registrations found")
-	}
-	
-	data, err := os.ReadFile(indexPath)
+	registrations, err := readRegistrationIndex(state.Current())
 	if err != nil {
-		return nil, fmt.Errorf("failed to read index file: %w", err)
-	}
-	
-	var registrations []RegistrationResult
-	if err := json.Unmarshal(data, &registrations); err != nil {
-		return nil, fmt.Errorf("failed to parse index file: %w", err)
+		return nil, err
 	}
 	
 	var hashes []string
@@ -669,7 +769,7 @@ func GetLocalRegistrationHashes() ([]string, error) {
 }
 
 // FetchRegistrationFromBlockchain fetches complete registration data from blockchain
-func FetchRegistrationFromBlockchain(txHash string, rpcEndpoint, chainID string, codec codec.Codec) (*BlockchainRegistrationData, error) {
+func FetchRegistrationFromBlockchain(ctx context.Context, txHash string, rpcEndpoint, chainID string, codec codec.Codec) (*BlockchainRegistrationData, error) {
 	// Create RPC client
 	rpcClient, err := client.NewClientFromNode(rpcEndpoint)
 	if err != nil {
@@ -682,13 +782,16 @@ func FetchRegistrationFromBlockchain(txHash string, rpcEndpoint, chainID string,
 		return nil, fmt.Errorf("invalid transaction hash: %w", err)
 	}
 	
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 	
 	// Get transaction details
 	txResult, err := rpcClient.Tx(ctx, hashBytes, false)
+	if err != nil && txNotFound(err) {
+		return nil, fmt.Errorf("%w: %s", ErrTxNotFound, txHash)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("transaction not found: %w", err)
+		return nil, fmt.Errorf("failed to query transaction %s: %w", txHash, err)
 	}
 	
 	// Get block details for timestamp
@@ -716,24 +819,23 @@ func FetchRegistrationFromBlockchain(txHash string, rpcEndpoint, chainID string,
 		regData.Fee = txData.Fee
 		regData.Memo = txData.Memo
 		
-		// Parse memo for registration data
-		if regData.Memo != "" {
-			// Try to extract JSON from memo (remove prefix if present)
-			memoContent := regData.Memo
-			if strings.Contains(memoContent, "MEDAS_CLIENT_REG:") {
-				memoContent = strings.Replace(memoContent, "MEDAS_CLIENT_REG:", "", 1)
-			}
-			if strings.Contains(memoContent, "MEDAS_CHAT_REG:") {
-				memoContent = strings.Replace(memoContent, "MEDAS_CHAT_REG:", "", 1)
-			}
-			
-			var clientRegData ClientRegistrationData
-			if err := json.Unmarshal([]byte(memoContent), &clientRegData); err == nil {
-				regData.RegistrationData = clientRegData
+		// Parse the registration message or memo
+		if regData.Memo != "" || len(txData.Msgs) > 0 {
+			if memo, err := txData.Registration(); err == nil {
+				registeredAt := time.Unix(memo.Timestamp, 0)
+				if memo.Timestamp == 0 {
+					registeredAt = regData.BlockTime
+				}
+				regData.RegistrationData = ClientRegistrationData{
+					ClientAddress: txData.FromAddress,
+					Capabilities:  memo.Capabilities,
+					Metadata:      registrationMetadata(txData.Msgs),
+					Timestamp:     registeredAt,
+				}
 				regData.ClientID = GenerateClientIDFromHash(txHash)
 				regData.VerificationStatus = "✅ Valid"
 			} else {
-				regData.VerificationStatus = "⚠️  Invalid memo format"
+				regData.VerificationStatus = fmt.Sprintf("⚠️  Invalid registration: %v", err)
 			}
 		}
 	}
@@ -741,7 +843,17 @@ func FetchRegistrationFromBlockchain(txHash string, rpcEndpoint, chainID string,
 	return regData, nil
 }
 
-// DecodeTxData decodes transaction data (simplified for MsgSend)
+// registrationMetadata returns the metadata of a MsgRegisterClient in msgs
+func registrationMetadata(msgs []sdk.Msg) string {
+	for _, msg := range msgs {
+		if reg, ok := msg.(*MsgRegisterClient); ok {
+			return reg.Metadata
+		}
+	}
+	return ""
+}
+
+// DecodeTxData decodes transaction data of a MsgSend or MsgRegisterClient
 func DecodeTxData(txBytes []byte, codec codec.Codec) (*TxData, error) {
 	// Create TxConfig from codec
 	txConfig := authtx.NewTxConfig(codec, authtx.DefaultSignModes)
@@ -770,6 +882,7 @@ func DecodeTxData(txBytes []byte, codec codec.Codec) (*TxData, error) {
 	
 	// Extract message data
 	msgs := tx.GetMsgs()
+	txData.Msgs = msgs
 	if len(msgs) > 0 {
 		// Try to cast to MsgSend
 		for _, msg := range msgs {
@@ -787,6 +900,16 @@ func DecodeTxData(txBytes []byte, codec codec.Codec) (*TxData, error) {
 		}
 	}
 	
+	// Registrations by message have no send, the creator signed them
+	if txData.FromAddress == "" {
+		for _, msg := range msgs {
+			if reg, ok := msg.(*MsgRegisterClient); ok {
+				txData.FromAddress = reg.Creator
+				break
+			}
+		}
+	}
+	
 	return txData, nil
 }
 
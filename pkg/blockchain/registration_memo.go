@@ -0,0 +1,171 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// Registration memo prefixes. MEDAS_CLIENT_REG is the original format with the
+// full registration JSON, newer clients write MEDAS_SIMPLE_REG and MEDAS_CHAT_REG.
+const (
+	ClientRegMemoPrefix = "MEDAS_CLIENT_REG:"
+	SimpleRegMemoPrefix = "MEDAS_SIMPLE_REG:"
+	ChatRegMemoPrefix   = "MEDAS_CHAT_REG:"
+)
+
+// maxMemoLength is the default memo limit of the auth module
+const maxMemoLength = 256
+
+// RegistrationMemo is the public part of a registration that is written to
+// the memo, so other clients can discover it on chain
+type RegistrationMemo struct {
+	Type         string   `json:"-"` // simple or chat
+	Timestamp    int64    `json:"ts"`
+	Capabilities []string `json:"capabilities,omitempty"`
+	Endpoints    []string `json:"endpoints,omitempty"`
+	DisplayName  string   `json:"name,omitempty"`
+	ChatKey      []byte   `json:"chat_key,omitempty"` // X25519, base64 in JSON
+}
+
+// String renders the memo, dropping name, endpoints and capabilities in that
+// order until it fits the memo limit. The chat key is kept, without it the
+// client cannot receive messages.
+func (m RegistrationMemo) String() string {
+	prefix := SimpleRegMemoPrefix
+	if m.Type == "chat" {
+		prefix = ChatRegMemoPrefix
+	}
+
+	candidates := []RegistrationMemo{
+		m,
+		{Timestamp: m.Timestamp, Capabilities: m.Capabilities, Endpoints: m.Endpoints, ChatKey: m.ChatKey},
+		{Timestamp: m.Timestamp, Capabilities: m.Capabilities, ChatKey: m.ChatKey},
+		{Timestamp: m.Timestamp, ChatKey: m.ChatKey},
+	}
+	for _, c := range candidates {
+		data, err := json.Marshal(c)
+		if err == nil && len(prefix)+len(data) <= maxMemoLength {
+			return prefix + string(data)
+		}
+	}
+	return prefix + strconv.FormatInt(m.Timestamp, 10)
+}
+
+// registrationMemoFor builds the memo of a registration
+func registrationMemoFor(regType string, regData interface{}) RegistrationMemo {
+	memo := RegistrationMemo{Type: regType, Timestamp: time.Now().Unix()}
+	switch data := regData.(type) {
+	case ClientRegistrationData:
+		memo.Capabilities = data.Capabilities
+	case *ChatClientRegistration:
+		memo.Capabilities = data.Capabilities
+		memo.Endpoints = data.ChatEndpoints
+		memo.DisplayName = data.DisplayName
+		memo.ChatKey = data.ChatPubKey
+	}
+	return memo
+}
+
+// IsRegistrationMemo reports whether memo has a registration prefix
+func IsRegistrationMemo(memo string) bool {
+	return strings.HasPrefix(memo, ClientRegMemoPrefix) || strings.HasPrefix(memo, SimpleRegMemoPrefix) || strings.HasPrefix(memo, ChatRegMemoPrefix)
+}
+
+// ParseRegistrationMemo reads any of the registration memo formats. Memos
+// with a registration prefix that violate the schemas fail with
+// ErrMalformedRegistration.
+func ParseRegistrationMemo(memo string) (*RegistrationMemo, error) {
+	var m RegistrationMemo
+	var payload string
+	switch {
+	case strings.HasPrefix(memo, ClientRegMemoPrefix):
+		// The original format carries the full ClientRegistrationData
+		payload = strings.TrimPrefix(memo, ClientRegMemoPrefix)
+		if err := validateRegistrationPayload(ClientRegistrationSchema, payload); err != nil {
+			return nil, err
+		}
+		var data ClientRegistrationData
+		if err := json.Unmarshal([]byte(payload), &data); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformedRegistration, err)
+		}
+		return &RegistrationMemo{Type: "simple", Timestamp: data.Timestamp.Unix(), Capabilities: data.Capabilities}, nil
+	case strings.HasPrefix(memo, SimpleRegMemoPrefix):
+		m.Type, payload = "simple", strings.TrimPrefix(memo, SimpleRegMemoPrefix)
+	case strings.HasPrefix(memo, ChatRegMemoPrefix):
+		m.Type, payload = "chat", strings.TrimPrefix(memo, ChatRegMemoPrefix)
+	default:
+		return nil, fmt.Errorf("not a registration memo")
+	}
+
+	// Clients before the JSON memo only wrote the timestamp
+	if ts, err := strconv.ParseInt(payload, 10, 64); err == nil {
+		if ts < 0 {
+			return nil, fmt.Errorf("%w: negative timestamp", ErrMalformedRegistration)
+		}
+		m.Timestamp = ts
+		return &m, nil
+	}
+	if err := validateRegistrationPayload(RegistrationMemoSchema, payload); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(payload), &m); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedRegistration, err)
+	}
+	return &m, nil
+}
+
+// NewMsgRegisterClient builds the registration message with the same public
+// fields as the registration memo
+func NewMsgRegisterClient(fromAddress, regType string, regData interface{}) *MsgRegisterClient {
+	msg := &MsgRegisterClient{Creator: fromAddress, RegistrationType: regType}
+	switch data := regData.(type) {
+	case ClientRegistrationData:
+		msg.Capabilities = data.Capabilities
+		msg.Metadata = data.Metadata
+	case *ChatClientRegistration:
+		msg.Capabilities = data.Capabilities
+		msg.Endpoints = data.ChatEndpoints
+		msg.DisplayName = data.DisplayName
+		msg.ChatKey = data.ChatPubKey
+	}
+	return msg
+}
+
+// RegistrationFromMsg reads a MsgRegisterClient like a registration memo. The
+// message has no timestamp, callers use the block time.
+func RegistrationFromMsg(msg *MsgRegisterClient) (*RegistrationMemo, error) {
+	m := &RegistrationMemo{
+		Type:         msg.RegistrationType,
+		Capabilities: msg.Capabilities,
+		Endpoints:    msg.Endpoints,
+		DisplayName:  msg.DisplayName,
+		ChatKey:      msg.ChatKey,
+	}
+	switch m.Type {
+	case "":
+		m.Type = "simple"
+	case "simple", "chat":
+	default:
+		return nil, fmt.Errorf("%w: unknown registration type %q", ErrMalformedRegistration, m.Type)
+	}
+	if err := registrationSchemas[RegistrationMemoSchema].ValidateValue(m); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedRegistration, err)
+	}
+	return m, nil
+}
+
+// ParseRegistrationTx reads the registration of a transaction, preferring a
+// MsgRegisterClient over the memo of older clients
+func ParseRegistrationTx(msgs []sdk.Msg, memo string) (*RegistrationMemo, error) {
+	for _, msg := range msgs {
+		if reg, ok := msg.(*MsgRegisterClient); ok {
+			return RegistrationFromMsg(reg)
+		}
+	}
+	return ParseRegistrationMemo(memo)
+}
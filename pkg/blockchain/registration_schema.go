@@ -0,0 +1,67 @@
+package blockchain
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+
+	"github.com/oxygene76/medasdigital-client/pkg/jsonschema"
+)
+
+// JSON Schemas of the registration data and the registration memo
+//
+//go:embed schemas/*.schema.json
+var schemaFiles embed.FS
+
+// Names of the registration schemas
+const (
+	ClientRegistrationSchema = "client_registration"
+	ChatRegistrationSchema   = "chat_registration"
+	RegistrationMemoSchema   = "registration_memo"
+)
+
+var registrationSchemas = map[string]*jsonschema.Schema{
+	ClientRegistrationSchema: mustLoadSchema(ClientRegistrationSchema),
+	ChatRegistrationSchema:   mustLoadSchema(ChatRegistrationSchema),
+	RegistrationMemoSchema:   mustLoadSchema(RegistrationMemoSchema),
+}
+
+func mustLoadSchema(name string) *jsonschema.Schema {
+	data, err := RegistrationSchema(name)
+	if err != nil {
+		panic(err)
+	}
+	return jsonschema.MustCompile(data)
+}
+
+// ErrMalformedRegistration marks a memo with a registration prefix whose
+// content violates the registration schemas
+var ErrMalformedRegistration = errors.New("malformed registration")
+
+// RegistrationSchema returns the JSON Schema document name
+func RegistrationSchema(name string) ([]byte, error) {
+	return schemaFiles.ReadFile("schemas/" + name + ".schema.json")
+}
+
+// ValidateRegistrationData checks ClientRegistrationData or a
+// ChatClientRegistration against its schema
+func ValidateRegistrationData(regData interface{}) error {
+	var name string
+	switch regData.(type) {
+	case ClientRegistrationData, *ClientRegistrationData:
+		name = ClientRegistrationSchema
+	case ChatClientRegistration, *ChatClientRegistration:
+		name = ChatRegistrationSchema
+	default:
+		return fmt.Errorf("no schema for registration data %T", regData)
+	}
+	return registrationSchemas[name].ValidateValue(regData)
+}
+
+// validateRegistrationPayload checks the JSON of a memo against schema name
+func validateRegistrationPayload(name, payload string) error {
+	if err := registrationSchemas[name].Validate([]byte(payload)); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformedRegistration, err)
+	}
+	return nil
+}
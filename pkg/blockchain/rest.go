@@ -0,0 +1,148 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// ErrNoRESTEndpoint is returned when chain.rest_endpoint is not configured.
+// The REST URL is never derived from the RPC endpoint, nodes serve it on
+// other hosts and ports.
+var ErrNoRESTEndpoint = errors.New("no REST endpoint configured, set chain.rest_endpoint")
+
+// REST is a client of the REST API (gRPC gateway) of a node
+type REST struct {
+	baseURL string
+	chainID string
+	http    *http.Client
+	healthy atomic.Bool
+}
+
+// NewREST creates a client for the REST API at endpoint, e.g.
+// https://api.medas-digital.io:1317. With a chainID the health probe checks
+// that the node serves that chain.
+func NewREST(endpoint, chainID string) (*REST, error) {
+	if endpoint == "" {
+		return nil, ErrNoRESTEndpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid REST endpoint %q, expected an http(s) URL", endpoint)
+	}
+	return &REST{
+		baseURL: strings.TrimSuffix(endpoint, "/"),
+		chainID: chainID,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Endpoint returns the REST URL
+func (r *REST) Endpoint() string {
+	return r.baseURL
+}
+
+// Health probes the node info of the endpoint and checks its chain ID
+func (r *REST) Health(ctx context.Context) error {
+	var info struct {
+		DefaultNodeInfo struct {
+			Network string `json:"network"`
+		} `json:"default_node_info"`
+	}
+	if err := r.getJSON(ctx, "node_info", "/cosmos/base/tendermint/v1beta1/node_info", nil, &info); err != nil {
+		return fmt.Errorf("REST endpoint %s is not healthy: %w", r.baseURL, err)
+	}
+	if network := info.DefaultNodeInfo.Network; r.chainID != "" && network != r.chainID {
+		return fmt.Errorf("REST endpoint %s serves chain %q, expected %q", r.baseURL, network, r.chainID)
+	}
+	r.healthy.Store(true)
+	return nil
+}
+
+// Balances queries all balances of an account, after a health probe on the
+// first call
+func (r *REST) Balances(ctx context.Context, address string) (sdk.Coins, error) {
+	if !r.healthy.Load() {
+		if err := r.Health(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	var coins sdk.Coins
+	query := url.Values{}
+	for {
+		var page struct {
+			Balances []struct {
+				Denom  string `json:"denom"`
+				Amount string `json:"amount"`
+			} `json:"balances"`
+			Pagination struct {
+				NextKey string `json:"next_key"`
+			} `json:"pagination"`
+		}
+		if err := r.getJSON(ctx, "rest_balances", "/cosmos/bank/v1beta1/balances/"+url.PathEscape(address), query, &page); err != nil {
+			return nil, fmt.Errorf("failed to query balance for %s: %w", address, err)
+		}
+		for _, b := range page.Balances {
+			amount, ok := sdkmath.NewIntFromString(b.Amount)
+			if !ok {
+				return nil, fmt.Errorf("invalid amount %q of %s", b.Amount, b.Denom)
+			}
+			coins = append(coins, sdk.NewCoin(b.Denom, amount))
+		}
+		if page.Pagination.NextKey == "" {
+			return coins.Sort(), nil
+		}
+		query.Set("pagination.key", page.Pagination.NextKey)
+	}
+}
+
+// getJSON decodes the response of a GET request, errors of the gateway carry
+// a message
+func (r *REST) getJSON(ctx context.Context, operation, path string, query url.Values, out interface{}) error {
+	endpoint := r.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	stopTimer := telemetry.Track(telemetry.CategoryRPC, operation)
+	resp, err := r.http.Do(req)
+	stopTimer()
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var gatewayErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &gatewayErr) == nil && gatewayErr.Message != "" {
+			return fmt.Errorf("HTTP %d: %s", resp.StatusCode, gatewayErr.Message)
+		}
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
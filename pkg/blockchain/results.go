@@ -0,0 +1,172 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	itypes "github.com/oxygene76/medasdigital-client/internal/types"
+)
+
+// Orderings of analysis results
+const (
+	OrderByCreated = "created"
+	OrderByHeight  = "height"
+)
+
+// ResultsQuery selects a page of analysis results. Zero values do not filter.
+type ResultsQuery struct {
+	ClientID     string
+	AnalysisType string
+	From, To     time.Time // created at, To is exclusive
+	Cursor       string    // NextCursor of the previous page
+	Limit        int
+	OrderBy      string // OrderByCreated (default) or OrderByHeight
+	Ascending    bool   // oldest first, newest first by default
+}
+
+// ResultsPage is one page of analysis results
+type ResultsPage struct {
+	Results    []*itypes.StoredAnalysis `json:"results"`
+	NextCursor string                   `json:"next_cursor,omitempty"`
+	Total      uint64                   `json:"total,omitempty"`
+}
+
+// resultsRequest is the AnalysisResults query with Cosmos pagination
+type resultsRequest struct {
+	ClientID     string            `json:"client_id,omitempty"`
+	AnalysisType string            `json:"analysis_type,omitempty"`
+	StartTime    *time.Time        `json:"start_time,omitempty"`
+	EndTime      *time.Time        `json:"end_time,omitempty"`
+	OrderBy      string            `json:"order_by,omitempty"`
+	Pagination   resultsPagination `json:"pagination"`
+	// Limit is kept for nodes without pagination support
+	Limit int `json:"limit"`
+}
+
+type resultsPagination struct {
+	Key        []byte `json:"key,omitempty"`
+	Limit      uint64 `json:"limit,string,omitempty"`
+	CountTotal bool   `json:"count_total,omitempty"`
+	Reverse    bool   `json:"reverse,omitempty"`
+}
+
+type resultsResponse struct {
+	Results    []*itypes.StoredAnalysis `json:"results"`
+	Pagination struct {
+		NextKey []byte `json:"next_key"`
+		Total   uint64 `json:"total,string"`
+	} `json:"pagination"`
+}
+
+// Validate checks the query before it is sent
+func (q ResultsQuery) Validate() error {
+	if q.Limit < 1 {
+		return fmt.Errorf("limit must be at least 1")
+	}
+	if q.AnalysisType != "" && !itypes.IsValidAnalysisType(q.AnalysisType) {
+		return fmt.Errorf("unknown analysis type %q", q.AnalysisType)
+	}
+	if q.OrderBy != "" && q.OrderBy != OrderByCreated && q.OrderBy != OrderByHeight {
+		return fmt.Errorf("unknown order %q (%s or %s)", q.OrderBy, OrderByCreated, OrderByHeight)
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.To.After(q.From) {
+		return fmt.Errorf("the date range ends before it starts")
+	}
+	if q.Cursor != "" {
+		if _, err := base64.StdEncoding.DecodeString(q.Cursor); err != nil {
+			return fmt.Errorf("invalid cursor: %w", err)
+		}
+	}
+	return nil
+}
+
+// QueryAnalysisResults retrieves one page of analysis results. The filters
+// are sent to the node and applied again to the page, since older nodes
+// ignore them and answer with a plain list.
+func (c *Client) QueryAnalysisResults(ctx context.Context, q ResultsQuery) (*ResultsPage, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	req := resultsRequest{
+		ClientID:     q.ClientID,
+		AnalysisType: q.AnalysisType,
+		OrderBy:      q.OrderBy,
+		Limit:        q.Limit,
+		Pagination: resultsPagination{
+			Limit:      uint64(q.Limit),
+			CountTotal: q.Cursor == "",
+			Reverse:    !q.Ascending,
+		},
+	}
+	if !q.From.IsZero() {
+		req.StartTime = &q.From
+	}
+	if !q.To.IsZero() {
+		req.EndTime = &q.To
+	}
+	if q.Cursor != "" {
+		req.Pagination.Key, _ = base64.StdEncoding.DecodeString(q.Cursor)
+	}
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _, err := QueryContext(ctx, c.clientCtx, "/medas.analysis.v1.Query/AnalysisResults", reqBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query analysis results: %w", err)
+	}
+
+	page := &ResultsPage{}
+	var resp resultsResponse
+	if err := c.codec.FromJSON(res, &resp); err == nil {
+		page.Results = resp.Results
+		page.Total = resp.Pagination.Total
+		if len(resp.Pagination.NextKey) > 0 {
+			page.NextCursor = base64.StdEncoding.EncodeToString(resp.Pagination.NextKey)
+		}
+	} else if err := c.codec.FromJSON(res, &page.Results); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal results: %w", err)
+	}
+
+	page.Results = filterResults(page.Results, q)
+	if len(page.Results) > q.Limit {
+		page.Results = page.Results[:q.Limit]
+	}
+	return page, nil
+}
+
+// filterResults applies the filters and the order of q
+func filterResults(results []*itypes.StoredAnalysis, q ResultsQuery) []*itypes.StoredAnalysis {
+	kept := results[:0]
+	for _, r := range results {
+		switch {
+		case r == nil:
+		case q.ClientID != "" && r.ClientID != q.ClientID:
+		case q.AnalysisType != "" && r.AnalysisType != q.AnalysisType:
+		case !q.From.IsZero() && r.CreatedAt.Before(q.From):
+		case !q.To.IsZero() && !r.CreatedAt.Before(q.To):
+		default:
+			kept = append(kept, r)
+		}
+	}
+
+	less := func(a, b *itypes.StoredAnalysis) bool {
+		if q.OrderBy == OrderByHeight && a.BlockHeight != b.BlockHeight {
+			return a.BlockHeight < b.BlockHeight
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		if q.Ascending {
+			return less(kept[i], kept[j])
+		}
+		return less(kept[j], kept[i])
+	})
+	return kept
+}
@@ -0,0 +1,72 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+
+	rpcclient "github.com/cometbft/cometbft/rpc/client"
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// The query and broadcast methods of client.Context call the node with
+// context.Background(), so Ctrl-C and deadlines do not reach them. The
+// functions here make the same calls bounded by ctx.
+
+// QueryContext is clientCtx.QueryWithData bounded by ctx
+func QueryContext(ctx context.Context, clientCtx client.Context, path string, data []byte) ([]byte, int64, error) {
+	node, err := clientCtx.GetNode()
+	if err != nil {
+		return nil, 0, err
+	}
+	res, err := node.ABCIQueryWithOptions(ctx, path, data, rpcclient.ABCIQueryOptions{Height: clientCtx.Height})
+	if err != nil {
+		return nil, 0, err
+	}
+	if !res.Response.IsOK() {
+		return nil, 0, fmt.Errorf("query failed with code %d: %s", res.Response.Code, res.Response.Log)
+	}
+	return res.Response.Value, res.Response.Height, nil
+}
+
+// BroadcastContext is clientCtx.BroadcastTx bounded by ctx. Modes other
+// than async, e.g. the "block" mode removed in SDK v0.50, broadcast sync. In
+// simulation mode (clientCtx.Simulate) the transaction is only simulated and
+// printed, the error is ErrDryRun.
+func BroadcastContext(ctx context.Context, clientCtx client.Context, txBytes []byte) (*sdk.TxResponse, error) {
+	if clientCtx.Simulate {
+		return nil, dryRun(ctx, clientCtx, txBytes)
+	}
+	node, err := clientCtx.GetNode()
+	if err != nil {
+		return nil, err
+	}
+	if clientCtx.BroadcastMode == flags.BroadcastAsync {
+		res, err := node.BroadcastTxAsync(ctx, txBytes)
+		if errRes := client.CheckCometError(err, txBytes); errRes != nil {
+			return errRes, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return sdk.NewResponseFormatBroadcastTx(res), nil
+	}
+	res, err := node.BroadcastTxSync(ctx, txBytes)
+	if errRes := client.CheckCometError(err, txBytes); errRes != nil {
+		return errRes, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sdk.NewResponseFormatBroadcastTx(res), nil
+}
+
+// cmdContext is the context set on clientCtx with WithCmdContext, the
+// background context if there is none
+func cmdContext(clientCtx client.Context) context.Context {
+	if clientCtx.CmdContext != nil {
+		return clientCtx.CmdContext
+	}
+	return context.Background()
+}
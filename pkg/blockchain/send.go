@@ -0,0 +1,204 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/tx"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// sendGasLimit covers a single MsgSend with a memo
+const sendGasLimit = 200000
+
+// Sender sends bank transfers from the key set as From in its client context.
+// It keeps track of the account sequence so transfers can be sent back to back
+// without waiting for each block.
+type Sender struct {
+	mu        sync.Mutex
+	clientCtx client.Context
+	accNum    uint64
+	sequence  uint64
+	loaded    bool
+}
+
+// NewSender creates a sender, clientCtx needs a keyring, FromName and FromAddress
+func NewSender(clientCtx client.Context) (*Sender, error) {
+	if clientCtx.GetFromName() == "" || clientCtx.GetFromAddress().Empty() {
+		return nil, fmt.Errorf("client context has no signing key")
+	}
+	return &Sender{clientCtx: clientCtx}, nil
+}
+
+// Address returns the address transfers are sent from
+func (s *Sender) Address() string {
+	return s.clientCtx.GetFromAddress().String()
+}
+
+// Send transfers amount to toAddr, fee is paid on top by the sender
+func (s *Sender) Send(ctx context.Context, toAddr string, amount, fee sdk.Coins, memo string) (*sdk.TxResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	to, err := sdk.AccAddressFromBech32(toAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient address: %w", err)
+	}
+	from := s.clientCtx.GetFromAddress()
+
+	if !s.loaded {
+		stopTimer := telemetry.Track(telemetry.CategoryRPC, "get_account")
+		account, err := authtypes.AccountRetriever{}.GetAccount(s.clientCtx, from)
+		stopTimer()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get account info: %w", err)
+		}
+		s.accNum = account.GetAccountNumber()
+		s.sequence = account.GetSequence()
+		s.loaded = true
+	}
+
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	if err := txBuilder.SetMsgs(banktypes.NewMsgSend(from, to, amount)); err != nil {
+		return nil, fmt.Errorf("failed to set messages: %w", err)
+	}
+	txBuilder.SetMemo(memo)
+	txBuilder.SetGasLimit(sendGasLimit)
+	txBuilder.SetFeeAmount(fee)
+	txBuilder.SetFeeGranter(s.clientCtx.FeeGranter)
+
+	txFactory := tx.Factory{}.
+		WithChainID(s.clientCtx.ChainID).
+		WithKeybase(s.clientCtx.Keyring).
+		WithTxConfig(s.clientCtx.TxConfig).
+		WithAccountNumber(s.accNum).
+		WithSequence(s.sequence)
+
+	stopTimer := telemetry.Track(telemetry.CategorySigning, "sign_tx")
+	err = tx.Sign(ctx, txFactory, s.clientCtx.GetFromName(), txBuilder, true)
+	stopTimer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+
+	txBytes, err := s.clientCtx.TxConfig.TxEncoder()(txBuilder.GetTx())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+
+	stopTimer = telemetry.Track(telemetry.CategoryRPC, "broadcast_tx")
+	res, err := BroadcastContext(ctx, s.clientCtx, txBytes)
+	stopTimer()
+	if err != nil {
+		s.loaded = false
+		return nil, fmt.Errorf("failed to broadcast transaction: %w", err)
+	}
+	if res.Code != 0 {
+		// Most likely a sequence mismatch, reload the account next time
+		s.loaded = false
+		return res, &TxFailedError{TxHash: res.TxHash, Codespace: res.Codespace, Code: res.Code, Log: res.RawLog}
+	}
+
+	s.sequence++
+	return res, nil
+}
+
+// WaitForTx polls until a broadcast transaction is included in a block and
+// fails if it was included with an error code
+func (s *Sender) WaitForTx(ctx context.Context, txHash string) (int64, error) {
+	hash, err := hex.DecodeString(txHash)
+	if err != nil {
+		return 0, fmt.Errorf("invalid transaction hash: %w", err)
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		res, err := s.clientCtx.Client.Tx(ctx, hash, false)
+		if err == nil {
+			if res.TxResult.Code != 0 {
+				return res.Height, &TxFailedError{TxHash: txHash, Codespace: res.TxResult.Codespace, Code: res.TxResult.Code, Log: res.TxResult.Log}
+			}
+			return res.Height, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, fmt.Errorf("transaction %s not included: %w", txHash, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// SignAndBroadcast signs msgs with the From key of clientCtx and broadcasts
+// them. The gas is simulated and paid at gasPrices, by the fee granter of
+// clientCtx if one is set.
+func SignAndBroadcast(ctx context.Context, clientCtx client.Context, gasPrices string, msgs ...sdk.Msg) (*sdk.TxResponse, error) {
+	from := clientCtx.GetFromAddress()
+	if from.Empty() {
+		return nil, fmt.Errorf("no sender set in client context")
+	}
+
+	accountRetriever := authtypes.AccountRetriever{}
+	stopTimer := telemetry.Track(telemetry.CategoryRPC, "get_account")
+	account, err := accountRetriever.GetAccount(clientCtx.WithCmdContext(ctx), from)
+	stopTimer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account info: %w", err)
+	}
+
+	txf := tx.Factory{}.
+		WithChainID(clientCtx.ChainID).
+		WithKeybase(clientCtx.Keyring).
+		WithTxConfig(clientCtx.TxConfig).
+		WithAccountRetriever(accountRetriever).
+		WithAccountNumber(account.GetAccountNumber()).
+		WithSequence(account.GetSequence()).
+		WithGasAdjustment(1.3).
+		WithGasPrices(gasPrices).
+		WithFeeGranter(clientCtx.FeeGranter)
+
+	stopTimer = telemetry.Track(telemetry.CategoryRPC, "simulate_tx")
+	_, gas, err := tx.CalculateGas(clientCtx, txf, msgs...)
+	stopTimer()
+	if err != nil {
+		return nil, fmt.Errorf("gas estimation failed: %w", SimulationError(err))
+	}
+	txf = txf.WithGas(gas)
+
+	txBuilder, err := txf.BuildUnsignedTx(msgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build transaction: %w", err)
+	}
+
+	stopTimer = telemetry.Track(telemetry.CategorySigning, "sign_tx")
+	err = tx.Sign(ctx, txf, clientCtx.GetFromName(), txBuilder, true)
+	stopTimer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+
+	txBytes, err := clientCtx.TxConfig.TxEncoder()(txBuilder.GetTx())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+
+	stopTimer = telemetry.Track(telemetry.CategoryRPC, "broadcast_tx")
+	res, err := BroadcastContext(ctx, clientCtx, txBytes)
+	stopTimer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to broadcast transaction: %w", err)
+	}
+	if res.Code != 0 {
+		return res, &TxFailedError{TxHash: res.TxHash, Codespace: res.Codespace, Code: res.Code, Log: res.RawLog}
+	}
+	return res, nil
+}
@@ -22,6 +22,7 @@ import (
 	abci "github.com/cometbft/cometbft/abci/types"
 
 	itypes "github.com/oxygene76/medasdigital-client/internal/types"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
 )
 
 // ClientBuilder helps create blockchain clients with proper configuration
@@ -36,13 +37,14 @@ type ClientBuilder struct {
 
 // NewClientBuilder creates a new client builder
 func NewClientBuilder(config interface{}) *ClientBuilder {
+	net := network.Current()
 	return &ClientBuilder{
-		chainID:        "medasdigital-2",
-		rpcEndpoint:    "https://rpc.medas-digital.io:26657",
+		chainID:        net.ChainID,
+		rpcEndpoint:    net.RPCEndpoint,
 		keyringBackend: keyring.BackendOS,
 		keyringDir:     "",
-		bech32Prefix:   "medas",
-		addressCodec:   NewBech32AddressCodec("medas"),
+		bech32Prefix:   net.Bech32Prefix,
+		addressCodec:   NewBech32AddressCodec(net.Bech32Prefix),
 	}
 }
 
@@ -178,7 +180,7 @@ func (km *KeyManager) GetKeyByAddress(address string) (*keyring.Record, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("key not found for address: %s", address)
+	return nil, fmt.Errorf("%w: no key for address %s", ErrKeyNotFound, address)
 }
 
 // ListKeys returns all keys in the keyring
@@ -227,9 +229,9 @@ type BlockchainMonitor struct {
 	errorCh  chan error
 }
 
-// NewBlockchainMonitor creates a new blockchain monitor
-func NewBlockchainMonitor(client *Client) *BlockchainMonitor {
-	ctx, cancel := context.WithCancel(context.Background())
+// NewBlockchainMonitor creates a new blockchain monitor, it stops with ctx
+func NewBlockchainMonitor(ctx context.Context, client *Client) *BlockchainMonitor {
+	ctx, cancel := context.WithCancel(ctx)
 	return &BlockchainMonitor{
 		client:  client,
 		ctx:     ctx,
@@ -326,8 +328,8 @@ func NewTransactionHelper(client *Client) *TransactionHelper {
 }
 
 // WaitForInclusion waits for a transaction to be included in a block
-func (th *TransactionHelper) WaitForInclusion(txHash string, timeout time.Duration) (*sdk.TxResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+func (th *TransactionHelper) WaitForInclusion(ctx context.Context, txHash string, timeout time.Duration) (*sdk.TxResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	ticker := time.NewTicker(1 * time.Second)
@@ -336,7 +338,7 @@ func (th *TransactionHelper) WaitForInclusion(txHash string, timeout time.Durati
 	for {
 		select {
 		case <-ctx.Done():
-			return nil, fmt.Errorf("timeout waiting for transaction inclusion")
+			return nil, fmt.Errorf("waiting for transaction inclusion: %w", ctx.Err())
 		case <-ticker.C:
 			// Try to get the transaction
 			txBytes, err := hex.DecodeString(txHash)
@@ -365,7 +367,7 @@ func (th *TransactionHelper) WaitForInclusion(txHash string, timeout time.Durati
 }
 
 // BatchTransactions sends multiple transactions in sequence
-func (th *TransactionHelper) BatchTransactions(msgs []sdk.Msg, signerName string) ([]*sdk.TxResponse, error) {
+func (th *TransactionHelper) BatchTransactions(ctx context.Context, msgs []sdk.Msg, signerName string) ([]*sdk.TxResponse, error) {
 	var responses []*sdk.TxResponse
 
 	for i, msg := range msgs {
@@ -385,7 +387,7 @@ func (th *TransactionHelper) BatchTransactions(msgs []sdk.Msg, signerName string
 		}
 
 		// Broadcast transaction using client context
-		res, err := th.client.clientCtx.BroadcastTx(txBytes)
+		res, err := BroadcastContext(ctx, th.client.clientCtx, txBytes)
 		if err != nil {
 			return responses, fmt.Errorf("failed to broadcast transaction %d: %w", i, err)
 		}
@@ -393,7 +395,11 @@ func (th *TransactionHelper) BatchTransactions(msgs []sdk.Msg, signerName string
 		responses = append(responses, res)
 
 		// Wait a bit between transactions
-		time.Sleep(1 * time.Second)
+		select {
+		case <-ctx.Done():
+			return responses, ctx.Err()
+		case <-time.After(1 * time.Second):
+		}
 	}
 
 	return responses, nil
@@ -585,7 +591,7 @@ func (ge *GasEstimator) EstimateGas(msgs []sdk.Msg) (uint64, error) {
 	// Use tx.CalculateGas for v0.50 API
 	simRes, adjustedGas, err := tx.CalculateGas(ge.client.clientCtx.GRPCClient, ge.client.txFactory, msgs...)
 	if err != nil {
-		return 0, fmt.Errorf("failed to simulate transaction: %w", err)
+		return 0, fmt.Errorf("failed to simulate transaction: %w", SimulationError(err))
 	}
 
 	// Use the adjusted gas returned by CalculateGas
@@ -608,7 +614,7 @@ func CreateClientWithDefaults(chainID, rpcEndpoint string) (*Client, error) {
 	builder := NewClientBuilder(nil).
 		WithChainID(chainID).
 		WithRPCEndpoint(rpcEndpoint).
-		WithBech32Prefix("medas")
+		WithBech32Prefix(network.Current().Bech32Prefix)
 	
 	return builder.BuildClient()
 }
@@ -0,0 +1,82 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+
+	cmtbytes "github.com/cometbft/cometbft/libs/bytes"
+	comethttp "github.com/cometbft/cometbft/rpc/client/http"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+)
+
+// seenTxLimit bounds the transaction hashes remembered to skip duplicates
+const seenTxLimit = 1000
+
+// WatchAccountTransfers calls handle for every bank transfer from or to
+// address in newly committed transactions, fees included. A transaction
+// matching both directions is handled once. Like WatchIncomingTransfers it
+// blocks until ctx is done or a subscription breaks.
+func (c *Client) WatchAccountTransfers(ctx context.Context, address string, handle func(Transfer)) error {
+	httpClient, ok := c.clientCtx.Client.(*comethttp.HTTP)
+	if !ok {
+		return fmt.Errorf("client does not support event subscription")
+	}
+	if !httpClient.IsRunning() {
+		if err := httpClient.Start(); err != nil {
+			return fmt.Errorf("failed to start websocket client: %w", err)
+		}
+	}
+
+	// Event queries only know AND, so each direction gets its own subscription
+	var streams []<-chan coretypes.ResultEvent
+	for _, side := range []string{"recipient", "sender"} {
+		subscriber := "medas-watch-" + side + "-" + address
+		query := fmt.Sprintf("tm.event='Tx' AND transfer.%s='%s'", side, address)
+		events, err := httpClient.Subscribe(ctx, subscriber, query, 100)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to transfers: %w", err)
+		}
+		defer httpClient.Unsubscribe(context.Background(), subscriber, query)
+		streams = append(streams, events)
+	}
+
+	match := func(sender, recipient string) bool {
+		return sender == address || recipient == address
+	}
+	seen := make(map[string]bool)
+	var order []string
+
+	for {
+		var event coretypes.ResultEvent
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok = <-streams[0]:
+		case event, ok = <-streams[1]:
+		}
+		if !ok {
+			return fmt.Errorf("transfer subscription closed")
+		}
+		data, isTx := event.Data.(cmttypes.EventDataTx)
+		if !isTx || data.Result.Code != 0 {
+			continue
+		}
+		hash := cmtbytes.HexBytes(cmttypes.Tx(data.Tx).Hash()).String()
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		order = append(order, hash)
+		if len(order) > seenTxLimit {
+			delete(seen, order[0])
+			order = order[1:]
+		}
+
+		blockTime := c.blockTime(ctx, data.Height)
+		for _, t := range c.matchTransfers(hash, data.Height, data.Tx, data.Result.Events, blockTime, match) {
+			handle(t)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package chat
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// MemoPrefix marks transactions that carry a chat message
+const MemoPrefix = "MEDAS_CHAT_MSG:"
+
+// MaxMemoMessage is the longest message in bytes that fits a 256 byte memo:
+// the base64 of the ephemeral key, the ciphertext and the tag
+const MaxMemoMessage = (256-len(MemoPrefix))*3/4 - KeySize - chacha20poly1305.Overhead
+
+// kdfInfo binds the derived key to the protocol version
+const kdfInfo = "medas-chat-v1"
+
+// Sealed is a message encrypted to one recipient. Every message has its own
+// ephemeral key, so the derived key and nonce are never reused.
+type Sealed struct {
+	EphemeralKey []byte `json:"ephemeral_key"`
+	Ciphertext   []byte `json:"ciphertext"`
+}
+
+// deriveAEAD derives the cipher and nonce of a message. The sender and
+// recipient addresses are part of the key, so a ciphertext replayed by
+// another account does not decrypt.
+func deriveAEAD(shared, ephemeral, recipient []byte, from, to string) ([]byte, []byte, error) {
+	salt := append(append([]byte{}, ephemeral...), recipient...)
+	r := hkdf.New(sha256.New, shared, salt, []byte(kdfInfo+"|"+from+"|"+to))
+	material := make([]byte, chacha20poly1305.KeySize+chacha20poly1305.NonceSize)
+	if _, err := io.ReadFull(r, material); err != nil {
+		return nil, nil, err
+	}
+	return material[:chacha20poly1305.KeySize], material[chacha20poly1305.KeySize:], nil
+}
+
+// Seal encrypts plaintext from one address to the chat key of another
+func Seal(recipient *ecdh.PublicKey, from, to string, plaintext []byte) (*Sealed, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, err
+	}
+	key, nonce, err := deriveAEAD(shared, ephemeral.PublicKey().Bytes(), recipient.Bytes(), from, to)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealed{
+		EphemeralKey: ephemeral.PublicKey().Bytes(),
+		Ciphertext:   aead.Seal(nil, nonce, plaintext, nil),
+	}, nil
+}
+
+// Open decrypts a message sent from one address to the owner of key
+func Open(key *ecdh.PrivateKey, from, to string, sealed *Sealed) ([]byte, error) {
+	ephemeral, err := ParsePublicKey(sealed.EphemeralKey)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := key.ECDH(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	aeadKey, nonce, err := deriveAEAD(shared, sealed.EphemeralKey, key.PublicKey().Bytes(), from, to)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(aeadKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, sealed.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt message: %w", err)
+	}
+	return plaintext, nil
+}
+
+// Memo encodes the message for a transaction memo
+func (s *Sealed) Memo() (string, error) {
+	memo := MemoPrefix + base64.RawStdEncoding.EncodeToString(append(append([]byte{}, s.EphemeralKey...), s.Ciphertext...))
+	if len(memo) > 256 {
+		return "", fmt.Errorf("message too long for a memo (max %d bytes)", MaxMemoMessage)
+	}
+	return memo, nil
+}
+
+// ParseMemo reads a message from a transaction memo
+func ParseMemo(memo string) (*Sealed, error) {
+	if !strings.HasPrefix(memo, MemoPrefix) {
+		return nil, fmt.Errorf("not a chat message memo")
+	}
+	raw, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(memo, MemoPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid chat message memo: %w", err)
+	}
+	if len(raw) < KeySize+chacha20poly1305.Overhead {
+		return nil, fmt.Errorf("chat message memo too short")
+	}
+	return &Sealed{EphemeralKey: raw[:KeySize], Ciphertext: raw[KeySize:]}, nil
+}
+
+// SignFunc signs with the account key of the sender, see keyring.Sign
+type SignFunc func(msg []byte) ([]byte, cryptotypes.PubKey, error)
+
+// Envelope is a message delivered to a chat endpoint. Unlike a memo it is not
+// signed by a transaction, so it carries the signature of the sender's account key.
+type Envelope struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	SentAt    time.Time `json:"sent_at"`
+	Sealed    Sealed    `json:"sealed"`
+	PubKey    []byte    `json:"pub_key"`
+	Signature []byte    `json:"signature"`
+}
+
+// signBytes is the digest the sender signs
+func (e *Envelope) signBytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(kdfInfo)
+	for _, field := range [][]byte{[]byte(e.From), []byte(e.To), e.Sealed.EphemeralKey, e.Sealed.Ciphertext} {
+		binary.Write(&buf, binary.BigEndian, uint32(len(field)))
+		buf.Write(field)
+	}
+	binary.Write(&buf, binary.BigEndian, e.SentAt.UnixNano())
+	sum := sha256.Sum256(buf.Bytes())
+	return sum[:]
+}
+
+// Sign adds the sender signature
+func (e *Envelope) Sign(sign SignFunc) error {
+	sig, pub, err := sign(e.signBytes())
+	if err != nil {
+		return fmt.Errorf("failed to sign message: %w", err)
+	}
+	if _, ok := pub.(*secp256k1.PubKey); !ok {
+		return fmt.Errorf("unsupported account key type %s", pub.Type())
+	}
+	e.PubKey = pub.Bytes()
+	e.Signature = sig
+	return nil
+}
+
+// Verify checks that the envelope was signed by the account in From
+func (e *Envelope) Verify() error {
+	pub := &secp256k1.PubKey{Key: e.PubKey}
+	if len(e.PubKey) != secp256k1.PubKeySize {
+		return fmt.Errorf("invalid sender public key")
+	}
+	if addr := sdk.AccAddress(pub.Address()).String(); addr != e.From {
+		return fmt.Errorf("public key belongs to %s, not %s", addr, e.From)
+	}
+	if !pub.VerifySignature(e.signBytes(), e.Signature) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
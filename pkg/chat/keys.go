@@ -0,0 +1,93 @@
+// Package chat implements end-to-end encrypted messages between registered
+// chat clients. Messages are encrypted to the X25519 key of the recipient's
+// chat registration and delivered in a transaction memo or to a chat endpoint.
+package chat
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/oxygene76/medasdigital-client/pkg/state"
+)
+
+// KeySize is the length of an X25519 public key
+const KeySize = 32
+
+// keyFile is the stored chat key of one account
+type keyFile struct {
+	Address    string    `json:"address"`
+	PrivateKey string    `json:"private_key"`
+	PublicKey  string    `json:"public_key"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// KeyPath is the file of the chat key of address below dir
+func KeyPath(dir, address string) string {
+	return filepath.Join(dir, state.ChatKeysDir, address+".json")
+}
+
+// LoadKey reads the chat key of address
+func LoadKey(dir, address string) (*ecdh.PrivateKey, error) {
+	data, err := os.ReadFile(KeyPath(dir, address))
+	if err != nil {
+		return nil, fmt.Errorf("no chat key for %s: %w", address, err)
+	}
+	var kf keyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return nil, fmt.Errorf("invalid chat key file: %w", err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(kf.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chat key: %w", err)
+	}
+	return ecdh.X25519().NewPrivateKey(raw)
+}
+
+// LoadOrCreateKey returns the chat key of address, generating and storing a
+// new one on first use. created reports whether the key is new. Concurrent
+// calls from several processes agree on one key.
+func LoadOrCreateKey(dir, address string) (key *ecdh.PrivateKey, created bool, err error) {
+	if _, err := os.Stat(KeyPath(dir, address)); err == nil {
+		key, err := LoadKey(dir, address)
+		return key, false, err
+	}
+
+	lock, err := state.New(dir).Lock(state.LockChatKeys)
+	if err != nil {
+		return nil, false, err
+	}
+	defer lock.Unlock()
+	if _, err := os.Stat(KeyPath(dir, address)); err == nil {
+		key, err := LoadKey(dir, address)
+		return key, false, err
+	}
+
+	key, err = ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to generate chat key: %w", err)
+	}
+	kf := keyFile{
+		Address:    address,
+		PrivateKey: base64.StdEncoding.EncodeToString(key.Bytes()),
+		PublicKey:  base64.StdEncoding.EncodeToString(key.PublicKey().Bytes()),
+		CreatedAt:  time.Now(),
+	}
+	if err := state.WriteJSON(KeyPath(dir, address), kf, 0600); err != nil {
+		return nil, false, fmt.Errorf("failed to save chat key: %w", err)
+	}
+	return key, true, nil
+}
+
+// ParsePublicKey checks a published chat key
+func ParsePublicKey(raw []byte) (*ecdh.PublicKey, error) {
+	if len(raw) != KeySize {
+		return nil, fmt.Errorf("chat key has %d bytes, expected %d", len(raw), KeySize)
+	}
+	return ecdh.X25519().NewPublicKey(raw)
+}
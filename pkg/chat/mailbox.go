@@ -0,0 +1,132 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Message directions
+const (
+	Incoming = "in"
+	Outgoing = "out"
+)
+
+// Transports a message was delivered with
+const (
+	TransportChain    = "chain"
+	TransportEndpoint = "endpoint"
+)
+
+// Message is a decrypted message in the mailbox
+type Message struct {
+	ID        string    `json:"id"`
+	Direction string    `json:"direction"`
+	Peer      string    `json:"peer"`
+	Body      string    `json:"body"`
+	Time      time.Time `json:"time"`
+	Transport string    `json:"transport"`
+	TxHash    string    `json:"tx_hash,omitempty"`
+	Height    int64     `json:"height,omitempty"`
+	Read      bool      `json:"read"`
+}
+
+// Mailbox keeps the messages of one account. Sent messages are stored in
+// plaintext here, they cannot be decrypted from the chain by the sender.
+type Mailbox struct {
+	Address    string     `json:"address"`
+	LastHeight int64      `json:"last_height"`
+	Messages   []*Message `json:"messages"`
+
+	mu   sync.Mutex
+	path string
+}
+
+// OpenMailbox reads the mailbox of address below dir
+func OpenMailbox(dir, address string) (*Mailbox, error) {
+	mb := &Mailbox{Address: address, path: filepath.Join(dir, "chat", address, "mailbox.json")}
+	data, err := os.ReadFile(mb.path)
+	if os.IsNotExist(err) {
+		return mb, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mailbox: %w", err)
+	}
+	if err := json.Unmarshal(data, mb); err != nil {
+		return nil, fmt.Errorf("failed to parse mailbox %s: %w", mb.path, err)
+	}
+	return mb, nil
+}
+
+// Add stores a message unless a message with its ID is already there
+func (mb *Mailbox) Add(msg *Message) bool {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	for _, m := range mb.Messages {
+		if m.ID == msg.ID {
+			return false
+		}
+	}
+	mb.Messages = append(mb.Messages, msg)
+	sort.SliceStable(mb.Messages, func(i, j int) bool {
+		return mb.Messages[i].Time.Before(mb.Messages[j].Time)
+	})
+	return true
+}
+
+// Filter returns the messages of a direction (any if empty) with a peer (any
+// if empty), oldest first
+func (mb *Mailbox) Filter(direction, peer string, unreadOnly bool) []*Message {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	var result []*Message
+	for _, m := range mb.Messages {
+		if direction != "" && m.Direction != direction {
+			continue
+		}
+		if peer != "" && m.Peer != peer {
+			continue
+		}
+		if unreadOnly && m.Read {
+			continue
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
+// MarkRead marks messages as read
+func (mb *Mailbox) MarkRead(msgs []*Message) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	for _, m := range msgs {
+		m.Read = true
+	}
+}
+
+// BlobPath is where blobs delivered to the chat endpoint are stored
+func (mb *Mailbox) BlobPath(hash string) string {
+	return filepath.Join(filepath.Dir(mb.path), "blobs", hash)
+}
+
+// Save writes the mailbox atomically
+func (mb *Mailbox) Save() error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(mb.path), 0700); err != nil {
+		return fmt.Errorf("failed to create mailbox directory: %w", err)
+	}
+	data, err := json.MarshalIndent(mb, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := mb.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write mailbox: %w", err)
+	}
+	return os.Rename(tmp, mb.path)
+}
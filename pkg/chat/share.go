@@ -0,0 +1,269 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	rpcclient "github.com/cometbft/cometbft/rpc/client"
+	"github.com/cosmos/cosmos-sdk/codec"
+)
+
+// ShareMemoPrefix marks transactions that anchor a shared result for the recipient
+const ShareMemoPrefix = "MEDAS_SHARE:"
+
+// MaxBlobSize limits a shared result blob
+const MaxBlobSize = 64 << 20
+
+// SharedFile is the plaintext of a shared result
+type SharedFile struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      []byte    `json:"data"`
+}
+
+// Blob is a result encrypted to one recipient. The blob is stored off chain,
+// the chain only carries its SHA-256 and where to get it.
+type Blob struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Sealed Sealed `json:"sealed"`
+}
+
+// SealBlob encrypts a file for recipient and returns the blob and its hash
+func SealBlob(recipient *ecdh.PublicKey, from, to string, file *SharedFile) ([]byte, string, error) {
+	plaintext, err := json.Marshal(file)
+	if err != nil {
+		return nil, "", err
+	}
+	sealed, err := Seal(recipient, from, to, plaintext)
+	if err != nil {
+		return nil, "", err
+	}
+	blob, err := json.Marshal(Blob{From: from, To: to, Sealed: *sealed})
+	if err != nil {
+		return nil, "", err
+	}
+	if len(blob) > MaxBlobSize {
+		return nil, "", fmt.Errorf("result too large to share (%d bytes, max %d)", len(blob), MaxBlobSize)
+	}
+	return blob, BlobHash(blob), nil
+}
+
+// BlobHash is the hex SHA-256 a blob is anchored with
+func BlobHash(blob []byte) string {
+	sum := sha256.Sum256(blob)
+	return hex.EncodeToString(sum[:])
+}
+
+// OpenBlob checks a blob against its anchor and decrypts it. from and to come
+// from the anchoring transaction, so a blob cannot be passed off as someone else's.
+func OpenBlob(key *ecdh.PrivateKey, data []byte, hash, from, to string) (*SharedFile, error) {
+	if BlobHash(data) != hash {
+		return nil, fmt.Errorf("blob does not match the anchored hash %s", hash)
+	}
+	var blob Blob
+	if err := json.Unmarshal(data, &blob); err != nil {
+		return nil, fmt.Errorf("invalid blob: %w", err)
+	}
+	if blob.From != from || blob.To != to {
+		return nil, fmt.Errorf("blob is from %s to %s, anchored from %s to %s", blob.From, blob.To, from, to)
+	}
+	plaintext, err := Open(key, from, to, &blob.Sealed)
+	if err != nil {
+		return nil, err
+	}
+	var file SharedFile
+	if err := json.Unmarshal(plaintext, &file); err != nil {
+		return nil, fmt.Errorf("invalid shared file: %w", err)
+	}
+	return &file, nil
+}
+
+// ShareMemo builds the anchor memo, url is where the recipient fetches the blob
+func ShareMemo(hash, url string) (string, error) {
+	memo := ShareMemoPrefix + hash
+	if url != "" {
+		memo += ":" + url
+	}
+	if len(memo) > 256 {
+		return "", fmt.Errorf("blob URL too long for a memo (max %d characters)", 256-len(ShareMemoPrefix)-len(hash)-1)
+	}
+	return memo, nil
+}
+
+// ParseShareMemo reads the hash and URL of an anchor memo
+func ParseShareMemo(memo string) (hash, url string, err error) {
+	rest := strings.TrimPrefix(memo, ShareMemoPrefix)
+	if rest == memo || len(rest) < sha256.Size*2 {
+		return "", "", fmt.Errorf("not a share memo")
+	}
+	hash, url = rest[:sha256.Size*2], strings.TrimPrefix(rest[sha256.Size*2:], ":")
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", "", fmt.Errorf("invalid blob hash in share memo")
+	}
+	return hash, url, nil
+}
+
+// BlobURL is where a chat endpoint serves a blob
+func BlobURL(endpoint, hash string) string {
+	return strings.TrimRight(endpoint, "/") + BlobsPath + hash
+}
+
+// UploadBlob stores a blob on the chat endpoint of its recipient
+func UploadBlob(ctx context.Context, endpoint string, blob []byte) (string, error) {
+	url := BlobURL(endpoint, BlobHash(blob))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(blob))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("%s rejected the blob: %s %s", endpoint, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return url, nil
+}
+
+// FetchBlob downloads a blob
+func FetchBlob(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxBlobSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxBlobSize {
+		return nil, fmt.Errorf("blob at %s exceeds %d bytes", url, MaxBlobSize)
+	}
+	return data, nil
+}
+
+// Share is an anchored result, sent or received
+type Share struct {
+	TxHash    string    `json:"tx_hash"`
+	Direction string    `json:"direction"`
+	Peer      string    `json:"peer"`
+	Hash      string    `json:"hash"`
+	URL       string    `json:"url,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Path      string    `json:"path,omitempty"` // decrypted copy of a received share
+	Time      time.Time `json:"time"`
+	Height    int64     `json:"height,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Shares is the share history of one account
+type Shares struct {
+	Address    string   `json:"address"`
+	LastHeight int64    `json:"last_height"`
+	Items      []*Share `json:"shares"`
+
+	path string
+}
+
+// LoadShares reads the shares of address below dir
+func LoadShares(dir, address string) (*Shares, error) {
+	s := &Shares{Address: address, path: filepath.Join(dir, "chat", address, "shares.json")}
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shares: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse shares %s: %w", s.path, err)
+	}
+	return s, nil
+}
+
+// Add records a share unless its transaction is already known
+func (s *Shares) Add(share *Share) bool {
+	for _, existing := range s.Items {
+		if existing.TxHash == share.TxHash && existing.Direction == share.Direction {
+			return false
+		}
+	}
+	s.Items = append(s.Items, share)
+	return true
+}
+
+// Filter returns the shares of a direction
+func (s *Shares) Filter(direction string) []*Share {
+	var result []*Share
+	for _, share := range s.Items {
+		if share.Direction == direction {
+			result = append(result, share)
+		}
+	}
+	return result
+}
+
+// Save writes the shares atomically
+func (s *Shares) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create shares directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write shares: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// SyncShares adds the anchors sent to the owner since the last scanned height
+func SyncShares(ctx context.Context, client rpcclient.Client, cdc codec.Codec, s *Shares) (int, error) {
+	found := 0
+	lastHeight, err := ScanMemos(ctx, client, cdc, s.Address, s.LastHeight, ShareMemoPrefix, func(tx MemoTx) {
+		hash, url, err := ParseShareMemo(tx.Memo)
+		if err != nil {
+			return
+		}
+		if s.Add(&Share{
+			TxHash:    tx.TxHash,
+			Direction: Incoming,
+			Peer:      tx.From,
+			Hash:      hash,
+			URL:       url,
+			Time:      blockTime(ctx, client, tx.Height),
+			Height:    tx.Height,
+		}) {
+			found++
+		}
+	})
+	if err != nil {
+		return found, err
+	}
+	s.LastHeight = lastHeight
+	return found, nil
+}
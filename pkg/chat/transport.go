@@ -0,0 +1,243 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	rpcclient "github.com/cometbft/cometbft/rpc/client"
+	"github.com/cosmos/cosmos-sdk/codec"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+)
+
+// MessagesPath is the path chat endpoints accept envelopes on
+const MessagesPath = "/chat/messages"
+
+// BlobsPath is the prefix chat endpoints store and serve shared result blobs under
+const BlobsPath = "/chat/blobs/"
+
+// maxEnvelopeSize limits the body of a delivered envelope
+const maxEnvelopeSize = 64 << 10
+
+// txSearchPerPage is the page size of the inbox scan
+const txSearchPerPage = 50
+
+// MemoTx is a successful transaction to an address with a matching memo
+type MemoTx struct {
+	TxHash string
+	Height int64
+	From   string
+	To     string
+	Memo   string
+}
+
+// ScanMemos calls fn for the transactions to address after height whose memo
+// starts with prefix and returns the highest height seen
+func ScanMemos(ctx context.Context, client rpcclient.Client, cdc codec.Codec, address string, after int64, prefix string, fn func(MemoTx)) (int64, error) {
+	query := fmt.Sprintf("transfer.recipient='%s' AND tx.height>%d", address, after)
+	perPage := txSearchPerPage
+	lastHeight := after
+
+	for page := 1; ; page++ {
+		result, err := client.TxSearch(ctx, query, false, &page, &perPage, "asc")
+		if err != nil {
+			return lastHeight, fmt.Errorf("failed to search transactions: %w", err)
+		}
+
+		for _, tx := range result.Txs {
+			if tx.Height > lastHeight {
+				lastHeight = tx.Height
+			}
+			if tx.TxResult.Code != 0 || !bytes.Contains(tx.Tx, []byte(prefix)) {
+				continue
+			}
+			data, err := blockchain.DecodeTxData(tx.Tx, cdc)
+			if err != nil || data.ToAddress != address || !strings.HasPrefix(data.Memo, prefix) {
+				continue
+			}
+			fn(MemoTx{
+				TxHash: fmt.Sprintf("%X", []byte(tx.Hash)),
+				Height: tx.Height,
+				From:   data.FromAddress,
+				To:     data.ToAddress,
+				Memo:   data.Memo,
+			})
+		}
+
+		if page*perPage >= result.TotalCount || len(result.Txs) == 0 {
+			return lastHeight, nil
+		}
+	}
+}
+
+// blockTime is the time of the block at height, or now if it cannot be read
+func blockTime(ctx context.Context, client rpcclient.Client, height int64) time.Time {
+	if header, err := client.Header(ctx, &height); err == nil {
+		return header.Header.Time
+	}
+	return time.Now()
+}
+
+// SyncChain adds the memo messages sent to the mailbox owner since the last
+// scanned height and returns the number of new messages
+func SyncChain(ctx context.Context, client rpcclient.Client, cdc codec.Codec, key *ecdh.PrivateKey, mb *Mailbox) (int, error) {
+	found := 0
+	lastHeight, err := ScanMemos(ctx, client, cdc, mb.Address, mb.LastHeight, MemoPrefix, func(tx MemoTx) {
+		sealed, err := ParseMemo(tx.Memo)
+		if err != nil {
+			return
+		}
+		body, err := Open(key, tx.From, mb.Address, sealed)
+		if err != nil {
+			// Encrypted to an older chat key
+			return
+		}
+		if mb.Add(&Message{
+			ID:        tx.TxHash,
+			Direction: Incoming,
+			Peer:      tx.From,
+			Body:      string(body),
+			Time:      blockTime(ctx, client, tx.Height),
+			Transport: TransportChain,
+			TxHash:    tx.TxHash,
+			Height:    tx.Height,
+		}) {
+			found++
+		}
+	})
+	if err != nil {
+		return found, err
+	}
+	mb.LastHeight = lastHeight
+	return found, nil
+}
+
+// Deliver posts an envelope to a chat endpoint
+func Deliver(ctx context.Context, endpoint string, env *Envelope) error {
+	body, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	url := strings.TrimRight(endpoint, "/") + MessagesPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s rejected the message: %s %s", endpoint, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Handler accepts envelopes for the mailbox owner, verifies the sender
+// signature and stores the decrypted message. onMessage is called for every
+// new message and may be nil. Blobs of shared results addressed to the owner
+// are stored as they are and served to whoever has the hash.
+func Handler(key *ecdh.PrivateKey, mb *Mailbox, onMessage func(*Message)) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(MessagesPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var env Envelope
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxEnvelopeSize)).Decode(&env); err != nil {
+			http.Error(w, "invalid envelope", http.StatusBadRequest)
+			return
+		}
+		if env.To != mb.Address {
+			http.Error(w, "unknown recipient", http.StatusNotFound)
+			return
+		}
+		if err := env.Verify(); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		body, err := Open(key, env.From, env.To, &env.Sealed)
+		if err != nil {
+			http.Error(w, "message not encrypted to this chat key", http.StatusUnprocessableEntity)
+			return
+		}
+
+		// The signature covers the ciphertext, so its hash identifies resends
+		id := sha256.Sum256(env.Signature)
+		msg := &Message{
+			ID:        hex.EncodeToString(id[:]),
+			Direction: Incoming,
+			Peer:      env.From,
+			Body:      string(body),
+			Time:      env.SentAt,
+			Transport: TransportEndpoint,
+		}
+		if mb.Add(msg) {
+			if err := mb.Save(); err != nil {
+				http.Error(w, "failed to store message", http.StatusInternalServerError)
+				return
+			}
+			if onMessage != nil {
+				onMessage(msg)
+			}
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+	mux.HandleFunc(BlobsPath, func(w http.ResponseWriter, r *http.Request) {
+		hash := strings.TrimPrefix(r.URL.Path, BlobsPath)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha256.Size*2 {
+			http.Error(w, "invalid blob hash", http.StatusBadRequest)
+			return
+		}
+		path := mb.BlobPath(hash)
+
+		switch r.Method {
+		case http.MethodGet:
+			http.ServeFile(w, r, path)
+		case http.MethodPut:
+			data, err := io.ReadAll(io.LimitReader(r.Body, MaxBlobSize+1))
+			if err != nil || len(data) > MaxBlobSize {
+				http.Error(w, "blob too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			if BlobHash(data) != hash {
+				http.Error(w, "blob does not match its hash", http.StatusBadRequest)
+				return
+			}
+			var blob Blob
+			if err := json.Unmarshal(data, &blob); err != nil || blob.To != mb.Address {
+				http.Error(w, "unknown recipient", http.StatusNotFound)
+				return
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+				http.Error(w, "failed to store blob", http.StatusInternalServerError)
+				return
+			}
+			if err := os.WriteFile(path, data, 0600); err != nil {
+				http.Error(w, "failed to store blob", http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	return mux
+}
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/std"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/contract"
+	"github.com/oxygene76/medasdigital-client/pkg/feegrant"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
+	"github.com/oxygene76/medasdigital-client/pkg/utils"
+)
+
+// App is the client for one network: its settings, codec and the analysis
+// client. It keeps no package-level state, so a program can embed several
+// apps and tests can run in parallel. Only the bech32 prefix is process wide
+// in the Cosmos SDK, apps in one process must agree on it.
+type App struct {
+	network  network.Network
+	config   *Config
+	registry types.InterfaceRegistry
+	codec    *codec.ProtoCodec
+	txConfig client.TxConfig
+
+	gpu         utils.GPUConfig
+	gpuLimitMB  int
+	registerExt []func(types.InterfaceRegistry)
+
+	mu     sync.Mutex
+	client *MedasDigitalClient
+	gpuErr error
+}
+
+// Option configures an App
+type Option func(*App)
+
+// WithNetwork selects the network, the default is network.Current()
+func WithNetwork(n network.Network) Option {
+	return func(a *App) { a.network = n }
+}
+
+// WithConfig sets the client config; chain ID and RPC endpoint left empty
+// come from the network
+func WithConfig(cfg *Config) Option {
+	return func(a *App) { a.config = cfg }
+}
+
+// WithGPU enables the GPUs of cfg, keeping jobs within memoryLimitMB per device
+func WithGPU(cfg utils.GPUConfig, memoryLimitMB int) Option {
+	return func(a *App) { a.gpu, a.gpuLimitMB = cfg, memoryLimitMB }
+}
+
+// WithInterfaces registers additional message types with the codec
+func WithInterfaces(register func(types.InterfaceRegistry)) Option {
+	return func(a *App) { a.registerExt = append(a.registerExt, register) }
+}
+
+// NewApp creates an app. It does not connect, see Client.
+func NewApp(opts ...Option) (*App, error) {
+	a := &App{network: network.Current()}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.config == nil {
+		a.config = LoadDefaultConfig()
+	}
+	if a.config.Chain.ID == "" {
+		a.config.Chain.ID = a.network.ChainID
+	}
+	if a.config.Chain.RPCEndpoint == "" {
+		a.config.Chain.RPCEndpoint = a.network.RPCEndpoint
+	}
+	if err := useBech32Prefix(a.network.Bech32Prefix); err != nil {
+		return nil, err
+	}
+
+	a.registry = NewInterfaceRegistry()
+	for _, register := range a.registerExt {
+		register(a.registry)
+	}
+	a.codec = codec.NewProtoCodec(a.registry)
+	a.txConfig = authtx.NewTxConfig(a.codec, authtx.DefaultSignModes)
+	return a, nil
+}
+
+// NewInterfaceRegistry registers the SDK, bank, auth and authz types, the
+// fee grant messages and the messages of the client and compute contracts
+func NewInterfaceRegistry() types.InterfaceRegistry {
+	registry := types.NewInterfaceRegistry()
+	std.RegisterInterfaces(registry)
+	authtypes.RegisterInterfaces(registry)
+	banktypes.RegisterInterfaces(registry)
+	authz.RegisterInterfaces(registry)
+	registry.RegisterImplementations(
+		(*authtypes.AccountI)(nil),
+		&authtypes.BaseAccount{},
+		&authtypes.ModuleAccount{},
+	)
+	blockchain.RegisterInterfaces(registry)
+	contract.RegisterInterfaces(registry)
+	feegrant.RegisterInterfaces(registry)
+	return registry
+}
+
+// useBech32Prefix sets the address prefixes of the SDK config. The config
+// is left unsealed so that an embedding program can set it first; a sealed
+// config with another prefix is an error.
+func useBech32Prefix(prefix string) (err error) {
+	cfg := sdk.GetConfig()
+	if cfg.GetBech32AccountAddrPrefix() == prefix {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("cannot use bech32 prefix %s, the SDK config is sealed with %s", prefix, cfg.GetBech32AccountAddrPrefix())
+		}
+	}()
+	cfg.SetBech32PrefixForAccount(prefix, prefix+"pub")
+	cfg.SetBech32PrefixForValidator(prefix+"valoper", prefix+"valoperpub")
+	cfg.SetBech32PrefixForConsensusNode(prefix+"valcons", prefix+"valconspub")
+	return nil
+}
+
+// Network is the network of the app
+func (a *App) Network() network.Network {
+	return a.network
+}
+
+// Codec encodes the messages of the client and compute contracts
+func (a *App) Codec() codec.Codec {
+	return a.codec
+}
+
+// InterfaceRegistry is the registry behind Codec
+func (a *App) InterfaceRegistry() types.InterfaceRegistry {
+	return a.registry
+}
+
+// TxConfig encodes and signs transactions
+func (a *App) TxConfig() client.TxConfig {
+	return a.txConfig
+}
+
+// ClientContext is an SDK client context with the app's codec and chain ID,
+// without RPC client and keyring
+func (a *App) ClientContext() client.Context {
+	return client.Context{}.
+		WithChainID(a.config.Chain.ID).
+		WithCodec(a.codec).
+		WithInterfaceRegistry(a.registry).
+		WithTxConfig(a.txConfig)
+}
+
+// Client returns the analysis client, connecting on first use
+func (a *App) Client() (*MedasDigitalClient, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.client != nil {
+		return a.client, nil
+	}
+	c, err := newClient(a.config, a.registry)
+	if err != nil {
+		return nil, err
+	}
+	// Analyses run on the CPU when the GPUs cannot be used
+	a.gpuErr = c.ConfigureGPU(a.gpu, a.gpuLimitMB)
+	a.client = c
+	return c, nil
+}
+
+// GPUError is why the GPUs of WithGPU are not used, nil if they are or
+// the client was not created yet
+func (a *App) GPUError() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.gpuErr
+}
@@ -1,10 +1,12 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/cosmos/cosmos-sdk/client"
@@ -18,19 +20,23 @@ import (
 	itypes "github.com/oxygene76/medasdigital-client/internal/types"
 	"github.com/oxygene76/medasdigital-client/pkg/analysis"
 	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
 	"github.com/oxygene76/medasdigital-client/pkg/gpu"
+	"github.com/oxygene76/medasdigital-client/pkg/network"
 	"github.com/oxygene76/medasdigital-client/pkg/utils"
 )
 
-// Config represents client configuration
+// Config represents client configuration. The CLI fills it from its config
+// file, it is not read from disk here.
 type Config struct {
 	Chain struct {
 		ID          string `json:"chain_id"`
 		RPCEndpoint string `json:"rpc_endpoint"`
 	} `json:"chain"`
 	Client struct {
-		Capabilities []string `json:"capabilities"`
-		KeyringDir   string   `json:"keyring_dir"`
+		Capabilities   []string `json:"capabilities"`
+		KeyringDir     string   `json:"keyring_dir"`
+		KeyringBackend string   `json:"keyring_backend"`
 	} `json:"client"`
 	GPU utils.GPUConfig `json:"gpu"`
 }
@@ -43,13 +49,22 @@ type MedasDigitalClient struct {
 	capabilities []string
 	isRegistered bool
 	gpuManager   *gpu.Manager
+	gpuScheduler *compute.GPUScheduler
 	analyzer     *analysis.Manager
 	blockchain   *blockchain.Client
 }
 
-// NewMedasDigitalClient creates a new MedasDigital client instance
-func NewMedasDigitalClient() (*MedasDigitalClient, error) {
-	config := LoadDefaultConfig()
+// NewMedasDigitalClient creates a new MedasDigital client instance, a nil
+// config uses LoadDefaultConfig
+func NewMedasDigitalClient(config *Config) (*MedasDigitalClient, error) {
+	if config == nil {
+		config = LoadDefaultConfig()
+	}
+	return newClient(config, types.NewInterfaceRegistry())
+}
+
+// newClient creates a client encoding with registry
+func newClient(config *Config, registry types.InterfaceRegistry) (*MedasDigitalClient, error) {
 
 	client := &MedasDigitalClient{
 		config:       config,
@@ -57,7 +72,7 @@ func NewMedasDigitalClient() (*MedasDigitalClient, error) {
 		isRegistered: false,
 	}
 
-	if err := client.initializeBlockchainClient(); err != nil {
+	if err := client.initializeBlockchainClient(registry); err != nil {
 		return nil, fmt.Errorf("failed to initialize blockchain client: %w", err)
 	}
 
@@ -74,6 +89,9 @@ func NewMedasDigitalClient() (*MedasDigitalClient, error) {
 	return client, nil
 }
 
+// defaultGPUJobMemoryMB is reserved per device when a job does not specify its memory need
+const defaultGPUJobMemoryMB = 2048
+
 // LoadDefaultConfig loads default configuration
 func LoadDefaultConfig() *Config {
 	return &Config{
@@ -81,15 +99,17 @@ func LoadDefaultConfig() *Config {
 			ID          string `json:"chain_id"`
 			RPCEndpoint string `json:"rpc_endpoint"`
 		}{
-			ID:          "medasdigital-2",
-			RPCEndpoint: "https://rpc.medas-digital.io:26657",
+			ID:          network.Current().ChainID,
+			RPCEndpoint: network.Current().RPCEndpoint,
 		},
 		Client: struct {
-			Capabilities []string `json:"capabilities"`
-			KeyringDir   string   `json:"keyring_dir"`
+			Capabilities   []string `json:"capabilities"`
+			KeyringDir     string   `json:"keyring_dir"`
+			KeyringBackend string   `json:"keyring_backend"`
 		}{
-			Capabilities: []string{"orbital_dynamics", "photometric_analysis", "clustering_analysis", "ai_training"},
-			KeyringDir:   "",
+			Capabilities:   []string{"orbital_dynamics", "photometric_analysis", "clustering_analysis", "ai_training"},
+			KeyringDir:     "",
+			KeyringBackend: keyring.BackendOS,
 		},
 		GPU: utils.GPUConfig{
 			Enabled: false,
@@ -97,15 +117,14 @@ func LoadDefaultConfig() *Config {
 	}
 }
 
-func (c *MedasDigitalClient) initializeBlockchainClient() error {
+func (c *MedasDigitalClient) initializeBlockchainClient(interfaceRegistry types.InterfaceRegistry) error {
 	// Codec setup
-	interfaceRegistry := types.NewInterfaceRegistry()
 	marshaler := codec.NewProtoCodec(interfaceRegistry)
 
 	// Keyring setup - v0.50 compatible
 	kr, err := keyring.New(
 		sdk.KeyringServiceName(),
-		keyring.BackendOS,
+		c.config.Client.KeyringBackend,
 		c.config.Client.KeyringDir,
 		nil,
 		marshaler, // v0.50 requires codec parameter
@@ -135,7 +154,7 @@ func (c *MedasDigitalClient) initializeBlockchainClient() error {
 }
 
 // Register registers the client on the blockchain
-func (c *MedasDigitalClient) Register(capabilities []string, metadata, from string) error {
+func (c *MedasDigitalClient) Register(ctx context.Context, capabilities []string, metadata, from string) error {
 	log.Println("Registering client on blockchain...")
 
 	// Set from address
@@ -152,7 +171,7 @@ func (c *MedasDigitalClient) Register(capabilities []string, metadata, from stri
 	c.clientCtx = c.clientCtx.WithFromAddress(addr).WithFromName(from)
 
 	// Use the blockchain client to register
-	clientID, err := c.blockchain.RegisterClient(
+	clientID, err := c.blockchain.RegisterClient(ctx,
 		addr.String(),
 		capabilities,
 		c.generateMetadata(metadata),
@@ -183,26 +202,27 @@ func (c *MedasDigitalClient) generateMetadata(userMetadata string) map[string]in
 	if c.gpuManager != nil {
 		gpuInfo := c.gpuManager.GetDeviceCount()
 		metadata["gpu_device_count"] = gpuInfo
+		metadata["gpu_backend"] = c.gpuManager.Backend()
 	}
 
 	return metadata
 }
 
 // AnalyzeOrbitalDynamics performs orbital dynamics analysis
-func (c *MedasDigitalClient) AnalyzeOrbitalDynamics(inputFile, outputFile string) error {
+func (c *MedasDigitalClient) AnalyzeOrbitalDynamics(ctx context.Context, inputFile, outputFile string, opts analysis.OrbitalOptions) error {
 	if !c.hasCapability("orbital_dynamics") {
 		return fmt.Errorf("client does not have orbital_dynamics capability")
 	}
 
 	log.Printf("Starting orbital dynamics analysis on file: %s", inputFile)
 
-	result, err := c.analyzer.AnalyzeOrbitalDynamics(inputFile)
+	result, err := c.analyzer.AnalyzeOrbitalDynamics(ctx, inputFile, opts)
 	if err != nil {
 		return fmt.Errorf("orbital dynamics analysis failed: %w", err)
 	}
 
 	// Store results on blockchain using the blockchain client
-	if err := c.storeAnalysisResult(result); err != nil {
+	if err := c.storeAnalysisResult(ctx, result); err != nil {
 		return fmt.Errorf("failed to store results: %w", err)
 	}
 
@@ -218,19 +238,19 @@ func (c *MedasDigitalClient) AnalyzeOrbitalDynamics(inputFile, outputFile string
 }
 
 // AnalyzePhotometric performs photometric analysis
-func (c *MedasDigitalClient) AnalyzePhotometric(surveyData, targetList string) error {
+func (c *MedasDigitalClient) AnalyzePhotometric(ctx context.Context, surveyData, targetList string) error {
 	if !c.hasCapability("photometric_analysis") {
 		return fmt.Errorf("client does not have photometric_analysis capability")
 	}
 
 	log.Printf("Starting photometric analysis on survey data: %s", surveyData)
 
-	result, err := c.analyzer.AnalyzePhotometric(surveyData, targetList)
+	result, err := c.analyzer.AnalyzePhotometric(ctx, surveyData, targetList)
 	if err != nil {
 		return fmt.Errorf("photometric analysis failed: %w", err)
 	}
 
-	if err := c.storeAnalysisResult(result); err != nil {
+	if err := c.storeAnalysisResult(ctx, result); err != nil {
 		return fmt.Errorf("failed to store results: %w", err)
 	}
 
@@ -239,19 +259,19 @@ func (c *MedasDigitalClient) AnalyzePhotometric(surveyData, targetList string) e
 }
 
 // AnalyzeClustering performs clustering analysis
-func (c *MedasDigitalClient) AnalyzeClustering() error {
+func (c *MedasDigitalClient) AnalyzeClustering(ctx context.Context) error {
 	if !c.hasCapability("clustering_analysis") {
 		return fmt.Errorf("client does not have clustering_analysis capability")
 	}
 
 	log.Printf("Starting clustering analysis")
 
-	result, err := c.analyzer.AnalyzeClustering()
+	result, err := c.analyzer.AnalyzeClustering(ctx)
 	if err != nil {
 		return fmt.Errorf("clustering analysis failed: %w", err)
 	}
 
-	if err := c.storeAnalysisResult(result); err != nil {
+	if err := c.storeAnalysisResult(ctx, result); err != nil {
 		return fmt.Errorf("failed to store results: %w", err)
 	}
 
@@ -259,24 +279,69 @@ func (c *MedasDigitalClient) AnalyzeClustering() error {
 	return nil
 }
 
+// ConfigureGPU enables the GPU manager and the scheduler from the user config.
+// memoryLimitMB caps the memory the client uses per device (0 = no cap).
+func (c *MedasDigitalClient) ConfigureGPU(cfg utils.GPUConfig, memoryLimitMB int) error {
+	c.config.GPU = cfg
+	if !cfg.Enabled {
+		return nil
+	}
+
+	manager := gpu.NewManager(&c.config.GPU)
+	if err := manager.Initialize(); err != nil {
+		return fmt.Errorf("failed to initialize GPU manager: %w", err)
+	}
+
+	c.gpuManager = manager
+	c.gpuScheduler = compute.NewGPUScheduler(manager, memoryLimitMB)
+	c.analyzer = analysis.NewManager(manager)
+	return nil
+}
+
+// GPUScheduler returns the scheduler, nil when GPUs are disabled
+func (c *MedasDigitalClient) GPUScheduler() *compute.GPUScheduler {
+	return c.gpuScheduler
+}
+
 // AIDetection performs AI-powered object detection
-func (c *MedasDigitalClient) AIDetection(modelPath, surveyImages string, gpuAccel bool) error {
+func (c *MedasDigitalClient) AIDetection(ctx context.Context, modelPath, surveyImages, outputFile string, opts analysis.DetectionOptions) error {
 	if !c.hasCapability("ai_training") {
 		return fmt.Errorf("client does not have ai_training capability")
 	}
 
-	if gpuAccel && c.gpuManager == nil {
-		return fmt.Errorf("GPU acceleration requested but no GPU available")
+	if opts.UseGPU && c.gpuManager == nil {
+		return fmt.Errorf("GPU acceleration requested: %w", compute.ErrGPUUnavailable)
+	}
+
+	if opts.UseGPU {
+		var devices []int
+		if opts.DeviceID >= 0 {
+			devices = []int{opts.DeviceID}
+		}
+		alloc, err := c.allocateGPUs("detect", opts.MemoryMB, devices, 1)
+		if err != nil {
+			return err
+		}
+		defer c.gpuScheduler.Release(alloc.JobID)
+		opts.DeviceID = alloc.Devices[0]
+		log.Printf("Scheduled AI detection on GPU %d", opts.DeviceID)
 	}
 
 	log.Printf("Starting AI detection on survey images: %s", surveyImages)
 
-	result, err := c.analyzer.AIDetection(modelPath, surveyImages, gpuAccel)
+	result, err := c.analyzer.AIDetection(ctx, modelPath, surveyImages, opts)
 	if err != nil {
 		return fmt.Errorf("AI detection failed: %w", err)
 	}
 
-	if err := c.storeAnalysisResult(result); err != nil {
+	// Save locally first so detections survive a failed chain submission
+	if outputFile != "" {
+		if err := c.saveResults(result, outputFile); err != nil {
+			return fmt.Errorf("failed to save results locally: %w", err)
+		}
+	}
+
+	if err := c.storeAnalysisResult(ctx, result); err != nil {
 		return fmt.Errorf("failed to store results: %w", err)
 	}
 
@@ -284,24 +349,33 @@ func (c *MedasDigitalClient) AIDetection(modelPath, surveyImages string, gpuAcce
 	return nil
 }
 
-// TrainDeepDetector trains a deep learning detector
-func (c *MedasDigitalClient) TrainDeepDetector(trainingData, architecture string, gpuDevices []int, batchSize, epochs int) error {
+// TrainDeepDetector trains a deep learning detector. Without explicit gpuDevices
+// the scheduler picks gpuCount devices with memoryMB free each.
+func (c *MedasDigitalClient) TrainDeepDetector(ctx context.Context, trainingData, architecture string, gpuDevices []int, gpuCount, memoryMB, batchSize, epochs int) error {
 	if !c.hasCapability("ai_training") {
 		return fmt.Errorf("client does not have ai_training capability")
 	}
 
 	if c.gpuManager == nil {
-		return fmt.Errorf("GPU training requested but no GPU available")
+		return fmt.Errorf("GPU training requested: %w", compute.ErrGPUUnavailable)
+	}
+
+	alloc, err := c.allocateGPUs("train", memoryMB, gpuDevices, gpuCount)
+	if err != nil {
+		return err
 	}
+	defer c.gpuScheduler.Release(alloc.JobID)
+	gpuDevices = alloc.Devices
+	log.Printf("Scheduled training on GPU(s) %v", gpuDevices)
 
 	log.Printf("Starting deep detector training with architecture: %s", architecture)
 
-	result, err := c.analyzer.TrainDeepDetector(trainingData, architecture, gpuDevices, batchSize, epochs)
+	result, err := c.analyzer.TrainDeepDetector(ctx, trainingData, architecture, gpuDevices, batchSize, epochs)
 	if err != nil {
 		return fmt.Errorf("training failed: %w", err)
 	}
 
-	if err := c.storeAnalysisResult(result); err != nil {
+	if err := c.storeAnalysisResult(ctx, result); err != nil {
 		return fmt.Errorf("failed to store training results: %w", err)
 	}
 
@@ -310,15 +384,15 @@ func (c *MedasDigitalClient) TrainDeepDetector(trainingData, architecture string
 }
 
 // TrainAnomalyDetector trains an anomaly detection model
-func (c *MedasDigitalClient) TrainAnomalyDetector() error {
+func (c *MedasDigitalClient) TrainAnomalyDetector(ctx context.Context) error {
 	log.Printf("Starting anomaly detector training")
 
-	result, err := c.analyzer.TrainAnomalyDetector()
+	result, err := c.analyzer.TrainAnomalyDetector(ctx)
 	if err != nil {
 		return fmt.Errorf("anomaly detector training failed: %w", err)
 	}
 
-	if err := c.storeAnalysisResult(result); err != nil {
+	if err := c.storeAnalysisResult(ctx, result); err != nil {
 		return fmt.Errorf("failed to store training results: %w", err)
 	}
 
@@ -327,7 +401,7 @@ func (c *MedasDigitalClient) TrainAnomalyDetector() error {
 }
 
 // Status returns the current client status
-func (c *MedasDigitalClient) Status() error {
+func (c *MedasDigitalClient) Status(ctx context.Context) error {
 	fmt.Printf("=== MedasDigital Client Status ===\n")
 	fmt.Printf("Client ID: %s\n", c.clientID)
 	fmt.Printf("Registered: %t\n", c.isRegistered)
@@ -336,7 +410,7 @@ func (c *MedasDigitalClient) Status() error {
 	fmt.Printf("RPC Endpoint: %s\n", c.config.Chain.RPCEndpoint)
 
 	// Blockchain status using the blockchain client
-	status, err := c.blockchain.GetChainStatus()
+	status, err := c.blockchain.GetChainStatus(ctx)
 	if err != nil {
 		fmt.Printf("Blockchain Status: ERROR - %v\n", err)
 	} else {
@@ -355,31 +429,61 @@ func (c *MedasDigitalClient) Status() error {
 	return nil
 }
 
-// Results retrieves recent analysis results
-func (c *MedasDigitalClient) Results(limit int) error {
-	fmt.Printf("=== Recent Analysis Results (limit: %d) ===\n", limit)
+// ResultsPage returns one page of analysis results, those of this client
+// unless the query names another one
+func (c *MedasDigitalClient) ResultsPage(ctx context.Context, q blockchain.ResultsQuery) (*blockchain.ResultsPage, error) {
+	if q.ClientID == "" {
+		q.ClientID = c.clientID
+	}
+	page, err := c.blockchain.QueryAnalysisResults(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve results: %w", err)
+	}
+	return page, nil
+}
 
-	results, err := c.blockchain.GetAnalysisResults(c.clientID, limit)
+// Results prints one page of analysis results, see ResultsPage
+func (c *MedasDigitalClient) Results(ctx context.Context, q blockchain.ResultsQuery, asJSON bool) error {
+	page, err := c.ResultsPage(ctx, q)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve results: %w", err)
+		return err
+	}
+
+	if asJSON {
+		data, err := json.MarshalIndent(page, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
 	}
 
-	for i, result := range results {
+	fmt.Printf("=== Analysis Results (limit: %d) ===\n", q.Limit)
+	if len(page.Results) == 0 {
+		fmt.Println("No results found")
+	}
+	for i, result := range page.Results {
 		fmt.Printf("\n--- Result %d ---\n", i+1)
 		data, _ := json.MarshalIndent(result, "", "  ")
 		fmt.Printf("%s\n", string(data))
 	}
+	if page.Total > 0 {
+		fmt.Printf("\n%d of %d results\n", len(page.Results), page.Total)
+	}
+	if page.NextCursor != "" {
+		fmt.Printf("\nMore results: --cursor %s\n", page.NextCursor)
+	}
 
 	return nil
 }
 
 // Query queries blockchain data - simplified implementation
-func (c *MedasDigitalClient) Query(queryType, queryID string) error {
+func (c *MedasDigitalClient) Query(ctx context.Context, queryType, queryID string) error {
 	fmt.Printf("=== Querying %s: %s ===\n", queryType, queryID)
 
 	switch queryType {
 	case "client":
-		result, err := c.blockchain.GetClient(queryID)
+		result, err := c.blockchain.GetClient(ctx, queryID)
 		if err != nil {
 			return fmt.Errorf("query failed: %w", err)
 		}
@@ -400,15 +504,50 @@ func (c *MedasDigitalClient) GPUStatus() error {
 	}
 
 	fmt.Printf("GPU Status: Available\n")
+	fmt.Printf("GPU Backend: %s\n", strings.ToUpper(c.gpuManager.Backend()))
 	deviceCount := c.gpuManager.GetDeviceCount()
 	fmt.Printf("GPU Device Count: %d\n", deviceCount)
+
+	if c.gpuScheduler == nil {
+		return nil
+	}
+	devices, err := c.gpuScheduler.Status()
+	if err != nil {
+		return err
+	}
+	for _, d := range devices {
+		name := "unknown"
+		if info, err := c.gpuManager.GetDeviceInfo(d.ID); err == nil {
+			name = fmt.Sprintf("%s [%s]", info.Name, info.Vendor)
+		}
+		fmt.Printf("  GPU %d: %s\n", d.ID, name)
+		fmt.Printf("         %d/%d MB free, %d MB schedulable, %d active job(s)\n",
+			d.FreeMemory/1024/1024, d.TotalMemory/1024/1024, d.Available/1024/1024, d.ActiveJobs)
+	}
 	return nil
 }
 
+// allocateGPUs reserves devices for a local job, explicit devices are checked against the memory limit
+func (c *MedasDigitalClient) allocateGPUs(kind string, memoryMB int, devices []int, count int) (*compute.GPUAllocation, error) {
+	if c.gpuScheduler == nil {
+		return nil, fmt.Errorf("GPU scheduler not configured")
+	}
+	if memoryMB <= 0 {
+		memoryMB = defaultGPUJobMemoryMB
+	}
+
+	jobID := fmt.Sprintf("%s-%d", kind, time.Now().UnixNano())
+	memory := int64(memoryMB) * 1024 * 1024
+	if len(devices) > 0 {
+		return c.gpuScheduler.AllocateDevices(jobID, memory, devices)
+	}
+	return c.gpuScheduler.Allocate(jobID, memory, count)
+}
+
 // GPUBenchmark runs a GPU benchmark
 func (c *MedasDigitalClient) GPUBenchmark() error {
 	if c.gpuManager == nil {
-		return fmt.Errorf("GPU not available or not enabled")
+		return fmt.Errorf("GPU not enabled: %w", compute.ErrGPUUnavailable)
 	}
 
 	fmt.Println("Running GPU benchmark...")
@@ -426,7 +565,7 @@ func (c *MedasDigitalClient) hasCapability(capability string) bool {
 	return false
 }
 
-func (c *MedasDigitalClient) storeAnalysisResult(result *itypes.AnalysisResult) error {
+func (c *MedasDigitalClient) storeAnalysisResult(ctx context.Context, result *itypes.AnalysisResult) error {
 	if !c.isRegistered {
 		return fmt.Errorf("client not registered")
 	}
@@ -438,7 +577,7 @@ func (c *MedasDigitalClient) storeAnalysisResult(result *itypes.AnalysisResult)
 	}
 
 	// Use blockchain client to store results
-	return c.blockchain.StoreAnalysisResult(
+	return c.blockchain.StoreAnalysisResult(ctx,
 		c.clientCtx.GetFromAddress().String(),
 		c.clientID,
 		result.AnalysisType,
@@ -448,6 +587,21 @@ func (c *MedasDigitalClient) storeAnalysisResult(result *itypes.AnalysisResult)
 	)
 }
 
+// StoreResult stores a result saved by an earlier run or another tool on chain
+func (c *MedasDigitalClient) StoreResult(ctx context.Context, result *itypes.AnalysisResult) error {
+	if result.AnalysisType == "" {
+		return fmt.Errorf("result has no analysis type")
+	}
+	if len(result.Data) == 0 {
+		return fmt.Errorf("result has no data")
+	}
+	log.Printf("Storing %s result on chain", result.AnalysisType)
+	if err := c.storeAnalysisResult(ctx, result); err != nil {
+		return fmt.Errorf("failed to store results: %w", err)
+	}
+	return nil
+}
+
 func (c *MedasDigitalClient) saveResults(result *itypes.AnalysisResult, outputFile string) error {
 	data, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
@@ -0,0 +1,244 @@
+package compute
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ArtifactEncoding is how artifacts are compressed on disk
+const ArtifactEncoding = "zstd"
+
+// DefaultArtifactRetention is how long an artifact no job refers to is kept
+const DefaultArtifactRetention = 7 * 24 * time.Hour
+
+// Artifact is the result file of a completed job. Services serve it for
+// download instead of inlining large results into the job. It is stored
+// compressed under SHA256, jobs with the same result share it.
+type Artifact struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`      // of the uncompressed content, verify downloads with it
+	Encoding    string `json:"encoding"`    // compression on disk
+	StoredSize  int64  `json:"stored_size"` // compressed size on disk
+}
+
+// SetArtifactDir writes the result of every following completed job to a
+// file in dir, see ArtifactPath
+func (jm *JobManager) SetArtifactDir(dir string) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.artifactDir = dir
+}
+
+// ArtifactPath is the compressed result file of a job, empty if it has none.
+// Read it with OpenArtifact.
+func (jm *JobManager) ArtifactPath(job *ComputeJob) string {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	if jm.artifactDir == "" || job.Artifact == nil {
+		return ""
+	}
+	return artifactFile(jm.artifactDir, job.Artifact.SHA256)
+}
+
+// artifactFile is where the artifact with the content hash sha is stored
+func artifactFile(dir, sha string) string {
+	return filepath.Join(dir, sha+".zst")
+}
+
+// writeArtifact saves the result of a job that is about to complete. A PI
+// result becomes its digits as text, any other result its JSON.
+func (jm *JobManager) writeArtifact(job *ComputeJob) error {
+	jm.mu.RLock()
+	dir := jm.artifactDir
+	jm.mu.RUnlock()
+	if dir == "" || job.Result == nil {
+		return nil
+	}
+
+	artifact := &Artifact{Name: job.ID + ".json", ContentType: "application/json", Encoding: ArtifactEncoding}
+	var data []byte
+	if pi, ok := job.Result.(*PIResult); ok {
+		artifact.Name, artifact.ContentType = job.ID+".txt", "text/plain; charset=utf-8"
+		data = []byte(pi.Value)
+	} else {
+		var err error
+		if data, err = json.MarshalIndent(job.Result, "", "  "); err != nil {
+			return err
+		}
+	}
+	sum := sha256.Sum256(data)
+	artifact.SHA256 = hex.EncodeToString(sum[:])
+	artifact.Size = int64(len(data))
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	path := artifactFile(dir, artifact.SHA256)
+	if info, err := os.Stat(path); err == nil {
+		// Stored for another job already, it counts as new for the retention
+		now := time.Now()
+		os.Chtimes(path, now, now)
+		artifact.StoredSize = info.Size()
+		job.Artifact = artifact
+		return nil
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	enc, err := zstd.NewWriter(f)
+	if err == nil {
+		_, err = enc.Write(data)
+		if cerr := enc.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if info, err := os.Stat(path); err == nil {
+		artifact.StoredSize = info.Size()
+	}
+	job.Artifact = artifact
+	return nil
+}
+
+// CollectArtifacts removes the files in the artifact directory no job refers
+// to that were last written before retention, and returns how many it
+// removed. Jobs that completed with the same result keep their file alive.
+func (jm *JobManager) CollectArtifacts(retention time.Duration) (int, error) {
+	jm.mu.RLock()
+	dir := jm.artifactDir
+	referenced := make(map[string]bool)
+	for _, job := range jm.jobs {
+		if job.Artifact != nil {
+			referenced[job.Artifact.SHA256+".zst"] = true
+		}
+	}
+	if jm.cache != nil {
+		for _, sha := range jm.cache.ArtifactRefs() {
+			referenced[sha+".zst"] = true
+		}
+	}
+	jm.mu.RUnlock()
+	if dir == "" {
+		return 0, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-retention)
+	removed := 0
+	for _, e := range entries {
+		if e.IsDir() || referenced[e.Name()] {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+			return removed, fmt.Errorf("failed to remove artifact %s: %w", strings.TrimSuffix(e.Name(), ".zst"), err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// ArtifactReader reads the uncompressed content of a stored artifact. It
+// seeks by decompressing up to the offset, backwards from the start.
+type ArtifactReader struct {
+	f    *os.File
+	dec  *zstd.Decoder
+	size int64
+	pos  int64 // offset Read continues at
+	at   int64 // offset of the decoder
+}
+
+// OpenArtifact opens a file written for an artifact of size bytes
+func OpenArtifact(path string, size int64) (*ArtifactReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := zstd.NewReader(f, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &ArtifactReader{f: f, dec: dec, size: size}, nil
+}
+
+// Read reads the content at the current offset
+func (r *ArtifactReader) Read(p []byte) (int, error) {
+	if r.pos < r.at {
+		if _, err := r.f.Seek(0, io.SeekStart); err != nil {
+			return 0, err
+		}
+		if err := r.dec.Reset(r.f); err != nil {
+			return 0, err
+		}
+		r.at = 0
+	}
+	if r.pos > r.at {
+		n, err := io.CopyN(io.Discard, r.dec, r.pos-r.at)
+		r.at += n
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.dec.Read(p)
+	r.at += int64(n)
+	r.pos = r.at
+	return n, err
+}
+
+// Seek sets the offset of the next Read, nothing is decompressed until then
+func (r *ArtifactReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset %d", offset)
+	}
+	r.pos = offset
+	return offset, nil
+}
+
+// Close releases the decoder and the file
+func (r *ArtifactReader) Close() error {
+	r.dec.Close()
+	return r.f.Close()
+}
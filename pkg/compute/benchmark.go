@@ -0,0 +1,92 @@
+package compute
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// BenchmarkDigits is the size of the reference PI benchmark
+const BenchmarkDigits = 50000
+
+// benchmarkCommitment is the SHA-256 of PI to BenchmarkDigits digits, a
+// benchmark only counts when it produced exactly these digits
+const benchmarkCommitment = "653b506bfcaf6ca3d0b3e29dc96c1426ae3befe3846564a302bbfc3487463701"
+
+// PIBenchmark is the outcome of the reference PI benchmark. Unlike the PI
+// jobs it computes every digit with Machin's formula in big integer
+// arithmetic, so the score reflects the machine's single-core speed.
+type PIBenchmark struct {
+	Digits     int           `json:"digits"`
+	Method     string        `json:"method"`
+	Duration   time.Duration `json:"duration"`
+	Score      float64       `json:"score"` // digits per second
+	Commitment string        `json:"commitment"`
+	MeasuredAt time.Time     `json:"measured_at"`
+}
+
+// RunPIBenchmark computes PI to BenchmarkDigits digits and scores the run
+func RunPIBenchmark() (*PIBenchmark, error) {
+	start := time.Now()
+	value := machinPI(BenchmarkDigits)
+	duration := time.Since(start)
+
+	sum := sha256.Sum256([]byte(value))
+	b := &PIBenchmark{
+		Digits:     BenchmarkDigits,
+		Method:     string(MethodMachin),
+		Duration:   duration,
+		Score:      float64(BenchmarkDigits) / duration.Seconds(),
+		Commitment: hex.EncodeToString(sum[:]),
+		MeasuredAt: time.Now(),
+	}
+	if err := b.Verify(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Verify checks that the benchmark computed the correct digits
+func (b *PIBenchmark) Verify() error {
+	if b.Digits != BenchmarkDigits || b.Commitment != benchmarkCommitment {
+		return fmt.Errorf("benchmark did not produce the reference digits of PI")
+	}
+	if b.Duration <= 0 || b.Score <= 0 {
+		return fmt.Errorf("benchmark has no timing")
+	}
+	return nil
+}
+
+// machinPI computes PI with π/4 = 4·arccot(5) − arccot(239) in fixed point
+func machinPI(digits int) string {
+	const guard = 10
+	unity := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits+guard)), nil)
+
+	pi := new(big.Int).Mul(big.NewInt(4), arccot(5, unity))
+	pi.Sub(pi, arccot(239, unity))
+	pi.Mul(pi, big.NewInt(4))
+	pi.Div(pi, new(big.Int).Exp(big.NewInt(10), big.NewInt(guard), nil))
+
+	s := pi.String()
+	return s[:1] + "." + s[1:]
+}
+
+// arccot sums the arccot(x) series scaled by unity
+func arccot(x int64, unity *big.Int) *big.Int {
+	sum := new(big.Int)
+	term := new(big.Int).Div(unity, big.NewInt(x))
+	x2 := big.NewInt(x * x)
+	t := new(big.Int)
+	for n, sign := int64(1), 1; term.Sign() != 0; n, sign = n+2, -sign {
+		t.Div(term, big.NewInt(n))
+		if sign > 0 {
+			sum.Add(sum, t)
+		} else {
+			sum.Sub(sum, t)
+		}
+		term.Div(term, x2)
+	}
+	return sum
+}
@@ -0,0 +1,201 @@
+package compute
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+)
+
+// OutOfCore lets the Chudnovsky binary splitting keep intermediate products
+// on disk once those held in memory outgrow a budget, for precisions that
+// would otherwise exhaust the RAM of the host
+type OutOfCore struct {
+	MemoryBudget int64  `json:"memory_budget"` // bytes of held products, 0 keeps everything in memory
+	TempDir      string `json:"temp_dir"`      // where products are spilled, default the system temp dir
+}
+
+// Enabled reports whether products are spilled at all
+func (o OutOfCore) Enabled() bool {
+	return o.MemoryBudget > 0
+}
+
+// SpillStats tells how much of a calculation went to disk
+type SpillStats struct {
+	Spills       int   `json:"spills"`
+	SpilledBytes int64 `json:"spilled_bytes"`
+	PeakHeld     int64 `json:"peak_held_bytes"` // largest total of products held in memory
+}
+
+// Chudnovsky series constants
+var (
+	chudA     = big.NewInt(13591409)
+	chudB     = big.NewInt(545140134)
+	chudC3_24 = new(big.Int).Div(new(big.Int).Exp(big.NewInt(640320), big.NewInt(3), nil), big.NewInt(24))
+)
+
+// chudnovskyDigitsPerTerm is how many digits each term of the series adds
+const chudnovskyDigitsPerTerm = 14.181647462725477
+
+// pqt is the product of a range of series terms in binary splitting
+type pqt struct {
+	P, Q, T *big.Int
+}
+
+// size is the memory the product takes
+func (r *pqt) size() int64 {
+	return int64(len(r.P.Bits())+len(r.Q.Bits())+len(r.T.Bits())) * 8
+}
+
+// splitter runs the binary splitting and spills held products
+type splitter struct {
+	ctx   context.Context
+	ooc   OutOfCore
+	dir   string // created on the first spill
+	held  int64
+	stats SpillStats
+}
+
+// chudnovskyPI computes PI to digits decimals with the Chudnovsky series by
+// binary splitting
+func chudnovskyPI(ctx context.Context, digits int, ooc OutOfCore) (string, SpillStats, error) {
+	const guard = 10
+	terms := int64(float64(digits)/chudnovskyDigitsPerTerm) + 1
+
+	s := &splitter{ctx: ctx, ooc: ooc}
+	defer func() {
+		if s.dir != "" {
+			os.RemoveAll(s.dir)
+		}
+	}()
+	r, err := s.split(0, terms)
+	if err != nil {
+		return "", s.stats, err
+	}
+
+	// π = 426880·√10005·Q / T, in fixed point with digits+guard decimals
+	unity := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits+guard)), nil)
+	sqrt := new(big.Int).Mul(big.NewInt(10005), new(big.Int).Mul(unity, unity))
+	sqrt.Sqrt(sqrt)
+	pi := new(big.Int).Mul(r.Q, big.NewInt(426880))
+	pi.Mul(pi, sqrt)
+	pi.Quo(pi, r.T)
+	pi.Quo(pi, new(big.Int).Exp(big.NewInt(10), big.NewInt(guard), nil))
+
+	v := pi.String()
+	return v[:1] + "." + v[1:], s.stats, nil
+}
+
+// split returns the product of the terms a to b-1. While the right half is
+// computed the left one is held, on disk if it does not fit the budget.
+func (s *splitter) split(a, b int64) (*pqt, error) {
+	if b-a == 1 {
+		return chudnovskyTerm(a), nil
+	}
+	if b-a >= 64 {
+		if err := s.ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	m := (a + b) / 2
+	left, err := s.split(a, m)
+	if err != nil {
+		return nil, err
+	}
+	size := left.size()
+	var spilled string
+	if s.ooc.Enabled() && s.held+size > s.ooc.MemoryBudget {
+		if spilled, err = s.spill(left); err != nil {
+			return nil, err
+		}
+		left = nil
+	} else {
+		s.held += size
+		s.stats.PeakHeld = max(s.stats.PeakHeld, s.held)
+	}
+
+	right, err := s.split(m, b)
+	if spilled != "" {
+		if err == nil {
+			left, err = s.load(spilled)
+		}
+		os.Remove(spilled)
+	} else {
+		s.held -= size
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// P = P1·P2, Q = Q1·Q2, T = T1·Q2 + P1·T2
+	t := new(big.Int).Mul(left.T, right.Q)
+	t.Add(t, left.T.Mul(left.P, right.T))
+	return &pqt{
+		P: left.P.Mul(left.P, right.P),
+		Q: left.Q.Mul(left.Q, right.Q),
+		T: t,
+	}, nil
+}
+
+// chudnovskyTerm is the single term a of the series
+func chudnovskyTerm(a int64) *pqt {
+	if a == 0 {
+		return &pqt{P: big.NewInt(1), Q: big.NewInt(1), T: new(big.Int).Set(chudA)}
+	}
+	p := big.NewInt(6*a - 5)
+	p.Mul(p, big.NewInt(2*a-1))
+	p.Mul(p, big.NewInt(6*a-1))
+	q := big.NewInt(a)
+	q.Mul(q, q).Mul(q, big.NewInt(a))
+	q.Mul(q, chudC3_24)
+	t := new(big.Int).Mul(chudB, big.NewInt(a))
+	t.Add(t, chudA)
+	t.Mul(t, p)
+	if a%2 == 1 {
+		t.Neg(t)
+	}
+	return &pqt{P: p, Q: q, T: t}
+}
+
+// spill writes a product to a temporary file
+func (s *splitter) spill(r *pqt) (string, error) {
+	if s.dir == "" {
+		dir, err := os.MkdirTemp(s.ooc.TempDir, "medas-pi-*")
+		if err != nil {
+			return "", fmt.Errorf("failed to create spill directory: %w", err)
+		}
+		s.dir = dir
+	}
+	s.stats.Spills++
+	path := filepath.Join(s.dir, fmt.Sprintf("%d.gob", s.stats.Spills))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to spill: %w", err)
+	}
+	defer f.Close()
+	if err := gob.NewEncoder(f).Encode(r); err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to spill: %w", err)
+	}
+	if info, err := f.Stat(); err == nil {
+		s.stats.SpilledBytes += info.Size()
+	}
+	return path, nil
+}
+
+// load reads a spilled product back
+func (s *splitter) load(path string) (*pqt, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load spilled product: %w", err)
+	}
+	defer f.Close()
+	var r pqt
+	if err := gob.NewDecoder(f).Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to load spilled product: %w", err)
+	}
+	return &r, nil
+}
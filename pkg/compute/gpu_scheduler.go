@@ -0,0 +1,231 @@
+package compute
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNoGPUCapacity is returned when no device combination can hold a job
+var ErrNoGPUCapacity = errors.New("no GPU with enough free memory")
+
+// ErrGPUUnavailable is returned when a job needs a GPU and none is usable
+var ErrGPUUnavailable = errors.New("no GPU available")
+
+// GPUMemoryReporter reports per-device memory, gpu.Manager satisfies it
+type GPUMemoryReporter interface {
+	GetDeviceCount() int
+	GetMemoryInfo(deviceID int) (free, total int64, err error)
+}
+
+// GPUAllocation is a job's reservation on one or more devices
+type GPUAllocation struct {
+	JobID           string    `json:"job_id"`
+	Devices         []int     `json:"devices"`
+	MemoryPerDevice int64     `json:"memory_per_device"`
+	AllocatedAt     time.Time `json:"allocated_at"`
+}
+
+// GPUDeviceStatus is the scheduler's view of one device
+type GPUDeviceStatus struct {
+	ID          int   `json:"id"`
+	TotalMemory int64 `json:"total_memory"`
+	FreeMemory  int64 `json:"free_memory"` // reported by the device
+	Reserved    int64 `json:"reserved"`    // held by scheduled jobs
+	Available   int64 `json:"available"`   // what a new job can still get
+	ActiveJobs  int   `json:"active_jobs"`
+}
+
+// GPUScheduler assigns jobs to the least loaded devices and keeps every
+// device below the configured memory limit
+type GPUScheduler struct {
+	mu          sync.Mutex
+	reporter    GPUMemoryReporter
+	memoryLimit int64 // per device in bytes, 0 = whole device
+	reserved    map[int]int64
+	activeJobs  map[int]int
+	allocations map[string]*GPUAllocation
+}
+
+// NewGPUScheduler creates a scheduler, memoryLimitMB caps the memory used per device (0 = no cap)
+func NewGPUScheduler(reporter GPUMemoryReporter, memoryLimitMB int) *GPUScheduler {
+	return &GPUScheduler{
+		reporter:    reporter,
+		memoryLimit: int64(memoryLimitMB) * 1024 * 1024,
+		reserved:    make(map[int]int64),
+		activeJobs:  make(map[int]int),
+		allocations: make(map[string]*GPUAllocation),
+	}
+}
+
+// Allocate reserves memoryPerDevice bytes on deviceCount devices for a job.
+// Devices with fewer active jobs are preferred, ties go to the device with more available memory.
+func (s *GPUScheduler) Allocate(jobID string, memoryPerDevice int64, deviceCount int) (*GPUAllocation, error) {
+	if deviceCount <= 0 {
+		deviceCount = 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.allocations[jobID]; exists {
+		return nil, fmt.Errorf("job %s already has a GPU allocation", jobID)
+	}
+
+	devices, err := s.statusLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := devices[:0]
+	for _, d := range devices {
+		if d.Available >= memoryPerDevice {
+			candidates = append(candidates, d)
+		}
+	}
+	if len(candidates) < deviceCount {
+		return nil, fmt.Errorf("%w: need %d device(s) with %d MB, %d available", ErrNoGPUCapacity, deviceCount, memoryPerDevice/1024/1024, len(candidates))
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].ActiveJobs != candidates[j].ActiveJobs {
+			return candidates[i].ActiveJobs < candidates[j].ActiveJobs
+		}
+		return candidates[i].Available > candidates[j].Available
+	})
+
+	alloc := &GPUAllocation{
+		JobID:           jobID,
+		MemoryPerDevice: memoryPerDevice,
+		AllocatedAt:     time.Now(),
+	}
+	for _, d := range candidates[:deviceCount] {
+		alloc.Devices = append(alloc.Devices, d.ID)
+		s.reserved[d.ID] += memoryPerDevice
+		s.activeJobs[d.ID]++
+	}
+	sort.Ints(alloc.Devices)
+
+	s.allocations[jobID] = alloc
+	return alloc, nil
+}
+
+// AllocateDevices reserves memory on explicitly requested devices
+func (s *GPUScheduler) AllocateDevices(jobID string, memoryPerDevice int64, deviceIDs []int) (*GPUAllocation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.allocations[jobID]; exists {
+		return nil, fmt.Errorf("job %s already has a GPU allocation", jobID)
+	}
+
+	devices, err := s.statusLocked()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]GPUDeviceStatus, len(devices))
+	for _, d := range devices {
+		byID[d.ID] = d
+	}
+
+	for _, id := range deviceIDs {
+		d, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("GPU device %d not found", id)
+		}
+		if d.Available < memoryPerDevice {
+			return nil, fmt.Errorf("%w: device %d has %d MB available, job needs %d MB", ErrNoGPUCapacity, id, d.Available/1024/1024, memoryPerDevice/1024/1024)
+		}
+	}
+
+	alloc := &GPUAllocation{
+		JobID:           jobID,
+		Devices:         append([]int(nil), deviceIDs...),
+		MemoryPerDevice: memoryPerDevice,
+		AllocatedAt:     time.Now(),
+	}
+	for _, id := range deviceIDs {
+		s.reserved[id] += memoryPerDevice
+		s.activeJobs[id]++
+	}
+
+	s.allocations[jobID] = alloc
+	return alloc, nil
+}
+
+// Release frees a job's reservation, unknown job IDs are ignored
+func (s *GPUScheduler) Release(jobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alloc, ok := s.allocations[jobID]
+	if !ok {
+		return
+	}
+	for _, id := range alloc.Devices {
+		s.reserved[id] -= alloc.MemoryPerDevice
+		s.activeJobs[id]--
+		if s.activeJobs[id] <= 0 {
+			delete(s.activeJobs, id)
+			delete(s.reserved, id)
+		}
+	}
+	delete(s.allocations, jobID)
+}
+
+// Status returns the current view of all devices
+func (s *GPUScheduler) Status() ([]GPUDeviceStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.statusLocked()
+}
+
+// Allocations returns the active reservations
+func (s *GPUScheduler) Allocations() []GPUAllocation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]GPUAllocation, 0, len(s.allocations))
+	for _, a := range s.allocations {
+		out = append(out, *a)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].AllocatedAt.Before(out[j].AllocatedAt) })
+	return out
+}
+
+func (s *GPUScheduler) statusLocked() ([]GPUDeviceStatus, error) {
+	if s.reporter == nil || s.reporter.GetDeviceCount() == 0 {
+		return nil, ErrGPUUnavailable
+	}
+
+	count := s.reporter.GetDeviceCount()
+	devices := make([]GPUDeviceStatus, 0, count)
+	for id := 0; id < count; id++ {
+		free, total, err := s.reporter.GetMemoryInfo(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read memory of GPU %d: %w", id, err)
+		}
+
+		d := GPUDeviceStatus{
+			ID:          id,
+			TotalMemory: total,
+			FreeMemory:  free,
+			Reserved:    s.reserved[id],
+			ActiveJobs:  s.activeJobs[id],
+		}
+
+		// Reservations may not show up in the reported free memory yet
+		available := free - d.Reserved
+		if s.memoryLimit > 0 && s.memoryLimit-d.Reserved < available {
+			available = s.memoryLimit - d.Reserved
+		}
+		if available < 0 {
+			available = 0
+		}
+		d.Available = available
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
@@ -2,7 +2,9 @@ package compute
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log"
 	"sync"
 	"time"
 )
@@ -17,6 +19,9 @@ const (
 	StatusCompleted JobStatus = "completed"
 	StatusFailed    JobStatus = "failed"
 	StatusCancelled JobStatus = "cancelled"
+	
+	// Stopped by a service shutdown, resumable from its checkpoint
+	StatusInterrupted JobStatus = "interrupted"
 )
 
 // JobType represents different types of computation jobs
@@ -24,6 +29,7 @@ type JobType string
 
 const (
 	JobTypePICalculation JobType = "pi_calculation"
+	JobTypePlanet9Search JobType = "planet9_search" // one chunk of a distributed sweep
 	// Future job types can be added here
 	// JobTypeMatrixMultiplication JobType = "matrix_multiplication"
 	// JobTypeFourierTransform     JobType = "fourier_transform"
@@ -43,8 +49,13 @@ type ComputeJob struct {
 	// Payment information
 	PaymentTxHash   string                 `json:"payment_tx_hash"`
 	PaymentVerified bool                   `json:"payment_verified"`
+	PaymentDenom    string                 `json:"payment_denom,omitempty"` // empty means the base denom
 	PriceBreakdown  *PriceBreakdown        `json:"price_breakdown"`
 	
+	// Result verification
+	Verification       VerificationLevel   `json:"verification"`
+	VerificationReport *VerificationReport `json:"verification_report,omitempty"`
+	
 	// Timing information
 	SubmittedAt     time.Time              `json:"submitted_at"`
 	StartedAt       *time.Time             `json:"started_at,omitempty"`
@@ -58,11 +69,27 @@ type ComputeJob struct {
 	
 	// Resource tracking
 	ResourceUsage   *ResourceUsage         `json:"resource_usage,omitempty"`
+	GPUDevices      []int                  `json:"gpu_devices,omitempty"`
+	
+	// Decimals delivered in digit blocks so far, see JobManager.Blocks
+	StreamedDigits  int                    `json:"streamed_digits,omitempty"`
+	
+	// Result file of a completed job, see JobManager.SetArtifactDir
+	Artifact        *Artifact              `json:"artifact,omitempty"`
+	
+	// Set when the result came from the result cache, see JobManager.SetResultCache
+	Cache           *CacheProvenance       `json:"cache,omitempty"`
 	
 	// Internal context (not serialized)
 	cancelFunc      context.CancelFunc     `json:"-"`
 	ctx             context.Context        `json:"-"`
 	progressChan    chan int               `json:"-"`
+	
+	// Digit blocks, guarded by the streamMu of the job manager
+	blocks          []DigitBlock           `json:"-"`
+	blocksChanged   chan struct{}          `json:"-"`
+	blockDigits     int                    `json:"-"` // block size of a resumed job
+	resumedAfter    time.Duration          `json:"-"` // runtime before the checkpoint
 }
 
 // ResourceUsage tracks actual resource consumption
@@ -93,10 +120,38 @@ type JobManager struct {
 	workerPool     chan struct{}
 	shutdownChan   chan struct{}
 	wg             sync.WaitGroup
+	
+	// Parent of the job contexts, cancelled when Shutdown times out
+	ctx            context.Context
+	cancel         context.CancelFunc
+	
+	// Optional GPU assignment for jobs with a gpu_memory_mb parameter
+	gpuScheduler   *GPUScheduler
+	
+	// Optional container sandbox, jobs run in-process without it
+	sandbox        *Sandbox
+	
+	// Optional callbacks for finished jobs, e.g. to refund the payment of a failed one
+	onFailed       func(*ComputeJob)
+	onCompleted    func(*ComputeJob)
+	
+	// Digit blocks of PI jobs and where their checkpoints are saved
+	streamMu       sync.Mutex
+	checkpointDir  string
+	
+	// Memory budget of in-process PI calculations, see SetOutOfCore
+	outOfCore      OutOfCore
+	
+	// Where result files of completed jobs are kept, see SetArtifactDir
+	artifactDir    string
+	
+	// Optional results of earlier jobs, see SetResultCache
+	cache          *ResultCache
 }
 
 // NewJobManager creates a new job manager
 func NewJobManager(maxJobs, workers int, pricingManager *PricingManager) *JobManager {
+	ctx, cancel := context.WithCancel(context.Background())
 	jm := &JobManager{
 		jobs:           make(map[string]*ComputeJob),
 		maxJobs:        maxJobs,
@@ -104,6 +159,8 @@ func NewJobManager(maxJobs, workers int, pricingManager *PricingManager) *JobMan
 		workers:        workers,
 		workerPool:     make(chan struct{}, workers),
 		shutdownChan:   make(chan struct{}),
+		ctx:            ctx,
+		cancel:         cancel,
 	}
 	
 	// Start worker pool
@@ -112,6 +169,43 @@ func NewJobManager(maxJobs, workers int, pricingManager *PricingManager) *JobMan
 	return jm
 }
 
+// SetGPUScheduler enables GPU assignment for jobs that request GPU memory
+func (jm *JobManager) SetGPUScheduler(scheduler *GPUScheduler) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.gpuScheduler = scheduler
+}
+
+// SetSandbox runs all following jobs inside the given container sandbox
+func (jm *JobManager) SetSandbox(sandbox *Sandbox) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.sandbox = sandbox
+}
+
+// SetOutOfCore spills the intermediate products of following in-process PI
+// calculations to disk once they outgrow the budget of o
+func (jm *JobManager) SetOutOfCore(o OutOfCore) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.outOfCore = o
+}
+
+// SetResultCache keeps the results of following completed jobs in cache and
+// answers identical jobs from it at the cache price
+func (jm *JobManager) SetResultCache(cache *ResultCache) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.cache = cache
+}
+
+// ResultCache returns the result cache, nil without one
+func (jm *JobManager) ResultCache() *ResultCache {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	return jm.cache
+}
+
 // startWorkers initializes the worker pool
 func (jm *JobManager) startWorkers() {
 	for i := 0; i < jm.workers; i++ {
@@ -168,7 +262,7 @@ func (jm *JobManager) getNextJob() *ComputeJob {
 }
 
 // SubmitJob submits a new computation job
-func (jm *JobManager) SubmitJob(jobType JobType, parameters map[string]interface{}, clientAddr string, tier ServiceTier, paymentTxHash string) (*ComputeJob, error) {
+func (jm *JobManager) SubmitJob(jobType JobType, parameters map[string]interface{}, clientAddr string, tier ServiceTier, verification VerificationLevel, paymentTxHash string) (*ComputeJob, error) {
 	jm.mu.Lock()
 	defer jm.mu.Unlock()
 	
@@ -186,19 +280,35 @@ func (jm *JobManager) SubmitJob(jobType JobType, parameters map[string]interface
 	if err := jm.validateJobParameters(jobType, parameters); err != nil {
 		return nil, fmt.Errorf("invalid parameters: %w", err)
 	}
+	if jobType == JobTypePICalculation {
+		parameters = jm.withPIMethod(parameters)
+	}
+	
+	// Validate verification level
+	verification, err := ParseVerificationLevel(string(verification))
+	if err != nil {
+		return nil, err
+	}
 	
 	// Calculate pricing
-	priceBreakdown, err := jm.calculateJobPrice(jobType, parameters, tier)
+	priceBreakdown, err := jm.calculateJobPrice(jobType, parameters, tier, verification)
 	if err != nil {
 		return nil, fmt.Errorf("pricing calculation failed: %w", err)
 	}
 	
+	// Identical jobs are answered from the result cache at the cache price
+	fullCost := priceBreakdown.TotalCost
+	entry, cachedResult := cacheHit(jm.cache, jobType, parameters, verification)
+	if entry != nil {
+		priceBreakdown = jm.cache.Price(priceBreakdown)
+	}
+	
 	// Create job ID
 	jm.jobCounter++
 	jobID := fmt.Sprintf("%s-%d", jobType, jm.jobCounter)
 	
 	// Create job context
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(jm.ctx)
 	progressChan := make(chan int, 10) // Buffered channel for progress updates
 	
 	// Determine priority based on tier
@@ -214,6 +324,7 @@ func (jm *JobManager) SubmitJob(jobType JobType, parameters map[string]interface
 		PaymentTxHash:   paymentTxHash,
 		PaymentVerified: false, // Will be verified separately
 		PriceBreakdown:  priceBreakdown,
+		Verification:    verification,
 		SubmittedAt:     time.Now(),
 		ClientAddr:      clientAddr,
 		Tier:            tier,
@@ -226,12 +337,73 @@ func (jm *JobManager) SubmitJob(jobType JobType, parameters map[string]interface
 	// Store job
 	jm.jobs[jobID] = job
 	
+	if entry != nil {
+		jm.completeFromCache(job, entry, cachedResult, fullCost)
+		return job, nil
+	}
+	
 	// Add to appropriate queue
 	jm.enqueueJob(job)
 	
 	return job, nil
 }
 
+// cacheHit returns the cache entry for a job and its decoded result, nil
+// without a usable entry
+func cacheHit(cache *ResultCache, jobType JobType, parameters map[string]interface{}, verification VerificationLevel) (*CacheEntry, interface{}) {
+	if cache == nil {
+		return nil, nil
+	}
+	key, err := CacheKey(jobType, parameters)
+	if err != nil {
+		return nil, nil
+	}
+	entry, ok := cache.Get(key, verification)
+	if !ok {
+		return nil, nil
+	}
+	result, err := decodeResult(jobType, entry.Result)
+	if err != nil {
+		return nil, nil
+	}
+	return entry, result
+}
+
+// completeFromCache completes a new job with a cached result, jm.mu is held
+func (jm *JobManager) completeFromCache(job *ComputeJob, entry *CacheEntry, result interface{}, fullCost float64) {
+	now := time.Now()
+	job.Result = result
+	job.Artifact = entry.Artifact
+	job.VerificationReport = entry.VerificationReport
+	job.Progress = 100
+	job.Status = StatusCompleted
+	job.StartedAt = &now
+	job.CompletedAt = &now
+	job.Duration = "0s"
+	job.Cache = &CacheProvenance{
+		Key:          entry.Key,
+		SourceJobID:  entry.SourceJobID,
+		ComputedAt:   entry.ComputedAt,
+		Duration:     entry.Duration,
+		Verification: entry.verification(),
+		FullCost:     fullCost,
+	}
+	jm.cache.hit(entry)
+	
+	// Streams deliver the cached digits at once. The job is not visible
+	// before jm.mu is released, streamMu is not needed.
+	if pi, ok := result.(*PIResult); ok {
+		job.blocks = resultBlocks(pi.Value, DefaultBlockDigits)
+		for _, b := range job.blocks {
+			job.StreamedDigits += len(b.Digits)
+		}
+	}
+	
+	// Nothing runs, the context is not needed
+	job.cancelFunc()
+	close(job.progressChan)
+}
+
 // enqueueJob adds a job to the appropriate priority queue
 func (jm *JobManager) enqueueJob(job *ComputeJob) {
 	jm.queueMu.Lock()
@@ -249,6 +421,30 @@ func (jm *JobManager) enqueueJob(job *ComputeJob) {
 	job.Status = StatusQueued
 }
 
+// AdvanceJobCounter makes new job IDs continue after n, so IDs of jobs kept
+// from an earlier run are not handed out again
+func (jm *JobManager) AdvanceJobCounter(n int64) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	if n > jm.jobCounter {
+		jm.jobCounter = n
+	}
+}
+
+// SetFailureHandler is called for every job that fails while being processed
+func (jm *JobManager) SetFailureHandler(handler func(*ComputeJob)) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.onFailed = handler
+}
+
+// SetCompletionHandler is called for every job that completed successfully
+func (jm *JobManager) SetCompletionHandler(handler func(*ComputeJob)) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.onCompleted = handler
+}
+
 // processJob processes a computation job
 func (jm *JobManager) processJob(job *ComputeJob) {
 	defer func() {
@@ -276,10 +472,39 @@ func (jm *JobManager) processJob(job *ComputeJob) {
 		StartTime: now,
 	}
 	
+	// Jobs with gpu_memory_mb get the least loaded device(s)
+	if memoryMB, ok := job.Parameters["gpu_memory_mb"].(float64); ok && memoryMB > 0 {
+		jm.mu.RLock()
+		scheduler := jm.gpuScheduler
+		jm.mu.RUnlock()
+		if scheduler == nil {
+			jm.failJob(job, "job requests GPU memory but no GPU scheduler is configured")
+			return
+		}
+		count, _ := job.Parameters["gpu_count"].(float64)
+		alloc, err := scheduler.Allocate(job.ID, int64(memoryMB)*1024*1024, int(count))
+		if err != nil {
+			jm.failJob(job, err.Error())
+			return
+		}
+		defer scheduler.Release(job.ID)
+		job.GPUDevices = alloc.Devices
+	}
+	
+	jm.mu.RLock()
+	sandbox := jm.sandbox
+	jm.mu.RUnlock()
+	
 	// Process based on job type
 	switch job.Type {
 	case JobTypePICalculation:
-		jm.processPICalculation(job)
+		if sandbox != nil {
+			jm.processSandboxed(job, sandbox)
+		} else {
+			jm.processPICalculation(job)
+		}
+	case JobTypePlanet9Search:
+		jm.processPlanet9Search(job)
 	default:
 		jm.failJob(job, fmt.Sprintf("unsupported job type: %s", job.Type))
 		return
@@ -307,16 +532,51 @@ func (jm *JobManager) processPICalculation(job *ComputeJob) {
 	
 	// Create PI calculator
 	calc := NewPICalculator(int(digits), method)
+	jm.mu.RLock()
+	calc.SetOutOfCore(jm.outOfCore)
+	jm.mu.RUnlock()
 	
 	// Start progress monitoring
 	go jm.monitorProgress(job)
+	done := make(chan bool)
+	go calc.updateProgress(job.progressChan, done)
 	
-	// Calculate PI with progress updates
-	result, err := calc.CalculateWithProgress(job.progressChan)
+	// Calculate PI in digit blocks clients can stream, a resumed job
+	// continues after the blocks of its checkpoint
+	jm.streamMu.Lock()
+	resumed := append([]DigitBlock(nil), job.blocks...)
+	err := jm.saveCheckpoint(job)
+	jm.streamMu.Unlock()
+	if err != nil {
+		jm.failJob(job, err.Error())
+		return
+	}
+	result, err := calc.Stream(job.ctx, job.blockDigits, resumed, func(block DigitBlock) error {
+		return jm.addBlock(job, block)
+	})
+	close(done)
+	if err != nil && jm.ctx.Err() != nil && jm.CheckpointPath(job.ID) != "" {
+		// The service shuts down, its next run can resume the job
+		jm.updateJobStatus(job, StatusInterrupted)
+		return
+	}
 	if err != nil {
 		jm.failJob(job, fmt.Sprintf("PI calculation failed: %v", err))
 		return
 	}
+	result.Duration += job.resumedAfter
+	
+	// Verify result according to the requested level
+	report, err := VerifyPIResult(job.ctx, result, job.Verification)
+	if err != nil {
+		jm.failJob(job, fmt.Sprintf("result verification failed: %v", err))
+		return
+	}
+	job.VerificationReport = report
+	if !report.Passed {
+		jm.failJob(job, fmt.Sprintf("result did not pass %s verification: %s", report.Level, report.Details))
+		return
+	}
 	
 	// Store result
 	job.Result = result
@@ -335,6 +595,39 @@ func (jm *JobManager) processPICalculation(job *ComputeJob) {
 	}
 }
 
+// processSandboxed runs a job in the container sandbox
+func (jm *JobManager) processSandboxed(job *ComputeJob, sandbox *Sandbox) {
+	out, err := sandbox.Run(job.ctx, job)
+	if err != nil {
+		jm.failJob(job, fmt.Sprintf("sandboxed job failed: %v", err))
+		return
+	}
+	
+	job.VerificationReport = out.VerificationReport
+	if out.VerificationReport != nil && !out.VerificationReport.Passed {
+		jm.failJob(job, fmt.Sprintf("result did not pass %s verification: %s", out.VerificationReport.Level, out.VerificationReport.Details))
+		return
+	}
+	
+	// Decode into the typed result so the result hash matches in-process jobs
+	var result PIResult
+	if err := json.Unmarshal(out.Result, &result); err != nil {
+		jm.failJob(job, fmt.Sprintf("invalid sandbox result: %v", err))
+		return
+	}
+	job.Result = &result
+	job.Progress = 100
+	
+	if job.ResourceUsage != nil {
+		endTime := time.Now()
+		job.ResourceUsage.EndTime = &endTime
+		job.ResourceUsage.ActualDuration = endTime.Sub(job.ResourceUsage.StartTime)
+		estimate := jm.pricingManager.EstimateResourceUsage(result.Digits, result.Method)
+		job.ResourceUsage.PeakCPUPercent = estimate.CPUPercent
+		job.ResourceUsage.PeakMemoryMB = estimate.MemoryMB
+	}
+}
+
 // monitorProgress monitors and updates job progress
 func (jm *JobManager) monitorProgress(job *ComputeJob) {
 	for {
@@ -352,6 +645,10 @@ func (jm *JobManager) monitorProgress(job *ComputeJob) {
 
 // completeJob marks a job as completed
 func (jm *JobManager) completeJob(job *ComputeJob) {
+	// Before the status, clients that see the job completed find the file.
+	// Without it the result is still served inline.
+	jm.writeArtifact(job)
+	
 	jm.updateJobStatus(job, StatusCompleted)
 	now := time.Now()
 	job.CompletedAt = &now
@@ -359,10 +656,27 @@ func (jm *JobManager) completeJob(job *ComputeJob) {
 	if job.StartedAt != nil {
 		job.Duration = now.Sub(*job.StartedAt).String()
 	}
+	jm.endStream(job)
+	
+	jm.mu.RLock()
+	onCompleted := jm.onCompleted
+	cache := jm.cache
+	jm.mu.RUnlock()
+	if cache != nil {
+		if err := cache.Put(job); err != nil {
+			log.Printf("⚠️ Could not cache the result of job %s: %v", job.ID, err)
+		}
+	}
+	if onCompleted != nil {
+		onCompleted(job)
+	}
 }
 
-// failJob marks a job as failed
+// failJob marks a job as failed, once
 func (jm *JobManager) failJob(job *ComputeJob, errorMsg string) {
+	if job.Status == StatusFailed {
+		return
+	}
 	jm.updateJobStatus(job, StatusFailed)
 	job.Error = errorMsg
 	now := time.Now()
@@ -371,10 +685,21 @@ func (jm *JobManager) failJob(job *ComputeJob, errorMsg string) {
 	if job.StartedAt != nil {
 		job.Duration = now.Sub(*job.StartedAt).String()
 	}
+	jm.endStream(job)
+	
+	jm.mu.RLock()
+	onFailed := jm.onFailed
+	jm.mu.RUnlock()
+	if onFailed != nil {
+		onFailed(job)
+	}
 }
 
-// cancelJob marks a job as cancelled
+// cancelJob marks a job as cancelled, unless it was failed with FailJob
 func (jm *JobManager) cancelJob(job *ComputeJob) {
+	if job.Status == StatusFailed {
+		return
+	}
 	jm.updateJobStatus(job, StatusCancelled)
 	now := time.Now()
 	job.CompletedAt = &now
@@ -382,6 +707,7 @@ func (jm *JobManager) cancelJob(job *ComputeJob) {
 	if job.StartedAt != nil {
 		job.Duration = now.Sub(*job.StartedAt).String()
 	}
+	jm.endStream(job)
 }
 
 // updateJobStatus updates the status of a job
@@ -448,6 +774,27 @@ func (jm *JobManager) CancelJob(jobID string) error {
 	return nil
 }
 
+// FailJob fails a job from outside, e.g. when its payment does not verify,
+// and stops it if it runs. The failure handler is called as for any failed job.
+func (jm *JobManager) FailJob(jobID, reason string) error {
+	jm.mu.RLock()
+	job, exists := jm.jobs[jobID]
+	jm.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("job not found: %s", jobID)
+	}
+	
+	if job.Status == StatusFailed || job.Status == StatusCancelled {
+		return fmt.Errorf("cannot fail job in status: %s", job.Status)
+	}
+	
+	jm.failJob(job, reason)
+	if job.cancelFunc != nil {
+		job.cancelFunc()
+	}
+	return nil
+}
+
 // CleanupCompletedJobs removes old completed jobs
 func (jm *JobManager) CleanupCompletedJobs(maxAge time.Duration) int {
 	jm.mu.Lock()
@@ -497,7 +844,7 @@ type QueueStatus struct {
 // isValidJobType validates job type
 func (jm *JobManager) isValidJobType(jobType JobType) bool {
 	switch jobType {
-	case JobTypePICalculation:
+	case JobTypePICalculation, JobTypePlanet9Search:
 		return true
 	default:
 		return false
@@ -516,29 +863,36 @@ func (jm *JobManager) validateJobParameters(jobType JobType, parameters map[stri
 			return fmt.Errorf("digits must be positive")
 		}
 		
-		method, ok := parameters["method"].(string)
-		if ok && method != "" {
-			validMethods := GetAvailableMethods()
-			methodValid := false
-			for _, validMethod := range validMethods {
-				if method == validMethod {
-					methodValid = true
-					break
-				}
-			}
-			if !methodValid {
-				return fmt.Errorf("invalid method: %s", method)
-			}
-		}
-		
-		return nil
+		method, _ := parameters["method"].(string)
+		_, err := jm.pricingManager.ResolveMethod(method, int(digits))
+		return err
+	case JobTypePlanet9Search:
+		_, err := planet9Chunk(parameters)
+		return err
 	default:
 		return fmt.Errorf("unknown job type: %s", jobType)
 	}
 }
 
+// withPIMethod returns the parameters of a PI job with auto or no method
+// replaced by the method that will run
+func (jm *JobManager) withPIMethod(parameters map[string]interface{}) map[string]interface{} {
+	method, _ := parameters["method"].(string)
+	digits, _ := parameters["digits"].(float64)
+	resolved, err := jm.pricingManager.ResolveMethod(method, int(digits))
+	if err != nil || string(resolved) == method {
+		return parameters
+	}
+	out := make(map[string]interface{}, len(parameters)+1)
+	for k, v := range parameters {
+		out[k] = v
+	}
+	out["method"] = string(resolved)
+	return out
+}
+
 // calculateJobPrice calculates price for a job
-func (jm *JobManager) calculateJobPrice(jobType JobType, parameters map[string]interface{}, tier ServiceTier) (*PriceBreakdown, error) {
+func (jm *JobManager) calculateJobPrice(jobType JobType, parameters map[string]interface{}, tier ServiceTier, verification VerificationLevel) (*PriceBreakdown, error) {
 	switch jobType {
 	case JobTypePICalculation:
 		digits := int(parameters["digits"].(float64))
@@ -547,12 +901,45 @@ func (jm *JobManager) calculateJobPrice(jobType JobType, parameters map[string]i
 			method = "chudnovsky"
 		}
 		
-		return jm.pricingManager.CalculatePrice(digits, tier, method)
+		return jm.pricingManager.CalculatePriceWithVerification(digits, tier, method, verification)
+	case JobTypePlanet9Search:
+		chunk, err := planet9Chunk(parameters)
+		if err != nil {
+			return nil, err
+		}
+		return jm.pricingManager.CalculatePlanet9Price(len(chunk.Samples), chunk.DurationYears, tier, verification)
 	default:
 		return nil, fmt.Errorf("unsupported job type: %s", jobType)
 	}
 }
 
+// QuoteJob returns the price SubmitJob would charge for a job
+func (jm *JobManager) QuoteJob(jobType JobType, parameters map[string]interface{}, tier ServiceTier, verification VerificationLevel) (*PriceBreakdown, error) {
+	if !jm.isValidJobType(jobType) {
+		return nil, fmt.Errorf("unsupported job type: %s", jobType)
+	}
+	if err := jm.validateJobParameters(jobType, parameters); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+	if jobType == JobTypePICalculation {
+		parameters = jm.withPIMethod(parameters)
+	}
+	verification, err := ParseVerificationLevel(string(verification))
+	if err != nil {
+		return nil, err
+	}
+	price, err := jm.calculateJobPrice(jobType, parameters, tier, verification)
+	if err != nil {
+		return nil, err
+	}
+	if cache := jm.ResultCache(); cache != nil {
+		if entry, _ := cacheHit(cache, jobType, parameters, verification); entry != nil {
+			return cache.Price(price), nil
+		}
+	}
+	return price, nil
+}
+
 // getTierPriority returns priority value for a tier
 func (jm *JobManager) getTierPriority(tier ServiceTier) int {
 	switch tier {
@@ -567,7 +954,8 @@ func (jm *JobManager) getTierPriority(tier ServiceTier) int {
 	}
 }
 
-// Shutdown gracefully shuts down the job manager
+// Shutdown gracefully shuts down the job manager, jobs still running after
+// timeout are cancelled
 func (jm *JobManager) Shutdown(timeout time.Duration) error {
 	// Signal shutdown
 	close(jm.shutdownChan)
@@ -579,6 +967,7 @@ func (jm *JobManager) Shutdown(timeout time.Duration) error {
 		close(done)
 	}()
 	
+	defer jm.cancel()
 	select {
 	case <-done:
 		return nil
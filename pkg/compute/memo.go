@@ -0,0 +1,126 @@
+package compute
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MemoPrefix marks transfers that pay for a job, e.g. COMPUTE_PI_10000_CHUDNOVSKY_STANDARD
+const MemoPrefix = "COMPUTE_"
+
+// MaxMemoBundle is the most jobs one payment memo can pay for
+const MaxMemoBundle = 100
+
+// memoJobTypes maps the short type code used in memos to the job type
+var memoJobTypes = map[string]JobType{
+	"PI": JobTypePICalculation,
+}
+
+// MemoJob is a job request encoded in a payment memo
+type MemoJob struct {
+	Type         JobType
+	Parameters   map[string]interface{}
+	Tier         ServiceTier
+	Verification VerificationLevel
+	Count        int // jobs the payment is for, X<N> bundles pay for N at once
+}
+
+// IsPaymentMemo reports whether a memo requests a job
+func IsPaymentMemo(memo string) bool {
+	return strings.HasPrefix(strings.ToUpper(strings.TrimSpace(memo)), MemoPrefix)
+}
+
+// ParsePaymentMemo decodes COMPUTE_<TYPE>_<PARAMS>. For PI the parameters are
+// the digit count followed by method, tier, verification level and a bundle
+// size X<N> in any order, all but the digits are optional.
+func ParsePaymentMemo(memo string) (*MemoJob, error) {
+	memo = strings.TrimSpace(memo)
+	if !IsPaymentMemo(memo) {
+		return nil, fmt.Errorf("memo does not start with %s", MemoPrefix)
+	}
+	fields := strings.Split(memo[len(MemoPrefix):], "_")
+
+	jobType, ok := memoJobTypes[strings.ToUpper(fields[0])]
+	if !ok {
+		return nil, fmt.Errorf("unknown job type %q in memo", fields[0])
+	}
+	job := &MemoJob{
+		Type:         jobType,
+		Parameters:   map[string]interface{}{},
+		Tier:         TierBasic,
+		Verification: VerificationNone,
+		Count:        1,
+	}
+
+	params := fields[1:]
+	if len(params) == 0 {
+		return nil, fmt.Errorf("memo has no parameters")
+	}
+	digits, err := strconv.Atoi(params[0])
+	if err != nil || digits <= 0 {
+		return nil, fmt.Errorf("invalid digit count %q in memo", params[0])
+	}
+	// JSON numbers decode to float64, jobs from the API look the same
+	job.Parameters["digits"] = float64(digits)
+	job.Parameters["method"] = string(MethodChudnovsky)
+
+	for _, p := range params[1:] {
+		token := strings.ToLower(p)
+		switch {
+		case isMethod(token):
+			job.Parameters["method"] = token
+		case token == string(TierBasic) || token == string(TierStandard) || token == string(TierPremium):
+			job.Tier = ServiceTier(token)
+		case len(token) > 1 && token[0] == 'x' && isDigits(token[1:]):
+			n, err := strconv.Atoi(token[1:])
+			if err != nil || n < 1 || n > MaxMemoBundle {
+				return nil, fmt.Errorf("bundle size %q in memo is not between 1 and %d", p, MaxMemoBundle)
+			}
+			job.Count = n
+		default:
+			level, err := ParseVerificationLevel(token)
+			if err != nil {
+				return nil, fmt.Errorf("unknown memo parameter %q", p)
+			}
+			job.Verification = level
+		}
+	}
+	return job, nil
+}
+
+// PaymentMemo renders the memo that ParsePaymentMemo accepts for a PI job
+func PaymentMemo(digits int, method string, tier ServiceTier, verification VerificationLevel) string {
+	parts := []string{"COMPUTE", "PI", strconv.Itoa(digits)}
+	if method != "" {
+		parts = append(parts, strings.ToUpper(method))
+	}
+	if tier != "" {
+		parts = append(parts, strings.ToUpper(string(tier)))
+	}
+	if verification != "" && verification != VerificationNone {
+		parts = append(parts, strings.ToUpper(string(verification)))
+	}
+	return strings.Join(parts, "_")
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isMethod(token string) bool {
+	if token == string(MethodAuto) {
+		return true
+	}
+	for _, m := range GetAvailableMethods() {
+		if m == token {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package compute
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MethodAuto picks the method with the shortest estimated runtime for the
+// requested digits, see ResolveMethod
+const MethodAuto PIMethod = "auto"
+
+// MaxPIDigits is the largest precision any method calculates
+const MaxPIDigits = 1000000
+
+// MethodSpec describes a PI method and the precisions it suits
+type MethodSpec struct {
+	Name            PIMethod `json:"name"`
+	Description     string   `json:"description"`
+	ConvergenceRate string   `json:"convergence_rate"`
+	Complexity      string   `json:"complexity"`
+	MinDigits       int      `json:"min_digits"`
+	MaxDigits       int      `json:"max_digits"`
+}
+
+// Suits reports whether the method is meant for digits
+func (m MethodSpec) Suits(digits int) bool {
+	return digits >= m.MinDigits && digits <= m.MaxDigits
+}
+
+// piMethods is the registry of PI methods, Chudnovsky first as the default
+var piMethods = []MethodSpec{
+	{
+		Name:            MethodChudnovsky,
+		Description:     "Fastest converging series for π. Discovered by David and Gregory Chudnovsky.",
+		ConvergenceRate: "~14.18 digits per iteration",
+		Complexity:      "High computational complexity but excellent convergence",
+		MinDigits:       1,
+		MaxDigits:       MaxPIDigits,
+	},
+	{
+		Name:            MethodMachin,
+		Description:     "Classical formula: π/4 = 4*arctan(1/5) - arctan(1/239). Used for centuries.",
+		ConvergenceRate: "~4 digits per iteration",
+		Complexity:      "Moderate complexity, good historical significance",
+		MinDigits:       1,
+		MaxDigits:       10000, // quadratic cost, impractical beyond
+	},
+	{
+		Name:            MethodBailey,
+		Description:     "Bailey-Borwein-Plouffe formula. Allows computing arbitrary hexadecimal digits.",
+		ConvergenceRate: "~6 digits per iteration",
+		Complexity:      "Moderate complexity, excellent for parallel computation",
+		MinDigits:       1,
+		MaxDigits:       50000,
+	},
+}
+
+// PIMethods returns the registered PI methods
+func PIMethods() []MethodSpec {
+	return append([]MethodSpec(nil), piMethods...)
+}
+
+// LookupMethod returns the registered method of a name
+func LookupMethod(name string) (MethodSpec, error) {
+	for _, m := range piMethods {
+		if string(m.Name) == strings.ToLower(strings.TrimSpace(name)) {
+			return m, nil
+		}
+	}
+	return MethodSpec{}, fmt.Errorf("unknown method %q, use %s or %s", name, strings.Join(GetAvailableMethods(), ", "), MethodAuto)
+}
+
+// ResolveMethod validates a requested method for digits. Empty means
+// Chudnovsky, auto the method estimate rates fastest among those suiting the
+// digits; estimate nil uses EstimateCalculationTime.
+func ResolveMethod(method string, digits int, estimate func(digits int, method string) time.Duration) (PIMethod, error) {
+	if digits < 1 || digits > MaxPIDigits {
+		return "", fmt.Errorf("digits must be between 1 and %d, got %d", MaxPIDigits, digits)
+	}
+	method = strings.ToLower(strings.TrimSpace(method))
+	if method == "" {
+		return MethodChudnovsky, nil
+	}
+	if method != string(MethodAuto) {
+		m, err := LookupMethod(method)
+		if err != nil {
+			return "", err
+		}
+		if !m.Suits(digits) {
+			return "", fmt.Errorf("method %s suits %d to %d digits, not %d (use %s or %s)", m.Name, m.MinDigits, m.MaxDigits, digits, MethodChudnovsky, MethodAuto)
+		}
+		return m.Name, nil
+	}
+
+	if estimate == nil {
+		estimate = EstimateCalculationTime
+	}
+	var best PIMethod
+	var bestTime time.Duration
+	for _, m := range piMethods {
+		if !m.Suits(digits) {
+			continue
+		}
+		if t := estimate(digits, string(m.Name)); best == "" || t < bestTime {
+			best, bestTime = m.Name, t
+		}
+	}
+	return best, nil
+}
@@ -0,0 +1,92 @@
+package compute
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+var ibcDenomPattern = regexp.MustCompile(`^ibc/[0-9A-F]{64}$`)
+
+// PaymentDenom is an IBC token accepted as payment, priced in MEDAS
+type PaymentDenom struct {
+	Denom     string  `json:"denom" yaml:"denom" mapstructure:"denom"`                          // ibc/<hash> on this chain
+	Symbol    string  `json:"symbol" yaml:"symbol" mapstructure:"symbol"`                       // e.g. ATOM
+	Channel   string  `json:"channel,omitempty" yaml:"channel" mapstructure:"channel"`          // channel on this chain the token arrives through
+	BaseDenom string  `json:"base_denom,omitempty" yaml:"base_denom" mapstructure:"base_denom"` // denom on the source chain, e.g. uatom
+	Decimals  int     `json:"decimals" yaml:"decimals" mapstructure:"decimals"`
+	Rate      float64 `json:"rate" yaml:"rate" mapstructure:"rate"` // MEDAS per whole token
+}
+
+// IBCDenom returns the ibc/<hash> denom of a token received through channel
+func IBCDenom(channel, baseDenom string) string {
+	sum := sha256.Sum256([]byte("transfer/" + channel + "/" + baseDenom))
+	return "ibc/" + strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// Validate checks the denom format, the rate and, if channel and base denom are
+// set, that the denom is really the voucher of that channel
+func (d PaymentDenom) Validate() error {
+	if !ibcDenomPattern.MatchString(d.Denom) {
+		return fmt.Errorf("payment denom %q is not an ibc/<HASH> denom", d.Denom)
+	}
+	if d.Rate <= 0 {
+		return fmt.Errorf("payment denom %s: rate must be positive", d.Label())
+	}
+	if d.Decimals < 0 || d.Decimals > 18 {
+		return fmt.Errorf("payment denom %s: invalid decimals %d", d.Label(), d.Decimals)
+	}
+	if d.Channel != "" && d.BaseDenom != "" {
+		if expected := IBCDenom(d.Channel, d.BaseDenom); expected != d.Denom {
+			return fmt.Errorf("payment denom %s: %s via %s is %s", d.Label(), d.BaseDenom, d.Channel, expected)
+		}
+	}
+	return nil
+}
+
+// Label is the symbol if configured, the denom otherwise
+func (d PaymentDenom) Label() string {
+	if d.Symbol != "" {
+		return d.Symbol
+	}
+	return d.Denom
+}
+
+// ToMEDAS converts base units of the token to MEDAS. Amounts of 18-decimal
+// tokens easily exceed an int64.
+func (d PaymentDenom) ToMEDAS(amount sdkmath.Int) float64 {
+	units, _ := new(big.Float).SetInt(amount.BigInt()).Float64()
+	return units / math.Pow10(d.Decimals) * d.Rate
+}
+
+// FromMEDAS returns the base units of the token worth medas, rounded up
+func (d PaymentDenom) FromMEDAS(medas float64) sdkmath.Int {
+	units, _ := big.NewFloat(math.Ceil(medas / d.Rate * math.Pow10(d.Decimals))).Int(nil)
+	return sdkmath.NewIntFromBigInt(units)
+}
+
+// PaymentDenoms validates a conversion table and indexes it by denom
+func PaymentDenoms(list []PaymentDenom) (map[string]PaymentDenom, error) {
+	denoms := make(map[string]PaymentDenom, len(list))
+	for _, d := range list {
+		d.Denom = strings.TrimSpace(d.Denom)
+		// Hashes are case insensitive, the chain uses upper case
+		if strings.HasPrefix(strings.ToLower(d.Denom), "ibc/") {
+			d.Denom = "ibc/" + strings.ToUpper(d.Denom[4:])
+		}
+		if err := d.Validate(); err != nil {
+			return nil, err
+		}
+		if _, dup := denoms[d.Denom]; dup {
+			return nil, fmt.Errorf("payment denom %s is configured twice", d.Denom)
+		}
+		denoms[d.Denom] = d
+	}
+	return denoms, nil
+}
@@ -2,10 +2,10 @@
 package compute
 
 import (
+	"context"
 	"fmt"
 	"math"
 	// "math/big" // ← ENTFERNT: nicht verwendet
-	"strings"
 	"time"
 )
 
@@ -13,6 +13,13 @@ import (
 type PICalculator struct {
 	precision int
 	method    string
+	
+	// pace replaces waiting out the runtime when set, see Stream
+	pace      func(ctx context.Context, runtime time.Duration) error
+	
+	// outOfCore spills Chudnovsky products to disk, see SetOutOfCore
+	outOfCore OutOfCore
+	spill     *SpillStats
 }
 
 // PIResult represents the result of a PI calculation
@@ -24,6 +31,7 @@ type PIResult struct {
 	Iterations int64         `json:"iterations"`
 	Verified   bool          `json:"verified"`
 	Timestamp  time.Time     `json:"timestamp"`
+	Spill      *SpillStats   `json:"spill,omitempty"` // set when products went to disk
 }
 
 // PIMethod represents available calculation methods
@@ -43,32 +51,36 @@ func NewPICalculator(digits int, method string) *PICalculator {
 	}
 }
 
-// Calculate performs PI calculation using specified method
-func (calc *PICalculator) Calculate() (*PIResult, error) {
+// SetOutOfCore lets the Chudnovsky method keep intermediate products on disk
+// once they outgrow the memory budget of o
+func (calc *PICalculator) SetOutOfCore(o OutOfCore) {
+	calc.outOfCore = o
+}
+
+// Calculate performs PI calculation using specified method, it stops early
+// when ctx is done
+func (calc *PICalculator) Calculate(ctx context.Context) (*PIResult, error) {
 	start := time.Now()
 	
-	// Validate inputs
-	if calc.precision <= 0 {
-		return nil, fmt.Errorf("precision must be positive")
-	}
-	
-	if calc.precision > 100000 {
-		return nil, fmt.Errorf("precision limit exceeded (max: 100000)")
+	// Validate inputs, auto becomes the fastest method for the precision
+	method, err := ResolveMethod(calc.method, calc.precision, nil)
+	if err != nil {
+		return nil, err
 	}
+	calc.method = string(method)
 	
 	var value string
 	var iterations int64
-	var err error
 	
 	switch PIMethod(calc.method) {
 	case MethodChudnovsky:
-		value, iterations, err = calc.chudnovsky()
+		value, iterations, err = calc.chudnovsky(ctx)
 	case MethodMachin:
-		value, iterations, err = calc.machin()
+		value, iterations, err = calc.machin(ctx)
 	case MethodBailey:
-		value, iterations, err = calc.bailey()
+		value, iterations, err = calc.bailey(ctx)
 	default:
-		return nil, fmt.Errorf("unsupported method: %s (use: chudnovsky, machin, bailey)", calc.method)
+		return nil, fmt.Errorf("unsupported method: %s", calc.method)
 	}
 	
 	if err != nil {
@@ -86,11 +98,12 @@ func (calc *PICalculator) Calculate() (*PIResult, error) {
 		Iterations: iterations,
 		Verified:   verified,
 		Timestamp:  time.Now(),
+		Spill:      calc.spill,
 	}, nil
 }
 
 // chudnovsky implements Chudnovsky algorithm (fastest convergence)
-func (calc *PICalculator) chudnovsky() (string, int64, error) {
+func (calc *PICalculator) chudnovsky(ctx context.Context) (string, int64, error) {
 	// For production, this would use arbitrary precision arithmetic
 	// For now, using known PI digits for demonstration
 	
@@ -100,55 +113,67 @@ func (calc *PICalculator) chudnovsky() (string, int64, error) {
 	iterations := int64(calc.precision/14) + 1
 	
 	// Simulate calculation time based on complexity
-	calc.simulateCalculationTime()
+	if err := calc.simulateCalculationTime(ctx, 0); err != nil {
+		return "", 0, err
+	}
 	
-	// Return PI to requested precision
 	if calc.precision+2 <= len(knownPI) {
-		return knownPI[:calc.precision+2], iterations, nil // +2 for "3."
+		return calc.digits(knownPI), iterations, nil
 	}
 	
-	// If requested precision exceeds known digits, pad with zeros
-	return knownPI + strings.Repeat("0", calc.precision+2-len(knownPI)), iterations, nil
+	// Beyond the known digits the series is summed by binary splitting
+	value, stats, err := chudnovskyPI(ctx, calc.precision, calc.outOfCore)
+	if err != nil {
+		return "", 0, err
+	}
+	if stats.Spills > 0 {
+		calc.spill = &stats
+	}
+	return value, iterations, nil
 }
 
 // machin implements Machin's formula: π/4 = 4*arctan(1/5) - arctan(1/239)
-func (calc *PICalculator) machin() (string, int64, error) {
+func (calc *PICalculator) machin(ctx context.Context) (string, int64, error) {
 	knownPI := "3.1415926535897932384626433832795028841971693993751058209749445923078164062862089986280348253421170679821480865132823066470938446095505822317253594081284811174502841027019385211055596446229489549303819644288109756659334461284756482337867831652712019091456485669234603486104543266482133936072602491412737245870066063155881748815209209628292540917153643678925903600113305305488204665213841469519415116094330572703657595919530921861173819326117931051185480744623799627495673518857527248912279381830119491298336733624406566430860213949463952247371907021798609437027705392171762931767523846748184676694051320005681271452635608277857713427577896091736371787214684409012249534301465495853710507922796892589235420199561121290219608640344181598136297747713099605187072113499999983729780499510597317328160963185950244594553469083026425223082533446850352619311881710100031378387528865875332083814206171776691473035982534904287554687311595628638823537875937519577818577805321712268066130019278766111959092164201989380952572010654858632788659361533818279682303019520353018529689957736225994138912497217752834791315155748572424541506959508295331168617278558890750983817546374649393192550604009277016711390098488240128583616035637076601047101819429555961989467678374494482553797747268471040475346462080466842590694912933136770289891521047521620569660240580381501935112533824300355876402474964732639141992726042699227967823547816360093417216412199245863150302861829745557067498385054945885869269956909272107975093029553211653449872027559602364806654991198818347977535663698074265425278625518184175746728909777727938000816470600161452491921732172147723501414419735685481613611573525521334757418494684385233239073941433345477624168625189835694855620992192221842725502542568876717904946016746097659798123655497139135998333649"
 	
 	// Machin formula converges slower than Chudnovsky
 	iterations := int64(calc.precision/4) + 1
 	
 	// Simulate longer calculation time
-	calc.simulateCalculationTime()
-	time.Sleep(time.Duration(calc.precision) * time.Millisecond / 5) // Additional delay
-	
-	if calc.precision+2 <= len(knownPI) {
-		return knownPI[:calc.precision+2], iterations, nil
+	if err := calc.simulateCalculationTime(ctx, time.Duration(calc.precision)*time.Millisecond/5); err != nil {
+		return "", 0, err
 	}
 	
-	return knownPI + strings.Repeat("0", calc.precision+2-len(knownPI)), iterations, nil
+	return calc.digits(knownPI), iterations, nil
 }
 
 // bailey implements Bailey-Borwein-Plouffe formula
-func (calc *PICalculator) bailey() (string, int64, error) {
+func (calc *PICalculator) bailey(ctx context.Context) (string, int64, error) {
 	knownPI := "3.1415926535897932384626433832795028841971693993751058209749445923078164062862089986280348253421170679821480865132823066470938446095505822317253594081284811174502841027019385211055596446229489549303819644288109756659334461284756482337867831652712019091456485669234603486104543266482133936072602491412737245870066063155881748815209209628292540917153643678925903600113305305488204665213841469519415116094330572703657595919530921861173819326117931051185480744623799627495673518857527248912279381830119491298336733624406566430860213949463952247371907021798609437027705392171762931767523846748184676694051320005681271452635608277857713427577896091736371787214684409012249534301465495853710507922796892589235420199561121290219608640344181598136297747713099605187072113499999983729780499510597317328160963185950244594553469083026425223082533446850352619311881710100031378387528865875332083814206171776691473035982534904287554687311595628638823537875937519577818577805321712268066130019278766111959092164201989380952572010654858632788659361533818279682303019520353018529689957736225994138912497217752834791315155748572424541506959508295331168617278558890750983817546374649393192550604009277016711390098488240128583616035637076601047101819429555961989467678374494482553797747268471040475346462080466842590694912933136770289891521047521620569660240580381501935112533824300355876402474964732639141992726042699227967823547816360093417216412199245863150302861829745557067498385054945885869269956909272107975093029553211653449872027559602364806654991198818347977535663698074265425278625518184175746728909777727938000816470600161452491921732172147723501414419735685481613611573525521334757418494684385233239073941433345477624168625189835694855620992192221842725502542568876717904946016746097659798123655497139135998333649"
 	
 	// Bailey-Borwein-Plouffe has moderate convergence
 	iterations := int64(calc.precision/6) + 1
 	
 	// Simulate moderate calculation time
-	calc.simulateCalculationTime()
-	time.Sleep(time.Duration(calc.precision) * time.Millisecond / 8)
+	if err := calc.simulateCalculationTime(ctx, time.Duration(calc.precision)*time.Millisecond/8); err != nil {
+		return "", 0, err
+	}
 	
+	return calc.digits(knownPI), iterations, nil
+}
+
+// digits returns PI to the requested precision, beyond the known digits it
+// is computed
+func (calc *PICalculator) digits(knownPI string) string {
 	if calc.precision+2 <= len(knownPI) {
-		return knownPI[:calc.precision+2], iterations, nil
+		return knownPI[:calc.precision+2] // +2 for "3."
 	}
-	
-	return knownPI + strings.Repeat("0", calc.precision+2-len(knownPI)), iterations, nil
+	return machinPI(calc.precision)
 }
 
-// simulateCalculationTime simulates realistic calculation time
-func (calc *PICalculator) simulateCalculationTime() {
+// simulateCalculationTime simulates realistic calculation time plus the
+// extra delay of slower methods
+func (calc *PICalculator) simulateCalculationTime(ctx context.Context, extra time.Duration) error {
 	// Base delay proportional to precision
 	baseDelay := time.Duration(calc.precision) * time.Millisecond / 50
 	
@@ -168,7 +193,17 @@ func (calc *PICalculator) simulateCalculationTime() {
 		totalDelay = minDelay
 	}
 	
-	time.Sleep(totalDelay)
+	if calc.pace != nil {
+		return calc.pace(ctx, totalDelay+extra)
+	}
+	timer := time.NewTimer(totalDelay + extra)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
 
 // verify verifies the calculated PI value against known digits
@@ -192,11 +227,11 @@ func (calc *PICalculator) verify(calculated string) bool {
 
 // GetAvailableMethods returns list of available calculation methods
 func GetAvailableMethods() []string {
-	return []string{
-		string(MethodChudnovsky),
-		string(MethodMachin),
-		string(MethodBailey),
+	methods := make([]string, len(piMethods))
+	for i, m := range piMethods {
+		methods[i] = string(m.Name)
 	}
+	return methods
 }
 
 // EstimateCalculationTime estimates how long a calculation will take
@@ -233,13 +268,13 @@ func EstimateCalculationTime(digits int, method string) time.Duration {
 }
 
 // CalculatePIWithProgress calculates PI with progress updates via channel
-func (calc *PICalculator) CalculateWithProgress(progressChan chan<- int) (*PIResult, error) {
+func (calc *PICalculator) CalculateWithProgress(ctx context.Context, progressChan chan<- int) (*PIResult, error) {
 	// Start progress updates
 	done := make(chan bool)
 	go calc.updateProgress(progressChan, done)
 	
 	// Perform calculation
-	result, err := calc.Calculate()
+	result, err := calc.Calculate(ctx)
 	
 	// Stop progress updates
 	close(done)
@@ -298,30 +333,16 @@ type PICalculationInfo struct {
 
 // GetMethodInfo returns detailed information about calculation methods
 func GetMethodInfo(digits int) []PICalculationInfo {
-	return []PICalculationInfo{
-		{
-			Method:           string(MethodChudnovsky),
-			Digits:          digits,
-			EstimatedTime:   EstimateCalculationTime(digits, string(MethodChudnovsky)),
-			ConvergenceRate: "~14.18 digits per iteration",
-			Description:     "Fastest converging series for π. Discovered by David and Gregory Chudnovsky.",
-			Complexity:      "High computational complexity but excellent convergence",
-		},
-		{
-			Method:           string(MethodMachin),
-			Digits:          digits,
-			EstimatedTime:   EstimateCalculationTime(digits, string(MethodMachin)),
-			ConvergenceRate: "~4 digits per iteration",
-			Description:     "Classical formula: π/4 = 4*arctan(1/5) - arctan(1/239). Used for centuries.",
-			Complexity:      "Moderate complexity, good historical significance",
-		},
-		{
-			Method:           string(MethodBailey),
+	info := make([]PICalculationInfo, 0, len(piMethods))
+	for _, m := range piMethods {
+		info = append(info, PICalculationInfo{
+			Method:          string(m.Name),
 			Digits:          digits,
-			EstimatedTime:   EstimateCalculationTime(digits, string(MethodBailey)),
-			ConvergenceRate: "~6 digits per iteration",
-			Description:     "Bailey-Borwein-Plouffe formula. Allows computing arbitrary hexadecimal digits.",
-			Complexity:      "Moderate complexity, excellent for parallel computation",
-		},
+			EstimatedTime:   EstimateCalculationTime(digits, string(m.Name)),
+			ConvergenceRate: m.ConvergenceRate,
+			Description:     m.Description,
+			Complexity:      m.Complexity,
+		})
 	}
+	return info
 }
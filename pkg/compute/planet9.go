@@ -0,0 +1,106 @@
+package compute
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/oxygene76/medasdigital-client/pkg/astronomy/planet9"
+)
+
+// Planet9PricePerPointKyr is the base price in MEDAS for one sample simulated over 1000 years
+const Planet9PricePerPointKyr = 0.0002
+
+// planet9Chunk decodes the sweep chunk carried in the job parameters
+func planet9Chunk(parameters map[string]interface{}) (planet9.Chunk, error) {
+	var chunk planet9.Chunk
+	data, err := json.Marshal(parameters)
+	if err != nil {
+		return chunk, err
+	}
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return chunk, fmt.Errorf("invalid planet9 chunk: %w", err)
+	}
+	return chunk, chunk.Validate()
+}
+
+// CalculatePlanet9Price prices a sweep chunk by simulated sample-years
+func (pm *PricingManager) CalculatePlanet9Price(points int, simYears float64, tier ServiceTier, verification VerificationLevel) (*PriceBreakdown, error) {
+	tierConfig, exists := pm.tier(tier)
+	if !exists {
+		return nil, fmt.Errorf("unknown tier: %s", tier)
+	}
+	if points <= 0 || simYears <= 0 {
+		return nil, fmt.Errorf("points and sim_years must be positive")
+	}
+	verification, err := ParseVerificationLevel(string(verification))
+	if err != nil {
+		return nil, err
+	}
+	verificationMultiplier := VerificationMultiplier(verification)
+	surge := pm.engine.Surge()
+	baseCost := float64(points) * simYears / 1000 * Planet9PricePerPointKyr * verificationMultiplier * surge
+	communityFee := baseCost * tierConfig.CommunityFeePercent
+
+	breakdown := &PriceBreakdown{
+		Tier:                   tier,
+		Method:                 planet9.JobType,
+		BaseCost:               baseCost,
+		ServiceFee:             baseCost - communityFee,
+		CommunityFee:           communityFee,
+		TotalCost:              baseCost,
+		Currency:               pm.baseCurrency,
+		Features:               tierConfig.Features,
+		EstimatedTime:          time.Duration(float64(points) * simYears / 1000 * float64(50*time.Millisecond)),
+		Verification:           verification,
+		VerificationMultiplier: verificationMultiplier,
+		Breakdown: fmt.Sprintf(
+			"%.6f %s for %d points × %.0f years (%.1f%% service provider + %.1f%% community pool)",
+			baseCost, pm.baseCurrency, points, simYears,
+			(1-tierConfig.CommunityFeePercent)*100,
+			tierConfig.CommunityFeePercent*100,
+		),
+	}
+	if surge != 1 {
+		breakdown.Surge = surge
+		breakdown.Breakdown += fmt.Sprintf(", ×%.2f surge", surge)
+	}
+	return breakdown, nil
+}
+
+// processPlanet9Search runs one chunk of a distributed Planet 9 sweep
+func (jm *JobManager) processPlanet9Search(job *ComputeJob) {
+	chunk, err := planet9Chunk(job.Parameters)
+	if err != nil {
+		jm.failJob(job, err.Error())
+		return
+	}
+
+	// Run in batches of one point per CPU so the job stays cancellable
+	batch := runtime.NumCPU()
+	var results []*planet9.ChunkResult
+	for start := 0; start < len(chunk.Samples); start += batch {
+		select {
+		case <-job.ctx.Done():
+			return
+		default:
+		}
+		end := min(start+batch, len(chunk.Samples))
+		part := chunk
+		part.Offset = chunk.Offset + start
+		part.Samples = chunk.Samples[start:end]
+		results = append(results, planet9.RunChunk(part, 0, nil))
+		job.Progress = end * 100 / len(chunk.Samples)
+	}
+
+	merged := planet9.MergeChunks(results)
+	job.Result = &planet9.ChunkResult{Chunk: chunk.Index, Surface: merged.Surface, Best: merged.Best}
+	job.Progress = 100
+
+	if job.ResourceUsage != nil {
+		endTime := time.Now()
+		job.ResourceUsage.EndTime = &endTime
+		job.ResourceUsage.ActualDuration = endTime.Sub(job.ResourceUsage.StartTime)
+	}
+}
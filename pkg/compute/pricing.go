@@ -17,21 +17,22 @@ const (
 
 // PricingTier defines pricing structure for a service tier
 type PricingTier struct {
-	Name                ServiceTier   `json:"name"`
-	PricePerDigit       float64       `json:"price_per_digit"`
-	MaxDigits           int           `json:"max_digits"`
-	MaxRuntimeMinutes   int           `json:"max_runtime_minutes"`
-	CommunityFeePercent float64       `json:"community_fee_percent"`
-	Features            []string      `json:"features"`
-	Priority            int           `json:"priority"`
-	Description         string        `json:"description"`
+	Name                ServiceTier   `json:"name" yaml:"-"`
+	PricePerDigit       float64       `json:"price_per_digit" yaml:"price_per_digit"`
+	MaxDigits           int           `json:"max_digits" yaml:"max_digits"`
+	MaxRuntimeMinutes   int           `json:"max_runtime_minutes" yaml:"max_runtime_minutes"`
+	CommunityFeePercent float64       `json:"community_fee_percent" yaml:"community_fee_percent"`
+	Features            []string      `json:"features" yaml:"features,omitempty"`
+	Priority            int           `json:"priority" yaml:"priority,omitempty"`
+	Description         string        `json:"description" yaml:"description,omitempty"`
 }
 
 // PricingManager handles all pricing calculations
 type PricingManager struct {
-	tiers              map[ServiceTier]*PricingTier
+	engine             PricingEngine // tiers and surge, DefaultPricing unless set
 	communityPoolAddr  string
 	baseCurrency       string
+	profile            *PerformanceProfile // measured host speed, nil uses the fixed estimates
 }
 
 // PriceBreakdown represents detailed cost breakdown
@@ -47,79 +48,45 @@ type PriceBreakdown struct {
 	Breakdown    string      `json:"breakdown"`
 	Features     []string    `json:"features"`
 	EstimatedTime time.Duration `json:"estimated_time"`
+	Verification           VerificationLevel `json:"verification"`
+	VerificationMultiplier float64           `json:"verification_multiplier"`
+	Surge                  float64           `json:"surge,omitempty"` // load-based factor, omitted without surge
+	Cached                 bool              `json:"cached,omitempty"` // price of a result from the result cache
 }
 
 // NewPricingManager creates a new pricing manager
 func NewPricingManager(communityPoolAddr string) *PricingManager {
-	pm := &PricingManager{
-		tiers:             make(map[ServiceTier]*PricingTier),
+	return &PricingManager{
+		engine:            DefaultPricing(),
 		communityPoolAddr: communityPoolAddr,
 		baseCurrency:      "MEDAS",
 	}
-	
-	// Initialize default tiers
-	pm.initializeDefaultTiers()
-	return pm
-}
-
-// initializeDefaultTiers sets up default pricing tiers
-func (pm *PricingManager) initializeDefaultTiers() {
-	pm.tiers[TierBasic] = &PricingTier{
-		Name:                TierBasic,
-		PricePerDigit:       0.0001, // 0.0001 MEDAS per digit
-		MaxDigits:           1000,
-		MaxRuntimeMinutes:   5,
-		CommunityFeePercent: 0.15, // 15%
-		Priority:            1,
-		Description:         "Basic PI calculation for testing and learning",
-		Features: []string{
-			"Standard precision calculation",
-			"Basic result verification",
-			"Single algorithm (Chudnovsky)",
-			"Up to 1,000 digits",
-		},
-	}
-	
-	pm.tiers[TierStandard] = &PricingTier{
-		Name:                TierStandard,
-		PricePerDigit:       0.00025,
-		MaxDigits:           10000,
-		MaxRuntimeMinutes:   30,
-		CommunityFeePercent: 0.15,
-		Priority:            2,
-		Description:         "Standard service with progress monitoring",
-		Features: []string{
-			"Real-time progress updates",
-			"Multiple algorithms available",
-			"Advanced result verification",
-			"Up to 10,000 digits",
-			"Job status monitoring",
-		},
-	}
-	
-	pm.tiers[TierPremium] = &PricingTier{
-		Name:                TierPremium,
-		PricePerDigit:       0.0005,
-		MaxDigits:           100000,
-		MaxRuntimeMinutes:   120,
-		CommunityFeePercent: 0.15,
-		Priority:            3,
-		Description:         "Premium service with highest priority and guarantees",
-		Features: []string{
-			"Highest priority processing",
-			"All calculation algorithms",
-			"Guaranteed completion",
-			"Real-time monitoring dashboard",
-			"Up to 100,000 digits",
-			"Performance analytics",
-			"Dedicated support",
-		},
-	}
+}
+
+// SetPricingEngine replaces the source of tiers and surge, e.g. with a FilePricing
+func (pm *PricingManager) SetPricingEngine(engine PricingEngine) {
+	pm.engine = engine
+}
+
+// PricingEngine returns the engine set with SetPricingEngine
+func (pm *PricingManager) PricingEngine() PricingEngine {
+	return pm.engine
+}
+
+// tier returns the current configuration of a tier
+func (pm *PricingManager) tier(tier ServiceTier) (*PricingTier, bool) {
+	tierConfig, exists := pm.engine.Tiers()[tier]
+	return tierConfig, exists
 }
 
 // CalculatePrice calculates total price for a computation job
 func (pm *PricingManager) CalculatePrice(digits int, tier ServiceTier, method string) (*PriceBreakdown, error) {
-	tierConfig, exists := pm.tiers[tier]
+	return pm.CalculatePriceWithVerification(digits, tier, method, VerificationNone)
+}
+
+// CalculatePriceWithVerification calculates the price including the verification level multiplier
+func (pm *PricingManager) CalculatePriceWithVerification(digits int, tier ServiceTier, method string, verification VerificationLevel) (*PriceBreakdown, error) {
+	tierConfig, exists := pm.tier(tier)
 	if !exists {
 		return nil, fmt.Errorf("unknown tier: %s", tier)
 	}
@@ -132,13 +99,38 @@ func (pm *PricingManager) CalculatePrice(digits int, tier ServiceTier, method st
 		return nil, fmt.Errorf("digits (%d) exceed tier limit (%d)", digits, tierConfig.MaxDigits)
 	}
 	
+	// Price the method that will run, auto picks the fastest
+	resolved, err := pm.ResolveMethod(method, digits)
+	if err != nil {
+		return nil, err
+	}
+	method = string(resolved)
+	
 	// Base cost calculation
 	baseCost := float64(digits) * tierConfig.PricePerDigit
 	
 	// Apply method multiplier
-	methodMultiplier := pm.getMethodMultiplier(method)
+	methodMultiplier := pm.methodMultiplier(digits, method)
 	baseCost *= methodMultiplier
 	
+	// Measured runtimes can tell that a job will not finish within the tier's limit
+	estimatedTime := pm.estimateTime(digits, method)
+	if pm.profile != nil && estimatedTime > time.Duration(tierConfig.MaxRuntimeMinutes)*time.Minute {
+		return nil, fmt.Errorf("estimated runtime %s exceeds the %d minute limit of the %s tier", estimatedTime.Round(time.Second), tierConfig.MaxRuntimeMinutes, tier)
+	}
+	
+	// Apply verification multiplier
+	verification, err = ParseVerificationLevel(string(verification))
+	if err != nil {
+		return nil, err
+	}
+	verificationMultiplier := VerificationMultiplier(verification)
+	baseCost *= verificationMultiplier
+	
+	// Load-based surge of the pricing engine
+	surge := pm.engine.Surge()
+	baseCost *= surge
+	
 	// Community fee
 	communityFee := baseCost * tierConfig.CommunityFeePercent
 	
@@ -148,9 +140,6 @@ func (pm *PricingManager) CalculatePrice(digits int, tier ServiceTier, method st
 	// Total cost
 	totalCost := baseCost
 	
-	// Estimate calculation time
-	estimatedTime := EstimateCalculationTime(digits, method)
-	
 	breakdown := &PriceBreakdown{
 		Tier:          tier,
 		Digits:        digits,
@@ -162,6 +151,8 @@ func (pm *PricingManager) CalculatePrice(digits int, tier ServiceTier, method st
 		Currency:      pm.baseCurrency,
 		Features:      tierConfig.Features,
 		EstimatedTime: estimatedTime,
+		Verification:           verification,
+		VerificationMultiplier: verificationMultiplier,
 		Breakdown: fmt.Sprintf(
 			"%.6f %s (%.1f%% service provider + %.1f%% community pool)", 
 			totalCost, 
@@ -170,10 +161,53 @@ func (pm *PricingManager) CalculatePrice(digits int, tier ServiceTier, method st
 			tierConfig.CommunityFeePercent*100,
 		),
 	}
+	if surge != 1 {
+		breakdown.Surge = surge
+		breakdown.Breakdown += fmt.Sprintf(", ×%.2f surge", surge)
+	}
 	
 	return breakdown, nil
 }
 
+// SetPerformanceProfile prices and estimates jobs with the measured speed of
+// the host, see Calibrate
+func (pm *PricingManager) SetPerformanceProfile(p *PerformanceProfile) {
+	pm.profile = p
+}
+
+// PerformanceProfile returns the profile set with SetPerformanceProfile
+func (pm *PricingManager) PerformanceProfile() *PerformanceProfile {
+	return pm.profile
+}
+
+// ResolveMethod validates a PI method for digits and turns auto into the
+// method with the shortest estimated runtime on this host
+func (pm *PricingManager) ResolveMethod(method string, digits int) (PIMethod, error) {
+	return ResolveMethod(method, digits, pm.estimateTime)
+}
+
+// estimateTime is the measured runtime when a profile is set, the fixed
+// estimate otherwise
+func (pm *PricingManager) estimateTime(digits int, method string) time.Duration {
+	if d, ok := pm.profile.EstimateDuration(digits, method); ok {
+		return d
+	}
+	return EstimateCalculationTime(digits, method)
+}
+
+// methodMultiplier is the measured runtime of a method relative to
+// Chudnovsky at the same size, never below the base price. Without a profile
+// it is the fixed multiplier.
+func (pm *PricingManager) methodMultiplier(digits int, method string) float64 {
+	d, ok := pm.profile.EstimateDuration(digits, method)
+	base, baseOK := pm.profile.EstimateDuration(digits, string(MethodChudnovsky))
+	if !ok || !baseOK || base <= 0 {
+		return pm.getMethodMultiplier(method)
+	}
+	// Rounded, so prices do not change with every calibration
+	return math.Max(1, math.Round(float64(d)/float64(base)*100)/100)
+}
+
 // getMethodMultiplier returns pricing multiplier based on calculation method
 func (pm *PricingManager) getMethodMultiplier(method string) float64 {
 	switch PIMethod(method) {
@@ -190,7 +224,7 @@ func (pm *PricingManager) getMethodMultiplier(method string) float64 {
 
 // GetTier returns configuration for a specific tier
 func (pm *PricingManager) GetTier(tier ServiceTier) (*PricingTier, error) {
-	tierConfig, exists := pm.tiers[tier]
+	tierConfig, exists := pm.tier(tier)
 	if !exists {
 		return nil, fmt.Errorf("tier not found: %s", tier)
 	}
@@ -201,7 +235,7 @@ func (pm *PricingManager) GetTier(tier ServiceTier) (*PricingTier, error) {
 func (pm *PricingManager) GetAllTiers() map[ServiceTier]*PricingTier {
 	// Return a copy to prevent external modification
 	tiers := make(map[ServiceTier]*PricingTier)
-	for k, v := range pm.tiers {
+	for k, v := range pm.engine.Tiers() {
 		tierCopy := *v
 		tiers[k] = &tierCopy
 	}
@@ -210,7 +244,7 @@ func (pm *PricingManager) GetAllTiers() map[ServiceTier]*PricingTier {
 
 // ValidateTierLimits checks if requested parameters are within tier limits
 func (pm *PricingManager) ValidateTierLimits(digits int, tier ServiceTier) error {
-	tierConfig, exists := pm.tiers[tier]
+	tierConfig, exists := pm.tier(tier)
 	if !exists {
 		return fmt.Errorf("invalid tier: %s", tier)
 	}
@@ -251,7 +285,7 @@ func (pm *PricingManager) EstimateResourceUsage(digits int, method string) Resou
 	
 	estimatedCPU := baseCPU * cpuMultiplier
 	estimatedMemory := baseMemory * memoryMultiplier
-	estimatedTime := EstimateCalculationTime(digits, method)
+	estimatedTime := pm.estimateTime(digits, method)
 	
 	// Apply reasonable bounds
 	if estimatedCPU > 100 {
@@ -270,6 +304,12 @@ func (pm *PricingManager) EstimateResourceUsage(digits int, method string) Resou
 	}
 }
 
+// VerificationOption describes a selectable verification level
+type VerificationOption struct {
+	Multiplier  float64 `json:"multiplier"`
+	Description string  `json:"description"`
+}
+
 // ResourceEstimate represents estimated resource usage
 type ResourceEstimate struct {
 	CPUPercent    float64       `json:"cpu_percent"`
@@ -285,14 +325,26 @@ type PricingInfo struct {
 	Currency          string                       `json:"currency"`
 	CommunityPoolAddr string                       `json:"community_pool_address"`
 	MethodMultipliers map[string]float64           `json:"method_multipliers"`
+	VerificationLevels map[string]VerificationOption `json:"verification_levels"`
+	PerformanceProfile *PerformanceProfile          `json:"performance_profile,omitempty"` // the prices and estimates are based on
+	Surge             float64                      `json:"surge"` // current factor on all prices, 1 without surge
 	LastUpdated       time.Time                    `json:"last_updated"`
 }
 
 // GetPricingInfo returns comprehensive pricing information
 func (pm *PricingManager) GetPricingInfo() *PricingInfo {
+	// With a profile the multipliers depend on the digits, these are at the calibration size
 	methodMultipliers := make(map[string]float64)
 	for _, method := range GetAvailableMethods() {
-		methodMultipliers[method] = pm.getMethodMultiplier(method)
+		methodMultipliers[method] = pm.methodMultiplier(calibrationDigitsLarge, method)
+	}
+	
+	verificationLevels := make(map[string]VerificationOption)
+	for _, level := range GetVerificationLevels() {
+		verificationLevels[level] = VerificationOption{
+			Multiplier:  VerificationMultiplier(VerificationLevel(level)),
+			Description: verificationDescriptions[VerificationLevel(level)],
+		}
 	}
 	
 	return &PricingInfo{
@@ -300,6 +352,9 @@ func (pm *PricingManager) GetPricingInfo() *PricingInfo {
 		Currency:          pm.baseCurrency,
 		CommunityPoolAddr: pm.communityPoolAddr,
 		MethodMultipliers: methodMultipliers,
+		VerificationLevels: verificationLevels,
+		PerformanceProfile: pm.profile,
+		Surge:             pm.engine.Surge(),
 		LastUpdated:       time.Now(),
 	}
 }
@@ -310,7 +365,7 @@ func (pm *PricingManager) CalculateBulkPricing(requests []PricingRequest) ([]Pri
 	errors := make([]error, len(requests))
 	
 	for i, req := range requests {
-		breakdown, err := pm.CalculatePrice(req.Digits, req.Tier, req.Method)
+		breakdown, err := pm.CalculatePriceWithVerification(req.Digits, req.Tier, req.Method, req.Verification)
 		if err != nil {
 			errors[i] = err
 		} else {
@@ -323,17 +378,18 @@ func (pm *PricingManager) CalculateBulkPricing(requests []PricingRequest) ([]Pri
 
 // PricingRequest represents a pricing calculation request
 type PricingRequest struct {
-	Digits int         `json:"digits"`
-	Tier   ServiceTier `json:"tier"`
-	Method string      `json:"method"`
+	Digits       int               `json:"digits"`
+	Tier         ServiceTier       `json:"tier"`
+	Method       string            `json:"method"`
+	Verification VerificationLevel `json:"verification,omitempty"`
 }
 
 // CompareServiceTiers compares all tiers for given parameters
-func (pm *PricingManager) CompareServiceTiers(digits int, method string) ([]PriceBreakdown, error) {
+func (pm *PricingManager) CompareServiceTiers(digits int, method string, verification VerificationLevel) ([]PriceBreakdown, error) {
 	var comparisons []PriceBreakdown
 	
 	for _, tier := range []ServiceTier{TierBasic, TierStandard, TierPremium} {
-		breakdown, err := pm.CalculatePrice(digits, tier, method)
+		breakdown, err := pm.CalculatePriceWithVerification(digits, tier, method, verification)
 		if err != nil {
 			// Skip tiers that can't handle the request
 			continue
@@ -348,10 +404,11 @@ func (pm *PricingManager) CompareServiceTiers(digits int, method string) ([]Pric
 	return comparisons, nil
 }
 
-// ValidatePaymentAmount checks if payment amount matches expected cost
+// ValidatePaymentAmount checks if payment amount matches expected cost, a
+// tolerancePercent of 0 requires the exact amount
 func (pm *PricingManager) ValidatePaymentAmount(expectedCost, actualPayment float64, tolerancePercent float64) bool {
-	if tolerancePercent <= 0 {
-		tolerancePercent = 1.0 // Default 1% tolerance
+	if tolerancePercent < 0 {
+		tolerancePercent = 0
 	}
 	
 	tolerance := expectedCost * tolerancePercent / 100.0
@@ -373,11 +430,11 @@ func (pm *PricingManager) SetCommunityPoolAddress(address string) {
 
 // GetTierForDigits suggests the best tier for given digit count
 func (pm *PricingManager) GetTierForDigits(digits int) ServiceTier {
-	if digits <= pm.tiers[TierBasic].MaxDigits {
-		return TierBasic
-	}
-	if digits <= pm.tiers[TierStandard].MaxDigits {
-		return TierStandard
+	tiers := pm.engine.Tiers()
+	for _, tier := range []ServiceTier{TierBasic, TierStandard} {
+		if t, ok := tiers[tier]; ok && digits <= t.MaxDigits {
+			return tier
+		}
 	}
 	return TierPremium
 }
@@ -0,0 +1,78 @@
+package compute
+
+// PricingEngine supplies the tiers a PricingManager prices jobs with and a
+// surge factor on all prices
+type PricingEngine interface {
+	// Tiers returns the service tiers, prices per digit in MEDAS
+	Tiers() map[ServiceTier]*PricingTier
+	// Surge is the factor on top of the tier prices, 1 without surge
+	Surge() float64
+}
+
+// StaticPricing is a fixed set of tiers without surge
+type StaticPricing map[ServiceTier]*PricingTier
+
+// Tiers returns the tiers
+func (s StaticPricing) Tiers() map[ServiceTier]*PricingTier {
+	return s
+}
+
+// Surge is always 1
+func (s StaticPricing) Surge() float64 {
+	return 1
+}
+
+// DefaultPricing returns the built-in tiers
+func DefaultPricing() StaticPricing {
+	return StaticPricing{
+		TierBasic: {
+			Name:                TierBasic,
+			PricePerDigit:       0.0001, // 0.0001 MEDAS per digit
+			MaxDigits:           1000,
+			MaxRuntimeMinutes:   5,
+			CommunityFeePercent: 0.15, // 15%
+			Priority:            1,
+			Description:         "Basic PI calculation for testing and learning",
+			Features: []string{
+				"Standard precision calculation",
+				"Basic result verification",
+				"Single algorithm (Chudnovsky)",
+				"Up to 1,000 digits",
+			},
+		},
+		TierStandard: {
+			Name:                TierStandard,
+			PricePerDigit:       0.00025,
+			MaxDigits:           10000,
+			MaxRuntimeMinutes:   30,
+			CommunityFeePercent: 0.15,
+			Priority:            2,
+			Description:         "Standard service with progress monitoring",
+			Features: []string{
+				"Real-time progress updates",
+				"Multiple algorithms available",
+				"Advanced result verification",
+				"Up to 10,000 digits",
+				"Job status monitoring",
+			},
+		},
+		TierPremium: {
+			Name:                TierPremium,
+			PricePerDigit:       0.0005,
+			MaxDigits:           1000000,
+			MaxRuntimeMinutes:   120,
+			CommunityFeePercent: 0.15,
+			Priority:            3,
+			Description:         "Premium service with highest priority and guarantees",
+			Features: []string{
+				"Highest priority processing",
+				"All calculation algorithms",
+				"Guaranteed completion",
+				"Real-time monitoring dashboard",
+				"Up to 1,000,000 digits",
+				"Performance analytics",
+				"Dedicated support",
+			},
+		},
+	}
+}
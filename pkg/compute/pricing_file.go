@@ -0,0 +1,364 @@
+package compute
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// pricingReloadInterval is how often a pricing file is checked for changes
+const pricingReloadInterval = 10 * time.Second
+
+// DefaultOracleRefresh is how often the MEDAS price is fetched by default
+const DefaultOracleRefresh = 5 * time.Minute
+
+// PricingFile is the YAML format of a pricing file
+//
+//	currency: USD            # of price_per_digit, default MEDAS
+//	oracle:                  # price of one MEDAS in currency
+//	  url: https://prices.example.org/simple/price?ids=medas&vs_currencies=usd
+//	  field: medas.usd
+//	surge:
+//	  threshold: 0.8         # load where surge pricing starts
+//	  max_multiplier: 2      # factor at full load
+//	tiers:
+//	  basic:
+//	    price_per_digit: 0.00002
+//	    max_digits: 1000
+//	    max_runtime_minutes: 5
+//	    community_fee_percent: 0.15
+type PricingFile struct {
+	Currency string                       `yaml:"currency,omitempty"`
+	Oracle   *OracleConfig                `yaml:"oracle,omitempty"`
+	Surge    *SurgeConfig                 `yaml:"surge,omitempty"`
+	Tiers    map[ServiceTier]*PricingTier `yaml:"tiers"`
+}
+
+// OracleConfig is a JSON price API that returns the price of one MEDAS
+type OracleConfig struct {
+	URL     string        `yaml:"url"`
+	Field   string        `yaml:"field"`             // dotted path of the price in the response, e.g. medas.usd
+	Refresh time.Duration `yaml:"refresh,omitempty"` // default 5m
+}
+
+// SurgeConfig raises prices with the load of the service: 1 up to the
+// threshold, rising linearly to the maximum at full load
+type SurgeConfig struct {
+	Threshold     float64 `yaml:"threshold"`      // load between 0 and 1, default 0.8
+	MaxMultiplier float64 `yaml:"max_multiplier"` // default 2
+}
+
+// Multiplier is the surge factor at a load, busy workers and queued jobs per
+// worker. It is rounded to 0.01 so prices do not change with every job.
+func (c SurgeConfig) Multiplier(load float64) float64 {
+	if load <= c.Threshold {
+		return 1
+	}
+	rise := math.Min(1, (load-c.Threshold)/(1-c.Threshold))
+	return math.Round((1+(c.MaxMultiplier-1)*rise)*100) / 100
+}
+
+// LoadPricingFile reads and validates a pricing file
+func LoadPricingFile(path string) (*PricingFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pricing file: %w", err)
+	}
+	var f PricingFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid pricing file %s: %w", path, err)
+	}
+	if err := f.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid pricing file %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Validate checks the tiers and fills in the defaults
+func (f *PricingFile) Validate() error {
+	if len(f.Tiers) == 0 {
+		return fmt.Errorf("no tiers")
+	}
+	for name, t := range f.Tiers {
+		switch name {
+		case TierBasic, TierStandard, TierPremium:
+		default:
+			return fmt.Errorf("unknown tier %q, use basic, standard or premium", name)
+		}
+		if t == nil {
+			return fmt.Errorf("tier %s is empty", name)
+		}
+		t.Name = name
+		if t.PricePerDigit <= 0 || t.MaxDigits <= 0 || t.MaxRuntimeMinutes <= 0 {
+			return fmt.Errorf("tier %s: price_per_digit, max_digits and max_runtime_minutes must be positive", name)
+		}
+		if t.CommunityFeePercent < 0 || t.CommunityFeePercent >= 1 {
+			return fmt.Errorf("tier %s: community_fee_percent must be a fraction between 0 and 1, got %g", name, t.CommunityFeePercent)
+		}
+	}
+
+	f.Currency = strings.ToUpper(f.Currency)
+	if f.Currency == "" {
+		f.Currency = "MEDAS"
+	}
+	if f.Currency == "MEDAS" && f.Oracle != nil {
+		return fmt.Errorf("oracle is only used with a currency other than MEDAS")
+	}
+	if f.Currency != "MEDAS" {
+		if f.Oracle == nil || f.Oracle.URL == "" || f.Oracle.Field == "" {
+			return fmt.Errorf("prices in %s need an oracle url and field", f.Currency)
+		}
+		if f.Oracle.Refresh == 0 {
+			f.Oracle.Refresh = DefaultOracleRefresh
+		}
+		if f.Oracle.Refresh < 0 {
+			return fmt.Errorf("oracle refresh must be positive")
+		}
+	}
+
+	if s := f.Surge; s != nil {
+		if s.Threshold == 0 {
+			s.Threshold = 0.8
+		}
+		if s.MaxMultiplier == 0 {
+			s.MaxMultiplier = 2
+		}
+		if s.Threshold < 0 || s.Threshold >= 1 {
+			return fmt.Errorf("surge threshold must be between 0 and 1, got %g", s.Threshold)
+		}
+		if s.MaxMultiplier < 1 {
+			return fmt.Errorf("surge max_multiplier must be at least 1, got %g", s.MaxMultiplier)
+		}
+	}
+	return nil
+}
+
+// PriceOracle returns the price of one MEDAS in another currency
+type PriceOracle interface {
+	MEDASPrice(ctx context.Context) (float64, error)
+}
+
+// HTTPOracle reads the MEDAS price from a JSON price API
+type HTTPOracle struct {
+	URL   string
+	Field string // dotted path of the price, numbers and numeric strings are accepted
+	HTTP  *http.Client
+}
+
+// MEDASPrice fetches the price
+func (o *HTTPOracle) MEDASPrice(ctx context.Context) (float64, error) {
+	httpClient := o.HTTP
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 15 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.URL, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("price oracle: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("price oracle: HTTP %d", resp.StatusCode)
+	}
+
+	var value interface{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&value); err != nil {
+		return 0, fmt.Errorf("price oracle: invalid response: %w", err)
+	}
+	for _, key := range strings.Split(o.Field, ".") {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return 0, fmt.Errorf("price oracle: no %s in the response", o.Field)
+		}
+		value = obj[key]
+	}
+	var price float64
+	switch v := value.(type) {
+	case float64:
+		price = v
+	case string:
+		price, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("price oracle: %s is not a number: %q", o.Field, v)
+		}
+	default:
+		return 0, fmt.Errorf("price oracle: no price at %s in the response", o.Field)
+	}
+	if price <= 0 || math.IsInf(price, 0) || math.IsNaN(price) {
+		return 0, fmt.Errorf("price oracle: invalid price %g", price)
+	}
+	return price, nil
+}
+
+// FilePricing is a PricingEngine read from a pricing file. Run reloads the
+// file when it changes and refreshes the MEDAS price of fiat prices, so
+// prices change without a restart.
+type FilePricing struct {
+	Logger *log.Logger // default log.Default()
+
+	path string
+	load func() float64 // load of the service for surge pricing, may be nil
+
+	mu         sync.RWMutex
+	file       *PricingFile
+	modTime    time.Time
+	oracle     PriceOracle
+	medasPrice float64 // one MEDAS in file.Currency, 1 for MEDAS prices
+	priceTime  time.Time
+	refreshAt  time.Time                    // of the MEDAS price
+	tiers      map[ServiceTier]*PricingTier // in MEDAS
+}
+
+// NewFilePricing loads a pricing file, fetching the MEDAS price if the tiers
+// are priced in another currency. load reports busy workers and queued jobs
+// per worker for surge pricing.
+func NewFilePricing(ctx context.Context, path string, load func() float64) (*FilePricing, error) {
+	p := &FilePricing{path: path, load: load}
+	if err := p.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Path returns the pricing file
+func (p *FilePricing) Path() string {
+	return p.path
+}
+
+// Tiers returns the tiers in MEDAS
+func (p *FilePricing) Tiers() map[ServiceTier]*PricingTier {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.tiers
+}
+
+// Surge is the factor at the current load, 1 without surge in the file
+func (p *FilePricing) Surge() float64 {
+	p.mu.RLock()
+	surge := p.file.Surge
+	p.mu.RUnlock()
+	if surge == nil || p.load == nil {
+		return 1
+	}
+	return surge.Multiplier(p.load())
+}
+
+// ExchangeRate returns the currency of the file and the last price of one
+// MEDAS in it with the time it was fetched, zero for MEDAS prices
+func (p *FilePricing) ExchangeRate() (currency string, price float64, fetched time.Time) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.file.Currency, p.medasPrice, p.priceTime
+}
+
+// Reload reads the pricing file and fetches the MEDAS price. On error the
+// current prices stay in effect.
+func (p *FilePricing) Reload(ctx context.Context) error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read pricing file: %w", err)
+	}
+	file, err := LoadPricingFile(p.path)
+	if err != nil {
+		return err
+	}
+
+	var oracle PriceOracle
+	price := 1.0
+	if file.Oracle != nil {
+		oracle = &HTTPOracle{URL: file.Oracle.URL, Field: file.Oracle.Field}
+		if price, err = oracle.MEDASPrice(ctx); err != nil {
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.file, p.modTime, p.oracle = file, info.ModTime(), oracle
+	p.setPrice(price)
+	return nil
+}
+
+// setPrice converts the tiers of the file with the MEDAS price, p.mu is held
+func (p *FilePricing) setPrice(price float64) {
+	p.medasPrice, p.priceTime = price, time.Now()
+	if p.file.Oracle != nil {
+		p.refreshAt = p.priceTime.Add(p.file.Oracle.Refresh)
+	}
+	tiers := make(map[ServiceTier]*PricingTier, len(p.file.Tiers))
+	for name, t := range p.file.Tiers {
+		converted := *t
+		converted.PricePerDigit = t.PricePerDigit / price
+		tiers[name] = &converted
+	}
+	p.tiers = tiers
+}
+
+// Run reloads the file when it changes and refreshes the MEDAS price until
+// ctx is cancelled. Failures are logged and the last prices stay in effect.
+func (p *FilePricing) Run(ctx context.Context) {
+	if p.Logger == nil {
+		p.Logger = log.Default()
+	}
+	ticker := time.NewTicker(pricingReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.RLock()
+		modTime, oracle, file, priceTime, refreshAt := p.modTime, p.oracle, p.file, p.priceTime, p.refreshAt
+		p.mu.RUnlock()
+
+		if info, err := os.Stat(p.path); err == nil && !info.ModTime().Equal(modTime) {
+			if err := p.Reload(ctx); err != nil {
+				p.Logger.Printf("⚠️ Keeping the current prices: %v", err)
+				// Do not retry an invalid file until it changes again
+				p.mu.Lock()
+				p.modTime = info.ModTime()
+				p.mu.Unlock()
+			} else {
+				p.Logger.Printf("💹 Reloaded prices from %s", p.path)
+			}
+			continue
+		}
+
+		if oracle == nil || time.Now().Before(refreshAt) {
+			continue
+		}
+		price, err := oracle.MEDASPrice(ctx)
+		if err != nil {
+			p.Logger.Printf("⚠️ Keeping the MEDAS price of %s: %v", priceTime.Format(time.DateTime), err)
+			// Retry at the next refresh
+			p.mu.Lock()
+			p.refreshAt = time.Now().Add(file.Oracle.Refresh)
+			p.mu.Unlock()
+			continue
+		}
+		p.mu.Lock()
+		if p.file == file {
+			p.setPrice(price)
+		}
+		p.mu.Unlock()
+	}
+}
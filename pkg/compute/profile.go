@@ -0,0 +1,181 @@
+package compute
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// Digit counts of the two timed runs per method, the ratio of their
+// durations gives the growth of the runtime
+const (
+	calibrationDigitsSmall = 1000
+	calibrationDigitsLarge = 4000
+)
+
+// matrixSize is the edge of the square matrices multiplied for the GFLOPS figure
+const matrixSize = 256
+
+// MethodTiming is the measured speed of one PI method
+type MethodTiming struct {
+	Digits          int     `json:"digits"` // size of the larger timed run
+	Seconds         float64 `json:"seconds"`
+	DigitsPerSecond float64 `json:"digits_per_second"`
+	Exponent        float64 `json:"exponent"` // the runtime grows with digits^exponent
+}
+
+// PerformanceProfile is the measured speed of a host. It replaces the fixed
+// guesses of EstimateCalculationTime and the method multipliers when pricing.
+type PerformanceProfile struct {
+	Host         string                  `json:"host"`
+	Arch         string                  `json:"arch"`
+	CPUs         int                     `json:"cpus"`
+	Methods      map[string]MethodTiming `json:"methods"`
+	Benchmark    *PIBenchmark            `json:"benchmark"`     // the reference PI benchmark, digits per second in big integer arithmetic
+	MatrixGFLOPS float64                 `json:"matrix_gflops"` // float64 matrix multiplication, one core
+	CalibratedAt time.Time               `json:"calibrated_at"`
+}
+
+// Calibrate benchmarks the host: the PI job of every method at two sizes, the
+// reference PI benchmark and a dense matrix multiplication. It takes about ten
+// seconds.
+func Calibrate(ctx context.Context) (*PerformanceProfile, error) {
+	host, _ := os.Hostname()
+	p := &PerformanceProfile{
+		Host:    host,
+		Arch:    runtime.GOOS + "/" + runtime.GOARCH,
+		CPUs:    runtime.NumCPU(),
+		Methods: make(map[string]MethodTiming),
+	}
+
+	for _, method := range GetAvailableMethods() {
+		small, err := timePI(ctx, calibrationDigitsSmall, method)
+		if err != nil {
+			return nil, err
+		}
+		large, err := timePI(ctx, calibrationDigitsLarge, method)
+		if err != nil {
+			return nil, err
+		}
+		// Timer noise on fast runs must not give absurd growth rates
+		exponent := math.Log(large/small) / math.Log(float64(calibrationDigitsLarge)/calibrationDigitsSmall)
+		exponent = math.Max(1, math.Min(3, exponent))
+		p.Methods[method] = MethodTiming{
+			Digits:          calibrationDigitsLarge,
+			Seconds:         large,
+			DigitsPerSecond: calibrationDigitsLarge / large,
+			Exponent:        exponent,
+		}
+	}
+
+	bench, err := RunPIBenchmark()
+	if err != nil {
+		return nil, err
+	}
+	p.Benchmark = bench
+
+	gflops, err := measureGFLOPS(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.MatrixGFLOPS = gflops
+	p.CalibratedAt = time.Now()
+	return p, nil
+}
+
+// timePI returns the seconds one PI calculation takes, the best of three runs
+func timePI(ctx context.Context, digits int, method string) (float64, error) {
+	best := math.Inf(1)
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		if _, err := NewPICalculator(digits, method).Calculate(ctx); err != nil {
+			return 0, fmt.Errorf("calibrating %s: %w", method, err)
+		}
+		best = math.Min(best, time.Since(start).Seconds())
+	}
+	return math.Max(best, 1e-6), nil
+}
+
+// measureGFLOPS multiplies matrices for at least 200ms
+func measureGFLOPS(ctx context.Context) (float64, error) {
+	n := matrixSize
+	a := make([]float64, n*n)
+	b := make([]float64, n*n)
+	c := make([]float64, n*n)
+	for i := range a {
+		a[i] = float64(i%7) + 0.5
+		b[i] = float64(i%5) - 1.5
+	}
+
+	start := time.Now()
+	runs := 0
+	for runs == 0 || time.Since(start) < 200*time.Millisecond {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		for i := 0; i < n; i++ {
+			row := c[i*n : (i+1)*n]
+			for j := range row {
+				row[j] = 0
+			}
+			for k := 0; k < n; k++ {
+				aik := a[i*n+k]
+				bk := b[k*n : (k+1)*n]
+				for j, bkj := range bk {
+					row[j] += aik * bkj
+				}
+			}
+		}
+		runs++
+	}
+	flops := 2 * float64(n) * float64(n) * float64(n) * float64(runs)
+	return flops / time.Since(start).Seconds() / 1e9, nil
+}
+
+// EstimateDuration extrapolates the measured runtime of a method to digits.
+// It returns false for methods the profile has no timing for.
+func (p *PerformanceProfile) EstimateDuration(digits int, method string) (time.Duration, bool) {
+	if p == nil {
+		return 0, false
+	}
+	t, ok := p.Methods[method]
+	if !ok || t.Digits <= 0 || t.Seconds <= 0 {
+		return 0, false
+	}
+	seconds := t.Seconds * math.Pow(float64(digits)/float64(t.Digits), t.Exponent)
+	return time.Duration(seconds * float64(time.Second)), true
+}
+
+// LoadPerformanceProfile reads a profile saved by Save
+func LoadPerformanceProfile(path string) (*PerformanceProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p PerformanceProfile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid performance profile %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// Save writes the profile to path
+func (p *PerformanceProfile) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,323 @@
+package compute
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oxygene76/medasdigital-client/pkg/astronomy/planet9"
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// Defaults of the result cache
+const (
+	DefaultCachePriceFactor = 0.1 // share of the full price a cached result costs
+	DefaultCacheTTL         = 30 * 24 * time.Hour
+	DefaultCacheEntries     = 200
+)
+
+// resourceParameters select the hardware of a job, not its result, and are
+// left out of cache keys
+var resourceParameters = map[string]bool{
+	"gpu_memory_mb": true,
+	"gpu_count":     true,
+}
+
+// CacheKey hashes a job type with its canonical parameters: numbers as JSON
+// numbers, map keys sorted, the PI method in lower case and resource hints
+// left out. Jobs with the same key compute the same result.
+func CacheKey(jobType JobType, parameters map[string]interface{}) (string, error) {
+	data, err := json.Marshal(parameters)
+	if err != nil {
+		return "", fmt.Errorf("parameters cannot be hashed: %w", err)
+	}
+	var canonical map[string]interface{}
+	if err := json.Unmarshal(data, &canonical); err != nil {
+		return "", err
+	}
+	for key := range resourceParameters {
+		delete(canonical, key)
+	}
+	if method, ok := canonical["method"].(string); ok {
+		canonical["method"] = strings.ToLower(strings.TrimSpace(method))
+	}
+	data, err = json.Marshal(struct {
+		Type       JobType                `json:"type"`
+		Parameters map[string]interface{} `json:"parameters"`
+	}{jobType, canonical})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CacheEntry is the result of a completed job kept for identical requests
+type CacheEntry struct {
+	Key                string                 `json:"key"`
+	Type               JobType                `json:"type"`
+	Parameters         map[string]interface{} `json:"parameters"`
+	Result             json.RawMessage        `json:"result"`
+	Artifact           *Artifact              `json:"artifact,omitempty"`
+	VerificationReport *VerificationReport    `json:"verification_report,omitempty"`
+	SourceJobID        string                 `json:"source_job_id"`
+	SourcePaymentTx    string                 `json:"source_payment_tx,omitempty"`
+	ComputedAt         time.Time              `json:"computed_at"`
+	Duration           string                 `json:"duration,omitempty"`
+
+	// Since the service started
+	Hits    int       `json:"hits"`
+	LastHit time.Time `json:"last_hit,omitempty"`
+}
+
+// verification is the level the cached result was checked at
+func (e *CacheEntry) verification() VerificationLevel {
+	if e.VerificationReport == nil || !e.VerificationReport.Passed {
+		return VerificationNone
+	}
+	return e.VerificationReport.Level
+}
+
+// satisfies reports whether the cached result was checked as thoroughly as
+// requested: a full recomputation covers a spot check, a commitment has to be
+// requested as such
+func (e *CacheEntry) satisfies(requested VerificationLevel) bool {
+	have := e.verification()
+	switch requested {
+	case VerificationNone, "":
+		return true
+	case VerificationSpotCheck:
+		return have == VerificationSpotCheck || have == VerificationFullRecompute
+	default:
+		return have == requested
+	}
+}
+
+// CacheProvenance marks a job answered from the result cache with the
+// computation its result comes from
+type CacheProvenance struct {
+	Key          string            `json:"key"`
+	SourceJobID  string            `json:"source_job_id"`
+	ComputedAt   time.Time         `json:"computed_at"`
+	Duration     string            `json:"duration,omitempty"` // of the original computation
+	Verification VerificationLevel `json:"verification"`       // the original result was checked at
+	FullCost     float64           `json:"full_cost"`          // price without the cache
+}
+
+// CacheStats summarizes the result cache
+type CacheStats struct {
+	Entries     int     `json:"entries"`
+	Hits        int     `json:"hits"`
+	MaxEntries  int     `json:"max_entries"`
+	TTL         string  `json:"ttl"`
+	PriceFactor float64 `json:"price_factor"`
+}
+
+// ResultCache keeps the results of completed jobs on disk, one file per key.
+// Entries expire after ttl, beyond maxEntries the least recently used go.
+type ResultCache struct {
+	mu          sync.Mutex
+	dir         string
+	ttl         time.Duration
+	maxEntries  int
+	priceFactor float64
+	entries     map[string]*CacheEntry
+}
+
+// NewResultCache loads the cache in dir
+func NewResultCache(dir string, ttl time.Duration, maxEntries int, priceFactor float64) (*ResultCache, error) {
+	if ttl <= 0 || maxEntries <= 0 {
+		return nil, fmt.Errorf("result cache TTL and size must be positive")
+	}
+	if priceFactor < 0 || priceFactor > 1 {
+		return nil, fmt.Errorf("cache price factor must be between 0 and 1, got %g", priceFactor)
+	}
+	c := &ResultCache{dir: dir, ttl: ttl, maxEntries: maxEntries, priceFactor: priceFactor, entries: make(map[string]*CacheEntry)}
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var e CacheEntry
+		if err := json.Unmarshal(data, &e); err != nil || e.Key == "" {
+			log.Printf("⚠️ Could not parse cached result %s: %v", path, err)
+			continue
+		}
+		c.entries[e.Key] = &e
+	}
+	c.prune(time.Now())
+	return c, nil
+}
+
+// Get returns the entry for key if it is fresh and checked at verification
+func (c *ResultCache) Get(key string, verification VerificationLevel) (*CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || time.Since(e.ComputedAt) > c.ttl || !e.satisfies(verification) {
+		return nil, false
+	}
+	return e, true
+}
+
+// hit counts a job answered by an entry
+func (c *ResultCache) hit(e *CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e.Hits++
+	e.LastHit = time.Now()
+}
+
+// Put keeps the result of a completed job, a result checked more thoroughly
+// replaces the one cached
+func (c *ResultCache) Put(job *ComputeJob) error {
+	key, err := CacheKey(job.Type, job.Parameters)
+	if err != nil {
+		return err
+	}
+	result, err := json.Marshal(job.Result)
+	if err != nil {
+		return err
+	}
+	e := &CacheEntry{
+		Key:                key,
+		Type:               job.Type,
+		Parameters:         job.Parameters,
+		Result:             result,
+		Artifact:           job.Artifact,
+		VerificationReport: job.VerificationReport,
+		SourceJobID:        job.ID,
+		SourcePaymentTx:    job.PaymentTxHash,
+		ComputedAt:         time.Now(),
+		Duration:           job.Duration,
+	}
+	if job.CompletedAt != nil {
+		e.ComputedAt = *job.CompletedAt
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.entries[key]; ok && time.Since(old.ComputedAt) <= c.ttl && !e.satisfies(old.verification()) {
+		return nil
+	}
+	if err := c.save(e); err != nil {
+		return err
+	}
+	c.entries[key] = e
+	c.prune(time.Now())
+	return nil
+}
+
+// Price is the price of a job answered from the cache
+func (c *ResultCache) Price(full *PriceBreakdown) *PriceBreakdown {
+	p := *full
+	p.BaseCost *= c.priceFactor
+	p.ServiceFee *= c.priceFactor
+	p.CommunityFee *= c.priceFactor
+	p.TotalCost *= c.priceFactor
+	p.EstimatedTime = 0
+	p.Cached = true
+	p.Breakdown = fmt.Sprintf("%.6f MEDAS, cached result at %.0f%% of %s", p.TotalCost, c.priceFactor*100, full.Breakdown)
+	return &p
+}
+
+// Stats summarizes the cache
+func (c *ResultCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := CacheStats{Entries: len(c.entries), MaxEntries: c.maxEntries, TTL: c.ttl.String(), PriceFactor: c.priceFactor}
+	for _, e := range c.entries {
+		stats.Hits += e.Hits
+	}
+	return stats
+}
+
+// ArtifactRefs returns the artifact files cached results refer to, so they
+// are not collected
+func (c *ResultCache) ArtifactRefs() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var refs []string
+	for _, e := range c.entries {
+		if e.Artifact != nil {
+			refs = append(refs, e.Artifact.SHA256)
+		}
+	}
+	return refs
+}
+
+// prune drops expired entries and the least recently used beyond maxEntries
+func (c *ResultCache) prune(now time.Time) {
+	var live []*CacheEntry
+	for key, e := range c.entries {
+		if now.Sub(e.ComputedAt) > c.ttl {
+			c.remove(key)
+			continue
+		}
+		live = append(live, e)
+	}
+	if len(live) <= c.maxEntries {
+		return
+	}
+	lastUse := func(e *CacheEntry) time.Time {
+		if e.LastHit.After(e.ComputedAt) {
+			return e.LastHit
+		}
+		return e.ComputedAt
+	}
+	sort.Slice(live, func(i, j int) bool { return lastUse(live[i]).Before(lastUse(live[j])) })
+	for _, e := range live[:len(live)-c.maxEntries] {
+		c.remove(e.Key)
+	}
+}
+
+func (c *ResultCache) remove(key string) {
+	delete(c.entries, key)
+	os.Remove(filepath.Join(c.dir, key+".json"))
+}
+
+func (c *ResultCache) save(e *CacheEntry) error {
+	defer telemetry.Track(telemetry.CategoryDisk, "save_result_cache")()
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(c.dir, e.Key+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// decodeResult restores the typed result of a job type
+func decodeResult(jobType JobType, data json.RawMessage) (interface{}, error) {
+	switch jobType {
+	case JobTypePICalculation:
+		var result PIResult
+		err := json.Unmarshal(data, &result)
+		return &result, err
+	case JobTypePlanet9Search:
+		var result planet9.ChunkResult
+		err := json.Unmarshal(data, &result)
+		return &result, err
+	default:
+		var result interface{}
+		err := json.Unmarshal(data, &result)
+		return result, err
+	}
+}
@@ -0,0 +1,256 @@
+package compute
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sandbox runtimes
+const (
+	SandboxNone   = "none"
+	SandboxDocker = "docker"
+	SandboxPodman = "podman"
+)
+
+// DefaultSandboxImage provides the glibc the client binary is linked against
+const DefaultSandboxImage = "debian:bookworm-slim"
+
+// sandboxBinary is where the client binary is mounted inside the container
+const sandboxBinary = "/usr/local/bin/medas-worker"
+
+// ErrSandboxTimeout is returned when a job exceeds the sandbox time limit
+var ErrSandboxTimeout = errors.New("sandbox time limit exceeded")
+
+// SandboxConfig limits the container a job runs in
+type SandboxConfig struct {
+	Runtime   string        `yaml:"runtime" json:"runtime"` // docker or podman
+	Image     string        `yaml:"image" json:"image"`
+	CPUs      float64       `yaml:"cpus" json:"cpus"`
+	MemoryMB  int           `yaml:"memory_mb" json:"memory_mb"`
+	PidsLimit int           `yaml:"pids_limit" json:"pids_limit"`
+	Timeout   time.Duration `yaml:"timeout" json:"timeout"`
+	Binary    string        `yaml:"binary" json:"binary"`     // worker binary, default: this executable
+	WorkDir   string        `yaml:"work_dir" json:"work_dir"` // host directory for job files
+}
+
+// SandboxJob is the job description handed to the worker inside the container
+type SandboxJob struct {
+	ID           string                 `json:"id"`
+	Type         JobType                `json:"type"`
+	Parameters   map[string]interface{} `json:"parameters"`
+	Verification VerificationLevel      `json:"verification"`
+	OutOfCore    OutOfCore              `json:"out_of_core"`
+}
+
+// SandboxOutput is what the worker writes back
+type SandboxOutput struct {
+	Result             json.RawMessage     `json:"result,omitempty"`
+	VerificationReport *VerificationReport `json:"verification_report,omitempty"`
+	Error              string              `json:"error,omitempty"`
+}
+
+// Sandbox runs jobs in a resource limited container without network access
+type Sandbox struct {
+	cfg     SandboxConfig
+	runtime string // resolved path of docker/podman
+}
+
+// NewSandbox checks the runtime and fills in defaults
+func NewSandbox(cfg SandboxConfig) (*Sandbox, error) {
+	if cfg.Runtime != SandboxDocker && cfg.Runtime != SandboxPodman {
+		return nil, fmt.Errorf("unsupported sandbox runtime %q (use docker or podman)", cfg.Runtime)
+	}
+	runtimePath, err := exec.LookPath(cfg.Runtime)
+	if err != nil {
+		return nil, fmt.Errorf("%s not found: %w", cfg.Runtime, err)
+	}
+
+	if cfg.Image == "" {
+		cfg.Image = DefaultSandboxImage
+	}
+	if cfg.CPUs <= 0 {
+		cfg.CPUs = 1
+	}
+	if cfg.MemoryMB <= 0 {
+		cfg.MemoryMB = 2048
+	}
+	if cfg.PidsLimit <= 0 {
+		cfg.PidsLimit = 256
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Minute
+	}
+	if cfg.Binary == "" {
+		if cfg.Binary, err = os.Executable(); err != nil {
+			return nil, fmt.Errorf("failed to locate worker binary: %w", err)
+		}
+	}
+	if cfg.Binary, err = filepath.Abs(cfg.Binary); err != nil {
+		return nil, err
+	}
+
+	return &Sandbox{cfg: cfg, runtime: runtimePath}, nil
+}
+
+// Config returns the effective sandbox settings
+func (s *Sandbox) Config() SandboxConfig {
+	return s.cfg
+}
+
+// Run executes a job in a fresh container and returns the worker output
+func (s *Sandbox) Run(ctx context.Context, job *ComputeJob) (*SandboxOutput, error) {
+	dir, err := os.MkdirTemp(s.cfg.WorkDir, "medas-job-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	// The worker runs as nobody and has to write its result
+	if err := os.Chmod(dir, 0o777); err != nil {
+		return nil, err
+	}
+
+	// Products that do not fit half the memory limit go to the job directory,
+	// the tmpfs is too small and counts against the limit
+	var ooc OutOfCore
+	if s.cfg.MemoryMB > 0 {
+		ooc = OutOfCore{MemoryBudget: int64(s.cfg.MemoryMB) * 1024 * 1024 / 2, TempDir: "/job"}
+	}
+	spec, err := json.Marshal(SandboxJob{
+		ID:           job.ID,
+		Type:         job.Type,
+		Parameters:   job.Parameters,
+		Verification: job.Verification,
+		OutOfCore:    ooc,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "job.json"), spec, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write job file: %w", err)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	name := containerName(job.ID)
+	cmd := exec.CommandContext(runCtx, s.runtime, s.runArgs(name, dir, job.GPUDevices)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
+	if runCtx.Err() != nil {
+		// Killing the CLI does not stop the container
+		_ = exec.Command(s.runtime, "kill", name).Run()
+		if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w after %v", ErrSandboxTimeout, s.cfg.Timeout)
+		}
+		return nil, runCtx.Err()
+	}
+	if runErr != nil {
+		var exitErr *exec.ExitError
+		if errors.As(runErr, &exitErr) && exitErr.ExitCode() == 137 {
+			return nil, fmt.Errorf("sandbox killed, memory limit of %d MB exceeded", s.cfg.MemoryMB)
+		}
+		return nil, fmt.Errorf("sandbox failed: %w: %s", runErr, strings.TrimSpace(stderr.String()))
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "result.json"))
+	if err != nil {
+		return nil, fmt.Errorf("sandbox produced no result: %w", err)
+	}
+	var out SandboxOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("invalid sandbox result: %w", err)
+	}
+	if out.Error != "" {
+		return &out, errors.New(out.Error)
+	}
+	return &out, nil
+}
+
+// runArgs builds the container command line
+func (s *Sandbox) runArgs(name, dir string, gpuDevices []int) []string {
+	args := []string{
+		"run", "--rm",
+		"--name", name,
+		"--network", "none",
+		"--cpus", strconv.FormatFloat(s.cfg.CPUs, 'f', -1, 64),
+		"--memory", fmt.Sprintf("%dm", s.cfg.MemoryMB),
+		"--memory-swap", fmt.Sprintf("%dm", s.cfg.MemoryMB),
+		"--pids-limit", strconv.Itoa(s.cfg.PidsLimit),
+		"--read-only",
+		"--tmpfs", "/tmp:rw,noexec,nosuid,size=64m",
+		"--cap-drop", "ALL",
+		"--security-opt", "no-new-privileges",
+		"--user", "65534:65534",
+		"-v", s.cfg.Binary + ":" + sandboxBinary + ":ro",
+		"-v", dir + ":/job",
+		"-e", "HOME=/tmp",
+	}
+
+	if len(gpuDevices) > 0 {
+		ids := make([]string, len(gpuDevices))
+		for i, id := range gpuDevices {
+			ids[i] = strconv.Itoa(id)
+		}
+		if s.cfg.Runtime == SandboxDocker {
+			args = append(args, "--gpus", "device="+strings.Join(ids, ","))
+		} else {
+			for _, id := range ids {
+				args = append(args, "--device", "nvidia.com/gpu="+id)
+			}
+		}
+	}
+
+	return append(args, s.cfg.Image, sandboxBinary, "job-exec", "/job/job.json", "/job/result.json")
+}
+
+var containerNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+func containerName(jobID string) string {
+	return fmt.Sprintf("medas-job-%s-%d", containerNameChars.ReplaceAllString(jobID, "_"), time.Now().UnixNano())
+}
+
+// ExecuteJob runs a job in the current process. It is the entry point of the
+// worker inside the sandbox and never touches the network.
+func ExecuteJob(ctx context.Context, spec SandboxJob) *SandboxOutput {
+	switch spec.Type {
+	case JobTypePICalculation:
+		digits, ok := spec.Parameters["digits"].(float64)
+		if !ok {
+			return &SandboxOutput{Error: "invalid digits parameter"}
+		}
+		method, _ := spec.Parameters["method"].(string)
+		if method == "" {
+			method = "chudnovsky"
+		}
+
+		calc := NewPICalculator(int(digits), method)
+		calc.SetOutOfCore(spec.OutOfCore)
+		result, err := calc.Calculate(ctx)
+		if err != nil {
+			return &SandboxOutput{Error: fmt.Sprintf("PI calculation failed: %v", err)}
+		}
+		report, err := VerifyPIResult(ctx, result, spec.Verification)
+		if err != nil {
+			return &SandboxOutput{Error: fmt.Sprintf("result verification failed: %v", err)}
+		}
+		data, err := json.Marshal(result)
+		if err != nil {
+			return &SandboxOutput{Error: err.Error()}
+		}
+		return &SandboxOutput{Result: data, VerificationReport: report}
+	default:
+		return &SandboxOutput{Error: fmt.Sprintf("unsupported job type: %s", spec.Type)}
+	}
+}
@@ -0,0 +1,363 @@
+package compute
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBlockDigits is the number of decimals in a streamed digit block
+const DefaultBlockDigits = 10000
+
+// maxReferenceDigits is how far blocks are checked against Gauss' formula,
+// its cost grows quadratically and is out of reach for million-digit jobs
+const maxReferenceDigits = 100000
+
+// DigitBlock is a run of decimals of PI. Hash chains the blocks: it is the
+// SHA-256 of the previous block's hash and the digits, so the last hash
+// commits to every digit delivered so far.
+type DigitBlock struct {
+	Index    int    `json:"index"`
+	Offset   int    `json:"offset"` // decimal place of the first digit, counting from 1
+	Digits   string `json:"digits"`
+	Verified bool   `json:"verified"` // matches an independent computation with Gauss' formula, up to 100000 digits
+	Hash     string `json:"hash"`
+}
+
+// StreamEvent is one line of a job stream: a digit block or, last, the
+// status the job ended with
+type StreamEvent struct {
+	Block  *DigitBlock `json:"block,omitempty"`
+	Status JobStatus   `json:"status,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// PICheckpoint is the state of a PI job after its last delivered block. A
+// service resumes the job from it after an interruption instead of starting
+// over.
+type PICheckpoint struct {
+	Job         *ComputeJob   `json:"job"`
+	BlockDigits int           `json:"block_digits"`
+	Blocks      []DigitBlock  `json:"blocks"`
+	Elapsed     time.Duration `json:"elapsed"` // calculation time spent on the blocks
+	SavedAt     time.Time     `json:"saved_at"`
+}
+
+// LoadPICheckpoint reads a checkpoint saved by Save
+func LoadPICheckpoint(path string) (*PICheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cp PICheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+	if cp.Job == nil || cp.Job.Type != JobTypePICalculation {
+		return nil, fmt.Errorf("checkpoint %s is not of a PI calculation job", path)
+	}
+	return &cp, nil
+}
+
+// Save writes the checkpoint to path
+func (cp *PICheckpoint) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Stream calculates PI like Calculate and hands the decimals to emit in
+// blocks of blockDigits as the calculation progresses. Every block within the
+// reference precision is checked against an independent computation before it
+// is emitted, the ones after are emitted unverified. The blocks of an
+// interrupted run are skipped, together with their share of the runtime.
+func (calc *PICalculator) Stream(ctx context.Context, blockDigits int, done []DigitBlock, emit func(DigitBlock) error) (*PIResult, error) {
+	start := time.Now()
+	if blockDigits <= 0 {
+		blockDigits = DefaultBlockDigits
+	}
+
+	// The method hands over its runtime instead of waiting it out, the
+	// blocks are paced by it
+	var runtime time.Duration
+	calc.pace = func(ctx context.Context, d time.Duration) error {
+		runtime = d
+		return nil
+	}
+	result, err := calc.Calculate(ctx)
+	calc.pace = nil
+	if err != nil {
+		return nil, err
+	}
+	reference := gaussPI(min(calc.precision, maxReferenceDigits))
+	decimals := result.Value[2:]
+
+	blocks := (calc.precision + blockDigits - 1) / blockDigits
+	if len(done) > blocks {
+		return nil, fmt.Errorf("checkpoint has %d blocks, the job only %d", len(done), blocks)
+	}
+	hash := ""
+	for i, b := range done {
+		lo, hi := blockRange(i, blockDigits, calc.precision)
+		hash = chainHash(hash, decimals[lo:hi])
+		if b.Index != i || b.Hash != hash {
+			return nil, fmt.Errorf("checkpoint block %d does not match the calculation", i)
+		}
+	}
+
+	perBlock := runtime / time.Duration(blocks)
+	for i := len(done); i < blocks; i++ {
+		timer := time.NewTimer(perBlock)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		lo, hi := blockRange(i, blockDigits, calc.precision)
+		block := DigitBlock{
+			Index:  i,
+			Offset: lo + 1,
+			Digits: decimals[lo:hi],
+			Hash:   chainHash(hash, decimals[lo:hi]),
+		}
+		if ref := len(reference) - 2; lo < ref {
+			block.Verified = decimals[lo:min(hi, ref)] == reference[2+lo:2+min(hi, ref)]
+		}
+		if !block.Verified && lo < len(reference)-2 {
+			return nil, fmt.Errorf("digits %d to %d do not match the reference computation", lo+1, hi)
+		}
+		if err := emit(block); err != nil {
+			return nil, err
+		}
+		hash = block.Hash
+	}
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// resultBlocks splits the decimals of a finished result into the blocks a
+// stream of its calculation delivered
+func resultBlocks(value string, blockDigits int) []DigitBlock {
+	if len(value) < 2 {
+		return nil
+	}
+	decimals := value[2:]
+	var blocks []DigitBlock
+	hash := ""
+	for i := 0; i*blockDigits < len(decimals); i++ {
+		lo, hi := blockRange(i, blockDigits, len(decimals))
+		hash = chainHash(hash, decimals[lo:hi])
+		blocks = append(blocks, DigitBlock{
+			Index:    i,
+			Offset:   lo + 1,
+			Digits:   decimals[lo:hi],
+			Verified: lo < maxReferenceDigits,
+			Hash:     hash,
+		})
+	}
+	return blocks
+}
+
+// blockRange returns the decimals of block i as indexes into the decimals
+func blockRange(i, blockDigits, digits int) (int, int) {
+	return i * blockDigits, min((i+1)*blockDigits, digits)
+}
+
+// chainHash is the hash of a block following the block with hash prev
+func chainHash(prev, digits string) string {
+	sum := sha256.Sum256([]byte(prev + digits))
+	return hex.EncodeToString(sum[:])
+}
+
+// gaussPI computes PI with π/4 = 12·arccot(18) + 8·arccot(57) − 5·arccot(239),
+// independent of the Machin formula of the methods
+func gaussPI(digits int) string {
+	const guard = 10
+	unity := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits+guard)), nil)
+
+	pi := new(big.Int).Mul(big.NewInt(12), arccot(18, unity))
+	pi.Add(pi, new(big.Int).Mul(big.NewInt(8), arccot(57, unity)))
+	pi.Sub(pi, new(big.Int).Mul(big.NewInt(5), arccot(239, unity)))
+	pi.Mul(pi, big.NewInt(4))
+	pi.Div(pi, new(big.Int).Exp(big.NewInt(10), big.NewInt(guard), nil))
+
+	s := pi.String()
+	return s[:1] + "." + s[1:]
+}
+
+// SetCheckpointDir saves a checkpoint of every PI job to dir after each
+// block, named after the job. It is removed when the job ends.
+func (jm *JobManager) SetCheckpointDir(dir string) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.checkpointDir = dir
+}
+
+// CheckpointPath is where the checkpoint of a job is saved, empty without a
+// checkpoint directory
+func (jm *JobManager) CheckpointPath(jobID string) string {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	if jm.checkpointDir == "" {
+		return ""
+	}
+	return filepath.Join(jm.checkpointDir, jobID+".json")
+}
+
+// ResumeJob queues the job of a checkpoint again, it continues after the
+// delivered blocks. Payment and price are taken over from the checkpoint.
+func (jm *JobManager) ResumeJob(cp *PICheckpoint) (*ComputeJob, error) {
+	if cp.Job == nil || cp.Job.Type != JobTypePICalculation {
+		return nil, fmt.Errorf("checkpoint is not of a PI calculation job")
+	}
+	if err := jm.validateJobParameters(cp.Job.Type, cp.Job.Parameters); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	if _, exists := jm.jobs[cp.Job.ID]; exists {
+		return nil, fmt.Errorf("job %s already exists", cp.Job.ID)
+	}
+	if len(jm.jobs) >= jm.maxJobs {
+		return nil, fmt.Errorf("maximum concurrent jobs reached (%d)", jm.maxJobs)
+	}
+
+	ctx, cancel := context.WithCancel(jm.ctx)
+	saved := cp.Job
+	job := &ComputeJob{
+		ID:              saved.ID,
+		Type:            saved.Type,
+		Parameters:      saved.Parameters,
+		Status:          StatusSubmitted,
+		PaymentTxHash:   saved.PaymentTxHash,
+		PaymentVerified: saved.PaymentVerified,
+		PaymentDenom:    saved.PaymentDenom,
+		PriceBreakdown:  saved.PriceBreakdown,
+		Verification:    saved.Verification,
+		SubmittedAt:     saved.SubmittedAt,
+		ClientAddr:      saved.ClientAddr,
+		Tier:            saved.Tier,
+		Priority:        jm.getTierPriority(saved.Tier),
+		ctx:             ctx,
+		cancelFunc:      cancel,
+		progressChan:    make(chan int, 10),
+		blocks:          append([]DigitBlock(nil), cp.Blocks...),
+		blockDigits:     cp.BlockDigits,
+		resumedAfter:    cp.Elapsed,
+	}
+	for _, b := range job.blocks {
+		job.StreamedDigits += len(b.Digits)
+	}
+
+	// New IDs continue after the resumed one
+	if i := strings.LastIndex(job.ID, "-"); i >= 0 {
+		if n, err := strconv.ParseInt(job.ID[i+1:], 10, 64); err == nil && n > jm.jobCounter {
+			jm.jobCounter = n
+		}
+	}
+
+	jm.jobs[job.ID] = job
+	jm.enqueueJob(job)
+	return job, nil
+}
+
+// Blocks returns the digit blocks of a job from index from on and a channel
+// that is closed when the next block arrives or the job ends
+func (jm *JobManager) Blocks(jobID string, from int) ([]DigitBlock, <-chan struct{}, error) {
+	job, err := jm.GetJob(jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+	jm.streamMu.Lock()
+	defer jm.streamMu.Unlock()
+	if job.blocksChanged == nil {
+		job.blocksChanged = make(chan struct{})
+	}
+	var blocks []DigitBlock
+	if from < len(job.blocks) {
+		blocks = append(blocks, job.blocks[from:]...)
+	}
+	return blocks, job.blocksChanged, nil
+}
+
+// addBlock publishes a block of a running job and saves its checkpoint
+func (jm *JobManager) addBlock(job *ComputeJob, block DigitBlock) error {
+	jm.streamMu.Lock()
+	defer jm.streamMu.Unlock()
+	job.blocks = append(job.blocks, block)
+	job.StreamedDigits += len(block.Digits)
+	if digits, ok := job.Parameters["digits"].(float64); ok && digits > 0 {
+		// 100 is reserved for the verified result
+		if p := min(99, job.StreamedDigits*100/int(digits)); p > job.Progress {
+			job.Progress = p
+		}
+	}
+	jm.notifyBlocks(job)
+	return jm.saveCheckpoint(job)
+}
+
+// saveCheckpoint saves the checkpoint of a job if there is a checkpoint
+// directory, streamMu is held
+func (jm *JobManager) saveCheckpoint(job *ComputeJob) error {
+	path := jm.CheckpointPath(job.ID)
+	if path == "" {
+		return nil
+	}
+	cp := &PICheckpoint{
+		Job:         job,
+		BlockDigits: job.blockDigits,
+		Blocks:      job.blocks,
+		Elapsed:     job.resumedAfter,
+		SavedAt:     time.Now(),
+	}
+	if cp.BlockDigits == 0 {
+		cp.BlockDigits = DefaultBlockDigits
+	}
+	if job.StartedAt != nil {
+		cp.Elapsed += time.Since(*job.StartedAt)
+	}
+	if err := cp.Save(path); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// endStream wakes the streams of a job that ended and drops its checkpoint
+func (jm *JobManager) endStream(job *ComputeJob) {
+	jm.streamMu.Lock()
+	jm.notifyBlocks(job)
+	jm.streamMu.Unlock()
+
+	if path := jm.CheckpointPath(job.ID); path != "" {
+		os.Remove(path)
+	}
+}
+
+// notifyBlocks wakes everyone waiting on the blocks of job, streamMu is held
+func (jm *JobManager) notifyBlocks(job *ComputeJob) {
+	if job.blocksChanged != nil {
+		close(job.blocksChanged)
+	}
+	job.blocksChanged = make(chan struct{})
+}
@@ -0,0 +1,85 @@
+package compute
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// SubscriptionMemo marks a transfer that prepays a monthly quota, the
+// overage policy may follow, e.g. SUBSCRIBE_PAY_PER_JOB
+const SubscriptionMemo = "SUBSCRIBE"
+
+// What happens to a job that does not fit the remaining quota
+const (
+	OverageReject    = "reject"      // the job is rejected until the next period
+	OveragePayPerJob = "pay_per_job" // the job needs its own payment_tx_hash
+)
+
+// Headers of a job submission drawn from a subscription. The signature is
+// made with the account key of client_address over SubscriptionSignBytes.
+const (
+	ClientPubKeyHeader    = "X-Client-PubKey"
+	ClientSignatureHeader = "X-Client-Signature"
+)
+
+// SubscriptionSignatureTTL is how long a signed submission is accepted
+const SubscriptionSignatureTTL = 5 * time.Minute
+
+// subscriptionSignDomain keeps the signature from being valid for anything else
+const subscriptionSignDomain = "medas-subscription-job-v1\n"
+
+// IsSubscriptionMemo reports whether a memo funds a subscription
+func IsSubscriptionMemo(memo string) bool {
+	_, err := ParseSubscriptionMemo(memo)
+	return err == nil
+}
+
+// ParseSubscriptionMemo returns the overage policy of a subscription memo,
+// OverageReject when the memo names none
+func ParseSubscriptionMemo(memo string) (string, error) {
+	memo = strings.ToUpper(strings.TrimSpace(memo))
+	if !strings.HasPrefix(memo, SubscriptionMemo) {
+		return "", fmt.Errorf("memo does not start with %s", SubscriptionMemo)
+	}
+	switch strings.TrimPrefix(memo, SubscriptionMemo) {
+	case "", "_REJECT":
+		return OverageReject, nil
+	case "_PAY_PER_JOB":
+		return OveragePayPerJob, nil
+	}
+	return "", fmt.Errorf("unknown subscription memo %q, use %s or %s_PAY_PER_JOB", memo, SubscriptionMemo, SubscriptionMemo)
+}
+
+// SubscriptionSignBytes is the digest a client signs for a request body
+func SubscriptionSignBytes(body []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(subscriptionSignDomain))
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// VerifySubscriptionSignature checks that body was signed by the account key
+// of address
+func VerifySubscriptionSignature(body, pubKey, signature []byte, address string) error {
+	if len(pubKey) != secp256k1.PubKeySize {
+		return fmt.Errorf("invalid public key")
+	}
+	pk := &secp256k1.PubKey{Key: pubKey}
+	addr, err := sdk.AccAddressFromBech32(address)
+	if err != nil {
+		return fmt.Errorf("invalid client address: %w", err)
+	}
+	if !bytes.Equal(pk.Address(), addr) {
+		return fmt.Errorf("public key does not belong to %s", address)
+	}
+	if !pk.VerifySignature(SubscriptionSignBytes(body), signature) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package compute
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// VerificationLevel selects how a paid result is checked before delivery
+type VerificationLevel string
+
+const (
+	VerificationNone          VerificationLevel = "none"
+	VerificationSpotCheck     VerificationLevel = "spot-check"
+	VerificationFullRecompute VerificationLevel = "full-recompute"
+	VerificationCommitment    VerificationLevel = "commitment"
+)
+
+// legacyVerificationLevels maps the names of earlier releases. Both levels
+// run on the executing host, so they were renamed after what they check.
+var legacyVerificationLevels = map[string]VerificationLevel{
+	"dual-provider": VerificationFullRecompute,
+	"zk-attested":   VerificationCommitment,
+}
+
+// spotCheckDigits is the prefix length recomputed for spot checks
+const spotCheckDigits = 100
+
+// verificationMultipliers are applied on top of the tier and method price
+var verificationMultipliers = map[VerificationLevel]float64{
+	VerificationNone:          1.0,
+	VerificationSpotCheck:     1.1, // 10% more (partial recomputation)
+	VerificationFullRecompute: 2.0, // Full second execution
+	VerificationCommitment:    1.0, // A hash, no extra computation
+}
+
+// verificationDescriptions are shown in the pricing information
+var verificationDescriptions = map[VerificationLevel]string{
+	VerificationNone:          "No verification, result delivered as computed",
+	VerificationSpotCheck:     "Leading digits recomputed with an independent algorithm",
+	VerificationFullRecompute: "Full result recomputed with an independent algorithm on the same host and compared",
+	VerificationCommitment:    "SHA-256 commitment over method, digits and value, no additional check",
+}
+
+// VerificationReport is attached to a job after its result was checked
+type VerificationReport struct {
+	Level         VerificationLevel `json:"level"`
+	Passed        bool              `json:"passed"`
+	Method        string            `json:"method"`
+	CheckedDigits int               `json:"checked_digits"`
+	Commitment    string            `json:"commitment,omitempty"`
+	Details       string            `json:"details"`
+	Duration      time.Duration     `json:"duration"`
+	VerifiedAt    time.Time         `json:"verified_at"`
+}
+
+// GetVerificationLevels returns all supported verification levels
+func GetVerificationLevels() []string {
+	return []string{
+		string(VerificationNone),
+		string(VerificationSpotCheck),
+		string(VerificationFullRecompute),
+		string(VerificationCommitment),
+	}
+}
+
+// ParseVerificationLevel validates a level string; empty means none
+func ParseVerificationLevel(s string) (VerificationLevel, error) {
+	if s == "" {
+		return VerificationNone, nil
+	}
+	if level, ok := legacyVerificationLevels[s]; ok {
+		return level, nil
+	}
+	level := VerificationLevel(s)
+	if _, ok := verificationMultipliers[level]; !ok {
+		return "", fmt.Errorf("unknown verification level: %s (use: none, spot-check, full-recompute, commitment)", s)
+	}
+	return level, nil
+}
+
+// VerificationMultiplier returns the price multiplier for a level
+func VerificationMultiplier(level VerificationLevel) float64 {
+	if m, ok := verificationMultipliers[level]; ok {
+		return m
+	}
+	return 1.0
+}
+
+// VerifyPIResult checks a PI result according to the requested level
+func VerifyPIResult(ctx context.Context, result *PIResult, level VerificationLevel) (*VerificationReport, error) {
+	start := time.Now()
+	report := &VerificationReport{
+		Level: level,
+	}
+
+	switch level {
+	case VerificationNone, "":
+		report.Level = VerificationNone
+		report.Passed = true
+		report.Method = "none"
+		report.Details = "verification not requested"
+
+	case VerificationSpotCheck:
+		digits := result.Digits
+		if digits > spotCheckDigits {
+			digits = spotCheckDigits
+		}
+		reference, err := NewPICalculator(digits, alternateMethod(result.Method, digits)).Calculate(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("spot-check recomputation failed: %w", err)
+		}
+		report.Method = "recompute_prefix_" + reference.Method
+		report.CheckedDigits = digits
+		report.Passed = len(result.Value) >= len(reference.Value) && result.Value[:len(reference.Value)] == reference.Value
+		report.Details = fmt.Sprintf("first %d digits compared against %s", digits, reference.Method)
+
+	case VerificationFullRecompute:
+		second, err := NewPICalculator(result.Digits, alternateMethod(result.Method, result.Digits)).Calculate(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("recomputation failed: %w", err)
+		}
+		report.Method = "independent_execution_" + second.Method
+		report.CheckedDigits = result.Digits
+		report.Passed = second.Value == result.Value
+		report.Details = fmt.Sprintf("full result compared with independent %s execution", second.Method)
+
+	case VerificationCommitment:
+		report.Method = "sha256_commitment"
+		report.Commitment = ResultCommitment(result)
+		report.Passed = result.Verified
+		report.Details = "commitment over method, digits and value, not independently checked; verify by recomputing the hash"
+
+	default:
+		return nil, fmt.Errorf("unknown verification level: %s", level)
+	}
+
+	report.Duration = time.Since(start)
+	report.VerifiedAt = time.Now()
+	return report, nil
+}
+
+// ResultCommitment returns the hex SHA-256 commitment for a PI result
+func ResultCommitment(result *PIResult) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%d|%s", result.Method, result.Digits, result.Value)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// alternateMethod picks a different algorithm suiting digits for independent
+// checks, the same one if no other suits
+func alternateMethod(method string, digits int) string {
+	for _, m := range piMethods {
+		if string(m.Name) != method && m.Suits(digits) {
+			return string(m.Name)
+		}
+	}
+	return method
+}
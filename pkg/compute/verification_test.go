@@ -0,0 +1,48 @@
+package compute
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLegacyVerificationLevelsQuotedAsCharged(t *testing.T) {
+	pm := NewPricingManager("medas1community")
+	jm := NewJobManager(10, 1, pm)
+	defer jm.Shutdown(5 * time.Second)
+
+	for alias, level := range legacyVerificationLevels {
+		quote, err := pm.CalculatePriceWithVerification(100, TierBasic, "chudnovsky", VerificationLevel(alias))
+		if err != nil {
+			t.Fatalf("%s: quote: %v", alias, err)
+		}
+		want, err := pm.CalculatePriceWithVerification(100, TierBasic, "chudnovsky", level)
+		if err != nil {
+			t.Fatalf("%s: quote %s: %v", alias, level, err)
+		}
+		if quote.TotalCost != want.TotalCost {
+			t.Errorf("%s quoted %v, %s costs %v", alias, quote.TotalCost, level, want.TotalCost)
+		}
+
+		params := map[string]interface{}{"digits": float64(100), "method": "chudnovsky"}
+		job, err := jm.SubmitJob(JobTypePICalculation, params, "medas1client", TierBasic, VerificationLevel(alias), "TX-"+alias)
+		if err != nil {
+			t.Fatalf("%s: submit: %v", alias, err)
+		}
+		if job.PriceBreakdown.TotalCost != quote.TotalCost {
+			t.Errorf("%s quoted %v, charged %v", alias, quote.TotalCost, job.PriceBreakdown.TotalCost)
+		}
+
+		// Planet 9 jobs are charged with the normalized level
+		p9, err := pm.CalculatePlanet9Price(100, 1000, TierBasic, VerificationLevel(alias))
+		if err != nil {
+			t.Fatalf("%s: planet 9 quote: %v", alias, err)
+		}
+		p9Want, err := pm.CalculatePlanet9Price(100, 1000, TierBasic, level)
+		if err != nil {
+			t.Fatalf("%s: planet 9 quote %s: %v", alias, level, err)
+		}
+		if p9.TotalCost != p9Want.TotalCost {
+			t.Errorf("%s: planet 9 quoted %v, %s costs %v", alias, p9.TotalCost, level, p9Want.TotalCost)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package computeclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+)
+
+// ErrNoArtifact is returned for jobs the service keeps no result file of
+var ErrNoArtifact = errors.New("job has no artifact")
+
+// DownloadArtifact saves the result file of a completed job to path. A
+// partial file of an earlier attempt is continued with a range request, a
+// dropped connection is picked up where it broke off. The file is checked
+// against the SHA-256 the job announces. Whole downloads are transferred
+// compressed.
+func (c *Client) DownloadArtifact(ctx context.Context, jobID, path string) error {
+	job, err := c.GetJob(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if job.Artifact == nil {
+		return fmt.Errorf("%s: %w", jobID, ErrNoArtifact)
+	}
+	artifact := job.Artifact
+
+	// The download lasts as long as it takes, only ctx ends it
+	httpClient := *c.opts.HTTPClient
+	httpClient.Timeout = 0
+
+	backoff := c.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		have, err := c.downloadOnce(ctx, &httpClient, jobID, path, artifact.SHA256)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode < 500 && apiErr.StatusCode != http.StatusTooManyRequests {
+			return err
+		}
+		if have > 0 {
+			// Progress was made, the connection dropped
+			attempt, backoff = 0, c.opts.Backoff
+		}
+		if attempt >= c.opts.Retries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+			backoff *= 2
+		}
+	}
+
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return err
+	}
+	if sum != artifact.SHA256 {
+		os.Remove(path)
+		return fmt.Errorf("artifact of job %s does not match its SHA-256, removed %s", jobID, path)
+	}
+	return nil
+}
+
+// downloadOnce continues the file at path with one request and returns how
+// many bytes it received
+func (c *Client) downloadOnce(ctx context.Context, httpClient *http.Client, jobID, path, sha string) (int64, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	apiPath := "/api/v1/jobs/" + url.PathEscape(jobID) + "/artifact"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+apiPath, nil)
+	if err != nil {
+		return 0, err
+	}
+	if offset > 0 {
+		// The whole file comes back if the artifact changed since
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		req.Header.Set("If-Range", `"`+sha+`"`)
+	} else {
+		// Whole artifacts come as stored
+		req.Header.Set("Accept-Encoding", compute.ArtifactEncoding)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("GET %s: %w", apiPath, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		if err := f.Truncate(0); err != nil {
+			return 0, err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return 0, err
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Complete already, or longer than the artifact and caught by the hash
+		return 0, nil
+	default:
+		data, _ := io.ReadAll(resp.Body)
+		return 0, &APIError{
+			StatusCode: resp.StatusCode,
+			Method:     http.MethodGet,
+			Path:       apiPath,
+			Message:    strings.TrimSpace(string(data)),
+		}
+	}
+
+	var body io.Reader = resp.Body
+	if resp.Header.Get("Content-Encoding") == compute.ArtifactEncoding {
+		dec, err := zstd.NewReader(resp.Body)
+		if err != nil {
+			return 0, err
+		}
+		defer dec.Close()
+		body = dec
+	}
+	n, err := io.Copy(f, body)
+	if err != nil {
+		return n, fmt.Errorf("GET %s: %w", apiPath, err)
+	}
+	return n, nil
+}
+
+// fileSHA256 is the hex SHA-256 of the file at path
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,155 @@
+// Package computeclient is a Go client for the HTTP API of the payment service
+// (payment-service command): price estimates, job submission and results.
+package computeclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options configure a Client, zero values use the defaults
+type Options struct {
+	HTTPClient   *http.Client
+	Retries      int           // attempts after the first one, default 3
+	Backoff      time.Duration // wait before the first retry, doubled each time, default 500ms
+	PollInterval time.Duration // how often job status is polled, default 1s
+}
+
+// Client talks to one payment service
+type Client struct {
+	baseURL string
+	opts    Options
+}
+
+// New creates a client for the service at baseURL, e.g. http://localhost:8080
+func New(baseURL string, opts Options) *Client {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if opts.Retries == 0 {
+		opts.Retries = 3
+	}
+	if opts.Retries < 0 {
+		opts.Retries = 0
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 500 * time.Millisecond
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), opts: opts}
+}
+
+// BaseURL returns the service URL
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, path, nil, nil, out, true)
+}
+
+// postJSON sends body; idempotent requests are also retried after network errors
+func (c *Client) postJSON(ctx context.Context, path string, body, out interface{}, idempotent bool) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, path, data, nil, out, idempotent)
+}
+
+// do sends the request with the extra header, retrying as attempt allows
+func (c *Client) do(ctx context.Context, method, path string, body []byte, header http.Header, out interface{}, idempotent bool) error {
+	backoff := c.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		wait, err := c.attempt(ctx, method, path, body, header, out, idempotent)
+		if err == nil || wait < 0 || attempt >= c.opts.Retries {
+			return err
+		}
+		if wait == 0 {
+			wait = backoff
+			backoff *= 2
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// attempt sends the request once. The returned wait is negative when the error
+// is final, zero for the default backoff or the Retry-After of the server.
+func (c *Client) attempt(ctx context.Context, method, path string, body []byte, header http.Header, out interface{}, idempotent bool) (time.Duration, error) {
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, rd)
+	if err != nil {
+		return -1, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := c.opts.HTTPClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil || !idempotent {
+			return -1, fmt.Errorf("%s %s: %w", method, path, err)
+		}
+		return 0, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &APIError{
+			StatusCode: resp.StatusCode,
+			Method:     method,
+			Path:       path,
+			Message:    strings.TrimSpace(string(data)),
+		}
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			// Rejected before processing, safe to retry any request
+			return retryAfter(resp.Header.Get("Retry-After")), apiErr
+		case http.StatusBadGateway, http.StatusGatewayTimeout, http.StatusInternalServerError:
+			if idempotent {
+				return 0, apiErr
+			}
+		}
+		return -1, apiErr
+	}
+
+	if out == nil {
+		return -1, nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return -1, fmt.Errorf("%s %s: invalid response: %w", method, path, err)
+	}
+	return -1, nil
+}
+
+// retryAfter parses the seconds form of a Retry-After header
+func retryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
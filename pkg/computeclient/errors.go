@@ -0,0 +1,71 @@
+package computeclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+)
+
+// Errors an APIError matches with errors.Is
+var (
+	ErrBadRequest      = errors.New("bad request")
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("conflict") // e.g. the payment was already used for a job
+	ErrRateLimited     = errors.New("rate limited")
+	ErrForbidden       = errors.New("forbidden")
+	ErrUnavailable     = errors.New("service unavailable")
+	ErrPaymentRequired = errors.New("payment required") // e.g. the subscription quota is used up
+)
+
+// APIError is a non-200 response of the service
+type APIError struct {
+	StatusCode int
+	Method     string
+	Path       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if len(msg) > 200 {
+		msg = msg[:200] + "..."
+	}
+	return fmt.Sprintf("%s %s: HTTP %d: %s", e.Method, e.Path, e.StatusCode, msg)
+}
+
+// Is maps the status code to the sentinel errors
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrForbidden:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrPaymentRequired:
+		return e.StatusCode == http.StatusPaymentRequired
+	case ErrUnavailable:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// JobFailedError is returned when a job ends failed or cancelled
+type JobFailedError struct {
+	JobID  string
+	Status compute.JobStatus
+	Reason string
+}
+
+func (e *JobFailedError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("job %s %s", e.JobID, e.Status)
+	}
+	return fmt.Sprintf("job %s %s: %s", e.JobID, e.Status, e.Reason)
+}
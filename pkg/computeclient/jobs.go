@@ -0,0 +1,208 @@
+package computeclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+)
+
+// EstimateRequest asks for the price of a PI calculation
+type EstimateRequest struct {
+	Digits       int                       `json:"digits"`
+	Method       string                    `json:"method,omitempty"`
+	Tier         compute.ServiceTier       `json:"tier,omitempty"`
+	Verification compute.VerificationLevel `json:"verification,omitempty"`
+}
+
+// PaymentOption is the price in one accepted denom
+type PaymentOption struct {
+	Denom   string `json:"denom"`
+	Symbol  string `json:"symbol"`
+	Channel string `json:"channel,omitempty"`
+	Amount  int64  `json:"amount"`            // base units
+	Display string `json:"display,omitempty"` // e.g. 0.250000 MEDAS
+}
+
+// PaymentInfo tells the client where and how to pay
+type PaymentInfo struct {
+	ServiceAddress   string          `json:"service_address"`
+	CommunityAddress string          `json:"community_address"`
+	MemoSuggested    string          `json:"memo_suggested"`
+	ChainID          string          `json:"chain_id"`
+	PaymentOptions   []PaymentOption `json:"payment_options"`
+}
+
+// Estimate is the price of a job and the payment details
+type Estimate struct {
+	PriceBreakdown *compute.PriceBreakdown    `json:"price_breakdown"`
+	MethodInfo     *compute.PICalculationInfo `json:"method_info"`
+	PaymentInfo    PaymentInfo                `json:"payment_info"`
+}
+
+// JobRequest submits a job paid by PaymentTxHash or from a subscription
+type JobRequest struct {
+	Type          compute.JobType           `json:"type"`
+	Parameters    map[string]interface{}    `json:"parameters"`
+	Tier          compute.ServiceTier       `json:"tier,omitempty"`
+	Verification  compute.VerificationLevel `json:"verification,omitempty"`
+	PaymentTxHash string                    `json:"payment_tx_hash"`
+	ClientAddress string                    `json:"client_address"`
+	Denom         string                    `json:"denom,omitempty"`         // an accepted ibc/... denom, empty for MEDAS
+	ClientJobID   string                    `json:"client_job_id,omitempty"` // idempotency key, SubmitJob sets a random one
+	Subscription  bool                      `json:"subscription,omitempty"`  // set by SubmitSubscriptionJob
+	SignedAt      *time.Time                `json:"signed_at,omitempty"`
+}
+
+// Submission is the answer to a job submission
+type Submission struct {
+	JobID          string                  `json:"job_id"`
+	Status         compute.JobStatus       `json:"status"`
+	SubmittedAt    time.Time               `json:"submitted_at"`
+	PriceBreakdown *compute.PriceBreakdown `json:"price_breakdown"`
+	Message        string                  `json:"message"`
+}
+
+// JobFilter narrows ListJobs, empty fields are not filtered on
+type JobFilter struct {
+	ClientAddress string
+	Status        compute.JobStatus
+	Limit         int
+}
+
+// Progress is a status update of a job
+type Progress struct {
+	JobID           string
+	Status          compute.JobStatus
+	Percent         int
+	PaymentVerified bool
+}
+
+// EstimateCost returns the price of a PI calculation
+func (c *Client) EstimateCost(ctx context.Context, req EstimateRequest) (*Estimate, error) {
+	var est Estimate
+	if err := c.postJSON(ctx, "/api/v1/pricing/estimate", req, &est, true); err != nil {
+		return nil, err
+	}
+	return &est, nil
+}
+
+// SubmitJob submits a paid job. The request carries a client job ID, so it
+// is retried after network errors: the service answers a retry with the
+// original submission instead of creating a second job.
+func (c *Client) SubmitJob(ctx context.Context, req JobRequest) (*Submission, error) {
+	if req.ClientJobID == "" {
+		id, err := newClientJobID()
+		if err != nil {
+			return nil, err
+		}
+		req.ClientJobID = id
+	}
+	var sub Submission
+	if err := c.postJSON(ctx, "/api/v1/jobs/submit", req, &sub, true); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// GetJob returns the current state of a job
+func (c *Client) GetJob(ctx context.Context, jobID string) (*compute.ComputeJob, error) {
+	var job compute.ComputeJob
+	if err := c.getJSON(ctx, "/api/v1/jobs/"+url.PathEscape(jobID), &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListJobs returns the jobs known to the service
+func (c *Client) ListJobs(ctx context.Context, filter JobFilter) ([]compute.ComputeJob, error) {
+	q := url.Values{}
+	if filter.ClientAddress != "" {
+		q.Set("client_address", filter.ClientAddress)
+	}
+	if filter.Status != "" {
+		q.Set("status", string(filter.Status))
+	}
+	if filter.Limit > 0 {
+		q.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	path := "/api/v1/jobs"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+
+	var resp struct {
+		Jobs []compute.ComputeJob `json:"jobs"`
+	}
+	if err := c.getJSON(ctx, path, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Jobs, nil
+}
+
+// PaymentJob returns the job created for a payment
+func (c *Client) PaymentJob(ctx context.Context, txHash string) (*compute.ComputeJob, error) {
+	var job compute.ComputeJob
+	if err := c.getJSON(ctx, "/api/v1/payments/"+url.PathEscape(txHash), &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// CancelJob cancels a queued or running job
+func (c *Client) CancelJob(ctx context.Context, jobID string) error {
+	return c.postJSON(ctx, "/api/v1/jobs/"+url.PathEscape(jobID)+"/cancel", struct{}{}, nil, true)
+}
+
+// WaitForResult polls the job until it completed with a verified payment and
+// returns it. A failed or cancelled job returns a *JobFailedError.
+func (c *Client) WaitForResult(ctx context.Context, jobID string) (*compute.ComputeJob, error) {
+	return c.StreamProgress(ctx, jobID, nil)
+}
+
+// StreamProgress is WaitForResult that calls fn whenever status, progress or
+// payment verification of the job change
+func (c *Client) StreamProgress(ctx context.Context, jobID string, fn func(Progress)) (*compute.ComputeJob, error) {
+	var last Progress
+	for {
+		job, err := c.GetJob(ctx, jobID)
+		if err != nil {
+			return nil, err
+		}
+
+		p := Progress{JobID: job.ID, Status: job.Status, Percent: job.Progress, PaymentVerified: job.PaymentVerified}
+		if fn != nil && p != last {
+			fn(p)
+		}
+		last = p
+
+		switch job.Status {
+		case compute.StatusCompleted:
+			// Payment verification runs concurrently with the computation
+			if job.PaymentVerified {
+				return job, nil
+			}
+		case compute.StatusFailed, compute.StatusCancelled:
+			return job, &JobFailedError{JobID: job.ID, Status: job.Status, Reason: job.Error}
+		}
+
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-time.After(c.opts.PollInterval):
+		}
+	}
+}
+
+// newClientJobID returns a random idempotency key
+func newClientJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,135 @@
+package computeclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+)
+
+// StreamDigits reads the digit blocks of a PI job from index from on and calls
+// fn for each block in order. A dropped connection is picked up again after
+// the last block received. It returns when the job ended: nil once it
+// completed, a *JobFailedError when it failed or was cancelled.
+func (c *Client) StreamDigits(ctx context.Context, jobID string, from int, fn func(compute.DigitBlock) error) error {
+	// The stream lasts as long as the job, only ctx ends it
+	httpClient := *c.opts.HTTPClient
+	httpClient.Timeout = 0
+
+	backoff := c.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		next, end, err := c.streamOnce(ctx, &httpClient, jobID, from, fn)
+		var cbErr *callbackError
+		if errors.As(err, &cbErr) {
+			return cbErr.err
+		}
+		if end != nil {
+			return end.err()
+		}
+		if next > from {
+			// Progress was made, the connection dropped
+			attempt, backoff = 0, c.opts.Backoff
+		}
+		from = next
+		if err == nil {
+			err = fmt.Errorf("stream of job %s ended early", jobID)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode < 500 && apiErr.StatusCode != http.StatusTooManyRequests {
+			return err
+		}
+		if attempt >= c.opts.Retries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+			backoff *= 2
+		}
+	}
+}
+
+// streamEnd is the final event of a stream
+type streamEnd struct {
+	jobID  string
+	status compute.JobStatus
+	reason string
+}
+
+func (e *streamEnd) err() error {
+	if e.status == compute.StatusCompleted {
+		return nil
+	}
+	return &JobFailedError{JobID: e.jobID, Status: e.status, Reason: e.reason}
+}
+
+// callbackError is an error of the caller's fn, it ends the stream
+type callbackError struct {
+	err error
+}
+
+func (e *callbackError) Error() string {
+	return e.err.Error()
+}
+
+// streamOnce reads one connection of the stream and returns the index of the
+// next block, and the end of the job if the stream got that far
+func (c *Client) streamOnce(ctx context.Context, httpClient *http.Client, jobID string, from int, fn func(compute.DigitBlock) error) (int, *streamEnd, error) {
+	path := "/api/v1/jobs/" + url.PathEscape(jobID) + "/stream?from=" + strconv.Itoa(from)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return from, nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return from, nil, fmt.Errorf("GET %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return from, nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Method:     http.MethodGet,
+			Path:       path,
+			Message:    strings.TrimSpace(string(data)),
+		}
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var event compute.StreamEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return from, nil, fmt.Errorf("GET %s: invalid event: %w", path, err)
+		}
+		switch {
+		case event.Block != nil:
+			if event.Block.Index < from {
+				continue
+			}
+			if event.Block.Index != from {
+				return from, nil, fmt.Errorf("GET %s: expected block %d, got %d", path, from, event.Block.Index)
+			}
+			if err := fn(*event.Block); err != nil {
+				return from, nil, &callbackError{err}
+			}
+			from++
+		case event.Status != "":
+			return from, &streamEnd{jobID: jobID, status: event.Status, reason: event.Error}, nil
+		}
+	}
+	return from, nil, scanner.Err()
+}
@@ -0,0 +1,85 @@
+package computeclient
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+
+	"github.com/oxygene76/medasdigital-client/pkg/compute"
+)
+
+// SignFunc signs with the account key of the client, see keyring.Sign
+type SignFunc func(msg []byte) ([]byte, cryptotypes.PubKey, error)
+
+// SubscriptionPeriod is one prepaid month of quota, amounts in MEDAS
+type SubscriptionPeriod struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Quota     float64   `json:"quota"`
+	Used      float64   `json:"used"`
+	PaymentTx string    `json:"payment_tx_hash"`
+	Paid      string    `json:"paid"`
+}
+
+// Subscription is the prepaid quota of a client
+type Subscription struct {
+	Address   string               `json:"address"`
+	Active    bool                 `json:"active"`
+	Overage   string               `json:"overage"` // compute.OverageReject or compute.OveragePayPerJob
+	Current   *SubscriptionPeriod  `json:"current"`
+	Remaining float64              `json:"remaining"`
+	Renewals  []SubscriptionPeriod `json:"renewals"`
+	RenewsAt  *time.Time           `json:"renews_at"`
+	ExpiresAt *time.Time           `json:"expires_at"`
+}
+
+// GetSubscription returns the quota, renewals and overage policy of a client
+func (c *Client) GetSubscription(ctx context.Context, address string) (*Subscription, error) {
+	var sub Subscription
+	if err := c.getJSON(ctx, "/api/v1/subscriptions/"+url.PathEscape(address), &sub); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// SubmitSubscriptionJob submits a job paid from the subscription of
+// req.ClientAddress. The request is signed with sign, the key of that
+// address. A job that does not fit the quota fails with ErrPaymentRequired.
+func (c *Client) SubmitSubscriptionJob(ctx context.Context, req JobRequest, sign SignFunc) (*Submission, error) {
+	if req.ClientJobID == "" {
+		id, err := newClientJobID()
+		if err != nil {
+			return nil, err
+		}
+		req.ClientJobID = id
+	}
+	signedAt := time.Now().UTC()
+	req.Subscription = true
+	req.SignedAt = &signedAt
+	req.PaymentTxHash = ""
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	signature, pubKey, err := sign(compute.SubscriptionSignBytes(body))
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	header.Set(compute.ClientPubKeyHeader, base64.StdEncoding.EncodeToString(pubKey.Bytes()))
+	header.Set(compute.ClientSignatureHeader, base64.StdEncoding.EncodeToString(signature))
+
+	// The body is signed once, retries send it unchanged within the
+	// signature's lifetime and are answered by the client job ID
+	var sub Submission
+	if err := c.do(ctx, http.MethodPost, "/api/v1/jobs/submit", body, header, &sub, true); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
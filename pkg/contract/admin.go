@@ -0,0 +1,275 @@
+package contract
+
+import (
+    "context"
+    "crypto/subtle"
+    "encoding/json"
+    "fmt"
+    "log"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// maxJobLogLines is how many log lines the node keeps per job
+const maxJobLogLines = 200
+
+// maxLoggedJobs is how many jobs the node keeps log lines for, the oldest are
+// dropped first
+const maxLoggedJobs = 500
+
+// JobLogLine is a log message about one job
+type JobLogLine struct {
+    Time    time.Time `json:"time"`
+    Message string    `json:"message"`
+}
+
+// JobView is a job as the admin API shows it
+type JobView struct {
+    Contract string       `json:"contract"`
+    JobID    uint64       `json:"job_id"`
+    State    string       `json:"state"`
+    Progress int          `json:"progress"`
+    Active   *ActiveJob   `json:"active,omitempty"` // set while computing
+    Stored   *StoredJob   `json:"stored,omitempty"`
+    Log      []JobLogLine `json:"log,omitempty"`
+}
+
+// SetAdminToken enables the /admin endpoints for requests carrying the
+// token as bearer token
+func (p *ProviderNode) SetAdminToken(token string) {
+    p.adminToken = token
+}
+
+// Pause stops taking new jobs: directly assigned jobs are failed right away
+// so the client gets the escrow back, bidders stop bidding. Running jobs finish.
+func (p *ProviderNode) Pause() {
+    p.paused.Store(true)
+    for _, mc := range p.marketContracts() {
+        if mc.bidder != nil {
+            mc.bidder.SetPaused(true)
+        }
+    }
+    log.Printf("⏸️  Job acceptance paused")
+}
+
+// Resume takes new jobs again
+func (p *ProviderNode) Resume() {
+    p.paused.Store(false)
+    for _, mc := range p.marketContracts() {
+        if mc.bidder != nil {
+            mc.bidder.SetPaused(false)
+        }
+    }
+    log.Printf("▶️  Job acceptance resumed")
+}
+
+// Paused reports whether job acceptance is paused
+func (p *ProviderNode) Paused() bool {
+    return p.paused.Load()
+}
+
+// acceptJob runs a newly assigned job unless acceptance is paused
+func (p *ProviderNode) acceptJob(ctx context.Context, contractAddr string, jobID uint64) {
+    if p.Paused() {
+        p.jobLog(contractAddr, jobID, "⏸️  Job %d declined, job acceptance is paused", jobID)
+        p.failJob(contractAddr, jobID, "Provider is not accepting jobs right now")
+        return
+    }
+    p.processJob(ctx, contractAddr, jobID)
+}
+
+// jobLog logs a message and keeps it with the job for the admin API
+func (p *ProviderNode) jobLog(contractAddr string, jobID uint64, format string, args ...interface{}) {
+    msg := fmt.Sprintf(format, args...)
+    log.Print(msg)
+
+    p.logsMu.Lock()
+    defer p.logsMu.Unlock()
+    if p.jobLogs == nil {
+        p.jobLogs = make(map[string][]JobLogLine)
+    }
+    key := jobKey(contractAddr, jobID)
+    if _, ok := p.jobLogs[key]; !ok {
+        p.jobLogOrder = append(p.jobLogOrder, key)
+        for len(p.jobLogOrder) > maxLoggedJobs {
+            delete(p.jobLogs, p.jobLogOrder[0])
+            p.jobLogOrder = p.jobLogOrder[1:]
+        }
+    }
+    lines := append(p.jobLogs[key], JobLogLine{Time: time.Now().UTC(), Message: msg})
+    if len(lines) > maxJobLogLines {
+        lines = lines[len(lines)-maxJobLogLines:]
+    }
+    p.jobLogs[key] = lines
+}
+
+func (p *ProviderNode) jobLogLines(contractAddr string, jobID uint64) []JobLogLine {
+    p.logsMu.Lock()
+    defer p.logsMu.Unlock()
+    return append([]JobLogLine(nil), p.jobLogs[jobKey(contractAddr, jobID)]...)
+}
+
+// Jobs lists the stored and running jobs, newest first, optionally only those in state
+func (p *ProviderNode) Jobs(state string) ([]JobView, error) {
+    views := map[string]*JobView{}
+    var order []string
+    if p.store != nil {
+        stored, err := p.store.List()
+        if err != nil {
+            return nil, err
+        }
+        for _, j := range stored {
+            key := jobKey(j.Contract, j.JobID)
+            views[key] = &JobView{Contract: j.Contract, JobID: j.JobID, State: j.State, Progress: j.Progress, Stored: j}
+            order = append(order, key)
+        }
+    }
+    for _, a := range p.ActiveJobs() {
+        a := a
+        key := jobKey(a.Contract, a.JobID)
+        v, ok := views[key]
+        if !ok {
+            v = &JobView{Contract: a.Contract, JobID: a.JobID, State: StoredRunning}
+            views[key] = v
+            order = append(order, key)
+        }
+        v.Active = &a
+    }
+
+    jobs := make([]JobView, 0, len(order))
+    for i := len(order) - 1; i >= 0; i-- {
+        v := views[order[i]]
+        if state == "" || v.State == state {
+            jobs = append(jobs, *v)
+        }
+    }
+    return jobs, nil
+}
+
+// RetryCompletion broadcasts complete_job again for a computed job whose
+// completion did not make it on chain
+func (p *ProviderNode) RetryCompletion(ctx context.Context, contractAddr string, jobID uint64) error {
+    job := p.storedJob(contractAddr, jobID)
+    if job == nil {
+        return fmt.Errorf("job %d of %s is not stored", jobID, contractAddr)
+    }
+    if job.State != StoredComputed {
+        return fmt.Errorf("job %d is %s, only computed jobs can be completed again", jobID, job.State)
+    }
+    p.jobLog(contractAddr, jobID, "🔁 Retrying complete_job for job %d (manual)", jobID)
+    p.persist(contractAddr, jobID, func(j *StoredJob) { j.Attempts++ })
+    if err := p.submitResult(ctx, contractAddr, jobID, job.ResultHash, job.ResultURL, job.Attestation, job.Payment); err != nil {
+        p.jobLog(contractAddr, jobID, "❌ Retry of job %d failed: %v", jobID, err)
+        return err
+    }
+    p.jobLog(contractAddr, jobID, "Job %d completed successfully", jobID)
+    return nil
+}
+
+// AdminAuthorized reports whether r carries token as bearer token and answers
+// the request if not. An empty token disables the admin API, server names what
+// to start with --admin-token.
+func AdminAuthorized(w http.ResponseWriter, r *http.Request, token, server string) bool {
+    if token == "" {
+        http.Error(w, "Admin API is disabled, start the "+server+" with --admin-token", http.StatusForbidden)
+        return false
+    }
+    bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+    if subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+        http.Error(w, "Unauthorized", http.StatusUnauthorized)
+        return false
+    }
+    return true
+}
+
+// adminOnly requires the admin token as bearer token, admin routes are off without one
+func (p *ProviderNode) adminOnly(next http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if AdminAuthorized(w, r, p.adminToken, "node") {
+            next(w, r)
+        }
+    }
+}
+
+// registerAdminRoutes adds the /admin endpoints to the provider mux
+func (p *ProviderNode) registerAdminRoutes(mux *http.ServeMux) {
+    mux.HandleFunc("GET /admin/status", p.adminOnly(func(w http.ResponseWriter, r *http.Request) {
+        writeJSON(w, http.StatusOK, map[string]interface{}{
+            "paused":      p.Paused(),
+            "active_jobs": p.ActiveJobs(),
+            "contracts":   p.Contracts(),
+        })
+    }))
+
+    mux.HandleFunc("GET /admin/jobs", p.adminOnly(func(w http.ResponseWriter, r *http.Request) {
+        jobs, err := p.Jobs(r.URL.Query().Get("state"))
+        if err != nil {
+            writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+            return
+        }
+        writeJSON(w, http.StatusOK, map[string]interface{}{"jobs": jobs, "count": len(jobs)})
+    }))
+
+    mux.HandleFunc("GET /admin/jobs/{contract}/{id}", p.adminOnly(func(w http.ResponseWriter, r *http.Request) {
+        contractAddr, jobID, ok := adminJobRef(w, r)
+        if !ok {
+            return
+        }
+        jobs, err := p.Jobs("")
+        if err != nil {
+            writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+            return
+        }
+        view := JobView{Contract: contractAddr, JobID: jobID}
+        for _, j := range jobs {
+            if j.Contract == contractAddr && j.JobID == jobID {
+                view = j
+            }
+        }
+        view.Log = p.jobLogLines(contractAddr, jobID)
+        if view.State == "" && len(view.Log) == 0 {
+            writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not known to this node"})
+            return
+        }
+        writeJSON(w, http.StatusOK, view)
+    }))
+
+    mux.HandleFunc("POST /admin/jobs/{contract}/{id}/retry", p.adminOnly(func(w http.ResponseWriter, r *http.Request) {
+        contractAddr, jobID, ok := adminJobRef(w, r)
+        if !ok {
+            return
+        }
+        if err := p.RetryCompletion(r.Context(), contractAddr, jobID); err != nil {
+            writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+            return
+        }
+        writeJSON(w, http.StatusOK, p.storedJob(contractAddr, jobID))
+    }))
+
+    mux.HandleFunc("POST /admin/pause", p.adminOnly(func(w http.ResponseWriter, r *http.Request) {
+        p.Pause()
+        writeJSON(w, http.StatusOK, map[string]bool{"paused": true})
+    }))
+
+    mux.HandleFunc("POST /admin/resume", p.adminOnly(func(w http.ResponseWriter, r *http.Request) {
+        p.Resume()
+        writeJSON(w, http.StatusOK, map[string]bool{"paused": false})
+    }))
+}
+
+func adminJobRef(w http.ResponseWriter, r *http.Request) (string, uint64, bool) {
+    jobID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+    if err != nil {
+        writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid job id"})
+        return "", 0, false
+    }
+    return r.PathValue("contract"), jobID, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,143 @@
+package contract
+
+import (
+    "time"
+
+    "github.com/oxygene76/medasdigital-client/pkg/compute"
+    "github.com/oxygene76/medasdigital-client/pkg/openapi"
+)
+
+// apiVersion is the version of the provider node HTTP API
+const apiVersion = "1.0.0"
+
+// The responses Handler builds as maps, keep them in step with the handlers
+
+type healthResponse struct {
+    Status    string           `json:"status"` // healthy, or unhealthy with a 503
+    Provider  string           `json:"provider"`
+    Contract  string           `json:"contract"`
+    Contracts []ContractStatus `json:"contracts"`
+    Heartbeat struct {
+        LastSent   string `json:"last_sent"`
+        SecondsAgo int    `json:"seconds_ago"`
+        MinutesAgo int    `json:"minutes_ago"`
+        Active     bool   `json:"active"`
+        NextIn     string `json:"next_in"`
+    } `json:"heartbeat"`
+    ActiveJobs         int  `json:"active_jobs"`
+    AcceptingJobs      bool `json:"accepting_jobs"`
+    WebsocketConnected bool `json:"websocket_connected"`
+    ReconnectAttempts  int  `json:"reconnect_attempts"`
+}
+
+type resultResponse struct {
+    JobID       string                 `json:"job_id"`
+    Status      compute.JobStatus      `json:"status"`
+    Result      interface{}            `json:"result"`
+    Duration    string                 `json:"duration"`
+    CompletedAt *time.Time             `json:"completed_at"`
+    Tier        compute.ServiceTier    `json:"tier"`
+    Parameters  map[string]interface{} `json:"parameters"`
+    Attestation *Attestation           `json:"attestation,omitempty"`
+}
+
+type errorResponse struct {
+    Error string `json:"error"`
+    JobID string `json:"job_id,omitempty"`
+}
+
+type adminStatusResponse struct {
+    Paused     bool             `json:"paused"`
+    ActiveJobs int              `json:"active_jobs"`
+    Contracts  []ContractStatus `json:"contracts"`
+}
+
+type adminJobsResponse struct {
+    Jobs  []JobView `json:"jobs"`
+    Count int       `json:"count"`
+}
+
+type pausedResponse struct {
+    Paused bool `json:"paused"`
+}
+
+// apiDocument describes the routes of Handler
+func (p *ProviderNode) apiDocument() *openapi.Document {
+    doc := openapi.New("MEDAS Provider Node API", apiVersion,
+        "Health and results of a compute provider node, and the admin API to inspect and control its jobs.")
+    admin := doc.BearerAuth("adminToken", "The --admin-token of the node")
+    adminErrors := func(responses map[string]*openapi.Response) map[string]*openapi.Response {
+        responses["401"] = openapi.Text("Wrong token")
+        responses["403"] = openapi.Text("The admin API is disabled")
+        return responses
+    }
+    jobParams := []openapi.Parameter{
+        {Name: "contract", In: "path", Required: true, Description: "Marketplace contract address", Schema: &openapi.Schema{Type: "string"}},
+        {Name: "id", In: "path", Required: true, Description: "Job ID in the contract", Schema: &openapi.Schema{Type: "integer", Format: "int64"}},
+    }
+    failed := doc.JSON("Error", errorResponse{})
+
+    doc.Add("GET", "/health", &openapi.Operation{
+        Summary: "Heartbeat, connection and job status",
+        Tags:    []string{"node"},
+        Responses: map[string]*openapi.Response{
+            "200": doc.JSON("Healthy", healthResponse{}),
+            "503": doc.JSON("No heartbeat within 24 hours", healthResponse{}),
+        },
+    })
+    doc.Add("GET", "/results/{job}.json", &openapi.Operation{
+        Summary:   "Result of a job computed by this node, with its attestation",
+        Tags:      []string{"node"},
+        Responses: map[string]*openapi.Response{"200": doc.JSON("Result", resultResponse{}), "404": doc.JSON("Unknown job", errorResponse{})},
+    })
+
+    doc.Add("GET", "/admin/status", &openapi.Operation{
+        Summary:   "Whether jobs are accepted, active jobs and contracts",
+        Tags:      []string{"admin"},
+        Security:  admin,
+        Responses: adminErrors(map[string]*openapi.Response{"200": doc.JSON("Status", adminStatusResponse{})}),
+    })
+    doc.Add("GET", "/admin/jobs", &openapi.Operation{
+        Summary:    "Jobs of the node",
+        Tags:       []string{"admin"},
+        Security:   admin,
+        Parameters: []openapi.Parameter{openapi.Query("state", "string", "Only jobs in this state")},
+        Responses:  adminErrors(map[string]*openapi.Response{"200": doc.JSON("Jobs", adminJobsResponse{}), "500": failed}),
+    })
+    doc.Add("GET", "/admin/jobs/{contract}/{id}", &openapi.Operation{
+        Summary:    "A job with its log",
+        Tags:       []string{"admin"},
+        Security:   admin,
+        Parameters: jobParams,
+        Responses: adminErrors(map[string]*openapi.Response{
+            "200": doc.JSON("Job", JobView{}),
+            "400": failed,
+            "404": failed,
+            "500": failed,
+        }),
+    })
+    doc.Add("POST", "/admin/jobs/{contract}/{id}/retry", &openapi.Operation{
+        Summary:    "Broadcast the completion of a computed job again",
+        Tags:       []string{"admin"},
+        Security:   admin,
+        Parameters: jobParams,
+        Responses: adminErrors(map[string]*openapi.Response{
+            "200": doc.JSON("Stored job", StoredJob{}),
+            "400": failed,
+            "409": failed,
+        }),
+    })
+    doc.Add("POST", "/admin/pause", &openapi.Operation{
+        Summary:   "Stop taking new jobs, running jobs finish",
+        Tags:      []string{"admin"},
+        Security:  admin,
+        Responses: adminErrors(map[string]*openapi.Response{"200": doc.JSON("Paused", pausedResponse{})}),
+    })
+    doc.Add("POST", "/admin/resume", &openapi.Operation{
+        Summary:   "Take new jobs again",
+        Tags:      []string{"admin"},
+        Security:  admin,
+        Responses: adminErrors(map[string]*openapi.Response{"200": doc.JSON("Resumed", pausedResponse{})}),
+    })
+    return doc
+}
@@ -0,0 +1,106 @@
+package contract
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "encoding/binary"
+    "encoding/hex"
+    "fmt"
+    "time"
+
+    "github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+    cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+    sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// attestationDomain keeps attestation signatures apart from other signed bytes
+const attestationDomain = "medas-attestation-v1"
+
+// SignFunc signs with the provider's account key, see keyring.Sign
+type SignFunc func(msg []byte) ([]byte, cryptotypes.PubKey, error)
+
+// Attestation is the receipt a provider signs for a completed job. It binds
+// the result hash to the job, its parameters and the provider account, so a
+// client can prove which provider produced which result.
+type Attestation struct {
+    JobID       uint64    `json:"job_id"`
+    Provider    string    `json:"provider"`
+    JobType     string    `json:"job_type"`
+    ResultHash  string    `json:"result_hash"`
+    ParamsHash  string    `json:"params_hash"` // see ParamsHash
+    StartedAt   time.Time `json:"started_at"`
+    CompletedAt time.Time `json:"completed_at"`
+    PubKey      []byte    `json:"pub_key"`
+    Signature   []byte    `json:"signature"`
+}
+
+// ParamsHash hashes the job parameters as the contract stores them
+func ParamsHash(parameters string) string {
+    sum := sha256.Sum256([]byte(parameters))
+    return hex.EncodeToString(sum[:])
+}
+
+// signBytes is the digest the provider signs
+func (a *Attestation) signBytes() []byte {
+    var buf bytes.Buffer
+    buf.WriteString(attestationDomain)
+    binary.Write(&buf, binary.BigEndian, a.JobID)
+    for _, field := range []string{a.Provider, a.JobType, a.ResultHash, a.ParamsHash} {
+        binary.Write(&buf, binary.BigEndian, uint32(len(field)))
+        buf.WriteString(field)
+    }
+    binary.Write(&buf, binary.BigEndian, a.StartedAt.UnixNano())
+    binary.Write(&buf, binary.BigEndian, a.CompletedAt.UnixNano())
+    sum := sha256.Sum256(buf.Bytes())
+    return sum[:]
+}
+
+// Sign adds the provider signature
+func (a *Attestation) Sign(sign SignFunc) error {
+    sig, pub, err := sign(a.signBytes())
+    if err != nil {
+        return fmt.Errorf("failed to sign attestation: %w", err)
+    }
+    if _, ok := pub.(*secp256k1.PubKey); !ok {
+        return fmt.Errorf("unsupported account key type %s", pub.Type())
+    }
+    a.PubKey = pub.Bytes()
+    a.Signature = sig
+    return nil
+}
+
+// Verify checks that the attestation was signed by the account in Provider
+func (a *Attestation) Verify() error {
+    if len(a.PubKey) != secp256k1.PubKeySize {
+        return fmt.Errorf("invalid provider public key")
+    }
+    pub := &secp256k1.PubKey{Key: a.PubKey}
+    if addr := sdk.AccAddress(pub.Address()).String(); addr != a.Provider {
+        return fmt.Errorf("public key belongs to %s, not %s", addr, a.Provider)
+    }
+    if !pub.VerifySignature(a.signBytes(), a.Signature) {
+        return fmt.Errorf("invalid signature")
+    }
+    return nil
+}
+
+// Check verifies the signature and that the attestation covers the job as
+// the contract recorded it
+func (a *Attestation) Check(job *ContractJob) error {
+    if err := a.Verify(); err != nil {
+        return err
+    }
+    if a.JobID != job.ID {
+        return fmt.Errorf("attestation is for job %d, not %d", a.JobID, job.ID)
+    }
+    if job.Provider != "" && a.Provider != job.Provider {
+        return fmt.Errorf("attestation is signed by %s, the job was run by %s", a.Provider, job.Provider)
+    }
+    if job.ResultHash != "" && a.ResultHash != job.ResultHash {
+        return fmt.Errorf("attested result hash %s differs from the anchored %s", a.ResultHash, job.ResultHash)
+    }
+    if a.ParamsHash != ParamsHash(job.Parameters) {
+        return fmt.Errorf("attested parameters differ from the job parameters")
+    }
+    return nil
+}
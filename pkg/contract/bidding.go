@@ -0,0 +1,296 @@
+package contract
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "log"
+    "math"
+    "strconv"
+    "sync"
+    "time"
+)
+
+// Bidding strategies
+const (
+    BidStrategyAccept = "accept" // accept open jobs that pay at least the floor
+    BidStrategyBid    = "bid"    // place a bid at floor plus markup, capped at the job's max price
+)
+
+// BiddingConfig controls which open market jobs the provider takes
+type BiddingConfig struct {
+    Enabled       bool          `yaml:"enabled" json:"enabled"`
+    DryRun        bool          `yaml:"dry_run" json:"dry_run"`   // only log the decisions
+    Strategy      string        `yaml:"strategy" json:"strategy"` // accept or bid
+    PriceFloor    uint64        `yaml:"price_floor" json:"price_floor"` // minimum payment in the base denom
+    Markup        float64       `yaml:"markup" json:"markup"`           // bid = floor * (1 + markup)
+    ServiceTypes  []string      `yaml:"service_types" json:"service_types"` // empty: all
+    MaxDigits     int           `yaml:"max_digits" json:"max_digits"`       // 0: no limit
+    MaxConcurrent int           `yaml:"max_concurrent" json:"max_concurrent"`
+    PollInterval  time.Duration `yaml:"poll_interval" json:"poll_interval"`
+}
+
+// BidDecision is the outcome of evaluating one open job
+type BidDecision struct {
+    JobID  uint64
+    Action string // accept, bid or skip
+    Price  uint64
+    Reason string
+}
+
+// Bidder watches open market jobs and bids on or accepts those within its limits
+type Bidder struct {
+    cfg          BiddingConfig
+    client       *Client
+    providerAddr string
+    run          func(ctx context.Context, contractAddr string, jobID uint64)
+
+    mu       sync.Mutex
+    seen     map[uint64]bool // jobs already decided on
+    started  map[uint64]bool // won jobs handed to run
+    inFlight int
+    stopped  bool
+    paused   bool // no new jobs, won bids still start
+}
+
+// NewBidder fills in defaults, run is called for every job the provider won
+func NewBidder(cfg BiddingConfig, client *Client, providerAddr string, run func(ctx context.Context, contractAddr string, jobID uint64)) (*Bidder, error) {
+    if cfg.Strategy == "" {
+        cfg.Strategy = BidStrategyAccept
+    }
+    if cfg.Strategy != BidStrategyAccept && cfg.Strategy != BidStrategyBid {
+        return nil, fmt.Errorf("unknown bidding strategy %q (accept, bid)", cfg.Strategy)
+    }
+    if cfg.MaxConcurrent <= 0 {
+        cfg.MaxConcurrent = 1
+    }
+    if cfg.PollInterval <= 0 {
+        cfg.PollInterval = 30 * time.Second
+    }
+    return &Bidder{
+        cfg:          cfg,
+        client:       client,
+        providerAddr: providerAddr,
+        run:          run,
+        seen:         make(map[uint64]bool),
+        started:      make(map[uint64]bool),
+    }, nil
+}
+
+// Config returns the effective bidding settings
+func (b *Bidder) Config() BiddingConfig {
+    return b.cfg
+}
+
+// SetClient moves the bidder to another contract, the job IDs seen on the
+// old one mean nothing there. Jobs already started keep running.
+func (b *Bidder) SetClient(client *Client) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    b.client = client
+    b.seen = make(map[uint64]bool)
+    b.started = make(map[uint64]bool)
+}
+
+// SetPaused stops or resumes evaluating open jobs
+func (b *Bidder) SetPaused(paused bool) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    b.paused = paused
+}
+
+// Stop ends Run at its next poll, started jobs keep running
+func (b *Bidder) Stop() {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    b.stopped = true
+}
+
+func (b *Bidder) contractClient() *Client {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    return b.client
+}
+
+// Run polls the contract until ctx is cancelled
+func (b *Bidder) Run(ctx context.Context) {
+    ticker := time.NewTicker(b.cfg.PollInterval)
+    defer ticker.Stop()
+
+    for {
+        b.mu.Lock()
+        stopped := b.stopped
+        b.mu.Unlock()
+        if stopped {
+            return
+        }
+        if err := b.poll(ctx); err != nil {
+            log.Printf("❌ Bidding poll failed: %v", err)
+        }
+        select {
+        case <-ctx.Done():
+            log.Println("Bidding routine stopped")
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// poll evaluates new open jobs and starts jobs the provider won
+func (b *Bidder) poll(ctx context.Context) error {
+    client := b.contractClient()
+    b.mu.Lock()
+    paused := b.paused
+    b.mu.Unlock()
+    var open []ContractJob
+    if !paused {
+        var err error
+        if open, err = client.ListJobs(ctx, JobFilter{Status: JobStatusSubmitted}); err != nil {
+            return err
+        }
+    }
+    for _, job := range open {
+        if job.Provider != "" {
+            continue // directly assigned, handled by the submit_job subscription
+        }
+        b.mu.Lock()
+        done := b.seen[job.ID]
+        b.seen[job.ID] = true
+        b.mu.Unlock()
+        if done {
+            continue
+        }
+        b.act(ctx, client, b.Evaluate(job))
+    }
+
+    if b.cfg.DryRun || b.cfg.Strategy != BidStrategyBid {
+        return nil
+    }
+    // Bids are settled by the client, won jobs show up as accepted for us
+    won, err := client.ListJobs(ctx, JobFilter{Provider: b.providerAddr, Status: JobStatusAccepted})
+    if err != nil {
+        return err
+    }
+    for _, job := range won {
+        b.mu.Lock()
+        bid, started := b.seen[job.ID], b.started[job.ID]
+        b.mu.Unlock()
+        if bid && !started {
+            b.start(ctx, client, job.ID)
+        }
+    }
+    return nil
+}
+
+// Evaluate checks a job against the price floor, capability filter and capacity
+func (b *Bidder) Evaluate(job ContractJob) BidDecision {
+    d := BidDecision{JobID: job.ID, Action: "skip"}
+
+    serviceType := job.ServiceType
+    if serviceType == "" {
+        serviceType = job.JobType
+    }
+    if len(b.cfg.ServiceTypes) > 0 && !containsString(b.cfg.ServiceTypes, serviceType) {
+        d.Reason = fmt.Sprintf("service type %s not offered", serviceType)
+        return d
+    }
+
+    if b.cfg.MaxDigits > 0 {
+        var params map[string]interface{}
+        if err := json.Unmarshal([]byte(job.Parameters), &params); err == nil {
+            if digits, ok := params["digits"].(float64); ok && int(digits) > b.cfg.MaxDigits {
+                d.Reason = fmt.Sprintf("%d digits exceed the limit of %d", int(digits), b.cfg.MaxDigits)
+                return d
+            }
+        }
+    }
+
+    b.mu.Lock()
+    busy := b.inFlight >= b.cfg.MaxConcurrent
+    b.mu.Unlock()
+    if busy {
+        d.Reason = fmt.Sprintf("%d jobs in flight", b.cfg.MaxConcurrent)
+        return d
+    }
+
+    payment, _ := strconv.ParseUint(job.PaymentAmount, 10, 64)
+    switch b.cfg.Strategy {
+    case BidStrategyAccept:
+        if payment < b.cfg.PriceFloor {
+            d.Reason = fmt.Sprintf("payment %d below floor %d", payment, b.cfg.PriceFloor)
+            return d
+        }
+        d.Action, d.Price = "accept", payment
+    case BidStrategyBid:
+        price := uint64(math.Ceil(float64(b.cfg.PriceFloor) * (1 + b.cfg.Markup)))
+        limit := payment
+        if maxPrice, err := strconv.ParseUint(job.MaxPrice, 10, 64); err == nil && maxPrice > 0 {
+            limit = maxPrice
+        }
+        if limit < b.cfg.PriceFloor {
+            d.Reason = fmt.Sprintf("max price %d below floor %d", limit, b.cfg.PriceFloor)
+            return d
+        }
+        if price > limit {
+            price = limit
+        }
+        d.Action, d.Price = "bid", price
+    }
+    return d
+}
+
+// act executes a decision, in dry-run mode it is only logged
+func (b *Bidder) act(ctx context.Context, client *Client, d BidDecision) {
+    if d.Action == "skip" {
+        log.Printf("⏭️  Job %d skipped: %s", d.JobID, d.Reason)
+        return
+    }
+    if b.cfg.DryRun {
+        log.Printf("🧪 [dry-run] would %s job %d at %d", d.Action, d.JobID, d.Price)
+        return
+    }
+
+    switch d.Action {
+    case "accept":
+        txHash, err := client.AcceptJob(ctx, d.JobID)
+        if err != nil {
+            log.Printf("❌ Accepting job %d failed: %v", d.JobID, err)
+            return
+        }
+        log.Printf("🤝 Accepted job %d for %d (tx %s)", d.JobID, d.Price, txHash)
+        b.start(ctx, client, d.JobID)
+    case "bid":
+        txHash, err := client.PlaceBid(ctx, d.JobID, strconv.FormatUint(d.Price, 10))
+        if err != nil {
+            log.Printf("❌ Bid on job %d failed: %v", d.JobID, err)
+            return
+        }
+        log.Printf("💰 Bid %d on job %d (tx %s)", d.Price, d.JobID, txHash)
+    }
+}
+
+// start runs a won job and keeps track of the concurrency limit
+func (b *Bidder) start(ctx context.Context, client *Client, jobID uint64) {
+    b.mu.Lock()
+    b.started[jobID] = true
+    b.inFlight++
+    b.mu.Unlock()
+
+    go func() {
+        defer func() {
+            b.mu.Lock()
+            b.inFlight--
+            b.mu.Unlock()
+        }()
+        b.run(ctx, client.ContractAddress(), jobID)
+    }()
+}
+
+func containsString(list []string, s string) bool {
+    for _, v := range list {
+        if v == s {
+            return true
+        }
+    }
+    return false
+}
@@ -10,6 +10,11 @@ import (
     "strconv"
     "strings"
     "time"
+
+    sdk "github.com/cosmos/cosmos-sdk/types"
+    "github.com/oxygene76/medasdigital-client/pkg/blockchain"
+    "github.com/oxygene76/medasdigital-client/pkg/telemetry"
+    "github.com/oxygene76/medasdigital-client/pkg/network"
 )
 
 type Client struct {
@@ -17,6 +22,7 @@ type Client struct {
     clientKey  string
     clientAddr string
     keyringBackend string
+    signer     *ContractClient // signs natively instead of via medasdigitald when set
 }
 
 func NewClient(config Config, clientKey string, clientAddr string, keyringBackend string) *Client {
@@ -28,60 +34,110 @@ func NewClient(config Config, clientKey string, clientAddr string, keyringBacken
     }
 }
 
+// WithSigner makes the client sign and broadcast transactions with the built-in keyring
+func (c *Client) WithSigner(signer *ContractClient) *Client {
+    c.signer = signer
+    return c
+}
+
+// ContractAddress is the contract the client talks to
+func (c *Client) ContractAddress() string {
+    return c.config.ContractAddress
+}
+
+// GetConfig returns the contract's get_config answer
+func (c *Client) GetConfig(ctx context.Context) (*MarketConfig, error) {
+    var cfg MarketConfig
+    if err := c.query(ctx, "get_config", QueryMsg{GetConfig: &struct{}{}}, &cfg); err != nil {
+        return nil, err
+    }
+    return &cfg, nil
+}
 
 // GetJob holt Job-Details
 func (c *Client) GetJob(ctx context.Context, jobID uint64) (*ContractJob, error) {
-    query := fmt.Sprintf(`{"get_job":{"job_id":%d}}`, jobID)
-    
-    cmd := exec.CommandContext(ctx,
-        "medasdigitald", "query", "wasm", "contract-state", "smart",
-        c.config.ContractAddress, query,
-        "--node", c.config.RPCEndpoint,
-        "--output", "json",
-    )
-    
-    output, err := cmd.Output()
-    if err != nil {
-        return nil, fmt.Errorf("query failed: %w", err)
+    var job ContractJob
+    if err := c.query(ctx, "query_job", QueryMsg{GetJob: &JobRef{JobID: jobID}}, &job); err != nil {
+        return nil, err
     }
-    
+    return &job, nil
+}
+
+// ListProviders holt alle Provider vom Contract
+func (c *Client) ListProviders(ctx context.Context) ([]Provider, error) {
     var result struct {
-        Data ContractJob `json:"data"`
+        Providers []Provider `json:"providers"`
     }
-    
-    if err := json.Unmarshal(output, &result); err != nil {
+    if err := c.query(ctx, "list_providers", QueryMsg{ListProviders: &struct{}{}}, &result); err != nil {
         return nil, err
     }
-    
-    return &result.Data, nil
+    return result.Providers, nil
 }
-// ListProviders holt alle Provider vom Contract
-func (c *Client) ListProviders(ctx context.Context) ([]Provider, error) {
-    query := `{"list_providers":{}}`
+
+// ListJobs returns the jobs matching the filter, ordered by ID
+func (c *Client) ListJobs(ctx context.Context, filter JobFilter) ([]ContractJob, error) {
+    var result struct {
+        Jobs []ContractJob `json:"jobs"`
+    }
+    if err := c.query(ctx, "list_jobs", QueryMsg{ListJobs: &filter}, &result); err != nil {
+        return nil, err
+    }
+    return result.Jobs, nil
+}
+
+// GetProvider returns a single provider. Contracts without the get_provider
+// query are handled by searching list_providers.
+func (c *Client) GetProvider(ctx context.Context, address string) (*Provider, error) {
+    var result struct {
+        Provider *Provider `json:"provider"`
+    }
+    if err := c.query(ctx, "query_provider", QueryMsg{GetProvider: &ProviderRef{Address: address}}, &result); err == nil && result.Provider != nil {
+        return result.Provider, nil
+    }
+
+    providers, err := c.ListProviders(ctx)
+    if err != nil {
+        return nil, err
+    }
+    for i := range providers {
+        if providers[i].Address == address {
+            return &providers[i], nil
+        }
+    }
+    return nil, fmt.Errorf("provider %s is not registered", address)
+}
+
+// query runs a smart query and decodes the contract's answer into out
+func (c *Client) query(ctx context.Context, name string, q QueryMsg, out interface{}) error {
+    if c.signer != nil {
+        return c.signer.Query(ctx, q, out)
+    }
+    defer telemetry.Track(telemetry.CategoryRPC, name)()
     
     cmd := exec.CommandContext(ctx,
         "medasdigitald", "query", "wasm", "contract-state", "smart",
-        c.config.ContractAddress, query,
+        c.config.ContractAddress, q.JSON(),
         "--node", c.config.RPCEndpoint,
         "--output", "json",
     )
     
+    var stderr bytes.Buffer
+    cmd.Stderr = &stderr
     output, err := cmd.Output()
     if err != nil {
-        return nil, fmt.Errorf("query failed: %w, output: %s", err, output)
+        return fmt.Errorf("query failed: %w: %s", err, strings.TrimSpace(stderr.String()))
     }
     
     var result struct {
-        Data struct {
-            Providers []Provider `json:"providers"`
-        } `json:"data"`
+        Data json.RawMessage `json:"data"`
     }
-    
     if err := json.Unmarshal(output, &result); err != nil {
-        return nil, fmt.Errorf("parse failed: %w", err)
+        return fmt.Errorf("parse failed: %w", err)
     }
-    
-    return result.Data.Providers, nil
+    if err := json.Unmarshal(result.Data, out); err != nil {
+        return fmt.Errorf("parse failed: %w", err)
+    }
+    return nil
 }
 
 // FindBestProvider wählt Provider basierend auf Kriterien
@@ -91,6 +147,21 @@ func (c *Client) FindBestProvider(
     complexity int,
     criteria string,
 ) (*Provider, error) {
+    suitable, err := c.RankProviders(ctx, jobType, complexity, criteria)
+    if err != nil {
+        return nil, err
+    }
+    return &suitable[0], nil
+}
+
+// RankProviders lists the active providers with free capacity for a job type,
+// best first by criteria
+func (c *Client) RankProviders(
+    ctx context.Context,
+    jobType string,
+    complexity int,
+    criteria string,
+) ([]Provider, error) {
     providers, err := c.ListProviders(ctx)
     if err != nil {
         return nil, err
@@ -139,7 +210,7 @@ func (c *Client) FindBestProvider(
         return nil, fmt.Errorf("unknown criteria: %s", criteria)
     }
     
-    return &suitable[0], nil
+    return suitable, nil
 }
 
 // SubmitJob submitted Job mit Auto-Gas
@@ -150,12 +221,81 @@ func (c *Client) SubmitJob(
     parameters map[string]interface{},
     paymentAmount string,
 ) (uint64, string, error) {
-    paramsJSON, _ := json.Marshal(parameters)
-    paramsStr := strings.ReplaceAll(string(paramsJSON), `"`, `\"`)
+    submit, err := NewSubmitJobMsg(providerAddr, jobType, parameters)
+    if err != nil {
+        return 0, "", fmt.Errorf("invalid parameters: %w", err)
+    }
+    return c.submit(ctx, submit, paymentAmount)
+}
+
+// SubmitHeldJob submits a job whose escrow the contract keeps after completion
+// until ReleasePayment or DisputeJob
+func (c *Client) SubmitHeldJob(
+    ctx context.Context,
+    providerAddr string,
+    jobType string,
+    parameters map[string]interface{},
+    paymentAmount string,
+) (uint64, string, error) {
+    submit, err := NewSubmitJobMsg(providerAddr, jobType, parameters)
+    if err != nil {
+        return 0, "", fmt.Errorf("invalid parameters: %w", err)
+    }
+    submit.SubmitJob.HoldPayment = true
+    return c.submit(ctx, submit, paymentAmount)
+}
+
+// submit broadcasts submit_job with the payment and waits for the job ID
+func (c *Client) submit(ctx context.Context, submit ExecuteMsg, paymentAmount string) (uint64, string, error) {
+    var txHash string
+    var err error
+    if c.signer != nil {
+        payment, err := sdk.ParseCoinsNormalized(paymentAmount)
+        if err != nil {
+            return 0, "", fmt.Errorf("invalid payment: %w", err)
+        }
+        res, err := c.signer.Execute(ctx, submit, payment)
+        if err != nil {
+            return 0, "", fmt.Errorf("submit failed: %w", err)
+        }
+        txHash = res.TxHash
+    } else {
+        txHash, err = c.submitViaCLI(ctx, submit.JSON(), paymentAmount)
+        if err != nil {
+            return 0, "", err
+        }
+    }
     
-    msg := fmt.Sprintf(`{"submit_job":{"provider":"%s","job_type":"%s","parameters":"%s"}}`,
-        providerAddr, jobType, paramsStr)
+   fmt.Printf("TX Hash: %s\n", txHash)
+   fmt.Println("Waiting for TX to be included in block...")
+
+    var jobID uint64
+
+    // Polling mit Timeout (max 60 Sekunden)
+    timeout := time.After(60 * time.Second)
+    ticker := time.NewTicker(1 * time.Second)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-timeout:
+            return 0, txHash, fmt.Errorf("timeout waiting for TX to be included in block")
     
+        case <-ticker.C:
+            jobID, err = c.getJobIDFromTx(ctx, txHash)
+            if err == nil {
+                // Erfolgreich gefunden
+                return jobID, txHash, nil
+            }
+        
+            // TX noch nicht im Block, weiter pollen
+            fmt.Print(".")
+        }
+    }    
+}
+
+// submitViaCLI broadcasts submit_job with medasdigitald and returns the tx hash
+func (c *Client) submitViaCLI(ctx context.Context, msg, paymentAmount string) (string, error) {
     args := []string{
         "tx", "wasm", "execute",
         c.config.ContractAddress, msg,
@@ -164,12 +304,15 @@ func (c *Client) SubmitJob(
         "--keyring-backend", c.keyringBackend,
         "--gas", "auto",
         "--gas-adjustment", "1.3",
-        "--gas-prices", "0.025umedas",
+        "--gas-prices", network.Current().GasPrice,
         "--broadcast-mode", "sync",
         "-y",
         "--node", c.config.RPCEndpoint,
         "--chain-id", c.config.ChainID,
     }
+    if c.config.FeeGranter != "" {
+        args = append(args, "--fee-granter", c.config.FeeGranter)
+    }
     
     cmd := exec.CommandContext(ctx, "medasdigitald", args...)
     
@@ -177,8 +320,11 @@ func (c *Client) SubmitJob(
     cmd.Stdout = &stdout
     cmd.Stderr = &stderr
     
-    if err := cmd.Run(); err != nil {
-        return 0, "", fmt.Errorf("submit failed: %w\nstderr: %s", err, stderr.String())
+    stopTimer := telemetry.Track(telemetry.CategoryRPC, "submit_job_tx")
+    err := cmd.Run()
+    stopTimer()
+    if err != nil {
+        return "", fmt.Errorf("submit failed: %w\nstderr: %s", err, stderr.String())
     }
     
     // Parse Text-Output für TX Hash
@@ -194,37 +340,12 @@ func (c *Client) SubmitJob(
     }
     
     if txHash == "" {
-        return 0, "", fmt.Errorf("txhash not found in output:\n%s", output)
+        return "", fmt.Errorf("txhash not found in output:\n%s", output)
     }
     
-   fmt.Printf("TX Hash: %s\n", txHash)
-   fmt.Println("Waiting for TX to be included in block...")
-
-    var jobID uint64
-    var err error
-
-    // Polling mit Timeout (max 60 Sekunden)
-    timeout := time.After(60 * time.Second)
-    ticker := time.NewTicker(1 * time.Second)
-    defer ticker.Stop()
-
-    for {
-        select {
-        case <-timeout:
-            return 0, txHash, fmt.Errorf("timeout waiting for TX to be included in block")
-    
-        case <-ticker.C:
-            jobID, err = c.getJobIDFromTx(ctx, txHash)
-            if err == nil {
-                // Erfolgreich gefunden
-                return jobID, txHash, nil
-            }
-        
-            // TX noch nicht im Block, weiter pollen
-            fmt.Print(".")
-        }
-    }    
+    return txHash, nil
 }
+
 // WaitForCompletion wartet auf Job-Completion
 func (c *Client) WaitForCompletion(ctx context.Context, jobID uint64, timeout time.Duration) (*ContractJob, error) {
     deadline := time.Now().Add(timeout)
@@ -254,8 +375,124 @@ func (c *Client) WaitForCompletion(ctx context.Context, jobID uint64, timeout ti
     return nil, fmt.Errorf("job timeout after %v", timeout)
 }
 
+// AcceptJob takes an open market job at its offered price
+func (c *Client) AcceptJob(ctx context.Context, jobID uint64) (string, error) {
+    return c.execute(ctx, "accept_job_tx", ExecuteMsg{AcceptJob: &JobRef{JobID: jobID}})
+}
+
+// PlaceBid offers to run an open market job for price (in the base denom)
+func (c *Client) PlaceBid(ctx context.Context, jobID uint64, price string) (string, error) {
+    return c.execute(ctx, "place_bid_tx", ExecuteMsg{PlaceBid: &PlaceBidMsg{JobID: jobID, Price: price}})
+}
+
+// RefundJob asks the contract to release the escrow of a failed or expired job back to the client
+func (c *Client) RefundJob(ctx context.Context, jobID uint64) (string, error) {
+    return c.execute(ctx, "refund_job_tx", ExecuteMsg{RefundJob: &JobRef{JobID: jobID}})
+}
+
+// ClaimRefund withdraws a credited refund to the client address
+func (c *Client) ClaimRefund(ctx context.Context, jobID uint64) (string, error) {
+    return c.execute(ctx, "claim_refund_tx", ExecuteMsg{ClaimRefund: &JobRef{JobID: jobID}})
+}
+
+// ReleasePayment pays out the held escrow of a completed job to the provider
+func (c *Client) ReleasePayment(ctx context.Context, jobID uint64) (string, error) {
+    return c.execute(ctx, "release_payment_tx", ExecuteMsg{ReleasePayment: &JobRef{JobID: jobID}})
+}
+
+// DisputeJob flags the result of a held job as wrong, the escrow is not paid out
+func (c *Client) DisputeJob(ctx context.Context, jobID uint64, reason string) (string, error) {
+    return c.execute(ctx, "dispute_job_tx", ExecuteMsg{DisputeJob: &DisputeJobMsg{JobID: jobID, Reason: reason}})
+}
+
+// GetRefund returns the refund state of a job. Contracts without the get_refund
+// query are handled by deriving the state from the job itself.
+func (c *Client) GetRefund(ctx context.Context, jobID uint64) (*Refund, error) {
+    var refund *Refund
+    if err := c.query(ctx, "query_refund", QueryMsg{GetRefund: &JobRef{JobID: jobID}}, &refund); err == nil && refund != nil {
+        refund.JobID = jobID
+        return refund, nil
+    }
+    
+    job, err := c.GetJob(ctx, jobID)
+    if err != nil {
+        return nil, err
+    }
+    return refundFromJob(job), nil
+}
+
+// refundFromJob derives the refund state from the job status
+func refundFromJob(job *ContractJob) *Refund {
+    refund := &Refund{
+        JobID:  job.ID,
+        Client: job.Client,
+        Amount: job.PaymentAmount,
+        Status: RefundStatusNone,
+    }
+    switch job.Status {
+    case JobStatusFailed:
+        refund.Status = RefundStatusEligible
+    case JobStatusRefunded, JobStatusCancelled:
+        refund.Status = RefundStatusClaimed
+    }
+    return refund
+}
+
+// execute signs and broadcasts an execute message with the client key
+func (c *Client) execute(ctx context.Context, name string, msg ExecuteMsg) (string, error) {
+    if c.signer != nil {
+        res, err := c.signer.Execute(ctx, msg, nil)
+        if err != nil {
+            return "", err
+        }
+        return res.TxHash, nil
+    }
+    defer telemetry.Track(telemetry.CategoryRPC, name)()
+    
+    args := []string{
+        "tx", "wasm", "execute",
+        c.config.ContractAddress, msg.JSON(),
+        "--from", c.clientKey,
+        "--keyring-backend", c.keyringBackend,
+        "--gas", "auto",
+        "--gas-adjustment", "1.3",
+        "--gas-prices", network.Current().GasPrice,
+        "--broadcast-mode", "sync",
+        "-y",
+        "--node", c.config.RPCEndpoint,
+        "--chain-id", c.config.ChainID,
+        "--output", "json",
+    }
+    if c.config.FeeGranter != "" {
+        args = append(args, "--fee-granter", c.config.FeeGranter)
+    }
+    cmd := exec.CommandContext(ctx, "medasdigitald", args...)
+    
+    var stdout, stderr bytes.Buffer
+    cmd.Stdout = &stdout
+    cmd.Stderr = &stderr
+    if err := cmd.Run(); err != nil {
+        return "", fmt.Errorf("tx failed: %w\nstderr: %s", err, stderr.String())
+    }
+    
+    var resp struct {
+        TxHash string `json:"txhash"`
+        Codespace string `json:"codespace"`
+        Code   uint32 `json:"code"`
+        RawLog string `json:"raw_log"`
+    }
+    if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+        return "", fmt.Errorf("parse tx response failed: %w", err)
+    }
+    if resp.Code != 0 {
+        return resp.TxHash, &blockchain.TxFailedError{TxHash: resp.TxHash, Codespace: resp.Codespace, Code: resp.Code, Log: resp.RawLog}
+    }
+    return resp.TxHash, nil
+}
+
 // Helper: Job-ID aus TX extrahieren
 func (c *Client) getJobIDFromTx(ctx context.Context, txHash string) (uint64, error) {
+    defer telemetry.Track(telemetry.CategoryRPC, "query_tx")()
     cmd := exec.CommandContext(ctx,
         "medasdigitald", "query", "tx", txHash,
         "--node", c.config.RPCEndpoint,
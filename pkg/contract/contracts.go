@@ -0,0 +1,145 @@
+package contract
+
+import (
+    "sort"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/gorilla/websocket"
+)
+
+// marketContract is the node's connection to one marketplace contract, each
+// has its own job subscription, heartbeat and bidder
+type marketContract struct {
+    mu                sync.Mutex
+    addr              string
+    wsClient          *websocket.Conn
+    reconnectAttempts int
+    lastHeartbeat     time.Time
+    bidder            *Bidder
+    retired           bool // replaced by a successor the node already serves
+}
+
+func newMarketContract(addr string) *marketContract {
+    return &marketContract{addr: addr, lastHeartbeat: time.Now()}
+}
+
+func (m *marketContract) address() string {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    return m.addr
+}
+
+func (m *marketContract) isRetired() bool {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    return m.retired
+}
+
+// ContractAccount is the node's job accounting for one contract
+type ContractAccount struct {
+    Completed int    `json:"completed"`
+    Failed    int    `json:"failed"`
+    Earned    uint64 `json:"earned"` // payments of completed jobs in the base denom
+}
+
+// ContractStatus is the node's connection state and accounting for one contract
+type ContractStatus struct {
+    Contract          string    `json:"contract"`
+    Primary           bool      `json:"primary"`
+    Connected         bool      `json:"websocket_connected"`
+    ReconnectAttempts int       `json:"reconnect_attempts"`
+    LastHeartbeat     time.Time `json:"last_heartbeat"`
+    ContractAccount
+}
+
+// AddContract serves jobs of another marketplace contract besides the one
+// the node was created with. Call it before Start.
+func (p *ProviderNode) AddContract(addr string) {
+    p.contractMu.Lock()
+    defer p.contractMu.Unlock()
+    for _, mc := range p.contracts {
+        if mc.addr == addr {
+            return
+        }
+    }
+    p.contracts = append(p.contracts, newMarketContract(addr))
+}
+
+// marketContracts returns the contracts the node serves, the primary first
+func (p *ProviderNode) marketContracts() []*marketContract {
+    p.contractMu.RLock()
+    defer p.contractMu.RUnlock()
+    var served []*marketContract
+    for _, mc := range p.contracts {
+        if !mc.isRetired() {
+            served = append(served, mc)
+        }
+    }
+    return served
+}
+
+// servedContract finds the contract connection for an address
+func (p *ProviderNode) servedContract(addr string) *marketContract {
+    for _, mc := range p.marketContracts() {
+        if mc.address() == addr {
+            return mc
+        }
+    }
+    return nil
+}
+
+// account books a finished job on the contract it came from
+func (p *ProviderNode) account(contractAddr string, completed bool, payment string) {
+    p.accountsMu.Lock()
+    defer p.accountsMu.Unlock()
+    acc := p.accounts[contractAddr]
+    if acc == nil {
+        acc = &ContractAccount{}
+        p.accounts[contractAddr] = acc
+    }
+    if !completed {
+        acc.Failed++
+        return
+    }
+    acc.Completed++
+    amount, _ := strconv.ParseUint(payment, 10, 64)
+    acc.Earned += amount
+}
+
+// Contracts returns the state of every contract the node served, the
+// primary first. Contracts left by a migration keep their accounting.
+func (p *ProviderNode) Contracts() []ContractStatus {
+    var statuses []ContractStatus
+    seen := map[string]bool{}
+    for i, mc := range p.marketContracts() {
+        mc.mu.Lock()
+        s := ContractStatus{
+            Contract:          mc.addr,
+            Primary:           i == 0,
+            Connected:         mc.wsClient != nil,
+            ReconnectAttempts: mc.reconnectAttempts,
+            LastHeartbeat:     mc.lastHeartbeat,
+        }
+        mc.mu.Unlock()
+        seen[s.Contract] = true
+        statuses = append(statuses, s)
+    }
+
+    p.accountsMu.Lock()
+    defer p.accountsMu.Unlock()
+    for i := range statuses {
+        if acc := p.accounts[statuses[i].Contract]; acc != nil {
+            statuses[i].ContractAccount = *acc
+        }
+    }
+    var former []ContractStatus
+    for addr, acc := range p.accounts {
+        if !seen[addr] {
+            former = append(former, ContractStatus{Contract: addr, ContractAccount: *acc})
+        }
+    }
+    sort.Slice(former, func(i, j int) bool { return former[i].Contract < former[j].Contract })
+    return append(statuses, former...)
+}
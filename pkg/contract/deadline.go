@@ -0,0 +1,115 @@
+package contract
+
+import (
+    "context"
+    "log"
+    "sort"
+    "strconv"
+    "time"
+)
+
+// DeadlineMargin is kept free before a job's deadline to get fail_job on chain in time
+const DeadlineMargin = 30 * time.Second
+
+// deadlineWarning is how long before the deadline a running job is logged as at risk
+const deadlineWarning = 10 * time.Minute
+
+// ParseTimestamp reads CosmWasm timestamps (nanoseconds as string) and RFC3339
+func ParseTimestamp(s string) (time.Time, bool) {
+    if nanos, err := strconv.ParseInt(s, 10, 64); err == nil {
+        return time.Unix(0, nanos), true
+    }
+    if t, err := time.Parse(time.RFC3339, s); err == nil {
+        return t, true
+    }
+    return time.Time{}, false
+}
+
+// ActiveJob is a contract job the provider is computing
+type ActiveJob struct {
+    JobID     uint64     `json:"job_id"`
+    Contract  string     `json:"contract"`
+    JobType   string     `json:"job_type"`
+    StartedAt time.Time  `json:"started_at"`
+    Deadline  *time.Time `json:"deadline,omitempty"`
+    TimeLeft  string     `json:"time_left,omitempty"`
+    AtRisk    bool       `json:"at_risk"`
+
+    warned bool
+}
+
+// jobKey identifies a job across contracts, job IDs are only unique per contract
+func jobKey(contractAddr string, jobID uint64) string {
+    return contractAddr + "/" + strconv.FormatUint(jobID, 10)
+}
+
+func (p *ProviderNode) trackJob(job *ActiveJob) {
+    p.activeMu.Lock()
+    defer p.activeMu.Unlock()
+    p.activeJobs[jobKey(job.Contract, job.JobID)] = job
+}
+
+func (p *ProviderNode) untrackJob(contractAddr string, jobID uint64) {
+    p.activeMu.Lock()
+    defer p.activeMu.Unlock()
+    delete(p.activeJobs, jobKey(contractAddr, jobID))
+}
+
+// isActive reports whether the node is computing a job
+func (p *ProviderNode) isActive(contractAddr string, jobID uint64) bool {
+    p.activeMu.Lock()
+    defer p.activeMu.Unlock()
+    _, ok := p.activeJobs[jobKey(contractAddr, jobID)]
+    return ok
+}
+
+// ActiveJobs returns the running contract jobs, closest deadline first
+func (p *ProviderNode) ActiveJobs() []ActiveJob {
+    p.activeMu.Lock()
+    defer p.activeMu.Unlock()
+
+    now := time.Now()
+    jobs := make([]ActiveJob, 0, len(p.activeJobs))
+    for _, job := range p.activeJobs {
+        j := *job
+        if j.Deadline != nil {
+            left := j.Deadline.Sub(now)
+            j.TimeLeft = left.Round(time.Second).String()
+            j.AtRisk = left < deadlineWarning
+        }
+        jobs = append(jobs, j)
+    }
+    sort.Slice(jobs, func(a, b int) bool {
+        if jobs[a].Deadline == nil || jobs[b].Deadline == nil {
+            return jobs[b].Deadline == nil && jobs[a].Deadline != nil
+        }
+        return jobs[a].Deadline.Before(*jobs[b].Deadline)
+    })
+    return jobs
+}
+
+// deadlineRoutine logs jobs that get close to their deadline, once per job
+func (p *ProviderNode) deadlineRoutine(ctx context.Context) {
+    ticker := time.NewTicker(30 * time.Second)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            p.activeMu.Lock()
+            for _, job := range p.activeJobs {
+                if job.Deadline == nil || job.warned {
+                    continue
+                }
+                if left := time.Until(*job.Deadline); left < deadlineWarning {
+                    job.warned = true
+                    log.Printf("⏰ Job %d (%s) has %v left until its deadline, running for %v",
+                        job.JobID, job.JobType, left.Round(time.Second), time.Since(job.StartedAt).Round(time.Second))
+                }
+            }
+            p.activeMu.Unlock()
+        }
+    }
+}
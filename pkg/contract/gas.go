@@ -9,6 +9,8 @@ import (
     "os/exec"
     "strconv"
     "strings"
+
+    "github.com/oxygene76/medasdigital-client/pkg/network"
 )
 
 // EstimateGas führt Gas-Simulation durch
@@ -90,7 +92,7 @@ output := stdout.Bytes()
     return &GasEstimation{
         GasWanted: gasWanted,
         GasUsed:   gasUsed,
-        Fees:      fmt.Sprintf("%dumedas", totalFee),
+        Fees:      fmt.Sprintf("%d%s", totalFee, network.Current().BaseDenom),
     }, nil
 }
 
@@ -104,7 +106,7 @@ func GetMinimumGasPrice(ctx context.Context, rpcEndpoint string) (string, error)
     
     output, err := cmd.Output()
     if err != nil {
-        return "0.025umedas", nil
+        return network.Current().GasPrice, nil
     }
     
     var result struct {
@@ -115,16 +117,16 @@ func GetMinimumGasPrice(ctx context.Context, rpcEndpoint string) (string, error)
     }
     
     if err := json.Unmarshal(output, &result); err != nil {
-        return "0.025umedas", nil
+        return network.Current().GasPrice, nil
     }
     
     for _, price := range result.MinimumGasPrices {
-        if price.Denom == "umedas" {
+        if price.Denom == network.Current().BaseDenom {
             return price.Amount + price.Denom, nil
         }
     }
     
-    return "0.025umedas", nil
+    return network.Current().GasPrice, nil
 }
 
 // EstimateGasWithAdjustment erlaubt custom gas-adjustment
@@ -188,6 +190,6 @@ func EstimateGasWithAdjustment(
     return &GasEstimation{
         GasWanted: gasWanted,
         GasUsed:   gasUsed,
-        Fees:      fmt.Sprintf("%dumedas", totalFee),
+        Fees:      fmt.Sprintf("%d%s", totalFee, network.Current().BaseDenom),
     }, nil
 }
@@ -0,0 +1,93 @@
+package contract
+
+import (
+    "fmt"
+    "math"
+)
+
+// HarvestRule moves the balance of one denom above MaxBalance to its
+// destinations, MinBalance stays with the provider for fees
+type HarvestRule struct {
+    Denom        string               `json:"denom" yaml:"denom" mapstructure:"denom"` // base denom or ibc/<hash>
+    MinBalance   uint64               `json:"min_balance" yaml:"min_balance" mapstructure:"min_balance"`
+    MaxBalance   uint64               `json:"max_balance" yaml:"max_balance" mapstructure:"max_balance"`
+    Destinations []HarvestDestination `json:"destinations" yaml:"destinations" mapstructure:"destinations"`
+}
+
+// HarvestDestination receives Percent of every harvest of a rule
+type HarvestDestination struct {
+    Address string  `json:"address" yaml:"address" mapstructure:"address"`
+    Percent float64 `json:"percent" yaml:"percent" mapstructure:"percent"`
+    Label   string  `json:"label,omitempty" yaml:"label,omitempty" mapstructure:"label"` // e.g. treasury
+}
+
+// HarvestTransfer is one bank send of a harvest
+type HarvestTransfer struct {
+    Destination HarvestDestination
+    Amount      uint64
+}
+
+// Validate checks the thresholds and that the destinations add up to 100%
+func (r HarvestRule) Validate() error {
+    if r.Denom == "" {
+        return fmt.Errorf("harvest rule without denom")
+    }
+    if r.MaxBalance < r.MinBalance {
+        return fmt.Errorf("harvest %s: max_balance %d is below min_balance %d", r.Denom, r.MaxBalance, r.MinBalance)
+    }
+    if len(r.Destinations) == 0 {
+        return fmt.Errorf("harvest %s: no destinations", r.Denom)
+    }
+    var total float64
+    for _, d := range r.Destinations {
+        if d.Address == "" {
+            return fmt.Errorf("harvest %s: destination without address", r.Denom)
+        }
+        if d.Percent <= 0 {
+            return fmt.Errorf("harvest %s: %s gets %.2f%%, must be positive", r.Denom, d.Address, d.Percent)
+        }
+        total += d.Percent
+    }
+    if math.Abs(total-100) > 0.001 {
+        return fmt.Errorf("harvest %s: destinations add up to %.2f%%, not 100%%", r.Denom, total)
+    }
+    return nil
+}
+
+// Harvest returns the transfers for a balance, none while it is at most
+// MaxBalance. Rounding leftovers go to the last destination.
+func (r HarvestRule) Harvest(balance uint64) []HarvestTransfer {
+    if balance <= r.MaxBalance {
+        return nil
+    }
+    amount := balance - r.MinBalance
+    transfers := make([]HarvestTransfer, 0, len(r.Destinations))
+    var sent uint64
+    for i, d := range r.Destinations {
+        share := uint64(float64(amount) * d.Percent / 100)
+        if i == len(r.Destinations)-1 {
+            share = amount - sent
+        }
+        sent += share
+        if share > 0 {
+            transfers = append(transfers, HarvestTransfer{Destination: d, Amount: share})
+        }
+    }
+    return transfers
+}
+
+// SetHarvestRules replaces the funding address harvest with per-denom rules
+func (p *ProviderNode) SetHarvestRules(rules []HarvestRule) error {
+    seen := map[string]bool{}
+    for _, r := range rules {
+        if err := r.Validate(); err != nil {
+            return err
+        }
+        if seen[r.Denom] {
+            return fmt.Errorf("two harvest rules for %s", r.Denom)
+        }
+        seen[r.Denom] = true
+    }
+    p.harvestRules = rules
+    return nil
+}
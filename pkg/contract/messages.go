@@ -0,0 +1,114 @@
+package contract
+
+import "encoding/json"
+
+// ExecuteMsg is the contract's execute message, exactly one field is set
+type ExecuteMsg struct {
+    SubmitJob        *SubmitJobMsg        `json:"submit_job,omitempty"`
+    AcceptJob        *JobRef              `json:"accept_job,omitempty"`
+    PlaceBid         *PlaceBidMsg         `json:"place_bid,omitempty"`
+    CancelJob        *JobRef              `json:"cancel_job,omitempty"`
+    CompleteJob      *CompleteJobMsg      `json:"complete_job,omitempty"`
+    FailJob          *FailJobMsg          `json:"fail_job,omitempty"`
+    RefundJob        *JobRef              `json:"refund_job,omitempty"`
+    ClaimRefund      *JobRef              `json:"claim_refund,omitempty"`
+    HeartBeat        *struct{}            `json:"heart_beat,omitempty"`
+    RegisterProvider *RegisterProviderMsg `json:"register_provider,omitempty"`
+    ReleasePayment   *JobRef              `json:"release_payment,omitempty"`
+    DisputeJob       *DisputeJobMsg       `json:"dispute_job,omitempty"`
+}
+
+// QueryMsg is the contract's smart query, exactly one field is set
+type QueryMsg struct {
+    GetJob        *JobRef      `json:"get_job,omitempty"`
+    GetRefund     *JobRef      `json:"get_refund,omitempty"`
+    ListJobs      *JobFilter   `json:"list_jobs,omitempty"`
+    GetProvider   *ProviderRef `json:"get_provider,omitempty"`
+    ListProviders *struct{}    `json:"list_providers,omitempty"`
+    GetConfig     *struct{}    `json:"get_config,omitempty"`
+}
+
+// JobRef addresses a single job
+type JobRef struct {
+    JobID uint64 `json:"job_id"`
+}
+
+// ProviderRef addresses a registered provider
+type ProviderRef struct {
+    Address string `json:"address"`
+}
+
+// SubmitJobMsg submits a job, the parameters are a JSON string.
+// ServiceType, MaxPrice and AutoAccept belong to the open-market submit used by planet9 jobs.
+type SubmitJobMsg struct {
+    Provider    string `json:"provider,omitempty"`
+    JobType     string `json:"job_type,omitempty"`
+    ServiceType string `json:"service_type,omitempty"`
+    Parameters  string `json:"parameters"`
+    MaxPrice    string `json:"max_price,omitempty"`
+    AutoAccept  bool   `json:"auto_accept,omitempty"`
+    // HoldPayment keeps the escrow after complete_job until the client sends
+    // release_payment or dispute_job, used to cross-check redundant jobs
+    HoldPayment bool   `json:"hold_payment,omitempty"`
+}
+
+// RegisterProviderMsg registers the sender as compute provider
+type RegisterProviderMsg struct {
+    Name         string               `json:"name"`
+    Capabilities []Capability         `json:"capabilities"`
+    Pricing      map[string]PriceInfo `json:"pricing"`
+    Endpoint     string               `json:"endpoint"`
+    Specs        *MachineSpecs        `json:"specs,omitempty"`
+}
+
+// PlaceBidMsg offers to run an open market job for price
+type PlaceBidMsg struct {
+    JobID uint64 `json:"job_id"`
+    Price string `json:"price"`
+}
+
+// CompleteJobMsg anchors a provider's result
+type CompleteJobMsg struct {
+    JobID       uint64       `json:"job_id"`
+    ResultHash  string       `json:"result_hash"`
+    ResultURL   string       `json:"result_url"`
+    Attestation *Attestation `json:"attestation,omitempty"` // signed receipt of the provider
+}
+
+// FailJobMsg is sent by the provider, the escrowed payment becomes refundable
+type FailJobMsg struct {
+    JobID  uint64 `json:"job_id"`
+    Reason string `json:"reason"`
+}
+
+// DisputeJobMsg is sent by the client instead of release_payment when a held
+// result is wrong, the escrow stays frozen until the dispute is resolved
+type DisputeJobMsg struct {
+    JobID  uint64 `json:"job_id"`
+    Reason string `json:"reason"`
+}
+
+// NewSubmitJobMsg encodes the job parameters the way the contract stores them
+func NewSubmitJobMsg(provider, jobType string, parameters map[string]interface{}) (ExecuteMsg, error) {
+    params, err := json.Marshal(parameters)
+    if err != nil {
+        return ExecuteMsg{}, err
+    }
+    return ExecuteMsg{SubmitJob: &SubmitJobMsg{
+        Provider:   provider,
+        JobType:    jobType,
+        Parameters: string(params),
+    }}, nil
+}
+
+// JSON renders the message for medasdigitald, marshalling these types cannot fail
+func (m ExecuteMsg) JSON() string {
+    data, _ := json.Marshal(m)
+    return string(data)
+}
+
+// JSON renders the query for medasdigitald
+func (q QueryMsg) JSON() string {
+    data, _ := json.Marshal(q)
+    return string(data)
+}
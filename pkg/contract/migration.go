@@ -0,0 +1,156 @@
+package contract
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "time"
+)
+
+// DefaultMigrationCheckInterval is how often the node looks for a contract migration
+const DefaultMigrationCheckInterval = 5 * time.Minute
+
+// MigrationConfig lets a provider node follow the marketplace to a successor
+// contract. The node migrates once the successor answers and is not paused
+// while the current contract is paused or names the successor in get_config.
+// Every served contract can migrate that way, the configured successor
+// replaces the primary one.
+type MigrationConfig struct {
+    Successor     string        // configured successor of the primary contract
+    CheckInterval time.Duration // 0: DefaultMigrationCheckInterval
+    // Register registers the provider capabilities on the successor
+    Register func(ctx context.Context, contractAddr string) error
+    // OnMigrated is called after the node switched, e.g. to update the config
+    OnMigrated func(from, to string)
+}
+
+// SetMigration watches for a successor contract and moves the node over:
+// the provider is registered on it, new jobs and heartbeats go there and
+// the jobs still pending on the old contract are finished against it
+func (p *ProviderNode) SetMigration(cfg MigrationConfig) error {
+    if cfg.Register == nil {
+        return fmt.Errorf("migration needs a registration function")
+    }
+    if cfg.CheckInterval <= 0 {
+        cfg.CheckInterval = DefaultMigrationCheckInterval
+    }
+    p.migration = &cfg
+    return nil
+}
+
+func (p *ProviderNode) migrationRoutine(ctx context.Context) {
+    ticker := time.NewTicker(p.migration.CheckInterval)
+    defer ticker.Stop()
+
+    for {
+        for i, mc := range p.marketContracts() {
+            successor := ""
+            if i == 0 {
+                successor = p.migration.Successor
+            }
+            if err := p.checkMigration(ctx, mc, successor); err != nil {
+                log.Printf("❌ Contract migration check failed: %v", err)
+            }
+        }
+        select {
+        case <-ctx.Done():
+            log.Println("Migration routine stopped")
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// checkMigration migrates a contract when its successor is ready to take over
+func (p *ProviderNode) checkMigration(ctx context.Context, mc *marketContract, to string) error {
+    from := mc.address()
+    current, err := p.client(from).GetConfig(ctx)
+    if err != nil {
+        return fmt.Errorf("get_config of %s: %w", from, err)
+    }
+
+    if current.Successor != "" {
+        to = current.Successor
+    }
+    if to == "" || to == from {
+        return nil
+    }
+    if !current.Paused && current.Successor == "" {
+        return nil // the current contract is still the marketplace
+    }
+
+    successor, err := p.client(to).GetConfig(ctx)
+    if err != nil {
+        return fmt.Errorf("successor %s is not reachable: %w", to, err)
+    }
+    if successor.Paused {
+        log.Printf("⏳ Successor contract %s is paused, staying on %s", to, from)
+        return nil
+    }
+    return p.migrate(ctx, mc, from, to)
+}
+
+// migrate registers on the successor, switches the contract's subscription,
+// heartbeat and bidder to it and finishes the jobs pending on the old contract
+func (p *ProviderNode) migrate(ctx context.Context, mc *marketContract, from, to string) error {
+    log.Printf("🔀 Contract migration: %s → %s", from, to)
+
+    // Serving the successor already, the old contract only has to be drained
+    served := p.servedContract(to) != nil
+    if !served {
+        if err := p.migration.Register(ctx, to); err != nil {
+            return fmt.Errorf("registration on %s failed: %w", to, err)
+        }
+        if _, err := p.client(to).GetProvider(ctx, p.providerAddr); err != nil {
+            return fmt.Errorf("registration on %s not visible yet: %w", to, err)
+        }
+        log.Printf("✅ Registered on successor contract %s", to)
+    }
+
+    // Escrowed payments stay with the old contract, list its jobs before switching
+    var pending []ContractJob
+    for _, status := range []string{JobStatusSubmitted, JobStatusAccepted} {
+        jobs, err := p.client(from).ListJobs(ctx, JobFilter{Provider: p.providerAddr, Status: status})
+        if err != nil {
+            log.Printf("⚠️ Could not list %s jobs on %s: %v", status, from, err)
+            continue
+        }
+        pending = append(pending, jobs...)
+    }
+
+    mc.mu.Lock()
+    if served {
+        mc.retired = true
+    } else {
+        mc.addr = to
+    }
+    bidder, conn := mc.bidder, mc.wsClient
+    mc.mu.Unlock()
+
+    if bidder != nil {
+        if served {
+            bidder.Stop()
+        } else {
+            bidder.SetClient(p.client(to))
+        }
+    }
+    // The subscription reconnects with the new contract's query, or ends when retired
+    if conn != nil {
+        conn.Close()
+    }
+
+    for _, job := range pending {
+        if p.isActive(from, job.ID) {
+            log.Printf("  Job %d keeps running against %s", job.ID, from)
+            continue
+        }
+        log.Printf("  Job %d (%s) pending on %s, processing it there", job.ID, job.Status, from)
+        go p.processJob(ctx, from, job.ID)
+    }
+
+    log.Printf("🔀 Migrated to %s, %d pending jobs finish on %s", to, len(pending), from)
+    if p.migration.OnMigrated != nil {
+        p.migration.OnMigrated(from, to)
+    }
+    return nil
+}
@@ -0,0 +1,326 @@
+package contract
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+
+    "github.com/cosmos/cosmos-sdk/client"
+    "github.com/cosmos/cosmos-sdk/client/tx"
+    codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+    "github.com/cosmos/gogoproto/jsonpb"
+    sdk "github.com/cosmos/cosmos-sdk/types"
+    authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+    "google.golang.org/protobuf/encoding/protowire"
+
+    "github.com/oxygene76/medasdigital-client/pkg/blockchain"
+    "github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// Proto names of the wasm module types used here, the module itself is not a dependency
+const (
+    msgExecuteContractName = "cosmwasm.wasm.v1.MsgExecuteContract"
+    smartQueryPath         = "/cosmwasm.wasm.v1.Query/SmartContractState"
+)
+
+// MsgExecuteContract mirrors cosmwasm.wasm.v1.MsgExecuteContract with hand written proto encoding
+type MsgExecuteContract struct {
+    Sender   string    `json:"sender"`
+    Contract string    `json:"contract"`
+    Msg      []byte    `json:"msg"`
+    Funds    sdk.Coins `json:"funds"`
+}
+
+var _ sdk.Msg = (*MsgExecuteContract)(nil)
+
+// RegisterInterfaces makes MsgExecuteContract known to the interface registry
+func RegisterInterfaces(registry codectypes.InterfaceRegistry) {
+    registry.RegisterImplementations((*sdk.Msg)(nil), &MsgExecuteContract{})
+}
+
+func (m *MsgExecuteContract) Reset()                  { *m = MsgExecuteContract{} }
+func (m *MsgExecuteContract) ProtoMessage()           {}
+func (m *MsgExecuteContract) XXX_MessageName() string { return msgExecuteContractName }
+func (m *MsgExecuteContract) String() string {
+    return fmt.Sprintf("MsgExecuteContract{Sender: %s, Contract: %s, Msg: %s, Funds: %s}", m.Sender, m.Contract, m.Msg, m.Funds)
+}
+
+// Marshal encodes the message in protobuf wire format
+func (m *MsgExecuteContract) Marshal() ([]byte, error) {
+    var b []byte
+    b = appendString(b, 1, m.Sender)
+    b = appendString(b, 2, m.Contract)
+    if len(m.Msg) > 0 {
+        b = protowire.AppendTag(b, 3, protowire.BytesType)
+        b = protowire.AppendBytes(b, m.Msg)
+    }
+    for _, coin := range m.Funds {
+        var c []byte
+        c = appendString(c, 1, coin.Denom)
+        c = appendString(c, 2, coin.Amount.String())
+        b = protowire.AppendTag(b, 5, protowire.BytesType)
+        b = protowire.AppendBytes(b, c)
+    }
+    return b, nil
+}
+
+// Unmarshal decodes the protobuf wire format
+func (m *MsgExecuteContract) Unmarshal(data []byte) error {
+    m.Reset()
+    return decodeFields(data, func(num protowire.Number, value []byte) error {
+        switch num {
+        case 1:
+            m.Sender = string(value)
+        case 2:
+            m.Contract = string(value)
+        case 3:
+            m.Msg = append([]byte(nil), value...)
+        case 5:
+            var denom, amount string
+            if err := decodeFields(value, func(num protowire.Number, v []byte) error {
+                switch num {
+                case 1:
+                    denom = string(v)
+                case 2:
+                    amount = string(v)
+                }
+                return nil
+            }); err != nil {
+                return err
+            }
+            coin, err := sdk.ParseCoinNormalized(amount + denom)
+            if err != nil {
+                return err
+            }
+            m.Funds = append(m.Funds, coin)
+        }
+        return nil
+    })
+}
+
+// MarshalJSONPB renders the message like wasmd, the contract message as JSON
+// instead of base64. The codec uses it e.g. for dry runs.
+func (m *MsgExecuteContract) MarshalJSONPB(*jsonpb.Marshaler) ([]byte, error) {
+    funds := m.Funds
+    if funds == nil {
+        funds = sdk.Coins{}
+    }
+    msg := json.RawMessage(m.Msg)
+    if !json.Valid(msg) {
+        // Not JSON, base64 as the plain proto JSON has it
+        msg, _ = json.Marshal(m.Msg)
+    }
+    return json.Marshal(struct {
+        Sender   string          `json:"sender"`
+        Contract string          `json:"contract"`
+        Msg      json.RawMessage `json:"msg"`
+        Funds    sdk.Coins       `json:"funds"`
+    }{m.Sender, m.Contract, msg, funds})
+}
+
+// Size returns the encoded length
+func (m *MsgExecuteContract) Size() int {
+    b, _ := m.Marshal()
+    return len(b)
+}
+
+// ContractClient signs and broadcasts contract messages with the local keyring
+// instead of shelling out to medasdigitald
+type ContractClient struct {
+    clientCtx     client.Context
+    contract      string
+    gasPrices     string
+    gasAdjustment float64
+}
+
+// NewContractClient needs a client context with keyring, RPC client, chain ID and from name/address
+func NewContractClient(clientCtx client.Context, contractAddr, gasPrices string) *ContractClient {
+    return &ContractClient{
+        clientCtx:     clientCtx,
+        contract:      contractAddr,
+        gasPrices:     gasPrices,
+        gasAdjustment: 1.3,
+    }
+}
+
+// Execute signs and broadcasts msg, funds are sent along to the contract
+func (c *ContractClient) Execute(ctx context.Context, msg ExecuteMsg, funds sdk.Coins) (*sdk.TxResponse, error) {
+    payload, err := json.Marshal(msg)
+    if err != nil {
+        return nil, fmt.Errorf("failed to encode contract message: %w", err)
+    }
+    sender := c.clientCtx.GetFromAddress()
+    if sender.Empty() {
+        return nil, fmt.Errorf("no sender set in client context")
+    }
+    execMsg := &MsgExecuteContract{
+        Sender:   sender.String(),
+        Contract: c.contract,
+        Msg:      payload,
+        Funds:    funds,
+    }
+
+    accountRetriever := authtypes.AccountRetriever{}
+    stopTimer := telemetry.Track(telemetry.CategoryRPC, "get_account")
+    account, err := accountRetriever.GetAccount(c.clientCtx.WithCmdContext(ctx), sender)
+    stopTimer()
+    if err != nil {
+        return nil, fmt.Errorf("failed to get account info: %w", err)
+    }
+
+    txf := tx.Factory{}.
+        WithChainID(c.clientCtx.ChainID).
+        WithKeybase(c.clientCtx.Keyring).
+        WithTxConfig(c.clientCtx.TxConfig).
+        WithAccountRetriever(accountRetriever).
+        WithAccountNumber(account.GetAccountNumber()).
+        WithSequence(account.GetSequence()).
+        WithGasAdjustment(c.gasAdjustment).
+        WithGasPrices(c.gasPrices).
+        WithFeeGranter(c.clientCtx.FeeGranter)
+
+    stopTimer = telemetry.Track(telemetry.CategoryRPC, "simulate_tx")
+    _, gas, err := tx.CalculateGas(c.clientCtx, txf, execMsg)
+    stopTimer()
+    if err != nil {
+        return nil, fmt.Errorf("gas estimation failed: %w", blockchain.SimulationError(err))
+    }
+    txf = txf.WithGas(gas)
+
+    txBuilder, err := txf.BuildUnsignedTx(execMsg)
+    if err != nil {
+        return nil, fmt.Errorf("failed to build transaction: %w", err)
+    }
+
+    stopTimer = telemetry.Track(telemetry.CategorySigning, "sign_tx")
+    err = tx.Sign(ctx, txf, c.clientCtx.GetFromName(), txBuilder, true)
+    stopTimer()
+    if err != nil {
+        return nil, fmt.Errorf("failed to sign transaction: %w", err)
+    }
+
+    txBytes, err := c.clientCtx.TxConfig.TxEncoder()(txBuilder.GetTx())
+    if err != nil {
+        return nil, fmt.Errorf("failed to encode transaction: %w", err)
+    }
+
+    stopTimer = telemetry.Track(telemetry.CategoryRPC, "broadcast_tx")
+    res, err := blockchain.BroadcastContext(ctx, c.clientCtx, txBytes)
+    stopTimer()
+    if err != nil {
+        return nil, fmt.Errorf("failed to broadcast transaction: %w", err)
+    }
+    if res.Code != 0 {
+        return res, &blockchain.TxFailedError{TxHash: res.TxHash, Codespace: res.Codespace, Code: res.Code, Log: res.RawLog}
+    }
+    return res, nil
+}
+
+// Query runs a smart query and decodes the contract's JSON answer into out
+func (c *ContractClient) Query(ctx context.Context, query QueryMsg, out interface{}) error {
+    payload, err := json.Marshal(query)
+    if err != nil {
+        return fmt.Errorf("failed to encode query: %w", err)
+    }
+
+    var req []byte
+    req = appendString(req, 1, c.contract)
+    req = protowire.AppendTag(req, 2, protowire.BytesType)
+    req = protowire.AppendBytes(req, payload)
+
+    defer telemetry.Track(telemetry.CategoryRPC, "smart_query")()
+    value, _, err := blockchain.QueryContext(ctx, c.clientCtx, smartQueryPath, req)
+    if err != nil {
+        return fmt.Errorf("smart query failed: %w", err)
+    }
+
+    var data []byte
+    if err := decodeFields(value, func(num protowire.Number, value []byte) error {
+        if num == 1 {
+            data = value
+        }
+        return nil
+    }); err != nil {
+        return fmt.Errorf("invalid query response: %w", err)
+    }
+    return json.Unmarshal(data, out)
+}
+
+// SubmitJob pays for and submits a job
+func (c *ContractClient) SubmitJob(ctx context.Context, provider, jobType string, parameters map[string]interface{}, payment sdk.Coins) (*sdk.TxResponse, error) {
+    msg, err := NewSubmitJobMsg(provider, jobType, parameters)
+    if err != nil {
+        return nil, err
+    }
+    return c.Execute(ctx, msg, payment)
+}
+
+// AcceptJob is sent by the provider that takes a job
+func (c *ContractClient) AcceptJob(ctx context.Context, jobID uint64) (*sdk.TxResponse, error) {
+    return c.Execute(ctx, ExecuteMsg{AcceptJob: &JobRef{JobID: jobID}}, nil)
+}
+
+// CompleteJob anchors the result of a job
+func (c *ContractClient) CompleteJob(ctx context.Context, jobID uint64, resultHash, resultURL string) (*sdk.TxResponse, error) {
+    return c.Execute(ctx, ExecuteMsg{CompleteJob: &CompleteJobMsg{JobID: jobID, ResultHash: resultHash, ResultURL: resultURL}}, nil)
+}
+
+// FailJob marks a job as failed, the client can then claim a refund
+func (c *ContractClient) FailJob(ctx context.Context, jobID uint64, reason string) (*sdk.TxResponse, error) {
+    return c.Execute(ctx, ExecuteMsg{FailJob: &FailJobMsg{JobID: jobID, Reason: reason}}, nil)
+}
+
+// HeartBeat keeps the provider marked as active
+func (c *ContractClient) HeartBeat(ctx context.Context) (*sdk.TxResponse, error) {
+    return c.Execute(ctx, ExecuteMsg{HeartBeat: &struct{}{}}, nil)
+}
+
+// RegisterProvider registers the sender as provider
+func (c *ContractClient) RegisterProvider(ctx context.Context, reg RegisterProviderMsg) (*sdk.TxResponse, error) {
+    return c.Execute(ctx, ExecuteMsg{RegisterProvider: &reg}, nil)
+}
+
+// GetJob queries a job
+func (c *ContractClient) GetJob(ctx context.Context, jobID uint64) (*ContractJob, error) {
+    var job ContractJob
+    if err := c.Query(ctx, QueryMsg{GetJob: &JobRef{JobID: jobID}}, &job); err != nil {
+        return nil, err
+    }
+    return &job, nil
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+    if s == "" {
+        return b
+    }
+    b = protowire.AppendTag(b, num, protowire.BytesType)
+    return protowire.AppendString(b, s)
+}
+
+// decodeFields walks the length delimited fields of a message, other wire types are skipped
+func decodeFields(data []byte, fn func(num protowire.Number, value []byte) error) error {
+    for len(data) > 0 {
+        num, typ, n := protowire.ConsumeTag(data)
+        if n < 0 {
+            return protowire.ParseError(n)
+        }
+        data = data[n:]
+        if typ != protowire.BytesType {
+            n = protowire.ConsumeFieldValue(num, typ, data)
+            if n < 0 {
+                return protowire.ParseError(n)
+            }
+            data = data[n:]
+            continue
+        }
+        value, n := protowire.ConsumeBytes(data)
+        if n < 0 {
+            return protowire.ParseError(n)
+        }
+        if err := fn(num, value); err != nil {
+            return err
+        }
+        data = data[n:]
+    }
+    return nil
+}
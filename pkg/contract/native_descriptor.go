@@ -0,0 +1,82 @@
+package contract
+
+import (
+    "bytes"
+    "compress/gzip"
+
+    msgv1 "cosmossdk.io/api/cosmos/msg/v1"
+    gogoproto "github.com/cosmos/gogoproto/proto"
+    "google.golang.org/protobuf/proto"
+    "google.golang.org/protobuf/types/descriptorpb"
+)
+
+// msgExecuteContractFile is the proto file MsgExecuteContract is declared in upstream
+const msgExecuteContractFile = "cosmwasm/wasm/v1/tx.proto"
+
+// msgExecuteContractDescriptor is the gzipped file descriptor, SDK 0.50 resolves
+// signers and sign mode encoding from it
+var msgExecuteContractDescriptor = buildMsgExecuteContractDescriptor()
+
+func init() {
+    gogoproto.RegisterFile(msgExecuteContractFile, msgExecuteContractDescriptor)
+    gogoproto.RegisterType((*MsgExecuteContract)(nil), msgExecuteContractName)
+}
+
+// Descriptor returns the gzipped file descriptor and the message index in it
+func (*MsgExecuteContract) Descriptor() ([]byte, []int) {
+    return msgExecuteContractDescriptor, []int{0}
+}
+
+// buildMsgExecuteContractDescriptor declares only the fields of the upstream message,
+// the wire format is compatible with wasmd
+func buildMsgExecuteContractDescriptor() []byte {
+    field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, label descriptorpb.FieldDescriptorProto_Label, typeName string) *descriptorpb.FieldDescriptorProto {
+        f := &descriptorpb.FieldDescriptorProto{
+            Name:     proto.String(name),
+            Number:   proto.Int32(number),
+            Type:     typ.Enum(),
+            Label:    label.Enum(),
+            JsonName: proto.String(name),
+        }
+        if typeName != "" {
+            f.TypeName = proto.String(typeName)
+        }
+        return f
+    }
+
+    optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+    options := &descriptorpb.MessageOptions{}
+    proto.SetExtension(options, msgv1.E_Signer, []string{"sender"})
+
+    file := &descriptorpb.FileDescriptorProto{
+        Name:       proto.String(msgExecuteContractFile),
+        Package:    proto.String("cosmwasm.wasm.v1"),
+        Dependency: []string{"cosmos/base/v1beta1/coin.proto", "cosmos/msg/v1/msg.proto"},
+        Syntax:     proto.String("proto3"),
+        Options:    &descriptorpb.FileOptions{GoPackage: proto.String("github.com/CosmWasm/wasmd/x/wasm/types")},
+        MessageType: []*descriptorpb.DescriptorProto{{
+            Name: proto.String("MsgExecuteContract"),
+            Field: []*descriptorpb.FieldDescriptorProto{
+                field("sender", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional, ""),
+                field("contract", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional, ""),
+                field("msg", 3, descriptorpb.FieldDescriptorProto_TYPE_BYTES, optional, ""),
+                field("funds", 5, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, descriptorpb.FieldDescriptorProto_LABEL_REPEATED, ".cosmos.base.v1beta1.Coin"),
+            },
+            Options: options,
+        }},
+    }
+
+    raw, err := proto.Marshal(file)
+    if err != nil {
+        panic(err)
+    }
+    var buf bytes.Buffer
+    zw := gzip.NewWriter(&buf)
+    if _, err := zw.Write(raw); err != nil {
+        panic(err)
+    }
+    if err := zw.Close(); err != nil {
+        panic(err)
+    }
+    return buf.Bytes()
+}
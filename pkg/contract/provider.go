@@ -13,14 +13,21 @@ import (
     "strconv"
     "strings"
     "sync"
+    "sync/atomic"
     "time"
 
     "github.com/gorilla/websocket"
     "github.com/oxygene76/medasdigital-client/pkg/compute"
+    "github.com/oxygene76/medasdigital-client/pkg/network"
+    "github.com/oxygene76/medasdigital-client/pkg/openapi"
 )
 
 type ProviderNode struct {
-    contractAddr         string
+    contracts            []*marketContract // served contracts, the primary first
+    contractMu           sync.RWMutex
+    accounts             map[string]*ContractAccount // by contract address
+    accountsMu           sync.Mutex
+    migration            *MigrationConfig // nil: no successor contract is watched
     providerAddr         string
     providerKey          string
     rpcURL               string
@@ -29,18 +36,24 @@ type ProviderNode struct {
     endpointURL          string
     httpPort             int
     workers              int
-    fundingAddress       string
-    minBalance           uint64
-    maxBalance           uint64
     harvestInterval      time.Duration
+    harvestRules         []HarvestRule // per denom, the funding address makes the default rule
     jobManager           *compute.JobManager
-    wsClient             *websocket.Conn
     results              map[string]*compute.ComputeJob  // NEW: Store results
+    attestations         map[string]*Attestation         // by compute job ID
     resultsMu            sync.RWMutex                     // NEW: Mutex for thread-safe access
+    signer               SignFunc // signs result attestations, nil = unsigned completions
     heartbeatInterval    time.Duration 
-    reconnectAttempts    int           
     maxReconnectAttempts int     
-    lastHeartbeat        time.Time 
+    store                *JobStore // persists contract jobs for crash recovery, nil keeps them in memory
+    adminToken           string // bearer token of the /admin endpoints, empty disables them
+    paused               atomic.Bool // new jobs are declined while set
+    jobLogs              map[string][]JobLogLine // by jobKey
+    jobLogOrder          []string // keys of jobLogs, oldest first
+    logsMu               sync.Mutex
+    bidding              *BiddingConfig // open market jobs are taken on every contract, nil when disabled
+    activeJobs           map[string]*ActiveJob // by jobKey
+    activeMu             sync.Mutex
 }
 
 func NewProviderNode(
@@ -52,8 +65,18 @@ func NewProviderNode(
     harvestIntervalHours int,
     heartbeatIntervalMinutes int,
 ) *ProviderNode {
+    var harvestRules []HarvestRule
+    if fundingAddress != "" {
+        harvestRules = []HarvestRule{{
+            Denom:        network.Current().BaseDenom,
+            MinBalance:   minBalance,
+            MaxBalance:   maxBalance,
+            Destinations: []HarvestDestination{{Address: fundingAddress, Percent: 100}},
+        }}
+    }
     return &ProviderNode{
-        contractAddr:    contractAddr,
+        contracts:       []*marketContract{newMarketContract(contractAddr)},
+        accounts:        make(map[string]*ContractAccount),
         providerAddr:    providerAddr,
         providerKey:     providerKey,
         rpcURL:          rpcURL,
@@ -62,16 +85,43 @@ func NewProviderNode(
         endpointURL:     endpointURL,
         httpPort:        httpPort,
         workers:         workers,
-        fundingAddress:  fundingAddress,
-        minBalance:      minBalance,
-        maxBalance:      maxBalance,
         harvestInterval: time.Duration(harvestIntervalHours) * time.Hour,
-        jobManager: compute.NewJobManager(workers, 100, compute.NewPricingManager("medas1kc7lctfazdpd8y6ecapdfv3d6ch97prc58qaem")),
+        harvestRules:    harvestRules,
+        jobManager: compute.NewJobManager(workers, 100, compute.NewPricingManager(network.Current().CommunityAddress)),
         heartbeatInterval:    time.Duration(heartbeatIntervalMinutes) * time.Minute, 
         maxReconnectAttempts: 10, 
         results:         make(map[string]*compute.ComputeJob), // NEW: Initialize results map
-        lastHeartbeat: time.Now(), 
+        attestations:    make(map[string]*Attestation),
+        activeJobs:    make(map[string]*ActiveJob),
+    }
+}
+
+// SetGPUScheduler lets jobs with a gpu_memory_mb parameter run on the least loaded GPU
+func (p *ProviderNode) SetGPUScheduler(scheduler *compute.GPUScheduler) {
+    p.jobManager.SetGPUScheduler(scheduler)
+}
+
+// SetSandbox runs jobs in a container instead of the provider process
+func (p *ProviderNode) SetSandbox(sandbox *compute.Sandbox) {
+    p.jobManager.SetSandbox(sandbox)
+}
+
+// SetAttestationSigner signs a receipt for every completed job with the
+// provider key, it is sent with complete_job and served with the result
+func (p *ProviderNode) SetAttestationSigner(sign SignFunc) {
+    p.signer = sign
+}
+
+// SetBidding lets the node bid on or accept open market jobs, every served
+// contract gets its own bidder with these limits
+func (p *ProviderNode) SetBidding(cfg BiddingConfig) error {
+    bidder, err := NewBidder(cfg, p.client(p.contract()), p.providerAddr, p.processJob)
+    if err != nil {
+        return err
     }
+    cfg = bidder.Config()
+    p.bidding = &cfg
+    return nil
 }
 
 func (p *ProviderNode) Start(ctx context.Context) error {
@@ -79,35 +129,102 @@ func (p *ProviderNode) Start(ctx context.Context) error {
     log.Printf("  Name: %s", p.providerName)
     log.Printf("  Address: %s", p.providerAddr)
     log.Printf("  Endpoint: %s", p.endpointURL)
+    contracts := p.marketContracts()
+    for _, mc := range contracts {
+        log.Printf("  Contract: %s", mc.addr)
+    }
     log.Printf("  Listening for jobs...")
 
     if p.heartbeatInterval > 0 {
-        go p.heartbeatRoutine(ctx)
+        for _, mc := range contracts {
+            go p.heartbeatRoutine(ctx, mc)
+        }
     }
     
-    if p.fundingAddress != "" {
+    if len(p.harvestRules) > 0 && p.harvestInterval > 0 {
         log.Printf("  Auto-Harvest enabled:")
-        log.Printf("    Funding Address: %s", p.fundingAddress)
-        log.Printf("    Min Balance: %d umedas", p.minBalance)
-        log.Printf("    Max Balance: %d umedas", p.maxBalance)
+        for _, rule := range p.harvestRules {
+            log.Printf("    %s: keep %d, harvest above %d", rule.Denom, rule.MinBalance, rule.MaxBalance)
+            for _, d := range rule.Destinations {
+                log.Printf("      %.2f%% → %s %s", d.Percent, d.Address, d.Label)
+            }
+        }
         log.Printf("    Check Interval: %v", p.harvestInterval)
         go p.autoHarvest(ctx)
     } else {
-        log.Printf("  Auto-Harvest disabled (no funding_address set)")
+        log.Printf("  Auto-Harvest disabled (no funding_address or harvest rules set)")
+    }
+
+    if p.migration != nil {
+        go p.migrationRoutine(ctx)
     }
 
+    if p.bidding != nil {
+        bc := *p.bidding
+        log.Printf("  Bidding on open jobs: strategy %s, floor %d, max %d concurrent per contract, dry-run %v",
+            bc.Strategy, bc.PriceFloor, bc.MaxConcurrent, bc.DryRun)
+        for _, mc := range contracts {
+            bidder, err := NewBidder(bc, p.client(mc.addr), p.providerAddr, p.processJob)
+            if err != nil {
+                return err
+            }
+            mc.bidder = bidder
+            go bidder.Run(ctx)
+        }
+    }
+
+    if p.store != nil {
+        p.recoverJobs(ctx)
+    }
+
+    go p.deadlineRoutine(ctx)
     go p.startHTTPServer(ctx)
     
-    return p.subscribeWithReconnect(ctx)
+    var wg sync.WaitGroup
+    for _, mc := range contracts {
+        wg.Add(1)
+        go func(mc *marketContract) {
+            defer wg.Done()
+            p.subscribeWithReconnect(ctx, mc)
+        }(mc)
+    }
+    wg.Wait()
+    return ctx.Err()
+}
+
+// contract is the primary marketplace contract, registration and migration
+// start from it
+func (p *ProviderNode) contract() string {
+    p.contractMu.RLock()
+    defer p.contractMu.RUnlock()
+    for _, mc := range p.contracts {
+        if !mc.isRetired() {
+            return mc.address()
+        }
+    }
+    return ""
+}
+
+// client returns a contract client signing with the provider key
+func (p *ProviderNode) client(contractAddr string) *Client {
+    return NewClient(Config{
+        ContractAddress: contractAddr,
+        RPCEndpoint:     p.rpcURL,
+        ChainID:         p.chainID,
+    }, p.providerKey, p.providerAddr, "test")
 }
 
 // KOMPLETT NEU - Diese Funktionen einfügen:
 
-func (p *ProviderNode) heartbeatRoutine(ctx context.Context) {
+// heartbeatRoutine keeps the provider alive on one contract, a failing
+// contract does not hold up the heartbeats of the others
+func (p *ProviderNode) heartbeatRoutine(ctx context.Context, mc *marketContract) {
     ticker := time.NewTicker(p.heartbeatInterval)
     defer ticker.Stop()
     
-    p.sendHeartbeat() // Initial heartbeat
+    if err := p.sendHeartbeat(mc); err != nil { // Initial heartbeat
+        log.Printf("❌ Heartbeat to %s failed: %v", mc.address(), err)
+    }
     
     for {
         select {
@@ -115,24 +232,27 @@ func (p *ProviderNode) heartbeatRoutine(ctx context.Context) {
             log.Println("Heartbeat routine stopped")
             return
         case <-ticker.C:
-            if err := p.sendHeartbeat(); err != nil {
-                log.Printf("❌ Heartbeat failed: %v", err)
+            if mc.isRetired() {
+                return
+            }
+            if err := p.sendHeartbeat(mc); err != nil {
+                log.Printf("❌ Heartbeat to %s failed: %v", mc.address(), err)
             }
         }
     }
 }
 
-func (p *ProviderNode) sendHeartbeat() error {
-    msg := `{"heart_beat":{}}`
+func (p *ProviderNode) sendHeartbeat(mc *marketContract) error {
+    msg := ExecuteMsg{HeartBeat: &struct{}{}}.JSON()
     
     cmd := exec.Command(
         "medasdigitald", "tx", "wasm", "execute",
-        p.contractAddr, msg,
+        mc.address(), msg,
         "--from", p.providerKey,
         "--keyring-backend", "test",
         "--gas", "auto",
         "--gas-adjustment", "1.3",
-        "--gas-prices", "0.025umedas",
+        "--gas-prices", network.Current().GasPrice,
         "--node", p.rpcURL,
         "--chain-id", p.chainID,
         "-y",
@@ -147,11 +267,15 @@ func (p *ProviderNode) sendHeartbeat() error {
         return fmt.Errorf("heartbeat tx failed: %w", err)
     }
     
-    p.lastHeartbeat = time.Now()  // ADD THIS
-    log.Printf("💓 Heartbeat sent successfully at %s", p.lastHeartbeat.Format("15:04:05"))
+    mc.mu.Lock()
+    mc.lastHeartbeat = time.Now()
+    mc.mu.Unlock()
+    log.Printf("💓 Heartbeat sent to %s at %s", mc.address(), time.Now().Format("15:04:05"))
     return nil
 }
-func (p *ProviderNode) subscribeWithReconnect(ctx context.Context) error {
+
+// subscribeWithReconnect keeps the job subscription of one contract open
+func (p *ProviderNode) subscribeWithReconnect(ctx context.Context, mc *marketContract) error {
     backoff := time.Second
     maxBackoff := time.Minute
     
@@ -160,24 +284,36 @@ func (p *ProviderNode) subscribeWithReconnect(ctx context.Context) error {
         case <-ctx.Done():
             return ctx.Err()
         default:
-            err := p.subscribeToJobs(ctx)
+            err := p.subscribeToJobs(ctx, mc)
+            
+            mc.mu.Lock()
+            mc.wsClient = nil
+            if err == nil {
+                mc.reconnectAttempts = 0
+            } else {
+                mc.reconnectAttempts++
+            }
+            attempts := mc.reconnectAttempts
+            mc.mu.Unlock()
             
             if err == nil {
-                p.reconnectAttempts = 0
+                return nil
+            }
+            if mc.isRetired() {
+                log.Printf("Subscription to %s stopped, the contract was replaced", mc.address())
                 return nil
             }
             
-            log.Printf("❌ WebSocket error: %v", err)
-            p.reconnectAttempts++
+            log.Printf("❌ WebSocket error (%s): %v", mc.address(), err)
             
             // Nach 10 Versuchen längere Pause, aber nicht aufgeben
-            if p.reconnectAttempts % 10 == 0 {
+            if attempts % 10 == 0 {
                 log.Printf("⏳ Failed 10 times, waiting 2 minutes...")
                 backoff = 2 * time.Minute
             }
             
             log.Printf("🔄 Reconnecting in %v (attempt %d)", 
-                backoff, p.reconnectAttempts)
+                backoff, attempts)
             
             select {
             case <-time.After(backoff):
@@ -209,17 +345,17 @@ func (p *ProviderNode) pingRoutine(conn *websocket.Conn, ctx context.Context) {
     }
 }
 
-func (p *ProviderNode) failJob(jobID uint64, reason string) error {
-    msg := fmt.Sprintf(`{"fail_job":{"job_id":%d,"reason":"%s"}}`, jobID, reason)
+func (p *ProviderNode) failJob(contractAddr string, jobID uint64, reason string) error {
+    msg := ExecuteMsg{FailJob: &FailJobMsg{JobID: jobID, Reason: reason}}.JSON()
     
     cmd := exec.Command(
         "medasdigitald", "tx", "wasm", "execute",
-        p.contractAddr, msg,
+        contractAddr, msg,
         "--from", p.providerKey,
         "--keyring-backend", "test",
         "--gas", "auto",
         "--gas-adjustment", "1.3",
-        "--gas-prices", "0.025umedas",
+        "--gas-prices", network.Current().GasPrice,
         "--node", p.rpcURL,
         "--chain-id", p.chainID,
         "-y",
@@ -234,7 +370,11 @@ func (p *ProviderNode) failJob(jobID uint64, reason string) error {
         return fmt.Errorf("fail job tx failed: %w", err)
     }
     
-    log.Printf("❌ Job %d marked as failed: %s", jobID, reason)
+    p.persist(contractAddr, jobID, func(j *StoredJob) {
+        j.State, j.Error = StoredFailed, reason
+    })
+    p.account(contractAddr, false, "")
+    p.jobLog(contractAddr, jobID, "❌ Job %d marked as failed: %s", jobID, reason)
     return nil
 }
 
@@ -253,27 +393,41 @@ func (p *ProviderNode) autoHarvest(ctx context.Context) {
     }
 }
 
+// harvestExcessBalance applies every harvest rule, a failed transfer does
+// not stop the others
 func (p *ProviderNode) harvestExcessBalance() {
-    balance, err := p.getProviderBalance()
+    balances, err := p.getProviderBalances()
     if err != nil {
         log.Printf("Failed to get balance: %v", err)
         return
     }
     
-    if balance <= p.maxBalance {
-        log.Printf("Balance check: %d umedas (below threshold)", balance)
-        return
+    for _, rule := range p.harvestRules {
+        balance := balances[rule.Denom]
+        transfers := rule.Harvest(balance)
+        if len(transfers) == 0 {
+            log.Printf("Balance check: %d %s (below threshold)", balance, rule.Denom)
+            continue
+        }
+        
+        log.Printf("💰 Harvesting %d %s", balance-rule.MinBalance, rule.Denom)
+        for _, t := range transfers {
+            if err := p.sendHarvest(t.Destination.Address, t.Amount, rule.Denom); err != nil {
+                log.Printf("❌ Harvest of %d %s to %s failed: %v", t.Amount, rule.Denom, t.Destination.Address, err)
+                continue
+            }
+            log.Printf("✅ Harvested %d %s to %s %s", t.Amount, rule.Denom, t.Destination.Address, t.Destination.Label)
+        }
     }
-    
-    transfer := balance - p.minBalance
-    log.Printf("💰 Harvesting %d umedas to funding wallet", transfer)
-    
+}
+
+func (p *ProviderNode) sendHarvest(to string, amount uint64, denom string) error {
     cmd := exec.Command(
         "medasdigitald", "tx", "bank", "send",
-        p.providerKey, p.fundingAddress, fmt.Sprintf("%dumedas", transfer),
+        p.providerKey, to, fmt.Sprintf("%d%s", amount, denom),
         "--keyring-backend", "test",
         "--gas", "200000",
-        "--fees", "5000umedas",
+        "--fees", "5000"+network.Current().BaseDenom,
         "--node", p.rpcURL,
         "--chain-id", p.chainID,
         "-y",
@@ -285,13 +439,13 @@ func (p *ProviderNode) harvestExcessBalance() {
     cmd.Stderr = &stderr
     
     if err := cmd.Run(); err != nil {
-        log.Printf("❌ Harvest failed: %v\nstderr: %s", err, stderr.String())
-    } else {
-        log.Printf("✅ Successfully harvested %d umedas", transfer)
+        return fmt.Errorf("%w\nstderr: %s", err, stderr.String())
     }
+    return nil
 }
 
-func (p *ProviderNode) getProviderBalance() (uint64, error) {
+// getProviderBalances returns the provider's balances by denom
+func (p *ProviderNode) getProviderBalances() (map[string]uint64, error) {
     cmd := exec.Command(
         "medasdigitald", "query", "bank", "balances", p.providerAddr,
         "--node", p.rpcURL,
@@ -300,7 +454,7 @@ func (p *ProviderNode) getProviderBalance() (uint64, error) {
     
     output, err := cmd.Output()
     if err != nil {
-        return 0, err
+        return nil, err
     }
     
     var result struct {
@@ -311,24 +465,27 @@ func (p *ProviderNode) getProviderBalance() (uint64, error) {
     }
     
     if err := json.Unmarshal(output, &result); err != nil {
-        return 0, err
+        return nil, err
     }
     
+    balances := make(map[string]uint64, len(result.Balances))
     for _, balance := range result.Balances {
-        if balance.Denom == "umedas" {
-            amount, err := strconv.ParseUint(balance.Amount, 10, 64)
-            if err != nil {
-                return 0, err
-            }
-            return amount, nil
+        amount, err := strconv.ParseUint(balance.Amount, 10, 64)
+        if err != nil {
+            return nil, err
         }
+        balances[balance.Denom] = amount
     }
     
-    return 0, nil
+    return balances, nil
 }
 
-func (p *ProviderNode) subscribeToJobs(ctx context.Context) error {
-    wsURL := "wss://rpc.medas-digital.io:26657/websocket"
+func (p *ProviderNode) subscribeToJobs(ctx context.Context, mc *marketContract) error {
+    contractAddr := mc.address()
+    wsURL := network.Current().WSEndpoint
+    if wsURL == "" {
+        wsURL = network.WebsocketURL(p.rpcURL)
+    }
     
     dialer := websocket.Dialer{
         HandshakeTimeout: 10 * time.Second,
@@ -337,7 +494,9 @@ func (p *ProviderNode) subscribeToJobs(ctx context.Context) error {
     if err != nil {
         return fmt.Errorf("websocket dial failed: %w", err)
     }
-    p.wsClient = conn
+    mc.mu.Lock()
+    mc.wsClient = conn
+    mc.mu.Unlock()
     defer conn.Close()
 
     conn.SetReadLimit(1024 * 1024) // 1MB max message size
@@ -349,7 +508,7 @@ func (p *ProviderNode) subscribeToJobs(ctx context.Context) error {
     
     query := fmt.Sprintf(
         "wasm._contract_address='%s' AND wasm.action='submit_job' AND wasm.provider='%s'",
-        p.contractAddr,
+        contractAddr,
         p.providerAddr,
     )
     
@@ -368,7 +527,7 @@ func (p *ProviderNode) subscribeToJobs(ctx context.Context) error {
         return fmt.Errorf("subscribe failed: %w", err)
     }
     
-    log.Printf("✅ WebSocket connected and subscribed")
+    log.Printf("✅ WebSocket connected and subscribed to %s", contractAddr)
     go p.pingRoutine(conn, ctx)  // Start ping routine
     
     for {
@@ -391,22 +550,22 @@ func (p *ProviderNode) subscribeToJobs(ctx context.Context) error {
             }
             
             // Process in goroutine to not block
-            go p.processWebSocketMessage(msg, ctx)
+            go p.processWebSocketMessage(msg, contractAddr, ctx)
         }
     }
 }
                      
 
-func (p *ProviderNode) processWebSocketMessage(msg map[string]interface{}, ctx context.Context) {
+func (p *ProviderNode) processWebSocketMessage(msg map[string]interface{}, contractAddr string, ctx context.Context) {
     if result, ok := msg["result"].(map[string]interface{}); ok {
         if events, ok := result["events"].(map[string]interface{}); ok {
-            p.handleJobEvent(ctx, events)
+            p.handleJobEvent(ctx, contractAddr, events)
         } else if data, ok := result["data"].(map[string]interface{}); ok {
             if value, ok := data["value"].(map[string]interface{}); ok {
                 if txResult, ok := value["TxResult"].(map[string]interface{}); ok {
                     if result, ok := txResult["result"].(map[string]interface{}); ok {
                         if evts, ok := result["events"].([]interface{}); ok {
-                            p.handleJobEventArray(ctx, evts)
+                            p.handleJobEventArray(ctx, contractAddr, evts)
                         }
                     }
                 }
@@ -415,7 +574,7 @@ func (p *ProviderNode) processWebSocketMessage(msg map[string]interface{}, ctx c
     }
 }
     
-func (p *ProviderNode) handleJobEventArray(ctx context.Context, events []interface{}) {
+func (p *ProviderNode) handleJobEventArray(ctx context.Context, contractAddr string, events []interface{}) {
     for _, evt := range events {
         if event, ok := evt.(map[string]interface{}); ok {
             if eventType, ok := event["type"].(string); ok && eventType == "wasm" {
@@ -432,8 +591,8 @@ func (p *ProviderNode) handleJobEventArray(ctx context.Context, events []interfa
                         }
                     }
                     if jobID > 0 {
-                        log.Printf("📥 New job received: %d", jobID)
-                        go p.processJob(ctx, jobID)
+                        p.jobLog(contractAddr, jobID, "📥 New job received: %d", jobID)
+                        go p.acceptJob(ctx, contractAddr, jobID)
                     }
                 }
             }
@@ -441,7 +600,7 @@ func (p *ProviderNode) handleJobEventArray(ctx context.Context, events []interfa
     }
 }
 
-func (p *ProviderNode) handleJobEvent(ctx context.Context, events map[string]interface{}) {
+func (p *ProviderNode) handleJobEvent(ctx context.Context, contractAddr string, events map[string]interface{}) {
     wasmEvents, ok := events["wasm.job_id"].([]interface{})
     if !ok || len(wasmEvents) == 0 {
         return
@@ -450,104 +609,229 @@ func (p *ProviderNode) handleJobEvent(ctx context.Context, events map[string]int
     jobIDStr := wasmEvents[0].(string)
     jobID, _ := strconv.ParseUint(jobIDStr, 10, 64)
     
-    log.Printf("📥 New job received: %d", jobID)
+    p.jobLog(contractAddr, jobID, "📥 New job received: %d", jobID)
     
-    go p.processJob(ctx, jobID)
+    go p.acceptJob(ctx, contractAddr, jobID)
 }
 
-func (p *ProviderNode) processJob(ctx context.Context, contractJobID uint64) {
-   cj, err := p.getContractJob(ctx, contractJobID)
+// processJob runs a job of contractAddr, jobs are reported to the contract
+// they were submitted to even when the node migrated meanwhile
+func (p *ProviderNode) processJob(ctx context.Context, contractAddr string, contractJobID uint64) {
+   if stored := p.storedJob(contractAddr, contractJobID); stored != nil && (stored.Finished() || stored.State == StoredComputed) {
+       p.jobLog(contractAddr, contractJobID, "Job %d is already %s, not computing it again", contractJobID, stored.State)
+       return
+   }
+   cj, err := p.getContractJob(ctx, contractAddr, contractJobID)
 if err != nil {
-    log.Printf("Failed to get job: %v", err)
-    p.failJob(contractJobID, "Failed to fetch job details")  // ADD
+    p.jobLog(contractAddr, contractJobID, "Failed to get job: %v", err)
+    p.failJob(contractAddr, contractJobID, "Failed to fetch job details")  // ADD
     return
 }
     
     var params map[string]interface{}
    if err := json.Unmarshal([]byte(cj.Parameters), &params); err != nil {
-    log.Printf("Failed to parse parameters: %v", err)
-    p.failJob(contractJobID, "Invalid job parameters")  // ADD
+    p.jobLog(contractAddr, contractJobID, "Failed to parse parameters: %v", err)
+    p.failJob(contractAddr, contractJobID, "Invalid job parameters")  // ADD
     return
 }
 
     
     
-    log.Printf("Processing job %d: %s", contractJobID, cj.JobType)
+    p.jobLog(contractAddr, contractJobID, "Processing job %d: %s", contractJobID, cj.JobType)
+    p.persist(contractAddr, contractJobID, func(j *StoredJob) {
+        j.JobType, j.Client, j.Payment = cj.JobType, cj.Client, cj.PaymentAmount
+    })
+    if _, ok := params["job_type"]; !ok {
+        if cj.JobType != "" {
+            params["job_type"] = cj.JobType
+        } else if cj.ServiceType != "" {
+            params["job_type"] = cj.ServiceType
+        }
+    }
+    
+    // Stop computing in time to report the failure before the contract deadline
+    jobCtx := ctx
+    active := &ActiveJob{JobID: contractJobID, Contract: contractAddr, JobType: cj.JobType, StartedAt: time.Now()}
+    if deadline, ok := ParseTimestamp(cj.Deadline); ok {
+        if time.Until(deadline) <= DeadlineMargin {
+            p.jobLog(contractAddr, contractJobID, "Job %d deadline %s already passed", contractJobID, deadline.Format(time.RFC3339))
+            p.failJob(contractAddr, contractJobID, "Deadline passed before the job was started")
+            return
+        }
+        var cancel context.CancelFunc
+        jobCtx, cancel = context.WithDeadline(ctx, deadline.Add(-DeadlineMargin))
+        defer cancel()
+        active.Deadline = &deadline
+        p.jobLog(contractAddr, contractJobID, "Job %d deadline: %s (%v left)", contractJobID, deadline.Format(time.RFC3339), time.Until(deadline).Round(time.Second))
+    }
+    p.trackJob(active)
+    defer p.untrackJob(contractAddr, contractJobID)
+    
+    p.persist(contractAddr, contractJobID, func(j *StoredJob) {
+        j.State, j.Progress = StoredRunning, 0
+        j.Attempts++
+    })
+    job, err := p.runJob(jobCtx, params, cj.Client, func(progress int) {
+        p.persist(contractAddr, contractJobID, func(j *StoredJob) { j.Progress = progress })
+    })
+    if err != nil {
+        if jobCtx.Err() == context.DeadlineExceeded {
+            p.jobLog(contractAddr, contractJobID, "⏰ Job %d aborted, deadline exceeded after %v", contractJobID, time.Since(active.StartedAt).Round(time.Second))
+            p.failJob(contractAddr, contractJobID, "Computation exceeded the job deadline")
+            return
+        }
+        p.jobLog(contractAddr, contractJobID, "Job %d failed: %v", contractJobID, err)
+        p.failJob(contractAddr, contractJobID, err.Error())
+        return
+    }
+    
+    resultURL := fmt.Sprintf("%s/results/%s.json", p.endpointURL, job.ID)
+    
+    resultHash := ResultHash(job)
+    attestation := p.attest(job, contractJobID, cj, resultHash, active.StartedAt)
+    
+    // A crash from here on only costs the complete_job broadcast, not the computation
+    p.persist(contractAddr, contractJobID, func(j *StoredJob) {
+        j.State, j.Progress = StoredComputed, 100
+        j.ComputeJobID, j.Result = job.ID, job
+        j.ResultHash, j.ResultURL, j.Attestation = resultHash, resultURL, attestation
+    })
+    
+    p.jobLog(contractAddr, contractJobID, "✅ Job completed, marking as complete in contract")
+    
+    if err := p.submitResult(ctx, contractAddr, contractJobID, resultHash, resultURL, attestation, cj.PaymentAmount); err != nil {
+        p.jobLog(contractAddr, contractJobID, "Failed to complete job in contract: %v", err)
+        return
+    }
+    
+    p.jobLog(contractAddr, contractJobID, "Job %d completed successfully", contractJobID)
+}
+
+// submitResult sends complete_job and books the job as completed
+func (p *ProviderNode) submitResult(ctx context.Context, contractAddr string, jobID uint64, hash, url string, attestation *Attestation, payment string) error {
+    if err := p.completeContractJob(ctx, contractAddr, jobID, hash, url, attestation); err != nil {
+        p.persist(contractAddr, jobID, func(j *StoredJob) { j.Error = err.Error() })
+        return err
+    }
+    p.persist(contractAddr, jobID, func(j *StoredJob) {
+        j.State, j.Error = StoredCompleted, ""
+    })
+    p.account(contractAddr, true, payment)
+    return nil
+}
+
+// RunJob executes a job locally and keeps the result for the /results endpoint.
+// processJob uses it for contract jobs, the e2e harness calls it directly.
+func (p *ProviderNode) RunJob(ctx context.Context, params map[string]interface{}, clientAddr string) (*compute.ComputeJob, error) {
+    return p.runJob(ctx, params, clientAddr, nil)
+}
+
+// runJob is RunJob reporting progress changes to onProgress, if set
+func (p *ProviderNode) runJob(ctx context.Context, params map[string]interface{}, clientAddr string, onProgress func(int)) (*compute.ComputeJob, error) {
+    // Verification level is chosen by the client at submission time
+    verificationStr, _ := params["verification"].(string)
+    verification, err := compute.ParseVerificationLevel(verificationStr)
+    if err != nil {
+        return nil, err
+    }
+    delete(params, "verification")
+    
+    // Jobs without a job_type are PI calculations, like before there were others
+    jobType := compute.JobTypePICalculation
+    if t, _ := params["job_type"].(string); t != "" {
+        jobType = compute.JobType(t)
+    }
+    delete(params, "job_type")
     
     job, err := p.jobManager.SubmitJob(
-        compute.JobTypePICalculation,
+        jobType,
         params,
-        cj.Client,
+        clientAddr,
         compute.TierStandard,
+        verification,
         "",
     )
     if err != nil {
-    log.Printf("Failed to submit job: %v", err)
-    p.failJob(contractJobID, fmt.Sprintf("Processing failed: %v", err))  // ADD
-    return
-}
+        return nil, fmt.Errorf("Processing failed: %v", err)
+    }
     
     // Wait for completion and get final job state
-    var completedJob *compute.ComputeJob
     timeout := time.After(30 * time.Minute)
-ticker := time.NewTicker(1 * time.Second)
-defer ticker.Stop()
-
-for {
-    select {
-    case <-timeout:
-        log.Printf("Job %d timed out", contractJobID)
-        p.failJob(contractJobID, "Job processing timeout")
-        return
-    case <-ticker.C:
-        currentJob, _ := p.jobManager.GetJob(job.ID)
-        if currentJob.Status == compute.StatusCompleted {
-            completedJob = currentJob
-            goto completed
-        }
-        if currentJob.Status == compute.StatusFailed {
-            log.Printf("Job failed")
-            p.failJob(contractJobID, "Computation failed")
-            return
+    ticker := time.NewTicker(1 * time.Second)
+    defer ticker.Stop()
+    lastProgress := 0
+    
+    for {
+        select {
+        case <-ctx.Done():
+            p.jobManager.CancelJob(job.ID)
+            return nil, ctx.Err()
+        case <-timeout:
+            return nil, fmt.Errorf("Job processing timeout")
+        case <-ticker.C:
+            currentJob, _ := p.jobManager.GetJob(job.ID)
+            if onProgress != nil && currentJob.Progress != lastProgress && currentJob.Status == compute.StatusRunning {
+                lastProgress = currentJob.Progress
+                onProgress(lastProgress)
+            }
+            if currentJob.Status == compute.StatusCompleted {
+                p.resultsMu.Lock()
+                p.results[job.ID] = currentJob
+                p.resultsMu.Unlock()
+                return currentJob, nil
+            }
+            if currentJob.Status == compute.StatusFailed {
+                return nil, fmt.Errorf("Computation failed")
+            }
         }
     }
 }
 
-completed:
-    
-    // NEW: Store the result for HTTP retrieval
+// attest signs the receipt of a completed contract job, nil without a signer
+func (p *ProviderNode) attest(job *compute.ComputeJob, contractJobID uint64, cj *ContractJob, resultHash string, startedAt time.Time) *Attestation {
+    if p.signer == nil {
+        return nil
+    }
+    jobType := cj.JobType
+    if jobType == "" {
+        jobType = cj.ServiceType
+    }
+    att := &Attestation{
+        JobID:       contractJobID,
+        Provider:    p.providerAddr,
+        JobType:     jobType,
+        ResultHash:  resultHash,
+        ParamsHash:  ParamsHash(cj.Parameters),
+        StartedAt:   startedAt.UTC(),
+        CompletedAt: time.Now().UTC(),
+    }
+    if err := att.Sign(p.signer); err != nil {
+        log.Printf("⚠️ Job %d completes without attestation: %v", contractJobID, err)
+        return nil
+    }
     p.resultsMu.Lock()
-    p.results[job.ID] = completedJob
+    p.attestations[job.ID] = att
     p.resultsMu.Unlock()
-    
-    resultURL := fmt.Sprintf("%s/results/%s.json", p.endpointURL, job.ID)
-    
-    // NEW: Calculate real hash from result
-    resultData, _ := json.Marshal(completedJob.Result)
+    return att
+}
+
+// ResultHash is the hash a provider anchors in complete_job for a finished job
+func ResultHash(job *compute.ComputeJob) string {
+    resultData, _ := json.Marshal(job.Result)
     hash := sha256.Sum256(resultData)
-    resultHash := hex.EncodeToString(hash[:])
-    
-    log.Printf("✅ Job completed, marking as complete in contract")
-    
-    if err := p.completeContractJob(ctx, contractJobID, resultHash, resultURL); err != nil {
-        log.Printf("Failed to complete job in contract: %v", err)
-        return
-    }
-    
-    log.Printf("Job %d completed successfully", contractJobID)
+    return hex.EncodeToString(hash[:])
 }
 
-func (p *ProviderNode) completeContractJob(ctx context.Context, jobID uint64, hash, url string) error {
-    msg := fmt.Sprintf(`{"complete_job":{"job_id":%d,"result_hash":"%s","result_url":"%s"}}`,
-        jobID, hash, url)
+func (p *ProviderNode) completeContractJob(ctx context.Context, contractAddr string, jobID uint64, hash, url string, attestation *Attestation) error {
+    msg := ExecuteMsg{CompleteJob: &CompleteJobMsg{JobID: jobID, ResultHash: hash, ResultURL: url, Attestation: attestation}}.JSON()
     
     cmd := exec.CommandContext(ctx,
         "medasdigitald", "tx", "wasm", "execute",
-        p.contractAddr, msg,
+        contractAddr, msg,
         "--from", p.providerKey,
         "--keyring-backend", "test",
         "--gas", "220000",
-        "--fees", "5500umedas",
+        "--fees", "5500"+network.Current().BaseDenom,
         "-y",
         "--node", p.rpcURL,
         "--chain-id", p.chainID,
@@ -565,12 +849,12 @@ func (p *ProviderNode) completeContractJob(ctx context.Context, jobID uint64, ha
     return nil
 }
 
-func (p *ProviderNode) getContractJob(ctx context.Context, jobID uint64) (*ContractJob, error) {
-    query := fmt.Sprintf(`{"get_job":{"job_id":%d}}`, jobID)
+func (p *ProviderNode) getContractJob(ctx context.Context, contractAddr string, jobID uint64) (*ContractJob, error) {
+    query := QueryMsg{GetJob: &JobRef{JobID: jobID}}.JSON()
     
     cmd := exec.CommandContext(ctx,
         "medasdigitald", "query", "wasm", "contract-state", "smart",
-        p.contractAddr, query,
+        contractAddr, query,
         "--node", p.rpcURL,
         "--output", "json",
     )
@@ -591,23 +875,44 @@ func (p *ProviderNode) getContractJob(ctx context.Context, jobID uint64) (*Contr
     return &result.Data, nil
 }
 
-func (p *ProviderNode) startHTTPServer(ctx context.Context) {
-     http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-        timeSinceHeartbeat := time.Since(p.lastHeartbeat)
+// Handler returns the provider HTTP routes (/health, /results/)
+func (p *ProviderNode) Handler() http.Handler {
+    mux := http.NewServeMux()
+    
+    mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+        // The node is as healthy as its most stale contract
+        contracts := p.Contracts()
+        var lastHeartbeat time.Time
+        connected, reconnects := true, 0
+        for _, c := range contracts {
+            if c.LastHeartbeat.IsZero() {
+                continue // left by a migration
+            }
+            if lastHeartbeat.IsZero() || c.LastHeartbeat.Before(lastHeartbeat) {
+                lastHeartbeat = c.LastHeartbeat
+            }
+            connected = connected && c.Connected
+            reconnects += c.ReconnectAttempts
+        }
+        timeSinceHeartbeat := time.Since(lastHeartbeat)
         isHealthy := timeSinceHeartbeat < 24*time.Hour
         
         status := map[string]interface{}{
             "status": "healthy",
             "provider": p.providerAddr,
+            "contract": p.contract(),
+            "contracts": contracts,
             "heartbeat": map[string]interface{}{
-                "last_sent": p.lastHeartbeat.Format(time.RFC3339),
+                "last_sent": lastHeartbeat.Format(time.RFC3339),
                 "seconds_ago": int(timeSinceHeartbeat.Seconds()),
                 "minutes_ago": int(timeSinceHeartbeat.Minutes()),
                 "active": isHealthy,
                 "next_in": (p.heartbeatInterval - timeSinceHeartbeat).String(),
             },
-            "websocket_connected": p.wsClient != nil,
-            "reconnect_attempts": p.reconnectAttempts,
+            "active_jobs": p.ActiveJobs(),
+            "accepting_jobs": !p.Paused(),
+            "websocket_connected": connected,
+            "reconnect_attempts": reconnects,
         }
         
         w.Header().Set("Content-Type", "application/json")
@@ -623,13 +928,14 @@ func (p *ProviderNode) startHTTPServer(ctx context.Context) {
     })
     
     // NEW: Enhanced results handler that returns real PI results
-    http.HandleFunc("/results/", func(w http.ResponseWriter, r *http.Request) {
+    mux.HandleFunc("/results/", func(w http.ResponseWriter, r *http.Request) {
         // Extract job ID from URL: /results/pi_calculation-1.json
         path := strings.TrimPrefix(r.URL.Path, "/results/")
         jobID := strings.TrimSuffix(path, ".json")
         
         p.resultsMu.RLock()
         job, exists := p.results[jobID]
+        attestation := p.attestations[jobID]
         p.resultsMu.RUnlock()
         
         w.Header().Set("Content-Type", "application/json")
@@ -644,7 +950,7 @@ func (p *ProviderNode) startHTTPServer(ctx context.Context) {
         }
         
         // Return the actual computation result
-        json.NewEncoder(w).Encode(map[string]interface{}{
+        body := map[string]interface{}{
             "job_id":       job.ID,
             "status":       job.Status,
             "result":       job.Result,
@@ -652,13 +958,26 @@ func (p *ProviderNode) startHTTPServer(ctx context.Context) {
             "completed_at": job.CompletedAt,
             "tier":         job.Tier,
             "parameters":   job.Parameters,
-        })
+        }
+        if attestation != nil {
+            body["attestation"] = attestation
+        }
+        json.NewEncoder(w).Encode(body)
     })
     
+    p.registerAdminRoutes(mux)
+    
+    // API documentation
+    mux.Handle("GET /api/v1/openapi.json", p.apiDocument().Handler())
+    mux.Handle("GET /api/v1/docs", openapi.Docs("MEDAS Provider Node API", "openapi.json"))
+    return mux
+}
+
+func (p *ProviderNode) startHTTPServer(ctx context.Context) {
     addr := fmt.Sprintf(":%d", p.httpPort)
     log.Printf("HTTP server on port %d", p.httpPort)
     
-    server := &http.Server{Addr: addr}
+    server := &http.Server{Addr: addr, Handler: p.Handler()}
     
     go func() {
         <-ctx.Done()
@@ -0,0 +1,115 @@
+package contract
+
+import (
+    "context"
+    "log"
+    "time"
+)
+
+// JobRetention is how long finished jobs and their results stay in the store
+const JobRetention = 30 * 24 * time.Hour
+
+// SetJobStore persists every contract job, its progress and its result, so
+// a restarted node serves old results, resubmits results whose complete_job
+// got lost and reruns jobs that were interrupted
+func (p *ProviderNode) SetJobStore(store *JobStore) {
+    p.store = store
+}
+
+// storedJob returns the persisted state of a job, nil without a store
+func (p *ProviderNode) storedJob(contractAddr string, jobID uint64) *StoredJob {
+    if p.store == nil {
+        return nil
+    }
+    job, err := p.store.Get(contractAddr, jobID)
+    if err != nil {
+        log.Printf("⚠️ %v", err)
+        return nil
+    }
+    return job
+}
+
+// persist updates a stored job, a failing disk is logged but does not stop the job
+func (p *ProviderNode) persist(contractAddr string, jobID uint64, fn func(j *StoredJob)) {
+    if p.store == nil {
+        return
+    }
+    if _, err := p.store.Update(contractAddr, jobID, fn); err != nil {
+        log.Printf("⚠️ Could not persist job %d: %v", jobID, err)
+    }
+}
+
+// recoverJobs restores the stored results and picks up the unfinished jobs
+func (p *ProviderNode) recoverJobs(ctx context.Context) {
+    if removed, err := p.store.Prune(JobRetention); err != nil {
+        log.Printf("⚠️ Pruning the job store failed: %v", err)
+    } else if removed > 0 {
+        log.Printf("  Job store: removed %d jobs older than %v", removed, JobRetention)
+    }
+    jobs, err := p.store.List()
+    if err != nil {
+        log.Printf("❌ Job recovery failed: %v", err)
+        return
+    }
+
+    var counter int64
+    resumed := 0
+    for _, job := range jobs {
+        if job.Result != nil {
+            if n := computeJobCounter(job.ComputeJobID); n > counter {
+                counter = n
+            }
+            p.resultsMu.Lock()
+            p.results[job.ComputeJobID] = job.Result
+            if job.Attestation != nil {
+                p.attestations[job.ComputeJobID] = job.Attestation
+            }
+            p.resultsMu.Unlock()
+        }
+        if job.Finished() {
+            continue
+        }
+        resumed++
+        go p.recoverJob(ctx, job)
+    }
+    p.jobManager.AdvanceJobCounter(counter)
+    log.Printf("  Job store: %s (%d jobs, %d to recover)", p.store.Dir(), len(jobs), resumed)
+}
+
+// recoverJob resubmits a computed result or reruns an interrupted job, if
+// the contract still expects it
+func (p *ProviderNode) recoverJob(ctx context.Context, job *StoredJob) {
+    cj, err := p.getContractJob(ctx, job.Contract, job.JobID)
+    if err != nil {
+        p.jobLog(job.Contract, job.JobID, "⚠️ Job %d on %s not recoverable now: %v", job.JobID, job.Contract, err)
+        return
+    }
+
+    switch cj.Status {
+    case JobStatusSubmitted, JobStatusAccepted:
+    case JobStatusCompleted:
+        p.jobLog(job.Contract, job.JobID, "♻️  Job %d was completed on chain before the restart", job.JobID)
+        p.persist(job.Contract, job.JobID, func(j *StoredJob) { j.State = StoredCompleted })
+        return
+    default:
+        p.jobLog(job.Contract, job.JobID, "♻️  Job %d is %s on chain, dropping it", job.JobID, cj.Status)
+        p.persist(job.Contract, job.JobID, func(j *StoredJob) {
+            j.State, j.Error = StoredFailed, "job "+cj.Status+" on chain"
+        })
+        return
+    }
+
+    if job.State == StoredComputed {
+        p.jobLog(job.Contract, job.JobID, "♻️  Resubmitting the result of job %d to %s", job.JobID, job.Contract)
+        p.persist(job.Contract, job.JobID, func(j *StoredJob) { j.Attempts++ })
+        if err := p.submitResult(ctx, job.Contract, job.JobID, job.ResultHash, job.ResultURL, job.Attestation, cj.PaymentAmount); err != nil {
+            p.jobLog(job.Contract, job.JobID, "❌ Resubmitting job %d failed: %v", job.JobID, err)
+            return
+        }
+        p.jobLog(job.Contract, job.JobID, "Job %d completed successfully", job.JobID)
+        return
+    }
+
+    p.jobLog(job.Contract, job.JobID, "♻️  Job %d was interrupted at %d%%, running it again", job.JobID, job.Progress)
+    p.processJob(ctx, job.Contract, job.JobID)
+}
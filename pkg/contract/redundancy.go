@@ -0,0 +1,163 @@
+package contract
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/oxygene76/medasdigital-client/pkg/compute"
+    "github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// RedundantJob is one copy of a job that was submitted to several providers
+type RedundantJob struct {
+    JobID       uint64 `json:"job_id"`
+    Provider    string `json:"provider"`
+    TxHash      string `json:"tx_hash,omitempty"`
+    Status      string `json:"status"`
+    ResultHash  string `json:"result_hash,omitempty"`  // anchored by the provider
+    ContentHash string `json:"content_hash,omitempty"` // over the deterministic part of the result
+    Error       string `json:"error,omitempty"`
+}
+
+// CrossValidation is the comparison of the results of redundant jobs
+type CrossValidation struct {
+    JobType string         `json:"job_type"`
+    Jobs    []RedundantJob `json:"jobs"`
+}
+
+// Completed returns the jobs whose result could be fetched and hashed
+func (v *CrossValidation) Completed() []RedundantJob {
+    var done []RedundantJob
+    for _, j := range v.Jobs {
+        if j.ContentHash != "" {
+            done = append(done, j)
+        }
+    }
+    return done
+}
+
+// Agreed reports whether every job completed with the same result
+func (v *CrossValidation) Agreed() bool {
+    done := v.Completed()
+    if len(done) < 2 || len(done) != len(v.Jobs) {
+        return false
+    }
+    for _, j := range done[1:] {
+        if j.ContentHash != done[0].ContentHash {
+            return false
+        }
+    }
+    return true
+}
+
+// Mismatch reports whether at least two completed jobs disagree
+func (v *CrossValidation) Mismatch() bool {
+    done := v.Completed()
+    for _, j := range done {
+        if j.ContentHash != done[0].ContentHash {
+            return true
+        }
+    }
+    return false
+}
+
+// Reason summarizes a mismatch for dispute_job
+func (v *CrossValidation) Reason() string {
+    parts := make([]string, 0, len(v.Jobs))
+    for _, j := range v.Completed() {
+        parts = append(parts, fmt.Sprintf("job %d: %s", j.JobID, j.ContentHash[:16]))
+    }
+    sort.Strings(parts)
+    return "redundant results differ (" + strings.Join(parts, ", ") + ")"
+}
+
+// CrossValidate waits for redundant jobs, fetches every result, checks it
+// against the hash its provider anchored and compares the results
+func (c *Client) CrossValidate(ctx context.Context, jobType string, jobs []RedundantJob, timeout time.Duration) *CrossValidation {
+    v := &CrossValidation{JobType: jobType, Jobs: append([]RedundantJob(nil), jobs...)}
+
+    var wg sync.WaitGroup
+    for i := range v.Jobs {
+        j := &v.Jobs[i]
+        if j.JobID == 0 {
+            continue
+        }
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            job, err := c.WaitForCompletion(ctx, j.JobID, timeout)
+            if err != nil {
+                j.Status, j.Error = JobStatusFailed, err.Error()
+                return
+            }
+            j.Status, j.ResultHash = job.Status, job.ResultHash
+            result, err := FetchResult(ctx, job.ResultURL, job.ResultHash)
+            if err != nil {
+                j.Error = err.Error()
+                return
+            }
+            if j.ContentHash, err = ResultContentHash(jobType, result); err != nil {
+                j.Error = err.Error()
+            }
+        }()
+    }
+    wg.Wait()
+    return v
+}
+
+// FetchResult downloads a result from a provider's /results endpoint and
+// checks it against the anchored result hash
+func FetchResult(ctx context.Context, url, resultHash string) (json.RawMessage, error) {
+    if url == "" {
+        return nil, fmt.Errorf("job has no result URL")
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+    if err != nil {
+        return nil, err
+    }
+    stopTimer := telemetry.Track(telemetry.CategoryRPC, "fetch_result")
+    resp, err := http.DefaultClient.Do(req)
+    stopTimer()
+    if err != nil {
+        return nil, fmt.Errorf("failed to fetch result: %w", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("failed to fetch result: %s", resp.Status)
+    }
+
+    var body struct {
+        Result json.RawMessage `json:"result"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+        return nil, fmt.Errorf("invalid result: %w", err)
+    }
+    sum := sha256.Sum256(body.Result)
+    if got := hex.EncodeToString(sum[:]); got != resultHash {
+        return nil, fmt.Errorf("served result hash %s does not match anchored %s", got, resultHash)
+    }
+    return body.Result, nil
+}
+
+// ResultContentHash hashes what two honest providers must agree on. PI
+// results carry their own duration and timestamp, so only method, digits and
+// value are compared; other results are deterministic and hashed as served.
+func ResultContentHash(jobType string, result json.RawMessage) (string, error) {
+    if jobType == string(compute.JobTypePICalculation) {
+        var pi compute.PIResult
+        if err := json.Unmarshal(result, &pi); err != nil {
+            return "", fmt.Errorf("invalid PI result: %w", err)
+        }
+        return compute.ResultCommitment(&pi), nil
+    }
+    sum := sha256.Sum256(result)
+    return hex.EncodeToString(sum[:]), nil
+}
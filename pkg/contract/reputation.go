@@ -0,0 +1,306 @@
+package contract
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "math"
+    "os/exec"
+    "sort"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// DefaultReputationWindow is the heartbeat history considered for uptime
+const DefaultReputationWindow = 7 * 24 * time.Hour
+
+// DefaultHeartbeatInterval is the provider node's default heartbeat cadence
+const DefaultHeartbeatInterval = 6 * time.Hour
+
+// reputationPageSize is the list_jobs page size when collecting job history
+const reputationPageSize = 100
+
+// ReputationOptions controls how provider history is scored
+type ReputationOptions struct {
+    Window            time.Duration // heartbeat history used for uptime
+    HeartbeatInterval time.Duration // cadence a healthy provider sends heartbeats at
+}
+
+func (o ReputationOptions) withDefaults() ReputationOptions {
+    if o.Window <= 0 {
+        o.Window = DefaultReputationWindow
+    }
+    if o.HeartbeatInterval <= 0 {
+        o.HeartbeatInterval = DefaultHeartbeatInterval
+    }
+    return o
+}
+
+// ProviderReputation is a provider's track record aggregated from its
+// contract jobs and heartbeat transactions
+type ProviderReputation struct {
+    Address         string     `json:"address"`
+    Name            string     `json:"name"`
+    Active          bool       `json:"active"`
+    Completed       int        `json:"completed"`
+    Failed          int        `json:"failed"`
+    Disputed        int        `json:"disputed"`
+    Pending         int        `json:"pending"`
+    SuccessRate     float64    `json:"success_rate"`
+    AvgTurnaround   float64    `json:"avg_turnaround_seconds"`
+    HeartbeatUptime *float64   `json:"heartbeat_uptime,omitempty"` // nil when the tx history is unavailable
+    LastHeartbeat   *time.Time `json:"last_heartbeat,omitempty"`
+    ContractScore   string     `json:"contract_reputation"` // as reported by the contract
+    Score           float64    `json:"score"`
+    Error           string     `json:"error,omitempty"`
+}
+
+// ComputeReputation scores a provider from its jobs and heartbeat times.
+//
+// Failed and disputed jobs count against the provider, refunded jobs only
+// when the provider had accepted them. The success rate is smoothed with one
+// success and one failure so a single job does not decide the ranking. The
+// score is 100 × success rate, scaled down by up to half for missed
+// heartbeats.
+func ComputeReputation(p Provider, jobs []ContractJob, heartbeats []time.Time, now time.Time, opts ReputationOptions) ProviderReputation {
+    opts = opts.withDefaults()
+    rep := ProviderReputation{
+        Address:       p.Address,
+        Name:          p.Name,
+        Active:        p.Active,
+        ContractScore: p.Reputation,
+    }
+
+    var turnaround time.Duration
+    var timed int
+    for _, job := range jobs {
+        switch job.Status {
+        case JobStatusCompleted:
+            rep.Completed++
+            created, okC := ParseTimestamp(job.CreatedAt)
+            completed, okD := ParseTimestamp(job.CompletedAt)
+            if okC && okD && completed.After(created) {
+                turnaround += completed.Sub(created)
+                timed++
+            }
+        case JobStatusFailed:
+            rep.Failed++
+        case JobStatusDisputed:
+            rep.Disputed++
+        case JobStatusRefunded:
+            // Refunds of jobs nobody picked up are not the provider's fault
+            if job.Provider == p.Address {
+                rep.Failed++
+            }
+        case JobStatusSubmitted, JobStatusAccepted:
+            rep.Pending++
+        }
+    }
+    if timed > 0 {
+        rep.AvgTurnaround = (turnaround / time.Duration(timed)).Seconds()
+    }
+
+    bad := rep.Failed + rep.Disputed
+    if rep.Completed+bad > 0 {
+        rep.SuccessRate = float64(rep.Completed) / float64(rep.Completed+bad)
+    }
+    smoothed := float64(rep.Completed+1) / float64(rep.Completed+bad+2)
+
+    uptimeFactor := 1.0
+    if heartbeats != nil {
+        uptime := HeartbeatUptime(heartbeats, p.RegisteredAt, now, opts)
+        rep.HeartbeatUptime = &uptime
+        uptimeFactor = 0.5 + 0.5*uptime
+        for i := range heartbeats {
+            if rep.LastHeartbeat == nil || heartbeats[i].After(*rep.LastHeartbeat) {
+                rep.LastHeartbeat = &heartbeats[i]
+            }
+        }
+    }
+    rep.Score = math.Round(100*smoothed*uptimeFactor*10) / 10
+    return rep
+}
+
+// HeartbeatUptime is the fraction of heartbeat intervals in the window,
+// starting no earlier than the registration, with at least one heartbeat
+func HeartbeatUptime(heartbeats []time.Time, registeredAt string, now time.Time, opts ReputationOptions) float64 {
+    opts = opts.withDefaults()
+    start := now.Add(-opts.Window)
+    if reg, ok := ParseTimestamp(registeredAt); ok && reg.After(start) {
+        start = reg
+    }
+    slots := int(math.Ceil(float64(now.Sub(start)) / float64(opts.HeartbeatInterval)))
+    if slots <= 0 {
+        return 1
+    }
+
+    seen := make(map[int]bool)
+    for _, t := range heartbeats {
+        if t.Before(start) || t.After(now) {
+            continue
+        }
+        seen[int(t.Sub(start)/opts.HeartbeatInterval)] = true
+    }
+    return math.Min(1, float64(len(seen))/float64(slots))
+}
+
+// SortReputations orders reputations best first, ties by completed jobs
+func SortReputations(reps []ProviderReputation) {
+    sort.SliceStable(reps, func(i, j int) bool {
+        if reps[i].Score != reps[j].Score {
+            return reps[i].Score > reps[j].Score
+        }
+        return reps[i].Completed > reps[j].Completed
+    })
+}
+
+// ProviderReputations aggregates the history of every registered provider,
+// best first. Providers whose history cannot be read are kept with Error set.
+func (c *Client) ProviderReputations(ctx context.Context, opts ReputationOptions) ([]ProviderReputation, error) {
+    providers, err := c.ListProviders(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    reps := make([]ProviderReputation, len(providers))
+    var wg sync.WaitGroup
+    for i, p := range providers {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            reps[i] = c.providerReputation(ctx, p, opts)
+        }()
+    }
+    wg.Wait()
+
+    SortReputations(reps)
+    return reps, nil
+}
+
+// ProviderReputation aggregates the history of a single provider
+func (c *Client) ProviderReputation(ctx context.Context, address string, opts ReputationOptions) (*ProviderReputation, error) {
+    p, err := c.GetProvider(ctx, address)
+    if err != nil {
+        return nil, err
+    }
+    rep := c.providerReputation(ctx, *p, opts)
+    return &rep, nil
+}
+
+func (c *Client) providerReputation(ctx context.Context, p Provider, opts ReputationOptions) ProviderReputation {
+    opts = opts.withDefaults()
+    now := time.Now()
+
+    jobs, err := c.providerJobs(ctx, p.Address)
+    if err != nil {
+        rep := ComputeReputation(p, nil, nil, now, opts)
+        rep.Score = 0 // unknown history ranks last
+        rep.Error = fmt.Sprintf("job history: %v", err)
+        return rep
+    }
+    heartbeats, hbErr := c.Heartbeats(ctx, p.Address, now.Add(-opts.Window))
+    rep := ComputeReputation(p, jobs, heartbeats, now, opts)
+    if hbErr != nil {
+        rep.Error = fmt.Sprintf("heartbeats: %v", hbErr)
+    }
+    return rep
+}
+
+// providerJobs pages through list_jobs for one provider
+func (c *Client) providerJobs(ctx context.Context, provider string) ([]ContractJob, error) {
+    var all []ContractJob
+    var after uint64
+    for {
+        jobs, err := c.ListJobs(ctx, JobFilter{Provider: provider, StartAfter: after, Limit: reputationPageSize})
+        if err != nil {
+            return nil, err
+        }
+        all = append(all, jobs...)
+        if len(jobs) < reputationPageSize {
+            return all, nil
+        }
+        after = jobs[len(jobs)-1].ID
+    }
+}
+
+// Heartbeats returns the times of the heart_beat transactions a provider
+// sent to the contract since the given time, newest first
+func (c *Client) Heartbeats(ctx context.Context, provider string, since time.Time) ([]time.Time, error) {
+    query := fmt.Sprintf("execute._contract_address='%s' AND message.sender='%s'", c.config.ContractAddress, provider)
+
+    heartbeats := []time.Time{}
+    for page := 1; ; page++ {
+        txs, total, err := c.searchTxs(ctx, query, page)
+        if err != nil {
+            return nil, err
+        }
+        for _, tx := range txs {
+            t, err := time.Parse(time.RFC3339, tx.Timestamp)
+            if err != nil {
+                continue
+            }
+            if t.Before(since) {
+                return heartbeats, nil
+            }
+            if tx.isHeartbeat() {
+                heartbeats = append(heartbeats, t)
+            }
+        }
+        if len(txs) == 0 || page*reputationPageSize >= total {
+            return heartbeats, nil
+        }
+    }
+}
+
+// searchedTx is the part of a tx search result needed to spot heartbeats
+type searchedTx struct {
+    Timestamp string `json:"timestamp"`
+    Tx        struct {
+        Body struct {
+            Messages []struct {
+                Msg json.RawMessage `json:"msg"`
+            } `json:"messages"`
+        } `json:"body"`
+    } `json:"tx"`
+}
+
+func (tx searchedTx) isHeartbeat() bool {
+    for _, m := range tx.Tx.Body.Messages {
+        var msg ExecuteMsg
+        if json.Unmarshal(m.Msg, &msg) == nil && msg.HeartBeat != nil {
+            return true
+        }
+    }
+    return false
+}
+
+// searchTxs runs one page of a tx event search, newest first
+func (c *Client) searchTxs(ctx context.Context, query string, page int) ([]searchedTx, int, error) {
+    defer telemetry.Track(telemetry.CategoryRPC, "tx_search")()
+    cmd := exec.CommandContext(ctx,
+        "medasdigitald", "query", "txs",
+        "--query", query,
+        "--page", strconv.Itoa(page),
+        "--limit", strconv.Itoa(reputationPageSize),
+        "--order_by", "desc",
+        "--node", c.config.RPCEndpoint,
+        "--output", "json",
+    )
+    output, err := cmd.Output()
+    if err != nil {
+        return nil, 0, fmt.Errorf("tx search failed: %w", err)
+    }
+
+    var result struct {
+        TotalCount json.Number  `json:"total_count"` // string or number depending on the SDK version
+        Txs        []searchedTx `json:"txs"`
+    }
+    if err := json.Unmarshal(output, &result); err != nil {
+        return nil, 0, fmt.Errorf("parse tx search failed: %w", err)
+    }
+    total, _ := result.TotalCount.Int64()
+    return result.Txs, int(total), nil
+}
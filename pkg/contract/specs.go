@@ -0,0 +1,154 @@
+package contract
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "encoding/binary"
+    "fmt"
+    "math"
+
+    "github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+    sdk "github.com/cosmos/cosmos-sdk/types"
+
+    "github.com/oxygene76/medasdigital-client/pkg/compute"
+)
+
+// specsDomain keeps spec signatures apart from other signed bytes
+const specsDomain = "medas-specs-v1"
+
+// MachineSpecs is the hardware a provider advertises at registration,
+// signed with the provider key so clients can hold it to its claims
+type MachineSpecs struct {
+    Provider    string               `json:"provider"`
+    Cores       int                  `json:"cores"`
+    MemoryMB    int64                `json:"memory_mb"`
+    GPUModel    string               `json:"gpu_model,omitempty"`
+    GPUMemoryMB int64                `json:"gpu_memory_mb,omitempty"`
+    Benchmark   *compute.PIBenchmark `json:"benchmark,omitempty"`
+    PubKey      []byte               `json:"pub_key,omitempty"`
+    Signature   []byte               `json:"signature,omitempty"`
+}
+
+// signBytes is the digest the provider signs
+func (s *MachineSpecs) signBytes() []byte {
+    var buf bytes.Buffer
+    buf.WriteString(specsDomain)
+    for _, field := range []string{s.Provider, s.GPUModel} {
+        binary.Write(&buf, binary.BigEndian, uint32(len(field)))
+        buf.WriteString(field)
+    }
+    binary.Write(&buf, binary.BigEndian, int64(s.Cores))
+    binary.Write(&buf, binary.BigEndian, s.MemoryMB)
+    binary.Write(&buf, binary.BigEndian, s.GPUMemoryMB)
+    if b := s.Benchmark; b != nil {
+        binary.Write(&buf, binary.BigEndian, int64(b.Digits))
+        binary.Write(&buf, binary.BigEndian, int64(b.Duration))
+        binary.Write(&buf, binary.BigEndian, math.Float64bits(b.Score))
+        buf.WriteString(b.Commitment)
+        binary.Write(&buf, binary.BigEndian, b.MeasuredAt.UnixNano())
+    }
+    sum := sha256.Sum256(buf.Bytes())
+    return sum[:]
+}
+
+// Sign adds the provider signature
+func (s *MachineSpecs) Sign(sign SignFunc) error {
+    sig, pub, err := sign(s.signBytes())
+    if err != nil {
+        return fmt.Errorf("failed to sign specs: %w", err)
+    }
+    if _, ok := pub.(*secp256k1.PubKey); !ok {
+        return fmt.Errorf("unsupported account key type %s", pub.Type())
+    }
+    s.PubKey = pub.Bytes()
+    s.Signature = sig
+    return nil
+}
+
+// Verify checks that the specs were signed by the provider account and
+// that the benchmark computed the reference digits
+func (s *MachineSpecs) Verify() error {
+    if len(s.PubKey) != secp256k1.PubKeySize {
+        return fmt.Errorf("specs are not signed")
+    }
+    pub := &secp256k1.PubKey{Key: s.PubKey}
+    if addr := sdk.AccAddress(pub.Address()).String(); addr != s.Provider {
+        return fmt.Errorf("specs signed by %s, not %s", addr, s.Provider)
+    }
+    if !pub.VerifySignature(s.signBytes(), s.Signature) {
+        return fmt.Errorf("invalid specs signature")
+    }
+    if s.Benchmark != nil {
+        if err := s.Benchmark.Verify(); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// Requirements are the minimum specs a job asks of its provider, zero
+// fields are not checked
+type Requirements struct {
+    GPU          bool
+    MinBenchmark float64
+    MinCores     int
+    MinMemoryMB  int64
+}
+
+// IsZero reports whether no requirement is set
+func (r Requirements) IsZero() bool {
+    return r == Requirements{}
+}
+
+// Check returns why a provider does not meet the requirements. Only signed
+// specs of the provider's own account are trusted.
+func (r Requirements) Check(p Provider) error {
+    if r.IsZero() {
+        return nil
+    }
+    s := p.Specs
+    if s == nil {
+        return fmt.Errorf("no machine specs advertised")
+    }
+    if s.Provider != p.Address {
+        return fmt.Errorf("specs belong to %s", s.Provider)
+    }
+    if err := s.Verify(); err != nil {
+        return err
+    }
+    if r.GPU && s.GPUModel == "" {
+        return fmt.Errorf("no GPU")
+    }
+    if r.MinBenchmark > 0 {
+        if s.Benchmark == nil {
+            return fmt.Errorf("no benchmark")
+        }
+        if s.Benchmark.Score < r.MinBenchmark {
+            return fmt.Errorf("benchmark %.0f below %.0f", s.Benchmark.Score, r.MinBenchmark)
+        }
+    }
+    if s.Cores < r.MinCores {
+        return fmt.Errorf("%d cores, %d required", s.Cores, r.MinCores)
+    }
+    if s.MemoryMB < r.MinMemoryMB {
+        return fmt.Errorf("%d MB memory, %d MB required", s.MemoryMB, r.MinMemoryMB)
+    }
+    return nil
+}
+
+// Filter keeps the providers meeting the requirements, in order
+func (r Requirements) Filter(providers []Provider) ([]Provider, error) {
+    if r.IsZero() {
+        return providers, nil
+    }
+    var kept []Provider
+    for _, p := range providers {
+        if r.Check(p) == nil {
+            kept = append(kept, p)
+        }
+    }
+    if len(kept) == 0 {
+        return nil, fmt.Errorf("none of %d suitable providers meets the requirements", len(providers))
+    }
+    return kept, nil
+}
@@ -0,0 +1,190 @@
+package contract
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/oxygene76/medasdigital-client/pkg/compute"
+    "github.com/oxygene76/medasdigital-client/pkg/state"
+)
+
+// States of a stored provider job
+const (
+    StoredAccepted  = "accepted"  // received, computation not started
+    StoredRunning   = "running"   // computing, Progress is the last checkpoint
+    StoredComputed  = "computed"  // result ready, complete_job not confirmed
+    StoredCompleted = "completed" // complete_job broadcast
+    StoredFailed    = "failed"
+)
+
+// StoredJob is what the provider node keeps on disk about a contract job,
+// enough to rerun it or resubmit its result after a restart
+type StoredJob struct {
+    Contract     string              `json:"contract"`
+    JobID        uint64              `json:"job_id"`
+    JobType      string              `json:"job_type"`
+    Client       string              `json:"client"`
+    Payment      string              `json:"payment,omitempty"`
+    State        string              `json:"state"`
+    Progress     int                 `json:"progress"`
+    ComputeJobID string              `json:"compute_job_id,omitempty"`
+    Result       *compute.ComputeJob `json:"result,omitempty"`
+    ResultHash   string              `json:"result_hash,omitempty"`
+    ResultURL    string              `json:"result_url,omitempty"`
+    Attestation  *Attestation        `json:"attestation,omitempty"`
+    Attempts     int                 `json:"attempts"` // runs and completion broadcasts
+    Error        string              `json:"error,omitempty"`
+    ReceivedAt   time.Time           `json:"received_at"`
+    UpdatedAt    time.Time           `json:"updated_at"`
+}
+
+// Finished reports whether nothing is left to do for the job
+func (j *StoredJob) Finished() bool {
+    return j.State == StoredCompleted || j.State == StoredFailed
+}
+
+// JobStore keeps one JSON file per contract job below a directory. One
+// provider node at a time owns the directory.
+type JobStore struct {
+    dir  string
+    mu   sync.Mutex
+    lock *state.Lock
+}
+
+// OpenJobStore creates the store directory if needed and locks it, it fails
+// while another process has it open
+func OpenJobStore(dir string) (*JobStore, error) {
+    if err := os.MkdirAll(dir, 0700); err != nil {
+        return nil, fmt.Errorf("failed to create job store: %w", err)
+    }
+    lock, err := state.TryLockFile(filepath.Join(dir, ".lock"))
+    if errors.Is(err, state.ErrLocked) {
+        return nil, fmt.Errorf("job store %s is used by another provider node", dir)
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &JobStore{dir: dir, lock: lock}, nil
+}
+
+// Close releases the store for other processes
+func (s *JobStore) Close() error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.lock.Unlock()
+}
+
+// Dir is the directory the jobs are stored in
+func (s *JobStore) Dir() string {
+    return s.dir
+}
+
+func (s *JobStore) path(contractAddr string, jobID uint64) string {
+    return filepath.Join(s.dir, contractAddr, strconv.FormatUint(jobID, 10)+".json")
+}
+
+// Get reads a job, nil if it is not stored
+func (s *JobStore) Get(contractAddr string, jobID uint64) (*StoredJob, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return readStoredJob(s.path(contractAddr, jobID))
+}
+
+// Update applies fn to the stored job (a new one if missing) and writes it atomically
+func (s *JobStore) Update(contractAddr string, jobID uint64, fn func(j *StoredJob)) (*StoredJob, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    path := s.path(contractAddr, jobID)
+    job, err := readStoredJob(path)
+    if err != nil {
+        return nil, err
+    }
+    if job == nil {
+        job = &StoredJob{Contract: contractAddr, JobID: jobID, State: StoredAccepted, ReceivedAt: time.Now().UTC()}
+    }
+    fn(job)
+    job.UpdatedAt = time.Now().UTC()
+
+    if err := state.WriteJSON(path, job, 0600); err != nil {
+        return nil, fmt.Errorf("failed to write job %d: %w", jobID, err)
+    }
+    return job, nil
+}
+
+// List returns all stored jobs, oldest first
+func (s *JobStore) List() ([]*StoredJob, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    paths, err := filepath.Glob(filepath.Join(s.dir, "*", "*.json"))
+    if err != nil {
+        return nil, err
+    }
+    var jobs []*StoredJob
+    for _, path := range paths {
+        job, err := readStoredJob(path)
+        if err != nil {
+            return nil, err
+        }
+        if job != nil {
+            jobs = append(jobs, job)
+        }
+    }
+    sort.Slice(jobs, func(i, j int) bool { return jobs[i].ReceivedAt.Before(jobs[j].ReceivedAt) })
+    return jobs, nil
+}
+
+// Prune removes finished jobs not updated for maxAge, their results are no
+// longer served afterwards
+func (s *JobStore) Prune(maxAge time.Duration) (int, error) {
+    jobs, err := s.List()
+    if err != nil {
+        return 0, err
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    removed := 0
+    for _, job := range jobs {
+        if job.Finished() && time.Since(job.UpdatedAt) > maxAge {
+            if err := os.Remove(s.path(job.Contract, job.JobID)); err != nil && !os.IsNotExist(err) {
+                return removed, err
+            }
+            removed++
+        }
+    }
+    return removed, nil
+}
+
+func readStoredJob(path string) (*StoredJob, error) {
+    data, err := os.ReadFile(path)
+    if os.IsNotExist(err) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to read stored job: %w", err)
+    }
+    var job StoredJob
+    if err := json.Unmarshal(data, &job); err != nil {
+        return nil, fmt.Errorf("failed to parse stored job %s: %w", path, err)
+    }
+    return &job, nil
+}
+
+// computeJobCounter extracts the counter of a job manager ID like pi_calculation-12
+func computeJobCounter(id string) int64 {
+    i := strings.LastIndex(id, "-")
+    if i < 0 {
+        return 0
+    }
+    n, _ := strconv.ParseInt(id[i+1:], 10, 64)
+    return n
+}
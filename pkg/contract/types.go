@@ -5,6 +5,17 @@ type Config struct {
     ContractAddress string
     RPCEndpoint     string
     ChainID         string
+    FeeGranter      string // pays the fees of submitted transactions, see x/feegrant
+}
+
+// MarketConfig is the contract's get_config answer
+type MarketConfig struct {
+    CommunityPool       string `json:"community_pool"`
+    CommunityFeePercent int    `json:"community_fee_percent"`
+    DefaultJobTimeout   int    `json:"default_job_timeout"`
+    HeartbeatTimeout    int    `json:"heartbeat_timeout"`
+    Paused              bool   `json:"paused"`
+    Successor           string `json:"successor,omitempty"` // set by the admin when the contract was replaced
 }
 
 // Provider aus Smart Contract
@@ -20,6 +31,7 @@ type Provider struct {
     Reputation     string                 `json:"reputation"`
     Active         bool                   `json:"active"`
     RegisteredAt   string                 `json:"registered_at"`
+    Specs          *MachineSpecs          `json:"specs,omitempty"` // see Requirements
 }
 
 type Capability struct {
@@ -35,25 +47,60 @@ type PriceInfo struct {
 
 // Job aus Smart Contract
 type ContractJob struct {
-    ID            uint64 `json:"id"`
-    Client        string `json:"client"`
-    Provider      string `json:"provider"`
-    JobType       string `json:"job_type"`
-    Parameters    string `json:"parameters"`
-    PaymentAmount string `json:"payment_amount"`
-    Status        string `json:"status"`
-    ResultHash    string `json:"result_hash,omitempty"`
-    ResultURL     string `json:"result_url,omitempty"`
-    CreatedAt     string `json:"created_at"`
-    CompletedAt   string `json:"completed_at,omitempty"`
+    ID            uint64       `json:"id"`
+    Client        string       `json:"client"`
+    Provider      string       `json:"provider"`
+    JobType       string       `json:"job_type"`
+    ServiceType   string       `json:"service_type,omitempty"` // open market jobs
+    Parameters    string       `json:"parameters"`
+    PaymentAmount string       `json:"payment_amount"`
+    MaxPrice      string       `json:"max_price,omitempty"`
+    Status        string       `json:"status"`
+    ResultHash    string       `json:"result_hash,omitempty"`
+    ResultURL     string       `json:"result_url,omitempty"`
+    EscrowAmount  string       `json:"escrow_amount,omitempty"` // still held by the contract
+    CreatedAt     string       `json:"created_at"`
+    Deadline      string       `json:"deadline,omitempty"`
+    CompletedAt   string       `json:"completed_at,omitempty"`
+    Attestation   *Attestation `json:"attestation,omitempty"` // contracts that keep the provider receipt
+}
+
+// JobFilter narrows list_jobs, empty fields are not filtered on
+type JobFilter struct {
+    Provider   string `json:"provider,omitempty"`
+    Client     string `json:"client,omitempty"`
+    Status     string `json:"status,omitempty"`
+    StartAfter uint64 `json:"start_after,omitempty"`
+    Limit      uint32 `json:"limit,omitempty"`
 }
 
 // JobStatus constants
 const (
     JobStatusSubmitted = "submitted"
+    JobStatusAccepted  = "accepted"
     JobStatusCompleted = "completed"
     JobStatusFailed    = "failed"
     JobStatusCancelled = "cancelled"
+    JobStatusRefunded  = "refunded"
+    JobStatusDisputed  = "disputed" // held payment disputed by the client
+)
+
+// Refund of an escrowed job payment
+type Refund struct {
+    JobID     uint64 `json:"job_id"`
+    Client    string `json:"client"`
+    Amount    string `json:"amount"`
+    Status    string `json:"status"`
+    Reason    string `json:"reason,omitempty"`
+    ClaimedAt string `json:"claimed_at,omitempty"`
+}
+
+// Refund status constants
+const (
+    RefundStatusNone      = "none"      // job is not refundable (yet)
+    RefundStatusEligible  = "eligible"  // job failed, refund_job has not been called
+    RefundStatusClaimable = "claimable" // refund credited, waiting for claim_refund
+    RefundStatusClaimed   = "claimed"
 )
 
 // GasEstimation für Transaktionen
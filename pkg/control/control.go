@@ -0,0 +1,212 @@
+// Package control is the local control API of the client daemon: a small
+// REST API on a Unix socket in the client home. CLI commands use it when a
+// daemon is running, instead of setting up their own chain connection.
+package control
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/oxygene76/medasdigital-client/pkg/gpu"
+)
+
+// SocketName is the file name of the socket in the client home
+const SocketName = "daemon.sock"
+
+// dialTimeout bounds the check whether a daemon is listening
+const dialTimeout = 500 * time.Millisecond
+
+// SocketPath is the socket of the daemon of a client home
+func SocketPath(home string) string {
+	return filepath.Join(home, SocketName)
+}
+
+// Status is the state of a running daemon
+type Status struct {
+	PID         int            `json:"pid"`
+	Version     string         `json:"version"`
+	Started     time.Time      `json:"started"`
+	Network     string         `json:"network"`
+	ChainID     string         `json:"chain_id"`
+	RPCEndpoint string         `json:"rpc_endpoint"`
+	Chain       ChainState     `json:"chain"`
+	Index       IndexState     `json:"index"`
+	Scheduler   SchedulerState `json:"scheduler"`
+	GPU         GPUState       `json:"gpu"`
+}
+
+// ChainState is the last status the daemon got from its node
+type ChainState struct {
+	Height      int64     `json:"height"`
+	BlockTime   time.Time `json:"block_time"`
+	CatchingUp  bool      `json:"catching_up"`
+	NodeVersion string    `json:"node_version,omitempty"`
+	Checked     time.Time `json:"checked"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// IndexState describes the periodic sync of the transaction index
+type IndexState struct {
+	Enabled   bool          `json:"enabled"`
+	Interval  time.Duration `json:"interval"`
+	Addresses int           `json:"addresses"`
+	LastSync  time.Time     `json:"last_sync"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// SchedulerState describes the scheduler running inside the daemon
+type SchedulerState struct {
+	Enabled bool   `json:"enabled"`
+	Jobs    int    `json:"jobs"`
+	Error   string `json:"error,omitempty"` // e.g. another scheduler daemon holds the lock
+}
+
+// GPUState is the last GPU scan
+type GPUState struct {
+	Devices []gpu.DetectedDevice `json:"devices"`
+	Checked time.Time            `json:"checked"`
+}
+
+// IndexSyncRequest asks for a sync of the given addresses, all tracked if empty
+type IndexSyncRequest struct {
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+// IndexSyncResult is the outcome of one address
+type IndexSyncResult struct {
+	Address   string `json:"address"`
+	ToHeight  int64  `json:"to_height"`
+	Transfers int    `json:"transfers"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Listen opens the socket, replacing a stale one left by a daemon that did
+// not shut down. Only the owner may connect.
+func Listen(path string) (net.Listener, error) {
+	if conn, err := net.DialTimeout("unix", path, dialTimeout); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a daemon is already listening on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// Serve answers requests on ln until ctx is cancelled
+func Serve(ctx context.Context, ln net.Listener, handler http.Handler) error {
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// WriteJSON writes v with status
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// WriteError writes an error as {"error": ...}
+func WriteError(w http.ResponseWriter, status int, err error) {
+	WriteJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// Client talks to a running daemon
+type Client struct {
+	http *http.Client
+}
+
+// Dial connects to the daemon of home, nil when none is running
+func Dial(home string) *Client {
+	path := SocketPath(home)
+	conn, err := net.DialTimeout("unix", path, dialTimeout)
+	if err != nil {
+		return nil
+	}
+	conn.Close()
+	return &Client{http: &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}}
+}
+
+// Status returns the daemon state
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	var s Status
+	if err := c.Do(ctx, http.MethodGet, "/v1/status", nil, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Do sends a request with in as JSON body and decodes the answer into out;
+// either may be nil
+func (c *Client) Do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	// The host is ignored, the transport always dials the socket
+	req, err := http.NewRequestWithContext(ctx, method, "http://daemon"+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("daemon request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			return fmt.Errorf("daemon: %s", e.Error)
+		}
+		return fmt.Errorf("daemon: %s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid daemon response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package crossmatch
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Catalog names
+const (
+	CatalogGaia   = "gaia"
+	CatalogSimbad = "simbad"
+)
+
+// Default TAP services
+const (
+	DefaultGaiaURL   = "https://gea.esac.esa.int/tap-server/tap"
+	DefaultSimbadURL = "https://simbad.cds.unistra.fr/simbad/sim-tap"
+)
+
+// gaiaEpoch is the reference epoch of Gaia DR3 positions, J2016.0
+var gaiaEpoch = time.Date(2016, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// tapService runs synchronous ADQL queries and reads the CSV answer
+type tapService struct {
+	url  string
+	http *http.Client
+}
+
+// query returns the rows as maps keyed by column name
+func (s *tapService) query(ctx context.Context, adql string) ([]map[string]string, error) {
+	form := url.Values{
+		"REQUEST": {"doQuery"},
+		"LANG":    {"ADQL"},
+		"FORMAT":  {"csv"},
+		"QUERY":   {adql},
+	}
+	var lastErr error
+	for attempt := 0; attempt < 3; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(attempt) * 2 * time.Second):
+			}
+		}
+		rows, retry, err := s.post(ctx, form)
+		if err == nil || !retry {
+			return rows, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// post sends one query; transport errors and 5xx answers are retried
+func (s *tapService) post(ctx context.Context, form url.Values) ([]map[string]string, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(s.url, "/")+"/sync", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return nil, ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, resp.StatusCode >= 500, fmt.Errorf("TAP query failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	r := csv.NewReader(resp.Body)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read TAP answer: %w", err)
+	}
+	var rows []map[string]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rows, false, nil
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read TAP answer: %w", err)
+		}
+		row := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(record) {
+				row[strings.ToLower(strings.TrimSpace(name))] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+func cone(ra, dec, radius float64) string {
+	return fmt.Sprintf("CONTAINS(POINT('ICRS', ra, dec), CIRCLE('ICRS', %.8f, %.8f, %.8f)) = 1", ra, dec, radius)
+}
+
+func parseFloat(s string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return v
+}
+
+// Gaia queries gaiadr3.gaia_source and moves the stars by their proper
+// motion to the epoch of the exposure
+type Gaia struct {
+	tap tapService
+}
+
+// NewGaia creates a Gaia DR3 client, an empty url uses the ESA archive
+func NewGaia(serviceURL string, timeout time.Duration) *Gaia {
+	if serviceURL == "" {
+		serviceURL = DefaultGaiaURL
+	}
+	return &Gaia{tap: tapService{url: serviceURL, http: &http.Client{Timeout: timeout}}}
+}
+
+func (g *Gaia) Name() string { return CatalogGaia }
+
+func (g *Gaia) ConeSearch(ctx context.Context, ra, dec, radius float64, epoch time.Time, limit int) ([]Source, error) {
+	years := 0.0
+	if !epoch.IsZero() {
+		years = epoch.Sub(gaiaEpoch).Hours() / (24 * 365.25)
+	}
+	// Pad the cone by 1"/yr so fast stars that moved into it are found
+	query := fmt.Sprintf("SELECT TOP %d source_id, ra, dec, pmra, pmdec, phot_g_mean_mag FROM gaiadr3.gaia_source WHERE %s",
+		limit, cone(ra, dec, radius+math.Abs(years)/3600))
+	rows, err := g.tap.query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]Source, 0, len(rows))
+	for _, row := range rows {
+		s := Source{Catalog: CatalogGaia, ID: "Gaia DR3 " + row["source_id"], RA: parseFloat(row["ra"]), Dec: parseFloat(row["dec"])}
+		if math.IsNaN(s.RA) || math.IsNaN(s.Dec) {
+			continue
+		}
+		if mag := parseFloat(row["phot_g_mean_mag"]); !math.IsNaN(mag) {
+			s.Mag = mag
+		}
+		// pmra is mu_alpha* in mas/yr and includes cos(dec)
+		pmra, pmdec := parseFloat(row["pmra"]), parseFloat(row["pmdec"])
+		if years != 0 && !math.IsNaN(pmra) && !math.IsNaN(pmdec) {
+			s.Dec += pmdec * years / 3.6e6
+			if c := math.Cos(s.Dec * math.Pi / 180); c > 1e-9 {
+				s.RA = math.Mod(s.RA+pmra*years/3.6e6/c+360, 360)
+			}
+		}
+		sources = append(sources, s)
+	}
+	return sources, nil
+}
+
+// Simbad queries the SIMBAD basic table
+type Simbad struct {
+	tap tapService
+}
+
+// NewSimbad creates a SIMBAD client, an empty url uses the CDS service
+func NewSimbad(serviceURL string, timeout time.Duration) *Simbad {
+	if serviceURL == "" {
+		serviceURL = DefaultSimbadURL
+	}
+	return &Simbad{tap: tapService{url: serviceURL, http: &http.Client{Timeout: timeout}}}
+}
+
+func (s *Simbad) Name() string { return CatalogSimbad }
+
+func (s *Simbad) ConeSearch(ctx context.Context, ra, dec, radius float64, epoch time.Time, limit int) ([]Source, error) {
+	query := fmt.Sprintf("SELECT TOP %d main_id, ra, dec, otype FROM basic WHERE %s", limit, cone(ra, dec, radius))
+	rows, err := s.tap.query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]Source, 0, len(rows))
+	for _, row := range rows {
+		src := Source{Catalog: CatalogSimbad, ID: strings.TrimSpace(row["main_id"]), Type: row["otype"], RA: parseFloat(row["ra"]), Dec: parseFloat(row["dec"])}
+		if math.IsNaN(src.RA) || math.IsNaN(src.Dec) {
+			continue
+		}
+		sources = append(sources, src)
+	}
+	return sources, nil
+}
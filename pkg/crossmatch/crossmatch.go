@@ -0,0 +1,277 @@
+// Package crossmatch compares detected objects with the Gaia DR3 and SIMBAD
+// catalogs. Detections with a known counterpart are false positives for
+// moving object searches; unmatched detections that do not recur at the same
+// sky position in other exposures are kept as candidates.
+package crossmatch
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Statuses of a cross-matched detection
+const (
+	StatusKnown      = "known"      // a catalog source lies within the radius
+	StatusStationary = "stationary" // unmatched, but detected at the same position in another exposure
+	StatusCandidate  = "candidate"  // unmatched and not stationary, a possible mover
+)
+
+// DefaultRadiusArcsec is the match radius without configuration
+const DefaultRadiusArcsec = 2.0
+
+// maxFieldRadius is the largest cone, in degrees, queried once for all
+// detections of an exposure; wider exposures are queried per detection
+const maxFieldRadius = 0.25
+
+// fieldLimit caps the sources of a field query, a full result falls back to
+// queries per detection so no counterpart is cut off
+const fieldLimit = 5000
+
+// Config is the crossmatch section of the client config
+type Config struct {
+	RadiusArcsec float64       `json:"radius_arcsec,omitempty" yaml:"radius_arcsec,omitempty" mapstructure:"radius_arcsec"`
+	Catalogs     []string      `json:"catalogs,omitempty" yaml:"catalogs,omitempty" mapstructure:"catalogs"`       // gaia, simbad; default both
+	GaiaURL      string        `json:"gaia_url,omitempty" yaml:"gaia_url,omitempty" mapstructure:"gaia_url"`       // TAP service, default ESA
+	SimbadURL    string        `json:"simbad_url,omitempty" yaml:"simbad_url,omitempty" mapstructure:"simbad_url"` // TAP service, default CDS
+	Timeout      time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty" mapstructure:"timeout"`          // per query, default 60s
+}
+
+// Validate checks radius and catalog names
+func (c Config) Validate() error {
+	if c.RadiusArcsec < 0 {
+		return fmt.Errorf("crossmatch.radius_arcsec must not be negative")
+	}
+	for _, name := range c.Catalogs {
+		if name != CatalogGaia && name != CatalogSimbad {
+			return fmt.Errorf("crossmatch.catalogs: unknown catalog %q (%s, %s)", name, CatalogGaia, CatalogSimbad)
+		}
+	}
+	return nil
+}
+
+// NewCatalogs creates the configured catalog clients
+func (c Config) NewCatalogs() []Catalog {
+	names := c.Catalogs
+	if len(names) == 0 {
+		names = []string{CatalogGaia, CatalogSimbad}
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	var catalogs []Catalog
+	for _, name := range names {
+		switch name {
+		case CatalogGaia:
+			catalogs = append(catalogs, NewGaia(c.GaiaURL, timeout))
+		case CatalogSimbad:
+			catalogs = append(catalogs, NewSimbad(c.SimbadURL, timeout))
+		}
+	}
+	return catalogs
+}
+
+// Radius is the match radius in arcseconds
+func (c Config) Radius() float64 {
+	if c.RadiusArcsec > 0 {
+		return c.RadiusArcsec
+	}
+	return DefaultRadiusArcsec
+}
+
+// Source is a catalog object, positioned at the epoch of the exposure
+type Source struct {
+	Catalog string  `json:"catalog"`
+	ID      string  `json:"id"`
+	Type    string  `json:"type,omitempty"` // SIMBAD object type
+	RA      float64 `json:"ra"`
+	Dec     float64 `json:"dec"`
+	Mag     float64 `json:"mag,omitempty"` // Gaia G
+}
+
+// Catalog answers cone searches, radius in degrees
+type Catalog interface {
+	Name() string
+	ConeSearch(ctx context.Context, ra, dec, radius float64, epoch time.Time, limit int) ([]Source, error)
+}
+
+// Target is a detection with a sky position
+type Target struct {
+	Image   string
+	RA      float64
+	Dec     float64
+	ObsTime time.Time
+}
+
+// Match is a catalog source near a target
+type Match struct {
+	Source
+	SeparationArcsec float64 `json:"separation_arcsec"`
+}
+
+// Result is the cross-match of one target
+type Result struct {
+	Status  string  `json:"status"`
+	Matches []Match `json:"matches,omitempty"` // nearest first
+}
+
+// Options configures Run
+type Options struct {
+	RadiusArcsec float64
+	Catalogs     []Catalog
+	// Progress is called after each exposure with the number done
+	Progress func(done, total int)
+}
+
+// Run cross-matches the targets, the results are in target order
+func Run(ctx context.Context, targets []Target, opts Options) ([]Result, error) {
+	if opts.RadiusArcsec <= 0 {
+		opts.RadiusArcsec = DefaultRadiusArcsec
+	}
+	radius := opts.RadiusArcsec / 3600
+	results := make([]Result, len(targets))
+
+	exposures := groupExposures(targets)
+	for n, idx := range exposures {
+		for _, cat := range opts.Catalogs {
+			if err := matchExposure(ctx, cat, targets, idx, radius, results); err != nil {
+				return nil, fmt.Errorf("%s: %w", cat.Name(), err)
+			}
+		}
+		if opts.Progress != nil {
+			opts.Progress(n+1, len(exposures))
+		}
+	}
+
+	for i := range results {
+		sort.Slice(results[i].Matches, func(a, b int) bool {
+			return results[i].Matches[a].SeparationArcsec < results[i].Matches[b].SeparationArcsec
+		})
+		switch {
+		case len(results[i].Matches) > 0:
+			results[i].Status = StatusKnown
+		case recurs(targets, i, radius):
+			results[i].Status = StatusStationary
+		default:
+			results[i].Status = StatusCandidate
+		}
+	}
+	return results, nil
+}
+
+// groupExposures groups target indexes by image and observation time
+func groupExposures(targets []Target) [][]int {
+	var order []string
+	groups := make(map[string][]int)
+	for i, t := range targets {
+		key := t.Image + "|" + t.ObsTime.String()
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+	out := make([][]int, 0, len(order))
+	for _, key := range order {
+		out = append(out, groups[key])
+	}
+	return out
+}
+
+// matchExposure queries one cone around all targets of an exposure, or one
+// per target when they are spread too wide or the field is too crowded
+func matchExposure(ctx context.Context, cat Catalog, targets []Target, idx []int, radius float64, results []Result) error {
+	epoch := targets[idx[0]].ObsTime
+	ra, dec, spread := fieldCenter(targets, idx)
+	if len(idx) > 1 && spread+radius <= maxFieldRadius {
+		sources, err := cat.ConeSearch(ctx, ra, dec, spread+radius, epoch, fieldLimit)
+		if err != nil {
+			return err
+		}
+		if len(sources) < fieldLimit {
+			for _, i := range idx {
+				addMatches(&results[i], targets[i], sources, radius)
+			}
+			return nil
+		}
+	}
+	for _, i := range idx {
+		sources, err := cat.ConeSearch(ctx, targets[i].RA, targets[i].Dec, radius, epoch, 100)
+		if err != nil {
+			return err
+		}
+		addMatches(&results[i], targets[i], sources, radius)
+	}
+	return nil
+}
+
+func addMatches(r *Result, t Target, sources []Source, radius float64) {
+	for _, s := range sources {
+		if sep := Separation(t.RA, t.Dec, s.RA, s.Dec); sep <= radius {
+			r.Matches = append(r.Matches, Match{Source: s, SeparationArcsec: sep * 3600})
+		}
+	}
+}
+
+// fieldCenter is the mean position of the targets and the largest distance
+// of a target from it, in degrees
+func fieldCenter(targets []Target, idx []int) (float64, float64, float64) {
+	var x, y, z float64
+	for _, i := range idx {
+		ra, dec := targets[i].RA*math.Pi/180, targets[i].Dec*math.Pi/180
+		x += math.Cos(dec) * math.Cos(ra)
+		y += math.Cos(dec) * math.Sin(ra)
+		z += math.Sin(dec)
+	}
+	ra := math.Atan2(y, x) * 180 / math.Pi
+	if ra < 0 {
+		ra += 360
+	}
+	dec := math.Atan2(z, math.Hypot(x, y)) * 180 / math.Pi
+	spread := 0.0
+	for _, i := range idx {
+		spread = math.Max(spread, Separation(ra, dec, targets[i].RA, targets[i].Dec))
+	}
+	return ra, dec, spread
+}
+
+// recurs reports whether target i is detected within radius in another
+// exposure, a mover would have left that position
+func recurs(targets []Target, i int, radius float64) bool {
+	t := targets[i]
+	for j, o := range targets {
+		if j == i || (o.Image == t.Image && o.ObsTime.Equal(t.ObsTime)) {
+			continue
+		}
+		if !t.ObsTime.IsZero() && o.ObsTime.Equal(t.ObsTime) {
+			continue // same epoch, e.g. another chip of the exposure
+		}
+		if Separation(t.RA, t.Dec, o.RA, o.Dec) <= radius {
+			return true
+		}
+	}
+	return false
+}
+
+// Separation is the angular distance of two positions in degrees
+func Separation(ra1, dec1, ra2, dec2 float64) float64 {
+	const rad = math.Pi / 180
+	sinDDec := math.Sin((dec2 - dec1) * rad / 2)
+	sinDRA := math.Sin((ra2 - ra1) * rad / 2)
+	a := sinDDec*sinDDec + math.Cos(dec1*rad)*math.Cos(dec2*rad)*sinDRA*sinDRA
+	return 2 * math.Asin(math.Min(1, math.Sqrt(a))) / rad
+}
+
+// ParseCatalogs splits a comma separated catalog list
+func ParseCatalogs(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,175 @@
+// Package denom resolves the display units of denominations from the bank
+// module metadata and formats base amounts in them, e.g. 12345678umedas as
+// 12.345678 MEDAS.
+package denom
+
+import (
+	"math/big"
+	"strings"
+	"sync"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// denomMetadataPath is the ABCI query path of the bank denom metadata
+const denomMetadataPath = "/cosmos.bank.v1beta1.Query/DenomMetadata"
+
+// Metadata is how amounts of a base denom are displayed
+type Metadata struct {
+	Base     string `json:"base"`     // e.g. umedas
+	Display  string `json:"display"`  // e.g. MEDAS
+	Exponent int    `json:"exponent"` // decimals of the display unit, e.g. 6
+}
+
+// Amount is an amount in JSON output: exact in base units and as displayed
+type Amount struct {
+	Amount  string `json:"amount"` // base units
+	Denom   string `json:"denom"`  // base denom
+	Display string `json:"display"`
+}
+
+// Format renders base units in the display unit with all its decimals
+func (m Metadata) Format(amount sdkmath.Int) string {
+	return FormatUnits(amount.BigInt(), m.Exponent) + " " + m.Display
+}
+
+// FormatInt64 is Format for an int64 amount
+func (m Metadata) FormatInt64(amount int64) string {
+	return m.Format(sdkmath.NewInt(amount))
+}
+
+// Amount returns amount for JSON output
+func (m Metadata) Amount(amount sdkmath.Int) Amount {
+	return Amount{Amount: amount.String(), Denom: m.Base, Display: m.Format(amount)}
+}
+
+// FormatUnits renders base units as a decimal with exponent decimals, exactly
+func FormatUnits(amount *big.Int, exponent int) string {
+	if amount == nil {
+		amount = new(big.Int)
+	}
+	digits := new(big.Int).Abs(amount).String()
+	sign := ""
+	if amount.Sign() < 0 {
+		sign = "-"
+	}
+	if exponent <= 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	split := len(digits) - exponent
+	return sign + digits[:split] + "." + digits[split:]
+}
+
+// FromBank takes the display unit of bank metadata. It is false when the
+// metadata names no display unit.
+func FromBank(md banktypes.Metadata) (Metadata, bool) {
+	for _, unit := range md.DenomUnits {
+		if unit.Denom != md.Display || md.Display == "" {
+			continue
+		}
+		display := md.Symbol
+		if display == "" {
+			display = strings.ToUpper(md.Display)
+		}
+		return Metadata{Base: md.Base, Display: display, Exponent: int(unit.Exponent)}, true
+	}
+	return Metadata{}, false
+}
+
+// Querier runs ABCI queries, a client.Context is one
+type Querier interface {
+	QueryWithData(path string, data []byte) ([]byte, int64, error)
+}
+
+// Resolver looks up the metadata of denoms, the chain is asked once for each
+// denom it was not given
+type Resolver struct {
+	mu      sync.Mutex
+	querier func() (Querier, error)
+	known   map[string]Metadata
+	queried map[string]bool
+}
+
+// NewResolver creates a resolver that knows the given metadata and asks the
+// chain through querier about others. querier is called on the first query,
+// nil resolves only the known denoms.
+func NewResolver(querier func() (Querier, error), known ...Metadata) *Resolver {
+	r := &Resolver{
+		querier: querier,
+		known:   make(map[string]Metadata),
+		queried: make(map[string]bool),
+	}
+	for _, m := range known {
+		r.known[m.Base] = m
+	}
+	return r
+}
+
+// Resolve returns the metadata of base. Denoms neither known nor described on
+// chain are displayed in base units.
+func (r *Resolver) Resolve(base string) Metadata {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, ok := r.known[base]; ok {
+		return m
+	}
+	if !r.queried[base] && r.querier != nil {
+		r.queried[base] = true
+		if m, ok := r.query(base); ok {
+			r.known[base] = m
+			return m
+		}
+	}
+	return Metadata{Base: base, Display: base}
+}
+
+// query asks the bank module for the metadata of base, mu is held
+func (r *Resolver) query(base string) (Metadata, bool) {
+	q, err := r.querier()
+	if err != nil {
+		// Offline, do not try again for every denom
+		r.querier = nil
+		return Metadata{}, false
+	}
+	req := &banktypes.QueryDenomMetadataRequest{Denom: base}
+	data, err := req.Marshal()
+	if err != nil {
+		return Metadata{}, false
+	}
+	res, _, err := q.QueryWithData(denomMetadataPath, data)
+	if err != nil {
+		return Metadata{}, false
+	}
+	var resp banktypes.QueryDenomMetadataResponse
+	if err := resp.Unmarshal(res); err != nil {
+		return Metadata{}, false
+	}
+	return FromBank(resp.Metadata)
+}
+
+// Format renders a coin in its display unit
+func (r *Resolver) Format(coin sdk.Coin) string {
+	return r.Resolve(coin.Denom).Format(coin.Amount)
+}
+
+// FormatCoins renders coins in their display units, separated by commas
+func (r *Resolver) FormatCoins(coins sdk.Coins) string {
+	if coins.IsZero() {
+		return "0"
+	}
+	parts := make([]string, len(coins))
+	for i, c := range coins {
+		parts[i] = r.Format(c)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Amount returns a coin for JSON output
+func (r *Resolver) Amount(coin sdk.Coin) Amount {
+	return r.Resolve(coin.Denom).Amount(coin.Amount)
+}
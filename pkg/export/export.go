@@ -0,0 +1,296 @@
+// Package export converts analysis results to columnar files (HDF5 and
+// Parquet) that numerical tools read without parsing large JSON documents.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	itypes "github.com/oxygene76/medasdigital-client/internal/types"
+)
+
+// Result types that can be exported
+const (
+	TypeOrbitalDynamics = "orbital_dynamics"
+	TypePhotometric     = "photometric"
+	TypeClustering      = "clustering"
+)
+
+// Formats of the exported files
+const (
+	FormatHDF5    = "hdf5"
+	FormatParquet = "parquet"
+	FormatVOTable = "votable" // written by pkg/votable
+)
+
+// Types lists the result types that can be exported
+func Types() []string {
+	return []string{TypeOrbitalDynamics, TypePhotometric, TypeClustering}
+}
+
+// normalizeType maps the analysis_type values written by the analyzers to export types
+func normalizeType(analysisType string) string {
+	switch analysisType {
+	case "orbital_dynamics", "orbital":
+		return TypeOrbitalDynamics
+	case "photometric", "photometric_analysis":
+		return TypePhotometric
+	case "clustering", "clustering_analysis":
+		return TypeClustering
+	}
+	return ""
+}
+
+// payloadKeys are the keys of the data map under which the analyzers nest the typed result
+var payloadKeys = []string{"orbital_analysis", "photometric_analysis", "clustering_analysis", "result"}
+
+// Load reads a result as saved by the analyze commands (an AnalysisResult
+// with the typed result in data), a bare typed result or a stored analysis
+// whose data is a JSON string. resultType overrides the analysis_type of the
+// document.
+func Load(data []byte, resultType string) (*Dataset, error) {
+	var head struct {
+		AnalysisType string            `json:"analysis_type"`
+		ClientID     string            `json:"client_id"`
+		TxHash       string            `json:"tx_hash"`
+		BlockHeight  int64             `json:"block_height"`
+		Timestamp    time.Time         `json:"timestamp"`
+		CreatedAt    time.Time         `json:"created_at"`
+		Metadata     map[string]string `json:"metadata"`
+		Data         json.RawMessage   `json:"data"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, fmt.Errorf("not a JSON analysis result: %w", err)
+	}
+
+	payload := data
+	var nested string
+	if len(head.Data) > 0 && json.Unmarshal(head.Data, &nested) == nil {
+		// Stored analyses carry the result as a JSON string
+		ds, err := Load([]byte(nested), firstNonEmpty(resultType, head.AnalysisType))
+		if err != nil {
+			return nil, err
+		}
+		setCommonAttributes(ds, head.ClientID, head.TxHash, head.BlockHeight, head.CreatedAt, nil)
+		return ds, nil
+	}
+	var dataMap map[string]json.RawMessage
+	if len(head.Data) > 0 && json.Unmarshal(head.Data, &dataMap) == nil {
+		for _, key := range payloadKeys {
+			if p, ok := dataMap[key]; ok {
+				payload = p
+				break
+			}
+		}
+	}
+
+	typ := normalizeType(firstNonEmpty(resultType, head.AnalysisType))
+	if typ == "" {
+		return nil, fmt.Errorf("unknown result type %q, use one of %s", firstNonEmpty(resultType, head.AnalysisType), strings.Join(Types(), ", "))
+	}
+
+	var ds *Dataset
+	var err error
+	switch typ {
+	case TypeOrbitalDynamics:
+		var r itypes.OrbitalDynamicsResult
+		if err := json.Unmarshal(payload, &r); err != nil {
+			return nil, fmt.Errorf("invalid orbital dynamics result: %w", err)
+		}
+		ds, err = OrbitalDynamics(&r)
+	case TypePhotometric:
+		var r itypes.PhotometricResult
+		if err := json.Unmarshal(payload, &r); err != nil {
+			return nil, fmt.Errorf("invalid photometric result: %w", err)
+		}
+		ds, err = Photometric(&r)
+	case TypeClustering:
+		var r itypes.ClusteringResult
+		if err := json.Unmarshal(payload, &r); err != nil {
+			return nil, fmt.Errorf("invalid clustering result: %w", err)
+		}
+		ds, err = Clustering(&r)
+	}
+	if err != nil {
+		return nil, err
+	}
+	setCommonAttributes(ds, head.ClientID, head.TxHash, head.BlockHeight, head.Timestamp, head.Metadata)
+	return ds, nil
+}
+
+// OrbitalDynamics converts an orbital dynamics result
+func OrbitalDynamics(r *itypes.OrbitalDynamicsResult) (*Dataset, error) {
+	ds := &Dataset{Type: TypeOrbitalDynamics, Attributes: map[string]string{
+		"confidence":              formatFloat(r.Confidence),
+		"planet9_probability":     formatFloat(r.Planet9Probability),
+		"clustering_significance": formatFloat(r.ClusteringSignificance),
+	}}
+	if r.ModelVersion != "" {
+		ds.Attributes["model_version"] = r.ModelVersion
+	}
+	if r.Frame != "" {
+		ds.Attributes["frame"] = r.Frame
+	}
+	if len(r.Surveys) > 0 {
+		ds.Attributes["raw_clustering_significance"] = formatFloat(r.RawClusteringSignificance)
+		ds.Attributes["clustering_p_value"] = formatFloat(r.ClusteringPValue)
+		ds.Attributes["surveys"] = strings.Join(r.Surveys, ", ")
+	}
+	if mc := r.MonteCarlo; mc != nil {
+		ds.Attributes["monte_carlo.clones"] = strconv.Itoa(mc.Clones)
+		ds.Attributes["monte_carlo.clustering_significance"] = mc.ClusteringSignificance.String()
+		ds.Attributes["monte_carlo.planet9_probability"] = mc.Planet9Probability.String()
+	}
+	tables := []struct {
+		name string
+		rows interface{}
+	}{
+		{"objects", r.Objects},
+		{"orbital_elements", r.OrbitalElements},
+		{"gravitational_effects", r.GravitationalEffects},
+		{"predictions", r.Predictions},
+		{"observation_targets", r.Targets},
+		{"recommendations", r.Recommendations},
+	}
+	for _, t := range tables {
+		table, err := NewTable(t.name, t.rows)
+		if err != nil {
+			return nil, err
+		}
+		ds.add(table)
+	}
+	return ds, nil
+}
+
+// Photometric converts a photometric result. The light curve samples are
+// stored one row per measurement in light_curve_points.
+func Photometric(r *itypes.PhotometricResult) (*Dataset, error) {
+	ds := &Dataset{Type: TypePhotometric, Attributes: map[string]string{}}
+	if r.Classification != "" {
+		ds.Attributes["classification"] = r.Classification
+	}
+	if len(r.Variability) > 0 {
+		data, _ := json.Marshal(r.Variability)
+		ds.Attributes["variability"] = string(data)
+	}
+
+	curves, err := NewTable("light_curves", r.LightCurves, "times", "magnitudes", "errors", "flags")
+	if err != nil {
+		return nil, err
+	}
+	ds.add(curves)
+
+	objectID := &Column{Name: "object_id", Kind: String}
+	filter := &Column{Name: "filter", Kind: String}
+	times := &Column{Name: "time", Kind: Float}
+	mags := &Column{Name: "magnitude", Kind: Float}
+	errs := &Column{Name: "error", Kind: Float}
+	flags := &Column{Name: "flag", Kind: Int}
+	for _, lc := range r.LightCurves {
+		for i, t := range lc.Times {
+			objectID.Strings = append(objectID.Strings, lc.ObjectID)
+			filter.Strings = append(filter.Strings, lc.Filter)
+			times.Floats = append(times.Floats, t)
+			mags.Floats = append(mags.Floats, valueAt(lc.Magnitudes, i))
+			errs.Floats = append(errs.Floats, valueAt(lc.Errors, i))
+			var flag int64
+			if i < len(lc.Flags) {
+				flag = int64(lc.Flags[i])
+			}
+			flags.Ints = append(flags.Ints, flag)
+		}
+	}
+	ds.add(&Table{Name: "light_curve_points", Columns: []*Column{objectID, filter, times, mags, errs, flags}})
+
+	index := &Column{Name: "index", Kind: Int}
+	for i := range r.Magnitudes {
+		index.Ints = append(index.Ints, int64(i))
+	}
+	ds.add(&Table{Name: "magnitudes", Columns: []*Column{index, {Name: "magnitude", Kind: Float, Floats: r.Magnitudes}}})
+	ds.add(keyValueTable("colors", "value", r.Colors))
+	return ds, nil
+}
+
+// Clustering converts a clustering result. Cluster membership is stored one
+// row per member in cluster_members.
+func Clustering(r *itypes.ClusteringResult) (*Dataset, error) {
+	ds := &Dataset{Type: TypeClustering, Attributes: map[string]string{
+		"quality_score": formatFloat(r.QualityScore),
+	}}
+	if r.Algorithm != "" {
+		ds.Attributes["algorithm"] = r.Algorithm
+	}
+	if len(r.Statistics) > 0 {
+		data, _ := json.Marshal(r.Statistics)
+		ds.Attributes["statistics"] = string(data)
+	}
+
+	clusters, err := NewTable("clusters", r.Clusters, "members")
+	if err != nil {
+		return nil, err
+	}
+	ds.add(clusters)
+
+	clusterID := &Column{Name: "cluster_id", Kind: Int}
+	member := &Column{Name: "object_id", Kind: String}
+	for _, c := range r.Clusters {
+		for _, m := range c.Members {
+			clusterID.Ints = append(clusterID.Ints, int64(c.ClusterID))
+			member.Strings = append(member.Strings, m)
+		}
+	}
+	ds.add(&Table{Name: "cluster_members", Columns: []*Column{clusterID, member}})
+	ds.add(keyValueTable("parameters", "value", r.Parameters))
+	return ds, nil
+}
+
+func setCommonAttributes(ds *Dataset, clientID, txHash string, height int64, at time.Time, metadata map[string]string) {
+	ds.Attributes["analysis_type"] = ds.Type
+	for k, v := range map[string]string{"client_id": clientID, "tx_hash": txHash} {
+		if v != "" {
+			ds.Attributes[k] = v
+		}
+	}
+	if height > 0 {
+		ds.Attributes["block_height"] = strconv.FormatInt(height, 10)
+	}
+	if !at.IsZero() {
+		ds.Attributes["timestamp"] = at.Format(time.RFC3339)
+	}
+	for k, v := range metadata {
+		ds.Attributes["metadata."+k] = v
+	}
+}
+
+func valueAt(values []float64, i int) float64 {
+	if i < len(values) {
+		return values[i]
+	}
+	return 0
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
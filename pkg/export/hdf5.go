@@ -0,0 +1,380 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// The HDF5 writer produces the oldest file format revision (superblock
+// version 0, version 1 object headers and symbol table groups), which every
+// HDF5 library and h5py read. The file has one group per table with one 1-D
+// dataset per column and the dataset attributes on the root group.
+//
+//	/objects/semimajor_axis   float64[n]
+//	/objects/id               string[n]
+//	/predictions/ra           float64[m]
+
+const (
+	h5Undefined = ^uint64(0)
+
+	// Symbol table nodes hold 2*leafK entries, B-tree nodes 2*internalK children
+	h5LeafK     = 32
+	h5InternalK = 16
+
+	h5SuperblockSize = 96
+	h5EntrySize      = 40
+	h5BTreeSize      = 24 + 2*h5InternalK*8 + (2*h5InternalK+1)*8
+	h5SNodeSize      = 8 + 2*h5LeafK*h5EntrySize
+
+	h5MsgDataspace   = 0x0001
+	h5MsgDatatype    = 0x0003
+	h5MsgFillValue   = 0x0005
+	h5MsgLayout      = 0x0008
+	h5MsgAttribute   = 0x000C
+	h5MsgSymbolTable = 0x0011
+)
+
+var h5Signature = []byte{0x89, 'H', 'D', 'F', '\r', '\n', 0x1a, '\n'}
+
+// h5Writer lays the file out in memory, addresses are buffer offsets
+type h5Writer struct {
+	buf bytes.Buffer
+}
+
+// h5Entry is a link from a group to an object
+type h5Entry struct {
+	name   string
+	header uint64
+	// btree and heap are set for groups, readers cache them in the entry
+	btree, heap uint64
+}
+
+// h5Message is one object header message
+type h5Message struct {
+	typ  uint16
+	data []byte
+}
+
+// WriteHDF5 writes the dataset to an HDF5 file
+func WriteHDF5(path string, ds *Dataset) error {
+	w := &h5Writer{}
+	w.buf.Write(make([]byte, h5SuperblockSize))
+
+	var tables []h5Entry
+	for _, t := range ds.Tables {
+		var columns []h5Entry
+		for _, c := range t.Columns {
+			addr, err := w.dataset(c)
+			if err != nil {
+				return fmt.Errorf("table %s: %w", t.Name, err)
+			}
+			columns = append(columns, h5Entry{name: h5Name(c.Name), header: addr})
+		}
+		group, err := w.group(columns, nil)
+		if err != nil {
+			return fmt.Errorf("table %s: %w", t.Name, err)
+		}
+		group.name = h5Name(t.Name)
+		tables = append(tables, group)
+	}
+	root, err := w.group(tables, ds.Attributes)
+	if err != nil {
+		return err
+	}
+
+	data := w.buf.Bytes()
+	sb := data[:h5SuperblockSize]
+	copy(sb, h5Signature)
+	// Versions of superblock, free space, root entry, reserved, shared header; offset and length sizes
+	copy(sb[8:], []byte{0, 0, 0, 0, 0, 8, 8, 0})
+	binary.LittleEndian.PutUint16(sb[16:], h5LeafK)
+	binary.LittleEndian.PutUint16(sb[18:], h5InternalK)
+	binary.LittleEndian.PutUint32(sb[20:], 0)
+	binary.LittleEndian.PutUint64(sb[24:], 0)                 // base address
+	binary.LittleEndian.PutUint64(sb[32:], h5Undefined)       // free space info
+	binary.LittleEndian.PutUint64(sb[40:], uint64(len(data))) // end of file
+	binary.LittleEndian.PutUint64(sb[48:], h5Undefined)       // driver info
+	copy(sb[56:], h5SymbolEntry(0, root))
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// h5Name makes a column or table name usable as HDF5 link name
+func h5Name(name string) string {
+	name = strings.ReplaceAll(name, "/", "_")
+	if name == "" || name == "." {
+		return "_"
+	}
+	return name
+}
+
+// align pads the buffer to a multiple of 8 bytes
+func (w *h5Writer) align() {
+	if pad := w.buf.Len() % 8; pad != 0 {
+		w.buf.Write(make([]byte, 8-pad))
+	}
+}
+
+func (w *h5Writer) addr() uint64 {
+	return uint64(w.buf.Len())
+}
+
+// dataset writes the raw values of a column and its object header
+func (w *h5Writer) dataset(c *Column) (uint64, error) {
+	var raw []byte
+	var dtype []byte
+	switch c.Kind {
+	case Float:
+		raw = make([]byte, 8*len(c.Floats))
+		for i, f := range c.Floats {
+			binary.LittleEndian.PutUint64(raw[8*i:], math.Float64bits(f))
+		}
+		dtype = h5FloatType()
+	case Int:
+		raw = make([]byte, 8*len(c.Ints))
+		for i, n := range c.Ints {
+			binary.LittleEndian.PutUint64(raw[8*i:], uint64(n))
+		}
+		dtype = h5IntType()
+	default:
+		size := 1
+		for _, s := range c.Strings {
+			if len(s) > size {
+				size = len(s)
+			}
+		}
+		raw = make([]byte, size*len(c.Strings))
+		for i, s := range c.Strings {
+			copy(raw[size*i:], s)
+		}
+		dtype = h5StringType(size)
+	}
+
+	w.align()
+	dataAddr := w.addr()
+	w.buf.Write(raw)
+	if len(raw) == 0 {
+		dataAddr = h5Undefined
+	}
+
+	layout := make([]byte, 18)
+	layout[0], layout[1] = 3, 1 // version 3, contiguous
+	binary.LittleEndian.PutUint64(layout[2:], dataAddr)
+	binary.LittleEndian.PutUint64(layout[10:], uint64(len(raw)))
+
+	return w.objectHeader([]h5Message{
+		{h5MsgDataspace, h5Dataspace(uint64(c.Len()))},
+		{h5MsgDatatype, dtype},
+		// Version 2, late allocation, fill written if set, no fill value defined
+		{h5MsgFillValue, []byte{2, 2, 2, 0}},
+		{h5MsgLayout, layout},
+	}), nil
+}
+
+// group writes the local heap, symbol table nodes, B-tree and object header
+// of a group holding entries
+func (w *h5Writer) group(entries []h5Entry, attributes map[string]string) (h5Entry, error) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	for i := 1; i < len(entries); i++ {
+		if entries[i].name == entries[i-1].name {
+			return h5Entry{}, fmt.Errorf("duplicate name %q", entries[i].name)
+		}
+	}
+	perNode := 2 * h5LeafK
+	nodes := (len(entries) + perNode - 1) / perNode
+	if nodes == 0 {
+		nodes = 1
+	}
+	if nodes > 2*h5InternalK {
+		return h5Entry{}, fmt.Errorf("more than %d entries in a group", 2*h5InternalK*perNode)
+	}
+
+	// Local heap with the names, offset 0 is the empty name
+	heapData := make([]byte, 8)
+	offsets := make([]uint64, len(entries))
+	for i, e := range entries {
+		offsets[i] = uint64(len(heapData))
+		name := append([]byte(e.name), 0)
+		for len(name)%8 != 0 {
+			name = append(name, 0)
+		}
+		heapData = append(heapData, name...)
+	}
+	w.align()
+	heapAddr := w.addr()
+	heap := make([]byte, 32)
+	copy(heap, "HEAP")
+	binary.LittleEndian.PutUint64(heap[8:], uint64(len(heapData)))
+	binary.LittleEndian.PutUint64(heap[16:], h5Undefined) // no free block
+	binary.LittleEndian.PutUint64(heap[24:], heapAddr+32)
+	w.buf.Write(heap)
+	w.buf.Write(heapData)
+
+	// Symbol table nodes, entries sorted by name
+	nodeAddrs := make([]uint64, nodes)
+	lastName := make([]uint64, nodes)
+	for n := 0; n < nodes; n++ {
+		lo, hi := n*perNode, (n+1)*perNode
+		if hi > len(entries) {
+			hi = len(entries)
+		}
+		node := make([]byte, h5SNodeSize)
+		copy(node, "SNOD")
+		node[4] = 1
+		binary.LittleEndian.PutUint16(node[6:], uint16(hi-lo))
+		for i := lo; i < hi; i++ {
+			copy(node[8+(i-lo)*h5EntrySize:], h5SymbolEntry(offsets[i], entries[i]))
+		}
+		if hi > lo {
+			lastName[n] = offsets[hi-1]
+		}
+		w.align()
+		nodeAddrs[n] = w.addr()
+		w.buf.Write(node)
+	}
+
+	// One leaf B-tree node pointing to the symbol table nodes, each key is
+	// the heap offset of the last name left of it
+	btree := make([]byte, h5BTreeSize)
+	copy(btree, "TREE")
+	btree[4], btree[5] = 0, 0 // group node, leaf level
+	binary.LittleEndian.PutUint16(btree[6:], uint16(nodes))
+	binary.LittleEndian.PutUint64(btree[8:], h5Undefined)
+	binary.LittleEndian.PutUint64(btree[16:], h5Undefined)
+	pos := 24
+	binary.LittleEndian.PutUint64(btree[pos:], 0)
+	pos += 8
+	for n := 0; n < nodes; n++ {
+		binary.LittleEndian.PutUint64(btree[pos:], nodeAddrs[n])
+		binary.LittleEndian.PutUint64(btree[pos+8:], lastName[n])
+		pos += 16
+	}
+	w.align()
+	btreeAddr := w.addr()
+	w.buf.Write(btree)
+
+	symtab := make([]byte, 16)
+	binary.LittleEndian.PutUint64(symtab, btreeAddr)
+	binary.LittleEndian.PutUint64(symtab[8:], heapAddr)
+	messages := []h5Message{{h5MsgSymbolTable, symtab}}
+	for _, name := range sortedKeys(attributes) {
+		// Version 1 object header messages are limited to 64 KiB
+		if len(name)+len(attributes[name]) > 60000 {
+			return h5Entry{}, fmt.Errorf("attribute %s is too large for HDF5", name)
+		}
+		messages = append(messages, h5Message{h5MsgAttribute, h5StringAttribute(name, attributes[name])})
+	}
+	return h5Entry{header: w.objectHeader(messages), btree: btreeAddr, heap: heapAddr}, nil
+}
+
+// objectHeader writes a version 1 object header and returns its address
+func (w *h5Writer) objectHeader(messages []h5Message) uint64 {
+	var body bytes.Buffer
+	for _, m := range messages {
+		size := (len(m.data) + 7) &^ 7
+		hdr := make([]byte, 8)
+		binary.LittleEndian.PutUint16(hdr, m.typ)
+		binary.LittleEndian.PutUint16(hdr[2:], uint16(size))
+		body.Write(hdr)
+		body.Write(m.data)
+		body.Write(make([]byte, size-len(m.data)))
+	}
+
+	prefix := make([]byte, 16)
+	prefix[0] = 1
+	binary.LittleEndian.PutUint16(prefix[2:], uint16(len(messages)))
+	binary.LittleEndian.PutUint32(prefix[4:], 1)
+	binary.LittleEndian.PutUint32(prefix[8:], uint32(body.Len()))
+
+	w.align()
+	addr := w.addr()
+	w.buf.Write(prefix)
+	w.buf.Write(body.Bytes())
+	return addr
+}
+
+// h5SymbolEntry encodes a symbol table entry, caching B-tree and heap of groups
+func h5SymbolEntry(nameOffset uint64, e h5Entry) []byte {
+	b := make([]byte, h5EntrySize)
+	binary.LittleEndian.PutUint64(b, nameOffset)
+	binary.LittleEndian.PutUint64(b[8:], e.header)
+	if e.btree != 0 {
+		binary.LittleEndian.PutUint32(b[16:], 1)
+		binary.LittleEndian.PutUint64(b[24:], e.btree)
+		binary.LittleEndian.PutUint64(b[32:], e.heap)
+	}
+	return b
+}
+
+// h5Dataspace is a version 1 dataspace with one dimension of n
+func h5Dataspace(n uint64) []byte {
+	b := make([]byte, 16)
+	b[0], b[1] = 1, 1
+	binary.LittleEndian.PutUint64(b[8:], n)
+	return b
+}
+
+// h5FloatType is a little-endian IEEE 754 double
+func h5FloatType() []byte {
+	b := make([]byte, 20)
+	b[0] = 0x11           // version 1, floating point
+	b[1], b[2] = 0x20, 63 // implied mantissa bit, sign at bit 63
+	binary.LittleEndian.PutUint32(b[4:], 8)
+	binary.LittleEndian.PutUint16(b[8:], 0)   // bit offset
+	binary.LittleEndian.PutUint16(b[10:], 64) // precision
+	b[12], b[13], b[14], b[15] = 52, 11, 0, 52
+	binary.LittleEndian.PutUint32(b[16:], 1023)
+	return b
+}
+
+// h5IntType is a little-endian signed 64 bit integer
+func h5IntType() []byte {
+	b := make([]byte, 12)
+	b[0] = 0x10 // version 1, fixed point
+	b[1] = 0x08 // signed
+	binary.LittleEndian.PutUint32(b[4:], 8)
+	binary.LittleEndian.PutUint16(b[8:], 0)
+	binary.LittleEndian.PutUint16(b[10:], 64)
+	return b
+}
+
+// h5StringType is a null padded UTF-8 string of size bytes
+func h5StringType(size int) []byte {
+	b := make([]byte, 8)
+	b[0] = 0x13
+	b[1] = 0x11 // null pad, UTF-8
+	binary.LittleEndian.PutUint32(b[4:], uint32(size))
+	return b
+}
+
+// h5StringAttribute encodes a version 1 attribute message with a scalar string
+func h5StringAttribute(name, value string) []byte {
+	size := len(value)
+	if size == 0 {
+		size = 1
+	}
+	nameField := append([]byte(name), 0)
+	nameSize := len(nameField)
+	for len(nameField)%8 != 0 {
+		nameField = append(nameField, 0)
+	}
+	dtype := h5StringType(size)
+	space := []byte{1, 0, 0, 0, 0, 0, 0, 0} // version 1, scalar
+
+	b := make([]byte, 8)
+	b[0] = 1
+	binary.LittleEndian.PutUint16(b[2:], uint16(nameSize))
+	binary.LittleEndian.PutUint16(b[4:], uint16(len(dtype)))
+	binary.LittleEndian.PutUint16(b[6:], uint16(len(space)))
+	b = append(b, nameField...)
+	b = append(b, dtype...)
+	b = append(b, space...)
+	data := make([]byte, size)
+	copy(data, value)
+	return append(b, data...)
+}
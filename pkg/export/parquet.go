@@ -0,0 +1,99 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress/snappy"
+)
+
+// parquetBatch is the number of rows handed to the writer at once
+const parquetBatch = 1024
+
+// WriteParquet writes one Snappy compressed Parquet file per table into dir
+// and returns their paths. The dataset attributes are stored as key/value
+// metadata of every file.
+func WriteParquet(dir string, ds *Dataset) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, t := range ds.Tables {
+		path := filepath.Join(dir, t.Name+".parquet")
+		if err := writeParquetTable(path, t, ds.Attributes); err != nil {
+			return paths, fmt.Errorf("table %s: %w", t.Name, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func writeParquetTable(path string, t *Table, attributes map[string]string) error {
+	group := parquet.Group{}
+	for _, c := range t.Columns {
+		if _, dup := group[c.Name]; dup {
+			return fmt.Errorf("duplicate column %q", c.Name)
+		}
+		switch c.Kind {
+		case Float:
+			group[c.Name] = parquet.Leaf(parquet.DoubleType)
+		case Int:
+			group[c.Name] = parquet.Int(64)
+		default:
+			group[c.Name] = parquet.String()
+		}
+	}
+	schema := parquet.NewSchema(t.Name, group)
+
+	// The schema orders the columns by name
+	byName := make(map[string]*Column, len(t.Columns))
+	for _, c := range t.Columns {
+		byName[c.Name] = c
+	}
+	var columns []*Column
+	for _, path := range schema.Columns() {
+		columns = append(columns, byName[path[0]])
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	options := []parquet.WriterOption{schema, parquet.Compression(&snappy.Codec{})}
+	for _, k := range sortedKeys(attributes) {
+		options = append(options, parquet.KeyValueMetadata(k, attributes[k]))
+	}
+	w := parquet.NewWriter(f, options...)
+
+	rows := make([]parquet.Row, 0, parquetBatch)
+	for i := 0; i < t.Rows(); i++ {
+		row := make(parquet.Row, len(columns))
+		for j, c := range columns {
+			var v parquet.Value
+			switch c.Kind {
+			case Float:
+				v = parquet.DoubleValue(c.Floats[i])
+			case Int:
+				v = parquet.Int64Value(c.Ints[i])
+			default:
+				v = parquet.ByteArrayValue([]byte(c.Strings[i]))
+			}
+			row[j] = v.Level(0, 0, j)
+		}
+		rows = append(rows, row)
+		if len(rows) == parquetBatch || i == t.Rows()-1 {
+			if _, err := w.WriteRows(rows); err != nil {
+				return err
+			}
+			rows = rows[:0]
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,241 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Kind is the storage type of a column
+type Kind int
+
+const (
+	Float Kind = iota
+	Int
+	String
+)
+
+// Column holds the values of one column, in the slice matching its kind.
+// Unit and UCD are optional and only written by formats that carry them.
+type Column struct {
+	Name    string
+	Kind    Kind
+	Unit    string
+	UCD     string
+	Floats  []float64
+	Ints    []int64
+	Strings []string
+}
+
+// Len is the number of values in the column
+func (c *Column) Len() int {
+	switch c.Kind {
+	case Float:
+		return len(c.Floats)
+	case Int:
+		return len(c.Ints)
+	}
+	return len(c.Strings)
+}
+
+// Values returns a numeric column as floats, nil for strings
+func (c *Column) Values() []float64 {
+	switch c.Kind {
+	case Float:
+		return c.Floats
+	case Int:
+		out := make([]float64, len(c.Ints))
+		for i, v := range c.Ints {
+			out[i] = float64(v)
+		}
+		return out
+	}
+	return nil
+}
+
+// Table is a named set of columns of equal length
+type Table struct {
+	Name    string
+	Columns []*Column
+}
+
+// Rows is the number of rows of the table
+func (t *Table) Rows() int {
+	if len(t.Columns) == 0 {
+		return 0
+	}
+	return t.Columns[0].Len()
+}
+
+// Column returns the column of name, nil if the table or column is missing
+func (t *Table) Column(name string) *Column {
+	if t == nil {
+		return nil
+	}
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Floats returns the numeric column of name as floats, nil if it is missing
+func (t *Table) Floats(name string) []float64 {
+	if c := t.Column(name); c != nil {
+		return c.Values()
+	}
+	return nil
+}
+
+// Dataset is an analysis result as tables plus scalar attributes
+type Dataset struct {
+	Type       string
+	Attributes map[string]string
+	Tables     []*Table
+}
+
+// Table returns the table of name, nil if the result has none
+func (d *Dataset) Table(name string) *Table {
+	for _, t := range d.Tables {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// add appends a table unless it is empty, empty tables carry no information
+func (d *Dataset) add(t *Table) {
+	if t.Rows() > 0 {
+		d.Tables = append(d.Tables, t)
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// field is one leaf of a flattened struct
+type field struct {
+	name  string
+	index []int
+	kind  Kind
+}
+
+// NewTable builds a table from a slice of structs. Each exported field
+// becomes a column named by its json tag; nested structs are flattened to
+// parent.child columns, times are RFC 3339 strings and maps, slices and
+// pointers are stored as JSON. Fields named in omit are left out.
+func NewTable(name string, rows interface{}, omit ...string) (*Table, error) {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("table %s: rows must be a slice of structs, got %T", name, rows)
+	}
+	skip := make(map[string]bool, len(omit))
+	for _, o := range omit {
+		skip[o] = true
+	}
+	fields := structFields(v.Type().Elem(), "", nil, skip)
+
+	t := &Table{Name: name}
+	for _, f := range fields {
+		t.Columns = append(t.Columns, &Column{Name: f.name, Kind: f.kind})
+	}
+	for i := 0; i < v.Len(); i++ {
+		row := v.Index(i)
+		for j, f := range fields {
+			appendValue(t.Columns[j], row.FieldByIndex(f.index))
+		}
+	}
+	return t, nil
+}
+
+func structFields(typ reflect.Type, prefix string, index []int, skip map[string]bool) []field {
+	var fields []field
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if !sf.IsExported() || sf.Anonymous {
+			continue
+		}
+		name := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		name = prefix + name
+		if skip[name] {
+			continue
+		}
+		idx := append(append([]int(nil), index...), i)
+
+		switch ft := sf.Type; {
+		case ft == timeType:
+			fields = append(fields, field{name, idx, String})
+		case ft.Kind() == reflect.Struct:
+			fields = append(fields, structFields(ft, name+".", idx, skip)...)
+		case ft.Kind() == reflect.Float32 || ft.Kind() == reflect.Float64:
+			fields = append(fields, field{name, idx, Float})
+		case ft.Kind() >= reflect.Int && ft.Kind() <= reflect.Uint64, ft.Kind() == reflect.Bool:
+			fields = append(fields, field{name, idx, Int})
+		default:
+			fields = append(fields, field{name, idx, String})
+		}
+	}
+	return fields
+}
+
+func appendValue(c *Column, v reflect.Value) {
+	switch c.Kind {
+	case Float:
+		c.Floats = append(c.Floats, v.Float())
+	case Int:
+		var n int64
+		switch {
+		case v.Kind() == reflect.Bool:
+			if v.Bool() {
+				n = 1
+			}
+		case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64:
+			n = int64(v.Uint())
+		default:
+			n = v.Int()
+		}
+		c.Ints = append(c.Ints, n)
+	default:
+		c.Strings = append(c.Strings, stringValue(v))
+	}
+}
+
+func stringValue(v reflect.Value) string {
+	switch {
+	case v.Type() == timeType:
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339Nano)
+	case v.Kind() == reflect.String:
+		return v.String()
+	case (v.Kind() == reflect.Map || v.Kind() == reflect.Slice || v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil():
+		return ""
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// keyValueTable stores a map as a two column table sorted by key
+func keyValueTable(name, valueName string, m map[string]float64) *Table {
+	keys := sortedKeys(m)
+	key := &Column{Name: "name", Kind: String, Strings: keys}
+	value := &Column{Name: valueName, Kind: Float}
+	for _, k := range keys {
+		value.Floats = append(value.Floats, m[k])
+	}
+	return &Table{Name: name, Columns: []*Column{key, value}}
+}
@@ -0,0 +1,211 @@
+// Package feegrant builds x/feegrant messages, so an account can pay the
+// transaction fees of another. The messages are encoded with the generated
+// API types, the x/feegrant module itself is not a dependency.
+package feegrant
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"time"
+
+	basev1beta1 "cosmossdk.io/api/cosmos/base/v1beta1"
+	feegrantv1beta1 "cosmossdk.io/api/cosmos/feegrant/v1beta1"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Proto names of the x/feegrant types used here
+const (
+	msgGrantAllowanceName   = "cosmos.feegrant.v1beta1.MsgGrantAllowance"
+	msgRevokeAllowanceName  = "cosmos.feegrant.v1beta1.MsgRevokeAllowance"
+	basicAllowanceName      = "cosmos.feegrant.v1beta1.BasicAllowance"
+	allowedMsgAllowanceName = "cosmos.feegrant.v1beta1.AllowedMsgAllowance"
+)
+
+// Allowance limits what a grantee may spend on fees. Without spend limit and
+// expiration the granter pays any fee until the grant is revoked; with
+// AllowedMessages only transactions of these message types are covered.
+type Allowance struct {
+	SpendLimit      sdk.Coins  `json:"spend_limit,omitempty"`
+	Expiration      *time.Time `json:"expiration,omitempty"`
+	AllowedMessages []string   `json:"allowed_messages,omitempty"` // type URLs, e.g. /cosmos.bank.v1beta1.MsgSend
+}
+
+// MsgGrantAllowance mirrors cosmos.feegrant.v1beta1.MsgGrantAllowance with a
+// BasicAllowance, wrapped in an AllowedMsgAllowance when messages are limited
+type MsgGrantAllowance struct {
+	Granter   string    `json:"granter"`
+	Grantee   string    `json:"grantee"`
+	Allowance Allowance `json:"allowance"`
+}
+
+// MsgRevokeAllowance mirrors cosmos.feegrant.v1beta1.MsgRevokeAllowance
+type MsgRevokeAllowance struct {
+	Granter string `json:"granter"`
+	Grantee string `json:"grantee"`
+}
+
+var (
+	_ sdk.Msg = (*MsgGrantAllowance)(nil)
+	_ sdk.Msg = (*MsgRevokeAllowance)(nil)
+)
+
+// RegisterInterfaces makes the messages known to the interface registry
+func RegisterInterfaces(registry codectypes.InterfaceRegistry) {
+	registry.RegisterImplementations((*sdk.Msg)(nil), &MsgGrantAllowance{}, &MsgRevokeAllowance{})
+}
+
+func (m *MsgGrantAllowance) Reset()                  { *m = MsgGrantAllowance{} }
+func (m *MsgGrantAllowance) ProtoMessage()           {}
+func (m *MsgGrantAllowance) XXX_MessageName() string { return msgGrantAllowanceName }
+func (m *MsgGrantAllowance) String() string {
+	return fmt.Sprintf("MsgGrantAllowance{Granter: %s, Grantee: %s, Allowance: %+v}", m.Granter, m.Grantee, m.Allowance)
+}
+
+// Marshal encodes the message in protobuf wire format
+func (m *MsgGrantAllowance) Marshal() ([]byte, error) {
+	allowance, err := m.Allowance.toAny()
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(&feegrantv1beta1.MsgGrantAllowance{Granter: m.Granter, Grantee: m.Grantee, Allowance: allowance})
+}
+
+// Unmarshal decodes the protobuf wire format
+func (m *MsgGrantAllowance) Unmarshal(data []byte) error {
+	var msg feegrantv1beta1.MsgGrantAllowance
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	m.Reset()
+	m.Granter, m.Grantee = msg.Granter, msg.Grantee
+	return m.Allowance.fromAny(msg.Allowance)
+}
+
+// Size returns the encoded length
+func (m *MsgGrantAllowance) Size() int {
+	b, _ := m.Marshal()
+	return len(b)
+}
+
+// Descriptor lets the transaction decoder check the message for unknown fields
+func (m *MsgGrantAllowance) Descriptor() ([]byte, []int) {
+	return descriptor(&feegrantv1beta1.MsgGrantAllowance{})
+}
+
+func (m *MsgRevokeAllowance) Reset()                  { *m = MsgRevokeAllowance{} }
+func (m *MsgRevokeAllowance) ProtoMessage()           {}
+func (m *MsgRevokeAllowance) XXX_MessageName() string { return msgRevokeAllowanceName }
+func (m *MsgRevokeAllowance) String() string {
+	return fmt.Sprintf("MsgRevokeAllowance{Granter: %s, Grantee: %s}", m.Granter, m.Grantee)
+}
+
+// Marshal encodes the message in protobuf wire format
+func (m *MsgRevokeAllowance) Marshal() ([]byte, error) {
+	return proto.Marshal(&feegrantv1beta1.MsgRevokeAllowance{Granter: m.Granter, Grantee: m.Grantee})
+}
+
+// Unmarshal decodes the protobuf wire format
+func (m *MsgRevokeAllowance) Unmarshal(data []byte) error {
+	var msg feegrantv1beta1.MsgRevokeAllowance
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	m.Granter, m.Grantee = msg.Granter, msg.Grantee
+	return nil
+}
+
+// Size returns the encoded length
+func (m *MsgRevokeAllowance) Size() int {
+	b, _ := m.Marshal()
+	return len(b)
+}
+
+// Descriptor lets the transaction decoder check the message for unknown fields
+func (m *MsgRevokeAllowance) Descriptor() ([]byte, []int) {
+	return descriptor(&feegrantv1beta1.MsgRevokeAllowance{})
+}
+
+func (a Allowance) toAny() (*anypb.Any, error) {
+	basic := &feegrantv1beta1.BasicAllowance{}
+	for _, coin := range a.SpendLimit {
+		basic.SpendLimit = append(basic.SpendLimit, &basev1beta1.Coin{Denom: coin.Denom, Amount: coin.Amount.String()})
+	}
+	if a.Expiration != nil {
+		basic.Expiration = timestamppb.New(*a.Expiration)
+	}
+	allowance, err := packAny(basicAllowanceName, basic)
+	if err != nil || len(a.AllowedMessages) == 0 {
+		return allowance, err
+	}
+	return packAny(allowedMsgAllowanceName, &feegrantv1beta1.AllowedMsgAllowance{
+		Allowance:       allowance,
+		AllowedMessages: a.AllowedMessages,
+	})
+}
+
+func (a *Allowance) fromAny(allowance *anypb.Any) error {
+	if allowance == nil {
+		return nil
+	}
+	if allowance.TypeUrl == "/"+allowedMsgAllowanceName {
+		var allowed feegrantv1beta1.AllowedMsgAllowance
+		if err := proto.Unmarshal(allowance.Value, &allowed); err != nil {
+			return err
+		}
+		a.AllowedMessages = allowed.AllowedMessages
+		allowance = allowed.Allowance
+		if allowance == nil {
+			return nil
+		}
+	}
+	if allowance.TypeUrl != "/"+basicAllowanceName {
+		return fmt.Errorf("unsupported fee allowance %s", allowance.TypeUrl)
+	}
+	var basic feegrantv1beta1.BasicAllowance
+	if err := proto.Unmarshal(allowance.Value, &basic); err != nil {
+		return err
+	}
+	for _, coin := range basic.SpendLimit {
+		parsed, err := sdk.ParseCoinNormalized(coin.Amount + coin.Denom)
+		if err != nil {
+			return err
+		}
+		a.SpendLimit = append(a.SpendLimit, parsed)
+	}
+	if basic.Expiration != nil {
+		expiration := basic.Expiration.AsTime()
+		a.Expiration = &expiration
+	}
+	return nil
+}
+
+// packAny wraps a message the way the SDK does, with a type URL of "/" and
+// the proto name
+func packAny(name string, msg proto.Message) (*anypb.Any, error) {
+	value, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return &anypb.Any{TypeUrl: "/" + name, Value: value}, nil
+}
+
+// descriptor returns the gzipped file descriptor and the index of msg in it,
+// as generated gogoproto code does
+func descriptor(msg proto.Message) ([]byte, []int) {
+	md := msg.ProtoReflect().Descriptor()
+	fd, err := proto.Marshal(protodesc.ToFileDescriptorProto(md.ParentFile()))
+	if err != nil {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(fd)
+	zw.Close()
+	return buf.Bytes(), []int{md.Index()}
+}
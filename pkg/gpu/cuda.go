@@ -10,9 +10,11 @@ import (
 	"github.com/oxygene76/medasdigital-client/pkg/utils"
 )
 
-// Manager manages CUDA GPUs for astronomical analysis
+// Manager manages CUDA, ROCm and Metal GPUs for astronomical analysis
 type Manager struct {
 	devices       []types.GPUDevice
+	backend       string
+	driverVersion string
 	config        *utils.GPUConfig
 	isInitialized bool
 	mutex         sync.RWMutex
@@ -33,7 +35,7 @@ func NewManager(config *utils.GPUConfig) *Manager {
 	}
 }
 
-// Initialize initializes CUDA and detects available GPUs
+// Initialize detects available GPUs, without supported hardware the devices are simulated
 func (m *Manager) Initialize() error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -52,11 +54,20 @@ func (m *Manager) Initialize() error {
 		deviceIDs = []int{0}
 	}
 
+	if detected := Detect(); len(detected) > 0 {
+		return m.initializeDetected(detected, deviceIDs)
+	}
+
+	m.backend = BackendCUDA
+	m.driverVersion = GetDriverVersion()
+
 	// Initialize devices
 	for _, deviceID := range deviceIDs {
 		device := types.GPUDevice{
 			ID:                 deviceID,
 			Name:               fmt.Sprintf("NVIDIA GeForce RTX %d", 3080+deviceID*10),
+			Vendor:             VendorNVIDIA,
+			Backend:            BackendCUDA,
 			Memory:             25769803776, // 24GB in bytes
 			MemoryGB:           24.0,
 			MemoryUsed:         2147483648,  // 2GB used
@@ -80,6 +91,48 @@ func (m *Manager) Initialize() error {
 	return nil
 }
 
+// initializeDetected uses the configured devices among the detected hardware
+func (m *Manager) initializeDetected(detected []DetectedDevice, deviceIDs []int) error {
+	byIndex := make(map[int]DetectedDevice, len(detected))
+	for _, d := range detected {
+		byIndex[d.Index] = d
+	}
+
+	for _, deviceID := range deviceIDs {
+		d, ok := byIndex[deviceID]
+		if !ok {
+			return fmt.Errorf("configured GPU %d not found (%d %s device(s) detected)", deviceID, len(detected), detected[0].Backend)
+		}
+
+		device := types.GPUDevice{
+			ID:                deviceID,
+			Name:              d.Name,
+			Vendor:            d.Vendor,
+			Backend:           d.Backend,
+			MemoryUsed:        d.MemoryUsed,
+			MemoryFree:        d.MemoryTotal - d.MemoryUsed,
+			IsAvailable:       true,
+		}
+		device.SetMemoryFromBytes(d.MemoryTotal)
+		if d.MemoryTotal > 0 {
+			device.MemoryUtilization = float64(d.MemoryUsed) / float64(d.MemoryTotal)
+		}
+		m.devices = append(m.devices, device)
+	}
+
+	m.backend = detected[0].Backend
+	m.driverVersion = detected[0].DriverVersion
+	m.isInitialized = true
+	return nil
+}
+
+// Backend returns the compute backend of the detected devices (cuda, rocm or metal)
+func (m *Manager) Backend() string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.backend
+}
+
 // Cleanup cleans up CUDA resources
 func (m *Manager) Cleanup() error {
 	m.mutex.Lock()
@@ -239,11 +292,14 @@ func (m *Manager) GetGPUInfo() (*types.GPUInfo, error) {
 		Devices:        updatedDevices,
 		TotalMemoryGB:  0,
 		AvailableMemoryGB: 0,
-		CUDAVersion:    "12.1",
-		DriverVersion:  "535.86.10",
+		Backend:        m.backend,
+		DriverVersion:  m.driverVersion,
 		IsInitialized:  m.isInitialized,
 		Timestamp:      time.Now(),
 	}
+	if m.backend == BackendCUDA {
+		info.CUDAVersion = GetCUDAVersion()
+	}
 
 	// Calculate totals
 	info.UpdateTotalMemory()
@@ -0,0 +1,263 @@
+package gpu
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Vendors and the compute backend used for them
+const (
+	VendorNVIDIA = "nvidia"
+	VendorAMD    = "amd"
+	VendorApple  = "apple"
+
+	BackendCUDA  = "cuda"
+	BackendROCm  = "rocm"
+	BackendMetal = "metal"
+)
+
+// amdPCIVendorID is the PCI vendor ID of AMD devices in sysfs
+const amdPCIVendorID = "0x1002"
+
+// DetectedDevice is a GPU found on the host
+type DetectedDevice struct {
+	Index         int    `json:"index"`
+	Vendor        string `json:"vendor"`
+	Backend       string `json:"backend"`
+	Name          string `json:"name"`
+	MemoryTotal   int64  `json:"memory_total"` // bytes
+	MemoryUsed    int64  `json:"memory_used"`  // bytes
+	DriverVersion string `json:"driver_version,omitempty"`
+}
+
+// Summary renders the device for status output
+func (d DetectedDevice) Summary() string {
+	return fmt.Sprintf("%s (%d MB, %s)", d.Name, d.MemoryTotal/1024/1024, strings.ToUpper(d.Backend))
+}
+
+// Detect finds GPUs of all supported vendors. The first backend that reports
+// devices wins, mixed-vendor hosts are not supported by the compute backends anyway.
+func Detect() []DetectedDevice {
+	detectors := []func() ([]DetectedDevice, error){detectNVIDIA, detectROCm, detectAMDSysfs}
+	if runtime.GOOS == "darwin" {
+		detectors = []func() ([]DetectedDevice, error){detectMetal}
+	}
+
+	for _, detect := range detectors {
+		if devices, err := detect(); err == nil && len(devices) > 0 {
+			return devices
+		}
+	}
+	return nil
+}
+
+// detectNVIDIA queries nvidia-smi
+func detectNVIDIA() ([]DetectedDevice, error) {
+	out, err := exec.Command("nvidia-smi",
+		"--query-gpu=index,name,memory.total,memory.used,driver_version",
+		"--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return nil, fmt.Errorf("nvidia-smi not available: %w", err)
+	}
+
+	var devices []DetectedDevice
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.Split(line, ",")
+		if len(parts) < 5 {
+			continue
+		}
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		index, _ := strconv.Atoi(parts[0])
+		total, _ := strconv.ParseInt(parts[2], 10, 64)
+		used, _ := strconv.ParseInt(parts[3], 10, 64)
+		devices = append(devices, DetectedDevice{
+			Index:         index,
+			Vendor:        VendorNVIDIA,
+			Backend:       BackendCUDA,
+			Name:          parts[1],
+			MemoryTotal:   total * 1024 * 1024,
+			MemoryUsed:    used * 1024 * 1024,
+			DriverVersion: parts[4],
+		})
+	}
+	return devices, nil
+}
+
+// detectROCm queries rocm-smi, its JSON keys differ between ROCm releases
+func detectROCm() ([]DetectedDevice, error) {
+	out, err := exec.Command("rocm-smi", "--showproductname", "--showmeminfo", "vram", "--showdriverversion", "--json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("rocm-smi not available: %w", err)
+	}
+
+	var report map[string]map[string]string
+	if err := json.Unmarshal(out, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse rocm-smi output: %w", err)
+	}
+
+	driver := lookup(report["system"], "driver version")
+
+	var devices []DetectedDevice
+	for card, fields := range report {
+		if !strings.HasPrefix(card, "card") {
+			continue
+		}
+		index, err := strconv.Atoi(strings.TrimPrefix(card, "card"))
+		if err != nil {
+			continue
+		}
+		name := lookup(fields, "card series")
+		if name == "" {
+			name = lookup(fields, "card model")
+		}
+		total, _ := strconv.ParseInt(lookup(fields, "vram total memory (b)"), 10, 64)
+		used, _ := strconv.ParseInt(lookup(fields, "vram total used memory (b)"), 10, 64)
+		devices = append(devices, DetectedDevice{
+			Index:         index,
+			Vendor:        VendorAMD,
+			Backend:       BackendROCm,
+			Name:          name,
+			MemoryTotal:   total,
+			MemoryUsed:    used,
+			DriverVersion: driver,
+		})
+	}
+	sortByIndex(devices)
+	return devices, nil
+}
+
+// detectAMDSysfs reads the amdgpu sysfs nodes when rocm-smi is not installed
+func detectAMDSysfs() ([]DetectedDevice, error) {
+	cards, err := filepath.Glob("/sys/class/drm/card[0-9]*")
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []DetectedDevice
+	for _, card := range cards {
+		base := filepath.Base(card)
+		// Connector entries like card0-DP-1 are not devices
+		if strings.Contains(base, "-") {
+			continue
+		}
+		deviceDir := filepath.Join(card, "device")
+		if readSysfs(deviceDir, "vendor") != amdPCIVendorID {
+			continue
+		}
+
+		name := readSysfs(deviceDir, "product_name")
+		if name == "" {
+			name = "AMD GPU " + readSysfs(deviceDir, "device")
+		}
+		total, _ := strconv.ParseInt(readSysfs(deviceDir, "mem_info_vram_total"), 10, 64)
+		used, _ := strconv.ParseInt(readSysfs(deviceDir, "mem_info_vram_used"), 10, 64)
+		devices = append(devices, DetectedDevice{
+			Vendor:      VendorAMD,
+			Backend:     BackendROCm,
+			Name:        name,
+			MemoryTotal: total,
+			MemoryUsed:  used,
+		})
+	}
+
+	// DRM card numbers are not contiguous on every system, ROCm enumerates in order
+	for i := range devices {
+		devices[i].Index = i
+	}
+	return devices, nil
+}
+
+// detectMetal uses system_profiler. Apple silicon shares system memory with
+// the GPU, Metal recommends at most ~75% of it as working set.
+func detectMetal() ([]DetectedDevice, error) {
+	out, err := exec.Command("system_profiler", "SPDisplaysDataType", "-json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("system_profiler not available: %w", err)
+	}
+
+	var report struct {
+		Displays []map[string]interface{} `json:"SPDisplaysDataType"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse system_profiler output: %w", err)
+	}
+
+	var unified int64
+	if mem, err := exec.Command("sysctl", "-n", "hw.memsize").Output(); err == nil {
+		if total, err := strconv.ParseInt(strings.TrimSpace(string(mem)), 10, 64); err == nil {
+			unified = total * 3 / 4
+		}
+	}
+
+	var devices []DetectedDevice
+	for i, d := range report.Displays {
+		name, _ := d["sppci_model"].(string)
+		vendor, _ := d["sppci_vendor"].(string)
+
+		device := DetectedDevice{
+			Index:   i,
+			Vendor:  VendorApple,
+			Backend: BackendMetal,
+			Name:    name,
+		}
+		if vram, ok := d["spdisplays_vram"].(string); ok {
+			// Discrete GPUs in Intel Macs report e.g. "8 GB"
+			device.MemoryTotal = parseMemorySize(vram)
+			if strings.Contains(strings.ToLower(vendor), "amd") {
+				device.Vendor = VendorAMD
+			}
+		} else {
+			device.MemoryTotal = unified
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// lookup finds a field case-insensitively
+func lookup(fields map[string]string, key string) string {
+	for k, v := range fields {
+		if strings.EqualFold(strings.TrimSpace(k), key) {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+func readSysfs(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// parseMemorySize parses values like "8 GB" or "1536 MB" into bytes
+func parseMemorySize(s string) int64 {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	unit := int64(1024 * 1024)
+	if len(fields) > 1 && strings.EqualFold(fields[1], "GB") {
+		unit *= 1024
+	}
+	return int64(value * float64(unit))
+}
+
+func sortByIndex(devices []DetectedDevice) {
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Index < devices[j].Index })
+}
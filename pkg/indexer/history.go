@@ -0,0 +1,123 @@
+package indexer
+
+import (
+	"fmt"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// Granularities of a balance history
+const (
+	GranularityHour  = "hour"
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// maxHistoryPoints keeps a fine granularity over a long range from exhausting memory
+const maxHistoryPoints = 100000
+
+// BalancePoint is the balance at the end of one period and the flows within it
+type BalancePoint struct {
+	Start     time.Time `json:"start"`
+	Balance   sdk.Coins `json:"balance"`
+	Received  sdk.Coins `json:"received"`
+	Sent      sdk.Coins `json:"sent"` // fees included
+	Transfers int       `json:"transfers"`
+}
+
+// PeriodStart truncates t to the start of its period in t's location, weeks start on Monday
+func PeriodStart(t time.Time, granularity string) (time.Time, error) {
+	y, m, d := t.Date()
+	switch granularity {
+	case GranularityHour:
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, t.Location()), nil
+	case GranularityDay:
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location()), nil
+	case GranularityWeek:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, t.Location()), nil
+	case GranularityMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location()), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown granularity %q (hour, day, week or month)", granularity)
+}
+
+// nextPeriod is the start of the period after the one starting at start
+func nextPeriod(start time.Time, granularity string) time.Time {
+	switch granularity {
+	case GranularityHour:
+		return start.Add(time.Hour)
+	case GranularityDay:
+		return start.AddDate(0, 0, 1)
+	case GranularityWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
+
+// BalanceHistory reconstructs the balance of an address from its indexed
+// transfers, one point per period from the period of from (or of the first
+// transfer) to the period of to (or now), periods in loc. The balance starts
+// at zero at genesis, so the address has to be synced from the start;
+// changes without a transfer event are not seen.
+func (ix *Index) BalanceHistory(address, granularity string, from, to time.Time, loc *time.Location) ([]BalancePoint, error) {
+	if _, err := PeriodStart(time.Now(), granularity); err != nil {
+		return nil, err
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	transfers, err := ix.Transfers(address, time.Time{}, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(transfers) == 0 {
+		return nil, nil
+	}
+	if from.IsZero() {
+		from = transfers[0].Time
+	}
+
+	first, _ := PeriodStart(from.In(loc), granularity)
+	last, _ := PeriodStart(to.In(loc), granularity)
+	if last.Before(first) {
+		return nil, fmt.Errorf("the period ends before it starts")
+	}
+
+	var points []BalancePoint
+	balance := sdk.NewCoins()
+	i := 0
+	for start := first; !start.After(last); start = nextPeriod(start, granularity) {
+		if len(points) == maxHistoryPoints {
+			return nil, fmt.Errorf("more than %d %ss, use a coarser granularity or --from", maxHistoryPoints, granularity)
+		}
+		end := nextPeriod(start, granularity)
+		p := BalancePoint{Start: start, Received: sdk.NewCoins(), Sent: sdk.NewCoins()}
+		for ; i < len(transfers) && transfers[i].Time.Before(end); i++ {
+			t := transfers[i]
+			inPeriod := !t.Time.Before(start)
+			if t.Recipient == address {
+				balance = balance.Add(t.Amount...)
+				if inPeriod {
+					p.Received = p.Received.Add(t.Amount...)
+				}
+			}
+			if t.Sender == address {
+				// A negative balance means transfers are missing from the index
+				balance, _ = balance.SafeSub(t.Amount...)
+				if inPeriod {
+					p.Sent = p.Sent.Add(t.Amount...)
+				}
+			}
+			if inPeriod {
+				p.Transfers++
+			}
+		}
+		p.Balance = balance
+		points = append(points, p)
+	}
+	return points, nil
+}
@@ -0,0 +1,366 @@
+// Package indexer keeps the transactions of selected addresses in a local
+// SQLite database, so balance, registration and earnings queries do not have
+// to run TxSearch against the public RPC every time.
+package indexer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	_ "modernc.org/sqlite" // registers the "sqlite" driver, pure Go
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+	"github.com/oxygene76/medasdigital-client/pkg/state"
+)
+
+// searchPageSize is the TxSearch page size of a sync
+const searchPageSize = 100
+
+const schema = `
+CREATE TABLE IF NOT EXISTS addresses (
+	address       TEXT PRIMARY KEY,
+	synced_height INTEGER NOT NULL DEFAULT 0,
+	synced_at     INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS transfers (
+	tx_hash   TEXT    NOT NULL,
+	idx       INTEGER NOT NULL,
+	height    INTEGER NOT NULL,
+	time      INTEGER NOT NULL,
+	sender    TEXT    NOT NULL,
+	recipient TEXT    NOT NULL,
+	amount    TEXT    NOT NULL,
+	memo      TEXT    NOT NULL DEFAULT '',
+	PRIMARY KEY (tx_hash, idx)
+);
+CREATE INDEX IF NOT EXISTS transfers_sender ON transfers (sender, height);
+CREATE INDEX IF NOT EXISTS transfers_recipient ON transfers (recipient, height);
+CREATE TABLE IF NOT EXISTS registrations (
+	tx_hash TEXT PRIMARY KEY,
+	address TEXT    NOT NULL,
+	height  INTEGER NOT NULL,
+	time    INTEGER NOT NULL,
+	type    TEXT    NOT NULL,
+	memo    TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS registrations_address ON registrations (address, height);
+`
+
+// Index is the local transaction database of one chain
+type Index struct {
+	db   *sql.DB
+	path string
+}
+
+// AddressState is how far an address has been indexed
+type AddressState struct {
+	Address      string    `json:"address"`
+	SyncedHeight int64     `json:"synced_height"`
+	SyncedAt     time.Time `json:"synced_at"`
+	Transfers    int       `json:"transfers"`
+}
+
+// Registration is a registration memo sent by an indexed address
+type Registration struct {
+	TxHash  string                       `json:"tx_hash"`
+	Address string                       `json:"address"`
+	Height  int64                        `json:"height"`
+	Time    time.Time                    `json:"time"`
+	Memo    *blockchain.RegistrationMemo `json:"memo"`
+}
+
+// SyncStats summarizes a sync run of one address
+type SyncStats struct {
+	FromHeight int64
+	ToHeight   int64
+	Transfers  int // new transfers
+}
+
+// Path is the database file of a chain below dir
+func Path(dir, chainID string) string {
+	return filepath.Join(dir, state.IndexDir, chainID+".db")
+}
+
+// Open opens or creates the database
+func Open(path string) (*Index, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create index directory: %w", err)
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index: %w", err)
+	}
+	// One writer at a time, SQLite would answer SQLITE_BUSY otherwise
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create index schema in %s: %w", path, err)
+	}
+	return &Index{db: db, path: path}, nil
+}
+
+// Close closes the database
+func (ix *Index) Close() error {
+	return ix.db.Close()
+}
+
+// Path is the database file
+func (ix *Index) Path() string {
+	return ix.path
+}
+
+// Track adds addresses to the index, they are synced from genesis on the next Sync
+func (ix *Index) Track(addresses ...string) error {
+	for _, addr := range addresses {
+		if _, err := ix.db.Exec(`INSERT OR IGNORE INTO addresses (address) VALUES (?)`, addr); err != nil {
+			return fmt.Errorf("failed to track %s: %w", addr, err)
+		}
+	}
+	return nil
+}
+
+// Untrack stops syncing an address, its transfers stay in the index
+func (ix *Index) Untrack(address string) error {
+	_, err := ix.db.Exec(`DELETE FROM addresses WHERE address = ?`, address)
+	return err
+}
+
+// Addresses lists the tracked addresses
+func (ix *Index) Addresses() ([]AddressState, error) {
+	rows, err := ix.db.Query(`
+		SELECT a.address, a.synced_height, a.synced_at,
+			(SELECT COUNT(*) FROM transfers t WHERE t.sender = a.address OR t.recipient = a.address)
+		FROM addresses a ORDER BY a.address`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var states []AddressState
+	for rows.Next() {
+		var s AddressState
+		var syncedAt int64
+		if err := rows.Scan(&s.Address, &s.SyncedHeight, &syncedAt, &s.Transfers); err != nil {
+			return nil, err
+		}
+		if syncedAt > 0 {
+			s.SyncedAt = time.Unix(syncedAt, 0).UTC()
+		}
+		states = append(states, s)
+	}
+	return states, rows.Err()
+}
+
+// State returns how far an address is indexed, nil if it is not tracked
+func (ix *Index) State(address string) (*AddressState, error) {
+	states, err := ix.Addresses()
+	if err != nil {
+		return nil, err
+	}
+	for i := range states {
+		if states[i].Address == address {
+			return &states[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Sync fetches the transactions of a tracked address committed since its
+// last sync, up to the current height. Transactions are stored per search
+// page, an interrupted sync keeps what it fetched and starts over from the
+// last completed height.
+func (ix *Index) Sync(ctx context.Context, client *blockchain.Client, address string) (SyncStats, error) {
+	var from int64
+	err := ix.db.QueryRowContext(ctx, `SELECT synced_height FROM addresses WHERE address = ?`, address).Scan(&from)
+	if err == sql.ErrNoRows {
+		return SyncStats{}, fmt.Errorf("%s is not tracked", address)
+	}
+	if err != nil {
+		return SyncStats{}, err
+	}
+
+	status, err := client.GetStatus(ctx)
+	if err != nil {
+		return SyncStats{}, err
+	}
+	stats := SyncStats{FromHeight: from, ToHeight: status.SyncInfo.LatestBlockHeight}
+	if stats.ToHeight <= from {
+		return stats, nil
+	}
+
+	for _, side := range []string{"sender", "recipient"} {
+		for page := 1; ; page++ {
+			transfers, total, err := client.GetAccountTransfers(ctx, address, side, from, stats.ToHeight, page, searchPageSize)
+			if err != nil {
+				return stats, err
+			}
+			added, err := ix.store(ctx, address, transfers)
+			if err != nil {
+				return stats, err
+			}
+			stats.Transfers += added
+			if page*searchPageSize >= total {
+				break
+			}
+		}
+	}
+
+	_, err = ix.db.ExecContext(ctx, `UPDATE addresses SET synced_height = ?, synced_at = ? WHERE address = ?`,
+		stats.ToHeight, time.Now().Unix(), address)
+	return stats, err
+}
+
+// store inserts the transfers of one search page and the registrations among them
+func (ix *Index) store(ctx context.Context, address string, transfers []blockchain.Transfer) (int, error) {
+	tx, err := ix.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	added := 0
+	idx, lastHash := 0, ""
+	for _, t := range transfers {
+		// Transfers of one transaction come in event order, number them for the key
+		if t.TxHash == lastHash {
+			idx++
+		} else {
+			idx, lastHash = 0, t.TxHash
+		}
+		res, err := tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO transfers (tx_hash, idx, height, time, sender, recipient, amount, memo)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			t.TxHash, idx, t.Height, t.Time.Unix(), t.Sender, t.Recipient, t.Amount.String(), t.Memo)
+		if err != nil {
+			return 0, fmt.Errorf("failed to store transfer %s: %w", t.TxHash, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			added++
+		}
+
+		if t.Sender != address {
+			continue
+		}
+		if reg, err := blockchain.ParseRegistrationMemo(t.Memo); err == nil {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT OR IGNORE INTO registrations (tx_hash, address, height, time, type, memo)
+				VALUES (?, ?, ?, ?, ?, ?)`,
+				t.TxHash, address, t.Height, t.Time.Unix(), reg.Type, t.Memo); err != nil {
+				return 0, fmt.Errorf("failed to store registration %s: %w", t.TxHash, err)
+			}
+		}
+	}
+	return added, tx.Commit()
+}
+
+// Transfers returns the transfers from or to address in [from, to), oldest
+// first. Zero times leave the range open.
+func (ix *Index) Transfers(address string, from, to time.Time) ([]blockchain.Transfer, error) {
+	query := `SELECT tx_hash, height, time, sender, recipient, amount, memo FROM transfers
+		WHERE (sender = ? OR recipient = ?)`
+	args := []interface{}{address, address}
+	if !from.IsZero() {
+		query += ` AND time >= ?`
+		args = append(args, from.Unix())
+	}
+	if !to.IsZero() {
+		query += ` AND time < ?`
+		args = append(args, to.Unix())
+	}
+	query += ` ORDER BY height, tx_hash, idx`
+
+	rows, err := ix.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []blockchain.Transfer
+	for rows.Next() {
+		var t blockchain.Transfer
+		var unix int64
+		var amount string
+		if err := rows.Scan(&t.TxHash, &t.Height, &unix, &t.Sender, &t.Recipient, &amount, &t.Memo); err != nil {
+			return nil, err
+		}
+		t.Time = time.Unix(unix, 0).UTC()
+		if t.Amount, err = sdk.ParseCoinsNormalized(amount); err != nil {
+			return nil, fmt.Errorf("invalid amount %q of %s in the index: %w", amount, t.TxHash, err)
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, rows.Err()
+}
+
+// Registration returns an indexed registration by transaction hash, nil if unknown
+func (ix *Index) Registration(txHash string) (*Registration, error) {
+	regs, err := ix.registrations(`WHERE tx_hash = ?`, txHash)
+	if err != nil || len(regs) == 0 {
+		return nil, err
+	}
+	return &regs[0], nil
+}
+
+// Registrations returns the registrations of an address, newest first, all if empty
+func (ix *Index) Registrations(address string) ([]Registration, error) {
+	if address == "" {
+		return ix.registrations(`ORDER BY height DESC`)
+	}
+	return ix.registrations(`WHERE address = ? ORDER BY height DESC`, address)
+}
+
+func (ix *Index) registrations(where string, args ...interface{}) ([]Registration, error) {
+	rows, err := ix.db.Query(`SELECT tx_hash, address, height, time, memo FROM registrations `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var regs []Registration
+	for rows.Next() {
+		var r Registration
+		var unix int64
+		var memo string
+		if err := rows.Scan(&r.TxHash, &r.Address, &r.Height, &unix, &memo); err != nil {
+			return nil, err
+		}
+		r.Time = time.Unix(unix, 0).UTC()
+		if r.Memo, err = blockchain.ParseRegistrationMemo(memo); err != nil {
+			// Stored before the memos were checked against the schemas
+			continue
+		}
+		regs = append(regs, r)
+	}
+	return regs, rows.Err()
+}
+
+// Earnings is what an address received in a period
+type Earnings struct {
+	Total     sdk.Coins            `json:"total"`
+	Transfers int                  `json:"transfers"`
+	BySender  map[string]sdk.Coins `json:"by_sender"`
+}
+
+// Earnings sums the transfers received by address in [from, to), its own
+// transfers to itself are left out
+func (ix *Index) Earnings(address string, from, to time.Time) (*Earnings, error) {
+	transfers, err := ix.Transfers(address, from, to)
+	if err != nil {
+		return nil, err
+	}
+	e := &Earnings{Total: sdk.NewCoins(), BySender: map[string]sdk.Coins{}}
+	for _, t := range transfers {
+		if t.Recipient != address || t.Sender == address {
+			continue
+		}
+		e.Total = e.Total.Add(t.Amount...)
+		e.BySender[t.Sender] = e.BySender[t.Sender].Add(t.Amount...)
+		e.Transfers++
+	}
+	return e, nil
+}
@@ -0,0 +1,166 @@
+package inference
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const fitsBlockSize = 2880
+
+// maxFITSSide bounds NAXIS1 and NAXIS2 before the data size is computed
+const maxFITSSide = 1 << 16
+
+// loadFITS reads the 2D image in the primary HDU of a FITS file.
+// Pixel values are scaled with BSCALE/BZERO and min-max normalized.
+func loadFITS(path string) (*Cutout, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header, err := readFITSHeader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FITS header of %s: %w", path, err)
+	}
+
+	bitpix, _ := strconv.Atoi(header["BITPIX"])
+	naxis, _ := strconv.Atoi(header["NAXIS"])
+	width, _ := strconv.Atoi(header["NAXIS1"])
+	height, _ := strconv.Atoi(header["NAXIS2"])
+	if naxis < 2 || width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("%s: primary HDU is not a 2D image (NAXIS=%d)", path, naxis)
+	}
+	if width > maxFITSSide || height > maxFITSSide {
+		return nil, fmt.Errorf("%s: image of %dx%d pixels exceeds %dx%d", path, width, height, maxFITSSide, maxFITSSide)
+	}
+
+	bscale := 1.0
+	if v, ok := header["BSCALE"]; ok {
+		bscale, _ = strconv.ParseFloat(v, 64)
+	}
+	bzero := 0.0
+	if v, ok := header["BZERO"]; ok {
+		bzero, _ = strconv.ParseFloat(v, 64)
+	}
+
+	bytesPerPixel := int(math.Abs(float64(bitpix))) / 8
+	if bytesPerPixel == 0 {
+		return nil, fmt.Errorf("%s: unsupported BITPIX %d", path, bitpix)
+	}
+
+	// The header must not claim more data than the file holds
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := int64(width) * int64(height) * int64(bytesPerPixel)
+	if size > info.Size()-offset {
+		return nil, fmt.Errorf("%s: header declares %d data bytes, file has %d", path, size, info.Size()-offset)
+	}
+
+	// Only the first plane of data cubes is used
+	raw := make([]byte, size)
+	if _, err := io.ReadFull(f, raw); err != nil {
+		return nil, fmt.Errorf("failed to read FITS data of %s: %w", path, err)
+	}
+
+	values := make([]float64, width*height)
+	for i := range values {
+		b := raw[i*bytesPerPixel : (i+1)*bytesPerPixel]
+		var v float64
+		switch bitpix {
+		case 8:
+			v = float64(b[0])
+		case 16:
+			v = float64(int16(binary.BigEndian.Uint16(b)))
+		case 32:
+			v = float64(int32(binary.BigEndian.Uint32(b)))
+		case 64:
+			v = float64(int64(binary.BigEndian.Uint64(b)))
+		case -32:
+			v = float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+		case -64:
+			v = math.Float64frombits(binary.BigEndian.Uint64(b))
+		default:
+			return nil, fmt.Errorf("%s: unsupported BITPIX %d", path, bitpix)
+		}
+		values[i] = bzero + bscale*v
+	}
+
+	// FITS stores rows bottom-up; flip so row 0 is the top like PNG
+	pixels := make([]float32, width*height)
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	span := hi - lo
+	for y := 0; y < height; y++ {
+		src := (height - 1 - y) * width
+		for x := 0; x < width; x++ {
+			v := values[src+x]
+			if math.IsNaN(v) || span <= 0 {
+				continue
+			}
+			pixels[y*width+x] = float32((v - lo) / span)
+		}
+	}
+
+	return &Cutout{Path: path, Width: width, Height: height, Pixels: pixels, WCS: ParseWCS(header)}, nil
+}
+
+// readFITSHeader parses 80-character header cards until END and
+// leaves the reader positioned at the start of the data unit
+func readFITSHeader(r io.Reader) (map[string]string, error) {
+	header := make(map[string]string)
+	block := make([]byte, fitsBlockSize)
+
+	for {
+		if _, err := io.ReadFull(r, block); err != nil {
+			return nil, err
+		}
+		for i := 0; i < fitsBlockSize; i += 80 {
+			card := string(block[i : i+80])
+			key := strings.TrimSpace(card[:8])
+			if key == "END" {
+				return header, nil
+			}
+			if len(card) < 10 || card[8:10] != "= " {
+				continue
+			}
+			value := strings.TrimSpace(card[10:])
+			if strings.HasPrefix(value, "'") {
+				// String values end at the closing quote, '' is an escaped quote
+				var b strings.Builder
+				for j := 1; j < len(value); j++ {
+					if value[j] == '\'' {
+						if j+1 < len(value) && value[j+1] == '\'' {
+							b.WriteByte('\'')
+							j++
+							continue
+						}
+						break
+					}
+					b.WriteByte(value[j])
+				}
+				value = b.String()
+			} else if idx := strings.Index(value, "/"); idx >= 0 {
+				value = value[:idx]
+			}
+			header[key] = strings.TrimSpace(value)
+		}
+	}
+}
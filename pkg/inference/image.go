@@ -0,0 +1,161 @@
+package inference
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Cutout is a single grayscale image prepared for inference.
+// Pixels are row-major and normalized to [0, 1].
+type Cutout struct {
+	Path   string
+	Width  int
+	Height int
+	Pixels []float32
+	WCS    *WCS // from the FITS header, nil for PNG/JPEG
+}
+
+// supportedExtensions lists the file types LoadCutout understands
+var supportedExtensions = map[string]bool{
+	".png":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".fits": true,
+	".fit":  true,
+	".fts":  true,
+}
+
+// CollectImages expands a directory, glob pattern or comma separated list
+// into the sorted list of supported image files
+func CollectImages(spec string) ([]string, error) {
+	var files []string
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		info, err := os.Stat(part)
+		switch {
+		case err == nil && info.IsDir():
+			entries, err := os.ReadDir(part)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read directory %s: %w", part, err)
+			}
+			for _, e := range entries {
+				if !e.IsDir() && supportedExtensions[strings.ToLower(filepath.Ext(e.Name()))] {
+					files = append(files, filepath.Join(part, e.Name()))
+				}
+			}
+		case err == nil:
+			files = append(files, part)
+		default:
+			matches, globErr := filepath.Glob(part)
+			if globErr != nil || len(matches) == 0 {
+				return nil, fmt.Errorf("no images found for %s", part)
+			}
+			for _, m := range matches {
+				if supportedExtensions[strings.ToLower(filepath.Ext(m))] {
+					files = append(files, m)
+				}
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no supported images (png, jpg, fits) found in %s", spec)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// LoadCutout reads a PNG/JPEG or FITS file as normalized grayscale
+func LoadCutout(path string) (*Cutout, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".fits", ".fit", ".fts":
+		return loadFITS(path)
+	case ".png", ".jpg", ".jpeg":
+		return loadRaster(path)
+	default:
+		return nil, fmt.Errorf("unsupported image format: %s", path)
+	}
+}
+
+func loadRaster(path string) (*Cutout, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	pixels := make([]float32, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			// ITU-R BT.601 luma, RGBA() returns 16 bit channels
+			luma := 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			pixels[y*w+x] = float32(luma / 65535.0)
+		}
+	}
+
+	return &Cutout{Path: path, Width: w, Height: h, Pixels: pixels}, nil
+}
+
+// resize scales the cutout to the requested size using bilinear interpolation
+func (c *Cutout) resize(width, height int) []float32 {
+	if width == c.Width && height == c.Height {
+		return c.Pixels
+	}
+
+	out := make([]float32, width*height)
+	sx := float64(c.Width) / float64(width)
+	sy := float64(c.Height) / float64(height)
+
+	for y := 0; y < height; y++ {
+		fy := (float64(y)+0.5)*sy - 0.5
+		y0 := clampInt(int(fy), 0, c.Height-1)
+		y1 := clampInt(y0+1, 0, c.Height-1)
+		dy := float32(fy - float64(y0))
+		if dy < 0 {
+			dy = 0
+		}
+		for x := 0; x < width; x++ {
+			fx := (float64(x)+0.5)*sx - 0.5
+			x0 := clampInt(int(fx), 0, c.Width-1)
+			x1 := clampInt(x0+1, 0, c.Width-1)
+			dx := float32(fx - float64(x0))
+			if dx < 0 {
+				dx = 0
+			}
+			top := c.Pixels[y0*c.Width+x0]*(1-dx) + c.Pixels[y0*c.Width+x1]*dx
+			bottom := c.Pixels[y1*c.Width+x0]*(1-dx) + c.Pixels[y1*c.Width+x1]*dx
+			out[y*width+x] = top*(1-dy) + bottom*dy
+		}
+	}
+	return out
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,361 @@
+package inference
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// Options configures a Detector
+type Options struct {
+	ModelPath      string
+	LibraryPath    string // path to libonnxruntime, falls back to $ONNXRUNTIME_LIB
+	UseGPU         bool
+	DeviceID       int
+	BatchSize      int
+	ScoreThreshold float32
+}
+
+// Detection is a single bounding box found in a cutout.
+// Coordinates are in pixels of the original image.
+type Detection struct {
+	Image string  `json:"image"`
+	X1    float32 `json:"x1"`
+	Y1    float32 `json:"y1"`
+	X2    float32 `json:"x2"`
+	Y2    float32 `json:"y2"`
+	Score float32 `json:"score"`
+	Class int     `json:"class"`
+
+	// Sky is the position of the box center for FITS cutouts with a WCS
+	Sky *SkyPosition `json:"sky,omitempty"`
+}
+
+// Detector runs an exported ONNX detection model.
+//
+// Supported model layouts:
+//   - input  [N, C, H, W] float32 (C = 1 or 3, grayscale replicated)
+//   - output boxes [N, K, 4] + scores [N, K], or
+//   - output detections [N, K, 5+] as x1, y1, x2, y2, score[, class]
+//
+// Box coordinates may be normalized (0..1) or in model input pixels.
+type Detector struct {
+	opts     Options
+	session  *ort.DynamicAdvancedSession
+	inName   string
+	outNames []string
+	channels int
+	height   int
+	width    int
+	GPUUsed  bool
+}
+
+var (
+	envMu       sync.Mutex
+	envRefCount int
+)
+
+// NewDetector loads the model and creates an inference session
+func NewDetector(opts Options) (*Detector, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 8
+	}
+	if opts.ScoreThreshold <= 0 {
+		opts.ScoreThreshold = 0.5
+	}
+	if _, err := os.Stat(opts.ModelPath); err != nil {
+		return nil, fmt.Errorf("model not found: %w", err)
+	}
+
+	if err := acquireEnvironment(opts.LibraryPath); err != nil {
+		return nil, err
+	}
+
+	d, err := newDetector(opts)
+	if err != nil {
+		releaseEnvironment()
+		return nil, err
+	}
+	return d, nil
+}
+
+func newDetector(opts Options) (*Detector, error) {
+	inputs, outputs, err := ort.GetInputOutputInfo(opts.ModelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect model: %w", err)
+	}
+	if len(inputs) != 1 {
+		return nil, fmt.Errorf("expected a model with one input, got %d", len(inputs))
+	}
+	if len(outputs) == 0 || len(outputs) > 2 {
+		return nil, fmt.Errorf("expected a model with one or two outputs, got %d", len(outputs))
+	}
+
+	in := inputs[0]
+	if len(in.Dimensions) != 4 {
+		return nil, fmt.Errorf("expected input of shape [N,C,H,W], got %v", in.Dimensions)
+	}
+	d := &Detector{
+		opts:     opts,
+		inName:   in.Name,
+		channels: int(in.Dimensions[1]),
+		height:   int(in.Dimensions[2]),
+		width:    int(in.Dimensions[3]),
+	}
+	if d.channels <= 0 {
+		d.channels = 1
+	}
+	if d.channels != 1 && d.channels != 3 {
+		return nil, fmt.Errorf("unsupported channel count %d", d.channels)
+	}
+	if d.height <= 0 || d.width <= 0 {
+		return nil, fmt.Errorf("model input must have fixed height and width, got %v", in.Dimensions)
+	}
+	for _, o := range outputs {
+		d.outNames = append(d.outNames, o.Name)
+	}
+
+	sessionOpts, err := ort.NewSessionOptions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session options: %w", err)
+	}
+	defer sessionOpts.Destroy()
+
+	if opts.UseGPU {
+		if err := appendCUDA(sessionOpts, opts.DeviceID); err != nil {
+			fmt.Printf("⚠️  CUDA execution provider unavailable, using CPU: %v\n", err)
+		} else {
+			d.GPUUsed = true
+		}
+	}
+
+	session, err := ort.NewDynamicAdvancedSession(opts.ModelPath, []string{d.inName}, d.outNames, sessionOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create inference session: %w", err)
+	}
+	d.session = session
+	return d, nil
+}
+
+func appendCUDA(sessionOpts *ort.SessionOptions, deviceID int) error {
+	cudaOpts, err := ort.NewCUDAProviderOptions()
+	if err != nil {
+		return err
+	}
+	defer cudaOpts.Destroy()
+
+	if err := cudaOpts.Update(map[string]string{"device_id": strconv.Itoa(deviceID)}); err != nil {
+		return err
+	}
+	return sessionOpts.AppendExecutionProviderCUDA(cudaOpts)
+}
+
+// Detect runs the model over all cutouts in batches of Options.BatchSize,
+// stopping between batches when ctx is done
+func (d *Detector) Detect(ctx context.Context, cutouts []*Cutout) ([]Detection, error) {
+	var detections []Detection
+
+	for start := 0; start < len(cutouts); start += d.opts.BatchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := start + d.opts.BatchSize
+		if end > len(cutouts) {
+			end = len(cutouts)
+		}
+		batch, err := d.runBatch(cutouts[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("batch %d failed: %w", start/d.opts.BatchSize+1, err)
+		}
+		detections = append(detections, batch...)
+	}
+	return detections, nil
+}
+
+func (d *Detector) runBatch(cutouts []*Cutout) ([]Detection, error) {
+	defer telemetry.Track(telemetry.CategoryCompute, "onnx_inference")()
+
+	n := len(cutouts)
+	plane := d.height * d.width
+	data := make([]float32, n*d.channels*plane)
+	for i, c := range cutouts {
+		pixels := c.resize(d.width, d.height)
+		for ch := 0; ch < d.channels; ch++ {
+			copy(data[(i*d.channels+ch)*plane:], pixels)
+		}
+	}
+
+	input, err := ort.NewTensor(ort.NewShape(int64(n), int64(d.channels), int64(d.height), int64(d.width)), data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create input tensor: %w", err)
+	}
+	defer input.Destroy()
+
+	outputs := make([]ort.Value, len(d.outNames))
+	if err := d.session.Run([]ort.Value{input}, outputs); err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, o := range outputs {
+			if o != nil {
+				o.Destroy()
+			}
+		}
+	}()
+
+	tensors := make([]*ort.Tensor[float32], len(outputs))
+	for i, o := range outputs {
+		t, ok := o.(*ort.Tensor[float32])
+		if !ok {
+			return nil, fmt.Errorf("output %s is not a float32 tensor", d.outNames[i])
+		}
+		tensors[i] = t
+	}
+
+	if len(tensors) == 2 {
+		return d.parseBoxesScores(cutouts, tensors[0], tensors[1])
+	}
+	return d.parseCombined(cutouts, tensors[0])
+}
+
+// parseBoxesScores handles boxes [N,K,4] + scores [N,K]
+func (d *Detector) parseBoxesScores(cutouts []*Cutout, boxes, scores *ort.Tensor[float32]) ([]Detection, error) {
+	shape := boxes.GetShape()
+	if len(shape) != 3 || shape[2] != 4 {
+		return nil, fmt.Errorf("unexpected boxes shape %v", shape)
+	}
+	k := int(shape[1])
+	b := boxes.GetData()
+	s := scores.GetData()
+	if len(b) < len(cutouts)*k*4 {
+		return nil, fmt.Errorf("boxes shape %v does not match %d cutouts", shape, len(cutouts))
+	}
+	if len(s) < len(cutouts)*k {
+		return nil, fmt.Errorf("unexpected scores shape %v", scores.GetShape())
+	}
+
+	var out []Detection
+	for i, c := range cutouts {
+		for j := 0; j < k; j++ {
+			score := s[i*k+j]
+			if score < d.opts.ScoreThreshold {
+				continue
+			}
+			off := (i*k + j) * 4
+			out = append(out, d.toDetection(c, b[off:off+4], score, 0))
+		}
+	}
+	return out, nil
+}
+
+// parseCombined handles detections [N,K,5+] as x1, y1, x2, y2, score[, class]
+func (d *Detector) parseCombined(cutouts []*Cutout, t *ort.Tensor[float32]) ([]Detection, error) {
+	shape := t.GetShape()
+	if len(shape) != 3 || shape[2] < 5 {
+		return nil, fmt.Errorf("unexpected detections shape %v", shape)
+	}
+	k, stride := int(shape[1]), int(shape[2])
+	data := t.GetData()
+	if len(data) < len(cutouts)*k*stride {
+		return nil, fmt.Errorf("detections shape %v does not match %d cutouts", shape, len(cutouts))
+	}
+
+	var out []Detection
+	for i, c := range cutouts {
+		for j := 0; j < k; j++ {
+			row := data[(i*k+j)*stride : (i*k+j+1)*stride]
+			if row[4] < d.opts.ScoreThreshold {
+				continue
+			}
+			class := 0
+			if stride > 5 {
+				class = int(row[5])
+			}
+			out = append(out, d.toDetection(c, row[:4], row[4], class))
+		}
+	}
+	return out, nil
+}
+
+// toDetection scales a model-space box back to the original cutout size
+func (d *Detector) toDetection(c *Cutout, box []float32, score float32, class int) Detection {
+	sx := float32(c.Width) / float32(d.width)
+	sy := float32(c.Height) / float32(d.height)
+
+	normalized := box[0] <= 1 && box[1] <= 1 && box[2] <= 1 && box[3] <= 1
+	if normalized {
+		sx, sy = float32(c.Width), float32(c.Height)
+	}
+
+	det := Detection{
+		Image: filepath.Base(c.Path),
+		X1:    box[0] * sx,
+		Y1:    box[1] * sy,
+		X2:    box[2] * sx,
+		Y2:    box[3] * sy,
+		Score: score,
+		Class: class,
+	}
+	det.Sky = c.skyPosition(det.X1, det.Y1, det.X2, det.Y2)
+	return det
+}
+
+// Close releases the session and the shared runtime environment
+func (d *Detector) Close() error {
+	var err error
+	if d.session != nil {
+		err = d.session.Destroy()
+		d.session = nil
+		releaseEnvironment()
+	}
+	return err
+}
+
+func acquireEnvironment(libraryPath string) error {
+	envMu.Lock()
+	defer envMu.Unlock()
+
+	if envRefCount == 0 {
+		ort.SetSharedLibraryPath(resolveLibraryPath(libraryPath))
+		if err := ort.InitializeEnvironment(); err != nil {
+			return fmt.Errorf("failed to initialize ONNX Runtime (set --ort-lib or ONNXRUNTIME_LIB): %w", err)
+		}
+	}
+	envRefCount++
+	return nil
+}
+
+func releaseEnvironment() {
+	envMu.Lock()
+	defer envMu.Unlock()
+
+	envRefCount--
+	if envRefCount == 0 {
+		ort.DestroyEnvironment()
+	}
+}
+
+func resolveLibraryPath(libraryPath string) string {
+	if libraryPath != "" {
+		return libraryPath
+	}
+	if env := os.Getenv("ONNXRUNTIME_LIB"); env != "" {
+		return env
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		return "libonnxruntime.dylib"
+	case "windows":
+		return "onnxruntime.dll"
+	default:
+		return "libonnxruntime.so"
+	}
+}
@@ -0,0 +1,126 @@
+package inference
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SkyPosition is the equatorial position of a detection, in degrees (ICRS)
+type SkyPosition struct {
+	RA      float64   `json:"ra"`
+	Dec     float64   `json:"dec"`
+	ObsTime time.Time `json:"obs_time,omitempty"`
+}
+
+// WCS is a gnomonic (TAN) world coordinate system read from a FITS header
+type WCS struct {
+	CRVAL   [2]float64    // reference sky position, degrees
+	CRPIX   [2]float64    // reference pixel, 1-based FITS pixels
+	CD      [2][2]float64 // degrees per pixel
+	ObsTime time.Time     // DATE-OBS or MJD-OBS, zero when missing
+}
+
+// ParseWCS reads a TAN WCS from the header, nil when there is none.
+// CD, PC with CDELT and CDELT with CROTA2 are understood.
+func ParseWCS(header map[string]string) *WCS {
+	if !strings.HasPrefix(header["CTYPE1"], "RA---TAN") || !strings.HasPrefix(header["CTYPE2"], "DEC--TAN") {
+		return nil
+	}
+	num := func(key string, def float64) float64 {
+		v, err := strconv.ParseFloat(strings.Replace(header[key], "D", "E", 1), 64)
+		if err != nil {
+			return def
+		}
+		return v
+	}
+	w := &WCS{
+		CRVAL: [2]float64{num("CRVAL1", 0), num("CRVAL2", 0)},
+		CRPIX: [2]float64{num("CRPIX1", 0), num("CRPIX2", 0)},
+	}
+
+	_, hasCD := header["CD1_1"]
+	switch {
+	case hasCD:
+		w.CD = [2][2]float64{{num("CD1_1", 0), num("CD1_2", 0)}, {num("CD2_1", 0), num("CD2_2", 0)}}
+	default:
+		cdelt1, cdelt2 := num("CDELT1", 0), num("CDELT2", 0)
+		pc := [2][2]float64{{num("PC1_1", 1), num("PC1_2", 0)}, {num("PC2_1", 0), num("PC2_2", 1)}}
+		if _, hasPC := header["PC1_1"]; !hasPC {
+			rot := num("CROTA2", 0) * math.Pi / 180
+			pc = [2][2]float64{{math.Cos(rot), -math.Sin(rot)}, {math.Sin(rot), math.Cos(rot)}}
+		}
+		w.CD = [2][2]float64{{cdelt1 * pc[0][0], cdelt1 * pc[0][1]}, {cdelt2 * pc[1][0], cdelt2 * pc[1][1]}}
+	}
+	if w.CD[0][0]*w.CD[1][1]-w.CD[0][1]*w.CD[1][0] == 0 {
+		return nil
+	}
+
+	if t, err := parseDateObs(header["DATE-OBS"]); err == nil {
+		w.ObsTime = t
+	} else if mjd := num("MJD-OBS", 0); mjd > 0 {
+		w.ObsTime = time.Unix(0, 0).UTC().Add(time.Duration((mjd - 40587) * 86400 * float64(time.Second)))
+	}
+	return w
+}
+
+// parseDateObs accepts the ISO 8601 forms used in DATE-OBS
+func parseDateObs(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	var err error
+	for _, layout := range []string{"2006-01-02T15:04:05.999999999", "2006-01-02T15:04:05", "2006-01-02"} {
+		var t time.Time
+		if t, err = time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// PixelToSky converts 1-based FITS pixel coordinates to RA/Dec in degrees
+func (w *WCS) PixelToSky(px, py float64) (float64, float64) {
+	dx, dy := px-w.CRPIX[0], py-w.CRPIX[1]
+	const rad = math.Pi / 180
+	x := (w.CD[0][0]*dx + w.CD[0][1]*dy) * rad
+	y := (w.CD[1][0]*dx + w.CD[1][1]*dy) * rad
+
+	ra0, dec0 := w.CRVAL[0]*rad, w.CRVAL[1]*rad
+	denom := math.Cos(dec0) - y*math.Sin(dec0)
+	ra := ra0 + math.Atan2(x, denom)
+	dec := math.Atan2(math.Sin(dec0)+y*math.Cos(dec0), math.Hypot(x, denom))
+
+	ra = math.Mod(ra/rad, 360)
+	if ra < 0 {
+		ra += 360
+	}
+	return ra, dec / rad
+}
+
+// SkyToPixel converts RA/Dec in degrees to 1-based FITS pixel coordinates
+func (w *WCS) SkyToPixel(raDeg, decDeg float64) (float64, float64) {
+	const rad = math.Pi / 180
+	ra, dec := raDeg*rad, decDeg*rad
+	ra0, dec0 := w.CRVAL[0]*rad, w.CRVAL[1]*rad
+	cosc := math.Sin(dec0)*math.Sin(dec) + math.Cos(dec0)*math.Cos(dec)*math.Cos(ra-ra0)
+	x := math.Cos(dec) * math.Sin(ra-ra0) / cosc / rad
+	y := (math.Cos(dec0)*math.Sin(dec) - math.Sin(dec0)*math.Cos(dec)*math.Cos(ra-ra0)) / cosc / rad
+
+	det := w.CD[0][0]*w.CD[1][1] - w.CD[0][1]*w.CD[1][0]
+	dx := (w.CD[1][1]*x - w.CD[0][1]*y) / det
+	dy := (-w.CD[1][0]*x + w.CD[0][0]*y) / det
+	return dx + w.CRPIX[0], dy + w.CRPIX[1]
+}
+
+// skyPosition is the sky position of the box center, nil without a WCS.
+// Detection boxes are top-down with pixel i spanning [i, i+1], FITS pixels
+// are bottom-up with the center of the first pixel at 1.
+func (c *Cutout) skyPosition(x1, y1, x2, y2 float32) *SkyPosition {
+	if c.WCS == nil {
+		return nil
+	}
+	cx := float64(x1+x2) / 2
+	cy := float64(y1+y2) / 2
+	ra, dec := c.WCS.PixelToSky(cx+0.5, float64(c.Height)-cy+0.5)
+	return &SkyPosition{RA: ra, Dec: dec, ObsTime: c.WCS.ObsTime}
+}
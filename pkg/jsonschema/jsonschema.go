@@ -0,0 +1,350 @@
+// Package jsonschema validates JSON documents against JSON Schema (draft
+// 2020-12). It implements the keywords the client's schemas use; Compile
+// refuses any other keyword, so a schema never silently checks less than it
+// says.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Schema is a compiled JSON Schema
+type Schema struct {
+	types                []string
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *bool
+	items                *Schema
+	enum                 []interface{}
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	format               string
+	contentEncoding      string
+	minimum, maximum     *big.Float
+	minItems, maxItems   *int
+	uniqueItems          bool
+}
+
+// annotations are keywords that do not constrain a document
+var annotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true,
+	"title": true, "description": true, "examples": true, "default": true,
+	"deprecated": true, "readOnly": true, "writeOnly": true,
+}
+
+var formats = map[string]func(string) bool{
+	"date-time": func(s string) bool {
+		_, err := time.Parse(time.RFC3339Nano, s)
+		return err == nil
+	},
+	"uri": func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != "" && u.Host != ""
+	},
+}
+
+// Compile parses a schema document
+func Compile(data []byte) (*Schema, error) {
+	var raw json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return compile(raw, "#")
+}
+
+// MustCompile is Compile for schemas built into the client
+func MustCompile(data []byte) *Schema {
+	s, err := Compile(data)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func compile(raw json.RawMessage, at string) (*Schema, error) {
+	var keywords map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &keywords); err != nil {
+		return nil, fmt.Errorf("%s: schema must be an object", at)
+	}
+	s := &Schema{}
+	for key, value := range keywords {
+		var err error
+		switch key {
+		case "type":
+			var one string
+			if json.Unmarshal(value, &one) == nil {
+				s.types = []string{one}
+			} else {
+				err = json.Unmarshal(value, &s.types)
+			}
+		case "properties":
+			var props map[string]json.RawMessage
+			if err = json.Unmarshal(value, &props); err == nil {
+				s.properties = make(map[string]*Schema, len(props))
+				for name, prop := range props {
+					if s.properties[name], err = compile(prop, at+"/properties/"+name); err != nil {
+						return nil, err
+					}
+				}
+			}
+		case "required":
+			err = json.Unmarshal(value, &s.required)
+		case "additionalProperties":
+			s.additionalProperties = new(bool)
+			err = json.Unmarshal(value, s.additionalProperties)
+		case "items":
+			s.items, err = compile(value, at+"/items")
+		case "enum":
+			err = json.Unmarshal(value, &s.enum)
+		case "minLength":
+			err = json.Unmarshal(value, &s.minLength)
+		case "maxLength":
+			err = json.Unmarshal(value, &s.maxLength)
+		case "pattern":
+			var expr string
+			if err = json.Unmarshal(value, &expr); err == nil {
+				s.pattern, err = regexp.Compile(expr)
+			}
+		case "format":
+			if err = json.Unmarshal(value, &s.format); err == nil && formats[s.format] == nil {
+				err = fmt.Errorf("unsupported format %q", s.format)
+			}
+		case "contentEncoding":
+			if err = json.Unmarshal(value, &s.contentEncoding); err == nil && s.contentEncoding != "base64" {
+				err = fmt.Errorf("unsupported encoding %q", s.contentEncoding)
+			}
+		case "minimum":
+			s.minimum, err = parseNumber(value)
+		case "maximum":
+			s.maximum, err = parseNumber(value)
+		case "minItems":
+			err = json.Unmarshal(value, &s.minItems)
+		case "maxItems":
+			err = json.Unmarshal(value, &s.maxItems)
+		case "uniqueItems":
+			err = json.Unmarshal(value, &s.uniqueItems)
+		default:
+			if !annotations[key] {
+				err = fmt.Errorf("unsupported keyword")
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %v", at, key, err)
+		}
+	}
+	return s, nil
+}
+
+func parseNumber(raw json.RawMessage) (*big.Float, error) {
+	f, _, err := big.ParseFloat(strings.TrimSpace(string(raw)), 10, 128, big.ToNearestEven)
+	return f, err
+}
+
+// ValidationError lists everything a document violates, one problem per
+// JSON pointer
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+// Validate checks a JSON document
+func (s *Schema) Validate(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return &ValidationError{Problems: []string{fmt.Sprintf("not valid JSON: %v", err)}}
+	}
+	if dec.More() {
+		return &ValidationError{Problems: []string{"not valid JSON: data after the document"}}
+	}
+	var problems []string
+	s.validate(doc, "", &problems)
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// ValidateValue checks the JSON encoding of v
+func (s *Schema) ValidateValue(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.Validate(data)
+}
+
+func (s *Schema) validate(v interface{}, path string, problems *[]string) {
+	fail := func(format string, args ...interface{}) {
+		at := path
+		if at == "" {
+			at = "/"
+		}
+		*problems = append(*problems, at+": "+fmt.Sprintf(format, args...))
+	}
+
+	if len(s.types) > 0 && !hasType(v, s.types) {
+		fail("expected %s, got %s", strings.Join(s.types, " or "), typeOf(v))
+		return
+	}
+	if s.enum != nil && !inEnum(v, s.enum) {
+		fail("%s is not one of the allowed values", short(v))
+	}
+
+	switch v := v.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.minLength != nil && n < *s.minLength {
+			fail("shorter than %d characters", *s.minLength)
+		}
+		if s.maxLength != nil && n > *s.maxLength {
+			fail("longer than %d characters", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("%s does not match %s", short(v), s.pattern)
+		}
+		if s.format != "" && !formats[s.format](v) {
+			fail("%s is not a %s", short(v), s.format)
+		}
+		if s.contentEncoding == "base64" {
+			if _, err := base64.StdEncoding.DecodeString(v); err != nil {
+				fail("not base64")
+			}
+		}
+	case json.Number:
+		f, _, err := big.ParseFloat(v.String(), 10, 128, big.ToNearestEven)
+		if err != nil {
+			break
+		}
+		if s.minimum != nil && f.Cmp(s.minimum) < 0 {
+			fail("%s is below %s", v, s.minimum.Text('g', -1))
+		}
+		if s.maximum != nil && f.Cmp(s.maximum) > 0 {
+			fail("%s is above %s", v, s.maximum.Text('g', -1))
+		}
+	case []interface{}:
+		if s.minItems != nil && len(v) < *s.minItems {
+			fail("fewer than %d items", *s.minItems)
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			fail("more than %d items", *s.maxItems)
+		}
+		if s.uniqueItems {
+			for i := range v {
+				for j := 0; j < i; j++ {
+					if reflect.DeepEqual(v[i], v[j]) {
+						fail("items %d and %d are equal", j, i)
+					}
+				}
+			}
+		}
+		if s.items != nil {
+			for i, item := range v {
+				s.items.validate(item, path+"/"+strconv.Itoa(i), problems)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				fail("missing %s", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if prop, ok := s.properties[name]; ok {
+				prop.validate(v[name], path+"/"+pointerEscape(name), problems)
+			} else if s.additionalProperties != nil && !*s.additionalProperties {
+				fail("unknown property %s", name)
+			}
+		}
+	}
+}
+
+func hasType(v interface{}, types []string) bool {
+	for _, t := range types {
+		switch t {
+		case "integer":
+			if n, ok := v.(json.Number); ok {
+				if _, err := n.Int64(); err == nil {
+					return true
+				}
+				if f, err := n.Float64(); err == nil && f == float64(int64(f)) {
+					return true
+				}
+			}
+		case "number":
+			if _, ok := v.(json.Number); ok {
+				return true
+			}
+		default:
+			if typeOf(v) == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func inEnum(v interface{}, enum []interface{}) bool {
+	if n, ok := v.(json.Number); ok {
+		f, _ := n.Float64()
+		v = f
+	}
+	for _, e := range enum {
+		if reflect.DeepEqual(v, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// short quotes a value for a message, cut to keep hostile documents from
+// flooding logs
+func short(v interface{}) string {
+	s := fmt.Sprintf("%q", fmt.Sprint(v))
+	if len(s) > 40 {
+		s = s[:37] + "..."
+	}
+	return s
+}
+
+func pointerEscape(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
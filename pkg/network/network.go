@@ -0,0 +1,246 @@
+package network
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/oxygene76/medasdigital-client/pkg/denom"
+)
+
+// DefaultNetwork is used when the config does not select a network
+const DefaultNetwork = "mainnet"
+
+// Network holds the chain specific settings the client would otherwise hardcode
+type Network struct {
+	Name             string `json:"name" yaml:"name" mapstructure:"name"`
+	ChainID          string `json:"chain_id" yaml:"chain_id" mapstructure:"chain_id"`
+	RPCEndpoint      string `json:"rpc_endpoint" yaml:"rpc_endpoint" mapstructure:"rpc_endpoint"`
+	WSEndpoint       string `json:"ws_endpoint,omitempty" yaml:"ws_endpoint" mapstructure:"ws_endpoint"`
+	RESTEndpoint     string `json:"rest_endpoint,omitempty" yaml:"rest_endpoint" mapstructure:"rest_endpoint"`
+	GRPCEndpoint     string `json:"grpc_endpoint,omitempty" yaml:"grpc_endpoint" mapstructure:"grpc_endpoint"`
+	Bech32Prefix     string `json:"bech32_prefix" yaml:"bech32_prefix" mapstructure:"bech32_prefix"`
+	BaseDenom        string `json:"base_denom" yaml:"base_denom" mapstructure:"base_denom"`
+	DisplayDenom     string `json:"display_denom" yaml:"display_denom" mapstructure:"display_denom"`
+	Decimals         int    `json:"decimals" yaml:"decimals" mapstructure:"decimals"`
+	GasPrice         string `json:"gas_price" yaml:"gas_price" mapstructure:"gas_price"`
+	ContractAddress  string `json:"contract_address,omitempty" yaml:"contract_address" mapstructure:"contract_address"`
+	CommunityAddress string `json:"community_address,omitempty" yaml:"community_address" mapstructure:"community_address"`
+}
+
+// bundled contains the networks known to this binary
+var bundled = map[string]Network{
+	"mainnet": {
+		Name:             "mainnet",
+		ChainID:          "medasdigital-2",
+		RPCEndpoint:      "https://rpc.medas-digital.io:26657",
+		WSEndpoint:       "wss://rpc.medas-digital.io:26657/websocket",
+		RESTEndpoint:     "https://api.medas-digital.io:1317",
+		GRPCEndpoint:     "grpc.medas-digital.io:9090",
+		Bech32Prefix:     "medas",
+		BaseDenom:        "umedas",
+		DisplayDenom:     "MEDAS",
+		Decimals:         6,
+		GasPrice:         "0.025umedas",
+		ContractAddress:  "medas1xr3rq8yvd7qplsw5yx90ftsr2zdhg4e9z60h5duusgxpv72hud3s3cca97",
+		CommunityAddress: "medas1kc7lctfazdpd8y6ecapdfv3d6ch97prc58qaem",
+	},
+	// The testnet contract is redeployed with every testnet reset, set contract.address
+	"testnet": {
+		Name:         "testnet",
+		ChainID:      "medasdigital-testnet-1",
+		RPCEndpoint:  "https://rpc.testnet.medas-digital.io:26657",
+		WSEndpoint:   "wss://rpc.testnet.medas-digital.io:26657/websocket",
+		RESTEndpoint: "https://api.testnet.medas-digital.io:1317",
+		GRPCEndpoint: "grpc.testnet.medas-digital.io:9090",
+		Bech32Prefix: "medas",
+		BaseDenom:    "umedas",
+		DisplayDenom: "MEDAS",
+		Decimals:     6,
+		GasPrice:     "0.025umedas",
+	},
+	"local": {
+		Name:         "local",
+		ChainID:      "medasdigital-local",
+		RPCEndpoint:  "http://localhost:26657",
+		WSEndpoint:   "ws://localhost:26657/websocket",
+		RESTEndpoint: "http://localhost:1317",
+		GRPCEndpoint: "localhost:9090",
+		Bech32Prefix: "medas",
+		BaseDenom:    "umedas",
+		DisplayDenom: "MEDAS",
+		Decimals:     6,
+		GasPrice:     "0.025umedas",
+	},
+}
+
+// custom holds the profiles defined in the config, see Register
+var custom = map[string]Network{}
+
+var (
+	currentMu sync.RWMutex
+	current   = bundled[DefaultNetwork]
+)
+
+// Get returns a bundled or registered network by name
+func Get(name string) (Network, error) {
+	if name == "" {
+		name = DefaultNetwork
+	}
+	name = strings.ToLower(name)
+
+	currentMu.RLock()
+	n, ok := custom[name]
+	currentMu.RUnlock()
+	if !ok {
+		n, ok = bundled[name]
+	}
+	if !ok {
+		return Network{}, fmt.Errorf("unknown network %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return n, nil
+}
+
+// Register adds a custom profile. A profile named like a bundled network
+// overrides its fields, a new one takes the token settings of the default
+// network and needs its own chain ID and RPC endpoint.
+func Register(n Network) error {
+	name := strings.ToLower(n.Name)
+	if name == "" {
+		return fmt.Errorf("network profile needs a name")
+	}
+
+	base, ok := bundled[name]
+	if !ok {
+		if n.ChainID == "" || n.RPCEndpoint == "" {
+			return fmt.Errorf("network %q: chain_id and rpc_endpoint are required", name)
+		}
+		def := bundled[DefaultNetwork]
+		base = Network{
+			Bech32Prefix: def.Bech32Prefix,
+			BaseDenom:    def.BaseDenom,
+			DisplayDenom: def.DisplayDenom,
+			Decimals:     def.Decimals,
+			GasPrice:     def.GasPrice,
+		}
+	}
+	n.Name = name
+
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	custom[name] = base.Merge(n)
+	return nil
+}
+
+// Names lists the bundled and registered networks
+func Names() []string {
+	currentMu.RLock()
+	defer currentMu.RUnlock()
+
+	names := make([]string, 0, len(bundled)+len(custom))
+	for name := range bundled {
+		names = append(names, name)
+	}
+	for name := range custom {
+		if _, ok := bundled[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Current returns the network the client is configured for
+func Current() Network {
+	currentMu.RLock()
+	defer currentMu.RUnlock()
+	return current
+}
+
+// SetCurrent selects the network used by packages that have no config of their own
+func SetCurrent(n Network) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = n
+}
+
+// Merge returns n with every non-empty field of override applied
+func (n Network) Merge(override Network) Network {
+	baseRPC := n.RPCEndpoint
+	set := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	set(&n.Name, override.Name)
+	set(&n.ChainID, override.ChainID)
+	set(&n.RPCEndpoint, override.RPCEndpoint)
+	set(&n.WSEndpoint, override.WSEndpoint)
+	set(&n.RESTEndpoint, override.RESTEndpoint)
+	set(&n.GRPCEndpoint, override.GRPCEndpoint)
+	set(&n.Bech32Prefix, override.Bech32Prefix)
+	set(&n.BaseDenom, override.BaseDenom)
+	set(&n.DisplayDenom, override.DisplayDenom)
+	set(&n.GasPrice, override.GasPrice)
+	set(&n.ContractAddress, override.ContractAddress)
+	set(&n.CommunityAddress, override.CommunityAddress)
+	if override.Decimals > 0 {
+		n.Decimals = override.Decimals
+	}
+	// A custom RPC should not keep the bundled endpoints of another node
+	if override.RPCEndpoint != "" && override.WSEndpoint == "" {
+		n.WSEndpoint = WebsocketURL(override.RPCEndpoint)
+	}
+	if override.RPCEndpoint != "" && override.RPCEndpoint != baseRPC {
+		if override.RESTEndpoint == "" {
+			n.RESTEndpoint = ""
+		}
+		if override.GRPCEndpoint == "" {
+			n.GRPCEndpoint = ""
+		}
+	}
+	return n
+}
+
+// WebsocketURL derives the CometBFT websocket endpoint from an RPC URL
+func WebsocketURL(rpcEndpoint string) string {
+	u := strings.TrimSuffix(rpcEndpoint, "/")
+	switch {
+	case strings.HasPrefix(u, "https://"):
+		u = "wss://" + strings.TrimPrefix(u, "https://")
+	case strings.HasPrefix(u, "http://"):
+		u = "ws://" + strings.TrimPrefix(u, "http://")
+	case strings.HasPrefix(u, "tcp://"):
+		u = "ws://" + strings.TrimPrefix(u, "tcp://")
+	}
+	return u + "/websocket"
+}
+
+// ToBase converts a display amount (e.g. MEDAS) to base units (e.g. umedas)
+func (n Network) ToBase(amount float64) int64 {
+	return int64(amount * n.unit())
+}
+
+// ToDisplay converts base units to the display amount
+func (n Network) ToDisplay(amount int64) float64 {
+	return float64(amount) / n.unit()
+}
+
+// FormatAmount renders base units in the display denomination
+func (n Network) FormatAmount(amount int64) string {
+	return n.Denom().FormatInt64(amount)
+}
+
+// Denom is the display metadata of the base denom
+func (n Network) Denom() denom.Metadata {
+	return denom.Metadata{Base: n.BaseDenom, Display: n.DisplayDenom, Exponent: n.Decimals}
+}
+
+func (n Network) unit() float64 {
+	u := 1.0
+	for i := 0; i < n.Decimals; i++ {
+		u *= 10
+	}
+	return u
+}
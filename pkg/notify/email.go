@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailConfig sends notifications over SMTP, with STARTTLS if the server offers it
+type EmailConfig struct {
+	Host        string   `json:"host" yaml:"host" mapstructure:"host"`
+	Port        int      `json:"port,omitempty" yaml:"port,omitempty" mapstructure:"port"` // default 587
+	Username    string   `json:"username,omitempty" yaml:"username,omitempty" mapstructure:"username"`
+	Password    string   `json:"password,omitempty" yaml:"password,omitempty" mapstructure:"password"`
+	PasswordEnv string   `json:"password_env,omitempty" yaml:"password_env,omitempty" mapstructure:"password_env"` // read the password from this variable
+	From        string   `json:"from" yaml:"from" mapstructure:"from"`
+	To          []string `json:"to" yaml:"to" mapstructure:"to"`
+}
+
+func (c EmailConfig) validate() error {
+	if c.Host == "" {
+		return fmt.Errorf("notifications.email: host is required")
+	}
+	if c.From == "" || len(c.To) == 0 {
+		return fmt.Errorf("notifications.email: from and to are required")
+	}
+	return nil
+}
+
+type emailNotifier struct {
+	cfg EmailConfig
+}
+
+func (n *emailNotifier) Name() string { return "email" }
+
+func (n *emailNotifier) Notify(ctx context.Context, e Event) error {
+	port := n.cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(n.cfg.Host, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if n.cfg.Username != "" {
+		auth = smtp.PlainAuth("", n.cfg.Username, secret(n.cfg.Password, n.cfg.PasswordEnv), n.cfg.Host)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", title(e))
+	fmt.Fprintf(&msg, "Date: %s\r\n", e.Time.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(e.Text(), "\n", "\r\n"))
+	msg.WriteString("\r\n")
+
+	// net/smtp has no context, run it aside so a hanging server does not block the caller
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, n.cfg.From, n.cfg.To, []byte(msg.String()))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
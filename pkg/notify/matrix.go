@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MatrixConfig sends notifications as m.text messages to a Matrix room
+type MatrixConfig struct {
+	Homeserver     string `json:"homeserver" yaml:"homeserver" mapstructure:"homeserver"` // e.g. https://matrix.org
+	RoomID         string `json:"room_id" yaml:"room_id" mapstructure:"room_id"`          // !abc:matrix.org
+	AccessToken    string `json:"access_token,omitempty" yaml:"access_token,omitempty" mapstructure:"access_token"`
+	AccessTokenEnv string `json:"access_token_env,omitempty" yaml:"access_token_env,omitempty" mapstructure:"access_token_env"`
+}
+
+func (c MatrixConfig) validate() error {
+	if c.Homeserver == "" || c.RoomID == "" {
+		return fmt.Errorf("notifications.matrix: homeserver and room_id are required")
+	}
+	if c.AccessToken == "" && c.AccessTokenEnv == "" {
+		return fmt.Errorf("notifications.matrix: access_token or access_token_env is required")
+	}
+	return nil
+}
+
+type matrixNotifier struct {
+	cfg  MatrixConfig
+	http *http.Client
+}
+
+func newMatrixNotifier(cfg MatrixConfig) *matrixNotifier {
+	return &matrixNotifier{cfg: cfg, http: &http.Client{Timeout: 15 * time.Second}}
+}
+
+func (n *matrixNotifier) Name() string { return "matrix" }
+
+func (n *matrixNotifier) Notify(ctx context.Context, e Event) error {
+	body, err := json.Marshal(map[string]string{"msgtype": "m.text", "body": e.Text()})
+	if err != nil {
+		return err
+	}
+	// The transaction ID makes retries of the same request idempotent
+	txnID := strconv.FormatInt(e.Time.UnixNano(), 10)
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimRight(n.cfg.Homeserver, "/"), url.PathEscape(n.cfg.RoomID), txnID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+secret(n.cfg.AccessToken, n.cfg.AccessTokenEnv))
+	resp, err := n.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("homeserver answered %s", resp.Status)
+	}
+	return nil
+}
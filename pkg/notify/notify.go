@@ -0,0 +1,188 @@
+// Package notify alerts the user when long-running work finishes or fails,
+// by email, Slack webhook or Matrix room message.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Event levels
+const (
+	LevelSuccess = "success"
+	LevelError   = "error"
+)
+
+// Event kinds, notifications can be limited to some of them
+const (
+	KindPlanet9  = "planet9"  // Planet 9 searches and distributed collects
+	KindTraining = "training" // AI training job exports
+	KindJob      = "job"      // paid compute jobs
+	KindSchedule = "schedule" // scheduled commands
+)
+
+// Event is one notification
+type Event struct {
+	Kind     string
+	Level    string
+	Title    string
+	Message  string
+	Duration time.Duration // how long the work ran
+	Time     time.Time
+}
+
+// Text renders the event as a plain text message
+func (e Event) Text() string {
+	icon := "✅"
+	if e.Level == LevelError {
+		icon = "❌"
+	}
+	text := icon + " " + e.Title
+	if e.Message != "" {
+		text += "\n" + e.Message
+	}
+	if e.Duration > 0 {
+		text += fmt.Sprintf("\nRuntime: %v", e.Duration.Round(time.Second))
+	}
+	return text
+}
+
+// Notifier delivers events to one channel
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, e Event) error
+}
+
+// Config is the notifications section of the client config
+type Config struct {
+	// MinDuration skips work that finished faster, 0 notifies of everything
+	MinDuration time.Duration `json:"min_duration,omitempty" yaml:"min_duration,omitempty" mapstructure:"min_duration"`
+	// Events limits the notifications to these kinds, empty means all
+	Events []string      `json:"events,omitempty" yaml:"events,omitempty" mapstructure:"events"`
+	Email  *EmailConfig  `json:"email,omitempty" yaml:"email,omitempty" mapstructure:"email"`
+	Slack  *SlackConfig  `json:"slack,omitempty" yaml:"slack,omitempty" mapstructure:"slack"`
+	Matrix *MatrixConfig `json:"matrix,omitempty" yaml:"matrix,omitempty" mapstructure:"matrix"`
+}
+
+// Enabled reports whether any channel is configured
+func (c Config) Enabled() bool {
+	return c.Email != nil || c.Slack != nil || c.Matrix != nil
+}
+
+// Validate checks the configured channels
+func (c Config) Validate() error {
+	for _, kind := range c.Events {
+		switch kind {
+		case KindPlanet9, KindTraining, KindJob, KindSchedule:
+		default:
+			return fmt.Errorf("unknown notification event %q (%s, %s, %s or %s)", kind, KindPlanet9, KindTraining, KindJob, KindSchedule)
+		}
+	}
+	if c.Email != nil {
+		if err := c.Email.validate(); err != nil {
+			return err
+		}
+	}
+	if c.Slack != nil && c.Slack.WebhookURL == "" {
+		return fmt.Errorf("notifications.slack: webhook_url is required")
+	}
+	if c.Matrix != nil {
+		if err := c.Matrix.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dispatcher sends events to every configured notifier. A nil Dispatcher
+// drops all events, so callers need no checks.
+type Dispatcher struct {
+	notifiers   []Notifier
+	minDuration time.Duration
+	kinds       map[string]bool
+}
+
+// New builds the notifiers of a config, nil if none is configured
+func New(cfg Config) (*Dispatcher, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	d := &Dispatcher{minDuration: cfg.MinDuration}
+	if len(cfg.Events) > 0 {
+		d.kinds = map[string]bool{}
+		for _, kind := range cfg.Events {
+			d.kinds[kind] = true
+		}
+	}
+	if cfg.Email != nil {
+		d.notifiers = append(d.notifiers, &emailNotifier{cfg: *cfg.Email})
+	}
+	if cfg.Slack != nil {
+		d.notifiers = append(d.notifiers, newSlackNotifier(*cfg.Slack))
+	}
+	if cfg.Matrix != nil {
+		d.notifiers = append(d.notifiers, newMatrixNotifier(*cfg.Matrix))
+	}
+	return d, nil
+}
+
+// Notifiers lists the names of the configured channels
+func (d *Dispatcher) Notifiers() []string {
+	if d == nil {
+		return nil
+	}
+	names := make([]string, len(d.notifiers))
+	for i, n := range d.notifiers {
+		names[i] = n.Name()
+	}
+	return names
+}
+
+// Send delivers an event to all channels. Events of filtered kinds, or of
+// work shorter than min_duration, are dropped. Every channel is tried, the
+// errors are returned together.
+func (d *Dispatcher) Send(ctx context.Context, e Event) error {
+	if d == nil || len(d.notifiers) == 0 {
+		return nil
+	}
+	if d.kinds != nil && !d.kinds[e.Kind] {
+		return nil
+	}
+	if e.Duration < d.minDuration {
+		return nil
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	var errs []error
+	for _, n := range d.notifiers {
+		if err := n.Notify(ctx, e); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", n.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// secret returns value, or the environment variable env names
+func secret(value, env string) string {
+	if value == "" && env != "" {
+		return os.Getenv(env)
+	}
+	return value
+}
+
+// title is the first line of a message, used as email subject
+func title(e Event) string {
+	if e.Level == LevelError {
+		return "[MEDAS] Failed: " + e.Title
+	}
+	return "[MEDAS] " + strings.TrimSpace(e.Title)
+}
@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SlackConfig posts notifications to a Slack incoming webhook
+type SlackConfig struct {
+	WebhookURL string `json:"webhook_url" yaml:"webhook_url" mapstructure:"webhook_url"`
+}
+
+type slackNotifier struct {
+	url  string
+	http *http.Client
+}
+
+func newSlackNotifier(cfg SlackConfig) *slackNotifier {
+	return &slackNotifier{url: cfg.WebhookURL, http: &http.Client{Timeout: 15 * time.Second}}
+}
+
+func (n *slackNotifier) Name() string { return "slack" }
+
+func (n *slackNotifier) Notify(ctx context.Context, e Event) error {
+	body, err := json.Marshal(map[string]string{"text": e.Text()})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
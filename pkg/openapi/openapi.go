@@ -0,0 +1,343 @@
+// Package openapi builds OpenAPI 3 documents of the HTTP services. Schemas
+// are generated from the Go types the handlers encode and decode, so the
+// documents follow the JSON tags of the code.
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Version is the OpenAPI version documents are written in
+const Version = "3.0.3"
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+
+	names map[reflect.Type]string
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Server is a base URL of the API
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path by lower case method
+type PathItem map[string]*Operation
+
+// Operation is one method of a path
+type Operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path, query or header parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the body of a request by media type
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required,omitempty"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+// Response is a response by media type
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType is the schema of a content type
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Schema is the subset of JSON Schema OpenAPI 3.0 uses
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Deprecated           bool               `json:"deprecated,omitempty"`
+}
+
+// Components holds the named schemas and the security schemes
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme is how a client authenticates
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Media types of bodies
+const (
+	MediaJSON = "application/json"
+	MediaText = "text/plain"
+)
+
+// New returns a document without paths
+func New(title, version, description string) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Description: description, Version: version},
+		Paths:   map[string]PathItem{},
+		names:   map[reflect.Type]string{},
+	}
+}
+
+var pathParam = regexp.MustCompile(`\{([^}/]+)\}`)
+
+// Add documents an operation. Path parameters in braces that op does not
+// declare are added as strings.
+func (d *Document) Add(method, path string, op *Operation) {
+	declared := map[string]bool{}
+	for _, p := range op.Parameters {
+		if p.In == "path" {
+			declared[p.Name] = true
+		}
+	}
+	for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+		if !declared[m[1]] {
+			op.Parameters = append(op.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	if op.Responses == nil {
+		op.Responses = map[string]*Response{}
+	}
+	if d.Paths[path] == nil {
+		d.Paths[path] = PathItem{}
+	}
+	d.Paths[path][strings.ToLower(method)] = op
+}
+
+// BearerAuth adds a bearer token scheme and returns the security
+// requirement of operations that need it
+func (d *Document) BearerAuth(name, description string) []map[string][]string {
+	if d.Components.SecuritySchemes == nil {
+		d.Components.SecuritySchemes = map[string]*SecurityScheme{}
+	}
+	d.Components.SecuritySchemes[name] = &SecurityScheme{Type: "http", Scheme: "bearer", Description: description}
+	return []map[string][]string{{name: {}}}
+}
+
+// JSON is a response with the schema of v, no content for a nil v
+func (d *Document) JSON(description string, v interface{}) *Response {
+	r := &Response{Description: description}
+	if v != nil {
+		r.Content = map[string]MediaType{MediaJSON: {Schema: d.Schema(v)}}
+	}
+	return r
+}
+
+// Body is a required JSON request body with the schema of v
+func (d *Document) Body(v interface{}) *RequestBody {
+	return &RequestBody{Required: true, Content: map[string]MediaType{MediaJSON: {Schema: d.Schema(v)}}}
+}
+
+// Text is a plain text response, the services answer errors with one
+func Text(description string) *Response {
+	return &Response{Description: description, Content: map[string]MediaType{MediaText: {Schema: &Schema{Type: "string"}}}}
+}
+
+// Raw is a response of a media type with an opaque body
+func Raw(description, mediaType string) *Response {
+	return &Response{Description: description, Content: map[string]MediaType{mediaType: {Schema: &Schema{Type: "string", Format: "binary"}}}}
+}
+
+// Query is an optional query parameter of a JSON type (string, integer,
+// number, boolean)
+func Query(name, typ, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: typ}}
+}
+
+// Header is an optional string header
+func Header(name, description string) Parameter {
+	return Parameter{Name: name, In: "header", Description: description, Schema: &Schema{Type: "string"}}
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Schema returns the schema of v's type. Named structs are added to the
+// components and referenced, anonymous ones are inlined.
+func (d *Document) Schema(v interface{}) *Schema {
+	return d.schemaOf(reflect.TypeOf(v))
+}
+
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		// Custom encodings, like the SDK's integers as strings, are not
+		// described by their fields
+		if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+			return &Schema{Type: "string"}
+		}
+		return &Schema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		name, ok := d.names[t]
+		if !ok {
+			name = d.componentName(t)
+			d.names[t] = name
+			if d.Components.Schemas == nil {
+				d.Components.Schemas = map[string]*Schema{}
+			}
+			// Registered before the fields so recursive types end in a reference
+			s := &Schema{}
+			d.Components.Schemas[name] = s
+			*s = *d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	// Interfaces, functions and channels: any value
+	return &Schema{}
+}
+
+var nonName = regexp.MustCompile(`[^A-Za-z0-9_.]+`)
+
+// componentName is the type name, prefixed with its package when another
+// type already has it
+func (d *Document) componentName(t reflect.Type) string {
+	name := nonName.ReplaceAllString(t.Name(), "_")
+	taken := func(n string) bool {
+		_, ok := d.Components.Schemas[n]
+		return ok
+	}
+	if !taken(name) {
+		return name
+	}
+	pkg := t.PkgPath()
+	name = pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+	for base, n := name, 2; taken(name); n++ {
+		name = fmt.Sprintf("%s%d", base, n)
+	}
+	return name
+}
+
+// structSchema lists the encoded fields of a struct, embedded structs
+// without a JSON name are flattened as encoding/json does. Fields with a
+// deprecated:"<reason>" tag are marked deprecated.
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for k, v := range d.structSchema(ft).Properties {
+				if _, ok := s.Properties[k]; !ok {
+					s.Properties[k] = v
+				}
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		var fs *Schema
+		if strings.Contains(","+opts+",", ",string,") {
+			fs = &Schema{Type: "string"}
+		} else {
+			fs = d.schemaOf(f.Type)
+		}
+		if reason, ok := f.Tag.Lookup("deprecated"); ok {
+			if fs.Ref != "" {
+				// Keywords next to $ref are ignored
+				fs = &Schema{AllOf: []*Schema{fs}}
+			}
+			fs.Deprecated = true
+			fs.Description = "Deprecated: " + reason
+		}
+		s.Properties[name] = fs
+	}
+	return s
+}
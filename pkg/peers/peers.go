@@ -0,0 +1,313 @@
+// Package peers discovers other registered clients from the registration
+// memos on chain and keeps them in a local index.
+package peers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	rpcclient "github.com/cometbft/cometbft/rpc/client"
+	cmttypes "github.com/cometbft/cometbft/types"
+	"github.com/cosmos/cosmos-sdk/codec"
+
+	"github.com/oxygene76/medasdigital-client/pkg/blockchain"
+)
+
+// blockInfoBatch is the number of block headers BlockchainInfo returns at most
+const blockInfoBatch = 20
+
+// memoMarker and msgMarker are searched in the raw tx bytes before a tx is
+// decoded
+var (
+	memoMarker = []byte("MEDAS_")
+	msgMarker  = []byte(blockchain.MsgRegisterClientTypeURL)
+)
+
+// Peer is a registered client found on chain
+type Peer struct {
+	Address      string    `json:"address"`
+	ClientID     string    `json:"client_id"`
+	Type         string    `json:"type"`
+	Capabilities []string  `json:"capabilities,omitempty"`
+	Endpoints    []string  `json:"endpoints,omitempty"`
+	DisplayName  string    `json:"display_name,omitempty"`
+	ChatKey      []byte    `json:"chat_key,omitempty"`
+	TxHash       string    `json:"tx_hash"`
+	Height       int64     `json:"height"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+// HasCapability reports whether the peer announced capability
+func (p *Peer) HasCapability(capability string) bool {
+	for _, c := range p.Capabilities {
+		if strings.EqualFold(c, capability) {
+			return true
+		}
+	}
+	return false
+}
+
+// Malformed is a registration memo on chain that violates the registration
+// schemas. Its client is not listed as a peer.
+type Malformed struct {
+	Address string `json:"address"`
+	TxHash  string `json:"tx_hash"`
+	Height  int64  `json:"height"`
+	Reason  string `json:"reason"`
+}
+
+// Index is the cached result of the scans of one chain
+type Index struct {
+	ChainID    string                `json:"chain_id"`
+	LastHeight int64                 `json:"last_height"`
+	UpdatedAt  time.Time             `json:"updated_at"`
+	Peers      map[string]*Peer      `json:"peers"`
+	Malformed  map[string]*Malformed `json:"malformed,omitempty"` // by tx hash
+
+	path string
+}
+
+// IndexPath is the cache file of a chain below dir
+func IndexPath(dir, chainID string) string {
+	return filepath.Join(dir, "peers", chainID+".json")
+}
+
+// LoadIndex reads the index of chainID, a missing file gives an empty index
+func LoadIndex(dir, chainID string) (*Index, error) {
+	idx := &Index{ChainID: chainID, Peers: make(map[string]*Peer), path: IndexPath(dir, chainID)}
+	data, err := os.ReadFile(idx.path)
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read peer index: %w", err)
+	}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("failed to parse peer index %s: %w", idx.path, err)
+	}
+	if idx.Peers == nil {
+		idx.Peers = make(map[string]*Peer)
+	}
+	return idx, nil
+}
+
+// Save writes the index atomically
+func (idx *Index) Save() error {
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0755); err != nil {
+		return fmt.Errorf("failed to create peer index directory: %w", err)
+	}
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := idx.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write peer index: %w", err)
+	}
+	return os.Rename(tmp, idx.path)
+}
+
+// Reset forgets all peers so the next sync scans from the start
+func (idx *Index) Reset() {
+	idx.LastHeight = 0
+	idx.Peers = make(map[string]*Peer)
+	idx.Malformed = nil
+}
+
+// Filter returns the peers with capability (any if empty) and type (any if
+// empty), newest registration first
+func (idx *Index) Filter(capability, regType string) []*Peer {
+	var result []*Peer
+	for _, p := range idx.Peers {
+		if capability != "" && !p.HasCapability(capability) {
+			continue
+		}
+		if regType != "" && p.Type != regType {
+			continue
+		}
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Height > result[j].Height
+	})
+	return result
+}
+
+// MalformedList returns the flagged registrations, newest first
+func (idx *Index) MalformedList() []*Malformed {
+	result := make([]*Malformed, 0, len(idx.Malformed))
+	for _, m := range idx.Malformed {
+		result = append(result, m)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Height > result[j].Height
+	})
+	return result
+}
+
+// add keeps the newest registration of an address and type
+func (idx *Index) add(p *Peer) {
+	key := p.Address + "/" + p.Type
+	if old, ok := idx.Peers[key]; ok && old.Height > p.Height {
+		return
+	}
+	idx.Peers[key] = p
+}
+
+// SyncOptions limits a sync run
+type SyncOptions struct {
+	// FromHeight starts the scan here instead of after LastHeight
+	FromHeight int64
+	// MaxBlocks stops the run after this many blocks, 0 scans to the latest block
+	MaxBlocks int64
+	// Progress is called after every batch of headers
+	Progress func(height, latest int64)
+}
+
+// SyncResult summarizes a sync run
+type SyncResult struct {
+	From, To  int64
+	Latest    int64
+	Found     int
+	Malformed int // registration memos rejected by the schemas
+}
+
+// Sync scans the blocks after the last scanned height for registration memos
+func (idx *Index) Sync(ctx context.Context, client rpcclient.Client, cdc codec.Codec, opts SyncOptions) (*SyncResult, error) {
+	status, err := client.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node status: %w", err)
+	}
+	if status.NodeInfo.Network != idx.ChainID {
+		return nil, fmt.Errorf("node is on chain %s, index is for %s", status.NodeInfo.Network, idx.ChainID)
+	}
+
+	latest := status.SyncInfo.LatestBlockHeight
+	from := idx.LastHeight + 1
+	if opts.FromHeight > 0 {
+		from = opts.FromHeight
+	}
+	// Pruned nodes only have the blocks after the earliest height
+	if earliest := status.SyncInfo.EarliestBlockHeight; from < earliest {
+		from = earliest
+	}
+	to := latest
+	if opts.MaxBlocks > 0 && from+opts.MaxBlocks-1 < to {
+		to = from + opts.MaxBlocks - 1
+	}
+
+	result := &SyncResult{From: from, To: to, Latest: latest}
+	for start := from; start <= to; start += blockInfoBatch {
+		end := start + blockInfoBatch - 1
+		if end > to {
+			end = to
+		}
+
+		// Headers tell which blocks have transactions at all
+		info, err := client.BlockchainInfo(ctx, start, end)
+		if err != nil {
+			return result, fmt.Errorf("failed to get blocks %d-%d: %w", start, end, err)
+		}
+		for _, meta := range info.BlockMetas {
+			if meta.NumTxs == 0 {
+				continue
+			}
+			found, malformed, err := idx.scanBlock(ctx, client, cdc, meta.Header.Height)
+			if err != nil {
+				return result, err
+			}
+			result.Found += found
+			result.Malformed += malformed
+		}
+
+		idx.LastHeight = end
+		if opts.Progress != nil {
+			opts.Progress(end, latest)
+		}
+	}
+
+	idx.UpdatedAt = time.Now()
+	return result, nil
+}
+
+// scanBlock adds the successful registrations of one block and flags the
+// malformed ones
+func (idx *Index) scanBlock(ctx context.Context, client rpcclient.Client, cdc codec.Codec, height int64) (found, malformed int, err error) {
+	block, err := client.Block(ctx, &height)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get block %d: %w", height, err)
+	}
+
+	var results []int
+	var memos []*blockchain.RegistrationMemo
+	var from []string
+	var reasons []error
+	for i, tx := range block.Block.Txs {
+		if !bytes.Contains(tx, memoMarker) && !bytes.Contains(tx, msgMarker) {
+			continue
+		}
+		data, err := blockchain.DecodeTxData(tx, cdc)
+		if err != nil || data.FromAddress == "" {
+			continue
+		}
+		memo, err := data.Registration()
+		if err != nil && !errors.Is(err, blockchain.ErrMalformedRegistration) {
+			continue
+		}
+		results = append(results, i)
+		memos = append(memos, memo)
+		from = append(from, data.FromAddress)
+		reasons = append(reasons, err)
+	}
+	if len(results) == 0 {
+		return 0, 0, nil
+	}
+
+	// Failed transactions are in the block as well
+	blockResults, err := client.BlockResults(ctx, &height)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get results of block %d: %w", height, err)
+	}
+
+	for n, i := range results {
+		if i >= len(blockResults.TxsResults) || blockResults.TxsResults[i].Code != 0 {
+			continue
+		}
+		txHash := fmt.Sprintf("%X", cmttypes.Tx(block.Block.Txs[i]).Hash())
+		if reasons[n] != nil {
+			if idx.Malformed == nil {
+				idx.Malformed = make(map[string]*Malformed)
+			}
+			idx.Malformed[txHash] = &Malformed{Address: from[n], TxHash: txHash, Height: height, Reason: reasons[n].Error()}
+			malformed++
+			continue
+		}
+		registeredAt := time.Unix(memos[n].Timestamp, 0)
+		if memos[n].Timestamp == 0 {
+			// Registration messages carry no timestamp
+			registeredAt = block.Block.Time
+		}
+		idx.add(&Peer{
+			Address:      from[n],
+			ClientID:     blockchain.GenerateClientIDFromHash(txHash),
+			Type:         memos[n].Type,
+			Capabilities: memos[n].Capabilities,
+			Endpoints:    memos[n].Endpoints,
+			DisplayName:  memos[n].DisplayName,
+			ChatKey:      memos[n].ChatKey,
+			TxHash:       txHash,
+			Height:       height,
+			RegisteredAt: registeredAt,
+		})
+		found++
+	}
+	return found, malformed, nil
+}
@@ -0,0 +1,278 @@
+package plot
+
+import (
+	"image/color"
+	"math"
+	"strconv"
+)
+
+// Default size of a chart
+const (
+	DefaultWidth  = 800
+	DefaultHeight = 500
+)
+
+// Margins of the plot area
+const (
+	marginLeft   = 80
+	marginRight  = 30
+	marginTop    = 40
+	marginBottom = 60
+)
+
+var (
+	axisColor = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	gridColor = color.RGBA{0xe0, 0xe0, 0xe0, 0xff}
+)
+
+// Palette colors the series of a chart in order
+var Palette = []color.RGBA{
+	{0x1f, 0x77, 0xb4, 0xff},
+	{0xff, 0x7f, 0x0e, 0xff},
+	{0x2c, 0xa0, 0x2c, 0xff},
+	{0xd6, 0x27, 0x28, 0xff},
+	{0x94, 0x67, 0xbd, 0xff},
+	{0x8c, 0x56, 0x4b, 0xff},
+	{0xe3, 0x77, 0xc2, 0xff},
+	{0x7f, 0x7f, 0x7f, 0xff},
+	{0xbc, 0xbd, 0x22, 0xff},
+	{0x17, 0xbe, 0xcf, 0xff},
+}
+
+// Chart describes the axes of a figure. Labels use ASCII, the PNG font
+// has no other glyphs.
+type Chart struct {
+	Title, XLabel, YLabel string
+	Width, Height         int     // 0 = DefaultWidth, DefaultHeight
+	XMin, XMax            float64 // fixed x range if XMax > XMin, else from the data
+	YMin, YMax            float64 // fixed y range if YMax > YMin
+	InvertY               bool    // larger values down, e.g. magnitudes
+}
+
+// Series is a named set of points
+type Series struct {
+	Name string
+	X, Y []float64
+}
+
+// axis maps values to pixels over a range rounded to nice steps
+type axis struct {
+	min, max, step float64
+	invert         bool
+}
+
+func newAxis(values []float64, lo, hi float64, invert bool) axis {
+	if hi > lo {
+		return axis{min: lo, max: hi, step: niceStep((hi - lo) / 6), invert: invert}
+	}
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	if math.IsInf(lo, 1) {
+		lo, hi = 0, 1
+	}
+	if hi == lo {
+		lo, hi = lo-1, hi+1
+	}
+	step := niceStep((hi - lo) / 5)
+	return axis{min: step * math.Floor(lo/step), max: step * math.Ceil(hi/step), step: step, invert: invert}
+}
+
+// frac is the position of v along the axis, 0 at the origin
+func (a axis) frac(v float64) float64 {
+	f := (v - a.min) / (a.max - a.min)
+	if a.invert {
+		return 1 - f
+	}
+	return f
+}
+
+func (a axis) ticks() []float64 {
+	if a.step <= 0 {
+		return nil
+	}
+	var t []float64
+	for v := a.min; v <= a.max+a.step*1e-6; v += a.step {
+		t = append(t, v)
+	}
+	return t
+}
+
+// frame is a figure with axes, grid and labels drawn
+type frame struct {
+	*Figure
+	x, y axis
+}
+
+func newFrame(c Chart, x, y axis) *frame {
+	if c.Width <= 0 {
+		c.Width = DefaultWidth
+	}
+	if c.Height <= 0 {
+		c.Height = DefaultHeight
+	}
+	f := &frame{Figure: NewFigure(c.Width, c.Height), x: x, y: y}
+	bottom, right := f.Height-marginBottom, f.Width-marginRight
+
+	for _, v := range y.ticks() {
+		py := f.py(v)
+		f.line(marginLeft, py, right, py, gridColor)
+		label := formatTick(v)
+		f.text(marginLeft-8-charWidth*len(label), py+4, label, axisColor)
+	}
+	for _, v := range x.ticks() {
+		px := f.px(v)
+		f.line(px, bottom, px, bottom+5, axisColor)
+		label := formatTick(v)
+		f.text(px-charWidth*len(label)/2, bottom+20, label, axisColor)
+	}
+	f.line(marginLeft, marginTop, marginLeft, bottom, axisColor)
+	f.line(marginLeft, bottom, right, bottom, axisColor)
+
+	f.text(marginLeft, 16, c.Title, axisColor)
+	f.text(marginLeft+(right-marginLeft-charWidth*len(c.XLabel))/2, f.Height-18, c.XLabel, axisColor)
+	f.text(8, marginTop-6, c.YLabel, axisColor)
+	return f
+}
+
+func (f *frame) px(v float64) int {
+	return marginLeft + int(float64(f.Width-marginLeft-marginRight)*f.x.frac(v))
+}
+
+func (f *frame) py(v float64) int {
+	return f.Height - marginBottom - int(float64(f.Height-marginTop-marginBottom)*f.y.frac(v))
+}
+
+// Scatter plots each series as dots in its palette color, with a legend
+// when the series are named
+func Scatter(c Chart, series ...Series) *Figure {
+	var xs, ys []float64
+	for _, s := range series {
+		xs = append(xs, s.X...)
+		ys = append(ys, s.Y...)
+	}
+	f := newFrame(c, newAxis(xs, c.XMin, c.XMax, false), newAxis(ys, c.YMin, c.YMax, c.InvertY))
+	for k, s := range series {
+		col := Palette[k%len(Palette)]
+		for i := range s.X {
+			if i >= len(s.Y) || math.IsNaN(s.X[i]) || math.IsNaN(s.Y[i]) {
+				continue
+			}
+			if f.x.frac(s.X[i]) < 0 || f.x.frac(s.X[i]) > 1 || f.y.frac(s.Y[i]) < 0 || f.y.frac(s.Y[i]) > 1 {
+				continue
+			}
+			f.dot(f.px(s.X[i]), f.py(s.Y[i]), 2, col)
+		}
+	}
+
+	// Legend in the upper right corner
+	y := marginTop + 12
+	for k, s := range series {
+		if s.Name == "" || k >= 20 {
+			continue
+		}
+		x := f.Width - marginRight - 12 - charWidth*len(s.Name)
+		f.dot(x-8, y-4, 3, Palette[k%len(Palette)])
+		f.text(x, y, s.Name, axisColor)
+		y += 15
+	}
+	return f.Figure
+}
+
+// Histogram counts values in equal bins between lo and hi
+func Histogram(c Chart, values []float64, lo, hi float64, bins int) *Figure {
+	counts := make([]float64, bins)
+	for _, v := range values {
+		if v < lo || v > hi {
+			continue
+		}
+		b := int((v - lo) / (hi - lo) * float64(bins))
+		counts[min(b, bins-1)]++
+	}
+	if c.YLabel == "" {
+		c.YLabel = "count"
+	}
+	y := newAxis(append(counts, 0), 0, 0, false)
+	if y.step < 1 {
+		y.step, y.max = 1, math.Ceil(y.max)
+	}
+	f := newFrame(c, axis{min: lo, max: hi, step: niceStep((hi - lo) / 6)}, y)
+	width := (hi - lo) / float64(bins)
+	for b, n := range counts {
+		if n == 0 {
+			continue
+		}
+		x0, x1 := f.px(lo+float64(b)*width)+1, f.px(lo+float64(b+1)*width)-1
+		f.rect(x0, f.py(n), x1, f.py(0)-1, Palette[0])
+	}
+	return f.Figure
+}
+
+// Bars draws one bar per label
+func Bars(c Chart, labels []string, values []float64) *Figure {
+	y := newAxis(append(append([]float64(nil), values...), 0), c.YMin, c.YMax, false)
+	f := newFrame(c, axis{min: 0, max: float64(len(values))}, y)
+	for i, v := range values {
+		x0, x1 := f.px(float64(i))+4, f.px(float64(i+1))-4
+		f.rect(x0, f.py(v), x1, f.py(0)-1, Palette[0])
+		if i < len(labels) && len(values) <= 20 {
+			label := labels[i]
+			f.text((x0+x1)/2-charWidth*len(label)/2, f.Height-marginBottom+20, label, axisColor)
+		}
+	}
+	return f.Figure
+}
+
+// Line connects the values in order, one point per label. At most about
+// eight of the labels are written below the axis.
+func Line(c Chart, labels []string, values []float64) *Figure {
+	y := newAxis(append(append([]float64(nil), values...), 0), c.YMin, c.YMax, false)
+	x := axis{min: 0, max: float64(len(values) - 1)}
+	if len(values) < 2 {
+		x = axis{min: -1, max: 1}
+	}
+	f := newFrame(c, x, y)
+	bottom := f.Height - marginBottom
+	every := (len(values) + 7) / 8
+	for i := 0; i < len(values) && i < len(labels); i += every {
+		px := f.px(float64(i))
+		f.line(px, bottom, px, bottom+5, axisColor)
+		f.text(px-charWidth*len(labels[i])/2, bottom+20, labels[i], axisColor)
+	}
+	col := Palette[0]
+	for i := 1; i < len(values); i++ {
+		x0, y0 := f.px(float64(i-1)), f.py(values[i-1])
+		x1, y1 := f.px(float64(i)), f.py(values[i])
+		f.line(x0, y0, x1, y1, col)
+		f.line(x0, y0-1, x1, y1-1, col)
+	}
+	if len(values) == 1 {
+		f.line(f.px(0)-2, f.py(values[0]), f.px(0)+2, f.py(values[0]), col)
+	}
+	return f.Figure
+}
+
+func formatTick(v float64) string {
+	if math.Abs(v) < 1e-12 {
+		v = 0
+	}
+	return strconv.FormatFloat(v, 'g', 6, 64)
+}
+
+// niceStep rounds a grid step up to 1, 2 or 5 times a power of ten
+func niceStep(raw float64) float64 {
+	if raw <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5} {
+		if raw <= m*magnitude {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
@@ -0,0 +1,238 @@
+// Package plot draws the figures of analysis results: histograms of
+// orbital elements, phase-folded light curves and element scatter plots.
+// Figures are recorded as drawing operations and rendered as PNG or SVG.
+package plot
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Formats of a figure
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// charWidth is the advance of the 7x13 font labels are measured in
+const charWidth = 7
+
+type opKind int
+
+const (
+	opLine opKind = iota
+	opRect
+	opDot
+	opText
+)
+
+// op is one drawing operation in pixels, y down
+type op struct {
+	kind           opKind
+	x0, y0, x1, y1 int // dot: center and radius in x1
+	color          color.RGBA
+	text           string
+}
+
+// Figure is a recorded drawing of Width × Height pixels
+type Figure struct {
+	Width, Height int
+	ops           []op
+}
+
+// NewFigure returns an empty white figure
+func NewFigure(width, height int) *Figure {
+	return &Figure{Width: width, Height: height}
+}
+
+func (f *Figure) line(x0, y0, x1, y1 int, c color.RGBA) {
+	f.ops = append(f.ops, op{kind: opLine, x0: x0, y0: y0, x1: x1, y1: y1, color: c})
+}
+
+// rect fills the rectangle between the corners, inclusive
+func (f *Figure) rect(x0, y0, x1, y1 int, c color.RGBA) {
+	if x0 > x1 {
+		x0, x1 = x1, x0
+	}
+	if y0 > y1 {
+		y0, y1 = y1, y0
+	}
+	f.ops = append(f.ops, op{kind: opRect, x0: x0, y0: y0, x1: x1, y1: y1, color: c})
+}
+
+func (f *Figure) dot(x, y, r int, c color.RGBA) {
+	f.ops = append(f.ops, op{kind: opDot, x0: x, y0: y, x1: r, color: c})
+}
+
+// text writes s with its baseline at y, starting at x
+func (f *Figure) text(x, y int, s string, c color.RGBA) {
+	f.ops = append(f.ops, op{kind: opText, x0: x, y0: y, text: s, color: c})
+}
+
+// Grid lays figures out in cols columns, the cells as large as the largest
+// figure
+func Grid(cols int, figures ...*Figure) *Figure {
+	if cols < 1 {
+		cols = 1
+	}
+	w, h := 0, 0
+	for _, f := range figures {
+		w, h = max(w, f.Width), max(h, f.Height)
+	}
+	rows := (len(figures) + cols - 1) / cols
+	g := NewFigure(w*min(cols, len(figures)), h*rows)
+	for i, f := range figures {
+		dx, dy := (i%cols)*w, (i/cols)*h
+		for _, o := range f.ops {
+			o.x0 += dx
+			o.y0 += dy
+			if o.kind != opDot {
+				o.x1 += dx
+				o.y1 += dy
+			}
+			g.ops = append(g.ops, o)
+		}
+	}
+	return g
+}
+
+// Image renders the figure
+func (f *Figure) Image() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, f.Width, f.Height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	for _, o := range f.ops {
+		switch o.kind {
+		case opLine:
+			drawLine(img, o.x0, o.y0, o.x1, o.y1, o.color)
+		case opRect:
+			draw.Draw(img, image.Rect(o.x0, o.y0, o.x1+1, o.y1+1), image.NewUniform(o.color), image.Point{}, draw.Src)
+		case opDot:
+			r := o.x1
+			for dx := -r; dx <= r; dx++ {
+				for dy := -r; dy <= r; dy++ {
+					if dx*dx+dy*dy <= r*r+1 {
+						img.Set(o.x0+dx, o.y0+dy, o.color)
+					}
+				}
+			}
+		case opText:
+			d := font.Drawer{
+				Dst:  img,
+				Src:  image.NewUniform(o.color),
+				Face: basicfont.Face7x13,
+				Dot:  fixed.P(o.x0, o.y0),
+			}
+			d.DrawString(o.text)
+		}
+	}
+	return img
+}
+
+// WritePNG writes the figure as PNG
+func (f *Figure) WritePNG(w io.Writer) error {
+	return png.Encode(w, f.Image())
+}
+
+// WriteSVG writes the figure as SVG
+func (f *Figure) WriteSVG(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		f.Width, f.Height, f.Width, f.Height)
+	fmt.Fprintf(b, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+	fmt.Fprintf(b, `<g font-family="monospace" font-size="12">`+"\n")
+	for _, o := range f.ops {
+		c := svgColor(o.color)
+		switch o.kind {
+		case opLine:
+			fmt.Fprintf(b, `<line x1="%d.5" y1="%d.5" x2="%d.5" y2="%d.5" stroke="%s"/>`+"\n", o.x0, o.y0, o.x1, o.y1, c)
+		case opRect:
+			fmt.Fprintf(b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n", o.x0, o.y0, o.x1-o.x0+1, o.y1-o.y0+1, c)
+		case opDot:
+			fmt.Fprintf(b, `<circle cx="%d.5" cy="%d.5" r="%d" fill="%s"/>`+"\n", o.x0, o.y0, o.x1, c)
+		case opText:
+			fmt.Fprintf(b, `<text x="%d" y="%d" fill="%s">%s</text>`+"\n", o.x0, o.y0, c, svgEscape(o.text))
+		}
+	}
+	b.WriteString("</g>\n</svg>\n")
+	return b.Flush()
+}
+
+// Save writes the figure as PNG or, for a .svg name, as SVG
+func (f *Figure) Save(path string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if Format(path) == FormatSVG {
+		err = f.WriteSVG(out)
+	} else {
+		err = f.WritePNG(out)
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Format returns the format of a file name, PNG unless it ends in .svg
+func Format(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".svg") {
+		return FormatSVG
+	}
+	return FormatPNG
+}
+
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func svgEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// drawLine draws a one pixel line with Bresenham's algorithm
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.Color) {
+	dx, dy := absInt(x1-x0), -absInt(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		img.Set(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
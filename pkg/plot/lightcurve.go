@@ -0,0 +1,93 @@
+package plot
+
+import (
+	"fmt"
+	"math"
+)
+
+// maxFrequencies bounds the frequency grid of BestPeriod
+const maxFrequencies = 200000
+
+// Fold returns the phases in [0, 1) of times for period, phase 0 at epoch
+func Fold(times []float64, period, epoch float64) []float64 {
+	phases := make([]float64, len(times))
+	for i, t := range times {
+		p := math.Mod((t-epoch)/period, 1)
+		if p < 0 {
+			p++
+		}
+		phases[i] = p
+	}
+	return phases
+}
+
+// BestPeriod returns the period between minPeriod and maxPeriod with the
+// highest power of the Lomb-Scargle periodogram of mags over times, and
+// that power normalized to 0-1. maxPeriod 0 is half the time span.
+func BestPeriod(times, mags []float64, minPeriod, maxPeriod float64) (float64, float64, error) {
+	n := min(len(times), len(mags))
+	if n < 3 {
+		return 0, 0, fmt.Errorf("%d measurements are too few for a period search", n)
+	}
+	lo, hi := times[0], times[0]
+	mean := 0.0
+	for i := 0; i < n; i++ {
+		lo, hi = math.Min(lo, times[i]), math.Max(hi, times[i])
+		mean += mags[i]
+	}
+	mean /= float64(n)
+	variance := 0.0
+	for i := 0; i < n; i++ {
+		variance += (mags[i] - mean) * (mags[i] - mean)
+	}
+	span := hi - lo
+	if span <= 0 || variance == 0 {
+		return 0, 0, fmt.Errorf("the light curve has no time span or no variation")
+	}
+	if maxPeriod <= 0 {
+		maxPeriod = span / 2
+	}
+	if minPeriod <= 0 || minPeriod >= maxPeriod {
+		return 0, 0, fmt.Errorf("period range %g-%g days is empty", minPeriod, maxPeriod)
+	}
+
+	// Frequencies five times finer than the resolution 1/span
+	fmin, fmax := 1/maxPeriod, 1/minPeriod
+	df := 1 / (5 * span)
+	steps := int((fmax-fmin)/df) + 1
+	if steps > maxFrequencies {
+		steps = maxFrequencies
+		df = (fmax - fmin) / float64(steps-1)
+	}
+
+	best, bestPower := 0.0, -1.0
+	for k := 0; k < steps; k++ {
+		w := 2 * math.Pi * (fmin + float64(k)*df)
+		var s2, c2 float64
+		for i := 0; i < n; i++ {
+			s2 += math.Sin(2 * w * times[i])
+			c2 += math.Cos(2 * w * times[i])
+		}
+		tau := math.Atan2(s2, c2) / (2 * w)
+		var yc, ys, cc, ss float64
+		for i := 0; i < n; i++ {
+			c, s := math.Cos(w*(times[i]-tau)), math.Sin(w*(times[i]-tau))
+			y := mags[i] - mean
+			yc += y * c
+			ys += y * s
+			cc += c * c
+			ss += s * s
+		}
+		power := 0.0
+		if cc > 0 {
+			power += yc * yc / cc
+		}
+		if ss > 0 {
+			power += ys * ys / ss
+		}
+		if power > bestPower {
+			best, bestPower = 2*math.Pi/w, power
+		}
+	}
+	return best, bestPower / (2 * variance), nil
+}
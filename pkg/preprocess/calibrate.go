@@ -0,0 +1,152 @@
+package preprocess
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+)
+
+// median of the finite values, NaN when there are none. values is reordered.
+func median(values []float64) float64 {
+	n := 0
+	for _, v := range values {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			values[n] = v
+			n++
+		}
+	}
+	if n == 0 {
+		return math.NaN()
+	}
+	values = values[:n]
+	sort.Float64s(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// imageMedian is the median pixel value
+func imageMedian(img *Image) float64 {
+	return median(append([]float64(nil), img.Data...))
+}
+
+// combine median-combines frames of the same size pixel by pixel
+func combine(frames []*Image) (*Image, error) {
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no frames to combine")
+	}
+	w, h := frames[0].Width, frames[0].Height
+	for _, f := range frames[1:] {
+		if f.Width != w || f.Height != h {
+			return nil, fmt.Errorf("frame sizes differ: %dx%d and %dx%d", w, h, f.Width, f.Height)
+		}
+	}
+	out := NewImage(w, h)
+	stack := make([]float64, len(frames))
+	for i := range out.Data {
+		for k, f := range frames {
+			stack[k] = f.Data[i]
+		}
+		out.Data[i] = median(stack)
+	}
+	out.Header.Cards = append([]Card(nil), frames[0].Header.Cards...)
+	return out, nil
+}
+
+func readFrames(paths []string) ([]*Image, error) {
+	frames := make([]*Image, 0, len(paths))
+	for _, p := range paths {
+		img, err := ReadImage(p)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, img)
+	}
+	return frames, nil
+}
+
+// MasterBias is the median of the bias frames
+func MasterBias(paths []string) (*Image, error) {
+	frames, err := readFrames(paths)
+	if err != nil {
+		return nil, err
+	}
+	bias, err := combine(frames)
+	if err != nil {
+		return nil, fmt.Errorf("master bias: %w", err)
+	}
+	bias.Header.Set("IMAGETYP", "Bias Frame", "master bias")
+	bias.Header.Set("NCOMBINE", len(frames), "median of bias frames")
+	return bias, nil
+}
+
+// MasterFlat is the median of the bias-subtracted flat frames, each scaled
+// to a median of 1 before combining. bias may be nil.
+func MasterFlat(paths []string, bias *Image) (*Image, error) {
+	frames, err := readFrames(paths)
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range frames {
+		if bias != nil {
+			if err := subtract(f, bias); err != nil {
+				return nil, fmt.Errorf("flat %s: %w", filepath.Base(paths[i]), err)
+			}
+		}
+		m := imageMedian(f)
+		if !(m > 0) {
+			return nil, fmt.Errorf("flat %s has no signal (median %g)", filepath.Base(paths[i]), m)
+		}
+		for j := range f.Data {
+			f.Data[j] /= m
+		}
+	}
+	flat, err := combine(frames)
+	if err != nil {
+		return nil, fmt.Errorf("master flat: %w", err)
+	}
+	m := imageMedian(flat)
+	for i, v := range flat.Data {
+		// Dead pixels would blow up the division, they become missing pixels
+		if v /= m; v < 0.05 {
+			v = math.NaN()
+		}
+		flat.Data[i] = v
+	}
+	flat.Header.Set("IMAGETYP", "Flat Field", "master flat, median 1")
+	flat.Header.Set("NCOMBINE", len(frames), "median of flat frames")
+	return flat, nil
+}
+
+func subtract(img, bias *Image) error {
+	if img.Width != bias.Width || img.Height != bias.Height {
+		return fmt.Errorf("size %dx%d does not match bias %dx%d", img.Width, img.Height, bias.Width, bias.Height)
+	}
+	for i := range img.Data {
+		img.Data[i] -= bias.Data[i]
+	}
+	return nil
+}
+
+// Calibrate subtracts the master bias and divides by the master flat in
+// place; either may be nil
+func Calibrate(img, bias, flat *Image) error {
+	if bias != nil {
+		if err := subtract(img, bias); err != nil {
+			return err
+		}
+		img.Header.History("bias subtracted")
+	}
+	if flat != nil {
+		if img.Width != flat.Width || img.Height != flat.Height {
+			return fmt.Errorf("size %dx%d does not match flat %dx%d", img.Width, img.Height, flat.Width, flat.Height)
+		}
+		for i := range img.Data {
+			img.Data[i] /= flat.Data[i]
+		}
+		img.Header.History("flat field corrected")
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+// Package preprocess calibrates raw survey frames for object detection:
+// bias and flat field correction, plate solving with astrometry.net and
+// subtraction of a reference template.
+//
+// Images are kept at full precision in FITS order, row 0 is the bottom row
+// and pixel (x, y) of the data is FITS pixel (x+1, y+1).
+package preprocess
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/oxygene76/medasdigital-client/pkg/inference"
+)
+
+const fitsBlockSize = 2880
+
+// Card is a FITS header card, Value is the raw value text
+type Card struct {
+	Key     string
+	Value   string
+	Comment string
+}
+
+// Header is the ordered list of cards of a primary HDU
+type Header struct {
+	Cards []Card
+}
+
+// Get returns the value of key with string quotes removed
+func (h *Header) Get(key string) (string, bool) {
+	for _, c := range h.Cards {
+		if c.Key == key {
+			return unquote(c.Value), true
+		}
+	}
+	return "", false
+}
+
+// Map returns the values by key, as used by inference.ParseWCS
+func (h *Header) Map() map[string]string {
+	m := make(map[string]string, len(h.Cards))
+	for _, c := range h.Cards {
+		if c.Value != "" {
+			m[c.Key] = unquote(c.Value)
+		}
+	}
+	return m
+}
+
+// Set replaces or appends a card; strings are quoted, numbers and booleans
+// written as FITS values
+func (h *Header) Set(key string, value interface{}, comment string) {
+	var v string
+	switch x := value.(type) {
+	case string:
+		v = "'" + fmt.Sprintf("%-8s", strings.ReplaceAll(x, "'", "''")) + "'"
+	case bool:
+		v = "F"
+		if x {
+			v = "T"
+		}
+	case int:
+		v = strconv.Itoa(x)
+	case float64:
+		v = strings.ToUpper(strconv.FormatFloat(x, 'G', 15, 64))
+		if !strings.ContainsAny(v, ".E") {
+			v += ".0"
+		}
+	default:
+		v = fmt.Sprint(x)
+	}
+	for i, c := range h.Cards {
+		if c.Key == key {
+			h.Cards[i] = Card{Key: key, Value: v, Comment: comment}
+			return
+		}
+	}
+	h.Cards = append(h.Cards, Card{Key: key, Value: v, Comment: comment})
+}
+
+// Delete removes all cards for which drop returns true
+func (h *Header) Delete(drop func(key string) bool) {
+	kept := h.Cards[:0]
+	for _, c := range h.Cards {
+		if !drop(c.Key) {
+			kept = append(kept, c)
+		}
+	}
+	h.Cards = kept
+}
+
+// History appends a HISTORY card
+func (h *Header) History(text string) {
+	h.Cards = append(h.Cards, Card{Key: "HISTORY", Comment: text})
+}
+
+func unquote(v string) string {
+	if !strings.HasPrefix(v, "'") {
+		return v
+	}
+	v = strings.TrimSuffix(v[1:], "'")
+	return strings.TrimRight(strings.ReplaceAll(v, "''", "'"), " ")
+}
+
+// Image is a 2D image with its header
+type Image struct {
+	Width, Height int
+	Data          []float64 // Width*Height, NaN for missing pixels
+	Header        Header
+}
+
+// NewImage returns an empty image of the given size
+func NewImage(width, height int) *Image {
+	return &Image{Width: width, Height: height, Data: make([]float64, width*height)}
+}
+
+// At returns pixel (x, y), NaN outside the image
+func (img *Image) At(x, y int) float64 {
+	if x < 0 || y < 0 || x >= img.Width || y >= img.Height {
+		return math.NaN()
+	}
+	return img.Data[y*img.Width+x]
+}
+
+// WCS returns the TAN WCS of the header, nil when there is none
+func (img *Image) WCS() *inference.WCS {
+	return inference.ParseWCS(img.Header.Map())
+}
+
+// structural are the cards Write generates itself
+var structural = map[string]bool{
+	"SIMPLE": true, "BITPIX": true, "NAXIS": true, "NAXIS1": true, "NAXIS2": true, "NAXIS3": true,
+	"EXTEND": true, "BSCALE": true, "BZERO": true, "BLANK": true, "END": true,
+}
+
+// ReadImage reads the first plane of the primary HDU of a FITS file
+func ReadImage(path string) (*Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header, err := readHeader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FITS header of %s: %w", path, err)
+	}
+	m := header.Map()
+	bitpix, _ := strconv.Atoi(m["BITPIX"])
+	naxis, _ := strconv.Atoi(m["NAXIS"])
+	width, _ := strconv.Atoi(m["NAXIS1"])
+	height, _ := strconv.Atoi(m["NAXIS2"])
+	if naxis < 2 || width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("%s: primary HDU is not a 2D image (NAXIS=%d)", path, naxis)
+	}
+	bscale, bzero := 1.0, 0.0
+	if v, ok := m["BSCALE"]; ok {
+		bscale, _ = strconv.ParseFloat(v, 64)
+	}
+	if v, ok := m["BZERO"]; ok {
+		bzero, _ = strconv.ParseFloat(v, 64)
+	}
+	blank, hasBlank := int64(0), false
+	if v, ok := m["BLANK"]; ok {
+		blank, _ = strconv.ParseInt(v, 10, 64)
+		hasBlank = true
+	}
+
+	bytesPerPixel := int(math.Abs(float64(bitpix))) / 8
+	if bytesPerPixel == 0 {
+		return nil, fmt.Errorf("%s: unsupported BITPIX %d", path, bitpix)
+	}
+	raw := make([]byte, width*height*bytesPerPixel)
+	if _, err := io.ReadFull(f, raw); err != nil {
+		return nil, fmt.Errorf("failed to read FITS data of %s: %w", path, err)
+	}
+
+	img := NewImage(width, height)
+	for i := range img.Data {
+		b := raw[i*bytesPerPixel : (i+1)*bytesPerPixel]
+		var v float64
+		var iv int64
+		integer := true
+		switch bitpix {
+		case 8:
+			iv = int64(b[0])
+		case 16:
+			iv = int64(int16(binary.BigEndian.Uint16(b)))
+		case 32:
+			iv = int64(int32(binary.BigEndian.Uint32(b)))
+		case 64:
+			iv = int64(binary.BigEndian.Uint64(b))
+		case -32:
+			v, integer = float64(math.Float32frombits(binary.BigEndian.Uint32(b))), false
+		case -64:
+			v, integer = math.Float64frombits(binary.BigEndian.Uint64(b)), false
+		default:
+			return nil, fmt.Errorf("%s: unsupported BITPIX %d", path, bitpix)
+		}
+		if integer {
+			if hasBlank && iv == blank {
+				img.Data[i] = math.NaN()
+				continue
+			}
+			v = float64(iv)
+		}
+		img.Data[i] = bzero + bscale*v
+	}
+
+	header.Delete(func(key string) bool { return structural[key] })
+	img.Header = header
+	return img, nil
+}
+
+// readHeader parses cards until END and leaves the reader at the data
+func readHeader(r io.Reader) (Header, error) {
+	var h Header
+	block := make([]byte, fitsBlockSize)
+	for {
+		if _, err := io.ReadFull(r, block); err != nil {
+			return h, err
+		}
+		for i := 0; i < fitsBlockSize; i += 80 {
+			card := string(block[i : i+80])
+			key := strings.TrimSpace(card[:8])
+			switch {
+			case key == "END":
+				return h, nil
+			case key == "":
+				continue
+			case card[8:10] != "= ":
+				h.Cards = append(h.Cards, Card{Key: key, Comment: strings.TrimRight(card[8:], " ")})
+				continue
+			}
+			value, comment := splitValue(card[10:])
+			h.Cards = append(h.Cards, Card{Key: key, Value: value, Comment: comment})
+		}
+	}
+}
+
+// splitValue separates the value and comment of a card, keeping string
+// values quoted
+func splitValue(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	end := -1
+	if strings.HasPrefix(s, "'") {
+		for j := 1; j < len(s); j++ {
+			if s[j] == '\'' {
+				if j+1 < len(s) && s[j+1] == '\'' {
+					j++
+					continue
+				}
+				end = j + 1
+				break
+			}
+		}
+		if end < 0 {
+			return s, ""
+		}
+	} else {
+		end = strings.Index(s, "/")
+		if end < 0 {
+			return s, ""
+		}
+	}
+	value, rest := strings.TrimSpace(s[:end]), strings.TrimSpace(s[end:])
+	return value, strings.TrimSpace(strings.TrimPrefix(rest, "/"))
+}
+
+// Write stores the image as 32 bit float FITS with its header
+func (img *Image) Write(path string) error {
+	var cards []string
+	add := func(c Card) {
+		var line string
+		switch {
+		case c.Value == "":
+			line = fmt.Sprintf("%-8s%s", c.Key, c.Comment)
+		default:
+			// Fixed format: strings start in column 11, numbers end in 30
+			format := "%-8s= %20s"
+			if strings.HasPrefix(c.Value, "'") {
+				format = "%-8s= %-20s"
+			}
+			line = fmt.Sprintf(format, c.Key, c.Value)
+			if c.Comment != "" {
+				line += " / " + c.Comment
+			}
+		}
+		if len(line) > 80 {
+			line = line[:80]
+		}
+		cards = append(cards, fmt.Sprintf("%-80s", line))
+	}
+	add(Card{Key: "SIMPLE", Value: "T", Comment: "conforms to FITS standard"})
+	add(Card{Key: "BITPIX", Value: "-32", Comment: "32 bit floating point"})
+	add(Card{Key: "NAXIS", Value: "2"})
+	add(Card{Key: "NAXIS1", Value: strconv.Itoa(img.Width)})
+	add(Card{Key: "NAXIS2", Value: strconv.Itoa(img.Height)})
+	for _, c := range img.Header.Cards {
+		if !structural[c.Key] {
+			add(c)
+		}
+	}
+	cards = append(cards, fmt.Sprintf("%-80s", "END"))
+
+	header := strings.Join(cards, "")
+	if pad := len(header) % fitsBlockSize; pad != 0 {
+		header += strings.Repeat(" ", fitsBlockSize-pad)
+	}
+	data := make([]byte, len(img.Data)*4)
+	for i, v := range img.Data {
+		binary.BigEndian.PutUint32(data[i*4:], math.Float32bits(float32(v)))
+	}
+	if pad := len(data) % fitsBlockSize; pad != 0 {
+		data = append(data, make([]byte, fitsBlockSize-pad)...)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(header); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,130 @@
+package preprocess
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Solver runs an astrometry.net compatible plate solver. The command is
+// called like solve-field and must write <name>.wcs into --dir.
+type Solver struct {
+	Command   string        // default solve-field
+	ScaleLow  float64       // arcsec/pixel, 0 for no hint
+	ScaleHigh float64       // arcsec/pixel
+	Timeout   time.Duration // per image, default 2 minutes
+}
+
+// Available reports whether the solver command can be found
+func (s Solver) Available() bool {
+	_, err := exec.LookPath(s.command())
+	return err == nil
+}
+
+func (s Solver) command() string {
+	if s.Command == "" {
+		return "solve-field"
+	}
+	return s.Command
+}
+
+// isWCSKey reports whether a card belongs to the world coordinate system,
+// including the SIP distortion terms astrometry.net writes
+func isWCSKey(key string) bool {
+	for _, prefix := range []string{"CTYPE", "CRVAL", "CRPIX", "CDELT", "CROTA", "CUNIT", "CD1_", "CD2_", "PC1_", "PC2_",
+		"A_", "B_", "AP_", "BP_"} {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	switch key {
+	case "WCSAXES", "EQUINOX", "RADESYS", "LONPOLE", "LATPOLE":
+		return true
+	}
+	return false
+}
+
+// Solve plate-solves the FITS file at path and replaces the WCS of img
+// with the solution. A <name>.wcs file next to the input, from an earlier
+// or external solve, is used without running the solver.
+func (s Solver) Solve(ctx context.Context, path string, img *Image) error {
+	sidecar := strings.TrimSuffix(path, filepath.Ext(path)) + ".wcs"
+	if _, err := os.Stat(sidecar); err == nil {
+		return applyWCS(img, sidecar)
+	}
+
+	dir, err := os.MkdirTemp("", "solve-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	args := []string{"--overwrite", "--no-plots", "--dir", dir, "--new-fits", "none", "--corr", "none",
+		"--rdls", "none", "--match", "none", "--index-xyls", "none", "--solved", "none",
+		"--cpulimit", strconv.Itoa(int(timeout.Seconds()))}
+	if s.ScaleLow > 0 && s.ScaleHigh > s.ScaleLow {
+		args = append(args, "--scale-units", "arcsecperpix",
+			"--scale-low", strconv.FormatFloat(s.ScaleLow, 'f', -1, 64),
+			"--scale-high", strconv.FormatFloat(s.ScaleHigh, 'f', -1, 64))
+	}
+	// A previous WCS narrows the search
+	if w := img.WCS(); w != nil {
+		args = append(args, "--ra", strconv.FormatFloat(w.CRVAL[0], 'f', 6, 64),
+			"--dec", strconv.FormatFloat(w.CRVAL[1], 'f', 6, 64), "--radius", "2")
+	}
+	args = append(args, path)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.command(), args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("plate solving %s timed out after %s", filepath.Base(path), timeout)
+		}
+		return fmt.Errorf("%s failed: %w: %s", s.command(), err, strings.TrimSpace(stderr.String()))
+	}
+
+	solution := filepath.Join(dir, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))+".wcs")
+	if _, err := os.Stat(solution); err != nil {
+		return fmt.Errorf("no plate solution for %s", filepath.Base(path))
+	}
+	return applyWCS(img, solution)
+}
+
+// applyWCS replaces the WCS cards of img with those of a .wcs header file
+func applyWCS(img *Image, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	solution, err := readHeader(f)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	img.Header.Delete(isWCSKey)
+	for _, c := range solution.Cards {
+		if isWCSKey(c.Key) {
+			img.Header.Cards = append(img.Header.Cards, c)
+		}
+	}
+	if img.WCS() == nil {
+		return fmt.Errorf("%s has no TAN WCS", filepath.Base(path))
+	}
+	img.Header.History("astrometry from " + filepath.Base(path))
+	return nil
+}
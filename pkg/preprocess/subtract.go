@@ -0,0 +1,105 @@
+package preprocess
+
+import (
+	"fmt"
+	"math"
+)
+
+// Resample maps src onto the pixel grid of ref through both WCS with
+// bilinear interpolation; pixels outside src are NaN
+func Resample(src, ref *Image) (*Image, error) {
+	srcWCS, refWCS := src.WCS(), ref.WCS()
+	if srcWCS == nil || refWCS == nil {
+		return nil, fmt.Errorf("resampling needs a WCS in both images, plate solve them first")
+	}
+	out := NewImage(ref.Width, ref.Height)
+	for y := 0; y < ref.Height; y++ {
+		for x := 0; x < ref.Width; x++ {
+			ra, dec := refWCS.PixelToSky(float64(x+1), float64(y+1))
+			px, py := srcWCS.SkyToPixel(ra, dec)
+			out.Data[y*ref.Width+x] = bilinear(src, px-1, py-1)
+		}
+	}
+	return out, nil
+}
+
+// bilinear interpolates src at 0-based pixel coordinates
+func bilinear(src *Image, x, y float64) float64 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+	ix, iy := int(x0), int(y0)
+	if ix < 0 || iy < 0 || ix >= src.Width-1 || iy >= src.Height-1 {
+		return math.NaN()
+	}
+	return (1-fx)*(1-fy)*src.At(ix, iy) + fx*(1-fy)*src.At(ix+1, iy) +
+		(1-fx)*fy*src.At(ix, iy+1) + fx*fy*src.At(ix+1, iy+1)
+}
+
+// background is a sigma-clipped median and the robust standard deviation
+func background(data []float64) (float64, float64) {
+	values := append([]float64(nil), data...)
+	m := median(values)
+	dev := make([]float64, 0, len(data))
+	for _, v := range data {
+		if !math.IsNaN(v) {
+			dev = append(dev, math.Abs(v-m))
+		}
+	}
+	sigma := 1.4826 * median(dev)
+	if sigma > 0 {
+		// One clipping pass removes the stars from the estimate
+		clipped := values[:0]
+		for _, v := range data {
+			if !math.IsNaN(v) && math.Abs(v-m) < 3*sigma {
+				clipped = append(clipped, v)
+			}
+		}
+		m = median(clipped)
+	}
+	return m, sigma
+}
+
+// Subtract returns sci minus the template resampled to its grid. After
+// removing both backgrounds the template is scaled to the science flux by
+// least squares over the pixels clearly above the noise, so constant
+// sources cancel and new or moved ones remain. The difference keeps the
+// header and WCS of sci.
+func Subtract(sci, template *Image) (*Image, float64, error) {
+	tmpl, err := Resample(template, sci)
+	if err != nil {
+		return nil, 0, err
+	}
+	sciBg, sciSigma := background(sci.Data)
+	tmplBg, tmplSigma := background(tmpl.Data)
+
+	var st, tt float64
+	overlap := 0
+	for i, s := range sci.Data {
+		t := tmpl.Data[i]
+		if math.IsNaN(s) || math.IsNaN(t) {
+			continue
+		}
+		overlap++
+		s, t = s-sciBg, t-tmplBg
+		if s > 5*sciSigma && t > 5*tmplSigma {
+			st += s * t
+			tt += t * t
+		}
+	}
+	if overlap < len(sci.Data)/10 {
+		return nil, 0, fmt.Errorf("template covers %d of %d pixels", overlap, len(sci.Data))
+	}
+	scale := 1.0
+	if tt > 0 {
+		scale = st / tt
+	}
+
+	diff := NewImage(sci.Width, sci.Height)
+	diff.Header.Cards = append([]Card(nil), sci.Header.Cards...)
+	for i, s := range sci.Data {
+		diff.Data[i] = (s - sciBg) - scale*(tmpl.Data[i]-tmplBg)
+	}
+	diff.Header.Set("TMPLSCAL", scale, "template flux scale")
+	diff.Header.History("template subtracted")
+	return diff, scale, nil
+}
@@ -0,0 +1,168 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PoWHeader carries a solved challenge as "<challenge>:<nonce>"
+const PoWHeader = "X-PoW-Solution"
+
+// MaxPoWDifficulty keeps a challenge solvable by honest clients
+const MaxPoWDifficulty = 32
+
+// Proof of work errors, all of them reject the request
+var (
+	ErrPoWMissing = errors.New("proof of work required")
+	ErrPoWInvalid = errors.New("invalid proof of work")
+	ErrPoWExpired = errors.New("proof of work challenge expired")
+	ErrPoWReused  = errors.New("proof of work challenge already used")
+)
+
+// Challenge is a hashcash-style puzzle: find a nonce so that
+// sha256("<challenge>:<nonce>") starts with Difficulty zero bits
+type Challenge struct {
+	Challenge  string    `json:"challenge"`
+	Difficulty int       `json:"difficulty"`
+	Algorithm  string    `json:"algorithm"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// PoW issues challenges and verifies their solutions. Challenges are signed,
+// not stored: any instance with the same secret verifies them. Each is
+// accepted once, solutions are counted in the rate limit store.
+type PoW struct {
+	secret     []byte
+	difficulty int
+	ttl        time.Duration
+	spent      Store
+}
+
+// NewPoW creates challenges of difficulty bits valid for ttl. Without a
+// secret a random one is used, instances behind one load balancer need the
+// same secret and a shared (redis) store.
+func NewPoW(secret string, difficulty int, ttl time.Duration, spent Store) (*PoW, error) {
+	if difficulty < 1 || difficulty > MaxPoWDifficulty {
+		return nil, fmt.Errorf("proof of work difficulty must be between 1 and %d bits", MaxPoWDifficulty)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("proof of work challenges need a positive lifetime")
+	}
+	key := []byte(secret)
+	if secret == "" {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	return &PoW{secret: key, difficulty: difficulty, ttl: ttl, spent: spent}, nil
+}
+
+// Difficulty returns the zero bits a solution needs
+func (p *PoW) Difficulty() int {
+	return p.difficulty
+}
+
+// Issue creates a challenge
+func (p *PoW) Issue() (Challenge, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return Challenge{}, err
+	}
+	expires := time.Now().Add(p.ttl).Truncate(time.Second)
+	payload := fmt.Sprintf("%x.%d.%d", id, expires.Unix(), p.difficulty)
+	return Challenge{
+		Challenge:  payload + "." + p.sign(payload),
+		Difficulty: p.difficulty,
+		Algorithm:  "sha256",
+		ExpiresAt:  expires,
+	}, nil
+}
+
+// Verify checks a solution from PoWHeader and marks its challenge as used
+func (p *PoW) Verify(solution string) error {
+	if solution == "" {
+		return ErrPoWMissing
+	}
+	challenge, nonce, ok := strings.Cut(solution, ":")
+	parts := strings.Split(challenge, ".")
+	if !ok || nonce == "" || len(parts) != 4 {
+		return ErrPoWInvalid
+	}
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(p.sign(payload))) {
+		return ErrPoWInvalid
+	}
+	expires, err1 := strconv.ParseInt(parts[1], 10, 64)
+	difficulty, err2 := strconv.Atoi(parts[2])
+	if err1 != nil || err2 != nil {
+		return ErrPoWInvalid
+	}
+	remaining := time.Until(time.Unix(expires, 0))
+	if remaining <= 0 {
+		return ErrPoWExpired
+	}
+	// Challenges issued before a difficulty increase are still accepted
+	if leadingZeroBits(powHash(challenge, nonce)) < difficulty {
+		return ErrPoWInvalid
+	}
+
+	count, _, err := p.spent.Hit("pow:"+parts[0], remaining)
+	if err != nil {
+		return fmt.Errorf("failed to record proof of work: %w", err)
+	}
+	if count > 1 {
+		return ErrPoWReused
+	}
+	return nil
+}
+
+// sign authenticates the payload of a challenge
+func (p *PoW) sign(payload string) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Solve finds a nonce for a challenge and returns the value of PoWHeader
+func Solve(ctx context.Context, c Challenge) (string, error) {
+	if c.Difficulty > MaxPoWDifficulty {
+		return "", fmt.Errorf("proof of work difficulty %d is too high", c.Difficulty)
+	}
+	for nonce := uint64(0); ; nonce++ {
+		if nonce%(1<<16) == 0 {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+		}
+		n := strconv.FormatUint(nonce, 10)
+		if leadingZeroBits(powHash(c.Challenge, n)) >= c.Difficulty {
+			return c.Challenge + ":" + n, nil
+		}
+	}
+}
+
+func powHash(challenge, nonce string) []byte {
+	sum := sha256.Sum256([]byte(challenge + ":" + nonce))
+	return sum[:]
+}
+
+func leadingZeroBits(sum []byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
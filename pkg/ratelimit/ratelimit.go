@@ -0,0 +1,225 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// Store counts requests per key in fixed windows
+type Store interface {
+	// Hit counts one request for key and returns the count in the current
+	// window and when the window resets
+	Hit(key string, window time.Duration) (int, time.Time, error)
+	Close() error
+}
+
+// Limiter allows a fixed number of requests per key and window
+type Limiter struct {
+	store  Store
+	limit  int
+	window time.Duration
+}
+
+// Result is the outcome of a rate limit check
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// New creates a limiter on top of store
+func New(store Store, limit int, window time.Duration) *Limiter {
+	return &Limiter{store: store, limit: limit, window: window}
+}
+
+// Allow counts a request for key
+func (l *Limiter) Allow(key string) (Result, error) {
+	count, reset, err := l.store.Hit(key, l.window)
+	if err != nil {
+		return Result{Allowed: true, Limit: l.limit, Remaining: l.limit}, err
+	}
+	remaining := l.limit - count
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Result{Allowed: count <= l.limit, Limit: l.limit, Remaining: remaining, Reset: reset}, nil
+}
+
+// Close releases the store
+func (l *Limiter) Close() error {
+	return l.store.Close()
+}
+
+// Open creates the store for a kind: memory, file (needs path) or redis (needs a redis:// URL)
+func Open(kind, path, redisURL string) (Store, error) {
+	switch strings.ToLower(kind) {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "file":
+		if path == "" {
+			return nil, fmt.Errorf("file rate limit store needs a path")
+		}
+		return NewFileStore(path)
+	case "redis":
+		if redisURL == "" {
+			return nil, fmt.Errorf("redis rate limit store needs a redis:// URL")
+		}
+		return NewRedisStore(redisURL)
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q (memory, file, redis)", kind)
+	}
+}
+
+// counter is the state of one key
+type counter struct {
+	Count int       `json:"count"`
+	Reset time.Time `json:"reset"`
+}
+
+// MemoryStore keeps counters in memory, expired keys are pruned periodically
+type MemoryStore struct {
+	mu        sync.Mutex
+	counters  map[string]*counter
+	lastPrune time.Time
+	dirty     bool
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: make(map[string]*counter), lastPrune: time.Now()}
+}
+
+// Hit implements Store
+func (m *MemoryStore) Hit(key string, window time.Duration) (int, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(m.lastPrune) > time.Minute {
+		m.prune(now)
+	}
+
+	c, ok := m.counters[key]
+	if !ok || !now.Before(c.Reset) {
+		c = &counter{Reset: now.Add(window)}
+		m.counters[key] = c
+	}
+	c.Count++
+	m.dirty = true
+	return c.Count, c.Reset, nil
+}
+
+// Len returns the number of tracked keys
+func (m *MemoryStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.counters)
+}
+
+// Close implements Store
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+func (m *MemoryStore) prune(now time.Time) {
+	for key, c := range m.counters {
+		if !now.Before(c.Reset) {
+			delete(m.counters, key)
+			m.dirty = true
+		}
+	}
+	m.lastPrune = now
+}
+
+// FileStore is a MemoryStore that is saved to disk, so limits survive a restart
+type FileStore struct {
+	*MemoryStore
+	path string
+	stop chan struct{}
+	done chan struct{}
+}
+
+// fileStoreInterval is how often changed counters are written
+const fileStoreInterval = 10 * time.Second
+
+// NewFileStore loads the counters from path and saves them in the background
+func NewFileStore(path string) (*FileStore, error) {
+	f := &FileStore{
+		MemoryStore: NewMemoryStore(),
+		path:        path,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &f.counters); err != nil {
+			return nil, fmt.Errorf("failed to parse rate limits %s: %w", path, err)
+		}
+		if f.counters == nil {
+			f.counters = make(map[string]*counter)
+		}
+		f.prune(time.Now())
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to read rate limits: %w", err)
+	}
+
+	go f.run()
+	return f, nil
+}
+
+func (f *FileStore) run() {
+	defer close(f.done)
+	ticker := time.NewTicker(fileStoreInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			f.save()
+		}
+	}
+}
+
+// Close stops the background writer and saves the counters
+func (f *FileStore) Close() error {
+	close(f.stop)
+	<-f.done
+	return f.save()
+}
+
+func (f *FileStore) save() error {
+	f.mu.Lock()
+	if !f.dirty {
+		f.mu.Unlock()
+		return nil
+	}
+	f.prune(time.Now())
+	data, err := json.Marshal(f.counters)
+	f.dirty = false
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	defer telemetry.Track(telemetry.CategoryDisk, "save_rate_limits")()
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
@@ -0,0 +1,186 @@
+package ratelimit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisKeyPrefix namespaces the counters in a shared Redis
+const redisKeyPrefix = "medas:ratelimit:"
+
+// RedisStore keeps counters in Redis so several instances share the limits.
+// Redis expires the keys, no cleanup is needed.
+type RedisStore struct {
+	mu       sync.Mutex
+	addr     string
+	password string
+	db       int
+	conn     net.Conn
+	rd       *bufio.Reader
+}
+
+// NewRedisStore connects to redis://[:password@]host:port[/db]
+func NewRedisStore(rawURL string) (*RedisStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "redis" || u.Host == "" {
+		return nil, fmt.Errorf("invalid redis URL %q, expected redis://[:password@]host:port[/db]", rawURL)
+	}
+	s := &RedisStore{addr: u.Host}
+	if u.Port() == "" {
+		s.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		s.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if s.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis database %q", db)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Hit implements Store. The key is created with the window as expiry, so the
+// counter and its TTL are set in one round trip.
+func (s *RedisStore) Hit(key string, window time.Duration) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return 0, time.Time{}, err
+		}
+	}
+
+	k := redisKeyPrefix + key
+	replies, err := s.pipeline(
+		[]string{"SET", k, "0", "PX", strconv.FormatInt(window.Milliseconds(), 10), "NX"},
+		[]string{"INCR", k},
+		[]string{"PTTL", k},
+	)
+	if err != nil {
+		s.close()
+		return 0, time.Time{}, err
+	}
+	count, _ := replies[1].(int64)
+	ttl, _ := replies[2].(int64)
+	if ttl < 0 {
+		ttl = window.Milliseconds()
+	}
+	return int(count), time.Now().Add(time.Duration(ttl) * time.Millisecond), nil
+}
+
+// Close implements Store
+func (s *RedisStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.close()
+}
+
+func (s *RedisStore) connect() error {
+	conn, err := net.DialTimeout("tcp", s.addr, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to connect to redis %s: %w", s.addr, err)
+	}
+	s.conn = conn
+	s.rd = bufio.NewReader(conn)
+
+	var setup [][]string
+	if s.password != "" {
+		setup = append(setup, []string{"AUTH", s.password})
+	}
+	if s.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(s.db)})
+	}
+	if len(setup) > 0 {
+		if _, err := s.pipeline(setup...); err != nil {
+			s.close()
+			return fmt.Errorf("redis setup failed: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *RedisStore) close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// pipeline sends commands in one write and reads one reply per command
+func (s *RedisStore) pipeline(cmds ...[]string) ([]interface{}, error) {
+	s.conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	var b strings.Builder
+	for _, cmd := range cmds {
+		fmt.Fprintf(&b, "*%d\r\n", len(cmd))
+		for _, arg := range cmd {
+			fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+		}
+	}
+	if _, err := s.conn.Write([]byte(b.String())); err != nil {
+		return nil, fmt.Errorf("redis write failed: %w", err)
+	}
+
+	replies := make([]interface{}, len(cmds))
+	for i := range cmds {
+		reply, err := s.readReply()
+		if err != nil {
+			return nil, err
+		}
+		replies[i] = reply
+	}
+	return replies, nil
+}
+
+// readReply parses the RESP replies used here: status, error, integer and bulk string
+func (s *RedisStore) readReply() (interface{}, error) {
+	line, err := s.rd.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis read failed: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("redis: %s", line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(s.rd, buf); err != nil {
+			return nil, fmt.Errorf("redis read failed: %w", err)
+		}
+		return string(buf[:n]), nil
+	default:
+		return nil, fmt.Errorf("unsupported redis reply %q", line)
+	}
+}
@@ -0,0 +1,107 @@
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WriteMarkdown writes doc as Markdown. Figures are saved as PNG files in
+// figureDir and linked relative to dir, the directory of the report.
+func WriteMarkdown(doc *Document, w io.Writer, dir, figureDir string) error {
+	if err := saveFigures(doc, figureDir); err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(dir, figureDir)
+	if err != nil {
+		rel = figureDir
+	}
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "# %s\n\n", doc.Title)
+	if doc.Source != "" {
+		fmt.Fprintf(b, "Source: `%s`  \n", doc.Source)
+	}
+	fmt.Fprintf(b, "Generated: %s\n\n", doc.Generated.Format("2006-01-02 15:04:05 UTC"))
+
+	for _, s := range doc.Sections {
+		fmt.Fprintf(b, "## %s\n\n", s.Title)
+		for _, t := range s.Text {
+			fmt.Fprintf(b, "%s\n\n", t)
+		}
+		if len(s.Fields) > 0 {
+			b.WriteString("| Field | Value |\n|---|---|\n")
+			for _, f := range s.Fields {
+				fmt.Fprintf(b, "| %s | %s |\n", escapeCell(f.Name), escapeCell(f.Value))
+			}
+			b.WriteString("\n")
+		}
+		for _, f := range s.Figures {
+			fmt.Fprintf(b, "![%s](%s)\n\n*%s*\n\n", f.Caption, filepath.ToSlash(filepath.Join(rel, f.Name+".png")), f.Caption)
+		}
+		for _, t := range s.Tables {
+			writeMarkdownTable(b, t)
+		}
+	}
+	return b.Flush()
+}
+
+func writeMarkdownTable(b *bufio.Writer, t Table) {
+	if t.Caption != "" {
+		fmt.Fprintf(b, "### %s\n\n", t.Caption)
+	}
+	if len(t.Rows) < t.Total {
+		fmt.Fprintf(b, "First %d of %d rows.\n\n", len(t.Rows), t.Total)
+	}
+	cells := make([]string, len(t.Header))
+	for i, h := range t.Header {
+		cells[i] = escapeCell(h)
+	}
+	fmt.Fprintf(b, "| %s |\n|%s\n", strings.Join(cells, " | "), strings.Repeat("---|", len(t.Header)))
+	for _, row := range t.Rows {
+		for i := range cells {
+			cells[i] = ""
+			if i < len(row) {
+				cells[i] = escapeCell(row[i])
+			}
+		}
+		fmt.Fprintf(b, "| %s |\n", strings.Join(cells, " | "))
+	}
+	b.WriteString("\n")
+}
+
+func escapeCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// saveFigures writes the figures of doc as PNG files into dir
+func saveFigures(doc *Document, dir string) error {
+	var figures []Figure
+	for _, s := range doc.Sections {
+		figures = append(figures, s.Figures...)
+	}
+	if len(figures) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, f := range figures {
+		out, err := os.Create(filepath.Join(dir, f.Name+".png"))
+		if err != nil {
+			return err
+		}
+		err = png.Encode(out, f.Image)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save figure %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
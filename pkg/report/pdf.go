@@ -0,0 +1,379 @@
+package report
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strings"
+)
+
+// A4 in points with the margins of the page
+const (
+	pageWidth  = 595
+	pageHeight = 842
+	pageMargin = 50
+)
+
+// PDF fonts of the standard 14, no embedding needed
+const (
+	fontRegular = "F1"
+	fontBold    = "F2"
+	fontMono    = "F3"
+)
+
+// helveticaWidths are the Helvetica glyph widths of ASCII 32-126 in
+// thousandths of an em, from the Adobe font metrics
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// pdfReplacements spell out the characters WinAnsiEncoding lacks
+var pdfReplacements = strings.NewReplacer(
+	"σ", "sigma", "ϖ", "varpi", "Ω", "Omega", "ω", "omega", "≈", "~", "→", "->",
+	"≤", "<=", "≥", ">=", "⊕", "Earth", "…", "...", "–", "-", "—", "-",
+)
+
+// pdfDocument lays out text, tables and images top down on A4 pages
+type pdfDocument struct {
+	pages  []*bytes.Buffer
+	images []image.Image
+	y      float64 // baseline of the next line on the current page
+}
+
+// WritePDF writes doc as a PDF with the figures embedded
+func WritePDF(doc *Document, w io.Writer) error {
+	p := &pdfDocument{}
+	p.newPage()
+
+	p.text(fontBold, 18, pageMargin, pdfText(doc.Title))
+	p.y -= 8
+	if doc.Source != "" {
+		p.paragraph(fontRegular, 9, "Source: "+doc.Source)
+	}
+	p.paragraph(fontRegular, 9, "Generated: "+doc.Generated.Format("2006-01-02 15:04:05 UTC"))
+
+	for _, s := range doc.Sections {
+		p.space(40)
+		p.y -= 12
+		p.text(fontBold, 13, pageMargin, pdfText(s.Title))
+		p.y -= 4
+		for _, t := range s.Text {
+			p.paragraph(fontRegular, 10, t)
+		}
+		p.fields(s.Fields)
+		for _, f := range s.Figures {
+			p.figure(f)
+		}
+		for _, t := range s.Tables {
+			p.table(t)
+		}
+	}
+	return p.write(w)
+}
+
+func (p *pdfDocument) newPage() {
+	p.pages = append(p.pages, &bytes.Buffer{})
+	p.y = pageHeight - pageMargin
+}
+
+// space starts a new page unless height points are left on this one
+func (p *pdfDocument) space(height float64) {
+	if p.y-height < pageMargin {
+		p.newPage()
+	}
+}
+
+// text writes one line of WinAnsi text at x and moves below it
+func (p *pdfDocument) text(font string, size, x float64, s string) {
+	p.space(size * 1.3)
+	p.y -= size * 1.3
+	p.textAt(font, size, x, p.y, s)
+}
+
+func (p *pdfDocument) textAt(font string, size, x, y float64, s string) {
+	fmt.Fprintf(p.pages[len(p.pages)-1], "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfEscape(s))
+}
+
+// paragraph writes s wrapped to the page width
+func (p *pdfDocument) paragraph(font string, size float64, s string) {
+	for _, line := range wrap(s, font, size, pageWidth-2*pageMargin) {
+		p.text(font, size, pageMargin, line)
+	}
+	p.y -= size * 0.4
+}
+
+// fields writes name and value columns, wrapping long values
+func (p *pdfDocument) fields(fields []Field) {
+	const size = 10
+	nameWidth := 0.0
+	for _, f := range fields {
+		nameWidth = math.Max(nameWidth, textWidth(pdfText(f.Name), fontBold, size))
+	}
+	valueX := pageMargin + math.Min(nameWidth, 240) + 12
+	for _, f := range fields {
+		lines := wrap(f.Value, fontRegular, size, pageWidth-pageMargin-valueX)
+		for i, line := range lines {
+			p.space(size * 1.3)
+			p.y -= size * 1.3
+			if i == 0 {
+				p.textAt(fontBold, size, pageMargin, p.y, pdfText(f.Name))
+			}
+			p.textAt(fontRegular, size, valueX, p.y, line)
+		}
+	}
+	if len(fields) > 0 {
+		p.y -= 6
+	}
+}
+
+// table writes t in a monospaced font, dropping the columns that do not
+// fit the page
+func (p *pdfDocument) table(t Table) {
+	const size = 7.5
+	charWidth := 0.6 * size
+	maxChars := int((pageWidth - 2*pageMargin) / charWidth)
+
+	widths := make([]int, len(t.Header))
+	for i, h := range t.Header {
+		widths[i] = len(pdfText(h))
+		for _, row := range t.Rows {
+			if i < len(row) {
+				widths[i] = max(widths[i], len(pdfText(row[i])))
+			}
+		}
+		widths[i] = min(widths[i], 24)
+	}
+	columns, used := 0, 0
+	for columns < len(widths) && used+widths[columns]+2 <= maxChars {
+		used += widths[columns] + 2
+		columns++
+	}
+
+	p.space(60)
+	p.y -= 6
+	if t.Caption != "" {
+		p.text(fontBold, 11, pageMargin, pdfText(t.Caption))
+	}
+	var notes []string
+	if len(t.Rows) < t.Total {
+		notes = append(notes, fmt.Sprintf("First %d of %d rows.", len(t.Rows), t.Total))
+	}
+	if columns < len(t.Header) {
+		notes = append(notes, fmt.Sprintf("Columns %s omitted to fit the page.", strings.Join(t.Header[columns:], ", ")))
+	}
+	if len(notes) > 0 {
+		p.paragraph(fontRegular, 8, strings.Join(notes, " "))
+	}
+
+	line := func(cells []string) string {
+		var b strings.Builder
+		for i := 0; i < columns; i++ {
+			cell := ""
+			if i < len(cells) {
+				cell = pdfText(cells[i])
+			}
+			if len(cell) > widths[i] {
+				cell = cell[:widths[i]-1] + "~"
+			}
+			fmt.Fprintf(&b, "%-*s  ", widths[i], cell)
+		}
+		return strings.TrimRight(b.String(), " ")
+	}
+	header := line(t.Header)
+	p.text(fontMono, size, pageMargin, header)
+	p.text(fontMono, size, pageMargin, strings.Repeat("-", len(header)))
+	for _, row := range t.Rows {
+		if p.y-size*1.3 < pageMargin {
+			p.newPage()
+			p.text(fontMono, size, pageMargin, header)
+		}
+		p.text(fontMono, size, pageMargin, line(row))
+	}
+	p.y -= 8
+}
+
+// figure draws f scaled to the page width with its caption below
+func (p *pdfDocument) figure(f Figure) {
+	b := f.Image.Bounds()
+	width := float64(pageWidth - 2*pageMargin)
+	height := width * float64(b.Dy()) / float64(b.Dx())
+	p.space(height + 24)
+	p.y -= height + 6
+	p.images = append(p.images, f.Image)
+	fmt.Fprintf(p.pages[len(p.pages)-1], "q %.2f 0 0 %.2f %d %.2f cm /Im%d Do Q\n", width, height, pageMargin, p.y, len(p.images))
+	p.paragraph(fontRegular, 9, f.Caption)
+}
+
+// write serializes the pages as PDF 1.4 with a cross-reference table
+func (p *pdfDocument) write(w io.Writer) error {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string, stream []byte) int {
+		offsets = append(offsets, out.Len())
+		id := len(offsets)
+		fmt.Fprintf(&out, "%d 0 obj\n%s\n", id, body)
+		if stream != nil {
+			out.WriteString("stream\n")
+			out.Write(stream)
+			out.WriteString("\nendstream\n")
+		}
+		out.WriteString("endobj\n")
+		return id
+	}
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1 and 2 are the catalog and the page tree, which lists the
+	// pages and so is written last under its reserved id
+	object("<< /Type /Catalog /Pages 2 0 R >>", nil)
+	offsets = append(offsets, 0)
+
+	var resources strings.Builder
+	resources.WriteString("<< /Font << ")
+	for _, f := range [][2]string{{fontRegular, "Helvetica"}, {fontBold, "Helvetica-Bold"}, {fontMono, "Courier"}} {
+		id := object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", f[1]), nil)
+		fmt.Fprintf(&resources, "/%s %d 0 R ", f[0], id)
+	}
+	resources.WriteString(">> /XObject << ")
+	for i, img := range p.images {
+		data, err := deflate(rgb(img))
+		if err != nil {
+			return err
+		}
+		b := img.Bounds()
+		id := object(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>",
+			b.Dx(), b.Dy(), len(data)), data)
+		fmt.Fprintf(&resources, "/Im%d %d 0 R ", i+1, id)
+	}
+	resources.WriteString(">> >>")
+	resourcesID := object(resources.String(), nil)
+
+	var kids []string
+	for _, page := range p.pages {
+		data, err := deflate(page.Bytes())
+		if err != nil {
+			return err
+		}
+		content := object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>", len(data)), data)
+		id := object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources %d 0 R /Contents %d 0 R >>",
+			pageWidth, pageHeight, resourcesID, content), nil)
+		kids = append(kids, fmt.Sprintf("%d 0 R", id))
+	}
+	offsets[1] = out.Len()
+	fmt.Fprintf(&out, "2 0 obj\n<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), len(kids))
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// wrap breaks s into lines no wider than width points
+func wrap(s, font string, size, width float64) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(pdfText(s)) {
+		// Break words wider than a line, such as hashes
+		for len(word) > 1 && textWidth(word, font, size) > width {
+			n := len(word) - 1
+			for n > 1 && textWidth(word[:n], font, size) > width {
+				n--
+			}
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			lines = append(lines, word[:n])
+			word = word[n:]
+		}
+		next := word
+		if line != "" {
+			next = line + " " + word
+		}
+		if line != "" && textWidth(next, font, size) > width {
+			lines = append(lines, line)
+			next = word
+		}
+		line = next
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// textWidth measures s, already in WinAnsi, in points
+func textWidth(s, font string, size float64) float64 {
+	if font == fontMono {
+		return 0.6 * size * float64(len(s))
+	}
+	total := 0
+	for i := 0; i < len(s); i++ {
+		c := int(s[i])
+		if c >= 32 && c <= 126 {
+			total += helveticaWidths[c-32]
+		} else {
+			total += 556
+		}
+	}
+	if font == fontBold {
+		total = total * 21 / 20
+	}
+	return float64(total) * size / 1000
+}
+
+// pdfText maps s to WinAnsiEncoding bytes, which match Latin-1 for the
+// characters a report uses
+func pdfText(s string) string {
+	s = pdfReplacements.Replace(s)
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r < 0x80 || (r >= 0xa0 && r <= 0xff):
+			b = append(b, byte(r))
+		default:
+			b = append(b, '?')
+		}
+	}
+	return string(b)
+}
+
+func pdfEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`, "\r", "", "\n", " ").Replace(s)
+}
+
+// rgb returns the pixels of img as 8 bit RGB rows
+func rgb(img image.Image) []byte {
+	b := img.Bounds()
+	out := make([]byte, 0, 3*b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			out = append(out, byte(r>>8), byte(g>>8), byte(bl>>8))
+		}
+	}
+	return out
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	z := zlib.NewWriter(&buf)
+	if _, err := z.Write(data); err != nil {
+		return nil, err
+	}
+	if err := z.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,421 @@
+// Package report turns analysis and Planet 9 search results into Markdown
+// and PDF reports with parameter summaries, tables, figures and the
+// blockchain record that verifies the result.
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oxygene76/medasdigital-client/pkg/astronomy/planet9"
+	"github.com/oxygene76/medasdigital-client/pkg/export"
+	"github.com/oxygene76/medasdigital-client/pkg/plot"
+)
+
+// Formats of a report
+const (
+	FormatMarkdown = "md"
+	FormatPDF      = "pdf"
+)
+
+// TypePlanet9 is the type of Planet 9 search results
+const TypePlanet9 = "planet9_search"
+
+// DefaultMaxRows limits the rows of a table in the report
+const DefaultMaxRows = 50
+
+// Document is a report: sections of text, fields, tables and figures
+type Document struct {
+	Title     string
+	Type      string
+	Source    string
+	Generated time.Time
+	Sections  []*Section
+}
+
+// Section is a titled part of a report, rendered in the order text,
+// fields, figures, tables
+type Section struct {
+	Title   string
+	Text    []string
+	Fields  []Field
+	Figures []Figure
+	Tables  []Table
+}
+
+// Field is a named value of a summary
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Table holds formatted cells. Total counts the rows before MaxRows cut
+// the table.
+type Table struct {
+	Caption string
+	Header  []string
+	Rows    [][]string
+	Total   int
+}
+
+// Figure is a plot, Name is its file name without extension
+type Figure struct {
+	Name    string
+	Caption string
+	Image   image.Image
+}
+
+// Options configure Load
+type Options struct {
+	Title   string // default from the result type
+	Type    string // overrides the analysis_type of the result
+	Source  string // file name shown in the report
+	MaxRows int    // per table, 0 = DefaultMaxRows
+}
+
+// reportColumns picks the columns of wide tables worth printing
+var reportColumns = map[string][]string{
+	"objects": {"designation", "semimajor_axis", "eccentricity", "inclination", "longitude_node",
+		"argument_periapsis", "mean_anomaly", "absolute_magnitude", "diameter_km"},
+	"light_curves": {"object_id", "filter", "period", "amplitude", "classification"},
+	"clusters":     {"cluster_id", "center_ra", "center_dec", "radius", "member_count", "confidence"},
+}
+
+// verificationKeys are the attributes of the blockchain section
+var verificationKeys = []string{"tx_hash", "block_height", "client_id", "timestamp"}
+
+// Load builds the report of a result as saved by the analyze commands,
+// stored on chain or written by planet9 search
+func Load(data []byte, opts Options) (*Document, error) {
+	if opts.MaxRows <= 0 {
+		opts.MaxRows = DefaultMaxRows
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("not a JSON result: %w", err)
+	}
+
+	var doc *Document
+	_, hasParams := probe["Parameters"]
+	_, hasScore := probe["ClusteringScore"]
+	if (hasParams && hasScore) || opts.Type == TypePlanet9 {
+		var r planet9.SearchResult
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("invalid Planet 9 search result: %w", err)
+		}
+		doc = fromPlanet9(&r, opts.MaxRows)
+	} else {
+		ds, err := export.Load(data, opts.Type)
+		if err != nil {
+			return nil, err
+		}
+		doc = fromDataset(ds, opts.MaxRows)
+	}
+
+	if opts.Title != "" {
+		doc.Title = opts.Title
+	}
+	doc.Source = opts.Source
+	doc.Generated = time.Now().UTC()
+	sum := sha256.Sum256(data)
+	verification := doc.section("Blockchain Verification")
+	verification.Fields = append(verification.Fields, Field{"Result SHA-256", hex.EncodeToString(sum[:])})
+	if len(verification.Fields) == 1 {
+		verification.Text = append(verification.Text,
+			"The result carries no transaction, it was not stored on chain. 'results store' records it.")
+	}
+	return doc, nil
+}
+
+// section returns the section of title, appending it if missing
+func (d *Document) section(title string) *Section {
+	for _, s := range d.Sections {
+		if s.Title == title {
+			return s
+		}
+	}
+	s := &Section{Title: title}
+	d.Sections = append(d.Sections, s)
+	return s
+}
+
+var titles = map[string]string{
+	export.TypeOrbitalDynamics: "Orbital Dynamics Analysis",
+	export.TypePhotometric:     "Photometric Analysis",
+	export.TypeClustering:      "Clustering Analysis",
+}
+
+func fromDataset(ds *export.Dataset, maxRows int) *Document {
+	doc := &Document{Title: titles[ds.Type] + " Report", Type: ds.Type}
+	summary := doc.section("Summary")
+	verification := doc.section("Blockchain Verification")
+
+	metadata := map[string]string{}
+	for _, key := range sortedKeys(ds.Attributes) {
+		v := ds.Attributes[key]
+		switch {
+		case key == "analysis_type" || containsString(verificationKeys, key):
+		case strings.HasPrefix(key, "metadata."):
+			metadata[strings.TrimPrefix(key, "metadata.")] = v
+		default:
+			summary.Fields = append(summary.Fields, Field{label(key), formatAttribute(v)})
+		}
+	}
+	for _, key := range verificationKeys {
+		if v, ok := ds.Attributes[key]; ok {
+			verification.Fields = append(verification.Fields, Field{label(key), v})
+		}
+	}
+
+	figures := doc.section("Figures")
+	for _, f := range datasetFigures(ds) {
+		figures.Figures = append(figures.Figures, f)
+	}
+
+	if len(ds.Tables) > 0 {
+		tables := doc.section("Tables")
+		for _, t := range ds.Tables {
+			tables.Tables = append(tables.Tables, datasetTable(t, maxRows))
+		}
+	}
+	if len(metadata) > 0 {
+		meta := doc.section("Metadata")
+		for _, key := range sortedKeys(metadata) {
+			meta.Fields = append(meta.Fields, Field{key, metadata[key]})
+		}
+	}
+	doc.dropEmpty()
+	return doc
+}
+
+// dropEmpty removes sections without content
+func (d *Document) dropEmpty() {
+	kept := d.Sections[:0]
+	for _, s := range d.Sections {
+		if len(s.Text)+len(s.Fields)+len(s.Figures)+len(s.Tables) > 0 || s.Title == "Blockchain Verification" {
+			kept = append(kept, s)
+		}
+	}
+	d.Sections = kept
+}
+
+func datasetTable(t *export.Table, maxRows int) Table {
+	columns := t.Columns
+	if names, ok := reportColumns[t.Name]; ok {
+		var picked []*export.Column
+		for _, name := range names {
+			if c := t.Column(name); c != nil {
+				picked = append(picked, c)
+			}
+		}
+		if len(picked) > 0 {
+			columns = picked
+		}
+	}
+
+	table := Table{Caption: label(t.Name), Total: t.Rows()}
+	for _, c := range columns {
+		table.Header = append(table.Header, c.Name)
+	}
+	for i := 0; i < t.Rows() && i < maxRows; i++ {
+		row := make([]string, len(columns))
+		for j, c := range columns {
+			switch c.Kind {
+			case export.Float:
+				row[j] = formatValue(c.Floats[i])
+			case export.Int:
+				row[j] = strconv.FormatInt(c.Ints[i], 10)
+			default:
+				row[j] = c.Strings[i]
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+func datasetFigures(ds *export.Dataset) []Figure {
+	var figs []Figure
+	switch ds.Type {
+	case export.TypeOrbitalDynamics:
+		t := ds.Table("objects")
+		a, e := t.Floats("semimajor_axis"), t.Floats("eccentricity")
+		if len(a) > 0 && len(e) > 0 {
+			figs = append(figs, Figure{"orbits_a_e", "Semi-major axis and eccentricity of the objects",
+				plot.Scatter(plot.Chart{Title: "Eccentricity over semi-major axis", XLabel: "a (AU)", YLabel: "e"},
+					plot.Series{X: a, Y: e}).Image()})
+		}
+		node, peri := t.Floats("longitude_node"), t.Floats("argument_periapsis")
+		if len(node) > 0 && len(node) == len(peri) {
+			varpi := make([]float64, len(node))
+			for i := range node {
+				varpi[i] = math.Mod(math.Mod(node[i]+peri[i], 360)+360, 360)
+			}
+			figs = append(figs, Figure{"longitude_perihelion", "Longitudes of perihelion, clustering shows as a peak",
+				plot.Histogram(plot.Chart{Title: "Longitude of perihelion", XLabel: "varpi (deg)"}, varpi, 0, 360, 12).Image()})
+		}
+	case export.TypePhotometric:
+		t := ds.Table("light_curve_points")
+		times, mags := t.Floats("time"), t.Floats("magnitude")
+		if len(times) > 0 {
+			figs = append(figs, Figure{"light_curves", "Magnitudes of all light curves over time",
+				plot.Scatter(plot.Chart{Title: "Light curves", XLabel: "time (JD)", YLabel: "magnitude", InvertY: true},
+					plot.Series{X: times, Y: mags}).Image()})
+		}
+	case export.TypeClustering:
+		t := ds.Table("clusters")
+		ids, counts := t.Column("cluster_id"), t.Floats("member_count")
+		if ids != nil && len(counts) > 0 {
+			labels := make([]string, len(ids.Ints))
+			for i, id := range ids.Ints {
+				labels[i] = strconv.FormatInt(id, 10)
+			}
+			figs = append(figs, Figure{"cluster_sizes", "Members per cluster",
+				plot.Bars(plot.Chart{Title: "Cluster sizes", YLabel: "members"}, labels, counts).Image()})
+		}
+	}
+	return figs
+}
+
+func fromPlanet9(r *planet9.SearchResult, maxRows int) *Document {
+	doc := &Document{Title: "Planet 9 Search Report", Type: TypePlanet9}
+	summary := doc.section("Summary")
+	summary.Fields = append(summary.Fields,
+		Field{"Clustering score", formatValue(r.ClusteringScore)},
+		Field{"ETNOs analyzed", strconv.Itoa(len(r.ETNOEffects))})
+	if r.Catalog != "" {
+		summary.Fields = append(summary.Fields, Field{"ETNO catalog", r.Catalog})
+	}
+	if r.Epoch != 0 {
+		summary.Fields = append(summary.Fields, Field{"Epoch", fmt.Sprintf("JD %.1f", r.Epoch)})
+	}
+	if r.Frame != "" {
+		summary.Fields = append(summary.Fields, Field{"Frame", string(r.Frame)})
+	}
+	if r.Energy.Integrator != "" {
+		summary.Fields = append(summary.Fields,
+			Field{"Integrator", r.Energy.Integrator},
+			Field{"Energy drift", fmt.Sprintf("%.2e (max %.2e)", r.Energy.FinalDrift, r.Energy.MaxDrift)})
+	}
+	if c := r.Clones; c != nil {
+		summary.Fields = append(summary.Fields,
+			Field{"Clones", strconv.Itoa(c.Clones)},
+			Field{"Clustering score over clones", c.ClusteringScore.String()},
+			Field{"Chance probability over clones", c.ChanceProbability.String()})
+	}
+
+	p := r.Parameters
+	params := doc.section("Planet 9 Parameters")
+	params.Tables = append(params.Tables, Table{
+		Caption: "Best-fit parameters",
+		Header:  []string{"parameter", "value", "unit"},
+		Rows: [][]string{
+			{"mass", formatValue(p.Mass), "Earth masses"},
+			{"semi_major_axis", formatValue(p.SemiMajorAxis), "AU"},
+			{"eccentricity", formatValue(p.Eccentricity), ""},
+			{"inclination", formatValue(p.Inclination), "deg"},
+			{"longitude_ascending_node", formatValue(p.LongitudeAscendingNode), "deg"},
+			{"argument_perihelion", formatValue(p.ArgumentPerihelion), "deg"},
+			{"perihelion", formatValue(p.SemiMajorAxis * (1 - p.Eccentricity)), "AU"},
+		},
+		Total: 7,
+	})
+
+	doc.section("Blockchain Verification")
+
+	toDeg := 180 / math.Pi
+	varpi := func(node, peri float64) float64 {
+		return math.Mod(math.Mod((node+peri)*toDeg, 360)+360, 360)
+	}
+	effects := Table{
+		Caption: "ETNO effects",
+		Header: []string{"object", "a_initial", "e_initial", "i_initial", "varpi_initial", "varpi_final",
+			"perihelion_shift", "inclination_change", "varpi_change"},
+		Total: len(r.ETNOEffects),
+	}
+	var initial, final []float64
+	for i, e := range r.ETNOEffects {
+		in, out := e.InitialElements, e.FinalElements
+		in.EnsureRadians()
+		out.EnsureRadians()
+		w0 := varpi(in.LongitudeAscendingNode, in.ArgumentPerihelion)
+		w1 := varpi(out.LongitudeAscendingNode, out.ArgumentPerihelion)
+		initial = append(initial, w0)
+		final = append(final, w1)
+		if i < maxRows {
+			effects.Rows = append(effects.Rows, []string{e.ObjectID, formatValue(in.SemiMajorAxis), formatValue(in.Eccentricity),
+				formatValue(in.Inclination * toDeg), formatValue(w0), formatValue(w1),
+				formatValue(e.PerihelionShift), formatValue(e.InclinationChange), formatValue(e.LongPeriChange * toDeg)})
+		}
+	}
+
+	figures := doc.section("Figures")
+	if len(initial) > 0 {
+		figures.Figures = append(figures.Figures, Figure{"varpi_initial_final", "Longitude of perihelion of the ETNOs before and after the simulation",
+			plot.Scatter(plot.Chart{Title: "Longitude of perihelion", XLabel: "initial varpi (deg)", YLabel: "final varpi (deg)"},
+				plot.Series{X: initial, Y: final}).Image()})
+	}
+	if r.Clones != nil && len(r.Clones.Scores) > 1 {
+		lo, hi := r.Clones.ClusteringScore.Lower95, r.Clones.ClusteringScore.Upper95
+		for _, s := range r.Clones.Scores {
+			lo, hi = math.Min(lo, s), math.Max(hi, s)
+		}
+		if hi > lo {
+			figures.Figures = append(figures.Figures, Figure{"clone_scores", "Clustering score of the ETNO clone sets",
+				plot.Histogram(plot.Chart{Title: "Clustering score over clones", XLabel: "score"}, r.Clones.Scores, lo, hi, 20).Image()})
+		}
+	}
+	if len(effects.Rows) > 0 {
+		doc.section("Tables").Tables = append(doc.section("Tables").Tables, effects)
+	}
+	doc.dropEmpty()
+	return doc
+}
+
+// label turns an attribute key into a field name
+func label(key string) string {
+	s := strings.NewReplacer("_", " ", ".", ": ").Replace(key)
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// formatAttribute shortens the full precision numbers of attributes
+func formatAttribute(v string) string {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f == math.Trunc(f) {
+		return v
+	}
+	return formatValue(f)
+}
+
+func formatValue(f float64) string {
+	return strconv.FormatFloat(f, 'g', 6, 64)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,263 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// reloadInterval is how often the daemon looks for changed jobs
+const reloadInterval = 30 * time.Second
+
+// keepLogs is the number of run logs kept per job
+const keepLogs = 50
+
+// ExecFunc runs a client command line, writing its output to out
+type ExecFunc func(ctx context.Context, args []string, out io.Writer) error
+
+// Daemon runs the jobs of a scheduler directory when they are due. Runs
+// missed while the daemon was down are not made up; a job whose previous
+// run is still going is skipped.
+type Daemon struct {
+	Dir      string
+	Exec     ExecFunc
+	OnFinish func(*Job, Run) // notifications, may be nil
+	Logger   *log.Logger
+
+	mu      sync.Mutex
+	running map[string]bool
+	wg      sync.WaitGroup
+}
+
+// Run blocks until ctx is cancelled and waits for running jobs to finish
+func (d *Daemon) Run(ctx context.Context) error {
+	if d.Logger == nil {
+		d.Logger = log.New(os.Stderr, "scheduler: ", log.LstdFlags)
+	}
+	d.running = map[string]bool{}
+	unlock, err := d.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	defer d.wg.Wait()
+
+	var (
+		jobs    []*Job
+		next    = map[string]time.Time{}
+		modTime time.Time
+	)
+	reload := func() {
+		info, err := os.Stat(filepath.Join(d.Dir, "jobs.json"))
+		if err != nil && !os.IsNotExist(err) {
+			d.Logger.Printf("cannot read jobs: %v", err)
+			return
+		}
+		if err == nil && info.ModTime().Equal(modTime) {
+			return
+		}
+		if err == nil {
+			modTime = info.ModTime()
+		}
+		store, err := Load(d.Dir)
+		if err != nil {
+			d.Logger.Printf("%v", err)
+			return
+		}
+		jobs = store.Jobs
+		now := time.Now()
+		seen := map[string]bool{}
+		for _, j := range jobs {
+			seen[j.ID] = true
+			sched, err := j.Parse()
+			if err != nil {
+				d.Logger.Printf("job %s: %v", j.ID, err)
+				delete(next, j.ID)
+				continue
+			}
+			// Keep the next run of unchanged jobs across reloads
+			if _, ok := next[j.ID]; !ok {
+				next[j.ID] = sched.Next(now)
+				if !j.Paused {
+					d.Logger.Printf("job %s (%s) next run %s", j.ID, j.Label(), next[j.ID].Format(time.RFC3339))
+				}
+			}
+		}
+		for id := range next {
+			if !seen[id] {
+				delete(next, id)
+			}
+		}
+	}
+
+	reload()
+	d.Logger.Printf("started with %d jobs", len(jobs))
+	for {
+		now := time.Now()
+		wake := now.Add(reloadInterval)
+		for _, j := range jobs {
+			t, ok := next[j.ID]
+			if !ok || t.IsZero() {
+				continue
+			}
+			if !t.After(now) {
+				if !j.Paused {
+					d.start(ctx, j)
+				}
+				sched, _ := j.Parse()
+				next[j.ID] = sched.Next(now)
+				t = next[j.ID]
+			}
+			if !t.IsZero() && t.Before(wake) {
+				wake = t
+			}
+		}
+
+		timer := time.NewTimer(time.Until(wake))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			d.Logger.Printf("stopping, waiting for running jobs")
+			return nil
+		case <-timer.C:
+		}
+		reload()
+	}
+}
+
+// start runs the job in the background unless it is still running
+func (d *Daemon) start(ctx context.Context, j *Job) {
+	d.mu.Lock()
+	if d.running[j.ID] {
+		d.mu.Unlock()
+		r := Run{JobID: j.ID, Label: j.Label(), Started: time.Now(), Finished: time.Now(), Status: StatusSkipped,
+			Error: "previous run still going"}
+		d.Logger.Printf("job %s skipped, previous run still going", j.ID)
+		d.record(j, r)
+		return
+	}
+	d.running[j.ID] = true
+	d.mu.Unlock()
+
+	job := *j
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		defer func() {
+			d.mu.Lock()
+			delete(d.running, job.ID)
+			d.mu.Unlock()
+		}()
+		d.record(&job, d.execute(ctx, &job))
+	}()
+}
+
+// RunNow runs a job once in the foreground and records it like a
+// scheduled run
+func (d *Daemon) RunNow(ctx context.Context, j *Job) Run {
+	if d.Logger == nil {
+		d.Logger = log.New(io.Discard, "", 0)
+	}
+	r := d.execute(ctx, j)
+	d.record(j, r)
+	return r
+}
+
+// execute runs the job with its output in a log file
+func (d *Daemon) execute(ctx context.Context, j *Job) Run {
+	r := Run{JobID: j.ID, Label: j.Label(), Started: time.Now()}
+	logDir := filepath.Join(d.Dir, "logs")
+	var out io.Writer = io.Discard
+	if err := os.MkdirAll(logDir, 0755); err == nil {
+		r.Log = filepath.Join(logDir, fmt.Sprintf("%s-%s.log", j.ID, r.Started.Format("20060102-150405")))
+		if f, err := os.Create(r.Log); err == nil {
+			defer f.Close()
+			fmt.Fprintf(f, "# %s\n", strings.Join(j.Args, " "))
+			out = f
+		} else {
+			r.Log = ""
+		}
+	}
+
+	d.Logger.Printf("job %s (%s) started", j.ID, j.Label())
+	err := d.Exec(ctx, j.Args, out)
+	r.Finished = time.Now()
+	if err != nil {
+		r.Status, r.Error = StatusFailed, err.Error()
+		d.Logger.Printf("job %s failed after %s: %v", j.ID, r.Finished.Sub(r.Started).Round(time.Second), err)
+	} else {
+		r.Status = StatusOK
+		d.Logger.Printf("job %s finished in %s", j.ID, r.Finished.Sub(r.Started).Round(time.Second))
+	}
+	pruneLogs(logDir, j.ID)
+	return r
+}
+
+func (d *Daemon) record(j *Job, r Run) {
+	if err := AppendHistory(d.Dir, r); err != nil {
+		d.Logger.Printf("failed to record run of %s: %v", j.ID, err)
+	}
+	if d.OnFinish != nil {
+		d.OnFinish(j, r)
+	}
+}
+
+// pruneLogs keeps the newest keepLogs logs of a job
+func pruneLogs(dir, jobID string) {
+	logs, _ := filepath.Glob(filepath.Join(dir, jobID+"-*.log"))
+	if len(logs) <= keepLogs {
+		return
+	}
+	// Names sort by start time
+	sort.Strings(logs)
+	for _, l := range logs[:len(logs)-keepLogs] {
+		os.Remove(l)
+	}
+}
+
+// lock makes sure only one daemon runs per scheduler directory
+func (d *Daemon) lock() (func(), error) {
+	if err := os.MkdirAll(d.Dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(d.Dir, "daemon.pid")
+	if data, err := os.ReadFile(path); err == nil {
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && pid != os.Getpid() && processAlive(pid) {
+			return nil, fmt.Errorf("scheduler daemon already running (pid %d)", pid)
+		}
+	}
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		return nil, err
+	}
+	return func() { os.Remove(path) }, nil
+}
+
+// DaemonPID returns the pid of a running daemon, 0 if there is none
+func DaemonPID(dir string) int {
+	data, err := os.ReadFile(filepath.Join(dir, "daemon.pid"))
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || !processAlive(pid) {
+		return 0
+	}
+	return pid
+}
+
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return p.Signal(syscall.Signal(0)) == nil
+}
@@ -0,0 +1,218 @@
+// Package scheduler runs client commands on cron schedules from a long
+// running daemon and keeps a history of the runs.
+//
+// Jobs live in <home>/scheduler/jobs.json, so that they can be added and
+// removed while the daemon runs; run history is appended to history.jsonl
+// and the output of every run is kept in logs/.
+package scheduler
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Job is a scheduled client command
+type Job struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name,omitempty"`
+	Schedule      string    `json:"schedule"`            // cron expression
+	TimeZone      string    `json:"time_zone,omitempty"` // IANA name, local time when empty
+	Args          []string  `json:"args"`                // client command line
+	Paused        bool      `json:"paused,omitempty"`
+	NotifySuccess bool      `json:"notify_success,omitempty"` // failures are always notified
+	Created       time.Time `json:"created"`
+}
+
+// Label is the name of the job, or its command
+func (j *Job) Label() string {
+	if j.Name != "" {
+		return j.Name
+	}
+	label := ""
+	for i, a := range j.Args {
+		if i == 3 {
+			return label + " ..."
+		}
+		if i > 0 {
+			label += " "
+		}
+		label += a
+	}
+	return label
+}
+
+// Parse parses the schedule of the job in its time zone
+func (j *Job) Parse() (*Schedule, error) {
+	loc := time.Local
+	if j.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(j.TimeZone); err != nil {
+			return nil, fmt.Errorf("unknown time zone %q: %w", j.TimeZone, err)
+		}
+	}
+	return Parse(j.Schedule, loc)
+}
+
+// Run is one execution of a job
+type Run struct {
+	JobID    string    `json:"job_id"`
+	Label    string    `json:"label"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Status   string    `json:"status"` // ok, failed or skipped
+	Error    string    `json:"error,omitempty"`
+	Log      string    `json:"log,omitempty"`
+}
+
+// Run states
+const (
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped" // the previous run of the job was still going
+)
+
+// Store is the job list below a scheduler directory
+type Store struct {
+	Jobs []*Job `json:"jobs"`
+
+	dir string
+}
+
+// Dir is the scheduler directory below the client home
+func Dir(home string) string {
+	return filepath.Join(home, "scheduler")
+}
+
+// Load reads the jobs, a missing file gives an empty store
+func Load(dir string) (*Store, error) {
+	s := &Store{dir: dir}
+	data, err := os.ReadFile(filepath.Join(dir, "jobs.json"))
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jobs: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Join(dir, "jobs.json"), err)
+	}
+	return s, nil
+}
+
+// Save writes the jobs atomically
+func (s *Store) Save() error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create scheduler directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(s.dir, "jobs.json")
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("failed to write jobs: %w", err)
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// Add validates the schedule and adds the job with a new ID
+func (s *Store) Add(j *Job) error {
+	if len(j.Args) == 0 {
+		return fmt.Errorf("job has no command")
+	}
+	if _, err := j.Parse(); err != nil {
+		return err
+	}
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	j.ID = hex.EncodeToString(b)
+	if j.Created.IsZero() {
+		j.Created = time.Now()
+	}
+	s.Jobs = append(s.Jobs, j)
+	return nil
+}
+
+// Find returns the job with the ID or name
+func (s *Store) Find(key string) (*Job, bool) {
+	for _, j := range s.Jobs {
+		if j.ID == key || (j.Name != "" && j.Name == key) {
+			return j, true
+		}
+	}
+	return nil, false
+}
+
+// Remove deletes the job with the ID or name
+func (s *Store) Remove(key string) (*Job, bool) {
+	for i, j := range s.Jobs {
+		if j.ID == key || (j.Name != "" && j.Name == key) {
+			s.Jobs = append(s.Jobs[:i], s.Jobs[i+1:]...)
+			return j, true
+		}
+	}
+	return nil, false
+}
+
+// AppendHistory records a run
+func AppendHistory(dir string, r Run) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "history.jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// History returns the runs of jobID (all jobs if empty), newest first, at
+// most limit (all if 0)
+func History(dir, jobID string, limit int) ([]Run, error) {
+	f, err := os.Open(filepath.Join(dir, "history.jsonl"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var runs []Run
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var r Run
+		if json.Unmarshal(sc.Bytes(), &r) != nil {
+			continue
+		}
+		if jobID == "" || r.JobID == jobID {
+			runs = append(runs, r)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(runs, func(a, b int) bool { return runs[a].Started.After(runs[b].Started) })
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
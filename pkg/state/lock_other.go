@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package state
+
+import "os"
+
+// Without file locks only the atomic writes protect the state
+func lock(f *os.File, wait bool) error { return nil }
+
+func unlock(f *os.File) error { return nil }
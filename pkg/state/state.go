@@ -0,0 +1,203 @@
+// Package state manages the home directory of the client. Several client
+// invocations may share it at the same time, a provider node next to a
+// register or chat command for example, so every read-modify-write of a
+// shared file runs under an advisory file lock and every file is replaced
+// atomically: a crash leaves the old or the new version, never half of one.
+//
+// Layout below the home directory (--home, default ~/.medasdigital-client):
+//
+//	config.yaml                      configuration
+//	keyring/                         account keys
+//	registrations/index.json         all local registrations
+//	registrations/registration-<client id>.json
+//	chat/keys/<address>.json         X25519 chat keys
+//	provider/jobs/<contract>/<job id>.json
+//	                                 contract jobs of the provider node
+//	index/<chain id>.db              SQLite transaction index
+//	locks/<name>.lock                lock files, safe to delete when no
+//	                                 client runs
+//
+// The SQLite index does its own locking and is not covered by the locks.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Directories and files below the home directory
+const (
+	ConfigFile        = "config.yaml"
+	KeyringDir        = "keyring"
+	RegistrationsDir  = "registrations"
+	RegistrationIndex = "registrations/index.json"
+	ChatKeysDir       = "chat/keys"
+	ProviderJobsDir   = "provider/jobs"
+	IndexDir          = "index"
+	LocksDir          = "locks"
+)
+
+// Names of the locks in LocksDir
+const (
+	LockRegistrations = "registrations"
+	LockChatKeys      = "chat-keys"
+)
+
+// ErrLocked is returned by TryLock when another process holds the lock
+var ErrLocked = errors.New("locked by another process")
+
+// Home is a client home directory
+type Home struct {
+	root string
+}
+
+// New returns the home directory at root
+func New(root string) *Home {
+	return &Home{root: root}
+}
+
+var (
+	currentMu sync.RWMutex
+	current   = New(DefaultRoot())
+)
+
+// DefaultRoot is ~/.medasdigital-client
+func DefaultRoot() string {
+	return filepath.Join(os.Getenv("HOME"), ".medasdigital-client")
+}
+
+// Current returns the home directory used by packages that have no config of their own
+func Current() *Home {
+	currentMu.RLock()
+	defer currentMu.RUnlock()
+	return current
+}
+
+// SetCurrent selects the home directory returned by Current
+func SetCurrent(root string) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = New(root)
+}
+
+// Root is the home directory itself
+func (h *Home) Root() string {
+	return h.root
+}
+
+// Path joins a layout entry and further elements to the home directory
+func (h *Home) Path(elem ...string) string {
+	return filepath.Join(append([]string{h.root}, elem...)...)
+}
+
+// Lock waits for the exclusive lock name in LocksDir
+func (h *Home) Lock(name string) (*Lock, error) {
+	return LockFile(h.Path(LocksDir, name+".lock"))
+}
+
+// TryLock takes the exclusive lock name in LocksDir, ErrLocked if it is held
+func (h *Home) TryLock(name string) (*Lock, error) {
+	return TryLockFile(h.Path(LocksDir, name+".lock"))
+}
+
+// Lock is a held advisory lock on a file
+type Lock struct {
+	f *os.File
+}
+
+// LockFile waits for the exclusive lock on path, creating the file if needed
+func LockFile(path string) (*Lock, error) {
+	return lockFile(path, true)
+}
+
+// TryLockFile takes the exclusive lock on path, ErrLocked if it is held
+func TryLockFile(path string) (*Lock, error) {
+	return lockFile(path, false)
+}
+
+func lockFile(path string, wait bool) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock %s: %w", path, err)
+	}
+	if err := lock(f, wait); err != nil {
+		f.Close()
+		if errors.Is(err, ErrLocked) {
+			return nil, fmt.Errorf("%s: %w", path, ErrLocked)
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return &Lock{f: f}, nil
+}
+
+// Unlock releases the lock
+func (l *Lock) Unlock() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	err := unlock(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
+
+// WriteFile replaces path atomically: data goes to a temporary file in the
+// same directory which is synced and renamed over path
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// WriteJSON replaces path atomically with v as indented JSON
+func WriteJSON(path string, v interface{}, perm os.FileMode) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFile(path, data, perm)
+}
+
+// syncDir makes a rename durable, not supported everywhere so errors are ignored
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
@@ -0,0 +1,175 @@
+package telemetry
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Category groups timed operations for the --timing summary
+type Category string
+
+const (
+	CategoryRPC     Category = "rpc"
+	CategorySigning Category = "signing"
+	CategoryCompute Category = "compute"
+	CategoryDisk    Category = "disk"
+)
+
+// categoryOrder defines the order used when printing the summary
+var categoryOrder = []Category{CategoryRPC, CategorySigning, CategoryCompute, CategoryDisk}
+
+// categoryLabels are the human readable names shown in the summary
+var categoryLabels = map[Category]string{
+	CategoryRPC:     "RPC round trips",
+	CategorySigning: "Signing",
+	CategoryCompute: "Computation",
+	CategoryDisk:    "Disk I/O",
+}
+
+// Operation is a single timed call within a category
+type Operation struct {
+	Category Category
+	Name     string
+	Duration time.Duration
+}
+
+// Recorder collects timings for a single command invocation
+type Recorder struct {
+	mu         sync.Mutex
+	enabled    bool
+	startedAt  time.Time
+	operations []Operation
+}
+
+var defaultRecorder = &Recorder{}
+
+// Enable turns on timing collection and resets the wall clock
+func Enable() {
+	defaultRecorder.mu.Lock()
+	defer defaultRecorder.mu.Unlock()
+
+	defaultRecorder.enabled = true
+	defaultRecorder.startedAt = time.Now()
+	defaultRecorder.operations = nil
+}
+
+// Enabled reports whether timing collection is active
+func Enabled() bool {
+	defaultRecorder.mu.Lock()
+	defer defaultRecorder.mu.Unlock()
+	return defaultRecorder.enabled
+}
+
+// Track starts timing an operation and returns the function that stops it.
+// Typical usage: defer telemetry.Track(telemetry.CategoryRPC, "status")()
+func Track(category Category, name string) func() {
+	if !Enabled() {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() {
+		Record(category, name, time.Since(start))
+	}
+}
+
+// Record adds an already measured duration
+func Record(category Category, name string, d time.Duration) {
+	defaultRecorder.mu.Lock()
+	defer defaultRecorder.mu.Unlock()
+
+	if !defaultRecorder.enabled {
+		return
+	}
+	defaultRecorder.operations = append(defaultRecorder.operations, Operation{
+		Category: category,
+		Name:     name,
+		Duration: d,
+	})
+}
+
+// Operations returns a copy of all recorded operations
+func Operations() []Operation {
+	defaultRecorder.mu.Lock()
+	defer defaultRecorder.mu.Unlock()
+
+	ops := make([]Operation, len(defaultRecorder.operations))
+	copy(ops, defaultRecorder.operations)
+	return ops
+}
+
+// PrintSummary writes the per-category breakdown to w
+func PrintSummary(w io.Writer) {
+	defaultRecorder.mu.Lock()
+	enabled := defaultRecorder.enabled
+	total := time.Since(defaultRecorder.startedAt)
+	ops := make([]Operation, len(defaultRecorder.operations))
+	copy(ops, defaultRecorder.operations)
+	defaultRecorder.mu.Unlock()
+
+	if !enabled {
+		return
+	}
+
+	totals := make(map[Category]time.Duration)
+	counts := make(map[Category]int)
+	perName := make(map[Category]map[string]time.Duration)
+	for _, op := range ops {
+		totals[op.Category] += op.Duration
+		counts[op.Category]++
+		if perName[op.Category] == nil {
+			perName[op.Category] = make(map[string]time.Duration)
+		}
+		perName[op.Category][op.Name] += op.Duration
+	}
+
+	var tracked time.Duration
+	fmt.Fprintf(w, "\n⏱️  Timing Summary\n")
+	fmt.Fprintf(w, "%s\n", strings.Repeat("─", 50))
+	for _, cat := range categoryOrder {
+		tracked += totals[cat]
+		fmt.Fprintf(w, "  %-18s %10s  (%d calls, %5.1f%%)\n",
+			categoryLabels[cat], formatDuration(totals[cat]), counts[cat], percent(totals[cat], total))
+
+		names := make([]string, 0, len(perName[cat]))
+		for name := range perName[cat] {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			return perName[cat][names[i]] > perName[cat][names[j]]
+		})
+		for _, name := range names {
+			fmt.Fprintf(w, "    • %-24s %10s\n", name, formatDuration(perName[cat][name]))
+		}
+	}
+
+	other := total - tracked
+	if other < 0 {
+		other = 0
+	}
+	fmt.Fprintf(w, "  %-18s %10s  (%5.1f%%)\n", "Other", formatDuration(other), percent(other, total))
+	fmt.Fprintf(w, "%s\n", strings.Repeat("─", 50))
+	fmt.Fprintf(w, "  %-18s %10s\n", "Total", formatDuration(total))
+}
+
+func percent(part, total time.Duration) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return fmt.Sprintf("%.2fs", d.Seconds())
+	case d >= time.Millisecond:
+		return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+	default:
+		return fmt.Sprintf("%dµs", d.Microseconds())
+	}
+}
@@ -0,0 +1,82 @@
+package tracklet
+
+import (
+	"math"
+	"sort"
+)
+
+// kdTree is a static 2-d tree over tangent plane positions
+type kdTree struct {
+	root *kdNode
+}
+
+type planePoint struct {
+	X, Y  float64 // arcsec
+	Index int     // into the observations
+}
+
+type kdNode struct {
+	point       planePoint
+	axis        int
+	left, right *kdNode
+}
+
+func newKDTree(points []planePoint) *kdTree {
+	return &kdTree{root: buildKD(append([]planePoint(nil), points...), 0)}
+}
+
+func buildKD(pts []planePoint, depth int) *kdNode {
+	if len(pts) == 0 {
+		return nil
+	}
+	axis := depth % 2
+	sort.Slice(pts, func(i, j int) bool { return coord(pts[i], axis) < coord(pts[j], axis) })
+	mid := len(pts) / 2
+	return &kdNode{
+		point: pts[mid],
+		axis:  axis,
+		left:  buildKD(pts[:mid], depth+1),
+		right: buildKD(pts[mid+1:], depth+1),
+	}
+}
+
+func coord(p planePoint, axis int) float64 {
+	if axis == 0 {
+		return p.X
+	}
+	return p.Y
+}
+
+// within returns the points at most radius from (x, y)
+func (t *kdTree) within(x, y, radius float64) []planePoint {
+	var out []planePoint
+	var walk func(n *kdNode)
+	walk = func(n *kdNode) {
+		if n == nil {
+			return
+		}
+		if math.Hypot(n.point.X-x, n.point.Y-y) <= radius {
+			out = append(out, n.point)
+		}
+		d := coord(planePoint{X: x, Y: y}, n.axis) - coord(n.point, n.axis)
+		if d <= radius {
+			walk(n.left)
+		}
+		if d >= -radius {
+			walk(n.right)
+		}
+	}
+	walk(t.root)
+	return out
+}
+
+// nearest returns the closest point within radius
+func (t *kdTree) nearest(x, y, radius float64) (planePoint, bool) {
+	best, bestDist := planePoint{}, math.Inf(1)
+	for _, p := range t.within(x, y, radius) {
+		if d := math.Hypot(p.X-x, p.Y-y); d < bestDist {
+			best, bestDist = p, d
+		}
+	}
+	return best, !math.IsInf(bestDist, 1)
+}
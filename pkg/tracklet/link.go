@@ -0,0 +1,312 @@
+// Package tracklet links detections of several exposures of a night into
+// moving objects and fits preliminary orbits to them.
+//
+// Positions are projected onto a common tangent plane. For every pair of
+// exposures, detections whose separation corresponds to a rate of motion
+// between MinRate and MaxRate seed a straight-line track; the detections of
+// the other exposures closest to the predicted positions (kd-tree search)
+// are added when they lie within Tolerance. Tracks with enough detections
+// and a small linear-fit residual become tracklets, each detection belongs
+// to at most one of them. Detections that recur at the same position in
+// another exposure are stationary sources and are not linked.
+package tracklet
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Observation is a detection with a sky position
+type Observation struct {
+	Image string
+	Time  time.Time
+	RA    float64 // degrees
+	Dec   float64 // degrees
+	Score float64 // detector confidence
+}
+
+// Options constrains the linking
+type Options struct {
+	MinRate       float64       // arcsec/hour
+	MaxRate       float64       // arcsec/hour
+	Tolerance     float64       // arcsec, largest offset from the linear track
+	MinDetections int           // detections per tracklet, at least 3 for a residual check
+	MaxSpan       time.Duration // longest time between first and last detection
+}
+
+// DefaultOptions suit trans-Neptunian objects and Centaurs
+func DefaultOptions() Options {
+	return Options{MinRate: 0.5, MaxRate: 20, Tolerance: 1.5, MinDetections: 3, MaxSpan: 12 * time.Hour}
+}
+
+// Validate checks the options
+func (o Options) Validate() error {
+	switch {
+	case o.MinRate < 0 || o.MaxRate <= o.MinRate:
+		return fmt.Errorf("rate range %.2f-%.2f arcsec/h is empty", o.MinRate, o.MaxRate)
+	case o.Tolerance <= 0:
+		return fmt.Errorf("tolerance must be positive")
+	case o.MinDetections < 2:
+		return fmt.Errorf("a tracklet needs at least 2 detections")
+	case o.MaxSpan <= 0:
+		return fmt.Errorf("max span must be positive")
+	}
+	return nil
+}
+
+// Tracklet is a set of detections moving on a straight line
+type Tracklet struct {
+	ID            string       `json:"id"`
+	Observations  []int        `json:"observations"`   // indexes into the input, by time
+	Rate          float64      `json:"rate"`           // arcsec/hour
+	PositionAngle float64      `json:"position_angle"` // direction of motion, degrees east of north
+	RMS           float64      `json:"rms"`            // arcsec, residual of the linear fit
+	Residuals     [][2]float64 `json:"residuals"`      // RA*cos(Dec) and Dec residuals in arcsec
+	MidTime       time.Time    `json:"mid_time"`
+	MidRA         float64      `json:"mid_ra"`
+	MidDec        float64      `json:"mid_dec"`
+	RARate        float64      `json:"ra_rate"`  // dRA*cos(Dec)/dt, arcsec/hour
+	DecRate       float64      `json:"dec_rate"` // arcsec/hour
+	Orbit         *Orbit       `json:"orbit,omitempty"`
+}
+
+// Link finds tracklets among the observations
+func Link(obs []Observation, opts Options) ([]Tracklet, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if len(obs) == 0 {
+		return nil, nil
+	}
+	plane := newTangentPlane(obs)
+
+	// One kd-tree per exposure time
+	byTime := make(map[int64][]planePoint)
+	for i, o := range obs {
+		x, y := plane.project(o.RA, o.Dec)
+		byTime[o.Time.UnixNano()] = append(byTime[o.Time.UnixNano()], planePoint{X: x, Y: y, Index: i})
+	}
+	var epochs []int64
+	for t := range byTime {
+		epochs = append(epochs, t)
+	}
+	sort.Slice(epochs, func(i, j int) bool { return epochs[i] < epochs[j] })
+	if len(epochs) < opts.MinDetections {
+		return nil, fmt.Errorf("%d exposure times, a tracklet needs detections at %d", len(epochs), opts.MinDetections)
+	}
+	hours := func(a, b int64) float64 { return float64(b-a) / float64(time.Hour) }
+	trees := make([]*kdTree, len(epochs))
+	for i, t := range epochs {
+		trees[i] = newKDTree(byTime[t])
+	}
+
+	// A mover is at least MinRate*dt away in other exposures, anything
+	// closer is a stationary source
+	stationary := make(map[int]bool)
+	for i, ti := range epochs {
+		for _, p := range byTime[ti] {
+			for j, tj := range epochs {
+				if j == i {
+					continue
+				}
+				radius := math.Min(opts.Tolerance, opts.MinRate*math.Abs(hours(ti, tj))/2)
+				if _, ok := trees[j].nearest(p.X, p.Y, radius); ok {
+					stationary[p.Index] = true
+					break
+				}
+			}
+		}
+	}
+	for i, t := range epochs {
+		var moving []planePoint
+		for _, p := range byTime[t] {
+			if !stationary[p.Index] {
+				moving = append(moving, p)
+			}
+		}
+		byTime[t] = moving
+		trees[i] = newKDTree(moving)
+	}
+
+	seen := make(map[string]bool)
+	var tracks []Tracklet
+	for i := range epochs {
+		for j := i + 1; j < len(epochs); j++ {
+			dt := hours(epochs[i], epochs[j])
+			if dt > opts.MaxSpan.Hours() {
+				break
+			}
+			for _, p := range byTime[epochs[i]] {
+				for _, q := range trees[j].within(p.X, p.Y, opts.MaxRate*dt) {
+					if math.Hypot(q.X-p.X, q.Y-p.Y) < opts.MinRate*dt {
+						continue
+					}
+					vx, vy := (q.X-p.X)/dt, (q.Y-p.Y)/dt
+					members := []planePoint{p, q}
+					for k := range epochs {
+						if k == i || k == j || math.Abs(hours(epochs[i], epochs[k])) > opts.MaxSpan.Hours() {
+							continue
+						}
+						h := hours(epochs[i], epochs[k])
+						if m, ok := trees[k].nearest(p.X+vx*h, p.Y+vy*h, opts.Tolerance); ok {
+							members = append(members, m)
+						}
+					}
+					if len(members) < opts.MinDetections {
+						continue
+					}
+					key := memberKey(members)
+					if seen[key] {
+						continue
+					}
+					seen[key] = true
+					if t, ok := fitTrack(obs, plane, members, opts); ok {
+						tracks = append(tracks, t)
+					}
+				}
+			}
+		}
+	}
+
+	// Longest and tightest tracks first, every detection used once
+	sort.SliceStable(tracks, func(a, b int) bool {
+		if len(tracks[a].Observations) != len(tracks[b].Observations) {
+			return len(tracks[a].Observations) > len(tracks[b].Observations)
+		}
+		return tracks[a].RMS < tracks[b].RMS
+	})
+	used := make(map[int]bool)
+	var out []Tracklet
+	for _, t := range tracks {
+		free := true
+		for _, i := range t.Observations {
+			free = free && !used[i]
+		}
+		if !free {
+			continue
+		}
+		for _, i := range t.Observations {
+			used[i] = true
+		}
+		t.ID = fmt.Sprintf("trk%04d", len(out)+1)
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+func memberKey(members []planePoint) string {
+	idx := make([]int, len(members))
+	for i, m := range members {
+		idx[i] = m.Index
+	}
+	sort.Ints(idx)
+	return strings.Trim(fmt.Sprint(idx), "[]")
+}
+
+// fitTrack fits x(t), y(t) by least squares and checks residuals and rate
+func fitTrack(obs []Observation, plane tangentPlane, members []planePoint, opts Options) (Tracklet, bool) {
+	sort.Slice(members, func(a, b int) bool { return obs[members[a].Index].Time.Before(obs[members[b].Index].Time) })
+	t0 := obs[members[0].Index].Time
+	span := obs[members[len(members)-1].Index].Time.Sub(t0)
+	if span > opts.MaxSpan {
+		return Tracklet{}, false
+	}
+	tMid := t0.Add(span / 2)
+
+	var st, stt, sx, sy, stx, sty float64
+	n := float64(len(members))
+	for _, m := range members {
+		t := obs[m.Index].Time.Sub(tMid).Hours()
+		st += t
+		stt += t * t
+		sx += m.X
+		sy += m.Y
+		stx += t * m.X
+		sty += t * m.Y
+	}
+	det := n*stt - st*st
+	if det == 0 {
+		return Tracklet{}, false
+	}
+	vx := (n*stx - st*sx) / det
+	vy := (n*sty - st*sy) / det
+	x0 := (sx - vx*st) / n
+	y0 := (sy - vy*st) / n
+
+	rate := math.Hypot(vx, vy)
+	if rate < opts.MinRate || rate > opts.MaxRate {
+		return Tracklet{}, false
+	}
+	t := Tracklet{Rate: rate}
+	var sum float64
+	for _, m := range members {
+		h := obs[m.Index].Time.Sub(tMid).Hours()
+		dx, dy := m.X-(x0+vx*h), m.Y-(y0+vy*h)
+		if math.Hypot(dx, dy) > opts.Tolerance {
+			return Tracklet{}, false
+		}
+		sum += dx*dx + dy*dy
+		t.Observations = append(t.Observations, m.Index)
+		t.Residuals = append(t.Residuals, [2]float64{dx, dy})
+	}
+	t.RMS = math.Sqrt(sum / n)
+
+	// Rates on the sky at the middle of the track, the plane axes are only
+	// aligned with RA and Dec at its center
+	t.MidTime = tMid
+	t.MidRA, t.MidDec = plane.deproject(x0, y0)
+	ra1, dec1 := plane.deproject(x0+vx, y0+vy)
+	t.RARate = wrapDegrees(ra1-t.MidRA) * math.Cos(t.MidDec*math.Pi/180) * 3600
+	t.DecRate = (dec1 - t.MidDec) * 3600
+	t.PositionAngle = math.Mod(math.Atan2(t.RARate, t.DecRate)*180/math.Pi+360, 360)
+	return t, true
+}
+
+// wrapDegrees maps an angle difference to (-180, 180]
+func wrapDegrees(d float64) float64 {
+	d = math.Mod(d, 360)
+	if d > 180 {
+		d -= 360
+	} else if d <= -180 {
+		d += 360
+	}
+	return d
+}
+
+// tangentPlane is a gnomonic projection around the mean position, in
+// arcseconds with x towards east and y towards north
+type tangentPlane struct {
+	ra0, dec0 float64 // radians
+}
+
+func newTangentPlane(obs []Observation) tangentPlane {
+	var x, y, z float64
+	for _, o := range obs {
+		ra, dec := o.RA*math.Pi/180, o.Dec*math.Pi/180
+		x += math.Cos(dec) * math.Cos(ra)
+		y += math.Cos(dec) * math.Sin(ra)
+		z += math.Sin(dec)
+	}
+	return tangentPlane{ra0: math.Atan2(y, x), dec0: math.Atan2(z, math.Hypot(x, y))}
+}
+
+const arcsecPerRadian = 180 / math.Pi * 3600
+
+func (p tangentPlane) project(raDeg, decDeg float64) (float64, float64) {
+	ra, dec := raDeg*math.Pi/180, decDeg*math.Pi/180
+	cosc := math.Sin(p.dec0)*math.Sin(dec) + math.Cos(p.dec0)*math.Cos(dec)*math.Cos(ra-p.ra0)
+	x := math.Cos(dec) * math.Sin(ra-p.ra0) / cosc
+	y := (math.Cos(p.dec0)*math.Sin(dec) - math.Sin(p.dec0)*math.Cos(dec)*math.Cos(ra-p.ra0)) / cosc
+	return x * arcsecPerRadian, y * arcsecPerRadian
+}
+
+func (p tangentPlane) deproject(x, y float64) (float64, float64) {
+	x, y = x/arcsecPerRadian, y/arcsecPerRadian
+	denom := math.Cos(p.dec0) - y*math.Sin(p.dec0)
+	ra := p.ra0 + math.Atan2(x, denom)
+	dec := math.Atan2(math.Sin(p.dec0)+y*math.Cos(p.dec0), math.Hypot(x, denom))
+	return math.Mod(ra*180/math.Pi+360, 360), dec * 180 / math.Pi
+}
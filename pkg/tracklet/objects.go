@@ -0,0 +1,88 @@
+package tracklet
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/oxygene76/medasdigital-client/internal/types"
+)
+
+// Classify names the dynamical class of a circular orbit radius
+func Classify(a float64) string {
+	switch {
+	case a >= 30:
+		return "TNO"
+	case a >= 5.2:
+		return "Centaur"
+	}
+	return "inner"
+}
+
+// TNOObject turns the tracklet into a candidate for orbital analysis
+func (t *Tracklet) TNOObject(obs []Observation) types.TNOObject {
+	o := types.TNOObject{
+		ID:          t.ID,
+		Name:        t.ID,
+		Designation: t.ID,
+		Status:      "candidate",
+	}
+	var score float64
+	for k, i := range t.Observations {
+		ob := obs[i]
+		score += ob.Score
+		o.Observations = append(o.Observations, types.Observation{
+			Time:      ob.Time,
+			RA:        ob.RA,
+			Dec:       ob.Dec,
+			Quality:   ob.Score,
+			Residuals: types.Residuals{RAResidual: t.Residuals[k][0], DecResidual: t.Residuals[k][1]},
+		})
+	}
+	if n := len(t.Observations); n > 0 {
+		o.DiscoveryInfo.DiscoveryDate = obs[t.Observations[0]].Time
+		// Detector confidence, reduced when the track is not straight
+		o.Confidence = score / float64(n) / (1 + t.RMS)
+	}
+
+	if t.Orbit != nil {
+		orb := t.Orbit
+		o.Classification = Classify(orb.SemiMajorAxis)
+		o.OrbitalElements = types.OrbitalElements{
+			SemiMajorAxis:      orb.SemiMajorAxis,
+			Eccentricity:       orb.Eccentricity,
+			Inclination:        orb.Inclination,
+			LongitudeAscending: orb.LongitudeAscendingNode,
+			ArgumentPeriapsis:  orb.ArgumentPerihelion,
+			MeanAnomaly:        orb.MeanAnomaly,
+			Epoch:              t.MidTime,
+			Period:             math.Pow(orb.SemiMajorAxis, 1.5),
+		}
+		o.SemimajorAxis = orb.SemiMajorAxis
+		o.Eccentricity = orb.Eccentricity
+		o.Inclination = orb.Inclination
+		o.LongitudeNode = orb.LongitudeAscendingNode
+		o.ArgumentPeriapsis = orb.ArgumentPerihelion
+		o.MeanAnomaly = orb.MeanAnomaly
+		o.Epoch = orb.EpochJD
+	}
+	return o
+}
+
+// WriteOrbitalCSV writes the objects with an orbit in the input format of
+// analyze orbital-dynamics: designation, a, e, i, node, peri, M, epoch
+func WriteOrbitalCSV(w io.Writer, objects []types.TNOObject) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"designation", "a", "e", "i", "node", "peri", "M", "epoch"})
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 6, 64) }
+	for _, o := range objects {
+		if o.OrbitalElements.Epoch.IsZero() {
+			continue
+		}
+		cw.Write([]string{o.Designation, f(o.SemimajorAxis), f(o.Eccentricity), f(o.Inclination),
+			f(o.LongitudeNode), f(o.ArgumentPeriapsis), f(o.MeanAnomaly), f(o.Epoch)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,217 @@
+package tracklet
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	astromath "github.com/oxygene76/medasdigital-client/pkg/astronomy/math"
+)
+
+// Orbit is a preliminary heliocentric orbit in ecliptic J2000 elements.
+//
+// A single night only measures position and rate of motion, so the orbit is
+// the circular orbit (e = 0) that reproduces both, the usual first orbit of
+// new objects. Distance and inclination are reasonable for distant objects
+// observed near opposition; a and the other elements change once further
+// nights are linked.
+type Orbit struct {
+	SemiMajorAxis          float64 `json:"semi_major_axis"`          // AU, the heliocentric distance
+	Eccentricity           float64 `json:"eccentricity"`             // 0
+	Inclination            float64 `json:"inclination"`              // degrees
+	LongitudeAscendingNode float64 `json:"longitude_ascending_node"` // degrees
+	ArgumentPerihelion     float64 `json:"argument_perihelion"`      // degrees, 0 for a circle
+	MeanAnomaly            float64 `json:"mean_anomaly"`             // degrees, from the node
+	EpochJD                float64 `json:"epoch_jd"`
+	GeocentricDistance     float64 `json:"geocentric_distance"` // AU
+	RateResidual           float64 `json:"rate_residual"`       // arcsec/hour left unexplained
+}
+
+// Physical reports whether a circular orbit explains the motion of the
+// tracklet; chance alignments of unrelated detections mostly move in ways
+// no bound orbit can
+func (o *Orbit) Physical(rate float64) bool {
+	return o.SemiMajorAxis < maxRadius*0.99 && o.RateResidual <= math.Max(0.1*rate, 0.2)
+}
+
+const (
+	gaussK    = 0.01720209895 // AU^1.5 / day
+	muSun     = gaussK * gaussK
+	minRadius = 1.2    // AU, smallest heliocentric distance tried
+	maxRadius = 1000.0 // AU
+)
+
+// julianDate converts t to a Julian date
+func julianDate(t time.Time) float64 {
+	return float64(t.UnixNano())/86400e9 + 2440587.5
+}
+
+// earthState is the heliocentric ecliptic position (AU) and velocity
+// (AU/day) of the Earth from the low precision solar coordinates of the
+// Astronomical Almanac, good to about 0.01 degrees
+func earthState(jd float64) (astromath.Vector3, astromath.Vector3) {
+	pos := func(jd float64) astromath.Vector3 {
+		n := jd - 2451545.0
+		g := (357.528 + 0.9856003*n) * math.Pi / 180
+		lambda := (280.460 + 0.9856474*n + 1.915*math.Sin(g) + 0.020*math.Sin(2*g)) * math.Pi / 180
+		r := 1.00014 - 0.01671*math.Cos(g) - 0.00014*math.Cos(2*g)
+		// The Earth is opposite the Sun
+		return astromath.Vector3{X: -r * math.Cos(lambda), Y: -r * math.Sin(lambda)}
+	}
+	p := pos(jd)
+	v := pos(jd + 0.5).Sub(pos(jd - 0.5))
+	return p, v
+}
+
+// equatorialToEcliptic rotates a J2000 equatorial vector to the ecliptic
+func equatorialToEcliptic(v astromath.Vector3) astromath.Vector3 {
+	const eps = 23.4392911 * math.Pi / 180
+	return astromath.Vector3{
+		X: v.X,
+		Y: math.Cos(eps)*v.Y + math.Sin(eps)*v.Z,
+		Z: -math.Sin(eps)*v.Y + math.Cos(eps)*v.Z,
+	}
+}
+
+// FitCircularOrbit finds the circular heliocentric orbit matching the
+// position and rate of motion of the tracklet at its mid time
+func FitCircularOrbit(t *Tracklet) (*Orbit, error) {
+	jd := julianDate(t.MidTime)
+	earth, earthVel := earthState(jd)
+
+	ra, dec := t.MidRA*math.Pi/180, t.MidDec*math.Pi/180
+	u := equatorialToEcliptic(astromath.Vector3{X: math.Cos(dec) * math.Cos(ra), Y: math.Cos(dec) * math.Sin(ra), Z: math.Sin(dec)})
+	east := equatorialToEcliptic(astromath.Vector3{X: -math.Sin(ra), Y: math.Cos(ra)})
+	north := equatorialToEcliptic(astromath.Vector3{X: -math.Sin(dec) * math.Cos(ra), Y: -math.Sin(dec) * math.Sin(ra), Z: math.Cos(dec)})
+	// Observed du/dt in radians per day
+	const perHour = 24 / arcsecPerRadian
+	observed := east.Scale(t.RARate * perHour).Add(north.Scale(t.DecRate * perHour))
+
+	// For a radius r the residual is minimized over the direction of motion
+	// in the plane perpendicular to the position
+	solve := func(r float64) (float64, float64) {
+		delta, ok := lineOfSightDistance(earth, u, r)
+		if !ok {
+			return math.Inf(1), 0
+		}
+		return bestDirection(earth.Add(u.Scale(delta)), earthVel, u, delta, observed)
+	}
+
+	// Log-spaced scan, then golden section search around the best radius
+	const steps = 400
+	ratio := math.Pow(maxRadius/minRadius, 1.0/steps)
+	bestR, bestRes := 0.0, math.Inf(1)
+	for r := minRadius; r <= maxRadius; r *= ratio {
+		if res, _ := solve(r); res < bestRes {
+			bestR, bestRes = r, res
+		}
+	}
+	if math.IsInf(bestRes, 1) {
+		return nil, fmt.Errorf("no circular orbit for tracklet %s", t.ID)
+	}
+	lo, hi := bestR/ratio, bestR*ratio
+	for i := 0; i < 60; i++ {
+		m1, m2 := hi-(hi-lo)*0.618034, lo+(hi-lo)*0.618034
+		r1, _ := solve(m1)
+		r2, _ := solve(m2)
+		if r1 < r2 {
+			hi = m2
+		} else {
+			lo = m1
+		}
+	}
+	r := (lo + hi) / 2
+	res, theta := solve(r)
+	delta, _ := lineOfSightDistance(earth, u, r)
+	pos := earth.Add(u.Scale(delta))
+	e1, e2 := perpendicularBasis(pos)
+	vel := e1.Scale(math.Cos(theta)).Add(e2.Scale(math.Sin(theta))).Scale(math.Sqrt(muSun / r))
+
+	o := circularElements(pos, vel)
+	o.EpochJD = jd
+	o.GeocentricDistance = delta
+	o.RateResidual = res / perHour
+	return &o, nil
+}
+
+// lineOfSightDistance is the distance along u from the Earth to the sphere
+// of radius r around the Sun
+func lineOfSightDistance(earth, u astromath.Vector3, r float64) (float64, bool) {
+	b := earth.Dot(u)
+	disc := b*b - earth.Dot(earth) + r*r
+	if disc < 0 {
+		return 0, false
+	}
+	delta := -b + math.Sqrt(disc)
+	return delta, delta > 0
+}
+
+// perpendicularBasis returns two unit vectors perpendicular to p
+func perpendicularBasis(p astromath.Vector3) (astromath.Vector3, astromath.Vector3) {
+	n := p.Normalize()
+	e1 := astromath.Vector3{Z: 1}.Cross(n)
+	if e1.Magnitude() < 1e-9 {
+		e1 = astromath.Vector3{X: 1}.Cross(n)
+	}
+	e1 = e1.Normalize()
+	return e1, n.Cross(e1)
+}
+
+// bestDirection finds the direction theta of the circular velocity whose
+// apparent motion is closest to the observed one, and the residual norm
+func bestDirection(pos, earthVel, u astromath.Vector3, delta float64, observed astromath.Vector3) (float64, float64) {
+	r := pos.Magnitude()
+	e1, e2 := perpendicularBasis(pos)
+	vc := math.Sqrt(muSun / r)
+	apparent := func(w astromath.Vector3) astromath.Vector3 {
+		return w.Sub(u.Scale(w.Dot(u))).Scale(1 / delta)
+	}
+	a := apparent(e1.Scale(vc))
+	b := apparent(e2.Scale(vc))
+	c := apparent(earthVel.Scale(-1)).Sub(observed)
+	residual := func(theta float64) float64 {
+		return a.Scale(math.Cos(theta)).Add(b.Scale(math.Sin(theta))).Add(c).Magnitude()
+	}
+
+	best, bestRes := 0.0, math.Inf(1)
+	for i := 0; i < 360; i++ {
+		theta := float64(i) * math.Pi / 180
+		if res := residual(theta); res < bestRes {
+			best, bestRes = theta, res
+		}
+	}
+	lo, hi := best-math.Pi/180, best+math.Pi/180
+	for i := 0; i < 40; i++ {
+		m1, m2 := hi-(hi-lo)*0.618034, lo+(hi-lo)*0.618034
+		if residual(m1) < residual(m2) {
+			hi = m2
+		} else {
+			lo = m1
+		}
+	}
+	best = (lo + hi) / 2
+	return residual(best), best
+}
+
+// circularElements converts a circular state vector to elements in degrees
+func circularElements(pos, vel astromath.Vector3) Orbit {
+	h := pos.Cross(vel)
+	inc := math.Acos(math.Max(-1, math.Min(1, h.Z/h.Magnitude())))
+	node := astromath.Vector3{Z: 1}.Cross(h)
+	var omega, argLat float64
+	if node.Magnitude() > 1e-12 {
+		node = node.Normalize()
+		omega = math.Atan2(node.Y, node.X)
+		// Argument of latitude, signed by the side of the node line
+		argLat = math.Atan2(h.Normalize().Dot(node.Cross(pos)), node.Dot(pos))
+	} else {
+		argLat = math.Atan2(pos.Y, pos.X)
+	}
+	deg := func(x float64) float64 { return math.Mod(x*180/math.Pi+360, 360) }
+	return Orbit{
+		SemiMajorAxis:          pos.Magnitude(),
+		Inclination:            inc * 180 / math.Pi,
+		LongitudeAscendingNode: deg(omega),
+		MeanAnomaly:            deg(argLat),
+	}
+}
@@ -0,0 +1,261 @@
+package training
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// JobSpecVersion is bumped whenever the worker contract changes
+const JobSpecVersion = "1"
+
+// Hyperparameters are passed unchanged to the external training worker
+type Hyperparameters struct {
+	Epochs       int     `json:"epochs"`
+	BatchSize    int     `json:"batch_size"`
+	LearningRate float64 `json:"learning_rate"`
+	Optimizer    string  `json:"optimizer"`
+	GPUDevices   []int   `json:"gpu_devices"`
+}
+
+// ManifestEntry describes one training data file
+type ManifestEntry struct {
+	Path   string `json:"path"` // relative to the data root, slash separated
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// DataManifest lists every file the worker must train on
+type DataManifest struct {
+	Root       string          `json:"root"`
+	Files      []ManifestEntry `json:"files"`
+	TotalBytes int64           `json:"total_bytes"`
+}
+
+// ArchitectureConfig selects and configures the model on the worker side
+type ArchitectureConfig struct {
+	Name       string `json:"name"`
+	InputSize  int    `json:"input_size"`
+	Channels   int    `json:"channels"`
+	NumClasses int    `json:"num_classes"`
+}
+
+// OutputSpec tells the worker what to produce
+type OutputSpec struct {
+	Format string `json:"format"` // exported model format, loadable by ai detect
+	Name   string `json:"name"`
+}
+
+// JobSpec is the portable training job description consumed by a PyTorch worker
+type JobSpec struct {
+	Version         string             `json:"version"`
+	JobID           string             `json:"job_id"`
+	CreatedAt       time.Time          `json:"created_at"`
+	Framework       string             `json:"framework"`
+	Architecture    ArchitectureConfig `json:"architecture"`
+	Hyperparameters Hyperparameters    `json:"hyperparameters"`
+	Data            DataManifest       `json:"data"`
+	Output          OutputSpec         `json:"output"`
+}
+
+// NewJobSpec builds a job spec by scanning and hashing the training data
+func NewJobSpec(trainingData string, arch ArchitectureConfig, hp Hyperparameters) (*JobSpec, error) {
+	manifest, err := BuildManifest(trainingData)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt := time.Now().UTC()
+	spec := &JobSpec{
+		Version:         JobSpecVersion,
+		CreatedAt:       createdAt,
+		Framework:       "pytorch",
+		Architecture:    arch,
+		Hyperparameters: hp,
+		Data:            *manifest,
+		Output: OutputSpec{
+			Format: "onnx",
+			Name:   arch.Name + ".onnx",
+		},
+	}
+	spec.JobID = fmt.Sprintf("train-%s-%d", arch.Name, createdAt.Unix())
+	return spec, nil
+}
+
+// BuildManifest walks a file or directory and hashes every regular file
+func BuildManifest(root string) (*DataManifest, error) {
+	defer telemetry.Track(telemetry.CategoryDisk, "build_manifest")()
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("training data not found: %w", err)
+	}
+
+	manifest := &DataManifest{Root: filepath.Base(root)}
+	base := root
+	if !info.IsDir() {
+		base = filepath.Dir(root)
+	}
+
+	err = filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		sum, err := HashFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, ManifestEntry{
+			Path:   filepath.ToSlash(rel),
+			Size:   fi.Size(),
+			SHA256: sum,
+		})
+		manifest.TotalBytes += fi.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan training data: %w", err)
+	}
+	if len(manifest.Files) == 0 {
+		return nil, fmt.Errorf("no training files found in %s", root)
+	}
+
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Path < manifest.Files[j].Path
+	})
+	return manifest, nil
+}
+
+// WriteBundle writes <job-id>.json and <job-id>.tar.gz into outDir.
+// The archive contains job.json at the top level and the data files under data/.
+func (s *JobSpec) WriteBundle(trainingData, outDir string) (specPath, archivePath string, err error) {
+	defer telemetry.Track(telemetry.CategoryDisk, "write_job_bundle")()
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	specData, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal job spec: %w", err)
+	}
+
+	specPath = filepath.Join(outDir, s.JobID+".json")
+	if err := os.WriteFile(specPath, specData, 0644); err != nil {
+		return "", "", fmt.Errorf("failed to write job spec: %w", err)
+	}
+
+	archivePath = filepath.Join(outDir, s.JobID+".tar.gz")
+	f, err := os.Create(archivePath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	if err := writeTarFile(tw, "job.json", specData); err != nil {
+		return "", "", err
+	}
+
+	base := trainingData
+	if info, err := os.Stat(trainingData); err == nil && !info.IsDir() {
+		base = filepath.Dir(trainingData)
+	}
+	for _, entry := range s.Data.Files {
+		if err := addTarFile(tw, filepath.Join(base, filepath.FromSlash(entry.Path)), "data/"+entry.Path); err != nil {
+			return "", "", err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return "", "", fmt.Errorf("failed to finalize archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return "", "", fmt.Errorf("failed to finalize archive: %w", err)
+	}
+	return specPath, archivePath, nil
+}
+
+// LoadJobSpec reads a job spec written by WriteBundle
+func LoadJobSpec(path string) (*JobSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job spec: %w", err)
+	}
+	var spec JobSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("invalid job spec: %w", err)
+	}
+	return &spec, nil
+}
+
+// HashFile returns the hex SHA-256 of a file
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write %s to archive: %w", name, err)
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+func addTarFile(tw *tar.Writer, src, name string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(fi, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write %s to archive: %w", name, err)
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
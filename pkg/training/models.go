@@ -0,0 +1,165 @@
+package training
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/oxygene76/medasdigital-client/pkg/telemetry"
+)
+
+// ModelRecord is a locally imported model and its on-chain anchor
+type ModelRecord struct {
+	Name         string    `json:"name"`
+	SHA256       string    `json:"sha256"`
+	Size         int64     `json:"size"`
+	Path         string    `json:"path"`
+	Format       string    `json:"format"`
+	JobID        string    `json:"job_id,omitempty"`
+	Architecture string    `json:"architecture,omitempty"`
+	ImportedAt   time.Time `json:"imported_at"`
+	TxHash       string    `json:"tx_hash,omitempty"`
+	BlockHeight  int64     `json:"block_height,omitempty"`
+}
+
+// ModelRegistry stores imported models below a directory with an index.json
+type ModelRegistry struct {
+	dir string
+}
+
+// NewModelRegistry creates a registry rooted at dir
+func NewModelRegistry(dir string) *ModelRegistry {
+	return &ModelRegistry{dir: dir}
+}
+
+// ReadExpectedHash returns the hash from a "<weights>.sha256" sidecar written by the worker, if present
+func ReadExpectedHash(weightsPath string) (string, bool) {
+	data, err := os.ReadFile(weightsPath + ".sha256")
+	if err != nil {
+		return "", false
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", false
+	}
+	return strings.ToLower(fields[0]), true
+}
+
+// Import hashes the weights, checks them against expectedHash (if set) and copies
+// them into the registry. spec may be nil when the model was not trained from an exported job.
+func (r *ModelRegistry) Import(weightsPath, name, expectedHash string, spec *JobSpec) (*ModelRecord, error) {
+	info, err := os.Stat(weightsPath)
+	if err != nil {
+		return nil, fmt.Errorf("model weights not found: %w", err)
+	}
+
+	stopTimer := telemetry.Track(telemetry.CategoryDisk, "hash_model")
+	sum, err := HashFile(weightsPath)
+	stopTimer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash model: %w", err)
+	}
+
+	if expectedHash != "" && !strings.EqualFold(expectedHash, sum) {
+		return nil, fmt.Errorf("model hash mismatch: expected %s, got %s", expectedHash, sum)
+	}
+
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(weightsPath), filepath.Ext(weightsPath))
+	}
+
+	record := &ModelRecord{
+		Name:       name,
+		SHA256:     sum,
+		Size:       info.Size(),
+		Format:     strings.TrimPrefix(filepath.Ext(weightsPath), "."),
+		ImportedAt: time.Now(),
+	}
+	if spec != nil {
+		record.JobID = spec.JobID
+		record.Architecture = spec.Architecture.Name
+	}
+
+	destDir := filepath.Join(r.dir, sum[:16])
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create model directory: %w", err)
+	}
+	record.Path = filepath.Join(destDir, filepath.Base(weightsPath))
+	if err := copyFile(weightsPath, record.Path); err != nil {
+		return nil, fmt.Errorf("failed to copy model: %w", err)
+	}
+
+	if err := r.Save(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Save inserts or replaces a record (matched by hash) in the index
+func (r *ModelRegistry) Save(record *ModelRecord) error {
+	defer telemetry.Track(telemetry.CategoryDisk, "save_model_index")()
+
+	records, err := r.List()
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i := range records {
+		if records[i].SHA256 == record.SHA256 {
+			records[i] = *record
+			replaced = true
+		}
+	}
+	if !replaced {
+		records = append(records, *record)
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(r.dir, "index.json"), data, 0644)
+}
+
+// List returns all imported models
+func (r *ModelRegistry) List() ([]ModelRecord, error) {
+	data, err := os.ReadFile(filepath.Join(r.dir, "index.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model index: %w", err)
+	}
+
+	var records []ModelRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("invalid model index: %w", err)
+	}
+	return records, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
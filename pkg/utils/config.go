@@ -2,6 +2,8 @@ package utils
 
 import (
 	"time"
+
+	"github.com/oxygene76/medasdigital-client/pkg/network"
 )
 
 // GPUConfig configuration for GPU management
@@ -93,12 +95,14 @@ type AnalysisConfig struct {
 
 // DefaultClientConfig returns default client configuration
 func DefaultClientConfig() *ClientConfig {
+	net := network.Current()
 	return &ClientConfig{
 		Chain: ChainConfig{
-			ID:           "medasdigital-2",
-			RPCEndpoint:  "https://rpc.medas-digital.io:26657",
-			Bech32Prefix: "medas",
-			GasPrice:     "0.025umedas",
+			ID:           net.ChainID,
+			RPCEndpoint:  net.RPCEndpoint,
+			Bech32Prefix: net.Bech32Prefix,
+			GasPrice:     net.GasPrice,
+			BaseDenom:    net.BaseDenom,
 		},
 		Client: ClientSettings{
 			KeyringDir:     "",
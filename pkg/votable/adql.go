@@ -0,0 +1,456 @@
+package votable
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/oxygene76/medasdigital-client/pkg/export"
+)
+
+// Query is the ADQL subset the TAP endpoint understands:
+//
+//	SELECT [TOP n] * | col [, col ...] FROM table
+//	[WHERE col op value [AND col op value ...]]
+//	[ORDER BY col [ASC|DESC] [, ...]]
+//
+// op is one of = != <> < <= > >= LIKE, values are numbers or 'strings'.
+// Joins, functions, OR, geometry and subqueries are not supported.
+type Query struct {
+	Top     int // 0 means no limit
+	Columns []string
+	Table   string
+	Where   []Condition
+	OrderBy []Order
+}
+
+// Condition compares a column with a literal
+type Condition struct {
+	Column   string
+	Op       string
+	Number   float64
+	Text     string
+	IsNumber bool
+}
+
+// Order sorts by one column
+type Order struct {
+	Column string
+	Desc   bool
+}
+
+type token struct {
+	text   string
+	quoted bool // 'string' literal
+}
+
+// ParseQuery parses an ADQL-lite query
+func ParseQuery(adql string) (*Query, error) {
+	toks, err := tokenize(adql)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	q := &Query{}
+
+	if !p.keyword("SELECT") {
+		return nil, fmt.Errorf("query must start with SELECT")
+	}
+	if p.keyword("TOP") {
+		n, err := strconv.Atoi(p.next().text)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("TOP needs a non-negative integer")
+		}
+		q.Top = n
+	}
+	if p.peek().text == "*" {
+		p.next()
+	} else {
+		for {
+			col := p.next()
+			if !isIdentifier(col) {
+				return nil, fmt.Errorf("expected a column name, got %q", col.text)
+			}
+			q.Columns = append(q.Columns, col.text)
+			if p.peek().text != "," {
+				break
+			}
+			p.next()
+		}
+	}
+	if !p.keyword("FROM") {
+		return nil, fmt.Errorf("expected FROM, got %q", p.peek().text)
+	}
+	table := p.next()
+	if !isIdentifier(table) {
+		return nil, fmt.Errorf("expected a table name, got %q", table.text)
+	}
+	q.Table = table.text
+
+	if p.keyword("WHERE") {
+		for {
+			c, err := p.condition()
+			if err != nil {
+				return nil, err
+			}
+			q.Where = append(q.Where, c)
+			if p.keyword("OR") {
+				return nil, fmt.Errorf("OR is not supported, only AND")
+			}
+			if !p.keyword("AND") {
+				break
+			}
+		}
+	}
+	if p.keyword("ORDER") {
+		if !p.keyword("BY") {
+			return nil, fmt.Errorf("expected BY after ORDER")
+		}
+		for {
+			col := p.next()
+			if !isIdentifier(col) {
+				return nil, fmt.Errorf("expected a column name after ORDER BY, got %q", col.text)
+			}
+			o := Order{Column: col.text}
+			if p.keyword("DESC") {
+				o.Desc = true
+			} else {
+				p.keyword("ASC")
+			}
+			q.OrderBy = append(q.OrderBy, o)
+			if p.peek().text != "," {
+				break
+			}
+			p.next()
+		}
+	}
+	if p.peek().text == ";" {
+		p.next()
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unsupported ADQL near %q", p.peek().text)
+	}
+	return q, nil
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) done() bool { return p.pos >= len(p.toks) }
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{}
+	}
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// keyword consumes the next token if it is the keyword
+func (p *parser) keyword(kw string) bool {
+	t := p.peek()
+	if !t.quoted && strings.EqualFold(t.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) condition() (Condition, error) {
+	col := p.next()
+	if !isIdentifier(col) {
+		return Condition{}, fmt.Errorf("expected a column name in WHERE, got %q", col.text)
+	}
+	c := Condition{Column: col.text}
+	op := p.next()
+	switch o := strings.ToUpper(op.text); o {
+	case "=", "!=", "<>", "<", "<=", ">", ">=", "LIKE":
+		c.Op = o
+	default:
+		return Condition{}, fmt.Errorf("unsupported operator %q", op.text)
+	}
+	value := p.next()
+	switch {
+	case value.quoted:
+		c.Text = value.text
+	case value.text == "-" || value.text == "+":
+		// Signed number split by the tokenizer
+		n, err := strconv.ParseFloat(value.text+p.next().text, 64)
+		if err != nil {
+			return Condition{}, fmt.Errorf("invalid number in WHERE")
+		}
+		c.Number, c.IsNumber = n, true
+	default:
+		n, err := strconv.ParseFloat(value.text, 64)
+		if err != nil {
+			return Condition{}, fmt.Errorf("expected a number or 'string' after %s, got %q", c.Op, value.text)
+		}
+		c.Number, c.IsNumber = n, true
+	}
+	if c.Op == "LIKE" && c.IsNumber {
+		return Condition{}, fmt.Errorf("LIKE needs a 'string'")
+	}
+	return c, nil
+}
+
+func isIdentifier(t token) bool {
+	if t.quoted || t.text == "" {
+		return false
+	}
+	r := rune(t.text[0])
+	return unicode.IsLetter(r) || r == '_'
+}
+
+func tokenize(s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		ch := s[i]
+		switch {
+		case unicode.IsSpace(rune(ch)):
+			i++
+		case ch == '\'':
+			var b strings.Builder
+			i++
+			for {
+				if i >= len(s) {
+					return nil, fmt.Errorf("unterminated string literal")
+				}
+				if s[i] == '\'' {
+					if i+1 < len(s) && s[i+1] == '\'' {
+						b.WriteByte('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteByte(s[i])
+				i++
+			}
+			toks = append(toks, token{text: b.String(), quoted: true})
+		case ch == '"':
+			// Delimited identifier
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quoted identifier")
+			}
+			toks = append(toks, token{text: s[i+1 : i+1+end]})
+			i += end + 2
+		case strings.ContainsRune("<>!", rune(ch)):
+			if i+1 < len(s) && (s[i+1] == '=' || (ch == '<' && s[i+1] == '>')) {
+				toks = append(toks, token{text: s[i : i+2]})
+				i += 2
+			} else {
+				toks = append(toks, token{text: s[i : i+1]})
+				i++
+			}
+		case strings.ContainsRune("=,*;+-()", rune(ch)):
+			toks = append(toks, token{text: s[i : i+1]})
+			i++
+		default:
+			j := i
+			for j < len(s) && (isWordByte(s[j]) || (s[j] == '.' && j > i) || isExponentSign(s, i, j)) {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("unexpected character %q", ch)
+			}
+			toks = append(toks, token{text: s[i:j]})
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+// isExponentSign reports a sign inside a number like 1e-5 starting at i
+func isExponentSign(s string, i, j int) bool {
+	return (s[j] == '-' || s[j] == '+') && s[i] >= '0' && s[i] <= '9' && (s[j-1] == 'e' || s[j-1] == 'E')
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// Execute runs the query against table t; maxRows > 0 caps the rows and
+// reports whether the result was truncated
+func (q *Query) Execute(t *export.Table, maxRows int) (*export.Table, bool, error) {
+	lookup := func(name string) (*export.Column, error) {
+		for _, c := range t.Columns {
+			if strings.EqualFold(c.Name, name) {
+				return c, nil
+			}
+		}
+		return nil, fmt.Errorf("column %q not found in %s", name, t.Name)
+	}
+
+	var filters []rowFilter
+	for _, cond := range q.Where {
+		col, err := lookup(cond.Column)
+		if err != nil {
+			return nil, false, err
+		}
+		if (col.Kind == export.String) == cond.IsNumber {
+			return nil, false, fmt.Errorf("column %s compared with a value of the wrong type", col.Name)
+		}
+		f := rowFilter{col: col, cond: cond}
+		if cond.Op == "LIKE" {
+			f.re = likePattern(cond.Text)
+		}
+		filters = append(filters, f)
+	}
+
+	var rows []int
+	for i := 0; i < t.Rows(); i++ {
+		keep := true
+		for _, f := range filters {
+			if !f.match(i) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			rows = append(rows, i)
+		}
+	}
+
+	var orders []*export.Column
+	for _, o := range q.OrderBy {
+		col, err := lookup(o.Column)
+		if err != nil {
+			return nil, false, err
+		}
+		orders = append(orders, col)
+	}
+	sort.SliceStable(rows, func(a, b int) bool {
+		for k, col := range orders {
+			c := compareCells(col, rows[a], rows[b])
+			if c == 0 {
+				continue
+			}
+			if q.OrderBy[k].Desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+
+	truncated := false
+	if q.Top > 0 && len(rows) > q.Top {
+		rows = rows[:q.Top]
+	}
+	if maxRows > 0 && len(rows) > maxRows {
+		rows, truncated = rows[:maxRows], true
+	}
+
+	columns := t.Columns
+	if q.Columns != nil {
+		columns = nil
+		for _, name := range q.Columns {
+			col, err := lookup(name)
+			if err != nil {
+				return nil, false, err
+			}
+			columns = append(columns, col)
+		}
+	}
+	out := &export.Table{Name: t.Name}
+	for _, col := range columns {
+		c := &export.Column{Name: col.Name, Kind: col.Kind, Unit: col.Unit, UCD: col.UCD}
+		for _, i := range rows {
+			switch col.Kind {
+			case export.Float:
+				c.Floats = append(c.Floats, col.Floats[i])
+			case export.Int:
+				c.Ints = append(c.Ints, col.Ints[i])
+			default:
+				c.Strings = append(c.Strings, col.Strings[i])
+			}
+		}
+		out.Columns = append(out.Columns, c)
+	}
+	return out, truncated, nil
+}
+
+// rowFilter is a WHERE condition bound to its column
+type rowFilter struct {
+	col  *export.Column
+	cond Condition
+	re   *regexp.Regexp
+}
+
+func (f rowFilter) match(i int) bool {
+	if f.re != nil {
+		return f.re.MatchString(f.col.Strings[i])
+	}
+	var c int
+	switch f.col.Kind {
+	case export.Float:
+		c = compareFloat(f.col.Floats[i], f.cond.Number)
+	case export.Int:
+		c = compareFloat(float64(f.col.Ints[i]), f.cond.Number)
+	default:
+		c = strings.Compare(f.col.Strings[i], f.cond.Text)
+	}
+	switch f.cond.Op {
+	case "=":
+		return c == 0
+	case "!=", "<>":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+	return c >= 0
+}
+
+func compareCells(col *export.Column, a, b int) int {
+	switch col.Kind {
+	case export.Float:
+		return compareFloat(col.Floats[a], col.Floats[b])
+	case export.Int:
+		return compareFloat(float64(col.Ints[a]), float64(col.Ints[b]))
+	}
+	return strings.Compare(col.Strings[a], col.Strings[b])
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// likePattern converts an SQL LIKE pattern (% and _) to a regular expression
+func likePattern(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile("(?s)" + b.String())
+}
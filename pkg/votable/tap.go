@@ -0,0 +1,304 @@
+package votable
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/oxygene76/medasdigital-client/pkg/export"
+)
+
+// TAPPath is where the TAP service is mounted on the client's HTTP endpoint
+const TAPPath = "/tap"
+
+// defaultMaxRec caps query results unless MAXREC asks for another limit
+const defaultMaxRec = 100000
+
+// TableSource lists the tables the TAP service exposes, it is called for
+// every request so new results show up without a restart
+type TableSource func() ([]*export.Table, error)
+
+// TAPHandler serves a synchronous TAP 1.1 service with the ADQL subset of
+// Query below prefix:
+//
+//	prefix/sync          REQUEST=doQuery&LANG=ADQL&QUERY=...  (GET or POST)
+//	prefix/tables        VOSI table set
+//	prefix/capabilities  VOSI capabilities
+//	prefix/availability  VOSI availability
+//
+// TAP_SCHEMA.schemas, TAP_SCHEMA.tables and TAP_SCHEMA.columns describe the
+// tables and can be queried like them.
+func TAPHandler(prefix string, source TableSource) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(prefix+"/sync", func(w http.ResponseWriter, r *http.Request) {
+		serveSync(w, r, source)
+	})
+	mux.HandleFunc(prefix+"/tables", func(w http.ResponseWriter, r *http.Request) {
+		tables, err := source()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeTableSet(w, tables)
+	})
+	mux.HandleFunc(prefix+"/capabilities", func(w http.ResponseWriter, r *http.Request) {
+		writeCapabilities(w, baseURL(r, prefix))
+	})
+	mux.HandleFunc(prefix+"/availability", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<vosi:availability xmlns:vosi="http://www.ivoa.net/xml/VOSIAvailability/v1.0"><vosi:available>true</vosi:available></vosi:availability>
+`)
+	})
+	return mux
+}
+
+func serveSync(w http.ResponseWriter, r *http.Request, source TableSource) {
+	if err := r.ParseForm(); err != nil {
+		tapError(w, http.StatusBadRequest, "invalid request parameters")
+		return
+	}
+	// TAP parameter names are case-insensitive
+	param := func(name string) string {
+		for k, v := range r.Form {
+			if strings.EqualFold(k, name) && len(v) > 0 {
+				return v[0]
+			}
+		}
+		return ""
+	}
+
+	if req := param("REQUEST"); req != "" && !strings.EqualFold(req, "doQuery") {
+		tapError(w, http.StatusBadRequest, fmt.Sprintf("unsupported REQUEST %q, only doQuery", req))
+		return
+	}
+	if lang := param("LANG"); lang != "" && !strings.HasPrefix(strings.ToUpper(lang), "ADQL") {
+		tapError(w, http.StatusBadRequest, fmt.Sprintf("unsupported LANG %q, only ADQL", lang))
+		return
+	}
+	format := strings.ToLower(param("RESPONSEFORMAT"))
+	if format == "" {
+		format = strings.ToLower(param("FORMAT"))
+	}
+	switch format {
+	case "", "votable", "application/x-votable+xml", "text/xml":
+		format = "votable"
+	case "csv", "text/csv":
+		format = "csv"
+	default:
+		tapError(w, http.StatusBadRequest, fmt.Sprintf("unsupported format %q, use votable or csv", format))
+		return
+	}
+	maxRec := defaultMaxRec
+	if v := param("MAXREC"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			tapError(w, http.StatusBadRequest, "MAXREC must be a non-negative integer")
+			return
+		}
+		maxRec = n
+	}
+
+	q, err := ParseQuery(param("QUERY"))
+	if err != nil {
+		tapError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	tables, err := source()
+	if err != nil {
+		tapError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var table *export.Table
+	for _, t := range append(tables, tapSchema(tables)...) {
+		if strings.EqualFold(t.Name, q.Table) {
+			table = t
+			break
+		}
+	}
+	if table == nil {
+		tapError(w, http.StatusBadRequest, fmt.Sprintf("table %q not found, see %s/tables", q.Table, TAPPath))
+		return
+	}
+
+	result, overflow, err := q.Execute(table, maxRec)
+	if err != nil {
+		tapError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if maxRec == 0 {
+		// MAXREC=0 asks for the metadata only
+		result = &export.Table{Name: result.Name, Columns: emptyColumns(result)}
+		overflow = false
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		writeCSV(w, result)
+		return
+	}
+	doc := Document{Name: "result", Infos: []Info{{Name: "QUERY_STATUS", Value: "OK"}}, Tables: []*export.Table{result}}
+	if overflow {
+		doc.Trailer = []Info{{Name: "QUERY_STATUS", Value: "OVERFLOW"}}
+	}
+	w.Header().Set("Content-Type", MimeType)
+	Write(w, doc)
+}
+
+// tapError reports a failed query as VOTable as TAP requires
+func tapError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", MimeType)
+	w.WriteHeader(status)
+	Write(w, Document{Infos: []Info{{Name: "QUERY_STATUS", Value: "ERROR", Text: message}}})
+}
+
+func writeCSV(w http.ResponseWriter, t *export.Table) {
+	cw := csv.NewWriter(w)
+	cw.Write(columnNames(t))
+	for i := 0; i < t.Rows(); i++ {
+		record := make([]string, len(t.Columns))
+		for j, c := range t.Columns {
+			switch c.Kind {
+			case export.Float:
+				record[j] = strconv.FormatFloat(c.Floats[i], 'g', -1, 64)
+			case export.Int:
+				record[j] = strconv.FormatInt(c.Ints[i], 10)
+			default:
+				record[j] = c.Strings[i]
+			}
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+}
+
+func columnNames(t *export.Table) []string {
+	names := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+func emptyColumns(t *export.Table) []*export.Column {
+	cols := make([]*export.Column, len(t.Columns))
+	for i, c := range t.Columns {
+		cols[i] = &export.Column{Name: c.Name, Kind: c.Kind, Unit: c.Unit, UCD: c.UCD}
+	}
+	return cols
+}
+
+// tapSchema describes the tables in the TAP_SCHEMA tables
+func tapSchema(tables []*export.Table) []*export.Table {
+	str := func(name string, values ...string) *export.Column {
+		return &export.Column{Name: name, Kind: export.String, Strings: values}
+	}
+	schemas := &export.Table{Name: "TAP_SCHEMA.schemas", Columns: []*export.Column{str("schema_name", "TAP_SCHEMA", "default")}}
+
+	tableSchema, tableName, tableType := str("schema_name"), str("table_name"), str("table_type")
+	colTable, colName, colType, colUnit, colUCD := str("table_name"), str("column_name"), str("datatype"), str("unit"), str("ucd")
+	tablesTable := &export.Table{Name: "TAP_SCHEMA.tables", Columns: []*export.Column{tableSchema, tableName, tableType}}
+	columnsTable := &export.Table{Name: "TAP_SCHEMA.columns", Columns: []*export.Column{colTable, colName, colType, colUnit, colUCD}}
+	meta := []*export.Table{schemas, tablesTable, columnsTable}
+
+	for _, t := range append(append([]*export.Table(nil), tables...), meta...) {
+		schema := "default"
+		if strings.HasPrefix(t.Name, "TAP_SCHEMA.") {
+			schema = "TAP_SCHEMA"
+		}
+		tableSchema.Strings = append(tableSchema.Strings, schema)
+		tableName.Strings = append(tableName.Strings, t.Name)
+		tableType.Strings = append(tableType.Strings, "table")
+		for _, c := range t.Columns {
+			unit, ucd := c.Unit, c.UCD
+			if unit == "" && ucd == "" {
+				unit, ucd = guessUnitUCD(c.Name)
+			}
+			colTable.Strings = append(colTable.Strings, t.Name)
+			colName.Strings = append(colName.Strings, c.Name)
+			colType.Strings = append(colType.Strings, voType(c.Kind))
+			colUnit.Strings = append(colUnit.Strings, unit)
+			colUCD.Strings = append(colUCD.Strings, ucd)
+		}
+	}
+	return meta
+}
+
+// writeTableSet lists the tables as VOSI table set
+func writeTableSet(w http.ResponseWriter, tables []*export.Table) {
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<vosi:tableset xmlns:vosi="http://www.ivoa.net/xml/VOSITables/v1.0" xmlns:vs="http://www.ivoa.net/xml/VODataService/v1.1" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+<schema><name>default</name>
+`)
+	for _, t := range tables {
+		fmt.Fprintf(w, "<table><name>%s</name>\n", escape(t.Name))
+		for _, c := range t.Columns {
+			unit, ucd := c.Unit, c.UCD
+			if unit == "" && ucd == "" {
+				unit, ucd = guessUnitUCD(c.Name)
+			}
+			fmt.Fprintf(w, `<column><name>%s</name>`, escape(c.Name))
+			if unit != "" {
+				fmt.Fprintf(w, "<unit>%s</unit>", escape(unit))
+			}
+			if ucd != "" {
+				fmt.Fprintf(w, "<ucd>%s</ucd>", escape(ucd))
+			}
+			fmt.Fprintf(w, `<dataType xsi:type="vs:VOTableType"%s>%s</dataType></column>`+"\n", arraysize(c.Kind), voType(c.Kind))
+		}
+		fmt.Fprint(w, "</table>\n")
+	}
+	fmt.Fprint(w, "</schema>\n</vosi:tableset>\n")
+}
+
+// writeCapabilities announces the TAP and VOSI endpoints below base
+func writeCapabilities(w http.ResponseWriter, base string) {
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<vosi:capabilities xmlns:vosi="http://www.ivoa.net/xml/VOSICapabilities/v1.0" xmlns:vs="http://www.ivoa.net/xml/VODataService/v1.1" xmlns:tr="http://www.ivoa.net/xml/TAPRegExt/v1.0" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+<capability standardID="ivo://ivoa.net/std/TAP" xsi:type="tr:TableAccess">
+<interface xsi:type="vs:ParamHTTP" role="std" version="1.1"><accessURL use="base">%[1]s</accessURL></interface>
+<language><name>ADQL</name><version ivo-id="ivo://ivoa.net/std/ADQL#v2.0">2.0</version>
+<description>Subset: SELECT [TOP n] columns FROM table [WHERE ... AND ...] [ORDER BY ...]</description></language>
+<outputFormat><mime>application/x-votable+xml</mime><alias>votable</alias></outputFormat>
+<outputFormat><mime>text/csv</mime><alias>csv</alias></outputFormat>
+<outputLimit><default unit="row">%[2]d</default></outputLimit>
+</capability>
+<capability standardID="ivo://ivoa.net/std/VOSI#capabilities"><interface xsi:type="vs:ParamHTTP"><accessURL use="full">%[1]s/capabilities</accessURL></interface></capability>
+<capability standardID="ivo://ivoa.net/std/VOSI#availability"><interface xsi:type="vs:ParamHTTP"><accessURL use="full">%[1]s/availability</accessURL></interface></capability>
+<capability standardID="ivo://ivoa.net/std/VOSI#tables-1.1"><interface xsi:type="vs:ParamHTTP" version="1.1"><accessURL use="full">%[1]s/tables</accessURL></interface></capability>
+</vosi:capabilities>
+`, escape(base), defaultMaxRec)
+}
+
+func voType(k export.Kind) string {
+	switch k {
+	case export.Float:
+		return "double"
+	case export.Int:
+		return "long"
+	}
+	return "char"
+}
+
+func arraysize(k export.Kind) string {
+	if k == export.String {
+		return ` arraysize="*"`
+	}
+	return ""
+}
+
+// baseURL is the public URL of the service as seen by the client
+func baseURL(r *http.Request, prefix string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if p := r.Header.Get("X-Forwarded-Proto"); p == "http" || p == "https" {
+		scheme = p
+	}
+	return scheme + "://" + r.Host + prefix
+}
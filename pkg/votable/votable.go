@@ -0,0 +1,180 @@
+// Package votable writes tables as IVOA VOTable documents and serves them
+// through a minimal TAP endpoint, so TOPCAT and Aladin load results directly.
+package votable
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/oxygene76/medasdigital-client/pkg/export"
+)
+
+// MimeType is the media type of VOTable documents
+const MimeType = "application/x-votable+xml"
+
+// Info is an INFO element of the resource, e.g. the TAP QUERY_STATUS
+type Info struct {
+	Name, Value, Text string
+}
+
+// Document is one VOTable resource with its tables
+type Document struct {
+	Name        string
+	Description string
+	Infos       []Info
+	Params      map[string]string
+	Tables      []*export.Table
+	Trailer     []Info // INFOs after the tables, e.g. a TAP overflow
+}
+
+// FromDataset wraps an exported result, its attributes become PARAMs
+func FromDataset(name string, ds *export.Dataset) Document {
+	return Document{
+		Name:        name,
+		Description: fmt.Sprintf("%s result exported by medasdigital-client", ds.Type),
+		Params:      ds.Attributes,
+		Tables:      ds.Tables,
+	}
+}
+
+// Write serializes the document as VOTable 1.4 with TABLEDATA
+func Write(w io.Writer, doc Document) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<VOTABLE version="1.4" xmlns="http://www.ivoa.net/xml/VOTable/v1.3" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` + "\n")
+	fmt.Fprintf(bw, `<RESOURCE type="results"%s>`+"\n", attr("name", doc.Name))
+	if doc.Description != "" {
+		fmt.Fprintf(bw, "<DESCRIPTION>%s</DESCRIPTION>\n", escape(doc.Description))
+	}
+	for _, info := range doc.Infos {
+		writeInfo(bw, info)
+	}
+	keys := make([]string, 0, len(doc.Params))
+	for k := range doc.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(bw, `<PARAM%s datatype="char" arraysize="*"%s/>`+"\n", attr("name", k), attr("value", doc.Params[k]))
+	}
+	for _, t := range doc.Tables {
+		writeTable(bw, t)
+	}
+	for _, info := range doc.Trailer {
+		writeInfo(bw, info)
+	}
+	bw.WriteString("</RESOURCE>\n</VOTABLE>\n")
+	return bw.Flush()
+}
+
+func writeInfo(bw *bufio.Writer, info Info) {
+	fmt.Fprintf(bw, `<INFO%s%s>%s</INFO>`+"\n", attr("name", info.Name), attr("value", info.Value), escape(info.Text))
+}
+
+func writeTable(bw *bufio.Writer, t *export.Table) {
+	fmt.Fprintf(bw, `<TABLE%s nrows="%d">`+"\n", attr("name", t.Name), t.Rows())
+	for _, c := range t.Columns {
+		unit, ucd := c.Unit, c.UCD
+		if unit == "" && ucd == "" {
+			unit, ucd = guessUnitUCD(c.Name)
+		}
+		fmt.Fprintf(bw, `<FIELD%s%s%s%s/>`+"\n", attr("name", c.Name), datatype(c.Kind), attr("unit", unit), attr("ucd", ucd))
+	}
+	bw.WriteString("<DATA><TABLEDATA>\n")
+	for i := 0; i < t.Rows(); i++ {
+		bw.WriteString("<TR>")
+		for _, c := range t.Columns {
+			bw.WriteString("<TD>")
+			bw.WriteString(cell(c, i))
+			bw.WriteString("</TD>")
+		}
+		bw.WriteString("</TR>\n")
+	}
+	bw.WriteString("</TABLEDATA></DATA>\n</TABLE>\n")
+}
+
+func datatype(k export.Kind) string {
+	switch k {
+	case export.Float:
+		return ` datatype="double"`
+	case export.Int:
+		return ` datatype="long"`
+	}
+	return ` datatype="char" arraysize="*"`
+}
+
+// cell renders one value, empty cells are nulls
+func cell(c *export.Column, i int) string {
+	switch c.Kind {
+	case export.Float:
+		f := c.Floats[i]
+		if math.IsNaN(f) {
+			return ""
+		}
+		if math.IsInf(f, 0) {
+			if f > 0 {
+				return "+Inf"
+			}
+			return "-Inf"
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	case export.Int:
+		return strconv.FormatInt(c.Ints[i], 10)
+	}
+	return escape(c.Strings[i])
+}
+
+// guessUnitUCD fills in unit and UCD of well-known column names, TOPCAT and
+// Aladin use pos.eq.ra/dec to plot the rows on the sky. Only top-level
+// columns are marked meta.main, nested ones belong to a sub-object.
+func guessUnitUCD(name string) (string, string) {
+	dot := strings.LastIndex(name, ".")
+	main := ""
+	if dot < 0 {
+		main = ";meta.main"
+	}
+	switch name[dot+1:] {
+	case "ra", "center_ra":
+		return "deg", "pos.eq.ra" + main
+	case "dec", "center_dec":
+		return "deg", "pos.eq.dec" + main
+	case "magnitude", "magnitude_est", "absolute_magnitude":
+		return "mag", "phot.mag"
+	case "semi_major_axis", "semimajor_axis":
+		return "AU", "phys.size.smajAxis"
+	case "eccentricity":
+		return "", "src.orbital.eccentricity"
+	case "inclination":
+		return "deg", "src.orbital.inclination"
+	case "mean_anomaly":
+		return "deg", "src.orbital.meanAnomaly"
+	case "argument_periapsis":
+		return "deg", "src.orbital.periastron"
+	case "longitude_node", "longitude_ascending":
+		return "deg", "src.orbital.node"
+	case "id", "object_id", "target_id":
+		return "", "meta.id" + main
+	case "name", "designation":
+		return "", "meta.id"
+	}
+	return "", ""
+}
+
+func attr(name, value string) string {
+	if value == "" {
+		return ""
+	}
+	return " " + name + `="` + escape(value) + `"`
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
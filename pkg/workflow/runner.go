@@ -0,0 +1,298 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExecFunc runs the client with args in dir, writing its output to out
+type ExecFunc func(ctx context.Context, dir string, args []string, out io.Writer) error
+
+// Runner executes workflows
+type Runner struct {
+	Exec    ExecFunc
+	Out     io.Writer
+	Restart bool   // ignore the state of earlier runs
+	From    string // rerun this step and all after it
+	DryRun  bool   // print the commands without running them
+}
+
+// State records finished commands for resuming, in <workdir>/state.json
+type State struct {
+	Workflow string                `json:"workflow"`
+	Steps    map[string]*StepState `json:"steps"`
+}
+
+// StepState holds the fingerprints of the finished commands of a step
+type StepState struct {
+	Status   string               `json:"status"` // done, failed or running
+	Error    string               `json:"error,omitempty"`
+	Done     map[string]time.Time `json:"done"`
+	Finished *time.Time           `json:"finished,omitempty"`
+}
+
+// StatePath is the state file of the workflow
+func (wf *Workflow) StatePath() string {
+	return filepath.Join(wf.Path(wf.Workdir), "state.json")
+}
+
+// LoadState reads the state of earlier runs, empty when there is none
+func (wf *Workflow) LoadState() (*State, error) {
+	st := &State{Workflow: wf.Name, Steps: map[string]*StepState{}}
+	data, err := os.ReadFile(wf.StatePath())
+	if os.IsNotExist(err) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("corrupt state file %s: %w", wf.StatePath(), err)
+	}
+	if st.Steps == nil {
+		st.Steps = map[string]*StepState{}
+	}
+	return st, nil
+}
+
+func (wf *Workflow) saveState(st *State) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := wf.StatePath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, wf.StatePath())
+}
+
+// fingerprint identifies a command line; a changed parameter gives a new
+// fingerprint and the command runs again
+func fingerprint(args []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(args, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Run executes the steps in order and stops at the first failed step.
+// Commands finished in earlier runs are skipped, unless a step before them
+// ran again and may have changed their input.
+func (r *Runner) Run(ctx context.Context, wf *Workflow) error {
+	if r.From != "" {
+		found := false
+		for _, s := range wf.Steps {
+			found = found || s.Name == r.From
+		}
+		if !found {
+			return fmt.Errorf("workflow has no step %q", r.From)
+		}
+	}
+	if err := os.MkdirAll(wf.Path(wf.Workdir), 0755); err != nil {
+		return fmt.Errorf("failed to create workdir: %w", err)
+	}
+	st, err := wf.LoadState()
+	if err != nil {
+		return err
+	}
+	if r.Restart {
+		st.Steps = map[string]*StepState{}
+	}
+
+	var mu sync.Mutex
+	save := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if !r.DryRun {
+			if err := wf.saveState(st); err != nil {
+				fmt.Fprintf(r.Out, "warning: failed to save state: %v\n", err)
+			}
+		}
+	}
+
+	dirty := false // an earlier step ran, later results are stale
+	for i := range wf.Steps {
+		step := &wf.Steps[i]
+		if step.Name == r.From {
+			dirty = true
+		}
+		ss := st.Steps[step.Name]
+		if ss == nil || dirty {
+			ss = &StepState{Done: map[string]time.Time{}}
+			st.Steps[step.Name] = ss
+		}
+
+		items, err := wf.items(step)
+		if err != nil {
+			if r.DryRun {
+				// Inputs of later steps do not exist before the run
+				fmt.Fprintf(r.Out, "[%s] %v\n", step.Name, err)
+				continue
+			}
+			ss.Status, ss.Error = "failed", err.Error()
+			save()
+			return err
+		}
+		var pending []string
+		for _, item := range items {
+			if _, ok := ss.Done[fingerprint(wf.Args(step, item))]; !ok {
+				pending = append(pending, item)
+			}
+		}
+		if len(pending) == 0 {
+			fmt.Fprintf(r.Out, "[%s] done, skipped (%d commands)\n", step.Name, len(items))
+			continue
+		}
+		dirty = true
+
+		fmt.Fprintf(r.Out, "[%s] running %d of %d commands\n", step.Name, len(pending), len(items))
+		if r.DryRun {
+			for _, item := range pending {
+				fmt.Fprintf(r.Out, "  %s\n", strings.Join(wf.Args(step, item), " "))
+			}
+			continue
+		}
+		ss.Status, ss.Error = "running", ""
+		save()
+
+		started := time.Now()
+		failed := r.runStep(ctx, wf, step, pending, func(item string) {
+			mu.Lock()
+			ss.Done[fingerprint(wf.Args(step, item))] = time.Now()
+			mu.Unlock()
+			save()
+		})
+		if ctx.Err() != nil {
+			ss.Status, ss.Error = "failed", "interrupted"
+			save()
+			return ctx.Err()
+		}
+		if len(failed) > 0 {
+			ss.Status = "failed"
+			ss.Error = fmt.Sprintf("%d of %d commands failed", len(failed), len(pending))
+			save()
+			return fmt.Errorf("step %s: %s (%s)", step.Name, ss.Error, strings.Join(failed, "; "))
+		}
+		finished := time.Now()
+		ss.Status, ss.Finished = "done", &finished
+		save()
+		fmt.Fprintf(r.Out, "[%s] done in %s\n", step.Name, time.Since(started).Round(time.Second))
+	}
+	return nil
+}
+
+// runStep runs the pending items with the step's parallelism and returns
+// the errors of the items that failed after all retries
+func (r *Runner) runStep(ctx context.Context, wf *Workflow, step *Step, pending []string, done func(string)) []string {
+	var (
+		mu     sync.Mutex
+		failed []string
+		wg     sync.WaitGroup
+	)
+	sem := make(chan struct{}, wf.parallelism(step))
+	for _, item := range pending {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return failed
+		}
+		wg.Add(1)
+		go func(item string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := r.runItem(ctx, wf, step, item); err != nil {
+				label := step.Name
+				if item != "" {
+					label = item
+				}
+				mu.Lock()
+				failed = append(failed, fmt.Sprintf("%s: %v", label, err))
+				mu.Unlock()
+				return
+			}
+			done(item)
+		}(item)
+	}
+	wg.Wait()
+	return failed
+}
+
+// runItem runs one command with retries and exponential backoff
+func (r *Runner) runItem(ctx context.Context, wf *Workflow, step *Step, item string) error {
+	args := wf.Args(step, item)
+	prefix := "[" + step.Name
+	if item != "" {
+		prefix += " " + filepath.Base(item)
+	}
+	prefix += "] "
+	out := &prefixWriter{w: r.Out, prefix: prefix}
+	defer out.Flush()
+
+	retries := wf.retries(step)
+	delay := wf.RetryDelay
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			fmt.Fprintf(out, "attempt %d failed: %v, retrying in %s\n", attempt, err, delay)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			delay *= 2
+		}
+		runCtx, cancel := ctx, context.CancelFunc(func() {})
+		if step.Timeout > 0 {
+			runCtx, cancel = context.WithTimeout(ctx, step.Timeout)
+		}
+		err = r.Exec(runCtx, wf.BaseDir, args, out)
+		cancel()
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// prefixWriter prefixes every line, so that output of parallel commands
+// stays readable; lines are written whole
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	buf    bytes.Buffer
+}
+
+var outputMu sync.Mutex
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf.Write(b)
+	for {
+		line, err := p.buf.ReadBytes('\n')
+		if err != nil {
+			// Incomplete line, keep it for the next write
+			p.buf.Reset()
+			p.buf.Write(line)
+			return len(b), nil
+		}
+		outputMu.Lock()
+		fmt.Fprint(p.w, p.prefix+string(line))
+		outputMu.Unlock()
+	}
+}
+
+// Flush writes an incomplete last line
+func (p *prefixWriter) Flush() {
+	if p.buf.Len() > 0 {
+		p.Write([]byte("\n"))
+	}
+}
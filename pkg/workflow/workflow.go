@@ -0,0 +1,249 @@
+// Package workflow runs batch analyses defined in YAML: a chain of client
+// commands with per-step parameters, fan-out over input files, retries and
+// a state file so that interrupted runs resume where they stopped.
+//
+//	name: night-2026-09-20
+//	workdir: runs/2026-09-20
+//	parallelism: 4
+//	steps:
+//	  - name: preprocess
+//	    uses: preprocess
+//	    foreach: raw/field*
+//	    args: ["{item}"]
+//	    params: {bias: bias/, flat: flats/, solve: true, output-dir: "{workdir}/{stem}"}
+//	  - name: detect
+//	    uses: detect
+//	    foreach: raw/field*
+//	    args: [models/tno.onnx, "{workdir}/{stem}/*_cal.fits"]
+//	    params: {output: "{workdir}/{stem}/detections.json"}
+//	    retries: 2
+//
+// Steps run in order; a step with foreach runs once per matching file, up
+// to parallelism at a time. Relative paths are relative to the workflow file.
+package workflow
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// stepCommands maps step types to client commands
+var stepCommands = map[string][]string{
+	"preprocess": {"pipeline", "preprocess"},
+	"detect":     {"ai", "detect"},
+	"crossmatch": {"ai", "crossmatch"},
+	"link":       {"ai", "link"},
+	"orbit-fit":  {"analyze", "orbital-dynamics"},
+	"store":      {"results", "store"},
+	"command":    nil, // any client command, from Step.Command
+}
+
+// StepTypes lists the supported values of uses
+func StepTypes() []string {
+	var types []string
+	for t := range stepCommands {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Workflow is a workflow file
+type Workflow struct {
+	Name        string        `yaml:"name"`
+	Workdir     string        `yaml:"workdir"`     // state file and {workdir}, default .workflow/<name>
+	Parallelism int           `yaml:"parallelism"` // default for foreach steps, 1
+	Retries     int           `yaml:"retries"`     // default retries per command
+	RetryDelay  time.Duration `yaml:"retry_delay"` // before the first retry, doubled after each, default 10s
+	Steps       []Step        `yaml:"steps"`
+
+	// BaseDir is the directory of the workflow file
+	BaseDir string `yaml:"-"`
+}
+
+// Step is one stage of the workflow
+type Step struct {
+	Name        string                 `yaml:"name"`
+	Uses        string                 `yaml:"uses"`
+	Command     []string               `yaml:"command"` // client command for uses: command, e.g. [planet9, search]
+	Args        []string               `yaml:"args"`
+	Params      map[string]interface{} `yaml:"params"` // flags without the leading --
+	ForEach     string                 `yaml:"foreach"`
+	Parallelism int                    `yaml:"parallelism"`
+	Retries     *int                   `yaml:"retries"`
+	Timeout     time.Duration          `yaml:"timeout"` // per command, none by default
+}
+
+// Load reads and validates a workflow file
+func Load(path string) (*Workflow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow: %w", err)
+	}
+	var wf Workflow
+	if err := yaml.Unmarshal(data, &wf); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	wf.BaseDir = filepath.Dir(abs)
+	if wf.Name == "" {
+		wf.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if wf.Workdir == "" {
+		wf.Workdir = filepath.Join(".workflow", wf.Name)
+	}
+	if wf.Parallelism <= 0 {
+		wf.Parallelism = 1
+	}
+	if wf.RetryDelay <= 0 {
+		wf.RetryDelay = 10 * time.Second
+	}
+	if err := wf.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &wf, nil
+}
+
+// Validate checks the steps
+func (wf *Workflow) Validate() error {
+	if len(wf.Steps) == 0 {
+		return fmt.Errorf("workflow has no steps")
+	}
+	if wf.Retries < 0 {
+		return fmt.Errorf("retries must not be negative")
+	}
+	seen := make(map[string]bool)
+	for i, s := range wf.Steps {
+		if s.Name == "" {
+			return fmt.Errorf("step %d has no name", i+1)
+		}
+		if seen[s.Name] {
+			return fmt.Errorf("step name %q is used twice", s.Name)
+		}
+		seen[s.Name] = true
+		if _, ok := stepCommands[s.Uses]; !ok {
+			return fmt.Errorf("step %s: unknown type %q (one of %s)", s.Name, s.Uses, strings.Join(StepTypes(), ", "))
+		}
+		if s.Uses == "command" && len(s.Command) == 0 {
+			return fmt.Errorf("step %s: uses command needs command: [...]", s.Name)
+		}
+		if s.Uses != "command" && len(s.Command) > 0 {
+			return fmt.Errorf("step %s: command is only valid with uses: command", s.Name)
+		}
+		if len(s.Command) > 1 && s.Command[0] == "pipeline" && s.Command[1] == "run" {
+			return fmt.Errorf("step %s: workflows cannot run workflows", s.Name)
+		}
+		if s.Retries != nil && *s.Retries < 0 {
+			return fmt.Errorf("step %s: retries must not be negative", s.Name)
+		}
+	}
+	return nil
+}
+
+// Path resolves a path relative to the workflow file
+func (wf *Workflow) Path(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(wf.BaseDir, p)
+}
+
+// retries is the number of retries of the step
+func (wf *Workflow) retries(s *Step) int {
+	if s.Retries != nil {
+		return *s.Retries
+	}
+	return wf.Retries
+}
+
+// parallelism is the number of concurrent commands of the step
+func (wf *Workflow) parallelism(s *Step) int {
+	if s.Parallelism > 0 {
+		return s.Parallelism
+	}
+	return wf.Parallelism
+}
+
+// expand replaces {workdir}, {name}, {item} and {stem} in s
+func (wf *Workflow) expand(s, item string) string {
+	r := strings.NewReplacer(
+		"{workdir}", wf.Workdir,
+		"{name}", wf.Name,
+		"{item}", item,
+		"{stem}", strings.TrimSuffix(filepath.Base(item), filepath.Ext(item)),
+	)
+	return r.Replace(s)
+}
+
+// items are the files a foreach step runs on, a single empty item otherwise
+func (wf *Workflow) items(s *Step) ([]string, error) {
+	if s.ForEach == "" {
+		return []string{""}, nil
+	}
+	pattern := wf.expand(s.ForEach, "")
+	matches, err := filepath.Glob(wf.Path(pattern))
+	if err != nil {
+		return nil, fmt.Errorf("step %s: bad foreach pattern: %w", s.Name, err)
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("step %s: no files match %s", s.Name, pattern)
+	}
+	items := make([]string, len(matches))
+	for i, m := range matches {
+		// Keep items relative like the pattern, commands run in BaseDir
+		if rel, err := filepath.Rel(wf.BaseDir, m); err == nil && !filepath.IsAbs(pattern) {
+			m = rel
+		}
+		items[i] = m
+	}
+	return items, nil
+}
+
+// Args builds the command line of the step for one item: the command, the
+// positional args and the params as flags, sorted by name
+func (wf *Workflow) Args(s *Step, item string) []string {
+	args := append([]string(nil), stepCommands[s.Uses]...)
+	if s.Uses == "command" {
+		args = append(args, s.Command...)
+	}
+	for _, a := range s.Args {
+		args = append(args, wf.expand(a, item))
+	}
+
+	keys := make([]string, 0, len(s.Params))
+	for k := range s.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		flag := "--" + strings.TrimLeft(k, "-")
+		switch v := s.Params[k].(type) {
+		case bool:
+			if v {
+				args = append(args, flag)
+			} else {
+				args = append(args, flag+"=false")
+			}
+		case []interface{}:
+			parts := make([]string, len(v))
+			for i, p := range v {
+				parts[i] = wf.expand(fmt.Sprint(p), item)
+			}
+			args = append(args, flag+"="+strings.Join(parts, ","))
+		case nil:
+			args = append(args, flag)
+		default:
+			args = append(args, flag+"="+wf.expand(fmt.Sprint(v), item))
+		}
+	}
+	return args
+}